@@ -1,7 +1,7 @@
 """AI orchestration agent"""
 
 import os
-from typing import Dict, Any, Optional, List
+from typing import Dict, Any, Optional, List, AsyncIterator
 from openai import OpenAI
 
 from .scan_planner import ScanPlanner, ScanPlan
@@ -70,6 +70,25 @@ class CyperAI:
             context
         )
 
+    def stream_analysis(
+        self,
+        scan_type: str,
+        raw_results: Dict[str, Any],
+        context: Optional[Dict[str, Any]] = None
+    ) -> AsyncIterator[str]:
+        """
+        Interpret scan results, streaming the analysis as it is written.
+        
+        Args:
+            scan_type: Type of scan performed
+            raw_results: Raw scan output
+            context: Additional context
+            
+        Returns:
+            Async iterator of analysis text chunks
+        """
+        return self.results_analyzer.stream_analysis(scan_type, raw_results, context)
+
     async def generate_report(
         self,
         scan_results: Dict[str, Any],
@@ -184,6 +203,47 @@ Include specific technical details, exploitation scenarios, and remediation step
 """
         
         else:  # compliance
+            # Framework report types (pci-dss, iso-27001, nist-csf) come with
+            # the gateway's assessment of findings against the framework's controls
+            assessment = scan_results.get("compliance")
+            if assessment:
+                failing = [c for c in assessment.get("controls", []) if c.get("status") == "failing"]
+                passing = [c for c in assessment.get("controls", []) if c.get("status") == "passing"]
+                summary = assessment.get("summary", {})
+                failing_str = "\n".join(
+                    f"- {c['id']} {c['title']}: {c['findings']} finding(s), highest severity {c.get('highest_severity', 'unknown')}"
+                    for c in failing
+                ) or "- None"
+                passing_str = "\n".join(f"- {c['id']} {c['title']}" for c in passing) or "- None"
+
+                return f"""Generate a {assessment.get('name')} {assessment.get('version')} compliance report.
+
+Analysis: {analysis.executive_summary}
+Risk Score: {analysis.risk_score}/100
+
+Control coverage: {summary.get('controls_passing', 0)} of {summary.get('controls', 0)} mapped controls have no open findings.
+Findings mapped to controls: {summary.get('findings_mapped', 0)} of {summary.get('findings', 0)}
+
+Controls with open findings:
+{failing_str}
+
+Controls without open findings:
+{passing_str}
+
+Create a compliance report in markdown format that includes:
+
+# {assessment.get('name')} Compliance Assessment
+
+## 1. Summary of Control Coverage
+## 2. Non-Compliant Controls
+For each failing control: the requirement, the findings violating it, and the business impact
+## 3. Remediation Required for Compliance
+Ordered by severity, referencing control IDs
+## 4. Controls Without Findings
+## 5. Evidence and Limitations
+Note that automated scanning covers only technical controls.
+"""
+
             return f"""Generate a compliance-focused security report.
 
 Analysis: {analysis.executive_summary}
@@ -1,6 +1,6 @@
 """AI-driven results analysis and interpretation"""
 
-from typing import Dict, Any, List, Optional
+from typing import Dict, Any, List, Optional, AsyncIterator
 from dataclasses import dataclass
 import os
 import json
@@ -63,6 +63,43 @@ class ResultsAnalyzer:
         
         return analysis
 
+    async def stream_analysis(
+        self,
+        scan_type: str,
+        raw_results: Dict[str, Any],
+        context: Optional[Dict[str, Any]] = None
+    ) -> AsyncIterator[str]:
+        """
+        Stream the analysis text as the model produces it.
+        
+        Args:
+            scan_type: Type of scan performed
+            raw_results: Raw scan output
+            context: Additional context
+            
+        Yields:
+            Chunks of analysis text
+        """
+        
+        prompt = self._build_analysis_prompt(scan_type, raw_results, context or {})
+        
+        stream = self.client.chat.completions.create(
+            model=self.model,
+            max_tokens=4000,
+            messages=[{"role": "user", "content": prompt}],
+            stream=True
+        )
+        
+        try:
+            for chunk in stream:
+                if not chunk.choices:
+                    continue
+                text = chunk.choices[0].delta.content
+                if text:
+                    yield text
+        finally:
+            stream.close()
+
     def _build_analysis_prompt(
         self,
         scan_type: str,
@@ -1,7 +1,11 @@
 import os
+import json
+import base64
+import asyncio
 import logging
 from aiohttp import web
 from cyper_brain.ai.agent import CyperAI
+from cyper_brain.reporting.watermark import watermark_pdf
 
 # Configure logging
 logging.basicConfig(level=logging.INFO)
@@ -32,6 +36,41 @@ async def analyze_target(request):
         logger.error(f"Analysis failed: {e}")
         return web.json_response({"error": str(e)}, status=500)
 
+async def stream_analysis(request):
+    """Streams analysis of scan results as server-sent events: "chunk" events
+    carry text as it is generated, then "done" or "error" ends the stream."""
+    data = await request.json()
+    scan_results = data.get("scan_results")
+    if not scan_results:
+        return web.json_response({"error": "Scan results required"}, status=400)
+
+    response = web.StreamResponse(headers={
+        "Content-Type": "text/event-stream",
+        "Cache-Control": "no-cache",
+    })
+    await response.prepare(request)
+
+    async def send(event, payload):
+        await response.write(f"event: {event}\ndata: {json.dumps(payload)}\n\n".encode())
+
+    try:
+        async for text in agent.stream_analysis(
+            data.get("scan_type", scan_results.get("scan_type", "unknown")),
+            scan_results,
+            data.get("context"),
+        ):
+            await send("chunk", {"text": text})
+        await send("done", {})
+    except (ConnectionResetError, asyncio.CancelledError):
+        # The gateway went away; stop generating
+        logger.info("Analysis stream cancelled by client")
+        raise
+    except Exception as e:
+        logger.error(f"Streaming analysis failed: {e}")
+        await send("error", {"error": str(e)})
+
+    return response
+
 async def generate_report(request):
     try:
         data = await request.json()
@@ -72,6 +111,13 @@ async def generate_report(request):
             pdf_path = agent.generate_formatted_report(analysis, metadata, output_path)
             return web.json_response({"path": pdf_path, "status": "generated"})
 
+        elif data.get("format") == "html":
+            # HTML Generation (same templates as the PDF, returned inline)
+            analysis_dict = data.get("analysis") or {}
+            metadata = data.get("metadata", {})
+            html = agent.report_generator.render_html({**analysis_dict, **metadata})
+            return web.json_response({"report": html, "status": "generated"})
+
         else:
             # Markdown Generation
             report = await agent.generate_report(scan_results, report_type)
@@ -81,6 +127,34 @@ async def generate_report(request):
         logger.error(f"Report generation failed: {e}")
         return web.json_response({"error": str(e)}, status=500)
 
+REPORTS_DIR = os.getenv("REPORTS_DIR", "/reports")
+
+async def download_report_file(request):
+    """Serves a generated report file to the gateway for storage."""
+    path = os.path.realpath(request.query.get("path", ""))
+    if not path.startswith(os.path.realpath(REPORTS_DIR) + os.sep):
+        return web.json_response({"error": "Path outside reports directory"}, status=400)
+    if not os.path.isfile(path):
+        return web.json_response({"error": "Report file not found"}, status=404)
+    return web.FileResponse(path)
+
+# Watermark requests carry the report base64-encoded; reports are at most 50 MB
+MAX_REQUEST_SIZE = 80 * 1024 * 1024
+
+async def watermark_report(request):
+    """Stamps a report PDF with who is downloading it, for the gateway."""
+    try:
+        data = await request.json()
+        pdf = base64.b64decode(data.get("pdf") or "")
+        if not pdf:
+            return web.json_response({"error": "PDF required"}, status=400)
+
+        stamped = await asyncio.to_thread(watermark_pdf, pdf, data.get("metadata") or {})
+        return web.Response(body=stamped, content_type="application/pdf")
+    except Exception as e:
+        logger.error(f"Report watermarking failed: {e}")
+        return web.json_response({"error": str(e)}, status=500)
+
 async def ask_question(request):
     try:
         data = await request.json()
@@ -97,11 +171,14 @@ async def ask_question(request):
         return web.json_response({"error": str(e)}, status=500)
 
 def main():
-    app = web.Application()
+    app = web.Application(client_max_size=MAX_REQUEST_SIZE)
     app.add_routes([
         web.get('/health', health_check),
         web.post('/api/v1/analyze', analyze_target),
+        web.post('/api/v1/analyze/stream', stream_analysis),
         web.post('/api/v1/report', generate_report),
+        web.get('/api/v1/report/file', download_report_file),
+        web.post('/api/v1/report/watermark', watermark_report),
         web.post('/api/v1/ask', ask_question),
     ])
     
@@ -42,6 +42,11 @@ class ReportGenerator:
         
         return output_path
 
+    def render_html(self, data: Dict[str, Any]) -> str:
+        """Renders the report as a standalone HTML document."""
+        context = self._prepare_context(data)
+        return self.env.get_template("executive_summary.html").render(**context)
+
     def _prepare_context(self, data: Dict[str, Any]) -> Dict[str, Any]:
         """Enriches raw data with formatting for the template."""
         return {
@@ -0,0 +1,84 @@
+"""Stamps downloaded PDF reports with the identity of whoever downloaded them."""
+import io
+from html import escape
+from typing import Any, Dict, Tuple
+
+from pypdf import PdfReader, PdfWriter
+from pypdf.generic import PageObject
+from weasyprint import HTML
+
+
+def watermark_text(metadata: Dict[str, Any]) -> str:
+    """One line naming the downloader, where from and when."""
+    who = metadata.get("email") or metadata.get("user_id") or "unknown user"
+    text = f"Downloaded by {who}"
+    if metadata.get("ip_address"):
+        text += f" from {metadata['ip_address']}"
+    if metadata.get("downloaded_at"):
+        text += f" at {metadata['downloaded_at']}"
+    if metadata.get("download_id"):
+        text += f" - download {metadata['download_id']}"
+    return text
+
+
+def _overlay(size: Tuple[float, float], text: str) -> PageObject:
+    """Renders a transparent page of the given size carrying the stamp."""
+    width, height = size
+    html = f"""<html><head><style>
+        @page {{ size: {width}pt {height}pt; margin: 0; }}
+        body {{ margin: 0; font-family: sans-serif; }}
+        .diagonal {{
+            position: absolute; top: 45%; left: 0; right: 0;
+            text-align: center; transform: rotate(-30deg);
+            font-size: 14pt; font-weight: bold; color: rgba(200, 0, 0, 0.12);
+        }}
+        .footer {{
+            position: absolute; bottom: 6pt; left: 0; right: 0;
+            text-align: center; font-size: 7pt; color: rgba(90, 90, 90, 0.9);
+        }}
+    </style></head><body>
+        <div class="diagonal">{escape(text)}</div>
+        <div class="footer">{escape(text)}</div>
+    </body></html>"""
+    return PdfReader(io.BytesIO(HTML(string=html).write_pdf())).pages[0]
+
+
+def watermark_pdf(pdf: bytes, metadata: Dict[str, Any]) -> bytes:
+    """
+    Stamps every page of a PDF with the downloader's identity and records it
+    in the document information.
+
+    Args:
+        pdf: The stored report
+        metadata: download_id, user_id, email, organization_id, ip_address
+            and downloaded_at, as sent by the gateway
+
+    Returns:
+        bytes: The watermarked PDF
+    """
+    reader = PdfReader(io.BytesIO(pdf))
+    writer = PdfWriter()
+    text = watermark_text(metadata)
+
+    overlays: Dict[Tuple[float, float], PageObject] = {}
+    for page in reader.pages:
+        size = (float(page.mediabox.width), float(page.mediabox.height))
+        if size not in overlays:
+            overlays[size] = _overlay(size, text)
+        page.merge_page(overlays[size])
+        writer.add_page(page)
+
+    if reader.metadata:
+        writer.add_metadata(dict(reader.metadata))
+    writer.add_metadata({
+        "/DownloadID": str(metadata.get("download_id", "")),
+        "/DownloadedBy": str(metadata.get("email") or metadata.get("user_id", "")),
+        "/DownloadedByUserID": str(metadata.get("user_id", "")),
+        "/DownloadOrganizationID": str(metadata.get("organization_id", "")),
+        "/DownloadIPAddress": str(metadata.get("ip_address", "")),
+        "/DownloadedAt": str(metadata.get("downloaded_at", "")),
+    })
+
+    out = io.BytesIO()
+    writer.write(out)
+    return out.getvalue()
@@ -0,0 +1,39 @@
+-- Migration: Add Versioned Terms of Use
+-- Date: 2026-10-15
+-- Description: Stores published terms versions and per-user acceptance records
+
+CREATE TABLE terms_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    version VARCHAR(20) UNIQUE NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    document_url TEXT,
+    effective_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_terms_versions_effective ON terms_versions(effective_at DESC);
+
+CREATE TABLE terms_acceptances (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    terms_version VARCHAR(20) NOT NULL REFERENCES terms_versions(version),
+    ip_address INET,
+    user_agent TEXT,
+    accepted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(user_id, terms_version)
+);
+
+CREATE INDEX idx_terms_acceptances_user ON terms_acceptances(user_id, accepted_at DESC);
+
+-- Seed the version already recorded for existing users
+INSERT INTO terms_versions (version, title, content, document_url, effective_at)
+VALUES ('1.0', 'Cyper Security Terms of Use', 'See TERMS_OF_USE.md', '/TERMS_OF_USE.md', '2025-12-31')
+ON CONFLICT (version) DO NOTHING;
+
+INSERT INTO terms_acceptances (user_id, terms_version, accepted_at)
+SELECT id, terms_version, terms_accepted_at
+FROM users
+WHERE terms_accepted_at IS NOT NULL AND terms_version = '1.0'
+ON CONFLICT (user_id, terms_version) DO NOTHING;
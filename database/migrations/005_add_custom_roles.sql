@@ -0,0 +1,54 @@
+-- Migration: Add Custom Organization Roles
+-- Date: 2026-10-15
+-- Description: Lets organizations define roles composed of built-in permissions
+
+-- ============================================================================
+-- Custom Roles
+-- ============================================================================
+
+CREATE TABLE organization_roles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT reserved_role_name CHECK (name NOT IN ('owner', 'admin', 'scanner', 'viewer')),
+    UNIQUE(organization_id, name)
+);
+
+CREATE INDEX idx_organization_roles_org ON organization_roles(organization_id);
+
+CREATE TRIGGER update_organization_roles_updated_at BEFORE UPDATE ON organization_roles
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- ============================================================================
+-- Memberships may reference built-in or organization-defined roles
+-- ============================================================================
+
+ALTER TABLE organization_memberships DROP CONSTRAINT valid_role;
+
+CREATE OR REPLACE FUNCTION validate_membership_role()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.role IN ('owner', 'admin', 'scanner', 'viewer') THEN
+        RETURN NEW;
+    END IF;
+
+    IF NOT EXISTS (
+        SELECT 1 FROM organization_roles
+        WHERE organization_id = NEW.organization_id AND name = NEW.role
+    ) THEN
+        RAISE EXCEPTION 'role % is not defined for organization %', NEW.role, NEW.organization_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER validate_membership_role BEFORE INSERT OR UPDATE OF role ON organization_memberships
+    FOR EACH ROW EXECUTE FUNCTION validate_membership_role();
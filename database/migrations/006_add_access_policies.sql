@@ -0,0 +1,33 @@
+-- Migration: Add Attribute-Based Access Policies
+-- Date: 2026-10-15
+-- Description: Adds target tags and per-organization policies that constrain roles by resource attributes
+
+-- Tags on authorized targets (e.g. staging, production) used by policy conditions
+ALTER TABLE authorized_targets ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_authorized_targets_tags ON authorized_targets USING GIN(tags);
+
+-- ============================================================================
+-- Access Policies
+-- ============================================================================
+
+CREATE TABLE access_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    permissions TEXT[] NOT NULL,
+    conditions JSONB NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(organization_id, name)
+);
+
+CREATE INDEX idx_access_policies_org ON access_policies(organization_id) WHERE enabled;
+
+CREATE TRIGGER update_access_policies_updated_at BEFORE UPDATE ON access_policies
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,18 @@
+-- Migration: Add Vulnerability Fingerprints
+-- Date: 2026-10-15
+-- Description: Dedup key identifying the same finding across scan runs (see internal/findings)
+
+ALTER TABLE vulnerabilities ADD COLUMN fingerprint VARCHAR(64);
+
+-- Backfill with the same normalization as findings.Fingerprint
+UPDATE vulnerabilities
+SET fingerprint = encode(sha256(convert_to(
+    lower(btrim(title)) || '|' ||
+    lower(btrim(COALESCE(category, ''))) || '|' ||
+    lower(btrim(COALESCE(affected_component, ''))),
+    'UTF8')), 'hex')
+WHERE fingerprint IS NULL;
+
+ALTER TABLE vulnerabilities ALTER COLUMN fingerprint SET NOT NULL;
+
+CREATE INDEX idx_vulnerabilities_fingerprint ON vulnerabilities(scan_job_id, fingerprint);
@@ -0,0 +1,76 @@
+-- Migration: Add Dashboard Statistics Aggregates
+-- Date: 2026-10-15
+-- Description: Materialized per-organization aggregates for dashboards, refreshed by the gateway
+
+-- ============================================================================
+-- Scans per day
+-- ============================================================================
+
+CREATE MATERIALIZED VIEW org_scan_daily_stats AS
+SELECT organization_id,
+       date_trunc('day', created_at)::date AS day,
+       COUNT(*) AS total,
+       COUNT(*) FILTER (WHERE status = 'completed') AS completed,
+       COUNT(*) FILTER (WHERE status = 'failed') AS failed
+FROM scan_jobs
+WHERE organization_id IS NOT NULL
+GROUP BY organization_id, date_trunc('day', created_at)::date;
+
+CREATE UNIQUE INDEX idx_org_scan_daily_stats ON org_scan_daily_stats(organization_id, day);
+
+-- ============================================================================
+-- Findings by severity and remediation time
+-- ============================================================================
+
+CREATE MATERIALIZED VIEW org_finding_stats AS
+SELECT organization_id,
+       severity,
+       COUNT(*) AS total,
+       COUNT(*) FILTER (WHERE status IN ('open', 'confirmed')) AS open,
+       COUNT(*) FILTER (WHERE status = 'fixed') AS fixed,
+       AVG(EXTRACT(EPOCH FROM (updated_at - discovered_at)) / 3600)
+           FILTER (WHERE status = 'fixed') AS mean_hours_to_remediate
+FROM vulnerabilities
+WHERE organization_id IS NOT NULL
+GROUP BY organization_id, severity;
+
+CREATE UNIQUE INDEX idx_org_finding_stats ON org_finding_stats(organization_id, severity);
+
+-- ============================================================================
+-- Most vulnerable assets (open findings per target)
+-- ============================================================================
+
+CREATE MATERIALIZED VIEW org_asset_stats AS
+SELECT v.organization_id,
+       st.target_type,
+       st.target_value,
+       COUNT(*) AS open_findings,
+       COUNT(*) FILTER (WHERE v.severity = 'critical') AS critical,
+       COUNT(*) FILTER (WHERE v.severity = 'high') AS high,
+       MAX(v.discovered_at) AS last_seen_at
+FROM vulnerabilities v
+INNER JOIN scan_jobs sj ON sj.id = v.scan_job_id
+INNER JOIN scan_targets st ON st.id = sj.target_id
+WHERE v.organization_id IS NOT NULL AND v.status IN ('open', 'confirmed')
+GROUP BY v.organization_id, st.target_type, st.target_value;
+
+CREATE UNIQUE INDEX idx_org_asset_stats ON org_asset_stats(organization_id, target_type, target_value);
+
+-- ============================================================================
+-- Authentication activity per day
+-- ============================================================================
+
+-- Failed attempts carry no user_id, so they are attributed through the attempted email
+CREATE MATERIALIZED VIEW org_auth_daily_stats AS
+SELECT om.organization_id,
+       date_trunc('day', al.timestamp)::date AS day,
+       COUNT(*) FILTER (WHERE al.action = 'login_success') AS logins,
+       COUNT(*) FILTER (WHERE al.action = 'login_attempt' AND al.status = 'failure') AS failed_logins,
+       COUNT(DISTINCT u.id) FILTER (WHERE al.action = 'login_success') AS active_users
+FROM audit_logs al
+INNER JOIN users u ON u.id = al.user_id OR (al.user_id IS NULL AND u.email = al.details->>'email')
+INNER JOIN organization_memberships om ON om.user_id = u.id
+WHERE al.action IN ('login_success', 'login_attempt')
+GROUP BY om.organization_id, date_trunc('day', al.timestamp)::date;
+
+CREATE UNIQUE INDEX idx_org_auth_daily_stats ON org_auth_daily_stats(organization_id, day);
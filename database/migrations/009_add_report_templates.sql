@@ -0,0 +1,29 @@
+-- Migration: Add Report Templates
+-- Date: 2026-10-15
+-- Description: Per-organization report customization passed to the brain service
+
+CREATE TABLE report_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    logo_url TEXT,
+    sections TEXT[] NOT NULL,
+    executive_summary TEXT NOT NULL DEFAULT '',
+    min_severity VARCHAR(20) NOT NULL DEFAULT 'info',
+    severity_thresholds JSONB NOT NULL DEFAULT '{}',
+    is_default BOOLEAN NOT NULL DEFAULT false,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_min_severity CHECK (min_severity IN ('critical', 'high', 'medium', 'low', 'info')),
+    UNIQUE(organization_id, name)
+);
+
+CREATE INDEX idx_report_templates_org ON report_templates(organization_id);
+
+-- At most one default template per organization
+CREATE UNIQUE INDEX idx_report_templates_default ON report_templates(organization_id) WHERE is_default;
+
+CREATE TRIGGER update_report_templates_updated_at BEFORE UPDATE ON report_templates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,11 @@
+-- Migration: Add Report Formats
+-- Date: 2026-10-15
+-- Description: Store generated reports in markdown, HTML, PDF or SARIF form
+
+ALTER TABLE reports ADD COLUMN format VARCHAR(20) NOT NULL DEFAULT 'markdown';
+ALTER TABLE reports ADD COLUMN content_type VARCHAR(100) NOT NULL DEFAULT 'text/markdown';
+ALTER TABLE reports ADD COLUMN file_data BYTEA;  -- binary formats (PDF)
+
+ALTER TABLE reports ADD CONSTRAINT valid_report_format
+    CHECK (format IN ('markdown', 'html', 'pdf', 'sarif'));
+
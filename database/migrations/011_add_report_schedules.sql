@@ -0,0 +1,57 @@
+-- Migration: Add Report Schedules
+-- Date: 2026-10-15
+-- Description: Weekly/monthly report generation with email and webhook delivery
+
+CREATE TABLE report_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+
+    name VARCHAR(100) NOT NULL,
+    frequency VARCHAR(20) NOT NULL,
+    day_of_week INTEGER NOT NULL DEFAULT 1,   -- weekly: 0 = Sunday
+    day_of_month INTEGER NOT NULL DEFAULT 1,  -- monthly
+    hour_utc INTEGER NOT NULL DEFAULT 6,
+
+    -- Scan selection; empty arrays match everything
+    target_values TEXT[] NOT NULL DEFAULT '{}',
+    scan_types TEXT[] NOT NULL DEFAULT '{}',
+
+    report_type VARCHAR(50) NOT NULL DEFAULT 'executive',
+    format VARCHAR(20) NOT NULL DEFAULT 'pdf',
+    template_id UUID REFERENCES report_templates(id) ON DELETE SET NULL,
+
+    -- Delivery
+    email_recipients TEXT[] NOT NULL DEFAULT '{}',
+    webhook_url TEXT,
+    webhook_secret TEXT,
+
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP,
+
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_schedule_frequency CHECK (frequency IN ('weekly', 'monthly')),
+    CONSTRAINT valid_schedule_day_of_week CHECK (day_of_week BETWEEN 0 AND 6),
+    CONSTRAINT valid_schedule_day_of_month CHECK (day_of_month BETWEEN 1 AND 28),
+    CONSTRAINT valid_schedule_hour CHECK (hour_utc BETWEEN 0 AND 23),
+    CONSTRAINT valid_schedule_format CHECK (format IN ('markdown', 'html', 'pdf', 'sarif')),
+    UNIQUE (organization_id, name)
+);
+
+CREATE INDEX idx_report_schedules_due ON report_schedules(next_run_at) WHERE enabled;
+
+CREATE TRIGGER update_report_schedules_updated_at BEFORE UPDATE ON report_schedules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Report history: who or what produced a report, and whether it was delivered
+ALTER TABLE reports ADD COLUMN source VARCHAR(20) NOT NULL DEFAULT 'manual';
+ALTER TABLE reports ADD COLUMN schedule_id UUID REFERENCES report_schedules(id) ON DELETE SET NULL;
+ALTER TABLE reports ADD COLUMN delivered_at TIMESTAMP;
+ALTER TABLE reports ADD COLUMN delivery_error TEXT;
+
+ALTER TABLE reports ADD CONSTRAINT valid_report_source CHECK (source IN ('manual', 'scheduled'));
+
+CREATE INDEX idx_reports_org_source ON reports(organization_id, source, generated_at DESC);
@@ -0,0 +1,26 @@
+-- Migration: Add Admin Impersonation
+-- Date: 2026-10-15
+-- Description: Time-boxed, revocable support impersonation sessions with a consent trail
+
+CREATE TABLE impersonation_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_user_id UUID NOT NULL REFERENCES users(id),
+    target_user_id UUID NOT NULL REFERENCES users(id),
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+
+    reason TEXT NOT NULL,
+    ticket_reference VARCHAR(100),
+
+    ip_address INET,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    ended_by UUID REFERENCES users(id),
+
+    CONSTRAINT no_self_impersonation CHECK (admin_user_id <> target_user_id),
+    CONSTRAINT valid_impersonation_window CHECK (expires_at > started_at)
+);
+
+CREATE INDEX idx_impersonation_active ON impersonation_sessions(expires_at) WHERE ended_at IS NULL;
+CREATE INDEX idx_impersonation_admin ON impersonation_sessions(admin_user_id, started_at DESC);
+CREATE INDEX idx_impersonation_target ON impersonation_sessions(target_user_id, started_at DESC);
@@ -0,0 +1,26 @@
+-- Migration: Add Known Login Devices
+-- Date: 2026-10-15
+-- Description: Remembers the devices and countries each user signs in from so new ones can be flagged
+
+CREATE TABLE user_known_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- SHA-256 of the parsed device, OS and browser (not the raw User-Agent,
+    -- which changes with every browser update)
+    fingerprint VARCHAR(64) NOT NULL,
+    device VARCHAR(50),
+    os VARCHAR(50),
+    browser VARCHAR(50),
+
+    -- Empty when the IP could not be geolocated
+    country VARCHAR(100) NOT NULL DEFAULT '',
+
+    last_ip_address INET,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(user_id, fingerprint, country)
+);
+
+CREATE INDEX idx_known_devices_user ON user_known_devices(user_id, last_seen_at DESC);
@@ -0,0 +1,14 @@
+-- Migration: Add Organization Scoping to Audit Logs
+-- Date: 2026-10-15
+-- Description: Tags audit entries with the organization they belong to so tenants can read their own trail
+
+ALTER TABLE audit_logs ADD COLUMN organization_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_audit_logs_organization ON audit_logs(organization_id, timestamp DESC) WHERE organization_id IS NOT NULL;
+
+-- Attribute existing entries to the acting user's primary organization.
+-- organization_id is not part of the signed payload, so signatures stay valid.
+UPDATE audit_logs al
+SET organization_id = u.organization_id
+FROM users u
+WHERE al.user_id = u.id AND u.organization_id IS NOT NULL;
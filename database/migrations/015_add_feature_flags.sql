@@ -0,0 +1,37 @@
+-- Migration: Add Feature Flags
+-- Date: 2026-10-15
+-- Description: Runtime feature toggles with organization- and user-level overrides
+
+CREATE TABLE feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+
+    -- Default for everyone without an override
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    -- Gradual rollout: when not enabled, this share of users (bucketed by
+    -- user ID) still gets the flag
+    rollout_percentage INTEGER NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    CONSTRAINT valid_flag_key CHECK (key ~ '^[a-z0-9_]+$'),
+    CONSTRAINT valid_rollout CHECK (rollout_percentage BETWEEN 0 AND 100)
+);
+
+-- User overrides win over organization overrides, which win over the default
+CREATE TABLE feature_flag_overrides (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    flag_key VARCHAR(100) NOT NULL REFERENCES feature_flags(key) ON DELETE CASCADE ON UPDATE CASCADE,
+    scope VARCHAR(20) NOT NULL,
+    target_id UUID NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    CONSTRAINT valid_override_scope CHECK (scope IN ('organization', 'user')),
+    UNIQUE(flag_key, scope, target_id)
+);
+
+CREATE INDEX idx_flag_overrides_target ON feature_flag_overrides(scope, target_id);
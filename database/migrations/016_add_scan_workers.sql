@@ -0,0 +1,32 @@
+-- Migration: Add Scan Workers
+-- Date: 2026-10-15
+-- Description: Registry of scanner workers with heartbeats, and job ownership so work held by dead workers can be re-queued
+
+CREATE TABLE scan_workers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    hostname VARCHAR(255),
+    version VARCHAR(50),
+
+    -- Capabilities advertised at registration and refreshed by heartbeats
+    scan_types TEXT[] NOT NULL DEFAULT '{}',
+    capacity INTEGER NOT NULL DEFAULT 1,
+    active_jobs INTEGER NOT NULL DEFAULT 0,
+    metadata JSONB NOT NULL DEFAULT '{}',
+
+    status VARCHAR(20) NOT NULL DEFAULT 'online',
+    ip_address INET,
+    registered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_heartbeat_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    offline_at TIMESTAMP,
+
+    CONSTRAINT valid_worker_status CHECK (status IN ('online', 'draining', 'offline')),
+    CONSTRAINT valid_worker_capacity CHECK (capacity >= 0 AND active_jobs >= 0)
+);
+
+CREATE INDEX idx_scan_workers_heartbeat ON scan_workers(status, last_heartbeat_at);
+
+ALTER TABLE scan_jobs ADD COLUMN worker_id UUID REFERENCES scan_workers(id) ON DELETE SET NULL;
+ALTER TABLE scan_jobs ADD COLUMN requeue_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_scan_jobs_worker ON scan_jobs(worker_id) WHERE status = 'running';
@@ -0,0 +1,31 @@
+-- Migration: Add Data Exports
+-- Date: 2026-10-15
+-- Description: Organization and personal data export bundles, built in the background and downloaded through expiring signed URLs
+
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scope VARCHAR(20) NOT NULL,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,  -- subject of a personal export
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    storage_key VARCHAR(500),
+    size_bytes BIGINT,
+    error_message TEXT,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+
+    CONSTRAINT valid_export_scope CHECK (scope IN ('organization', 'user')),
+    CONSTRAINT valid_export_status CHECK (status IN ('pending', 'running', 'completed', 'failed', 'expired')),
+    CONSTRAINT export_subject CHECK (
+        (scope = 'organization' AND organization_id IS NOT NULL) OR
+        (scope = 'user' AND user_id IS NOT NULL)
+    )
+);
+
+CREATE INDEX idx_data_exports_org ON data_exports(organization_id, created_at DESC) WHERE organization_id IS NOT NULL;
+CREATE INDEX idx_data_exports_user ON data_exports(user_id, created_at DESC) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_data_exports_expiry ON data_exports(expires_at) WHERE status = 'completed';
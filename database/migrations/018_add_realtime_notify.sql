@@ -0,0 +1,55 @@
+-- Migration: Add Realtime Notifications
+-- Date: 2026-10-15
+-- Description: NOTIFY on scan progress and new findings so the gateway can push changes made by any service to WebSocket clients
+
+-- Payloads stay well under the 8000 byte NOTIFY limit: long text is truncated
+-- and clients load full records over the API.
+
+CREATE OR REPLACE FUNCTION notify_scan_job_change() RETURNS trigger AS $$
+DECLARE
+    finding_count INTEGER;
+BEGIN
+    IF NEW.status IN ('completed', 'failed', 'stopped') THEN
+        SELECT COUNT(*) INTO finding_count FROM vulnerabilities WHERE scan_job_id = NEW.id;
+    END IF;
+
+    PERFORM pg_notify('scan_events', json_build_object(
+        'scan_id', NEW.id,
+        'user_id', NEW.user_id,
+        'status', NEW.status,
+        'progress', NEW.progress_percentage,
+        'current_phase', NEW.current_phase,
+        'error_message', left(NEW.error_message, 500),
+        'vulnerability_count', finding_count
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER scan_jobs_notify
+    AFTER UPDATE ON scan_jobs
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status
+       OR OLD.progress_percentage IS DISTINCT FROM NEW.progress_percentage
+       OR OLD.current_phase IS DISTINCT FROM NEW.current_phase)
+    EXECUTE FUNCTION notify_scan_job_change();
+
+CREATE OR REPLACE FUNCTION notify_vulnerability_insert() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('finding_events', json_build_object(
+        'vulnerability_id', NEW.id,
+        'scan_id', NEW.scan_job_id,
+        'user_id', (SELECT user_id FROM scan_jobs WHERE id = NEW.scan_job_id),
+        'title', left(NEW.title, 500),
+        'severity', NEW.severity,
+        'cvss_score', NEW.cvss_score,
+        'affected_component', left(NEW.affected_component, 500)
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER vulnerabilities_notify
+    AFTER INSERT ON vulnerabilities
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_vulnerability_insert();
@@ -0,0 +1,20 @@
+-- Migration: Add Event Outbox
+-- Date: 2026-10-15
+-- Description: Transactional outbox of domain events, relayed to NATS JetStream or Kafka after the writing transaction commits
+
+CREATE TABLE event_outbox (
+    id UUID PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    event_version INTEGER NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    organization_id UUID,  -- no FK: events outlive the rows they describe
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    published_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX idx_event_outbox_pending ON event_outbox(created_at) WHERE published_at IS NULL;
+CREATE INDEX idx_event_outbox_published ON event_outbox(published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Migration: Add Scan Control
+-- Date: 2026-10-15
+-- Description: Paused scan state so users can pause, resume and stop scans; workers learn about changes through the ScanJobControl RPC
+
+ALTER TABLE scan_jobs DROP CONSTRAINT valid_status;
+ALTER TABLE scan_jobs ADD CONSTRAINT valid_status
+    CHECK (status IN ('pending', 'running', 'paused', 'completed', 'failed', 'stopped'));
+
+-- Set while paused. A paused job keeps its worker_id when it was running so
+-- it can resume on the same worker.
+ALTER TABLE scan_jobs ADD COLUMN paused_at TIMESTAMP;
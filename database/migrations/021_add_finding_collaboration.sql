@@ -0,0 +1,49 @@
+-- Migration: Add Finding Collaboration
+-- Date: 2026-10-15
+-- Description: Threaded comments, assignment with due dates, status history and watchers for triaging findings
+
+ALTER TABLE vulnerabilities ADD COLUMN assignee_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE vulnerabilities ADD COLUMN assigned_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE vulnerabilities ADD COLUMN assigned_at TIMESTAMP;
+ALTER TABLE vulnerabilities ADD COLUMN due_date DATE;
+
+CREATE INDEX idx_vulnerabilities_assignee ON vulnerabilities(assignee_id, due_date) WHERE assignee_id IS NOT NULL;
+
+CREATE TABLE finding_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vulnerability_id UUID NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- Replies point at the top-level comment of their thread
+    parent_id UUID REFERENCES finding_comments(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_comment_body CHECK (length(body) BETWEEN 1 AND 10000)
+);
+
+CREATE INDEX idx_finding_comments_vulnerability ON finding_comments(vulnerability_id, created_at);
+
+CREATE TABLE finding_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vulnerability_id UUID NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_finding_status_history_vulnerability ON finding_status_history(vulnerability_id, changed_at);
+
+-- Users notified over WebSocket about a finding's activity. Commenters and
+-- assignees are added automatically.
+CREATE TABLE finding_watchers (
+    vulnerability_id UUID NOT NULL REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (vulnerability_id, user_id)
+);
+
+CREATE INDEX idx_finding_watchers_user ON finding_watchers(user_id);
@@ -0,0 +1,25 @@
+-- Migration: Add Slack Integration
+-- Date: 2026-10-15
+-- Description: Slack workspaces linked to organizations and Slack users linked to platform users, for slash commands and alert actions
+
+CREATE TABLE slack_workspaces (
+    team_id VARCHAR(32) PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    team_name VARCHAR(255),
+    installed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_slack_workspaces_org ON slack_workspaces(organization_id);
+
+CREATE TABLE slack_user_links (
+    team_id VARCHAR(32) NOT NULL REFERENCES slack_workspaces(team_id) ON DELETE CASCADE,
+    slack_user_id VARCHAR(32) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    linked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (team_id, slack_user_id),
+    UNIQUE (team_id, user_id)
+);
+
+CREATE INDEX idx_slack_user_links_user ON slack_user_links(user_id);
@@ -0,0 +1,6 @@
+-- Migration: Add Report Storage
+-- Date: 2026-10-15
+-- Description: Binary report formats (PDF) are kept in artifact storage (local disk, S3 or GCS) instead of the database
+
+-- file_data still serves reports generated before this migration
+ALTER TABLE reports ADD COLUMN storage_key VARCHAR(500);
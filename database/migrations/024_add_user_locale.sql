@@ -0,0 +1,5 @@
+-- Migration: Add User Locale
+-- Date: 2026-10-15
+-- Description: Preferred language for API error and validation messages; NULL follows the request's Accept-Language
+
+ALTER TABLE users ADD COLUMN locale VARCHAR(16);
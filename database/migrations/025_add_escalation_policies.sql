@@ -0,0 +1,65 @@
+-- Migration: Add Escalation Policies
+-- Date: 2026-10-15
+-- Description: Per-organization escalation policies paging ordered contact levels (email, SMS, webhook) for critical findings and emergency stops until acknowledged, with history per escalation
+
+CREATE TABLE escalation_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    triggers TEXT[] NOT NULL,
+    -- [{"contacts": [{"name", "channel", "target"}], "escalate_after_minutes": 15}, ...]
+    levels JSONB NOT NULL,
+    webhook_secret TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (organization_id, name),
+    CONSTRAINT valid_escalation_triggers CHECK (triggers <@ ARRAY['critical_finding', 'emergency_stop'] AND cardinality(triggers) > 0)
+);
+
+CREATE TABLE escalations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    policy_id UUID REFERENCES escalation_policies(id) ON DELETE SET NULL,
+    trigger VARCHAR(30) NOT NULL,
+    subject_id VARCHAR(100) NOT NULL,
+    summary TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    -- Copy of the policy's levels when the escalation started
+    levels JSONB NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    level INTEGER NOT NULL DEFAULT 0,   -- Levels paged so far
+    next_notify_at TIMESTAMP,
+
+    acknowledged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ack_note TEXT,
+    acknowledged_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (policy_id, trigger, subject_id),
+    CONSTRAINT valid_escalation_status CHECK (status IN ('open', 'acknowledged', 'exhausted'))
+);
+
+CREATE INDEX idx_escalations_org ON escalations(organization_id, created_at DESC);
+CREATE INDEX idx_escalations_due ON escalations(next_notify_at) WHERE status = 'open';
+
+CREATE TABLE escalation_history (
+    id BIGSERIAL PRIMARY KEY,
+    escalation_id UUID NOT NULL REFERENCES escalations(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL,   -- triggered, notified, notify_failed, acknowledged, exhausted
+    level INTEGER,
+    channel VARCHAR(20),
+    target TEXT,
+    error TEXT,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_escalation_history_escalation ON escalation_history(escalation_id, id);
+
+-- New critical findings are polled by discovery time
+CREATE INDEX idx_vulnerabilities_critical_discovered ON vulnerabilities(discovered_at) WHERE severity = 'critical';
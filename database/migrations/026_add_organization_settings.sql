@@ -0,0 +1,17 @@
+-- Migration: Add Organization Settings
+-- Date: 2026-10-15
+-- Description: Per-organization branding (logo, colors, report footer, email sender name) used in reports and outgoing email
+
+CREATE TABLE organization_settings (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    -- Artifact storage key of the uploaded logo
+    logo_key TEXT,
+    logo_content_type VARCHAR(50),
+    primary_color VARCHAR(7),
+    secondary_color VARCHAR(7),
+    accent_color VARCHAR(7),
+    report_footer TEXT,
+    email_sender_name VARCHAR(100),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,12 @@
+-- Migration: Add Organization Slugs
+-- Date: 2026-10-15
+-- Description: Unique URL-safe slug per organization; existing organizations get one derived from their name
+
+ALTER TABLE organizations ADD COLUMN slug VARCHAR(63);
+
+UPDATE organizations
+SET slug = trim(both '-' from lower(regexp_replace(name, '[^A-Za-z0-9]+', '-', 'g'))) || '-' || left(id::text, 8)
+WHERE slug IS NULL;
+
+ALTER TABLE organizations ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX idx_organizations_slug ON organizations(slug);
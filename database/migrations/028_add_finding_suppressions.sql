@@ -0,0 +1,33 @@
+-- Migration: Add Finding Suppressions
+-- Date: 2026-10-15
+-- Description: Per-organization rules that suppress known false positives at ingestion, matched on plugin ID, CVE or affected path (see internal/findings)
+
+ALTER TABLE vulnerabilities ADD COLUMN plugin_id VARCHAR(100);
+ALTER TABLE vulnerabilities ADD COLUMN cve_id VARCHAR(20);
+
+CREATE TABLE finding_suppression_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- Scan target value the rule is limited to; NULL applies to every asset
+    asset TEXT,
+    plugin_id VARCHAR(100),
+    cve_id VARCHAR(20),
+    -- Glob over the affected component: * matches any run of characters, ? one
+    path_pattern VARCHAR(500),
+    justification TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT suppression_has_criteria CHECK (plugin_id IS NOT NULL OR cve_id IS NOT NULL OR path_pattern IS NOT NULL),
+    CONSTRAINT valid_suppression_justification CHECK (length(justification) BETWEEN 1 AND 2000)
+);
+
+CREATE INDEX idx_finding_suppression_rules_org ON finding_suppression_rules(organization_id, expires_at);
+
+-- Findings stay suppressed when the rule that matched them expires or is
+-- deleted; only new ingestions stop being suppressed
+ALTER TABLE vulnerabilities ADD COLUMN suppression_rule_id UUID REFERENCES finding_suppression_rules(id) ON DELETE SET NULL;
+ALTER TABLE vulnerabilities ADD COLUMN suppressed_at TIMESTAMP;
+
+CREATE INDEX idx_vulnerabilities_suppressed ON vulnerabilities(scan_job_id) WHERE suppressed_at IS NOT NULL;
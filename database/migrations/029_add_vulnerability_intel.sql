@@ -0,0 +1,48 @@
+-- Migration: Add Vulnerability Intelligence
+-- Date: 2026-10-15
+-- Description: Local copy of CVE metadata synced from NVD/OSV with EPSS scores and CISA KEV flags, used to enrich findings (see internal/intel)
+
+CREATE TABLE cve_intel (
+    cve_id VARCHAR(20) PRIMARY KEY,
+    -- nvd or osv; unknown when neither source has the CVE yet
+    source VARCHAR(10) NOT NULL,
+    description TEXT,
+    cvss_score DECIMAL(3, 1),
+    cvss_vector VARCHAR(200),
+    cvss_version VARCHAR(5),
+    cwe_ids TEXT[] NOT NULL DEFAULT '{}',
+    epss_score DECIMAL(6, 5),
+    epss_percentile DECIMAL(6, 5),
+    epss_updated_at TIMESTAMP,
+    published_at TIMESTAMP,
+    modified_at TIMESTAMP,
+    synced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_intel_source CHECK (source IN ('nvd', 'osv', 'unknown'))
+);
+
+CREATE INDEX idx_cve_intel_epss ON cve_intel(epss_score DESC NULLS LAST);
+
+-- CISA's Known Exploited Vulnerabilities catalog, replaced on each sync. Kept
+-- apart from cve_intel so it also flags CVEs fetched after the sync.
+CREATE TABLE known_exploited_cves (
+    cve_id VARCHAR(20) PRIMARY KEY,
+    added_at DATE
+);
+
+-- One row per upstream feed; a gateway instance claims a feed by bumping
+-- last_run_at, so only one instance syncs it per interval
+CREATE TABLE intel_sync_state (
+    source VARCHAR(10) PRIMARY KEY,
+    -- NVD lastModified watermark reached by the incremental sync
+    synced_through TIMESTAMP,
+    last_run_at TIMESTAMP,
+    last_error TEXT
+);
+
+INSERT INTO intel_sync_state (source) VALUES ('nvd'), ('kev'), ('epss');
+
+ALTER TABLE vulnerabilities ADD COLUMN cwe_ids TEXT[];
+ALTER TABLE vulnerabilities ADD COLUMN enriched_at TIMESTAMP;
+
+CREATE INDEX idx_vulnerabilities_cve ON vulnerabilities(cve_id) WHERE cve_id IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Migration: Add Status Page Uptime
+-- Date: 2026-10-15
+-- Description: Daily per-component health sample counts behind the public status page's uptime windows
+
+CREATE TABLE status_uptime_daily (
+    component VARCHAR(50) NOT NULL, -- A health check name, or 'system' for the overall status
+    day DATE NOT NULL,
+    operational_samples INTEGER NOT NULL DEFAULT 0,
+    degraded_samples INTEGER NOT NULL DEFAULT 0,
+    down_samples INTEGER NOT NULL DEFAULT 0,
+    maintenance_samples INTEGER NOT NULL DEFAULT 0, -- Not counted against uptime
+    PRIMARY KEY (component, day)
+);
+
+CREATE INDEX idx_status_uptime_daily_day ON status_uptime_daily(day);
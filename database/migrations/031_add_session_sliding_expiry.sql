@@ -0,0 +1,13 @@
+-- Migration: Add Session Sliding Expiry
+-- Date: 2026-10-15
+-- Description: Sessions slide their expires_at forward while in use, up to an absolute limit fixed at login; idle sessions are revoked by the gateway
+
+ALTER TABLE sessions ADD COLUMN absolute_expires_at TIMESTAMP;
+
+-- Sessions from before sliding expiry cannot be extended
+UPDATE sessions SET absolute_expires_at = expires_at;
+
+ALTER TABLE sessions ALTER COLUMN absolute_expires_at SET NOT NULL;
+
+-- Idle session sweep
+CREATE INDEX idx_sessions_last_activity ON sessions(last_activity_at) WHERE revoked_at IS NULL;
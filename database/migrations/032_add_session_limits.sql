@@ -0,0 +1,15 @@
+-- Migration: Add Session Limits
+-- Date: 2026-10-15
+-- Description: Per-organization, per-role limits on concurrent sessions, overriding the gateway's defaults
+
+CREATE TABLE session_limit_policies (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- An organization role, or '*' for roles without a policy of their own
+    role VARCHAR(50) NOT NULL,
+    -- 0 lifts the limit
+    max_sessions INTEGER NOT NULL CHECK (max_sessions >= 0),
+    on_limit VARCHAR(20) NOT NULL CHECK (on_limit IN ('reject', 'revoke_oldest')),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, role)
+);
@@ -0,0 +1,14 @@
+-- Migration: Add Scan Quotas
+-- Date: 2026-10-15
+-- Description: Per-organization overrides of the scan quotas that come with the subscription tier, checked when scans are created and by the preflight endpoint
+
+-- NULL keeps the tier's quota; 0 is unlimited
+ALTER TABLE organizations ADD COLUMN max_concurrent_scans INTEGER;
+ALTER TABLE organizations ADD COLUMN max_monthly_scans INTEGER;
+
+ALTER TABLE organizations ADD CONSTRAINT valid_max_concurrent_scans CHECK (max_concurrent_scans >= 0);
+ALTER TABLE organizations ADD CONSTRAINT valid_max_monthly_scans CHECK (max_monthly_scans >= 0);
+
+-- Overlap and quota checks look up an organization's unfinished scans
+CREATE INDEX idx_scan_jobs_org_active ON scan_jobs(organization_id)
+    WHERE status IN ('pending', 'running', 'paused');
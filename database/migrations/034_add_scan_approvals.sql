@@ -0,0 +1,42 @@
+-- Migration: Add Scan Approvals
+-- Date: 2026-10-15
+-- Description: Approval workflow for high-impact scan types: such scans wait in pending_approval until enough members with approve:scan sign off, and are rejected by any one of them
+
+ALTER TABLE scan_jobs DROP CONSTRAINT valid_status;
+ALTER TABLE scan_jobs ADD CONSTRAINT valid_status
+    CHECK (status IN ('pending_approval', 'pending', 'running', 'paused', 'completed', 'failed', 'stopped', 'rejected'));
+
+-- Approvals the scan needed when it was created, and why the requester wants it
+ALTER TABLE scan_jobs ADD COLUMN required_approvals INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scan_jobs ADD COLUMN justification TEXT;
+
+CREATE INDEX idx_scan_jobs_pending_approval ON scan_jobs(organization_id, created_at)
+    WHERE status = 'pending_approval';
+
+-- Scan types needing approval in an organization, overriding the built-in
+-- defaults; 0 approvals turns approval off for the type
+CREATE TABLE scan_approval_rules (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    scan_type VARCHAR(50) NOT NULL,
+    required_approvals INTEGER NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (organization_id, scan_type),
+    CONSTRAINT valid_required_approvals CHECK (required_approvals BETWEEN 0 AND 5)
+);
+
+-- One decision per approver and scan
+CREATE TABLE scan_approvals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scan_job_id UUID NOT NULL REFERENCES scan_jobs(id) ON DELETE CASCADE,
+    approver_id UUID NOT NULL REFERENCES users(id),
+    decision VARCHAR(10) NOT NULL,
+    justification TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (scan_job_id, approver_id),
+    CONSTRAINT valid_decision CHECK (decision IN ('approve', 'reject'))
+);
+
+CREATE INDEX idx_scan_approvals_scan ON scan_approvals(scan_job_id, created_at);
@@ -0,0 +1,29 @@
+-- Migration: Add Scan Windows
+-- Date: 2026-10-15
+-- Description: Allowed execution windows per organization or authorized target; scans outside them are held until the next window opens and, where configured, paused when a window ends
+
+CREATE TABLE scan_windows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- NULL applies to every target without windows of its own
+    authorization_target_id UUID REFERENCES authorized_targets(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- Five-field cron spec of when the window opens, in timezone
+    cron_spec VARCHAR(100) NOT NULL,
+    duration_minutes INTEGER NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    pause_at_end BOOLEAN NOT NULL DEFAULT false,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_window_duration CHECK (duration_minutes BETWEEN 1 AND 10080)
+);
+
+CREATE INDEX idx_scan_windows_org ON scan_windows(organization_id);
+
+-- The dispatcher only hands out jobs between not_before and not_after; the
+-- enforcer keeps both current
+ALTER TABLE scan_jobs ADD COLUMN not_before TIMESTAMP;
+ALTER TABLE scan_jobs ADD COLUMN not_after TIMESTAMP;
+-- Paused at a window's end, to be resumed when the next one opens
+ALTER TABLE scan_jobs ADD COLUMN paused_by_window BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,25 @@
+-- Migration: Add Organization Data Keys
+-- Date: 2026-10-15
+-- Description: Per-organization data keys for encrypting sensitive columns (authorization proofs, raw scan evidence), stored wrapped by the master key
+
+CREATE TABLE organization_data_keys (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- Values are sealed with the newest version; older versions are kept to
+    -- read what was sealed before
+    version INTEGER NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    -- Master key the data key is wrapped by; the rotation job re-wraps keys
+    -- whose master key is no longer current
+    master_key_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rewrapped_at TIMESTAMP,
+
+    PRIMARY KEY (organization_id, version)
+);
+
+CREATE INDEX idx_organization_data_keys_master ON organization_data_keys(master_key_id);
+
+-- Sealed values ("enc:v1:<version>:<base64>") are written to
+-- authorized_targets.authorization_document_url as text and to
+-- scan_results.raw_data as a JSON string; rows written earlier stay readable
+-- as plaintext
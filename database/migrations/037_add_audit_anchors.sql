@@ -0,0 +1,28 @@
+-- Migration: Add Audit Anchors
+-- Date: 2026-10-15
+-- Description: Receipts from an external notary (RFC 3161 timestamp authority or transparency-log witness) attesting to the audit log hash chain head, so a wholesale rewrite of the log is detectable
+
+CREATE TABLE audit_anchors (
+    id BIGSERIAL PRIMARY KEY,
+    -- The chain continues from prev_hash (the previous anchor's head) over
+    -- audit_logs first_log_id..last_log_id in ID order
+    first_log_id BIGINT NOT NULL,
+    last_log_id BIGINT NOT NULL,
+    entry_count INTEGER NOT NULL,
+    range_start TIMESTAMP NOT NULL,
+    range_end TIMESTAMP NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    chain_hash VARCHAR(64) NOT NULL,
+    notary VARCHAR(20) NOT NULL,
+    receipt BYTEA NOT NULL,
+    -- Time attested by the notary
+    notarized_at TIMESTAMP NOT NULL,
+    anchored_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_anchor_range CHECK (last_log_id >= first_log_id)
+);
+
+-- One anchor per starting entry, so concurrent gateways cannot fork the chain
+CREATE UNIQUE INDEX idx_audit_anchors_first_log ON audit_anchors(first_log_id);
+CREATE INDEX idx_audit_anchors_last_log ON audit_anchors(last_log_id);
+CREATE INDEX idx_audit_anchors_range ON audit_anchors(range_start, range_end);
@@ -0,0 +1,23 @@
+-- Migration: Add Organization API Tokens
+-- Date: 2026-10-15
+-- Description: Service tokens of an organization, scoped to permissions and optionally to specific authorizations, with expiry, IP allowlists and last-used tracking
+
+CREATE TABLE org_api_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- SHA-256 of the token; the token itself is only shown at creation
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(20) NOT NULL,
+    -- [{"permission": "create:scan", "assets": ["<authorized_targets.id>"]}]
+    scopes JSONB NOT NULL,
+    allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(45),
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_org_api_tokens_org ON org_api_tokens(organization_id, created_at DESC);
@@ -0,0 +1,6 @@
+-- Migration: Add Manual Finding Suppression
+-- Date: 2026-10-15
+-- Description: Findings suppressed by hand (e.g. through the bulk triage API) rather than by a suppression rule keep their own justification and who suppressed them
+
+ALTER TABLE vulnerabilities ADD COLUMN suppression_justification TEXT;
+ALTER TABLE vulnerabilities ADD COLUMN suppressed_by UUID REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1,35 @@
+-- Migration: Add Billing Events
+-- Date: 2026-10-15
+-- Description: Ledger of billable usage (scans started, reports generated, daily storage), exported to Stripe for organizations with a Stripe customer
+
+-- IDs are derived from what the event counts (scan_started:<scan id>,
+-- storage_used:<organization id>:<day>), so recording twice is a no-op
+CREATE TABLE billing_events (
+    id VARCHAR(200) PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    quantity BIGINT NOT NULL,
+    subject VARCHAR(100),  -- scan or report counted
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    exported_at TIMESTAMP,
+    export_attempts INTEGER NOT NULL DEFAULT 0,
+    last_export_error TEXT,
+
+    CONSTRAINT valid_billing_event_type CHECK (event_type IN ('scan_started', 'report_generated', 'storage_used')),
+    CONSTRAINT valid_billing_quantity CHECK (quantity >= 0)
+);
+
+CREATE INDEX idx_billing_events_org ON billing_events(organization_id, occurred_at);
+CREATE INDEX idx_billing_events_unexported ON billing_events(occurred_at) WHERE exported_at IS NULL;
+
+-- Stripe customer billed for an organization's metered usage; organizations
+-- without one are metered but not exported
+CREATE TABLE billing_customers (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    stripe_customer_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Size of the stored report (file or content), for storage metering
+ALTER TABLE reports ADD COLUMN size_bytes BIGINT;
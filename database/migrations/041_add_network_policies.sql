@@ -0,0 +1,29 @@
+-- Migration: Add Organization Network Policies
+-- Date: 2026-10-15
+-- Description: Per-organization IP/CIDR allowlists enforced at login and on every authenticated request, with break-glass overrides for owners
+
+CREATE TABLE org_network_policies (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    allowed_cidrs TEXT[] NOT NULL DEFAULT '{}',
+    updated_by UUID REFERENCES users(id),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT enabled_policy_has_cidrs CHECK (NOT enabled OR cardinality(allowed_cidrs) > 0)
+);
+
+-- An owner locked out by the policy signs in with a reason; the session may
+-- then reach the organization from that address until the override expires
+CREATE TABLE network_policy_overrides (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_network_policy_overrides_session ON network_policy_overrides(session_id, organization_id);
+CREATE INDEX idx_network_policy_overrides_org ON network_policy_overrides(organization_id, created_at DESC);
@@ -0,0 +1,34 @@
+-- Migration: Add Uploaded Artifacts
+-- Date: 2026-10-15
+-- Description: Metadata and content scan verdicts of user uploads (organization logos, finding evidence), with quarantine of infected files
+
+CREATE TABLE uploaded_artifacts (
+    id UUID PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    finding_id UUID REFERENCES vulnerabilities(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    -- Sniffed from the content, not taken from the client
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    -- Infected uploads are stored under quarantine/ and never served
+    storage_key TEXT NOT NULL UNIQUE,
+    scan_verdict VARCHAR(20) NOT NULL,
+    scan_signature TEXT,
+    scanner VARCHAR(50),
+    scanned_at TIMESTAMP,
+    quarantined BOOLEAN NOT NULL DEFAULT false,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_upload_kind CHECK (kind IN ('logo', 'evidence')),
+    CONSTRAINT valid_scan_verdict CHECK (scan_verdict IN ('clean', 'infected', 'unscanned')),
+    CONSTRAINT evidence_has_finding CHECK (kind <> 'evidence' OR finding_id IS NOT NULL)
+);
+
+CREATE INDEX idx_uploaded_artifacts_org ON uploaded_artifacts(organization_id, created_at DESC);
+CREATE INDEX idx_uploaded_artifacts_finding ON uploaded_artifacts(finding_id) WHERE finding_id IS NOT NULL;
+CREATE INDEX idx_uploaded_artifacts_quarantined ON uploaded_artifacts(created_at DESC) WHERE quarantined;
+
+ALTER TABLE organization_settings ADD COLUMN logo_artifact_id UUID REFERENCES uploaded_artifacts(id) ON DELETE SET NULL;
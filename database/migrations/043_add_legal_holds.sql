@@ -0,0 +1,80 @@
+-- Migration: Add Legal Holds
+-- Date: 2026-10-15
+-- Description: Legal holds placed by platform admins on an organization or user, freezing their data against deletion and audit retention purges until released
+
+-- Holds are never deleted: released ones stay as the hold history
+CREATE TABLE legal_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    scope VARCHAR(20) NOT NULL,
+    target_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    case_reference VARCHAR(200),
+    placed_by UUID NOT NULL REFERENCES users(id),
+    placed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    released_by UUID REFERENCES users(id),
+    released_at TIMESTAMP,
+    release_reason TEXT,
+
+    CONSTRAINT valid_legal_hold_scope CHECK (scope IN ('organization', 'user')),
+    CONSTRAINT legal_hold_release_complete CHECK ((released_at IS NULL) = (released_by IS NULL))
+);
+
+-- One active hold per subject
+CREATE UNIQUE INDEX idx_legal_holds_active ON legal_holds(scope, target_id) WHERE released_at IS NULL;
+CREATE INDEX idx_legal_holds_placed ON legal_holds(placed_at DESC);
+
+CREATE OR REPLACE FUNCTION under_legal_hold(org_id UUID, usr_id UUID)
+RETURNS BOOLEAN AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM legal_holds
+        WHERE released_at IS NULL
+        AND ((scope = 'organization' AND target_id = org_id) OR (scope = 'user' AND target_id = usr_id))
+    );
+$$ LANGUAGE sql STABLE;
+
+-- Deleting held rows fails with SQLSTATE LH001, whichever job or statement
+-- tries it, so retention purges and deletion jobs must skip held subjects
+CREATE OR REPLACE FUNCTION block_held_audit_deletion()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF under_legal_hold(OLD.organization_id, OLD.user_id) THEN
+        RAISE EXCEPTION 'audit log % is under legal hold', OLD.id USING ERRCODE = 'LH001';
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER enforce_legal_hold_audit_logs
+    BEFORE DELETE ON audit_logs
+    FOR EACH ROW
+    EXECUTE FUNCTION block_held_audit_deletion();
+
+CREATE OR REPLACE FUNCTION block_held_organization_deletion()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF under_legal_hold(OLD.id, NULL) THEN
+        RAISE EXCEPTION 'organization % is under legal hold', OLD.id USING ERRCODE = 'LH001';
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER enforce_legal_hold_organizations
+    BEFORE DELETE ON organizations
+    FOR EACH ROW
+    EXECUTE FUNCTION block_held_organization_deletion();
+
+CREATE OR REPLACE FUNCTION block_held_user_deletion()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF under_legal_hold(NULL, OLD.id) THEN
+        RAISE EXCEPTION 'user % is under legal hold', OLD.id USING ERRCODE = 'LH001';
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER enforce_legal_hold_users
+    BEFORE DELETE ON users
+    FOR EACH ROW
+    EXECUTE FUNCTION block_held_user_deletion();
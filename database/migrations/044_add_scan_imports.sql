@@ -0,0 +1,30 @@
+-- Migration: Add Scan Imports
+-- Date: 2026-10-15
+-- Description: Results of customers' own scanners (Nmap XML, Nuclei JSONL, Burp XML) imported as completed scans, one per host, linked to matching assets
+
+CREATE TABLE scan_imports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    format VARCHAR(20) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    imported_by UUID NOT NULL REFERENCES users(id),
+    hosts INTEGER NOT NULL DEFAULT 0,
+    linked_hosts INTEGER NOT NULL DEFAULT 0,
+    findings_stored INTEGER NOT NULL DEFAULT 0,
+    findings_duplicate INTEGER NOT NULL DEFAULT 0,
+    findings_suppressed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_import_format CHECK (format IN ('nmap', 'nuclei', 'burp'))
+);
+
+-- The same file is imported once per organization
+CREATE UNIQUE INDEX idx_scan_imports_file ON scan_imports(organization_id, sha256);
+CREATE INDEX idx_scan_imports_org ON scan_imports(organization_id, created_at DESC);
+
+-- Imported scans were never run by the gateway, so they carry no scan
+-- authorization (requires_authorization = false); authorization_target_id
+-- links them to the matching asset, if any
+ALTER TABLE scan_jobs ADD COLUMN import_id UUID REFERENCES scan_imports(id) ON DELETE SET NULL;
+CREATE INDEX idx_scan_jobs_import ON scan_jobs(import_id) WHERE import_id IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Migration: Add Session Region
+-- Date: 2026-10-15
+-- Description: Tag sessions with the region of the gateway that issued them, for multi-region deployments sharing one database
+
+ALTER TABLE sessions ADD COLUMN region VARCHAR(64);
+
+CREATE INDEX idx_sessions_region ON sessions(region) WHERE revoked_at IS NULL;
@@ -0,0 +1,17 @@
+-- Migration: Add User Preferences
+-- Date: 2026-10-15
+-- Description: Per-user settings (timezone, locale, default organization, notification digest, dashboard layout) as typed keys validated by the gateway; locales move here from users.locale
+
+CREATE TABLE user_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(64) NOT NULL,
+    value JSONB NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, key)
+);
+
+INSERT INTO user_preferences (user_id, key, value)
+SELECT id, 'locale', to_jsonb(locale) FROM users WHERE locale IS NOT NULL;
+
+-- users.locale is no longer read; kept so the previous release can still roll back
+COMMENT ON COLUMN users.locale IS 'Deprecated: see user_preferences key ''locale''';
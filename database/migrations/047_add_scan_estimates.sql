@@ -0,0 +1,13 @@
+-- Migration: Add Scan Estimates
+-- Date: 2026-10-15
+-- Description: Impact estimates for scans: the traffic workers report when a scan completes, which later estimates learn from, and the estimate a scan was created with, shown to approvers
+
+-- Requests sent and bytes transferred by the scan, as reported by its worker
+ALTER TABLE scan_jobs ADD COLUMN requests_sent BIGINT;
+ALTER TABLE scan_jobs ADD COLUMN bytes_sent BIGINT;
+
+-- Predicted duration, requests and bandwidth when the scan was created
+ALTER TABLE scan_jobs ADD COLUMN estimate JSONB;
+
+CREATE INDEX idx_scan_jobs_estimate_history ON scan_jobs(organization_id, scan_type, scan_mode, completed_at DESC)
+    WHERE status = 'completed';
@@ -0,0 +1,42 @@
+-- Migration: Add Scan Grants
+-- Date: 2026-10-15
+-- Description: Delegated scan authorization: an MSSP organization drafts a grant (targets, time window, scan types) for one of its end clients, who approves or rejects it through a signed link without an account; approved grants authorize scans until they expire or are revoked
+
+CREATE TABLE scan_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    client_name VARCHAR(200) NOT NULL,
+    client_email VARCHAR(255) NOT NULL,
+    -- [{"target_type": "cidr", "target_value": "203.0.113.0/24"}, ...]
+    targets JSONB NOT NULL,
+    scan_types TEXT[] NOT NULL,
+    valid_from TIMESTAMP NOT NULL,
+    valid_until TIMESTAMP NOT NULL,
+    notes TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- The client's decision, as they signed it
+    decided_by_name VARCHAR(200),
+    decided_by_title VARCHAR(200),
+    decided_at TIMESTAMP,
+    decided_from_ip VARCHAR(45),
+    -- HMAC over the approved scope; a grant whose scope no longer matches
+    -- its signature authorizes nothing
+    signature VARCHAR(128),
+
+    revoked_at TIMESTAMP,
+    revoked_by VARCHAR(100), -- a user ID, or 'client' for the client's own link
+    revoke_reason TEXT,
+
+    CONSTRAINT valid_scan_grant_status CHECK (status IN ('pending', 'approved', 'rejected', 'revoked')),
+    CONSTRAINT valid_scan_grant_window CHECK (valid_until > valid_from),
+    CONSTRAINT scan_grant_approval_signed CHECK (status <> 'approved' OR signature IS NOT NULL)
+);
+
+CREATE INDEX idx_scan_grants_org ON scan_grants(organization_id, created_at DESC);
+
+-- Scans run under a grant instead of an authorized target
+ALTER TABLE scan_jobs ADD COLUMN grant_id UUID REFERENCES scan_grants(id) ON DELETE SET NULL;
+CREATE INDEX idx_scan_jobs_grant ON scan_jobs(grant_id) WHERE grant_id IS NOT NULL;
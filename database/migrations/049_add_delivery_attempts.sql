@@ -0,0 +1,30 @@
+-- Migration: Add Delivery Attempts
+-- Date: 2026-10-15
+-- Description: Log of every outbound webhook and Slack delivery per organization (scheduled reports, escalation pages, security alerts and test events), so integration failures can be inspected and failed deliveries replayed
+
+CREATE TABLE delivery_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    source_id VARCHAR(255),
+    event VARCHAR(100) NOT NULL,
+    target TEXT NOT NULL, -- the webhook URL or Slack channel
+    request_headers JSONB NOT NULL DEFAULT '{}',
+    -- Exactly as sent, for replays; sealed with the organization's data key
+    -- when one is configured and redacted whenever it is shown
+    request_body TEXT NOT NULL,
+    response_status INTEGER,
+    response_body TEXT, -- redacted and truncated before it is stored
+    error TEXT,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    succeeded BOOLEAN NOT NULL,
+    replay_of UUID REFERENCES delivery_attempts(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_delivery_channel CHECK (channel IN ('webhook', 'slack'))
+);
+
+CREATE INDEX idx_delivery_attempts_org ON delivery_attempts(organization_id, created_at DESC);
+CREATE INDEX idx_delivery_attempts_created ON delivery_attempts(created_at);
@@ -0,0 +1,22 @@
+-- Migration: Add Activity Digests
+-- Date: 2026-10-15
+-- Description: Daily and weekly activity digest emails sent to users who opted in through their notification_digest preference; one row per user and period, claimed before sending so several gateway instances never send the same digest twice
+
+CREATE TABLE activity_digests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL,
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL, -- the scheduled send time in the user's timezone, as UTC
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_digest_frequency CHECK (frequency IN ('daily', 'weekly')),
+    CONSTRAINT valid_digest_status CHECK (status IN ('pending', 'sent', 'skipped', 'failed')),
+    CONSTRAINT valid_digest_period CHECK (period_start < period_end),
+    UNIQUE(user_id, frequency, period_end)
+);
+
+CREATE INDEX idx_activity_digests_created ON activity_digests(created_at);
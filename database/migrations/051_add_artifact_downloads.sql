@@ -0,0 +1,27 @@
+-- Migration: Add Artifact Downloads
+-- Date: 2026-10-15
+-- Description: Every report download and data export link handed out, with who took it, from where and how many bytes were served; reports list their download history from here
+
+CREATE TABLE artifact_downloads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    artifact_type VARCHAR(20) NOT NULL,
+    artifact_id UUID NOT NULL,
+    format VARCHAR(20),
+    bytes BIGINT NOT NULL DEFAULT 0,
+    -- completed, incomplete (the client went away mid-transfer) or
+    -- link_issued (a signed storage link the client downloads directly)
+    status VARCHAR(20) NOT NULL,
+    watermarked BOOLEAN NOT NULL DEFAULT false,
+    ip_address INET,
+    user_agent TEXT,
+    downloaded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_download_artifact_type CHECK (artifact_type IN ('report', 'export')),
+    CONSTRAINT valid_download_status CHECK (status IN ('completed', 'incomplete', 'link_issued')),
+    CONSTRAINT valid_download_bytes CHECK (bytes >= 0)
+);
+
+CREATE INDEX idx_artifact_downloads_artifact ON artifact_downloads(artifact_type, artifact_id, downloaded_at DESC);
+CREATE INDEX idx_artifact_downloads_org ON artifact_downloads(organization_id, downloaded_at DESC);
@@ -0,0 +1,30 @@
+-- Migration: Add Organization Domains
+-- Date: 2026-10-15
+-- Description: Email domains an organization has proven it owns with a DNS TXT record; users who verify an address on one can join the organization automatically
+
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+CREATE TABLE organization_domains (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    domain VARCHAR(253) NOT NULL,
+    -- Published as cyper-verification=<token> in a TXT record at
+    -- _cyper-verification.<domain>
+    verification_token VARCHAR(64) NOT NULL,
+    verified_at TIMESTAMP,
+    last_checked_at TIMESTAMP,
+    last_error TEXT,
+    auto_join BOOLEAN NOT NULL DEFAULT false,
+    auto_join_role VARCHAR(50) NOT NULL DEFAULT 'viewer',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT valid_domain_name CHECK (domain = lower(domain) AND domain LIKE '%.%'),
+    CONSTRAINT valid_auto_join_role CHECK (auto_join_role <> 'owner'),
+    UNIQUE(organization_id, domain)
+);
+
+-- Any number of organizations may claim a domain, only one can verify it
+CREATE UNIQUE INDEX idx_organization_domains_verified ON organization_domains(domain) WHERE verified_at IS NOT NULL;
+CREATE INDEX idx_organization_domains_org ON organization_domains(organization_id);
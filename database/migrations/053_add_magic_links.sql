@@ -0,0 +1,26 @@
+-- Migration: Add Magic Links
+-- Date: 2026-10-15
+-- Description: Password-less sign-in by emailed single-use links, which each organization opts its members into
+
+CREATE TABLE org_login_policies (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    -- A member may use magic links only when every organization they belong to allows it
+    magic_link_enabled BOOLEAN NOT NULL DEFAULT false,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per link sent; the signed token carries the row ID, and consuming
+-- the link stamps consumed_at so it works once
+CREATE TABLE magic_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requested_ip INET,
+    expires_at TIMESTAMP NOT NULL,
+    consumed_at TIMESTAMP,
+    consumed_ip INET,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_magic_links_user ON magic_links(user_id, created_at DESC);
+CREATE INDEX idx_magic_links_expires ON magic_links(expires_at);
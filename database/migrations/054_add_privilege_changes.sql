@@ -0,0 +1,24 @@
+-- Migration: Add Privilege Changes
+-- Date: 2026-10-15
+-- Description: Record of role elevations, custom role permission grants and API token scope expansions
+
+CREATE TABLE privilege_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- role_elevated, custom_role_expanded or api_token_scope_expanded
+    kind VARCHAR(50) NOT NULL,
+    -- The member, custom role or API token whose privileges grew
+    subject_type VARCHAR(50) NOT NULL,
+    subject_id VARCHAR(255) NOT NULL,
+    subject_name VARCHAR(255) NOT NULL DEFAULT '',
+    before JSONB NOT NULL DEFAULT '{}',
+    after JSONB NOT NULL DEFAULT '{}',
+    added TEXT[] NOT NULL DEFAULT '{}',
+    removed TEXT[] NOT NULL DEFAULT '{}',
+    severity VARCHAR(20) NOT NULL DEFAULT 'high',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_privilege_changes_org ON privilege_changes(organization_id, created_at DESC);
+CREATE INDEX idx_privilege_changes_subject ON privilege_changes(subject_type, subject_id);
@@ -0,0 +1,46 @@
+-- Migration: Add Tasks
+-- Date: 2026-10-15
+-- Description: Persistent background tasks with retries, delayed runs and recurring schedules
+
+CREATE TABLE tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    queue VARCHAR(100) NOT NULL,
+    kind VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    -- pending, running, succeeded, failed or cancelled
+    state VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    -- Earliest time the task may run; retries push it back
+    run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- At most one pending or running task per key
+    unique_key VARCHAR(255),
+    last_error TEXT,
+    -- The instance running the task extends locked_until while it works; a
+    -- lapsed lock means the instance died and the task is retried
+    locked_by VARCHAR(255),
+    locked_until TIMESTAMP,
+    cancel_requested BOOLEAN NOT NULL DEFAULT false,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_tasks_due ON tasks(queue, run_at) WHERE state = 'pending';
+CREATE INDEX idx_tasks_running ON tasks(locked_until) WHERE state = 'running';
+CREATE INDEX idx_tasks_state ON tasks(state, created_at DESC);
+CREATE UNIQUE INDEX idx_tasks_unique_key ON tasks(unique_key) WHERE unique_key IS NOT NULL AND state IN ('pending', 'running');
+
+-- Recurring tasks; next_run_at is advanced by whichever instance enqueues
+-- the run, so each run is enqueued once however many instances there are
+CREATE TABLE task_schedules (
+    name VARCHAR(100) PRIMARY KEY,
+    kind VARCHAR(100) NOT NULL,
+    interval_seconds INT NOT NULL,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP,
+    last_task_id UUID,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,57 @@
+-- Migration: Add Scan Event Stream
+-- Date: 2026-10-15
+-- Description: Findings streamed by workers while a scan runs, and NOTIFY triggers that skip changes already published to the scan event stream
+
+-- Findings a worker streams mid-scan are stored before the scan has a result;
+-- SubmitScanResult attaches them to the result it creates
+ALTER TABLE vulnerabilities ALTER COLUMN scan_result_id DROP NOT NULL;
+
+CREATE INDEX idx_vulnerabilities_streamed ON vulnerabilities(scan_job_id) WHERE scan_result_id IS NULL;
+
+-- Writers that publish their changes to the Redis scan event stream set
+-- cyper.scan_events_published for the transaction, so WebSocket clients do
+-- not receive the change twice
+
+CREATE OR REPLACE FUNCTION notify_scan_job_change() RETURNS trigger AS $$
+DECLARE
+    finding_count INTEGER;
+BEGIN
+    IF current_setting('cyper.scan_events_published', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+
+    IF NEW.status IN ('completed', 'failed', 'stopped') THEN
+        SELECT COUNT(*) INTO finding_count FROM vulnerabilities WHERE scan_job_id = NEW.id;
+    END IF;
+
+    PERFORM pg_notify('scan_events', json_build_object(
+        'scan_id', NEW.id,
+        'user_id', NEW.user_id,
+        'status', NEW.status,
+        'progress', NEW.progress_percentage,
+        'current_phase', NEW.current_phase,
+        'error_message', left(NEW.error_message, 500),
+        'vulnerability_count', finding_count
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notify_vulnerability_insert() RETURNS trigger AS $$
+BEGIN
+    IF current_setting('cyper.scan_events_published', true) = 'on' THEN
+        RETURN NEW;
+    END IF;
+
+    PERFORM pg_notify('finding_events', json_build_object(
+        'vulnerability_id', NEW.id,
+        'scan_id', NEW.scan_job_id,
+        'user_id', (SELECT user_id FROM scan_jobs WHERE id = NEW.scan_job_id),
+        'title', left(NEW.title, 500),
+        'severity', NEW.severity,
+        'cvss_score', NEW.cvss_score,
+        'affected_component', left(NEW.affected_component, 500)
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
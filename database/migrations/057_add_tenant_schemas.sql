@@ -0,0 +1,48 @@
+-- Migration: Add Tenant Schemas
+-- Date: 2026-10-15
+-- Description: Optional per-organization schemas holding an organization's scan evidence apart from other tenants
+
+-- Organizations with a schema of their own. The schema's tables are created
+-- by the migrations in database/tenant_migrations; version is the last one
+-- applied to it.
+CREATE TABLE tenant_schemas (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    schema_name VARCHAR(63) NOT NULL UNIQUE,
+    version INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Size of the scan evidence held in an organization's schema, for storage
+-- metering; 0 for organizations without one
+CREATE OR REPLACE FUNCTION tenant_evidence_bytes(org UUID) RETURNS BIGINT AS $$
+DECLARE
+    tenant_schema TEXT;
+    bytes BIGINT;
+BEGIN
+    SELECT schema_name INTO tenant_schema FROM tenant_schemas WHERE organization_id = org;
+    IF tenant_schema IS NULL THEN
+        RETURN 0;
+    END IF;
+    EXECUTE format('SELECT COALESCE(SUM(pg_column_size(raw_data)), 0) FROM %I.scan_result_data', tenant_schema)
+        INTO bytes;
+    RETURN bytes;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- Deleting an organization drops its schema along with it
+CREATE OR REPLACE FUNCTION drop_tenant_schema() RETURNS TRIGGER AS $$
+DECLARE
+    tenant_schema TEXT;
+BEGIN
+    SELECT schema_name INTO tenant_schema FROM tenant_schemas WHERE organization_id = OLD.id;
+    IF tenant_schema IS NOT NULL THEN
+        EXECUTE format('DROP SCHEMA IF EXISTS %I CASCADE', tenant_schema);
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER organizations_drop_tenant_schema
+    BEFORE DELETE ON organizations
+    FOR EACH ROW EXECUTE FUNCTION drop_tenant_schema();
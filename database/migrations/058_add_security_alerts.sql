@@ -0,0 +1,24 @@
+-- Migration: Add Security Alerts
+-- Date: 2026-10-15
+-- Description: Anomaly alerts raised for each organization's admins, and who acknowledged them
+
+CREATE TABLE security_alerts (
+    -- The anomaly's alert ID (kind:user:unix time), shared by the
+    -- organizations it was raised for
+    id VARCHAR(255) NOT NULL,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    subject_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    target TEXT NOT NULL DEFAULT '',
+    count INTEGER NOT NULL DEFAULT 0,
+    details JSONB NOT NULL DEFAULT '{}',
+    detected_at TIMESTAMP NOT NULL,
+    acknowledged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    acknowledged_at TIMESTAMP,
+    -- websocket or slack
+    acknowledged_via VARCHAR(20),
+
+    PRIMARY KEY (id, organization_id)
+);
+
+CREATE INDEX idx_security_alerts_org ON security_alerts(organization_id, detected_at DESC);
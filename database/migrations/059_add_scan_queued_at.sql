@@ -0,0 +1,22 @@
+-- Migration: Add Scan Queued At
+-- Date: 2026-10-15
+-- Description: When each scan job last became dispatchable, for the queue wait metric
+
+-- Set whenever a job enters pending: on creation, approval, resume or
+-- re-queue after a lost worker; not on pending_approval
+ALTER TABLE scan_jobs ADD COLUMN queued_at TIMESTAMP;
+
+UPDATE scan_jobs SET queued_at = created_at WHERE status = 'pending';
+
+CREATE OR REPLACE FUNCTION set_scan_queued_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.status = 'pending' AND (TG_OP = 'INSERT' OR OLD.status IS DISTINCT FROM 'pending') THEN
+        NEW.queued_at = CURRENT_TIMESTAMP;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_scan_queued_at BEFORE INSERT OR UPDATE OF status ON scan_jobs
+    FOR EACH ROW EXECUTE FUNCTION set_scan_queued_at();
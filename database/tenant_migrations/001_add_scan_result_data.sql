@@ -0,0 +1,12 @@
+-- Tenant migration: Add Scan Result Data
+-- Date: 2026-10-15
+-- Description: Scan evidence (the raw data workers submit) of an organization with its own schema
+--
+-- Tenant migrations run with the organization's schema first on the
+-- search_path; refer to shared tables through public.
+
+CREATE TABLE scan_result_data (
+    scan_result_id UUID PRIMARY KEY REFERENCES public.scan_results(id) ON DELETE CASCADE,
+    raw_data JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,29 @@
+// Command openapi writes the gateway OpenAPI specification for SDK generation.
+//
+//	go generate ./internal/api
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cyper-security/gateway/internal/api"
+)
+
+func main() {
+	output := flag.String("o", "api/openapi.json", "output file")
+	flag.Parse()
+
+	data, err := json.MarshalIndent(api.OpenAPIDocument(), "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to render specification: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*output, append(data, '\n'), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *output, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/audit"
+)
+
+// Exit codes for `gateway audit verify`
+const (
+	exitVerified = 0
+	exitFailed   = 1 // the bundle did not verify
+	exitUsage    = 2 // bad arguments or unreadable input
+)
+
+const auditUsage = `Usage: gateway audit verify [flags] BUNDLE
+
+Verifies an audit export bundle (NDJSON or CSV from GET /api/v1/audit/export?format=...)
+offline: entry signatures, the hash chain and the signed manifest.
+BUNDLE may be "-" to read standard input.
+
+Exit codes: 0 verified, 1 verification failed, 2 usage or input error.
+
+Flags:
+`
+
+// keyList collects repeated -key flags
+type keyList []string
+
+func (k *keyList) String() string { return strings.Join(*k, ",") }
+
+func (k *keyList) Set(value string) error {
+	*k = append(*k, value)
+	return nil
+}
+
+// runAuditCommand implements the `gateway audit` subcommands
+func runAuditCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprint(stderr, auditUsage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, auditUsage)
+		fs.PrintDefaults()
+	}
+
+	var keys keyList
+	fs.Var(&keys, "key", "trusted base64 Ed25519 public key (repeatable)")
+	keysFile := fs.String("keys-file", "", "file with one trusted base64 public key per line")
+	allowUnsigned := fs.Bool("allow-unsigned", false, "do not fail on entries exported before they were signed")
+	jsonOutput := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	if *keysFile != "" {
+		fileKeys, err := readKeysFile(*keysFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if len(keys) == 0 {
+		fmt.Fprintln(stderr, "error: at least one -key or -keys-file is required")
+		return exitUsage
+	}
+	trusted, err := audit.ParsePublicKeys(keys)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	input := os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		defer f.Close()
+		input = f
+	}
+
+	bundle, err := audit.ReadBundle(input)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitUsage
+	}
+
+	report := audit.VerifyBundle(bundle, trusted)
+	ok := report.OK(*allowUnsigned)
+
+	if *jsonOutput {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(struct {
+			Verified bool `json:"verified"`
+			*audit.VerificationReport
+		}{ok, report})
+	} else {
+		printReport(stdout, bundle, report, ok)
+	}
+
+	if !ok {
+		return exitFailed
+	}
+	return exitVerified
+}
+
+func printReport(w io.Writer, bundle *audit.Bundle, report *audit.VerificationReport, ok bool) {
+	m := bundle.Manifest
+	fmt.Fprintf(w, "Bundle:      %s, %s to %s (exported %s)\n", m.Format,
+		m.RangeStart.Format("2006-01-02T15:04:05Z07:00"), m.RangeEnd.Format("2006-01-02T15:04:05Z07:00"),
+		m.ExportedAt.Format("2006-01-02T15:04:05Z07:00"))
+	fmt.Fprintf(w, "Entries:     %d\n", report.Entries)
+	fmt.Fprintf(w, "Signatures:  %d valid, %d invalid, %d untrusted key, %d unsigned\n",
+		report.ValidSignatures, report.InvalidSignatures, report.UntrustedKeys, report.Unsigned)
+	fmt.Fprintf(w, "Hash chain:  %s\n", passFail(report.ChainValid))
+	fmt.Fprintf(w, "Manifest:    %s\n", passFail(report.ManifestValid))
+
+	for _, issue := range report.Issues {
+		if issue.Line == 0 {
+			fmt.Fprintf(w, "  manifest: %s\n", issue.Problem)
+		} else {
+			fmt.Fprintf(w, "  entry %d (id %d): %s\n", issue.Line, issue.EntryID, issue.Problem)
+		}
+	}
+
+	if ok {
+		fmt.Fprintln(w, "Result:      VERIFIED")
+	} else {
+		fmt.Fprintln(w, "Result:      FAILED")
+	}
+}
+
+func passFail(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "FAILED"
+}
+
+func readKeysFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
+	}
+	return keys, scanner.Err()
+}
@@ -2,27 +2,100 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/cyper-security/gateway/internal/adminserver"
+	"github.com/cyper-security/gateway/internal/anchoring"
 	"github.com/cyper-security/gateway/internal/api"
+	"github.com/cyper-security/gateway/internal/apiversion"
+	"github.com/cyper-security/gateway/internal/approvals"
 	"github.com/cyper-security/gateway/internal/audit"
 	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/billing"
+	"github.com/cyper-security/gateway/internal/bootstrap"
 	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/branding"
+	"github.com/cyper-security/gateway/internal/challenge"
+	"github.com/cyper-security/gateway/internal/console"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/deliveries"
+	"github.com/cyper-security/gateway/internal/diagnostics"
+	"github.com/cyper-security/gateway/internal/digests"
+	"github.com/cyper-security/gateway/internal/domains"
+	"github.com/cyper-security/gateway/internal/downloads"
+	"github.com/cyper-security/gateway/internal/escalation"
+	"github.com/cyper-security/gateway/internal/estimation"
+	"github.com/cyper-security/gateway/internal/events"
+	"github.com/cyper-security/gateway/internal/export"
+	"github.com/cyper-security/gateway/internal/flags"
+	"github.com/cyper-security/gateway/internal/grants"
+	"github.com/cyper-security/gateway/internal/graphql"
+	"github.com/cyper-security/gateway/internal/health"
+	"github.com/cyper-security/gateway/internal/i18n"
+	"github.com/cyper-security/gateway/internal/imports"
+	"github.com/cyper-security/gateway/internal/intel"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/loadshed"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/maintenance"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/mtls"
+	"github.com/cyper-security/gateway/internal/notify"
+	"github.com/cyper-security/gateway/internal/openapi"
+	"github.com/cyper-security/gateway/internal/outbound"
+	"github.com/cyper-security/gateway/internal/payload"
+	"github.com/cyper-security/gateway/internal/preferences"
+	"github.com/cyper-security/gateway/internal/privileges"
 	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/cyper-security/gateway/internal/reports"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/rpc"
+	"github.com/cyper-security/gateway/internal/scanevents"
+	"github.com/cyper-security/gateway/internal/scanwindows"
+	"github.com/cyper-security/gateway/internal/secrets"
+	"github.com/cyper-security/gateway/internal/slack"
+	"github.com/cyper-security/gateway/internal/stats"
+	"github.com/cyper-security/gateway/internal/status"
+	"github.com/cyper-security/gateway/internal/storage"
+	"github.com/cyper-security/gateway/internal/supportbundle"
+	"github.com/cyper-security/gateway/internal/tasks"
+	"github.com/cyper-security/gateway/internal/tenancy"
+	"github.com/cyper-security/gateway/internal/tenantkeys"
+	"github.com/cyper-security/gateway/internal/uploads"
+	"github.com/cyper-security/gateway/internal/workers"
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
+// version is overridden at build time with -ldflags "-X main.version=..."
+var version = "0.1.0"
+
 func main() {
+	// Offline tooling runs without the server's dependencies
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(runAuditCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "support-bundle" {
+		_ = godotenv.Load()
+		os.Exit(runSupportBundleCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Load environment variables
 	_ = godotenv.Load()
 
@@ -30,25 +103,49 @@ func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
 
+	// Recent warnings and errors are kept for support bundles
+	recentLogs := logging.NewRecent(getEnvInt("SUPPORT_BUNDLE_LOG_ENTRIES", 500), zap.WarnLevel)
+	logger = recentLogs.Attach(logger)
+	bundleRedactor := supportbundle.NewRedactor()
+
 	logger.Info("Starting Cyper Gateway...")
 
+	// Outbound requests to integrations are held to the egress policy: no
+	// private addresses, unexpected ports or schemes, on redirects too
+	egress := newOutboundGuard(logger)
+
+	// Secrets (JWT secret, DB password, audit signing keys) come from Vault or
+	// AWS Secrets Manager when configured. Names missing there fall back to
+	// the environment.
+	secretStore := secrets.NewCache(newSecretsProvider(egress, logger), getEnvDuration("SECRETS_CACHE_TTL", 5*time.Minute), logger)
+	getSecret := func(name, defaultValue string) string {
+		secretCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		value, err := secretStore.Get(secretCtx, name)
+		if err == secrets.ErrSecretNotFound {
+			return getEnv(name, defaultValue)
+		}
+		if err != nil {
+			logger.Fatal("Failed to fetch secret", zap.String("name", name), zap.Error(err))
+		}
+		bundleRedactor.Add(value)
+		return value
+	}
+	audit.SetKeyLookup(func(name string) string { return getSecret(name, "") })
+
 	// Database connection
 	dbHost := getEnv("DB_HOST", "localhost")
 	dbPort := getEnv("DB_PORT", "5432")
 	dbName := getEnv("DB_NAME", "cyper_security")
 	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "postgres")
+	dbPassword := getSecret("DB_PASSWORD", "postgres")
 
 	dsn := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=disable",
 		dbHost, dbPort, dbName, dbUser, dbPassword)
 
-	db, err := sqlx.Connect("postgres", dsn)
-	if err != nil {
-		logger.Fatal("Failed to connect to database", zap.Error(err))
-	}
-	defer db.Close()
-
-	logger.Info("Connected to PostgreSQL")
+	dbConfig := database.DefaultConfig()
+	dbConfig.QueryTimeout = getEnvDuration("DB_QUERY_TIMEOUT", dbConfig.QueryTimeout)
+	dbConfig.SlowQueryThreshold = getEnvDuration("DB_SLOW_QUERY_THRESHOLD", dbConfig.SlowQueryThreshold)
 
 	// Redis connection
 	redisURL := getEnv("REDIS_URL", "localhost:6379")
@@ -57,17 +154,65 @@ func main() {
 		Password: os.Getenv("REDIS_PASSWORD"),
 		DB:       0,
 	})
+	defer redisClient.Close()
 
+	// Dependencies start in dependency order, each retried with backoff. If
+	// Redis stays down the gateway starts read-only (when allowed) and keeps
+	// retrying it in the background.
 	ctx := context.Background()
-	if err := redisClient.Ping(ctx).Err(); err != nil {
-		logger.Warn("Failed to connect to Redis", zap.Error(err))
-	} else {
-		logger.Info("Connected to Redis")
+	retryPolicy := bootstrap.DefaultRetryPolicy()
+	retryPolicy.Attempts = getEnvInt("BOOTSTRAP_ATTEMPTS", retryPolicy.Attempts)
+	retryPolicy.InitialBackoff = getEnvDuration("BOOTSTRAP_INITIAL_BACKOFF", retryPolicy.InitialBackoff)
+	retryPolicy.MaxBackoff = getEnvDuration("BOOTSTRAP_MAX_BACKOFF", retryPolicy.MaxBackoff)
+	retryPolicy.AttemptTimeout = getEnvDuration("BOOTSTRAP_ATTEMPT_TIMEOUT", retryPolicy.AttemptTimeout)
+	allowDegraded := getEnv("BOOTSTRAP_ALLOW_DEGRADED", "true") == "true"
+
+	boot := bootstrap.New(retryPolicy, logger)
+	var db *database.DB
+	boot.Add(bootstrap.Component{Name: "database", Start: func(ctx context.Context) error {
+		conn, err := database.Connect("postgres", dsn, dbConfig, logger)
+		if err != nil {
+			return err
+		}
+		db = conn
+		return nil
+	}})
+	// Optional read replica for audit exports, dashboards and report data;
+	// reads fall back to the primary without it
+	var replica *sqlx.DB
+	if replicaDSN := os.Getenv("DB_REPLICA_DSN"); replicaDSN != "" {
+		boot.Add(bootstrap.Component{Name: "database_replica", DependsOn: []string{"database"}, Optional: true, Start: func(ctx context.Context) error {
+			conn, err := sqlx.ConnectContext(ctx, "postgres", replicaDSN)
+			if err != nil {
+				return err
+			}
+			replica = conn
+			replicaConfig := database.DefaultReplicaConfig()
+			replicaConfig.MaxLag = getEnvDuration("DB_REPLICA_MAX_LAG", replicaConfig.MaxLag)
+			db.AttachReplica(replica, replicaConfig)
+			return nil
+		}})
+	}
+	boot.Add(bootstrap.Component{Name: "redis", Optional: allowDegraded, Start: func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	}})
+
+	if _, err := boot.Run(ctx); err != nil {
+		logger.Fatal("Failed to start dependencies", zap.Error(err))
+	}
+	defer db.Close()
+	defer func() {
+		if replica != nil {
+			replica.Close()
+		}
+	}()
+	if boot.Degraded() {
+		logger.Warn("Starting in degraded mode: the API is read-only until every dependency is ready")
+		go boot.Recover(ctx, getEnvDuration("BOOTSTRAP_RECOVER_INTERVAL", 10*time.Second))
 	}
-	defer redisClient.Close()
 
 	// Initialize services
-	jwtSecret := os.Getenv("JWT_SECRET")
+	jwtSecret := getSecret("JWT_SECRET", "")
 	if jwtSecret == "" {
 		jwtSecret = "default-secret-change-in-production"
 		logger.Warn("Using default JWT_SECRET - CHANGE THIS IN PRODUCTION")
@@ -77,76 +222,1239 @@ func main() {
 	brainURL := getEnv("BRAIN_URL", "http://brain:50051")
 	pulseInterval := 5 * time.Minute
 
+	// Mutual TLS between the gateway, brain and workers. The certificate and
+	// CA are re-read on SIGHUP, so rotated files take effect without a restart.
+	var internalTLS *mtls.Reloader
+	if certFile := getEnv("INTERNAL_TLS_CERT", os.Getenv("GRPC_TLS_CERT")); certFile != "" {
+		identities, err := mtls.ParseIdentities(os.Getenv("MTLS_IDENTITIES"))
+		if err != nil {
+			logger.Fatal("Invalid MTLS_IDENTITIES", zap.Error(err))
+		}
+		internalTLS, err = mtls.NewReloader(mtls.Config{
+			CertFile:   certFile,
+			KeyFile:    getEnv("INTERNAL_TLS_KEY", os.Getenv("GRPC_TLS_KEY")),
+			CAFile:     getEnv("INTERNAL_TLS_CA", os.Getenv("GRPC_TLS_CLIENT_CA")),
+			Identities: identities,
+		}, logger)
+		if err != nil {
+			logger.Fatal("Failed to load mTLS certificates", zap.Error(err))
+		}
+		go internalTLS.WatchSIGHUP(ctx)
+	}
+
 	brainClient := brain.NewClient(brainURL, logger)
+	brainOptions := []outbound.Option{outbound.AllowURLs(brainURL)}
+	if internalTLS != nil && strings.HasPrefix(brainURL, "https://") {
+		// Without an identity mapping any certificate from the CA is accepted
+		brainIdentity := ""
+		if os.Getenv("MTLS_IDENTITIES") != "" {
+			brainIdentity = getEnv("BRAIN_TLS_IDENTITY", "brain")
+		}
+		brainOptions = append(brainOptions, outbound.TLS(internalTLS.ClientConfig(brainIdentity)))
+	}
+	brainClient.SetTransport(egress.Transport("brain", brainOptions...))
+	// Typed queries over the core tables (users, organizations, sessions, audit logs)
+	repos := repository.New(db)
+
+	// Translation catalogs: the built-in ones with deployment catalogs
+	// layered over them
+	i18nRegistry := i18n.NewRegistry()
+	if dir := os.Getenv("I18N_CATALOG_DIR"); dir != "" {
+		if err := i18nRegistry.LoadDir(dir); err != nil {
+			logger.Fatal("Failed to load translation catalogs", zap.Error(err))
+		}
+	}
+
+	// User preferences (timezone, locale, default organization, ...) follow
+	// users into API errors, reports and notification emails
+	prefsService := preferences.NewService(db, i18nRegistry, logger)
+	prefsService.SetCache(redisClient, getEnvDuration("PREFERENCES_CACHE_TTL", preferences.DefaultCacheTTL))
+
 	authService := auth.NewAuthService(db, redisClient, jwtSecret, centralAuthURL, pulseInterval, logger)
+	authService.SetTermsGraceMode(os.Getenv("TERMS_GRACE_MODE") == "true")
+	authService.SetDefaultOrganization(prefsService.DefaultOrganization)
+
+	// Multi-region deployments: tokens and sessions carry the issuing region,
+	// tokens are bound to an audience, and revocations are announced to the
+	// other regions over a Redis they all reach
+	if region := os.Getenv("GATEWAY_REGION"); region != "" {
+		regionConfig := auth.RegionConfig{
+			Region:            region,
+			Audience:          os.Getenv("JWT_AUDIENCE"),
+			RevocationChannel: getEnv("SESSION_REVOCATION_CHANNEL", auth.DefaultRevocationChannel),
+		}
+		if accepted := getEnv("JWT_ACCEPTED_AUDIENCES", regionConfig.Audience); accepted != "" {
+			regionConfig.AcceptedAudiences = strings.Split(accepted, ",")
+		}
+		authService.SetRegionConfig(regionConfig)
+
+		revocationBus := redisClient
+		if busURL := os.Getenv("SESSION_REVOCATION_REDIS_URL"); busURL != "" {
+			revocationBus = redis.NewClient(&redis.Options{
+				Addr:     busURL,
+				Password: os.Getenv("SESSION_REVOCATION_REDIS_PASSWORD"),
+			})
+			defer revocationBus.Close()
+		}
+		authService.SetRevocationBus(revocationBus)
+		go authService.StartRevocationListener(ctx)
+	}
+
+	// A JWT_SECRET rotated in the backend is picked up on renewal; tokens
+	// signed with the old secret stay valid for the grace window
+	jwtGrace := getEnvDuration("JWT_ROTATION_GRACE", time.Hour)
+	secretStore.OnChange("JWT_SECRET", func(secret string) {
+		authService.RotateJWTSecret(secret, jwtGrace)
+	})
+	if os.Getenv("SECRETS_PROVIDER") != "" {
+		go secretStore.StartRenewal(ctx, getEnvDuration("SECRETS_RENEW_INTERVAL", 5*time.Minute))
+	}
 	auditLogger := audit.NewAuditLogger(db, logger)
+	egress.OnBlocked(func(ctx context.Context, blocked *outbound.BlockedError) {
+		auditLogger.LogSecurityEvent(ctx, "", "outbound_request_blocked", blocked.URL, "high", map[string]interface{}{
+			"integration":     blocked.Integration,
+			"organization_id": blocked.OrganizationID,
+			"reason":          blocked.Reason,
+			"detail":          blocked.Detail,
+		})
+	})
+	roleStore := rbac.NewRoleStore(db, logger)
+	flagService := flags.NewService(db, redisClient, logger)
+	legalHoldService := legalhold.NewService(db, logger)
+	authService.SetFeatureSource(flagService)
+	policyEngine := rbac.NewPolicyEngine(db, logger)
+	redaction := audit.DefaultRedactionConfig()
+	redaction.Strict = os.Getenv("AUDIT_REDACTION_STRICT") == "true"
+	if extra := os.Getenv("AUDIT_REDACT_KEYS"); extra != "" {
+		redaction.KeyPatterns = append(redaction.KeyPatterns, strings.Split(extra, ",")...)
+	}
+	redactor, err := audit.NewRedactor(redaction)
+	if err != nil {
+		logger.Fatal("Invalid audit redaction rules", zap.Error(err))
+	}
+	auditLogger.SetRedactor(redactor)
+	if getEnv("AUDIT_BUFFERING", "true") == "true" {
+		auditLogger.EnableBuffering(audit.DefaultBufferConfig())
+	}
+
+	// Sliding session expiry: activity is batched in Redis, sessions in use
+	// are extended up to their maximum lifetime and idle ones are revoked
+	sessionConfig := auth.DefaultSessionConfig()
+	sessionConfig.IdleTimeout = getEnvDuration("SESSION_IDLE_TIMEOUT", sessionConfig.IdleTimeout)
+	sessionConfig.MaxLifetime = getEnvDuration("SESSION_MAX_LIFETIME", sessionConfig.MaxLifetime)
+	sessionConfig.ExtendWithin = getEnvDuration("SESSION_EXTEND_WITHIN", sessionConfig.ExtendWithin)
+	sessionConfig.FlushInterval = getEnvDuration("SESSION_ACTIVITY_FLUSH_INTERVAL", sessionConfig.FlushInterval)
+	sessionConfig.SweepInterval = getEnvDuration("SESSION_IDLE_SWEEP_INTERVAL", sessionConfig.SweepInterval)
+	// Concurrent session limits, unless an organization sets its own
+	sessionConfig.MaxSessions = getEnvInt("SESSION_MAX_CONCURRENT", sessionConfig.MaxSessions)
+	sessionConfig.MaxSessionsByRole = parseRoleLimits(os.Getenv("SESSION_LIMITS_BY_ROLE"), logger)
+	sessionConfig.OnSessionLimit = getEnv("SESSION_LIMIT_ACTION", sessionConfig.OnSessionLimit)
+	if !auth.ValidSessionLimitAction(sessionConfig.OnSessionLimit) {
+		logger.Fatal("Invalid SESSION_LIMIT_ACTION", zap.String("action", sessionConfig.OnSessionLimit))
+	}
+	sessionConfig.BreakGlassTTL = getEnvDuration("NETWORK_POLICY_BREAK_GLASS_TTL", sessionConfig.BreakGlassTTL)
+	authService.SetSessionConfig(sessionConfig)
+	authService.SetOrgTokenRoutes(api.TokenRoutePermissions())
+	go authService.StartSessionMaintenance(ctx)
+
+	// X-Forwarded-For is only believed from these reverse proxies, by the
+	// network policy and by the router's ClientIP; by default from none
+	trustedProxies, err := adminserver.ParseNetworks(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES", zap.Error(err))
+	}
+	authService.SetTrustedProxies(trustedProxies)
 
 	// Start authorization pulse checker
 	go authService.StartPulseCheck(ctx)
 
+	// Refresh database-backed gauges (active sessions, running scans)
+	go metrics.StartCollector(ctx, db.DB, 30*time.Second, logger)
+	go db.StartPoolMonitor(ctx, 15*time.Second)
+	go db.StartReplicaMonitor(ctx)
+
+	// Refresh dashboard aggregates
+	go stats.StartRefresher(ctx, db, getEnvDuration("STATS_REFRESH_INTERVAL", 5*time.Minute), logger)
+
+	// Outgoing email (scheduled reports, login alerts, activity digests)
+	publicURL := getEnv("PUBLIC_URL", "http://localhost:8080")
+	mailer := notify.NewMailer(notify.SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     getEnv("SMTP_PORT", "587"),
+		User:     os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getEnv("SMTP_FROM", "noreply@cyper.security"),
+	})
+
+	// Artifact storage for report files and data exports. Local storage has
+	// its signed download links served by the gateway itself.
+	artifactStore, localStore := newArtifactStore(publicURL, jwtSecret, getSecret, egress, logger)
+
+	// Logos and finding evidence are size- and type-checked, then scanned by
+	// clamd when CLAMAV_ADDRESS is set; infected files are quarantined
+	var uploadScanner uploads.Scanner
+	if address := os.Getenv("CLAMAV_ADDRESS"); address != "" {
+		clamav, err := uploads.NewClamAV(address, getEnvDuration("CLAMAV_TIMEOUT", 30*time.Second))
+		if err != nil {
+			logger.Fatal("Invalid CLAMAV_ADDRESS", zap.Error(err))
+		}
+		uploadScanner = clamav
+	} else {
+		logger.Warn("CLAMAV_ADDRESS is not set; uploads are stored unscanned")
+	}
+	uploadService := uploads.NewService(db, artifactStore, uploadScanner, uploads.Config{
+		FailOpen: os.Getenv("UPLOAD_SCAN_FAIL_OPEN") == "true",
+	}, logger)
+	uploadService.SetLegalHolds(legalHoldService)
+
+	// Encrypt authorization proofs, raw scan evidence and delivery payloads with per-organization
+	// data keys, wrapped by the master key and re-wrapped after it changes
+	var dataCipher repository.Cipher = repository.Plaintext
+	if masterKeys := newMasterKeys(getSecret, egress, logger); masterKeys != nil {
+		dataKeyConfig := tenantkeys.DefaultConfig()
+		dataKeyConfig.Interval = getEnvDuration("DATA_KEY_ROTATION_INTERVAL", dataKeyConfig.Interval)
+		dataKeyConfig.MaxAge = getEnvDuration("DATA_KEY_MAX_AGE", dataKeyConfig.MaxAge)
+		dataKeyService := tenantkeys.NewService(db, masterKeys, dataKeyConfig, logger)
+		go dataKeyService.Start(ctx)
+		dataCipher = dataKeyService
+	}
+
+	// Outbound webhooks and Slack messages, logged per organization so
+	// failed deliveries can be inspected and replayed
+	var slackClient *slack.Client
+	if botToken := getSecret("SLACK_BOT_TOKEN", ""); botToken != "" {
+		slackClient = slack.NewClient(botToken)
+		slackClient.SetTransport(egress.Transport("slack"))
+	}
+	deliveryConfig := deliveries.DefaultConfig()
+	deliveryConfig.Retention = getEnvDuration("DELIVERY_LOG_RETENTION", deliveryConfig.Retention)
+	deliveryConfig.Transport = egress.Transport("webhook")
+	deliveryService := deliveries.NewService(db, dataCipher, slackClient, deliveryConfig, logger)
+	go deliveryService.Start(ctx)
+
+	// Generate and deliver scheduled reports
+	// Render reports through the brain service or the built-in renderer per
+	// report type, falling back while the primary is down
+	reportRouterConfig := reports.DefaultRouterConfig()
+	reportRouterConfig.Default = getEnv("REPORT_BACKEND_DEFAULT", reportRouterConfig.Default)
+	reportRouterConfig.Fallback = getEnv("REPORT_BACKEND_FALLBACK", reportRouterConfig.Fallback)
+	reportRouterConfig.DownFor = getEnvDuration("REPORT_BACKEND_DOWN_FOR", reportRouterConfig.DownFor)
+	reportRouterConfig.Routes = parseKeyValues(os.Getenv("REPORT_BACKEND_ROUTES"), "report backend route", logger)
+	for flag, backend := range parseKeyValues(os.Getenv("REPORT_BACKEND_EXPERIMENTS"), "report backend experiment", logger) {
+		reportRouterConfig.Experiments = append(reportRouterConfig.Experiments, reports.Experiment{Flag: flag, Backend: backend})
+	}
+	reportRouter, err := reports.NewRouter(map[string]reports.ReportBackend{
+		reports.BackendBrain: reports.NewBrainBackend(brainClient),
+		reports.BackendLocal: reports.NewLocalBackend(),
+	}, reportRouterConfig, flagService, logger)
+	if err != nil {
+		logger.Fatal("Invalid report backend configuration", zap.Error(err))
+	}
+	reportService := reports.NewService(db, reportRouter, artifactStore, logger)
+	reportService.SetPreferences(prefsService)
+	reportDeliverer := reports.NewDeliverer(reports.DeliveryConfig{
+		PublicURL: publicURL,
+		SenderName: func(ctx context.Context, orgID string) string {
+			return branding.SenderName(ctx, db, orgID)
+		},
+	}, mailer, deliveryService)
+	go reports.StartScheduler(ctx, reportService, reportDeliverer, getEnvDuration("REPORT_SCHEDULER_INTERVAL", time.Minute), logger)
+
+	// Background tasks run from a queue in the database with retries, and
+	// workers per queue (TASK_QUEUE_CONCURRENCY, e.g. "exports=2")
+	taskConfig := tasks.DefaultConfig()
+	taskConfig.DefaultConcurrency = getEnvInt("TASK_CONCURRENCY", taskConfig.DefaultConcurrency)
+	taskConfig.PollInterval = getEnvDuration("TASK_POLL_INTERVAL", taskConfig.PollInterval)
+	taskConfig.Timeout = getEnvDuration("TASK_TIMEOUT", taskConfig.Timeout)
+	taskConfig.MaxAttempts = getEnvInt("TASK_MAX_ATTEMPTS", taskConfig.MaxAttempts)
+	taskConfig.Retention = getEnvDuration("TASK_RETENTION", taskConfig.Retention)
+	for queue, value := range parseKeyValues(os.Getenv("TASK_QUEUE_CONCURRENCY"), "task queue concurrency", logger) {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			logger.Fatal("Invalid task queue concurrency", zap.String("queue", queue), zap.String("value", value))
+		}
+		taskConfig.Concurrency[queue] = n
+	}
+	taskService := tasks.NewService(db, taskConfig, logger)
+
+	exportConfig := export.DefaultConfig()
+	exportConfig.Retention = getEnvDuration("EXPORT_RETENTION", exportConfig.Retention)
+	exportConfig.LinkTTL = getEnvDuration("EXPORT_LINK_TTL", exportConfig.LinkTTL)
+	exportService := export.NewService(db, artifactStore, taskService, exportConfig, logger)
+	go exportService.StartReaper(ctx, time.Hour)
+
+	// Enterprise organizations may keep their scan evidence in a database
+	// schema of their own; every schema is brought to the latest tenant
+	// migration before serving
+	var tenancyService *tenancy.Service
+	if getEnv("TENANT_SCHEMAS_ENABLED", "false") == "true" {
+		tenantMigrations, err := tenancy.LoadMigrations(getEnv("TENANT_MIGRATIONS_DIR", defaultTenantMigrationsDir))
+		if err != nil {
+			logger.Fatal("Failed to load tenant migrations", zap.Error(err))
+		}
+		tenancyService = tenancy.NewService(db, taskService, tenantMigrations, logger)
+		if err := tenancyService.Migrate(ctx); err != nil {
+			logger.Error("Some tenant schemas could not be migrated", zap.Error(err))
+		}
+	}
+
+	// Daily and weekly activity digests for users who opt in, sent at
+	// DIGEST_HOUR in each user's timezone
+	digestConfig := digests.DefaultConfig()
+	digestConfig.PublicURL = publicURL
+	digestConfig.Interval = getEnvDuration("DIGEST_INTERVAL", digestConfig.Interval)
+	digestConfig.Hour = getEnvInt("DIGEST_HOUR", digestConfig.Hour)
+	digestConfig.Weekday = time.Weekday(getEnvInt("DIGEST_WEEKDAY", int(digestConfig.Weekday)))
+	digestConfig.AgingAfter = getEnvDuration("DIGEST_CRITICAL_AGING_AFTER", digestConfig.AgingAfter)
+	if digestConfig.Hour < 0 || digestConfig.Hour > 23 || digestConfig.Weekday < time.Sunday || digestConfig.Weekday > time.Saturday {
+		logger.Fatal("DIGEST_HOUR must be 0-23 and DIGEST_WEEKDAY 0 (Sunday) to 6")
+	}
+	digestService := digests.NewService(db, digestConfig, mailer, prefsService, roleStore, authService.SecurityActivity, logger)
+	go digestService.Start(ctx)
+
+	// Page organizations' emergency contacts until alerts are acknowledged
+	escalationConfig := escalation.DefaultConfig()
+	escalationConfig.PublicURL = publicURL
+	escalationConfig.LinkSecret = []byte(getSecret("ESCALATION_LINK_KEY", jwtSecret))
+	escalationConfig.Interval = getEnvDuration("ESCALATION_INTERVAL", escalationConfig.Interval)
+	escalationService := escalation.NewService(db, escalationConfig, mailer, newSMSProvider(getSecret, egress, logger), deliveryService, logger)
+	go escalationService.Start(ctx)
+
+	// Delegated authorization: clients of an MSSP organization approve scans
+	// of their assets through signed links
+	grantConfig := grants.DefaultConfig()
+	grantConfig.PublicURL = publicURL
+	grantConfig.LinkSecret = []byte(getSecret("SCAN_GRANT_LINK_KEY", jwtSecret))
+	grantConfig.MaxValidity = getEnvDuration("SCAN_GRANT_MAX_VALIDITY", grantConfig.MaxValidity)
+	grantService := grants.NewService(db, grantConfig, mailer, logger)
+
+	// Hold, pause and resume scans according to their execution windows
+	scanWindowConfig := scanwindows.DefaultConfig()
+	scanWindowConfig.Interval = getEnvDuration("SCAN_WINDOW_INTERVAL", scanWindowConfig.Interval)
+	scanWindowService := scanwindows.NewService(db, scanWindowConfig, logger)
+	go scanWindowService.Start(ctx)
+
+	// Publish the audit hash chain head to an external notary, so a rewritten
+	// log is detectable even by someone holding the signing key
+	var anchorService *anchoring.Service
+	if notary := newNotary(egress, logger); notary != nil {
+		anchorConfig := anchoring.DefaultConfig()
+		anchorConfig.Interval = getEnvDuration("AUDIT_ANCHOR_INTERVAL", anchorConfig.Interval)
+		anchorConfig.Settle = getEnvDuration("AUDIT_ANCHOR_SETTLE", anchorConfig.Settle)
+		anchorService = anchoring.NewService(db, notary, anchorConfig, logger)
+		go anchorService.Start(ctx)
+	}
+
+	importService := imports.NewService(db, dataCipher, logger)
+
+	// Meter usage for billing and export it to Stripe when configured
+	billingConfig := billing.DefaultConfig()
+	billingConfig.MeterInterval = getEnvDuration("BILLING_METER_INTERVAL", billingConfig.MeterInterval)
+	billingConfig.ExportInterval = getEnvDuration("BILLING_EXPORT_INTERVAL", billingConfig.ExportInterval)
+	var stripeExporter *billing.StripeExporter
+	if stripeKey := getSecret("STRIPE_API_KEY", ""); stripeKey != "" {
+		stripeConfig := billing.DefaultStripeConfig()
+		stripeConfig.APIKey = stripeKey
+		stripeConfig.URL = getEnv("STRIPE_API_URL", stripeConfig.URL)
+		stripeConfig.Transport = egress.Transport("stripe", outbound.AllowURLs(stripeConfig.URL))
+		if meters := getEnv("BILLING_STRIPE_METERS", ""); meters != "" {
+			if stripeConfig.Meters, err = billing.ParseMeters(meters); err != nil {
+				logger.Fatal("Invalid BILLING_STRIPE_METERS", zap.Error(err))
+			}
+		}
+		stripeExporter = billing.NewStripeExporter(db, stripeConfig, logger)
+	}
+	billingService := billing.NewService(db, stripeExporter, billingConfig, logger)
+	go billingService.Start(ctx)
+
+	// Sync CVE metadata, EPSS scores and the KEV catalog, and enrich findings
+	intelConfig := intel.DefaultConfig()
+	intelConfig.NVDAPIKey = getSecret("NVD_API_KEY", "")
+	intelConfig.NVDURL = getEnv("INTEL_NVD_URL", intelConfig.NVDURL)
+	intelConfig.OSVURL = getEnv("INTEL_OSV_URL", intelConfig.OSVURL)
+	intelConfig.KEVURL = getEnv("INTEL_KEV_URL", intelConfig.KEVURL)
+	intelConfig.EPSSURL = getEnv("INTEL_EPSS_URL", intelConfig.EPSSURL)
+	intelConfig.Transport = egress.Transport("intel",
+		outbound.AllowURLs(intelConfig.NVDURL, intelConfig.OSVURL, intelConfig.KEVURL, intelConfig.EPSSURL))
+	intelConfig.SyncInterval = getEnvDuration("INTEL_SYNC_INTERVAL", intelConfig.SyncInterval)
+	intelConfig.CheckInterval = getEnvDuration("INTEL_CHECK_INTERVAL", intelConfig.CheckInterval)
+	intelService := intel.NewService(db, intelConfig, logger)
+	if getEnv("INTEL_SYNC", "true") == "true" {
+		go intelService.Start(ctx)
+	}
+
+	// Relay domain events from the outbox to NATS or Kafka
+	eventPublisher := newEventPublisher(logger)
+	defer eventPublisher.Close()
+	relayConfig := events.DefaultRelayConfig()
+	relayConfig.BatchSize = getEnvInt("EVENT_RELAY_BATCH_SIZE", relayConfig.BatchSize)
+	relayConfig.Interval = getEnvDuration("EVENT_RELAY_INTERVAL", relayConfig.Interval)
+	relayConfig.Retention = getEnvDuration("EVENT_OUTBOX_RETENTION", relayConfig.Retention)
+	go events.NewRelay(db, eventPublisher, relayConfig, logger).Start(ctx)
+
+	// Start WebSocket hub
+	hub := realtime.NewHub(logger)
+	replayConfig := realtime.DefaultReplayConfig()
+	replayConfig.BufferSize = int64(getEnvInt("WS_REPLAY_BUFFER_SIZE", int(replayConfig.BufferSize)))
+	replayConfig.Retention = getEnvDuration("WS_REPLAY_RETENTION", replayConfig.Retention)
+	hub.SetReplayStore(realtime.NewReplayStore(redisClient, replayConfig))
+	livenessConfig := realtime.DefaultLivenessConfig()
+	livenessConfig.PingInterval = getEnvDuration("WS_PING_INTERVAL", livenessConfig.PingInterval)
+	livenessConfig.PongTimeout = getEnvDuration("WS_PONG_TIMEOUT", livenessConfig.PongTimeout)
+	livenessConfig.ReapInterval = getEnvDuration("WS_REAP_INTERVAL", livenessConfig.ReapInterval)
+	if livenessConfig.PongTimeout <= livenessConfig.PingInterval {
+		logger.Fatal("WS_PONG_TIMEOUT must exceed WS_PING_INTERVAL")
+	}
+	hub.SetLiveness(livenessConfig)
+	// Raw tool output from running scans, on scan_console:<scan id> topics
+	consoleConfig := console.DefaultConfig()
+	consoleConfig.RetainBytes = getEnvInt("SCAN_CONSOLE_RETAIN_BYTES", consoleConfig.RetainBytes)
+	consoleConfig.Retention = getEnvDuration("SCAN_CONSOLE_RETENTION", consoleConfig.Retention)
+	consoleConfig.MaxLineBytes = getEnvInt("SCAN_CONSOLE_MAX_LINE_BYTES", consoleConfig.MaxLineBytes)
+	consoleConfig.RateBytes = getEnvInt("SCAN_CONSOLE_RATE_BYTES", consoleConfig.RateBytes)
+	consoleConfig.BurstBytes = getEnvInt("SCAN_CONSOLE_BURST_BYTES", consoleConfig.BurstBytes)
+	if consoleConfig.BurstBytes < consoleConfig.MaxLineBytes {
+		logger.Fatal("SCAN_CONSOLE_BURST_BYTES must be at least SCAN_CONSOLE_MAX_LINE_BYTES")
+	}
+	consoleService := console.NewService(redisClient, db, roleStore, hub, consoleConfig, logger)
+	hub.AddTopicSource(console.TopicPrefix, consoleService)
+	go consoleService.Start(ctx)
+	go hub.Run(ctx)
+	// Close connections whose TCP connection died without a close frame
+	go hub.StartReaper(ctx)
+	wsHandler := realtime.NewHandler(hub, logger)
+
+	// Push scan progress and findings written by other services (workers,
+	// the brain) to WebSocket clients via Postgres LISTEN/NOTIFY
+	if getEnv("REALTIME_DB_BRIDGE", "true") == "true" {
+		bridgeConfig := realtime.DefaultDBBridgeConfig()
+		bridgeConfig.QueueSize = getEnvInt("REALTIME_DB_BRIDGE_QUEUE", bridgeConfig.QueueSize)
+		go realtime.NewDBBridge(dsn, hub, bridgeConfig, logger).Run(ctx)
+	}
+
+	// Scan events published by workers go through a Redis stream; each
+	// consumer group acknowledges what it handled, so events outlive
+	// restarts. Writes made while publishing to the stream skip the NOTIFY
+	// triggers above.
+	scanEventConfig := scanevents.DefaultConfig()
+	scanEventConfig.Stream = getEnv("SCAN_EVENTS_STREAM", scanEventConfig.Stream)
+	scanEventConfig.MaxLen = int64(getEnvInt("SCAN_EVENTS_MAX_LEN", int(scanEventConfig.MaxLen)))
+	scanEventConfig.ClaimIdle = getEnvDuration("SCAN_EVENTS_CLAIM_IDLE", scanEventConfig.ClaimIdle)
+	scanEventConfig.MaxDeliveries = int64(getEnvInt("SCAN_EVENTS_MAX_DELIVERIES", int(scanEventConfig.MaxDeliveries)))
+	scanEventConfig.Instance = os.Getenv("SCAN_EVENTS_INSTANCE")
+	if scanEventConfig.Instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger.Fatal("SCAN_EVENTS_INSTANCE is required when the hostname is unknown", zap.Error(err))
+		}
+		scanEventConfig.Instance = hostname
+	}
+	scanEvents := scanevents.NewStream(redisClient, scanEventConfig, logger)
+	scanEvents.SubscribeEach("broadcaster", scanevents.Broadcast(hub))
+	scanEvents.Subscribe("persister", scanevents.NewPersister(db, logger).Handle)
+	scanNotifier := scanevents.NewNotifier(db, escalationService, logger)
+	scanNotifier.AddFailureNotifier(notify.ScanFailureEmailer(mailer, prefsService))
+	scanEvents.Subscribe("notifier", scanNotifier.Handle)
+	go scanEvents.Start(ctx)
+
+	// Maintenance mode: regular users get 503s, platform admins keep working,
+	// WebSocket clients are told when it starts and ends, and queued scans
+	// wait until it is over
+	maintenanceService := maintenance.NewService(redisClient, logger)
+	maintenanceService.OnChange(func(state maintenance.State) {
+		status := realtime.SystemStatusEvent{Status: "operational"}
+		if state.Active {
+			status = realtime.SystemStatusEvent{
+				Status:      "maintenance",
+				Message:     state.Message,
+				Maintenance: true,
+				EndsAt:      state.EndsAt,
+			}
+		}
+		wsHandler.BroadcastSystemStatus(status)
+	})
+	go maintenanceService.Watch(ctx, getEnvDuration("MAINTENANCE_POLL_INTERVAL", 5*time.Second))
+	authService.SetLoginGate(func(ctx context.Context, userID string) error {
+		state := maintenanceService.Current()
+		if !state.Active {
+			return nil
+		}
+		if isAdmin, err := authService.IsPlatformAdmin(ctx, userID); err != nil || !isAdmin {
+			return &maintenance.ActiveError{State: state}
+		}
+		return nil
+	})
+	maintenanceBypass := func(c *gin.Context) bool {
+		if c.GetString("impersonator_id") != "" {
+			return false
+		}
+		isAdmin, err := authService.IsPlatformAdmin(c.Request.Context(), c.GetString("user_id"))
+		return err == nil && isAdmin
+	}
+
+	// Dependency checks for the readiness probe and the public status page,
+	// which records their history and caches its responses in Redis
+	healthChecker := health.NewChecker(getEnvDuration("HEALTH_CHECK_TIMEOUT", 3*time.Second))
+	healthChecker.Add(health.Check{Name: "database", Critical: true, Slow: time.Second, Run: db.PingContext})
+	if db.HasReplica() {
+		healthChecker.Add(health.Check{Name: "database_replica", Run: db.CheckReplica})
+	}
+	// Redis is only critical when the gateway cannot run without it
+	healthChecker.Add(health.Check{Name: "redis", Critical: !allowDegraded, Slow: 500 * time.Millisecond, Run: func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	}})
+	healthChecker.Add(health.Check{Name: "reports", Slow: 2 * time.Second, Run: reportRouter.Health})
+	statusConfig := status.DefaultConfig()
+	statusConfig.SampleInterval = getEnvDuration("STATUS_SAMPLE_INTERVAL", statusConfig.SampleInterval)
+	statusConfig.CacheTTL = getEnvDuration("STATUS_CACHE_TTL", statusConfig.CacheTTL)
+	statusConfig.UptimeCacheTTL = getEnvDuration("STATUS_UPTIME_CACHE_TTL", statusConfig.UptimeCacheTTL)
+	statusConfig.RetentionDays = getEnvInt("STATUS_RETENTION_DAYS", statusConfig.RetentionDays)
+	statusService := status.NewService(db, redisClient, healthChecker, maintenanceService, statusConfig, logger)
+	go statusService.Start(ctx)
+
+	// Start audit anomaly detector (alerts org admins over WebSocket, and Slack
+	// when a bot token is configured)
+	anomalyDetector := audit.NewAnomalyDetector(db, auditLogger, audit.DefaultAnomalyConfig(), logger)
+	anomalyDetector.AddNotifier(func(userID string, anomaly audit.Anomaly) {
+		wsHandler.BroadcastAlert(userID, realtime.AlertEvent{
+			ID:         anomaly.AlertID(),
+			Severity:   "high",
+			Kind:       anomaly.Kind,
+			UserID:     anomaly.UserID,
+			Target:     anomaly.Target,
+			Count:      anomaly.Count,
+			Details:    anomaly.Details,
+			DetectedAt: anomaly.DetectedAt,
+		})
+	})
+	if slackClient != nil {
+		anomalyDetector.AddNotifier(api.SlackAlertNotifier(db, deliveryService, logger))
+	}
+	go anomalyDetector.Start(ctx)
+
+	// Role elevations, custom role grants and API token scope expansions are
+	// recorded and sent to the organization's owners and admins
+	privilegeService := privileges.NewService(db, roleStore, auditLogger, logger)
+	privilegeService.AddNotifier(notify.PrivilegeChangeEmailer(mailer, logger))
+	privilegeService.AddNotifier(func(recipient privileges.Recipient, change privileges.Change) {
+		actorID := ""
+		if change.ActorID != nil {
+			actorID = *change.ActorID
+		}
+		wsHandler.BroadcastAlert(recipient.UserID, realtime.AlertEvent{
+			Severity: change.Severity,
+			Kind:     "privilege_" + change.Kind,
+			UserID:   actorID,
+			Target:   change.SubjectName,
+			Count:    len(change.Added),
+			Details: map[string]interface{}{
+				"change_id":       change.ID,
+				"organization_id": change.OrganizationID,
+				"subject_type":    change.SubjectType,
+				"subject_id":      change.SubjectID,
+				"added":           []string(change.Added),
+				"removed":         []string(change.Removed),
+			},
+			DetectedAt: change.CreatedAt,
+		})
+	})
+
+	// Scanner worker fleet: mark workers that stop sending heartbeats offline
+	// and re-queue their jobs
+	serviceToken := os.Getenv("INTERNAL_SERVICE_TOKEN")
+	workerConfig := workers.DefaultConfig()
+	workerConfig.HeartbeatInterval = getEnvDuration("WORKER_HEARTBEAT_INTERVAL", workerConfig.HeartbeatInterval)
+	workerConfig.StaleAfter = getEnvDuration("WORKER_STALE_AFTER", workerConfig.StaleAfter)
+	workerConfig.MaxRequeues = getEnvInt("WORKER_MAX_REQUEUES", workerConfig.MaxRequeues)
+	workerRegistry := workers.NewRegistry(db, workerConfig, logger)
+	go workerRegistry.StartReaper(ctx)
+
+	// Alert users to sign-ins from new devices or countries by email and WebSocket
+	authService.AddLoginNotifier(notify.LoginAlertEmailer(mailer, prefsService, publicURL, logger))
+	authService.AddLoginNotifier(func(alert auth.LoginAlert) {
+		details := map[string]interface{}{
+			"session_id":  alert.SessionID,
+			"ip_address":  alert.IPAddress,
+			"device":      alert.Device,
+			"new_device":  alert.NewDevice,
+			"new_country": alert.NewCountry,
+		}
+		if alert.Geo != nil {
+			details["geo"] = alert.Geo
+		}
+		auditLogger.LogSecurityEvent(context.Background(), alert.UserID, "suspicious_login", "", "medium", details)
+		wsHandler.BroadcastAlert(alert.UserID, realtime.AlertEvent{
+			Severity:   "medium",
+			Kind:       "new_login",
+			UserID:     alert.UserID,
+			Count:      1,
+			Details:    details,
+			DetectedAt: alert.OccurredAt,
+		})
+	})
+
+	// Email verification links; verified addresses auto-join organizations by domain
+	authService.AddEmailVerificationNotifier(notify.EmailVerificationEmailer(mailer, publicURL, logger))
+	domainService := domains.NewService(db, roleStore, nil, logger)
+
+	// Password-less sign-in by emailed single-use links, for members of
+	// organizations that allow it
+	magicLinkConfig := auth.DefaultMagicLinkConfig()
+	magicLinkConfig.Enabled = getEnv("MAGIC_LINK_ENABLED", "true") == "true"
+	magicLinkConfig.TTL = getEnvDuration("MAGIC_LINK_TTL", magicLinkConfig.TTL)
+	magicLinkConfig.Window = getEnvDuration("MAGIC_LINK_RATE_WINDOW", magicLinkConfig.Window)
+	magicLinkConfig.PerEmail = getEnvInt("MAGIC_LINK_MAX_PER_EMAIL", magicLinkConfig.PerEmail)
+	magicLinkConfig.PerIP = getEnvInt("MAGIC_LINK_MAX_PER_IP", magicLinkConfig.PerIP)
+	authService.SetMagicLinkConfig(magicLinkConfig)
+	authService.AddMagicLinkNotifier(notify.MagicLinkEmailer(mailer, publicURL, logger))
+
+	// Tell users when a login hit their concurrent session limit
+	authService.AddSessionLimitNotifier(notify.SessionLimitEmailer(mailer, prefsService, logger))
+	authService.AddSessionLimitNotifier(func(alert auth.SessionLimitAlert) {
+		details := map[string]interface{}{
+			"max_sessions":        alert.MaxSessions,
+			"action":              alert.Action,
+			"revoked_session_ids": alert.RevokedIDs,
+			"ip_address":          alert.IPAddress,
+			"device":              alert.Device,
+		}
+		auditLogger.LogSecurityEvent(context.Background(), alert.UserID, "session_limit_reached", "", "low", details)
+		wsHandler.BroadcastAlert(alert.UserID, realtime.AlertEvent{
+			Severity:   "low",
+			Kind:       "session_limit",
+			UserID:     alert.UserID,
+			Count:      1,
+			Details:    details,
+			DetectedAt: alert.OccurredAt,
+		})
+	})
+
+	authService.AddNetworkPolicyNotifier(func(event auth.NetworkPolicyEvent) {
+		params := audit.LogParams{
+			UserID:         event.UserID,
+			OrganizationID: event.OrganizationID,
+			Action:         "network_policy_blocked",
+			IPAddress:      event.IPAddress,
+			Status:         "failure",
+			Severity:       "medium",
+			Details: map[string]interface{}{
+				"stage":  event.Stage,
+				"method": event.Method,
+				"path":   event.Path,
+			},
+		}
+		if event.TokenID != "" {
+			params.ResourceType, params.ResourceID = "org_api_token", event.TokenID
+		}
+		if event.BreakGlass {
+			params.Action, params.Status, params.Severity = "network_policy_break_glass", "success", "critical"
+			params.Details["reason"] = event.Reason
+			params.Details["expires_at"] = event.ExpiresAt
+		}
+		auditLogger.Log(context.Background(), params)
+	})
+
+	// Every task kind is registered by now
+	go taskService.Start(ctx)
+
 	// Set Gin mode
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Error and validation messages in the caller's language
+	i18n.UseJSONFieldNames()
+	translator := i18n.NewTranslator(i18nRegistry, prefsService.Locale)
+
+	// Request body limits: report generation takes scan results and uploads
+	// take files, everything else gets the defaults
+	defaultPayload := payload.DefaultLimits()
+	payloadGuard := payload.NewGuard(payload.Limits{
+		MaxBytes:       int64(getEnvInt("MAX_REQUEST_BODY_BYTES", int(defaultPayload.MaxBytes))),
+		MaxDepth:       getEnvInt("MAX_JSON_DEPTH", defaultPayload.MaxDepth),
+		MaxArrayLength: getEnvInt("MAX_JSON_ARRAY_LENGTH", defaultPayload.MaxArrayLength),
+	}, api.PayloadRouteGroups())
+	payloadGuard.SetGroup("reports", payload.Limits{
+		MaxBytes:       int64(getEnvInt("MAX_REPORT_REQUEST_BODY_BYTES", 16<<20)),
+		MaxDepth:       getEnvInt("MAX_JSON_DEPTH", defaultPayload.MaxDepth),
+		MaxArrayLength: getEnvInt("MAX_REPORT_JSON_ARRAY_LENGTH", 100000),
+	})
+	payloadGuard.SetGroup("uploads", payload.Limits{
+		// Evidence files and their multipart envelope
+		MaxBytes: uploads.MaxEvidenceBytes + 1<<20,
+	})
+	payloadGuard.SetGroup("imports", payload.Limits{
+		MaxBytes: imports.MaxFileBytes + 1<<20,
+	})
+
 	// Create router
 	router := gin.Default()
+	// gin believes forwarding headers from any client unless told otherwise
+	proxyCIDRs := make([]string, 0, len(trustedProxies))
+	for _, network := range trustedProxies {
+		proxyCIDRs = append(proxyCIDRs, network.String())
+	}
+	if err := router.SetTrustedProxies(proxyCIDRs); err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES", zap.Error(err))
+	}
+	router.Use(logging.RequestMiddleware(logger), metrics.PrometheusMiddleware(), translator.Middleware(), payloadGuard.Middleware())
+	// Signing in and out only needs the database
+	router.Use(boot.ReadOnlyMiddleware(api.APIBasePath + "/auth/"))
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":    "healthy",
 			"timestamp": time.Now().UTC(),
-			"version":   "0.1.0",
+			"version":   version,
+			"db_pool":   db.PoolStats(),
 		})
 	})
 
+	// Readiness probe: 503 while a critical dependency is down
+	router.GET("/ready", healthChecker.Handler())
+
+	// Per-component startup status, and whether the gateway is degraded
+	router.GET("/startup", boot.Handler())
+
+	// API versions: each is a route group under its prefix, selected by path
+	// or by Accept header on unversioned paths
+	deprecatedRoutes, err := api.DeprecatedRoutes()
+	if err != nil {
+		logger.Fatal("Invalid route deprecation", zap.Error(err))
+	}
+	apiVersions, err := apiversion.New(api.APIVersions, deprecatedRoutes)
+	if err != nil {
+		logger.Fatal("Invalid API versions", zap.Error(err))
+	}
+
 	// API v1 routes
-	v1 := router.Group("/v1")
+	v1 := apiVersions.Group(router, "v1")
+
+	// Admin listener for metrics, profiling and platform administration,
+	// never exposed publicly: callers need a client certificate (with mTLS
+	// configured) or an allowed source address
+	adminNetworks, err := adminserver.ParseNetworks(os.Getenv("ADMIN_ALLOWED_CIDRS"))
+	if err != nil {
+		logger.Fatal("Invalid ADMIN_ALLOWED_CIDRS", zap.Error(err))
+	}
+	adminConfig := adminserver.Config{
+		Addr:            getEnv("ADMIN_ADDR", adminserver.DefaultAddr),
+		AllowedNetworks: adminNetworks,
+	}
+	if internalTLS != nil && os.Getenv("ADMIN_MTLS") != "false" {
+		adminConfig.TLS = internalTLS
+		// Only admin certificates are accepted, never merely any certificate
+		// from the CA: workers and the brain hold those too. Without
+		// MTLS_IDENTITIES a certificate's identity is its first SAN or its
+		// common name.
+		for _, identity := range strings.Split(getEnv("ADMIN_TLS_IDENTITIES", "admin"), ",") {
+			if identity = strings.TrimSpace(identity); identity != "" {
+				adminConfig.Identities = append(adminConfig.Identities, identity)
+			}
+		}
+		if len(adminConfig.Identities) == 0 {
+			logger.Fatal("ADMIN_TLS_IDENTITIES names no identity")
+		}
+	}
+	adminServer, err := adminserver.New(adminConfig, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize admin listener", zap.Error(err))
+	}
+	// pprof, stored CPU/heap profiles and runtime statistics
+	diagnosticsService := diagnostics.NewService(db, redisClient, artifactStore, logger)
+	diagnosticsService.Mount(adminServer.Router())
+
+	// Support bundles gather version, sanitized configuration, recent errors,
+	// migration status, health and metrics into one archive
+	supportBundles := supportbundle.New(supportbundle.Sources{
+		Version:       version,
+		MigrationsDir: getEnv("MIGRATIONS_DIR", defaultMigrationsDir),
+		DB:            db,
+		Health:        healthChecker,
+		Bootstrap:     boot,
+		Logs:          recentLogs,
+		Runtime:       func() interface{} { return diagnosticsService.Runtime() },
+		Metrics:       promhttp.Handler(),
+	}, bundleRedactor, logger)
+	adminServer.Router().GET("/debug/support-bundle", supportBundles.Handler())
+
+	// Internal listener for service-to-service calls, requiring client certificates
+	internalRouter := gin.New()
+	internalRouter.Use(gin.Recovery(), logging.RequestMiddleware(logger), metrics.PrometheusMiddleware())
+	var requestAudit *audit.RequestAuditConfig
+	if getEnv("AUDIT_REQUESTS", "true") == "true" {
+		config := audit.DefaultRequestAuditConfig()
+		config.RecordBodies = os.Getenv("AUDIT_REQUEST_BODIES") == "true"
+		// Login already records a richer login_attempt/login_success entry
+		config.SkipRoutes = []string{"POST " + api.APIBasePath + "/auth/login"}
+		requestAudit = &config
+		v1.Use(auditLogger.RequestMiddleware(config))
+	}
 	{
 		authHandler := api.NewAuthHandler(authService, auditLogger)
-		reportHandler := api.NewReportHandler(brainClient, logger)
-		orgHandler := api.NewOrganizationHandler(db, logger)
-		scanAuthHandler := api.NewScanAuthorizationHandler(db, logger)
-		emergencyHandler := api.NewEmergencyHandler(db, redisClient, auditLogger, logger)
+		impersonationHandler := api.NewImpersonationHandler(authService, auditLogger, logger)
+		flagHandler := api.NewFlagHandler(flagService, authService, auditLogger, logger)
+		legalHoldHandler := api.NewLegalHoldHandler(legalHoldService, authService, auditLogger, logger)
+		adminUserHandler := api.NewAdminUserHandler(authService, auditLogger, logger)
+		workerHandler := api.NewWorkerHandler(workerRegistry, authService, logger)
+		taskHandler := api.NewTaskHandler(taskService, authService, auditLogger, logger)
+		// Report downloads and export links are recorded per downloader;
+		// PDFs are stamped with the downloader's identity by the brain service
+		downloadService := downloads.NewService(db)
+		reportHandler := api.NewReportHandler(db, reportService, artifactStore, policyEngine, downloadService, auditLogger, logger)
+		if getEnv("REPORT_WATERMARK_PDF", "true") == "true" {
+			reportHandler.SetWatermarker(brainClient)
+		}
+		localeHandler := api.NewLocaleHandler(prefsService, translator, logger)
+		preferencesHandler := api.NewPreferencesHandler(prefsService, logger)
+		digestHandler := api.NewDigestHandler(digestService, logger)
+		analysisHandler := api.NewAnalysisHandler(db, reportService, brainClient, policyEngine, hub, logger)
+		exportHandler := api.NewExportHandler(exportService, roleStore, downloadService, auditLogger, logger)
+		orgHandler := api.NewOrganizationHandler(repos, repository.NewUnitOfWork(db), roleStore, privilegeService, logger)
+		if tenancyService != nil {
+			orgHandler.SetTenancy(tenancyService)
+		}
+		roleHandler := api.NewRoleHandler(roleStore, privilegeService, auditLogger, logger)
+		accessHandler := api.NewAccessHandler(roleStore, logger)
+		reportTemplateHandler := api.NewReportTemplateHandler(db, roleStore, auditLogger, logger)
+		reportScheduleHandler := api.NewReportScheduleHandler(db, roleStore, auditLogger, logger)
+		policyHandler := api.NewPolicyHandler(roleStore, policyEngine, auditLogger, logger)
+		approvalService := approvals.NewService(db, roleStore, hub, mailer, logger)
+		scanHandler := api.NewScanHandler(db, redisClient, policyEngine, approvalService, scanWindowService, estimation.NewService(db, logger), grantService, auditLogger, logger)
+		scanGrantHandler := api.NewScanGrantHandler(grantService, auditLogger, logger)
+		scanApprovalHandler := api.NewScanApprovalHandler(approvalService, roleStore, auditLogger, logger)
+		scanWindowHandler := api.NewScanWindowHandler(scanWindowService, roleStore, auditLogger, logger)
+		importHandler := api.NewImportHandler(importService, roleStore, auditLogger, logger)
+		// Scan and alert commands over the WebSocket connection
+		alertStore := audit.NewAlertStore(db)
+		hub.SetCommander(api.NewScanCommander(scanHandler, roleStore, alertStore, redisClient, auditLogger, logger))
+		findingHandler := api.NewFindingHandler(db, roleStore, uploadService, hub, auditLogger, logger)
+		suppressionHandler := api.NewSuppressionHandler(db, roleStore, auditLogger, logger)
+		intelHandler := api.NewIntelHandler(db, intelService, roleStore, logger)
+		complianceHandler := api.NewComplianceHandler(db, roleStore, logger)
+		slackHandler := api.NewSlackHandler(db, redisClient, roleStore, alertStore, scanHandler, maintenanceService, slackClient, getEnv("SLACK_DEFAULT_SCAN_TYPE", "web"), auditLogger, logger)
+		deliveryHandler := api.NewDeliveryHandler(db, roleStore, deliveryService, auditLogger, logger)
+		statsHandler := api.NewStatsHandler(stats.NewService(db), roleStore, logger)
+		scanAuthHandler := api.NewScanAuthorizationHandler(db, repository.NewAuthorizationRepo(db, dataCipher), logger)
+		maintenanceHandler := api.NewMaintenanceHandler(maintenanceService, authService, auditLogger, logger)
+		statusHandler := api.NewStatusHandler(statusService, logger)
+		emergencyHandler := api.NewEmergencyHandler(db, redisClient, auditLogger, escalationService, logger)
+		sessionLimitHandler := api.NewSessionLimitHandler(authService, roleStore, auditLogger, logger)
+		uploadHandler := api.NewUploadHandler(uploadService, roleStore, logger)
+		networkPolicyHandler := api.NewNetworkPolicyHandler(authService, roleStore, auditLogger, logger)
+		orgTokenHandler := api.NewOrgTokenHandler(authService, roleStore, privilegeService, auditLogger, logger)
+		privilegeChangeHandler := api.NewPrivilegeChangeHandler(privilegeService, roleStore, logger)
+		domainHandler := api.NewDomainHandler(domainService, authService, roleStore, auditLogger, logger)
+		magicLinkHandler := api.NewMagicLinkHandler(authService, roleStore, auditLogger, logger)
+		billingHandler := api.NewBillingHandler(billingService, roleStore, logger)
+		graphqlHandler := api.NewGraphQLHandler(db, repos, roleStore, graphql.Limits{
+			MaxDepth:      getEnvInt("GRAPHQL_MAX_DEPTH", graphql.DefaultLimits().MaxDepth),
+			MaxComplexity: getEnvInt("GRAPHQL_MAX_COMPLEXITY", graphql.DefaultLimits().MaxComplexity),
+		}, logger)
+		settingsHandler := api.NewSettingsHandler(roleStore, branding.NewService(db, artifactStore, uploadService, logger), auditLogger, logger)
+		escalationHandler := api.NewEscalationHandler(db, roleStore, escalationService, auditLogger, logger)
+		auditHandler, err := api.NewAuditHandler(repos, roleStore, auditLogger.Stream(), anchorService, auditLogger, logger)
+		if err != nil {
+			logger.Fatal("Failed to initialize audit handler", zap.Error(err))
+		}
+		auditHandler.SetSummaryCache(redisClient, getEnvDuration("AUDIT_SUMMARY_CACHE_TTL", 5*time.Minute))
+
+		// Concurrency limits for expensive routes: past them requests queue
+		// briefly, then are shed with 503 (429 for a user over their share)
+		reportLimiter := newLimiter("reports", "REPORT", loadshed.Config{MaxConcurrent: 8, MaxQueue: 16, QueueTimeout: 15 * time.Second, MaxPerUser: 2})
+		analysisLimiter := newLimiter("analysis", "ANALYSIS", loadshed.Config{MaxConcurrent: 8, MaxQueue: 8, QueueTimeout: 5 * time.Second, MaxPerUser: 1})
+		exportLimiter := newLimiter("exports", "EXPORT", loadshed.Config{MaxConcurrent: 4, MaxQueue: 8, QueueTimeout: 10 * time.Second, MaxPerUser: 1})
+
+		// Bot protection: past the per-IP attempt thresholds, login and
+		// registration need a solved CAPTCHA or proof of work
+		challengeGuard := challenge.NewGuard(newChallengeProvider(redisClient, logger), redisClient, challenge.Config{
+			Window: getEnvDuration("CHALLENGE_WINDOW", 15*time.Minute),
+			Thresholds: map[string]int{
+				"login":      getEnvInt("CHALLENGE_LOGIN_THRESHOLD", 5),
+				"register":   getEnvInt("CHALLENGE_REGISTER_THRESHOLD", 3),
+				"magic_link": getEnvInt("CHALLENGE_MAGIC_LINK_THRESHOLD", 3),
+			},
+		}, logger)
+		challengeHandler := api.NewChallengeHandler(challengeGuard, logger)
+
+		// API documentation (served publicly as /api/v1/openapi.json and /api/docs)
+		v1.GET("/openapi.json", openapi.SpecHandler(api.OpenAPIDocument()))
+		router.GET("/docs", openapi.SwaggerUIHandler("/api"+api.APIBasePath+"/openapi.json"))
 
 		// Public routes
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
+			auth.GET("/challenge", challengeHandler.GetChallenge)
+			auth.POST("/register", maintenanceService.Middleware(nil), challengeGuard.Require("register"), authHandler.Register)
+			auth.POST("/login", challengeGuard.Require("login"), authHandler.Login)
+			auth.POST("/magic-link", challengeGuard.Require("magic_link"), magicLinkHandler.RequestMagicLink)
+			auth.POST("/magic-link/login", challengeGuard.Require("login"), magicLinkHandler.LoginWithMagicLink)
 			auth.POST("/accept-terms", authHandler.AcceptTerms)
+			auth.GET("/terms", authHandler.GetTerms)
+			auth.GET("/sessions/revoke", authHandler.RevokeSessionByLink)
+			auth.POST("/sessions/revoke", authHandler.RevokeSessionByLink)
+			auth.GET("/verify-email", domainHandler.VerifyEmail)
+			auth.POST("/verify-email", domainHandler.VerifyEmail)
+		}
+
+		v1.GET("/maintenance", maintenanceHandler.GetStatus)
+
+		// Public status page
+		v1.GET("/status", statusHandler.GetStatus)
+		v1.GET("/status/components", statusHandler.GetComponents)
+		v1.GET("/status/uptime", statusHandler.GetUptime)
+
+		// One-click acknowledgement from escalation pages (the signed token authenticates)
+		v1.GET("/escalations/acknowledge", escalationHandler.AcknowledgeByLink)
+		v1.POST("/escalations/acknowledge", escalationHandler.AcknowledgeByLink)
+
+		// Clients review, approve or revoke scan grants through their link
+		// (the signed token authenticates)
+		v1.GET("/scan-grants/review", scanGrantHandler.ReviewGrant)
+		v1.POST("/scan-grants/review", scanGrantHandler.DecideGrant)
+		v1.POST("/scan-grants/review/revoke", scanGrantHandler.RevokeGrantByLink)
+
+		// Slack app (requests are authenticated by Slack's signature)
+		if signingSecret := getSecret("SLACK_SIGNING_SECRET", ""); signingSecret != "" {
+			slackRoutes := v1.Group("/integrations/slack")
+			slackRoutes.Use(slack.VerifyRequests([]byte(signingSecret), logger))
+			{
+				slackRoutes.POST("/commands", slackHandler.Command)
+				slackRoutes.POST("/interactions", slackHandler.Interaction)
+			}
+		}
+
+		// Signed artifact downloads (the signature authenticates the request);
+		// cloud backends sign URLs against the bucket instead
+		if localStore != nil {
+			v1.GET("/downloads/*key", localStore.ServeSigned())
+		}
+
+		// Scanner workers: on the mTLS internal listener when it is configured,
+		// otherwise on the public one with the internal service token
+		var workerRoutes *gin.RouterGroup
+		if internalTLS != nil {
+			workerRoutes = internalRouter.Group(api.APIBasePath + "/workers")
+			workerRoutes.Use(internalTLS.RequireIdentity(logger, getEnv("WORKER_TLS_IDENTITY", "worker")))
+		} else {
+			workerRoutes = v1.Group("/workers")
+			workerRoutes.Use(workers.RequireServiceToken(serviceToken, logger))
+		}
+		{
+			workerRoutes.POST("/register", workerHandler.Register)
+			workerRoutes.POST("/:id/heartbeat", workerHandler.Heartbeat)
+		}
+
+		// Platform administration, on the admin listener only
+		adminAPI := apiVersions.Group(adminServer.Router(), "v1")
+		adminAPI.Use(authService.AuthMiddleware(), auditLogger.OrganizationMiddleware(), auditLogger.ImpersonationMiddleware(), logging.IdentityMiddleware(logger))
+		if requestAudit != nil {
+			adminAPI.Use(auditLogger.RequestMiddleware(*requestAudit))
+		}
+		{
+			// Support impersonation (platform admins; checked in the handler)
+			adminAPI.POST("/admin/impersonations", impersonationHandler.StartImpersonation)
+			adminAPI.GET("/admin/impersonations", impersonationHandler.ListImpersonations)
+			adminAPI.DELETE("/admin/impersonations/:id", impersonationHandler.StopImpersonation)
+
+			// Feature flags (platform admins; checked in the handler)
+			adminAPI.GET("/admin/flags", flagHandler.ListFlags)
+			adminAPI.PUT("/admin/flags/:key", flagHandler.SetFlag)
+			adminAPI.DELETE("/admin/flags/:key", flagHandler.DeleteFlag)
+			adminAPI.PUT("/admin/flags/:key/overrides", flagHandler.SetOverride)
+			adminAPI.DELETE("/admin/flags/:key/overrides/:scope/:target_id", flagHandler.DeleteOverride)
+			adminAPI.GET("/admin/legal-holds", legalHoldHandler.ListHolds)
+			adminAPI.POST("/admin/legal-holds", legalHoldHandler.PlaceHold)
+			adminAPI.POST("/admin/legal-holds/:id/release", legalHoldHandler.ReleaseHold)
+
+			// User accounts (platform admins; checked in the handler)
+			adminAPI.GET("/admin/users", adminUserHandler.ListUsers)
+			adminAPI.PUT("/admin/users/:id/status", adminUserHandler.SetUserStatus)
+
+			// Maintenance mode (platform admins; checked in the handler)
+			adminAPI.PUT("/admin/maintenance", maintenanceHandler.Enable)
+			adminAPI.DELETE("/admin/maintenance", maintenanceHandler.Disable)
+
+			// Worker fleet (platform admins; checked in the handler)
+			adminAPI.GET("/admin/workers", workerHandler.ListWorkers)
+			adminAPI.GET("/admin/workers/:id", workerHandler.GetWorker)
+
+			// Background tasks (platform admins; checked in the handler)
+			adminAPI.GET("/admin/tasks", taskHandler.ListTasks)
+			adminAPI.GET("/admin/tasks/queues", taskHandler.ListQueues)
+			adminAPI.GET("/admin/tasks/:id", taskHandler.GetTask)
+			adminAPI.POST("/admin/tasks/:id/retry", taskHandler.RetryTask)
+			adminAPI.POST("/admin/tasks/:id/cancel", taskHandler.CancelTask)
+
+			// Per-organization schemas (platform admins; checked in the handler)
+			if tenancyService != nil {
+				tenancyHandler := api.NewTenancyHandler(tenancyService, repos.Orgs, authService, auditLogger, logger)
+				adminAPI.GET("/admin/tenant-schemas", tenancyHandler.ListTenantSchemas)
+				adminAPI.PUT("/admin/organizations/:id/isolation", tenancyHandler.SetIsolation)
+			}
+
+			// Emergency Stop (Owner only)
+			adminAPI.POST("/emergency/stop",
+				rbac.RequireRole(rbac.RoleOwner),
+				emergencyHandler.ActivateEmergencyStop,
+			)
+			adminAPI.POST("/emergency/resume",
+				rbac.RequireRole(rbac.RoleOwner),
+				emergencyHandler.DeactivateEmergencyStop,
+			)
 		}
 
 		// Protected routes
 		protected := v1.Group("")
-		protected.Use(authService.AuthMiddleware())
+		protected.Use(authService.AuthMiddleware(), auditLogger.OrganizationMiddleware(), auditLogger.ImpersonationMiddleware(), logging.IdentityMiddleware(logger), flagService.Middleware(), maintenanceService.Middleware(maintenanceBypass))
 		{
 			protected.POST("/auth/logout", authHandler.Logout)
 			protected.GET("/auth/pulse", authHandler.AuthPulse)
+			protected.POST("/auth/switch-org", authHandler.SwitchOrganization)
+			protected.POST("/auth/verify-email/request", domainHandler.RequestEmailVerification)
+			protected.GET("/auth/sessions", authHandler.ListSessions)
+			protected.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
+			protected.GET("/users/me/security-activity", authHandler.SecurityActivity)
+			protected.GET("/users/me/export", exportHandler.UserExport)
+			protected.GET("/users/me/locale", localeHandler.GetLocale)
+			protected.PUT("/users/me/locale", localeHandler.SetLocale)
+			protected.GET("/users/me/preferences", preferencesHandler.GetPreferences)
+			protected.PATCH("/users/me/preferences", preferencesHandler.UpdatePreferences)
+			protected.GET("/users/me/digest", digestHandler.PreviewDigest)
+
+			// Real-time updates
+			protected.GET("/ws", wsHandler.HandleWebSocket)
 
 			// Organization management
 			protected.POST("/organizations", orgHandler.CreateOrganization)
 			protected.GET("/organizations", orgHandler.ListOrganizations)
 			protected.GET("/organizations/:id", orgHandler.GetOrganization)
 
+			// Organization data export (permission checked in the handler)
+			protected.POST("/organizations/:id/export", exportHandler.RequestOrganizationExport)
+			protected.GET("/organizations/:id/exports/:export_id", exportHandler.GetOrganizationExport)
+
 			// Organization invites (requires permission)
 			protected.POST("/organizations/:id/invite",
-				rbac.RequirePermission(rbac.PermInviteUsers, logger),
+				rbac.RequirePermission(roleStore, rbac.PermInviteUsers, logger),
 				orgHandler.InviteUser,
 			)
 
+			// Custom organization roles (permission checked against the :id organization)
+			protected.GET("/organizations/:id/roles", roleHandler.ListRoles)
+			protected.POST("/organizations/:id/roles", roleHandler.CreateRole)
+			protected.PUT("/organizations/:id/roles/:role_id", roleHandler.UpdateRole)
+			protected.DELETE("/organizations/:id/roles/:role_id", roleHandler.DeleteRole)
+
+			// Concurrent session limits per role ("*" for any role)
+			protected.GET("/organizations/:id/session-limits", sessionLimitHandler.ListLimits)
+			protected.PUT("/organizations/:id/session-limits/:role", sessionLimitHandler.SetLimit)
+			protected.DELETE("/organizations/:id/session-limits/:role", sessionLimitHandler.DeleteLimit)
+			protected.GET("/organizations/:id/uploads", uploadHandler.ListUploads)
+			protected.GET("/organizations/:id/network-policy", networkPolicyHandler.GetPolicy)
+			protected.PUT("/organizations/:id/network-policy", networkPolicyHandler.SetPolicy)
+			protected.GET("/organizations/:id/login-policy", magicLinkHandler.GetLoginPolicy)
+			protected.PUT("/organizations/:id/login-policy", magicLinkHandler.SetLoginPolicy)
+
+			// Scoped organization API tokens (permission checked against the :id organization)
+			protected.GET("/organizations/:id/api-tokens", orgTokenHandler.ListTokens)
+			protected.POST("/organizations/:id/api-tokens", orgTokenHandler.CreateToken)
+			protected.PATCH("/organizations/:id/api-tokens/:token_id", orgTokenHandler.UpdateToken)
+			protected.DELETE("/organizations/:id/api-tokens/:token_id", orgTokenHandler.RevokeToken)
+
+			// Verified email domains and auto-join (permission checked against the :id organization)
+			protected.GET("/organizations/:id/domains", domainHandler.ListDomains)
+			protected.POST("/organizations/:id/domains", domainHandler.AddDomain)
+			protected.POST("/organizations/:id/domains/:domain_id/verify", domainHandler.VerifyDomain)
+			protected.PATCH("/organizations/:id/domains/:domain_id", domainHandler.UpdateDomain)
+			protected.DELETE("/organizations/:id/domains/:domain_id", domainHandler.RemoveDomain)
+
+			// Metered usage for billing
+			protected.GET("/organizations/:id/billing/usage", billingHandler.GetUsage)
+
+			// Dashboard queries over GraphQL (permissions checked per field)
+			protected.POST("/graphql", graphqlHandler.Query)
+			protected.GET("/graphql/schema", graphqlHandler.Schema)
+
+			// Scan types that need approval before they start
+			protected.GET("/organizations/:id/scan-approval-rules", scanApprovalHandler.ListRules)
+			protected.PUT("/organizations/:id/scan-approval-rules/:scan_type", scanApprovalHandler.SetRule)
+			protected.DELETE("/organizations/:id/scan-approval-rules/:scan_type", scanApprovalHandler.DeleteRule)
+			protected.GET("/organizations/:id/scan-windows", scanWindowHandler.ListWindows)
+			protected.POST("/organizations/:id/scan-windows", scanWindowHandler.CreateWindow)
+			protected.DELETE("/organizations/:id/scan-windows/:window_id", scanWindowHandler.DeleteWindow)
+			protected.GET("/organizations/:id/scan-imports", importHandler.ListImports)
+			protected.POST("/organizations/:id/scan-imports", importHandler.CreateImport)
+			protected.GET("/organizations/:id/scan-imports/:import_id", importHandler.GetImport)
+
+			// Dashboard statistics
+			// Branding and white-labeling
+			protected.GET("/organizations/:id/settings", settingsHandler.GetSettings)
+			protected.PUT("/organizations/:id/settings", settingsHandler.UpdateSettings)
+			protected.PUT("/organizations/:id/settings/logo", settingsHandler.UploadLogo)
+			protected.DELETE("/organizations/:id/settings/logo", settingsHandler.DeleteLogo)
+
+			protected.GET("/organizations/:id/stats", statsHandler.GetOrganizationStats)
+
+			// Finding triage (permission checked against the :id organization)
+			protected.GET("/organizations/:id/findings/export", findingHandler.ExportFindings)
+			protected.GET("/organizations/:id/findings/:finding_id/comments", findingHandler.ListComments)
+			protected.POST("/organizations/:id/findings/:finding_id/comments", findingHandler.CreateComment)
+			protected.PUT("/organizations/:id/findings/:finding_id/assignment", findingHandler.AssignFinding)
+			protected.POST("/findings:batchUpdate",
+				rbac.RequirePermission(roleStore, rbac.PermTriageFinding, logger),
+				findingHandler.BatchUpdate,
+			)
+			protected.PUT("/organizations/:id/findings/:finding_id/status", findingHandler.UpdateStatus)
+			protected.GET("/organizations/:id/findings/:finding_id/history", findingHandler.StatusHistory)
+			protected.GET("/organizations/:id/findings/:finding_id/evidence", findingHandler.ListEvidence)
+			protected.POST("/organizations/:id/findings/:finding_id/evidence", findingHandler.UploadEvidence)
+			protected.PUT("/organizations/:id/findings/:finding_id/watch", findingHandler.Watch)
+			protected.DELETE("/organizations/:id/findings/:finding_id/watch", findingHandler.Unwatch)
+
+			// Suppression rules for known false positives
+			protected.GET("/organizations/:id/suppression-rules", suppressionHandler.ListRules)
+			protected.POST("/organizations/:id/suppression-rules", suppressionHandler.CreateRule)
+			protected.DELETE("/organizations/:id/suppression-rules/:rule_id", suppressionHandler.DeleteRule)
+
+			// Vulnerability intelligence (NVD/OSV, EPSS, CISA KEV)
+			protected.GET("/intel/cves", intelHandler.ListCVEs)
+			protected.GET("/intel/cves/:cve_id", intelHandler.GetCVE)
+			protected.GET("/intel/status", intelHandler.SyncStatus)
+			protected.GET("/organizations/:id/findings/:finding_id/intel", intelHandler.FindingIntel)
+
+			// Compliance mapping (PCI DSS, ISO 27001, NIST CSF)
+			protected.GET("/compliance/frameworks", complianceHandler.ListFrameworks)
+			protected.GET("/organizations/:id/compliance", complianceHandler.Coverage)
+			protected.GET("/organizations/:id/compliance/:framework", complianceHandler.Assess)
+
+			// Slack integration
+			protected.GET("/organizations/:id/integrations/slack", slackHandler.ListWorkspaces)
+			protected.PUT("/organizations/:id/integrations/slack", slackHandler.LinkWorkspace)
+			protected.DELETE("/organizations/:id/integrations/slack/:team_id", slackHandler.UnlinkWorkspace)
+			protected.POST("/users/me/slack/link-code", slackHandler.CreateLinkCode)
+			protected.DELETE("/users/me/slack", slackHandler.UnlinkUser)
+
+			// Integration debug console: test events, delivery log and replays
+			protected.POST("/organizations/:id/integrations/test", deliveryHandler.TestDelivery)
+			protected.GET("/organizations/:id/integrations/deliveries", deliveryHandler.ListDeliveries)
+			protected.GET("/organizations/:id/integrations/deliveries/:delivery_id", deliveryHandler.GetDelivery)
+			protected.POST("/organizations/:id/integrations/deliveries/:delivery_id/replay", deliveryHandler.ReplayDelivery)
+
+			// Report templates
+			protected.GET("/organizations/:id/report-templates", reportTemplateHandler.ListTemplates)
+			protected.POST("/organizations/:id/report-templates", reportTemplateHandler.CreateTemplate)
+			protected.GET("/organizations/:id/report-templates/:template_id", reportTemplateHandler.GetTemplate)
+			protected.PUT("/organizations/:id/report-templates/:template_id", reportTemplateHandler.UpdateTemplate)
+			protected.DELETE("/organizations/:id/report-templates/:template_id", reportTemplateHandler.DeleteTemplate)
+
+			// Scheduled report delivery
+			protected.GET("/organizations/:id/report-schedules", reportScheduleHandler.ListSchedules)
+			protected.POST("/organizations/:id/report-schedules", reportScheduleHandler.CreateSchedule)
+			protected.PUT("/organizations/:id/report-schedules/:schedule_id", reportScheduleHandler.UpdateSchedule)
+			protected.DELETE("/organizations/:id/report-schedules/:schedule_id", reportScheduleHandler.DeleteSchedule)
+
+			// Emergency contacts and escalation
+			protected.GET("/organizations/:id/escalation-policies", escalationHandler.ListPolicies)
+			protected.POST("/organizations/:id/escalation-policies", escalationHandler.CreatePolicy)
+			protected.PUT("/organizations/:id/escalation-policies/:policy_id", escalationHandler.UpdatePolicy)
+			protected.DELETE("/organizations/:id/escalation-policies/:policy_id", escalationHandler.DeletePolicy)
+			protected.GET("/organizations/:id/escalations", escalationHandler.ListEscalations)
+			protected.GET("/organizations/:id/escalations/:escalation_id", escalationHandler.GetEscalation)
+			protected.POST("/organizations/:id/escalations/:escalation_id/acknowledge", escalationHandler.AcknowledgeEscalation)
+
+			// Attribute-based access policies
+			protected.GET("/organizations/:id/policies", policyHandler.ListPolicies)
+			protected.POST("/organizations/:id/policies", policyHandler.CreatePolicy)
+			protected.DELETE("/organizations/:id/policies/:policy_id", policyHandler.DeletePolicy)
+
+			// Organization-scoped audit trail
+			protected.GET("/organizations/:id/audit", auditHandler.ListOrganizationAuditLogs)
+
+			// Permission introspection for clients
+			protected.GET("/me/permissions", accessHandler.MyPermissions)
+			protected.POST("/access/check", accessHandler.CheckAccess)
+
 			// Scan routes (require permissions)
 			protected.POST("/scans",
-				rbac.RequirePermission(rbac.PermCreateScan, logger),
-				// TODO: scan handler
+				rbac.RequirePermission(roleStore, rbac.PermCreateScan, logger),
+				emergencyHandler.CheckEmergencyStop(),
+				scanHandler.CreateScan,
+			)
+			// Dry run of scan creation: authorization, quota, overlap and
+			// emergency stop, with a duration and cost estimate
+			protected.POST("/scans/preflight",
+				rbac.RequirePermission(roleStore, rbac.PermCreateScan, logger),
+				scanHandler.Preflight,
+			)
+			// Approval workflow for scan types that need sign-off
+			protected.GET("/scans/approvals",
+				rbac.RequirePermission(roleStore, rbac.PermApproveScan, logger),
+				scanApprovalHandler.ListPending,
+			)
+			protected.GET("/scans/:id/approvals",
+				rbac.RequirePermission(roleStore, rbac.PermViewScan, logger),
+				scanApprovalHandler.ListDecisions,
+			)
+			protected.POST("/scans/:id/approve",
+				rbac.RequirePermission(roleStore, rbac.PermApproveScan, logger),
+				scanApprovalHandler.ApproveScan,
+			)
+			protected.POST("/scans/:id/reject",
+				rbac.RequirePermission(roleStore, rbac.PermApproveScan, logger),
+				scanApprovalHandler.RejectScan,
+			)
+			protected.POST("/scans:batchCancel",
+				rbac.RequirePermission(roleStore, rbac.PermStopScan, logger),
+				scanHandler.BatchCancel,
+			)
+			protected.POST("/scans/:id/stop",
+				rbac.RequirePermission(roleStore, rbac.PermStopScan, logger),
+				scanHandler.StopScan,
+			)
+			protected.POST("/scans/:id/pause",
+				rbac.RequirePermission(roleStore, rbac.PermStopScan, logger),
+				scanHandler.PauseScan,
+			)
+			protected.POST("/scans/:id/resume",
+				rbac.RequirePermission(roleStore, rbac.PermStopScan, logger),
+				emergencyHandler.CheckEmergencyStop(),
+				scanHandler.ResumeScan,
+			)
+			protected.POST("/scans/:id/rerun",
+				rbac.RequirePermission(roleStore, rbac.PermCreateScan, logger),
+				emergencyHandler.CheckEmergencyStop(),
+				scanHandler.RerunScan,
+			)
+			protected.GET("/scans/:id/diff",
+				rbac.RequirePermission(roleStore, rbac.PermViewScan, logger),
+				scanHandler.DiffScans,
 			)
 
 			// Report generation (requires permission)
 			protected.POST("/scans/:id/report",
-				rbac.RequirePermission(rbac.PermGenerateReport, logger),
+				rbac.RequirePermission(roleStore, rbac.PermGenerateReport, logger),
+				reportLimiter.Middleware(),
 				reportHandler.GenerateReport,
 			)
+			protected.POST("/scans/:id/analyze",
+				rbac.RequirePermission(roleStore, rbac.PermGenerateReport, logger),
+				analysisLimiter.Middleware(),
+				analysisHandler.AnalyzeScan,
+			)
+			protected.GET("/reports",
+				rbac.RequirePermission(roleStore, rbac.PermViewReport, logger),
+				reportHandler.ListReports,
+			)
+			protected.GET("/reports/:id/download",
+				rbac.RequirePermission(roleStore, rbac.PermViewReport, logger),
+				reportHandler.DownloadReport,
+			)
+			protected.GET("/reports/:id/downloads",
+				rbac.RequirePermission(roleStore, rbac.PermViewAuditLogs, logger),
+				reportHandler.ListReportDownloads,
+			)
 
 			// Scan Authorization (Permission to Scan)
 			protected.POST("/scan-authorizations", scanAuthHandler.SubmitAuthorization)
@@ -157,21 +1465,58 @@ func main() {
 				scanAuthHandler.VerifyAuthorization,
 			)
 
-			// Emergency Stop (Owner only)
-			protected.POST("/emergency/stop",
-				rbac.RequireRole(rbac.RoleOwner),
-				emergencyHandler.ActivateEmergencyStop,
+			// Delegated authorizations from the organization's clients
+			protected.POST("/scan-grants",
+				rbac.RequirePermission(roleStore, rbac.PermCreateScan, logger),
+				scanGrantHandler.CreateGrant,
 			)
-			protected.POST("/emergency/resume",
-				rbac.RequireRole(rbac.RoleOwner),
-				emergencyHandler.DeactivateEmergencyStop,
+			protected.GET("/scan-grants", scanGrantHandler.ListGrants)
+			protected.GET("/scan-grants/:id", scanGrantHandler.GetGrant)
+			protected.POST("/scan-grants/:id/revoke",
+				rbac.RequirePermission(roleStore, rbac.PermCreateScan, logger),
+				scanGrantHandler.RevokeGrant,
 			)
+
+			// Emergency stop status; stopping and resuming are on the admin listener
 			protected.GET("/emergency/status", emergencyHandler.GetEmergencyStatus)
 
+			// Live audit tail for an organization (server-sent events)
+			protected.GET("/audit/stream", auditHandler.StreamAuditLogs)
+			// Aggregated activity of an organization or one of its users
+			protected.GET("/audit/summary", auditHandler.GetAuditSummary)
+			// Role elevations, custom role grants and API token scope expansions
+			protected.GET("/audit/privilege-changes", privilegeChangeHandler.ListPrivilegeChanges)
+
+			// Audit log export and verification (Owner/Admin)
+			protected.GET("/audit/export",
+				rbac.RequireRole(rbac.RoleOwner, rbac.RoleAdmin),
+				exportLimiter.Middleware(),
+				auditHandler.ExportAuditLogs,
+			)
+			protected.POST("/audit/verify",
+				audit.SkipRequestAudit, // read-only despite the method
+				rbac.RequireRole(rbac.RoleOwner, rbac.RoleAdmin),
+				auditHandler.VerifySignature,
+			)
+			protected.POST("/audit/verify-range",
+				audit.SkipRequestAudit, // the handler audits the run with its outcome
+				rbac.RequireRole(rbac.RoleOwner, rbac.RoleAdmin),
+				exportLimiter.Middleware(),
+				auditHandler.VerifyRange,
+			)
+			protected.GET("/audit/anchors",
+				rbac.RequireRole(rbac.RoleOwner, rbac.RoleAdmin),
+				auditHandler.ListAnchors,
+			)
+
 			// TODO: Add monitoring routes
 		}
 	}
 
+	for _, route := range openapi.MissingRoutes(router.Routes(), api.APIBasePath, api.Routes()) {
+		logger.Warn("Route missing from OpenAPI specification", zap.String("route", route))
+	}
+
 	// Get port
 	port := os.Getenv("API_PORT")
 	if port == "" {
@@ -181,7 +1526,7 @@ func main() {
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", port),
-		Handler: router,
+		Handler: apiVersions.Handler(router),
 	}
 
 	// Start server in goroutine
@@ -192,6 +1537,50 @@ func main() {
 		}
 	}()
 
+	var internalSrv *http.Server
+	if internalTLS != nil {
+		internalPort := getEnv("INTERNAL_PORT", "8443")
+		internalSrv = &http.Server{
+			Addr:      fmt.Sprintf(":%s", internalPort),
+			Handler:   internalRouter,
+			TLSConfig: internalTLS.ServerConfig(),
+		}
+		go func() {
+			logger.Info("Internal mTLS listener started", zap.String("port", internalPort))
+			if err := internalSrv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				logger.Fatal("Failed to start internal listener", zap.Error(err))
+			}
+		}()
+	}
+
+	go func() {
+		if err := adminServer.ListenAndServe(); err != nil {
+			logger.Fatal("Failed to start admin listener", zap.Error(err))
+		}
+	}()
+
+	// Internal gRPC API for brain and scanner workers
+	grpcConfig := rpc.Config{
+		Port:         getEnv("GRPC_PORT", "50051"),
+		ServiceToken: serviceToken,
+		TLS:          internalTLS,
+	}
+
+	internalService := rpc.NewInternalService(db, authService, auditLogger, dataCipher, logger)
+	internalService.SetDispatchPaused(maintenanceService.Active)
+	internalService.SetConsole(consoleService)
+	internalService.SetScanEvents(scanEvents)
+	grpcServer, err := rpc.NewServer(internalService, grpcConfig, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize gRPC server", zap.Error(err))
+	}
+
+	go func() {
+		if err := grpcServer.ListenAndServe(); err != nil {
+			logger.Fatal("Failed to start gRPC server", zap.Error(err))
+		}
+	}()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -203,10 +1592,26 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	grpcServer.GracefulStop()
+	if internalSrv != nil {
+		if err := internalSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Internal listener forced to shutdown", zap.Error(err))
+		}
+	}
+
+	if err := adminServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Admin listener forced to shutdown", zap.Error(err))
+	}
+
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
 
+	// Flush buffered audit entries once no more requests can produce them
+	if err := auditLogger.Close(shutdownCtx); err != nil {
+		logger.Error("Failed to flush audit log buffer", zap.Error(err))
+	}
+
 	logger.Info("Server exited")
 }
 
@@ -216,3 +1621,324 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// newOutboundGuard holds outbound integration requests to the policy from
+// OUTBOUND_* settings
+func newOutboundGuard(logger *zap.Logger) *outbound.Guard {
+	policy := outbound.DefaultPolicy()
+	policy.AllowPrivate = os.Getenv("OUTBOUND_ALLOW_PRIVATE") == "true"
+	policy.RequireHTTPS = os.Getenv("OUTBOUND_REQUIRE_HTTPS") == "true"
+	policy.MaxRedirects = getEnvInt("OUTBOUND_MAX_REDIRECTS", policy.MaxRedirects)
+	if hosts := os.Getenv("OUTBOUND_ALLOWED_HOSTS"); hosts != "" {
+		policy.AllowedHosts = strings.Split(hosts, ",")
+	}
+	if ports, ok := os.LookupEnv("OUTBOUND_ALLOWED_PORTS"); ok {
+		policy.AllowedPorts = nil
+		for _, port := range strings.Split(ports, ",") {
+			if port = strings.TrimSpace(port); port == "" {
+				continue
+			}
+			n, err := strconv.Atoi(port)
+			if err != nil || n < 1 || n > 65535 {
+				logger.Fatal("Invalid OUTBOUND_ALLOWED_PORTS", zap.String("port", port))
+			}
+			policy.AllowedPorts = append(policy.AllowedPorts, n)
+		}
+	}
+	guard, err := outbound.NewGuard(policy, logger)
+	if err != nil {
+		logger.Fatal("Invalid OUTBOUND_ALLOWED_HOSTS", zap.Error(err))
+	}
+	return guard
+}
+
+// newSecretsProvider selects the secrets backend from SECRETS_PROVIDER
+// (vault, aws, or unset for environment variables)
+func newSecretsProvider(egress *outbound.Guard, logger *zap.Logger) secrets.Provider {
+	switch provider := os.Getenv("SECRETS_PROVIDER"); provider {
+	case "":
+		return secrets.EnvProvider{}
+	case "vault":
+		logger.Info("Reading secrets from Vault", zap.String("path", os.Getenv("VAULT_SECRET_PATH")))
+		address := getEnv("VAULT_ADDR", "http://vault:8200")
+		return secrets.NewVaultProvider(secrets.VaultConfig{
+			Address:   address,
+			Token:     os.Getenv("VAULT_TOKEN"),
+			Namespace: os.Getenv("VAULT_NAMESPACE"),
+			Path:      getEnv("VAULT_SECRET_PATH", "secret/data/cyper/gateway"),
+			Transport: egress.Transport("vault", outbound.AllowURLs(address)),
+		})
+	case "aws":
+		logger.Info("Reading secrets from AWS Secrets Manager", zap.String("secret_id", os.Getenv("AWS_SECRET_ID")))
+		return secrets.NewAWSProvider(secrets.AWSConfig{
+			Region:          getEnv("AWS_REGION", "us-east-1"),
+			SecretID:        os.Getenv("AWS_SECRET_ID"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			Endpoint:        os.Getenv("AWS_SECRETS_ENDPOINT"),
+			Transport:       egress.Transport("secrets_manager", outbound.AllowURLs(os.Getenv("AWS_SECRETS_ENDPOINT"))),
+		})
+	default:
+		logger.Fatal("Unknown SECRETS_PROVIDER", zap.String("provider", provider))
+		return nil
+	}
+}
+
+// newEventPublisher selects the event bus from EVENT_BUS (nats, kafka, or
+// unset to discard events)
+func newEventPublisher(logger *zap.Logger) events.Publisher {
+	switch bus := os.Getenv("EVENT_BUS"); bus {
+	case "":
+		return events.Discard{}
+	case "nats":
+		publisher, err := events.NewNATSPublisher(events.NATSConfig{
+			URL:           getEnv("NATS_URL", "nats://localhost:4222"),
+			Stream:        getEnv("NATS_STREAM", "CYPER_EVENTS"),
+			SubjectPrefix: getEnv("NATS_SUBJECT_PREFIX", "cyper.events"),
+			CredsFile:     os.Getenv("NATS_CREDS"),
+		})
+		if err != nil {
+			logger.Fatal("Failed to connect to event bus", zap.Error(err))
+		}
+		logger.Info("Publishing domain events to NATS JetStream")
+		return publisher
+	case "kafka":
+		logger.Info("Publishing domain events to Kafka")
+		return events.NewKafkaPublisher(events.KafkaConfig{
+			Brokers: strings.Split(getEnv("KAFKA_BROKERS", "localhost:9092"), ","),
+			Topic:   getEnv("KAFKA_TOPIC", "cyper.events"),
+		})
+	default:
+		logger.Fatal("Unknown EVENT_BUS", zap.String("bus", bus))
+		return nil
+	}
+}
+
+// newChallengeProvider selects the auth challenge from CHALLENGE_PROVIDER
+// (hcaptcha, turnstile, pow, or unset to disable challenges)
+func newChallengeProvider(redisClient *redis.Client, logger *zap.Logger) challenge.Provider {
+	switch provider := os.Getenv("CHALLENGE_PROVIDER"); provider {
+	case "":
+		return nil
+	case "hcaptcha":
+		return challenge.NewHCaptcha(os.Getenv("CHALLENGE_SITE_KEY"), os.Getenv("CHALLENGE_SECRET"))
+	case "turnstile":
+		return challenge.NewTurnstile(os.Getenv("CHALLENGE_SITE_KEY"), os.Getenv("CHALLENGE_SECRET"))
+	case "pow":
+		return challenge.NewProofOfWork(redisClient, getEnvInt("CHALLENGE_POW_DIFFICULTY", 20), 5*time.Minute)
+	default:
+		logger.Fatal("Unknown CHALLENGE_PROVIDER", zap.String("provider", provider))
+		return nil
+	}
+}
+
+// newSMSProvider selects the SMS gateway for escalation paging from
+// SMS_PROVIDER (twilio, or unset to skip SMS contacts)
+func newSMSProvider(getSecret func(name, defaultValue string) string, egress *outbound.Guard, logger *zap.Logger) notify.SMSSender {
+	switch provider := os.Getenv("SMS_PROVIDER"); provider {
+	case "":
+		return nil
+	case "twilio":
+		return notify.NewTwilio(notify.TwilioConfig{
+			AccountSID: os.Getenv("TWILIO_ACCOUNT_SID"),
+			AuthToken:  getSecret("TWILIO_AUTH_TOKEN", ""),
+			From:       os.Getenv("TWILIO_FROM"),
+			Transport:  egress.Transport("twilio"),
+		})
+	default:
+		logger.Fatal("Unknown SMS_PROVIDER", zap.String("provider", provider))
+		return nil
+	}
+}
+
+// newArtifactStore selects artifact storage from STORAGE_BACKEND (s3, gcs, or
+// local/unset for the filesystem). The local store is also returned so its
+// signed download route can be mounted.
+func newArtifactStore(publicURL, jwtSecret string, getSecret func(name, defaultValue string) string, egress *outbound.Guard, logger *zap.Logger) (storage.Store, *storage.Local) {
+	switch backend := getEnv("STORAGE_BACKEND", "local"); backend {
+	case "local":
+		local, err := storage.NewLocal(
+			getEnv("STORAGE_LOCAL_PATH", "/var/lib/cyper/storage"),
+			publicURL+"/api"+api.APIBasePath+"/downloads",
+			[]byte(getSecret("STORAGE_SIGNING_KEY", jwtSecret)),
+		)
+		if err != nil {
+			logger.Fatal("Failed to initialize artifact storage", zap.Error(err))
+		}
+		return local, local
+	case "s3":
+		logger.Info("Storing artifacts in S3", zap.String("bucket", os.Getenv("STORAGE_S3_BUCKET")))
+		return storage.NewS3(storage.S3Config{
+			Region:               getEnv("STORAGE_S3_REGION", getEnv("AWS_REGION", "us-east-1")),
+			Bucket:               os.Getenv("STORAGE_S3_BUCKET"),
+			Prefix:               os.Getenv("STORAGE_PREFIX"),
+			AccessKeyID:          os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey:      getSecret("AWS_SECRET_ACCESS_KEY", ""),
+			SessionToken:         os.Getenv("AWS_SESSION_TOKEN"),
+			Endpoint:             os.Getenv("STORAGE_S3_ENDPOINT"),
+			ServerSideEncryption: os.Getenv("STORAGE_S3_SSE"),
+			KMSKeyID:             os.Getenv("STORAGE_S3_KMS_KEY_ID"),
+			Transport:            egress.Transport("s3", outbound.AllowURLs(os.Getenv("STORAGE_S3_ENDPOINT"))),
+		}), nil
+	case "gcs":
+		logger.Info("Storing artifacts in Cloud Storage", zap.String("bucket", os.Getenv("STORAGE_GCS_BUCKET")))
+		return storage.NewGCS(storage.GCSConfig{
+			Bucket:     os.Getenv("STORAGE_GCS_BUCKET"),
+			Prefix:     os.Getenv("STORAGE_PREFIX"),
+			AccessID:   os.Getenv("STORAGE_GCS_HMAC_ACCESS_ID"),
+			Secret:     getSecret("STORAGE_GCS_HMAC_SECRET", ""),
+			KMSKeyName: os.Getenv("STORAGE_GCS_KMS_KEY_NAME"),
+			Transport:  egress.Transport("gcs"),
+		}), nil
+	default:
+		logger.Fatal("Unknown STORAGE_BACKEND", zap.String("backend", backend))
+		return nil, nil
+	}
+}
+
+// newNotary returns the notary audit chain heads are anchored with, chosen
+// by AUDIT_ANCHOR_NOTARY (tsa or witness), or nil when anchoring is disabled
+func newNotary(egress *outbound.Guard, logger *zap.Logger) anchoring.Notary {
+	switch notary := os.Getenv("AUDIT_ANCHOR_NOTARY"); notary {
+	case "":
+		return nil
+	case anchoring.NotaryTSA:
+		var roots *x509.CertPool
+		if caFile := os.Getenv("AUDIT_ANCHOR_TSA_CA_FILE"); caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				logger.Fatal("Failed to read AUDIT_ANCHOR_TSA_CA_FILE", zap.Error(err))
+			}
+			roots = x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				logger.Fatal("AUDIT_ANCHOR_TSA_CA_FILE holds no PEM certificates")
+			}
+		} else {
+			logger.Warn("AUDIT_ANCHOR_TSA_CA_FILE is not set; timestamp tokens are checked against the certificate they carry only")
+		}
+		tsa := anchoring.NewTSANotary(os.Getenv("AUDIT_ANCHOR_TSA_URL"), roots)
+		tsa.SetTransport(egress.Transport("tsa", outbound.AllowURLs(os.Getenv("AUDIT_ANCHOR_TSA_URL"))))
+		return tsa
+	case anchoring.NotaryWitness:
+		key, err := base64.StdEncoding.DecodeString(os.Getenv("AUDIT_ANCHOR_WITNESS_PUBLIC_KEY"))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			logger.Fatal("AUDIT_ANCHOR_WITNESS_PUBLIC_KEY must be a base64 Ed25519 public key")
+		}
+		witness := anchoring.NewWitnessNotary(os.Getenv("AUDIT_ANCHOR_WITNESS_URL"), ed25519.PublicKey(key))
+		witness.SetTransport(egress.Transport("witness", outbound.AllowURLs(os.Getenv("AUDIT_ANCHOR_WITNESS_URL"))))
+		return witness
+	default:
+		logger.Fatal("Unknown AUDIT_ANCHOR_NOTARY", zap.String("notary", notary))
+		return nil
+	}
+}
+
+// newMasterKeys returns the master key organizations' data keys are wrapped
+// with, chosen by DATA_MASTER_KEY_BACKEND (local or kms), or nil to leave
+// designated columns unencrypted when it is unset
+func newMasterKeys(getSecret func(name, defaultValue string) string, egress *outbound.Guard, logger *zap.Logger) tenantkeys.MasterKeys {
+	switch backend := os.Getenv("DATA_MASTER_KEY_BACKEND"); backend {
+	case "":
+		logger.Warn("DATA_MASTER_KEY_BACKEND is not set; authorization proofs and scan evidence are stored unencrypted")
+		return nil
+	case "local":
+		current := getEnv("DATA_MASTER_KEY_ID", "local-1")
+		// Rotated-out keys, as id=base64 pairs, unwrap data keys until the
+		// rotation job has re-wrapped them
+		encoded := map[string]string{current: getSecret("DATA_MASTER_KEY", "")}
+		for _, pair := range strings.Split(getSecret("DATA_MASTER_KEYS_PREVIOUS", ""), ",") {
+			if id, value, ok := strings.Cut(strings.TrimSpace(pair), "="); ok && id != current {
+				encoded[id] = value
+			}
+		}
+		keys := map[string][]byte{}
+		for id, value := range encoded {
+			key, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				logger.Fatal("Invalid data master key", zap.String("key_id", id))
+			}
+			keys[id] = key
+		}
+		masterKeys, err := tenantkeys.NewLocalMasterKeys(current, keys)
+		if err != nil {
+			logger.Fatal("Failed to initialize data master keys", zap.Error(err))
+		}
+		return masterKeys
+	case "kms":
+		logger.Info("Wrapping data keys with AWS KMS", zap.String("key_id", os.Getenv("DATA_KMS_KEY_ID")))
+		return tenantkeys.NewKMSMasterKeys(tenantkeys.KMSConfig{
+			Region:          getEnv("DATA_KMS_REGION", getEnv("AWS_REGION", "us-east-1")),
+			KeyID:           os.Getenv("DATA_KMS_KEY_ID"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getSecret("AWS_SECRET_ACCESS_KEY", ""),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			Endpoint:        os.Getenv("DATA_KMS_ENDPOINT"),
+			Transport:       egress.Transport("kms", outbound.AllowURLs(os.Getenv("DATA_KMS_ENDPOINT"))),
+		})
+	default:
+		logger.Fatal("Unknown DATA_MASTER_KEY_BACKEND", zap.String("backend", backend))
+		return nil
+	}
+}
+
+// newLimiter bounds a route group, with defaults overridden by
+// <prefix>_MAX_CONCURRENT, <prefix>_MAX_QUEUE, <prefix>_QUEUE_TIMEOUT and
+// <prefix>_MAX_PER_USER
+func newLimiter(group, prefix string, defaults loadshed.Config) *loadshed.Limiter {
+	config := defaults
+	config.MaxConcurrent = getEnvInt(prefix+"_MAX_CONCURRENT", config.MaxConcurrent)
+	config.MaxQueue = getEnvInt(prefix+"_MAX_QUEUE", config.MaxQueue)
+	config.QueueTimeout = getEnvDuration(prefix+"_QUEUE_TIMEOUT", config.QueueTimeout)
+	config.MaxPerUser = getEnvInt(prefix+"_MAX_PER_USER", config.MaxPerUser)
+	return loadshed.NewLimiter(group, config)
+}
+
+// parseRoleLimits parses "role=limit" pairs separated by commas, e.g.
+// "analyst=5,admin=3"
+func parseRoleLimits(value string, logger *zap.Logger) map[string]int {
+	limits := map[string]int{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		role, limit, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if !ok || err != nil || n < 0 {
+			logger.Fatal("Invalid role limit", zap.String("value", pair))
+		}
+		limits[strings.TrimSpace(role)] = n
+	}
+	return limits
+}
+
+// parseKeyValues parses "key=value,key=value" settings
+func parseKeyValues(value, what string, logger *zap.Logger) map[string]string {
+	pairs := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok || key == "" || val == "" {
+			logger.Fatal("Invalid "+what, zap.String("value", pair))
+		}
+		pairs[key] = val
+	}
+	return pairs
+}
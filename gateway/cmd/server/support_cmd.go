@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/adminserver"
+	"github.com/cyper-security/gateway/internal/supportbundle"
+	"go.uber.org/zap"
+)
+
+// defaultMigrationsDir is where migrations sit relative to the gateway in a
+// source checkout; packaged installs set MIGRATIONS_DIR
+const defaultMigrationsDir = "../database/migrations"
+
+// defaultTenantMigrationsDir holds the migrations applied to each tenant
+// schema; packaged installs set TENANT_MIGRATIONS_DIR
+const defaultTenantMigrationsDir = "../database/tenant_migrations"
+
+// exitWritten is `gateway support-bundle` succeeding; failures share the
+// audit command's codes
+const exitWritten = 0
+
+const supportBundleUsage = `Usage: gateway support-bundle [flags]
+
+Downloads a support bundle from the running gateway's admin listener
+(GET /debug/support-bundle): version, sanitized configuration, recent error
+logs, migration status, dependency health, runtime statistics and metrics,
+with secrets redacted. When the gateway cannot be reached, or with -offline,
+writes a bundle of what is available locally (version, configuration and
+migrations) instead.
+
+Exit codes: 0 written, 1 failed, 2 usage error.
+
+Flags:
+`
+
+// runSupportBundleCommand implements `gateway support-bundle`
+func runSupportBundleCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("support-bundle", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, supportBundleUsage)
+		fs.PrintDefaults()
+	}
+
+	addr := fs.String("addr", getEnv("ADMIN_ADDR", adminserver.DefaultAddr), "admin listener address")
+	output := fs.String("o", "", `output file ("-" for standard output; default support-bundle-<host>-<time>.tar.gz)`)
+	offline := fs.Bool("offline", false, "do not contact the gateway")
+	certFile := fs.String("cert", "", "client certificate, when the admin listener requires mutual TLS")
+	keyFile := fs.String("key", "", "client certificate key")
+	caFile := fs.String("cacert", "", "CA certificate of the admin listener")
+	timeout := fs.Duration("timeout", 2*time.Minute, "time allowed to gather the bundle")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 || (*certFile == "") != (*keyFile == "") {
+		fs.Usage()
+		return exitUsage
+	}
+
+	path := *output
+	if path == "" {
+		path = supportbundle.FileName(time.Now())
+	}
+	out := stdout
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitFailed
+		}
+		defer f.Close()
+		out = f
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if !*offline {
+		client, url, err := adminClient(*addr, *certFile, *keyFile, *caFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		// Buffered so a failed download does not leave half an archive ahead
+		// of the offline one
+		var bundle bytes.Buffer
+		err = downloadSupportBundle(ctx, client, url, &bundle)
+		if err == nil {
+			if _, err := bundle.WriteTo(out); err != nil {
+				fmt.Fprintf(stderr, "error: %v\n", err)
+				return exitFailed
+			}
+			if path != "-" {
+				fmt.Fprintf(stderr, "wrote %s\n", path)
+			}
+			return exitWritten
+		}
+		fmt.Fprintf(stderr, "warning: could not download from %s (%v); writing an offline bundle\n", url, err)
+	}
+
+	builder := supportbundle.New(supportbundle.Sources{
+		Version:       version,
+		MigrationsDir: getEnv("MIGRATIONS_DIR", defaultMigrationsDir),
+	}, supportbundle.NewRedactor(), zap.NewNop())
+	if err := builder.Write(ctx, out); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailed
+	}
+	if path != "-" {
+		fmt.Fprintf(stderr, "wrote %s\n", path)
+	}
+	return exitWritten
+}
+
+// adminClient builds a client for the admin listener at addr, over mutual
+// TLS when a client certificate is given
+func adminClient(addr, certFile, keyFile, caFile string) (*http.Client, string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid -addr %q: %w", addr, err)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	base := "http://" + net.JoinHostPort(host, port)
+
+	client := &http.Client{}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, "", err
+		}
+		config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, "", err
+			}
+			config.RootCAs = x509.NewCertPool()
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, "", fmt.Errorf("no certificates in %s", caFile)
+			}
+		}
+		client.Transport = &http.Transport{TLSClientConfig: config}
+		base = "https://" + net.JoinHostPort(host, port)
+	}
+	return client, base + "/debug/support-bundle", nil
+}
+
+func downloadSupportBundle(ctx context.Context, client *http.Client, url string, out io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("admin listener returned %s", resp.Status)
+	}
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
@@ -0,0 +1,164 @@
+// Package adminserver runs the operator listener: metrics, diagnostics and
+// platform administration (users, maintenance mode, emergency stop) served on
+// an internal address instead of the public API. Callers are admitted by
+// client certificate when mutual TLS is configured, otherwise by source
+// address.
+package adminserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/mtls"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// DefaultAddr keeps the listener off every interface but loopback
+const DefaultAddr = "127.0.0.1:9091"
+
+// Config controls where the listener binds and whom it admits
+type Config struct {
+	Addr string
+	// AllowedNetworks admit callers by source address. Empty admits only
+	// loopback, unless TLS is set.
+	AllowedNetworks []*net.IPNet
+	// TLS requires client certificates mapping to one of Identities
+	TLS        *mtls.Reloader
+	Identities []string
+}
+
+// ParseNetworks reads comma-separated CIDRs or single addresses
+func ParseNetworks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", entry)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			entry = fmt.Sprintf("%s/%d", entry, bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Server is the operator listener
+type Server struct {
+	config Config
+	router *gin.Engine
+	srv    *http.Server
+	logger *zap.Logger
+}
+
+// New builds the listener with /metrics mounted; callers add diagnostics
+// and the admin API to Router
+func New(config Config, logger *zap.Logger) (*Server, error) {
+	if config.Addr == "" {
+		config.Addr = DefaultAddr
+	}
+	if len(config.AllowedNetworks) == 0 && config.TLS == nil {
+		loopback, err := ParseNetworks("127.0.0.0/8,::1")
+		if err != nil {
+			return nil, err
+		}
+		config.AllowedNetworks = loopback
+	}
+
+	s := &Server{config: config, logger: logger}
+
+	router := gin.New()
+	router.Use(gin.Recovery(), logging.RequestMiddleware(logger), metrics.PrometheusMiddleware())
+	if len(config.AllowedNetworks) > 0 {
+		router.Use(s.requireNetwork())
+	}
+	if config.TLS != nil {
+		router.Use(config.TLS.RequireIdentity(logger, config.Identities...))
+	}
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	s.router = router
+	s.srv = &http.Server{
+		Addr:    config.Addr,
+		Handler: router,
+	}
+	if config.TLS != nil {
+		s.srv.TLSConfig = config.TLS.ServerConfig()
+	}
+	return s, nil
+}
+
+// Router is where the admin API is mounted
+func (s *Server) Router() *gin.Engine {
+	return s.router
+}
+
+// ListenAndServe serves until Shutdown
+func (s *Server) ListenAndServe() error {
+	s.logger.Info("Admin listener started",
+		zap.String("addr", s.config.Addr),
+		zap.Bool("mtls", s.config.TLS != nil),
+		zap.Int("allowed_networks", len(s.config.AllowedNetworks)),
+	)
+	var err error
+	if s.config.TLS != nil {
+		err = s.srv.ListenAndServeTLS("", "")
+	} else {
+		err = s.srv.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops the listener, waiting for requests in progress
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// requireNetwork admits callers whose connection comes from an allowed
+// network. Forwarding headers are ignored: the listener is never proxied.
+func (s *Server) requireNetwork() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+		if err != nil {
+			host = c.Request.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		if ip != nil {
+			for _, network := range s.config.AllowedNetworks {
+				if network.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		logging.FromContext(c.Request.Context(), s.logger).Warn("Rejected admin listener call",
+			zap.String("path", c.Request.URL.Path),
+			zap.String("remote_addr", c.Request.RemoteAddr),
+		)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "address not allowed"})
+	}
+}
@@ -0,0 +1,127 @@
+package anchoring
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Notary names
+const (
+	NotaryTSA     = "tsa"
+	NotaryWitness = "witness"
+)
+
+// ErrInvalidReceipt is returned when a receipt does not attest to the hash
+var ErrInvalidReceipt = errors.New("invalid anchor receipt")
+
+// Notary attests outside the gateway's control that a chain head existed at
+// a point in time
+type Notary interface {
+	Name() string
+	// Anchor has the notary attest to hash (a SHA-256 digest), returning its
+	// receipt and the time it attested to
+	Anchor(ctx context.Context, hash []byte) (receipt []byte, at time.Time, err error)
+	// Verify checks that receipt attests to hash and returns its time
+	Verify(receipt, hash []byte) (time.Time, error)
+}
+
+// WitnessNotary submits chain heads to a transparency-log style witness. The
+// gateway POSTs {"hash": "<hex>"} to the URL and expects
+// {"index": n, "integrated_time": "<RFC 3339>", "signature": "<base64>"},
+// where signature is the witness's Ed25519 signature over
+// "<hash>\n<index>\n<integrated_time>". The whole response is the receipt.
+type WitnessNotary struct {
+	url        string
+	publicKey  ed25519.PublicKey
+	httpClient *http.Client
+}
+
+var _ Notary = (*WitnessNotary)(nil)
+
+func NewWitnessNotary(url string, publicKey ed25519.PublicKey) *WitnessNotary {
+	return &WitnessNotary{
+		url:        url,
+		publicKey:  publicKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetTransport sends requests through transport, e.g. under an outbound
+// policy
+func (w *WitnessNotary) SetTransport(transport http.RoundTripper) {
+	w.httpClient.Transport = transport
+}
+
+type witnessReceipt struct {
+	Index          int64  `json:"index"`
+	IntegratedTime string `json:"integrated_time"` // Signed as sent
+	Signature      string `json:"signature"`
+}
+
+func (w *WitnessNotary) Name() string {
+	return NotaryWitness
+}
+
+func (w *WitnessNotary) Anchor(ctx context.Context, hash []byte) ([]byte, time.Time, error) {
+	payload, err := json.Marshal(map[string]string{"hash": hex.EncodeToString(hash)})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to reach witness: %w", err)
+	}
+	defer resp.Body.Close()
+
+	receipt, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read witness response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, time.Time{}, fmt.Errorf("witness returned status %d", resp.StatusCode)
+	}
+
+	// Refuse receipts that would not verify later
+	at, err := w.Verify(receipt, hash)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return receipt, at, nil
+}
+
+func (w *WitnessNotary) Verify(receipt, hash []byte) (time.Time, error) {
+	var r witnessReceipt
+	if err := json.Unmarshal(receipt, &r); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidReceipt, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: malformed signature", ErrInvalidReceipt)
+	}
+
+	statement := hex.EncodeToString(hash) + "\n" + strconv.FormatInt(r.Index, 10) + "\n" + r.IntegratedTime
+	if !ed25519.Verify(w.publicKey, []byte(statement), signature) {
+		return time.Time{}, fmt.Errorf("%w: signature does not match the witness key", ErrInvalidReceipt)
+	}
+	at, err := time.Parse(time.RFC3339Nano, r.IntegratedTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: malformed integrated_time", ErrInvalidReceipt)
+	}
+	return at, nil
+}
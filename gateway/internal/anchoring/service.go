@@ -0,0 +1,297 @@
+// Package anchoring anchors the audit log's hash chain outside the gateway.
+// A background job extends the chain over entries written since the last
+// anchor, has an external notary (an RFC 3161 timestamp authority or a
+// transparency-log style witness) attest to the new head, and stores the
+// receipt. Signatures alone cannot show that the log was not rewritten and
+// re-signed wholesale by someone holding the signing key; a head attested
+// by a third party at a known time can.
+package anchoring
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Config tunes anchoring
+type Config struct {
+	// Interval is how often a new anchor is published, when entries were
+	// written since the last one
+	Interval time.Duration
+	// Settle leaves entries younger than this for the next anchor, so ones
+	// still buffered or in open transactions (with lower IDs than entries
+	// already committed) are not skipped
+	Settle time.Duration
+	// PageSize bounds the entries loaded at a time while hashing
+	PageSize int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Interval: time.Hour,
+		Settle:   5 * time.Minute,
+		PageSize: 1000,
+	}
+}
+
+// Anchor is an attested chain head. The chain runs in ID order from
+// PrevHash, the previous anchor's head (audit.GenesisHash for the first),
+// over entries FirstLogID to LastLogID.
+type Anchor struct {
+	ID          int64     `json:"id" db:"id"`
+	FirstLogID  int64     `json:"first_log_id" db:"first_log_id"`
+	LastLogID   int64     `json:"last_log_id" db:"last_log_id"`
+	EntryCount  int       `json:"entry_count" db:"entry_count"`
+	RangeStart  time.Time `json:"range_start" db:"range_start"`
+	RangeEnd    time.Time `json:"range_end" db:"range_end"`
+	PrevHash    string    `json:"prev_hash" db:"prev_hash"`
+	ChainHash   string    `json:"chain_hash" db:"chain_hash"`
+	Notary      string    `json:"notary" db:"notary"`
+	Receipt     []byte    `json:"receipt" db:"receipt"`
+	NotarizedAt time.Time `json:"notarized_at" db:"notarized_at"`
+	AnchoredAt  time.Time `json:"anchored_at" db:"anchored_at"`
+}
+
+// Service publishes and verifies anchors
+type Service struct {
+	db     *database.DB
+	notary Notary
+	config Config
+	logger *zap.Logger
+}
+
+func NewService(db *database.DB, notary Notary, config Config, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		notary: notary,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Start publishes an anchor every Interval until ctx is cancelled
+func (s *Service) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting audit chain anchoring",
+		zap.String("notary", s.notary.Name()),
+		zap.Duration("interval", s.config.Interval),
+	)
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		anchor, err := s.Publish(ctx)
+		if err != nil {
+			metrics.AuditAnchors.WithLabelValues("failed").Inc()
+			s.logger.Error("Failed to anchor audit chain", zap.Error(err))
+			continue
+		}
+		if anchor != nil {
+			metrics.AuditAnchors.WithLabelValues("anchored").Inc()
+			s.logger.Info("Anchored audit chain",
+				zap.Int64("anchor_id", anchor.ID),
+				zap.Int64("last_log_id", anchor.LastLogID),
+				zap.Int("entries", anchor.EntryCount),
+				zap.String("chain_hash", anchor.ChainHash),
+			)
+		}
+	}
+}
+
+// Publish anchors the entries written since the last anchor, returning nil
+// when there are none
+func (s *Service) Publish(ctx context.Context) (*Anchor, error) {
+	prev, err := s.latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prevHash, afterID := audit.GenesisHash, int64(0)
+	if prev != nil {
+		prevHash, afterID = prev.ChainHash, prev.LastLogID
+	}
+
+	var upToID int64
+	err = s.db.GetContext(ctx, &upToID, `
+		SELECT COALESCE(MAX(id), 0) FROM audit_logs
+		WHERE timestamp < NOW() - make_interval(secs => $1)
+	`, s.config.Settle.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	if upToID <= afterID {
+		return nil, nil
+	}
+
+	anchor, err := s.chain(ctx, prevHash, afterID, upToID)
+	if err != nil {
+		return nil, err
+	}
+	if anchor.EntryCount == 0 {
+		return nil, nil
+	}
+
+	hash, err := hex.DecodeString(anchor.ChainHash)
+	if err != nil {
+		return nil, err
+	}
+	anchor.Receipt, anchor.NotarizedAt, err = s.notary.Anchor(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.notary.Name(), err)
+	}
+	anchor.Notary = s.notary.Name()
+
+	// The unique first_log_id keeps another instance anchoring the same
+	// entries from forking the chain
+	err = s.db.GetContext(ctx, anchor, `
+		INSERT INTO audit_anchors (
+			first_log_id, last_log_id, entry_count, range_start, range_end,
+			prev_hash, chain_hash, notary, receipt, notarized_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (first_log_id) DO NOTHING
+		RETURNING *
+	`, anchor.FirstLogID, anchor.LastLogID, anchor.EntryCount, anchor.RangeStart, anchor.RangeEnd,
+		anchor.PrevHash, anchor.ChainHash, anchor.Notary, anchor.Receipt, anchor.NotarizedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return anchor, nil
+}
+
+// chain hashes the entries with IDs in (afterID, upToID] onto prevHash
+func (s *Service) chain(ctx context.Context, prevHash string, afterID, upToID int64) (*Anchor, error) {
+	anchor := &Anchor{PrevHash: prevHash, ChainHash: prevHash}
+	for {
+		var logs []audit.AuditLog
+		err := s.db.SelectContext(ctx, &logs, `
+			SELECT * FROM audit_logs
+			WHERE id > $1 AND id <= $2
+			ORDER BY id
+			LIMIT $3
+		`, afterID, upToID, s.config.PageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(logs) == 0 {
+			return anchor, nil
+		}
+
+		if anchor.EntryCount == 0 {
+			anchor.FirstLogID, anchor.RangeStart = logs[0].ID, logs[0].Timestamp
+		}
+		last := logs[len(logs)-1]
+		anchor.LastLogID, anchor.RangeEnd = last.ID, last.Timestamp
+		anchor.EntryCount += len(logs)
+		if anchor.ChainHash, err = audit.ChainLogs(anchor.ChainHash, logs); err != nil {
+			return nil, err
+		}
+		afterID = last.ID
+	}
+}
+
+func (s *Service) latest(ctx context.Context) (*Anchor, error) {
+	var anchor Anchor
+	err := s.db.GetContext(ctx, &anchor, `SELECT * FROM audit_anchors ORDER BY last_log_id DESC LIMIT 1`)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &anchor, nil
+}
+
+// List returns the most recent anchors, newest first
+func (s *Service) List(ctx context.Context, limit int) ([]Anchor, error) {
+	anchors := []Anchor{}
+	err := s.db.Reader().SelectContext(ctx, &anchors, `
+		SELECT * FROM audit_anchors ORDER BY last_log_id DESC LIMIT $1
+	`, limit)
+	return anchors, err
+}
+
+// Verify checks every anchor covering entries in [start, end]: that its
+// receipt attests to its head, that it continues the previous anchor, and
+// that the stored entries it covers still hash to its head. It returns the
+// number of anchors checked and their problems.
+func (s *Service) Verify(ctx context.Context, start, end time.Time) (int, []audit.AnchorIssue, error) {
+	var anchors []Anchor
+	err := s.db.SelectContext(ctx, &anchors, `
+		SELECT * FROM audit_anchors
+		WHERE range_end >= $1 AND range_start <= $2
+		ORDER BY last_log_id
+	`, start, end)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	issues := []audit.AnchorIssue{}
+	for i := range anchors {
+		a := &anchors[i]
+		problem, err := s.verifyAnchor(ctx, a)
+		if err != nil {
+			return 0, nil, err
+		}
+		if problem != "" {
+			issues = append(issues, audit.AnchorIssue{AnchorID: a.ID, Problem: problem})
+		}
+	}
+	return len(anchors), issues, nil
+}
+
+// verifyAnchor returns what is wrong with the anchor, or "" when it holds
+func (s *Service) verifyAnchor(ctx context.Context, a *Anchor) (string, error) {
+	if a.Notary != s.notary.Name() {
+		return fmt.Sprintf("anchored with the %s notary, which is not configured", a.Notary), nil
+	}
+	hash, err := hex.DecodeString(a.ChainHash)
+	if err != nil {
+		return "malformed chain hash", nil
+	}
+	if _, err := s.notary.Verify(a.Receipt, hash); err != nil {
+		return err.Error(), nil
+	}
+
+	// Continuity with the previous anchor
+	var prevHash string
+	err = s.db.GetContext(ctx, &prevHash, `
+		SELECT chain_hash FROM audit_anchors WHERE last_log_id < $1 ORDER BY last_log_id DESC LIMIT 1
+	`, a.FirstLogID)
+	switch {
+	case err == sql.ErrNoRows:
+		prevHash = audit.GenesisHash
+	case err != nil:
+		return "", err
+	}
+	if prevHash != a.PrevHash {
+		return "does not continue the previous anchor's chain", nil
+	}
+
+	// Entries rewritten, added or removed since anchoring change the head
+	recomputed, err := s.chain(ctx, a.PrevHash, a.FirstLogID-1, a.LastLogID)
+	if err != nil {
+		return "", err
+	}
+	if recomputed.EntryCount != a.EntryCount {
+		return fmt.Sprintf("covers %d entries but %d are stored", a.EntryCount, recomputed.EntryCount), nil
+	}
+	if recomputed.ChainHash != a.ChainHash {
+		return "stored entries no longer hash to the anchored chain head", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,361 @@
+package anchoring
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	// Digests timestamp tokens may be signed with
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+var (
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidRSA           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECPublicKey   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDSAWithSHA2 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3} // .2 SHA-256, .3 SHA-384, .4 SHA-512
+)
+
+// TSANotary anchors chain heads with an RFC 3161 timestamp authority. The
+// receipt is the DER timestamp token, which verifies offline, e.g. with
+// "openssl ts -verify".
+type TSANotary struct {
+	url        string
+	roots      *x509.CertPool
+	httpClient *http.Client
+}
+
+var _ Notary = (*TSANotary)(nil)
+
+// NewTSANotary creates a client for the authority at url. Tokens must chain
+// to roots; with nil roots only the token's own signature is checked.
+func NewTSANotary(url string, roots *x509.CertPool) *TSANotary {
+	return &TSANotary{
+		url:        url,
+		roots:      roots,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetTransport sends requests through transport, e.g. under an outbound
+// policy
+func (t *TSANotary) SetTransport(transport http.RoundTripper) {
+	t.httpClient.Transport = transport
+}
+
+func (t *TSANotary) Name() string {
+	return NotaryTSA
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status struct {
+		Status int
+	}
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo struct {
+		EContentType asn1.ObjectIdentifier
+		EContent     []byte `asn1:"explicit,optional,tag:0"`
+	}
+	Certificates asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs         asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos  []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+}
+
+func (t *TSANotary) Anchor(ctx context.Context, hash []byte) ([]byte, time.Time, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	query, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			HashedMessage: hash,
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/timestamp-query")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to reach timestamp authority: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to read timestamp response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("timestamp authority returned status %d", resp.StatusCode)
+	}
+
+	var tsResp timeStampResp
+	if _, err := asn1.Unmarshal(body, &tsResp); err != nil {
+		return nil, time.Time{}, fmt.Errorf("malformed timestamp response: %w", err)
+	}
+	// 0 granted, 1 granted with modifications
+	if tsResp.Status.Status > 1 || len(tsResp.TimeStampToken.FullBytes) == 0 {
+		return nil, time.Time{}, fmt.Errorf("timestamp authority rejected the request with status %d", tsResp.Status.Status)
+	}
+
+	token := tsResp.TimeStampToken.FullBytes
+	at, err := t.Verify(token, hash)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return token, at, nil
+}
+
+// Verify checks that the token's imprint is hash, that it is signed by the
+// certificate it carries, and that the certificate chains to the configured
+// roots for timestamping
+func (t *TSANotary) Verify(receipt, hash []byte) (time.Time, error) {
+	invalid := func(format string, args ...interface{}) (time.Time, error) {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidReceipt, fmt.Sprintf(format, args...))
+	}
+
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(receipt, &ci); err != nil {
+		return invalid("malformed token: %v", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return invalid("token is not signed data")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return invalid("malformed signed data: %v", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return invalid("token does not hold timestamp info")
+	}
+
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &info); err != nil {
+		return invalid("malformed timestamp info: %v", err)
+	}
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) || !bytes.Equal(info.MessageImprint.HashedMessage, hash) {
+		return invalid("token is for another hash")
+	}
+
+	if len(sd.SignerInfos) != 1 {
+		return invalid("token has %d signers", len(sd.SignerInfos))
+	}
+	signer := sd.SignerInfos[0]
+	digestHash, ok := digestAlgorithm(signer.DigestAlgorithm.Algorithm)
+	if !ok {
+		return invalid("unsupported digest algorithm %v", signer.DigestAlgorithm.Algorithm)
+	}
+	if len(signer.SignedAttrs.FullBytes) == 0 {
+		return invalid("token has no signed attributes")
+	}
+
+	// Signed attributes are signed as a SET, not with their implicit tag
+	signedAttrs := append([]byte{0x31}, signer.SignedAttrs.FullBytes[1:]...)
+	messageDigest, err := attributeDigest(signedAttrs)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	h := digestHash.New()
+	h.Write(sd.EncapContentInfo.EContent)
+	if !bytes.Equal(h.Sum(nil), messageDigest) {
+		return invalid("message digest does not match the timestamp info")
+	}
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil || len(certs) == 0 {
+		return invalid("token carries no usable certificates")
+	}
+	cert := signerCertificate(certs, signer.SID)
+	if cert == nil {
+		return invalid("signer certificate not included")
+	}
+	sigAlg, ok := signatureAlgorithm(signer.SignatureAlgorithm.Algorithm, digestHash)
+	if !ok {
+		return invalid("unsupported signature algorithm %v", signer.SignatureAlgorithm.Algorithm)
+	}
+	if err := cert.CheckSignature(sigAlg, signedAttrs, signer.Signature); err != nil {
+		return invalid("signature: %v", err)
+	}
+
+	if t.roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range certs {
+			intermediates.AddCert(c)
+		}
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         t.roots,
+			Intermediates: intermediates,
+			CurrentTime:   info.GenTime,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		})
+		if err != nil {
+			return invalid("signer certificate: %v", err)
+		}
+	}
+	return info.GenTime, nil
+}
+
+func attributeDigest(signedAttrs []byte) ([]byte, error) {
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signedAttrs, &attrs, "set"); err != nil {
+		return nil, fmt.Errorf("malformed signed attributes: %v", err)
+	}
+	for _, attr := range attrs {
+		if !attr.Type.Equal(oidMessageDigest) {
+			continue
+		}
+		var digest []byte
+		if _, err := asn1.Unmarshal(attr.Values.Bytes, &digest); err != nil {
+			return nil, fmt.Errorf("malformed message digest: %v", err)
+		}
+		return digest, nil
+	}
+	return nil, fmt.Errorf("signed attributes have no message digest")
+}
+
+// signerCertificate finds the certificate sid names, by issuer and serial
+// number or by subject key identifier
+func signerCertificate(certs []*x509.Certificate, sid asn1.RawValue) *x509.Certificate {
+	var ias issuerAndSerial
+	if sid.Class == asn1.ClassUniversal {
+		if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+			return nil
+		}
+	}
+	for _, c := range certs {
+		if sid.Class == asn1.ClassContextSpecific {
+			if bytes.Equal(c.SubjectKeyId, sid.Bytes) {
+				return c
+			}
+			continue
+		}
+		if bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.Serial) == 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+func digestAlgorithm(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, true
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, true
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// signatureAlgorithm maps a SignerInfo signature algorithm, which may name
+// only the key type, to its x509 equivalent
+func signatureAlgorithm(oid asn1.ObjectIdentifier, digest crypto.Hash) (x509.SignatureAlgorithm, bool) {
+	rsa := map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA256: x509.SHA256WithRSA, crypto.SHA384: x509.SHA384WithRSA, crypto.SHA512: x509.SHA512WithRSA,
+	}
+	ecdsa := map[crypto.Hash]x509.SignatureAlgorithm{
+		crypto.SHA256: x509.ECDSAWithSHA256, crypto.SHA384: x509.ECDSAWithSHA384, crypto.SHA512: x509.ECDSAWithSHA512,
+	}
+	switch {
+	case oid.Equal(oidRSA):
+		alg, ok := rsa[digest]
+		return alg, ok
+	case oid.Equal(oidSHA256WithRSA):
+		return x509.SHA256WithRSA, true
+	case oid.Equal(oidSHA384WithRSA):
+		return x509.SHA384WithRSA, true
+	case oid.Equal(oidSHA512WithRSA):
+		return x509.SHA512WithRSA, true
+	case oid.Equal(oidECPublicKey):
+		alg, ok := ecdsa[digest]
+		return alg, ok
+	case len(oid) == len(oidECDSAWithSHA2)+1 && oid[:len(oidECDSAWithSHA2)].Equal(oidECDSAWithSHA2):
+		switch oid[len(oid)-1] {
+		case 2:
+			return x509.ECDSAWithSHA256, true
+		case 3:
+			return x509.ECDSAWithSHA384, true
+		case 4:
+			return x509.ECDSAWithSHA512, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AccessHandler exposes the caller's effective permissions so clients can
+// decide what to show, using the same RBAC resolution as RequirePermission
+type AccessHandler struct {
+	roles  *rbac.RoleStore
+	logger *zap.Logger
+}
+
+func NewAccessHandler(roles *rbac.RoleStore, logger *zap.Logger) *AccessHandler {
+	return &AccessHandler{
+		roles:  roles,
+		logger: logger,
+	}
+}
+
+type PermissionsResponse struct {
+	OrganizationID string            `json:"organization_id,omitempty"`
+	Role           string            `json:"role"`
+	Permissions    []rbac.Permission `json:"permissions"`
+}
+
+type AccessCheck struct {
+	Resource string `json:"resource" binding:"required"`
+	Action   string `json:"action" binding:"required"`
+}
+
+type AccessCheckRequest struct {
+	OrganizationID string        `json:"organization_id"`
+	Checks         []AccessCheck `json:"checks" binding:"required,min=1,max=100,dive"`
+}
+
+type AccessDecision struct {
+	Resource   string `json:"resource"`
+	Action     string `json:"action"`
+	Permission string `json:"permission"`
+	Allowed    bool   `json:"allowed"`
+}
+
+type AccessCheckResponse struct {
+	OrganizationID string           `json:"organization_id,omitempty"`
+	Role           string           `json:"role"`
+	Results        []AccessDecision `json:"results"`
+}
+
+// MyPermissions handles GET /api/v1/me/permissions?org=...
+func (h *AccessHandler) MyPermissions(c *gin.Context) {
+	orgID, role, ok := h.resolveRole(c, c.Query("org"))
+	if !ok {
+		return
+	}
+
+	perms, err := h.roles.Permissions(c.Request.Context(), orgID, role)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to resolve role permissions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve permissions"})
+		return
+	}
+	if perms == nil {
+		perms = []rbac.Permission{}
+	}
+
+	c.JSON(http.StatusOK, PermissionsResponse{
+		OrganizationID: orgID,
+		Role:           string(role),
+		Permissions:    perms,
+	})
+}
+
+// CheckAccess handles POST /api/v1/access/check
+func (h *AccessHandler) CheckAccess(c *gin.Context) {
+	var req AccessCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	orgID, role, ok := h.resolveRole(c, req.OrganizationID)
+	if !ok {
+		return
+	}
+
+	results := make([]AccessDecision, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		perm := rbac.PermissionFor(check.Resource, check.Action)
+
+		allowed, err := h.roles.HasPermission(c.Request.Context(), orgID, role, perm)
+		if err != nil {
+			logging.FromContext(c.Request.Context(), h.logger).Error("Failed to resolve role permissions", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+
+		results = append(results, AccessDecision{
+			Resource:   check.Resource,
+			Action:     check.Action,
+			Permission: string(perm),
+			Allowed:    allowed,
+		})
+	}
+
+	c.JSON(http.StatusOK, AccessCheckResponse{
+		OrganizationID: orgID,
+		Role:           string(role),
+		Results:        results,
+	})
+}
+
+// resolveRole determines the caller's role in orgID, defaulting to the
+// organization and role established by the auth middleware
+func (h *AccessHandler) resolveRole(c *gin.Context, orgID string) (string, rbac.Role, bool) {
+	if orgID == "" || orgID == c.GetString("organization_id") {
+		return c.GetString("organization_id"), rbac.Role(c.GetString("user_role")), true
+	}
+
+	role, err := h.roles.MemberRole(c.Request.Context(), c.GetString("user_id"), orgID)
+	if err == rbac.ErrNotMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return "", "", false
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to verify membership", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return "", "", false
+	}
+
+	return orgID, role, true
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminUserHandler lets platform admins find users and enable or disable
+// their accounts
+type AdminUserHandler struct {
+	authService *auth.AuthService
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewAdminUserHandler(authService *auth.AuthService, auditLogger Auditor, logger *zap.Logger) *AdminUserHandler {
+	return &AdminUserHandler{
+		authService: authService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// UserStatusRequest payload
+type UserStatusRequest struct {
+	Active bool   `json:"active"`
+	Reason string `json:"reason" binding:"required,min=10,max=2000"`
+}
+
+// UserStatusResponse is the user after a status change
+type UserStatusResponse struct {
+	User            auth.AdminUser `json:"user"`
+	SessionsRevoked int            `json:"sessions_revoked"`
+}
+
+// ListUsers handles GET /api/v1/admin/users?q=alice&limit=50
+func (h *AdminUserHandler) ListUsers(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	users, err := h.authService.SearchUsers(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list users", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// SetUserStatus handles PUT /api/v1/admin/users/:id/status. Disabling an
+// account signs the user out everywhere.
+func (h *AdminUserHandler) SetUserStatus(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	adminID := c.GetString("user_id")
+	userID := c.Param("id")
+
+	var req UserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if userID == adminID && !req.Active {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot disable your own account"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, revoked, err := h.authService.SetUserActive(ctx, userID, req.Active)
+	if err == auth.ErrUserNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to update user status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
+		return
+	}
+
+	action := "user_enabled"
+	if !req.Active {
+		action = "user_disabled"
+	}
+	h.auditLogger.LogSecurityEvent(ctx, adminID, action, "user:"+userID, "high", map[string]interface{}{
+		"email":            user.Email,
+		"reason":           req.Reason,
+		"sessions_revoked": revoked,
+	})
+
+	c.JSON(http.StatusOK, UserStatusResponse{User: *user, SessionsRevoked: revoked})
+}
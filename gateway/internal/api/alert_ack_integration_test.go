@@ -0,0 +1,168 @@
+//go:build integration
+
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/api"
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/cyper-security/gateway/internal/testenv"
+	"github.com/gin-gonic/gin"
+)
+
+// raiseAlert records a failed-login alert for the organizations
+func raiseAlert(t *testing.T, env *testenv.Env, detectedAt time.Time, orgIDs ...string) string {
+	t.Helper()
+	anomaly := audit.Anomaly{
+		Kind:       audit.AnomalyFailedLogins,
+		Target:     "203.0.113.9",
+		Count:      12,
+		Details:    map[string]interface{}{"ip_address": "203.0.113.9"},
+		DetectedAt: detectedAt,
+	}
+	if err := audit.NewAlertStore(env.DB).Record(context.Background(), anomaly, orgIDs); err != nil {
+		t.Fatal(err)
+	}
+	return anomaly.AlertID()
+}
+
+// acknowledgedBy returns who acknowledged the organization's alert, "" if no one
+func acknowledgedBy(t *testing.T, env *testenv.Env, orgID, alertID string) string {
+	t.Helper()
+	var userID *string
+	err := env.DB.GetContext(context.Background(), &userID, `
+		SELECT acknowledged_by FROM security_alerts WHERE id = $1 AND organization_id = $2
+	`, alertID, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID == nil {
+		return ""
+	}
+	return *userID
+}
+
+func TestAcknowledgeAlertCommand(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	org := env.CreateOrganization(t, "")
+	otherOrg := env.CreateOrganization(t, "")
+	admin := env.CreateUser(t, testenv.UserOptions{})
+	viewer := env.CreateUser(t, testenv.UserOptions{})
+	outsider := env.CreateUser(t, testenv.UserOptions{})
+	env.AddMember(t, admin.ID, org.ID, rbac.RoleAdmin)
+	env.AddMember(t, viewer.ID, org.ID, rbac.RoleViewer)
+	env.AddMember(t, outsider.ID, otherOrg.ID, rbac.RoleAdmin)
+
+	alertID := raiseAlert(t, env, time.Now(), org.ID)
+	otherAlertID := raiseAlert(t, env, time.Now().Add(-time.Hour), otherOrg.ID)
+	commander := api.NewScanCommander(nil, env.Roles, audit.NewAlertStore(env.DB), env.Redis, env.Audit, env.Logger)
+
+	tests := []struct {
+		name     string
+		caller   realtime.Identity
+		alertID  string
+		wantCode string // "" for success
+	}{
+		{"viewer lacks permission", realtime.Identity{UserID: viewer.ID, OrgID: org.ID}, alertID, "forbidden"},
+		{"non-member", realtime.Identity{UserID: outsider.ID, OrgID: org.ID}, alertID, "forbidden"},
+		{"another organization's alert", realtime.Identity{UserID: admin.ID, OrgID: org.ID}, otherAlertID, "not_found"},
+		{"unknown alert", realtime.Identity{UserID: admin.ID, OrgID: org.ID}, "failed_logins::1", "not_found"},
+		{"admin", realtime.Identity{UserID: admin.ID, OrgID: org.ID}, alertID, ""},
+		{"already acknowledged", realtime.Identity{UserID: admin.ID, OrgID: org.ID}, alertID, "conflict"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := commander.Execute(ctx, tt.caller, realtime.CommandAcknowledgeAlert, &realtime.AcknowledgeAlertCommand{AlertID: tt.alertID})
+			if tt.wantCode != "" {
+				var cmdErr *realtime.CommandError
+				if !errors.As(err, &cmdErr) || cmdErr.Code != tt.wantCode {
+					t.Fatalf("Execute = %v, want a %s command error", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if ack, ok := resp.(api.AlertAcknowledgement); !ok || ack.AlertID != tt.alertID {
+				t.Fatalf("Execute = %#v, want an acknowledgement of %s", resp, tt.alertID)
+			}
+		})
+	}
+
+	if got := acknowledgedBy(t, env, org.ID, alertID); got != admin.ID {
+		t.Errorf("alert acknowledged by %q, want the admin", got)
+	}
+	if got := acknowledgedBy(t, env, otherOrg.ID, otherAlertID); got != "" {
+		t.Errorf("another organization's alert acknowledged by %q", got)
+	}
+}
+
+func TestSlackAcknowledgeAlert(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	org := env.CreateOrganization(t, "")
+	otherOrg := env.CreateOrganization(t, "")
+	admin := env.CreateUser(t, testenv.UserOptions{})
+	viewer := env.CreateUser(t, testenv.UserOptions{})
+	env.AddMember(t, admin.ID, org.ID, rbac.RoleAdmin)
+	env.AddMember(t, viewer.ID, org.ID, rbac.RoleViewer)
+
+	_, err := env.DB.ExecContext(ctx, `INSERT INTO slack_workspaces (team_id, organization_id) VALUES ('T1', $1)`, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.DB.ExecContext(ctx, `
+		INSERT INTO slack_user_links (team_id, slack_user_id, user_id) VALUES ('T1', 'UADMIN', $1), ('T1', 'UVIEWER', $2)
+	`, admin.ID, viewer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alertID := raiseAlert(t, env, time.Now(), org.ID)
+	otherAlertID := raiseAlert(t, env, time.Now().Add(-time.Hour), otherOrg.ID)
+	handler := api.NewSlackHandler(env.DB, env.Redis, env.Roles, audit.NewAlertStore(env.DB), nil, nil, nil, "web", env.Audit, env.Logger)
+
+	click := func(slackUserID, alertID string) {
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type":    "block_actions",
+			"team":    map[string]string{"id": "T1"},
+			"user":    map[string]string{"id": slackUserID},
+			"actions": []map[string]string{{"action_id": "ack_alert", "value": alertID}},
+		})
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/interactions", handler.Interaction)
+		req := httptest.NewRequest(http.MethodPost, "/interactions", strings.NewReader(url.Values{"payload": {string(payload)}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("interaction status = %d", rec.Code)
+		}
+	}
+
+	click("UVIEWER", alertID)
+	if got := acknowledgedBy(t, env, org.ID, alertID); got != "" {
+		t.Fatalf("a viewer acknowledged the alert")
+	}
+	click("UADMIN", otherAlertID)
+	if got := acknowledgedBy(t, env, otherOrg.ID, otherAlertID); got != "" {
+		t.Fatalf("another organization's alert was acknowledged from this workspace")
+	}
+	click("UADMIN", alertID)
+	if got := acknowledgedBy(t, env, org.ID, alertID); got != admin.ID {
+		t.Fatalf("alert acknowledged by %q, want the admin", got)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/cyper-security/gateway/internal/reports"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AnalysisHandler streams the brain service's analysis of scans
+type AnalysisHandler struct {
+	db       *database.DB
+	reports  *reports.Service
+	brain    *brain.Client
+	policies *rbac.PolicyEngine
+	hub      *realtime.Hub
+	logger   *zap.Logger
+}
+
+func NewAnalysisHandler(db *database.DB, reportService *reports.Service, brainClient *brain.Client, policies *rbac.PolicyEngine, hub *realtime.Hub, logger *zap.Logger) *AnalysisHandler {
+	return &AnalysisHandler{
+		db:       db,
+		reports:  reportService,
+		brain:    brainClient,
+		policies: policies,
+		hub:      hub,
+		logger:   logger,
+	}
+}
+
+// AnalyzeScanRequest is the optional body of an analysis request
+type AnalyzeScanRequest struct {
+	Context map[string]interface{} `json:"context,omitempty"` // Extra context for the analyst, e.g. the asset's role
+}
+
+// AnalyzeScan handles POST /api/v1/scans/:id/analyze. The brain's analysis of
+// the scan's findings is relayed as server-sent "chunk" events while it is
+// written, ending with a "done" or "error" event. With broadcast=true the
+// chunks are also pushed to the caller's WebSocket connections. Disconnecting
+// cancels the analysis.
+func (h *AnalysisHandler) AnalyzeScan(c *gin.Context) {
+	scanID := c.Param("id")
+	ctx := c.Request.Context()
+
+	if !authorizeScanPolicy(c, h.db, h.policies, scanID, rbac.PermGenerateReport, h.logger) {
+		return
+	}
+
+	var body AnalyzeScanRequest
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		bindError(c, err)
+		return
+	}
+
+	req, err := h.reports.AnalysisInput(ctx, scanID, c.GetString("organization_id"))
+	if err == reports.ErrScanNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scan not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load scan results", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scan results"})
+		return
+	}
+	req.Context = body.Context
+
+	// The request context bounds the brain call, so a disconnect stops it
+	stream, err := h.brain.StreamAnalysis(ctx, req)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to start scan analysis", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start analysis"})
+		return
+	}
+	defer stream.Close()
+
+	userID := c.GetString("user_id")
+	broadcast := c.Query("broadcast") == "true"
+	relay := func(event realtime.AnalysisChunkEvent) {
+		if broadcast {
+			h.hub.PublishToUser(userID, event)
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		text, err := stream.Next()
+		switch {
+		case err == nil:
+			c.SSEvent("chunk", gin.H{"text": text})
+			relay(realtime.AnalysisChunkEvent{ScanID: scanID, Text: text})
+			return true
+		case err == io.EOF:
+			c.SSEvent("done", gin.H{})
+			relay(realtime.AnalysisChunkEvent{ScanID: scanID, Done: true})
+		case ctx.Err() != nil:
+			// The caller went away; closing the stream cancels the brain
+		default:
+			logging.FromContext(ctx, h.logger).Warn("Scan analysis failed", zap.Error(err))
+			c.SSEvent("error", gin.H{"error": "Analysis failed"})
+			relay(realtime.AnalysisChunkEvent{ScanID: scanID, Error: "Analysis failed"})
+		}
+		return false
+	})
+}
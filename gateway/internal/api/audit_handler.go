@@ -1,34 +1,181 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
+	"runtime"
+	"strconv"
 	"time"
 
+	"github.com/cyper-security/gateway/internal/anchoring"
 	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/repository"
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
 type AuditHandler struct {
-	db     *sqlx.DB
-	logger *zap.Logger
-	signer *audit.AuditSigner
+	repos       *repository.Repositories
+	roles       *rbac.RoleStore
+	stream      *audit.Stream
+	anchors     *anchoring.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+	signer      *audit.AuditSigner
+
+	summaryCache *redis.Client
+	summaryTTL   time.Duration
 }
 
-func NewAuditHandler(db *sqlx.DB, logger *zap.Logger) (*AuditHandler, error) {
+// NewAuditHandler creates the handler; anchors is nil when the hash chain is
+// not anchored externally
+func NewAuditHandler(repos *repository.Repositories, roles *rbac.RoleStore, stream *audit.Stream, anchors *anchoring.Service, auditLogger Auditor, logger *zap.Logger) (*AuditHandler, error) {
 	signer, err := audit.NewAuditSigner(logger)
 	if err != nil {
 		return nil, err
 	}
 
 	return &AuditHandler{
-		db:     db,
-		logger: logger,
-		signer: signer,
+		repos:       repos,
+		roles:       roles,
+		stream:      stream,
+		anchors:     anchors,
+		auditLogger: auditLogger,
+		logger:      logger,
+		signer:      signer,
 	}, nil
 }
 
+// ListOrganizationAuditLogs handles GET /api/v1/organizations/:id/audit.
+// Only entries attributed to the organization are returned.
+func (h *AuditHandler) ListOrganizationAuditLogs(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewAuditLogs, h.logger); !ok {
+		return
+	}
+
+	limit, offset := 100, 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		offset = n
+	}
+
+	var startTime, endTime *time.Time
+	for _, bound := range []struct {
+		name string
+		dest **time.Time
+	}{{"start_time", &startTime}, {"end_time", &endTime}} {
+		if v := c.Query(bound.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + bound.name + " format"})
+				return
+			}
+			*bound.dest = &t
+		}
+	}
+
+	logs, err := h.repos.Audit.List(c.Request.Context(), repository.AuditFilter{
+		OrganizationID: orgID,
+		UserID:         c.Query("user_id"),
+		Action:         c.Query("action"),
+		Severity:       c.Query("severity"),
+		Status:         c.Query("status"),
+		ResourceType:   c.Query("resource_type"),
+		Start:          startTime,
+		End:            endTime,
+		Limit:          limit,
+		Offset:         offset,
+	})
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list organization audit logs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":   logs,
+		"count":  len(logs),
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// streamHeartbeat keeps idle audit streams open through proxies
+const streamHeartbeat = 15 * time.Second
+
+// StreamAuditLogs handles GET /api/v1/audit/stream. New entries of the
+// organization (organization_id, defaulting to the caller's current one) are
+// sent as server-sent "audit" events, optionally filtered by minimum severity
+// and action prefix. A "dropped" event reports entries missed by a client that
+// could not keep up.
+func (h *AuditHandler) StreamAuditLogs(c *gin.Context) {
+	orgID := c.DefaultQuery("organization_id", c.GetString("organization_id"))
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "organization_id required"})
+		return
+	}
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewAuditLogs, h.logger); !ok {
+		return
+	}
+
+	severity := c.Query("severity")
+	if severity != "" && !audit.ValidSeverity(severity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid severity"})
+		return
+	}
+
+	sub := h.stream.Subscribe(audit.StreamFilter{
+		OrganizationID: orgID,
+		MinSeverity:    severity,
+		ActionPrefix:   c.Query("action"),
+	}, 256)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	var reportedDropped uint64
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case log := <-sub.C:
+			if dropped := sub.Dropped(); dropped > reportedDropped {
+				c.SSEvent("dropped", gin.H{"count": dropped - reportedDropped})
+				reportedDropped = dropped
+			}
+			c.SSEvent("audit", log)
+			return true
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
 // ExportAuditLogs handles GET /api/v1/audit/export
 func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
 	// Parse time range
@@ -52,16 +199,16 @@ func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
 		return
 	}
 
-	// Fetch audit logs
-	var logs []audit.AuditLog
-	err = h.db.Select(&logs, `
-		SELECT * FROM audit_logs
-		WHERE timestamp BETWEEN $1 AND $2
-		ORDER BY timestamp DESC
-	`, startTime, endTime)
+	// Signed, hash-chained bundles for offline verification (gateway audit verify)
+	if format := c.Query("format"); format == "ndjson" || format == "csv" {
+		h.exportBundle(c, format, startTime, endTime)
+		return
+	}
 
+	// Fetch audit logs
+	logs, err := h.repos.Audit.Range(c.Request.Context(), startTime, endTime, false)
 	if err != nil {
-		h.logger.Error("Failed to export audit logs", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to export audit logs", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export logs"})
 		return
 	}
@@ -76,20 +223,53 @@ func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
 	})
 }
 
+// exportBundle streams the range as an NDJSON or CSV bundle in ID order
+func (h *AuditHandler) exportBundle(c *gin.Context, format string, startTime, endTime time.Time) {
+	logs, err := h.repos.Audit.Range(c.Request.Context(), startTime, endTime, true)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to export audit logs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export logs"})
+		return
+	}
+
+	bundle, err := audit.NewBundle(logs, startTime, endTime, h.signer)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to build audit bundle", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export logs"})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s-%s.%s", startTime.UTC().Format("20060102T150405Z"), endTime.UTC().Format("20060102T150405Z"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		err = bundle.WriteCSV(c.Writer)
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		err = bundle.WriteNDJSON(c.Writer)
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to write audit bundle", zap.Error(err))
+	}
+}
+
+// VerifySignatureRequest payload
+type VerifySignatureRequest struct {
+	LogID int64 `json:"log_id" binding:"required"`
+}
+
 // VerifySignature handles POST /api/v1/audit/verify
 func (h *AuditHandler) VerifySignature(c *gin.Context) {
-	var req struct {
-		LogID int64 `json:"log_id" binding:"required"`
-	}
+	var req VerifySignatureRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
 	// Fetch log
-	var log audit.AuditLog
-	err := h.db.Get(&log, "SELECT * FROM audit_logs WHERE id = $1", req.LogID)
+	log, err := h.repos.Audit.Get(c.Request.Context(), req.LogID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Log not found"})
 		return
@@ -121,7 +301,7 @@ func (h *AuditHandler) VerifySignature(c *gin.Context) {
 	// Verify signature
 	valid, err := h.signer.VerifySignature(signableLog, *log.Signature, *log.SignerPublicKey)
 	if err != nil {
-		h.logger.Error("Failed to verify signature", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to verify signature", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Verification failed"})
 		return
 	}
@@ -135,6 +315,104 @@ func (h *AuditHandler) VerifySignature(c *gin.Context) {
 	})
 }
 
+// verifyRangePageSize is how many entries are loaded at a time while
+// verifying a range
+const verifyRangePageSize = 1000
+
+// VerifyRangeRequest payload
+type VerifyRangeRequest struct {
+	StartTime time.Time `json:"start_time" binding:"required"`
+	EndTime   time.Time `json:"end_time" binding:"required"`
+}
+
+// VerifyRange handles POST /api/v1/audit/verify-range. Every signature in the
+// range is checked server-side in parallel, as are the external anchors
+// covering it; the run itself is audited, as a security event when any
+// signature or anchor is invalid.
+func (h *AuditHandler) VerifyRange(c *gin.Context) {
+	var req VerifyRangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.EndTime.Before(req.StartTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_time must not be before start_time"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	next := func(ctx context.Context, afterID int64) ([]audit.AuditLog, error) {
+		return h.repos.Audit.Page(ctx, req.StartTime, req.EndTime, afterID, verifyRangePageSize)
+	}
+	result, err := audit.VerifyRange(ctx, req.StartTime, req.EndTime, next, runtime.NumCPU())
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to verify audit log range", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Verification failed"})
+		return
+	}
+	if h.anchors != nil {
+		result.AnchorsChecked, result.AnchorIssues, err = h.anchors.Verify(ctx, req.StartTime, req.EndTime)
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to verify audit anchors", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Verification failed"})
+			return
+		}
+	}
+
+	userID := c.GetString("user_id")
+	details := map[string]interface{}{
+		"start_time": req.StartTime,
+		"end_time":   req.EndTime,
+		"entries":    result.Entries,
+		"verified":   result.Verified,
+		"unsigned":   result.Unsigned,
+		"invalid":    result.Invalid,
+		"anchors":    result.AnchorsChecked,
+	}
+	if result.OK() {
+		h.auditLogger.LogSuccess(ctx, userID, "audit.verify_range", "audit_log", "", details)
+	} else {
+		details["invalid_ids"] = result.InvalidIDs
+		details["anchor_issues"] = result.AnchorIssues
+		h.auditLogger.LogSecurityEvent(ctx, userID, "audit.verify_range", "audit_log", "critical", details)
+		logging.FromContext(ctx, h.logger).Warn("Audit log range failed verification",
+			zap.Int("invalid", result.Invalid),
+			zap.Int("anchor_issues", len(result.AnchorIssues)),
+			zap.Time("start_time", req.StartTime),
+			zap.Time("end_time", req.EndTime),
+		)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListAnchors handles GET /api/v1/audit/anchors, the latest external anchors
+// of the audit hash chain with their notary receipts
+func (h *AuditHandler) ListAnchors(c *gin.Context) {
+	if h.anchors == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Audit anchoring is not enabled"})
+		return
+	}
+
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+
+	anchors, err := h.anchors.List(c.Request.Context(), limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list audit anchors", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit anchors"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"anchors": anchors, "count": len(anchors)})
+}
+
 func stringPtrOrEmpty(s *string) string {
 	if s == nil {
 		return ""
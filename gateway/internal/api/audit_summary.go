@@ -0,0 +1,159 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	auditSummaryCachePrefix = "audit:summary:"
+	// auditSummaryMaxRange bounds one summary, which scans the whole range
+	auditSummaryMaxRange    = 366 * 24 * time.Hour
+	auditSummaryDefaultSpan = 30 * 24 * time.Hour
+)
+
+// AuditSummaryResponse is an aggregated view of an organization's (or one of
+// its users') audit trail over a time range
+type AuditSummaryResponse struct {
+	OrganizationID string    `json:"organization_id"`
+	UserID         string    `json:"user_id,omitempty"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Timezone       string    `json:"timezone"`
+	repository.AuditSummary
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// SetSummaryCache caches audit summaries in Redis for ttl. Without it every
+// request runs the aggregates.
+func (h *AuditHandler) SetSummaryCache(client *redis.Client, ttl time.Duration) {
+	h.summaryCache = client
+	h.summaryTTL = ttl
+}
+
+// GetAuditSummary handles GET /api/v1/audit/summary. It aggregates the
+// entries of organization_id (defaulting to the caller's current one),
+// optionally only user_id's, between start_time and end_time (the last 30
+// days by default): actions by type, the most acted-on targets, failure
+// rates, severities and entries by hour of day in timezone (UTC by default).
+func (h *AuditHandler) GetAuditSummary(c *gin.Context) {
+	orgID := c.DefaultQuery("organization_id", c.GetString("organization_id"))
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "organization_id required"})
+		return
+	}
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewAuditLogs, h.logger); !ok {
+		return
+	}
+
+	// Default bounds are truncated so repeated requests share a cache entry
+	end := time.Now().UTC().Truncate(time.Minute)
+	if v := c.Query("end_time"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_time format"})
+			return
+		}
+		end = t.UTC()
+	}
+	start := end.Add(-auditSummaryDefaultSpan)
+	if v := c.Query("start_time"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_time format"})
+			return
+		}
+		start = t.UTC()
+	}
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_time must be before end_time"})
+		return
+	}
+	if end.Sub(start) > auditSummaryMaxRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "time range must be at most 366 days"})
+		return
+	}
+
+	top := 10
+	if v := c.Query("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top must be between 1 and 100"})
+			return
+		}
+		top = n
+	}
+	timezone := c.DefaultQuery("timezone", "UTC")
+	if _, err := time.LoadLocation(timezone); err != nil || strings.EqualFold(timezone, "local") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	filter := repository.AuditSummaryFilter{
+		OrganizationID: orgID,
+		UserID:         c.Query("user_id"),
+		Start:          start,
+		End:            end,
+		Top:            top,
+		Timezone:       timezone,
+	}
+	ctx := c.Request.Context()
+	key := auditSummaryKey(filter)
+	if h.summaryCache != nil {
+		if data, err := h.summaryCache.Get(ctx, key).Bytes(); err == nil {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+			return
+		}
+	}
+
+	summary, err := h.repos.Audit.Summary(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to summarize audit logs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarize audit logs"})
+		return
+	}
+
+	resp := AuditSummaryResponse{
+		OrganizationID: orgID,
+		UserID:         filter.UserID,
+		Start:          start,
+		End:            end,
+		Timezone:       timezone,
+		AuditSummary:   *summary,
+		GeneratedAt:    time.Now().UTC(),
+	}
+	if h.summaryCache != nil {
+		if data, err := json.Marshal(resp); err == nil {
+			if err := h.summaryCache.Set(ctx, key, data, h.summaryTTL).Err(); err != nil {
+				logging.FromContext(ctx, h.logger).Warn("Failed to cache audit summary", zap.Error(err))
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// auditSummaryKey identifies a summary's parameters in the cache
+func auditSummaryKey(f repository.AuditSummaryFilter) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		f.OrganizationID,
+		f.UserID,
+		f.Start.Format(time.RFC3339Nano),
+		f.End.Format(time.RFC3339Nano),
+		strconv.Itoa(f.Top),
+		f.Timezone,
+	}, "|")))
+	return auditSummaryCachePrefix + hex.EncodeToString(sum[:])
+}
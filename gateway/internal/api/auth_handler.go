@@ -1,19 +1,23 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/cyper-security/gateway/internal/auth"
-	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/maintenance"
+	"github.com/cyper-security/gateway/internal/metrics"
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	authService *auth.AuthService
-	auditLogger *audit.AuditLogger
+	authService Authenticator
+	auditLogger Auditor
 }
 
-func NewAuthHandler(authService *auth.AuthService, auditLogger *audit.AuditLogger) *AuthHandler {
+func NewAuthHandler(authService Authenticator, auditLogger Auditor) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
 		auditLogger: auditLogger,
@@ -24,7 +28,7 @@ func NewAuthHandler(authService *auth.AuthService, auditLogger *audit.AuditLogge
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req auth.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
@@ -53,7 +57,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req auth.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
@@ -62,14 +66,17 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	loginResp, err := h.authService.Login(c.Request.Context(), req, ipAddress, userAgent)
 	if err != nil {
+		metrics.AuthAttempts.WithLabelValues("failure").Inc()
 		h.auditLogger.LogFailure(c.Request.Context(), "", "login_attempt", err.Error(), map[string]interface{}{
 			"email":      req.Email,
 			"ip_address": ipAddress,
+			"user_agent": userAgent,
 		})
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		respondLoginError(c, err)
 		return
 	}
 
+	metrics.AuthAttempts.WithLabelValues("success").Inc()
 	h.auditLogger.LogSuccess(c.Request.Context(), loginResp.User.ID, "login_success", "session", "", map[string]interface{}{
 		"email":      loginResp.User.Email,
 		"ip_address": ipAddress,
@@ -78,57 +85,295 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, loginResp)
 }
 
+// respondLoginError answers a failed login, by any method, with the error
+// the client can act on
+func respondLoginError(c *gin.Context, err error) {
+	var inMaintenance *maintenance.ActiveError
+	if errors.As(err, &inMaintenance) {
+		maintenance.Abort(c, inMaintenance.State)
+		return
+	}
+	if err == auth.ErrTermsNotAccepted {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "terms of use must be accepted before login",
+			"code":  "terms_not_accepted",
+		})
+		return
+	}
+	if err == auth.ErrIPNotAllowed {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "sign-in from this address is not allowed by your organization",
+			"code":  "ip_not_allowed",
+		})
+		return
+	}
+	if err == auth.ErrSessionLimit {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "too many active sessions; sign out of another device first",
+			"code":  "session_limit",
+		})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+}
+
 // Logout handler
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID := c.GetString("user_id")
-	
-	// TODO: Revoke session in database
-	
-	h.auditLogger.LogSuccess(c.Request.Context(), userID, "logout", "session", "", nil)
-	
+	sessionID := c.GetString("session_id")
+
+	if err := h.authService.Logout(c.Request.Context(), userID, sessionID); err != nil && err != auth.ErrSessionNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to log out"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "logout", "session", sessionID, nil)
+
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// AcceptTermsRequest payload
+type AcceptTermsRequest struct {
+	UserID       string `json:"user_id" binding:"required"`
+	TermsVersion string `json:"terms_version" binding:"required"`
+}
+
 // AcceptTerms handler
 func (h *AuthHandler) AcceptTerms(c *gin.Context) {
-	var req struct {
-		UserID        string `json:"user_id" binding:"required"`
-		TermsVersion  string `json:"terms_version" binding:"required"`
-		AcceptanceIP  string `json:"acceptance_ip"`
-	}
+	var req AcceptTermsRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
+		return
+	}
+
+	ipAddress := c.ClientIP()
+
+	acceptance, err := h.authService.AcceptTerms(c.Request.Context(), req.UserID, req.TermsVersion, ipAddress, c.GetHeader("User-Agent"))
+	switch err {
+	case nil:
+	case auth.ErrTermsVersionMismatch:
+		c.JSON(http.StatusConflict, gin.H{"error": "terms version is outdated, fetch the current terms"})
+		return
+	case auth.ErrNoTermsPublished:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no terms of use published"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to accept terms"})
 		return
 	}
 
-	// TODO: Update user with terms acceptance
-	
 	h.auditLogger.LogSecurityEvent(c.Request.Context(), req.UserID, "terms_accepted", "", "info", map[string]interface{}{
-		"terms_version": req.TermsVersion,
-		"ip_address":    req.AcceptanceIP,
+		"terms_version": acceptance.TermsVersion,
+		"ip_address":    ipAddress,
 	})
 
 	c.JSON(http.StatusOK, gin.H{
-		"accepted_at":   "2025-12-30T15:00:00Z",
-		"terms_version": req.TermsVersion,
+		"accepted_at":   acceptance.AcceptedAt,
+		"terms_version": acceptance.TermsVersion,
 	})
 }
 
-// AuthPulse handler
+// GetTerms handles GET /api/v1/auth/terms
+func (h *AuthHandler) GetTerms(c *gin.Context) {
+	terms, err := h.authService.CurrentTerms(c.Request.Context())
+	if err == auth.ErrNoTermsPublished {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no terms of use published"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get terms"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":      terms.Version,
+		"title":        terms.Title,
+		"content":      terms.Content,
+		"document_url": terms.DocumentURL.String,
+		"effective_at": terms.EffectiveAt,
+	})
+}
+
+// AuthPulse handles GET /api/v1/auth/pulse. Clients call it periodically to
+// learn whether their session is still authorized and which features it
+// currently carries, without waiting for the token to expire.
 func (h *AuthHandler) AuthPulse(c *gin.Context) {
 	userID := c.GetString("user_id")
-	features := c.GetStringSlice("features")
 
-	// TODO: Check with central authorization server
-	
-	// Log pulse check
-	h.auditLogger.LogAction(c.Request.Context(), userID, "authorization_pulse", "", nil)
+	result, err := h.authService.Pulse(c.Request.Context(), c.GetString("session_id"), userID, c.GetString("token_org_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check authorization"})
+		return
+	}
+
+	h.auditLogger.LogAction(c.Request.Context(), userID, "authorization_pulse", "", map[string]interface{}{
+		"status": result.Status,
+		"reason": result.Reason,
+	})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListSessions handles GET /api/v1/auth/sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// RevokeSession handles DELETE /api/v1/auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	sessionID := c.Param("id")
+
+	err := h.authService.RevokeSession(c.Request.Context(), userID, sessionID)
+	if err == auth.ErrSessionNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(c.Request.Context(), userID, "session_revoked", "", "medium", map[string]interface{}{
+		"session_id": sessionID,
+		"current":    sessionID == c.GetString("session_id"),
+		"ip_address": c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Session revoked",
+		"session_id": sessionID,
+	})
+}
+
+// SwitchOrgRequest payload
+type SwitchOrgRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required,uuid"`
+}
+
+// SwitchOrganization handles POST /api/v1/auth/switch-org. The returned
+// token replaces the caller's current one, scoped to the chosen organization
+// so organization-level routes act on it without an explicit ID.
+func (h *AuthHandler) SwitchOrganization(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if c.GetString("impersonator_id") != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot switch organization while impersonating"})
+		return
+	}
+
+	var req SwitchOrgRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	resp, err := h.authService.SwitchOrganization(c.Request.Context(), userID, c.GetString("session_id"), req.OrganizationID)
+	switch err {
+	case nil:
+	case auth.ErrNotOrgMember:
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a member of this organization"})
+		return
+	case auth.ErrOrganizationInactive:
+		c.JSON(http.StatusForbidden, gin.H{"error": "organization is deactivated"})
+		return
+	case auth.ErrSessionNotFound, auth.ErrUserNotFound:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "session revoked or expired"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to switch organization"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "organization_switched", "organization", req.OrganizationID, map[string]interface{}{
+		"from_organization_id": c.GetString("organization_id"),
+		"role":                 resp.Organization.Role,
+		"session_id":           c.GetString("session_id"),
+	})
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeSessionByLink handles GET/POST /api/v1/auth/sessions/revoke, the
+// one-click link sent in new-login alerts. The signed token is the only credential.
+func (h *AuthHandler) RevokeSessionByLink(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	userID, sessionID, err := h.authService.RevokeSessionWithToken(c.Request.Context(), token)
+	switch err {
+	case nil:
+	case auth.ErrInvalidActionToken:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired link"})
+		return
+	case auth.ErrSessionNotFound:
+		// Already revoked or expired; the outcome the user asked for
+		c.JSON(http.StatusOK, gin.H{"message": "Session already signed out", "session_id": sessionID})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(c.Request.Context(), userID, "session_revoked", "", "high", map[string]interface{}{
+		"session_id": sessionID,
+		"via":        "login_alert_link",
+		"ip_address": c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Session revoked. Change your password if you did not sign in.",
+		"session_id": sessionID,
+	})
+}
+
+// SecurityActivity handles GET /api/v1/users/me/security-activity
+func (h *AuthHandler) SecurityActivity(c *gin.Context) {
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = n
+	}
+
+	var since time.Time
+	if v := c.Query("days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 || days > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+		since = time.Now().AddDate(0, 0, -days)
+	}
+
+	events, err := h.authService.SecurityActivity(c.Request.Context(), c.GetString("user_id"), c.GetString("email"), since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load security activity"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"authorized":    true,
-		"features":      features,
-		"expires_at":    "2025-12-30T16:00:00Z",
-		"next_check_in": 300,
+		"events": events,
+		"count":  len(events),
 	})
 }
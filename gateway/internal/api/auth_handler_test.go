@@ -0,0 +1,354 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/api"
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/maintenance"
+	"github.com/cyper-security/gateway/internal/mocks"
+)
+
+const loginBody = `{"email":"alice@example.com","password":"Correct-Horse-9"}`
+
+func TestLogin(t *testing.T) {
+	authService := &mocks.AuthenticatorMock{
+		LoginFunc: func(ctx context.Context, req auth.LoginRequest, ipAddress, userAgent string) (*auth.LoginResponse, error) {
+			return &auth.LoginResponse{
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+				ExpiresIn:    3600,
+				User:         auth.UserInfo{ID: "user-1", Email: req.Email},
+			}, nil
+		},
+	}
+	auditor := newAuditor()
+	h := api.NewAuthHandler(authService, auditor)
+
+	rec := serve(h.Login, request{method: http.MethodPost, route: "/login", body: loginBody})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (%s)", rec.Code, rec.Body)
+	}
+	if body := decode(t, rec); body["access_token"] != "access" || body["refresh_token"] != "refresh" {
+		t.Errorf("body = %v, want the tokens", body)
+	}
+
+	calls := authService.LoginCalls()
+	if len(calls) != 1 {
+		t.Fatalf("Login called %d times, want once", len(calls))
+	}
+	if calls[0].Req.Email != "alice@example.com" || calls[0].UserAgent != "handler-test" || calls[0].IpAddress == "" {
+		t.Errorf("Login called with %+v", calls[0])
+	}
+
+	success := auditor.LogSuccessCalls()
+	if len(success) != 1 || success[0].UserID != "user-1" || success[0].Action != "login_success" {
+		t.Fatalf("LogSuccess calls = %+v, want login_success for user-1", success)
+	}
+	if success[0].Details["email"] != "alice@example.com" {
+		t.Errorf("audit details = %v", success[0].Details)
+	}
+	if n := len(auditor.LogFailureCalls()); n != 0 {
+		t.Errorf("LogFailure called %d times", n)
+	}
+}
+
+func TestLoginErrors(t *testing.T) {
+	endsAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"wrong password", errors.New("invalid credentials"), http.StatusUnauthorized, ""},
+		{"terms not accepted", auth.ErrTermsNotAccepted, http.StatusForbidden, "terms_not_accepted"},
+		{"address not allowed", auth.ErrIPNotAllowed, http.StatusForbidden, "ip_not_allowed"},
+		{"session limit", auth.ErrSessionLimit, http.StatusConflict, "session_limit"},
+		{"maintenance", &maintenance.ActiveError{State: maintenance.State{Active: true, EndsAt: &endsAt}}, http.StatusServiceUnavailable, "maintenance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &mocks.AuthenticatorMock{
+				LoginFunc: func(context.Context, auth.LoginRequest, string, string) (*auth.LoginResponse, error) {
+					return nil, tt.err
+				},
+			}
+			auditor := newAuditor()
+			rec := serve(api.NewAuthHandler(authService, auditor).Login, request{method: http.MethodPost, route: "/login", body: loginBody})
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.status, rec.Body)
+			}
+			if code, _ := decode(t, rec)["code"].(string); code != tt.code {
+				t.Errorf("code = %q, want %q", code, tt.code)
+			}
+			failures := auditor.LogFailureCalls()
+			if len(failures) != 1 || failures[0].Action != "login_attempt" || failures[0].Details["email"] != "alice@example.com" {
+				t.Errorf("LogFailure calls = %+v, want one login_attempt", failures)
+			}
+			if n := len(auditor.LogSuccessCalls()); n != 0 {
+				t.Errorf("LogSuccess called %d times", n)
+			}
+		})
+	}
+}
+
+func TestLoginRejectsInvalidBody(t *testing.T) {
+	for _, body := range []string{`{`, `{"email":"alice@example.com"}`, `{"email":"not-an-email","password":"x"}`} {
+		// Neither the service nor the audit log may be reached
+		authService := &mocks.AuthenticatorMock{}
+		auditor := &mocks.AuditorMock{}
+		rec := serve(api.NewAuthHandler(authService, auditor).Login, request{method: http.MethodPost, route: "/login", body: body})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	body := `{"email":"bob@example.com","username":"bob","password":"long-enough"}`
+
+	t.Run("created", func(t *testing.T) {
+		authService := &mocks.AuthenticatorMock{
+			RegisterFunc: func(ctx context.Context, req auth.RegisterRequest) (*auth.User, error) {
+				return &auth.User{ID: "user-2", Email: req.Email, Username: req.Username}, nil
+			},
+		}
+		auditor := newAuditor()
+		rec := serve(api.NewAuthHandler(authService, auditor).Register, request{method: http.MethodPost, route: "/register", body: body})
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want 201 (%s)", rec.Code, rec.Body)
+		}
+		if got := decode(t, rec)["user_id"]; got != "user-2" {
+			t.Errorf("user_id = %v", got)
+		}
+		success := auditor.LogSuccessCalls()
+		if len(success) != 1 || success[0].Action != "user_registration" || success[0].ResourceID != "user-2" {
+			t.Errorf("LogSuccess calls = %+v", success)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		authService := &mocks.AuthenticatorMock{
+			RegisterFunc: func(context.Context, auth.RegisterRequest) (*auth.User, error) {
+				return nil, errors.New("duplicate email")
+			},
+		}
+		auditor := newAuditor()
+		rec := serve(api.NewAuthHandler(authService, auditor).Register, request{method: http.MethodPost, route: "/register", body: body})
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", rec.Code)
+		}
+		failures := auditor.LogFailureCalls()
+		if len(failures) != 1 || failures[0].Action != "user_registration" || failures[0].ErrorMsg != "duplicate email" {
+			t.Errorf("LogFailure calls = %+v", failures)
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	keys := map[string]string{"user_id": "user-1", "session_id": "session-1"}
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		audited bool
+	}{
+		{"signed out", nil, http.StatusNoContent, true},
+		// The session is gone either way
+		{"session already ended", auth.ErrSessionNotFound, http.StatusNoContent, true},
+		{"failed", errors.New("redis down"), http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &mocks.AuthenticatorMock{
+				LogoutFunc: func(context.Context, string, string) error { return tt.err },
+			}
+			auditor := newAuditor()
+			rec := serve(api.NewAuthHandler(authService, auditor).Logout, request{method: http.MethodPost, route: "/logout", keys: keys})
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if calls := authService.LogoutCalls(); calls[0].UserID != "user-1" || calls[0].SessionID != "session-1" {
+				t.Errorf("Logout called with %+v", calls[0])
+			}
+			if audited := len(auditor.LogSuccessCalls()) == 1; audited != tt.audited {
+				t.Errorf("audited = %v, want %v", audited, tt.audited)
+			}
+		})
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	keys := map[string]string{"user_id": "user-1", "session_id": "session-1"}
+
+	tests := []struct {
+		name    string
+		session string
+		err     error
+		status  int
+		current bool
+	}{
+		{"other session", "session-2", nil, http.StatusOK, false},
+		{"current session", "session-1", nil, http.StatusOK, true},
+		{"not the user's", "session-3", auth.ErrSessionNotFound, http.StatusNotFound, false},
+		{"failed", "session-2", errors.New("db down"), http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &mocks.AuthenticatorMock{
+				RevokeSessionFunc: func(context.Context, string, string) error { return tt.err },
+			}
+			auditor := newAuditor()
+			rec := serve(api.NewAuthHandler(authService, auditor).RevokeSession, request{
+				method: http.MethodDelete, route: "/sessions/:id", path: "/sessions/" + tt.session, keys: keys,
+			})
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if calls := authService.RevokeSessionCalls(); calls[0].UserID != "user-1" || calls[0].SessionID != tt.session {
+				t.Errorf("RevokeSession called with %+v", calls[0])
+			}
+			events := auditor.LogSecurityEventCalls()
+			if tt.err != nil {
+				if len(events) != 0 {
+					t.Errorf("audited a failed revocation: %+v", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Action != "session_revoked" || events[0].Details["current"] != tt.current {
+				t.Errorf("LogSecurityEvent calls = %+v", events)
+			}
+		})
+	}
+}
+
+func TestRevokeSessionByLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		err     error
+		status  int
+		audited bool
+	}{
+		{"revoked", "/revoke?token=t", nil, http.StatusOK, true},
+		{"already signed out", "/revoke?token=t", auth.ErrSessionNotFound, http.StatusOK, false},
+		{"bad token", "/revoke?token=t", auth.ErrInvalidActionToken, http.StatusUnauthorized, false},
+		{"failed", "/revoke?token=t", errors.New("db down"), http.StatusInternalServerError, false},
+		{"no token", "/revoke", nil, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &mocks.AuthenticatorMock{
+				RevokeSessionWithTokenFunc: func(context.Context, string) (string, string, error) {
+					return "user-1", "session-2", tt.err
+				},
+			}
+			auditor := newAuditor()
+			rec := serve(api.NewAuthHandler(authService, auditor).RevokeSessionByLink, request{
+				method: http.MethodGet, route: "/revoke", path: tt.path,
+			})
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			events := auditor.LogSecurityEventCalls()
+			if audited := len(events) == 1; audited != tt.audited {
+				t.Fatalf("audited = %v, want %v", audited, tt.audited)
+			}
+			if tt.audited && (events[0].UserID != "user-1" || events[0].Severity != "high") {
+				t.Errorf("LogSecurityEvent calls = %+v", events)
+			}
+		})
+	}
+}
+
+func TestSwitchOrganization(t *testing.T) {
+	orgID := "5f0c6a43-6f0e-4d53-9d5b-0d3a2f1b7c11"
+	body := `{"organization_id":"` + orgID + `"}`
+
+	tests := []struct {
+		name   string
+		keys   map[string]string
+		body   string
+		err    error
+		status int
+	}{
+		{"switched", map[string]string{"user_id": "user-1"}, body, nil, http.StatusOK},
+		{"not a member", map[string]string{"user_id": "user-1"}, body, auth.ErrNotOrgMember, http.StatusForbidden},
+		{"deactivated", map[string]string{"user_id": "user-1"}, body, auth.ErrOrganizationInactive, http.StatusForbidden},
+		{"session gone", map[string]string{"user_id": "user-1"}, body, auth.ErrSessionNotFound, http.StatusUnauthorized},
+		{"failed", map[string]string{"user_id": "user-1"}, body, errors.New("db down"), http.StatusInternalServerError},
+		{"not a UUID", map[string]string{"user_id": "user-1"}, `{"organization_id":"acme"}`, nil, http.StatusBadRequest},
+		{"impersonating", map[string]string{"user_id": "user-1", "impersonator_id": "admin-1"}, body, nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &mocks.AuthenticatorMock{
+				SwitchOrganizationFunc: func(ctx context.Context, userID, sessionID, orgID string) (*auth.SwitchOrgResponse, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &auth.SwitchOrgResponse{AccessToken: "scoped", Organization: auth.OrganizationContext{ID: orgID, Role: "admin"}}, nil
+				},
+			}
+			auditor := newAuditor()
+			rec := serve(api.NewAuthHandler(authService, auditor).SwitchOrganization, request{
+				method: http.MethodPost, route: "/switch-org", body: tt.body, keys: tt.keys,
+			})
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.status, rec.Body)
+			}
+			success := auditor.LogSuccessCalls()
+			if tt.status != http.StatusOK {
+				if auditCount(auditor) != 0 {
+					t.Errorf("audited a refused switch")
+				}
+				return
+			}
+			if len(success) != 1 || success[0].Action != "organization_switched" || success[0].ResourceID != orgID || success[0].Details["role"] != "admin" {
+				t.Errorf("LogSuccess calls = %+v", success)
+			}
+		})
+	}
+}
+
+func TestSecurityActivityBounds(t *testing.T) {
+	for _, query := range []string{"?limit=0", "?limit=201", "?limit=x", "?days=0", "?days=366"} {
+		// Rejected before the service is called
+		h := api.NewAuthHandler(&mocks.AuthenticatorMock{}, &mocks.AuditorMock{})
+		rec := serve(h.SecurityActivity, request{method: http.MethodGet, route: "/activity", path: "/activity" + query})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+
+	authService := &mocks.AuthenticatorMock{
+		SecurityActivityFunc: func(context.Context, string, string, time.Time, int) ([]auth.SecurityEvent, error) {
+			return nil, nil
+		},
+	}
+	rec := serve(api.NewAuthHandler(authService, &mocks.AuditorMock{}).SecurityActivity, request{
+		method: http.MethodGet, route: "/activity", path: "/activity?limit=200&days=30",
+		keys: map[string]string{"user_id": "user-1", "email": "alice@example.com"},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	call := authService.SecurityActivityCalls()[0]
+	if call.Limit != 200 || call.UserID != "user-1" || time.Since(call.Since) < 30*24*time.Hour-time.Minute {
+		t.Errorf("SecurityActivity called with %+v", call)
+	}
+}
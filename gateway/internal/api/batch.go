@@ -0,0 +1,362 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// BatchItemResult is the outcome of a batch request for one item
+type BatchItemResult struct {
+	ID     string      `json:"id"`
+	OK     bool        `json:"ok"`
+	Error  string      `json:"error,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// BatchResponse reports a batch request item by item
+type BatchResponse struct {
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+	Error     string            `json:"error,omitempty"` // Set when nothing was applied
+}
+
+func (r *BatchResponse) add(result BatchItemResult) {
+	if result.OK {
+		r.Succeeded++
+	} else {
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// isCustomMethod checks the request really was for "/resource:method". Gin
+// reads the colon as the start of a path parameter, so that route also
+// matches e.g. /resourcefoo.
+func isCustomMethod(c *gin.Context, method string) bool {
+	if c.Param(method) == ":"+method {
+		return true
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	return false
+}
+
+// uniqueIDs lowercases UUIDs to match their text form in the database and
+// drops repeats, keeping the first occurrence
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.ToLower(id)
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// BatchCancelScansRequest stops up to 500 scans
+type BatchCancelScansRequest struct {
+	ScanIDs []string `json:"scan_ids" binding:"required,min=1,max=500,dive,uuid"`
+	Reason  string   `json:"reason" binding:"max=500"`
+}
+
+// BatchCancel handles POST /api/v1/scans:batchCancel. Each scan is stopped on
+// its own, so scans that already finished are reported without holding up
+// the rest; one audit entry records every scan stopped.
+func (h *ScanHandler) BatchCancel(c *gin.Context) {
+	if !isCustomMethod(c, "batchCancel") {
+		return
+	}
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	var req BatchCancelScansRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp := BatchResponse{Results: []BatchItemResult{}}
+	var cancelled, failed []string
+	for _, scanID := range uniqueIDs(req.ScanIDs) {
+		control, scanErr := h.transitionScan(ctx, orgID, scanID, "stop", req.Reason)
+		if scanErr != nil {
+			failed = append(failed, scanID)
+			resp.add(BatchItemResult{ID: scanID, Error: scanErr.message})
+			continue
+		}
+		cancelled = append(cancelled, scanID)
+		resp.add(BatchItemResult{ID: scanID, OK: true, Result: control})
+	}
+
+	if len(cancelled) > 0 {
+		h.auditLogger.Log(ctx, audit.LogParams{
+			UserID:       c.GetString("user_id"),
+			Action:       "scans_batch_cancelled",
+			ResourceType: "scan_job",
+			Details: map[string]interface{}{
+				"scan_ids":        cancelled,
+				"failed_scan_ids": failed,
+				"reason":          req.Reason,
+			},
+			IPAddress: c.ClientIP(),
+			UserAgent: c.GetHeader("User-Agent"),
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// FindingSuppressionRequest suppresses or unsuppresses findings by hand
+type FindingSuppressionRequest struct {
+	Suppressed    bool   `json:"suppressed"`
+	Justification string `json:"justification" binding:"max=2000"` // Required to suppress
+}
+
+// BatchUpdateFindingsRequest applies the same changes to up to 500 findings;
+// at least one of status, assignment and suppression must be set
+type BatchUpdateFindingsRequest struct {
+	FindingIDs  []string                   `json:"finding_ids" binding:"required,min=1,max=500,dive,uuid"`
+	Status      *FindingStatusRequest      `json:"status"`
+	Assignment  *AssignFindingRequest      `json:"assignment"`
+	Suppression *FindingSuppressionRequest `json:"suppression"`
+	// Atomic changes nothing unless every finding can be updated
+	Atomic bool `json:"atomic"`
+}
+
+// BatchUpdate handles POST /api/v1/findings:batchUpdate for findings of the
+// caller's organization. Findings that cannot be updated are reported per
+// item; the rest are changed in one transaction, with one audit entry
+// naming them all.
+func (h *FindingHandler) BatchUpdate(c *gin.Context) {
+	if !isCustomMethod(c, "batchUpdate") {
+		return
+	}
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+	userID := c.GetString("user_id")
+
+	var req BatchUpdateFindingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.Status == nil && req.Assignment == nil && req.Suppression == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update: set status, assignment or suppression"})
+		return
+	}
+	if req.Suppression != nil && req.Suppression.Suppressed && req.Suppression.Justification == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A justification is required to suppress findings"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var dueDate *time.Time
+	if req.Assignment != nil {
+		if req.Assignment.DueDate != "" {
+			due, _ := time.Parse("2006-01-02", req.Assignment.DueDate)
+			dueDate = &due
+		}
+		if req.Assignment.AssigneeID != nil {
+			if _, err := h.roles.MemberRole(ctx, *req.Assignment.AssigneeID, orgID); err == rbac.ErrNotMember {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee is not a member of this organization"})
+				return
+			} else if err != nil {
+				logging.FromContext(ctx, h.logger).Error("Failed to verify assignee membership", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update findings"})
+				return
+			}
+		}
+	}
+
+	fail := func(err error, message string) {
+		logging.FromContext(ctx, h.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update findings"})
+	}
+
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		fail(err, "Failed to begin transaction")
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the rows so concurrent changes are recorded in order
+	ids := uniqueIDs(req.FindingIDs)
+	var rows []findingRef
+	err = tx.SelectContext(ctx, &rows, `
+		SELECT id, title, status FROM vulnerabilities
+		WHERE organization_id = $1 AND id::text = ANY($2)
+		ORDER BY id
+		FOR UPDATE
+	`, orgID, pq.Array(ids))
+	if err != nil {
+		fail(err, "Failed to lock findings")
+		return
+	}
+	found := make(map[string]findingRef, len(rows))
+	for _, row := range rows {
+		found[row.ID] = row
+	}
+
+	resp := BatchResponse{Results: []BatchItemResult{}}
+	var updated, missing []string
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+			resp.add(BatchItemResult{ID: id, Error: "Finding not found"})
+			continue
+		}
+		updated = append(updated, id)
+		resp.add(BatchItemResult{ID: id, OK: true})
+	}
+	if len(updated) == 0 || (req.Atomic && len(missing) > 0) {
+		for i := range resp.Results {
+			if resp.Results[i].OK {
+				resp.Results[i] = BatchItemResult{ID: resp.Results[i].ID, Error: "Not updated: the batch is atomic"}
+			}
+		}
+		resp.Succeeded, resp.Failed = 0, len(ids)
+		resp.Error = "No findings were updated"
+		c.JSON(http.StatusConflict, resp)
+		return
+	}
+
+	var statusChanged []findingRef
+	if req.Status != nil {
+		for _, id := range updated {
+			finding := found[id]
+			if finding.Status == req.Status.Status {
+				continue
+			}
+			_, err = tx.ExecContext(ctx, `
+				UPDATE vulnerabilities SET status = $2, updated_at = NOW() WHERE id = $1
+			`, id, req.Status.Status)
+			if err != nil {
+				fail(err, "Failed to update finding status")
+				return
+			}
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO finding_status_history (vulnerability_id, organization_id, from_status, to_status, reason, changed_by)
+				VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+			`, id, orgID, finding.Status, req.Status.Status, req.Status.Reason, userID)
+			if err != nil {
+				fail(err, "Failed to record finding status change")
+				return
+			}
+			statusChanged = append(statusChanged, finding)
+		}
+	}
+
+	if req.Assignment != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE vulnerabilities
+			SET assignee_id = $2,
+			    assigned_by = CASE WHEN $2::uuid IS NULL THEN NULL ELSE $3::uuid END,
+			    assigned_at = CASE WHEN $2::uuid IS NULL THEN NULL ELSE NOW() END,
+			    due_date = $4,
+			    updated_at = NOW()
+			WHERE id::text = ANY($1)
+		`, pq.Array(updated), req.Assignment.AssigneeID, userID, dueDate)
+		if err != nil {
+			fail(err, "Failed to assign findings")
+			return
+		}
+		if req.Assignment.AssigneeID != nil {
+			for _, id := range updated {
+				if err := addWatcher(ctx, tx, id, *req.Assignment.AssigneeID); err != nil {
+					fail(err, "Failed to add finding watcher")
+					return
+				}
+			}
+		}
+	}
+
+	if req.Suppression != nil {
+		// Unsuppressing also detaches the rule that suppressed a finding
+		_, err = tx.ExecContext(ctx, `
+			UPDATE vulnerabilities
+			SET suppressed_at = CASE WHEN $2 THEN COALESCE(suppressed_at, NOW()) ELSE NULL END,
+			    suppression_rule_id = CASE WHEN $2 THEN suppression_rule_id ELSE NULL END,
+			    suppression_justification = CASE WHEN $2 THEN $3 ELSE NULL END,
+			    suppressed_by = CASE WHEN $2 THEN $4::uuid ELSE NULL END,
+			    updated_at = NOW()
+			WHERE id::text = ANY($1)
+		`, pq.Array(updated), req.Suppression.Suppressed, req.Suppression.Justification, userID)
+		if err != nil {
+			fail(err, "Failed to update finding suppression")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fail(err, "Failed to commit finding updates")
+		return
+	}
+
+	details := map[string]interface{}{
+		"finding_ids":        updated,
+		"failed_finding_ids": missing,
+	}
+	if req.Status != nil {
+		details["status"] = req.Status.Status
+		details["reason"] = req.Status.Reason
+	}
+	if req.Assignment != nil {
+		details["assignee_id"] = req.Assignment.AssigneeID
+		details["due_date"] = req.Assignment.DueDate
+	}
+	if req.Suppression != nil {
+		details["suppressed"] = req.Suppression.Suppressed
+		details["justification"] = req.Suppression.Justification
+	}
+	h.auditLogger.LogSuccess(ctx, userID, "findings_batch_updated", "vulnerability", "", details)
+
+	for _, finding := range statusChanged {
+		h.notifyWatchers(ctx, finding.ID, userID, realtime.FindingActivityEvent{
+			FindingID:      finding.ID,
+			OrganizationID: orgID,
+			Kind:           "status_changed",
+			ActorID:        userID,
+			Title:          finding.Title,
+			FromStatus:     finding.Status,
+			ToStatus:       req.Status.Status,
+		})
+	}
+	if req.Assignment != nil {
+		for _, id := range updated {
+			event := realtime.FindingActivityEvent{
+				FindingID:      id,
+				OrganizationID: orgID,
+				Kind:           "assigned",
+				ActorID:        userID,
+				Title:          found[id].Title,
+				DueDate:        dueDate,
+			}
+			if req.Assignment.AssigneeID != nil {
+				event.AssigneeID = *req.Assignment.AssigneeID
+			}
+			h.notifyWatchers(ctx, id, userID, event)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
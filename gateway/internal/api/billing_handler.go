@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cyper-security/gateway/internal/billing"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type BillingHandler struct {
+	billing *billing.Service
+	roles   *rbac.RoleStore
+	logger  *zap.Logger
+}
+
+func NewBillingHandler(billingService *billing.Service, roles *rbac.RoleStore, logger *zap.Logger) *BillingHandler {
+	return &BillingHandler{
+		billing: billingService,
+		roles:   roles,
+		logger:  logger,
+	}
+}
+
+type BillingUsageResponse struct {
+	OrganizationID string                 `json:"organization_id"`
+	Months         []billing.MonthlyUsage `json:"months"` // Newest first
+}
+
+// GetUsage handles GET /api/v1/organizations/:id/billing/usage?months=12
+func (h *BillingHandler) GetUsage(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger); !ok {
+		return
+	}
+
+	months := 12
+	if v := c.Query("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 36 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months must be between 1 and 36"})
+			return
+		}
+		months = n
+	}
+
+	usage, err := h.billing.Usage(c.Request.Context(), orgID, months)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load billing usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, BillingUsageResponse{OrganizationID: orgID, Months: usage})
+}
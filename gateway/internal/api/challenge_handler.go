@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/challenge"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ChallengeHandler hands out CAPTCHA / proof-of-work challenges ahead of
+// login or registration
+type ChallengeHandler struct {
+	guard  *challenge.Guard
+	logger *zap.Logger
+}
+
+func NewChallengeHandler(guard *challenge.Guard, logger *zap.Logger) *ChallengeHandler {
+	return &ChallengeHandler{
+		guard:  guard,
+		logger: logger,
+	}
+}
+
+// GetChallenge handles GET /api/v1/auth/challenge
+func (h *ChallengeHandler) GetChallenge(c *gin.Context) {
+	provider := h.guard.Provider()
+	if provider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "challenges are not enabled"})
+		return
+	}
+
+	ch, err := provider.Challenge(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to issue challenge", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to issue challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ch)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/compliance"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ComplianceHandler maps an organization's open findings onto compliance
+// framework controls
+type ComplianceHandler struct {
+	db     *database.DB
+	roles  *rbac.RoleStore
+	logger *zap.Logger
+}
+
+func NewComplianceHandler(db *database.DB, roles *rbac.RoleStore, logger *zap.Logger) *ComplianceHandler {
+	return &ComplianceHandler{
+		db:     db,
+		roles:  roles,
+		logger: logger,
+	}
+}
+
+// FrameworkCoverage summarizes one framework's assessment
+type FrameworkCoverage struct {
+	Framework string                       `json:"framework"`
+	Name      string                       `json:"name"`
+	Version   string                       `json:"version"`
+	Summary   compliance.AssessmentSummary `json:"summary"`
+}
+
+// ListFrameworks handles GET /api/v1/compliance/frameworks
+func (h *ComplianceHandler) ListFrameworks(c *gin.Context) {
+	c.JSON(http.StatusOK, compliance.Frameworks())
+}
+
+// Coverage handles GET /api/v1/organizations/:id/compliance
+func (h *ComplianceHandler) Coverage(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewReport, h.logger); !ok {
+		return
+	}
+
+	findings, ok := h.loadFindings(c, orgID)
+	if !ok {
+		return
+	}
+
+	coverage := []FrameworkCoverage{}
+	for _, framework := range compliance.Frameworks() {
+		assessment := compliance.Assess(&framework, findings)
+		coverage = append(coverage, FrameworkCoverage{
+			Framework: assessment.Framework,
+			Name:      assessment.Name,
+			Version:   assessment.Version,
+			Summary:   assessment.Summary,
+		})
+	}
+
+	c.JSON(http.StatusOK, coverage)
+}
+
+// Assess handles GET /api/v1/organizations/:id/compliance/:framework
+func (h *ComplianceHandler) Assess(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewReport, h.logger); !ok {
+		return
+	}
+
+	framework, ok := compliance.LookupFramework(c.Param("framework"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown compliance framework"})
+		return
+	}
+
+	findings, ok := h.loadFindings(c, orgID)
+	if !ok {
+		return
+	}
+
+	assessment := compliance.Assess(framework, findings)
+	assessment.ScanID = c.Query("scan_id")
+	c.JSON(http.StatusOK, assessment)
+}
+
+// loadFindings loads the open findings to assess: the organization's, or one
+// of its scans' with ?scan_id
+func (h *ComplianceHandler) loadFindings(c *gin.Context, orgID string) ([]compliance.Finding, bool) {
+	scanID := c.Query("scan_id")
+	if scanID != "" {
+		if _, err := uuid.Parse(scanID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scan ID"})
+			return nil, false
+		}
+	}
+
+	findings, err := compliance.LoadOpenFindings(c.Request.Context(), h.db, orgID, scanID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load findings for compliance", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assess compliance"})
+		return nil, false
+	}
+	return findings, true
+}
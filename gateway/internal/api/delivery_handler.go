@@ -0,0 +1,359 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/deliveries"
+	"github.com/cyper-security/gateway/internal/escalation"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/slack"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TestEventName is the event of test deliveries
+const TestEventName = "integration.test"
+
+// TestDeliveryRequest sends a test event. A webhook test goes to url, to a
+// report schedule's webhook or to every webhook contact of an escalation
+// policy, signed as their real deliveries are; a Slack test goes to a
+// channel the bot is in.
+type TestDeliveryRequest struct {
+	Channel            string `json:"channel" binding:"required,oneof=webhook slack"`
+	URL                string `json:"url" binding:"omitempty,url,max=2048"`
+	Secret             string `json:"secret" binding:"max=200"`
+	ReportScheduleID   string `json:"report_schedule_id" binding:"omitempty,uuid"`
+	EscalationPolicyID string `json:"escalation_policy_id" binding:"omitempty,uuid"`
+	SlackChannel       string `json:"slack_channel" binding:"required_if=Channel slack,max=80"`
+}
+
+// TestEvent is the payload of test webhooks
+type TestEvent struct {
+	Event          string    `json:"event"`
+	OrganizationID string    `json:"organization_id"`
+	Message        string    `json:"message"`
+	SentAt         time.Time `json:"sent_at"`
+}
+
+// TestDeliveryResponse lists the attempts a test made, redacted
+type TestDeliveryResponse struct {
+	Succeeded bool                  `json:"succeeded"`
+	Attempts  []*deliveries.Attempt `json:"attempts"`
+}
+
+// DeliveryHandler is the integration debug console: test events, the log of
+// webhook and Slack delivery attempts, and replays of failed ones
+type DeliveryHandler struct {
+	db          *database.DB
+	roles       *rbac.RoleStore
+	deliveries  *deliveries.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewDeliveryHandler(db *database.DB, roles *rbac.RoleStore, deliveryService *deliveries.Service, auditLogger Auditor, logger *zap.Logger) *DeliveryHandler {
+	return &DeliveryHandler{
+		db:          db,
+		roles:       roles,
+		deliveries:  deliveryService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// ListDeliveries handles GET /api/v1/organizations/:id/integrations/deliveries
+func (h *DeliveryHandler) ListDeliveries(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger); !ok {
+		return
+	}
+
+	filter := deliveries.Filter{
+		Channel: c.Query("channel"),
+		Source:  c.Query("source"),
+		Outcome: c.Query("outcome"),
+		Limit:   50,
+	}
+	switch filter.Channel {
+	case "", deliveries.ChannelWebhook, deliveries.ChannelSlack:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "channel must be webhook or slack"})
+		return
+	}
+	switch filter.Outcome {
+	case "", "succeeded", "failed":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "outcome must be succeeded or failed"})
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	attempts, err := h.deliveries.List(c.Request.Context(), orgID, filter)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list deliveries", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, attempts)
+}
+
+// GetDelivery handles GET /api/v1/organizations/:id/integrations/deliveries/:delivery_id
+func (h *DeliveryHandler) GetDelivery(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger); !ok {
+		return
+	}
+
+	attempt, err := h.deliveries.Get(c.Request.Context(), orgID, c.Param("delivery_id"))
+	if err == deliveries.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load delivery", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load delivery"})
+		return
+	}
+	c.JSON(http.StatusOK, attempt)
+}
+
+// ReplayDelivery handles POST /api/v1/organizations/:id/integrations/deliveries/:delivery_id/replay.
+// The replay's outcome is in the body whether or not it got through.
+func (h *DeliveryHandler) ReplayDelivery(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	deliveryID := c.Param("delivery_id")
+	attempt, err := h.deliveries.Replay(ctx, orgID, deliveryID, userID)
+	switch {
+	case err == deliveries.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	case err == deliveries.ErrNotReplayable:
+		c.JSON(http.StatusConflict, gin.H{"error": "Only failed deliveries can be replayed"})
+		return
+	case err == deliveries.ErrSlackNotConfigured:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Slack is not configured"})
+		return
+	case attempt == nil:
+		logging.FromContext(ctx, h.logger).Error("Failed to replay delivery", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay delivery"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "delivery_replayed", "delivery", deliveryID, map[string]interface{}{
+		"organization_id": orgID,
+		"replay_id":       attempt.ID,
+		"succeeded":       attempt.Succeeded,
+	})
+
+	c.JSON(http.StatusOK, attempt)
+}
+
+// TestDelivery handles POST /api/v1/organizations/:id/integrations/test. The
+// attempts' outcomes are in the body whether or not they got through.
+func (h *DeliveryHandler) TestDelivery(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req TestDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	var sent []*deliveries.Attempt
+	var err error
+	if req.Channel == deliveries.ChannelSlack {
+		sent, err = h.testSlack(c, orgID, userID, req)
+	} else {
+		sent, err = h.testWebhooks(c, orgID, userID, req)
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to send test delivery", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test delivery"})
+		return
+	}
+	if sent == nil {
+		return // Already answered
+	}
+
+	resp := TestDeliveryResponse{Succeeded: true, Attempts: make([]*deliveries.Attempt, 0, len(sent))}
+	for _, attempt := range sent {
+		resp.Succeeded = resp.Succeeded && attempt.Succeeded
+		resp.Attempts = append(resp.Attempts, h.deliveries.Redacted(attempt))
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "delivery_tested", "organization", orgID, map[string]interface{}{
+		"channel":              req.Channel,
+		"report_schedule_id":   req.ReportScheduleID,
+		"escalation_policy_id": req.EscalationPolicyID,
+		"attempts":             len(resp.Attempts),
+		"succeeded":            resp.Succeeded,
+	})
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// testWebhooks sends a test event to the webhooks the request names. A nil
+// result with a nil error means the request was answered.
+func (h *DeliveryHandler) testWebhooks(c *gin.Context, orgID, userID string, req TestDeliveryRequest) ([]*deliveries.Attempt, error) {
+	named := 0
+	for _, v := range []string{req.URL, req.ReportScheduleID, req.EscalationPolicyID} {
+		if v != "" {
+			named++
+		}
+	}
+	if named != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "one of url, report_schedule_id or escalation_policy_id is required"})
+		return nil, nil
+	}
+
+	ctx := c.Request.Context()
+	test := deliveries.Webhook{
+		OrgID:  orgID,
+		Source: deliveries.SourceTest,
+		Event:  TestEventName,
+		SentBy: userID,
+		Payload: TestEvent{
+			Event:          TestEventName,
+			OrganizationID: orgID,
+			Message:        "This is a test delivery from Cyper. No action is needed.",
+			SentAt:         time.Now().UTC(),
+		},
+	}
+	var urls []string
+
+	switch {
+	case req.URL != "":
+		urls, test.Secret = []string{req.URL}, req.Secret
+
+	case req.ReportScheduleID != "":
+		var schedule struct {
+			WebhookURL    *string `db:"webhook_url"`
+			WebhookSecret *string `db:"webhook_secret"`
+		}
+		err := h.db.GetContext(ctx, &schedule, `
+			SELECT webhook_url, webhook_secret FROM report_schedules WHERE id = $1 AND organization_id = $2
+		`, req.ReportScheduleID, orgID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report schedule not found"})
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if schedule.WebhookURL == nil || *schedule.WebhookURL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Report schedule has no webhook"})
+			return nil, nil
+		}
+		urls, test.SourceID = []string{*schedule.WebhookURL}, req.ReportScheduleID
+		if schedule.WebhookSecret != nil {
+			test.Secret = *schedule.WebhookSecret
+		}
+
+	default:
+		var policy escalation.Policy
+		err := h.db.GetContext(ctx, &policy, `
+			SELECT `+escalation.PolicyColumns+` FROM escalation_policies WHERE id = $1 AND organization_id = $2
+		`, req.EscalationPolicyID, orgID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, level := range policy.Levels {
+			for _, contact := range level.Contacts {
+				if contact.Channel == escalation.ChannelWebhook {
+					urls = append(urls, contact.Target)
+				}
+			}
+		}
+		if len(urls) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Escalation policy has no webhook contacts"})
+			return nil, nil
+		}
+		test.SourceID = req.EscalationPolicyID
+		if policy.WebhookSecret != nil {
+			test.Secret = *policy.WebhookSecret
+		}
+	}
+
+	sent := make([]*deliveries.Attempt, 0, len(urls))
+	for _, url := range urls {
+		test.URL = url
+		attempt, err := h.deliveries.SendWebhook(ctx, test)
+		if attempt == nil {
+			return nil, err
+		}
+		sent = append(sent, attempt)
+	}
+	return sent, nil
+}
+
+// testSlack sends a test message to a channel, for organizations with a
+// linked workspace. A nil result with a nil error means the request was
+// answered.
+func (h *DeliveryHandler) testSlack(c *gin.Context, orgID, userID string, req TestDeliveryRequest) ([]*deliveries.Attempt, error) {
+	if !h.deliveries.SlackConfigured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Slack is not configured"})
+		return nil, nil
+	}
+
+	ctx := c.Request.Context()
+	var linked bool
+	err := h.db.GetContext(ctx, &linked, `
+		SELECT EXISTS (SELECT 1 FROM slack_workspaces WHERE organization_id = $1)
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !linked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No Slack workspace is linked to this organization"})
+		return nil, nil
+	}
+
+	text := ":white_check_mark: This is a test notification from Cyper. No action is needed."
+	attempt, err := h.deliveries.SendSlack(ctx, deliveries.SlackMessage{
+		OrgID:  orgID,
+		Source: deliveries.SourceTest,
+		Event:  TestEventName,
+		SentBy: userID,
+		Message: slack.Message{
+			Channel: req.SlackChannel,
+			Text:    text,
+			Blocks:  []interface{}{slack.Section(text)},
+		},
+	})
+	if attempt == nil {
+		if errors.Is(err, deliveries.ErrSlackNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Slack is not configured"})
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []*deliveries.Attempt{attempt}, nil
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/digests"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type DigestHandler struct {
+	digests *digests.Service
+	logger  *zap.Logger
+}
+
+func NewDigestHandler(digestService *digests.Service, logger *zap.Logger) *DigestHandler {
+	return &DigestHandler{
+		digests: digestService,
+		logger:  logger,
+	}
+}
+
+// DigestPreview is the digest email as it would be sent now
+type DigestPreview struct {
+	Subject string          `json:"subject"`
+	Body    string          `json:"body"`
+	Empty   bool            `json:"empty"` // an empty digest is not sent
+	Digest  *digests.Digest `json:"digest"`
+}
+
+// PreviewDigest handles GET /api/v1/users/me/digest: the user's daily or
+// weekly digest for the period ending now
+func (h *DigestHandler) PreviewDigest(c *gin.Context) {
+	ctx := c.Request.Context()
+	digest, err := h.digests.Preview(ctx, c.GetString("user_id"), c.DefaultQuery("frequency", "weekly"))
+	if errors.Is(err, digests.ErrInvalidFrequency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "frequency must be daily or weekly"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to build digest preview", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build digest"})
+		return
+	}
+
+	subject, body, err := h.digests.Render(digest)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to render digest preview", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build digest"})
+		return
+	}
+	c.JSON(http.StatusOK, DigestPreview{
+		Subject: subject,
+		Body:    body,
+		Empty:   digest.Empty(),
+		Digest:  digest,
+	})
+}
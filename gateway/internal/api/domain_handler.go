@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/domains"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DomainHandler manages organizations' verified email domains and the
+// email verification that auto-joining them depends on
+type DomainHandler struct {
+	domains     *domains.Service
+	auth        *auth.AuthService
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewDomainHandler(domainService *domains.Service, authService *auth.AuthService, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *DomainHandler {
+	return &DomainHandler{
+		domains:     domainService,
+		auth:        authService,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// AddDomainRequest claims an email domain
+type AddDomainRequest struct {
+	Domain string `json:"domain" binding:"required,max=253"`
+}
+
+// DomainsResponse lists an organization's domains
+type DomainsResponse struct {
+	Domains []domains.Domain `json:"domains"`
+}
+
+// VerifyEmailResponse is the outcome of following a verification link
+type VerifyEmailResponse struct {
+	Message string          `json:"message"`
+	UserID  string          `json:"user_id"`
+	Email   string          `json:"email"`
+	Joined  *domains.Joined `json:"joined,omitempty"` // the organization auto-joined, if any
+}
+
+// ListDomains handles GET /api/v1/organizations/:id/domains
+func (h *DomainHandler) ListDomains(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger); !ok {
+		return
+	}
+
+	list, err := h.domains.List(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list domains", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list domains"})
+		return
+	}
+	c.JSON(http.StatusOK, DomainsResponse{Domains: list})
+}
+
+// AddDomain handles POST /api/v1/organizations/:id/domains. The response
+// carries the TXT record to publish before verifying.
+func (h *DomainHandler) AddDomain(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req AddDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	domain, err := h.domains.Add(ctx, orgID, userID, req.Domain)
+	if err != nil {
+		if errors.Is(err, domains.ErrConflict) {
+			h.auditLogger.LogFailure(ctx, userID, "organization_domain_added", err.Error(), map[string]interface{}{
+				"organization_id": orgID,
+				"domain":          req.Domain,
+			})
+		}
+		h.respondDomainError(c, err, "Failed to add domain")
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "organization_domain_added", "organization", orgID, map[string]interface{}{
+		"domain_id": domain.ID,
+		"domain":    domain.Domain,
+	})
+	c.JSON(http.StatusCreated, domain)
+}
+
+// VerifyDomain handles POST /api/v1/organizations/:id/domains/:domain_id/verify
+func (h *DomainHandler) VerifyDomain(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	domain, err := h.domains.Verify(ctx, orgID, c.Param("domain_id"))
+	if errors.Is(err, domains.ErrAlreadyVerified) {
+		c.JSON(http.StatusOK, domain)
+		return
+	}
+	if err != nil {
+		if errors.Is(err, domains.ErrConflict) {
+			h.auditLogger.LogFailure(ctx, userID, "organization_domain_verified", err.Error(), map[string]interface{}{
+				"organization_id": orgID,
+				"domain_id":       c.Param("domain_id"),
+			})
+		}
+		h.respondDomainError(c, err, "Failed to verify domain")
+		return
+	}
+
+	h.auditLogger.Log(ctx, audit.LogParams{
+		UserID:         userID,
+		OrganizationID: orgID,
+		Action:         "organization_domain_verified",
+		ResourceType:   "organization",
+		ResourceID:     orgID,
+		Target:         domain.Domain,
+		Details:        map[string]interface{}{"domain_id": domain.ID},
+		Status:         "success",
+		Severity:       "medium",
+	})
+	c.JSON(http.StatusOK, domain)
+}
+
+// UpdateDomain handles PATCH /api/v1/organizations/:id/domains/:domain_id
+func (h *DomainHandler) UpdateDomain(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req domains.Update
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	domain, err := h.domains.Update(ctx, orgID, c.Param("domain_id"), req)
+	if err != nil {
+		h.respondDomainError(c, err, "Failed to update domain")
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "organization_domain_updated", "organization", orgID, map[string]interface{}{
+		"domain_id":      domain.ID,
+		"domain":         domain.Domain,
+		"auto_join":      domain.AutoJoin,
+		"auto_join_role": domain.AutoJoinRole,
+	})
+	c.JSON(http.StatusOK, domain)
+}
+
+// RemoveDomain handles DELETE /api/v1/organizations/:id/domains/:domain_id
+func (h *DomainHandler) RemoveDomain(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	domain, err := h.domains.Remove(ctx, orgID, c.Param("domain_id"))
+	if err != nil {
+		h.respondDomainError(c, err, "Failed to remove domain")
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "organization_domain_removed", "organization", orgID, map[string]interface{}{
+		"domain_id": domain.ID,
+		"domain":    domain.Domain,
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Domain removed"})
+}
+
+// RequestEmailVerification handles POST /api/v1/auth/verify-email/request:
+// emails the caller a link that verifies their address
+func (h *DomainHandler) RequestEmailVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	v, err := h.auth.RequestEmailVerification(ctx, c.GetString("user_id"))
+	switch {
+	case errors.Is(err, auth.ErrEmailAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": "Email address is already verified"})
+		return
+	case errors.Is(err, auth.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case err != nil:
+		logging.FromContext(ctx, h.logger).Error("Failed to request email verification", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Verification email sent",
+		"email":      v.Email,
+		"expires_at": v.ExpiresAt,
+	})
+}
+
+// VerifyEmail handles GET/POST /api/v1/auth/verify-email, the link sent to
+// verify an address. The signed token is the only credential. A verified
+// address on an organization's verified domain joins that organization when
+// it has auto-join on.
+func (h *DomainHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.auth.VerifyEmail(ctx, token)
+	if errors.Is(err, auth.ErrInvalidVerificationToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired link"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to verify email", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, user.ID, "email_verified", "user", user.ID, map[string]interface{}{
+		"email":      user.Email,
+		"ip_address": c.ClientIP(),
+	})
+
+	resp := VerifyEmailResponse{Message: "Email address verified", UserID: user.ID, Email: user.Email}
+	joined, err := h.domains.AutoJoin(ctx, user.ID, user.Email)
+	if err != nil {
+		// The address is verified either way; the join can be retried by
+		// verifying again
+		logging.FromContext(ctx, h.logger).Error("Failed to auto-join organization", zap.String("user_id", user.ID), zap.Error(err))
+	}
+	if joined != nil {
+		h.auditAutoJoin(ctx, user.ID, user.Email, joined)
+		resp.Joined = joined
+		resp.Message = "Email address verified. You have joined " + joined.Name + "."
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *DomainHandler) auditAutoJoin(ctx context.Context, userID, email string, joined *domains.Joined) {
+	h.auditLogger.Log(ctx, audit.LogParams{
+		UserID:         userID,
+		OrganizationID: joined.OrganizationID,
+		Action:         "organization_auto_joined",
+		ResourceType:   "organization",
+		ResourceID:     joined.OrganizationID,
+		Target:         joined.Domain,
+		Details: map[string]interface{}{
+			"email":  email,
+			"domain": joined.Domain,
+			"role":   joined.Role,
+		},
+		Status:   "success",
+		Severity: "medium",
+	})
+}
+
+func (h *DomainHandler) respondDomainError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, domains.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+	case errors.Is(err, domains.ErrInvalidDomain), errors.Is(err, domains.ErrPublicDomain),
+		errors.Is(err, domains.ErrInvalidRole), errors.Is(err, domains.ErrOwnerRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domains.ErrExists), errors.Is(err, domains.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, domains.ErrNotVerified):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "record_not_found"})
+	case errors.Is(err, domains.ErrLookupFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
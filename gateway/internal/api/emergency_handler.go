@@ -5,9 +5,12 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/escalation"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
@@ -15,32 +18,37 @@ import (
 const EmergencyStopKey = "emergency:stop:active"
 
 type EmergencyHandler struct {
-	db          *sqlx.DB
+	db          *database.DB
 	redis       *redis.Client
 	logger      *zap.Logger
-	auditLogger *audit.AuditLogger
+	auditLogger Auditor
+	escalations *escalation.Service
 }
 
-func NewEmergencyHandler(db *sqlx.DB, redisClient *redis.Client, auditLogger *audit.AuditLogger, logger *zap.Logger) *EmergencyHandler {
+func NewEmergencyHandler(db *database.DB, redisClient *redis.Client, auditLogger Auditor, escalations *escalation.Service, logger *zap.Logger) *EmergencyHandler {
 	return &EmergencyHandler{
 		db:          db,
 		redis:       redisClient,
 		logger:      logger,
 		auditLogger: auditLogger,
+		escalations: escalations,
 	}
 }
 
+// EmergencyStopRequest payload
+type EmergencyStopRequest struct {
+	Reason   string `json:"reason" binding:"required"`
+	Duration int    `json:"duration_minutes"` // Optional, defaults to 60 minutes
+}
+
 // ActivateEmergencyStop handles POST /api/v1/emergency/stop
 func (h *EmergencyHandler) ActivateEmergencyStop(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	var req struct {
-		Reason   string `json:"reason" binding:"required"`
-		Duration int    `json:"duration_minutes"` // Optional, defaults to 60 minutes
-	}
+	var req EmergencyStopRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
@@ -53,7 +61,7 @@ func (h *EmergencyHandler) ActivateEmergencyStop(c *gin.Context) {
 	// Set emergency stop flag in Redis with expiration
 	err := h.redis.Set(ctx, EmergencyStopKey, req.Reason, time.Duration(req.Duration)*time.Minute).Err()
 	if err != nil {
-		h.logger.Error("Failed to set emergency stop", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to set emergency stop", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate emergency stop"})
 		return
 	}
@@ -64,16 +72,17 @@ func (h *EmergencyHandler) ActivateEmergencyStop(c *gin.Context) {
 		SET status = 'stopped', 
 		    error_message = 'Emergency stop activated: ' || $1,
 		    completed_at = NOW()
-		WHERE status IN ('pending', 'running')
+		WHERE status IN ('pending_approval', 'pending', 'running', 'paused')
 	`, req.Reason)
 
 	if err != nil {
-		h.logger.Error("Failed to stop scans", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to stop scans", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop scans"})
 		return
 	}
 
 	rowsAffected, _ := result.RowsAffected()
+	metrics.EmergencyStopActive.Set(1)
 
 	// Audit log
 	h.auditLogger.LogSecurityEvent(ctx, userID, "emergency_stop_activated", "", "critical", map[string]interface{}{
@@ -82,12 +91,23 @@ func (h *EmergencyHandler) ActivateEmergencyStop(c *gin.Context) {
 		"scans_stopped":    rowsAffected,
 	})
 
-	h.logger.Warn("Emergency stop activated",
+	logging.FromContext(c.Request.Context(), h.logger).Warn("Emergency stop activated",
 		zap.String("user_id", userID),
 		zap.String("reason", req.Reason),
 		zap.Int64("scans_stopped", rowsAffected),
 	)
 
+	// Page every organization's emergency contacts; each activation is its own event
+	if _, err := h.escalations.Trigger(ctx, "", escalation.TriggerEmergencyStop, uuid.New().String(),
+		"Emergency stop activated: "+req.Reason, map[string]interface{}{
+			"reason":           req.Reason,
+			"duration_minutes": req.Duration,
+			"scans_stopped":    rowsAffected,
+			"activated_by":     userID,
+		}); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to trigger emergency stop escalations", zap.Error(err))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":          "Emergency stop activated",
 		"scans_stopped":    rowsAffected,
@@ -104,17 +124,19 @@ func (h *EmergencyHandler) DeactivateEmergencyStop(c *gin.Context) {
 	// Remove emergency stop flag
 	err := h.redis.Del(ctx, EmergencyStopKey).Err()
 	if err != nil {
-		h.logger.Error("Failed to deactivate emergency stop", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to deactivate emergency stop", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate emergency stop"})
 		return
 	}
 
+	metrics.EmergencyStopActive.Set(0)
+
 	// Audit log
 	h.auditLogger.LogSecurityEvent(ctx, userID, "emergency_stop_deactivated", "", "high", map[string]interface{}{
 		"resumed_by": userID,
 	})
 
-	h.logger.Info("Emergency stop deactivated", zap.String("user_id", userID))
+	logging.FromContext(c.Request.Context(), h.logger).Info("Emergency stop deactivated", zap.String("user_id", userID))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Emergency stop deactivated - scan operations resumed",
@@ -135,7 +157,7 @@ func (h *EmergencyHandler) GetEmergencyStatus(c *gin.Context) {
 	}
 
 	if err != nil {
-		h.logger.Error("Failed to get emergency status", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to get emergency status", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
 		return
 	}
@@ -143,7 +165,7 @@ func (h *EmergencyHandler) GetEmergencyStatus(c *gin.Context) {
 	// Get TTL
 	ttl, err := h.redis.TTL(ctx, EmergencyStopKey).Result()
 	if err != nil {
-		h.logger.Error("Failed to get TTL", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to get TTL", zap.Error(err))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -168,7 +190,7 @@ func (h *EmergencyHandler) CheckEmergencyStop() gin.HandlerFunc {
 		}
 
 		if err != nil {
-			h.logger.Error("Failed to check emergency stop", zap.Error(err))
+			logging.FromContext(c.Request.Context(), h.logger).Error("Failed to check emergency stop", zap.Error(err))
 			c.Next() // Allow on error (fail open for availability)
 			return
 		}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/escalation"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+type EscalationPolicyRequest struct {
+	Name          string            `json:"name" binding:"required,max=100"`
+	Triggers      []string          `json:"triggers" binding:"required,min=1,dive,oneof=critical_finding emergency_stop"`
+	Levels        escalation.Levels `json:"levels" binding:"required"`
+	WebhookSecret string            `json:"webhook_secret" binding:"max=200"`
+	Enabled       *bool             `json:"enabled"`
+}
+
+// AcknowledgeEscalationRequest optionally explains who is handling it
+type AcknowledgeEscalationRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// EscalationDetail is an escalation with its history
+type EscalationDetail struct {
+	escalation.Escalation
+	History []escalation.HistoryEntry `json:"history"`
+}
+
+type EscalationHandler struct {
+	db          *database.DB
+	roles       *rbac.RoleStore
+	escalations *escalation.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewEscalationHandler(db *database.DB, roles *rbac.RoleStore, escalations *escalation.Service, auditLogger Auditor, logger *zap.Logger) *EscalationHandler {
+	return &EscalationHandler{
+		db:          db,
+		roles:       roles,
+		escalations: escalations,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// ListPolicies handles GET /api/v1/organizations/:id/escalation-policies
+func (h *EscalationHandler) ListPolicies(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageEscalations, h.logger); !ok {
+		return
+	}
+
+	policies := []escalation.Policy{}
+	err := h.db.SelectContext(c.Request.Context(), &policies, `
+		SELECT `+escalation.PolicyColumns+` FROM escalation_policies
+		WHERE organization_id = $1
+		ORDER BY name
+	`, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list escalation policies", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list escalation policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreatePolicy handles POST /api/v1/organizations/:id/escalation-policies
+func (h *EscalationHandler) CreatePolicy(c *gin.Context) {
+	h.savePolicy(c, "")
+}
+
+// UpdatePolicy handles PUT /api/v1/organizations/:id/escalation-policies/:policy_id.
+// Escalations already in progress keep the levels they started with.
+func (h *EscalationHandler) UpdatePolicy(c *gin.Context) {
+	h.savePolicy(c, c.Param("policy_id"))
+}
+
+// savePolicy creates (policyID empty) or replaces a policy
+func (h *EscalationHandler) savePolicy(c *gin.Context, policyID string) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageEscalations, h.logger)
+	if !ok {
+		return
+	}
+
+	var req EscalationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if err := req.Levels.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	ctx := c.Request.Context()
+	var err error
+	var policy escalation.Policy
+	if policyID == "" {
+		err = h.db.GetContext(ctx, &policy, `
+			INSERT INTO escalation_policies (organization_id, name, triggers, levels, webhook_secret, enabled, created_by)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+			RETURNING `+escalation.PolicyColumns,
+			orgID, req.Name, pq.StringArray(req.Triggers), req.Levels, req.WebhookSecret, enabled, userID)
+	} else {
+		err = h.db.GetContext(ctx, &policy, `
+			UPDATE escalation_policies
+			SET name = $3, triggers = $4, levels = $5, webhook_secret = NULLIF($6, ''), enabled = $7,
+			    updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND organization_id = $2
+			RETURNING `+escalation.PolicyColumns,
+			policyID, orgID, req.Name, pq.StringArray(req.Triggers), req.Levels, req.WebhookSecret, enabled)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+		return
+	}
+	if database.IsUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An escalation policy with this name already exists"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to save escalation policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save escalation policy"})
+		return
+	}
+
+	action, status := "escalation_policy_updated", http.StatusOK
+	if policyID == "" {
+		action, status = "escalation_policy_created", http.StatusCreated
+	}
+	h.auditLogger.LogSuccess(ctx, userID, action, "escalation_policy", policy.ID, map[string]interface{}{
+		"organization_id": orgID,
+		"name":            policy.Name,
+		"triggers":        req.Triggers,
+		"levels":          len(policy.Levels),
+		"enabled":         policy.Enabled,
+	})
+
+	c.JSON(status, policy)
+}
+
+// DeletePolicy handles DELETE /api/v1/organizations/:id/escalation-policies/:policy_id.
+// Past escalations and their history are kept.
+func (h *EscalationHandler) DeletePolicy(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageEscalations, h.logger)
+	if !ok {
+		return
+	}
+
+	policyID := c.Param("policy_id")
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		DELETE FROM escalation_policies WHERE id = $1 AND organization_id = $2
+	`, policyID, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete escalation policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete escalation policy"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Escalation policy not found"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "escalation_policy_deleted", "escalation_policy", policyID, map[string]interface{}{
+		"organization_id": orgID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Escalation policy deleted"})
+}
+
+// ListEscalations handles GET /api/v1/organizations/:id/escalations
+func (h *EscalationHandler) ListEscalations(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewOrganization, h.logger); !ok {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", escalation.StatusOpen, escalation.StatusAcknowledged, escalation.StatusExhausted:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, acknowledged or exhausted"})
+		return
+	}
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = n
+	}
+
+	escalations := []escalation.Escalation{}
+	err := h.db.Reader().SelectContext(c.Request.Context(), &escalations, `
+		SELECT `+escalation.EscalationColumns+` FROM escalations
+		WHERE organization_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, orgID, status, limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list escalations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list escalations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, escalations)
+}
+
+// GetEscalation handles GET /api/v1/organizations/:id/escalations/:escalation_id
+func (h *EscalationHandler) GetEscalation(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewOrganization, h.logger); !ok {
+		return
+	}
+	escalationID := c.Param("escalation_id")
+	if _, err := uuid.Parse(escalationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escalation ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var detail EscalationDetail
+	err := h.db.GetContext(ctx, &detail.Escalation, `
+		SELECT `+escalation.EscalationColumns+` FROM escalations
+		WHERE id = $1 AND organization_id = $2
+	`, escalationID, orgID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Escalation not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load escalation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load escalation"})
+		return
+	}
+
+	detail.History = []escalation.HistoryEntry{}
+	err = h.db.SelectContext(ctx, &detail.History, `
+		SELECT id, kind, level, channel, target, error, actor_id, created_at
+		FROM escalation_history
+		WHERE escalation_id = $1
+		ORDER BY id
+	`, escalationID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load escalation history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load escalation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// AcknowledgeEscalation handles POST /api/v1/organizations/:id/escalations/:escalation_id/acknowledge
+func (h *EscalationHandler) AcknowledgeEscalation(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermAcknowledgeEscalations, h.logger)
+	if !ok {
+		return
+	}
+	escalationID := c.Param("escalation_id")
+	if _, err := uuid.Parse(escalationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escalation ID"})
+		return
+	}
+
+	var req AcknowledgeEscalationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		bindError(c, err)
+		return
+	}
+
+	h.acknowledge(c, orgID, escalationID, userID, req.Note, "api")
+}
+
+// AcknowledgeByLink handles GET/POST /api/v1/escalations/acknowledge, the
+// one-click link sent to paged contacts. The signed token is the only
+// credential, since contacts need not have an account.
+func (h *EscalationHandler) AcknowledgeByLink(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	escalationID, err := h.escalations.VerifyAckToken(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired link"})
+		return
+	}
+
+	h.acknowledge(c, "", escalationID, "", "", "link")
+}
+
+func (h *EscalationHandler) acknowledge(c *gin.Context, orgID, escalationID, userID, note, via string) {
+	ctx := c.Request.Context()
+	e, err := h.escalations.Acknowledge(ctx, orgID, escalationID, userID, note, via)
+	switch {
+	case err == escalation.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Escalation not found"})
+		return
+	case err == escalation.ErrAlreadyAcknowledged:
+		// Someone else got there first; the outcome the caller asked for
+		c.JSON(http.StatusOK, gin.H{"message": "Escalation already acknowledged", "escalation_id": escalationID})
+		return
+	case err != nil:
+		logging.FromContext(ctx, h.logger).Error("Failed to acknowledge escalation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge escalation"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(ctx, userID, "escalation_acknowledged", escalationID, "medium", map[string]interface{}{
+		"organization_id": e.OrganizationID,
+		"trigger":         e.Trigger,
+		"subject_id":      e.SubjectID,
+		"level":           e.Level,
+		"via":             via,
+		"ip_address":      c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, e)
+}
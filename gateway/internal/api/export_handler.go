@@ -0,0 +1,160 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/downloads"
+	"github.com/cyper-security/gateway/internal/export"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ExportHandler serves organization and personal data exports
+type ExportHandler struct {
+	exports     *export.Service
+	roles       *rbac.RoleStore
+	downloads   *downloads.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewExportHandler(exports *export.Service, roles *rbac.RoleStore, downloadService *downloads.Service, auditLogger Auditor, logger *zap.Logger) *ExportHandler {
+	return &ExportHandler{
+		exports:     exports,
+		roles:       roles,
+		downloads:   downloadService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// RequestOrganizationExport handles POST /api/v1/organizations/:id/export
+func (h *ExportHandler) RequestOrganizationExport(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	exp, err := h.exports.RequestOrganization(c.Request.Context(), orgID, userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to request organization export", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "data_export_requested", "organization", orgID, map[string]interface{}{
+		"export_id": exp.ID,
+		"scope":     exp.Scope,
+	})
+
+	c.JSON(http.StatusAccepted, exp)
+}
+
+// GetOrganizationExport handles GET /api/v1/organizations/:id/exports/:export_id
+func (h *ExportHandler) GetOrganizationExport(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger); !ok {
+		return
+	}
+
+	exportID := c.Param("export_id")
+	if _, err := uuid.Parse(exportID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	exp, err := h.exports.Get(c.Request.Context(), exportID)
+	if err == export.ErrExportNotFound || (err == nil && (exp.OrganizationID == nil || *exp.OrganizationID != orgID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load export", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load export"})
+		return
+	}
+
+	h.recordLink(c, exp)
+	c.JSON(http.StatusOK, exp)
+}
+
+// UserExport handles GET /api/v1/users/me/export. It returns the caller's
+// latest personal export, starting one when there is none; the response is
+// 202 until the bundle is ready.
+func (h *ExportHandler) UserExport(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	exp, err := h.exports.LatestForUser(ctx, userID)
+	if err == export.ErrExportNotFound {
+		exp, err = h.exports.RequestUser(ctx, userID)
+		if err == nil {
+			h.auditLogger.LogSuccess(ctx, userID, "data_export_requested", "user", userID, map[string]interface{}{
+				"export_id": exp.ID,
+				"scope":     exp.Scope,
+			})
+		}
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load personal export", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load export"})
+		return
+	}
+
+	status := http.StatusOK
+	if exp.Status != export.StatusCompleted {
+		status = http.StatusAccepted
+	}
+	h.recordLink(c, exp)
+	c.JSON(status, exp)
+}
+
+// recordLink records and audits the signed download link handed out with a
+// completed export. Storage serves the file itself, so the export's size
+// stands in for the bytes downloaded.
+func (h *ExportHandler) recordLink(c *gin.Context, exp *export.Export) {
+	if exp.DownloadURL == "" {
+		return
+	}
+	ctx := c.Request.Context()
+	download := &downloads.Download{
+		UserID:       c.GetString("user_id"),
+		ArtifactType: downloads.ArtifactExport,
+		ArtifactID:   exp.ID,
+		Format:       "zip",
+		Status:       downloads.StatusLinkIssued,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+	}
+	if exp.OrganizationID != nil {
+		download.OrganizationID = *exp.OrganizationID
+	}
+	if exp.SizeBytes != nil {
+		download.Bytes = *exp.SizeBytes
+	}
+	if err := h.downloads.Record(ctx, download); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to record export download link", zap.Error(err))
+	}
+	h.auditLogger.Log(ctx, audit.LogParams{
+		UserID:         download.UserID,
+		OrganizationID: download.OrganizationID,
+		Action:         "data_export_downloaded",
+		ResourceType:   "data_export",
+		ResourceID:     exp.ID,
+		Target:         exp.ID,
+		IPAddress:      download.IPAddress,
+		UserAgent:      download.UserAgent,
+		Severity:       "low",
+		Details: map[string]interface{}{
+			"download_id": download.ID,
+			"scope":       exp.Scope,
+			"bytes":       download.Bytes,
+			"status":      download.Status,
+		},
+	})
+}
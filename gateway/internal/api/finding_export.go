@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ExportFindings handles GET /api/v1/organizations/:id/findings/export. It
+// takes the filters of the organization findings field in GraphQL (severity,
+// status) plus scan_id, and streams every matching finding as csv or xlsx
+// (with columns=a,b,c to choose the columns) or in a format other tools
+// import: defectdojo (Generic Findings Import JSON) or nessus (.nessus XML).
+func (h *FindingHandler) ExportFindings(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewScan, h.logger)
+	if !ok {
+		return
+	}
+
+	formatName := c.DefaultQuery("format", "csv")
+	format, ok := findings.LookupExportFormat(formatName)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of " + strings.Join(findings.ExportFormatNames(), ", ")})
+		return
+	}
+	var columnNames []string
+	if v := c.Query("columns"); v != "" {
+		if !format.Tabular {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "columns can only be chosen for csv and xlsx exports"})
+			return
+		}
+		columnNames = strings.Split(v, ",")
+	}
+	columns, err := findings.SelectColumns(columnNames)
+	if errors.Is(err, findings.ErrUnknownColumn) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "columns": findings.ColumnNames()})
+		return
+	}
+	scanID := c.Query("scan_id")
+	if scanID != "" {
+		if _, err := uuid.Parse(scanID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scan ID"})
+			return
+		}
+	}
+	severity, status := c.Query("severity"), c.Query("status")
+
+	// Rows are streamed straight to the response; grouping by target keeps
+	// each host together for the formats that nest findings under it
+	ctx := c.Request.Context()
+	rows, err := h.db.Reader().QueryxContext(ctx, `
+		SELECT `+findings.RecordColumns+`
+		FROM vulnerabilities v
+		JOIN scan_jobs sj ON sj.id = v.scan_job_id
+		JOIN scan_targets st ON st.id = sj.target_id
+		LEFT JOIN cve_intel ci ON ci.cve_id = v.cve_id
+		LEFT JOIN known_exploited_cves k ON k.cve_id = v.cve_id
+		WHERE sj.organization_id = $1
+		AND ($2 = '' OR v.severity = $2)
+		AND ($3 = '' OR COALESCE(v.status, 'open') = $3)
+		AND ($4 = '' OR v.scan_job_id::text = $4)
+		ORDER BY st.target_value,
+		         CASE v.severity WHEN 'critical' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 ELSE 4 END,
+		         v.cvss_score DESC NULLS LAST, v.discovered_at DESC
+	`, orgID, severity, status, scanID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to export findings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export findings"})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", format.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="findings-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format.Extension))
+	c.Status(http.StatusOK)
+
+	// Past this point the status is sent, so a failure can only cut the
+	// export short
+	writer, err := format.New(c.Writer, columns)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to start findings export", zap.Error(err))
+		return
+	}
+	count := 0
+	for rows.Next() {
+		var record findings.Record
+		if err := rows.StructScan(&record); err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to read finding for export", zap.Error(err))
+			return
+		}
+		if err := writer.Write(&record); err != nil {
+			logging.FromContext(ctx, h.logger).Warn("Findings export interrupted", zap.Error(err))
+			return
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to export findings", zap.Error(err))
+		return
+	}
+	if err := writer.Close(); err != nil {
+		logging.FromContext(ctx, h.logger).Warn("Findings export interrupted", zap.Error(err))
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "findings_exported", "organization", orgID, map[string]interface{}{
+		"format":   format.Name,
+		"count":    count,
+		"severity": severity,
+		"status":   status,
+		"scan_id":  scanID,
+	})
+}
@@ -0,0 +1,614 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/cyper-security/gateway/internal/uploads"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// FindingHandler serves collaborative triage of findings: threaded comments,
+// assignment, status changes with history, and watcher notifications
+type FindingHandler struct {
+	db          *database.DB
+	roles       *rbac.RoleStore
+	uploads     *uploads.Service
+	notifier    Notifier
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewFindingHandler(db *database.DB, roles *rbac.RoleStore, uploadService *uploads.Service, notifier Notifier, auditLogger Auditor, logger *zap.Logger) *FindingHandler {
+	return &FindingHandler{
+		db:          db,
+		roles:       roles,
+		uploads:     uploadService,
+		notifier:    notifier,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// FindingComment is a comment on a finding; top-level comments carry their
+// replies
+type FindingComment struct {
+	ID        string           `json:"id" db:"id"`
+	ParentID  *string          `json:"parent_id,omitempty" db:"parent_id"`
+	AuthorID  *string          `json:"author_id" db:"author_id"`
+	Author    *string          `json:"author" db:"author"`
+	Body      string           `json:"body" db:"body"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	Replies   []FindingComment `json:"replies,omitempty" db:"-"`
+}
+
+// FindingStatusChange is one entry of a finding's status history
+type FindingStatusChange struct {
+	FromStatus string    `json:"from_status" db:"from_status"`
+	ToStatus   string    `json:"to_status" db:"to_status"`
+	Reason     *string   `json:"reason,omitempty" db:"reason"`
+	ChangedBy  *string   `json:"changed_by" db:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// FindingAssignment is who a finding is assigned to
+type FindingAssignment struct {
+	FindingID  string     `json:"finding_id" db:"id"`
+	AssigneeID *string    `json:"assignee_id" db:"assignee_id"`
+	AssignedBy *string    `json:"assigned_by" db:"assigned_by"`
+	AssignedAt *time.Time `json:"assigned_at" db:"assigned_at"`
+	DueDate    *time.Time `json:"due_date" db:"due_date"`
+}
+
+type CreateFindingCommentRequest struct {
+	Body     string `json:"body" binding:"required,max=10000"`
+	ParentID string `json:"parent_id" binding:"omitempty,uuid"`
+}
+
+type AssignFindingRequest struct {
+	AssigneeID *string `json:"assignee_id" binding:"omitempty,uuid"` // null unassigns
+	DueDate    string  `json:"due_date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type FindingStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=open confirmed false_positive fixed accepted"`
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+// FindingEvidence is a file attached to a finding, with its scan verdict.
+// Quarantined files have no download link.
+type FindingEvidence struct {
+	uploads.Artifact
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// evidenceURLTTL is how long evidence download links stay valid
+const evidenceURLTTL = 15 * time.Minute
+
+// findingRef is the part of a finding needed to authorize and notify
+type findingRef struct {
+	ID     string `db:"id"`
+	Title  string `db:"title"`
+	Status string `db:"status"`
+}
+
+// loadFinding authorizes the caller against the :id organization and loads
+// the :finding_id finding, which must belong to it
+func (h *FindingHandler) loadFinding(c *gin.Context, perm rbac.Permission) (string, *findingRef, bool) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, perm, h.logger)
+	if !ok {
+		return "", nil, false
+	}
+
+	findingID := c.Param("finding_id")
+	if _, err := uuid.Parse(findingID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid finding ID"})
+		return "", nil, false
+	}
+
+	var finding findingRef
+	err := h.db.GetContext(c.Request.Context(), &finding, `
+		SELECT id, title, status FROM vulnerabilities WHERE id = $1 AND organization_id = $2
+	`, findingID, orgID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Finding not found"})
+		return "", nil, false
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load finding", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load finding"})
+		return "", nil, false
+	}
+
+	return userID, &finding, true
+}
+
+// ListComments handles GET /api/v1/organizations/:id/findings/:finding_id/comments
+func (h *FindingHandler) ListComments(c *gin.Context) {
+	_, finding, ok := h.loadFinding(c, rbac.PermViewScan)
+	if !ok {
+		return
+	}
+
+	var comments []FindingComment
+	err := h.db.SelectContext(c.Request.Context(), &comments, `
+		SELECT fc.id, fc.parent_id, fc.author_id, u.username AS author, fc.body, fc.created_at
+		FROM finding_comments fc
+		LEFT JOIN users u ON u.id = fc.author_id
+		WHERE fc.vulnerability_id = $1
+		ORDER BY fc.created_at
+	`, finding.ID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list finding comments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list comments"})
+		return
+	}
+
+	// Replies always point at a top-level comment, so one pass builds the threads
+	threads := []FindingComment{}
+	index := make(map[string]int)
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			index[comment.ID] = len(threads)
+			threads = append(threads, comment)
+			continue
+		}
+		if i, ok := index[*comment.ParentID]; ok {
+			threads[i].Replies = append(threads[i].Replies, comment)
+		}
+	}
+
+	c.JSON(http.StatusOK, threads)
+}
+
+// CreateComment handles POST /api/v1/organizations/:id/findings/:finding_id/comments
+func (h *FindingHandler) CreateComment(c *gin.Context) {
+	userID, finding, ok := h.loadFinding(c, rbac.PermTriageFinding)
+	if !ok {
+		return
+	}
+	orgID := c.Param("id")
+
+	var req CreateFindingCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to begin transaction", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Replies to a reply join the thread of its top-level comment
+	var parentID *string
+	if req.ParentID != "" {
+		var threadID string
+		err = tx.GetContext(ctx, &threadID, `
+			SELECT COALESCE(parent_id, id) FROM finding_comments WHERE id = $1 AND vulnerability_id = $2
+		`, req.ParentID, finding.ID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent comment not found on this finding"})
+			return
+		}
+		if err != nil {
+			logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load parent comment", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+			return
+		}
+		parentID = &threadID
+	}
+
+	comment := FindingComment{ParentID: parentID, AuthorID: &userID, Body: req.Body}
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO finding_comments (vulnerability_id, organization_id, parent_id, author_id, body)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, finding.ID, orgID, parentID, userID, req.Body).Scan(&comment.ID, &comment.CreatedAt)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to add finding comment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+
+	if err := addWatcher(ctx, tx, finding.ID, userID); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to add finding watcher", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to commit finding comment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "finding_commented", "vulnerability", finding.ID, map[string]interface{}{
+		"comment_id": comment.ID,
+		"parent_id":  parentID,
+	})
+
+	h.notifyWatchers(ctx, finding.ID, userID, realtime.FindingActivityEvent{
+		FindingID:      finding.ID,
+		OrganizationID: orgID,
+		Kind:           "comment",
+		ActorID:        userID,
+		Title:          finding.Title,
+		CommentID:      comment.ID,
+	})
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// AssignFinding handles PUT /api/v1/organizations/:id/findings/:finding_id/assignment.
+// The assignee must be a member of the organization.
+func (h *FindingHandler) AssignFinding(c *gin.Context) {
+	userID, finding, ok := h.loadFinding(c, rbac.PermTriageFinding)
+	if !ok {
+		return
+	}
+	orgID := c.Param("id")
+
+	var req AssignFindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	var dueDate *time.Time
+	if req.DueDate != "" {
+		due, _ := time.Parse("2006-01-02", req.DueDate)
+		dueDate = &due
+	}
+
+	ctx := c.Request.Context()
+	if req.AssigneeID != nil {
+		if _, err := h.roles.MemberRole(ctx, *req.AssigneeID, orgID); err == rbac.ErrNotMember {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Assignee is not a member of this organization"})
+			return
+		} else if err != nil {
+			logging.FromContext(c.Request.Context(), h.logger).Error("Failed to verify assignee membership", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign finding"})
+			return
+		}
+	}
+
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to begin transaction", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign finding"})
+		return
+	}
+	defer tx.Rollback()
+
+	var assignment FindingAssignment
+	err = tx.GetContext(ctx, &assignment, `
+		UPDATE vulnerabilities
+		SET assignee_id = $2,
+		    assigned_by = CASE WHEN $2::uuid IS NULL THEN NULL ELSE $3::uuid END,
+		    assigned_at = CASE WHEN $2::uuid IS NULL THEN NULL ELSE NOW() END,
+		    due_date = $4,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, assignee_id, assigned_by, assigned_at, due_date
+	`, finding.ID, req.AssigneeID, userID, dueDate)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to assign finding", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign finding"})
+		return
+	}
+
+	if req.AssigneeID != nil {
+		if err := addWatcher(ctx, tx, finding.ID, *req.AssigneeID); err != nil {
+			logging.FromContext(c.Request.Context(), h.logger).Error("Failed to add finding watcher", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign finding"})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to commit finding assignment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign finding"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "finding_assigned", "vulnerability", finding.ID, map[string]interface{}{
+		"assignee_id": req.AssigneeID,
+		"due_date":    req.DueDate,
+	})
+
+	event := realtime.FindingActivityEvent{
+		FindingID:      finding.ID,
+		OrganizationID: orgID,
+		Kind:           "assigned",
+		ActorID:        userID,
+		Title:          finding.Title,
+		DueDate:        dueDate,
+	}
+	if req.AssigneeID != nil {
+		event.AssigneeID = *req.AssigneeID
+	}
+	h.notifyWatchers(ctx, finding.ID, userID, event)
+
+	c.JSON(http.StatusOK, assignment)
+}
+
+// UpdateStatus handles PUT /api/v1/organizations/:id/findings/:finding_id/status
+func (h *FindingHandler) UpdateStatus(c *gin.Context) {
+	userID, finding, ok := h.loadFinding(c, rbac.PermTriageFinding)
+	if !ok {
+		return
+	}
+	orgID := c.Param("id")
+
+	var req FindingStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to begin transaction", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the row so concurrent changes are recorded in order
+	var fromStatus string
+	err = tx.GetContext(ctx, &fromStatus, `
+		SELECT status FROM vulnerabilities WHERE id = $1 FOR UPDATE
+	`, finding.ID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to lock finding", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		return
+	}
+	if fromStatus == req.Status {
+		c.JSON(http.StatusOK, gin.H{"status": fromStatus})
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vulnerabilities SET status = $2, updated_at = NOW() WHERE id = $1
+	`, finding.ID, req.Status)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to update finding status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO finding_status_history (vulnerability_id, organization_id, from_status, to_status, reason, changed_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+	`, finding.ID, orgID, fromStatus, req.Status, req.Reason, userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to record finding status change", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to commit finding status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "finding_status_changed", "vulnerability", finding.ID, map[string]interface{}{
+		"from_status": fromStatus,
+		"to_status":   req.Status,
+		"reason":      req.Reason,
+	})
+
+	h.notifyWatchers(ctx, finding.ID, userID, realtime.FindingActivityEvent{
+		FindingID:      finding.ID,
+		OrganizationID: orgID,
+		Kind:           "status_changed",
+		ActorID:        userID,
+		Title:          finding.Title,
+		FromStatus:     fromStatus,
+		ToStatus:       req.Status,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": req.Status, "previous_status": fromStatus})
+}
+
+// StatusHistory handles GET /api/v1/organizations/:id/findings/:finding_id/history
+func (h *FindingHandler) StatusHistory(c *gin.Context) {
+	_, finding, ok := h.loadFinding(c, rbac.PermViewScan)
+	if !ok {
+		return
+	}
+
+	history := []FindingStatusChange{}
+	err := h.db.SelectContext(c.Request.Context(), &history, `
+		SELECT from_status, to_status, reason, changed_by, changed_at
+		FROM finding_status_history
+		WHERE vulnerability_id = $1
+		ORDER BY changed_at
+	`, finding.ID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load finding status history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// Watch handles PUT /api/v1/organizations/:id/findings/:finding_id/watch
+func (h *FindingHandler) Watch(c *gin.Context) {
+	userID, finding, ok := h.loadFinding(c, rbac.PermViewScan)
+	if !ok {
+		return
+	}
+
+	if err := addWatcher(c.Request.Context(), h.db, finding.ID, userID); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to add finding watcher", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to watch finding"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Unwatch handles DELETE /api/v1/organizations/:id/findings/:finding_id/watch
+func (h *FindingHandler) Unwatch(c *gin.Context) {
+	userID, finding, ok := h.loadFinding(c, rbac.PermViewScan)
+	if !ok {
+		return
+	}
+
+	_, err := h.db.ExecContext(c.Request.Context(), `
+		DELETE FROM finding_watchers WHERE vulnerability_id = $1 AND user_id = $2
+	`, finding.ID, userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to remove finding watcher", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unwatch finding"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func addWatcher(ctx context.Context, db execer, findingID, userID string) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO finding_watchers (vulnerability_id, user_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, findingID, userID)
+	return err
+}
+
+// notifyWatchers pushes event to every watcher except the actor. Watchers
+// who have since left the organization are skipped.
+func (h *FindingHandler) notifyWatchers(ctx context.Context, findingID, actorID string, event realtime.FindingActivityEvent) {
+	var watchers []string
+	err := h.db.SelectContext(ctx, &watchers, `
+		SELECT fw.user_id FROM finding_watchers fw
+		JOIN organization_memberships om ON om.user_id = fw.user_id AND om.organization_id = $2
+		WHERE fw.vulnerability_id = $1 AND fw.user_id <> $3
+	`, findingID, event.OrganizationID, actorID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load finding watchers", zap.Error(err))
+		return
+	}
+
+	for _, watcher := range watchers {
+		h.notifier.PublishToUser(watcher, event)
+	}
+}
+
+// UploadEvidence handles POST /api/v1/organizations/:id/findings/:finding_id/evidence,
+// a multipart form with the file in the "file" field. Files the content
+// scanner flags are quarantined and refused.
+func (h *FindingHandler) UploadEvidence(c *gin.Context) {
+	userID, finding, ok := h.loadFinding(c, rbac.PermTriageFinding)
+	if !ok {
+		return
+	}
+	orgID := c.Param("id")
+
+	// Leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, uploads.MaxEvidenceBytes+64<<10)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required (at most 25 MB)"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	ctx := c.Request.Context()
+	artifact, err := h.uploads.Accept(ctx, uploads.Upload{
+		OrganizationID: orgID,
+		Kind:           uploads.KindEvidence,
+		FindingID:      finding.ID,
+		Filename:       header.Filename,
+		UploadedBy:     userID,
+		Policy:         uploads.EvidencePolicy,
+		KeyPrefix:      fmt.Sprintf("evidence/%s/%s/", orgID, finding.ID),
+	}, file)
+	switch {
+	case err == uploads.ErrTooLarge, err == uploads.ErrUnsupportedType:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "evidence must be an image, PDF, text file or zip archive of at most 25 MB"})
+		return
+	case abortUpload(c, err, h.auditLogger, userID):
+		return
+	case err != nil:
+		logging.FromContext(ctx, h.logger).Error("Failed to store evidence", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store evidence"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "finding_evidence_uploaded", "vulnerability", finding.ID, map[string]interface{}{
+		"artifact_id":  artifact.ID,
+		"filename":     artifact.Filename,
+		"content_type": artifact.ContentType,
+		"sha256":       artifact.SHA256,
+		"scan_verdict": artifact.ScanVerdict,
+	})
+
+	c.JSON(http.StatusCreated, h.evidence(ctx, *artifact))
+}
+
+// ListEvidence handles GET /api/v1/organizations/:id/findings/:finding_id/evidence
+func (h *FindingHandler) ListEvidence(c *gin.Context) {
+	_, finding, ok := h.loadFinding(c, rbac.PermViewScan)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	artifacts, err := h.uploads.ListEvidence(ctx, c.Param("id"), finding.ID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to list evidence", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list evidence"})
+		return
+	}
+
+	evidence := make([]FindingEvidence, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		evidence = append(evidence, h.evidence(ctx, artifact))
+	}
+	c.JSON(http.StatusOK, evidence)
+}
+
+// evidence links a clean or unscanned file; a signing failure leaves the
+// link out
+func (h *FindingHandler) evidence(ctx context.Context, artifact uploads.Artifact) FindingEvidence {
+	evidence := FindingEvidence{Artifact: artifact}
+	if artifact.Quarantined {
+		return evidence
+	}
+	url, err := h.uploads.SignedURL(ctx, &artifact, evidenceURLTTL)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Warn("Failed to sign evidence URL", zap.String("artifact_id", artifact.ID), zap.Error(err))
+		return evidence
+	}
+	evidence.DownloadURL = url
+	return evidence
+}
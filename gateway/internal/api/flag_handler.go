@@ -0,0 +1,178 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/flags"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FlagHandler lets platform admins flip feature flags at runtime
+type FlagHandler struct {
+	flags       *flags.Service
+	authService Authenticator
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewFlagHandler(flagService *flags.Service, authService Authenticator, auditLogger Auditor, logger *zap.Logger) *FlagHandler {
+	return &FlagHandler{
+		flags:       flagService,
+		authService: authService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// FlagOverrideRequest forces a flag on or off for an organization or user
+type FlagOverrideRequest struct {
+	Scope    string `json:"scope" binding:"required,oneof=organization user"`
+	TargetID string `json:"target_id" binding:"required,uuid"`
+	Enabled  *bool  `json:"enabled" binding:"required"`
+}
+
+// ListFlags handles GET /api/v1/admin/flags
+func (h *FlagHandler) ListFlags(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	list, err := h.flags.List(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list feature flags", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list feature flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// SetFlag handles PUT /api/v1/admin/flags/:key, creating the flag if needed
+func (h *FlagHandler) SetFlag(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	userID := c.GetString("user_id")
+
+	var req flags.FlagUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	flag, err := h.flags.Set(c.Request.Context(), c.Param("key"), req, userID)
+	switch err {
+	case nil:
+	case flags.ErrInvalidKey, flags.ErrInvalidRollout:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to save feature flag", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save feature flag"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "feature_flag_updated", "feature_flag", flag.Key, map[string]interface{}{
+		"enabled":            flag.Enabled,
+		"rollout_percentage": flag.RolloutPercentage,
+	})
+
+	c.JSON(http.StatusOK, flag)
+}
+
+// DeleteFlag handles DELETE /api/v1/admin/flags/:key
+func (h *FlagHandler) DeleteFlag(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	userID := c.GetString("user_id")
+	key := c.Param("key")
+
+	err := h.flags.Delete(c.Request.Context(), key)
+	if err == flags.ErrFlagNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature flag not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete feature flag", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete feature flag"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "feature_flag_deleted", "feature_flag", key, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feature flag deleted"})
+}
+
+// SetOverride handles PUT /api/v1/admin/flags/:key/overrides
+func (h *FlagHandler) SetOverride(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	userID := c.GetString("user_id")
+	key := c.Param("key")
+
+	var req FlagOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	err := h.flags.SetOverride(c.Request.Context(), key, req.Scope, req.TargetID, *req.Enabled, userID)
+	switch err {
+	case nil:
+	case flags.ErrFlagNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature flag not found"})
+		return
+	case flags.ErrInvalidScope:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to save feature flag override", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save override"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "feature_flag_override_set", "feature_flag", key, map[string]interface{}{
+		"scope":     req.Scope,
+		"target_id": req.TargetID,
+		"enabled":   *req.Enabled,
+	})
+
+	flag, err := h.flags.Get(c.Request.Context(), key)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load feature flag", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feature flag"})
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// DeleteOverride handles DELETE /api/v1/admin/flags/:key/overrides/:scope/:target_id
+func (h *FlagHandler) DeleteOverride(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	userID := c.GetString("user_id")
+	key, scope, targetID := c.Param("key"), c.Param("scope"), c.Param("target_id")
+
+	err := h.flags.DeleteOverride(c.Request.Context(), key, scope, targetID)
+	if err == flags.ErrOverrideNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Override not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete feature flag override", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete override"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "feature_flag_override_deleted", "feature_flag", key, map[string]interface{}{
+		"scope":     scope,
+		"target_id": targetID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Override deleted"})
+}
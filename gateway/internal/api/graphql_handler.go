@@ -0,0 +1,767 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/graphql"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// maxGraphQLPage caps the limit argument of list fields
+const maxGraphQLPage = 100
+
+// GraphQLHandler serves the dashboard's read queries over GraphQL. Resolvers
+// check the same organization permissions as the REST endpoints, and nested
+// lists are loaded in one query per level through per-request loaders.
+type GraphQLHandler struct {
+	db     *database.DB
+	repos  *repository.Repositories
+	roles  *rbac.RoleStore
+	limits graphql.Limits
+	logger *zap.Logger
+	schema *graphql.Schema
+	sdl    string
+}
+
+func NewGraphQLHandler(db *database.DB, repos *repository.Repositories, roles *rbac.RoleStore, limits graphql.Limits, logger *zap.Logger) *GraphQLHandler {
+	h := &GraphQLHandler{
+		db:     db,
+		repos:  repos,
+		roles:  roles,
+		limits: limits,
+		logger: logger,
+	}
+	h.schema = h.buildSchema()
+	h.sdl = h.schema.SDL()
+	return h
+}
+
+// Query handles POST /api/v1/graphql
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req graphql.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphQLRequestKey{}, &graphQLRequest{
+		userID:  c.GetString("user_id"),
+		loaders: graphql.NewLoaders(),
+		roles:   map[string]rbac.Role{},
+	})
+	resp := graphql.Execute(ctx, h.schema, req, h.limits)
+	if resp.Data == nil {
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Schema handles GET /api/v1/graphql/schema
+func (h *GraphQLHandler) Schema(c *gin.Context) {
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(h.sdl))
+}
+
+type graphQLRequestKey struct{}
+
+// graphQLRequest is the state of one query: the viewer, their roles by
+// organization and the loaders batching its lookups
+type graphQLRequest struct {
+	userID  string
+	loaders *graphql.Loaders
+	roles   map[string]rbac.Role // Empty for organizations the viewer is not a member of
+}
+
+func requestFrom(ctx context.Context) *graphQLRequest {
+	return ctx.Value(graphQLRequestKey{}).(*graphQLRequest)
+}
+
+var errGraphQLAccessDenied = errors.New("access denied")
+
+// authorize checks the viewer's permission in the organization
+func (h *GraphQLHandler) authorize(ctx context.Context, orgID string, perm rbac.Permission) error {
+	req := requestFrom(ctx)
+	role, ok := req.roles[orgID]
+	if !ok {
+		var err error
+		role, err = h.roles.MemberRole(ctx, req.userID, orgID)
+		if err != nil && err != rbac.ErrNotMember {
+			logging.FromContext(ctx, h.logger).Error("Failed to verify membership", zap.Error(err))
+			return errors.New("failed to verify access")
+		}
+		req.roles[orgID] = role
+	}
+	if role == "" {
+		return errGraphQLAccessDenied
+	}
+
+	allowed, err := h.roles.HasPermission(ctx, orgID, role, perm)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to resolve role permissions", zap.Error(err))
+		return errors.New("failed to check permissions")
+	}
+	if !allowed {
+		return fmt.Errorf("you do not have the %s permission in this organization", perm)
+	}
+	return nil
+}
+
+// gqlOrganization is an Organization as seen by the viewer
+type gqlOrganization struct {
+	ID   string `graphql:"id"`
+	Name string `graphql:"name"`
+	Slug string `graphql:"slug"`
+	Tier string `graphql:"tier"`
+	Role string `graphql:"role"`
+}
+
+type gqlScan struct {
+	ID                    string     `db:"id" graphql:"id"`
+	OrganizationID        string     `db:"organization_id"`
+	AuthorizationTargetID *string    `db:"authorization_target_id"`
+	ScanType              string     `db:"scan_type" graphql:"scanType"`
+	ScanMode              string     `db:"scan_mode" graphql:"scanMode"`
+	Status                string     `db:"status" graphql:"status"`
+	Priority              int        `db:"priority" graphql:"priority"`
+	Progress              int        `db:"progress_percentage" graphql:"progress"`
+	TargetType            string     `db:"target_type" graphql:"targetType"`
+	Target                string     `db:"target_value" graphql:"target"`
+	CreatedAt             time.Time  `db:"created_at" graphql:"createdAt"`
+	StartedAt             *time.Time `db:"started_at" graphql:"startedAt"`
+	CompletedAt           *time.Time `db:"completed_at" graphql:"completedAt"`
+}
+
+const gqlScanColumns = `sj.id, sj.organization_id, sj.authorization_target_id, sj.scan_type, sj.scan_mode, sj.status,
+	COALESCE(sj.priority, 5) AS priority, COALESCE(sj.progress_percentage, 0) AS progress_percentage,
+	st.target_type, st.target_value, sj.created_at, sj.started_at, sj.completed_at`
+
+type gqlFinding struct {
+	ID                string     `db:"id" graphql:"id"`
+	OrganizationID    string     `db:"organization_id"`
+	ScanID            string     `db:"scan_job_id" graphql:"scanId"`
+	Title             string     `db:"title" graphql:"title"`
+	Severity          string     `db:"severity" graphql:"severity"`
+	Status            string     `db:"status" graphql:"status"`
+	CVSSScore         *float64   `db:"cvss_score" graphql:"cvssScore"`
+	CVEID             *string    `db:"cve_id" graphql:"cveId"`
+	AffectedComponent *string    `db:"affected_component" graphql:"affectedComponent"`
+	AssigneeID        *string    `db:"assignee_id" graphql:"assigneeId"`
+	Suppressed        bool       `db:"suppressed" graphql:"suppressed"`
+	DiscoveredAt      *time.Time `db:"discovered_at" graphql:"discoveredAt"`
+}
+
+const gqlFindingColumns = `v.id, sj.organization_id, v.scan_job_id, v.title, v.severity, COALESCE(v.status, 'open') AS status,
+	v.cvss_score, v.cve_id, v.affected_component, v.assignee_id, v.suppressed_at IS NOT NULL AS suppressed, v.discovered_at`
+
+type gqlAsset struct {
+	ID                 string         `db:"id" graphql:"id"`
+	OrganizationID     string         `db:"organization_id"`
+	TargetType         string         `db:"target_type" graphql:"targetType"`
+	TargetValue        string         `db:"target_value" graphql:"targetValue"`
+	VerificationStatus string         `db:"verification_status" graphql:"verificationStatus"`
+	Tags               pq.StringArray `db:"tags" graphql:"tags"`
+	ValidFrom          time.Time      `db:"valid_from" graphql:"validFrom"`
+	ValidUntil         time.Time      `db:"valid_until" graphql:"validUntil"`
+}
+
+const gqlAssetColumns = `at.id, at.organization_id, at.target_type, at.target_value,
+	COALESCE(at.verification_status, 'pending') AS verification_status, at.tags, at.valid_from, at.valid_until`
+
+type gqlSeverityCounts struct {
+	Critical int `graphql:"critical"`
+	High     int `graphql:"high"`
+	Medium   int `graphql:"medium"`
+	Low      int `graphql:"low"`
+	Info     int `graphql:"info"`
+}
+
+type gqlActionCount struct {
+	Action   string `db:"action" graphql:"action"`
+	Count    int    `db:"count" graphql:"count"`
+	Failures int    `db:"failures" graphql:"failures"`
+}
+
+type gqlAuditSummary struct {
+	Days     int              `graphql:"days"`
+	Total    int              `graphql:"total"`
+	Failures int              `graphql:"failures"`
+	Actions  []gqlActionCount `graphql:"actions"`
+}
+
+func (h *GraphQLHandler) buildSchema() *graphql.Schema {
+	nonNull := func(t graphql.Type) graphql.Type { return &graphql.NonNull{Of: t} }
+	listOf := func(t graphql.Type) graphql.Type { return nonNull(&graphql.List{Of: nonNull(t)}) }
+	limitArg := func(def int) *graphql.Argument {
+		return &graphql.Argument{Type: graphql.Int, Default: def, Description: fmt.Sprintf("At most %d", maxGraphQLPage)}
+	}
+
+	severity := &graphql.Enum{Name: "Severity", Values: []string{"critical", "high", "medium", "low", "info"}}
+	findingStatus := &graphql.Enum{Name: "FindingStatus", Values: []string{"open", "confirmed", "false_positive", "fixed", "accepted"}}
+	scanStatus := &graphql.Enum{Name: "ScanStatus", Values: []string{"pending_approval", "pending", "running", "paused", "completed", "failed", "stopped", "rejected"}}
+
+	severityCounts := &graphql.Object{Name: "SeverityCounts", Fields: graphql.Fields{
+		"critical": {Type: nonNull(graphql.Int)},
+		"high":     {Type: nonNull(graphql.Int)},
+		"medium":   {Type: nonNull(graphql.Int)},
+		"low":      {Type: nonNull(graphql.Int)},
+		"info":     {Type: nonNull(graphql.Int)},
+	}}
+	actionCount := &graphql.Object{Name: "ActionCount", Fields: graphql.Fields{
+		"action":   {Type: nonNull(graphql.String)},
+		"count":    {Type: nonNull(graphql.Int)},
+		"failures": {Type: nonNull(graphql.Int)},
+	}}
+	auditSummary := &graphql.Object{Name: "AuditSummary", Description: "Audit events of the last days, by action", Fields: graphql.Fields{
+		"days":     {Type: nonNull(graphql.Int)},
+		"total":    {Type: nonNull(graphql.Int)},
+		"failures": {Type: nonNull(graphql.Int), Description: "Events whose status is failure or error"},
+		"actions":  {Type: listOf(actionCount)},
+	}}
+
+	organization := &graphql.Object{Name: "Organization"}
+	scan := &graphql.Object{Name: "Scan"}
+	finding := &graphql.Object{Name: "Finding"}
+	asset := &graphql.Object{Name: "Asset", Description: "An authorized scan target"}
+
+	organization.Fields = graphql.Fields{
+		"id":   {Type: nonNull(graphql.ID)},
+		"name": {Type: nonNull(graphql.String)},
+		"slug": {Type: nonNull(graphql.String)},
+		"tier": {Type: nonNull(graphql.String)},
+		"role": {Type: nonNull(graphql.String), Description: "The viewer's role"},
+		"scans": {
+			Type:        listOf(scan),
+			Description: "Newest scans first",
+			Args:        map[string]*graphql.Argument{"limit": limitArg(20), "status": {Type: scanStatus}},
+			Resolve:     h.resolveOrgScans,
+		},
+		"findings": {
+			Type:        listOf(finding),
+			Description: "Newest findings first",
+			Args:        map[string]*graphql.Argument{"limit": limitArg(50), "severity": {Type: severity}, "status": {Type: findingStatus}},
+			Resolve:     h.resolveOrgFindings,
+		},
+		"assets": {
+			Type:    listOf(asset),
+			Args:    map[string]*graphql.Argument{"limit": limitArg(50)},
+			Resolve: h.resolveOrgAssets,
+		},
+		"auditSummary": {
+			Type:    nonNull(auditSummary),
+			Args:    map[string]*graphql.Argument{"days": {Type: graphql.Int, Default: 7, Description: "1 to 90"}},
+			Resolve: h.resolveAuditSummary,
+			Cost:    10,
+		},
+	}
+	scan.Fields = graphql.Fields{
+		"id":          {Type: nonNull(graphql.ID)},
+		"scanType":    {Type: nonNull(graphql.String)},
+		"scanMode":    {Type: nonNull(graphql.String)},
+		"status":      {Type: nonNull(scanStatus)},
+		"priority":    {Type: nonNull(graphql.Int)},
+		"progress":    {Type: nonNull(graphql.Int), Description: "Percentage complete"},
+		"targetType":  {Type: nonNull(graphql.String)},
+		"target":      {Type: nonNull(graphql.String)},
+		"createdAt":   {Type: nonNull(graphql.Time)},
+		"startedAt":   {Type: graphql.Time},
+		"completedAt": {Type: graphql.Time},
+		"findingCounts": {
+			Type:    nonNull(severityCounts),
+			Resolve: h.resolveFindingCounts,
+		},
+		"findings": {
+			Type:        listOf(finding),
+			Description: "Most severe findings first",
+			Args:        map[string]*graphql.Argument{"limit": limitArg(50), "severity": {Type: severity}},
+			Resolve:     h.resolveScanFindings,
+		},
+		"asset": {
+			Type:        asset,
+			Description: "The authorization the scan ran under",
+			Resolve:     h.resolveScanAsset,
+		},
+	}
+	finding.Fields = graphql.Fields{
+		"id":                {Type: nonNull(graphql.ID)},
+		"scanId":            {Type: nonNull(graphql.ID)},
+		"title":             {Type: nonNull(graphql.String)},
+		"severity":          {Type: nonNull(severity)},
+		"status":            {Type: nonNull(findingStatus)},
+		"cvssScore":         {Type: graphql.Float},
+		"cveId":             {Type: graphql.String},
+		"affectedComponent": {Type: graphql.String},
+		"assigneeId":        {Type: graphql.ID},
+		"suppressed":        {Type: nonNull(graphql.Boolean)},
+		"discoveredAt":      {Type: graphql.Time},
+		"scan": {
+			Type:    nonNull(scan),
+			Resolve: h.resolveFindingScan,
+		},
+	}
+	asset.Fields = graphql.Fields{
+		"id":                 {Type: nonNull(graphql.ID)},
+		"targetType":         {Type: nonNull(graphql.String)},
+		"targetValue":        {Type: nonNull(graphql.String)},
+		"verificationStatus": {Type: nonNull(graphql.String)},
+		"tags":               {Type: listOf(graphql.String)},
+		"validFrom":          {Type: nonNull(graphql.Time)},
+		"validUntil":         {Type: nonNull(graphql.Time)},
+		"lastScan": {
+			Type:    scan,
+			Resolve: h.resolveAssetLastScan,
+		},
+	}
+
+	return &graphql.Schema{Query: &graphql.Object{Name: "Query", Fields: graphql.Fields{
+		"organizations": {
+			Type:        listOf(organization),
+			Description: "Organizations the viewer is a member of",
+			Resolve:     h.resolveOrganizations,
+		},
+		"organization": {
+			Type:    organization,
+			Args:    map[string]*graphql.Argument{"id": {Type: nonNull(graphql.ID)}},
+			Resolve: h.resolveOrganization,
+		},
+		"scan": {
+			Type:    scan,
+			Args:    map[string]*graphql.Argument{"id": {Type: nonNull(graphql.ID)}},
+			Resolve: h.resolveScan,
+		},
+	}}}
+}
+
+func (h *GraphQLHandler) resolveOrganizations(p graphql.ResolveParams) (interface{}, error) {
+	req := requestFrom(p.Context)
+	memberships, err := h.repos.Orgs.ListForUser(p.Context, req.userID)
+	if err != nil {
+		logging.FromContext(p.Context, h.logger).Error("Failed to list organizations", zap.Error(err))
+		return nil, errors.New("failed to list organizations")
+	}
+
+	orgs := make([]*gqlOrganization, 0, len(memberships))
+	for _, m := range memberships {
+		req.roles[m.ID] = rbac.Role(m.Role)
+		orgs = append(orgs, &gqlOrganization{ID: m.ID, Name: m.Name, Slug: m.Slug, Tier: m.SubscriptionTier, Role: m.Role})
+	}
+	return orgs, nil
+}
+
+func (h *GraphQLHandler) resolveOrganization(p graphql.ResolveParams) (interface{}, error) {
+	orgID := p.Args["id"].(string)
+	if _, err := uuid.Parse(orgID); err != nil {
+		return nil, nil
+	}
+	if err := h.authorize(p.Context, orgID, rbac.PermViewOrganization); err != nil {
+		if err == errGraphQLAccessDenied {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	org, err := h.repos.Orgs.Get(p.Context, orgID)
+	if err == repository.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		logging.FromContext(p.Context, h.logger).Error("Failed to get organization", zap.Error(err))
+		return nil, errors.New("failed to load organization")
+	}
+	role := requestFrom(p.Context).roles[orgID]
+	return &gqlOrganization{ID: org.ID, Name: org.Name, Slug: org.Slug, Tier: org.SubscriptionTier, Role: string(role)}, nil
+}
+
+// resolveScan returns null for scans outside the viewer's organizations
+func (h *GraphQLHandler) resolveScan(p graphql.ResolveParams) (interface{}, error) {
+	scanID := p.Args["id"].(string)
+	if _, err := uuid.Parse(scanID); err != nil {
+		return nil, nil
+	}
+	value, err := h.scanLoader(p.Context).Load(p.Context, scanID)()
+	if err != nil || value == nil {
+		return nil, err
+	}
+	if err := h.authorize(p.Context, value.(*gqlScan).OrganizationID, rbac.PermViewScan); err != nil {
+		if err == errGraphQLAccessDenied {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+func (h *GraphQLHandler) resolveOrgScans(p graphql.ResolveParams) (interface{}, error) {
+	org := p.Source.(*gqlOrganization)
+	limit, err := pageLimit(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.authorize(p.Context, org.ID, rbac.PermViewScan); err != nil {
+		return nil, err
+	}
+
+	status, _ := p.Args["status"].(string)
+	loader := requestFrom(p.Context).loaders.Get(fmt.Sprintf("organization.scans:%d:%s", limit, status), func(ctx context.Context, orgIDs []string) (map[string]interface{}, error) {
+		var scans []*gqlScan
+		err := h.db.Reader().SelectContext(ctx, &scans, `
+			SELECT id, organization_id, authorization_target_id, scan_type, scan_mode, status, priority,
+			       progress_percentage, target_type, target_value, created_at, started_at, completed_at
+			FROM (
+				SELECT `+gqlScanColumns+`,
+				       row_number() OVER (PARTITION BY sj.organization_id ORDER BY sj.created_at DESC) AS rank
+				FROM scan_jobs sj
+				JOIN scan_targets st ON st.id = sj.target_id
+				WHERE sj.organization_id = ANY($1::uuid[]) AND ($2 = '' OR sj.status = $2)
+			) ranked
+			WHERE rank <= $3
+			ORDER BY created_at DESC
+		`, pq.Array(orgIDs), status, limit)
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to load scans", zap.Error(err))
+			return nil, errors.New("failed to load scans")
+		}
+		return groupScans(orgIDs, scans, func(s *gqlScan) string { return s.OrganizationID }), nil
+	})
+	return loader.Load(p.Context, org.ID), nil
+}
+
+func (h *GraphQLHandler) resolveOrgFindings(p graphql.ResolveParams) (interface{}, error) {
+	org := p.Source.(*gqlOrganization)
+	limit, err := pageLimit(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.authorize(p.Context, org.ID, rbac.PermViewScan); err != nil {
+		return nil, err
+	}
+
+	severity, _ := p.Args["severity"].(string)
+	status, _ := p.Args["status"].(string)
+	loader := requestFrom(p.Context).loaders.Get(fmt.Sprintf("organization.findings:%d:%s:%s", limit, severity, status), func(ctx context.Context, orgIDs []string) (map[string]interface{}, error) {
+		var findings []*gqlFinding
+		err := h.db.Reader().SelectContext(ctx, &findings, `
+			SELECT id, organization_id, scan_job_id, title, severity, status, cvss_score, cve_id,
+			       affected_component, assignee_id, suppressed, discovered_at
+			FROM (
+				SELECT `+gqlFindingColumns+`,
+				       row_number() OVER (PARTITION BY sj.organization_id ORDER BY v.discovered_at DESC) AS rank
+				FROM vulnerabilities v
+				JOIN scan_jobs sj ON sj.id = v.scan_job_id
+				WHERE sj.organization_id = ANY($1::uuid[])
+				AND ($2 = '' OR v.severity = $2)
+				AND ($3 = '' OR COALESCE(v.status, 'open') = $3)
+			) ranked
+			WHERE rank <= $4
+			ORDER BY discovered_at DESC
+		`, pq.Array(orgIDs), severity, status, limit)
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to load findings", zap.Error(err))
+			return nil, errors.New("failed to load findings")
+		}
+		return groupFindings(orgIDs, findings, func(f *gqlFinding) string { return f.OrganizationID }), nil
+	})
+	return loader.Load(p.Context, org.ID), nil
+}
+
+func (h *GraphQLHandler) resolveOrgAssets(p graphql.ResolveParams) (interface{}, error) {
+	org := p.Source.(*gqlOrganization)
+	limit, err := pageLimit(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.authorize(p.Context, org.ID, rbac.PermViewOrganization); err != nil {
+		return nil, err
+	}
+
+	loader := requestFrom(p.Context).loaders.Get(fmt.Sprintf("organization.assets:%d", limit), func(ctx context.Context, orgIDs []string) (map[string]interface{}, error) {
+		var assets []*gqlAsset
+		err := h.db.Reader().SelectContext(ctx, &assets, `
+			SELECT id, organization_id, target_type, target_value, verification_status, tags, valid_from, valid_until
+			FROM (
+				SELECT `+gqlAssetColumns+`,
+				       row_number() OVER (PARTITION BY at.organization_id ORDER BY at.created_at DESC) AS rank
+				FROM authorized_targets at
+				WHERE at.organization_id = ANY($1::uuid[])
+			) ranked
+			WHERE rank <= $2
+			ORDER BY target_value
+		`, pq.Array(orgIDs), limit)
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to load assets", zap.Error(err))
+			return nil, errors.New("failed to load assets")
+		}
+
+		byOrg := make(map[string]interface{}, len(orgIDs))
+		for _, id := range orgIDs {
+			byOrg[id] = []*gqlAsset{}
+		}
+		for _, a := range assets {
+			byOrg[a.OrganizationID] = append(byOrg[a.OrganizationID].([]*gqlAsset), a)
+		}
+		return byOrg, nil
+	})
+	return loader.Load(p.Context, org.ID), nil
+}
+
+func (h *GraphQLHandler) resolveAuditSummary(p graphql.ResolveParams) (interface{}, error) {
+	org := p.Source.(*gqlOrganization)
+	days, _ := p.Args["days"].(int)
+	if days < 1 || days > 90 {
+		return nil, errors.New("days must be between 1 and 90")
+	}
+	if err := h.authorize(p.Context, org.ID, rbac.PermViewAuditLogs); err != nil {
+		return nil, err
+	}
+
+	loader := requestFrom(p.Context).loaders.Get(fmt.Sprintf("organization.auditSummary:%d", days), func(ctx context.Context, orgIDs []string) (map[string]interface{}, error) {
+		var rows []struct {
+			OrganizationID string `db:"organization_id"`
+			gqlActionCount
+		}
+		err := h.db.Reader().SelectContext(ctx, &rows, `
+			SELECT organization_id, action, COUNT(*) AS count,
+			       COUNT(*) FILTER (WHERE status <> 'success') AS failures
+			FROM audit_logs
+			WHERE organization_id = ANY($1::uuid[]) AND timestamp > NOW() - make_interval(days => $2)
+			GROUP BY organization_id, action
+			ORDER BY count DESC, action
+		`, pq.Array(orgIDs), days)
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to summarize audit logs", zap.Error(err))
+			return nil, errors.New("failed to summarize audit logs")
+		}
+
+		summaries := make(map[string]*gqlAuditSummary, len(orgIDs))
+		for _, id := range orgIDs {
+			summaries[id] = &gqlAuditSummary{Days: days, Actions: []gqlActionCount{}}
+		}
+		for _, row := range rows {
+			s := summaries[row.OrganizationID]
+			s.Total += row.Count
+			s.Failures += row.Failures
+			s.Actions = append(s.Actions, row.gqlActionCount)
+		}
+		byOrg := make(map[string]interface{}, len(summaries))
+		for id, s := range summaries {
+			byOrg[id] = s
+		}
+		return byOrg, nil
+	})
+	return loader.Load(p.Context, org.ID), nil
+}
+
+func (h *GraphQLHandler) resolveFindingCounts(p graphql.ResolveParams) (interface{}, error) {
+	scan := p.Source.(*gqlScan)
+	loader := requestFrom(p.Context).loaders.Get("scan.findingCounts", func(ctx context.Context, scanIDs []string) (map[string]interface{}, error) {
+		var rows []struct {
+			ScanID   string `db:"scan_job_id"`
+			Severity string `db:"severity"`
+			Count    int    `db:"count"`
+		}
+		err := h.db.Reader().SelectContext(ctx, &rows, `
+			SELECT scan_job_id, severity, COUNT(*) AS count
+			FROM vulnerabilities
+			WHERE scan_job_id = ANY($1::uuid[])
+			GROUP BY scan_job_id, severity
+		`, pq.Array(scanIDs))
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to count findings", zap.Error(err))
+			return nil, errors.New("failed to count findings")
+		}
+
+		counts := make(map[string]interface{}, len(scanIDs))
+		for _, id := range scanIDs {
+			counts[id] = &gqlSeverityCounts{}
+		}
+		for _, row := range rows {
+			c := counts[row.ScanID].(*gqlSeverityCounts)
+			switch row.Severity {
+			case "critical":
+				c.Critical = row.Count
+			case "high":
+				c.High = row.Count
+			case "medium":
+				c.Medium = row.Count
+			case "low":
+				c.Low = row.Count
+			case "info":
+				c.Info = row.Count
+			}
+		}
+		return counts, nil
+	})
+	return loader.Load(p.Context, scan.ID), nil
+}
+
+func (h *GraphQLHandler) resolveScanFindings(p graphql.ResolveParams) (interface{}, error) {
+	scan := p.Source.(*gqlScan)
+	limit, err := pageLimit(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	severity, _ := p.Args["severity"].(string)
+	loader := requestFrom(p.Context).loaders.Get(fmt.Sprintf("scan.findings:%d:%s", limit, severity), func(ctx context.Context, scanIDs []string) (map[string]interface{}, error) {
+		var findings []*gqlFinding
+		err := h.db.Reader().SelectContext(ctx, &findings, `
+			SELECT id, organization_id, scan_job_id, title, severity, status, cvss_score, cve_id,
+			       affected_component, assignee_id, suppressed, discovered_at
+			FROM (
+				SELECT `+gqlFindingColumns+`,
+				       row_number() OVER (
+				           PARTITION BY v.scan_job_id
+				           ORDER BY CASE v.severity WHEN 'critical' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 ELSE 4 END,
+				                    v.cvss_score DESC NULLS LAST, v.discovered_at DESC
+				       ) AS rank
+				FROM vulnerabilities v
+				JOIN scan_jobs sj ON sj.id = v.scan_job_id
+				WHERE v.scan_job_id = ANY($1::uuid[]) AND ($2 = '' OR v.severity = $2)
+			) ranked
+			WHERE rank <= $3
+			ORDER BY scan_job_id, rank
+		`, pq.Array(scanIDs), severity, limit)
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to load findings", zap.Error(err))
+			return nil, errors.New("failed to load findings")
+		}
+		return groupFindings(scanIDs, findings, func(f *gqlFinding) string { return f.ScanID }), nil
+	})
+	return loader.Load(p.Context, scan.ID), nil
+}
+
+func (h *GraphQLHandler) resolveScanAsset(p graphql.ResolveParams) (interface{}, error) {
+	scan := p.Source.(*gqlScan)
+	if scan.AuthorizationTargetID == nil {
+		return nil, nil
+	}
+	if err := h.authorize(p.Context, scan.OrganizationID, rbac.PermViewOrganization); err != nil {
+		return nil, err
+	}
+
+	loader := requestFrom(p.Context).loaders.Get("asset", func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		var assets []*gqlAsset
+		err := h.db.Reader().SelectContext(ctx, &assets, `
+			SELECT `+gqlAssetColumns+` FROM authorized_targets at WHERE at.id = ANY($1::uuid[])
+		`, pq.Array(ids))
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to load assets", zap.Error(err))
+			return nil, errors.New("failed to load assets")
+		}
+		byID := make(map[string]interface{}, len(assets))
+		for _, a := range assets {
+			byID[a.ID] = a
+		}
+		return byID, nil
+	})
+	return loader.Load(p.Context, *scan.AuthorizationTargetID), nil
+}
+
+// resolveFindingScan needs no check: findings are only reachable through
+// fields that require view:scan in the finding's organization
+func (h *GraphQLHandler) resolveFindingScan(p graphql.ResolveParams) (interface{}, error) {
+	finding := p.Source.(*gqlFinding)
+	return h.scanLoader(p.Context).Load(p.Context, finding.ScanID), nil
+}
+
+func (h *GraphQLHandler) resolveAssetLastScan(p graphql.ResolveParams) (interface{}, error) {
+	asset := p.Source.(*gqlAsset)
+	if err := h.authorize(p.Context, asset.OrganizationID, rbac.PermViewScan); err != nil {
+		return nil, err
+	}
+
+	loader := requestFrom(p.Context).loaders.Get("asset.lastScan", func(ctx context.Context, assetIDs []string) (map[string]interface{}, error) {
+		var scans []*gqlScan
+		err := h.db.Reader().SelectContext(ctx, &scans, `
+			SELECT DISTINCT ON (sj.authorization_target_id) `+gqlScanColumns+`
+			FROM scan_jobs sj
+			JOIN scan_targets st ON st.id = sj.target_id
+			WHERE sj.authorization_target_id = ANY($1::uuid[])
+			ORDER BY sj.authorization_target_id, sj.created_at DESC
+		`, pq.Array(assetIDs))
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to load scans", zap.Error(err))
+			return nil, errors.New("failed to load scans")
+		}
+		byAsset := make(map[string]interface{}, len(scans))
+		for _, s := range scans {
+			byAsset[*s.AuthorizationTargetID] = s
+		}
+		return byAsset, nil
+	})
+	return loader.Load(p.Context, asset.ID), nil
+}
+
+// scanLoader loads scans by ID; callers authorize the result
+func (h *GraphQLHandler) scanLoader(ctx context.Context) *graphql.Loader {
+	return requestFrom(ctx).loaders.Get("scan", func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		var scans []*gqlScan
+		err := h.db.Reader().SelectContext(ctx, &scans, `
+			SELECT `+gqlScanColumns+`
+			FROM scan_jobs sj
+			JOIN scan_targets st ON st.id = sj.target_id
+			WHERE sj.id = ANY($1::uuid[]) AND sj.organization_id IS NOT NULL
+		`, pq.Array(ids))
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to load scans", zap.Error(err))
+			return nil, errors.New("failed to load scans")
+		}
+		byID := make(map[string]interface{}, len(scans))
+		for _, s := range scans {
+			byID[s.ID] = s
+		}
+		return byID, nil
+	})
+}
+
+func pageLimit(args map[string]interface{}) (int, error) {
+	limit, _ := args["limit"].(int)
+	if limit < 1 || limit > maxGraphQLPage {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxGraphQLPage)
+	}
+	return limit, nil
+}
+
+// groupScans and groupFindings split a batch by key, with an empty list for
+// keys that have no rows
+func groupScans(keys []string, scans []*gqlScan, key func(*gqlScan) string) map[string]interface{} {
+	groups := make(map[string][]*gqlScan, len(keys))
+	for _, s := range scans {
+		groups[key(s)] = append(groups[key(s)], s)
+	}
+	byKey := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if groups[k] == nil {
+			byKey[k] = []*gqlScan{}
+			continue
+		}
+		byKey[k] = groups[k]
+	}
+	return byKey
+}
+
+func groupFindings(keys []string, findings []*gqlFinding, key func(*gqlFinding) string) map[string]interface{} {
+	groups := make(map[string][]*gqlFinding, len(keys))
+	for _, f := range findings {
+		groups[key(f)] = append(groups[key(f)], f)
+	}
+	byKey := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if groups[k] == nil {
+			byKey[k] = []*gqlFinding{}
+			continue
+		}
+		byKey[k] = groups[k]
+	}
+	return byKey
+}
@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/mocks"
+	"github.com/gin-gonic/gin"
+)
+
+// request is a call to one handler, made as the middleware would leave it
+type request struct {
+	method string
+	route  string // Registered path, e.g. /sessions/:id
+	path   string // Requested path, the route when empty
+	body   string
+	// Context keys AuthMiddleware sets, e.g. user_id and session_id
+	keys map[string]string
+}
+
+func serve(handler gin.HandlerFunc, r request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(r.method, r.route, func(c *gin.Context) {
+		for key, value := range r.keys {
+			c.Set(key, value)
+		}
+		c.Next()
+	}, handler)
+
+	path := r.path
+	if path == "" {
+		path = r.route
+	}
+	req := httptest.NewRequest(r.method, path, strings.NewReader(r.body))
+	if r.body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "handler-test")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode reads a JSON response body into a map
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body, err)
+	}
+	return body
+}
+
+// newAuditor records every audit call and succeeds
+func newAuditor() *mocks.AuditorMock {
+	return &mocks.AuditorMock{
+		LogFunc: func(context.Context, audit.LogParams) error { return nil },
+		LogActionFunc: func(context.Context, string, string, string, map[string]interface{}) error {
+			return nil
+		},
+		LogSuccessFunc: func(context.Context, string, string, string, string, map[string]interface{}) error {
+			return nil
+		},
+		LogFailureFunc: func(context.Context, string, string, string, map[string]interface{}) error {
+			return nil
+		},
+		LogSecurityEventFunc: func(context.Context, string, string, string, string, map[string]interface{}) error {
+			return nil
+		},
+	}
+}
+
+// auditCount is the number of audit calls of any kind
+func auditCount(a *mocks.AuditorMock) int {
+	return len(a.LogCalls()) + len(a.LogActionCalls()) + len(a.LogSuccessCalls()) +
+		len(a.LogFailureCalls()) + len(a.LogSecurityEventCalls())
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ImpersonationHandler struct {
+	authService Authenticator
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewImpersonationHandler(authService Authenticator, auditLogger Auditor, logger *zap.Logger) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		authService: authService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// StartImpersonationRequest payload. The reason and ticket form the consent
+// trail shown in the audit log.
+type StartImpersonationRequest struct {
+	UserID          string `json:"user_id" binding:"required,uuid"`
+	Reason          string `json:"reason" binding:"required,min=10,max=1000"`
+	TicketReference string `json:"ticket_reference" binding:"max=100"`
+	DurationMinutes int    `json:"duration_minutes" binding:"omitempty,min=1,max=120"`
+}
+
+// StartImpersonation handles POST /api/v1/admin/impersonations
+func (h *ImpersonationHandler) StartImpersonation(c *gin.Context) {
+	adminID := c.GetString("user_id")
+	if c.GetString("impersonator_id") != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": auth.ErrNestedImpersonation.Error()})
+		return
+	}
+
+	var req StartImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	token, err := h.authService.StartImpersonation(c.Request.Context(), auth.StartImpersonationParams{
+		AdminUserID:     adminID,
+		TargetUserID:    req.UserID,
+		Reason:          req.Reason,
+		TicketReference: req.TicketReference,
+		Duration:        time.Duration(req.DurationMinutes) * time.Minute,
+		IPAddress:       c.ClientIP(),
+		UserAgent:       c.Request.UserAgent(),
+	})
+	switch err {
+	case nil:
+	case auth.ErrNotPlatformAdmin:
+		h.auditLogger.LogSecurityEvent(c.Request.Context(), adminID, "impersonation_denied", req.UserID, "high", map[string]interface{}{
+			"ip_address": c.ClientIP(),
+		})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Platform admin required"})
+		return
+	case auth.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case auth.ErrImpersonationTarget, auth.ErrImpersonationTooLong:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to start impersonation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start impersonation"})
+		return
+	}
+
+	imp := token.Impersonation
+	h.auditLogger.Log(c.Request.Context(), audit.LogParams{
+		UserID:       adminID,
+		SessionID:    c.GetString("session_id"),
+		Action:       "impersonation_started",
+		ResourceType: "user",
+		ResourceID:   imp.TargetUserID,
+		Target:       token.User.Email,
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		Severity:     "critical",
+		Details: map[string]interface{}{
+			"impersonation_id": imp.ID,
+			"impersonator_id":  adminID,
+			"target_user_id":   imp.TargetUserID,
+			"reason":           imp.Reason,
+			"ticket_reference": req.TicketReference,
+			"expires_at":       imp.ExpiresAt,
+		},
+	})
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// ListImpersonations handles GET /api/v1/admin/impersonations
+func (h *ImpersonationHandler) ListImpersonations(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	list, err := h.authService.ListImpersonations(c.Request.Context(), c.Query("include_ended") == "true")
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list impersonations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list impersonations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// StopImpersonation handles DELETE /api/v1/admin/impersonations/:id. It can be
+// called with the impersonation token itself to end the session early.
+func (h *ImpersonationHandler) StopImpersonation(c *gin.Context) {
+	actorID := c.GetString("impersonator_id")
+	if actorID == "" {
+		actorID = c.GetString("user_id")
+	}
+
+	imp, err := h.authService.StopImpersonation(c.Request.Context(), c.Param("id"), actorID)
+	if err == auth.ErrImpersonationNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to stop impersonation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop impersonation"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(c.Request.Context(), actorID, "impersonation_ended", imp.TargetUserID, "high", map[string]interface{}{
+		"impersonation_id": imp.ID,
+		"impersonator_id":  imp.AdminUserID,
+		"target_user_id":   imp.TargetUserID,
+		"ip_address":       c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, imp)
+}
+
+// requirePlatformAdmin aborts unless the caller is a platform admin acting
+// under their own identity
+func requirePlatformAdmin(c *gin.Context, authService Authenticator, logger *zap.Logger) bool {
+	// Impersonation tokens never carry platform privileges
+	if c.GetString("impersonator_id") != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Platform admin required"})
+		return false
+	}
+
+	isAdmin, err := authService.IsPlatformAdmin(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), logger).Error("Failed to check platform admin", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false
+	}
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Platform admin required"})
+		return false
+	}
+	return true
+}
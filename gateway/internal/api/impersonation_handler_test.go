@@ -0,0 +1,124 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/api"
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/mocks"
+	"go.uber.org/zap"
+)
+
+func TestPlatformAdminRequired(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    map[string]string
+		isAdmin bool
+		err     error
+		status  int
+	}{
+		{"platform admin", map[string]string{"user_id": "admin-1"}, true, nil, http.StatusOK},
+		{"other users", map[string]string{"user_id": "user-1"}, false, nil, http.StatusForbidden},
+		{"role lookup failed", map[string]string{"user_id": "admin-1"}, false, errors.New("db down"), http.StatusInternalServerError},
+		// Checked before the role, which would be the impersonated user's
+		{"impersonating", map[string]string{"user_id": "user-1", "impersonator_id": "admin-1"}, true, nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &mocks.AuthenticatorMock{
+				IsPlatformAdminFunc: func(context.Context, string) (bool, error) { return tt.isAdmin, tt.err },
+				ListImpersonationsFunc: func(context.Context, bool) ([]auth.Impersonation, error) {
+					return []auth.Impersonation{}, nil
+				},
+			}
+			h := api.NewImpersonationHandler(authService, newAuditor(), zap.NewNop())
+			rec := serve(h.ListImpersonations, request{method: http.MethodGet, route: "/impersonations", keys: tt.keys})
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if listed := len(authService.ListImpersonationsCalls()) == 1; listed != (tt.status == http.StatusOK) {
+				t.Errorf("listed = %v for status %d", listed, tt.status)
+			}
+			if _, impersonating := tt.keys["impersonator_id"]; impersonating && len(authService.IsPlatformAdminCalls()) != 0 {
+				t.Error("checked the impersonated user's role")
+			}
+		})
+	}
+}
+
+func TestStartImpersonation(t *testing.T) {
+	body := `{"user_id":"5f0c6a43-6f0e-4d53-9d5b-0d3a2f1b7c11","reason":"Customer ticket 4711, broken dashboard"}`
+	keys := map[string]string{"user_id": "admin-1", "session_id": "session-1"}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"started", nil, http.StatusCreated},
+		{"not a platform admin", auth.ErrNotPlatformAdmin, http.StatusForbidden},
+		{"unknown user", auth.ErrUserNotFound, http.StatusNotFound},
+		{"protected target", auth.ErrImpersonationTarget, http.StatusBadRequest},
+		{"too long", auth.ErrImpersonationTooLong, http.StatusBadRequest},
+		{"failed", errors.New("db down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := &mocks.AuthenticatorMock{
+				StartImpersonationFunc: func(ctx context.Context, p auth.StartImpersonationParams) (*auth.ImpersonationToken, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &auth.ImpersonationToken{
+						AccessToken:   "impersonation",
+						Impersonation: &auth.Impersonation{ID: "imp-1", AdminUserID: p.AdminUserID, TargetUserID: p.TargetUserID, Reason: p.Reason},
+						User:          auth.UserInfo{ID: p.TargetUserID, Email: "target@example.com"},
+					}, nil
+				},
+			}
+			auditor := newAuditor()
+			h := api.NewImpersonationHandler(authService, auditor, zap.NewNop())
+			rec := serve(h.StartImpersonation, request{method: http.MethodPost, route: "/impersonations", body: body, keys: keys})
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.status, rec.Body)
+			}
+			if p := authService.StartImpersonationCalls()[0].P; p.AdminUserID != "admin-1" || p.Reason == "" {
+				t.Errorf("StartImpersonation called with %+v", p)
+			}
+
+			logged, events := auditor.LogCalls(), auditor.LogSecurityEventCalls()
+			switch tt.err {
+			case nil:
+				if len(logged) != 1 || logged[0].Params.Action != "impersonation_started" || logged[0].Params.Severity != "critical" ||
+					logged[0].Params.SessionID != "session-1" || logged[0].Params.Target != "target@example.com" {
+					t.Errorf("Log calls = %+v", logged)
+				}
+			case auth.ErrNotPlatformAdmin:
+				// Attempts without the role are security events
+				if len(events) != 1 || events[0].Action != "impersonation_denied" || events[0].Severity != "high" {
+					t.Errorf("LogSecurityEvent calls = %+v", events)
+				}
+			default:
+				if auditCount(auditor) != 0 {
+					t.Error("audited a failed impersonation")
+				}
+			}
+		})
+	}
+}
+
+func TestStartImpersonationWhileImpersonating(t *testing.T) {
+	h := api.NewImpersonationHandler(&mocks.AuthenticatorMock{}, &mocks.AuditorMock{}, zap.NewNop())
+	rec := serve(h.StartImpersonation, request{
+		method: http.MethodPost, route: "/impersonations", body: `{}`,
+		keys: map[string]string{"user_id": "user-1", "impersonator_id": "admin-1"},
+	})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/imports"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ImportHandler imports results of customers' own scanners
+type ImportHandler struct {
+	imports     *imports.Service
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewImportHandler(importService *imports.Service, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *ImportHandler {
+	return &ImportHandler{
+		imports:     importService,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// CreateImport handles POST /api/v1/organizations/:id/scan-imports, a
+// multipart form with the scanner output in "file" and its "format": nmap
+// (XML), nuclei (JSONL) or burp (XML)
+func (h *ImportHandler) CreateImport(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermCreateScan, h.logger)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, imports.MaxFileBytes+64<<10)
+	format := c.PostForm("format")
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required (at most 50 MB)"})
+		return
+	}
+	if header.Size > imports.MaxFileBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file must be at most 50 MB"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	imp, err := h.imports.Import(ctx, imports.File{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Format:         format,
+		Filename:       header.Filename,
+		Data:           data,
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, imports.ErrUnknownFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of " + strings.Join(imports.Formats(), ", ")})
+		return
+	case errors.Is(err, imports.ErrInvalidFile), errors.Is(err, imports.ErrNoFindings):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, imports.ErrAlreadyImported):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	default:
+		logging.FromContext(ctx, h.logger).Error("Failed to import scan results", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import scan results"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "scan_imported", "scan_import", imp.ID, map[string]interface{}{
+		"organization_id": orgID,
+		"format":          imp.Format,
+		"filename":        imp.Filename,
+		"sha256":          imp.SHA256,
+		"hosts":           imp.Hosts,
+		"findings":        imp.FindingsStored,
+	})
+
+	c.JSON(http.StatusCreated, imp)
+}
+
+// ListImports handles GET /api/v1/organizations/:id/scan-imports?limit=50
+func (h *ImportHandler) ListImports(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewScan, h.logger); !ok {
+		return
+	}
+
+	limit := 50
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = n
+	}
+
+	list, err := h.imports.List(c.Request.Context(), orgID, limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list scan imports", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scan imports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetImport handles GET /api/v1/organizations/:id/scan-imports/:import_id
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewScan, h.logger); !ok {
+		return
+	}
+
+	imp, err := h.imports.Get(c.Request.Context(), orgID, c.Param("import_id"))
+	if err == imports.ErrImportNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load scan import", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scan import"})
+		return
+	}
+
+	c.JSON(http.StatusOK, imp)
+}
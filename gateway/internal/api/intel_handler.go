@@ -0,0 +1,162 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/intel"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxIntelResults bounds one CVE listing
+const maxIntelResults = 500
+
+// IntelHandler serves vulnerability intelligence synced from NVD, OSV, EPSS
+// and CISA KEV. The data is public, so any signed-in user may query it.
+type IntelHandler struct {
+	db      *database.DB
+	service *intel.Service
+	roles   *rbac.RoleStore
+	logger  *zap.Logger
+}
+
+func NewIntelHandler(db *database.DB, service *intel.Service, roles *rbac.RoleStore, logger *zap.Logger) *IntelHandler {
+	return &IntelHandler{
+		db:      db,
+		service: service,
+		roles:   roles,
+		logger:  logger,
+	}
+}
+
+// FindingIntel is a finding's CVE and what is known about it
+type FindingIntel struct {
+	FindingID string     `json:"finding_id"`
+	CVEID     *string    `json:"cve_id"`
+	Intel     *intel.CVE `json:"intel"` // null until the CVE is synced
+}
+
+// ListCVEs handles GET /api/v1/intel/cves
+func (h *IntelHandler) ListCVEs(c *gin.Context) {
+	filter := intel.Filter{
+		KnownExploited: c.Query("kev") == "true",
+		CWE:            strings.ToUpper(c.Query("cwe")),
+		Limit:          100,
+	}
+	if ids := c.Query("ids"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			id, ok := intel.NormalizeCVE(id)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "ids must be comma-separated CVE IDs"})
+				return
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+	}
+	if v := c.Query("min_epss"); v != "" {
+		minEPSS, err := strconv.ParseFloat(v, 64)
+		if err != nil || minEPSS < 0 || minEPSS > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_epss must be between 0 and 1"})
+			return
+		}
+		filter.MinEPSS = minEPSS
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxIntelResults {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	cves, err := intel.List(c.Request.Context(), h.db, filter)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list CVEs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list CVEs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cves)
+}
+
+// GetCVE handles GET /api/v1/intel/cves/:cve_id
+func (h *IntelHandler) GetCVE(c *gin.Context) {
+	id, ok := intel.NormalizeCVE(c.Param("cve_id"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CVE ID"})
+		return
+	}
+
+	cve, err := intel.Get(c.Request.Context(), h.db, id)
+	if err == intel.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "CVE not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load CVE", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load CVE"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cve)
+}
+
+// SyncStatus handles GET /api/v1/intel/status
+func (h *IntelHandler) SyncStatus(c *gin.Context) {
+	status, err := h.service.Status(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load intelligence sync status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load sync status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// FindingIntel handles GET /api/v1/organizations/:id/findings/:finding_id/intel
+func (h *IntelHandler) FindingIntel(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewScan, h.logger); !ok {
+		return
+	}
+
+	findingID := c.Param("finding_id")
+	if _, err := uuid.Parse(findingID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid finding ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	result := FindingIntel{FindingID: findingID}
+	err := h.db.GetContext(ctx, &result.CVEID, `
+		SELECT cve_id FROM vulnerabilities WHERE id = $1 AND organization_id = $2
+	`, findingID, orgID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Finding not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load finding", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load finding"})
+		return
+	}
+
+	if result.CVEID != nil {
+		result.Intel, err = intel.Get(ctx, h.db, *result.CVEID)
+		if err != nil && err != intel.ErrNotFound {
+			logging.FromContext(ctx, h.logger).Error("Failed to load CVE", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load CVE"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
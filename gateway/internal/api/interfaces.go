@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/realtime"
+)
+
+//go:generate go run github.com/matryer/moq@v0.3.4 -pkg mocks -out ../mocks/authenticator.go . Authenticator
+//go:generate go run github.com/matryer/moq@v0.3.4 -pkg mocks -out ../mocks/auditor.go . Auditor
+
+// Authenticator is the part of auth.AuthService the auth and impersonation
+// handlers depend on, so they can be tested without a database
+type Authenticator interface {
+	Register(ctx context.Context, req auth.RegisterRequest) (*auth.User, error)
+	Login(ctx context.Context, req auth.LoginRequest, ipAddress, userAgent string) (*auth.LoginResponse, error)
+	Logout(ctx context.Context, userID, sessionID string) error
+	Pulse(ctx context.Context, sessionID, userID, orgID string) (*auth.PulseResult, error)
+	SwitchOrganization(ctx context.Context, userID, sessionID, orgID string) (*auth.SwitchOrgResponse, error)
+
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]auth.SessionInfo, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	RevokeSessionWithToken(ctx context.Context, token string) (string, string, error)
+	SecurityActivity(ctx context.Context, userID, email string, since time.Time, limit int) ([]auth.SecurityEvent, error)
+
+	CurrentTerms(ctx context.Context) (*auth.TermsDocument, error)
+	AcceptTerms(ctx context.Context, userID, version, ipAddress, userAgent string) (*auth.TermsAcceptance, error)
+
+	IsPlatformAdmin(ctx context.Context, userID string) (bool, error)
+	StartImpersonation(ctx context.Context, p auth.StartImpersonationParams) (*auth.ImpersonationToken, error)
+	StopImpersonation(ctx context.Context, impersonationID, actorID string) (*auth.Impersonation, error)
+	ListImpersonations(ctx context.Context, includeEnded bool) ([]auth.Impersonation, error)
+}
+
+// Auditor is the part of audit.AuditLogger handlers use to record events
+type Auditor interface {
+	Log(ctx context.Context, params audit.LogParams) error
+	LogAction(ctx context.Context, userID, action, target string, details map[string]interface{}) error
+	LogSuccess(ctx context.Context, userID, action, resourceType, resourceID string, details map[string]interface{}) error
+	LogFailure(ctx context.Context, userID, action, errorMsg string, details map[string]interface{}) error
+	LogSecurityEvent(ctx context.Context, userID, action, target string, severity string, details map[string]interface{}) error
+}
+
+// Notifier pushes real-time events to a user's WebSocket connections
+type Notifier interface {
+	PublishToUser(userID string, event realtime.Event)
+}
+
+var (
+	_ Notifier      = (*realtime.Hub)(nil)
+	_ Authenticator = (*auth.AuthService)(nil)
+	_ Auditor       = (*audit.AuditLogger)(nil)
+)
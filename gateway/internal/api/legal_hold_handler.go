@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// LegalHoldHandler lets platform admins freeze an organization's or user's
+// data for compliance
+type LegalHoldHandler struct {
+	holds       *legalhold.Service
+	authService Authenticator
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewLegalHoldHandler(holdService *legalhold.Service, authService Authenticator, auditLogger Auditor, logger *zap.Logger) *LegalHoldHandler {
+	return &LegalHoldHandler{
+		holds:       holdService,
+		authService: authService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// PlaceLegalHoldRequest payload
+type PlaceLegalHoldRequest struct {
+	Scope         string `json:"scope" binding:"required,oneof=organization user"`
+	TargetID      string `json:"target_id" binding:"required,uuid"`
+	Reason        string `json:"reason" binding:"required,min=10,max=2000"`
+	CaseReference string `json:"case_reference" binding:"max=200"`
+}
+
+// ReleaseLegalHoldRequest payload
+type ReleaseLegalHoldRequest struct {
+	Reason string `json:"reason" binding:"required,min=10,max=2000"`
+}
+
+// ListHolds handles GET /api/v1/admin/legal-holds?include_released=true
+func (h *LegalHoldHandler) ListHolds(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	holds, err := h.holds.List(c.Request.Context(), c.Query("include_released") == "true")
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list legal holds", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list legal holds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+// PlaceHold handles POST /api/v1/admin/legal-holds
+func (h *LegalHoldHandler) PlaceHold(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	adminID := c.GetString("user_id")
+
+	var req PlaceLegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	hold, err := h.holds.Place(ctx, req.Scope, req.TargetID, req.Reason, req.CaseReference, adminID)
+	switch err {
+	case nil:
+	case legalhold.ErrTargetNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case legalhold.ErrAlreadyHeld:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	default:
+		logging.FromContext(ctx, h.logger).Error("Failed to place legal hold", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place legal hold"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(ctx, adminID, "legal_hold_placed", hold.Scope+":"+hold.TargetID, "high", map[string]interface{}{
+		"hold_id":        hold.ID,
+		"reason":         hold.Reason,
+		"case_reference": req.CaseReference,
+		"ip_address":     c.ClientIP(),
+	})
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+// ReleaseHold handles POST /api/v1/admin/legal-holds/:id/release
+func (h *LegalHoldHandler) ReleaseHold(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	adminID := c.GetString("user_id")
+
+	var req ReleaseLegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	hold, err := h.holds.Release(ctx, c.Param("id"), adminID, req.Reason)
+	if err == legalhold.ErrHoldNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Legal hold not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to release legal hold", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release legal hold"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(ctx, adminID, "legal_hold_released", hold.Scope+":"+hold.TargetID, "high", map[string]interface{}{
+		"hold_id":    hold.ID,
+		"reason":     req.Reason,
+		"ip_address": c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, hold)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/i18n"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/payload"
+	"github.com/cyper-security/gateway/internal/preferences"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// bindError rejects a request whose body or query failed to bind, with 413
+// past the body limit and otherwise 400 describing validation failures per
+// field in the caller's language
+func bindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit),
+			"code":  payload.CodeBodyTooLarge,
+			"limit": tooLarge.Limit,
+		})
+		return
+	}
+	if t := i18n.FromContext(c); t != nil {
+		c.JSON(http.StatusBadRequest, t.BindError(c, err))
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+type LocaleHandler struct {
+	prefs      *preferences.Service
+	translator *i18n.Translator
+	logger     *zap.Logger
+}
+
+func NewLocaleHandler(prefs *preferences.Service, translator *i18n.Translator, logger *zap.Logger) *LocaleHandler {
+	return &LocaleHandler{
+		prefs:      prefs,
+		translator: translator,
+		logger:     logger,
+	}
+}
+
+// LocaleResponse is the caller's effective locale and the ones available
+type LocaleResponse struct {
+	Locale    string   `json:"locale"`
+	Stored    *string  `json:"stored"` // The saved preference; null follows Accept-Language
+	Available []string `json:"available"`
+}
+
+// SetLocaleRequest saves a locale preference; null clears it
+type SetLocaleRequest struct {
+	Locale *string `json:"locale" binding:"omitempty,max=16"`
+}
+
+// GetLocale handles GET /api/v1/users/me/locale
+func (h *LocaleHandler) GetLocale(c *gin.Context) {
+	prefs, err := h.prefs.Get(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load user locale", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load preference"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LocaleResponse{
+		Locale:    h.translator.Locale(c),
+		Stored:    prefs.Locale,
+		Available: h.translator.Registry().Locales(),
+	})
+}
+
+// SetLocale handles PUT /api/v1/users/me/locale, the locale preference on
+// its own. Messages are translated into the stored locale regardless of
+// Accept-Language.
+func (h *LocaleHandler) SetLocale(c *gin.Context) {
+	var req SetLocaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	value, _ := json.Marshal(req.Locale)
+	prefs, err := h.prefs.Update(c.Request.Context(), c.GetString("user_id"), map[string]json.RawMessage{
+		preferences.KeyLocale: value,
+	})
+	var invalid *preferences.ValidationError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale", "available": h.translator.Registry().Locales()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to save user locale", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preference"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locale": prefs.Locale})
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MagicLinkHandler serves password-less sign-in and the organization login
+// policy that allows it
+type MagicLinkHandler struct {
+	auth        *auth.AuthService
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewMagicLinkHandler(authService *auth.AuthService, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		auth:        authService,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// MagicLinkRequest asks for a sign-in link
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkLoginRequest signs in with the token from a magic link
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// SetLoginPolicyRequest replaces the organization's login policy
+type SetLoginPolicyRequest struct {
+	MagicLinkEnabled bool `json:"magic_link_enabled"`
+}
+
+// magicLinkSent is the answer to every accepted request, whether or not a
+// link went out, so it can't be used to probe for accounts
+var magicLinkSent = gin.H{"message": "If an account with this address can sign in by email, a link is on its way"}
+
+// RequestMagicLink handles POST /api/v1/auth/magic-link
+func (h *MagicLinkHandler) RequestMagicLink(c *gin.Context) {
+	var req MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	ipAddress := c.ClientIP()
+	link, err := h.auth.RequestMagicLink(ctx, req.Email, ipAddress)
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrMagicLinkDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": "magic link sign-in is not available"})
+		return
+	case errors.Is(err, auth.ErrMagicLinkRateLimited):
+		h.auditLogger.LogSecurityEvent(ctx, "", "magic_link_rate_limited", req.Email, "medium", map[string]interface{}{
+			"ip_address": ipAddress,
+		})
+		c.Header("Retry-After", strconv.Itoa(int(h.auth.MagicLinkConfig().Window.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many sign-in links requested; try again later"})
+		return
+	case errors.Is(err, auth.ErrMagicLinkNotAllowed):
+		h.auditLogger.LogFailure(ctx, link.UserID, "magic_link_requested", err.Error(), map[string]interface{}{
+			"email":      link.Email,
+			"ip_address": ipAddress,
+		})
+		c.JSON(http.StatusAccepted, magicLinkSent)
+		return
+	default:
+		logging.FromContext(ctx, h.logger).Error("Failed to send magic link", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send sign-in link"})
+		return
+	}
+
+	if link != nil {
+		h.auditLogger.LogSuccess(ctx, link.UserID, "magic_link_requested", "user", link.UserID, map[string]interface{}{
+			"email":      link.Email,
+			"link_id":    link.ID,
+			"ip_address": ipAddress,
+			"expires_at": link.ExpiresAt,
+		})
+	}
+	c.JSON(http.StatusAccepted, magicLinkSent)
+}
+
+// LoginWithMagicLink handles POST /api/v1/auth/magic-link/login. Only POST
+// consumes a link: mail scanners fetch links in messages, which would use
+// up a single-use GET before the user got to it.
+func (h *MagicLinkHandler) LoginWithMagicLink(c *gin.Context) {
+	var req MagicLinkLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	loginResp, err := h.auth.LoginWithMagicLink(ctx, req.Token, ipAddress, userAgent)
+	if err != nil {
+		metrics.AuthAttempts.WithLabelValues("failure").Inc()
+		h.auditLogger.LogFailure(ctx, "", "login_attempt", err.Error(), map[string]interface{}{
+			"method":     "magic_link",
+			"ip_address": ipAddress,
+			"user_agent": userAgent,
+		})
+		switch {
+		case errors.Is(err, auth.ErrMagicLinkDisabled):
+			c.JSON(http.StatusNotFound, gin.H{"error": "magic link sign-in is not available"})
+		case errors.Is(err, auth.ErrInvalidMagicLink):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid, expired or already used link", "code": "invalid_magic_link"})
+		case errors.Is(err, auth.ErrMagicLinkNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": "your organization requires signing in with a password", "code": "magic_link_not_allowed"})
+		default:
+			respondLoginError(c, err)
+		}
+		return
+	}
+
+	metrics.AuthAttempts.WithLabelValues("success").Inc()
+	h.auditLogger.LogSuccess(ctx, loginResp.User.ID, "login_success", "session", "", map[string]interface{}{
+		"email":      loginResp.User.Email,
+		"method":     "magic_link",
+		"ip_address": ipAddress,
+	})
+
+	c.JSON(http.StatusOK, loginResp)
+}
+
+// GetLoginPolicy handles GET /api/v1/organizations/:id/login-policy
+func (h *MagicLinkHandler) GetLoginPolicy(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger); !ok {
+		return
+	}
+
+	policy, err := h.auth.GetLoginPolicy(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load login policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load login policy"})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetLoginPolicy handles PUT /api/v1/organizations/:id/login-policy
+func (h *MagicLinkHandler) SetLoginPolicy(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req SetLoginPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	policy, err := h.auth.SetLoginPolicy(ctx, orgID, userID, req.MagicLinkEnabled)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to save login policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save login policy"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(ctx, userID, "login_policy_set", orgID, "medium", map[string]interface{}{
+		"organization_id":    orgID,
+		"magic_link_enabled": policy.MagicLinkEnabled,
+	})
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/maintenance"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MaintenanceHandler lets platform admins switch maintenance mode on and off
+type MaintenanceHandler struct {
+	maintenance *maintenance.Service
+	authService Authenticator
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewMaintenanceHandler(maintenanceService *maintenance.Service, authService Authenticator, auditLogger Auditor, logger *zap.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		maintenance: maintenanceService,
+		authService: authService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// MaintenanceRequest starts a maintenance window
+type MaintenanceRequest struct {
+	Message  string `json:"message" binding:"max=500"`
+	Duration int    `json:"duration_minutes" binding:"min=0"` // 0 keeps it on until switched off
+}
+
+// GetStatus handles GET /api/v1/maintenance. It is public so clients can
+// show a banner before and after login.
+func (h *MaintenanceHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.maintenance.Current())
+}
+
+// Enable handles PUT /api/v1/admin/maintenance
+func (h *MaintenanceHandler) Enable(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	userID := c.GetString("user_id")
+
+	var req MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	state, err := h.maintenance.Enable(c.Request.Context(), userID, req.Message, time.Duration(req.Duration)*time.Minute)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to enable maintenance mode", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable maintenance mode"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(c.Request.Context(), userID, "maintenance_enabled", "", "high", map[string]interface{}{
+		"message":          req.Message,
+		"duration_minutes": req.Duration,
+	})
+
+	c.JSON(http.StatusOK, state)
+}
+
+// Disable handles DELETE /api/v1/admin/maintenance
+func (h *MaintenanceHandler) Disable(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	userID := c.GetString("user_id")
+
+	if err := h.maintenance.Disable(c.Request.Context()); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to disable maintenance mode", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable maintenance mode"})
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(c.Request.Context(), userID, "maintenance_disabled", "", "medium", nil)
+
+	c.JSON(http.StatusOK, h.maintenance.Current())
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// NetworkPolicyHandler manages an organization's IP allowlist
+type NetworkPolicyHandler struct {
+	auth        *auth.AuthService
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewNetworkPolicyHandler(authService *auth.AuthService, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *NetworkPolicyHandler {
+	return &NetworkPolicyHandler{
+		auth:        authService,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// NetworkPolicyResponse is the organization's policy and the break-glass
+// overrides currently bypassing it
+type NetworkPolicyResponse struct {
+	Policy    *auth.NetworkPolicy          `json:"policy"`
+	Overrides []auth.NetworkPolicyOverride `json:"active_overrides"`
+}
+
+// SetNetworkPolicyRequest replaces the policy. Entries are IP addresses or
+// CIDRs; an enabled policy must include the caller's address.
+type SetNetworkPolicyRequest struct {
+	Enabled      bool     `json:"enabled"`
+	AllowedCIDRs []string `json:"allowed_cidrs" binding:"max=100"`
+}
+
+// GetPolicy handles GET /api/v1/organizations/:id/network-policy
+func (h *NetworkPolicyHandler) GetPolicy(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	policy, err := h.auth.GetNetworkPolicy(ctx, orgID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load network policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load network policy"})
+		return
+	}
+	overrides, err := h.auth.ActiveNetworkPolicyOverrides(ctx, orgID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to list network policy overrides", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load network policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, NetworkPolicyResponse{Policy: policy, Overrides: overrides})
+}
+
+// SetPolicy handles PUT /api/v1/organizations/:id/network-policy. API
+// tokens are bound by the policy, so they cannot change it.
+func (h *NetworkPolicyHandler) SetPolicy(c *gin.Context) {
+	if _, ok := rbac.TokenGrantFrom(c.Request.Context()); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API tokens cannot change the network policy"})
+		return
+	}
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req SetNetworkPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	policy, err := h.auth.SetNetworkPolicy(ctx, orgID, userID, c.ClientIP(), req.Enabled, req.AllowedCIDRs)
+	if errors.Is(err, auth.ErrInvalidNetworkPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to save network policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save network policy"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "network_policy_set", "organization", orgID, map[string]interface{}{
+		"enabled":       policy.Enabled,
+		"allowed_cidrs": []string(policy.AllowedCIDRs),
+		"ip_address":    c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,378 @@
+package api
+
+//go:generate go run ../../cmd/openapi -o ../../api/openapi.json
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/apiversion"
+	"github.com/cyper-security/gateway/internal/approvals"
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/branding"
+	"github.com/cyper-security/gateway/internal/challenge"
+	"github.com/cyper-security/gateway/internal/compliance"
+	"github.com/cyper-security/gateway/internal/deliveries"
+	"github.com/cyper-security/gateway/internal/domains"
+	"github.com/cyper-security/gateway/internal/downloads"
+	"github.com/cyper-security/gateway/internal/escalation"
+	"github.com/cyper-security/gateway/internal/export"
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/flags"
+	"github.com/cyper-security/gateway/internal/grants"
+	"github.com/cyper-security/gateway/internal/graphql"
+	"github.com/cyper-security/gateway/internal/imports"
+	"github.com/cyper-security/gateway/internal/intel"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/maintenance"
+	"github.com/cyper-security/gateway/internal/openapi"
+	"github.com/cyper-security/gateway/internal/preferences"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/cyper-security/gateway/internal/reports"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/scanwindows"
+	"github.com/cyper-security/gateway/internal/stats"
+	"github.com/cyper-security/gateway/internal/status"
+	"github.com/cyper-security/gateway/internal/tasks"
+	"github.com/cyper-security/gateway/internal/workers"
+)
+
+// APIBasePath is the router group all REST routes are mounted under
+const APIBasePath = "/v1"
+
+// APIVersions are the REST API versions served, oldest first. Deprecating a
+// version (Deprecated, Sunset, Link) sends the deprecation headers on all of
+// its endpoints; single endpoints are deprecated on their Routes entry.
+var APIVersions = []apiversion.Version{
+	{Name: "v1", Prefix: APIBasePath},
+}
+
+// OpenAPIInfo describes the gateway REST API
+var OpenAPIInfo = openapi.Info{
+	Title:       "Cyper Security Gateway API",
+	Description: "REST API for authentication, organizations, scans, reports and audit logs.",
+	Version:     "0.1.0",
+}
+
+// OpenAPITags groups operations in the generated specification
+var OpenAPITags = []openapi.Tag{
+	{Name: "auth", Description: "Authentication, sessions and terms of use"},
+	{Name: "organizations", Description: "Organizations and memberships"},
+	{Name: "scans", Description: "Scans and scan authorization"},
+	{Name: "findings", Description: "Finding triage: comments, assignment and status"},
+	{Name: "reports", Description: "Report generation"},
+	{Name: "graphql", Description: "Read-only GraphQL queries for the dashboard, with depth and complexity limits"},
+	{Name: "intel", Description: "Vulnerability intelligence: CVE metadata, EPSS scores and known-exploited flags"},
+	{Name: "compliance", Description: "Mapping of findings to PCI DSS, ISO 27001 and NIST CSF controls"},
+	{Name: "integrations", Description: "Chat integrations (Slack)"},
+	{Name: "audit", Description: "Audit log export and verification"},
+	{Name: "emergency", Description: "Emergency stop controls"},
+	{Name: "escalations", Description: "Emergency contacts, escalation policies and acknowledgement"},
+	{Name: "admin", Description: "Platform administration, served on the admin listener (ADMIN_ADDR) only"},
+	{Name: "status", Description: "Public status page: system status, component health and uptime"},
+	{Name: "workers", Description: "Scanner worker registration and heartbeats"},
+	{Name: "docs", Description: "API documentation"},
+}
+
+// Routes declares every REST endpoint for the OpenAPI specification.
+// Keep in sync with cmd/server/main.go; the server logs undeclared routes at startup.
+func Routes() []openapi.Route {
+	return []openapi.Route{
+		// Auth
+		{Method: "GET", Path: "/auth/challenge", Tag: "auth", Summary: "Get a CAPTCHA or proof-of-work challenge", Public: true, Response: challenge.Challenge{}},
+		{Method: "POST", Path: "/auth/register", Tag: "auth", Summary: "Register a new user", Public: true, Request: auth.RegisterRequest{}, Status: 201},
+		{Method: "POST", Path: "/auth/login", Tag: "auth", Summary: "Log in and create a session", Public: true, Request: auth.LoginRequest{}, Response: auth.LoginResponse{}},
+		{Method: "POST", Path: "/auth/magic-link", Tag: "auth", Summary: "Email a single-use sign-in link; the answer is the same whether or not the account exists. Rate limited per address and client IP", Public: true, Request: MagicLinkRequest{}, Status: 202},
+		{Method: "POST", Path: "/auth/magic-link/login", Tag: "auth", Summary: "Sign in with the token from a magic link, creating a session as a password login does; each link works once", Public: true, Request: MagicLinkLoginRequest{}, Response: auth.LoginResponse{}},
+		{Method: "POST", Path: "/auth/accept-terms", Tag: "auth", Summary: "Accept the current terms of use", Public: true, Request: AcceptTermsRequest{}, Response: auth.TermsAcceptance{}},
+		{Method: "GET", Path: "/auth/terms", Tag: "auth", Summary: "Get the current terms of use", Public: true, Response: auth.TermsDocument{}},
+		{Method: "POST", Path: "/auth/logout", Tag: "auth", Summary: "Log out", Status: 204},
+		{Method: "GET", Path: "/auth/pulse", Tag: "auth", Summary: "Re-check the session and get its current features and expiry", Response: auth.PulseResult{}},
+		{Method: "GET", Path: "/auth/sessions/revoke", Tag: "auth", Summary: "Revoke a session from a new-login alert link", Public: true, Query: []string{"token"}},
+		{Method: "POST", Path: "/auth/sessions/revoke", Tag: "auth", Summary: "Revoke a session from a new-login alert link", Public: true, Query: []string{"token"}},
+		{Method: "GET", Path: "/auth/verify-email", Tag: "auth", Summary: "Verify your email address from the emailed link; joins the organization that verified its domain, if it has auto-join on", Public: true, Query: []string{"token"}, Response: VerifyEmailResponse{}},
+		{Method: "POST", Path: "/auth/verify-email", Tag: "auth", Summary: "Verify your email address from the emailed link; joins the organization that verified its domain, if it has auto-join on", Public: true, Query: []string{"token"}, Response: VerifyEmailResponse{}},
+		{Method: "POST", Path: "/auth/verify-email/request", Tag: "auth", Summary: "Email yourself a new email verification link", Status: 202},
+		{Method: "POST", Path: "/auth/switch-org", Tag: "auth", Summary: "Re-issue your token scoped to another of your organizations", Request: SwitchOrgRequest{}, Response: auth.SwitchOrgResponse{}},
+		{Method: "GET", Path: "/auth/sessions", Tag: "auth", Summary: "List active sessions"},
+		{Method: "DELETE", Path: "/auth/sessions/:id", Tag: "auth", Summary: "Revoke a session"},
+		{Method: "GET", Path: "/users/me/export", Tag: "auth", Summary: "Get or start a personal data export", Response: export.Export{}},
+		{Method: "GET", Path: "/users/me/locale", Tag: "auth", Summary: "Get your language for error messages and the ones available", Response: LocaleResponse{}},
+		{Method: "PUT", Path: "/users/me/locale", Tag: "auth", Summary: "Set or clear your preferred language", Request: SetLocaleRequest{}},
+		{Method: "GET", Path: "/users/me/preferences", Tag: "auth", Summary: "Get your preferences, with defaults for those not set", Response: preferences.Preferences{}},
+		{Method: "PATCH", Path: "/users/me/preferences", Tag: "auth", Summary: "Update preferences; null resets one to its default", Request: UpdatePreferencesRequest{}, Response: preferences.Preferences{}},
+		{Method: "GET", Path: "/users/me/digest", Tag: "auth", Summary: "Preview your daily or weekly activity digest for the period ending now", Query: []string{"frequency"}, Response: DigestPreview{}},
+		{Method: "GET", Path: "/users/me/security-activity", Tag: "auth", Summary: "Recent logins and account security events", Query: []string{"days", "limit"}, Response: []auth.SecurityEvent{}},
+		{Method: "POST", Path: "/admin/impersonations", Tag: "auth", Summary: "Start a time-boxed impersonation (platform admins)", Request: StartImpersonationRequest{}, Response: auth.ImpersonationToken{}, Status: 201},
+		{Method: "GET", Path: "/admin/impersonations", Tag: "auth", Summary: "List impersonations (platform admins)", Query: []string{"include_ended"}, Response: []auth.Impersonation{}},
+		{Method: "DELETE", Path: "/admin/impersonations/:id", Tag: "auth", Summary: "End an impersonation and revoke its token", Response: auth.Impersonation{}},
+		{Method: "GET", Path: "/admin/flags", Tag: "admin", Summary: "List feature flags with their overrides (platform admins)", Response: []flags.Flag{}},
+		{Method: "PUT", Path: "/admin/flags/:key", Tag: "admin", Summary: "Create or update a feature flag", Request: flags.FlagUpdate{}, Response: flags.Flag{}},
+		{Method: "DELETE", Path: "/admin/flags/:key", Tag: "admin", Summary: "Delete a feature flag"},
+		{Method: "PUT", Path: "/admin/flags/:key/overrides", Tag: "admin", Summary: "Force a feature flag on or off for an organization or user", Request: FlagOverrideRequest{}, Response: flags.Flag{}},
+		{Method: "DELETE", Path: "/admin/flags/:key/overrides/:scope/:target_id", Tag: "admin", Summary: "Remove a feature flag override"},
+		{Method: "GET", Path: "/admin/legal-holds", Tag: "admin", Summary: "List active legal holds, or the full hold history", Query: []string{"include_released"}, Response: []legalhold.Hold{}},
+		{Method: "POST", Path: "/admin/legal-holds", Tag: "admin", Summary: "Place a legal hold on an organization or user", Request: PlaceLegalHoldRequest{}, Response: legalhold.Hold{}, Status: 201},
+		{Method: "POST", Path: "/admin/legal-holds/:id/release", Tag: "admin", Summary: "Release a legal hold", Request: ReleaseLegalHoldRequest{}, Response: legalhold.Hold{}},
+		{Method: "POST", Path: "/workers/register", Tag: "workers", Summary: "Register a scanner worker and its capabilities", Service: true, Request: workers.Registration{}, Response: workers.HeartbeatAck{}, Status: 201},
+		{Method: "POST", Path: "/workers/:id/heartbeat", Tag: "workers", Summary: "Report a worker's liveness and load", Service: true, Request: workers.Heartbeat{}, Response: workers.HeartbeatAck{}},
+		{Method: "GET", Path: "/admin/users", Tag: "admin", Summary: "Search user accounts (platform admins)", Query: []string{"q", "limit"}, Response: []auth.AdminUser{}},
+		{Method: "PUT", Path: "/admin/users/:id/status", Tag: "admin", Summary: "Enable or disable a user account; disabling signs the user out everywhere", Request: UserStatusRequest{}, Response: UserStatusResponse{}},
+		{Method: "GET", Path: "/admin/workers", Tag: "admin", Summary: "List scanner workers (platform admins)", Query: []string{"status"}, Response: []workers.Worker{}},
+		{Method: "GET", Path: "/admin/workers/:id", Tag: "admin", Summary: "Get a scanner worker and its running jobs", Response: workers.Worker{}},
+		{Method: "GET", Path: "/admin/tasks", Tag: "admin", Summary: "List background tasks, newest first (platform admins)", Query: []string{"state", "queue", "kind", "limit", "offset"}, Response: TasksResponse{}},
+		{Method: "GET", Path: "/admin/tasks/queues", Tag: "admin", Summary: "Pending, running and failed tasks and workers of each task queue", Response: TaskQueuesResponse{}},
+		{Method: "GET", Path: "/admin/tasks/:id", Tag: "admin", Summary: "Get a background task with its attempts and last error", Response: tasks.Task{}},
+		{Method: "POST", Path: "/admin/tasks/:id/retry", Tag: "admin", Summary: "Run a failed or cancelled task again with its attempts reset", Response: tasks.Task{}},
+		{Method: "POST", Path: "/admin/tasks/:id/cancel", Tag: "admin", Summary: "Cancel a pending task, or stop a running one", Response: tasks.Task{}},
+		{Method: "GET", Path: "/admin/tenant-schemas", Tag: "admin", Summary: "List organizations with a database schema of their own (platform admins)", Response: TenantSchemasResponse{}},
+		{Method: "PUT", Path: "/admin/organizations/:id/isolation", Tag: "admin", Summary: "Move an organization's scan evidence into its own schema or back to the shared tables", Request: SetIsolationRequest{}, Response: tasks.Task{}, Status: 202},
+		{Method: "GET", Path: "/maintenance", Tag: "admin", Summary: "Get the maintenance mode status", Public: true, Response: maintenance.State{}},
+		{Method: "GET", Path: "/status", Tag: "status", Summary: "Current system status and component health", Public: true, Response: status.Summary{}},
+		{Method: "GET", Path: "/status/components", Tag: "status", Summary: "Health of each component", Public: true, Response: []status.Component{}},
+		{Method: "GET", Path: "/status/uptime", Tag: "status", Summary: "Uptime over the last 1, 7, 30 and 90 days, and per day", Public: true, Response: status.Uptime{}},
+		{Method: "PUT", Path: "/admin/maintenance", Tag: "admin", Summary: "Start maintenance mode (platform admins)", Request: MaintenanceRequest{}, Response: maintenance.State{}},
+		{Method: "DELETE", Path: "/admin/maintenance", Tag: "admin", Summary: "End maintenance mode", Response: maintenance.State{}},
+		{Method: "GET", Path: "/ws", Tag: "auth", Summary: "Open a WebSocket for real-time events"},
+
+		// Organizations
+		{Method: "POST", Path: "/organizations", Tag: "organizations", Summary: "Create an organization and become its owner", Request: CreateOrganizationRequest{}, Response: Organization{}, Status: 201},
+		{Method: "GET", Path: "/organizations", Tag: "organizations", Summary: "List the caller's organizations", Response: []repository.Membership{}},
+		{Method: "GET", Path: "/organizations/:id", Tag: "organizations", Summary: "Get an organization"},
+		{Method: "POST", Path: "/organizations/:id/export", Tag: "organizations", Summary: "Start an organization data export", Permission: string(rbac.PermManageOrganization), Response: export.Export{}, Status: 202},
+		{Method: "GET", Path: "/organizations/:id/exports/:export_id", Tag: "organizations", Summary: "Get an organization data export and its download link", Permission: string(rbac.PermManageOrganization), Response: export.Export{}},
+		{Method: "POST", Path: "/organizations/:id/invite", Tag: "organizations", Summary: "Invite a user", Permission: string(rbac.PermInviteUsers), Request: InviteUserRequest{}},
+		{Method: "GET", Path: "/organizations/:id/roles", Tag: "organizations", Summary: "List built-in and custom roles", Permission: string(rbac.PermViewOrganization), Response: RolesResponse{}},
+		{Method: "POST", Path: "/organizations/:id/roles", Tag: "organizations", Summary: "Create a custom role", Permission: string(rbac.PermManageOrganization), Request: CustomRoleRequest{}, Response: rbac.CustomRole{}, Status: 201},
+		{Method: "PUT", Path: "/organizations/:id/roles/:role_id", Tag: "organizations", Summary: "Update a custom role", Permission: string(rbac.PermManageOrganization), Request: UpdateCustomRoleRequest{}, Response: rbac.CustomRole{}},
+		{Method: "DELETE", Path: "/organizations/:id/roles/:role_id", Tag: "organizations", Summary: "Delete an unused custom role", Permission: string(rbac.PermManageOrganization)},
+		{Method: "GET", Path: "/organizations/:id/session-limits", Tag: "organizations", Summary: "List concurrent session limits per role and the defaults", Permission: string(rbac.PermViewOrganization), Response: SessionLimitsResponse{}},
+		{Method: "PUT", Path: "/organizations/:id/session-limits/:role", Tag: "organizations", Summary: "Set the concurrent session limit for a role (\"*\" for any role)", Permission: string(rbac.PermManageOrganization), Request: SetSessionLimitRequest{}, Response: auth.SessionLimitPolicy{}},
+		{Method: "DELETE", Path: "/organizations/:id/session-limits/:role", Tag: "organizations", Summary: "Remove a role's session limit", Permission: string(rbac.PermManageOrganization), Status: 204},
+		{Method: "GET", Path: "/organizations/:id/uploads", Tag: "organizations", Summary: "List the organization's uploads with their content scan verdicts, quarantined ones included", Permission: string(rbac.PermManageOrganization), Query: []string{"kind", "limit"}, Response: UploadsResponse{}},
+		{Method: "GET", Path: "/organizations/:id/network-policy", Tag: "organizations", Summary: "Get the organization's IP allowlist and active break-glass overrides", Permission: string(rbac.PermManageOrganization), Response: NetworkPolicyResponse{}},
+		{Method: "PUT", Path: "/organizations/:id/network-policy", Tag: "organizations", Summary: "Replace the organization's IP allowlist, enforced at login and on every request; an enabled allowlist must include the caller's address", Permission: string(rbac.PermManageOrganization), Request: SetNetworkPolicyRequest{}, Response: auth.NetworkPolicy{}},
+		{Method: "GET", Path: "/organizations/:id/login-policy", Tag: "organizations", Summary: "Get how the organization's members may sign in", Permission: string(rbac.PermManageOrganization), Response: auth.LoginPolicy{}},
+		{Method: "PUT", Path: "/organizations/:id/login-policy", Tag: "organizations", Summary: "Allow or forbid magic link sign-in; members can use it only when every organization they belong to allows it", Permission: string(rbac.PermManageOrganization), Request: SetLoginPolicyRequest{}, Response: auth.LoginPolicy{}},
+		{Method: "GET", Path: "/organizations/:id/api-tokens", Tag: "organizations", Summary: "List the organization's API tokens", Permission: string(rbac.PermManageOrganization), Response: OrgTokensResponse{}},
+		{Method: "POST", Path: "/organizations/:id/api-tokens", Tag: "organizations", Summary: "Create an API token scoped to permissions, optionally on specific authorizations; the token is only returned here", Permission: string(rbac.PermManageOrganization), Request: auth.CreateOrgTokenRequest{}, Response: auth.CreatedOrgToken{}, Status: 201},
+		{Method: "PATCH", Path: "/organizations/:id/api-tokens/:token_id", Tag: "organizations", Summary: "Replace an API token's scopes; wider scopes are recorded as a privilege change", Permission: string(rbac.PermManageOrganization), Request: auth.UpdateOrgTokenRequest{}, Response: auth.OrgToken{}},
+		{Method: "DELETE", Path: "/organizations/:id/api-tokens/:token_id", Tag: "organizations", Summary: "Revoke an API token", Permission: string(rbac.PermManageOrganization)},
+		{Method: "GET", Path: "/organizations/:id/domains", Tag: "organizations", Summary: "List the organization's email domains with their verification status and auto-join settings", Permission: string(rbac.PermManageOrganization), Response: DomainsResponse{}},
+		{Method: "POST", Path: "/organizations/:id/domains", Tag: "organizations", Summary: "Claim an email domain; the response has the DNS TXT record that proves ownership. Public email providers and subdomains of another organization's verified domain are refused", Permission: string(rbac.PermManageOrganization), Request: AddDomainRequest{}, Response: domains.Domain{}, Status: 201},
+		{Method: "POST", Path: "/organizations/:id/domains/:domain_id/verify", Tag: "organizations", Summary: "Check the domain's TXT record and mark it verified; a domain can be verified by one organization only", Permission: string(rbac.PermManageOrganization), Response: domains.Domain{}},
+		{Method: "PATCH", Path: "/organizations/:id/domains/:domain_id", Tag: "organizations", Summary: "Turn auto-join on or off and set the role users with a verified address on the domain join with", Permission: string(rbac.PermManageOrganization), Request: domains.Update{}, Response: domains.Domain{}},
+		{Method: "DELETE", Path: "/organizations/:id/domains/:domain_id", Tag: "organizations", Summary: "Remove a domain; members who joined through it stay", Permission: string(rbac.PermManageOrganization)},
+		{Method: "GET", Path: "/organizations/:id/billing/usage", Tag: "organizations", Summary: "Get metered usage (scans started, reports generated, storage) by month", Permission: string(rbac.PermManageOrganization), Query: []string{"months"}, Response: BillingUsageResponse{}},
+
+		// GraphQL
+		{Method: "POST", Path: "/graphql", Tag: "graphql", Summary: "Run a GraphQL query over organizations, scans, findings, assets and audit summaries", Request: graphql.Request{}, Response: graphql.Response{}},
+		{Method: "GET", Path: "/graphql/schema", Tag: "graphql", Summary: "Get the GraphQL schema in SDL"},
+		{Method: "GET", Path: "/organizations/:id/scan-approval-rules", Tag: "organizations", Summary: "List the scan types that need approval", Permission: string(rbac.PermViewOrganization), Response: []approvals.Rule{}},
+		{Method: "PUT", Path: "/organizations/:id/scan-approval-rules/:scan_type", Tag: "organizations", Summary: "Set how many approvals a scan type needs", Permission: string(rbac.PermManageOrganization), Request: ApprovalRuleRequest{}, Response: approvals.Rule{}},
+		{Method: "DELETE", Path: "/organizations/:id/scan-approval-rules/:scan_type", Tag: "organizations", Summary: "Remove a scan type's approval rule, restoring the default", Permission: string(rbac.PermManageOrganization), Status: 204},
+		{Method: "GET", Path: "/organizations/:id/scan-windows", Tag: "organizations", Summary: "List the execution windows scans are restricted to", Permission: string(rbac.PermViewOrganization), Response: []scanwindows.Window{}},
+		{Method: "POST", Path: "/organizations/:id/scan-windows", Tag: "organizations", Summary: "Add a recurring execution window, organization-wide or for one authorized target", Permission: string(rbac.PermManageOrganization), Request: CreateScanWindowRequest{}, Response: scanwindows.Window{}, Status: 201},
+		{Method: "DELETE", Path: "/organizations/:id/scan-windows/:window_id", Tag: "organizations", Summary: "Remove an execution window", Permission: string(rbac.PermManageOrganization), Status: 204},
+		{Method: "GET", Path: "/organizations/:id/scan-imports", Tag: "scans", Summary: "List imported scanner files and the scans created from them", Permission: string(rbac.PermViewScan), Query: []string{"limit"}, Response: []imports.Import{}},
+		{Method: "POST", Path: "/organizations/:id/scan-imports", Tag: "scans", Summary: "Import a scanner file (multipart fields \"file\", at most 50 MB, and \"format\": nmap, nuclei or burp) as one completed scan per host", Permission: string(rbac.PermCreateScan), Response: imports.Import{}, Status: 201, Payload: "imports"},
+		{Method: "GET", Path: "/organizations/:id/scan-imports/:import_id", Tag: "scans", Summary: "Get a scan import", Permission: string(rbac.PermViewScan), Response: imports.Import{}},
+		{Method: "GET", Path: "/organizations/:id/settings", Tag: "organizations", Summary: "Get the organization's branding settings", Permission: string(rbac.PermViewOrganization), Response: branding.Settings{}},
+		{Method: "PUT", Path: "/organizations/:id/settings", Tag: "organizations", Summary: "Replace the organization's branding settings", Permission: string(rbac.PermManageOrganization), Request: OrganizationSettingsRequest{}, Response: branding.Settings{}},
+		{Method: "PUT", Path: "/organizations/:id/settings/logo", Tag: "organizations", Summary: "Upload the organization's logo (multipart field \"logo\", PNG/JPEG/GIF/WebP, at most 1 MB); 422 if the content scanner quarantines it", Permission: string(rbac.PermManageOrganization), Response: branding.Settings{}, Payload: "uploads"},
+		{Method: "DELETE", Path: "/organizations/:id/settings/logo", Tag: "organizations", Summary: "Remove the organization's logo", Permission: string(rbac.PermManageOrganization)},
+		{Method: "GET", Path: "/organizations/:id/stats", Tag: "organizations", Summary: "Dashboard statistics", Permission: string(rbac.PermViewScan), Query: []string{"days"}, Response: stats.OrgStats{}},
+
+		// Finding triage
+		{Method: "GET", Path: "/organizations/:id/findings/export", Tag: "findings", Summary: "Stream the organization's findings as csv or xlsx (choose columns with columns=a,b) or as defectdojo JSON or nessus XML", Permission: string(rbac.PermViewScan), Query: []string{"format", "columns", "severity", "status", "scan_id"}},
+		{Method: "GET", Path: "/organizations/:id/findings/:finding_id/comments", Tag: "findings", Summary: "List a finding's comment threads", Permission: string(rbac.PermViewScan), Response: []FindingComment{}},
+		{Method: "POST", Path: "/organizations/:id/findings/:finding_id/comments", Tag: "findings", Summary: "Comment on a finding or reply to a comment", Permission: string(rbac.PermTriageFinding), Request: CreateFindingCommentRequest{}, Response: FindingComment{}, Status: 201},
+		{Method: "PUT", Path: "/organizations/:id/findings/:finding_id/assignment", Tag: "findings", Summary: "Assign a finding to a member with an optional due date", Permission: string(rbac.PermTriageFinding), Request: AssignFindingRequest{}, Response: FindingAssignment{}},
+		{Method: "PUT", Path: "/organizations/:id/findings/:finding_id/status", Tag: "findings", Summary: "Change a finding's status", Permission: string(rbac.PermTriageFinding), Request: FindingStatusRequest{}},
+		{Method: "POST", Path: "/findings:batchUpdate", Tag: "findings", Summary: "Change the status, assignment or suppression of up to 500 findings at once, reporting each finding's outcome", Permission: string(rbac.PermTriageFinding), Request: BatchUpdateFindingsRequest{}, Response: BatchResponse{}},
+		{Method: "GET", Path: "/organizations/:id/findings/:finding_id/history", Tag: "findings", Summary: "A finding's status history", Permission: string(rbac.PermViewScan), Response: []FindingStatusChange{}},
+		{Method: "GET", Path: "/organizations/:id/findings/:finding_id/evidence", Tag: "findings", Summary: "A finding's evidence files with their scan verdicts and download links", Permission: string(rbac.PermViewScan), Response: []FindingEvidence{}},
+		{Method: "POST", Path: "/organizations/:id/findings/:finding_id/evidence", Tag: "findings", Summary: "Attach evidence (multipart field \"file\": image, PDF, text or zip, at most 25 MB); 422 if the content scanner quarantines it", Permission: string(rbac.PermTriageFinding), Response: FindingEvidence{}, Status: 201, Payload: "uploads"},
+		{Method: "PUT", Path: "/organizations/:id/findings/:finding_id/watch", Tag: "findings", Summary: "Watch a finding for activity notifications", Permission: string(rbac.PermViewScan), Status: 204},
+		{Method: "DELETE", Path: "/organizations/:id/findings/:finding_id/watch", Tag: "findings", Summary: "Stop watching a finding", Permission: string(rbac.PermViewScan), Status: 204},
+		{Method: "GET", Path: "/organizations/:id/suppression-rules", Tag: "findings", Summary: "List suppression rules", Permission: string(rbac.PermViewScan), Query: []string{"include_expired"}, Response: []findings.SuppressionRule{}},
+		{Method: "POST", Path: "/organizations/:id/suppression-rules", Tag: "findings", Summary: "Suppress matching findings in future scan results", Permission: string(rbac.PermTriageFinding), Request: CreateSuppressionRuleRequest{}, Response: findings.SuppressionRule{}, Status: 201},
+		{Method: "DELETE", Path: "/organizations/:id/suppression-rules/:rule_id", Tag: "findings", Summary: "Delete a suppression rule", Permission: string(rbac.PermTriageFinding)},
+
+		// Vulnerability intelligence
+		{Method: "GET", Path: "/intel/cves", Tag: "intel", Summary: "List CVEs, known-exploited and most likely exploited first", Query: []string{"ids", "kev", "min_epss", "cwe", "limit"}, Response: []intel.CVE{}},
+		{Method: "GET", Path: "/intel/cves/:cve_id", Tag: "intel", Summary: "Get a CVE's metadata, EPSS score and KEV status", Response: intel.CVE{}},
+		{Method: "GET", Path: "/intel/status", Tag: "intel", Summary: "When each intelligence feed was last synced", Response: []intel.SyncStatus{}},
+		{Method: "GET", Path: "/organizations/:id/findings/:finding_id/intel", Tag: "intel", Summary: "Intelligence on a finding's CVE", Permission: string(rbac.PermViewScan), Response: FindingIntel{}},
+
+		// Compliance
+		{Method: "GET", Path: "/compliance/frameworks", Tag: "compliance", Summary: "List supported frameworks and their mapped controls", Response: []compliance.Framework{}},
+		{Method: "GET", Path: "/organizations/:id/compliance", Tag: "compliance", Summary: "Control coverage per framework from open findings", Permission: string(rbac.PermViewReport), Query: []string{"scan_id"}, Response: []FrameworkCoverage{}},
+		{Method: "GET", Path: "/organizations/:id/compliance/:framework", Tag: "compliance", Summary: "Assess open findings against a framework's controls", Permission: string(rbac.PermViewReport), Query: []string{"scan_id"}, Response: compliance.Assessment{}},
+		{Method: "GET", Path: "/organizations/:id/integrations/slack", Tag: "integrations", Summary: "List Slack workspaces connected to the organization", Permission: string(rbac.PermViewOrganization), Response: []SlackWorkspace{}},
+		{Method: "PUT", Path: "/organizations/:id/integrations/slack", Tag: "integrations", Summary: "Connect a Slack workspace to the organization", Permission: string(rbac.PermManageOrganization), Request: SlackWorkspaceRequest{}},
+		{Method: "DELETE", Path: "/organizations/:id/integrations/slack/:team_id", Tag: "integrations", Summary: "Disconnect a Slack workspace and its linked users", Permission: string(rbac.PermManageOrganization), Status: 204},
+		{Method: "POST", Path: "/organizations/:id/integrations/test", Tag: "integrations", Summary: "Send a test event to a webhook or Slack channel", Permission: string(rbac.PermManageOrganization), Request: TestDeliveryRequest{}, Response: TestDeliveryResponse{}},
+		{Method: "GET", Path: "/organizations/:id/integrations/deliveries", Tag: "integrations", Summary: "List recent webhook and Slack delivery attempts, redacted", Permission: string(rbac.PermManageOrganization), Query: []string{"channel", "source", "outcome", "limit"}, Response: []deliveries.Attempt{}},
+		{Method: "GET", Path: "/organizations/:id/integrations/deliveries/:delivery_id", Tag: "integrations", Summary: "Get a delivery attempt with its request and response, redacted", Permission: string(rbac.PermManageOrganization), Response: deliveries.Attempt{}},
+		{Method: "POST", Path: "/organizations/:id/integrations/deliveries/:delivery_id/replay", Tag: "integrations", Summary: "Send a failed delivery again as it was first sent", Permission: string(rbac.PermManageOrganization), Response: deliveries.Attempt{}},
+		{Method: "POST", Path: "/users/me/slack/link-code", Tag: "integrations", Summary: "Create a one-time code for /cyscan link", Response: SlackLinkCodeResponse{}, Status: 201},
+		{Method: "DELETE", Path: "/users/me/slack", Tag: "integrations", Summary: "Unlink your Slack accounts", Status: 204},
+		{Method: "POST", Path: "/integrations/slack/commands", Tag: "integrations", Summary: "Slack slash commands (signed by Slack)", Public: true},
+		{Method: "POST", Path: "/integrations/slack/interactions", Tag: "integrations", Summary: "Slack interactive message actions (signed by Slack)", Public: true},
+		{Method: "GET", Path: "/organizations/:id/policies", Tag: "organizations", Summary: "List access policies", Permission: string(rbac.PermViewOrganization), Response: []rbac.AccessPolicy{}},
+		{Method: "POST", Path: "/organizations/:id/policies", Tag: "organizations", Summary: "Create an access policy", Permission: string(rbac.PermManageOrganization), Request: AccessPolicyRequest{}, Response: rbac.AccessPolicy{}, Status: 201},
+		{Method: "DELETE", Path: "/organizations/:id/policies/:policy_id", Tag: "organizations", Summary: "Delete an access policy", Permission: string(rbac.PermManageOrganization)},
+		{Method: "GET", Path: "/me/permissions", Tag: "organizations", Summary: "Get the caller's effective permissions", Query: []string{"org"}, Response: PermissionsResponse{}},
+		{Method: "POST", Path: "/access/check", Tag: "organizations", Summary: "Check a batch of resource actions", Request: AccessCheckRequest{}, Response: AccessCheckResponse{}},
+
+		// Scans
+		{Method: "POST", Path: "/scans", Tag: "scans", Summary: "Create a scan", Permission: string(rbac.PermCreateScan), Request: CreateScanRequest{}, Response: ScanJob{}, Status: 201},
+		{Method: "POST", Path: "/scans/preflight", Tag: "scans", Summary: "Check whether a scan can start, with a go/no-go decision and estimate", Permission: string(rbac.PermCreateScan), Request: CreateScanRequest{}, Response: PreflightResponse{}},
+		{Method: "GET", Path: "/scans/approvals", Tag: "scans", Summary: "List scans awaiting approval", Permission: string(rbac.PermApproveScan), Response: []approvals.Scan{}},
+		{Method: "GET", Path: "/scans/:id/approvals", Tag: "scans", Summary: "List the approval decisions on a scan", Permission: string(rbac.PermViewScan), Response: ScanApprovalsResponse{}},
+		{Method: "POST", Path: "/scans/:id/approve", Tag: "scans", Summary: "Approve a scan awaiting approval", Permission: string(rbac.PermApproveScan), Request: ScanDecisionRequest{}, Response: approvals.Scan{}},
+		{Method: "POST", Path: "/scans/:id/reject", Tag: "scans", Summary: "Reject a scan awaiting approval", Permission: string(rbac.PermApproveScan), Request: ScanDecisionRequest{}, Response: approvals.Scan{}},
+		{Method: "GET", Path: "/scans/:id/diff", Tag: "scans", Summary: "Compare findings with another scan of the same target", Permission: string(rbac.PermViewScan), Query: []string{"against"}, Response: ScanDiffResponse{}},
+		{Method: "POST", Path: "/scans:batchCancel", Tag: "scans", Summary: "Stop up to 500 scans, reporting each scan's outcome", Permission: string(rbac.PermStopScan), Request: BatchCancelScansRequest{}, Response: BatchResponse{}},
+		{Method: "POST", Path: "/scans/:id/stop", Tag: "scans", Summary: "Stop a pending, running or paused scan", Permission: string(rbac.PermStopScan), Request: ScanControlRequest{}, Response: ScanControlResponse{}},
+		{Method: "POST", Path: "/scans/:id/pause", Tag: "scans", Summary: "Pause a pending or running scan", Permission: string(rbac.PermStopScan), Request: ScanControlRequest{}, Response: ScanControlResponse{}},
+		{Method: "POST", Path: "/scans/:id/resume", Tag: "scans", Summary: "Resume a paused scan", Permission: string(rbac.PermStopScan), Request: ScanControlRequest{}, Response: ScanControlResponse{}},
+		{Method: "POST", Path: "/scans/:id/rerun", Tag: "scans", Summary: "Queue a new scan with a finished scan's target and settings", Permission: string(rbac.PermCreateScan), Response: ScanJob{}, Status: 201},
+		{Method: "POST", Path: "/scan-authorizations", Tag: "scans", Summary: "Submit a scan authorization", Request: SubmitAuthorizationRequest{}, Status: 201},
+		{Method: "GET", Path: "/scan-authorizations", Tag: "scans", Summary: "List scan authorizations", Query: []string{"status"}, Response: []Authorization{}},
+		{Method: "POST", Path: "/scan-authorizations/check", Tag: "scans", Summary: "Check whether a target is authorized", Request: CheckTargetRequest{}},
+		{Method: "POST", Path: "/scan-authorizations/:id/verify", Tag: "scans", Summary: "Approve or reject an authorization", Request: VerifyAuthorizationRequest{}},
+		{Method: "POST", Path: "/scan-grants", Tag: "scans", Summary: "Ask a client to authorize scans of their assets through a signed link", Permission: string(rbac.PermCreateScan), Request: CreateScanGrantRequest{}, Response: ScanGrantResponse{}, Status: 201},
+		{Method: "GET", Path: "/scan-grants", Tag: "scans", Summary: "List client scan grants", Query: []string{"status"}, Response: []grants.Grant{}},
+		{Method: "GET", Path: "/scan-grants/:id", Tag: "scans", Summary: "Get a client scan grant and its review link", Response: ScanGrantResponse{}},
+		{Method: "POST", Path: "/scan-grants/:id/revoke", Tag: "scans", Summary: "Revoke a client scan grant", Permission: string(rbac.PermCreateScan), Request: RevokeScanGrantRequest{}, Response: grants.Grant{}},
+		{Method: "GET", Path: "/scan-grants/review", Tag: "scans", Summary: "Review a scan grant from the client's link", Public: true, Query: []string{"token"}, Response: grants.Grant{}},
+		{Method: "POST", Path: "/scan-grants/review", Tag: "scans", Summary: "Approve or reject a scan grant from the client's link", Public: true, Query: []string{"token"}, Request: DecideScanGrantRequest{}, Response: grants.Grant{}},
+		{Method: "POST", Path: "/scan-grants/review/revoke", Tag: "scans", Summary: "Revoke a scan grant from the client's link", Public: true, Query: []string{"token"}, Request: RevokeScanGrantRequest{}, Response: grants.Grant{}},
+
+		// Reports
+		{Method: "GET", Path: "/organizations/:id/report-templates", Tag: "reports", Summary: "List report templates", Permission: string(rbac.PermViewReport)},
+		{Method: "POST", Path: "/organizations/:id/report-templates", Tag: "reports", Summary: "Create a report template", Permission: string(rbac.PermManageReportTemplates), Request: ReportTemplateRequest{}, Response: reports.ReportTemplate{}, Status: 201},
+		{Method: "GET", Path: "/organizations/:id/report-templates/:template_id", Tag: "reports", Summary: "Get a report template", Permission: string(rbac.PermViewReport), Response: reports.ReportTemplate{}},
+		{Method: "PUT", Path: "/organizations/:id/report-templates/:template_id", Tag: "reports", Summary: "Replace a report template", Permission: string(rbac.PermManageReportTemplates), Request: ReportTemplateRequest{}, Response: reports.ReportTemplate{}},
+		{Method: "DELETE", Path: "/organizations/:id/report-templates/:template_id", Tag: "reports", Summary: "Delete a report template", Permission: string(rbac.PermManageReportTemplates)},
+		{Method: "POST", Path: "/scans/:id/report", Tag: "reports", Summary: "Generate a scan report", Permission: string(rbac.PermGenerateReport), Query: []string{"format", "template_id", "include_suppressed"}, Request: brain.GenerateReportRequest{}, Response: reports.Report{}, Status: 201, Payload: "reports"},
+		{Method: "POST", Path: "/scans/:id/analyze", Tag: "reports", Summary: "Stream the brain's analysis of a scan as server-sent events", Permission: string(rbac.PermGenerateReport), Query: []string{"broadcast"}, Request: AnalyzeScanRequest{}},
+		{Method: "GET", Path: "/reports", Tag: "reports", Summary: "List stored reports", Permission: string(rbac.PermViewReport), Query: []string{"scan_id", "source", "schedule_id"}, Response: []reports.Report{}},
+		{Method: "GET", Path: "/organizations/:id/report-schedules", Tag: "reports", Summary: "List report schedules", Permission: string(rbac.PermViewReport), Response: []reports.Schedule{}},
+		{Method: "POST", Path: "/organizations/:id/report-schedules", Tag: "reports", Summary: "Create a report schedule", Permission: string(rbac.PermManageReportSchedules), Request: ReportScheduleRequest{}, Response: reports.Schedule{}, Status: 201},
+		{Method: "PUT", Path: "/organizations/:id/report-schedules/:schedule_id", Tag: "reports", Summary: "Replace a report schedule", Permission: string(rbac.PermManageReportSchedules), Request: ReportScheduleRequest{}, Response: reports.Schedule{}},
+		{Method: "DELETE", Path: "/organizations/:id/report-schedules/:schedule_id", Tag: "reports", Summary: "Delete a report schedule", Permission: string(rbac.PermManageReportSchedules)},
+		{Method: "GET", Path: "/reports/:id/download", Tag: "reports", Summary: "Download a stored report in its generated format; PDFs are watermarked with the downloader's identity", Permission: string(rbac.PermViewReport)},
+		{Method: "GET", Path: "/reports/:id/downloads", Tag: "reports", Summary: "A report's download history: who, when, from which IP and how many bytes", Permission: string(rbac.PermViewAuditLogs), Response: []downloads.Download{}},
+
+		// Audit
+		{Method: "GET", Path: "/organizations/:id/audit", Tag: "audit", Summary: "List the organization's audit logs", Permission: string(rbac.PermViewAuditLogs), Query: []string{"user_id", "action", "severity", "status", "resource_type", "start_time", "end_time", "limit", "offset"}},
+		{Method: "GET", Path: "/audit/export", Tag: "audit", Summary: "Export audit logs for a time range", Query: []string{"start_time", "end_time", "format"}},
+		{Method: "GET", Path: "/audit/summary", Tag: "audit", Summary: "Summarize an organization's or user's audit trail over a time range", Permission: string(rbac.PermViewAuditLogs), Query: []string{"organization_id", "user_id", "start_time", "end_time", "top", "timezone"}, Response: AuditSummaryResponse{}},
+		{Method: "GET", Path: "/audit/privilege-changes", Tag: "audit", Summary: "List role elevations, custom role permission grants and API token scope expansions with their before and after state", Permission: string(rbac.PermViewAuditLogs), Query: []string{"organization_id", "kind", "subject_id", "start_time", "end_time", "limit"}, Response: PrivilegeChangesResponse{}},
+		{Method: "GET", Path: "/audit/stream", Tag: "audit", Summary: "Stream new audit entries as server-sent events", Permission: string(rbac.PermViewAuditLogs), Query: []string{"organization_id", "severity", "action"}},
+		{Method: "POST", Path: "/audit/verify", Tag: "audit", Summary: "Verify an audit log signature", Request: VerifySignatureRequest{}},
+		{Method: "POST", Path: "/audit/verify-range", Tag: "audit", Summary: "Verify every audit log signature and external anchor in a time range", Request: VerifyRangeRequest{}, Response: audit.RangeVerification{}},
+		{Method: "GET", Path: "/audit/anchors", Tag: "audit", Summary: "List external anchors of the audit hash chain", Query: []string{"limit"}},
+
+		// Emergency
+		{Method: "POST", Path: "/emergency/stop", Tag: "emergency", Summary: "Activate emergency stop (admin listener)", Request: EmergencyStopRequest{}},
+		{Method: "POST", Path: "/emergency/resume", Tag: "emergency", Summary: "Deactivate emergency stop (admin listener)"},
+		{Method: "GET", Path: "/emergency/status", Tag: "emergency", Summary: "Get emergency stop status"},
+
+		// Escalations
+		{Method: "GET", Path: "/organizations/:id/escalation-policies", Tag: "escalations", Summary: "List escalation policies", Permission: string(rbac.PermManageEscalations), Response: []escalation.Policy{}},
+		{Method: "POST", Path: "/organizations/:id/escalation-policies", Tag: "escalations", Summary: "Create an escalation policy", Permission: string(rbac.PermManageEscalations), Request: EscalationPolicyRequest{}, Response: escalation.Policy{}, Status: 201},
+		{Method: "PUT", Path: "/organizations/:id/escalation-policies/:policy_id", Tag: "escalations", Summary: "Replace an escalation policy", Permission: string(rbac.PermManageEscalations), Request: EscalationPolicyRequest{}, Response: escalation.Policy{}},
+		{Method: "DELETE", Path: "/organizations/:id/escalation-policies/:policy_id", Tag: "escalations", Summary: "Delete an escalation policy", Permission: string(rbac.PermManageEscalations)},
+		{Method: "GET", Path: "/organizations/:id/escalations", Tag: "escalations", Summary: "List escalations", Permission: string(rbac.PermViewOrganization), Query: []string{"status", "limit"}, Response: []escalation.Escalation{}},
+		{Method: "GET", Path: "/organizations/:id/escalations/:escalation_id", Tag: "escalations", Summary: "Get an escalation with its history", Permission: string(rbac.PermViewOrganization), Response: EscalationDetail{}},
+		{Method: "POST", Path: "/organizations/:id/escalations/:escalation_id/acknowledge", Tag: "escalations", Summary: "Acknowledge an escalation and stop paging", Permission: string(rbac.PermAcknowledgeEscalations), Request: AcknowledgeEscalationRequest{}, Response: escalation.Escalation{}},
+		{Method: "GET", Path: "/escalations/acknowledge", Tag: "escalations", Summary: "Acknowledge an escalation from a paging link", Public: true, Query: []string{"token"}},
+		{Method: "POST", Path: "/escalations/acknowledge", Tag: "escalations", Summary: "Acknowledge an escalation from a paging link", Public: true, Query: []string{"token"}},
+
+		// Docs
+		{Method: "GET", Path: "/openapi.json", Tag: "docs", Summary: "OpenAPI specification", Public: true},
+	}
+}
+
+// TokenRoutePermissions maps "METHOD /v1/path" to the permission an
+// organization API token needs for it. Only routes declaring a permission
+// are open to tokens.
+func TokenRoutePermissions() map[string]rbac.Permission {
+	perms := map[string]rbac.Permission{}
+	for _, route := range Routes() {
+		if route.Permission != "" {
+			perms[route.Method+" "+APIBasePath+route.Path] = rbac.Permission(route.Permission)
+		}
+	}
+	return perms
+}
+
+// PayloadRouteGroups maps "METHOD /full/path" to the body limit group of
+// each route assigned to one
+func PayloadRouteGroups() map[string]string {
+	groups := map[string]string{}
+	for _, route := range Routes() {
+		if route.Payload != "" {
+			groups[route.Method+" "+APIBasePath+route.Path] = route.Payload
+		}
+	}
+	return groups
+}
+
+// DeprecatedRoutes maps "METHOD /v1/path" to the deprecation of each route
+// declaring one
+func DeprecatedRoutes() (map[string]apiversion.Deprecation, error) {
+	deprecations := map[string]apiversion.Deprecation{}
+	for _, route := range Routes() {
+		if route.Deprecated == "" {
+			continue
+		}
+		deprecation := apiversion.Deprecation{Successor: route.Successor}
+		var err error
+		if deprecation.Deprecated, err = time.Parse("2006-01-02", route.Deprecated); err != nil {
+			return nil, fmt.Errorf("%s %s: invalid deprecation date: %w", route.Method, route.Path, err)
+		}
+		if route.Sunset != "" {
+			if deprecation.Sunset, err = time.Parse("2006-01-02", route.Sunset); err != nil {
+				return nil, fmt.Errorf("%s %s: invalid sunset date: %w", route.Method, route.Path, err)
+			}
+		}
+		if deprecation.Successor != "" {
+			deprecation.Successor = "/api" + deprecation.Successor
+		}
+		deprecations[route.Method+" "+APIBasePath+route.Path] = deprecation
+	}
+	return deprecations, nil
+}
+
+// OpenAPIDocument builds the specification served at /api/v1/openapi.json.
+// WebSocket envelopes and event payloads are published as component schemas.
+func OpenAPIDocument() *openapi.Document {
+	doc := openapi.Build(OpenAPIInfo, "/api"+APIBasePath, OpenAPITags, Routes())
+
+	doc.AddSchema(realtime.Message{}).Description = "WebSocket envelope for server events; data holds the payload named by type."
+	doc.AddSchema(realtime.ClientMessage{}).Description = "WebSocket envelope for client commands; data holds the payload named by type."
+
+	for _, spec := range realtime.EventCatalog() {
+		kind := "event"
+		if spec.Inbound {
+			kind = "command"
+		}
+		line := fmt.Sprintf("WebSocket %s `%s` (version %d).", kind, spec.Type, spec.Version)
+
+		schema := doc.AddSchema(spec.Payload)
+		if schema.Description != "" {
+			line = schema.Description + " " + line
+		}
+		schema.Description = line
+	}
+
+	return doc
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/privileges"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// OrgTokenHandler manages an organization's scoped API tokens
+type OrgTokenHandler struct {
+	auth        *auth.AuthService
+	roles       *rbac.RoleStore
+	privileges  *privileges.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewOrgTokenHandler(authService *auth.AuthService, roles *rbac.RoleStore, privilegeService *privileges.Service, auditLogger Auditor, logger *zap.Logger) *OrgTokenHandler {
+	return &OrgTokenHandler{
+		auth:        authService,
+		roles:       roles,
+		privileges:  privilegeService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// OrgTokensResponse lists an organization's API tokens
+type OrgTokensResponse struct {
+	Tokens []auth.OrgToken `json:"tokens"`
+}
+
+// authorize checks the caller may manage the organization's tokens. Tokens
+// are managed by people, never by other tokens.
+func (h *OrgTokenHandler) authorize(c *gin.Context, orgID string) (string, bool) {
+	if _, ok := rbac.TokenGrantFrom(c.Request.Context()); ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API tokens cannot manage API tokens"})
+		return "", false
+	}
+	return authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+}
+
+// ListTokens handles GET /api/v1/organizations/:id/api-tokens
+func (h *OrgTokenHandler) ListTokens(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := h.authorize(c, orgID); !ok {
+		return
+	}
+
+	tokens, err := h.auth.ListOrgTokens(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list API tokens", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, OrgTokensResponse{Tokens: tokens})
+}
+
+// CreateToken handles POST /api/v1/organizations/:id/api-tokens. A token can
+// only be scoped to permissions the creator's role holds; the secret is
+// returned once.
+func (h *OrgTokenHandler) CreateToken(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := h.authorize(c, orgID)
+	if !ok {
+		return
+	}
+
+	var req auth.CreateOrgTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	scopes, ok := h.checkScopesHeld(c, orgID, userID, req.Scopes, "Failed to create API token")
+	if !ok {
+		return
+	}
+
+	created, err := h.auth.CreateOrgToken(ctx, orgID, userID, req)
+	if errors.Is(err, auth.ErrInvalidOrgTokenScope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to create API token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "api_token_created", "organization", orgID, map[string]interface{}{
+		"token_id":    created.ID,
+		"name":        created.Name,
+		"scopes":      scopes,
+		"allowed_ips": []string(created.AllowedIPs),
+		"expires_at":  created.ExpiresAt,
+	})
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateToken handles PATCH /api/v1/organizations/:id/api-tokens/:token_id,
+// replacing the token's scopes. Scopes that let the token do more are
+// recorded as a privilege change.
+func (h *OrgTokenHandler) UpdateToken(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := h.authorize(c, orgID)
+	if !ok {
+		return
+	}
+
+	var req auth.UpdateOrgTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	scopes, ok := h.checkScopesHeld(c, orgID, userID, req.Scopes, "Failed to update API token")
+	if !ok {
+		return
+	}
+
+	token, previous, err := h.auth.UpdateOrgTokenScopes(ctx, orgID, c.Param("token_id"), req.Scopes)
+	switch {
+	case errors.Is(err, auth.ErrOrgTokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return
+	case errors.Is(err, auth.ErrInvalidOrgTokenScope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logging.FromContext(ctx, h.logger).Error("Failed to update API token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update API token"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "api_token_updated", "organization", orgID, map[string]interface{}{
+		"token_id": token.ID,
+		"name":     token.Name,
+		"scopes":   scopes,
+	})
+	if _, err := h.privileges.TokenScopesChanged(ctx, userID, orgID, token.ID, token.Name, previous, token.Scopes); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to record privilege change", zap.String("token_id", token.ID), zap.Error(err))
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// checkScopesHeld makes sure the caller's role holds every scoped
+// permission, since a token can't do more than the person it acts as. It
+// returns the scoped permissions for the audit log.
+func (h *OrgTokenHandler) checkScopesHeld(c *gin.Context, orgID, userID string, tokenScopes rbac.TokenScopes, message string) ([]string, bool) {
+	ctx := c.Request.Context()
+	role, err := h.roles.MemberRole(ctx, userID, orgID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to verify membership", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		return nil, false
+	}
+	scopes := make([]string, 0, len(tokenScopes))
+	for _, scope := range tokenScopes {
+		held, err := h.roles.HasPermission(ctx, orgID, role, scope.Permission)
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to resolve role permissions", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+			return nil, false
+		}
+		if !held {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot grant a permission your role does not have", "permission": scope.Permission})
+			return nil, false
+		}
+		scopes = append(scopes, string(scope.Permission))
+	}
+	return scopes, true
+}
+
+// RevokeToken handles DELETE /api/v1/organizations/:id/api-tokens/:token_id
+func (h *OrgTokenHandler) RevokeToken(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := h.authorize(c, orgID)
+	if !ok {
+		return
+	}
+
+	tokenID := c.Param("token_id")
+	err := h.auth.RevokeOrgToken(c.Request.Context(), orgID, tokenID)
+	if err == auth.ErrOrgTokenNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to revoke API token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "api_token_revoked", "organization", orgID, map[string]interface{}{
+		"token_id": tokenID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
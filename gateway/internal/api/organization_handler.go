@@ -1,48 +1,71 @@
 package api
 
 import (
-	"database/sql"
 	"net/http"
+	"regexp"
 
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/privileges"
 	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/tenancy"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
 type OrganizationHandler struct {
-	db     *sqlx.DB
-	logger *zap.Logger
+	repos      *repository.Repositories
+	uow        *repository.UnitOfWork
+	roles      *rbac.RoleStore
+	privileges *privileges.Service
+	tenancy    *tenancy.Service
+	logger     *zap.Logger
 }
 
-func NewOrganizationHandler(db *sqlx.DB, logger *zap.Logger) *OrganizationHandler {
+func NewOrganizationHandler(repos *repository.Repositories, uow *repository.UnitOfWork, roles *rbac.RoleStore, privilegeService *privileges.Service, logger *zap.Logger) *OrganizationHandler {
 	return &OrganizationHandler{
-		db:     db,
-		logger: logger,
+		repos:      repos,
+		uow:        uow,
+		roles:      roles,
+		privileges: privilegeService,
+		logger:     logger,
 	}
 }
 
+// SetTenancy lets enterprise organizations be created with a database
+// schema of their own. Without it only shared isolation is offered.
+func (h *OrganizationHandler) SetTenancy(tenancyService *tenancy.Service) {
+	h.tenancy = tenancyService
+}
+
+// validSlug is lowercase letters and digits, optionally separated by single hyphens
+var validSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
 type CreateOrganizationRequest struct {
-	Name string `json:"name" binding:"required"`
-	Slug string `json:"slug" binding:"required"`
+	Name string `json:"name" binding:"required,max=255"`
+	Slug string `json:"slug" binding:"required,min=3,max=63"`
 	Tier string `json:"tier"`
+	// Isolation is "shared" (the default) or, for the enterprise tier,
+	// "schema": the organization's scan evidence is kept in a database
+	// schema of its own
+	Isolation string `json:"isolation" binding:"omitempty,oneof=shared schema"`
 }
 
-type Organization struct {
-	ID               string `json:"id" db:"id"`
-	Name             string `json:"name" db:"name"`
-	Slug             string `json:"slug" db:"slug"`
-	SubscriptionTier string `json:"subscription_tier" db:"subscription_tier"`
-	IsActive         bool   `json:"is_active" db:"is_active"`
-	CreatedAt        string `json:"created_at" db:"created_at"`
-}
+type Organization = repository.Organization
 
-// CreateOrganization handles POST /api/v1/organizations
+// CreateOrganization handles POST /api/v1/organizations. The organization
+// and the caller's owner membership, and with schema isolation its schema,
+// are created together or not at all.
 func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
 	var req CreateOrganizationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
+		return
+	}
+	if !validSlug.MatchString(req.Slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must be lowercase letters, digits and single hyphens"})
 		return
 	}
 
@@ -58,36 +81,42 @@ func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
 	if tier == "" {
 		tier = "free"
 	}
-
-	_, err := h.db.Exec(`
-		INSERT INTO organizations (id, name, slug, subscription_tier)
-		VALUES ($1, $2, $3, $4)
-	`, orgID, req.Name, req.Slug, tier)
-
-	if err != nil {
-		h.logger.Error("Failed to create organization", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+	isolated := req.Isolation == tenancy.IsolationSchema
+	if isolated && h.tenancy == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Schema isolation is not enabled"})
+		return
+	}
+	if isolated && tier != "enterprise" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Schema isolation requires the enterprise tier"})
 		return
 	}
 
-	// Add creator as owner
-	_, err = h.db.Exec(`
-		INSERT INTO organization_memberships (user_id, organization_id, role)
-		VALUES ($1, $2, 'owner')
-	`, userID, orgID)
-
+	ctx := c.Request.Context()
+	org := &Organization{ID: orgID.String(), Name: req.Name, Slug: req.Slug, SubscriptionTier: tier}
+	err := h.uow.Do(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Orgs.Create(ctx, org); err != nil {
+			return err
+		}
+		// Add creator as owner
+		if err := repos.Orgs.SetMember(ctx, userID, org.ID, string(rbac.RoleOwner)); err != nil {
+			return err
+		}
+		if isolated {
+			return h.tenancy.Provision(ctx, repos.Q, org.ID)
+		}
+		return nil
+	})
+	if database.IsUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An organization with this slug already exists"})
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to add owner membership", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create membership"})
+		logging.FromContext(ctx, h.logger).Error("Failed to create organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"id":   orgID.String(),
-		"name": req.Name,
-		"slug": req.Slug,
-		"tier": tier,
-	})
+	c.JSON(http.StatusCreated, org)
 }
 
 // ListOrganizations handles GET /api/v1/organizations
@@ -98,21 +127,9 @@ func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
 		return
 	}
 
-	var orgs []struct {
-		Organization
-		Role string `json:"role" db:"role"`
-	}
-
-	err := h.db.Select(&orgs, `
-		SELECT o.id, o.name, o.slug, o.subscription_tier, o.is_active, o.created_at, om.role
-		FROM organizations o
-		INNER JOIN organization_memberships om ON o.id = om.organization_id
-		WHERE om.user_id = $1
-		ORDER BY o.created_at DESC
-	`, userID)
-
+	orgs, err := h.repos.Orgs.ListForUser(c.Request.Context(), userID)
 	if err != nil {
-		h.logger.Error("Failed to list organizations", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list organizations", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list organizations"})
 		return
 	}
@@ -126,32 +143,21 @@ func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	// Verify user has access to this organization
-	var role string
-	err := h.db.Get(&role, `
-		SELECT role FROM organization_memberships
-		WHERE user_id = $1 AND organization_id = $2
-	`, userID, orgID)
-
-	if err == sql.ErrNoRows {
+	role, err := h.repos.Orgs.MemberRole(c.Request.Context(), userID, orgID)
+	if err == repository.ErrNotFound {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
 	if err != nil {
-		h.logger.Error("Failed to verify membership", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to verify membership", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
 		return
 	}
 
-	var org Organization
-	err = h.db.Get(&org, `
-		SELECT id, name, slug, subscription_tier, is_active, created_at
-		FROM organizations
-		WHERE id = $1
-	`, orgID)
-
+	org, err := h.repos.Orgs.Get(c.Request.Context(), orgID)
 	if err != nil {
-		h.logger.Error("Failed to get organization", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to get organization", zap.Error(err))
 		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
 		return
 	}
@@ -173,44 +179,56 @@ func (h *OrganizationHandler) InviteUser(c *gin.Context) {
 
 	var req InviteUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
-	// Validate role
-	role := rbac.Role(req.Role)
-	if !role.IsValid() {
+	// Validate role (built-in or defined by this organization)
+	valid, err := h.roles.RoleExists(c.Request.Context(), orgID, rbac.Role(req.Role))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to validate role", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate role"})
+		return
+	}
+	if !valid {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
 		return
 	}
 
 	// Find user by email
-	var targetUserID string
-	err := h.db.Get(&targetUserID, "SELECT id FROM users WHERE email = $1", req.Email)
-	if err == sql.ErrNoRows {
+	targetUserID, err := h.repos.Users.IDByEmail(c.Request.Context(), req.Email)
+	if err == repository.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	if err != nil {
-		h.logger.Error("Failed to find user", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to find user", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find user"})
 		return
 	}
 
-	// Add membership
-	_, err = h.db.Exec(`
-		INSERT INTO organization_memberships (user_id, organization_id, role)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, organization_id) DO UPDATE SET role = $3
-	`, targetUserID, orgID, req.Role)
+	// Existing members have their role replaced; the previous one tells
+	// whether that elevated them
+	previousRole, err := h.repos.Orgs.MemberRole(c.Request.Context(), targetUserID, orgID)
+	if err != nil && err != repository.ErrNotFound {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to fetch membership", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite user"})
+		return
+	}
 
-	if err != nil {
-		h.logger.Error("Failed to add membership", zap.Error(err))
+	// Add membership
+	if err := h.repos.Orgs.SetMember(c.Request.Context(), targetUserID, orgID, req.Role); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to add membership", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite user"})
 		return
 	}
 
+	_, err = h.privileges.RoleChanged(c.Request.Context(), c.GetString("user_id"), orgID, targetUserID, rbac.Role(previousRole), rbac.Role(req.Role))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to record privilege change", zap.String("user_id", targetUserID), zap.Error(err))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User invited successfully",
 		"user_id": targetUserID,
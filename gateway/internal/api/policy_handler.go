@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PolicyHandler manages attribute-based access policies
+type PolicyHandler struct {
+	roles       *rbac.RoleStore
+	policies    *rbac.PolicyEngine
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewPolicyHandler(roles *rbac.RoleStore, policies *rbac.PolicyEngine, auditLogger Auditor, logger *zap.Logger) *PolicyHandler {
+	return &PolicyHandler{
+		roles:       roles,
+		policies:    policies,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+type AccessPolicyRequest struct {
+	Name        string          `json:"name" binding:"required,max=100"`
+	Description string          `json:"description"`
+	Roles       []string        `json:"roles"`
+	Permissions []string        `json:"permissions" binding:"required,min=1"`
+	Conditions  rbac.Conditions `json:"conditions"`
+	Enabled     *bool           `json:"enabled"`
+}
+
+// ListPolicies handles GET /api/v1/organizations/:id/policies
+func (h *PolicyHandler) ListPolicies(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewOrganization, h.logger); !ok {
+		return
+	}
+
+	policies, err := h.policies.List(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list access policies", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreatePolicy handles POST /api/v1/organizations/:id/policies
+func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req AccessPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	policy, err := h.policies.Create(c.Request.Context(), rbac.AccessPolicy{
+		OrganizationID: orgID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Roles:          req.Roles,
+		Permissions:    req.Permissions,
+		Conditions:     req.Conditions,
+		Enabled:        enabled,
+		CreatedBy:      &userID,
+	})
+	switch {
+	case err == nil:
+	case err == rbac.ErrPolicyExists:
+		c.JSON(http.StatusConflict, gin.H{"error": "A policy with this name already exists"})
+		return
+	case errors.Is(err, rbac.ErrUnknownPermission), errors.Is(err, rbac.ErrInvalidConditions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to create access policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create policy"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "access_policy_created", "access_policy", policy.ID, map[string]interface{}{
+		"organization_id": orgID,
+		"name":            policy.Name,
+		"roles":           req.Roles,
+		"permissions":     req.Permissions,
+		"conditions":      req.Conditions,
+	})
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// DeletePolicy handles DELETE /api/v1/organizations/:id/policies/:policy_id
+func (h *PolicyHandler) DeletePolicy(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	policyID := c.Param("policy_id")
+	err := h.policies.Delete(c.Request.Context(), orgID, policyID)
+	if err == rbac.ErrPolicyNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete access policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete policy"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "access_policy_deleted", "access_policy", policyID, map[string]interface{}{
+		"organization_id": orgID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Policy deleted"})
+}
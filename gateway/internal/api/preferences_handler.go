@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/preferences"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type PreferencesHandler struct {
+	prefs  *preferences.Service
+	logger *zap.Logger
+}
+
+func NewPreferencesHandler(prefs *preferences.Service, logger *zap.Logger) *PreferencesHandler {
+	return &PreferencesHandler{
+		prefs:  prefs,
+		logger: logger,
+	}
+}
+
+// UpdatePreferencesRequest documents PATCH /users/me/preferences: keys left
+// out are unchanged, and null resets a key to its default
+type UpdatePreferencesRequest struct {
+	Timezone              *string                `json:"timezone,omitempty"`
+	Locale                *string                `json:"locale,omitempty"`
+	DefaultOrganizationID *string                `json:"default_organization_id,omitempty"`
+	NotificationDigest    *string                `json:"notification_digest,omitempty"` // off, daily or weekly
+	DashboardLayout       map[string]interface{} `json:"dashboard_layout,omitempty"`
+}
+
+// GetPreferences handles GET /api/v1/users/me/preferences
+func (h *PreferencesHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.prefs.Get(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load preferences", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences handles PATCH /api/v1/users/me/preferences. Either every
+// key in the body is saved or, when one fails validation, none is.
+func (h *PreferencesHandler) UpdatePreferences(c *gin.Context) {
+	var changes map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&changes); err != nil {
+		bindError(c, err)
+		return
+	}
+	if len(changes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No preferences given", "keys": preferences.Keys()})
+		return
+	}
+
+	prefs, err := h.prefs.Update(c.Request.Context(), c.GetString("user_id"), changes)
+	var invalid *preferences.ValidationError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid preferences", "fields": invalid.Fields})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to update preferences", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
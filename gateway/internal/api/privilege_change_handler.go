@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/privileges"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PrivilegeChangeHandler lists the recorded role elevations, custom role
+// grants and API token scope expansions of an organization
+type PrivilegeChangeHandler struct {
+	privileges *privileges.Service
+	roles      *rbac.RoleStore
+	logger     *zap.Logger
+}
+
+func NewPrivilegeChangeHandler(privilegeService *privileges.Service, roles *rbac.RoleStore, logger *zap.Logger) *PrivilegeChangeHandler {
+	return &PrivilegeChangeHandler{
+		privileges: privilegeService,
+		roles:      roles,
+		logger:     logger,
+	}
+}
+
+// PrivilegeChangesResponse lists privilege changes, newest first
+type PrivilegeChangesResponse struct {
+	Changes []privileges.Change `json:"changes"`
+	Count   int                 `json:"count"`
+}
+
+// ListPrivilegeChanges handles GET /api/v1/audit/privilege-changes
+func (h *PrivilegeChangeHandler) ListPrivilegeChanges(c *gin.Context) {
+	orgID := c.DefaultQuery("organization_id", c.GetString("organization_id"))
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "organization_id required"})
+		return
+	}
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewAuditLogs, h.logger); !ok {
+		return
+	}
+
+	filter := privileges.Filter{
+		OrganizationID: orgID,
+		Kind:           c.Query("kind"),
+		SubjectID:      c.Query("subject_id"),
+		Limit:          100,
+	}
+	switch filter.Kind {
+	case "", privileges.KindRoleElevated, privileges.KindCustomRoleExpanded, privileges.KindTokenScopeExpanded:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid kind"})
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		filter.Limit = n
+	}
+	for _, bound := range []struct {
+		name string
+		dest **time.Time
+	}{{"start_time", &filter.Start}, {"end_time", &filter.End}} {
+		if v := c.Query(bound.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + bound.name + " format"})
+				return
+			}
+			*bound.dest = &t
+		}
+	}
+
+	changes, err := h.privileges.List(c.Request.Context(), filter)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list privilege changes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list privilege changes"})
+		return
+	}
+	c.JSON(http.StatusOK, PrivilegeChangesResponse{Changes: changes, Count: len(changes)})
+}
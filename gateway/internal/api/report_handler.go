@@ -1,53 +1,339 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
+	"github.com/cyper-security/gateway/internal/audit"
 	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/downloads"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/reports"
+	"github.com/cyper-security/gateway/internal/storage"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
 type ReportHandler struct {
-	brainClient *brain.Client
+	db          *database.DB
+	reports     *reports.Service
+	store       storage.Store
+	policies    *rbac.PolicyEngine
+	downloads   *downloads.Service
+	watermarker *brain.Client // Optional; stamps downloaded PDFs
+	auditLogger Auditor
 	logger      *zap.Logger
 }
 
-func NewReportHandler(brainClient *brain.Client, logger *zap.Logger) *ReportHandler {
+func NewReportHandler(db *database.DB, reportService *reports.Service, store storage.Store, policies *rbac.PolicyEngine, downloadService *downloads.Service, auditLogger Auditor, logger *zap.Logger) *ReportHandler {
 	return &ReportHandler{
-		brainClient: brainClient,
+		db:          db,
+		reports:     reportService,
+		store:       store,
+		policies:    policies,
+		downloads:   downloadService,
+		auditLogger: auditLogger,
 		logger:      logger,
 	}
 }
 
+// SetWatermarker stamps every downloaded PDF with the downloader's identity
+// through the brain service; downloads fail while it cannot be reached
+func (h *ReportHandler) SetWatermarker(watermarker *brain.Client) {
+	h.watermarker = watermarker
+}
+
 // GenerateReport handles POST /api/v1/scans/:id/report
 func (h *ReportHandler) GenerateReport(c *gin.Context) {
 	scanID := c.Param("id")
+	orgID := c.GetString("organization_id")
 
-	// In a real app, we would fetch scan results from DB here.
-	// For MVP, we allow passing context/mock data or just assuming fetch works.
-	// But the user request said "Connect ResultsAnalyzer output".
+	if !authorizeScanPolicy(c, h.db, h.policies, scanID, rbac.PermGenerateReport, h.logger) {
+		return
+	}
 
-	// For now, let's accept the analysis/results in the body to forward to Brain
-	// This makes it easier to test without a full DB population.
+	// The body may carry scan results and analysis to forward to the brain;
+	// without it the stored findings are used
 	var req brain.GenerateReportRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		bindError(c, err)
+		return
+	}
+
+	// The query parameter wins over the body so download links can pick a format
+	req.Format = c.DefaultQuery("format", req.Format)
+
+	report, err := h.reports.Generate(c.Request.Context(), reports.GenerateParams{
+		ScanID:            scanID,
+		OrgID:             orgID,
+		UserID:            c.GetString("user_id"),
+		TemplateID:        c.Query("template_id"),
+		Source:            reports.SourceManual,
+		IncludeSuppressed: c.Query("include_suppressed") == "true",
+		Request:           req,
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, report)
+	case err == reports.ErrUnknownFormat:
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err == reports.ErrScanNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scan not found"})
+	case err == reports.ErrTemplateNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report template not found"})
+	case errors.Is(err, reports.ErrBackendUnavailable):
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to generate report", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to generate report"})
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to generate report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate report"})
+	}
+}
+
+// authorizeScanPolicy evaluates the organization's access policies for perm
+// against a scan of the caller's organization, resolving the scan's target
+// tags and type. It writes the error response and returns false when the scan
+// is missing or access is denied.
+func authorizeScanPolicy(c *gin.Context, db *database.DB, policies *rbac.PolicyEngine, scanID string, perm rbac.Permission, logger *zap.Logger) bool {
+	orgID := c.GetString("organization_id")
+
+	var scan struct {
+		ScanType string         `db:"scan_type"`
+		Tags     pq.StringArray `db:"tags"`
+	}
+	err := db.GetContext(c.Request.Context(), &scan, `
+		SELECT sj.scan_type, COALESCE(at.tags, '{}') AS tags
+		FROM scan_jobs sj
+		LEFT JOIN authorized_targets at ON at.id = sj.authorization_target_id
+		WHERE sj.id = $1 AND sj.organization_id IS NOT DISTINCT FROM NULLIF($2, '')::uuid
+	`, scanID, orgID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scan not found"})
+		return false
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), logger).Error("Failed to load scan", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scan"})
+		return false
+	}
+
+	decision, err := policies.Evaluate(c.Request.Context(), orgID, rbac.Role(c.GetString("user_role")), perm, rbac.Attributes{
+		TargetTags: scan.Tags,
+		ScanType:   scan.ScanType,
+	})
+	if err != nil {
+		logging.FromContext(c.Request.Context(), logger).Error("Failed to evaluate access policies", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check access policies"})
+		return false
+	}
+	if !decision.Allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Denied by access policy", "reason": decision.Reason})
+		return false
+	}
+	return true
+}
+
+// ListReports handles GET /api/v1/reports
+func (h *ReportHandler) ListReports(c *gin.Context) {
+	source := c.Query("source")
+	if source != "" && source != reports.SourceManual && source != reports.SourceScheduled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source must be 'manual' or 'scheduled'"})
 		return
 	}
 
-	// Inject metadata if not present
-	if req.Metadata == nil {
-		req.Metadata = make(map[string]interface{})
+	list := []reports.Report{}
+	err := h.db.Reader().SelectContext(c.Request.Context(), &list, `
+		SELECT id, scan_job_id, report_type, format, content_type, source, schedule_id,
+		       generated_at, delivered_at, delivery_error
+		FROM reports
+		WHERE organization_id IS NOT DISTINCT FROM NULLIF($1, '')::uuid
+		AND ($2 = '' OR scan_job_id::text = $2)
+		AND ($3 = '' OR source = $3)
+		AND ($4 = '' OR schedule_id::text = $4)
+		ORDER BY generated_at DESC
+		LIMIT 200
+	`, c.GetString("organization_id"), c.Query("scan_id"), source, c.Query("schedule_id"))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list reports", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list reports"})
+		return
+	}
+	for i := range list {
+		list[i].DownloadURL = reports.DownloadPath(list[i].ID)
 	}
-	req.Metadata["scan_id"] = scanID
 
-	resp, err := h.brainClient.GenerateReport(req)
+	c.JSON(http.StatusOK, list)
+}
+
+// DownloadReport handles GET /api/v1/reports/:id/download. Every download
+// is recorded and audited with its size; PDFs are stamped with the
+// downloader's identity when watermarking is on.
+func (h *ReportHandler) DownloadReport(c *gin.Context) {
+	ctx := c.Request.Context()
+	reportID := c.Param("id")
+
+	var stored storedReport
+	err := h.db.GetContext(ctx, &stored, `
+		SELECT format, content_type, content, file_data, storage_key FROM reports
+		WHERE id = $1 AND organization_id IS NOT DISTINCT FROM NULLIF($2, '')::uuid
+	`, reportID, c.GetString("organization_id"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to generate report", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate report"})
+		logging.FromContext(ctx, h.logger).Error("Failed to load report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load report"})
+		return
+	}
+
+	body, size, err := h.openReport(ctx, &stored)
+	if err == storage.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report file no longer available"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to open report file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load report"})
+		return
+	}
+	defer body.Close()
+
+	download := &downloads.Download{
+		ID:             uuid.NewString(),
+		OrganizationID: c.GetString("organization_id"),
+		UserID:         c.GetString("user_id"),
+		ArtifactType:   downloads.ArtifactReport,
+		ArtifactID:     reportID,
+		Format:         stored.Format,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	}
+
+	if stored.Format == brain.FormatPDF && h.watermarker != nil {
+		data, err := io.ReadAll(body)
+		if err == nil {
+			data, err = h.watermarker.WatermarkPDF(ctx, data, brain.Watermark{
+				DownloadID:     download.ID,
+				UserID:         download.UserID,
+				Email:          c.GetString("email"),
+				OrganizationID: download.OrganizationID,
+				IPAddress:      download.IPAddress,
+				DownloadedAt:   time.Now().UTC(),
+			})
+		}
+		if err != nil {
+			logging.FromContext(ctx, h.logger).Error("Failed to watermark report", zap.String("report_id", reportID), zap.Error(err))
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to watermark report"})
+			return
+		}
+		body, size = io.NopCloser(bytes.NewReader(data)), int64(len(data))
+		download.Watermarked = true
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="report-%s.%s"`, reportID, reports.Extensions[stored.Format]))
+	c.DataFromReader(http.StatusOK, size, stored.ContentType, body, nil)
+	h.recordDownload(c, download)
+}
+
+type storedReport struct {
+	Format      string  `db:"format"`
+	ContentType string  `db:"content_type"`
+	Content     *string `db:"content"`
+	FileData    []byte  `db:"file_data"`
+	StorageKey  *string `db:"storage_key"`
+}
+
+// openReport returns a report's file and its size, -1 when unknown. Files in
+// artifact storage are streamed through rather than buffered.
+func (h *ReportHandler) openReport(ctx context.Context, stored *storedReport) (io.ReadCloser, int64, error) {
+	if stored.StorageKey != nil {
+		file, err := h.store.Open(ctx, *stored.StorageKey)
+		return file, -1, err
+	}
+
+	data := stored.FileData
+	if data == nil && stored.Content != nil {
+		data = []byte(*stored.Content)
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+// recordDownload records and audits a download once it has been served;
+// failures are only logged since the file has already gone out
+func (h *ReportHandler) recordDownload(c *gin.Context, download *downloads.Download) {
+	download.Bytes = int64(max(c.Writer.Size(), 0))
+	download.Status = downloads.StatusCompleted
+	if c.Request.Context().Err() != nil {
+		download.Status = downloads.StatusIncomplete
+	}
+
+	// The request context is cancelled when the client goes away mid-transfer
+	ctx := context.WithoutCancel(c.Request.Context())
+	if err := h.downloads.Record(ctx, download); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to record report download", zap.Error(err))
+	}
+	h.auditLogger.Log(ctx, audit.LogParams{
+		UserID:       download.UserID,
+		Action:       "report_downloaded",
+		ResourceType: "report",
+		ResourceID:   download.ArtifactID,
+		Target:       download.ArtifactID,
+		IPAddress:    download.IPAddress,
+		UserAgent:    download.UserAgent,
+		Severity:     "low",
+		Details: map[string]interface{}{
+			"download_id": download.ID,
+			"format":      download.Format,
+			"bytes":       download.Bytes,
+			"status":      download.Status,
+			"watermarked": download.Watermarked,
+		},
+	})
+}
+
+// ListReportDownloads handles GET /api/v1/reports/:id/downloads: who
+// downloaded the report, when, from where and how much was served
+func (h *ReportHandler) ListReportDownloads(c *gin.Context) {
+	ctx := c.Request.Context()
+	reportID := c.Param("id")
+	if _, err := uuid.Parse(reportID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	var exists bool
+	err := h.db.Reader().GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM reports
+			WHERE id = $1 AND organization_id IS NOT DISTINCT FROM NULLIF($2, '')::uuid
+		)
+	`, reportID, c.GetString("organization_id"))
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load report"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+
+	list, err := h.downloads.List(ctx, downloads.ArtifactReport, reportID, 500)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to list report downloads", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list downloads"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
 }
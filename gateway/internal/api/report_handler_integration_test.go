@@ -0,0 +1,130 @@
+//go:build integration
+
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/api"
+	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/mocks"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/reports"
+	"github.com/cyper-security/gateway/internal/storage"
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+// reportBackend renders markdown and HTML, failing with err when it is set
+func reportBackend(err error) *mocks.ReportBackendMock {
+	return &mocks.ReportBackendMock{
+		SupportsFunc: func(format string) bool {
+			return format == brain.FormatMarkdown || format == brain.FormatHTML
+		},
+		GenerateReportFunc: func(ctx context.Context, req brain.GenerateReportRequest) (*reports.Rendered, error) {
+			if err != nil {
+				return nil, err
+			}
+			return &reports.Rendered{Content: "# " + req.ReportType}, nil
+		},
+		HealthFunc: func(context.Context) error { return err },
+	}
+}
+
+func TestGenerateReport(t *testing.T) {
+	brainDown := errors.New("brain: connection refused")
+	// Executive summaries are rendered locally, everything else by the brain
+	routes := map[string]string{"executive": reports.BackendLocal}
+
+	tests := []struct {
+		name     string
+		remote   error // Brain backend error
+		local    error // Local backend error
+		fallback string
+		path     string // After /scans/<id>/report
+		body     string
+		status   int
+		backend  string // Expected renderer on success
+	}{
+		{name: "rendered remotely", path: "", status: http.StatusCreated, backend: reports.BackendBrain},
+		{name: "routed to the local backend", body: `{"report_type":"executive"}`, status: http.StatusCreated, backend: reports.BackendLocal},
+		{name: "remote down, local fallback", remote: brainDown, fallback: reports.BackendLocal, status: http.StatusCreated, backend: reports.BackendLocal},
+		{name: "remote down without fallback", remote: brainDown, status: http.StatusBadGateway},
+		{name: "both down", remote: brainDown, local: errors.New("render failed"), fallback: reports.BackendLocal, status: http.StatusBadGateway},
+		{name: "format no backend renders", path: "?format=pdf", status: http.StatusBadGateway},
+		{name: "unknown format", path: "?format=docx", status: http.StatusBadRequest},
+		{name: "unknown template", path: "?template_id=5f0c6a43-6f0e-4d53-9d5b-0d3a2f1b7c11", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testenv.Setup(t)
+			org := env.CreateOrganization(t, "")
+			user := env.CreateUser(t, testenv.UserOptions{OrgID: org.ID})
+			env.AddMember(t, user.ID, org.ID, rbac.RoleOwner)
+			scanID := env.CreateScan(t, user.ID, org.ID)
+
+			remote, local := reportBackend(tt.remote), reportBackend(tt.local)
+			router, err := reports.NewRouter(map[string]reports.ReportBackend{
+				reports.BackendBrain: remote,
+				reports.BackendLocal: local,
+			}, reports.RouterConfig{Default: reports.BackendBrain, Routes: routes, Fallback: tt.fallback}, nil, env.Logger)
+			if err != nil {
+				t.Fatalf("NewRouter: %v", err)
+			}
+			store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080", []byte("testenv-storage-secret"))
+			if err != nil {
+				t.Fatalf("NewLocal: %v", err)
+			}
+			service := reports.NewService(env.DB, router, store, env.Logger)
+			h := api.NewReportHandler(env.DB, service, store, rbac.NewPolicyEngine(env.DB, env.Logger), nil, newAuditor(), env.Logger)
+
+			rec := serve(h.GenerateReport, request{
+				method: http.MethodPost,
+				route:  "/scans/:id/report",
+				path:   "/scans/" + scanID + "/report" + tt.path,
+				body:   tt.body,
+				keys:   map[string]string{"user_id": user.ID, "organization_id": org.ID, "user_role": string(rbac.RoleOwner)},
+			})
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.status, rec.Body)
+			}
+			if tt.backend == "" {
+				return
+			}
+			if got := decode(t, rec)["backend"]; got != tt.backend {
+				t.Errorf("rendered by %v, want %s", got, tt.backend)
+			}
+		})
+	}
+}
+
+func TestGenerateReportForOtherOrganization(t *testing.T) {
+	env := testenv.Setup(t)
+	org, other := env.CreateOrganization(t, ""), env.CreateOrganization(t, "")
+	user := env.CreateUser(t, testenv.UserOptions{OrgID: org.ID})
+	env.AddMember(t, user.ID, org.ID, rbac.RoleOwner)
+	scanID := env.CreateScan(t, user.ID, other.ID)
+
+	remote := reportBackend(nil)
+	router, err := reports.NewRouter(map[string]reports.ReportBackend{reports.BackendBrain: remote}, reports.DefaultRouterConfig(), nil, env.Logger)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	service := reports.NewService(env.DB, router, nil, env.Logger)
+	h := api.NewReportHandler(env.DB, service, nil, rbac.NewPolicyEngine(env.DB, env.Logger), nil, newAuditor(), env.Logger)
+
+	rec := serve(h.GenerateReport, request{
+		method: http.MethodPost,
+		route:  "/scans/:id/report",
+		path:   "/scans/" + scanID + "/report",
+		keys:   map[string]string{"user_id": user.ID, "organization_id": org.ID, "user_role": string(rbac.RoleOwner)},
+	})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+	if n := len(remote.GenerateReportCalls()); n != 0 {
+		t.Errorf("backend called %d times for another organization's scan", n)
+	}
+}
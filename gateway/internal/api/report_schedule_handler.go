@@ -0,0 +1,243 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/reports"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+type ReportScheduleRequest struct {
+	Name            string   `json:"name" binding:"required,max=100"`
+	Frequency       string   `json:"frequency" binding:"required,oneof=weekly monthly"`
+	DayOfWeek       int      `json:"day_of_week" binding:"min=0,max=6"`   // weekly; 0 = Sunday
+	DayOfMonth      int      `json:"day_of_month" binding:"min=0,max=28"` // monthly; 0 defaults to 1
+	HourUTC         int      `json:"hour_utc" binding:"min=0,max=23"`
+	TargetValues    []string `json:"target_values"`
+	ScanTypes       []string `json:"scan_types"`
+	ReportType      string   `json:"report_type" binding:"omitempty,max=50"`
+	Format          string   `json:"format" binding:"omitempty,oneof=markdown html pdf sarif"`
+	TemplateID      string   `json:"template_id" binding:"omitempty,uuid"`
+	EmailRecipients []string `json:"email_recipients" binding:"max=50,dive,email"`
+	WebhookURL      string   `json:"webhook_url" binding:"omitempty,url"`
+	WebhookSecret   string   `json:"webhook_secret" binding:"max=200"`
+	Enabled         *bool    `json:"enabled"`
+}
+
+// schedule applies defaults and computes the first run
+func (r *ReportScheduleRequest) schedule(now time.Time) *reports.Schedule {
+	s := &reports.Schedule{
+		Name:            r.Name,
+		Frequency:       r.Frequency,
+		DayOfWeek:       r.DayOfWeek,
+		DayOfMonth:      r.DayOfMonth,
+		HourUTC:         r.HourUTC,
+		TargetValues:    pq.StringArray(r.TargetValues),
+		ScanTypes:       pq.StringArray(r.ScanTypes),
+		ReportType:      r.ReportType,
+		Format:          r.Format,
+		EmailRecipients: pq.StringArray(r.EmailRecipients),
+		Enabled:         r.Enabled == nil || *r.Enabled,
+	}
+	if s.DayOfMonth == 0 {
+		s.DayOfMonth = 1
+	}
+	if s.ReportType == "" {
+		s.ReportType = "executive"
+	}
+	if s.Format == "" {
+		s.Format = "pdf"
+	}
+	if s.TargetValues == nil {
+		s.TargetValues = pq.StringArray{}
+	}
+	if s.ScanTypes == nil {
+		s.ScanTypes = pq.StringArray{}
+	}
+	if s.EmailRecipients == nil {
+		s.EmailRecipients = pq.StringArray{}
+	}
+	s.NextRunAt = s.NextRun(now)
+	return s
+}
+
+type ReportScheduleHandler struct {
+	db          *database.DB
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewReportScheduleHandler(db *database.DB, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *ReportScheduleHandler {
+	return &ReportScheduleHandler{
+		db:          db,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// ListSchedules handles GET /api/v1/organizations/:id/report-schedules
+func (h *ReportScheduleHandler) ListSchedules(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewReport, h.logger); !ok {
+		return
+	}
+
+	schedules := []reports.Schedule{}
+	err := h.db.SelectContext(c.Request.Context(), &schedules, `
+		SELECT `+reports.ScheduleColumns+` FROM report_schedules
+		WHERE organization_id = $1
+		ORDER BY name
+	`, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list report schedules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list report schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// CreateSchedule handles POST /api/v1/organizations/:id/report-schedules
+func (h *ReportScheduleHandler) CreateSchedule(c *gin.Context) {
+	h.saveSchedule(c, "")
+}
+
+// UpdateSchedule handles PUT /api/v1/organizations/:id/report-schedules/:schedule_id
+func (h *ReportScheduleHandler) UpdateSchedule(c *gin.Context) {
+	h.saveSchedule(c, c.Param("schedule_id"))
+}
+
+// saveSchedule creates (scheduleID empty) or replaces a schedule. Saving
+// recomputes the next run from the new cadence.
+func (h *ReportScheduleHandler) saveSchedule(c *gin.Context, scheduleID string) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageReportSchedules, h.logger)
+	if !ok {
+		return
+	}
+
+	var req ReportScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if len(req.EmailRecipients) == 0 && req.WebhookURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one email recipient or a webhook_url is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.TemplateID != "" {
+		var exists bool
+		err := h.db.GetContext(ctx, &exists, `
+			SELECT EXISTS(SELECT 1 FROM report_templates WHERE id = $1 AND organization_id = $2)
+		`, req.TemplateID, orgID)
+		if err != nil {
+			logging.FromContext(c.Request.Context(), h.logger).Error("Failed to check report template", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report schedule"})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Report template not found"})
+			return
+		}
+	}
+
+	s := req.schedule(time.Now())
+
+	var err error
+	var schedule reports.Schedule
+	if scheduleID == "" {
+		err = h.db.GetContext(ctx, &schedule, `
+			INSERT INTO report_schedules (
+				organization_id, name, frequency, day_of_week, day_of_month, hour_utc,
+				target_values, scan_types, report_type, format, template_id,
+				email_recipients, webhook_url, webhook_secret, enabled, next_run_at, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, '')::uuid,
+				$12, NULLIF($13, ''), NULLIF($14, ''), $15, $16, $17)
+			RETURNING `+reports.ScheduleColumns,
+			orgID, s.Name, s.Frequency, s.DayOfWeek, s.DayOfMonth, s.HourUTC,
+			s.TargetValues, s.ScanTypes, s.ReportType, s.Format, req.TemplateID,
+			s.EmailRecipients, req.WebhookURL, req.WebhookSecret, s.Enabled, s.NextRunAt, userID)
+	} else {
+		err = h.db.GetContext(ctx, &schedule, `
+			UPDATE report_schedules
+			SET name = $3, frequency = $4, day_of_week = $5, day_of_month = $6, hour_utc = $7,
+			    target_values = $8, scan_types = $9, report_type = $10, format = $11,
+			    template_id = NULLIF($12, '')::uuid, email_recipients = $13,
+			    webhook_url = NULLIF($14, ''), webhook_secret = NULLIF($15, ''),
+			    enabled = $16, next_run_at = $17
+			WHERE id = $1 AND organization_id = $2
+			RETURNING `+reports.ScheduleColumns,
+			scheduleID, orgID, s.Name, s.Frequency, s.DayOfWeek, s.DayOfMonth, s.HourUTC,
+			s.TargetValues, s.ScanTypes, s.ReportType, s.Format,
+			req.TemplateID, s.EmailRecipients,
+			req.WebhookURL, req.WebhookSecret,
+			s.Enabled, s.NextRunAt)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report schedule not found"})
+		return
+	}
+	if database.IsUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A report schedule with this name already exists"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to save report schedule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report schedule"})
+		return
+	}
+
+	action, status := "report_schedule_updated", http.StatusOK
+	if scheduleID == "" {
+		action, status = "report_schedule_created", http.StatusCreated
+	}
+	h.auditLogger.LogSuccess(ctx, userID, action, "report_schedule", schedule.ID, map[string]interface{}{
+		"organization_id": orgID,
+		"name":            schedule.Name,
+		"frequency":       schedule.Frequency,
+		"recipients":      len(schedule.EmailRecipients),
+		"webhook":         schedule.WebhookURL != nil,
+	})
+
+	c.JSON(status, schedule)
+}
+
+// DeleteSchedule handles DELETE /api/v1/organizations/:id/report-schedules/:schedule_id
+func (h *ReportScheduleHandler) DeleteSchedule(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageReportSchedules, h.logger)
+	if !ok {
+		return
+	}
+
+	scheduleID := c.Param("schedule_id")
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		DELETE FROM report_schedules WHERE id = $1 AND organization_id = $2
+	`, scheduleID, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete report schedule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete report schedule"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report schedule not found"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "report_schedule_deleted", "report_schedule", scheduleID, map[string]interface{}{
+		"organization_id": orgID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report schedule deleted"})
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/reports"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+type ReportTemplateRequest struct {
+	Name               string                     `json:"name" binding:"required,max=100"`
+	LogoURL            string                     `json:"logo_url" binding:"omitempty,url"`
+	Sections           []string                   `json:"sections" binding:"required,min=1,dive,oneof=executive_summary risk_overview findings remediation methodology compliance appendix"`
+	ExecutiveSummary   string                     `json:"executive_summary" binding:"max=10000"`
+	MinSeverity        string                     `json:"min_severity" binding:"omitempty,oneof=critical high medium low info"`
+	SeverityThresholds reports.SeverityThresholds `json:"severity_thresholds"`
+	IsDefault          bool                       `json:"is_default"`
+}
+
+type ReportTemplateHandler struct {
+	db          *database.DB
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewReportTemplateHandler(db *database.DB, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *ReportTemplateHandler {
+	return &ReportTemplateHandler{
+		db:          db,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// ListTemplates handles GET /api/v1/organizations/:id/report-templates
+func (h *ReportTemplateHandler) ListTemplates(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewReport, h.logger); !ok {
+		return
+	}
+
+	templates := []reports.ReportTemplate{}
+	err := h.db.SelectContext(c.Request.Context(), &templates, `
+		SELECT `+reports.TemplateColumns+` FROM report_templates
+		WHERE organization_id = $1
+		ORDER BY is_default DESC, name
+	`, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list report templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list report templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates":       templates,
+		"builtin_default": reports.DefaultTemplate,
+	})
+}
+
+// GetTemplate handles GET /api/v1/organizations/:id/report-templates/:template_id
+func (h *ReportTemplateHandler) GetTemplate(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewReport, h.logger); !ok {
+		return
+	}
+
+	var template reports.ReportTemplate
+	err := h.db.GetContext(c.Request.Context(), &template, `
+		SELECT `+reports.TemplateColumns+` FROM report_templates
+		WHERE id = $1 AND organization_id = $2
+	`, c.Param("template_id"), orgID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report template not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to get report template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get report template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// CreateTemplate handles POST /api/v1/organizations/:id/report-templates
+func (h *ReportTemplateHandler) CreateTemplate(c *gin.Context) {
+	h.saveTemplate(c, "")
+}
+
+// UpdateTemplate handles PUT /api/v1/organizations/:id/report-templates/:template_id
+func (h *ReportTemplateHandler) UpdateTemplate(c *gin.Context) {
+	h.saveTemplate(c, c.Param("template_id"))
+}
+
+// saveTemplate creates (templateID empty) or replaces a template
+func (h *ReportTemplateHandler) saveTemplate(c *gin.Context, templateID string) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageReportTemplates, h.logger)
+	if !ok {
+		return
+	}
+
+	var req ReportTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.MinSeverity == "" {
+		req.MinSeverity = "info"
+	}
+	for severity := range req.SeverityThresholds {
+		if _, ok := reports.DefaultTemplate.SeverityThresholds[severity]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown severity in severity_thresholds: " + severity})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to begin transaction", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report template"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Only one default per organization
+	if req.IsDefault {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE report_templates SET is_default = false
+			WHERE organization_id = $1 AND is_default AND id::text <> $2
+		`, orgID, templateID)
+		if err != nil {
+			logging.FromContext(c.Request.Context(), h.logger).Error("Failed to clear default report template", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report template"})
+			return
+		}
+	}
+
+	var template reports.ReportTemplate
+	if templateID == "" {
+		err = tx.GetContext(ctx, &template, `
+			INSERT INTO report_templates (
+				organization_id, name, logo_url, sections, executive_summary,
+				min_severity, severity_thresholds, is_default, created_by
+			) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9)
+			RETURNING `+reports.TemplateColumns,
+			orgID, req.Name, req.LogoURL, pq.Array(req.Sections), req.ExecutiveSummary,
+			req.MinSeverity, req.SeverityThresholds, req.IsDefault, userID)
+	} else {
+		err = tx.GetContext(ctx, &template, `
+			UPDATE report_templates
+			SET name = $3, logo_url = NULLIF($4, ''), sections = $5, executive_summary = $6,
+			    min_severity = $7, severity_thresholds = $8, is_default = $9
+			WHERE id = $1 AND organization_id = $2
+			RETURNING `+reports.TemplateColumns,
+			templateID, orgID, req.Name, req.LogoURL, pq.Array(req.Sections), req.ExecutiveSummary,
+			req.MinSeverity, req.SeverityThresholds, req.IsDefault)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report template not found"})
+		return
+	}
+	if database.IsUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A report template with this name already exists"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to save report template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report template"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to commit report template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save report template"})
+		return
+	}
+
+	action, status := "report_template_updated", http.StatusOK
+	if templateID == "" {
+		action, status = "report_template_created", http.StatusCreated
+	}
+	h.auditLogger.LogSuccess(ctx, userID, action, "report_template", template.ID, map[string]interface{}{
+		"organization_id": orgID,
+		"name":            template.Name,
+		"is_default":      template.IsDefault,
+	})
+
+	c.JSON(status, template)
+}
+
+// DeleteTemplate handles DELETE /api/v1/organizations/:id/report-templates/:template_id
+func (h *ReportTemplateHandler) DeleteTemplate(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageReportTemplates, h.logger)
+	if !ok {
+		return
+	}
+
+	templateID := c.Param("template_id")
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		DELETE FROM report_templates WHERE id = $1 AND organization_id = $2
+	`, templateID, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete report template", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete report template"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report template not found"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "report_template_deleted", "report_template", templateID, map[string]interface{}{
+		"organization_id": orgID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report template deleted"})
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/privileges"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RoleHandler manages organization-defined roles
+type RoleHandler struct {
+	roles       *rbac.RoleStore
+	privileges  *privileges.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewRoleHandler(roles *rbac.RoleStore, privilegeService *privileges.Service, auditLogger Auditor, logger *zap.Logger) *RoleHandler {
+	return &RoleHandler{
+		roles:       roles,
+		privileges:  privilegeService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+type CustomRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+type UpdateCustomRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+type BuiltInRole struct {
+	Name        string            `json:"name"`
+	Permissions []rbac.Permission `json:"permissions"`
+}
+
+type RolesResponse struct {
+	BuiltIn     []BuiltInRole     `json:"built_in"`
+	Custom      []rbac.CustomRole `json:"custom"`
+	Permissions []rbac.Permission `json:"permissions"`
+}
+
+// ListRoles handles GET /api/v1/organizations/:id/roles
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := h.authorize(c, orgID, rbac.PermViewOrganization); !ok {
+		return
+	}
+
+	custom, err := h.roles.List(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list roles", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list roles"})
+		return
+	}
+
+	builtIn := make([]BuiltInRole, 0, 4)
+	for _, role := range []rbac.Role{rbac.RoleOwner, rbac.RoleAdmin, rbac.RoleScanner, rbac.RoleViewer} {
+		builtIn = append(builtIn, BuiltInRole{Name: string(role), Permissions: role.GetPermissions()})
+	}
+
+	c.JSON(http.StatusOK, RolesResponse{
+		BuiltIn:     builtIn,
+		Custom:      custom,
+		Permissions: rbac.AllPermissions(),
+	})
+}
+
+// CreateRole handles POST /api/v1/organizations/:id/roles
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := h.authorize(c, orgID, rbac.PermManageOrganization)
+	if !ok {
+		return
+	}
+
+	var req CustomRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	role, err := h.roles.Create(c.Request.Context(), orgID, userID, req.Name, req.Description, req.Permissions)
+	if err != nil {
+		h.respondRoleError(c, err, "Failed to create role")
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "custom_role_created", "organization_role", role.ID, map[string]interface{}{
+		"organization_id": orgID,
+		"name":            role.Name,
+		"permissions":     req.Permissions,
+	})
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole handles PUT /api/v1/organizations/:id/roles/:role_id
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := h.authorize(c, orgID, rbac.PermManageOrganization)
+	if !ok {
+		return
+	}
+
+	var req UpdateCustomRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	previous, err := h.roles.Get(ctx, orgID, c.Param("role_id"))
+	if err != nil {
+		h.respondRoleError(c, err, "Failed to update role")
+		return
+	}
+	role, err := h.roles.Update(ctx, orgID, previous.ID, req.Description, req.Permissions)
+	if err != nil {
+		h.respondRoleError(c, err, "Failed to update role")
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "custom_role_updated", "organization_role", role.ID, map[string]interface{}{
+		"organization_id": orgID,
+		"name":            role.Name,
+		"permissions":     req.Permissions,
+	})
+	if _, err := h.privileges.CustomRoleChanged(ctx, userID, previous, role); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to record privilege change", zap.String("role_id", role.ID), zap.Error(err))
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole handles DELETE /api/v1/organizations/:id/roles/:role_id
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := h.authorize(c, orgID, rbac.PermManageOrganization)
+	if !ok {
+		return
+	}
+
+	roleID := c.Param("role_id")
+	if err := h.roles.Delete(c.Request.Context(), orgID, roleID); err != nil {
+		h.respondRoleError(c, err, "Failed to delete role")
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "custom_role_deleted", "organization_role", roleID, map[string]interface{}{
+		"organization_id": orgID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+// authorize checks the caller's permission in the organization named by the path
+func (h *RoleHandler) authorize(c *gin.Context, orgID string, perm rbac.Permission) (string, bool) {
+	return authorizeOrgMember(c, h.roles, orgID, perm, h.logger)
+}
+
+// authorizeOrgMember checks the caller's permission in orgID, which may differ
+// from the organization in their token. It writes the error response on failure.
+func authorizeOrgMember(c *gin.Context, roles *rbac.RoleStore, orgID string, perm rbac.Permission, logger *zap.Logger) (string, bool) {
+	userID := c.GetString("user_id")
+
+	role, err := roles.MemberRole(c.Request.Context(), userID, orgID)
+	if err == rbac.ErrNotMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return "", false
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), logger).Error("Failed to verify membership", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify access"})
+		return "", false
+	}
+
+	allowed, err := roles.HasPermission(c.Request.Context(), orgID, role, perm)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), logger).Error("Failed to resolve role permissions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return "", false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+		return "", false
+	}
+	if !rbac.TokenAllows(c.Request.Context(), orgID, perm) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API token is not scoped for this action"})
+		return "", false
+	}
+
+	return userID, true
+}
+
+func (h *RoleHandler) respondRoleError(c *gin.Context, err error, message string) {
+	switch {
+	case err == rbac.ErrRoleNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	case err == rbac.ErrRoleExists:
+		c.JSON(http.StatusConflict, gin.H{"error": "A role with this name already exists"})
+	case err == rbac.ErrRoleInUse:
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is assigned to members; reassign them first"})
+	case err == rbac.ErrReservedRoleName:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, rbac.ErrUnknownPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,119 @@
+//go:build integration
+
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/api"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+// roleRequest calls a RoleHandler route on orgID as userID
+func roleRequest(method, route, path, userID, body string) request {
+	return request{method: method, route: route, path: path, body: body, keys: map[string]string{"user_id": userID}}
+}
+
+func TestRoleHandlerPermissions(t *testing.T) {
+	env := testenv.Setup(t)
+	org := env.CreateOrganization(t, "")
+	owner := env.CreateUser(t, testenv.UserOptions{})
+	viewer := env.CreateUser(t, testenv.UserOptions{})
+	outsider := env.CreateUser(t, testenv.UserOptions{})
+	env.AddMember(t, owner.ID, org.ID, rbac.RoleOwner)
+	env.AddMember(t, viewer.ID, org.ID, rbac.RoleViewer)
+
+	path := "/organizations/" + org.ID + "/roles"
+	body := `{"name":"triager","permissions":["view:scan","triage:finding"]}`
+
+	list := func(h *api.RoleHandler, userID string) int {
+		return serve(h.ListRoles, roleRequest(http.MethodGet, "/organizations/:id/roles", path, userID, "")).Code
+	}
+	create := func(h *api.RoleHandler, userID string) int {
+		return serve(h.CreateRole, roleRequest(http.MethodPost, "/organizations/:id/roles", path, userID, body)).Code
+	}
+
+	tests := []struct {
+		name   string
+		call   func(h *api.RoleHandler, userID string) int
+		userID string
+		status int
+	}{
+		{"viewers list roles", list, viewer.ID, http.StatusOK},
+		{"outsiders cannot list roles", list, outsider.ID, http.StatusForbidden},
+		{"viewers cannot create roles", create, viewer.ID, http.StatusForbidden},
+		{"outsiders cannot create roles", create, outsider.ID, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditor := newAuditor()
+			h := api.NewRoleHandler(env.Roles, nil, auditor, env.Logger)
+			if status := tt.call(h, tt.userID); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if auditCount(auditor) != 0 {
+				t.Errorf("audited a read or a denied change")
+			}
+		})
+	}
+}
+
+func TestRoleHandlerLifecycle(t *testing.T) {
+	env := testenv.Setup(t)
+	org := env.CreateOrganization(t, "")
+	owner := env.CreateUser(t, testenv.UserOptions{})
+	env.AddMember(t, owner.ID, org.ID, rbac.RoleOwner)
+
+	auditor := newAuditor()
+	h := api.NewRoleHandler(env.Roles, nil, auditor, env.Logger)
+	path := "/organizations/" + org.ID + "/roles"
+	create := func(body string) int {
+		return serve(h.CreateRole, roleRequest(http.MethodPost, "/organizations/:id/roles", path, owner.ID, body)).Code
+	}
+
+	rec := serve(h.CreateRole, roleRequest(http.MethodPost, "/organizations/:id/roles", path, owner.ID,
+		`{"name":"triager","permissions":["view:scan","triage:finding"]}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201 (%s)", rec.Code, rec.Body)
+	}
+	roleID, _ := decode(t, rec)["id"].(string)
+	success := auditor.LogSuccessCalls()
+	if len(success) != 1 || success[0].Action != "custom_role_created" || success[0].UserID != owner.ID ||
+		success[0].ResourceID != roleID || success[0].Details["organization_id"] != org.ID {
+		t.Fatalf("LogSuccess calls = %+v", success)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"duplicate name", `{"name":"triager","permissions":["view:scan"]}`, http.StatusConflict},
+		{"built-in name", `{"name":"admin","permissions":["view:scan"]}`, http.StatusBadRequest},
+		{"unknown permission", `{"name":"root","permissions":["everything"]}`, http.StatusBadRequest},
+		{"no permissions", `{"name":"empty","permissions":[]}`, http.StatusBadRequest},
+	} {
+		if status := create(tt.body); status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.status)
+		}
+	}
+	if n := len(auditor.LogSuccessCalls()); n != 1 {
+		t.Errorf("rejected creations were audited: %d LogSuccess calls", n)
+	}
+
+	deleteRole := func(id string) int {
+		return serve(h.DeleteRole, roleRequest(http.MethodDelete, "/organizations/:id/roles/:role_id", path+"/"+id, owner.ID, "")).Code
+	}
+	if status := deleteRole(roleID); status != http.StatusOK {
+		t.Fatalf("delete: status = %d, want 200", status)
+	}
+	if status := deleteRole(roleID); status != http.StatusNotFound {
+		t.Errorf("second delete: status = %d, want 404", status)
+	}
+	success = auditor.LogSuccessCalls()
+	if last := success[len(success)-1]; len(success) != 2 || last.Action != "custom_role_deleted" || last.ResourceID != roleID {
+		t.Errorf("LogSuccess calls = %+v", success)
+	}
+}
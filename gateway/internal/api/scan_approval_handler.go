@@ -0,0 +1,237 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/approvals"
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ScanApprovalHandler serves the approval workflow for high-impact scans
+type ScanApprovalHandler struct {
+	approvals   *approvals.Service
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewScanApprovalHandler(approvalService *approvals.Service, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *ScanApprovalHandler {
+	return &ScanApprovalHandler{
+		approvals:   approvalService,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// ScanDecisionRequest carries the approver's justification, which is required
+type ScanDecisionRequest struct {
+	Justification string `json:"justification" binding:"required,max=2000"`
+}
+
+// ScanApprovalsResponse is a scan's approval state and the decisions so far
+type ScanApprovalsResponse struct {
+	Decisions []approvals.Decision `json:"decisions"`
+}
+
+// ApprovalRuleRequest sets how many approvals a scan type needs; 0 turns
+// approval off for it
+type ApprovalRuleRequest struct {
+	RequiredApprovals *int `json:"required_approvals" binding:"required,min=0,max=5"`
+}
+
+// ListPending handles GET /api/v1/scans/approvals
+func (h *ScanApprovalHandler) ListPending(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	scans, err := h.approvals.Pending(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list scans awaiting approval", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scans awaiting approval"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scans)
+}
+
+// ListDecisions handles GET /api/v1/scans/:id/approvals
+func (h *ScanApprovalHandler) ListDecisions(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+	scanID := c.Param("id")
+	if _, err := uuid.Parse(scanID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scan ID"})
+		return
+	}
+
+	decisions, err := h.approvals.Decisions(c.Request.Context(), orgID, scanID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list scan decisions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list approval decisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ScanApprovalsResponse{Decisions: decisions})
+}
+
+// ApproveScan handles POST /api/v1/scans/:id/approve
+func (h *ScanApprovalHandler) ApproveScan(c *gin.Context) {
+	h.decide(c, approvals.Approve)
+}
+
+// RejectScan handles POST /api/v1/scans/:id/reject
+func (h *ScanApprovalHandler) RejectScan(c *gin.Context) {
+	h.decide(c, approvals.Reject)
+}
+
+func (h *ScanApprovalHandler) decide(c *gin.Context, decision string) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+	userID := c.GetString("user_id")
+	scanID := c.Param("id")
+	if _, err := uuid.Parse(scanID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scan ID"})
+		return
+	}
+
+	var req ScanDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	scan, err := h.approvals.Decide(ctx, orgID, scanID, userID, decision, req.Justification)
+	switch err {
+	case nil:
+	case approvals.ErrScanNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scan not found"})
+		return
+	case approvals.ErrNotPending, approvals.ErrAlreadyDecided:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case approvals.ErrSelfApproval:
+		h.auditLogger.LogSecurityEvent(ctx, userID, "scan_self_approval_attempt", scanID, "medium", map[string]interface{}{
+			"decision": decision,
+		})
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	default:
+		logging.FromContext(ctx, h.logger).Error("Failed to record scan decision", zap.String("scan_id", scanID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record decision"})
+		return
+	}
+
+	h.auditLogger.Log(ctx, audit.LogParams{
+		UserID:       userID,
+		Action:       "scan_" + decision,
+		ResourceType: "scan_job",
+		ResourceID:   scanID,
+		Target:       scan.TargetValue,
+		Details: map[string]interface{}{
+			"justification":      req.Justification,
+			"requested_by":       scan.RequestedBy,
+			"scan_type":          scan.ScanType,
+			"status":             scan.Status,
+			"approvals":          scan.Approvals,
+			"required_approvals": scan.RequiredApprovals,
+		},
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, scan)
+}
+
+// ListRules handles GET /api/v1/organizations/:id/scan-approval-rules
+func (h *ScanApprovalHandler) ListRules(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewOrganization, h.logger); !ok {
+		return
+	}
+
+	rules, err := h.approvals.Rules(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list approval rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list approval rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// SetRule handles PUT /api/v1/organizations/:id/scan-approval-rules/:scan_type
+func (h *ScanApprovalHandler) SetRule(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req ApprovalRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	scanType := c.Param("scan_type")
+	if len(scanType) > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scan_type must be at most 50 characters"})
+		return
+	}
+
+	rule, err := h.approvals.SetRule(c.Request.Context(), orgID, scanType, userID, *req.RequiredApprovals)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to save approval rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save approval rule"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "scan_approval_rule_set", "organization", orgID, map[string]interface{}{
+		"scan_type":          scanType,
+		"required_approvals": rule.RequiredApprovals,
+	})
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule handles DELETE /api/v1/organizations/:id/scan-approval-rules/:scan_type
+func (h *ScanApprovalHandler) DeleteRule(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	scanType := c.Param("scan_type")
+	err := h.approvals.DeleteRule(c.Request.Context(), orgID, scanType)
+	if err == approvals.ErrRuleNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval rule not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete approval rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete approval rule"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "scan_approval_rule_deleted", "organization", orgID, map[string]interface{}{
+		"scan_type": scanType,
+	})
+
+	c.Status(http.StatusNoContent)
+}
@@ -8,21 +8,25 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/repository"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
 type ScanAuthorizationHandler struct {
-	db     *sqlx.DB
-	logger *zap.Logger
+	db             *database.DB
+	authorizations repository.AuthorizationRepo
+	logger         *zap.Logger
 }
 
-func NewScanAuthorizationHandler(db *sqlx.DB, logger *zap.Logger) *ScanAuthorizationHandler {
+func NewScanAuthorizationHandler(db *database.DB, authorizations repository.AuthorizationRepo, logger *zap.Logger) *ScanAuthorizationHandler {
 	return &ScanAuthorizationHandler{
-		db:     db,
-		logger: logger,
+		db:             db,
+		authorizations: authorizations,
+		logger:         logger,
 	}
 }
 
@@ -34,24 +38,11 @@ type SubmitAuthorizationRequest struct {
 	ValidFrom           time.Time `json:"valid_from" binding:"required"`
 	ValidUntil          time.Time `json:"valid_until" binding:"required"`
 	ScopeLimitations    string    `json:"scope_limitations"` // JSON string
+	Tags                []string  `json:"tags"`              // e.g. staging, production; used by access policies
 }
 
-type Authorization struct {
-	ID                       string     `json:"id" db:"id"`
-	OrganizationID           string     `json:"organization_id" db:"organization_id"`
-	TargetType               string     `json:"target_type" db:"target_type"`
-	TargetValue              string     `json:"target_value" db:"target_value"`
-	AuthorizationDocumentURL string     `json:"authorization_document_url" db:"authorization_document_url"`
-	AuthorizationHash        string     `json:"authorization_hash" db:"authorization_hash"`
-	AuthorizedBy             string     `json:"authorized_by" db:"authorized_by"`
-	ValidFrom                time.Time  `json:"valid_from" db:"valid_from"`
-	ValidUntil               time.Time  `json:"valid_until" db:"valid_until"`
-	VerificationStatus       string     `json:"verification_status" db:"verification_status"`
-	VerifiedByUserID         *string    `json:"verified_by_user_id" db:"verified_by_user_id"`
-	VerifiedAt               *time.Time `json:"verified_at" db:"verified_at"`
-	RejectionReason          *string    `json:"rejection_reason" db:"rejection_reason"`
-	CreatedAt                time.Time  `json:"created_at" db:"created_at"`
-}
+// Authorization is a scan authorization as listed by the API
+type Authorization = repository.Authorization
 
 // SubmitAuthorization handles POST /api/v1/scan-authorizations
 func (h *ScanAuthorizationHandler) SubmitAuthorization(c *gin.Context) {
@@ -63,7 +54,7 @@ func (h *ScanAuthorizationHandler) SubmitAuthorization(c *gin.Context) {
 
 	var req SubmitAuthorizationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
@@ -77,27 +68,27 @@ func (h *ScanAuthorizationHandler) SubmitAuthorization(c *gin.Context) {
 	hash := sha256.Sum256([]byte(req.AuthorizationDocURL))
 	authHash := hex.EncodeToString(hash[:])
 
-	authID := uuid.New()
-
-	// Insert authorization
-	_, err := h.db.Exec(`
-		INSERT INTO authorized_targets (
-			id, organization_id, target_type, target_value,
-			authorization_document_url, authorization_hash,
-			authorized_by, valid_from, valid_until, verification_status
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'pending')
-	`, authID, orgID, req.TargetType, req.TargetValue,
-		req.AuthorizationDocURL, authHash,
-		req.AuthorizedBy, req.ValidFrom, req.ValidUntil)
-
-	if err != nil {
-		h.logger.Error("Failed to submit authorization", zap.Error(err))
+	// The document URL is encrypted with the organization's data key
+	auth := Authorization{
+		ID:                       uuid.New().String(),
+		OrganizationID:           orgID,
+		TargetType:               req.TargetType,
+		TargetValue:              req.TargetValue,
+		AuthorizationDocumentURL: req.AuthorizationDocURL,
+		AuthorizationHash:        authHash,
+		AuthorizedBy:             req.AuthorizedBy,
+		ValidFrom:                req.ValidFrom,
+		ValidUntil:               req.ValidUntil,
+		Tags:                     req.Tags,
+	}
+	if err := h.authorizations.Create(c.Request.Context(), &auth); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to submit authorization", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit authorization"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":                  authID.String(),
+		"id":                  auth.ID,
 		"verification_status": "pending",
 		"message":             "Authorization submitted for review",
 	})
@@ -111,25 +102,9 @@ func (h *ScanAuthorizationHandler) ListAuthorizations(c *gin.Context) {
 		return
 	}
 
-	status := c.Query("status") // Filter by status
-
-	query := `
-		SELECT * FROM authorized_targets
-		WHERE organization_id = $1
-	`
-	args := []interface{}{orgID}
-
-	if status != "" {
-		query += ` AND verification_status = $2`
-		args = append(args, status)
-	}
-
-	query += ` ORDER BY created_at DESC`
-
-	var authorizations []Authorization
-	err := h.db.Select(&authorizations, query, args...)
+	authorizations, err := h.authorizations.List(c.Request.Context(), orgID, c.Query("status"))
 	if err != nil {
-		h.logger.Error("Failed to list authorizations", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list authorizations", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list authorizations"})
 		return
 	}
@@ -137,18 +112,21 @@ func (h *ScanAuthorizationHandler) ListAuthorizations(c *gin.Context) {
 	c.JSON(http.StatusOK, authorizations)
 }
 
+// VerifyAuthorizationRequest payload
+type VerifyAuthorizationRequest struct {
+	Action string `json:"action" binding:"required"` // approve or reject
+	Reason string `json:"reason"`                    // Only for reject
+}
+
 // VerifyAuthorization handles POST /api/v1/scan-authorizations/:id/verify
 func (h *ScanAuthorizationHandler) VerifyAuthorization(c *gin.Context) {
 	authID := c.Param("id")
 	userID := c.GetString("user_id")
 
-	var req struct {
-		Action string `json:"action" binding:"required"` // approve or reject
-		Reason string `json:"reason"`                    // Only for reject
-	}
+	var req VerifyAuthorizationRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
@@ -158,15 +136,14 @@ func (h *ScanAuthorizationHandler) VerifyAuthorization(c *gin.Context) {
 	}
 
 	// Check if authorization exists
-	var auth Authorization
-	err := h.db.Get(&auth, "SELECT * FROM authorized_targets WHERE id = $1", authID)
-	if err == sql.ErrNoRows {
+	_, err := h.authorizations.Get(c.Request.Context(), authID)
+	if err == repository.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Authorization not found"})
 		return
 	}
 
 	if err != nil {
-		h.logger.Error("Failed to fetch authorization", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to fetch authorization", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authorization"})
 		return
 	}
@@ -198,7 +175,7 @@ func (h *ScanAuthorizationHandler) VerifyAuthorization(c *gin.Context) {
 
 	_, err = h.db.Exec(updateQuery, args...)
 	if err != nil {
-		h.logger.Error("Failed to update authorization", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to update authorization", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update authorization"})
 		return
 	}
@@ -210,6 +187,12 @@ func (h *ScanAuthorizationHandler) VerifyAuthorization(c *gin.Context) {
 	})
 }
 
+// CheckTargetRequest payload
+type CheckTargetRequest struct {
+	TargetType  string `json:"target_type" binding:"required"`
+	TargetValue string `json:"target_value" binding:"required"`
+}
+
 // CheckTargetAuthorization handles POST /api/v1/scan-authorizations/check
 // This is used before creating a scan to verify target is authorized
 func (h *ScanAuthorizationHandler) CheckTargetAuthorization(c *gin.Context) {
@@ -219,13 +202,10 @@ func (h *ScanAuthorizationHandler) CheckTargetAuthorization(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		TargetType  string `json:"target_type" binding:"required"`
-		TargetValue string `json:"target_value" binding:"required"`
-	}
+	var req CheckTargetRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindError(c, err)
 		return
 	}
 
@@ -252,7 +232,7 @@ func (h *ScanAuthorizationHandler) CheckTargetAuthorization(c *gin.Context) {
 	}
 
 	if err != nil {
-		h.logger.Error("Failed to check authorization", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to check authorization", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check authorization"})
 		return
 	}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/grants"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CreateScanGrantRequest drafts a delegated authorization for a client to approve
+type CreateScanGrantRequest struct {
+	ClientName  string          `json:"client_name" binding:"required,max=200"`
+	ClientEmail string          `json:"client_email" binding:"required,email,max=255"`
+	Targets     []grants.Target `json:"targets" binding:"required,min=1,max=100,dive"`
+	ScanTypes   []string        `json:"scan_types" binding:"required,min=1,dive,required,max=50"`
+	ValidFrom   time.Time       `json:"valid_from" binding:"required"`
+	ValidUntil  time.Time       `json:"valid_until" binding:"required"`
+	Notes       string          `json:"notes" binding:"max=2000"`
+}
+
+// ScanGrantResponse is a grant with the client's review link
+type ScanGrantResponse struct {
+	grants.Grant
+	ReviewURL string `json:"review_url"`
+}
+
+// DecideScanGrantRequest is the client's decision, signed with their name
+type DecideScanGrantRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Name     string `json:"name" binding:"required,max=200"`
+	Title    string `json:"title" binding:"max=200"`
+}
+
+// RevokeScanGrantRequest optionally explains a revocation
+type RevokeScanGrantRequest struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+type ScanGrantHandler struct {
+	grants      *grants.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewScanGrantHandler(grantService *grants.Service, auditLogger Auditor, logger *zap.Logger) *ScanGrantHandler {
+	return &ScanGrantHandler{
+		grants:      grantService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// CreateGrant handles POST /api/v1/scan-grants. The client is emailed the
+// review link, which is also returned for sending another way.
+func (h *ScanGrantHandler) CreateGrant(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	var req CreateScanGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	grant, err := h.grants.Create(ctx, orgID, userID, grants.Draft{
+		ClientName:  req.ClientName,
+		ClientEmail: req.ClientEmail,
+		Targets:     req.Targets,
+		ScanTypes:   req.ScanTypes,
+		ValidFrom:   req.ValidFrom,
+		ValidUntil:  req.ValidUntil,
+		Notes:       req.Notes,
+	})
+	if errors.Is(err, grants.ErrInvalidDraft) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to create scan grant", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create scan grant"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "scan_grant_requested", "scan_grant", grant.ID, map[string]interface{}{
+		"client_email": grant.ClientEmail,
+		"targets":      grant.Targets,
+		"scan_types":   grant.ScanTypes,
+		"valid_from":   grant.ValidFrom,
+		"valid_until":  grant.ValidUntil,
+	})
+
+	c.JSON(http.StatusCreated, ScanGrantResponse{Grant: *grant, ReviewURL: h.grants.ReviewURL(grant.ID)})
+}
+
+// ListGrants handles GET /api/v1/scan-grants
+func (h *ScanGrantHandler) ListGrants(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	list, err := h.grants.List(c.Request.Context(), orgID, c.Query("status"))
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list scan grants", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scan grants"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetGrant handles GET /api/v1/scan-grants/:id
+func (h *ScanGrantHandler) GetGrant(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	grant, err := h.grants.Get(c.Request.Context(), orgID, c.Param("id"))
+	if err == grants.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scan grant not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load scan grant", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scan grant"})
+		return
+	}
+	c.JSON(http.StatusOK, ScanGrantResponse{Grant: *grant, ReviewURL: h.grants.ReviewURL(grant.ID)})
+}
+
+// RevokeGrant handles POST /api/v1/scan-grants/:id/revoke
+func (h *ScanGrantHandler) RevokeGrant(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	var req RevokeScanGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		bindError(c, err)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	h.revoke(c, userID, func() (*grants.Grant, error) {
+		return h.grants.Revoke(c.Request.Context(), orgID, c.Param("id"), userID, req.Reason)
+	})
+}
+
+// ReviewGrant handles GET /api/v1/scan-grants/review, where the client's
+// link lands. The signed token is the only credential, since clients need
+// not have an account.
+func (h *ScanGrantHandler) ReviewGrant(c *gin.Context) {
+	token, ok := grantToken(c)
+	if !ok {
+		return
+	}
+	grant, err := h.grants.GetByToken(c.Request.Context(), token)
+	if !h.linkError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, grant)
+}
+
+// DecideGrant handles POST /api/v1/scan-grants/review: the client approves
+// or rejects the grant, signing with their name
+func (h *ScanGrantHandler) DecideGrant(c *gin.Context) {
+	token, ok := grantToken(c)
+	if !ok {
+		return
+	}
+	var req DecideScanGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	grant, err := h.grants.Decide(ctx, token, grants.Decision{
+		Decision:  req.Decision,
+		Name:      req.Name,
+		Title:     req.Title,
+		IPAddress: c.ClientIP(),
+	})
+	switch {
+	case err == grants.ErrNotPending:
+		c.JSON(http.StatusConflict, gin.H{"error": "This request was already answered"})
+		return
+	case err == grants.ErrExpired:
+		c.JSON(http.StatusConflict, gin.H{"error": "This request has expired"})
+		return
+	case !h.linkError(c, err):
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(ctx, "", "scan_grant_"+grant.Status, grant.ID, "medium", map[string]interface{}{
+		"organization_id":  grant.OrganizationID,
+		"client_email":     grant.ClientEmail,
+		"decided_by_name":  req.Name,
+		"decided_by_title": req.Title,
+		"ip_address":       c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, grant)
+}
+
+// RevokeGrantByLink handles POST /api/v1/scan-grants/review/revoke: the
+// client withdraws a grant through their link
+func (h *ScanGrantHandler) RevokeGrantByLink(c *gin.Context) {
+	token, ok := grantToken(c)
+	if !ok {
+		return
+	}
+	var req RevokeScanGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		bindError(c, err)
+		return
+	}
+
+	h.revoke(c, "", func() (*grants.Grant, error) {
+		return h.grants.RevokeByToken(c.Request.Context(), token, req.Reason)
+	})
+}
+
+func (h *ScanGrantHandler) revoke(c *gin.Context, userID string, revoke func() (*grants.Grant, error)) {
+	ctx := c.Request.Context()
+	grant, err := revoke()
+	switch {
+	case err == grants.ErrAlreadyEnded:
+		c.JSON(http.StatusConflict, gin.H{"error": "Scan grant was already rejected or revoked"})
+		return
+	case !h.linkError(c, err):
+		return
+	}
+
+	h.auditLogger.LogSecurityEvent(ctx, userID, "scan_grant_revoked", grant.ID, "medium", map[string]interface{}{
+		"organization_id": grant.OrganizationID,
+		"revoked_by":      grant.RevokedBy,
+		"reason":          grant.RevokeReason,
+		"ip_address":      c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, grant)
+}
+
+// linkError answers for errors shared by the grant endpoints, returning
+// false when it did
+func (h *ScanGrantHandler) linkError(c *gin.Context, err error) bool {
+	switch err {
+	case nil:
+		return true
+	case grants.ErrInvalidToken:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired link"})
+	case grants.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scan grant not found"})
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error("Scan grant request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process scan grant"})
+	}
+	return false
+}
+
+// grantToken reads the link's token from the query or form
+func grantToken(c *gin.Context) (string, bool) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,740 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/approvals"
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/estimation"
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/grants"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/scanwindows"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type ScanHandler struct {
+	db          *database.DB
+	redis       *redis.Client
+	policies    *rbac.PolicyEngine
+	approvals   *approvals.Service
+	windows     *scanwindows.Service
+	estimates   *estimation.Service
+	grants      *grants.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewScanHandler(db *database.DB, redisClient *redis.Client, policies *rbac.PolicyEngine, approvalService *approvals.Service, windowService *scanwindows.Service, estimates *estimation.Service, grantService *grants.Service, auditLogger Auditor, logger *zap.Logger) *ScanHandler {
+	return &ScanHandler{
+		db:          db,
+		redis:       redisClient,
+		policies:    policies,
+		approvals:   approvalService,
+		windows:     windowService,
+		estimates:   estimates,
+		grants:      grantService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+type CreateScanRequest struct {
+	AuthorizationTargetID string                 `json:"authorization_target_id" binding:"required_without=GrantID,omitempty,uuid"`
+	GrantID               string                 `json:"grant_id" binding:"omitempty,uuid"`                    // Scan under a client's grant instead of an authorization
+	TargetValue           string                 `json:"target_value" binding:"required_with=GrantID,max=255"` // One of the grant's targets, or inside one of its ranges
+	ScanType              string                 `json:"scan_type" binding:"required"`
+	ScanMode              string                 `json:"scan_mode"` // Defaults to passive
+	Priority              int                    `json:"priority" binding:"omitempty,min=1,max=10"`
+	Configuration         map[string]interface{} `json:"configuration"`
+	Justification         string                 `json:"justification" binding:"max=2000"` // Required when the scan type needs approval
+}
+
+type ScanJob struct {
+	ID                    string               `json:"id" db:"id"`
+	OrganizationID        string               `json:"organization_id" db:"organization_id"`
+	AuthorizationTargetID string               `json:"authorization_target_id,omitempty" db:"authorization_target_id"`
+	GrantID               string               `json:"grant_id,omitempty" db:"grant_id"`
+	TargetType            string               `json:"target_type" db:"target_type"`
+	TargetValue           string               `json:"target_value" db:"target_value"`
+	ScanType              string               `json:"scan_type" db:"scan_type"`
+	ScanMode              string               `json:"scan_mode" db:"scan_mode"`
+	Status                string               `json:"status" db:"status"`
+	Priority              int                  `json:"priority" db:"priority"`
+	NotBefore             *time.Time           `json:"not_before,omitempty" db:"not_before"` // Held until its execution window opens
+	Estimate              *estimation.Estimate `json:"estimate,omitempty" db:"estimate"`
+	CreatedAt             time.Time            `json:"created_at" db:"created_at"`
+}
+
+// authorizedTarget is the approved authorization a scan runs under: an
+// authorized target, or a target covered by a client's grant
+type authorizedTarget struct {
+	ID          string         `db:"id"`
+	GrantID     string         `db:"-"`
+	TargetType  string         `db:"target_type"`
+	TargetValue string         `db:"target_value"`
+	Tags        pq.StringArray `db:"tags"`
+	ValidUntil  time.Time      `db:"valid_until"`
+}
+
+// loadAuthorizedTarget returns the organization's approved, current
+// authorization, or sql.ErrNoRows
+func (h *ScanHandler) loadAuthorizedTarget(ctx context.Context, id, orgID string) (*authorizedTarget, error) {
+	var target authorizedTarget
+	err := h.db.GetContext(ctx, &target, `
+		SELECT id, target_type, target_value, tags, valid_until FROM authorized_targets
+		WHERE id = $1
+		AND organization_id = $2
+		AND verification_status = 'approved'
+		AND valid_from <= NOW()
+		AND valid_until >= NOW()
+	`, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+// proof is what authorizes scans of the target, for audit logs and token scopes
+func (t *authorizedTarget) proof() string {
+	if t.GrantID != "" {
+		return t.GrantID
+	}
+	return t.ID
+}
+
+// resolveTarget returns what authorizes the requested scan: the client
+// grant it names, which must be approved, current and cover the target and
+// scan type, or else its authorized target. Denials are sql.ErrNoRows or a
+// grants error.
+func (h *ScanHandler) resolveTarget(ctx context.Context, orgID string, req CreateScanRequest) (*authorizedTarget, error) {
+	if req.GrantID == "" {
+		return h.loadAuthorizedTarget(ctx, req.AuthorizationTargetID, orgID)
+	}
+	grant, target, err := h.grants.Authorize(ctx, orgID, req.GrantID, req.TargetValue, req.ScanType)
+	if err != nil {
+		return nil, err
+	}
+	return &authorizedTarget{
+		GrantID:     grant.ID,
+		TargetType:  target.Type,
+		TargetValue: target.Value,
+		ValidUntil:  grant.ValidUntil,
+	}, nil
+}
+
+// CreateScan handles POST /api/v1/scans
+func (h *ScanHandler) CreateScan(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	var req CreateScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	job, scanErr := h.startScan(c.Request.Context(), scanRequester{
+		UserID:    c.GetString("user_id"),
+		OrgID:     orgID,
+		Role:      rbac.Role(c.GetString("user_role")),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}, req)
+	if scanErr != nil {
+		c.JSON(scanErr.status, scanErr.body())
+		return
+	}
+
+	c.JSON(http.StatusCreated, job)
+}
+
+// scanRequester is who a scan is created for
+type scanRequester struct {
+	UserID    string
+	OrgID     string
+	Role      rbac.Role
+	IPAddress string
+	UserAgent string
+}
+
+// scanError is why a scan could not be created or changed, as an HTTP
+// status and message
+type scanError struct {
+	status  int
+	message string
+	reason  string // Policy denial or quota reason, if any
+	details gin.H  // Further fields of the error response
+}
+
+// body is the scanError as a REST error response
+func (e *scanError) body() gin.H {
+	body := gin.H{"error": e.message}
+	if e.reason != "" {
+		body["reason"] = e.reason
+	}
+	for k, v := range e.details {
+		body[k] = v
+	}
+	return body
+}
+
+// startScan queues a scan for the requester after checking the target's
+// authorization and the organization's access policies. It is shared by the
+// REST API and chat integrations.
+func (h *ScanHandler) startScan(ctx context.Context, requester scanRequester, req CreateScanRequest) (*ScanJob, *scanError) {
+	if req.ScanMode == "" {
+		req.ScanMode = "passive"
+	}
+	if req.Priority == 0 {
+		req.Priority = 5
+	}
+	orgID, userID := requester.OrgID, requester.UserID
+
+	// The scan must run under an approved, current authorization or client
+	// grant of this organization
+	target, err := h.resolveTarget(ctx, orgID, req)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, &scanError{status: http.StatusForbidden, message: "No valid authorization found for this target"}
+	case grants.IsDenial(err):
+		h.auditLogger.LogFailure(ctx, userID, "scan_create_denied", err.Error(), map[string]interface{}{
+			"grant_id":     req.GrantID,
+			"target_value": req.TargetValue,
+			"scan_type":    req.ScanType,
+		})
+		return nil, &scanError{status: http.StatusForbidden, message: "Scan grant does not authorize this scan", reason: err.Error()}
+	case err != nil:
+		logging.FromContext(ctx, h.logger).Error("Failed to load authorization", zap.Error(err))
+		return nil, &scanError{status: http.StatusInternalServerError, message: "Failed to verify authorization"}
+	}
+	if !rbac.TokenAllowsAsset(ctx, orgID, rbac.PermCreateScan, target.proof()) {
+		return nil, &scanError{status: http.StatusForbidden, message: "API token is not scoped for this target"}
+	}
+
+	// Attribute-based policies (target tags, scan type, time of day)
+	decision, err := h.policies.Evaluate(ctx, orgID, requester.Role, rbac.PermCreateScan, rbac.Attributes{
+		TargetTags: target.Tags,
+		ScanType:   req.ScanType,
+	})
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to evaluate access policies", zap.Error(err))
+		return nil, &scanError{status: http.StatusInternalServerError, message: "Failed to check access policies"}
+	}
+	if !decision.Allowed {
+		h.auditLogger.LogFailure(ctx, userID, "scan_create_denied", decision.Reason, map[string]interface{}{
+			"policy_id":               decision.PolicyID,
+			"authorization_target_id": target.ID,
+			"grant_id":                target.GrantID,
+			"scan_type":               req.ScanType,
+		})
+		return nil, &scanError{status: http.StatusForbidden, message: "Denied by access policy", reason: decision.Reason}
+	}
+
+	// High-impact scan types wait for approvers before any worker gets them
+	requiredApprovals, err := h.approvals.Required(ctx, orgID, req.ScanType)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load approval rules", zap.Error(err))
+		return nil, &scanError{status: http.StatusInternalServerError, message: "Failed to check approval rules"}
+	}
+	status := approvals.StatusApproved
+	if requiredApprovals > 0 {
+		if req.Justification == "" {
+			return nil, &scanError{status: http.StatusBadRequest, message: "This scan type needs approval; a justification is required"}
+		}
+		status = approvals.StatusPendingApproval
+	}
+
+	// Outside the target's execution windows the scan waits for the next one
+	slot, err := h.windows.Slot(ctx, orgID, target.ID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load execution windows", zap.Error(err))
+		return nil, &scanError{status: http.StatusInternalServerError, message: "Failed to check execution windows"}
+	}
+
+	// What the scan is expected to take, kept for approvers; it is only
+	// advisory, so the scan goes ahead without one
+	estimate, err := h.estimates.Estimate(ctx, estimation.Params{
+		OrgID:       orgID,
+		ScanType:    req.ScanType,
+		ScanMode:    req.ScanMode,
+		TargetType:  target.TargetType,
+		TargetValue: target.TargetValue,
+	})
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Warn("Failed to estimate scan", zap.Error(err))
+	}
+
+	configuration, err := json.Marshal(req.Configuration)
+	if err != nil {
+		return nil, &scanError{status: http.StatusBadRequest, message: "Invalid configuration"}
+	}
+
+	failed := &scanError{status: http.StatusInternalServerError, message: "Failed to create scan"}
+
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to begin transaction", zap.Error(err))
+		return nil, failed
+	}
+	defer tx.Rollback()
+
+	// Locks the organization's row, so concurrent creations cannot both
+	// take the last slot of its quota
+	quota, err := loadScanQuota(ctx, tx, orgID, true)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to check scan quota", zap.Error(err))
+		return nil, failed
+	}
+	if exceeded := quota.Exceeded(); exceeded != "" {
+		return nil, &scanError{status: http.StatusTooManyRequests, message: "Scan quota exceeded", reason: exceeded}
+	}
+
+	var targetID string
+	err = tx.GetContext(ctx, &targetID, `
+		INSERT INTO scan_targets (target_type, target_value)
+		VALUES ($1, $2)
+		RETURNING id
+	`, target.TargetType, target.TargetValue)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to create scan target", zap.Error(err))
+		return nil, failed
+	}
+
+	job := ScanJob{
+		OrganizationID:        orgID,
+		AuthorizationTargetID: target.ID,
+		GrantID:               target.GrantID,
+		TargetType:            target.TargetType,
+		TargetValue:           target.TargetValue,
+	}
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO scan_jobs (
+			user_id, organization_id, target_id, authorization_target_id, grant_id,
+			scan_type, scan_mode, priority, configuration,
+			status, required_approvals, justification, not_before, not_after, estimate
+		) VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, $14, $15)
+		RETURNING id, scan_type, scan_mode, status, priority, not_before, estimate, created_at
+	`, userID, orgID, targetID, target.ID, target.GrantID, req.ScanType, req.ScanMode, req.Priority, configuration,
+		status, requiredApprovals, req.Justification, slot.NotBefore, slot.NotAfter, estimate,
+	).Scan(&job.ID, &job.ScanType, &job.ScanMode, &job.Status, &job.Priority, &job.NotBefore, &job.Estimate, &job.CreatedAt)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to create scan job", zap.Error(err))
+		return nil, failed
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to commit scan job", zap.Error(err))
+		return nil, failed
+	}
+
+	h.auditLogger.Log(ctx, audit.LogParams{
+		UserID:             userID,
+		Action:             "scan_created",
+		ResourceType:       "scan_job",
+		ResourceID:         job.ID,
+		Target:             job.TargetValue,
+		AuthorizationProof: target.proof(),
+		Details: map[string]interface{}{
+			"scan_type":          job.ScanType,
+			"scan_mode":          job.ScanMode,
+			"priority":           job.Priority,
+			"grant_id":           target.GrantID,
+			"required_approvals": requiredApprovals,
+			"justification":      req.Justification,
+			"not_before":         slot.NotBefore,
+		},
+		IPAddress: requester.IPAddress,
+		UserAgent: requester.UserAgent,
+	})
+
+	if job.Status == approvals.StatusPendingApproval {
+		h.approvals.RequestApproval(ctx, approvals.Scan{
+			ID:                job.ID,
+			OrganizationID:    orgID,
+			RequestedBy:       userID,
+			ScanType:          job.ScanType,
+			ScanMode:          job.ScanMode,
+			TargetValue:       job.TargetValue,
+			Status:            job.Status,
+			Justification:     req.Justification,
+			RequiredApprovals: requiredApprovals,
+			Estimate:          job.Estimate,
+			CreatedAt:         job.CreatedAt,
+		})
+	}
+
+	return &job, nil
+}
+
+// ScanRun identifies a scan and the target it ran against
+type ScanRun struct {
+	ID          string     `json:"id" db:"id"`
+	Status      string     `json:"status" db:"status"`
+	TargetType  string     `json:"target_type" db:"target_type"`
+	TargetValue string     `json:"target_value" db:"target_value"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+}
+
+type ScanDiffResponse struct {
+	Scan    ScanRun `json:"scan"`
+	Against ScanRun `json:"against"`
+	findings.Diff
+}
+
+// DiffScans handles GET /api/v1/scans/:id/diff?against=:otherID
+// Findings are matched by fingerprint: new ones appear only in :id, resolved
+// ones only in the baseline, persisting ones in both.
+func (h *ScanHandler) DiffScans(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	scanID := c.Param("id")
+	againstID := c.Query("against")
+	if againstID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "against query parameter is required"})
+		return
+	}
+	if againstID == scanID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot compare a scan with itself"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var runs []ScanRun
+	err := h.db.SelectContext(ctx, &runs, `
+		SELECT sj.id, sj.status, st.target_type, st.target_value, sj.completed_at
+		FROM scan_jobs sj
+		INNER JOIN scan_targets st ON st.id = sj.target_id
+		WHERE sj.id IN ($1, $2) AND sj.organization_id = $3
+	`, scanID, againstID, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load scans", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scans"})
+		return
+	}
+	if len(runs) != 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scan not found"})
+		return
+	}
+
+	current, baseline := runs[0], runs[1]
+	if current.ID != scanID {
+		current, baseline = baseline, current
+	}
+	if current.TargetType != baseline.TargetType || current.TargetValue != baseline.TargetValue {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scans must be of the same target"})
+		return
+	}
+
+	var vulns []struct {
+		ScanJobID string `db:"scan_job_id"`
+		findings.Finding
+	}
+	err = h.db.SelectContext(ctx, &vulns, `
+		SELECT scan_job_id, id, fingerprint, title, severity, cvss_score, category, affected_component, status
+		FROM vulnerabilities
+		WHERE scan_job_id IN ($1, $2)
+	`, scanID, againstID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load findings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load findings"})
+		return
+	}
+
+	var currentFindings, baselineFindings []findings.Finding
+	for _, v := range vulns {
+		if v.ScanJobID == scanID {
+			currentFindings = append(currentFindings, v.Finding)
+		} else {
+			baselineFindings = append(baselineFindings, v.Finding)
+		}
+	}
+
+	c.JSON(http.StatusOK, ScanDiffResponse{
+		Scan:    current,
+		Against: baseline,
+		Diff:    *findings.Compare(currentFindings, baselineFindings),
+	})
+}
+
+// ScanControlRequest carries the optional reason for stopping, pausing or
+// resuming a scan
+type ScanControlRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ScanControlResponse is a scan's state after a control action
+type ScanControlResponse struct {
+	ID             string `json:"id" db:"id"`
+	Status         string `json:"status" db:"status"`
+	PreviousStatus string `json:"previous_status" db:"previous_status"`
+}
+
+// scanControls maps each action to the states it applies to. The worker
+// running the job picks up the change through the ScanJobControl RPC.
+var scanControls = map[string]struct {
+	from      []string
+	done      string
+	useReason bool
+	query     string
+}{
+	"stop": {
+		from:      []string{"pending_approval", "pending", "running", "paused"},
+		done:      "stopped",
+		useReason: true,
+		query: `
+			UPDATE scan_jobs sj
+			SET status = 'stopped', completed_at = NOW(), paused_at = NULL,
+			    error_message = 'Stopped by user' || COALESCE(': ' || NULLIF($3, ''), ''),
+			    updated_at = NOW()
+			FROM (SELECT id, status FROM scan_jobs WHERE id = $1 AND organization_id = $2 FOR UPDATE) old
+			WHERE sj.id = old.id AND old.status IN ('pending_approval', 'pending', 'running', 'paused')
+			RETURNING sj.id, sj.status, old.status AS previous_status`,
+	},
+	"pause": {
+		from: []string{"pending", "running"},
+		done: "paused",
+		query: `
+			UPDATE scan_jobs sj
+			SET status = 'paused', paused_at = NOW(), updated_at = NOW()
+			FROM (SELECT id, status FROM scan_jobs WHERE id = $1 AND organization_id = $2 FOR UPDATE) old
+			WHERE sj.id = old.id AND old.status IN ('pending', 'running')
+			RETURNING sj.id, sj.status, old.status AS previous_status`,
+	},
+	// A paused job goes back to its worker if that worker is still online,
+	// otherwise back to the queue
+	"resume": {
+		from: []string{"paused"},
+		done: "resumed",
+		query: `
+			UPDATE scan_jobs sj
+			SET status = CASE WHEN w.id IS NULL THEN 'pending' ELSE 'running' END,
+			    worker_id = w.id,
+			    started_at = CASE WHEN w.id IS NULL THEN NULL ELSE sj.started_at END,
+			    paused_at = NULL, paused_by_window = false,
+			    updated_at = NOW()
+			FROM (SELECT id, status, worker_id FROM scan_jobs WHERE id = $1 AND organization_id = $2 FOR UPDATE) old
+			LEFT JOIN scan_workers w ON w.id = old.worker_id AND w.status <> 'offline'
+			WHERE sj.id = old.id AND old.status = 'paused'
+			RETURNING sj.id, sj.status, old.status AS previous_status`,
+	},
+}
+
+// StopScan handles POST /api/v1/scans/:id/stop
+func (h *ScanHandler) StopScan(c *gin.Context) {
+	h.controlScan(c, "stop")
+}
+
+// PauseScan handles POST /api/v1/scans/:id/pause
+func (h *ScanHandler) PauseScan(c *gin.Context) {
+	h.controlScan(c, "pause")
+}
+
+// ResumeScan handles POST /api/v1/scans/:id/resume
+func (h *ScanHandler) ResumeScan(c *gin.Context) {
+	h.controlScan(c, "resume")
+}
+
+// controlScan handles the control endpoints for the caller's organization
+func (h *ScanHandler) controlScan(c *gin.Context, action string) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+	scanID := c.Param("id")
+	if _, err := uuid.Parse(scanID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scan ID"})
+		return
+	}
+
+	var req ScanControlRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		bindError(c, err)
+		return
+	}
+
+	resp, scanErr := h.applyControl(c.Request.Context(), scanRequester{
+		UserID:    c.GetString("user_id"),
+		OrgID:     orgID,
+		Role:      rbac.Role(c.GetString("user_role")),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}, scanID, action, req.Reason)
+	if scanErr != nil {
+		c.JSON(scanErr.status, scanErr.body())
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// applyControl applies a state transition in one statement, so it cannot
+// race a worker submitting results or another user's request. It is shared
+// by the REST API and WebSocket commands.
+func (h *ScanHandler) applyControl(ctx context.Context, requester scanRequester, scanID, action, reason string) (*ScanControlResponse, *scanError) {
+	resp, scanErr := h.transitionScan(ctx, requester.OrgID, scanID, action, reason)
+	if scanErr != nil {
+		return nil, scanErr
+	}
+
+	h.auditLogger.Log(ctx, audit.LogParams{
+		UserID:       requester.UserID,
+		Action:       "scan_" + action,
+		ResourceType: "scan_job",
+		ResourceID:   scanID,
+		Details: map[string]interface{}{
+			"reason":          reason,
+			"previous_status": resp.PreviousStatus,
+			"status":          resp.Status,
+		},
+		IPAddress: requester.IPAddress,
+		UserAgent: requester.UserAgent,
+	})
+
+	return resp, nil
+}
+
+// transitionScan is applyControl without the audit entry, for callers that
+// audit several scans at once
+func (h *ScanHandler) transitionScan(ctx context.Context, orgID, scanID, action, reason string) (*ScanControlResponse, *scanError) {
+	control := scanControls[action]
+
+	args := []interface{}{scanID, orgID}
+	if control.useReason {
+		args = append(args, reason)
+	}
+
+	var resp ScanControlResponse
+	err := h.db.GetContext(ctx, &resp, control.query, args...)
+	if err == sql.ErrNoRows {
+		var current string
+		err = h.db.GetContext(ctx, &current, `
+			SELECT status FROM scan_jobs WHERE id = $1 AND organization_id = $2
+		`, scanID, orgID)
+		if err == sql.ErrNoRows {
+			return nil, &scanError{status: http.StatusNotFound, message: "Scan not found"}
+		}
+		if err == nil {
+			return nil, &scanError{
+				status:  http.StatusConflict,
+				message: "Scan cannot be " + control.done + " in its current state",
+				details: gin.H{"status": current, "allowed": control.from},
+			}
+		}
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to update scan state", zap.String("action", action), zap.Error(err))
+		return nil, &scanError{status: http.StatusInternalServerError, message: "Failed to update scan"}
+	}
+	return &resp, nil
+}
+
+// RerunScan handles POST /api/v1/scans/:id/rerun. The new scan goes through
+// the same checks as creating one.
+func (h *ScanHandler) RerunScan(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+	scanID := c.Param("id")
+	if _, err := uuid.Parse(scanID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scan ID"})
+		return
+	}
+
+	job, scanErr := h.rerunScan(c.Request.Context(), scanRequester{
+		UserID:    c.GetString("user_id"),
+		OrgID:     orgID,
+		Role:      rbac.Role(c.GetString("user_role")),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}, scanID)
+	if scanErr != nil {
+		c.JSON(scanErr.status, scanErr.body())
+		return
+	}
+
+	c.JSON(http.StatusCreated, job)
+}
+
+// rerunScan queues a new scan with a finished scan's authorization, type,
+// mode, priority, configuration and justification
+func (h *ScanHandler) rerunScan(ctx context.Context, requester scanRequester, scanID string) (*ScanJob, *scanError) {
+	var previous struct {
+		Status                string          `db:"status"`
+		AuthorizationTargetID *string         `db:"authorization_target_id"`
+		GrantID               *string         `db:"grant_id"`
+		TargetValue           string          `db:"target_value"`
+		ScanType              string          `db:"scan_type"`
+		ScanMode              string          `db:"scan_mode"`
+		Priority              int             `db:"priority"`
+		Configuration         json.RawMessage `db:"configuration"`
+		Justification         *string         `db:"justification"`
+	}
+	err := h.db.GetContext(ctx, &previous, `
+		SELECT sj.status, sj.authorization_target_id::text AS authorization_target_id,
+		       sj.grant_id::text AS grant_id, st.target_value, sj.scan_type, sj.scan_mode, sj.priority,
+		       COALESCE(sj.configuration, '{}') AS configuration, sj.justification
+		FROM scan_jobs sj
+		JOIN scan_targets st ON st.id = sj.target_id
+		WHERE sj.id = $1 AND sj.organization_id = $2
+	`, scanID, requester.OrgID)
+	if err == sql.ErrNoRows {
+		return nil, &scanError{status: http.StatusNotFound, message: "Scan not found"}
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load scan", zap.Error(err))
+		return nil, &scanError{status: http.StatusInternalServerError, message: "Failed to load scan"}
+	}
+	switch previous.Status {
+	case "pending_approval", "pending", "running", "paused":
+		return nil, &scanError{
+			status:  http.StatusConflict,
+			message: "Scan is still in progress",
+			details: gin.H{"status": previous.Status},
+		}
+	}
+	req := CreateScanRequest{
+		ScanType: previous.ScanType,
+		ScanMode: previous.ScanMode,
+		Priority: previous.Priority,
+	}
+	switch {
+	case previous.AuthorizationTargetID != nil:
+		req.AuthorizationTargetID = *previous.AuthorizationTargetID
+	case previous.GrantID != nil:
+		// The grant must still be in effect; startScan checks it again
+		req.GrantID, req.TargetValue = *previous.GrantID, previous.TargetValue
+	default:
+		return nil, &scanError{status: http.StatusConflict, message: "Scan has no authorization to rerun under"}
+	}
+	if err := json.Unmarshal(previous.Configuration, &req.Configuration); err != nil {
+		logging.FromContext(ctx, h.logger).Warn("Dropping unreadable scan configuration", zap.String("scan_job_id", scanID), zap.Error(err))
+		req.Configuration = nil
+	}
+	if previous.Justification != nil {
+		req.Justification = *previous.Justification
+	}
+	return h.startScan(ctx, requester, req)
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/estimation"
+	"github.com/cyper-security/gateway/internal/grants"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/scanwindows"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Preflight decisions
+const (
+	PreflightGo   = "go"
+	PreflightNoGo = "no_go"
+)
+
+// Preflight check outcomes; any failure makes the decision no_go
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// ScanQuota bounds an organization's scans; a zero maximum is unlimited
+type ScanQuota struct {
+	Tier          string `json:"tier" db:"tier"`
+	MaxConcurrent int    `json:"max_concurrent" db:"max_concurrent"`
+	MaxMonthly    int    `json:"max_monthly" db:"max_monthly"`
+	Active        int    `json:"active" db:"active"`         // Pending, running and paused scans
+	ThisMonth     int    `json:"this_month" db:"this_month"` // Scans created this calendar month
+}
+
+// tierScanQuotas are the quotas of each subscription tier, unless the
+// organization overrides them
+var tierScanQuotas = map[string]ScanQuota{
+	"free":       {MaxConcurrent: 1, MaxMonthly: 20},
+	"basic":      {MaxConcurrent: 2, MaxMonthly: 100},
+	"pro":        {MaxConcurrent: 5, MaxMonthly: 500},
+	"enterprise": {MaxConcurrent: 20},
+}
+
+// Exceeded says which limit another scan would go over, or "" when it fits
+func (q ScanQuota) Exceeded() string {
+	if q.MaxConcurrent > 0 && q.Active >= q.MaxConcurrent {
+		return fmt.Sprintf("%d of %d concurrent scans in progress", q.Active, q.MaxConcurrent)
+	}
+	if q.MaxMonthly > 0 && q.ThisMonth >= q.MaxMonthly {
+		return fmt.Sprintf("%d of %d scans this month used", q.ThisMonth, q.MaxMonthly)
+	}
+	return ""
+}
+
+// loadScanQuota returns the organization's quota and usage. lock takes the
+// organization's row until the transaction q ends.
+func loadScanQuota(ctx context.Context, q sqlx.QueryerContext, orgID string, lock bool) (*ScanQuota, error) {
+	query := `
+		SELECT COALESCE(o.subscription_tier, 'free') AS tier,
+		       COALESCE(o.max_concurrent_scans, -1) AS max_concurrent,
+		       COALESCE(o.max_monthly_scans, -1) AS max_monthly,
+		       (SELECT COUNT(*) FROM scan_jobs
+		        WHERE organization_id = o.id AND status IN ('pending', 'running', 'paused')) AS active,
+		       (SELECT COUNT(*) FROM scan_jobs
+		        WHERE organization_id = o.id AND created_at >= date_trunc('month', NOW())
+		        AND import_id IS NULL) AS this_month
+		FROM organizations o
+		WHERE o.id = $1`
+	if lock {
+		query += ` FOR UPDATE OF o`
+	}
+
+	var quota ScanQuota
+	if err := sqlx.GetContext(ctx, q, &quota, query, orgID); err != nil {
+		return nil, err
+	}
+	defaults, ok := tierScanQuotas[quota.Tier]
+	if !ok {
+		defaults = tierScanQuotas["free"]
+	}
+	if quota.MaxConcurrent < 0 {
+		quota.MaxConcurrent = defaults.MaxConcurrent
+	}
+	if quota.MaxMonthly < 0 {
+		quota.MaxMonthly = defaults.MaxMonthly
+	}
+	return &quota, nil
+}
+
+// PreflightCheck is the outcome of one preflight check
+type PreflightCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // pass, warn or fail
+	Message string `json:"message"`
+}
+
+// PreflightResponse is the go/no-go decision for a scan and why
+type PreflightResponse struct {
+	Decision    string              `json:"decision"`
+	Checks      []PreflightCheck    `json:"checks"`
+	Window      *scanwindows.Slot   `json:"window,omitempty"`
+	Estimate    estimation.Estimate `json:"estimate"`
+	Quota       *ScanQuota          `json:"quota,omitempty"`
+	Overlapping []ScanRun           `json:"overlapping"`
+}
+
+// Preflight handles POST /api/v1/scans/preflight. It runs the checks
+// creating the scan would, plus overlap and duration ones, without creating
+// anything; the decision is in the body, so it answers 200 either way.
+func (h *ScanHandler) Preflight(c *gin.Context) {
+	orgID := c.GetString("organization_id")
+	if orgID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization context required"})
+		return
+	}
+
+	var req CreateScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	if req.ScanMode == "" {
+		req.ScanMode = "passive"
+	}
+	if _, ok := estimation.ModeFactors[req.ScanMode]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scan_mode must be passive, active or aggressive"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	failed := func(what string, err error) {
+		logging.FromContext(ctx, h.logger).Error("Scan preflight failed", zap.String("check", what), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run preflight checks"})
+	}
+
+	resp := PreflightResponse{Overlapping: []ScanRun{}}
+	check := func(name, status, message string) {
+		resp.Checks = append(resp.Checks, PreflightCheck{Name: name, Status: status, Message: message})
+	}
+
+	// Authorization or client grant, and the access policies its tags are
+	// subject to
+	target, err := h.resolveTarget(ctx, orgID, req)
+	denial := ""
+	switch {
+	case err == sql.ErrNoRows:
+		denial = "No valid authorization found for this target"
+	case grants.IsDenial(err):
+		denial = "Scan grant does not authorize this scan: " + err.Error()
+	case err != nil:
+		failed("authorization", err)
+		return
+	}
+
+	// Without an authorization the estimate is for a single host
+	params := estimation.Params{OrgID: orgID, ScanType: req.ScanType, ScanMode: req.ScanMode}
+	if target != nil {
+		params.TargetType, params.TargetValue = target.TargetType, target.TargetValue
+	}
+	estimate, err := h.estimates.Estimate(ctx, params)
+	if err != nil {
+		failed("estimate", err)
+		return
+	}
+	resp.Estimate = *estimate
+
+	if target == nil {
+		check("authorization", CheckFail, denial)
+	} else {
+		finish := time.Now().Add(estimate.Duration())
+		if target.ValidUntil.Before(finish) {
+			check("authorization", CheckWarn, "Authorization expires "+target.ValidUntil.UTC().Format(time.RFC3339)+", before the scan is expected to finish")
+		} else {
+			check("authorization", CheckPass, "Authorized until "+target.ValidUntil.UTC().Format(time.RFC3339))
+		}
+
+		decision, err := h.policies.Evaluate(ctx, orgID, rbac.Role(c.GetString("user_role")), rbac.PermCreateScan, rbac.Attributes{
+			TargetTags: target.Tags,
+			ScanType:   req.ScanType,
+		})
+		if err != nil {
+			failed("access_policy", err)
+			return
+		}
+		if decision.Allowed {
+			check("access_policy", CheckPass, "Allowed by access policies")
+		} else {
+			check("access_policy", CheckFail, "Denied by access policy: "+decision.Reason)
+		}
+
+		// Intrusive scans must not pile onto a target already being scanned
+		err = h.db.SelectContext(ctx, &resp.Overlapping, `
+			SELECT sj.id, sj.status, st.target_type, st.target_value, sj.completed_at
+			FROM scan_jobs sj
+			INNER JOIN scan_targets st ON st.id = sj.target_id
+			WHERE sj.organization_id = $1
+			AND sj.status IN ('pending', 'running', 'paused')
+			AND st.target_type = $2 AND st.target_value = $3
+			ORDER BY sj.created_at
+		`, orgID, target.TargetType, target.TargetValue)
+		if err != nil {
+			failed("overlap", err)
+			return
+		}
+		switch {
+		case len(resp.Overlapping) == 0:
+			check("overlap", CheckPass, "No other scans in progress on this target")
+		case req.ScanMode == "passive":
+			check("overlap", CheckWarn, fmt.Sprintf("%d other scan(s) in progress on this target", len(resp.Overlapping)))
+		default:
+			check("overlap", CheckFail, fmt.Sprintf("%d other scan(s) in progress on this target; wait for them before an %s scan", len(resp.Overlapping), req.ScanMode))
+		}
+
+		slot, err := h.windows.Slot(ctx, orgID, target.ID)
+		if err != nil {
+			failed("execution_window", err)
+			return
+		}
+		resp.Window = &slot
+		switch {
+		case slot.Open && slot.NotAfter == nil:
+			check("execution_window", CheckPass, "No execution window restrictions")
+		case slot.Open && slot.NotAfter.Before(finish) && slot.PauseAtEnd:
+			check("execution_window", CheckWarn, "Window closes "+slot.NotAfter.Format(time.RFC3339)+", before the scan is expected to finish; it will be paused until the next one")
+		case slot.Open:
+			check("execution_window", CheckPass, "Inside an execution window until "+slot.NotAfter.Format(time.RFC3339))
+		case slot.NotBefore != nil:
+			check("execution_window", CheckWarn, "Outside execution windows; the scan starts at "+slot.NotBefore.Format(time.RFC3339))
+		default:
+			check("execution_window", CheckFail, "Outside execution windows and none is upcoming")
+		}
+	}
+
+	quota, err := loadScanQuota(ctx, h.db, orgID, false)
+	if err != nil {
+		failed("quota", err)
+		return
+	}
+	resp.Quota = quota
+	if exceeded := quota.Exceeded(); exceeded != "" {
+		check("quota", CheckFail, "Scan quota exceeded: "+exceeded)
+	} else {
+		check("quota", CheckPass, "Within the "+quota.Tier+" tier's scan quota")
+	}
+
+	requiredApprovals, err := h.approvals.Required(ctx, orgID, req.ScanType)
+	if err != nil {
+		failed("approval", err)
+		return
+	}
+	switch {
+	case requiredApprovals == 0:
+		check("approval", CheckPass, "No approval needed")
+	case req.Justification == "":
+		check("approval", CheckFail, fmt.Sprintf("Needs %d approval(s); a justification is required", requiredApprovals))
+	default:
+		check("approval", CheckWarn, fmt.Sprintf("Waits for %d approval(s) before it starts", requiredApprovals))
+	}
+
+	reason, err := h.redis.Get(ctx, EmergencyStopKey).Result()
+	switch {
+	case err == redis.Nil:
+		check("emergency_stop", CheckPass, "No emergency stop in effect")
+	case err != nil:
+		// Scan creation fails open on this too
+		logging.FromContext(ctx, h.logger).Error("Failed to check emergency stop", zap.Error(err))
+		check("emergency_stop", CheckWarn, "Emergency stop state unknown")
+	default:
+		check("emergency_stop", CheckFail, "Emergency stop is active: "+reason)
+	}
+
+	resp.Decision = PreflightGo
+	for _, ch := range resp.Checks {
+		if ch.Status == CheckFail {
+			resp.Decision = PreflightNoGo
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
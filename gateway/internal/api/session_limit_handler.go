@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SessionLimitHandler manages an organization's concurrent session limits
+// per role
+type SessionLimitHandler struct {
+	auth        *auth.AuthService
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewSessionLimitHandler(authService *auth.AuthService, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *SessionLimitHandler {
+	return &SessionLimitHandler{
+		auth:        authService,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// SessionLimitsResponse lists the organization's policies and the defaults
+// for roles without one
+type SessionLimitsResponse struct {
+	Defaults auth.SessionLimitDefaults `json:"defaults"`
+	Policies []auth.SessionLimitPolicy `json:"policies"`
+}
+
+// SetSessionLimitRequest sets the limit for a role; max_sessions 0 is unlimited
+type SetSessionLimitRequest struct {
+	MaxSessions *int   `json:"max_sessions" binding:"required,min=0,max=1000"`
+	OnLimit     string `json:"on_limit" binding:"required,oneof=reject revoke_oldest"`
+}
+
+// ListLimits handles GET /api/v1/organizations/:id/session-limits
+func (h *SessionLimitHandler) ListLimits(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewOrganization, h.logger); !ok {
+		return
+	}
+
+	policies, err := h.auth.ListSessionLimitPolicies(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list session limits", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list session limits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SessionLimitsResponse{
+		Defaults: h.auth.SessionLimitDefaults(),
+		Policies: policies,
+	})
+}
+
+// SetLimit handles PUT /api/v1/organizations/:id/session-limits/:role. The
+// role "*" applies to every role without a policy of its own.
+func (h *SessionLimitHandler) SetLimit(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req SetSessionLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	role := c.Param("role")
+	if role != auth.AnyRole {
+		exists, err := h.roles.RoleExists(c.Request.Context(), orgID, rbac.Role(role))
+		if err != nil {
+			logging.FromContext(c.Request.Context(), h.logger).Error("Failed to check role", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session limit"})
+			return
+		}
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+			return
+		}
+	}
+
+	policy, err := h.auth.SetSessionLimitPolicy(c.Request.Context(), orgID, role, userID, auth.SessionLimit{
+		MaxSessions: *req.MaxSessions,
+		OnLimit:     req.OnLimit,
+	})
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to save session limit", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session limit"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "session_limit_set", "organization", orgID, map[string]interface{}{
+		"role":         role,
+		"max_sessions": policy.MaxSessions,
+		"on_limit":     policy.OnLimit,
+	})
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteLimit handles DELETE /api/v1/organizations/:id/session-limits/:role
+func (h *SessionLimitHandler) DeleteLimit(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	role := c.Param("role")
+	err := h.auth.DeleteSessionLimitPolicy(c.Request.Context(), orgID, role)
+	if err == auth.ErrSessionLimitPolicyNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session limit not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete session limit", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete session limit"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "session_limit_deleted", "organization", orgID, map[string]interface{}{
+		"role": role,
+	})
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/branding"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// OrganizationSettingsRequest replaces an organization's branding; omitted
+// fields are cleared
+type OrganizationSettingsRequest struct {
+	PrimaryColor    *string `json:"primary_color" binding:"omitempty,hexcolor"`
+	SecondaryColor  *string `json:"secondary_color" binding:"omitempty,hexcolor"`
+	AccentColor     *string `json:"accent_color" binding:"omitempty,hexcolor"`
+	ReportFooter    *string `json:"report_footer" binding:"omitempty,max=2000"`
+	EmailSenderName *string `json:"email_sender_name" binding:"omitempty,max=100"`
+}
+
+type SettingsHandler struct {
+	roles       *rbac.RoleStore
+	branding    *branding.Service
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewSettingsHandler(roles *rbac.RoleStore, brandingService *branding.Service, auditLogger Auditor, logger *zap.Logger) *SettingsHandler {
+	return &SettingsHandler{
+		roles:       roles,
+		branding:    brandingService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// GetSettings handles GET /api/v1/organizations/:id/settings
+func (h *SettingsHandler) GetSettings(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewOrganization, h.logger); !ok {
+		return
+	}
+
+	settings, err := h.branding.Get(c.Request.Context(), orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load organization settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load organization settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings handles PUT /api/v1/organizations/:id/settings
+func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req OrganizationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	// The sender name goes into the From header
+	if req.EmailSenderName != nil && strings.ContainsAny(*req.EmailSenderName, "\r\n") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email_sender_name must be a single line"})
+		return
+	}
+
+	settings, err := h.branding.Update(c.Request.Context(), orgID, userID, branding.Update{
+		PrimaryColor:    req.PrimaryColor,
+		SecondaryColor:  req.SecondaryColor,
+		AccentColor:     req.AccentColor,
+		ReportFooter:    req.ReportFooter,
+		EmailSenderName: req.EmailSenderName,
+	})
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to save organization settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save organization settings"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "organization_settings_updated", "organization", orgID, map[string]interface{}{
+		"primary_color":     req.PrimaryColor,
+		"secondary_color":   req.SecondaryColor,
+		"accent_color":      req.AccentColor,
+		"email_sender_name": req.EmailSenderName,
+		"report_footer_set": req.ReportFooter != nil,
+	})
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UploadLogo handles PUT /api/v1/organizations/:id/settings/logo, a
+// multipart form with the image in the "logo" field
+func (h *SettingsHandler) UploadLogo(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	// Leave room for the multipart envelope around the image
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, branding.MaxLogoBytes+64<<10)
+	header, err := c.FormFile("logo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "logo file is required (at most 1 MB)"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read logo"})
+		return
+	}
+	defer file.Close()
+
+	settings, err := h.branding.SetLogo(c.Request.Context(), orgID, userID, header.Filename, file)
+	switch {
+	case err == branding.ErrLogoTooLarge, err == branding.ErrLogoUnsupported:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case abortUpload(c, err, h.auditLogger, userID):
+		return
+	case err != nil:
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to store logo", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store logo"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "organization_logo_updated", "organization", orgID, map[string]interface{}{
+		"filename":     header.Filename,
+		"size":         header.Size,
+		"content_type": settings.LogoContentType,
+		"artifact_id":  settings.LogoArtifactID,
+	})
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// DeleteLogo handles DELETE /api/v1/organizations/:id/settings/logo
+func (h *SettingsHandler) DeleteLogo(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	if err := h.branding.DeleteLogo(c.Request.Context(), orgID, userID); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete logo", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete logo"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "organization_logo_deleted", "organization", orgID, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logo deleted"})
+}
@@ -0,0 +1,625 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/deliveries"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/maintenance"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/slack"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	slackLinkCodePrefix = "slack:link:"
+	slackLinkCodeTTL    = 10 * time.Minute
+
+	// ackAlertAction is the action ID of the Acknowledge button on alert DMs
+	ackAlertAction = "ack_alert"
+)
+
+// SlackHandler serves the Slack app: slash commands, interactive alert
+// actions, and linking Slack workspaces and users to the platform
+type SlackHandler struct {
+	db              *database.DB
+	redis           *redis.Client
+	roles           *rbac.RoleStore
+	alerts          *audit.AlertStore
+	scans           *ScanHandler
+	maintenance     *maintenance.Service
+	client          *slack.Client // Nil when no bot token is configured
+	defaultScanType string
+	auditLogger     Auditor
+	logger          *zap.Logger
+}
+
+func NewSlackHandler(db *database.DB, redisClient *redis.Client, roles *rbac.RoleStore, alerts *audit.AlertStore, scans *ScanHandler, maintenanceService *maintenance.Service, client *slack.Client, defaultScanType string, auditLogger Auditor, logger *zap.Logger) *SlackHandler {
+	return &SlackHandler{
+		db:              db,
+		redis:           redisClient,
+		roles:           roles,
+		alerts:          alerts,
+		scans:           scans,
+		maintenance:     maintenanceService,
+		client:          client,
+		defaultScanType: defaultScanType,
+		auditLogger:     auditLogger,
+		logger:          logger,
+	}
+}
+
+// SlackWorkspaceRequest links a Slack workspace to an organization
+type SlackWorkspaceRequest struct {
+	TeamID   string `json:"team_id" binding:"required,max=32"`
+	TeamName string `json:"team_name" binding:"max=255"`
+}
+
+// SlackWorkspace is a Slack workspace linked to an organization
+type SlackWorkspace struct {
+	TeamID      string    `json:"team_id" db:"team_id"`
+	TeamName    *string   `json:"team_name" db:"team_name"`
+	InstalledBy *string   `json:"installed_by" db:"installed_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	LinkedUsers int       `json:"linked_users" db:"linked_users"`
+}
+
+// SlackLinkCodeResponse is a one-time code for /cyscan link
+type SlackLinkCodeResponse struct {
+	Code      string    `json:"code"`
+	Command   string    `json:"command"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// slackCaller is a Slack user resolved to a platform user and organization
+type slackCaller struct {
+	TeamID      string
+	SlackUserID string
+	UserID      string
+	OrgID       string
+}
+
+const slackHelp = "*Cyper commands*\n" +
+	"`/cyscan start <target> [profile]` queue a scan of an authorized target\n" +
+	"`/cyscan status [scan_id]` show a scan, or your organization's latest scans\n" +
+	"`/cyscan link <code>` link your Slack account (get a code from your Cyper profile)\n" +
+	"`/cyscan help` show this help"
+
+// Command handles POST /api/v1/integrations/slack/commands. Replies are
+// ephemeral so only the caller sees them.
+func (h *SlackHandler) Command(c *gin.Context) {
+	ctx := c.Request.Context()
+	teamID := c.PostForm("team_id")
+	slackUserID := c.PostForm("user_id")
+	args := strings.Fields(c.PostForm("text"))
+
+	if len(args) == 0 || args[0] == "help" {
+		c.JSON(http.StatusOK, slack.Ephemeral(slackHelp))
+		return
+	}
+
+	if args[0] == "link" {
+		if len(args) != 2 {
+			c.JSON(http.StatusOK, slack.Ephemeral("Usage: `/cyscan link <code>`"))
+			return
+		}
+		c.JSON(http.StatusOK, slack.Ephemeral(h.linkUser(ctx, teamID, slackUserID, args[1])))
+		return
+	}
+
+	caller, reply := h.resolveCaller(ctx, teamID, slackUserID)
+	if caller == nil {
+		c.JSON(http.StatusOK, slack.Ephemeral(reply))
+		return
+	}
+	if state := h.maintenance.Current(); state.Active {
+		c.JSON(http.StatusOK, slack.Ephemeral("Cyper is under maintenance. "+state.Message))
+		return
+	}
+
+	switch args[0] {
+	case "start":
+		c.JSON(http.StatusOK, slack.Ephemeral(h.startScan(ctx, caller, args[1:])))
+	case "status":
+		c.JSON(http.StatusOK, slack.Ephemeral(h.scanStatus(ctx, caller, args[1:])))
+	default:
+		c.JSON(http.StatusOK, slack.Ephemeral(fmt.Sprintf("Unknown command `%s`.\n%s", args[0], slackHelp)))
+	}
+}
+
+// Interaction handles POST /api/v1/integrations/slack/interactions
+// (button clicks on messages the gateway posted)
+func (h *SlackHandler) Interaction(c *gin.Context) {
+	var payload slack.Interaction
+	if err := json.Unmarshal([]byte(c.PostForm("payload")), &payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+
+	// Slack wants an answer within three seconds; anything else is ignored
+	c.Status(http.StatusOK)
+
+	if payload.Type != "block_actions" {
+		return
+	}
+	for _, action := range payload.Actions {
+		if action.ActionID == ackAlertAction {
+			h.acknowledgeAlert(c.Request.Context(), payload, action.Value)
+		}
+	}
+}
+
+// CreateLinkCode handles POST /api/v1/users/me/slack/link-code
+func (h *SlackHandler) CreateLinkCode(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to generate link code", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link code"})
+		return
+	}
+	code := base32.StdEncoding.EncodeToString(buf)
+
+	if err := h.redis.Set(c.Request.Context(), slackLinkCodePrefix+code, userID, slackLinkCodeTTL).Err(); err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to store link code", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link code"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, SlackLinkCodeResponse{
+		Code:      code,
+		Command:   "/cyscan link " + code,
+		ExpiresAt: time.Now().Add(slackLinkCodeTTL),
+	})
+}
+
+// UnlinkUser handles DELETE /api/v1/users/me/slack
+func (h *SlackHandler) UnlinkUser(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result, err := h.db.ExecContext(c.Request.Context(), `DELETE FROM slack_user_links WHERE user_id = $1`, userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to unlink Slack account", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink Slack account"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No linked Slack account"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "slack_user_unlinked", "user", userID, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// ListWorkspaces handles GET /api/v1/organizations/:id/integrations/slack
+func (h *SlackHandler) ListWorkspaces(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewOrganization, h.logger); !ok {
+		return
+	}
+
+	var workspaces []SlackWorkspace
+	err := h.db.SelectContext(c.Request.Context(), &workspaces, `
+		SELECT w.team_id, w.team_name, w.installed_by, w.created_at,
+		       (SELECT COUNT(*) FROM slack_user_links l WHERE l.team_id = w.team_id) AS linked_users
+		FROM slack_workspaces w
+		WHERE w.organization_id = $1
+		ORDER BY w.created_at
+	`, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list Slack workspaces", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list Slack workspaces"})
+		return
+	}
+	if workspaces == nil {
+		workspaces = []SlackWorkspace{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaces})
+}
+
+// LinkWorkspace handles PUT /api/v1/organizations/:id/integrations/slack
+func (h *SlackHandler) LinkWorkspace(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+
+	var req SlackWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	// A workspace already linked to another organization is not taken over
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		INSERT INTO slack_workspaces (team_id, organization_id, team_name, installed_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		ON CONFLICT (team_id) DO UPDATE SET team_name = EXCLUDED.team_name
+		WHERE slack_workspaces.organization_id = EXCLUDED.organization_id
+	`, req.TeamID, orgID, req.TeamName, userID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to link Slack workspace", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link Slack workspace"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Slack workspace is linked to another organization"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "slack_workspace_linked", "organization", orgID, map[string]interface{}{
+		"team_id": req.TeamID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"team_id": req.TeamID, "organization_id": orgID})
+}
+
+// UnlinkWorkspace handles DELETE /api/v1/organizations/:id/integrations/slack/:team_id.
+// Linked users of the workspace are removed with it.
+func (h *SlackHandler) UnlinkWorkspace(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger)
+	if !ok {
+		return
+	}
+	teamID := c.Param("team_id")
+
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		DELETE FROM slack_workspaces WHERE team_id = $1 AND organization_id = $2
+	`, teamID, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to unlink Slack workspace", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink Slack workspace"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Slack workspace not found"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "slack_workspace_unlinked", "organization", orgID, map[string]interface{}{
+		"team_id": teamID,
+	})
+
+	c.Status(http.StatusNoContent)
+}
+
+// SlackAlertNotifier sends anomaly alerts to the user's linked Slack accounts
+// as DMs with an Acknowledge button
+func SlackAlertNotifier(db *database.DB, deliveryService *deliveries.Service, logger *zap.Logger) audit.AlertFunc {
+	return func(userID string, anomaly audit.Anomaly) {
+		notifySlackAnomaly(db, deliveryService, logger, userID, anomaly)
+	}
+}
+
+func notifySlackAnomaly(db *database.DB, deliveryService *deliveries.Service, logger *zap.Logger, userID string, anomaly audit.Anomaly) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// DMs are logged as deliveries of the organization the workspace is linked to
+	var links []struct {
+		SlackUserID string `db:"slack_user_id"`
+		OrgID       string `db:"organization_id"`
+	}
+	err := db.SelectContext(ctx, &links, `
+		SELECT l.slack_user_id, w.organization_id
+		FROM slack_user_links l
+		JOIN slack_workspaces w ON w.team_id = l.team_id
+		WHERE l.user_id = $1
+	`, userID)
+	if err != nil {
+		logger.Error("Failed to load Slack links", zap.Error(err))
+		return
+	}
+
+	text := fmt.Sprintf(":rotating_light: *Security alert: %s*\n%d events", anomaly.Kind, anomaly.Count)
+	if anomaly.Target != "" {
+		text += fmt.Sprintf(" against `%s`", anomaly.Target)
+	}
+	text += " at " + anomaly.DetectedAt.UTC().Format(time.RFC1123)
+	alertID := anomaly.AlertID()
+
+	for _, link := range links {
+		_, err := deliveryService.SendSlack(ctx, deliveries.SlackMessage{
+			OrgID:    link.OrgID,
+			Source:   deliveries.SourceSecurityAlert,
+			SourceID: alertID,
+			Event:    "security_alert." + anomaly.Kind,
+			Message: slack.Message{
+				Channel: link.SlackUserID,
+				Text:    text,
+				Blocks: []interface{}{
+					slack.Section(text),
+					slack.Actions(slack.Button(ackAlertAction, "Acknowledge", alertID)),
+				},
+			},
+		})
+		if err != nil {
+			logger.Warn("Failed to send Slack alert", zap.String("user_id", userID), zap.Error(err))
+		}
+	}
+}
+
+// resolveCaller maps a Slack user to their linked platform user and the
+// workspace's organization. On failure the reply explains what to do.
+func (h *SlackHandler) resolveCaller(ctx context.Context, teamID, slackUserID string) (*slackCaller, string) {
+	var userID sql.NullString
+	caller := slackCaller{TeamID: teamID, SlackUserID: slackUserID}
+	err := h.db.QueryRowContext(ctx, `
+		SELECT l.user_id, w.organization_id
+		FROM slack_workspaces w
+		LEFT JOIN slack_user_links l ON l.team_id = w.team_id AND l.slack_user_id = $2
+		WHERE w.team_id = $1
+	`, teamID, slackUserID).Scan(&userID, &caller.OrgID)
+	if err == sql.ErrNoRows {
+		return nil, "This Slack workspace is not connected to a Cyper organization."
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to resolve Slack user", zap.Error(err))
+		return nil, "Something went wrong, please try again."
+	}
+	if !userID.Valid {
+		return nil, "Your Slack account is not linked yet. Create a link code in your Cyper profile, then run `/cyscan link <code>`."
+	}
+	caller.UserID = userID.String
+	return &caller, ""
+}
+
+// authorize checks the caller still belongs to the organization with perm,
+// returning their role
+func (h *SlackHandler) authorize(ctx context.Context, caller *slackCaller, perm rbac.Permission) (rbac.Role, string) {
+	role, err := h.roles.MemberRole(ctx, caller.UserID, caller.OrgID)
+	if err == rbac.ErrNotMember {
+		return "", "You are not a member of the organization this workspace is connected to."
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to verify membership", zap.Error(err))
+		return "", "Something went wrong, please try again."
+	}
+
+	allowed, err := h.roles.HasPermission(ctx, caller.OrgID, role, perm)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to resolve role permissions", zap.Error(err))
+		return "", "Something went wrong, please try again."
+	}
+	if !allowed {
+		return "", "You do not have permission to do that."
+	}
+	return role, ""
+}
+
+// linkUser redeems a link code for the Slack user
+func (h *SlackHandler) linkUser(ctx context.Context, teamID, slackUserID, code string) string {
+	userID, err := h.redis.GetDel(ctx, slackLinkCodePrefix+strings.ToUpper(code)).Result()
+	if err == redis.Nil {
+		return "That link code is invalid or has expired."
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to redeem link code", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+
+	var orgID string
+	err = h.db.GetContext(ctx, &orgID, `SELECT organization_id FROM slack_workspaces WHERE team_id = $1`, teamID)
+	if err == sql.ErrNoRows {
+		return "This Slack workspace is not connected to a Cyper organization."
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load Slack workspace", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+
+	if _, err := h.roles.MemberRole(ctx, userID, orgID); err == rbac.ErrNotMember {
+		return "Your Cyper account is not a member of the organization this workspace is connected to."
+	} else if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to verify membership", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+
+	tx, err := h.db.BeginTxx(ctx, nil)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to begin transaction", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+	defer tx.Rollback()
+
+	// Each platform user has at most one Slack account per workspace
+	if _, err := tx.ExecContext(ctx, `DELETE FROM slack_user_links WHERE team_id = $1 AND user_id = $2`, teamID, userID); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to replace Slack link", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO slack_user_links (team_id, slack_user_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (team_id, slack_user_id) DO UPDATE SET user_id = EXCLUDED.user_id, linked_at = NOW()
+	`, teamID, slackUserID, userID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to link Slack user", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to commit Slack link", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "slack_user_linked", "user", userID, map[string]interface{}{
+		"team_id":       teamID,
+		"slack_user_id": slackUserID,
+	})
+
+	return ":white_check_mark: Your Slack account is now linked to Cyper."
+}
+
+// startScan handles /cyscan start <target> [profile]. The target must match
+// an approved authorization of the organization; the profile is the scan type.
+func (h *SlackHandler) startScan(ctx context.Context, caller *slackCaller, args []string) string {
+	if len(args) == 0 || len(args) > 2 {
+		return "Usage: `/cyscan start <target> [profile]`"
+	}
+	scanType := h.defaultScanType
+	if len(args) == 2 {
+		scanType = args[1]
+	}
+
+	role, reply := h.authorize(ctx, caller, rbac.PermCreateScan)
+	if role == "" {
+		return reply
+	}
+
+	if _, err := h.redis.Get(ctx, EmergencyStopKey).Result(); err == nil {
+		return "Emergency stop is active; all scan operations are suspended."
+	}
+
+	var authorizationID string
+	err := h.db.GetContext(ctx, &authorizationID, `
+		SELECT id FROM authorized_targets
+		WHERE organization_id = $1
+		AND target_value = $2
+		AND verification_status = 'approved'
+		AND valid_from <= NOW()
+		AND valid_until >= NOW()
+		ORDER BY valid_until DESC
+		LIMIT 1
+	`, caller.OrgID, args[0])
+	if err == sql.ErrNoRows {
+		return fmt.Sprintf("No valid authorization found for `%s`.", args[0])
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load authorization", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+
+	job, scanErr := h.scans.startScan(ctx, scanRequester{
+		UserID:    caller.UserID,
+		OrgID:     caller.OrgID,
+		Role:      role,
+		UserAgent: "Slack",
+	}, CreateScanRequest{
+		AuthorizationTargetID: authorizationID,
+		ScanType:              scanType,
+	})
+	if scanErr != nil {
+		if scanErr.reason != "" {
+			return fmt.Sprintf("Could not start the scan: %s (%s)", scanErr.message, scanErr.reason)
+		}
+		return "Could not start the scan: " + scanErr.message
+	}
+
+	return fmt.Sprintf(":white_check_mark: Queued %s scan of `%s`\nScan ID: `%s`", job.ScanType, job.TargetValue, job.ID)
+}
+
+// scanStatus handles /cyscan status [scan_id]
+func (h *SlackHandler) scanStatus(ctx context.Context, caller *slackCaller, args []string) string {
+	if role, reply := h.authorize(ctx, caller, rbac.PermViewScan); role == "" {
+		return reply
+	}
+
+	if len(args) > 0 {
+		if _, err := uuid.Parse(args[0]); err != nil {
+			return "Invalid scan ID."
+		}
+	}
+
+	var scans []ScanRun
+	query := `
+		SELECT sj.id, sj.status, st.target_type, st.target_value, sj.completed_at
+		FROM scan_jobs sj
+		JOIN scan_targets st ON st.id = sj.target_id
+		WHERE sj.organization_id = $1
+	`
+	var err error
+	if len(args) > 0 {
+		err = h.db.SelectContext(ctx, &scans, query+` AND sj.id = $2`, caller.OrgID, args[0])
+	} else {
+		err = h.db.SelectContext(ctx, &scans, query+` ORDER BY sj.created_at DESC LIMIT 5`, caller.OrgID)
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load scans", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+	if len(scans) == 0 {
+		if len(args) > 0 {
+			return "Scan not found."
+		}
+		return "No scans yet."
+	}
+
+	var b strings.Builder
+	for _, scan := range scans {
+		fmt.Fprintf(&b, "`%s` %s — *%s*", scan.ID, scan.TargetValue, scan.Status)
+		if scan.CompletedAt != nil {
+			fmt.Fprintf(&b, " (finished %s)", scan.CompletedAt.UTC().Format(time.RFC1123))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// acknowledgeAlert records who acknowledged an alert and replaces the
+// buttons on the original message
+func (h *SlackHandler) acknowledgeAlert(ctx context.Context, payload slack.Interaction, alertID string) {
+	reply := h.acknowledge(ctx, payload, alertID)
+	if h.client == nil {
+		return
+	}
+	if reply != "" {
+		h.client.Respond(ctx, payload.ResponseURL, slack.Ephemeral(reply))
+		return
+	}
+	err := h.client.Respond(ctx, payload.ResponseURL, slack.Message{
+		Text:            fmt.Sprintf(":white_check_mark: Alert acknowledged by <@%s>", payload.User.ID),
+		ReplaceOriginal: true,
+	})
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Warn("Failed to update Slack alert", zap.Error(err))
+	}
+}
+
+// acknowledge acknowledges the alert for the workspace's organization,
+// returning the reply explaining why not when it fails
+func (h *SlackHandler) acknowledge(ctx context.Context, payload slack.Interaction, alertID string) string {
+	caller, reply := h.resolveCaller(ctx, payload.Team.ID, payload.User.ID)
+	if caller == nil {
+		return reply
+	}
+	if _, reply := h.authorize(ctx, caller, rbac.PermViewAuditLogs); reply != "" {
+		return reply
+	}
+
+	alert, err := h.alerts.Acknowledge(ctx, caller.OrgID, alertID, caller.UserID, "slack")
+	switch {
+	case errors.Is(err, audit.ErrAlertNotFound):
+		return "That alert was not raised for the organization this workspace is connected to."
+	case errors.Is(err, audit.ErrAlertAcknowledged):
+		return "That alert was already acknowledged."
+	case err != nil:
+		logging.FromContext(ctx, h.logger).Error("Failed to acknowledge alert", zap.Error(err))
+		return "Something went wrong, please try again."
+	}
+
+	h.auditLogger.LogSuccess(ctx, caller.UserID, "alert_acknowledged", "anomaly_alert", alert.ID, map[string]interface{}{
+		"organization_id": alert.OrganizationID,
+		"team_id":         caller.TeamID,
+		"slack_user_id":   caller.SlackUserID,
+		"via":             "slack",
+	})
+	return ""
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/stats"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type StatsHandler struct {
+	stats  *stats.Service
+	roles  *rbac.RoleStore
+	logger *zap.Logger
+}
+
+func NewStatsHandler(statsService *stats.Service, roles *rbac.RoleStore, logger *zap.Logger) *StatsHandler {
+	return &StatsHandler{
+		stats:  statsService,
+		roles:  roles,
+		logger: logger,
+	}
+}
+
+// GetOrganizationStats handles GET /api/v1/organizations/:id/stats?days=30
+func (h *StatsHandler) GetOrganizationStats(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewScan, h.logger); !ok {
+		return
+	}
+
+	days := 30
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+		days = n
+	}
+
+	result, err := h.stats.OrgStats(c.Request.Context(), orgID, days, 10)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load organization stats", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
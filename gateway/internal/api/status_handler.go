@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/status"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StatusHandler serves the public status page. Responses come from the
+// status service's cache, so the routes need no authentication or rate limit.
+type StatusHandler struct {
+	status *status.Service
+	logger *zap.Logger
+}
+
+func NewStatusHandler(statusService *status.Service, logger *zap.Logger) *StatusHandler {
+	return &StatusHandler{
+		status: statusService,
+		logger: logger,
+	}
+}
+
+// GetStatus handles GET /api/v1/status
+func (h *StatusHandler) GetStatus(c *gin.Context) {
+	summary, ok := h.summary(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetComponents handles GET /api/v1/status/components
+func (h *StatusHandler) GetComponents(c *gin.Context) {
+	summary, ok := h.summary(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, summary.Components)
+}
+
+// GetUptime handles GET /api/v1/status/uptime
+func (h *StatusHandler) GetUptime(c *gin.Context) {
+	uptime, err := h.status.Uptime(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load uptime history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load uptime history"})
+		return
+	}
+	c.JSON(http.StatusOK, uptime)
+}
+
+func (h *StatusHandler) summary(c *gin.Context) (*status.Summary, bool) {
+	summary, err := h.status.Summary(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load system status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load system status"})
+		return nil, false
+	}
+	return summary, true
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/intel"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// SuppressionHandler manages the rules that suppress known false positives
+// when scan results are ingested
+type SuppressionHandler struct {
+	db          *database.DB
+	roles       *rbac.RoleStore
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewSuppressionHandler(db *database.DB, roles *rbac.RoleStore, auditLogger Auditor, logger *zap.Logger) *SuppressionHandler {
+	return &SuppressionHandler{
+		db:          db,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// CreateSuppressionRuleRequest needs at least one of plugin_id, cve_id and
+// path_pattern
+type CreateSuppressionRuleRequest struct {
+	Asset         string     `json:"asset" binding:"max=2048"`
+	PluginID      string     `json:"plugin_id" binding:"max=100"`
+	CVEID         string     `json:"cve_id" binding:"max=20"`
+	PathPattern   string     `json:"path_pattern" binding:"max=500"`
+	Justification string     `json:"justification" binding:"required,max=2000"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+}
+
+// ListRules handles GET /api/v1/organizations/:id/suppression-rules
+func (h *SuppressionHandler) ListRules(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermViewScan, h.logger); !ok {
+		return
+	}
+
+	rules := []findings.SuppressionRule{}
+	err := h.db.SelectContext(c.Request.Context(), &rules, `
+		SELECT `+findings.SuppressionRuleColumns+` FROM finding_suppression_rules
+		WHERE organization_id = $1
+		AND ($2 OR expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC
+	`, orgID, c.Query("include_expired") == "true")
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list suppression rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list suppression rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateRule handles POST /api/v1/organizations/:id/suppression-rules. The
+// rule applies to findings ingested from now on.
+func (h *SuppressionHandler) CreateRule(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermTriageFinding, h.logger)
+	if !ok {
+		return
+	}
+
+	var req CreateSuppressionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	req.Justification = strings.TrimSpace(req.Justification)
+	// Stored upper-cased, as intelligence and findings keep CVE IDs
+	cveID, validCVE := intel.NormalizeCVE(req.CVEID)
+	switch {
+	case req.PluginID == "" && cveID == "" && req.PathPattern == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one of plugin_id, cve_id and path_pattern is required"})
+		return
+	case cveID != "" && !validCVE:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cve_id must look like CVE-2024-12345"})
+		return
+	case req.Justification == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A justification is required"})
+		return
+	case req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var rule findings.SuppressionRule
+	err := h.db.GetContext(ctx, &rule, `
+		INSERT INTO finding_suppression_rules (
+			organization_id, asset, plugin_id, cve_id, path_pattern, justification, expires_at, created_by
+		) VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8)
+		RETURNING `+findings.SuppressionRuleColumns,
+		orgID, req.Asset, req.PluginID, cveID, req.PathPattern, req.Justification, req.ExpiresAt, userID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to create suppression rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create suppression rule"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, userID, "suppression_rule_created", "suppression_rule", rule.ID, map[string]interface{}{
+		"organization_id": orgID,
+		"asset":           rule.Asset,
+		"plugin_id":       rule.PluginID,
+		"cve_id":          rule.CVEID,
+		"path_pattern":    rule.PathPattern,
+		"justification":   rule.Justification,
+		"expires_at":      rule.ExpiresAt,
+	})
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteRule handles DELETE /api/v1/organizations/:id/suppression-rules/:rule_id.
+// Findings it already suppressed stay suppressed.
+func (h *SuppressionHandler) DeleteRule(c *gin.Context) {
+	orgID := c.Param("id")
+	userID, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermTriageFinding, h.logger)
+	if !ok {
+		return
+	}
+
+	ruleID := c.Param("rule_id")
+	if _, err := uuid.Parse(ruleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return
+	}
+
+	result, err := h.db.ExecContext(c.Request.Context(), `
+		DELETE FROM finding_suppression_rules WHERE id = $1 AND organization_id = $2
+	`, ruleID, orgID)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to delete suppression rule", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete suppression rule"})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suppression rule not found"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(c.Request.Context(), userID, "suppression_rule_deleted", "suppression_rule", ruleID, map[string]interface{}{
+		"organization_id": orgID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suppression rule deleted"})
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/tasks"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// TaskHandler is the admin view of background tasks
+type TaskHandler struct {
+	tasks       *tasks.Service
+	authService Authenticator
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewTaskHandler(taskService *tasks.Service, authService Authenticator, auditLogger Auditor, logger *zap.Logger) *TaskHandler {
+	return &TaskHandler{
+		tasks:       taskService,
+		authService: authService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// TasksResponse lists tasks, newest first
+type TasksResponse struct {
+	Tasks  []tasks.Task `json:"tasks"`
+	Count  int          `json:"count"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// TaskQueuesResponse summarizes the task queues
+type TaskQueuesResponse struct {
+	Queues []tasks.QueueStats `json:"queues"`
+}
+
+// ListTasks handles GET /api/v1/admin/tasks
+func (h *TaskHandler) ListTasks(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	filter := tasks.Filter{
+		State: c.Query("state"),
+		Queue: c.Query("queue"),
+		Kind:  c.Query("kind"),
+		Limit: 100,
+	}
+	switch filter.State {
+	case "", tasks.StatePending, tasks.StateRunning, tasks.StateSucceeded, tasks.StateFailed, tasks.StateCancelled:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state"})
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		filter.Limit = n
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		filter.Offset = n
+	}
+
+	list, err := h.tasks.List(c.Request.Context(), filter)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list tasks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tasks"})
+		return
+	}
+	c.JSON(http.StatusOK, TasksResponse{Tasks: list, Count: len(list), Limit: filter.Limit, Offset: filter.Offset})
+}
+
+// ListQueues handles GET /api/v1/admin/tasks/queues
+func (h *TaskHandler) ListQueues(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	queues, err := h.tasks.Queues(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to summarize task queues", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarize task queues"})
+		return
+	}
+	c.JSON(http.StatusOK, TaskQueuesResponse{Queues: queues})
+}
+
+// GetTask handles GET /api/v1/admin/tasks/:id
+func (h *TaskHandler) GetTask(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	taskID, ok := h.taskID(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.tasks.Get(c.Request.Context(), taskID)
+	if err != nil {
+		h.respondTaskError(c, err, "Failed to get task")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// RetryTask handles POST /api/v1/admin/tasks/:id/retry: a failed or
+// cancelled task runs again now with its attempts reset
+func (h *TaskHandler) RetryTask(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	taskID, ok := h.taskID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	task, err := h.tasks.Retry(ctx, taskID)
+	if err != nil {
+		h.respondTaskError(c, err, "Failed to retry task")
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, c.GetString("user_id"), "task_retried", "task", task.ID, map[string]interface{}{
+		"queue": task.Queue,
+		"kind":  task.Kind,
+	})
+	c.JSON(http.StatusOK, task)
+}
+
+// CancelTask handles POST /api/v1/admin/tasks/:id/cancel. A running task
+// stops once its worker notices, within a third of the lock timeout.
+func (h *TaskHandler) CancelTask(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	taskID, ok := h.taskID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	task, err := h.tasks.Cancel(ctx, taskID)
+	if err != nil {
+		h.respondTaskError(c, err, "Failed to cancel task")
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, c.GetString("user_id"), "task_cancelled", "task", task.ID, map[string]interface{}{
+		"queue": task.Queue,
+		"kind":  task.Kind,
+		"state": task.State,
+	})
+	status := http.StatusOK
+	if task.State == tasks.StateRunning {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, task)
+}
+
+func (h *TaskHandler) taskID(c *gin.Context) (string, bool) {
+	taskID := c.Param("id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return "", false
+	}
+	return taskID, true
+}
+
+func (h *TaskHandler) respondTaskError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, tasks.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	case errors.Is(err, tasks.ErrNotRetryable), errors.Is(err, tasks.ErrNotCancellable), errors.Is(err, tasks.ErrDuplicate):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(c.Request.Context(), h.logger).Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/tasks"
+	"github.com/cyper-security/gateway/internal/tenancy"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// TenancyHandler is the admin view of organizations' database isolation
+type TenancyHandler struct {
+	tenancy     *tenancy.Service
+	orgs        repository.OrgRepo
+	authService Authenticator
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+func NewTenancyHandler(tenancyService *tenancy.Service, orgs repository.OrgRepo, authService Authenticator, auditLogger Auditor, logger *zap.Logger) *TenancyHandler {
+	return &TenancyHandler{
+		tenancy:     tenancyService,
+		orgs:        orgs,
+		authService: authService,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// TenantSchemasResponse lists the organizations with a schema of their own
+type TenantSchemasResponse struct {
+	Schemas []tenancy.Schema `json:"schemas"`
+	Count   int              `json:"count"`
+	// Version is the tenant migration every schema is brought to at startup
+	Version int `json:"version"`
+}
+
+type SetIsolationRequest struct {
+	Isolation string `json:"isolation" binding:"required,oneof=shared schema"`
+}
+
+// ListTenantSchemas handles GET /api/v1/admin/tenant-schemas
+func (h *TenancyHandler) ListTenantSchemas(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	schemas, err := h.tenancy.List(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list tenant schemas", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tenant schemas"})
+		return
+	}
+	c.JSON(http.StatusOK, TenantSchemasResponse{Schemas: schemas, Count: len(schemas), Version: h.tenancy.Version()})
+}
+
+// SetIsolation handles PUT /api/v1/admin/organizations/:id/isolation. Moving
+// an organization's evidence in or out of its own schema runs as a
+// background task, returned with 202 Accepted.
+func (h *TenancyHandler) SetIsolation(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+	orgID := c.Param("id")
+	if _, err := uuid.Parse(orgID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+	var req SetIsolationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	org, err := h.orgs.Get(ctx, orgID)
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load organization", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load organization"})
+		return
+	}
+	schema, err := h.tenancy.Get(ctx, orgID)
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to load tenant schema", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tenant schema"})
+		return
+	}
+
+	var task *tasks.Task
+	switch req.Isolation {
+	case tenancy.IsolationSchema:
+		if schema != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Organization already has its own schema"})
+			return
+		}
+		if org.SubscriptionTier != "enterprise" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Schema isolation requires the enterprise tier"})
+			return
+		}
+		task, err = h.tenancy.Isolate(ctx, orgID, c.GetString("user_id"))
+	case tenancy.IsolationShared:
+		if schema == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Organization already uses the shared tables"})
+			return
+		}
+		task, err = h.tenancy.Share(ctx, orgID, c.GetString("user_id"))
+	}
+	if errors.Is(err, tasks.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The organization's isolation is already being changed"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, h.logger).Error("Failed to queue isolation change", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue isolation change"})
+		return
+	}
+
+	h.auditLogger.LogSuccess(ctx, c.GetString("user_id"), "organization_isolation_changed", "organization", orgID, map[string]interface{}{
+		"isolation": req.Isolation,
+		"task_id":   task.ID,
+	})
+	c.JSON(http.StatusAccepted, task)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/uploads"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UploadHandler lists an organization's uploads with their scan verdicts
+type UploadHandler struct {
+	uploads *uploads.Service
+	roles   *rbac.RoleStore
+	logger  *zap.Logger
+}
+
+func NewUploadHandler(uploadService *uploads.Service, roles *rbac.RoleStore, logger *zap.Logger) *UploadHandler {
+	return &UploadHandler{
+		uploads: uploadService,
+		roles:   roles,
+		logger:  logger,
+	}
+}
+
+type UploadsResponse struct {
+	Uploads []uploads.Artifact `json:"uploads"`
+}
+
+// ListUploads handles GET /api/v1/organizations/:id/uploads?kind=evidence&limit=100,
+// newest first, quarantined uploads included
+func (h *UploadHandler) ListUploads(c *gin.Context) {
+	orgID := c.Param("id")
+	if _, ok := authorizeOrgMember(c, h.roles, orgID, rbac.PermManageOrganization, h.logger); !ok {
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && kind != uploads.KindLogo && kind != uploads.KindEvidence {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be logo or evidence"})
+		return
+	}
+	limit := 100
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	artifacts, err := h.uploads.List(c.Request.Context(), orgID, kind, limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list uploads", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list uploads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, UploadsResponse{Uploads: artifacts})
+}
+
+// abortUpload answers an upload refused by the content scanner, auditing
+// quarantines, and reports whether it did
+func abortUpload(c *gin.Context, err error, auditLogger Auditor, userID string) bool {
+	var quarantined *uploads.QuarantineError
+	switch {
+	case errors.As(err, &quarantined):
+		artifact := quarantined.Artifact
+		auditLogger.LogSecurityEvent(c.Request.Context(), userID, "upload_quarantined", artifact.Filename, "high", map[string]interface{}{
+			"artifact_id": artifact.ID,
+			"kind":        artifact.Kind,
+			"sha256":      artifact.SHA256,
+			"signature":   artifact.ScanSignature,
+			"scanner":     artifact.Scanner,
+		})
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":       "the file was flagged by the content scanner and quarantined",
+			"code":        "upload_quarantined",
+			"artifact_id": artifact.ID,
+		})
+		return true
+	case errors.Is(err, uploads.ErrScanUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "uploads cannot be scanned right now; try again later"})
+		return true
+	}
+	return false
+}
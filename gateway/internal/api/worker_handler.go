@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/workers"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// WorkerHandler serves scanner worker registration and heartbeats, and the
+// admin view of the worker fleet
+type WorkerHandler struct {
+	registry    *workers.Registry
+	authService Authenticator
+	logger      *zap.Logger
+}
+
+func NewWorkerHandler(registry *workers.Registry, authService Authenticator, logger *zap.Logger) *WorkerHandler {
+	return &WorkerHandler{
+		registry:    registry,
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+// Register handles POST /api/v1/workers/register
+func (h *WorkerHandler) Register(c *gin.Context) {
+	var req workers.Registration
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ack, err := h.registry.Register(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to register worker", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register worker"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ack)
+}
+
+// Heartbeat handles POST /api/v1/workers/:id/heartbeat
+func (h *WorkerHandler) Heartbeat(c *gin.Context) {
+	workerID := c.Param("id")
+	if _, err := uuid.Parse(workerID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid worker ID"})
+		return
+	}
+
+	var req workers.Heartbeat
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	ack, err := h.registry.Heartbeat(c.Request.Context(), workerID, req)
+	if err == workers.ErrWorkerNotFound {
+		// The worker should register again
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not registered"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to record worker heartbeat", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record heartbeat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ack)
+}
+
+// ListWorkers handles GET /api/v1/admin/workers
+func (h *WorkerHandler) ListWorkers(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", workers.StatusOnline, workers.StatusDraining, workers.StatusOffline:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be online, draining or offline"})
+		return
+	}
+
+	list, err := h.registry.List(c.Request.Context(), status)
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to list workers", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetWorker handles GET /api/v1/admin/workers/:id
+func (h *WorkerHandler) GetWorker(c *gin.Context) {
+	if !requirePlatformAdmin(c, h.authService, h.logger) {
+		return
+	}
+
+	workerID := c.Param("id")
+	if _, err := uuid.Parse(workerID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid worker ID"})
+		return
+	}
+
+	worker, err := h.registry.Get(c.Request.Context(), workerID)
+	if err == workers.ErrWorkerNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to load worker", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load worker"})
+		return
+	}
+
+	c.JSON(http.StatusOK, worker)
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ScanCommander carries out WebSocket commands through the same checks and
+// code paths as the REST endpoints they mirror
+type ScanCommander struct {
+	scans       *ScanHandler
+	roles       *rbac.RoleStore
+	alerts      *audit.AlertStore
+	redis       *redis.Client
+	auditLogger Auditor
+	logger      *zap.Logger
+}
+
+var _ realtime.Commander = (*ScanCommander)(nil)
+
+func NewScanCommander(scans *ScanHandler, roles *rbac.RoleStore, alerts *audit.AlertStore, redisClient *redis.Client, auditLogger Auditor, logger *zap.Logger) *ScanCommander {
+	return &ScanCommander{
+		scans:       scans,
+		roles:       roles,
+		alerts:      alerts,
+		redis:       redisClient,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// AlertAcknowledgement answers acknowledge_alert
+type AlertAcknowledgement struct {
+	AlertID        string    `json:"alert_id"`
+	AcknowledgedAt time.Time `json:"acknowledged_at"`
+}
+
+// commandErrorCodes names REST statuses for command errors
+var commandErrorCodes = map[int]string{
+	http.StatusBadRequest:          "invalid_request",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusConflict:            "conflict",
+	http.StatusTooManyRequests:     "quota_exceeded",
+	http.StatusServiceUnavailable:  "emergency_stop",
+	http.StatusInternalServerError: "internal_error",
+}
+
+// Execute runs one command for the connection's user
+func (s *ScanCommander) Execute(ctx context.Context, caller realtime.Identity, msgType string, command interface{}) (interface{}, error) {
+	switch cmd := command.(type) {
+	case *realtime.StopScanCommand:
+		requester, err := s.authorize(ctx, caller, rbac.PermStopScan)
+		if err != nil {
+			return nil, err
+		}
+		resp, scanErr := s.scans.applyControl(ctx, requester, cmd.ScanID, "stop", cmd.Reason)
+		if scanErr != nil {
+			return nil, commandError(scanErr)
+		}
+		return resp, nil
+
+	case *realtime.RerunScanCommand:
+		requester, err := s.authorize(ctx, caller, rbac.PermCreateScan)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkEmergencyStop(ctx); err != nil {
+			return nil, err
+		}
+		job, scanErr := s.scans.rerunScan(ctx, requester, cmd.ScanID)
+		if scanErr != nil {
+			return nil, commandError(scanErr)
+		}
+		return job, nil
+
+	case *realtime.AcknowledgeAlertCommand:
+		// Alerts go to the admins of the organizations they concern, who
+		// can also read the audit trail they were raised from
+		if _, err := s.authorize(ctx, caller, rbac.PermViewAuditLogs); err != nil {
+			return nil, err
+		}
+		alert, err := s.alerts.Acknowledge(ctx, caller.OrgID, cmd.AlertID, caller.UserID, "websocket")
+		switch {
+		case errors.Is(err, audit.ErrAlertNotFound):
+			return nil, &realtime.CommandError{Code: "not_found", Message: "Alert not found"}
+		case errors.Is(err, audit.ErrAlertAcknowledged):
+			return nil, &realtime.CommandError{Code: "conflict", Message: "Alert already acknowledged"}
+		case err != nil:
+			logging.FromContext(ctx, s.logger).Error("Failed to acknowledge alert", zap.Error(err))
+			return nil, &realtime.CommandError{Code: "internal_error", Message: "Failed to acknowledge alert"}
+		}
+		s.auditLogger.LogSuccess(ctx, caller.UserID, "alert_acknowledged", "anomaly_alert", alert.ID, map[string]interface{}{
+			"organization_id": alert.OrganizationID,
+			"via":             "websocket",
+		})
+		return AlertAcknowledgement{AlertID: alert.ID, AcknowledgedAt: *alert.AcknowledgedAt}, nil
+	}
+	return nil, &realtime.CommandError{Code: "unsupported", Message: msgType + " is not supported"}
+}
+
+// authorize re-checks the caller's membership and permission, since roles may
+// change during a long-lived connection
+func (s *ScanCommander) authorize(ctx context.Context, caller realtime.Identity, perm rbac.Permission) (scanRequester, error) {
+	if caller.OrgID == "" {
+		return scanRequester{}, &realtime.CommandError{Code: "invalid_request", Message: "Organization context required"}
+	}
+
+	role, err := s.roles.MemberRole(ctx, caller.UserID, caller.OrgID)
+	if err == rbac.ErrNotMember {
+		return scanRequester{}, &realtime.CommandError{Code: "forbidden", Message: "Access denied"}
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to verify membership", zap.Error(err))
+		return scanRequester{}, err
+	}
+	allowed, err := s.roles.HasPermission(ctx, caller.OrgID, role, perm)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to resolve role permissions", zap.Error(err))
+		return scanRequester{}, err
+	}
+	if !allowed {
+		return scanRequester{}, &realtime.CommandError{Code: "forbidden", Message: "You do not have permission to perform this action"}
+	}
+
+	return scanRequester{
+		UserID:    caller.UserID,
+		OrgID:     caller.OrgID,
+		Role:      role,
+		UserAgent: "WebSocket",
+	}, nil
+}
+
+// checkEmergencyStop mirrors CheckEmergencyStop, failing open on Redis errors
+func (s *ScanCommander) checkEmergencyStop(ctx context.Context) error {
+	_, err := s.redis.Get(ctx, EmergencyStopKey).Result()
+	switch {
+	case err == redis.Nil:
+		return nil
+	case err != nil:
+		logging.FromContext(ctx, s.logger).Error("Failed to check emergency stop", zap.Error(err))
+		return nil
+	}
+	return &realtime.CommandError{Code: "emergency_stop", Message: "Emergency stop is active; all scan operations are temporarily suspended"}
+}
+
+// commandError converts a scanError for the client
+func commandError(e *scanError) *realtime.CommandError {
+	code, ok := commandErrorCodes[e.status]
+	if !ok {
+		code = "error"
+	}
+	message := e.message
+	if e.reason != "" {
+		message += ": " + e.reason
+	}
+	return &realtime.CommandError{Code: code, Message: message}
+}
@@ -0,0 +1,225 @@
+// Package apiversion serves the REST API in versions. Each version is a
+// router group under its own path prefix (/v1, /v2, ...); clients select one
+// by path or, on unversioned paths, with an Accept header such as
+// application/vnd.cyper.v2+json or application/json; version=2. Deprecated
+// versions and endpoints answer with Deprecation, Sunset and Link headers,
+// and every request is counted by version so adoption can be tracked before
+// old behaviors are removed.
+package apiversion
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// How a request's version was selected
+const (
+	SelectedByPath   = "path"
+	SelectedByAccept = "accept"
+)
+
+// ContextKey holds the version name in the gin context
+const ContextKey = "api_version"
+
+// Version is one version of the REST API
+type Version struct {
+	Name   string // e.g. v1
+	Prefix string // e.g. /v1
+	// Deprecated, when set, marks every endpoint of the version deprecated
+	// from then on; Sunset is when the version is to be removed
+	Deprecated time.Time
+	Sunset     time.Time
+	// Link documents the migration, sent with rel="deprecation"
+	Link string
+}
+
+// Deprecation marks one endpoint deprecated ahead of its version
+type Deprecation struct {
+	Deprecated time.Time
+	Sunset     time.Time
+	// Successor is the path replacing the endpoint, sent with
+	// rel="successor-version"
+	Successor string
+	Link      string
+}
+
+// Router selects versions and decorates their responses
+type Router struct {
+	versions  []Version
+	byName    map[string]Version
+	endpoints map[string]Deprecation
+	now       func() time.Time
+}
+
+// New serves versions, oldest first. endpoints maps "METHOD /v1/path"
+// (gin-style, including the version prefix) to that endpoint's deprecation.
+func New(versions []Version, endpoints map[string]Deprecation) (*Router, error) {
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("at least one API version is required")
+	}
+	r := &Router{
+		byName:    make(map[string]Version, len(versions)),
+		endpoints: endpoints,
+		now:       time.Now,
+	}
+	for _, v := range versions {
+		if v.Name == "" || !strings.HasPrefix(v.Prefix, "/") {
+			return nil, fmt.Errorf("API version %q needs a name and a path prefix", v.Name)
+		}
+		if _, ok := r.byName[v.Name]; ok {
+			return nil, fmt.Errorf("API version %q declared twice", v.Name)
+		}
+		r.byName[v.Name] = v
+		r.versions = append(r.versions, v)
+	}
+	return r, nil
+}
+
+// Versions returns the versions served, oldest first
+func (r *Router) Versions() []Version {
+	return append([]Version(nil), r.versions...)
+}
+
+// Group mounts the named version on router. It panics on an unknown name,
+// which is a wiring mistake.
+func (r *Router) Group(router gin.IRouter, name string, handlers ...gin.HandlerFunc) *gin.RouterGroup {
+	v, ok := r.byName[name]
+	if !ok {
+		panic(fmt.Sprintf("apiversion: unknown version %q", name))
+	}
+	return router.Group(v.Prefix, append([]gin.HandlerFunc{r.middleware(v)}, handlers...)...)
+}
+
+type selectedByKey struct{}
+
+// Handler routes requests on unversioned paths that name a version in their
+// Accept header to that version's prefix, ahead of next's routing. A path
+// naming a version takes precedence over the header.
+func (r *Router) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.versionOfPath(req.URL.Path) != nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+		name, ok := acceptedVersion(req.Header.Values("Accept"))
+		if !ok {
+			next.ServeHTTP(w, req)
+			return
+		}
+		v, known := r.byName[name]
+		if !known {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusNotAcceptable)
+			fmt.Fprintf(w, `{"error":"unsupported API version","supported":[%s]}`, r.quotedNames())
+			return
+		}
+
+		req = req.Clone(context.WithValue(req.Context(), selectedByKey{}, SelectedByAccept))
+		req.URL.Path = v.Prefix + req.URL.Path
+		if req.URL.RawPath != "" {
+			req.URL.RawPath = v.Prefix + req.URL.RawPath
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// middleware tags the request with v, sends the deprecation headers that
+// apply and counts the request
+func (r *Router) middleware(v Version) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		selectedBy := SelectedByPath
+		if by, ok := c.Request.Context().Value(selectedByKey{}).(string); ok {
+			selectedBy = by
+			c.Writer.Header().Add("Vary", "Accept")
+		}
+		c.Set(ContextKey, v.Name)
+		c.Header("API-Version", v.Name)
+
+		endpoint := c.Request.Method + " " + c.FullPath()
+		deprecation, deprecated := r.deprecation(v, endpoint)
+		if deprecated {
+			c.Header("Deprecation", fmt.Sprintf("@%d", deprecation.Deprecated.Unix()))
+			if !deprecation.Sunset.IsZero() {
+				c.Header("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if deprecation.Successor != "" {
+				c.Writer.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, deprecation.Successor))
+			}
+			if deprecation.Link != "" {
+				c.Writer.Header().Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, deprecation.Link))
+			}
+		}
+
+		c.Next()
+
+		metrics.APIVersionRequests.WithLabelValues(v.Name, selectedBy, strconv.FormatBool(deprecated)).Inc()
+		if deprecated {
+			metrics.DeprecatedEndpointRequests.WithLabelValues(v.Name, endpoint).Inc()
+		}
+	}
+}
+
+// deprecation returns the deprecation in effect for endpoint of v: the
+// endpoint's own, else the version's
+func (r *Router) deprecation(v Version, endpoint string) (Deprecation, bool) {
+	now := r.now()
+	if d, ok := r.endpoints[endpoint]; ok && !d.Deprecated.IsZero() && !now.Before(d.Deprecated) {
+		return d, true
+	}
+	if !v.Deprecated.IsZero() && !now.Before(v.Deprecated) {
+		return Deprecation{Deprecated: v.Deprecated, Sunset: v.Sunset, Link: v.Link}, true
+	}
+	return Deprecation{}, false
+}
+
+func (r *Router) versionOfPath(path string) *Version {
+	for i, v := range r.versions {
+		if path == v.Prefix || strings.HasPrefix(path, v.Prefix+"/") {
+			return &r.versions[i]
+		}
+	}
+	return nil
+}
+
+func (r *Router) quotedNames() string {
+	names := make([]string, len(r.versions))
+	for i, v := range r.versions {
+		names[i] = strconv.Quote(v.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// vendorType matches application/vnd.cyper.v2+json
+var vendorType = regexp.MustCompile(`^application/vnd\.cyper\.(v[0-9]+)(\+json)?$`)
+
+// acceptedVersion finds the version named in Accept headers, either as a
+// vendor media type or a version parameter
+func acceptedVersion(values []string) (string, bool) {
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			if m := vendorType.FindStringSubmatch(mediaType); m != nil {
+				return m[1], true
+			}
+			if version, ok := params["version"]; ok && version != "" {
+				if !strings.HasPrefix(version, "v") {
+					version = "v" + version
+				}
+				return version, true
+			}
+		}
+	}
+	return "", false
+}
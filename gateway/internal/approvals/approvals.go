@@ -0,0 +1,399 @@
+// Package approvals holds high-impact scans (exploitation, DoS testing) until
+// enough members with approve:scan sign off. Such scans are created in
+// pending_approval, which workers never pick up; the last required approval
+// queues them as pending, and any rejection ends them as rejected. The
+// requester cannot approve their own scan.
+package approvals
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/estimation"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/notify"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"go.uber.org/zap"
+)
+
+// Scan states of the workflow
+const (
+	StatusPendingApproval = "pending_approval"
+	StatusApproved        = "pending" // Queued for a worker
+	StatusRejected        = "rejected"
+)
+
+// Decisions an approver can make
+const (
+	Approve = "approve"
+	Reject  = "reject"
+)
+
+var (
+	ErrScanNotFound   = errors.New("scan not found")
+	ErrNotPending     = errors.New("scan is not awaiting approval")
+	ErrSelfApproval   = errors.New("requesters cannot decide on their own scans")
+	ErrAlreadyDecided = errors.New("approver already decided on this scan")
+	ErrRuleNotFound   = errors.New("approval rule not found")
+)
+
+// defaultRules are the scan types needing approval in organizations that set
+// no rule for them
+var defaultRules = map[string]int{
+	"exploitation": 1,
+	"dos":          1,
+}
+
+// Notifier pushes real-time events to a user's WebSocket connections
+type Notifier interface {
+	PublishToUser(userID string, event realtime.Event)
+}
+
+// Rule is the number of approvals a scan type needs in an organization
+type Rule struct {
+	ScanType          string     `json:"scan_type" db:"scan_type"`
+	RequiredApprovals int        `json:"required_approvals" db:"required_approvals"`
+	Default           bool       `json:"default" db:"-"` // Built in, not set by the organization
+	UpdatedBy         *string    `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// Scan is a scan as approvers see it
+type Scan struct {
+	ID                string               `json:"id" db:"id"`
+	OrganizationID    string               `json:"organization_id" db:"organization_id"`
+	RequestedBy       string               `json:"requested_by" db:"user_id"`
+	ScanType          string               `json:"scan_type" db:"scan_type"`
+	ScanMode          string               `json:"scan_mode" db:"scan_mode"`
+	TargetValue       string               `json:"target_value" db:"target_value"`
+	Status            string               `json:"status" db:"status"`
+	Justification     string               `json:"justification" db:"justification"`
+	RequiredApprovals int                  `json:"required_approvals" db:"required_approvals"`
+	Approvals         int                  `json:"approvals" db:"approvals"`
+	Estimate          *estimation.Estimate `json:"estimate,omitempty" db:"estimate"` // Predicted duration, requests and bandwidth
+	CreatedAt         time.Time            `json:"created_at" db:"created_at"`
+}
+
+// Decision is one approver's decision on a scan
+type Decision struct {
+	ID            string    `json:"id" db:"id"`
+	ScanJobID     string    `json:"scan_job_id" db:"scan_job_id"`
+	ApproverID    string    `json:"approver_id" db:"approver_id"`
+	Decision      string    `json:"decision" db:"decision"`
+	Justification string    `json:"justification" db:"justification"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// scanColumns selects every Scan field from scan_jobs sj joined to scan_targets st
+const scanColumns = `sj.id, sj.organization_id, sj.user_id, sj.scan_type, sj.scan_mode,
+	st.target_value, sj.status, COALESCE(sj.justification, '') AS justification, sj.required_approvals,
+	(SELECT COUNT(*) FROM scan_approvals sa WHERE sa.scan_job_id = sj.id AND sa.decision = 'approve') AS approvals,
+	sj.estimate, sj.created_at`
+
+type Service struct {
+	db       *database.DB
+	roles    *rbac.RoleStore
+	notifier Notifier
+	mailer   *notify.Mailer
+	logger   *zap.Logger
+}
+
+func NewService(db *database.DB, roles *rbac.RoleStore, notifier Notifier, mailer *notify.Mailer, logger *zap.Logger) *Service {
+	return &Service{
+		db:       db,
+		roles:    roles,
+		notifier: notifier,
+		mailer:   mailer,
+		logger:   logger,
+	}
+}
+
+// Required returns how many approvals a scan of scanType needs in the
+// organization; zero when it can start right away
+func (s *Service) Required(ctx context.Context, orgID, scanType string) (int, error) {
+	var required int
+	err := s.db.GetContext(ctx, &required, `
+		SELECT required_approvals FROM scan_approval_rules
+		WHERE organization_id = $1 AND scan_type = $2
+	`, orgID, scanType)
+	if err == sql.ErrNoRows {
+		return defaultRules[scanType], nil
+	}
+	return required, err
+}
+
+// RequestApproval tells the organization's approvers, other than the
+// requester, that the scan awaits their decision
+func (s *Service) RequestApproval(ctx context.Context, scan Scan) {
+	approvers, err := s.approvers(ctx, scan.OrganizationID, scan.RequestedBy)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to load scan approvers", zap.String("scan_id", scan.ID), zap.Error(err))
+		return
+	}
+	if len(approvers) == 0 {
+		logging.FromContext(ctx, s.logger).Warn("Scan awaits approval but the organization has no approvers",
+			zap.String("scan_id", scan.ID),
+			zap.String("organization_id", scan.OrganizationID),
+		)
+		return
+	}
+
+	event := scanEvent(scan, "requested", "")
+	emails := make([]string, 0, len(approvers))
+	for _, approver := range approvers {
+		s.notifier.PublishToUser(approver.ID, event)
+		emails = append(emails, approver.Email)
+	}
+
+	if !s.mailer.Enabled() {
+		return
+	}
+	// SMTP must not hold up the scan request
+	go func() {
+		subject := fmt.Sprintf("Approval needed: %s scan of %s", scan.ScanType, scan.TargetValue)
+		if err := s.mailer.Send(emails, subject, requestBody(scan)); err != nil {
+			s.logger.Error("Failed to email scan approvers", zap.String("scan_id", scan.ID), zap.Error(err))
+		}
+	}()
+}
+
+type approver struct {
+	ID    string `db:"user_id"`
+	Email string `db:"email"`
+	Role  string `db:"role"`
+}
+
+// approvers returns the active members holding approve:scan, except exclude
+func (s *Service) approvers(ctx context.Context, orgID, exclude string) ([]approver, error) {
+	var members []approver
+	err := s.db.SelectContext(ctx, &members, `
+		SELECT om.user_id, u.email, om.role
+		FROM organization_memberships om
+		JOIN users u ON u.id = om.user_id
+		WHERE om.organization_id = $1 AND om.user_id <> $2 AND COALESCE(u.is_active, true)
+	`, orgID, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	canApprove := map[string]bool{}
+	approvers := members[:0]
+	for _, member := range members {
+		allowed, seen := canApprove[member.Role]
+		if !seen {
+			allowed, err = s.roles.HasPermission(ctx, orgID, rbac.Role(member.Role), rbac.PermApproveScan)
+			if err != nil {
+				return nil, err
+			}
+			canApprove[member.Role] = allowed
+		}
+		if allowed {
+			approvers = append(approvers, member)
+		}
+	}
+	return approvers, nil
+}
+
+// Decide records an approver's decision. A rejection, or the last approval
+// needed, moves the scan out of pending_approval; the requester is told
+// about every decision.
+func (s *Service) Decide(ctx context.Context, orgID, scanID, approverID, decision, justification string) (*Scan, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var scan Scan
+	err = tx.GetContext(ctx, &scan, `
+		SELECT `+scanColumns+`
+		FROM scan_jobs sj
+		JOIN scan_targets st ON st.id = sj.target_id
+		WHERE sj.id = $1 AND sj.organization_id = $2
+		FOR UPDATE OF sj
+	`, scanID, orgID)
+	if err == sql.ErrNoRows {
+		return nil, ErrScanNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if scan.Status != StatusPendingApproval {
+		return nil, ErrNotPending
+	}
+	if scan.RequestedBy == approverID {
+		return nil, ErrSelfApproval
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO scan_approvals (scan_job_id, approver_id, decision, justification)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scan_job_id, approver_id) DO NOTHING
+	`, scanID, approverID, decision, justification)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrAlreadyDecided
+	}
+
+	switch {
+	case decision == Reject:
+		scan.Status = StatusRejected
+	case scan.Approvals+1 >= scan.RequiredApprovals:
+		scan.Status = StatusApproved
+	}
+	if decision == Approve {
+		scan.Approvals++
+	}
+	if scan.Status != StatusPendingApproval {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE scan_jobs
+			SET status = $2,
+			    completed_at = CASE WHEN $2 = 'rejected' THEN NOW() END,
+			    error_message = CASE WHEN $2 = 'rejected' THEN 'Rejected: ' || $3 END,
+			    updated_at = NOW()
+			WHERE id = $1
+		`, scanID, scan.Status, justification)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	kind := "approved"
+	if decision == Reject {
+		kind = "rejected"
+	}
+	event := scanEvent(scan, kind, approverID)
+	event.Justification = justification
+	s.notifier.PublishToUser(scan.RequestedBy, event)
+
+	return &scan, nil
+}
+
+// Pending lists the organization's scans awaiting approval, oldest first
+func (s *Service) Pending(ctx context.Context, orgID string) ([]Scan, error) {
+	scans := []Scan{}
+	err := s.db.SelectContext(ctx, &scans, `
+		SELECT `+scanColumns+`
+		FROM scan_jobs sj
+		JOIN scan_targets st ON st.id = sj.target_id
+		WHERE sj.organization_id = $1 AND sj.status = 'pending_approval'
+		ORDER BY sj.created_at
+	`, orgID)
+	return scans, err
+}
+
+// Decisions lists the decisions on one of the organization's scans
+func (s *Service) Decisions(ctx context.Context, orgID, scanID string) ([]Decision, error) {
+	decisions := []Decision{}
+	err := s.db.SelectContext(ctx, &decisions, `
+		SELECT sa.id, sa.scan_job_id, sa.approver_id, sa.decision, sa.justification, sa.created_at
+		FROM scan_approvals sa
+		JOIN scan_jobs sj ON sj.id = sa.scan_job_id
+		WHERE sa.scan_job_id = $1 AND sj.organization_id = $2
+		ORDER BY sa.created_at
+	`, scanID, orgID)
+	return decisions, err
+}
+
+// Rules lists the organization's rules, followed by the built-in ones it
+// does not override
+func (s *Service) Rules(ctx context.Context, orgID string) ([]Rule, error) {
+	rules := []Rule{}
+	err := s.db.SelectContext(ctx, &rules, `
+		SELECT scan_type, required_approvals, updated_by, updated_at
+		FROM scan_approval_rules
+		WHERE organization_id = $1
+		ORDER BY scan_type
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := map[string]bool{}
+	for _, rule := range rules {
+		set[rule.ScanType] = true
+	}
+	for scanType, required := range defaultRules {
+		if !set[scanType] {
+			rules = append(rules, Rule{ScanType: scanType, RequiredApprovals: required, Default: true})
+		}
+	}
+	return rules, nil
+}
+
+// SetRule creates or replaces the organization's rule for a scan type
+func (s *Service) SetRule(ctx context.Context, orgID, scanType, userID string, required int) (*Rule, error) {
+	var rule Rule
+	err := s.db.GetContext(ctx, &rule, `
+		INSERT INTO scan_approval_rules (organization_id, scan_type, required_approvals, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, scan_type) DO UPDATE SET
+			required_approvals = EXCLUDED.required_approvals,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING scan_type, required_approvals, updated_by, updated_at
+	`, orgID, scanType, required, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteRule removes the organization's rule for a scan type, restoring the
+// built-in one if there is one
+func (s *Service) DeleteRule(ctx context.Context, orgID, scanType string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM scan_approval_rules WHERE organization_id = $1 AND scan_type = $2
+	`, orgID, scanType)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+func scanEvent(scan Scan, kind, actorID string) realtime.ScanApprovalEvent {
+	return realtime.ScanApprovalEvent{
+		ScanID:         scan.ID,
+		OrganizationID: scan.OrganizationID,
+		Kind:           kind,
+		ScanType:       scan.ScanType,
+		TargetValue:    scan.TargetValue,
+		RequestedBy:    scan.RequestedBy,
+		ActorID:        actorID,
+		Justification:  scan.Justification,
+		Status:         scan.Status,
+		Approvals:      scan.Approvals,
+		Required:       scan.RequiredApprovals,
+		Estimate:       scan.Estimate,
+	}
+}
+
+func requestBody(scan Scan) string {
+	var body strings.Builder
+	body.WriteString("A scan in your organization needs your approval before it can start.\r\n\r\n")
+	fmt.Fprintf(&body, "Scan:          %s\r\n", scan.ID)
+	fmt.Fprintf(&body, "Type:          %s (%s)\r\n", scan.ScanType, scan.ScanMode)
+	fmt.Fprintf(&body, "Target:        %s\r\n", scan.TargetValue)
+	if scan.Estimate != nil {
+		fmt.Fprintf(&body, "Estimate:      %s\r\n", scan.Estimate.Summary())
+	}
+	fmt.Fprintf(&body, "Approvals:     %d needed\r\n", scan.RequiredApprovals)
+	fmt.Fprintf(&body, "Justification: %s\r\n", scan.Justification)
+	body.WriteString("\r\nApprove or reject it from the pending approvals list.\r\n")
+	return body.String()
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+)
+
+var (
+	// ErrAlertNotFound is returned for an alert that was not raised for the
+	// organization
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrAlertAcknowledged is returned when the alert was already acknowledged
+	ErrAlertAcknowledged = errors.New("alert already acknowledged")
+)
+
+// Alert is an anomaly alert as raised for one organization's admins
+type Alert struct {
+	ID              string          `json:"id" db:"id"`
+	OrganizationID  string          `json:"organization_id" db:"organization_id"`
+	Kind            string          `json:"kind" db:"kind"`
+	SubjectUserID   *string         `json:"subject_user_id,omitempty" db:"subject_user_id"`
+	Target          string          `json:"target" db:"target"`
+	Count           int             `json:"count" db:"count"`
+	Details         json.RawMessage `json:"details" db:"details"`
+	DetectedAt      time.Time       `json:"detected_at" db:"detected_at"`
+	AcknowledgedBy  *string         `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+	AcknowledgedAt  *time.Time      `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	AcknowledgedVia *string         `json:"acknowledged_via,omitempty" db:"acknowledged_via"`
+}
+
+// AlertColumns selects every Alert field
+const AlertColumns = `id, organization_id, kind, subject_user_id, target, count, details,
+	detected_at, acknowledged_by, acknowledged_at, acknowledged_via`
+
+// AlertStore records raised alerts and their acknowledgements
+type AlertStore struct {
+	db *database.DB
+}
+
+func NewAlertStore(db *database.DB) *AlertStore {
+	return &AlertStore{db: db}
+}
+
+// Record stores the anomaly's alert for each organization it was raised for
+func (s *AlertStore) Record(ctx context.Context, anomaly Anomaly, orgIDs []string) error {
+	details, err := json.Marshal(anomaly.Details)
+	if err != nil {
+		return err
+	}
+	for _, orgID := range orgIDs {
+		_, err := s.db.ExecContext(ctx, `
+			INSERT INTO security_alerts (id, organization_id, kind, subject_user_id, target, count, details, detected_at)
+			VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5, $6, $7, $8)
+			ON CONFLICT (id, organization_id) DO NOTHING
+		`, anomaly.AlertID(), orgID, anomaly.Kind, anomaly.UserID, anomaly.Target, anomaly.Count, details, anomaly.DetectedAt.UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Acknowledge records that userID acknowledged the organization's alert.
+// Callers check the user may see the organization's alerts.
+func (s *AlertStore) Acknowledge(ctx context.Context, orgID, alertID, userID, via string) (*Alert, error) {
+	var alert Alert
+	err := s.db.GetContext(ctx, &alert, `
+		UPDATE security_alerts
+		SET acknowledged_by = $3, acknowledged_at = NOW(), acknowledged_via = $4
+		WHERE id = $1 AND organization_id = $2 AND acknowledged_at IS NULL
+		RETURNING `+AlertColumns,
+		alertID, orgID, userID, via)
+	if err == sql.ErrNoRows {
+		var exists bool
+		err = s.db.GetContext(ctx, &exists, `
+			SELECT EXISTS(SELECT 1 FROM security_alerts WHERE id = $1 AND organization_id = $2)
+		`, alertID, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrAlertAcknowledged
+		}
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
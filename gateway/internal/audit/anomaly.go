@@ -0,0 +1,330 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Anomaly kinds reported by the detector
+const (
+	AnomalyFailedLogins     = "failed_logins"
+	AnomalyScanTargetSpread = "scan_target_spread"
+	AnomalyAfterHours       = "after_hours_access"
+)
+
+// AnomalyConfig controls detection thresholds
+type AnomalyConfig struct {
+	Interval           time.Duration // How often the analyzer runs
+	FailedLoginLimit   int           // Failed logins from one IP per interval
+	ScanTargetLimit    int           // Distinct scan targets per user per interval
+	BusinessHoursStart int           // Hour of day (0-23) business hours begin
+	BusinessHoursEnd   int           // Hour of day (0-23) business hours end
+	AlertWeekends      bool          // Treat Saturday/Sunday activity as after hours
+}
+
+// DefaultAnomalyConfig returns conservative defaults
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		Interval:           5 * time.Minute,
+		FailedLoginLimit:   10,
+		ScanTargetLimit:    25,
+		BusinessHoursStart: 7,
+		BusinessHoursEnd:   20,
+		AlertWeekends:      true,
+	}
+}
+
+// Anomaly describes a suspicious pattern found in the audit trail
+type Anomaly struct {
+	Kind       string                 `json:"kind"`
+	UserID     string                 `json:"user_id,omitempty"`
+	Target     string                 `json:"target,omitempty"`
+	Count      int                    `json:"count"`
+	Details    map[string]interface{} `json:"details"`
+	DetectedAt time.Time              `json:"detected_at"`
+}
+
+// AlertID identifies the anomaly's alert for acknowledgement
+func (a Anomaly) AlertID() string {
+	return fmt.Sprintf("%s:%s:%d", a.Kind, a.UserID, a.DetectedAt.Unix())
+}
+
+// AlertFunc delivers an anomaly alert to a single user (e.g. over the WebSocket hub)
+type AlertFunc func(userID string, anomaly Anomaly)
+
+// AnomalyDetector periodically analyzes audit_logs for suspicious patterns
+type AnomalyDetector struct {
+	db          *database.DB
+	auditLogger *AuditLogger
+	alerts      *AlertStore
+	config      AnomalyConfig
+	notifiers   []AlertFunc
+	logger      *zap.Logger
+}
+
+func NewAnomalyDetector(db *database.DB, auditLogger *AuditLogger, config AnomalyConfig, logger *zap.Logger) *AnomalyDetector {
+	return &AnomalyDetector{
+		db:          db,
+		auditLogger: auditLogger,
+		alerts:      NewAlertStore(db),
+		config:      config,
+		logger:      logger,
+	}
+}
+
+// AddNotifier registers a delivery channel for anomaly alerts
+func (d *AnomalyDetector) AddNotifier(fn AlertFunc) {
+	d.notifiers = append(d.notifiers, fn)
+}
+
+// Start runs the analyzer until ctx is cancelled
+func (d *AnomalyDetector) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	d.logger.Info("Starting audit anomaly detector", zap.Duration("interval", d.config.Interval))
+
+	since := time.Now().Add(-d.config.Interval)
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			d.Analyze(ctx, since)
+			since = now
+		case <-ctx.Done():
+			d.logger.Info("Stopping audit anomaly detector")
+			return
+		}
+	}
+}
+
+// Analyze inspects audit entries newer than since and raises alerts for anomalies
+func (d *AnomalyDetector) Analyze(ctx context.Context, since time.Time) []Anomaly {
+	var anomalies []Anomaly
+
+	checks := []func(context.Context, time.Time) ([]Anomaly, error){
+		d.detectFailedLogins,
+		d.detectScanTargetSpread,
+		d.detectAfterHoursAccess,
+	}
+
+	for _, check := range checks {
+		found, err := check(ctx, since)
+		if err != nil {
+			d.logger.Error("Anomaly check failed", zap.Error(err))
+			continue
+		}
+		anomalies = append(anomalies, found...)
+	}
+
+	for _, anomaly := range anomalies {
+		d.raise(ctx, anomaly)
+	}
+
+	d.logger.Debug("Anomaly analysis completed", zap.Int("anomalies", len(anomalies)))
+	return anomalies
+}
+
+// detectFailedLogins flags IPs with many failed logins in the window
+func (d *AnomalyDetector) detectFailedLogins(ctx context.Context, since time.Time) ([]Anomaly, error) {
+	var rows []struct {
+		IPAddress string         `db:"ip_address"`
+		Count     int            `db:"count"`
+		Emails    pq.StringArray `db:"emails"`
+	}
+
+	err := d.db.SelectContext(ctx, &rows, `
+		SELECT COALESCE(host(ip_address), details->>'ip_address', 'unknown') AS ip_address,
+		       COUNT(*) AS count,
+		       ARRAY_REMOVE(ARRAY_AGG(DISTINCT details->>'email'), NULL) AS emails
+		FROM audit_logs
+		WHERE action = 'login_attempt' AND status = 'failure' AND timestamp > $1
+		GROUP BY 1
+		HAVING COUNT(*) >= $2
+	`, since, d.config.FailedLoginLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed logins: %w", err)
+	}
+
+	anomalies := make([]Anomaly, 0, len(rows))
+	for _, row := range rows {
+		anomalies = append(anomalies, Anomaly{
+			Kind:   AnomalyFailedLogins,
+			Target: row.IPAddress,
+			Count:  row.Count,
+			Details: map[string]interface{}{
+				"ip_address": row.IPAddress,
+				"emails":     []string(row.Emails),
+				"threshold":  d.config.FailedLoginLimit,
+			},
+			DetectedAt: time.Now(),
+		})
+	}
+	return anomalies, nil
+}
+
+// detectScanTargetSpread flags users scanning unusually many distinct targets
+func (d *AnomalyDetector) detectScanTargetSpread(ctx context.Context, since time.Time) ([]Anomaly, error) {
+	var rows []struct {
+		UserID string `db:"user_id"`
+		Count  int    `db:"count"`
+	}
+
+	err := d.db.SelectContext(ctx, &rows, `
+		SELECT user_id, COUNT(DISTINCT target) AS count
+		FROM audit_logs
+		WHERE action LIKE 'scan\_%\_initiated' AND user_id IS NOT NULL AND timestamp > $1
+		GROUP BY user_id
+		HAVING COUNT(DISTINCT target) >= $2
+	`, since, d.config.ScanTargetLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan targets: %w", err)
+	}
+
+	anomalies := make([]Anomaly, 0, len(rows))
+	for _, row := range rows {
+		anomalies = append(anomalies, Anomaly{
+			Kind:   AnomalyScanTargetSpread,
+			UserID: row.UserID,
+			Count:  row.Count,
+			Details: map[string]interface{}{
+				"distinct_targets": row.Count,
+				"threshold":        d.config.ScanTargetLimit,
+			},
+			DetectedAt: time.Now(),
+		})
+	}
+	return anomalies, nil
+}
+
+// detectAfterHoursAccess flags users active outside configured business hours
+func (d *AnomalyDetector) detectAfterHoursAccess(ctx context.Context, since time.Time) ([]Anomaly, error) {
+	var rows []struct {
+		UserID  string         `db:"user_id"`
+		Count   int            `db:"count"`
+		Actions pq.StringArray `db:"actions"`
+	}
+
+	err := d.db.SelectContext(ctx, &rows, `
+		SELECT user_id, COUNT(*) AS count, ARRAY_AGG(DISTINCT action) AS actions
+		FROM audit_logs
+		WHERE user_id IS NOT NULL
+		AND timestamp > $1
+		AND action NOT LIKE 'anomaly\_%'
+		AND (
+			EXTRACT(HOUR FROM timestamp) < $2
+			OR EXTRACT(HOUR FROM timestamp) >= $3
+			OR ($4 AND EXTRACT(ISODOW FROM timestamp) >= 6)
+		)
+		GROUP BY user_id
+	`, since, d.config.BusinessHoursStart, d.config.BusinessHoursEnd, d.config.AlertWeekends)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query after-hours access: %w", err)
+	}
+
+	anomalies := make([]Anomaly, 0, len(rows))
+	for _, row := range rows {
+		anomalies = append(anomalies, Anomaly{
+			Kind:   AnomalyAfterHours,
+			UserID: row.UserID,
+			Count:  row.Count,
+			Details: map[string]interface{}{
+				"actions":              []string(row.Actions),
+				"business_hours_start": d.config.BusinessHoursStart,
+				"business_hours_end":   d.config.BusinessHoursEnd,
+			},
+			DetectedAt: time.Now(),
+		})
+	}
+	return anomalies, nil
+}
+
+// raise records the anomaly as a security event and alerts org admins
+func (d *AnomalyDetector) raise(ctx context.Context, anomaly Anomaly) {
+	details := map[string]interface{}{"count": anomaly.Count}
+	for k, v := range anomaly.Details {
+		details[k] = v
+	}
+
+	d.auditLogger.LogSecurityEvent(ctx, anomaly.UserID, "anomaly_"+anomaly.Kind, anomaly.Target, "high", details)
+
+	d.logger.Warn("Audit anomaly detected",
+		zap.String("kind", anomaly.Kind),
+		zap.String("user_id", anomaly.UserID),
+		zap.String("target", anomaly.Target),
+		zap.Int("count", anomaly.Count),
+	)
+
+	if len(d.notifiers) == 0 {
+		return
+	}
+
+	recipients, err := d.adminRecipients(ctx, anomaly)
+	if err != nil {
+		d.logger.Error("Failed to resolve anomaly alert recipients", zap.Error(err))
+		return
+	}
+
+	// Record the alert first so recipients can acknowledge it
+	var orgIDs, userIDs []string
+	seenOrgs, seenUsers := map[string]bool{}, map[string]bool{}
+	for _, r := range recipients {
+		if !seenOrgs[r.OrgID] {
+			seenOrgs[r.OrgID] = true
+			orgIDs = append(orgIDs, r.OrgID)
+		}
+		if !seenUsers[r.UserID] {
+			seenUsers[r.UserID] = true
+			userIDs = append(userIDs, r.UserID)
+		}
+	}
+	if err := d.alerts.Record(ctx, anomaly, orgIDs); err != nil {
+		d.logger.Error("Failed to record anomaly alert", zap.Error(err))
+	}
+
+	for _, userID := range userIDs {
+		for _, notify := range d.notifiers {
+			notify(userID, anomaly)
+		}
+	}
+}
+
+// alertRecipient is an owner or admin of an organization an anomaly relates to
+type alertRecipient struct {
+	UserID string `db:"user_id"`
+	OrgID  string `db:"organization_id"`
+}
+
+// adminRecipients returns owners/admins of the organizations the anomaly relates to
+func (d *AnomalyDetector) adminRecipients(ctx context.Context, anomaly Anomaly) ([]alertRecipient, error) {
+	var recipients []alertRecipient
+
+	if anomaly.UserID != "" {
+		err := d.db.SelectContext(ctx, &recipients, `
+			SELECT DISTINCT admins.user_id, admins.organization_id
+			FROM organization_memberships subject
+			INNER JOIN organization_memberships admins ON admins.organization_id = subject.organization_id
+			WHERE subject.user_id = $1 AND admins.role IN ('owner', 'admin')
+		`, anomaly.UserID)
+		return recipients, err
+	}
+
+	emails, _ := anomaly.Details["emails"].([]string)
+	if len(emails) == 0 {
+		return nil, nil
+	}
+
+	err := d.db.SelectContext(ctx, &recipients, `
+		SELECT DISTINCT admins.user_id, admins.organization_id
+		FROM users u
+		INNER JOIN organization_memberships subject ON subject.user_id = u.id
+		INNER JOIN organization_memberships admins ON admins.organization_id = subject.organization_id
+		WHERE u.email = ANY($1) AND admins.role IN ('owner', 'admin')
+	`, pq.Array(emails))
+	return recipients, err
+}
@@ -0,0 +1,252 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// BufferConfig controls asynchronous batched audit writes
+type BufferConfig struct {
+	QueueSize     int           // Maximum entries waiting to be written
+	BatchSize     int           // Maximum entries per INSERT
+	FlushInterval time.Duration // Maximum time an entry waits before being written
+}
+
+// DefaultBufferConfig returns settings suited to a single gateway instance
+func DefaultBufferConfig() BufferConfig {
+	return BufferConfig{
+		QueueSize:     10000,
+		BatchSize:     500,
+		FlushInterval: time.Second,
+	}
+}
+
+// bufferedEntry is a prepared audit log waiting to be written
+type bufferedEntry struct {
+	params  LogParams
+	details []byte
+}
+
+// BufferedWriter batches audit log inserts and signatures
+type BufferedWriter struct {
+	audit  *AuditLogger
+	config BufferConfig
+	queue  chan bufferedEntry
+	done   chan struct{}
+	once   sync.Once
+}
+
+// EnableBuffering switches non-critical audit writes to batched, asynchronous inserts.
+// Critical-severity events and writes made while the queue is full stay synchronous.
+func (a *AuditLogger) EnableBuffering(config BufferConfig) {
+	w := &BufferedWriter{
+		audit:  a,
+		config: config,
+		queue:  make(chan bufferedEntry, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	a.buffer = w
+	go w.run()
+
+	a.logger.Info("Audit log buffering enabled",
+		zap.Int("queue_size", config.QueueSize),
+		zap.Int("batch_size", config.BatchSize),
+		zap.Duration("flush_interval", config.FlushInterval),
+	)
+}
+
+// Close flushes buffered entries, waiting until ctx expires at most
+func (a *AuditLogger) Close(ctx context.Context) error {
+	if a.buffer == nil {
+		return nil
+	}
+
+	w := a.buffer
+	w.once.Do(func() { close(w.queue) })
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("audit buffer flush interrupted: %w", ctx.Err())
+	}
+}
+
+// enqueue hands an entry to the writer, reporting false when the queue is full
+func (w *BufferedWriter) enqueue(entry bufferedEntry) (queued bool) {
+	defer func() {
+		// Writing after Close panics on the closed channel; fall back to sync
+		if recover() != nil {
+			queued = false
+		}
+	}()
+
+	select {
+	case w.queue <- entry:
+		metrics.AuditBufferQueueDepth.Set(float64(len(w.queue)))
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *BufferedWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]bufferedEntry, 0, w.config.BatchSize)
+	for {
+		select {
+		case entry, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= w.config.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes a batch with one multi-row INSERT, then signs it in bulk
+func (w *BufferedWriter) flush(batch []bufferedEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	// Shutdown flushes must finish even though request contexts are gone
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	metrics.AuditBufferQueueDepth.Set(float64(len(w.queue)))
+	metrics.AuditBatchSize.Observe(float64(len(batch)))
+
+	n := len(batch)
+	cols := make([][]string, 14)
+	for i := range cols {
+		cols[i] = make([]string, n)
+	}
+	for i, e := range batch {
+		p := e.params
+		for c, v := range []string{
+			p.UserID, p.SessionID, p.Action, p.ResourceType, p.ResourceID,
+			p.Target, p.AuthorizationProof, string(e.details), p.IPAddress, p.UserAgent,
+			p.Status, p.ErrorMessage, p.Severity, p.OrganizationID,
+		} {
+			cols[c][i] = v
+		}
+	}
+
+	args := make([]interface{}, len(cols))
+	for i, col := range cols {
+		args[i] = pq.Array(col)
+	}
+
+	var ids []int64
+	err := w.audit.db.SelectContext(ctx, &ids, `
+		INSERT INTO audit_logs (
+			user_id, session_id, action, resource_type, resource_id,
+			target, authorization_proof, details, ip_address, user_agent,
+			status, error_message, severity, organization_id, timestamp
+		)
+		SELECT
+			NULLIF(u.user_id, '')::uuid, NULLIF(u.session_id, '')::uuid, u.action, NULLIF(u.resource_type, ''), NULLIF(u.resource_id, '')::uuid,
+			NULLIF(u.target, ''), NULLIF(u.authorization_proof, ''), u.details::jsonb, NULLIF(u.ip_address, '')::inet, NULLIF(u.user_agent, ''),
+			u.status, NULLIF(u.error_message, ''), u.severity, NULLIF(u.organization_id, '')::uuid, NOW()
+		FROM unnest(
+			$1::text[], $2::text[], $3::text[], $4::text[], $5::text[],
+			$6::text[], $7::text[], $8::text[], $9::text[], $10::text[],
+			$11::text[], $12::text[], $13::text[], $14::text[]
+		) AS u(
+			user_id, session_id, action, resource_type, resource_id,
+			target, authorization_proof, details, ip_address, user_agent,
+			status, error_message, severity, organization_id
+		)
+		RETURNING id
+	`, args...)
+	if err != nil {
+		// A single bad row fails the whole statement; retry row by row so
+		// valid entries are not lost
+		w.audit.logger.Error("Failed to write audit batch, retrying individually", zap.Error(err), zap.Int("entries", n))
+		for _, e := range batch {
+			w.audit.writeSync(ctx, e.params, e.details)
+		}
+		return
+	}
+
+	for _, e := range batch {
+		metrics.AuditLogsTotal.WithLabelValues(e.params.Severity, e.params.Action).Inc()
+	}
+
+	w.signBatch(ctx, ids)
+}
+
+// signBatch signs freshly inserted entries exactly as stored and saves the signatures in one UPDATE
+func (w *BufferedWriter) signBatch(ctx context.Context, ids []int64) {
+	var logs []AuditLog
+	err := w.audit.db.SelectContext(ctx, &logs, "SELECT * FROM audit_logs WHERE id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		w.audit.logger.Error("Failed to fetch audit batch for signing", zap.Error(err))
+		metrics.AuditSigningFailures.Add(float64(len(ids)))
+		return
+	}
+	w.audit.stream.publish(logs...)
+
+	signedIDs := make([]int64, 0, len(logs))
+	signatures := make([]string, 0, len(logs))
+	for _, log := range logs {
+		signature, err := w.audit.signer.SignLog(signableFromLog(&log))
+		if err != nil {
+			w.audit.logger.Error("Failed to sign audit log", zap.Error(err), zap.Int64("log_id", log.ID))
+			metrics.AuditSigningFailures.Inc()
+			continue
+		}
+		signedIDs = append(signedIDs, log.ID)
+		signatures = append(signatures, signature)
+	}
+
+	_, err = w.audit.db.ExecContext(ctx, `
+		UPDATE audit_logs a
+		SET signature = u.signature, signer_public_key = $3, signed_at = NOW()
+		FROM unnest($1::bigint[], $2::text[]) AS u(id, signature)
+		WHERE a.id = u.id
+	`, pq.Array(signedIDs), pq.Array(signatures), w.audit.signer.GetPublicKey())
+	if err != nil {
+		w.audit.logger.Error("Failed to save audit batch signatures", zap.Error(err))
+		metrics.AuditSigningFailures.Add(float64(len(signedIDs)))
+		return
+	}
+
+	metrics.AuditLogsSignedTotal.Add(float64(len(signedIDs)))
+}
+
+// marshalDetails converts details to JSON, defaulting to an empty object
+func (a *AuditLogger) marshalDetails(details map[string]interface{}) []byte {
+	if details == nil {
+		return []byte("{}")
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		a.logger.Error("Failed to marshal audit log details", zap.Error(err))
+		return []byte("{}")
+	}
+	return detailsJSON
+}
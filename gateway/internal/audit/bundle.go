@@ -0,0 +1,338 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// BundleFormat identifies the export bundle layout
+const BundleFormat = "cyper-audit-bundle/v1"
+
+// GenesisHash precedes the first entry of a bundle's hash chain
+var GenesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// csvManifestMarker labels the trailing manifest row of a CSV bundle
+const csvManifestMarker = "#manifest"
+
+var ErrMalformedBundle = errors.New("malformed audit bundle")
+
+// ExportEntry is an audit log as written to an export bundle. Hash chains the
+// entry to its predecessor: sha256(PrevHash || canonical signable JSON).
+type ExportEntry struct {
+	ID              int64           `json:"id"`
+	UserID          string          `json:"user_id"`
+	SessionID       string          `json:"session_id"`
+	Action          string          `json:"action"`
+	ResourceType    string          `json:"resource_type"`
+	ResourceID      string          `json:"resource_id"`
+	Target          string          `json:"target"`
+	Details         json.RawMessage `json:"details,omitempty"`
+	IPAddress       string          `json:"ip_address"`
+	UserAgent       string          `json:"user_agent"`
+	Status          string          `json:"status"`
+	ErrorMessage    string          `json:"error_message"`
+	Severity        string          `json:"severity"`
+	Timestamp       time.Time       `json:"timestamp"`
+	Signature       string          `json:"signature"`
+	SignerPublicKey string          `json:"signer_public_key"`
+	PrevHash        string          `json:"prev_hash"`
+	Hash            string          `json:"hash"`
+}
+
+// Signable re-canonicalizes the signed subset of the entry
+func (e *ExportEntry) Signable() *SignableAuditLog {
+	return &SignableAuditLog{
+		ID:           e.ID,
+		UserID:       e.UserID,
+		Action:       e.Action,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Target:       e.Target,
+		Status:       e.Status,
+		IPAddress:    e.IPAddress,
+		Timestamp:    e.Timestamp,
+	}
+}
+
+// BundleManifest summarizes a bundle and is signed by the exporting gateway,
+// so entries cannot be dropped, added or reordered without detection
+type BundleManifest struct {
+	Format     string    `json:"format"`
+	Count      int       `json:"count"`
+	RangeStart time.Time `json:"range_start"`
+	RangeEnd   time.Time `json:"range_end"`
+	FinalHash  string    `json:"final_hash"`
+	ExportedAt time.Time `json:"exported_at"`
+	PublicKey  string    `json:"public_key"`
+	Signature  string    `json:"signature,omitempty"`
+}
+
+// canonical is the manifest JSON covered by its signature
+func (m BundleManifest) canonical() ([]byte, error) {
+	m.Signature = ""
+	return json.Marshal(m)
+}
+
+// Bundle is an ordered, hash-chained export of audit logs
+type Bundle struct {
+	Entries  []ExportEntry  `json:"entries"`
+	Manifest BundleManifest `json:"manifest"`
+}
+
+// ChainHash links an entry to the previous hash in the chain
+func ChainHash(prevHash string, log *SignableAuditLog) (string, error) {
+	canonical, err := json.Marshal(log)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal log: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(prevHash), canonical...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainLogs extends the chain ending at prevHash with logs, in the given
+// (ascending ID) order, and returns its new head. Entries are chained as in
+// bundles, so an anchored head can be checked against stored entries.
+func ChainLogs(prevHash string, logs []AuditLog) (string, error) {
+	for i := range logs {
+		hash, err := ChainHash(prevHash, signableFromLog(&logs[i]))
+		if err != nil {
+			return "", err
+		}
+		prevHash = hash
+	}
+	return prevHash, nil
+}
+
+// NewBundle chains logs in the given order (ascending ID) and signs the manifest
+func NewBundle(logs []AuditLog, start, end time.Time, signer *AuditSigner) (*Bundle, error) {
+	bundle := &Bundle{Entries: make([]ExportEntry, 0, len(logs))}
+
+	prev := GenesisHash
+	for i := range logs {
+		log := &logs[i]
+		entry := ExportEntry{
+			ID:              log.ID,
+			UserID:          stringOrEmpty(log.UserID),
+			SessionID:       stringOrEmpty(log.SessionID),
+			Action:          log.Action,
+			ResourceType:    stringOrEmpty(log.ResourceType),
+			ResourceID:      stringOrEmpty(log.ResourceID),
+			Target:          stringOrEmpty(log.Target),
+			Details:         log.Details,
+			IPAddress:       stringOrEmpty(log.IPAddress),
+			UserAgent:       stringOrEmpty(log.UserAgent),
+			Status:          log.Status,
+			ErrorMessage:    stringOrEmpty(log.ErrorMessage),
+			Severity:        log.Severity,
+			Timestamp:       log.Timestamp,
+			Signature:       stringOrEmpty(log.Signature),
+			SignerPublicKey: stringOrEmpty(log.SignerPublicKey),
+			PrevHash:        prev,
+		}
+
+		hash, err := ChainHash(prev, entry.Signable())
+		if err != nil {
+			return nil, err
+		}
+		entry.Hash = hash
+		prev = hash
+
+		bundle.Entries = append(bundle.Entries, entry)
+	}
+
+	bundle.Manifest = BundleManifest{
+		Format:     BundleFormat,
+		Count:      len(bundle.Entries),
+		RangeStart: start.UTC(),
+		RangeEnd:   end.UTC(),
+		FinalHash:  prev,
+		ExportedAt: time.Now().UTC(),
+		PublicKey:  signer.GetPublicKey(),
+	}
+	canonical, err := bundle.Manifest.canonical()
+	if err != nil {
+		return nil, err
+	}
+	bundle.Manifest.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(signer.privateKey, canonical))
+
+	return bundle, nil
+}
+
+// WriteNDJSON writes one entry per line followed by a {"manifest": ...} line
+func (b *Bundle) WriteNDJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for i := range b.Entries {
+		if err := enc.Encode(&b.Entries[i]); err != nil {
+			return err
+		}
+	}
+	return enc.Encode(map[string]interface{}{"manifest": b.Manifest})
+}
+
+var csvHeader = []string{
+	"id", "user_id", "session_id", "action", "resource_type", "resource_id", "target",
+	"details", "ip_address", "user_agent", "status", "error_message", "severity",
+	"timestamp", "signature", "signer_public_key", "prev_hash", "hash",
+}
+
+// WriteCSV writes a header, one row per entry and a final "#manifest" row
+// holding the manifest JSON
+func (b *Bundle) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range b.Entries {
+		err := cw.Write([]string{
+			strconv.FormatInt(e.ID, 10), e.UserID, e.SessionID, e.Action, e.ResourceType, e.ResourceID, e.Target,
+			string(e.Details), e.IPAddress, e.UserAgent, e.Status, e.ErrorMessage, e.Severity,
+			e.Timestamp.Format(time.RFC3339Nano), e.Signature, e.SignerPublicKey, e.PrevHash, e.Hash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	manifest, err := json.Marshal(b.Manifest)
+	if err != nil {
+		return err
+	}
+	if err := cw.Write([]string{csvManifestMarker, string(manifest)}); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadBundle parses an NDJSON or CSV bundle, detected from its first byte
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: empty input", ErrMalformedBundle)
+	}
+	if first[0] == '{' {
+		return readNDJSON(br)
+	}
+	return readCSV(br)
+}
+
+func readNDJSON(r io.Reader) (*Bundle, error) {
+	bundle := &Bundle{}
+	var manifest *BundleManifest
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if manifest != nil {
+			return nil, fmt.Errorf("%w: line %d follows the manifest", ErrMalformedBundle, line)
+		}
+
+		var probe struct {
+			Manifest *BundleManifest `json:"manifest"`
+		}
+		if err := json.Unmarshal(raw, &probe); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrMalformedBundle, line, err)
+		}
+		if probe.Manifest != nil {
+			manifest = probe.Manifest
+			continue
+		}
+
+		var entry ExportEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrMalformedBundle, line, err)
+		}
+		bundle.Entries = append(bundle.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing manifest", ErrMalformedBundle)
+	}
+
+	bundle.Manifest = *manifest
+	return bundle, nil
+}
+
+func readCSV(r io.Reader) (*Bundle, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedBundle, err)
+	}
+	if len(header) != len(csvHeader) {
+		return nil, fmt.Errorf("%w: unexpected CSV header", ErrMalformedBundle)
+	}
+
+	bundle := &Bundle{}
+	manifestFound := false
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrMalformedBundle, row, err)
+		}
+		if manifestFound {
+			return nil, fmt.Errorf("%w: row %d follows the manifest", ErrMalformedBundle, row)
+		}
+
+		if record[0] == csvManifestMarker {
+			if len(record) < 2 {
+				return nil, fmt.Errorf("%w: empty manifest row", ErrMalformedBundle)
+			}
+			if err := json.Unmarshal([]byte(record[1]), &bundle.Manifest); err != nil {
+				return nil, fmt.Errorf("%w: manifest: %v", ErrMalformedBundle, err)
+			}
+			manifestFound = true
+			continue
+		}
+		if len(record) != len(csvHeader) {
+			return nil, fmt.Errorf("%w: row %d has %d fields", ErrMalformedBundle, row, len(record))
+		}
+
+		id, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: invalid id", ErrMalformedBundle, row)
+		}
+		ts, err := time.Parse(time.RFC3339Nano, record[13])
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: invalid timestamp", ErrMalformedBundle, row)
+		}
+		entry := ExportEntry{
+			ID: id, UserID: record[1], SessionID: record[2], Action: record[3],
+			ResourceType: record[4], ResourceID: record[5], Target: record[6],
+			IPAddress: record[8], UserAgent: record[9], Status: record[10],
+			ErrorMessage: record[11], Severity: record[12], Timestamp: ts,
+			Signature: record[14], SignerPublicKey: record[15], PrevHash: record[16], Hash: record[17],
+		}
+		if record[7] != "" {
+			entry.Details = json.RawMessage(record[7])
+		}
+		bundle.Entries = append(bundle.Entries, entry)
+	}
+	if !manifestFound {
+		return nil, fmt.Errorf("%w: missing manifest", ErrMalformedBundle)
+	}
+	return bundle, nil
+}
@@ -0,0 +1,183 @@
+//go:build integration
+
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/testenv"
+	"go.uber.org/zap"
+)
+
+// writeSigned logs n entries and waits for the background signing of each
+func writeSigned(t *testing.T, env *testenv.Env, n int) []audit.AuditLog {
+	t.Helper()
+	ctx := context.Background()
+	user := env.CreateUser(t, testenv.UserOptions{})
+	for i := 0; i < n; i++ {
+		if err := env.Audit.LogSuccess(ctx, user.ID, "scan_created", "scan", user.ID, map[string]interface{}{"index": i}); err != nil {
+			t.Fatalf("LogSuccess: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var unsigned int
+		if err := env.DB.GetContext(ctx, &unsigned, `SELECT COUNT(*) FROM audit_logs WHERE signature IS NULL`); err != nil {
+			t.Fatal(err)
+		}
+		if unsigned == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d audit logs still unsigned", unsigned)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	logs, err := repository.New(env.DB).Audit.Range(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != n {
+		t.Fatalf("stored %d audit logs, want %d", len(logs), n)
+	}
+	return logs
+}
+
+// newBundle exports logs signed by a new key, and returns the keys to trust
+func newBundle(t *testing.T, logs []audit.AuditLog) (*audit.Bundle, []ed25519.PublicKey) {
+	t.Helper()
+	signer, err := audit.NewAuditSigner(zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := audit.NewBundle(logs, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), signer)
+	if err != nil {
+		t.Fatalf("NewBundle: %v", err)
+	}
+	trusted, err := audit.ParsePublicKeys([]string{signer.GetPublicKey(), *logs[0].SignerPublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle, trusted
+}
+
+func TestBundleChain(t *testing.T) {
+	env := testenv.Setup(t)
+	logs := writeSigned(t, env, 4)
+
+	t.Run("exported bundles verify", func(t *testing.T) {
+		bundle, trusted := newBundle(t, logs)
+
+		// Through the file format an auditor receives
+		var buf bytes.Buffer
+		if err := bundle.WriteNDJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		read, err := audit.ReadBundle(&buf)
+		if err != nil {
+			t.Fatalf("ReadBundle: %v", err)
+		}
+		report := audit.VerifyBundle(read, trusted)
+		if !report.OK(false) || report.ValidSignatures != len(logs) {
+			t.Errorf("report = %+v, want every entry verified", report)
+		}
+	})
+
+	t.Run("removed entries break the chain", func(t *testing.T) {
+		bundle, trusted := newBundle(t, logs)
+		bundle.Entries = append(bundle.Entries[:1], bundle.Entries[2:]...)
+		report := audit.VerifyBundle(bundle, trusted)
+		if report.ChainValid || report.OK(true) {
+			t.Errorf("report = %+v, want a broken chain", report)
+		}
+	})
+
+	t.Run("reordered entries break the chain", func(t *testing.T) {
+		bundle, trusted := newBundle(t, logs)
+		bundle.Entries[1], bundle.Entries[2] = bundle.Entries[2], bundle.Entries[1]
+		if report := audit.VerifyBundle(bundle, trusted); report.ChainValid {
+			t.Errorf("report = %+v, want a broken chain", report)
+		}
+	})
+
+	t.Run("modified entries fail their hash and signature", func(t *testing.T) {
+		bundle, trusted := newBundle(t, logs)
+		bundle.Entries[2].Target = "somewhere-else"
+		report := audit.VerifyBundle(bundle, trusted)
+		if report.ChainValid || report.InvalidSignatures != 1 {
+			t.Errorf("report = %+v, want a hash mismatch and one invalid signature", report)
+		}
+	})
+
+	t.Run("entries by untrusted keys", func(t *testing.T) {
+		bundle, _ := newBundle(t, logs)
+		other, _, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := audit.VerifyBundle(bundle, []ed25519.PublicKey{other})
+		if report.UntrustedKeys != len(logs) || report.ManifestValid {
+			t.Errorf("report = %+v, want every key untrusted", report)
+		}
+	})
+}
+
+func TestVerifyStoredRange(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	logs := writeSigned(t, env, 5)
+	repos := repository.New(env.DB)
+
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	verify := func() *audit.RangeVerification {
+		t.Helper()
+		// Pages of two cover the range in several loads
+		next := func(ctx context.Context, afterID int64) ([]audit.AuditLog, error) {
+			return repos.Audit.Page(ctx, start, end, afterID, 2)
+		}
+		result, err := audit.VerifyRange(ctx, start, end, next, 3)
+		if err != nil {
+			t.Fatalf("VerifyRange: %v", err)
+		}
+		return result
+	}
+
+	if result := verify(); !result.OK() || result.Verified != len(logs) {
+		t.Fatalf("result = %+v, want every entry verified", result)
+	}
+
+	// Tampering with a stored entry invalidates its signature
+	tampered := logs[3].ID
+	if _, err := env.DB.ExecContext(ctx, `UPDATE audit_logs SET status = 'failure' WHERE id = $1`, tampered); err != nil {
+		t.Fatal(err)
+	}
+	result := verify()
+	if result.OK() || result.Invalid != 1 || len(result.InvalidIDs) != 1 || result.InvalidIDs[0] != tampered {
+		t.Errorf("result = %+v, want entry %d invalid", result, tampered)
+	}
+
+	// The chain over the stored entries no longer matches the exported one
+	before, err := audit.ChainLogs(audit.GenesisHash, logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repos.Audit.Range(ctx, start, end, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := audit.ChainLogs(audit.GenesisHash, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Error("chain head unchanged by a modified entry")
+	}
+}
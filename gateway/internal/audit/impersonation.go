@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type impersonationKey struct{}
+
+type impersonation struct {
+	impersonatorID  string
+	impersonationID string
+}
+
+// WithImpersonation marks a context as acting under an impersonation, so every
+// entry logged with it records the admin behind the action
+func WithImpersonation(ctx context.Context, impersonatorID, impersonationID string) context.Context {
+	return context.WithValue(ctx, impersonationKey{}, impersonation{
+		impersonatorID:  impersonatorID,
+		impersonationID: impersonationID,
+	})
+}
+
+// withImpersonationDetails copies details, adding the impersonating admin
+func withImpersonationDetails(ctx context.Context, details map[string]interface{}) map[string]interface{} {
+	imp, ok := ctx.Value(impersonationKey{}).(impersonation)
+	if !ok {
+		return details
+	}
+
+	merged := make(map[string]interface{}, len(details)+2)
+	for k, v := range details {
+		merged[k] = v
+	}
+	merged["impersonator_id"] = imp.impersonatorID
+	merged["impersonation_id"] = imp.impersonationID
+	return merged
+}
+
+// ImpersonationMiddleware audits every request made with an impersonation
+// token and tags audit entries written by handlers with both identities.
+// It must run after the auth middleware.
+func (a *AuditLogger) ImpersonationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		impersonatorID := c.GetString("impersonator_id")
+		if impersonatorID == "" {
+			c.Next()
+			return
+		}
+
+		ctx := WithImpersonation(c.Request.Context(), impersonatorID, c.GetString("impersonation_id"))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := "success"
+		if c.Writer.Status() >= http.StatusBadRequest {
+			status = "failure"
+		}
+		a.Log(ctx, LogParams{
+			UserID:       c.GetString("user_id"),
+			SessionID:    c.GetString("session_id"),
+			Action:       "impersonated_request",
+			ResourceType: "http_request",
+			Target:       c.Request.Method + " " + c.FullPath(),
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+			Status:       status,
+			Severity:     "medium",
+			Details: map[string]interface{}{
+				"method":      c.Request.Method,
+				"path":        c.Request.URL.Path,
+				"status_code": c.Writer.Status(),
+			},
+		})
+	}
+}
@@ -4,34 +4,57 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/jmoiron/sqlx"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/metrics"
 	"go.uber.org/zap"
 )
 
 type AuditLogger struct {
-	db     *sqlx.DB
-	logger *zap.Logger
-	signer *AuditSigner // Cryptographic signer for audit logs
+	db       *database.DB
+	logger   *zap.Logger
+	signer   *AuditSigner    // Cryptographic signer for audit logs
+	buffer   *BufferedWriter // Optional batched writer (see EnableBuffering)
+	redactor *Redactor       // Masks credentials in details before persistence
+	stream   *Stream         // Live feed of stored entries
 }
 
-func NewAuditLogger(db *sqlx.DB, logger *zap.Logger) *AuditLogger {
+func NewAuditLogger(db *database.DB, logger *zap.Logger) *AuditLogger {
 	signer, err := NewAuditSigner(logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize audit signer", zap.Error(err))
 	}
 
+	redactor, err := NewRedactor(DefaultRedactionConfig())
+	if err != nil {
+		logger.Fatal("Failed to initialize audit redaction", zap.Error(err))
+	}
+
 	return &AuditLogger{
-		db:     db,
-		logger: logger,
-		signer: signer,
+		db:       db,
+		logger:   logger,
+		signer:   signer,
+		redactor: redactor,
+		stream:   newStream(),
 	}
 }
 
+// Stream is the live feed of entries as they are stored
+func (a *AuditLogger) Stream() *Stream {
+	return a.stream
+}
+
+// SetRedactor replaces the redaction rules applied to every entry
+func (a *AuditLogger) SetRedactor(redactor *Redactor) {
+	a.redactor = redactor
+}
+
 type AuditLog struct {
 	ID                 int64           `db:"id"`
 	UserID             *string         `db:"user_id"`
+	OrganizationID     *string         `db:"organization_id"`
 	SessionID          *string         `db:"session_id"`
 	Action             string          `db:"action"`
 	ResourceType       *string         `db:"resource_type"`
@@ -52,6 +75,7 @@ type AuditLog struct {
 
 type LogParams struct {
 	UserID             string
+	OrganizationID     string // defaults to the organization in ctx (see WithOrganization)
 	SessionID          string
 	Action             string
 	ResourceType       string
@@ -76,33 +100,77 @@ func (a *AuditLogger) Log(ctx context.Context, params LogParams) error {
 		params.Severity = "info"
 	}
 
-	// Convert details to JSON
-	var detailsJSON []byte
-	var err error
-	if params.Details != nil {
-		detailsJSON, err = json.Marshal(params.Details)
-		if err != nil {
-			a.logger.Error("Failed to marshal audit log details", zap.Error(err))
-			detailsJSON = []byte("{}")
+	if params.OrganizationID == "" {
+		params.OrganizationID = organizationFromContext(ctx)
+	}
+	params.Details = withImpersonationDetails(ctx, params.Details)
+	if err := a.redact(&params); err != nil {
+		return err
+	}
+	detailsJSON := a.marshalDetails(params.Details)
+
+	// Critical events are written synchronously so they are never lost in a buffer
+	if a.buffer != nil && params.Severity != "critical" {
+		if a.buffer.enqueue(bufferedEntry{params: params, details: detailsJSON}) {
+			return nil
 		}
-	} else {
-		detailsJSON = []byte("{}")
+		metrics.AuditBufferOverflows.Inc()
 	}
 
+	return a.writeSync(ctx, params, detailsJSON)
+}
+
+// redact masks credentials in the entry's details, target and error message,
+// listing masked detail paths under "redacted". In strict mode the entry is
+// rejected instead.
+func (a *AuditLogger) redact(params *LogParams) error {
+	details, paths := a.redactor.Redact(params.Details)
+	target, targetHit := a.redactor.RedactString(params.Target)
+	errorMessage, errorHit := a.redactor.RedactString(params.ErrorMessage)
+	if targetHit {
+		paths = append(paths, "target")
+	}
+	if errorHit {
+		paths = append(paths, "error_message")
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	if a.redactor.Strict() {
+		a.logger.Error("Rejected audit entry containing credentials",
+			zap.String("action", params.Action),
+			zap.Strings("fields", paths),
+		)
+		return fmt.Errorf("%w: %s", ErrCredentialDetected, strings.Join(paths, ", "))
+	}
+
+	if details == nil {
+		details = make(map[string]interface{}, 1)
+	}
+	details["redacted"] = paths
+	params.Details = details
+	params.Target = target
+	params.ErrorMessage = errorMessage
+	return nil
+}
+
+// writeSync inserts a single audit log and signs it in the background
+func (a *AuditLogger) writeSync(ctx context.Context, params LogParams, detailsJSON []byte) error {
 	query := `
 		INSERT INTO audit_logs (
 			user_id, session_id, action, resource_type, resource_id, 
 			target, authorization_proof, details, ip_address, user_agent,
-			status, error_message, severity, timestamp
+			status, error_message, severity, organization_id, timestamp
 		) VALUES (
 			NULLIF($1, ''), NULLIF($2, ''), $3, NULLIF($4, ''), NULLIF($5, ''),
 			NULLIF($6, ''), NULLIF($7, ''), $8, NULLIF($9, ''), NULLIF($10, ''),
-			$11, NULLIF($12, ''), $13, NOW()
+			$11, NULLIF($12, ''), $13, NULLIF($14, '')::uuid, NOW()
 		)
 	`
 
 	var logID int64
-	err = a.db.QueryRowContext(ctx, query+` RETURNING id`,
+	err := a.db.QueryRowContext(ctx, query+` RETURNING id`,
 		params.UserID,
 		params.SessionID,
 		params.Action,
@@ -116,6 +184,7 @@ func (a *AuditLogger) Log(ctx context.Context, params LogParams) error {
 		params.Status,
 		params.ErrorMessage,
 		params.Severity,
+		params.OrganizationID,
 	).Scan(&logID)
 
 	if err != nil {
@@ -123,7 +192,10 @@ func (a *AuditLogger) Log(ctx context.Context, params LogParams) error {
 		return fmt.Errorf("failed to create audit log: %w", err)
 	}
 
+	metrics.AuditLogsTotal.WithLabelValues(params.Severity, params.Action).Inc()
+
 	// Sign the audit log asynchronously (don't block on signing)
+	metrics.AuditSigningQueueDepth.Inc()
 	go a.signAuditLog(ctx, logID, params)
 
 	a.logger.Debug("Audit log created",
@@ -139,31 +211,23 @@ func (a *AuditLogger) Log(ctx context.Context, params LogParams) error {
 
 // signAuditLog signs an audit log entry (called asynchronously)
 func (a *AuditLogger) signAuditLog(ctx context.Context, logID int64, params LogParams) {
+	defer metrics.AuditSigningQueueDepth.Dec()
+
 	// Fetch the complete log from DB to ensure we sign what's actually stored
 	var log AuditLog
 	err := a.db.GetContext(ctx, &log, "SELECT * FROM audit_logs WHERE id = $1", logID)
 	if err != nil {
 		a.logger.Error("Failed to fetch log for signing", zap.Error(err), zap.Int64("log_id", logID))
+		metrics.AuditSigningFailures.Inc()
 		return
 	}
-
-	// Create signable version
-	signableLog := &SignableAuditLog{
-		ID:           log.ID,
-		UserID:       stringOrEmpty(log.UserID),
-		Action:       log.Action,
-		ResourceType: stringOrEmpty(log.ResourceType),
-		ResourceID:   stringOrEmpty(log.ResourceID),
-		Target:       stringOrEmpty(log.Target),
-		Status:       log.Status,
-		IPAddress:    stringOrEmpty(log.IPAddress),
-		Timestamp:    log.Timestamp,
-	}
+	a.stream.publish(log)
 
 	// Sign the log
-	signature, err := a.signer.SignLog(signableLog)
+	signature, err := a.signer.SignLog(signableFromLog(&log))
 	if err != nil {
 		a.logger.Error("Failed to sign audit log", zap.Error(err), zap.Int64("log_id", logID))
+		metrics.AuditSigningFailures.Inc()
 		return
 	}
 
@@ -176,12 +240,30 @@ func (a *AuditLogger) signAuditLog(ctx context.Context, logID int64, params LogP
 
 	if err != nil {
 		a.logger.Error("Failed to save audit log signature", zap.Error(err), zap.Int64("log_id", logID))
+		metrics.AuditSigningFailures.Inc()
 		return
 	}
 
+	metrics.AuditLogsSignedTotal.Inc()
+
 	a.logger.Debug("Audit log signed", zap.Int64("log_id", logID))
 }
 
+// signableFromLog builds the signed subset of a stored audit log
+func signableFromLog(log *AuditLog) *SignableAuditLog {
+	return &SignableAuditLog{
+		ID:           log.ID,
+		UserID:       stringOrEmpty(log.UserID),
+		Action:       log.Action,
+		ResourceType: stringOrEmpty(log.ResourceType),
+		ResourceID:   stringOrEmpty(log.ResourceID),
+		Target:       stringOrEmpty(log.Target),
+		Status:       log.Status,
+		IPAddress:    stringOrEmpty(log.IPAddress),
+		Timestamp:    log.Timestamp,
+	}
+}
+
 // Helper function
 func stringOrEmpty(s *string) string {
 	if s == nil {
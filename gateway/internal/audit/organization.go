@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type organizationKey struct{}
+
+// WithOrganization scopes a context to an organization, so every entry logged
+// with it is attributed to that tenant
+func WithOrganization(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, organizationKey{}, orgID)
+}
+
+func organizationFromContext(ctx context.Context) string {
+	orgID, _ := ctx.Value(organizationKey{}).(string)
+	return orgID
+}
+
+// OrganizationMiddleware attributes audit entries written while handling a
+// request to its organization: the one in the path for /organizations/:id
+// routes, otherwise the caller's active organization. It must run after the
+// auth middleware.
+func (a *AuditLogger) OrganizationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID := c.GetString("organization_id")
+		if strings.Contains(c.FullPath(), "/organizations/:id") {
+			orgID = c.Param("id")
+		}
+
+		if orgID != "" {
+			c.Request = c.Request.WithContext(WithOrganization(c.Request.Context(), orgID))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,199 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrCredentialDetected is returned by Log in strict mode when an entry's
+// details or error message contain a credential
+var ErrCredentialDetected = errors.New("audit entry contains credentials")
+
+// redactedValue replaces masked values
+const redactedValue = "[REDACTED]"
+
+// RedactionConfig lists the rules applied to audit details before persistence
+type RedactionConfig struct {
+	// KeyPatterns are regular expressions matched against detail keys at any
+	// depth; matching values are masked whatever they contain
+	KeyPatterns []string
+	// ValuePatterns are regular expressions that detect credentials inside
+	// string values (and error messages); matches are masked in place
+	ValuePatterns []string
+	// Strict rejects entries containing credentials instead of masking them,
+	// so leaks are caught during development rather than silently hidden
+	Strict bool
+}
+
+// DefaultRedactionConfig masks common credential fields and token formats.
+// Key patterns match whole "_"-separated words so that e.g. authorization_id
+// survives while access_token does not.
+func DefaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		KeyPatterns: []string{
+			`(?i)(^|_)(password|passwd|passphrase|secret|token|api_?key|private_?key|credentials?|cookie)($|_)`,
+			`(?i)^(authorization|proxy_authorization|set_cookie)$`,
+		},
+		ValuePatterns: []string{
+			`(?i)\bbearer\s+[a-z0-9._~+/=-]{8,}`,                        // Authorization header values
+			`\beyJ[a-zA-Z0-9_-]{5,}\.[a-zA-Z0-9_-]{5,}\.[a-zA-Z0-9_-]*`, // JWTs
+			`-----BEGIN [A-Z ]*PRIVATE KEY-----`,                        // PEM private keys
+			`\b(AKIA|ASIA)[0-9A-Z]{16}\b`,                               // AWS access key IDs
+			`\bsk-[a-zA-Z0-9_-]{20,}`,                                   // API secret keys (sk-...)
+			`\bgh[pousr]_[A-Za-z0-9]{30,}`,                              // GitHub tokens
+			`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`,             // credentials embedded in URLs
+		},
+	}
+}
+
+// Redactor masks or rejects credentials in audit details
+type Redactor struct {
+	keys   []*regexp.Regexp
+	values []*regexp.Regexp
+	strict bool
+}
+
+// NewRedactor compiles the configured rules
+func NewRedactor(config RedactionConfig) (*Redactor, error) {
+	r := &Redactor{strict: config.Strict}
+	for _, pattern := range config.KeyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+		}
+		r.keys = append(r.keys, re)
+	}
+	for _, pattern := range config.ValuePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value pattern %q: %w", pattern, err)
+		}
+		r.values = append(r.values, re)
+	}
+	return r, nil
+}
+
+// Strict reports whether entries with credentials are rejected
+func (r *Redactor) Strict() bool {
+	return r.strict
+}
+
+// Redact returns a masked copy of details and the paths (e.g. "body.password")
+// of every value that was masked. The input is not modified.
+func (r *Redactor) Redact(details map[string]interface{}) (map[string]interface{}, []string) {
+	if len(details) == 0 {
+		return details, nil
+	}
+
+	var found []string
+	out := r.redactMap(details, "", &found)
+	sort.Strings(found)
+	return out, found
+}
+
+// RedactString masks credentials inside a free-form string, reporting whether any were found
+func (r *Redactor) RedactString(s string) (string, bool) {
+	masked := s
+	for _, re := range r.values {
+		masked = re.ReplaceAllString(masked, redactedValue)
+	}
+	return masked, masked != s
+}
+
+// MatchesKey reports whether a detail key is always masked
+func (r *Redactor) MatchesKey(key string) bool {
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) redactMap(m map[string]interface{}, prefix string, found *[]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		path := joinPath(prefix, key)
+		if r.MatchesKey(key) && !carriesNoSecret(value) {
+			out[key] = redactedValue
+			*found = append(*found, path)
+			continue
+		}
+		out[key] = r.redactValue(value, path, found)
+	}
+	return out
+}
+
+func (r *Redactor) redactValue(value interface{}, path string, found *[]string) interface{} {
+	switch v := value.(type) {
+	case nil, bool, int, int32, int64, float32, float64:
+		return value
+	case string:
+		masked, hit := r.RedactString(v)
+		if hit {
+			*found = append(*found, path)
+		}
+		return masked
+	case map[string]interface{}:
+		return r.redactMap(v, path, found)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, inner := range v {
+			out[i] = r.redactValue(inner, fmt.Sprintf("%s[%d]", path, i), found)
+		}
+		return out
+	case map[string]string:
+		generic := make(map[string]interface{}, len(v))
+		for key, inner := range v {
+			generic[key] = inner
+		}
+		return r.redactMap(generic, path, found)
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, inner := range v {
+			out[i] = r.redactValue(inner, fmt.Sprintf("%s[%d]", path, i), found)
+		}
+		return out
+	default:
+		// Structs and other types are inspected through their JSON form
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return value
+		}
+		var generic interface{}
+		if err := json.Unmarshal(raw, &generic); err != nil {
+			return value
+		}
+		before := len(*found)
+		redacted := r.redactValue(generic, path, found)
+		if len(*found) == before {
+			return value
+		}
+		return redacted
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// carriesNoSecret reports values that are safe to keep under a sensitive key
+func carriesNoSecret(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case bool:
+		// Flags such as "password_changed": true carry no secret
+		return true
+	}
+	return false
+}
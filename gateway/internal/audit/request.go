@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// skipRequestKey marks a request as opted out of automatic auditing
+const skipRequestKey = "audit_skip_request"
+
+// RequestAuditConfig controls automatic auditing of state-changing requests
+type RequestAuditConfig struct {
+	// SkipRoutes lists "METHOD /route/:pattern" entries (gin full paths) that
+	// are never recorded, e.g. endpoints that already log a richer entry
+	SkipRoutes []string
+	// RecordBodies stores a copy of JSON request bodies up to MaxBodyBytes,
+	// masked by the logger's redaction rules. The SHA-256 of the full body is
+	// always recorded.
+	RecordBodies bool
+	MaxBodyBytes int64
+}
+
+// DefaultRequestAuditConfig records body hashes only
+func DefaultRequestAuditConfig() RequestAuditConfig {
+	return RequestAuditConfig{
+		MaxBodyBytes: 64 << 10,
+	}
+}
+
+// SkipRequestAudit opts a single route out of RequestMiddleware
+func SkipRequestAudit(c *gin.Context) {
+	c.Set(skipRequestKey, true)
+	c.Next()
+}
+
+// RequestMiddleware records an "api_request" audit entry for every
+// state-changing request (POST, PUT, PATCH, DELETE) with the caller, the
+// route, the response status, latency and a hash of the request body. It
+// reads identity and organization after the handler chain has run, so it may
+// be installed ahead of the auth middleware.
+func (a *AuditLogger) RequestMiddleware(config RequestAuditConfig) gin.HandlerFunc {
+	skip := make(map[string]bool, len(config.SkipRoutes))
+	for _, route := range config.SkipRoutes {
+		skip[route] = true
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		hasher := sha256.New()
+		var snapshot []byte
+
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			if config.RecordBodies && isJSONRequest(c.Request) &&
+				c.Request.ContentLength >= 0 && c.Request.ContentLength <= config.MaxBodyBytes {
+				body, err := io.ReadAll(c.Request.Body)
+				c.Request.Body.Close()
+				if err == nil {
+					snapshot = body
+				}
+				hasher.Write(body)
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			} else {
+				// Hash whatever the handler consumes without buffering it
+				c.Request.Body = &hashingBody{ReadCloser: c.Request.Body, hash: hasher}
+			}
+		}
+
+		c.Next()
+
+		route := c.FullPath()
+		if c.GetBool(skipRequestKey) || skip[c.Request.Method+" "+route] {
+			return
+		}
+
+		code := c.Writer.Status()
+		status := "success"
+		switch {
+		case code >= http.StatusInternalServerError:
+			status = "error"
+		case code >= http.StatusBadRequest:
+			status = "failure"
+		}
+
+		details := map[string]interface{}{
+			"method":      c.Request.Method,
+			"route":       route,
+			"path":        c.Request.URL.Path,
+			"status_code": code,
+			"latency_ms":  time.Since(start).Milliseconds(),
+			"body_sha256": hex.EncodeToString(hasher.Sum(nil)),
+		}
+		if requestID := c.GetString("request_id"); requestID != "" {
+			details["request_id"] = requestID
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			details["query"] = map[string][]string(query)
+		}
+		if snapshot != nil {
+			var body interface{}
+			if err := json.Unmarshal(snapshot, &body); err == nil {
+				details["body"] = body
+			}
+		}
+
+		a.Log(c.Request.Context(), LogParams{
+			UserID:       c.GetString("user_id"),
+			SessionID:    c.GetString("session_id"),
+			Action:       "api_request",
+			ResourceType: "http_request",
+			Target:       c.Request.Method + " " + route,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+			Status:       status,
+			Details:      details,
+		})
+	}
+}
+
+// hashingBody hashes a request body as it is read
+type hashingBody struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	return n, err
+}
+
+func isJSONRequest(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	return contentType == "" || strings.HasPrefix(contentType, "application/json")
+}
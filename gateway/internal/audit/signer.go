@@ -19,11 +19,21 @@ type AuditSigner struct {
 	logger     *zap.Logger
 }
 
+// keyLookup resolves the signing keys by name; environment variables unless
+// a secrets provider is configured
+var keyLookup = os.Getenv
+
+// SetKeyLookup makes NewAuditSigner read its keys through lookup, e.g. from
+// a secrets provider
+func SetKeyLookup(lookup func(name string) string) {
+	keyLookup = lookup
+}
+
 // NewAuditSigner creates a new audit signer
 // Loads keys from environment or generates new ones
 func NewAuditSigner(logger *zap.Logger) (*AuditSigner, error) {
-	privateKeyB64 := os.Getenv("AUDIT_SIGNING_PRIVATE_KEY")
-	publicKeyB64 := os.Getenv("AUDIT_SIGNING_PUBLIC_KEY")
+	privateKeyB64 := keyLookup("AUDIT_SIGNING_PRIVATE_KEY")
+	publicKeyB64 := keyLookup("AUDIT_SIGNING_PUBLIC_KEY")
 
 	var privateKey ed25519.PrivateKey
 	var publicKey ed25519.PublicKey
@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/metrics"
+)
+
+// ValidSeverity reports whether severity is a known audit severity
+func ValidSeverity(severity string) bool {
+	return slices.Contains(findings.Severities, severity)
+}
+
+// StreamFilter selects which entries a stream subscriber receives
+type StreamFilter struct {
+	OrganizationID string // Required: subscribers only see their organization
+	MinSeverity    string // Entries at or above this severity; empty for all
+	ActionPrefix   string // Entries whose action starts with this prefix
+}
+
+func (f StreamFilter) matches(log *AuditLog) bool {
+	if log.OrganizationID == nil || *log.OrganizationID != f.OrganizationID {
+		return false
+	}
+	if f.MinSeverity != "" && findings.SeverityRank(log.Severity) > findings.SeverityRank(f.MinSeverity) {
+		return false
+	}
+	return strings.HasPrefix(log.Action, f.ActionPrefix)
+}
+
+// Stream fans newly written audit entries out to live subscribers. Entries
+// are published by the writer once stored, so only entries written by this
+// gateway instance are seen.
+type Stream struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+func newStream() *Stream {
+	return &Stream{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives matching entries until closed. A subscriber that
+// falls more than its buffer behind misses entries rather than slowing the
+// writer down.
+type Subscription struct {
+	C       <-chan AuditLog
+	ch      chan AuditLog
+	filter  StreamFilter
+	stream  *Stream
+	once    sync.Once
+	dropped uint64
+}
+
+// Subscribe starts receiving entries matching filter
+func (s *Stream) Subscribe(filter StreamFilter, buffer int) *Subscription {
+	ch := make(chan AuditLog, buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, stream: s}
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	metrics.AuditStreamSubscribers.Inc()
+
+	return sub
+}
+
+// Close stops delivery and releases the subscription
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		sub.stream.mu.Lock()
+		delete(sub.stream.subscribers, sub)
+		sub.stream.mu.Unlock()
+		metrics.AuditStreamSubscribers.Dec()
+	})
+}
+
+// Dropped is how many entries were skipped because the subscriber lagged
+func (sub *Subscription) Dropped() uint64 {
+	sub.stream.mu.RLock()
+	defer sub.stream.mu.RUnlock()
+	return sub.dropped
+}
+
+// publish delivers stored entries to matching subscribers without blocking
+func (s *Stream) publish(logs ...AuditLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.subscribers) == 0 {
+		return
+	}
+
+	for i := range logs {
+		for sub := range s.subscribers {
+			if !sub.filter.matches(&logs[i]) {
+				continue
+			}
+			select {
+			case sub.ch <- logs[i]:
+			default:
+				sub.dropped++
+				metrics.AuditStreamDropped.Inc()
+			}
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// VerificationIssue is one problem found while verifying a bundle
+type VerificationIssue struct {
+	EntryID int64  `json:"entry_id,omitempty"`
+	Line    int    `json:"line"` // 1-based position among entries; 0 for the manifest
+	Problem string `json:"problem"`
+}
+
+// VerificationReport is the outcome of verifying a bundle offline
+type VerificationReport struct {
+	Entries           int                 `json:"entries"`
+	ValidSignatures   int                 `json:"valid_signatures"`
+	InvalidSignatures int                 `json:"invalid_signatures"`
+	UntrustedKeys     int                 `json:"untrusted_keys"`
+	Unsigned          int                 `json:"unsigned"`
+	ChainValid        bool                `json:"chain_valid"`
+	ManifestValid     bool                `json:"manifest_valid"`
+	Issues            []VerificationIssue `json:"issues"`
+}
+
+// OK reports whether the bundle verified. Unsigned entries (not yet signed
+// when exported) only fail verification when allowUnsigned is false.
+func (r *VerificationReport) OK(allowUnsigned bool) bool {
+	if !r.ChainValid || !r.ManifestValid || r.InvalidSignatures > 0 || r.UntrustedKeys > 0 {
+		return false
+	}
+	return allowUnsigned || r.Unsigned == 0
+}
+
+func (r *VerificationReport) issue(line int, entryID int64, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, VerificationIssue{EntryID: entryID, Line: line, Problem: fmt.Sprintf(format, args...)})
+}
+
+// ParsePublicKeys decodes base64 Ed25519 public keys
+func ParsePublicKeys(keys []string) ([]ed25519.PublicKey, error) {
+	parsed := make([]ed25519.PublicKey, 0, len(keys))
+	for _, key := range keys {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode public key %q: %w", key, err)
+		}
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("public key %q is %d bytes, want %d", key, len(raw), ed25519.PublicKeySize)
+		}
+		parsed = append(parsed, ed25519.PublicKey(raw))
+	}
+	return parsed, nil
+}
+
+// VerifyBundle re-canonicalizes every entry, checks its signature against the
+// trusted keys, recomputes the hash chain and verifies the signed manifest
+func VerifyBundle(bundle *Bundle, trusted []ed25519.PublicKey) *VerificationReport {
+	report := &VerificationReport{
+		Entries:    len(bundle.Entries),
+		ChainValid: true,
+		Issues:     []VerificationIssue{},
+	}
+
+	prev := GenesisHash
+	for i := range bundle.Entries {
+		entry := &bundle.Entries[i]
+		line := i + 1
+		signable := entry.Signable()
+
+		// Hash chain
+		if entry.PrevHash != prev {
+			report.ChainValid = false
+			report.issue(line, entry.ID, "prev_hash does not match the preceding entry")
+		}
+		hash, err := ChainHash(prev, signable)
+		if err != nil {
+			report.ChainValid = false
+			report.issue(line, entry.ID, "cannot canonicalize entry: %v", err)
+			continue
+		}
+		if entry.Hash != hash {
+			report.ChainValid = false
+			report.issue(line, entry.ID, "hash mismatch: entry was modified")
+		}
+		// Continue from the recomputed hash so one bad entry is reported once
+		prev = hash
+
+		// Entry signature
+		if entry.Signature == "" {
+			report.Unsigned++
+			continue
+		}
+		key, ok := trustedKey(entry.SignerPublicKey, trusted)
+		if !ok {
+			report.UntrustedKeys++
+			report.issue(line, entry.ID, "signed by untrusted key %s", entry.SignerPublicKey)
+			continue
+		}
+		if verifySigned(key, signable, entry.Signature) {
+			report.ValidSignatures++
+		} else {
+			report.InvalidSignatures++
+			report.issue(line, entry.ID, "invalid signature")
+		}
+	}
+
+	// Manifest
+	m := bundle.Manifest
+	report.ManifestValid = true
+	if m.Format != BundleFormat {
+		report.ManifestValid = false
+		report.issue(0, 0, "unsupported bundle format %q", m.Format)
+	}
+	if m.Count != len(bundle.Entries) {
+		report.ManifestValid = false
+		report.issue(0, 0, "manifest lists %d entries, bundle has %d", m.Count, len(bundle.Entries))
+	}
+	if m.FinalHash != prev {
+		report.ManifestValid = false
+		report.issue(0, 0, "final hash does not match the chain: entries were added, removed or reordered")
+	}
+	key, ok := trustedKey(m.PublicKey, trusted)
+	if !ok {
+		report.ManifestValid = false
+		report.issue(0, 0, "manifest signed by untrusted key %s", m.PublicKey)
+	} else if !verifyManifest(key, m) {
+		report.ManifestValid = false
+		report.issue(0, 0, "invalid manifest signature")
+	}
+
+	return report
+}
+
+func trustedKey(encoded string, trusted []ed25519.PublicKey) (ed25519.PublicKey, bool) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	for _, key := range trusted {
+		if key.Equal(ed25519.PublicKey(raw)) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func verifySigned(key ed25519.PublicKey, log *SignableAuditLog, signatureB64 string) bool {
+	signature, err := base64.StdEncoding.DecodeString(signatureB64)
+	if err != nil {
+		return false
+	}
+	canonical, err := json.Marshal(log)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, canonical, signature)
+}
+
+func verifyManifest(key ed25519.PublicKey, m BundleManifest) bool {
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return false
+	}
+	canonical, err := m.canonical()
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, canonical, signature)
+}
+
+// maxFailingIDs bounds the entry IDs listed in a RangeVerification
+const maxFailingIDs = 1000
+
+// RangeVerification is the outcome of verifying the stored signatures of a
+// time range
+type RangeVerification struct {
+	Start    time.Time `json:"start_time"`
+	End      time.Time `json:"end_time"`
+	Entries  int       `json:"entries"`
+	Verified int       `json:"verified"`
+	Unsigned int       `json:"unsigned"`
+	Invalid  int       `json:"invalid"`
+	// InvalidIDs lists up to maxFailingIDs entries whose signature does not
+	// match, in ID order; Truncated is set when there were more
+	InvalidIDs []int64 `json:"invalid_ids"`
+	Truncated  bool    `json:"truncated"`
+	Workers    int     `json:"workers"`
+	DurationMS int64   `json:"duration_ms"`
+	// AnchorsChecked counts the external anchors covering the range whose
+	// receipts and chain heads were checked, when anchoring is enabled
+	AnchorsChecked int           `json:"anchors_checked"`
+	AnchorIssues   []AnchorIssue `json:"anchor_issues"`
+}
+
+// AnchorIssue is an external anchor that failed verification: its receipt
+// does not hold, or the stored entries it covers no longer hash to the
+// anchored chain head
+type AnchorIssue struct {
+	AnchorID int64  `json:"anchor_id"`
+	Problem  string `json:"problem"`
+}
+
+// OK reports whether every signed entry and every anchor verified
+func (v *RangeVerification) OK() bool {
+	return v.Invalid == 0 && len(v.AnchorIssues) == 0
+}
+
+// PageFunc returns up to a page of stored entries with IDs above afterID, in
+// ID order; an empty page ends the range
+type PageFunc func(ctx context.Context, afterID int64) ([]AuditLog, error)
+
+// VerifyRange checks every stored signature returned by next against the key
+// stored with it, spreading the checks over workers goroutines while the
+// next page loads
+func VerifyRange(ctx context.Context, start, end time.Time, next PageFunc, workers int) (*RangeVerification, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	began := time.Now()
+	result := &RangeVerification{Start: start, End: end, InvalidIDs: []int64{}, Workers: workers, AnchorIssues: []AnchorIssue{}}
+
+	type outcome struct {
+		id       int64
+		signed   bool
+		verified bool
+	}
+	logs := make(chan AuditLog, workers*4)
+	outcomes := make(chan outcome, workers*4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for log := range logs {
+				o := outcome{id: log.ID}
+				if log.Signature != nil && log.SignerPublicKey != nil {
+					o.signed = true
+					o.verified = verifyStored(&log)
+				}
+				outcomes <- o
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	// Load pages and feed the workers
+	loadErr := make(chan error, 1)
+	go func() {
+		defer close(logs)
+		var afterID int64
+		for {
+			page, err := next(ctx, afterID)
+			if err != nil {
+				loadErr <- err
+				return
+			}
+			if len(page) == 0 {
+				loadErr <- nil
+				return
+			}
+			for _, log := range page {
+				select {
+				case logs <- log:
+				case <-ctx.Done():
+					loadErr <- ctx.Err()
+					return
+				}
+			}
+			afterID = page[len(page)-1].ID
+		}
+	}()
+
+	var invalid []int64
+	for o := range outcomes {
+		result.Entries++
+		switch {
+		case !o.signed:
+			result.Unsigned++
+		case o.verified:
+			result.Verified++
+		default:
+			result.Invalid++
+			invalid = append(invalid, o.id)
+		}
+	}
+	if err := <-loadErr; err != nil {
+		return nil, err
+	}
+
+	// Workers finish out of order
+	sort.Slice(invalid, func(i, j int) bool { return invalid[i] < invalid[j] })
+	if len(invalid) > maxFailingIDs {
+		invalid = invalid[:maxFailingIDs]
+		result.Truncated = true
+	}
+	result.InvalidIDs = append(result.InvalidIDs, invalid...)
+	result.DurationMS = time.Since(began).Milliseconds()
+	return result, nil
+}
+
+// verifyStored checks a stored entry's signature against the key stored with it
+func verifyStored(log *AuditLog) bool {
+	raw, err := base64.StdEncoding.DecodeString(*log.SignerPublicKey)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return false
+	}
+	return verifySigned(ed25519.PublicKey(raw), signableFromLog(log), *log.Signature)
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Security activity event types
+const (
+	ActivityLogin             = "login"
+	ActivityLoginFailed       = "login_failed"
+	ActivityLogout            = "logout"
+	ActivitySessionRevoked    = "session_revoked"
+	ActivityPasswordChanged   = "password_changed"
+	ActivityMFAChanged        = "mfa_changed"
+	ActivityAPIKeyUsed        = "api_key_used"
+	ActivityAPIKeyChanged     = "api_key_changed"
+	ActivitySupportAccess     = "support_access"
+	ActivityImpersonatedLogin = "impersonated_login"
+)
+
+const defaultSecurityActivityAge = 90 * 24 * time.Hour
+
+// securityAuditActions maps audit log actions to activity event types
+var securityAuditActions = map[string]string{
+	"logout":                ActivityLogout,
+	"session_revoked":       ActivitySessionRevoked,
+	"password_changed":      ActivityPasswordChanged,
+	"password_reset":        ActivityPasswordChanged,
+	"mfa_enabled":           ActivityMFAChanged,
+	"mfa_disabled":          ActivityMFAChanged,
+	"api_key_created":       ActivityAPIKeyChanged,
+	"api_key_revoked":       ActivityAPIKeyChanged,
+	"api_key_used":          ActivityAPIKeyUsed,
+	"impersonation_started": ActivitySupportAccess,
+}
+
+// SecurityEvent is one entry in a user's security activity feed
+type SecurityEvent struct {
+	Type      string                 `json:"type"`
+	Action    string                 `json:"action"`
+	Success   bool                   `json:"success"`
+	Timestamp time.Time              `json:"timestamp"`
+	IPAddress string                 `json:"ip_address,omitempty"`
+	Device    *DeviceInfo            `json:"device,omitempty"`
+	Geo       *GeoInfo               `json:"geo,omitempty"`
+	SessionID string                 `json:"session_id,omitempty"`
+	Active    bool                   `json:"active,omitempty"` // session still valid
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// SecurityActivity returns the user's recent logins, failed login attempts,
+// credential changes and API key usage, newest first. Successful logins come
+// from sessions (which carry the device); everything else from audit logs.
+func (s *AuthService) SecurityActivity(ctx context.Context, userID, email string, since time.Time, limit int) ([]SecurityEvent, error) {
+	if since.IsZero() {
+		since = time.Now().Add(-defaultSecurityActivityAge)
+	}
+
+	var sessions []struct {
+		ID              string         `db:"id"`
+		IPAddress       string         `db:"ip_address"`
+		UserAgent       string         `db:"user_agent"`
+		CreatedAt       time.Time      `db:"created_at"`
+		Active          bool           `db:"active"`
+		ImpersonationID sql.NullString `db:"impersonation_id"`
+	}
+	err := s.db.SelectContext(ctx, &sessions, `
+		SELECT s.id, host(s.ip_address) AS ip_address, COALESCE(s.user_agent, '') AS user_agent, s.created_at,
+		       (s.revoked_at IS NULL AND s.expires_at > NOW()) AS active,
+		       imp.id::text AS impersonation_id
+		FROM sessions s
+		LEFT JOIN impersonation_sessions imp ON imp.session_id = s.id
+		WHERE s.user_id = $1 AND s.created_at >= $2
+		ORDER BY s.created_at DESC
+		LIMIT $3
+	`, userID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+
+	var logs []struct {
+		Action    string          `db:"action"`
+		Status    string          `db:"status"`
+		IPAddress sql.NullString  `db:"ip_address"`
+		UserAgent sql.NullString  `db:"user_agent"`
+		Details   json.RawMessage `db:"details"`
+		Timestamp time.Time       `db:"timestamp"`
+	}
+	actions := make([]string, 0, len(securityAuditActions))
+	for action := range securityAuditActions {
+		actions = append(actions, action)
+	}
+	err = s.db.SelectContext(ctx, &logs, `
+		SELECT action, status, host(ip_address) AS ip_address, user_agent, details, timestamp
+		FROM audit_logs
+		WHERE timestamp >= $2
+		AND (
+			(user_id = $1 AND action = ANY($3))
+			OR (action = 'login_attempt' AND status = 'failure' AND details->>'email' = $4)
+			OR (action = 'impersonation_started' AND resource_type = 'user' AND resource_id = $1::uuid)
+		)
+		ORDER BY timestamp DESC
+		LIMIT $5
+	`, userID, since, pq.Array(actions), email, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load audit logs: %w", err)
+	}
+
+	events := make([]SecurityEvent, 0, len(sessions)+len(logs))
+	for _, session := range sessions {
+		event := SecurityEvent{
+			Type:      ActivityLogin,
+			Action:    "login_success",
+			Success:   true,
+			Timestamp: session.CreatedAt,
+			IPAddress: session.IPAddress,
+			SessionID: session.ID,
+			Active:    session.Active,
+		}
+		if session.ImpersonationID.Valid {
+			event.Type = ActivityImpersonatedLogin
+			event.Details = map[string]interface{}{"impersonation_id": session.ImpersonationID.String}
+		}
+		s.describeClient(&event, session.IPAddress, session.UserAgent)
+		events = append(events, event)
+	}
+
+	for _, log := range logs {
+		var details map[string]interface{}
+		if len(log.Details) > 0 {
+			_ = json.Unmarshal(log.Details, &details)
+		}
+
+		event := SecurityEvent{
+			Type:      securityAuditActions[log.Action],
+			Action:    log.Action,
+			Success:   log.Status == "success",
+			Timestamp: log.Timestamp,
+		}
+		if log.Action == "login_attempt" {
+			event.Type = ActivityLoginFailed
+		}
+
+		// Older entries keep the client in details rather than columns
+		ip, ua := log.IPAddress.String, log.UserAgent.String
+		if ip == "" {
+			ip, _ = details["ip_address"].(string)
+		}
+		if ua == "" {
+			ua, _ = details["user_agent"].(string)
+		}
+		event.IPAddress = ip
+		s.describeClient(&event, ip, ua)
+
+		// Surface only non-sensitive context
+		for _, key := range []string{"session_id", "impersonation_id", "reason", "ticket_reference", "key_id", "key_name", "method"} {
+			if v, ok := details[key]; ok {
+				if event.Details == nil {
+					event.Details = make(map[string]interface{})
+				}
+				event.Details[key] = v
+			}
+		}
+		events = append(events, event)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// describeClient fills in device and location from the user agent and IP
+func (s *AuthService) describeClient(event *SecurityEvent, ipAddress, userAgent string) {
+	if userAgent != "" {
+		device := ParseUserAgent(userAgent)
+		event.Device = &device
+	}
+	if s.geo != nil && ipAddress != "" {
+		if geo, err := s.geo.Locate(ipAddress); err == nil {
+			event.Geo = geo
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AdminUser is a user as platform admins see it
+type AdminUser struct {
+	ID             string     `json:"id"`
+	Email          string     `json:"email"`
+	Username       string     `json:"username"`
+	FullName       string     `json:"full_name,omitempty"`
+	OrganizationID string     `json:"organization_id,omitempty"`
+	Role           string     `json:"role"`
+	IsActive       bool       `json:"is_active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastLoginAt    *time.Time `json:"last_login_at,omitempty"`
+}
+
+func newAdminUser(u repository.User) AdminUser {
+	user := AdminUser{
+		ID:             u.ID,
+		Email:          u.Email,
+		Username:       u.Username,
+		FullName:       u.FullName.String,
+		OrganizationID: u.OrganizationID.String,
+		Role:           u.Role,
+		IsActive:       u.IsActive,
+		CreatedAt:      u.CreatedAt,
+	}
+	if u.LastLoginAt.Valid {
+		user.LastLoginAt = &u.LastLoginAt.Time
+	}
+	return user
+}
+
+// SearchUsers lists users whose email, username or name contains query
+func (s *AuthService) SearchUsers(ctx context.Context, query string, limit int) ([]AdminUser, error) {
+	users, err := s.repos.Users.Search(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	result := make([]AdminUser, 0, len(users))
+	for _, u := range users {
+		result = append(result, newAdminUser(u))
+	}
+	return result, nil
+}
+
+// SetUserActive enables or disables a user's account. Disabling it also
+// revokes every session of the user, in every region. It returns the user
+// and the number of sessions revoked.
+func (s *AuthService) SetUserActive(ctx context.Context, userID string, active bool) (*AdminUser, int, error) {
+	var tokenHashes []string
+	err := s.uow.Do(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Users.SetActive(ctx, userID, active); err != nil {
+			return err
+		}
+		if active {
+			return nil
+		}
+		var err error
+		tokenHashes, err = repos.Sessions.RevokeAll(ctx, userID)
+		return err
+	})
+	if err == repository.ErrNotFound {
+		return nil, 0, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to update user: %w", err)
+	}
+	s.invalidateSessions(ctx, tokenHashes...)
+
+	user, err := s.repos.Users.Get(ctx, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	logging.FromContext(ctx, s.logger).Info("User account status changed",
+		zap.String("user_id", userID),
+		zap.Bool("active", active),
+		zap.Int("sessions_revoked", len(tokenHashes)),
+	)
+	adminUser := newAdminUser(*user)
+	return &adminUser, len(tokenHashes), nil
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const sessionCachePrefix = "auth:session:"
+
+// SessionCache caches token-hash -> session ID lookups made by AuthMiddleware.
+// Implementations must tolerate being bypassed: errors fall back to the database.
+type SessionCache interface {
+	Get(ctx context.Context, tokenHash string) (sessionID string, found bool, err error)
+	Set(ctx context.Context, tokenHash, sessionID string, ttl time.Duration) error
+	Delete(ctx context.Context, tokenHashes ...string) error
+}
+
+// RedisSessionCache stores validated sessions in Redis
+type RedisSessionCache struct {
+	client *redis.Client
+}
+
+func NewRedisSessionCache(client *redis.Client) *RedisSessionCache {
+	return &RedisSessionCache{client: client}
+}
+
+func (c *RedisSessionCache) Get(ctx context.Context, tokenHash string) (string, bool, error) {
+	sessionID, err := c.client.Get(ctx, sessionCachePrefix+tokenHash).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return sessionID, true, nil
+}
+
+func (c *RedisSessionCache) Set(ctx context.Context, tokenHash, sessionID string, ttl time.Duration) error {
+	return c.client.Set(ctx, sessionCachePrefix+tokenHash, sessionID, ttl).Err()
+}
+
+func (c *RedisSessionCache) Delete(ctx context.Context, tokenHashes ...string) error {
+	if len(tokenHashes) == 0 {
+		return nil
+	}
+	keys := make([]string, len(tokenHashes))
+	for i, hash := range tokenHashes {
+		keys[i] = sessionCachePrefix + hash
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+// NoopSessionCache disables caching; every lookup hits the database
+type NoopSessionCache struct{}
+
+func (NoopSessionCache) Get(context.Context, string) (string, bool, error) { return "", false, nil }
+
+func (NoopSessionCache) Set(context.Context, string, string, time.Duration) error { return nil }
+
+func (NoopSessionCache) Delete(context.Context, ...string) error { return nil }
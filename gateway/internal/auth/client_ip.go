@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetTrustedProxies sets the reverse proxies whose X-Forwarded-For the
+// network policy believes. Without any, the connection's peer is the client.
+func (s *AuthService) SetTrustedProxies(networks []*net.IPNet) {
+	s.trustedProxies = networks
+}
+
+// clientIP is the address the network policy checks: the connection's peer,
+// or when that is a trusted proxy, the nearest X-Forwarded-For hop that is
+// not. It does not depend on the gin engine's proxy settings, whose default
+// believes the header from anyone.
+func (s *AuthService) clientIP(c *gin.Context) string {
+	return forwardedClientIP(c.Request, s.trustedProxies)
+}
+
+func forwardedClientIP(r *http.Request, trusted []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+
+	// Each proxy appends the address it received the request from, so the
+	// chain is walked back from the peer while the hops are trusted
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && inNetworks(ip, trusted); i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+	}
+	return ip.String()
+}
+
+func inNetworks(ip net.IP, networks []*net.IPNet) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
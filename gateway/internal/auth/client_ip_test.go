@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func mustNetworks(t *testing.T, cidrs ...string) []*net.IPNet {
+	t.Helper()
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func TestForwardedClientIP(t *testing.T) {
+	proxies := mustNetworks(t, "10.0.0.0/8", "fd00::/8")
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string // X-Forwarded-For headers
+		trusted   []*net.IPNet
+		want      string
+	}{
+		{name: "direct", remote: "198.51.100.7:4711", want: "198.51.100.7"},
+		{name: "spoofed header, no trusted proxies", remote: "198.51.100.7:4711", forwarded: []string{"203.0.113.5"}, want: "198.51.100.7"},
+		{name: "spoofed header from an untrusted peer", remote: "198.51.100.7:4711", forwarded: []string{"203.0.113.5"}, trusted: proxies, want: "198.51.100.7"},
+		{name: "through a trusted proxy", remote: "10.1.2.3:4711", forwarded: []string{"203.0.113.5"}, trusted: proxies, want: "203.0.113.5"},
+		{
+			// The client prepended its own entry; the proxy appended the real peer
+			name:      "spoofed entry ahead of a trusted proxy",
+			remote:    "10.1.2.3:4711",
+			forwarded: []string{"203.0.113.5, 198.51.100.7"},
+			trusted:   proxies,
+			want:      "198.51.100.7",
+		},
+		{name: "chain of trusted proxies", remote: "10.1.2.3:4711", forwarded: []string{"198.51.100.7, 10.9.9.9"}, trusted: proxies, want: "198.51.100.7"},
+		{name: "several headers", remote: "10.1.2.3:4711", forwarded: []string{"203.0.113.5", "198.51.100.7"}, trusted: proxies, want: "198.51.100.7"},
+		{name: "only trusted hops", remote: "10.1.2.3:4711", forwarded: []string{"10.4.4.4"}, trusted: proxies, want: "10.4.4.4"},
+		{name: "malformed hop stops the walk", remote: "10.1.2.3:4711", forwarded: []string{"203.0.113.5, not-an-ip"}, trusted: proxies, want: "10.1.2.3"},
+		{name: "trusted proxy without a header", remote: "10.1.2.3:4711", trusted: proxies, want: "10.1.2.3"},
+		{name: "IPv6", remote: "[fd00::1]:4711", forwarded: []string{"2001:db8::5"}, trusted: proxies, want: "2001:db8::5"},
+		{name: "unparseable peer", remote: "pipe", forwarded: []string{"203.0.113.5"}, trusted: proxies, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, header := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", header)
+			}
+			if got := forwardedClientIP(req, tt.trusted); got != tt.want {
+				t.Errorf("forwardedClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
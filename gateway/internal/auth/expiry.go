@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// activityKey is the Redis hash of session ID -> Unix time of last use,
+// written on every authenticated request and flushed to the database in bulk
+const activityKey = "auth:activity"
+
+// SessionConfig controls sliding session expiry
+type SessionConfig struct {
+	// IdleTimeout is how long a session lasts without use; each extension
+	// moves its expiry this far past now
+	IdleTimeout time.Duration
+	// MaxLifetime caps a session's expiry, counted from login
+	MaxLifetime time.Duration
+	// ExtendWithin extends sessions used with less than this left. It must
+	// exceed the session cache TTL, since only cache misses are extended.
+	ExtendWithin time.Duration
+	// FlushInterval is how often recorded activity is written to the database
+	FlushInterval time.Duration
+	// SweepInterval is how often idle sessions are revoked
+	SweepInterval time.Duration
+	// MaxSessions bounds a user's concurrent sessions unless their
+	// organization sets a policy; zero leaves them unbounded
+	MaxSessions int
+	// MaxSessionsByRole overrides MaxSessions for particular roles
+	MaxSessionsByRole map[string]int
+	// OnSessionLimit is what a login over the limit does: SessionLimitReject
+	// or SessionLimitRevokeOldest
+	OnSessionLimit string
+	// BreakGlassTTL is how long an owner's break-glass login may bypass
+	// their organization's network policy
+	BreakGlassTTL time.Duration
+}
+
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		IdleTimeout:    time.Hour,
+		MaxLifetime:    12 * time.Hour,
+		ExtendWithin:   15 * time.Minute,
+		FlushInterval:  30 * time.Second,
+		SweepInterval:  5 * time.Minute,
+		MaxSessions:    5,
+		OnSessionLimit: SessionLimitRevokeOldest,
+		BreakGlassTTL:  time.Hour,
+	}
+}
+
+// SetSessionConfig configures sliding session expiry
+func (s *AuthService) SetSessionConfig(config SessionConfig) {
+	s.sessionConfig = config
+}
+
+// recordActivity notes that a session was used. Failures only delay the
+// last-activity time, so they are logged and ignored.
+func (s *AuthService) recordActivity(ctx context.Context, sessionID string) {
+	if err := s.redis.HSet(ctx, activityKey, sessionID, time.Now().Unix()).Err(); err != nil {
+		logging.FromContext(ctx, s.logger).Debug("Failed to record session activity", zap.Error(err))
+	}
+}
+
+// extendSession slides a session used close to its expiry, up to its absolute
+// expiry, and returns the expiry to honour
+func (s *AuthService) extendSession(ctx context.Context, session *Session) time.Time {
+	if time.Until(session.ExpiresAt) >= s.sessionConfig.ExtendWithin || !session.ExpiresAt.Before(session.AbsoluteExpiresAt) {
+		return session.ExpiresAt
+	}
+
+	expiresAt, err := s.repos.Sessions.Extend(ctx, session.ID, time.Now().Add(s.sessionConfig.IdleTimeout))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to extend session", zap.String("session_id", session.ID), zap.Error(err))
+		return session.ExpiresAt
+	}
+	metrics.SessionsExtended.Inc()
+	return expiresAt
+}
+
+// StartSessionMaintenance flushes recorded activity to the database,
+// revokes idle sessions and purges expired magic links until ctx is cancelled
+func (s *AuthService) StartSessionMaintenance(ctx context.Context) {
+	flush := time.NewTicker(s.sessionConfig.FlushInterval)
+	defer flush.Stop()
+	sweep := time.NewTicker(s.sessionConfig.SweepInterval)
+	defer sweep.Stop()
+
+	s.logger.Info("Session maintenance started",
+		zap.Duration("idle_timeout", s.sessionConfig.IdleTimeout),
+		zap.Duration("max_lifetime", s.sessionConfig.MaxLifetime),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Keep the activity recorded since the last flush
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.flushActivity(flushCtx)
+			cancel()
+			return
+		case <-flush.C:
+			s.flushActivity(ctx)
+		case <-sweep.C:
+			// Activity still in Redis must not count as idle
+			s.flushActivity(ctx)
+			s.revokeIdleSessions(ctx)
+			s.purgeMagicLinks(ctx)
+		}
+	}
+}
+
+// flushActivity moves the recorded activity out of Redis and into sessions.
+// Renaming the hash first claims it, so instances never flush the same
+// entries twice and activity recorded meanwhile goes to a fresh hash.
+func (s *AuthService) flushActivity(ctx context.Context) {
+	if n, err := s.redis.Exists(ctx, activityKey).Result(); err != nil || n == 0 {
+		return
+	}
+	claimed := activityKey + ":flush:" + uuid.New().String()
+	if err := s.redis.Rename(ctx, activityKey, claimed).Err(); err != nil {
+		// Also when another instance claimed it first
+		s.logger.Debug("Failed to claim session activity", zap.Error(err))
+		return
+	}
+
+	entries, err := s.redis.HGetAll(ctx, claimed).Result()
+	if err != nil {
+		s.logger.Error("Failed to read session activity", zap.Error(err))
+		return
+	}
+	defer s.redis.Del(ctx, claimed)
+
+	sessionIDs := make([]string, 0, len(entries))
+	times := make([]int64, 0, len(entries))
+	for sessionID, at := range entries {
+		unix, err := strconv.ParseInt(at, 10, 64)
+		if err != nil {
+			continue
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+		times = append(times, unix)
+	}
+	if len(sessionIDs) == 0 {
+		return
+	}
+
+	if err := s.repos.Sessions.TouchActivity(ctx, sessionIDs, times); err != nil {
+		s.logger.Error("Failed to flush session activity", zap.Int("sessions", len(sessionIDs)), zap.Error(err))
+		return
+	}
+	metrics.SessionActivityFlushed.Add(float64(len(sessionIDs)))
+}
+
+// revokeIdleSessions revokes sessions unused for the idle timeout
+func (s *AuthService) revokeIdleSessions(ctx context.Context) {
+	tokenHashes, err := s.repos.Sessions.RevokeIdle(ctx, s.sessionConfig.IdleTimeout)
+	if err != nil {
+		s.logger.Error("Failed to revoke idle sessions", zap.Error(err))
+		return
+	}
+	if len(tokenHashes) == 0 {
+		return
+	}
+
+	s.invalidateSessions(ctx, tokenHashes...)
+	metrics.SessionsRevokedIdle.Add(float64(len(tokenHashes)))
+	s.logger.Info("Revoked idle sessions", zap.Int("count", len(tokenHashes)))
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"go.uber.org/zap"
+)
+
+// FeatureSource supplies the runtime feature flags that are on for a user,
+// added to the JWT features claim for frontend gating
+type FeatureSource interface {
+	EnabledFlags(ctx context.Context, userID, orgID string) ([]string, error)
+}
+
+// SetFeatureSource adds evaluated feature flags to issued tokens
+func (s *AuthService) SetFeatureSource(source FeatureSource) {
+	s.features = source
+}
+
+// tierFeatures are the features every member of an organization gets from
+// its subscription tier, on top of features granted to the organization or
+// user directly
+var tierFeatures = map[string][]string{
+	"free":       {"port_scan", "web_scan"},
+	"basic":      {"port_scan", "web_scan", "wifi_scan"},
+	"pro":        {"port_scan", "web_scan", "wifi_scan", "cloud_audit"},
+	"enterprise": {"port_scan", "web_scan", "wifi_scan", "cloud_audit", "exploitation"},
+}
+
+// TierFeatures returns the features included in a subscription tier;
+// unknown tiers get the free tier's
+func TierFeatures(tier string) []string {
+	if features, ok := tierFeatures[tier]; ok {
+		return features
+	}
+	return tierFeatures["free"]
+}
+
+// orgEntitlement is what an organization contributes to its members' features
+type orgEntitlement struct {
+	Tier     string `db:"subscription_tier"`
+	Features []byte `db:"features"`
+	IsActive bool   `db:"is_active"`
+}
+
+// userFeatures merges the features stored on the user, those of orgID's
+// subscription tier and organization, and the flags that are on for them.
+// Lookup failures are logged and leave the stored features intact, so login
+// does not depend on the flag service.
+func (s *AuthService) userFeatures(ctx context.Context, user *User, orgID string) []string {
+	features := []string{}
+	seen := map[string]bool{}
+	add := func(list []string) {
+		for _, f := range list {
+			if !seen[f] {
+				seen[f] = true
+				features = append(features, f)
+			}
+		}
+	}
+
+	add(decodeFeatures(ctx, s.logger, user.Features, "user", user.ID))
+
+	if orgID != "" {
+		var org orgEntitlement
+		err := s.db.GetContext(ctx, &org, `
+			SELECT COALESCE(subscription_tier, 'free') AS subscription_tier, features, COALESCE(is_active, true) AS is_active
+			FROM organizations WHERE id = $1
+		`, orgID)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			logging.FromContext(ctx, s.logger).Error("Failed to load organization entitlements", zap.String("organization_id", orgID), zap.Error(err))
+		case org.IsActive:
+			add(TierFeatures(org.Tier))
+			add(decodeFeatures(ctx, s.logger, org.Features, "organization", orgID))
+		}
+	}
+
+	if s.features == nil {
+		return features
+	}
+	flags, err := s.features.EnabledFlags(ctx, user.ID, orgID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to evaluate feature flags", zap.String("user_id", user.ID), zap.Error(err))
+		return features
+	}
+	add(flags)
+	return features
+}
+
+// decodeFeatures parses a JSONB feature list, treating bad data as empty
+func decodeFeatures(ctx context.Context, logger *zap.Logger, raw []byte, owner, id string) []string {
+	var features []string
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &features); err != nil {
+		logging.FromContext(ctx, logger).Warn("Invalid features on "+owner, zap.String("id", id), zap.Error(err))
+		return nil
+	}
+	return features
+}
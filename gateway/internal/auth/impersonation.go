@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RolePlatformAdmin is the users.role of staff allowed to impersonate users
+const RolePlatformAdmin = "platform_admin"
+
+// Impersonation time box
+const (
+	DefaultImpersonationDuration = 30 * time.Minute
+	MaxImpersonationDuration     = 2 * time.Hour
+)
+
+var (
+	ErrNotPlatformAdmin      = errors.New("platform admin required")
+	ErrImpersonationTarget   = errors.New("target user cannot be impersonated")
+	ErrImpersonationNotFound = errors.New("impersonation not found")
+	ErrNestedImpersonation   = errors.New("cannot impersonate while impersonating")
+	ErrImpersonationTooLong  = errors.New("impersonation duration exceeds the maximum")
+	ErrUserNotFound          = errors.New("user not found")
+)
+
+// Impersonation is an admin's time-boxed session acting as another user
+type Impersonation struct {
+	ID              string     `json:"id" db:"id"`
+	AdminUserID     string     `json:"admin_user_id" db:"admin_user_id"`
+	TargetUserID    string     `json:"target_user_id" db:"target_user_id"`
+	SessionID       string     `json:"session_id" db:"session_id"`
+	Reason          string     `json:"reason" db:"reason"`
+	TicketReference *string    `json:"ticket_reference,omitempty" db:"ticket_reference"`
+	StartedAt       time.Time  `json:"started_at" db:"started_at"`
+	ExpiresAt       time.Time  `json:"expires_at" db:"expires_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	EndedBy         *string    `json:"ended_by,omitempty" db:"ended_by"`
+}
+
+// StartImpersonationParams describes who impersonates whom, and why
+type StartImpersonationParams struct {
+	AdminUserID     string
+	TargetUserID    string
+	Reason          string
+	TicketReference string
+	Duration        time.Duration
+	IPAddress       string
+	UserAgent       string
+}
+
+// ImpersonationToken is returned to the admin who started an impersonation
+type ImpersonationToken struct {
+	AccessToken   string         `json:"access_token"`
+	ExpiresIn     int            `json:"expires_in"`
+	Impersonation *Impersonation `json:"impersonation"`
+	User          UserInfo       `json:"user"`
+}
+
+// IsPlatformAdmin checks the user's platform role in the database, so a
+// demotion takes effect without waiting for tokens to expire
+func (s *AuthService) IsPlatformAdmin(ctx context.Context, userID string) (bool, error) {
+	var role string
+	err := s.db.GetContext(ctx, &role, "SELECT role FROM users WHERE id = $1 AND is_active = true", userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return role == RolePlatformAdmin, nil
+}
+
+// StartImpersonation issues a token for the target user marked with the
+// admin's identity. The token is backed by a session that StopImpersonation
+// revokes, and expires with the time box.
+func (s *AuthService) StartImpersonation(ctx context.Context, p StartImpersonationParams) (*ImpersonationToken, error) {
+	if p.Duration <= 0 {
+		p.Duration = DefaultImpersonationDuration
+	}
+	if p.Duration > MaxImpersonationDuration {
+		return nil, ErrImpersonationTooLong
+	}
+
+	isAdmin, err := s.IsPlatformAdmin(ctx, p.AdminUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check admin role: %w", err)
+	}
+	if !isAdmin {
+		return nil, ErrNotPlatformAdmin
+	}
+
+	target, err := s.repos.Users.GetActive(ctx, p.TargetUserID)
+	if err == repository.ErrNotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target user: %w", err)
+	}
+	// Admins cannot borrow each other's (or their own) privileges
+	if target.ID == p.AdminUserID || target.Role == RolePlatformAdmin {
+		return nil, ErrImpersonationTarget
+	}
+
+	impersonationID := uuid.New().String()
+	sessionID := uuid.New().String()
+	now := time.Now()
+	expiresAt := now.Add(p.Duration)
+
+	orgID := s.homeOrganization(ctx, target)
+
+	claims := &Claims{
+		UserID:          target.ID,
+		Email:           target.Email,
+		Role:            target.Role,
+		Features:        s.userFeatures(ctx, target, orgID),
+		OrgID:           orgID,
+		ImpersonatorID:  p.AdminUserID,
+		ImpersonationID: impersonationID,
+		Region:          s.region.Region,
+		RegisteredClaims: s.registeredClaims(jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.New().String(),
+		}),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.keys.signing())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	var imp Impersonation
+	err = s.uow.Do(ctx, func(repos *repository.Repositories) error {
+		err := repos.Sessions.Create(ctx, &Session{
+			ID:                sessionID,
+			UserID:            target.ID,
+			TokenHash:         hashToken(token),
+			IPAddress:         p.IPAddress,
+			UserAgent:         p.UserAgent,
+			ExpiresAt:         expiresAt,
+			AbsoluteExpiresAt: expiresAt, // Time-boxed: impersonations never slide
+			Region:            s.region.Region,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+
+		err = repos.Q.GetContext(ctx, &imp, `
+			INSERT INTO impersonation_sessions (
+				id, admin_user_id, target_user_id, session_id, reason, ticket_reference, ip_address, expires_at
+			) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+			RETURNING id, admin_user_id, target_user_id, session_id, reason, ticket_reference,
+			          started_at, expires_at, ended_at, ended_by
+		`, impersonationID, p.AdminUserID, target.ID, sessionID, p.Reason, p.TicketReference, p.IPAddress, expiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to record impersonation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx, s.logger).Warn("Impersonation started",
+		zap.String("impersonation_id", impersonationID),
+		zap.String("admin_user_id", p.AdminUserID),
+		zap.String("target_user_id", target.ID),
+		zap.Duration("duration", p.Duration),
+	)
+
+	return &ImpersonationToken{
+		AccessToken:   token,
+		ExpiresIn:     int(p.Duration.Seconds()),
+		Impersonation: &imp,
+		User: UserInfo{
+			ID:             target.ID,
+			Email:          target.Email,
+			Username:       target.Username,
+			Role:           target.Role,
+			OrganizationID: orgID,
+		},
+	}, nil
+}
+
+// StopImpersonation ends an impersonation and revokes its session. The admin
+// who started it may stop it (including from the impersonated session);
+// other platform admins may revoke it.
+func (s *AuthService) StopImpersonation(ctx context.Context, impersonationID, actorID string) (*Impersonation, error) {
+	var imp Impersonation
+	err := s.db.GetContext(ctx, &imp, `
+		SELECT id, admin_user_id, target_user_id, session_id, reason, ticket_reference,
+		       started_at, expires_at, ended_at, ended_by
+		FROM impersonation_sessions WHERE id = $1
+	`, impersonationID)
+	if err == sql.ErrNoRows {
+		return nil, ErrImpersonationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load impersonation: %w", err)
+	}
+
+	if imp.AdminUserID != actorID {
+		isAdmin, err := s.IsPlatformAdmin(ctx, actorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check admin role: %w", err)
+		}
+		if !isAdmin {
+			return nil, ErrImpersonationNotFound
+		}
+	}
+	if imp.EndedAt != nil {
+		return &imp, nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &imp, `
+		UPDATE impersonation_sessions SET ended_at = NOW(), ended_by = $2
+		WHERE id = $1 AND ended_at IS NULL
+		RETURNING id, admin_user_id, target_user_id, session_id, reason, ticket_reference,
+		          started_at, expires_at, ended_at, ended_by
+	`, impersonationID, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to end impersonation: %w", err)
+	}
+
+	var tokenHash string
+	err = tx.GetContext(ctx, &tokenHash, `
+		UPDATE sessions SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING token_hash
+	`, imp.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke impersonation session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	s.invalidateSessions(ctx, tokenHash)
+
+	logging.FromContext(ctx, s.logger).Warn("Impersonation ended",
+		zap.String("impersonation_id", impersonationID),
+		zap.String("ended_by", actorID),
+	)
+	return &imp, nil
+}
+
+// ListImpersonations returns active impersonations, or recent history when
+// includeEnded is set
+func (s *AuthService) ListImpersonations(ctx context.Context, includeEnded bool) ([]Impersonation, error) {
+	list := []Impersonation{}
+	err := s.db.SelectContext(ctx, &list, `
+		SELECT id, admin_user_id, target_user_id, session_id, reason, ticket_reference,
+		       started_at, expires_at, ended_at, ended_by
+		FROM impersonation_sessions
+		WHERE $1 OR (ended_at IS NULL AND expires_at > NOW())
+		ORDER BY started_at DESC
+		LIMIT 200
+	`, includeEnded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonations: %w", err)
+	}
+	return list, nil
+}
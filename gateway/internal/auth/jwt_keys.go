@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// jwtKeys holds the secret new tokens are signed with and, for a grace
+// window after a rotation, the secret it replaced so that tokens issued
+// before the rotation stay valid until they expire
+type jwtKeys struct {
+	mu            sync.RWMutex
+	current       []byte
+	previous      []byte
+	previousUntil time.Time
+}
+
+func newJWTKeys(secret string) *jwtKeys {
+	return &jwtKeys{current: []byte(secret)}
+}
+
+func (k *jwtKeys) signing() []byte {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// verification returns the current secret, then the previous one while its
+// grace window lasts
+func (k *jwtKeys) verification() [][]byte {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := [][]byte{k.current}
+	if k.previous != nil && time.Now().Before(k.previousUntil) {
+		keys = append(keys, k.previous)
+	}
+	return keys
+}
+
+// RotateJWTSecret signs new tokens with secret. Tokens signed with the old
+// secret are accepted for grace more, which should cover the longest token
+// lifetime. Rotating to the current secret is a no-op.
+func (s *AuthService) RotateJWTSecret(secret string, grace time.Duration) {
+	if secret == "" {
+		return
+	}
+
+	s.keys.mu.Lock()
+	if hmac.Equal(s.keys.current, []byte(secret)) {
+		s.keys.mu.Unlock()
+		return
+	}
+	s.keys.previous = s.keys.current
+	s.keys.previousUntil = time.Now().Add(grace)
+	s.keys.current = []byte(secret)
+	s.keys.mu.Unlock()
+
+	s.logger.Info("Rotated JWT secret", zap.Duration("grace", grace))
+}
+
+// verificationKeys is a jwt.Keyfunc accepting HMAC tokens signed with any
+// secret still in use, each passed through derive (nil for access tokens)
+func (s *AuthService) verificationKeys(derive func([]byte) []byte) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		set := jwt.VerificationKeySet{}
+		for _, key := range s.keys.verification() {
+			if derive != nil {
+				key = derive(key)
+			}
+			set.Keys = append(set.Keys, key)
+		}
+		return set, nil
+	}
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidActionToken is returned for malformed, expired or tampered session action links
+var ErrInvalidActionToken = errors.New("invalid or expired action token")
+
+const (
+	// sessionActionRevoke is the only action a session action token can carry
+	sessionActionRevoke = "revoke_session"
+	sessionActionTTL    = 7 * 24 * time.Hour
+)
+
+// LoginAlert describes a sign-in from a device or country the user has not used before
+type LoginAlert struct {
+	UserID      string     `json:"user_id"`
+	Email       string     `json:"email"`
+	SessionID   string     `json:"session_id"`
+	IPAddress   string     `json:"ip_address"`
+	Device      DeviceInfo `json:"device"`
+	Geo         *GeoInfo   `json:"geo,omitempty"`
+	NewDevice   bool       `json:"new_device"`
+	NewCountry  bool       `json:"new_country"`
+	RevokeToken string     `json:"-"` // signed one-click "revoke this session" token
+	OccurredAt  time.Time  `json:"occurred_at"`
+}
+
+// LoginAlertFunc delivers a login alert (e.g. by email or over the WebSocket hub)
+type LoginAlertFunc func(alert LoginAlert)
+
+// sessionActionClaims authorize a single action on a session without a login
+type sessionActionClaims struct {
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	Action    string `json:"action"`
+	jwt.RegisteredClaims
+}
+
+// AddLoginNotifier registers a delivery channel for suspicious login alerts
+func (s *AuthService) AddLoginNotifier(fn LoginAlertFunc) {
+	s.loginNotifiers = append(s.loginNotifiers, fn)
+}
+
+// checkLoginDevice records the device and country of a new session and
+// alerts the user when either has not been seen before. A user's very first
+// login only seeds the known devices.
+func (s *AuthService) checkLoginDevice(ctx context.Context, user *User, sessionID, ipAddress, userAgent string) {
+	device := ParseUserAgent(userAgent)
+	fingerprint := deviceFingerprint(device)
+
+	var geo *GeoInfo
+	if s.geo != nil {
+		located, err := s.geo.Locate(ipAddress)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Debug("Failed to geolocate login IP", zap.Error(err))
+		} else {
+			geo = located
+		}
+	}
+	country := ""
+	if geo != nil {
+		country = geo.Country
+	}
+
+	var known struct {
+		Any     bool `db:"any_device"`
+		Device  bool `db:"device"`
+		Country bool `db:"country"`
+	}
+	err := s.db.GetContext(ctx, &known, `
+		SELECT COUNT(*) > 0 AS any_device,
+		       COALESCE(bool_or(fingerprint = $2), false) AS device,
+		       COALESCE(bool_or(country = $3), false) AS country
+		FROM user_known_devices
+		WHERE user_id = $1
+	`, user.ID, fingerprint, country)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to load known devices", zap.Error(err))
+		return
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO user_known_devices (user_id, fingerprint, device, os, browser, country, last_ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, fingerprint, country)
+		DO UPDATE SET last_seen_at = NOW(), last_ip_address = EXCLUDED.last_ip_address
+	`, user.ID, fingerprint, device.Device, device.OS, device.Browser, country, ipAddress)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to record login device", zap.Error(err))
+	}
+
+	// Without a location a country can't be "new"
+	alert := LoginAlert{
+		UserID:     user.ID,
+		Email:      user.Email,
+		SessionID:  sessionID,
+		IPAddress:  ipAddress,
+		Device:     device,
+		Geo:        geo,
+		NewDevice:  !known.Device,
+		NewCountry: country != "" && !known.Country,
+		OccurredAt: time.Now().UTC(),
+	}
+	if !known.Any || (!alert.NewDevice && !alert.NewCountry) || len(s.loginNotifiers) == 0 {
+		return
+	}
+
+	alert.RevokeToken, err = s.sessionActionToken(user.ID, sessionID, sessionActionRevoke)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to sign session revoke token", zap.Error(err))
+		return
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Login from new device or country",
+		zap.String("user_id", user.ID),
+		zap.String("session_id", sessionID),
+		zap.Bool("new_device", alert.NewDevice),
+		zap.Bool("new_country", alert.NewCountry),
+	)
+
+	// Delivery (SMTP in particular) must not hold up the login response
+	go func() {
+		for _, notify := range s.loginNotifiers {
+			notify(alert)
+		}
+	}()
+}
+
+// RevokeSessionWithToken revokes the session named by a signed action token
+// from a login alert, returning the user and session it applied to
+func (s *AuthService) RevokeSessionWithToken(ctx context.Context, token string) (string, string, error) {
+	claims := &sessionActionClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, s.verificationKeys(sessionActionKey))
+	if err != nil || !parsed.Valid || claims.Action != sessionActionRevoke {
+		return "", "", ErrInvalidActionToken
+	}
+
+	if err := s.RevokeSession(ctx, claims.UserID, claims.SessionID); err != nil {
+		return claims.UserID, claims.SessionID, err
+	}
+	return claims.UserID, claims.SessionID, nil
+}
+
+func (s *AuthService) sessionActionToken(userID, sessionID, action string) (string, error) {
+	now := time.Now()
+	claims := sessionActionClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Action:    action,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(sessionActionTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(sessionActionKey(s.keys.signing()))
+}
+
+// sessionActionKey is derived from the JWT secret so action tokens can never
+// pass as access tokens (or the reverse)
+func sessionActionKey(jwtSecret []byte) []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("session-action"))
+	return mac.Sum(nil)
+}
+
+// deviceFingerprint identifies a device by its parsed attributes
+func deviceFingerprint(device DeviceInfo) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(device.Device + "|" + device.OS + "|" + device.Browser)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,313 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMagicLinkDisabled    = errors.New("magic link sign-in is disabled")
+	ErrMagicLinkNotAllowed  = errors.New("magic link sign-in is not allowed by the user's organization")
+	ErrMagicLinkRateLimited = errors.New("too many magic link requests")
+	// ErrInvalidMagicLink is returned for malformed, expired, tampered or
+	// already used links
+	ErrInvalidMagicLink = errors.New("invalid or expired magic link")
+)
+
+const magicLinkKeyPrefix = "auth:magic_link:requests:"
+
+// MagicLinkConfig configures password-less sign-in
+type MagicLinkConfig struct {
+	// Enabled turns magic links on for the deployment; organizations still
+	// have to allow them for their members
+	Enabled bool
+	// TTL is how long a link can be used
+	TTL time.Duration
+	// PerEmail and PerIP bound the links requested within Window for one
+	// address and from one client IP
+	Window   time.Duration
+	PerEmail int
+	PerIP    int
+}
+
+func DefaultMagicLinkConfig() MagicLinkConfig {
+	return MagicLinkConfig{
+		Enabled:  true,
+		TTL:      15 * time.Minute,
+		Window:   time.Hour,
+		PerEmail: 5,
+		PerIP:    20,
+	}
+}
+
+// MagicLink is a single-use sign-in link on its way to the user
+type MagicLink struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"-"` // signed single-use sign-in token
+	IPAddress string    `json:"ip_address"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MagicLinkFunc delivers a magic link (e.g. by email)
+type MagicLinkFunc func(link MagicLink)
+
+// LoginPolicy is how an organization's members may sign in. Without a
+// stored policy magic links are off.
+type LoginPolicy struct {
+	OrganizationID   string     `json:"organization_id" db:"organization_id"`
+	MagicLinkEnabled bool       `json:"magic_link_enabled" db:"magic_link_enabled"`
+	UpdatedBy        *string    `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// magicLinkClaims name the magic_links row that makes the link single-use,
+// and the address it was sent to
+type magicLinkClaims struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// SetMagicLinkConfig replaces the magic link settings
+func (s *AuthService) SetMagicLinkConfig(config MagicLinkConfig) {
+	s.magicLink = config
+}
+
+// MagicLinkConfig returns the magic link settings
+func (s *AuthService) MagicLinkConfig() MagicLinkConfig {
+	return s.magicLink
+}
+
+// AddMagicLinkNotifier registers a delivery channel for magic links
+func (s *AuthService) AddMagicLinkNotifier(fn MagicLinkFunc) {
+	s.magicLinkNotifiers = append(s.magicLinkNotifiers, fn)
+}
+
+// RequestMagicLink sends a sign-in link to the active user with the email
+// address. Unknown addresses return nil, nil so callers can't tell them
+// apart; ErrMagicLinkNotAllowed should be answered the same way.
+func (s *AuthService) RequestMagicLink(ctx context.Context, email, ipAddress string) (*MagicLink, error) {
+	if !s.magicLink.Enabled {
+		return nil, ErrMagicLinkDisabled
+	}
+	email = strings.TrimSpace(email)
+	if err := s.limitMagicLinks(ctx, "ip:"+ipAddress, s.magicLink.PerIP); err != nil {
+		return nil, err
+	}
+	if err := s.limitMagicLinks(ctx, "email:"+strings.ToLower(email), s.magicLink.PerEmail); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repos.Users.GetActiveByEmail(ctx, email)
+	if err == repository.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	allowed, err := s.magicLinkAllowed(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return &MagicLink{UserID: user.ID, Email: user.Email, IPAddress: ipAddress}, ErrMagicLinkNotAllowed
+	}
+
+	// A new link replaces any still unused
+	link := MagicLink{UserID: user.ID, Email: user.Email, IPAddress: ipAddress}
+	var stored struct {
+		ID        string    `db:"id"`
+		ExpiresAt time.Time `db:"expires_at"`
+	}
+	err = s.uow.Do(ctx, func(repos *repository.Repositories) error {
+		_, err := repos.Q.ExecContext(ctx, `
+			DELETE FROM magic_links WHERE user_id = $1 AND consumed_at IS NULL
+		`, user.ID)
+		if err != nil {
+			return err
+		}
+		return repos.Q.GetContext(ctx, &stored, `
+			INSERT INTO magic_links (user_id, requested_ip, expires_at)
+			VALUES ($1, NULLIF($2, '')::inet, NOW() + make_interval(secs => $3))
+			RETURNING id, expires_at
+		`, user.ID, ipAddress, s.magicLink.TTL.Seconds())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store magic link: %w", err)
+	}
+	link.ID, link.ExpiresAt = stored.ID, stored.ExpiresAt
+
+	now := time.Now()
+	claims := magicLinkClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        link.ID,
+			ExpiresAt: jwt.NewNumericDate(link.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	link.Token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(magicLinkKey(s.keys.signing()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign magic link: %w", err)
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Magic link requested", zap.String("user_id", user.ID), zap.String("ip_address", ipAddress))
+
+	// Delivery (SMTP in particular) must not hold up the response
+	go func() {
+		for _, notify := range s.magicLinkNotifiers {
+			notify(link)
+		}
+	}()
+	return &link, nil
+}
+
+// LoginWithMagicLink consumes a magic link and signs its user in, exactly as
+// a password login would. A link works once, whatever the outcome.
+func (s *AuthService) LoginWithMagicLink(ctx context.Context, token, ipAddress, userAgent string) (*LoginResponse, error) {
+	if !s.magicLink.Enabled {
+		return nil, ErrMagicLinkDisabled
+	}
+	claims := &magicLinkClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, s.verificationKeys(magicLinkKey))
+	if err != nil || !parsed.Valid || claims.ID == "" {
+		return nil, ErrInvalidMagicLink
+	}
+
+	var userID string
+	err = s.db.GetContext(ctx, &userID, `
+		UPDATE magic_links SET consumed_at = NOW(), consumed_ip = NULLIF($2, '')::inet
+		WHERE id = $1 AND consumed_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, claims.ID, ipAddress)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume magic link: %w", err)
+	}
+
+	// The address must still be the user's, and the account active
+	user, err := s.repos.Users.GetActive(ctx, userID)
+	if err == repository.ErrNotFound || (err == nil && (user.ID != claims.UserID || user.Email != claims.Email)) {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// The policy may have changed since the link was sent
+	allowed, err := s.magicLinkAllowed(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrMagicLinkNotAllowed
+	}
+
+	return s.startSession(ctx, user, ipAddress, userAgent, "")
+}
+
+// GetLoginPolicy returns the organization's login policy
+func (s *AuthService) GetLoginPolicy(ctx context.Context, orgID string) (*LoginPolicy, error) {
+	var policy LoginPolicy
+	err := s.db.GetContext(ctx, &policy, `
+		SELECT organization_id, magic_link_enabled, updated_by, updated_at
+		FROM org_login_policies WHERE organization_id = $1
+	`, orgID)
+	if err == sql.ErrNoRows {
+		return &LoginPolicy{OrganizationID: orgID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SetLoginPolicy replaces the organization's login policy
+func (s *AuthService) SetLoginPolicy(ctx context.Context, orgID, userID string, magicLinkEnabled bool) (*LoginPolicy, error) {
+	var policy LoginPolicy
+	err := s.db.GetContext(ctx, &policy, `
+		INSERT INTO org_login_policies (organization_id, magic_link_enabled, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			magic_link_enabled = EXCLUDED.magic_link_enabled,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING organization_id, magic_link_enabled, updated_by, updated_at
+	`, orgID, magicLinkEnabled, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// magicLinkAllowed reports whether every organization the user belongs to
+// allows magic links. Users without an organization follow the deployment.
+func (s *AuthService) magicLinkAllowed(ctx context.Context, userID string) (bool, error) {
+	var denied bool
+	err := s.db.GetContext(ctx, &denied, `
+		SELECT EXISTS (
+			SELECT 1 FROM organization_memberships m
+			LEFT JOIN org_login_policies p ON p.organization_id = m.organization_id
+			WHERE m.user_id = $1 AND NOT COALESCE(p.magic_link_enabled, false)
+		)
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check login policies: %w", err)
+	}
+	return !denied, nil
+}
+
+// limitMagicLinks counts a request against key's fixed window. Redis
+// trouble lets the request through rather than lock everyone out.
+func (s *AuthService) limitMagicLinks(ctx context.Context, key string, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	key = magicLinkKeyPrefix + key
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to count magic link requests", zap.Error(err))
+		return nil
+	}
+	if count == 1 {
+		if err := s.redis.Expire(ctx, key, s.magicLink.Window).Err(); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to expire magic link request count", zap.Error(err))
+		}
+	}
+	if count > int64(limit) {
+		return ErrMagicLinkRateLimited
+	}
+	return nil
+}
+
+// purgeMagicLinks deletes links a day after they expired
+func (s *AuthService) purgeMagicLinks(ctx context.Context) {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM magic_links WHERE expires_at < NOW() - INTERVAL '1 day'`)
+	if err != nil {
+		s.logger.Error("Failed to purge expired magic links", zap.Error(err))
+	}
+}
+
+// magicLinkKey is derived from the JWT secret so magic links can never pass
+// as access, session action or email verification tokens
+func magicLinkKey(jwtSecret []byte) []byte {
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte("magic-link"))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrIPNotAllowed is returned by Login when the address is outside the
+	// allowlist of the user's organization
+	ErrIPNotAllowed         = errors.New("address not allowed by the organization's network policy")
+	ErrInvalidNetworkPolicy = errors.New("invalid network policy")
+)
+
+// Where a network policy was enforced
+const (
+	NetworkStageLogin    = "login"
+	NetworkStageRequest  = "request"
+	NetworkStageAPIToken = "api_token"
+)
+
+// networkBlockReportInterval throttles audit events for a client retrying
+// from a blocked address
+const networkBlockReportInterval = time.Minute
+
+// NetworkPolicy restricts the addresses an organization's members may sign
+// in and make requests from. Without a stored policy it is disabled.
+type NetworkPolicy struct {
+	OrganizationID string         `json:"organization_id" db:"organization_id"`
+	Enabled        bool           `json:"enabled" db:"enabled"`
+	AllowedCIDRs   pq.StringArray `json:"allowed_cidrs" db:"allowed_cidrs"`
+	UpdatedBy      *string        `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt      *time.Time     `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// NetworkPolicyOverride is an owner's break-glass login: its session may
+// reach the organization from the login address until it expires
+type NetworkPolicyOverride struct {
+	ID             string    `json:"id" db:"id"`
+	SessionID      string    `json:"session_id" db:"session_id"`
+	OrganizationID string    `json:"organization_id" db:"organization_id"`
+	UserID         string    `json:"user_id" db:"user_id"`
+	Reason         string    `json:"reason" db:"reason"`
+	IPAddress      string    `json:"ip_address" db:"ip_address"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// NetworkPolicyEvent reports a login or request from outside an
+// organization's allowlist
+type NetworkPolicyEvent struct {
+	Stage          string     `json:"stage"`       // login, request or api_token
+	BreakGlass     bool       `json:"break_glass"` // An owner's override let it through
+	UserID         string     `json:"user_id"`
+	OrganizationID string     `json:"organization_id"`
+	IPAddress      string     `json:"ip_address"`
+	Method         string     `json:"method,omitempty"`
+	Path           string     `json:"path,omitempty"`
+	TokenID        string     `json:"token_id,omitempty"`   // For api_token
+	Reason         string     `json:"reason,omitempty"`     // For break-glass logins
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"` // Of the override
+	OccurredAt     time.Time  `json:"occurred_at"`
+}
+
+// NetworkPolicyFunc delivers a network policy event
+type NetworkPolicyFunc func(event NetworkPolicyEvent)
+
+// AddNetworkPolicyNotifier registers a delivery channel for network policy events
+func (s *AuthService) AddNetworkPolicyNotifier(fn NetworkPolicyFunc) {
+	s.networkPolicyNotifiers = append(s.networkPolicyNotifiers, fn)
+}
+
+// networkPolicyCache holds parsed allowlists for the per-request check.
+// Entries are dropped on change on this replica and expire after ttl on
+// the others.
+type networkPolicyCache struct {
+	ttl      time.Duration
+	mu       sync.RWMutex
+	entries  map[string]cachedNetworkPolicy
+	reported map[string]time.Time // Last blocked event per user, organization and address
+}
+
+type cachedNetworkPolicy struct {
+	networks []*net.IPNet // nil without an enabled policy
+	expires  time.Time
+}
+
+func newNetworkPolicyCache(ttl time.Duration) *networkPolicyCache {
+	return &networkPolicyCache{
+		ttl:      ttl,
+		entries:  make(map[string]cachedNetworkPolicy),
+		reported: make(map[string]time.Time),
+	}
+}
+
+func (c *networkPolicyCache) invalidate(orgID string) {
+	c.mu.Lock()
+	delete(c.entries, orgID)
+	c.mu.Unlock()
+}
+
+// shouldReport reports whether a block under key is the first in
+// networkBlockReportInterval
+func (c *networkPolicyCache) shouldReport(key string) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if last, ok := c.reported[key]; ok && now.Sub(last) < networkBlockReportInterval {
+		return false
+	}
+	if len(c.reported) > 10000 {
+		for k, last := range c.reported {
+			if now.Sub(last) >= networkBlockReportInterval {
+				delete(c.reported, k)
+			}
+		}
+	}
+	c.reported[key] = now
+	return true
+}
+
+// allowedNetworks returns the organization's allowlist, nil when it has no
+// enabled policy
+func (s *AuthService) allowedNetworks(ctx context.Context, orgID string) ([]*net.IPNet, error) {
+	s.networkPolicies.mu.RLock()
+	cached, ok := s.networkPolicies.entries[orgID]
+	s.networkPolicies.mu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.networks, nil
+	}
+
+	var cidrs pq.StringArray
+	err := s.db.GetContext(ctx, &cidrs, `
+		SELECT allowed_cidrs FROM org_network_policies WHERE organization_id = $1 AND enabled
+	`, orgID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load network policy: %w", err)
+	}
+
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Invalid CIDR in network policy", zap.String("organization_id", orgID), zap.String("cidr", cidr))
+			continue
+		}
+		networks = append(networks, network)
+	}
+	if len(cidrs) > 0 && networks == nil {
+		// Every entry was unparseable: allow nothing rather than everything
+		networks = []*net.IPNet{}
+	}
+
+	s.networkPolicies.mu.Lock()
+	s.networkPolicies.entries[orgID] = cachedNetworkPolicy{networks: networks, expires: time.Now().Add(s.networkPolicies.ttl)}
+	s.networkPolicies.mu.Unlock()
+	return networks, nil
+}
+
+// addressAllowed reports whether the organization's policy admits ip
+func (s *AuthService) addressAllowed(ctx context.Context, orgID, ip string) (bool, error) {
+	if orgID == "" {
+		return true, nil
+	}
+	networks, err := s.allowedNetworks(ctx, orgID)
+	if err != nil {
+		return false, err
+	}
+	if networks == nil {
+		return true, nil
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false, nil
+	}
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkLoginNetwork applies the network policy of the organization a login
+// is scoped to. An owner outside the allowlist who gives a reason breaks
+// glass: the login goes ahead and startBreakGlass records the override.
+func (s *AuthService) checkLoginNetwork(ctx context.Context, user *User, orgID, ipAddress, reason string) (bool, error) {
+	allowed, err := s.addressAllowed(ctx, orgID, ipAddress)
+	if err != nil {
+		return false, err
+	}
+	if allowed {
+		return false, nil
+	}
+
+	if reason != "" {
+		role, err := s.repos.Orgs.MemberRole(ctx, user.ID, orgID)
+		if err == nil && rbac.Role(role) == rbac.RoleOwner {
+			return true, nil
+		}
+	}
+
+	metrics.NetworkPolicyBlocks.WithLabelValues(NetworkStageLogin, "blocked").Inc()
+	logging.FromContext(ctx, s.logger).Warn("Login blocked by network policy",
+		zap.String("user_id", user.ID),
+		zap.String("organization_id", orgID),
+		zap.String("ip_address", ipAddress),
+	)
+	s.notifyNetworkPolicy(NetworkPolicyEvent{
+		Stage:          NetworkStageLogin,
+		UserID:         user.ID,
+		OrganizationID: orgID,
+		IPAddress:      ipAddress,
+		OccurredAt:     time.Now().UTC(),
+	})
+	return false, ErrIPNotAllowed
+}
+
+// startBreakGlass lets the new session reach the organization from
+// ipAddress for BreakGlassTTL
+func (s *AuthService) startBreakGlass(ctx context.Context, user *User, sessionID, orgID, ipAddress, reason string) error {
+	var override NetworkPolicyOverride
+	err := s.db.GetContext(ctx, &override, `
+		INSERT INTO network_policy_overrides (session_id, organization_id, user_id, reason, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, session_id, organization_id, user_id, reason, ip_address, created_at, expires_at
+	`, sessionID, orgID, user.ID, reason, ipAddress, time.Now().Add(s.sessionConfig.BreakGlassTTL))
+	if err != nil {
+		return fmt.Errorf("failed to record network policy override: %w", err)
+	}
+
+	metrics.NetworkPolicyBlocks.WithLabelValues(NetworkStageLogin, "break_glass").Inc()
+	logging.FromContext(ctx, s.logger).Warn("Network policy overridden by break-glass login",
+		zap.String("user_id", user.ID),
+		zap.String("organization_id", orgID),
+		zap.String("session_id", sessionID),
+		zap.String("ip_address", ipAddress),
+	)
+	s.notifyNetworkPolicy(NetworkPolicyEvent{
+		Stage:          NetworkStageLogin,
+		BreakGlass:     true,
+		UserID:         user.ID,
+		OrganizationID: orgID,
+		IPAddress:      ipAddress,
+		Reason:         reason,
+		ExpiresAt:      &override.ExpiresAt,
+		OccurredAt:     time.Now().UTC(),
+	})
+	return nil
+}
+
+// hasBreakGlass reports whether the session holds an unexpired override
+// for the organization from ipAddress
+func (s *AuthService) hasBreakGlass(ctx context.Context, sessionID, orgID, ipAddress string) (bool, error) {
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM network_policy_overrides
+			WHERE session_id = $1 AND organization_id = $2 AND ip_address = $3 AND expires_at > NOW()
+		)
+	`, sessionID, orgID, ipAddress)
+	return exists, err
+}
+
+// enforceNetworkPolicy aborts the request unless every organization it is
+// scoped to admits the client address, or the session broke glass for it
+func (s *AuthService) enforceNetworkPolicy(c *gin.Context, userID, sessionID string, orgIDs ...string) bool {
+	ctx := c.Request.Context()
+	clientIP := s.clientIP(c)
+	for i, orgID := range orgIDs {
+		if orgID == "" || (i > 0 && orgID == orgIDs[0]) {
+			continue
+		}
+		allowed, err := s.addressAllowed(ctx, orgID, clientIP)
+		if err == nil && !allowed {
+			allowed, err = s.hasBreakGlass(ctx, sessionID, orgID, clientIP)
+		}
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to check network policy", zap.String("organization_id", orgID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check network policy"})
+			c.Abort()
+			return false
+		}
+		if allowed {
+			continue
+		}
+
+		metrics.NetworkPolicyBlocks.WithLabelValues(NetworkStageRequest, "blocked").Inc()
+		if s.networkPolicies.shouldReport(userID + "|" + orgID + "|" + clientIP) {
+			s.notifyNetworkPolicy(NetworkPolicyEvent{
+				Stage:          NetworkStageRequest,
+				UserID:         userID,
+				OrganizationID: orgID,
+				IPAddress:      clientIP,
+				Method:         c.Request.Method,
+				Path:           c.FullPath(),
+				OccurredAt:     time.Now().UTC(),
+			})
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "this address is not allowed by the organization's network policy",
+			"code":  "ip_not_allowed",
+		})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// pathOrganization is the organization an /organizations/:id route acts on
+func pathOrganization(c *gin.Context) string {
+	_, rest, ok := strings.Cut(c.FullPath(), "/organizations/:id")
+	if !ok || (rest != "" && rest[0] != '/') {
+		return ""
+	}
+	return c.Param("id")
+}
+
+func (s *AuthService) notifyNetworkPolicy(event NetworkPolicyEvent) {
+	go func() {
+		for _, notify := range s.networkPolicyNotifiers {
+			notify(event)
+		}
+	}()
+}
+
+// GetNetworkPolicy returns the organization's policy, disabled if it never
+// set one
+func (s *AuthService) GetNetworkPolicy(ctx context.Context, orgID string) (*NetworkPolicy, error) {
+	var policy NetworkPolicy
+	err := s.db.GetContext(ctx, &policy, `
+		SELECT organization_id, enabled, allowed_cidrs, updated_by, updated_at
+		FROM org_network_policies WHERE organization_id = $1
+	`, orgID)
+	if err == sql.ErrNoRows {
+		return &NetworkPolicy{OrganizationID: orgID, AllowedCIDRs: pq.StringArray{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SetNetworkPolicy replaces the organization's policy. Enabling it requires
+// an allowlist that admits clientIP, so an owner cannot lock themselves out.
+func (s *AuthService) SetNetworkPolicy(ctx context.Context, orgID, userID, clientIP string, enabled bool, allowedCIDRs []string) (*NetworkPolicy, error) {
+	cidrs, err := parseAllowedIPs(allowedCIDRs, ErrInvalidNetworkPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		if len(cidrs) == 0 {
+			return nil, fmt.Errorf("%w: an enabled policy needs at least one address or CIDR", ErrInvalidNetworkPolicy)
+		}
+		if !ipAllowed(cidrs, clientIP) {
+			return nil, fmt.Errorf("%w: the allowlist must include your current address %s", ErrInvalidNetworkPolicy, clientIP)
+		}
+	}
+
+	var policy NetworkPolicy
+	err = s.db.GetContext(ctx, &policy, `
+		INSERT INTO org_network_policies (organization_id, enabled, allowed_cidrs, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			allowed_cidrs = EXCLUDED.allowed_cidrs,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING organization_id, enabled, allowed_cidrs, updated_by, updated_at
+	`, orgID, enabled, cidrs, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.networkPolicies.invalidate(orgID)
+	return &policy, nil
+}
+
+// ActiveNetworkPolicyOverrides returns the organization's unexpired
+// break-glass overrides, newest first
+func (s *AuthService) ActiveNetworkPolicyOverrides(ctx context.Context, orgID string) ([]NetworkPolicyOverride, error) {
+	overrides := []NetworkPolicyOverride{}
+	err := s.db.SelectContext(ctx, &overrides, `
+		SELECT id, session_id, organization_id, user_id, reason, ip_address, created_at, expires_at
+		FROM network_policy_overrides
+		WHERE organization_id = $1 AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`, orgID)
+	return overrides, err
+}
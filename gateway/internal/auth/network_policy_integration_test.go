@@ -0,0 +1,67 @@
+//go:build integration
+
+package auth_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+// fromAddress is an authenticated request whose connection comes from
+// remote, with an X-Forwarded-For header when forwarded is set
+func fromAddress(token, remote, forwarded string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.RemoteAddr = remote
+	req.Header.Set("Authorization", "Bearer "+token)
+	if forwarded != "" {
+		req.Header.Set("X-Forwarded-For", forwarded)
+	}
+	return req
+}
+
+func TestNetworkPolicyIgnoresSpoofedForwarding(t *testing.T) {
+	env := testenv.Setup(t)
+	org := env.CreateOrganization(t, "")
+	owner := env.CreateUser(t, testenv.UserOptions{OrgID: org.ID})
+	env.AddMember(t, owner.ID, org.ID, rbac.RoleOwner)
+	token := env.Login(t, owner).AccessToken
+
+	_, err := env.Auth.SetNetworkPolicy(context.Background(), org.ID, owner.ID, "203.0.113.5", true, []string{"203.0.113.0/24"})
+	if err != nil {
+		t.Fatalf("SetNetworkPolicy: %v", err)
+	}
+
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		name      string
+		trusted   bool // 10.0.0.0/8 is a trusted proxy
+		remote    string
+		forwarded string
+		status    int
+	}{
+		{"allowed address", false, "203.0.113.5:4711", "", http.StatusOK},
+		{"address outside the allowlist", false, "198.51.100.7:4711", "", http.StatusForbidden},
+		{"spoofed X-Forwarded-For", false, "198.51.100.7:4711", "203.0.113.5", http.StatusForbidden},
+		{"spoofed X-Forwarded-For with trusted proxies", true, "198.51.100.7:4711", "203.0.113.5", http.StatusForbidden},
+		{"spoofed entry ahead of a trusted proxy", true, "10.1.2.3:4711", "203.0.113.5, 198.51.100.7", http.StatusForbidden},
+		{"forwarded by a trusted proxy", true, "10.1.2.3:4711", "203.0.113.5", http.StatusOK},
+		{"untrusted proxy", false, "10.1.2.3:4711", "203.0.113.5", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.trusted {
+				env.Auth.SetTrustedProxies([]*net.IPNet{proxies})
+				t.Cleanup(func() { env.Auth.SetTrustedProxies(nil) })
+			}
+			if status, _ := serveMe(env, fromAddress(token, tt.remote, tt.forwarded)); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+//go:build integration
+
+package auth_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+func TestOrgTokenAllowedIPsIgnoreSpoofedForwarding(t *testing.T) {
+	env := testenv.Setup(t)
+	org := env.CreateOrganization(t, "")
+	owner := env.CreateUser(t, testenv.UserOptions{OrgID: org.ID})
+	env.AddMember(t, owner.ID, org.ID, rbac.RoleOwner)
+
+	env.Auth.SetOrgTokenRoutes(map[string]rbac.Permission{"GET /me": rbac.PermViewScan})
+	t.Cleanup(func() { env.Auth.SetOrgTokenRoutes(nil) })
+
+	created, err := env.Auth.CreateOrgToken(context.Background(), org.ID, owner.ID, auth.CreateOrgTokenRequest{
+		Name:       "ci",
+		Scopes:     rbac.TokenScopes{{Permission: rbac.PermViewScan}},
+		AllowedIPs: []string{"203.0.113.0/24"},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrgToken: %v", err)
+	}
+
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		name      string
+		trusted   bool // 10.0.0.0/8 is a trusted proxy
+		remote    string
+		forwarded string
+		status    int
+	}{
+		{"allowed address", false, "203.0.113.5:4711", "", http.StatusOK},
+		{"address outside the token's list", false, "198.51.100.7:4711", "", http.StatusForbidden},
+		{"spoofed X-Forwarded-For", false, "198.51.100.7:4711", "203.0.113.5", http.StatusForbidden},
+		{"spoofed X-Forwarded-For with trusted proxies", true, "198.51.100.7:4711", "203.0.113.5", http.StatusForbidden},
+		{"spoofed entry ahead of a trusted proxy", true, "10.1.2.3:4711", "203.0.113.5, 198.51.100.7", http.StatusForbidden},
+		{"forwarded by a trusted proxy", true, "10.1.2.3:4711", "203.0.113.5", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.trusted {
+				env.Auth.SetTrustedProxies([]*net.IPNet{proxies})
+				t.Cleanup(func() { env.Auth.SetTrustedProxies(nil) })
+			}
+			if status, _ := serveMe(env, fromAddress(created.Token, tt.remote, tt.forwarded)); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	// The recorded address is the one that was checked
+	var lastUsed string
+	if err := env.DB.GetContext(context.Background(), &lastUsed, `SELECT last_used_ip FROM org_api_tokens WHERE id = $1`, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if lastUsed != "203.0.113.5" {
+		t.Errorf("last_used_ip = %s, want 203.0.113.5", lastUsed)
+	}
+}
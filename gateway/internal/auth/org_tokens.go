@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// OrgTokenPrefix starts every organization API token, telling them apart
+// from JWTs in the Authorization header
+const OrgTokenPrefix = "cyp_"
+
+const (
+	defaultOrgTokenTTL = 90 * 24 * time.Hour
+	maxOrgTokenTTL     = 365 * 24 * time.Hour
+
+	// orgTokenTouchInterval throttles last-used updates for busy tokens
+	orgTokenTouchInterval = time.Minute
+)
+
+var (
+	ErrOrgTokenNotFound     = errors.New("API token not found")
+	ErrInvalidOrgTokenScope = errors.New("invalid API token scope")
+)
+
+// OrgToken is a service token of an organization. It acts as its creator,
+// limited to its scopes, and stops working when they leave the organization.
+type OrgToken struct {
+	ID             string           `json:"id" db:"id"`
+	OrganizationID string           `json:"organization_id" db:"organization_id"`
+	Name           string           `json:"name" db:"name"`
+	TokenPrefix    string           `json:"token_prefix" db:"token_prefix"` // Shown to tell tokens apart
+	Scopes         rbac.TokenScopes `json:"scopes" db:"scopes"`
+	AllowedIPs     pq.StringArray   `json:"allowed_ips" db:"allowed_ips"` // CIDRs; empty allows any address
+	ExpiresAt      time.Time        `json:"expires_at" db:"expires_at"`
+	LastUsedAt     *time.Time       `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP     *string          `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	CreatedBy      string           `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time        `json:"created_at" db:"created_at"`
+	RevokedAt      *time.Time       `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+const orgTokenColumns = `id, organization_id, name, token_prefix, scopes, allowed_ips, expires_at,
+	last_used_at, last_used_ip, created_by, created_at, revoked_at`
+
+// CreateOrgTokenRequest describes a new token
+type CreateOrgTokenRequest struct {
+	Name       string           `json:"name" binding:"required,max=100"`
+	Scopes     rbac.TokenScopes `json:"scopes" binding:"required,min=1"`
+	AllowedIPs []string         `json:"allowed_ips"`
+	ExpiresIn  int              `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // Defaults to 90
+}
+
+// UpdateOrgTokenRequest replaces a token's scopes
+type UpdateOrgTokenRequest struct {
+	Scopes rbac.TokenScopes `json:"scopes" binding:"required,min=1"`
+}
+
+// CreatedOrgToken is a new token with its secret, which is only ever
+// returned here
+type CreatedOrgToken struct {
+	OrgToken
+	Token string `json:"token"`
+}
+
+// SetOrgTokenRoutes sets the permission each route requires of API tokens,
+// keyed by "METHOD /full/path". Tokens are refused on routes not listed.
+func (s *AuthService) SetOrgTokenRoutes(routes map[string]rbac.Permission) {
+	s.orgTokenRoutes = routes
+}
+
+// CreateOrgToken issues a token for orgID. Scoped assets must be
+// authorizations of the organization; checking that createdBy holds the
+// scoped permissions is up to the caller.
+func (s *AuthService) CreateOrgToken(ctx context.Context, orgID, createdBy string, req CreateOrgTokenRequest) (*CreatedOrgToken, error) {
+	if err := s.validateOrgTokenScopes(ctx, orgID, req.Scopes); err != nil {
+		return nil, err
+	}
+
+	allowed, err := parseAllowedIPs(req.AllowedIPs, ErrInvalidOrgTokenScope)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := defaultOrgTokenTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * 24 * time.Hour
+	}
+	if ttl > maxOrgTokenTTL {
+		ttl = maxOrgTokenTTL
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := OrgTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	created := &CreatedOrgToken{Token: token}
+	err = s.db.GetContext(ctx, &created.OrgToken, `
+		INSERT INTO org_api_tokens (organization_id, name, token_hash, token_prefix, scopes, allowed_ips, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+orgTokenColumns,
+		orgID, req.Name, hashToken(token), token[:len(OrgTokenPrefix)+6], req.Scopes, allowed, time.Now().Add(ttl), createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateOrgTokenScopes replaces the scopes of an active token and returns
+// the scopes it had. As with CreateOrgToken, checking that the caller holds
+// the scoped permissions is up to them.
+func (s *AuthService) UpdateOrgTokenScopes(ctx context.Context, orgID, tokenID string, scopes rbac.TokenScopes) (*OrgToken, rbac.TokenScopes, error) {
+	if err := s.validateOrgTokenScopes(ctx, orgID, scopes); err != nil {
+		return nil, nil, err
+	}
+
+	// Reading the previous scopes in the same statement keeps concurrent
+	// updates from hiding a change
+	var updated struct {
+		OrgToken
+		PreviousScopes rbac.TokenScopes `db:"previous_scopes"`
+	}
+	err := s.db.GetContext(ctx, &updated, `
+		UPDATE org_api_tokens t SET scopes = $3
+		FROM (
+			SELECT id AS previous_id, scopes AS previous_scopes FROM org_api_tokens
+			WHERE id = $1 AND organization_id = $2 AND revoked_at IS NULL
+			FOR UPDATE
+		) previous
+		WHERE t.id = previous.previous_id
+		RETURNING previous.previous_scopes, `+orgTokenColumns,
+		tokenID, orgID, scopes)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrOrgTokenNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update token scopes: %w", err)
+	}
+	return &updated.OrgToken, updated.PreviousScopes, nil
+}
+
+// validateOrgTokenScopes checks scoped permissions exist and scoped assets
+// are authorizations of the organization
+func (s *AuthService) validateOrgTokenScopes(ctx context.Context, orgID string, scopes rbac.TokenScopes) error {
+	for _, scope := range scopes {
+		if !scope.Permission.IsValid() {
+			return fmt.Errorf("%w: unknown permission %q", ErrInvalidOrgTokenScope, scope.Permission)
+		}
+		if len(scope.Assets) == 0 {
+			continue
+		}
+		var found int
+		err := s.db.GetContext(ctx, &found, `
+			SELECT COUNT(*) FROM authorized_targets
+			WHERE organization_id = $1 AND id::text = ANY($2)
+		`, orgID, pq.Array(scope.Assets))
+		if err != nil {
+			return fmt.Errorf("failed to check token assets: %w", err)
+		}
+		if found != len(scope.Assets) {
+			return fmt.Errorf("%w: assets must be authorizations of the organization", ErrInvalidOrgTokenScope)
+		}
+	}
+	return nil
+}
+
+// parseAllowedIPs normalizes addresses and CIDRs to CIDRs; an invalid entry
+// fails with errInvalid
+func parseAllowedIPs(entries []string, errInvalid error) (pq.StringArray, error) {
+	allowed := pq.StringArray{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			entry = (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String()
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid IP address or CIDR %q", errInvalid, entry)
+		}
+		allowed = append(allowed, network.String())
+	}
+	return allowed, nil
+}
+
+// ListOrgTokens returns the organization's tokens, newest first, including
+// expired and revoked ones
+func (s *AuthService) ListOrgTokens(ctx context.Context, orgID string) ([]OrgToken, error) {
+	tokens := []OrgToken{}
+	err := s.db.SelectContext(ctx, &tokens, `
+		SELECT `+orgTokenColumns+` FROM org_api_tokens
+		WHERE organization_id = $1
+		ORDER BY created_at DESC
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeOrgToken stops a token from working immediately
+func (s *AuthService) RevokeOrgToken(ctx context.Context, orgID, tokenID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE org_api_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND organization_id = $2 AND revoked_at IS NULL
+	`, tokenID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrOrgTokenNotFound
+	}
+	return nil
+}
+
+// authenticateOrgToken is AuthMiddleware for organization API tokens: the
+// request acts as the token's creator, with their current role in the
+// token's organization narrowed to the token's scopes
+func (s *AuthService) authenticateOrgToken(c *gin.Context, tokenString string) {
+	ctx := c.Request.Context()
+	deny := func(status int, message string) {
+		metrics.OrgTokenRequests.WithLabelValues("denied").Inc()
+		c.JSON(status, gin.H{"error": message})
+		c.Abort()
+	}
+
+	var token OrgToken
+	err := s.db.GetContext(ctx, &token, `
+		SELECT `+orgTokenColumns+` FROM org_api_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	`, hashToken(tokenString))
+	if err == sql.ErrNoRows {
+		deny(http.StatusUnauthorized, "invalid or expired API token")
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to look up API token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate API token"})
+		c.Abort()
+		return
+	}
+
+	clientIP := s.clientIP(c)
+	if !ipAllowed(token.AllowedIPs, clientIP) {
+		logging.FromContext(ctx, s.logger).Warn("API token used from disallowed address",
+			zap.String("token_id", token.ID),
+			zap.String("ip", clientIP),
+		)
+		deny(http.StatusForbidden, "API token is not allowed from this address")
+		return
+	}
+	allowed, err := s.addressAllowed(ctx, token.OrganizationID, clientIP)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check network policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate API token"})
+		c.Abort()
+		return
+	}
+	if !allowed {
+		metrics.NetworkPolicyBlocks.WithLabelValues(NetworkStageAPIToken, "blocked").Inc()
+		if s.networkPolicies.shouldReport(token.ID + "|" + clientIP) {
+			s.notifyNetworkPolicy(NetworkPolicyEvent{
+				Stage:          NetworkStageAPIToken,
+				UserID:         token.CreatedBy,
+				OrganizationID: token.OrganizationID,
+				IPAddress:      clientIP,
+				Method:         c.Request.Method,
+				Path:           c.FullPath(),
+				TokenID:        token.ID,
+				OccurredAt:     time.Now().UTC(),
+			})
+		}
+		deny(http.StatusForbidden, "this address is not allowed by the organization's network policy")
+		return
+	}
+
+	perm, ok := s.orgTokenRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		deny(http.StatusForbidden, "API tokens cannot use this endpoint")
+		return
+	}
+	if !token.Scopes.Allows(perm) {
+		deny(http.StatusForbidden, "API token is not scoped for this action")
+		return
+	}
+
+	user, err := s.repos.Users.GetActive(ctx, token.CreatedBy)
+	if err == repository.ErrNotFound {
+		deny(http.StatusUnauthorized, "API token owner is no longer active")
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to load API token owner", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate API token"})
+		c.Abort()
+		return
+	}
+	role, err := s.repos.Orgs.MemberRole(ctx, token.CreatedBy, token.OrganizationID)
+	if err == repository.ErrNotFound {
+		deny(http.StatusUnauthorized, "API token owner is no longer a member of the organization")
+		return
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to fetch org role", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate API token"})
+		c.Abort()
+		return
+	}
+
+	s.touchOrgToken(ctx, token, clientIP)
+	metrics.OrgTokenRequests.WithLabelValues("allowed").Inc()
+
+	c.Request = c.Request.WithContext(rbac.WithTokenGrant(ctx, rbac.TokenGrant{
+		TokenID: token.ID,
+		OrgID:   token.OrganizationID,
+		Scopes:  token.Scopes,
+	}))
+	c.Set("api_token_id", token.ID)
+	c.Set("user_id", user.ID)
+	c.Set("email", user.Email)
+	c.Set("user_role", role)
+	c.Set("features", s.userFeatures(ctx, user, token.OrganizationID))
+	c.Set("token_org_id", token.OrganizationID)
+	c.Set("organization_id", token.OrganizationID)
+
+	c.Next()
+}
+
+// touchOrgToken records when and where the token was last used, at most once
+// per orgTokenTouchInterval
+func (s *AuthService) touchOrgToken(ctx context.Context, token OrgToken, clientIP string) {
+	if token.LastUsedAt != nil && time.Since(*token.LastUsedAt) < orgTokenTouchInterval &&
+		token.LastUsedIP != nil && *token.LastUsedIP == clientIP {
+		return
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE org_api_tokens SET last_used_at = NOW(), last_used_ip = $2 WHERE id = $1
+	`, token.ID, clientIP)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to record API token use", zap.String("token_id", token.ID), zap.Error(err))
+	}
+}
+
+// ipAllowed reports whether ip is in one of the CIDRs; an empty list allows
+// every address
+func ipAllowed(cidrs []string, ip string) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotOrgMember         = errors.New("not a member of the organization")
+	ErrOrganizationInactive = errors.New("organization is deactivated")
+)
+
+// OrganizationContext describes the organization a token is scoped to
+type OrganizationContext struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+	Tier string `json:"tier"`
+}
+
+// SwitchOrgResponse payload
+type SwitchOrgResponse struct {
+	AccessToken  string              `json:"access_token"`
+	ExpiresIn    int                 `json:"expires_in"`
+	Organization OrganizationContext `json:"organization"`
+	Features     []string            `json:"features"`
+}
+
+// DefaultOrganizationFunc returns the organization a user chose to sign in
+// to, or "" when they have not chosen one
+type DefaultOrganizationFunc func(ctx context.Context, userID string) string
+
+// SetDefaultOrganization lets users choose the organization new sessions are
+// scoped to instead of their home organization
+func (s *AuthService) SetDefaultOrganization(fn DefaultOrganizationFunc) {
+	s.defaultOrg = fn
+}
+
+// homeOrganization returns the organization to scope new tokens to: the
+// user's chosen default, else their own organization, or "" when they are
+// not (or no longer) a member of either
+func (s *AuthService) homeOrganization(ctx context.Context, user *User) string {
+	if s.defaultOrg != nil {
+		if orgID := s.defaultOrg(ctx, user.ID); orgID != "" {
+			if _, err := s.repos.Orgs.MemberRole(ctx, user.ID, orgID); err == nil {
+				return orgID
+			}
+		}
+	}
+	if !user.OrganizationID.Valid {
+		return ""
+	}
+	_, err := s.repos.Orgs.MemberRole(ctx, user.ID, user.OrganizationID.String)
+	if err == repository.ErrNotFound {
+		return ""
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to check home organization membership", zap.String("user_id", user.ID), zap.Error(err))
+		return ""
+	}
+	return user.OrganizationID.String
+}
+
+// SwitchOrganization re-issues the session's token scoped to orgID, with the
+// user's role there and the features of that organization's tier. The
+// session keeps its ID; the previous token stops working immediately.
+func (s *AuthService) SwitchOrganization(ctx context.Context, userID, sessionID, orgID string) (*SwitchOrgResponse, error) {
+	role, err := s.repos.Orgs.MemberRole(ctx, userID, orgID)
+	if err == repository.ErrNotFound {
+		return nil, ErrNotOrgMember
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load membership: %w", err)
+	}
+	org, err := s.repos.Orgs.Get(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organization: %w", err)
+	}
+	if !org.IsActive {
+		return nil, ErrOrganizationInactive
+	}
+
+	user, err := s.repos.Users.GetActive(ctx, userID)
+	if err == repository.ErrNotFound {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	features := s.userFeatures(ctx, user, orgID)
+	token, _, err := s.GenerateToken(user.ID, user.Email, role, orgID, features)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// Rotate the session onto the new token, evicting the old one from the
+	// cache. Its expiry slides as on use, still capped at the absolute expiry.
+	expiresIn := int(s.sessionConfig.IdleTimeout.Seconds())
+	oldHash, err := s.repos.Sessions.Rotate(ctx, userID, sessionID, hashToken(token), time.Now().Add(s.sessionConfig.IdleTimeout))
+	if err == repository.ErrNotFound {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	s.invalidateSessions(ctx, oldHash)
+
+	logging.FromContext(ctx, s.logger).Info("Switched organization",
+		zap.String("user_id", userID),
+		zap.String("session_id", sessionID),
+		zap.String("organization_id", orgID),
+	)
+
+	return &SwitchOrgResponse{
+		AccessToken: token,
+		ExpiresIn:   expiresIn,
+		Organization: OrganizationContext{
+			ID:   org.ID,
+			Name: org.Name,
+			Role: role,
+			Tier: org.SubscriptionTier,
+		},
+		Features: features,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Pulse statuses, as stored in authorization_pulses
+const (
+	PulseAuthorized = "authorized"
+	PulseRevoked    = "revoked"
+	PulseExpired    = "expired"
+	PulseError      = "error"
+)
+
+// PulseResult is a session's current entitlement, re-evaluated from the
+// database rather than trusted from the token
+type PulseResult struct {
+	Authorized     bool      `json:"authorized"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason,omitempty"`
+	Role           string    `json:"role,omitempty"`
+	OrganizationID string    `json:"organization_id,omitempty"`
+	Features       []string  `json:"features"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	NextCheckIn    int       `json:"next_check_in"` // Seconds until the client should pulse again
+}
+
+// Pulse checks that a session is still live and its user and organization
+// still active, recomputes the features it is entitled to, and records the
+// outcome. orgID is the organization the token is scoped to, if any; the
+// user's home organization is used otherwise.
+func (s *AuthService) Pulse(ctx context.Context, sessionID, userID, orgID string) (*PulseResult, error) {
+	start := time.Now()
+	result, err := s.evaluatePulse(ctx, sessionID, userID, orgID)
+	metrics.AuthPulseLatency.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.AuthPulses.WithLabelValues(PulseError).Inc()
+		return nil, err
+	}
+	metrics.AuthPulses.WithLabelValues(result.Status).Inc()
+
+	featuresJSON, _ := json.Marshal(result.Features)
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO authorization_pulses (session_id, user_id, status, features_granted, checked_at, next_check_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW() + $5 * INTERVAL '1 second')
+	`, sessionID, userID, result.Status, featuresJSON, result.NextCheckIn)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to record pulse", zap.String("session_id", sessionID), zap.Error(err))
+	}
+
+	return result, nil
+}
+
+func (s *AuthService) evaluatePulse(ctx context.Context, sessionID, userID, orgID string) (*PulseResult, error) {
+	session, err := s.repos.Sessions.Get(ctx, userID, sessionID)
+	if err == repository.ErrNotFound {
+		return &PulseResult{Status: PulseRevoked, Reason: "session not found", Features: []string{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	denied := func(status, reason string) *PulseResult {
+		// The middleware may still hold the session in its cache
+		s.invalidateSessions(ctx, session.TokenHash)
+		return &PulseResult{Status: status, Reason: reason, Features: []string{}, ExpiresAt: session.ExpiresAt}
+	}
+	if session.RevokedAt.Valid {
+		return denied(PulseRevoked, "session revoked"), nil
+	}
+	if !time.Now().Before(session.ExpiresAt) {
+		return denied(PulseExpired, "session expired"), nil
+	}
+
+	user, err := s.repos.Users.Get(ctx, userID)
+	if err == repository.ErrNotFound || (err == nil && !user.IsActive) {
+		return denied(PulseRevoked, "account disabled"), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	role := user.Role
+	if orgID != "" {
+		memberRole, err := s.repos.Orgs.MemberRole(ctx, userID, orgID)
+		if err == repository.ErrNotFound {
+			return denied(PulseRevoked, "no longer a member of the organization"), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load membership: %w", err)
+		}
+		org, err := s.repos.Orgs.Get(ctx, orgID)
+		if err != nil && err != repository.ErrNotFound {
+			return nil, fmt.Errorf("failed to load organization: %w", err)
+		}
+		if org == nil || !org.IsActive {
+			return denied(PulseRevoked, "organization deactivated"), nil
+		}
+		role = memberRole
+	} else {
+		orgID = user.OrganizationID.String
+	}
+
+	// Pulse again at the usual interval, or when the session runs out
+	next := s.pulseInterval
+	if remaining := time.Until(session.ExpiresAt); remaining < next {
+		next = remaining
+	}
+
+	return &PulseResult{
+		Authorized:     true,
+		Status:         PulseAuthorized,
+		Role:           role,
+		OrganizationID: orgID,
+		Features:       s.userFeatures(ctx, user, orgID),
+		ExpiresAt:      session.ExpiresAt,
+		NextCheckIn:    int(next.Seconds()),
+	}, nil
+}
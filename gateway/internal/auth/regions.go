@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DefaultRevocationChannel is the Redis pub/sub channel revoked sessions are
+// announced on
+const DefaultRevocationChannel = "auth:revocations"
+
+// RegionConfig describes a gateway deployed in one of several regions that
+// share a database. Each region keeps its own Redis, so a session revoked in
+// one region must also be evicted from the other regions' session caches.
+type RegionConfig struct {
+	// Region names this deployment (e.g. "eu-west-1"); it is stamped on the
+	// tokens and sessions it issues
+	Region string
+	// Audience is the aud claim of tokens issued here; empty omits it
+	Audience string
+	// AcceptedAudiences are the aud values tokens must carry one of to be
+	// accepted here; empty accepts any token, with or without an audience
+	AcceptedAudiences []string
+	// RevocationChannel is the pub/sub channel revocations are exchanged on
+	RevocationChannel string
+}
+
+// revocationMessage announces sessions revoked in one region to the others
+type revocationMessage struct {
+	Region      string   `json:"region"`
+	TokenHashes []string `json:"token_hashes"`
+}
+
+// SetRegionConfig configures the region and token audiences of this deployment
+func (s *AuthService) SetRegionConfig(config RegionConfig) {
+	if config.RevocationChannel == "" {
+		config.RevocationChannel = DefaultRevocationChannel
+	}
+	s.region = config
+}
+
+// SetRevocationBus publishes revocations through client, a Redis every
+// region can reach. Without it revocations only reach this region's cache
+// and other regions see them once their cached entries expire.
+func (s *AuthService) SetRevocationBus(client *redis.Client) {
+	s.revocationBus = client
+}
+
+// registeredClaims returns the registered claims of a token issued here
+func (s *AuthService) registeredClaims(claims jwt.RegisteredClaims) jwt.RegisteredClaims {
+	if s.region.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.region.Audience}
+	}
+	return claims
+}
+
+// audienceAccepted reports whether a token's audience is one this
+// deployment accepts
+func (s *AuthService) audienceAccepted(claims *Claims) bool {
+	if len(s.region.AcceptedAudiences) == 0 {
+		return true
+	}
+	for _, aud := range claims.Audience {
+		for _, accepted := range s.region.AcceptedAudiences {
+			if aud == accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// publishRevocation announces revoked sessions to the other regions
+func (s *AuthService) publishRevocation(ctx context.Context, tokenHashes []string) {
+	if s.revocationBus == nil || len(tokenHashes) == 0 {
+		return
+	}
+	payload, err := json.Marshal(revocationMessage{Region: s.region.Region, TokenHashes: tokenHashes})
+	if err != nil {
+		return
+	}
+	if err := s.revocationBus.Publish(ctx, s.region.RevocationChannel, payload).Err(); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to publish session revocation", zap.Error(err))
+	}
+}
+
+// StartRevocationListener evicts sessions revoked in other regions from this
+// region's session cache until ctx is cancelled. The subscription reconnects
+// on its own; revocations published while it is down are covered by the
+// cache TTL.
+func (s *AuthService) StartRevocationListener(ctx context.Context) {
+	if s.revocationBus == nil {
+		return
+	}
+
+	pubsub := s.revocationBus.Subscribe(ctx, s.region.RevocationChannel)
+	defer pubsub.Close()
+
+	s.logger.Info("Listening for session revocations",
+		zap.String("region", s.region.Region),
+		zap.String("channel", s.region.RevocationChannel),
+	)
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var revocation revocationMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &revocation); err != nil {
+				s.logger.Warn("Ignoring malformed session revocation", zap.Error(err))
+				continue
+			}
+			// This region's cache was cleared when the revocation was made
+			if revocation.Region == s.region.Region {
+				continue
+			}
+			if err := s.cache.Delete(ctx, revocation.TokenHashes...); err != nil {
+				s.logger.Error("Failed to apply session revocation", zap.String("region", revocation.Region), zap.Error(err))
+				continue
+			}
+			s.logger.Debug("Applied session revocation",
+				zap.String("region", revocation.Region),
+				zap.Int("sessions", len(revocation.TokenHashes)),
+			)
+		}
+	}
+}
@@ -6,38 +6,90 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/events"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/repository"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService struct {
-	db            *sqlx.DB
-	redis         *redis.Client
-	jwtSecret     string
-	centralURL    string
-	pulseInterval time.Duration
-	logger        *zap.Logger
+	db                         *database.DB
+	repos                      *repository.Repositories
+	uow                        *repository.UnitOfWork
+	redis                      *redis.Client
+	keys                       *jwtKeys
+	centralURL                 string
+	pulseInterval              time.Duration
+	geo                        GeoLocator
+	termsGraceMode             bool
+	cache                      SessionCache
+	cacheTTL                   time.Duration
+	sessionConfig              SessionConfig
+	loginNotifiers             []LoginAlertFunc
+	sessionLimitNotifiers      []SessionLimitFunc
+	networkPolicyNotifiers     []NetworkPolicyFunc
+	emailVerificationNotifiers []EmailVerificationFunc
+	magicLink                  MagicLinkConfig
+	magicLinkNotifiers         []MagicLinkFunc
+	networkPolicies            *networkPolicyCache
+	loginGate                  LoginGate
+	features                   FeatureSource
+	defaultOrg                 DefaultOrganizationFunc
+	orgTokenRoutes             map[string]rbac.Permission
+	region                     RegionConfig
+	revocationBus              *redis.Client
+	trustedProxies             []*net.IPNet
+	logger                     *zap.Logger
 }
 
-func NewAuthService(db *sqlx.DB, redisClient *redis.Client, jwtSecret, centralURL string, pulseInterval time.Duration, logger *zap.Logger) *AuthService {
+func NewAuthService(db *database.DB, redisClient *redis.Client, jwtSecret, centralURL string, pulseInterval time.Duration, logger *zap.Logger) *AuthService {
 	return &AuthService{
-		db:            db,
-		redis:         redisClient,
-		jwtSecret:     jwtSecret,
-		centralURL:    centralURL,
-		pulseInterval: pulseInterval,
-		logger:        logger,
+		db:              db,
+		repos:           repository.New(db),
+		uow:             repository.NewUnitOfWork(db),
+		redis:           redisClient,
+		keys:            newJWTKeys(jwtSecret),
+		centralURL:      centralURL,
+		pulseInterval:   pulseInterval,
+		cache:           NewRedisSessionCache(redisClient),
+		cacheTTL:        30 * time.Second,
+		sessionConfig:   DefaultSessionConfig(),
+		magicLink:       DefaultMagicLinkConfig(),
+		networkPolicies: newNetworkPolicyCache(time.Minute),
+		region:          RegionConfig{RevocationChannel: DefaultRevocationChannel},
+		logger:          logger,
 	}
 }
 
+// SetSessionCache replaces the session validation cache (e.g. NoopSessionCache in tests)
+func (s *AuthService) SetSessionCache(cache SessionCache, ttl time.Duration) {
+	s.cache = cache
+	s.cacheTTL = ttl
+}
+
+// LoginGate can refuse a login after the credentials are verified; its error
+// is returned from Login unchanged
+type LoginGate func(ctx context.Context, userID string) error
+
+// SetLoginGate installs a check run on every successful credential check
+// (e.g. to hold logins during maintenance)
+func (s *AuthService) SetLoginGate(gate LoginGate) {
+	s.loginGate = gate
+}
+
 // JWT Claims structure
 type Claims struct {
 	UserID   string   `json:"user_id"`
@@ -45,39 +97,21 @@ type Claims struct {
 	Role     string   `json:"role"`
 	Features []string `json:"features"`
 	OrgID    string   `json:"org_id"`
+
+	// Set on impersonation tokens: the platform admin acting as UserID
+	ImpersonatorID  string `json:"impersonator_id,omitempty"`
+	ImpersonationID string `json:"impersonation_id,omitempty"`
+
+	// Region of the gateway that issued the token
+	Region string `json:"region,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // User model
-type User struct {
-	ID              string         `db:"id"`
-	Email           string         `db:"email"`
-	Username        string         `db:"username"`
-	PasswordHash    string         `db:"password_hash"`
-	FullName        sql.NullString `db:"full_name"`
-	OrganizationID  sql.NullString `db:"organization_id"`
-	Role            string         `db:"role"`
-	Features        []byte         `db:"features"`
-	IsActive        bool           `db:"is_active"`
-	TermsAcceptedAt sql.NullTime   `db:"terms_accepted_at"`
-	TermsVersion    sql.NullString `db:"terms_version"`
-	CreatedAt       time.Time      `db:"created_at"`
-	UpdatedAt       time.Time      `db:"updated_at"`
-	LastLoginAt     sql.NullTime   `db:"last_login_at"`
-}
+type User = repository.User
 
 // Session model
-type Session struct {
-	ID             string       `db:"id"`
-	UserID         string       `db:"user_id"`
-	TokenHash      string       `db:"token_hash"`
-	IPAddress      string       `db:"ip_address"`
-	UserAgent      string       `db:"user_agent"`
-	ExpiresAt      time.Time    `db:"expires_at"`
-	RevokedAt      sql.NullTime `db:"revoked_at"`
-	CreatedAt      time.Time    `db:"created_at"`
-	LastActivityAt time.Time    `db:"last_activity_at"`
-}
+type Session = repository.Session
 
 // RegisterRequest payload
 type RegisterRequest struct {
@@ -92,14 +126,18 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// BreakGlassReason lets an organization owner sign in from outside its
+	// IP allowlist; the override is audited and expires
+	BreakGlassReason string `json:"break_glass_reason" binding:"max=500"`
 }
 
 // LoginResponse payload
 type LoginResponse struct {
-	AccessToken  string   `json:"access_token"`
-	RefreshToken string   `json:"refresh_token"`
-	ExpiresIn    int      `json:"expires_in"`
-	User         UserInfo `json:"user"`
+	AccessToken         string   `json:"access_token"`
+	RefreshToken        string   `json:"refresh_token"`
+	ExpiresIn           int      `json:"expires_in"`
+	TermsUpdateRequired bool     `json:"terms_update_required,omitempty"`
+	User                UserInfo `json:"user"`
 }
 
 type UserInfo struct {
@@ -137,28 +175,28 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*User,
 		user.OrganizationID = sql.NullString{String: req.OrganizationID, Valid: true}
 	}
 
-	query := `
-		INSERT INTO users (email, username, password_hash, full_name, organization_id, role, features, is_active)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, created_at, updated_at
-	`
-
-	err = s.db.QueryRowContext(ctx, query,
-		user.Email,
-		user.Username,
-		user.PasswordHash,
-		user.FullName,
-		user.OrganizationID,
-		user.Role,
-		user.Features,
-		user.IsActive,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
-
+	err = s.uow.Do(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Users.Create(ctx, user); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		return events.Enqueue(ctx, repos.Q, user.OrganizationID.String, user.ID, events.UserRegistered{
+			UserID:         user.ID,
+			Email:          user.Email,
+			Username:       user.Username,
+			OrganizationID: user.OrganizationID.String,
+			RegisteredAt:   user.CreatedAt,
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return nil, err
 	}
 
-	s.logger.Info("User registered", zap.String("user_id", user.ID), zap.String("email", user.Email))
+	logging.FromContext(ctx, s.logger).Info("User registered", zap.String("user_id", user.ID), zap.String("email", user.Email))
+
+	// Verifying the address is what lets the user join organizations by domain
+	if _, err := s.sendEmailVerification(ctx, user); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to send email verification", zap.String("user_id", user.ID), zap.Error(err))
+	}
 
 	return user, nil
 }
@@ -166,32 +204,52 @@ func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*User,
 // Login authenticates a user and creates a session
 func (s *AuthService) Login(ctx context.Context, req LoginRequest, ipAddress, userAgent string) (*LoginResponse, error) {
 	// Get user by email
-	var user User
-	err := s.db.GetContext(ctx, &user, "SELECT * FROM users WHERE email = $1 AND is_active = true", req.Email)
+	user, err := s.repos.Users.GetActiveByEmail(ctx, req.Email)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == repository.ErrNotFound {
 			return nil, fmt.Errorf("invalid credentials")
 		}
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
-	// Check if terms have been accepted
-	if !user.TermsAcceptedAt.Valid {
-		return nil, fmt.Errorf("terms of use must be accepted before login")
-	}
-
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 	if err != nil {
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
-	// Parse features
-	var features []string
-	// TODO: Properly unmarshal JSON features
+	return s.startSession(ctx, user, ipAddress, userAgent, req.BreakGlassReason)
+}
+
+// startSession signs an authenticated user in: every login method ends here,
+// so they all pass the same gates and get the same kind of session
+func (s *AuthService) startSession(ctx context.Context, user *User, ipAddress, userAgent, breakGlassReason string) (*LoginResponse, error) {
+	if s.loginGate != nil {
+		if err := s.loginGate(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check if the latest terms have been accepted
+	termsUpdateRequired, err := s.checkTerms(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scope the token to the user's own organization; switch-org moves it
+	orgID := s.homeOrganization(ctx, user)
+
+	// Only addresses on the organization's allowlist, unless an owner breaks glass
+	breakGlass, err := s.checkLoginNetwork(ctx, user, orgID, ipAddress, breakGlassReason)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stored features, the organization's tier and the feature flags that are on for the user
+	features := s.userFeatures(ctx, user, orgID)
 
 	// Generate JWT token
-	token, expiresIn, err := s.GenerateToken(user.ID, user.Email, user.Role, features)
+	token, _, err := s.GenerateToken(user.ID, user.Email, user.Role, orgID, features)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -199,40 +257,54 @@ func (s *AuthService) Login(ctx context.Context, req LoginRequest, ipAddress, us
 	// Hash token for storage
 	tokenHash := hashToken(token)
 
-	// Create session
-	sessionID := uuid.New().String()
-	expiresAt := time.Now().Add(time.Duration(expiresIn) * time.Second)
-
-	_, err = s.db.ExecContext(ctx, `
-		INSERT INTO sessions (id, user_id, token_hash, ip_address, user_agent, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, sessionID, user.ID, tokenHash, ipAddress, userAgent, expiresAt)
+	// Stay within the concurrent session limit for the user's role
+	if err := s.enforceSessionLimit(ctx, user, orgID, ipAddress, userAgent); err != nil {
+		return nil, err
+	}
 
+	// Create session: it slides while in use, up to the maximum lifetime
+	sessionID := uuid.New().String()
+	now := time.Now()
+	expiresIn := int(s.sessionConfig.IdleTimeout.Seconds())
+
+	err = s.repos.Sessions.Create(ctx, &Session{
+		ID:                sessionID,
+		UserID:            user.ID,
+		TokenHash:         tokenHash,
+		IPAddress:         ipAddress,
+		UserAgent:         userAgent,
+		ExpiresAt:         now.Add(s.sessionConfig.IdleTimeout),
+		AbsoluteExpiresAt: now.Add(s.sessionConfig.MaxLifetime),
+		Region:            s.region.Region,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
+	if breakGlass {
+		if err := s.startBreakGlass(ctx, user, sessionID, orgID, ipAddress, breakGlassReason); err != nil {
+			return nil, err
+		}
+	}
+
+	// Alert the user about sign-ins from unfamiliar devices or countries
+	s.checkLoginDevice(ctx, user, sessionID, ipAddress, userAgent)
 
 	// Update last login
-	_, err = s.db.ExecContext(ctx, "UPDATE users SET last_login_at = NOW() WHERE id = $1", user.ID)
-	if err != nil {
-		s.logger.Error("Failed to update last login", zap.Error(err))
+	if err := s.repos.Users.TouchLastLogin(ctx, user.ID); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to update last login", zap.Error(err))
 	}
 
-	s.logger.Info("User logged in",
+	logging.FromContext(ctx, s.logger).Info("User logged in",
 		zap.String("user_id", user.ID),
 		zap.String("email", user.Email),
 		zap.String("ip_address", ipAddress),
 	)
 
-	orgID := ""
-	if user.OrganizationID.Valid {
-		orgID = user.OrganizationID.String
-	}
-
 	return &LoginResponse{
-		AccessToken:  token,
-		RefreshToken: "", // TODO: Implement refresh token
-		ExpiresIn:    expiresIn,
+		AccessToken:         token,
+		RefreshToken:        "", // TODO: Implement refresh token
+		ExpiresIn:           expiresIn,
+		TermsUpdateRequired: termsUpdateRequired,
 		User: UserInfo{
 			ID:             user.ID,
 			Email:          user.Email,
@@ -244,24 +316,27 @@ func (s *AuthService) Login(ctx context.Context, req LoginRequest, ipAddress, us
 	}, nil
 }
 
-// GenerateToken creates a JWT token
-func (s *AuthService) GenerateToken(userID, email, role string, features []string) (string, int, error) {
-	expiresIn := 3600 // 1 hour
+// GenerateToken creates a JWT token. It is valid for the maximum session
+// lifetime; its session's sliding expiry ends it sooner when unused.
+func (s *AuthService) GenerateToken(userID, email, role, orgID string, features []string) (string, int, error) {
+	expiresIn := int(s.sessionConfig.MaxLifetime.Seconds())
 
 	claims := &Claims{
 		UserID:   userID,
 		Email:    email,
 		Role:     role,
 		Features: features,
-		RegisteredClaims: jwt.RegisteredClaims{
+		OrgID:    orgID,
+		Region:   s.region.Region,
+		RegisteredClaims: s.registeredClaims(jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiresIn) * time.Second)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ID:        uuid.New().String(),
-		},
+		}),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(s.jwtSecret))
+	signedToken, err := token.SignedString(s.keys.signing())
 	if err != nil {
 		return "", 0, err
 	}
@@ -271,18 +346,16 @@ func (s *AuthService) GenerateToken(userID, email, role string, features []strin
 
 // ValidateToken validates a JWT token
 func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.jwtSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.verificationKeys(nil))
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if !s.audienceAccepted(claims) {
+			return nil, jwt.ErrTokenInvalidAudience
+		}
 		return claims, nil
 	}
 
@@ -304,6 +377,11 @@ func (s *AuthService) AuthMiddleware() gin.HandlerFunc {
 			tokenString = tokenString[7:]
 		}
 
+		if strings.HasPrefix(tokenString, OrgTokenPrefix) {
+			s.authenticateOrgToken(c, tokenString)
+			return
+		}
+
 		claims, err := s.ValidateToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
@@ -311,28 +389,55 @@ func (s *AuthService) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens whose session was revoked (logout, device removal)
+		sessionID, err := s.lookupSession(c.Request.Context(), hashToken(tokenString))
+		if err == ErrSessionNotFound {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session revoked or expired"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			logging.FromContext(c.Request.Context(), s.logger).Error("Failed to look up session", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate session"})
+			c.Abort()
+			return
+		}
+
+		s.recordActivity(c.Request.Context(), sessionID)
+
 		// Set user info in context
+		c.Set("session_id", sessionID)
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("user_role", claims.Role) // Legacy role field
 		c.Set("features", claims.Features)
+		if claims.ImpersonatorID != "" {
+			c.Set("impersonator_id", claims.ImpersonatorID)
+			c.Set("impersonation_id", claims.ImpersonationID)
+		}
 
 		// If organization is in token, fetch user's role in that organization
 		if claims.OrgID != "" {
-			var orgRole string
-			err := s.db.Get(&orgRole, `
-				SELECT role FROM organization_memberships 
-				WHERE user_id = $1 AND organization_id = $2
-			`, claims.UserID, claims.OrgID)
-
+			c.Set("token_org_id", claims.OrgID)
+			orgRole, err := s.repos.Orgs.MemberRole(c.Request.Context(), claims.UserID, claims.OrgID)
 			if err == nil {
 				c.Set("user_role", orgRole) // Override with org-specific role
 				c.Set("organization_id", claims.OrgID)
-			} else if err != sql.ErrNoRows {
-				s.logger.Error("Failed to fetch org role", zap.Error(err))
+			} else if err == repository.ErrNotFound {
+				// Removed from the organization the token is scoped to
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "organization membership revoked"})
+				c.Abort()
+				return
+			} else {
+				logging.FromContext(c.Request.Context(), s.logger).Error("Failed to fetch org role", zap.Error(err))
 			}
 		}
 
+		// The organizations' IP allowlists: the token's and the one in the path
+		if !s.enforceNetworkPolicy(c, claims.UserID, sessionID, claims.OrgID, pathOrganization(c)) {
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -342,47 +447,63 @@ func (s *AuthService) StartPulseCheck(ctx context.Context) {
 	ticker := time.NewTicker(s.pulseInterval)
 	defer ticker.Stop()
 
-	s.logger.Info("Starting authorization pulse checker", zap.Duration("interval", s.pulseInterval))
+	logging.FromContext(ctx, s.logger).Info("Starting authorization pulse checker", zap.Duration("interval", s.pulseInterval))
 
 	for {
 		select {
 		case <-ticker.C:
 			s.performPulseCheck(ctx)
 		case <-ctx.Done():
-			s.logger.Info("Stopping authorization pulse checker")
+			logging.FromContext(ctx, s.logger).Info("Stopping authorization pulse checker")
 			return
 		}
 	}
 }
 
+// performPulseCheck evaluates every active session the way a client's pulse
+// is evaluated, recording the outcome of each. Sessions whose user, account
+// or entitlements no longer allow them are recorded as such, and dropped
+// from the middleware's cache.
 func (s *AuthService) performPulseCheck(ctx context.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.PulseCheckDuration.Observe(time.Since(start).Seconds())
+	}()
+
 	// Get all active sessions
 	var sessions []Session
 	err := s.db.SelectContext(ctx, &sessions, `
-		SELECT * FROM sessions 
+		SELECT `+repository.SessionColumns+` FROM sessions
 		WHERE revoked_at IS NULL AND expires_at > NOW()
 	`)
 
 	if err != nil {
-		s.logger.Error("Failed to get active sessions", zap.Error(err))
+		logging.FromContext(ctx, s.logger).Error("Failed to get active sessions", zap.Error(err))
 		return
 	}
 
-	for _, session := range sessions {
-		// TODO: Check with central authorization server
-		// For now, just log the pulse check
-
-		_, err = s.db.ExecContext(ctx, `
-			INSERT INTO authorization_pulses (session_id, user_id, status, checked_at, next_check_at)
-			VALUES ($1, $2, $3, NOW(), NOW() + INTERVAL '5 minutes')
-		`, session.ID, session.UserID, "authorized")
+	metrics.ActiveSessions.Set(float64(len(sessions)))
 
+	denied := 0
+	for _, session := range sessions {
+		if ctx.Err() != nil {
+			return
+		}
+		result, err := s.Pulse(ctx, session.ID, session.UserID, "")
 		if err != nil {
-			s.logger.Error("Failed to log pulse check", zap.Error(err))
+			logging.FromContext(ctx, s.logger).Error("Failed to check session authorization",
+				zap.String("session_id", session.ID), zap.Error(err))
+			continue
+		}
+		if !result.Authorized {
+			denied++
 		}
 	}
 
-	s.logger.Debug("Pulse check completed", zap.Int("sessions_checked", len(sessions)))
+	logging.FromContext(ctx, s.logger).Debug("Pulse check completed",
+		zap.Int("sessions_checked", len(sessions)),
+		zap.Int("sessions_denied", denied),
+	)
 }
 
 // Helper function to hash tokens
@@ -0,0 +1,224 @@
+//go:build integration
+
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/testenv"
+	"github.com/gin-gonic/gin"
+)
+
+// whoami serves a route behind AuthMiddleware that echoes what it set
+func whoami(env *testenv.Env, token string) (int, map[string]string) {
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return serveMe(env, req)
+}
+
+func serveMe(env *testenv.Env, req *http.Request) (int, map[string]string) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/me", env.Auth.AuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"session_id":      c.GetString("session_id"),
+			"user_id":         c.GetString("user_id"),
+			"organization_id": c.GetString("organization_id"),
+			"user_role":       c.GetString("user_role"),
+		})
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var body map[string]string
+	if rec.Code == http.StatusOK {
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	}
+	return rec.Code, body
+}
+
+// sessionExpiry is when a session expires
+func sessionExpiry(t *testing.T, env *testenv.Env, sessionID string) time.Time {
+	t.Helper()
+	var expiresAt time.Time
+	if err := env.DB.GetContext(context.Background(), &expiresAt, `SELECT expires_at FROM sessions WHERE id = $1`, sessionID); err != nil {
+		t.Fatal(err)
+	}
+	return expiresAt
+}
+
+func TestLogin(t *testing.T) {
+	env := testenv.Setup(t)
+	org := env.CreateOrganization(t, "")
+	user := env.CreateUser(t, testenv.UserOptions{OrgID: org.ID})
+	env.AddMember(t, user.ID, org.ID, rbac.RoleAdmin)
+
+	resp := env.Login(t, user)
+	if resp.User.ID != user.ID || resp.User.OrganizationID != org.ID {
+		t.Errorf("logged in as %+v, want %s in %s", resp.User, user.ID, org.ID)
+	}
+	if want := int(auth.DefaultSessionConfig().IdleTimeout.Seconds()); resp.ExpiresIn != want {
+		t.Errorf("expires_in = %d, want the idle timeout %d", resp.ExpiresIn, want)
+	}
+	claims := env.Claims(t, resp.AccessToken)
+	if claims.UserID != user.ID || claims.OrgID != org.ID {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	status, who := whoami(env, resp.AccessToken)
+	if status != http.StatusOK {
+		t.Fatalf("authenticated request: status %d", status)
+	}
+	if who["user_id"] != user.ID || who["organization_id"] != org.ID || who["user_role"] != string(rbac.RoleAdmin) {
+		t.Errorf("context = %v, want the organization role", who)
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	env := testenv.Setup(t)
+	user := env.CreateUser(t, testenv.UserOptions{})
+
+	for _, req := range []auth.LoginRequest{
+		{Email: user.Email, Password: "wrong-password"},
+		{Email: "nobody@example.com", Password: user.Password},
+	} {
+		if _, err := env.Auth.Login(context.Background(), req, "127.0.0.1", "testenv"); err == nil {
+			t.Errorf("Login(%s, %s) succeeded", req.Email, req.Password)
+		}
+	}
+	var sessions int
+	if err := env.DB.GetContext(context.Background(), &sessions, `SELECT COUNT(*) FROM sessions`); err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 0 {
+		t.Errorf("failed logins created %d sessions", sessions)
+	}
+
+	for _, token := range []string{"", "not-a-jwt"} {
+		if status, _ := whoami(env, token); status != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, status)
+		}
+	}
+}
+
+func TestSessionRefresh(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	user := env.CreateUser(t, testenv.UserOptions{})
+	resp := env.Login(t, user)
+
+	_, who := whoami(env, resp.AccessToken)
+	sessionID := who["session_id"]
+	if sessionID == "" {
+		t.Fatal("no session in context")
+	}
+
+	// A session used close to its expiry slides by the idle timeout. Only
+	// cache misses extend sessions, so the cache is emptied first.
+	if _, err := env.DB.ExecContext(ctx, `UPDATE sessions SET expires_at = NOW() + INTERVAL '5 minutes' WHERE id = $1`, sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Redis.FlushDB(ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := whoami(env, resp.AccessToken); status != http.StatusOK {
+		t.Fatalf("refresh request: status %d", status)
+	}
+	if left := time.Until(sessionExpiry(t, env, sessionID)); left < 50*time.Minute {
+		t.Errorf("session expires in %s after use, want it extended to about an hour", left)
+	}
+
+	// Never past the absolute expiry
+	_, err := env.DB.ExecContext(ctx, `
+		UPDATE sessions SET expires_at = NOW() + INTERVAL '5 minutes', absolute_expires_at = NOW() + INTERVAL '6 minutes'
+		WHERE id = $1
+	`, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Redis.FlushDB(ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := whoami(env, resp.AccessToken); status != http.StatusOK {
+		t.Fatalf("capped refresh request: status %d", status)
+	}
+	if left := time.Until(sessionExpiry(t, env, sessionID)); left > 7*time.Minute {
+		t.Errorf("session expires in %s, past its absolute expiry", left)
+	}
+
+	// Expired sessions are refused although the token itself is valid
+	if _, err := env.DB.ExecContext(ctx, `UPDATE sessions SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Redis.FlushDB(ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := whoami(env, resp.AccessToken); status != http.StatusUnauthorized {
+		t.Errorf("expired session: status %d, want 401", status)
+	}
+}
+
+func TestSwitchOrganizationRotatesToken(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	home, other, foreign := env.CreateOrganization(t, ""), env.CreateOrganization(t, ""), env.CreateOrganization(t, "")
+	user := env.CreateUser(t, testenv.UserOptions{OrgID: home.ID})
+	env.AddMember(t, user.ID, home.ID, rbac.RoleOwner)
+	env.AddMember(t, user.ID, other.ID, rbac.RoleViewer)
+
+	resp := env.Login(t, user)
+	_, who := whoami(env, resp.AccessToken)
+	sessionID := who["session_id"]
+
+	switched, err := env.Auth.SwitchOrganization(ctx, user.ID, sessionID, other.ID)
+	if err != nil {
+		t.Fatalf("SwitchOrganization: %v", err)
+	}
+	if switched.Organization.Role != string(rbac.RoleViewer) {
+		t.Errorf("role = %s, want viewer", switched.Organization.Role)
+	}
+
+	if status, _ := whoami(env, resp.AccessToken); status != http.StatusUnauthorized {
+		t.Errorf("previous token: status %d, want 401", status)
+	}
+	status, who := whoami(env, switched.AccessToken)
+	if status != http.StatusOK {
+		t.Fatalf("switched token: status %d", status)
+	}
+	if who["session_id"] != sessionID || who["organization_id"] != other.ID || who["user_role"] != string(rbac.RoleViewer) {
+		t.Errorf("context = %v, want the same session scoped to %s", who, other.ID)
+	}
+
+	if _, err := env.Auth.SwitchOrganization(ctx, user.ID, sessionID, foreign.ID); !errors.Is(err, auth.ErrNotOrgMember) {
+		t.Errorf("switch to a foreign organization = %v, want ErrNotOrgMember", err)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	env := testenv.Setup(t)
+	user := env.CreateUser(t, testenv.UserOptions{})
+	resp := env.Login(t, user)
+
+	// The session is now cached; revoking must evict it
+	_, who := whoami(env, resp.AccessToken)
+	if err := env.Auth.Logout(context.Background(), user.ID, who["session_id"]); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if status, _ := whoami(env, resp.AccessToken); status != http.StatusUnauthorized {
+		t.Errorf("after logout: status %d, want 401", status)
+	}
+	if err := env.Auth.Logout(context.Background(), user.ID, who["session_id"]); !errors.Is(err, auth.ErrSessionNotFound) {
+		t.Errorf("second Logout = %v, want ErrSessionNotFound", err)
+	}
+}
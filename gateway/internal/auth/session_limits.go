@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// What a login over the session limit does
+const (
+	SessionLimitReject       = "reject"        // Refuse the login
+	SessionLimitRevokeOldest = "revoke_oldest" // Sign the oldest sessions out to make room
+)
+
+// AnyRole is the policy role matching roles without a policy of their own
+const AnyRole = "*"
+
+// ErrSessionLimit is returned by Login when the user is at their concurrent
+// session limit and the policy rejects new logins
+var ErrSessionLimit = errors.New("concurrent session limit reached")
+
+// ErrSessionLimitPolicyNotFound is returned when deleting a policy that does not exist
+var ErrSessionLimitPolicyNotFound = errors.New("session limit policy not found")
+
+// SessionLimit is the concurrent session limit that applies to a login
+type SessionLimit struct {
+	MaxSessions int    `json:"max_sessions" db:"max_sessions"` // 0: unlimited
+	OnLimit     string `json:"on_limit" db:"on_limit"`
+}
+
+// SessionLimitPolicy overrides the default limit for a role of an organization
+type SessionLimitPolicy struct {
+	OrganizationID string    `json:"organization_id" db:"organization_id"`
+	Role           string    `json:"role" db:"role"`
+	MaxSessions    int       `json:"max_sessions" db:"max_sessions"`
+	OnLimit        string    `json:"on_limit" db:"on_limit"`
+	UpdatedBy      *string   `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SessionLimitAlert tells a user their session limit was hit
+type SessionLimitAlert struct {
+	UserID      string     `json:"user_id"`
+	Email       string     `json:"email"`
+	MaxSessions int        `json:"max_sessions"`
+	Action      string     `json:"action"`              // reject or revoke_oldest
+	RevokedIDs  []string   `json:"revoked_session_ids"` // Sessions signed out, oldest first
+	IPAddress   string     `json:"ip_address"`          // Of the new login
+	Device      DeviceInfo `json:"device"`              // Of the new login
+	OccurredAt  time.Time  `json:"occurred_at"`
+}
+
+// SessionLimitFunc delivers a session limit alert
+type SessionLimitFunc func(alert SessionLimitAlert)
+
+// AddSessionLimitNotifier registers a delivery channel for session limit alerts
+func (s *AuthService) AddSessionLimitNotifier(fn SessionLimitFunc) {
+	s.sessionLimitNotifiers = append(s.sessionLimitNotifiers, fn)
+}
+
+// ValidSessionLimitAction reports whether action is a known on-limit action
+func ValidSessionLimitAction(action string) bool {
+	return action == SessionLimitReject || action == SessionLimitRevokeOldest
+}
+
+// DefaultSessionLimit is the limit for a role when its organization sets none
+func (c SessionConfig) DefaultSessionLimit(role string) SessionLimit {
+	limit := SessionLimit{MaxSessions: c.MaxSessions, OnLimit: c.OnSessionLimit}
+	if max, ok := c.MaxSessionsByRole[role]; ok {
+		limit.MaxSessions = max
+	}
+	return limit
+}
+
+// SessionLimitDefaults are the limits that apply where an organization sets none
+type SessionLimitDefaults struct {
+	SessionLimit
+	ByRole map[string]int `json:"by_role"`
+}
+
+// SessionLimitDefaults returns the deployment's session limits
+func (s *AuthService) SessionLimitDefaults() SessionLimitDefaults {
+	byRole := s.sessionConfig.MaxSessionsByRole
+	if byRole == nil {
+		byRole = map[string]int{}
+	}
+	return SessionLimitDefaults{
+		SessionLimit: SessionLimit{MaxSessions: s.sessionConfig.MaxSessions, OnLimit: s.sessionConfig.OnSessionLimit},
+		ByRole:       byRole,
+	}
+}
+
+// sessionLimit resolves the limit for a role: the organization's policy for
+// the role, then its policy for any role, then the defaults
+func (s *AuthService) sessionLimit(ctx context.Context, orgID, role string) SessionLimit {
+	if orgID != "" {
+		var limit SessionLimit
+		err := s.db.GetContext(ctx, &limit, `
+			SELECT max_sessions, on_limit FROM session_limit_policies
+			WHERE organization_id = $1 AND role IN ($2, '*')
+			ORDER BY role = '*'
+			LIMIT 1
+		`, orgID, role)
+		if err == nil {
+			return limit
+		}
+		if err != sql.ErrNoRows {
+			logging.FromContext(ctx, s.logger).Error("Failed to load session limit policy", zap.String("organization_id", orgID), zap.Error(err))
+		}
+	}
+	return s.sessionConfig.DefaultSessionLimit(role)
+}
+
+// enforceSessionLimit makes room for a new session of the user, or refuses
+// it, per the limit for their role in orgID (their global role without one).
+// Impersonation sessions neither count nor get revoked.
+func (s *AuthService) enforceSessionLimit(ctx context.Context, user *User, orgID, ipAddress, userAgent string) error {
+	role := user.Role
+	if orgID != "" {
+		if orgRole, err := s.repos.Orgs.MemberRole(ctx, user.ID, orgID); err == nil {
+			role = orgRole
+		}
+	}
+	limit := s.sessionLimit(ctx, orgID, role)
+	if limit.MaxSessions <= 0 {
+		return nil
+	}
+
+	var sessionIDs []string
+	err := s.db.SelectContext(ctx, &sessionIDs, `
+		SELECT s.id FROM sessions s
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL AND s.expires_at > NOW()
+		AND NOT EXISTS (SELECT 1 FROM impersonation_sessions i WHERE i.session_id = s.id)
+		ORDER BY s.created_at
+	`, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count sessions: %w", err)
+	}
+	excess := len(sessionIDs) - limit.MaxSessions + 1
+	if excess <= 0 {
+		return nil
+	}
+
+	metrics.SessionLimitHits.WithLabelValues(limit.OnLimit).Inc()
+	alert := SessionLimitAlert{
+		UserID:      user.ID,
+		Email:       user.Email,
+		MaxSessions: limit.MaxSessions,
+		Action:      limit.OnLimit,
+		RevokedIDs:  []string{},
+		IPAddress:   ipAddress,
+		Device:      ParseUserAgent(userAgent),
+		OccurredAt:  time.Now().UTC(),
+	}
+
+	if limit.OnLimit == SessionLimitReject {
+		s.notifySessionLimit(alert)
+		return ErrSessionLimit
+	}
+
+	oldest := sessionIDs[:excess]
+	var tokenHashes []string
+	err = s.db.SelectContext(ctx, &tokenHashes, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = ANY($1::uuid[]) AND revoked_at IS NULL
+		RETURNING token_hash
+	`, pq.StringArray(oldest))
+	if err != nil {
+		return fmt.Errorf("failed to revoke oldest sessions: %w", err)
+	}
+	s.invalidateSessions(ctx, tokenHashes...)
+
+	logging.FromContext(ctx, s.logger).Info("Session limit reached, revoked oldest sessions",
+		zap.String("user_id", user.ID),
+		zap.Int("max_sessions", limit.MaxSessions),
+		zap.Strings("revoked_session_ids", oldest),
+	)
+
+	alert.RevokedIDs = oldest
+	s.notifySessionLimit(alert)
+	return nil
+}
+
+func (s *AuthService) notifySessionLimit(alert SessionLimitAlert) {
+	// Delivery (SMTP in particular) must not hold up the login response
+	go func() {
+		for _, notify := range s.sessionLimitNotifiers {
+			notify(alert)
+		}
+	}()
+}
+
+// sessionLimitPolicyColumns selects every SessionLimitPolicy field
+const sessionLimitPolicyColumns = `organization_id, role, max_sessions, on_limit, updated_by, updated_at`
+
+// ListSessionLimitPolicies returns the organization's policies by role
+func (s *AuthService) ListSessionLimitPolicies(ctx context.Context, orgID string) ([]SessionLimitPolicy, error) {
+	policies := []SessionLimitPolicy{}
+	err := s.db.SelectContext(ctx, &policies, `
+		SELECT `+sessionLimitPolicyColumns+` FROM session_limit_policies
+		WHERE organization_id = $1
+		ORDER BY role
+	`, orgID)
+	return policies, err
+}
+
+// SetSessionLimitPolicy creates or replaces the organization's policy for a role
+func (s *AuthService) SetSessionLimitPolicy(ctx context.Context, orgID, role, userID string, limit SessionLimit) (*SessionLimitPolicy, error) {
+	var policy SessionLimitPolicy
+	err := s.db.GetContext(ctx, &policy, `
+		INSERT INTO session_limit_policies (organization_id, role, max_sessions, on_limit, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (organization_id, role) DO UPDATE SET
+			max_sessions = EXCLUDED.max_sessions,
+			on_limit = EXCLUDED.on_limit,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING `+sessionLimitPolicyColumns+`
+	`, orgID, role, limit.MaxSessions, limit.OnLimit, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// DeleteSessionLimitPolicy removes the organization's policy for a role, so
+// the role falls back to the organization's "*" policy or the defaults
+func (s *AuthService) DeleteSessionLimitPolicy(ctx context.Context, orgID, role string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM session_limit_policies WHERE organization_id = $1 AND role = $2
+	`, orgID, role)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrSessionLimitPolicyNotFound
+	}
+	return nil
+}
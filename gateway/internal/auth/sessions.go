@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ErrSessionNotFound is returned when a session does not exist or belongs to another user
+var ErrSessionNotFound = errors.New("session not found")
+
+// GeoInfo is the approximate location of an IP address
+type GeoInfo struct {
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// GeoLocator resolves IP addresses to locations (e.g. a MaxMind database)
+type GeoLocator interface {
+	Locate(ipAddress string) (*GeoInfo, error)
+}
+
+// SetGeoLocator configures IP geolocation for session listings
+func (s *AuthService) SetGeoLocator(locator GeoLocator) {
+	s.geo = locator
+}
+
+// SessionInfo describes an active session for display to its owner
+type SessionInfo struct {
+	ID             string     `json:"id"`
+	IPAddress      string     `json:"ip_address"`
+	UserAgent      string     `json:"user_agent"`
+	Device         DeviceInfo `json:"device"`
+	Geo            *GeoInfo   `json:"geo,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastActivityAt time.Time  `json:"last_activity_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	Region         string     `json:"region,omitempty"` // Region that issued the session
+	Current        bool       `json:"current"`
+}
+
+// ListSessions returns the user's active sessions, most recently used first
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]SessionInfo, error) {
+	sessions, err := s.repos.Sessions.ListActive(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	infos := make([]SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		info := SessionInfo{
+			ID:             session.ID,
+			IPAddress:      session.IPAddress,
+			UserAgent:      session.UserAgent,
+			Device:         ParseUserAgent(session.UserAgent),
+			CreatedAt:      session.CreatedAt,
+			LastActivityAt: session.LastActivityAt,
+			ExpiresAt:      session.ExpiresAt,
+			Region:         session.Region,
+			Current:        session.ID == currentSessionID,
+		}
+
+		if s.geo != nil {
+			geo, err := s.geo.Locate(session.IPAddress)
+			if err != nil {
+				logging.FromContext(ctx, s.logger).Debug("Failed to geolocate session IP", zap.Error(err))
+			} else {
+				info.Geo = geo
+			}
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// RevokeSession revokes one of the user's sessions
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	tokenHash, err := s.repos.Sessions.Revoke(ctx, userID, sessionID)
+	if err == repository.ErrNotFound {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	s.invalidateSessions(ctx, tokenHash)
+
+	logging.FromContext(ctx, s.logger).Info("Session revoked", zap.String("user_id", userID), zap.String("session_id", sessionID))
+	return nil
+}
+
+// Logout revokes the session the caller authenticated with
+func (s *AuthService) Logout(ctx context.Context, userID, sessionID string) error {
+	return s.RevokeSession(ctx, userID, sessionID)
+}
+
+// invalidateSessions evicts revoked sessions from the validation cache, here
+// and in the other regions
+func (s *AuthService) invalidateSessions(ctx context.Context, tokenHashes ...string) {
+	if err := s.cache.Delete(ctx, tokenHashes...); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to invalidate session cache", zap.Error(err))
+	}
+	s.publishRevocation(ctx, tokenHashes)
+}
+
+// IntrospectToken validates a token and its session, returning claims and session ID
+func (s *AuthService) IntrospectToken(ctx context.Context, token string) (*Claims, string, error) {
+	claims, err := s.ValidateToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sessionID, err := s.lookupSession(ctx, hashToken(token))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return claims, sessionID, nil
+}
+
+// lookupSession returns the ID of the active session for a token hash,
+// consulting the validation cache before the database
+func (s *AuthService) lookupSession(ctx context.Context, tokenHash string) (string, error) {
+	sessionID, found, err := s.cache.Get(ctx, tokenHash)
+	switch {
+	case err != nil:
+		metrics.SessionCacheRequests.WithLabelValues("error").Inc()
+		logging.FromContext(ctx, s.logger).Warn("Session cache lookup failed", zap.Error(err))
+	case found:
+		metrics.SessionCacheRequests.WithLabelValues("hit").Inc()
+		return sessionID, nil
+	default:
+		metrics.SessionCacheRequests.WithLabelValues("miss").Inc()
+	}
+
+	session, err := s.repos.Sessions.FindActiveByTokenHash(ctx, tokenHash)
+	if err == repository.ErrNotFound {
+		return "", ErrSessionNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Slide sessions in use close to their expiry, then never cache beyond it
+	expiresAt := s.extendSession(ctx, session)
+	ttl := s.cacheTTL
+	if remaining := time.Until(expiresAt); remaining < ttl {
+		ttl = remaining
+	}
+	if ttl > 0 {
+		if err := s.cache.Set(ctx, tokenHash, session.ID, ttl); err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to cache session", zap.Error(err))
+		}
+	}
+
+	return session.ID, nil
+}
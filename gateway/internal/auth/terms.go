@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNoTermsPublished is returned when no terms version exists yet
+	ErrNoTermsPublished = errors.New("no terms of use published")
+	// ErrTermsNotAccepted is returned by Login when the latest terms are not accepted
+	ErrTermsNotAccepted = errors.New("terms of use must be accepted before login")
+	// ErrTermsVersionMismatch is returned when accepting a version other than the current one
+	ErrTermsVersionMismatch = errors.New("terms version is not the current version")
+)
+
+// TermsDocument is a published version of the terms of use
+type TermsDocument struct {
+	ID          string         `json:"id" db:"id"`
+	Version     string         `json:"version" db:"version"`
+	Title       string         `json:"title" db:"title"`
+	Content     string         `json:"content" db:"content"`
+	DocumentURL sql.NullString `json:"-" db:"document_url"`
+	EffectiveAt time.Time      `json:"effective_at" db:"effective_at"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
+}
+
+// TermsAcceptance records a user's acceptance of a terms version
+type TermsAcceptance struct {
+	UserID       string    `json:"user_id"`
+	TermsVersion string    `json:"terms_version"`
+	AcceptedAt   time.Time `json:"accepted_at"`
+}
+
+// SetTermsGraceMode lets users who accepted an older version keep logging in
+// (flagged with terms_update_required) instead of being blocked
+func (s *AuthService) SetTermsGraceMode(enabled bool) {
+	s.termsGraceMode = enabled
+}
+
+// CurrentTerms returns the latest effective terms version
+func (s *AuthService) CurrentTerms(ctx context.Context) (*TermsDocument, error) {
+	var doc TermsDocument
+	err := s.db.GetContext(ctx, &doc, `
+		SELECT id, version, title, content, document_url, effective_at, created_at
+		FROM terms_versions
+		WHERE effective_at <= NOW()
+		ORDER BY effective_at DESC
+		LIMIT 1
+	`)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoTermsPublished
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current terms: %w", err)
+	}
+	return &doc, nil
+}
+
+// AcceptTerms records acceptance of the current terms version for a user
+func (s *AuthService) AcceptTerms(ctx context.Context, userID, version, ipAddress, userAgent string) (*TermsAcceptance, error) {
+	current, err := s.CurrentTerms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != version {
+		return nil, ErrTermsVersionMismatch
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	acceptance := &TermsAcceptance{UserID: userID, TermsVersion: version}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO terms_acceptances (user_id, terms_version, ip_address, user_agent)
+		VALUES ($1, $2, NULLIF($3, '')::INET, NULLIF($4, ''))
+		ON CONFLICT (user_id, terms_version) DO UPDATE SET accepted_at = terms_acceptances.accepted_at
+		RETURNING accepted_at
+	`, userID, version, ipAddress, userAgent).Scan(&acceptance.AcceptedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record terms acceptance: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users SET terms_accepted_at = $1, terms_version = $2
+		WHERE id = $3
+	`, acceptance.AcceptedAt, version, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user terms: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit terms acceptance: %w", err)
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Terms accepted",
+		zap.String("user_id", userID),
+		zap.String("terms_version", version),
+		zap.String("ip_address", ipAddress),
+	)
+
+	return acceptance, nil
+}
+
+// checkTerms reports whether the user may log in and whether they must re-accept
+func (s *AuthService) checkTerms(ctx context.Context, user *User) (updateRequired bool, err error) {
+	current, err := s.CurrentTerms(ctx)
+	if err == ErrNoTermsPublished {
+		// Nothing versioned yet; fall back to the legacy acceptance flag
+		if !user.TermsAcceptedAt.Valid {
+			return false, ErrTermsNotAccepted
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if user.TermsVersion.Valid && user.TermsVersion.String == current.Version {
+		return false, nil
+	}
+
+	// Users who never accepted any version are always blocked
+	if s.termsGraceMode && user.TermsAcceptedAt.Valid {
+		return true, nil
+	}
+
+	return false, ErrTermsNotAccepted
+}
@@ -0,0 +1,74 @@
+package auth
+
+import "strings"
+
+// DeviceInfo is a coarse description of the client behind a User-Agent
+type DeviceInfo struct {
+	Device  string `json:"device"`
+	OS      string `json:"os"`
+	Browser string `json:"browser"`
+}
+
+// uaMatch maps a User-Agent substring to a display name; order matters
+type uaMatch struct {
+	token string
+	name  string
+}
+
+var browserMatches = []uaMatch{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"python-requests", "Python"},
+	{"Go-http-client", "Go"},
+	{"PostmanRuntime", "Postman"},
+}
+
+var osMatches = []uaMatch{
+	{"Windows", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// ParseUserAgent extracts device, OS and browser from a User-Agent header
+func ParseUserAgent(userAgent string) DeviceInfo {
+	info := DeviceInfo{Device: "unknown", OS: "unknown", Browser: "unknown"}
+	if userAgent == "" {
+		return info
+	}
+
+	for _, m := range browserMatches {
+		if strings.Contains(userAgent, m.token) {
+			info.Browser = m.name
+			break
+		}
+	}
+
+	for _, m := range osMatches {
+		if strings.Contains(userAgent, m.token) {
+			info.OS = m.name
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet"):
+		info.Device = "tablet"
+	case strings.Contains(userAgent, "Mobile") || strings.Contains(userAgent, "iPhone"):
+		info.Device = "mobile"
+	case info.OS != "unknown":
+		info.Device = "desktop"
+	case info.Browser != "unknown":
+		info.Device = "api_client"
+	}
+
+	return info
+}
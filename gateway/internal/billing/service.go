@@ -0,0 +1,158 @@
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Config tunes metering and export
+type Config struct {
+	// MeterInterval is how often storage is measured. Measurements share an
+	// ID per organization and day, so only the day's first one is kept.
+	MeterInterval time.Duration
+	// ExportInterval is how often unexported events are sent to Stripe
+	ExportInterval  time.Duration
+	ExportBatchSize int
+	// MaxExportAttempts stops retrying events Stripe keeps rejecting (it
+	// refuses meter events older than 35 days, for one)
+	MaxExportAttempts int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MeterInterval:     time.Hour,
+		ExportInterval:    5 * time.Minute,
+		ExportBatchSize:   100,
+		MaxExportAttempts: 10,
+	}
+}
+
+// MonthlyUsage is an organization's usage in a calendar month (UTC)
+type MonthlyUsage struct {
+	Month            string `json:"month" db:"month"` // YYYY-MM
+	ScansStarted     int64  `json:"scans_started" db:"scans_started"`
+	ReportsGenerated int64  `json:"reports_generated" db:"reports_generated"`
+	// PeakStorageBytes is the month's largest daily storage measurement
+	PeakStorageBytes int64 `json:"peak_storage_bytes" db:"peak_storage_bytes"`
+	// StorageByteDays sums the daily measurements, for per-day pricing
+	StorageByteDays int64 `json:"storage_byte_days" db:"storage_byte_days"`
+}
+
+// Service meters storage, aggregates usage and exports the ledger
+type Service struct {
+	db       *database.DB
+	exporter *StripeExporter // nil disables export
+	config   Config
+	logger   *zap.Logger
+}
+
+func NewService(db *database.DB, exporter *StripeExporter, config Config, logger *zap.Logger) *Service {
+	return &Service{
+		db:       db,
+		exporter: exporter,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// Usage returns the organization's usage for the current month and the
+// months before it, newest first. Months without events are omitted.
+func (s *Service) Usage(ctx context.Context, orgID string, months int) ([]MonthlyUsage, error) {
+	usage := []MonthlyUsage{}
+	err := s.db.Reader().SelectContext(ctx, &usage, `
+		SELECT to_char(date_trunc('month', occurred_at), 'YYYY-MM') AS month,
+		       COALESCE(SUM(quantity) FILTER (WHERE event_type = 'scan_started'), 0) AS scans_started,
+		       COALESCE(SUM(quantity) FILTER (WHERE event_type = 'report_generated'), 0) AS reports_generated,
+		       COALESCE(MAX(quantity) FILTER (WHERE event_type = 'storage_used'), 0) AS peak_storage_bytes,
+		       COALESCE(SUM(quantity) FILTER (WHERE event_type = 'storage_used'), 0) AS storage_byte_days
+		FROM billing_events
+		WHERE organization_id = $1
+		AND occurred_at >= date_trunc('month', timezone('UTC', NOW())) - make_interval(months => $2 - 1)
+		GROUP BY 1
+		ORDER BY 1 DESC
+	`, orgID, months)
+	return usage, err
+}
+
+// Start measures storage every MeterInterval and, with an exporter, sends
+// the ledger to Stripe every ExportInterval, until ctx is cancelled
+func (s *Service) Start(ctx context.Context) {
+	meter := time.NewTicker(s.config.MeterInterval)
+	defer meter.Stop()
+	var export <-chan time.Time
+	if s.exporter != nil {
+		ticker := time.NewTicker(s.config.ExportInterval)
+		defer ticker.Stop()
+		export = ticker.C
+	}
+
+	s.logger.Info("Starting usage metering",
+		zap.Duration("meter_interval", s.config.MeterInterval),
+		zap.Bool("stripe_export", s.exporter != nil),
+	)
+
+	s.meterStorage(ctx)
+	for {
+		select {
+		case <-meter.C:
+			s.meterStorage(ctx)
+		case <-export:
+			// Keep exporting while batches come back full
+			for {
+				n, err := s.exporter.ExportBatch(ctx, s.config.ExportBatchSize, s.config.MaxExportAttempts)
+				if err != nil {
+					s.logger.Error("Failed to export billing events", zap.Error(err))
+					break
+				}
+				if n < s.config.ExportBatchSize {
+					break
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// meterStorage records today's storage for every active organization: report
+// files, completed data exports and stored scan evidence, whether kept in
+// scan_results or in the organization's own schema
+func (s *Service) meterStorage(ctx context.Context) {
+	var rows []struct {
+		OrganizationID string `db:"id"`
+		Bytes          int64  `db:"bytes"`
+	}
+	err := s.db.SelectContext(ctx, &rows, `
+		SELECT o.id,
+		       COALESCE((SELECT SUM(COALESCE(r.size_bytes, octet_length(r.content), 0))
+		                 FROM reports r WHERE r.organization_id = o.id), 0)
+		     + COALESCE((SELECT SUM(e.size_bytes)
+		                 FROM data_exports e WHERE e.organization_id = o.id AND e.status = 'completed'), 0)
+		     + COALESCE((SELECT SUM(pg_column_size(sr.raw_data))
+		                 FROM scan_results sr WHERE sr.organization_id = o.id), 0)
+		     + tenant_evidence_bytes(o.id) AS bytes
+		FROM organizations o
+		WHERE COALESCE(o.is_active, true)
+		AND NOT EXISTS (
+			SELECT 1 FROM billing_events be
+			WHERE be.id = 'storage_used:' || o.id || ':' || to_char(timezone('UTC', NOW()), 'YYYY-MM-DD')
+		)
+	`)
+	if err != nil {
+		s.logger.Error("Failed to measure storage", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for _, row := range rows {
+		if err := Record(ctx, s.db, StorageUsed(row.OrganizationID, now, row.Bytes)); err != nil {
+			s.logger.Error("Failed to record storage usage", zap.String("organization_id", row.OrganizationID), zap.Error(err))
+			continue
+		}
+		metrics.BillingEvents.WithLabelValues(EventStorageUsed, "recorded").Inc()
+	}
+}
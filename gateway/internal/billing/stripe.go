@@ -0,0 +1,193 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// StripeConfig points the exporter at Stripe
+type StripeConfig struct {
+	APIKey string
+	URL    string
+	// Meters maps event types to the event_name of their Stripe billing
+	// meter. Event types without a meter are not exported. Count meters
+	// should aggregate with sum, the storage meter with last.
+	Meters map[string]string
+	// Transport sends requests, e.g. under an outbound policy; the default
+	// transport when nil
+	Transport http.RoundTripper
+}
+
+func DefaultStripeConfig() StripeConfig {
+	return StripeConfig{
+		URL: "https://api.stripe.com",
+		Meters: map[string]string{
+			EventScanStarted:     EventScanStarted,
+			EventReportGenerated: EventReportGenerated,
+			EventStorageUsed:     EventStorageUsed,
+		},
+	}
+}
+
+// ParseMeters reads "event_type=meter_event_name" pairs separated by commas
+func ParseMeters(value string) (map[string]string, error) {
+	meters := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		eventType, name, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid meter %q, want event_type=meter_event_name", pair)
+		}
+		if !isEventType(eventType) {
+			return nil, fmt.Errorf("unknown billing event type %q", eventType)
+		}
+		meters[eventType] = name
+	}
+	return meters, nil
+}
+
+func isEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// StripeExporter sends ledger events to Stripe as billing meter events for
+// the organization's Stripe customer (see billing_customers). The ledger ID
+// is the meter event's identifier, which Stripe deduplicates on, so an event
+// sent again after a crash is counted once.
+type StripeExporter struct {
+	db         *database.DB
+	config     StripeConfig
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+func NewStripeExporter(db *database.DB, config StripeConfig, logger *zap.Logger) *StripeExporter {
+	return &StripeExporter{
+		db:         db,
+		config:     config,
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: config.Transport},
+		logger:     logger,
+	}
+}
+
+type exportRow struct {
+	Event
+	CustomerID string `db:"stripe_customer_id"`
+}
+
+// ExportBatch sends up to limit unexported events of organizations with a
+// Stripe customer, and returns how many it claimed. Rows are claimed with
+// SKIP LOCKED, so several gateway replicas can export at once.
+func (e *StripeExporter) ExportBatch(ctx context.Context, limit, maxAttempts int) (int, error) {
+	eventTypes := make([]string, 0, len(e.config.Meters))
+	for eventType := range e.config.Meters {
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	tx, err := e.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rows []exportRow
+	err = tx.SelectContext(ctx, &rows, `
+		SELECT be.id, be.organization_id, be.event_type, be.quantity, be.subject, be.occurred_at, bc.stripe_customer_id
+		FROM billing_events be
+		JOIN billing_customers bc ON bc.organization_id = be.organization_id
+		WHERE be.exported_at IS NULL AND be.export_attempts < $2 AND be.event_type = ANY($3)
+		ORDER BY be.occurred_at
+		LIMIT $1
+		FOR UPDATE OF be SKIP LOCKED
+	`, limit, maxAttempts, pq.Array(eventTypes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim billing events: %w", err)
+	}
+
+	for _, row := range rows {
+		if err := e.send(ctx, row); err != nil {
+			metrics.BillingEvents.WithLabelValues(row.Type, "export_failed").Inc()
+			e.logger.Warn("Failed to export billing event",
+				zap.String("event_id", row.ID),
+				zap.String("organization_id", row.OrganizationID),
+				zap.Error(err),
+			)
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE billing_events SET export_attempts = export_attempts + 1, last_export_error = $2 WHERE id = $1
+			`, row.ID, err.Error()); err != nil {
+				return 0, fmt.Errorf("failed to record export failure: %w", err)
+			}
+			continue
+		}
+
+		metrics.BillingEvents.WithLabelValues(row.Type, "exported").Inc()
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE billing_events SET exported_at = NOW(), last_export_error = NULL WHERE id = $1
+		`, row.ID); err != nil {
+			return 0, fmt.Errorf("failed to mark billing event exported: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit export: %w", err)
+	}
+	return len(rows), nil
+}
+
+// send creates the meter event
+func (e *StripeExporter) send(ctx context.Context, row exportRow) error {
+	form := url.Values{}
+	form.Set("event_name", e.config.Meters[row.Type])
+	form.Set("identifier", row.ID)
+	form.Set("timestamp", strconv.FormatInt(row.OccurredAt.Unix(), 10))
+	form.Set("payload[stripe_customer_id]", row.CustomerID)
+	form.Set("payload[value]", strconv.FormatInt(row.Quantity, 10))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.config.URL, "/")+"/v1/billing/meter_events", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+	req.Header.Set("Idempotency-Key", row.ID)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Stripe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var stripeErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(body, &stripeErr) == nil && stripeErr.Error.Message != "" {
+		return fmt.Errorf("stripe returned status %d: %s", resp.StatusCode, stripeErr.Error.Message)
+	}
+	return fmt.Errorf("stripe returned status %d", resp.StatusCode)
+}
@@ -0,0 +1,441 @@
+// Package bootstrap starts the gateway's dependencies (database, replica,
+// Redis) in the order their dependency graph requires, retrying each with
+// backoff until it is healthy. Optional components that stay down leave the
+// gateway running degraded: read-only, while they are retried in the
+// background.
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Component states
+const (
+	StatePending  = "pending"
+	StateStarting = "starting"
+	StateReady    = "ready"
+	StateFailed   = "failed"
+	StateSkipped  = "skipped" // A dependency never became ready
+)
+
+// ErrDependencyCycle is returned by Run when components depend on each other
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// RetryPolicy bounds how long a component is retried at startup
+type RetryPolicy struct {
+	// Attempts is the number of tries before the component is failed
+	Attempts int
+	// InitialBackoff is the wait after the first failure; it doubles after
+	// each further failure up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// AttemptTimeout bounds a single try
+	AttemptTimeout time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:       8,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     15 * time.Second,
+		AttemptTimeout: 10 * time.Second,
+	}
+}
+
+// backoff is the wait after the given number of failed attempts
+func (p RetryPolicy) backoff(failures int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < failures && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// Component is one dependency to start
+type Component struct {
+	Name string
+	// DependsOn names the components that must be ready first
+	DependsOn []string
+	// Optional components may fail without failing startup; the gateway
+	// then runs degraded until they recover
+	Optional bool
+	// Start connects the dependency and checks it is healthy. It is called
+	// again on failure, so it must be safe to retry.
+	Start func(ctx context.Context) error
+	// Retry overrides the bootstrapper's default policy when Attempts is set
+	Retry RetryPolicy
+}
+
+// Status is a component's startup outcome
+type Status struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	Optional  bool       `json:"optional"`
+	DependsOn []string   `json:"depends_on,omitempty"`
+	Attempts  int        `json:"attempts"`
+	Error     string     `json:"error,omitempty"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+	// DurationMS is how long the component took to become ready
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// Report is the startup status of every component
+type Report struct {
+	Degraded   bool      `json:"degraded"` // An optional component is down
+	Components []Status  `json:"components"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// Bootstrapper starts registered components in dependency order
+type Bootstrapper struct {
+	retry  RetryPolicy
+	logger *zap.Logger
+
+	mu         sync.RWMutex
+	components []Component
+	status     map[string]*Status
+	startedAt  time.Time
+	onRecover  []func(name string)
+}
+
+func New(retry RetryPolicy, logger *zap.Logger) *Bootstrapper {
+	return &Bootstrapper{
+		retry:  retry,
+		logger: logger,
+		status: make(map[string]*Status),
+	}
+}
+
+// Add registers a component. Components are started once their
+// dependencies are ready; independent ones start concurrently.
+func (b *Bootstrapper) Add(component Component) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.components = append(b.components, component)
+	b.status[component.Name] = &Status{
+		Name:      component.Name,
+		State:     StatePending,
+		Optional:  component.Optional,
+		DependsOn: component.DependsOn,
+	}
+}
+
+// OnRecover registers a callback for when a component that failed at
+// startup becomes ready in the background
+func (b *Bootstrapper) OnRecover(fn func(name string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onRecover = append(b.onRecover, fn)
+}
+
+// Run starts every component, one dependency level at a time. It fails when
+// a required component (or one it depends on) cannot be started.
+func (b *Bootstrapper) Run(ctx context.Context) (Report, error) {
+	levels, err := b.levels()
+	if err != nil {
+		return b.Report(), err
+	}
+
+	b.mu.Lock()
+	b.startedAt = time.Now().UTC()
+	b.mu.Unlock()
+
+	var failed []string
+	for _, level := range levels {
+		var wg sync.WaitGroup
+		for _, component := range level {
+			if dep := b.unreadyDependency(component); dep != "" {
+				b.setState(component.Name, func(s *Status) {
+					s.State = StateSkipped
+					s.Error = fmt.Sprintf("dependency %s is not ready", dep)
+				})
+				b.logger.Warn("Skipped dependency", zap.String("component", component.Name), zap.String("waiting_on", dep))
+				continue
+			}
+			wg.Add(1)
+			go func(component Component) {
+				defer wg.Done()
+				b.start(ctx, component)
+			}(component)
+		}
+		wg.Wait()
+	}
+
+	report := b.Report()
+	for _, status := range report.Components {
+		if status.State != StateReady && !status.Optional {
+			failed = append(failed, fmt.Sprintf("%s (%s)", status.Name, status.Error))
+		}
+	}
+	if len(failed) > 0 {
+		return report, fmt.Errorf("failed to start %s", strings.Join(failed, ", "))
+	}
+	return report, nil
+}
+
+// start retries a component under its policy until it is ready
+func (b *Bootstrapper) start(ctx context.Context, component Component) {
+	policy := b.retry
+	if component.Retry.Attempts > 0 {
+		policy = component.Retry
+	}
+	logger := b.logger.With(zap.String("component", component.Name))
+
+	began := time.Now()
+	b.setState(component.Name, func(s *Status) { s.State = StateStarting })
+	for attempt := 1; ; attempt++ {
+		err := b.attempt(ctx, component, policy)
+		if err == nil {
+			now := time.Now().UTC()
+			b.setState(component.Name, func(s *Status) {
+				s.State = StateReady
+				s.Attempts = attempt
+				s.Error = ""
+				s.ReadyAt = &now
+				s.DurationMS = time.Since(began).Milliseconds()
+			})
+			logger.Info("Dependency ready", zap.Int("attempts", attempt), zap.Duration("took", time.Since(began)))
+			return
+		}
+
+		b.setState(component.Name, func(s *Status) {
+			s.Attempts = attempt
+			s.Error = err.Error()
+		})
+		if attempt >= policy.Attempts || ctx.Err() != nil {
+			b.setState(component.Name, func(s *Status) { s.State = StateFailed })
+			logger.Error("Dependency failed to start", zap.Int("attempts", attempt), zap.Bool("optional", component.Optional), zap.Error(err))
+			return
+		}
+
+		wait := policy.backoff(attempt)
+		logger.Warn("Dependency not ready, retrying", zap.Int("attempt", attempt), zap.Duration("backoff", wait), zap.Error(err))
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (b *Bootstrapper) attempt(ctx context.Context, component Component, policy RetryPolicy) error {
+	if policy.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.AttemptTimeout)
+		defer cancel()
+	}
+	return component.Start(ctx)
+}
+
+// Recover retries optional components that are not ready every interval
+// until they all are or ctx is cancelled, leaving degraded mode once the
+// last one recovers
+func (b *Bootstrapper) Recover(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for b.Degraded() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		levels, err := b.levels()
+		if err != nil {
+			return
+		}
+		for _, level := range levels {
+			for _, component := range level {
+				if b.state(component.Name) == StateReady || b.unreadyDependency(component) != "" {
+					continue
+				}
+				if err := b.attempt(ctx, component, b.retry); err != nil {
+					b.setState(component.Name, func(s *Status) {
+						s.Attempts++
+						s.Error = err.Error()
+					})
+					continue
+				}
+
+				now := time.Now().UTC()
+				b.setState(component.Name, func(s *Status) {
+					s.State = StateReady
+					s.Attempts++
+					s.Error = ""
+					s.ReadyAt = &now
+				})
+				b.logger.Info("Dependency recovered", zap.String("component", component.Name))
+
+				b.mu.RLock()
+				listeners := append([]func(string){}, b.onRecover...)
+				b.mu.RUnlock()
+				for _, fn := range listeners {
+					fn(component.Name)
+				}
+			}
+		}
+	}
+	b.logger.Info("All dependencies ready, leaving degraded mode")
+}
+
+// Degraded reports whether an optional component is not ready
+func (b *Bootstrapper) Degraded() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, status := range b.status {
+		if status.Optional && status.State != StateReady {
+			return true
+		}
+	}
+	return false
+}
+
+// Ready reports whether the named component is ready
+func (b *Bootstrapper) Ready(name string) bool {
+	return b.state(name) == StateReady
+}
+
+// Report returns the status of every component, in registration order
+func (b *Bootstrapper) Report() Report {
+	b.mu.RLock()
+	report := Report{StartedAt: b.startedAt}
+	for _, component := range b.components {
+		status := *b.status[component.Name]
+		report.Components = append(report.Components, status)
+		if status.Optional && status.State != StateReady {
+			report.Degraded = true
+		}
+	}
+	b.mu.RUnlock()
+	return report
+}
+
+// Handler serves the startup report
+func (b *Bootstrapper) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, b.Report())
+	}
+}
+
+// ReadOnlyMiddleware refuses requests that change state while the gateway is
+// degraded, except under the exempt path prefixes (e.g. sign-in)
+func (b *Bootstrapper) ReadOnlyMiddleware(exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if !b.Degraded() {
+			c.Next()
+			return
+		}
+		for _, prefix := range exempt {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		c.Header("Retry-After", "30")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Service is running in read-only mode",
+			"code":  "read_only",
+		})
+	}
+}
+
+// levels orders the components into dependency levels: each level depends
+// only on earlier ones
+func (b *Bootstrapper) levels() ([][]Component, error) {
+	b.mu.RLock()
+	components := append([]Component{}, b.components...)
+	b.mu.RUnlock()
+
+	byName := make(map[string]Component, len(components))
+	for _, component := range components {
+		byName[component.Name] = component
+	}
+	for _, component := range components {
+		for _, dep := range component.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("component %s depends on unknown component %s", component.Name, dep)
+			}
+		}
+	}
+
+	placed := make(map[string]bool, len(components))
+	var levels [][]Component
+	for len(placed) < len(components) {
+		var level []Component
+		for _, component := range components {
+			if placed[component.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range component.DependsOn {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level = append(level, component)
+			}
+		}
+		if len(level) == 0 {
+			var remaining []string
+			for _, component := range components {
+				if !placed[component.Name] {
+					remaining = append(remaining, component.Name)
+				}
+			}
+			sort.Strings(remaining)
+			return nil, fmt.Errorf("%w between %s", ErrDependencyCycle, strings.Join(remaining, ", "))
+		}
+		for _, component := range level {
+			placed[component.Name] = true
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// unreadyDependency returns the first dependency of component that is not
+// ready, or ""
+func (b *Bootstrapper) unreadyDependency(component Component) string {
+	for _, dep := range component.DependsOn {
+		if b.state(dep) != StateReady {
+			return dep
+		}
+	}
+	return ""
+}
+
+func (b *Bootstrapper) state(name string) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if status, ok := b.status[name]; ok {
+		return status.State
+	}
+	return ""
+}
+
+func (b *Bootstrapper) setState(name string, update func(*Status)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	update(b.status[name])
+}
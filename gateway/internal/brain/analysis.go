@@ -0,0 +1,123 @@
+package brain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrAnalysisFailed is returned by AnalysisStream.Next when the brain service
+// reports an error partway through an analysis
+var ErrAnalysisFailed = errors.New("brain service failed to analyze scan")
+
+// maxStreamLine bounds a single server-sent event line from the brain service
+const maxStreamLine = 1 << 20
+
+type AnalyzeRequest struct {
+	ScanType    string                 `json:"scan_type"`
+	ScanResults ScanResults            `json:"scan_results"`
+	Context     map[string]interface{} `json:"context,omitempty"`
+}
+
+// AnalysisStream reads incremental analysis text from the brain service.
+// It must be closed; closing early stops the brain generating.
+type AnalysisStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// StreamAnalysis starts an analysis of scan results, returned piece by piece
+// as the brain's model writes it. Cancelling ctx aborts the request.
+func (c *Client) StreamAnalysis(ctx context.Context, req AnalyzeRequest) (*AnalysisStream, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/analyze/stream", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.streamClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("brain service returned status: %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxStreamLine)
+	return &AnalysisStream{body: resp.Body, scanner: scanner}, nil
+}
+
+// Next returns the next piece of analysis text, io.EOF once the analysis is
+// complete, or an error wrapping ErrAnalysisFailed if the brain gave up
+func (s *AnalysisStream) Next() (string, error) {
+	for {
+		event, data, err := s.readEvent()
+		if err != nil {
+			return "", err
+		}
+
+		var payload struct {
+			Text  string `json:"text"`
+			Error string `json:"error"`
+		}
+		if data != "" {
+			if err := json.Unmarshal([]byte(data), &payload); err != nil {
+				return "", fmt.Errorf("failed to decode %s event: %w", event, err)
+			}
+		}
+
+		switch event {
+		case "chunk":
+			if payload.Text != "" {
+				return payload.Text, nil
+			}
+		case "done":
+			return "", io.EOF
+		case "error":
+			return "", fmt.Errorf("%w: %s", ErrAnalysisFailed, payload.Error)
+		}
+	}
+}
+
+// readEvent reads one server-sent event, skipping comments
+func (s *AnalysisStream) readEvent() (event, data string, err error) {
+	var lines []string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			if event != "" || len(lines) > 0 {
+				return event, strings.Join(lines, "\n"), nil
+			}
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return "", "", fmt.Errorf("failed to read analysis stream: %w", err)
+	}
+	// The brain closed the stream without a done event
+	return "", "", io.ErrUnexpectedEOF
+}
+
+// Close releases the connection, cancelling the analysis if still running
+func (s *AnalysisStream) Close() error {
+	return s.body.Close()
+}
@@ -2,9 +2,12 @@ package brain
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"go.uber.org/zap"
@@ -13,7 +16,10 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
-	logger     *zap.Logger
+	// streamClient has no overall timeout: streams last as long as the brain
+	// keeps writing, bounded by the caller's context
+	streamClient *http.Client
+	logger       *zap.Logger
 }
 
 func NewClient(url string, logger *zap.Logger) *Client {
@@ -22,25 +28,48 @@ func NewClient(url string, logger *zap.Logger) *Client {
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second, // PDF generation might take time
 		},
-		logger: logger,
+		streamClient: &http.Client{},
+		logger:       logger,
 	}
 }
 
+// SetTransport sends requests, streams included, through transport: under
+// an outbound policy, and over (mutual) TLS when it is configured with e.g.
+// mtls.Reloader.ClientConfig, so the brain service can verify the gateway
+// and vice versa
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+	c.streamClient.Transport = transport
+}
+
+// Report formats. SARIF is built by the gateway from stored findings; the
+// others are rendered by the brain service.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+	FormatSARIF    = "sarif"
+)
+
+// maxReportFileSize bounds PDFs fetched from the brain service
+const maxReportFileSize = 50 << 20
+
 type ScanResults map[string]interface{}
 type AnalysisResult map[string]interface{}
 
 type GenerateReportRequest struct {
 	ScanResults ScanResults            `json:"scan_results"`
-	ReportType  string                 `json:"report_type"`
+	ReportType  string                 `json:"report_type"` // executive, technical, or a compliance framework (pci-dss, iso-27001, nist-csf)
 	Format      string                 `json:"format"`
 	Analysis    AnalysisResult         `json:"analysis,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	OutputPath  string                 `json:"output_path,omitempty"` // PDF only, inside the brain container
 }
 
 type GenerateReportResponse struct {
 	Path   string `json:"path"`
 	Status string `json:"status"`
-	Report string `json:"report"` // Markdown content
+	Report string `json:"report"` // Markdown or HTML content
 }
 
 func (c *Client) GenerateReport(req GenerateReportRequest) (*GenerateReportResponse, error) {
@@ -67,3 +96,92 @@ func (c *Client) GenerateReport(req GenerateReportRequest) (*GenerateReportRespo
 
 	return &result, nil
 }
+
+// FetchReportFile downloads a file the brain service wrote during report generation
+func (c *Client) FetchReportFile(path string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/report/file?path=%s", c.baseURL, url.QueryEscape(path))
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("brain service returned status: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReportFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report file: %w", err)
+	}
+	if len(data) > maxReportFileSize {
+		return nil, fmt.Errorf("report file exceeds %d bytes", maxReportFileSize)
+	}
+	return data, nil
+}
+
+// Watermark identifies who a report was downloaded by
+type Watermark struct {
+	DownloadID     string    `json:"download_id"`
+	UserID         string    `json:"user_id"`
+	Email          string    `json:"email"`
+	OrganizationID string    `json:"organization_id,omitempty"`
+	IPAddress      string    `json:"ip_address,omitempty"`
+	DownloadedAt   time.Time `json:"downloaded_at"`
+}
+
+// WatermarkPDF has the brain service stamp every page of a PDF with the
+// downloader's identity and add it to the document metadata
+func (c *Client) WatermarkPDF(ctx context.Context, pdf []byte, watermark Watermark) ([]byte, error) {
+	body, err := json.Marshal(struct {
+		PDF      []byte    `json:"pdf"` // base64
+		Metadata Watermark `json:"metadata"`
+	}{pdf, watermark})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/report/watermark", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("brain service returned status: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReportFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watermarked report: %w", err)
+	}
+	if len(data) > maxReportFileSize {
+		return nil, fmt.Errorf("watermarked report exceeds %d bytes", maxReportFileSize)
+	}
+	return data, nil
+}
+
+// Health checks that the brain service is up. Cancelling ctx aborts the request.
+func (c *Client) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("brain service returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
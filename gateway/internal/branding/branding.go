@@ -0,0 +1,261 @@
+// Package branding stores organizations' white-label settings: logo, color
+// scheme, report footer and email sender name.
+package branding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/storage"
+	"github.com/cyper-security/gateway/internal/uploads"
+	"go.uber.org/zap"
+)
+
+// MaxLogoBytes bounds uploaded logos
+const MaxLogoBytes = 1 << 20
+
+// logoURLTTL is how long logo links in settings and reports stay valid
+const logoURLTTL = 24 * time.Hour
+
+var (
+	ErrLogoTooLarge    = errors.New("logo must be at most 1 MB")
+	ErrLogoUnsupported = errors.New("logo must be a PNG, JPEG, GIF or WebP image")
+)
+
+// logoPolicy accepts these types, as sniffed from the upload. SVG is not
+// accepted since it can carry script.
+var logoPolicy = uploads.Policy{
+	MaxBytes: MaxLogoBytes,
+	Types: map[string]string{
+		"image/png":  "png",
+		"image/jpeg": "jpg",
+		"image/gif":  "gif",
+		"image/webp": "webp",
+	},
+}
+
+// Settings is an organization's branding. Unset fields fall back to the
+// platform defaults.
+type Settings struct {
+	OrganizationID  string  `json:"organization_id" db:"organization_id"`
+	LogoKey         *string `json:"-" db:"logo_key"`
+	LogoContentType *string `json:"-" db:"logo_content_type"`
+	// LogoArtifactID links the logo's upload metadata and scan verdict
+	LogoArtifactID  *string   `json:"logo_artifact_id,omitempty" db:"logo_artifact_id"`
+	LogoURL         string    `json:"logo_url,omitempty" db:"-"`
+	PrimaryColor    *string   `json:"primary_color" db:"primary_color"`
+	SecondaryColor  *string   `json:"secondary_color" db:"secondary_color"`
+	AccentColor     *string   `json:"accent_color" db:"accent_color"`
+	ReportFooter    *string   `json:"report_footer" db:"report_footer"`
+	EmailSenderName *string   `json:"email_sender_name" db:"email_sender_name"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Update replaces the text settings; nil clears a field
+type Update struct {
+	PrimaryColor    *string
+	SecondaryColor  *string
+	AccentColor     *string
+	ReportFooter    *string
+	EmailSenderName *string
+}
+
+// Columns selects every Settings field
+const Columns = `organization_id, logo_key, logo_content_type, logo_artifact_id, primary_color, secondary_color,
+	accent_color, report_footer, email_sender_name, updated_at`
+
+// Load returns the organization's settings, or empty settings when none
+// have been saved
+func Load(ctx context.Context, db *database.DB, orgID string) (*Settings, error) {
+	var settings Settings
+	err := db.GetContext(ctx, &settings, `
+		SELECT `+Columns+` FROM organization_settings WHERE organization_id = $1
+	`, orgID)
+	if err == sql.ErrNoRows {
+		return &Settings{OrganizationID: orgID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organization settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SenderName returns the organization's email sender name, or "" for the
+// platform default. Lookup failures fall back to the default.
+func SenderName(ctx context.Context, db *database.DB, orgID string) string {
+	var name sql.NullString
+	err := db.GetContext(ctx, &name, `
+		SELECT email_sender_name FROM organization_settings WHERE organization_id = $1
+	`, orgID)
+	if err != nil {
+		return ""
+	}
+	return name.String
+}
+
+// SignLogo sets LogoURL to an expiring link to the logo, if there is one
+func (s *Settings) SignLogo(ctx context.Context, store storage.Store) error {
+	if s.LogoKey == nil {
+		return nil
+	}
+	url, err := store.SignedURL(ctx, *s.LogoKey, logoURLTTL)
+	if err != nil {
+		return err
+	}
+	s.LogoURL = url
+	return nil
+}
+
+// ReportMetadata is the branding passed to report rendering
+func (s *Settings) ReportMetadata() map[string]interface{} {
+	metadata := map[string]interface{}{}
+	set := func(key string, value *string) {
+		if value != nil && *value != "" {
+			metadata[key] = *value
+		}
+	}
+	if s.LogoURL != "" {
+		metadata["logo_url"] = s.LogoURL
+	}
+	set("primary_color", s.PrimaryColor)
+	set("secondary_color", s.SecondaryColor)
+	set("accent_color", s.AccentColor)
+	set("footer", s.ReportFooter)
+	return metadata
+}
+
+// Service manages settings and logo files
+type Service struct {
+	db      *database.DB
+	store   storage.Store
+	uploads *uploads.Service
+	logger  *zap.Logger
+}
+
+func NewService(db *database.DB, store storage.Store, uploadService *uploads.Service, logger *zap.Logger) *Service {
+	return &Service{db: db, store: store, uploads: uploadService, logger: logger}
+}
+
+// Get returns the organization's settings with a signed logo link
+func (s *Service) Get(ctx context.Context, orgID string) (*Settings, error) {
+	settings, err := Load(ctx, s.db, orgID)
+	if err != nil {
+		return nil, err
+	}
+	s.signLogo(ctx, settings)
+	return settings, nil
+}
+
+// signLogo fills in LogoURL; a signing failure leaves it empty
+func (s *Service) signLogo(ctx context.Context, settings *Settings) {
+	if err := settings.SignLogo(ctx, s.store); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to sign logo URL", zap.String("organization_id", settings.OrganizationID), zap.Error(err))
+	}
+}
+
+// Update saves the organization's text settings, keeping its logo
+func (s *Service) Update(ctx context.Context, orgID, userID string, u Update) (*Settings, error) {
+	var settings Settings
+	err := s.db.GetContext(ctx, &settings, `
+		INSERT INTO organization_settings
+			(organization_id, primary_color, secondary_color, accent_color, report_footer, email_sender_name, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			primary_color = EXCLUDED.primary_color,
+			secondary_color = EXCLUDED.secondary_color,
+			accent_color = EXCLUDED.accent_color,
+			report_footer = EXCLUDED.report_footer,
+			email_sender_name = EXCLUDED.email_sender_name,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING `+Columns,
+		orgID, u.PrimaryColor, u.SecondaryColor, u.AccentColor, u.ReportFooter, u.EmailSenderName, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save organization settings: %w", err)
+	}
+	s.signLogo(ctx, &settings)
+	return &settings, nil
+}
+
+// SetLogo stores a new logo and replaces the previous one. The upload is
+// validated and scanned by the upload service; an infected logo fails with
+// its *uploads.QuarantineError.
+func (s *Service) SetLogo(ctx context.Context, orgID, userID, filename string, r io.Reader) (*Settings, error) {
+	// A fresh key per upload, so cached links to the old logo don't show the new one
+	artifact, err := s.uploads.Accept(ctx, uploads.Upload{
+		OrganizationID: orgID,
+		Kind:           uploads.KindLogo,
+		Filename:       filename,
+		UploadedBy:     userID,
+		Policy:         logoPolicy,
+		KeyPrefix:      fmt.Sprintf("branding/%s/logo-", orgID),
+	}, r)
+	switch {
+	case err == uploads.ErrTooLarge:
+		return nil, ErrLogoTooLarge
+	case err == uploads.ErrUnsupportedType:
+		return nil, ErrLogoUnsupported
+	case err != nil:
+		return nil, err
+	}
+
+	var previous sql.NullString
+	err = s.db.GetContext(ctx, &previous, `
+		SELECT logo_key FROM organization_settings WHERE organization_id = $1
+	`, orgID)
+	if err != nil && err != sql.ErrNoRows {
+		s.uploads.Discard(ctx, artifact)
+		return nil, fmt.Errorf("failed to load organization settings: %w", err)
+	}
+
+	var settings Settings
+	err = s.db.GetContext(ctx, &settings, `
+		INSERT INTO organization_settings (organization_id, logo_key, logo_content_type, logo_artifact_id, updated_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			logo_key = EXCLUDED.logo_key,
+			logo_content_type = EXCLUDED.logo_content_type,
+			logo_artifact_id = EXCLUDED.logo_artifact_id,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING `+Columns,
+		orgID, artifact.StorageKey, artifact.ContentType, artifact.ID, userID)
+	if err != nil {
+		s.uploads.Discard(ctx, artifact)
+		return nil, fmt.Errorf("failed to save logo: %w", err)
+	}
+	if previous.Valid {
+		s.uploads.Delete(ctx, previous.String)
+	}
+
+	s.signLogo(ctx, &settings)
+	return &settings, nil
+}
+
+// DeleteLogo removes the organization's logo
+func (s *Service) DeleteLogo(ctx context.Context, orgID, userID string) error {
+	var key sql.NullString
+	err := s.db.GetContext(ctx, &key, `
+		UPDATE organization_settings new
+		SET logo_key = NULL, logo_content_type = NULL, logo_artifact_id = NULL, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		FROM organization_settings old
+		WHERE new.organization_id = $1 AND old.organization_id = new.organization_id
+		RETURNING old.logo_key
+	`, orgID, userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete logo: %w", err)
+	}
+	if key.Valid {
+		s.uploads.Delete(ctx, key.String)
+	}
+	return nil
+}
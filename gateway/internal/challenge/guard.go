@@ -0,0 +1,134 @@
+package challenge
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const attemptsKeyPrefix = "challenge:attempts:"
+
+// Config sets when a challenge is demanded: once a client IP has made more
+// than Thresholds[action] attempts within Window. Zero challenges every
+// attempt; actions without a threshold are never challenged.
+type Config struct {
+	Window     time.Duration
+	Thresholds map[string]int
+}
+
+// Guard counts attempts per client IP and action in Redis and, past the
+// threshold, requires a solved challenge in the X-Challenge-Response header.
+// A Guard without a provider never challenges.
+type Guard struct {
+	provider Provider
+	redis    *redis.Client
+	config   Config
+	logger   *zap.Logger
+}
+
+func NewGuard(provider Provider, redisClient *redis.Client, config Config, logger *zap.Logger) *Guard {
+	return &Guard{
+		provider: provider,
+		redis:    redisClient,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// Provider returns the configured provider, nil when challenges are off
+func (g *Guard) Provider() Provider {
+	return g.provider
+}
+
+// Require enforces the challenge for action (e.g. "login", "register")
+func (g *Guard) Require(action string) gin.HandlerFunc {
+	threshold, limited := g.config.Thresholds[action]
+	return func(c *gin.Context) {
+		if g.provider == nil || !limited {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		ip := c.ClientIP()
+		attempts, err := g.countAttempt(c, action, ip)
+		if err != nil {
+			// Fail open: Redis trouble should not lock everyone out
+			logging.FromContext(c.Request.Context(), g.logger).Error("Failed to count attempts for challenge", zap.String("action", action), zap.Error(err))
+			c.Next()
+			return
+		}
+		if attempts <= int64(threshold) {
+			c.Next()
+			return
+		}
+
+		response := c.GetHeader("X-Challenge-Response")
+		if response == "" {
+			metrics.AuthChallenges.WithLabelValues(action, "issued").Inc()
+			g.reject(c, "challenge_required", "a challenge must be solved to continue")
+			return
+		}
+
+		err = g.provider.Verify(ctx, response, ip)
+		if err == ErrChallengeFailed {
+			metrics.AuthChallenges.WithLabelValues(action, "failed").Inc()
+			logging.FromContext(c.Request.Context(), g.logger).Warn("Challenge failed",
+				zap.String("action", action),
+				zap.String("ip_address", ip),
+				zap.Int64("attempts", attempts),
+			)
+			g.reject(c, "challenge_failed", "challenge response was not accepted")
+			return
+		}
+		if err != nil {
+			logging.FromContext(c.Request.Context(), g.logger).Error("Challenge verification unavailable", zap.String("action", action), zap.Error(err))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "challenge verification unavailable"})
+			c.Abort()
+			return
+		}
+
+		metrics.AuthChallenges.WithLabelValues(action, "passed").Inc()
+		c.Next()
+	}
+}
+
+// countAttempt increments the fixed-window counter for ip and action
+func (g *Guard) countAttempt(c *gin.Context, action, ip string) (int64, error) {
+	key := fmt.Sprintf("%s%s:%s", attemptsKeyPrefix, action, ip)
+	attempts, err := g.redis.Incr(c.Request.Context(), key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if attempts == 1 {
+		if err := g.redis.Expire(c.Request.Context(), key, g.config.Window).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return attempts, nil
+}
+
+// reject answers 403 with a fresh challenge so the client can solve it and
+// retry without another round trip
+func (g *Guard) reject(c *gin.Context, code, message string) {
+	challenge, err := g.provider.Challenge(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context(), g.logger).Error("Failed to issue challenge", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "challenge verification unavailable"})
+		c.Abort()
+		return
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":     message,
+		"code":      code,
+		"challenge": challenge,
+	})
+	c.Abort()
+}
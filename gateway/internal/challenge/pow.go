@@ -0,0 +1,87 @@
+package challenge
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const powKeyPrefix = "challenge:pow:"
+
+// ProofOfWork is the built-in provider, needing no third party. The client
+// must find a counter such that SHA-256("<nonce>:<counter>") starts with
+// Difficulty zero bits, and answers with "<nonce>:<counter>". Nonces live in
+// Redis so any gateway replica can verify them, and each is accepted once.
+type ProofOfWork struct {
+	redis      *redis.Client
+	difficulty int
+	ttl        time.Duration
+}
+
+func NewProofOfWork(redisClient *redis.Client, difficulty int, ttl time.Duration) *ProofOfWork {
+	return &ProofOfWork{redis: redisClient, difficulty: difficulty, ttl: ttl}
+}
+
+func (p *ProofOfWork) Challenge(ctx context.Context) (*Challenge, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(buf)
+
+	if err := p.redis.Set(ctx, powKeyPrefix+nonce, p.difficulty, p.ttl).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	expiresAt := time.Now().Add(p.ttl)
+	return &Challenge{
+		Provider:   "pow",
+		Nonce:      nonce,
+		Difficulty: p.difficulty,
+		ExpiresAt:  &expiresAt,
+	}, nil
+}
+
+func (p *ProofOfWork) Verify(ctx context.Context, response, _ string) error {
+	nonce, counter, ok := strings.Cut(response, ":")
+	if !ok || nonce == "" || counter == "" {
+		return ErrChallengeFailed
+	}
+
+	// GetDel makes each nonce single-use
+	stored, err := p.redis.GetDel(ctx, powKeyPrefix+nonce).Result()
+	if err == redis.Nil {
+		return ErrChallengeFailed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load challenge: %w", err)
+	}
+	difficulty, err := strconv.Atoi(stored)
+	if err != nil {
+		return ErrChallengeFailed
+	}
+
+	if leadingZeroBits(sha256.Sum256([]byte(response))) < difficulty {
+		return ErrChallengeFailed
+	}
+	return nil
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
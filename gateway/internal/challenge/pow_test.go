@@ -0,0 +1,351 @@
+package challenge
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis serves the few commands ProofOfWork uses, with a clock tests
+// move forward to expire keys
+type fakeRedis struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	now     time.Time
+}
+
+func newFakeRedis(t *testing.T) (*fakeRedis, *redis.Client) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{values: map[string]string{}, expires: map[string]time.Time{}, now: time.Now()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String(), Protocol: 2, DisableIndentity: true})
+	t.Cleanup(func() {
+		client.Close()
+		ln.Close()
+	})
+	return f, client
+}
+
+func (f *fakeRedis) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, f.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, at := range f.expires {
+		if !f.now.Before(at) {
+			delete(f.values, key)
+			delete(f.expires, key)
+		}
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "SET":
+		f.values[args[1]] = args[2]
+		delete(f.expires, args[1])
+		if len(args) == 5 {
+			n, _ := strconv.Atoi(args[4])
+			unit := time.Second
+			if strings.EqualFold(args[3], "px") {
+				unit = time.Millisecond
+			}
+			f.expires[args[1]] = f.now.Add(time.Duration(n) * unit)
+		}
+		return "+OK\r\n"
+	case "GETDEL":
+		value, ok := f.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		delete(f.values, args[1])
+		delete(f.expires, args[1])
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// readCommand reads one RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("bad argument %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// solve finds an answer to the nonce with exactly zeros leading zero bits,
+// or at least them when exact is false
+func solve(nonce string, zeros int, exact bool) string {
+	for counter := 0; ; counter++ {
+		answer := nonce + ":" + strconv.Itoa(counter)
+		n := leadingZeroBits(sha256.Sum256([]byte(answer)))
+		if n == zeros || (!exact && n > zeros) {
+			return answer
+		}
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		prefix []byte
+		want   int
+	}{
+		{[]byte{0x80}, 0},
+		{[]byte{0x7f}, 1},
+		{[]byte{0x01}, 7},
+		{[]byte{0x00, 0xff}, 8},
+		{[]byte{0x00, 0x00, 0x10}, 19},
+	}
+	for _, tt := range tests {
+		var sum [sha256.Size]byte
+		copy(sum[:], tt.prefix)
+		if got := leadingZeroBits(sum); got != tt.want {
+			t.Errorf("leadingZeroBits(%x...) = %d, want %d", tt.prefix, got, tt.want)
+		}
+	}
+	if got := leadingZeroBits([sha256.Size]byte{}); got != 256 {
+		t.Errorf("leadingZeroBits(zero) = %d, want 256", got)
+	}
+}
+
+func TestProofOfWorkChallenge(t *testing.T) {
+	fake, client := newFakeRedis(t)
+	pow := NewProofOfWork(client, 10, 5*time.Minute)
+
+	before := time.Now()
+	c, err := pow.Challenge(context.Background())
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	if c.Provider != "pow" || c.Difficulty != 10 {
+		t.Errorf("challenge = %+v, want provider pow at difficulty 10", c)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(c.Nonce) {
+		t.Errorf("nonce = %q, want 16 random bytes in hex", c.Nonce)
+	}
+	if c.ExpiresAt == nil || c.ExpiresAt.Before(before.Add(5*time.Minute)) || c.ExpiresAt.After(time.Now().Add(5*time.Minute)) {
+		t.Errorf("expires at %v, want five minutes out", c.ExpiresAt)
+	}
+	fake.mu.Lock()
+	stored := fake.values[powKeyPrefix+c.Nonce]
+	fake.mu.Unlock()
+	if stored != "10" {
+		t.Errorf("stored difficulty = %q, want 10", stored)
+	}
+
+	other, err := pow.Challenge(context.Background())
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	if other.Nonce == c.Nonce {
+		t.Error("two challenges share a nonce")
+	}
+}
+
+func TestProofOfWorkVerify(t *testing.T) {
+	ctx := context.Background()
+	const difficulty = 8
+
+	tests := []struct {
+		name   string
+		answer func(nonce string) string
+		// Runs between issuing the challenge and answering it
+		before func(f *fakeRedis, pow *ProofOfWork)
+		err    error
+	}{
+		{
+			name:   "exactly the difficulty",
+			answer: func(nonce string) string { return solve(nonce, difficulty, true) },
+		},
+		{
+			name:   "more than the difficulty",
+			answer: func(nonce string) string { return solve(nonce, difficulty+1, false) },
+		},
+		{
+			name:   "one bit short",
+			answer: func(nonce string) string { return solve(nonce, difficulty-1, true) },
+			err:    ErrChallengeFailed,
+		},
+		{
+			name:   "no work",
+			answer: func(nonce string) string { return solve(nonce, 0, true) },
+			err:    ErrChallengeFailed,
+		},
+		{
+			name:   "unknown nonce",
+			answer: func(string) string { return solve("0123456789abcdef0123456789abcdef", 0, false) },
+			err:    ErrChallengeFailed,
+		},
+		{
+			name:   "expired",
+			answer: func(nonce string) string { return solve(nonce, difficulty, false) },
+			before: func(f *fakeRedis, _ *ProofOfWork) { f.advance(time.Minute) },
+			err:    ErrChallengeFailed,
+		},
+		{
+			name:   "just before expiry",
+			answer: func(nonce string) string { return solve(nonce, difficulty, false) },
+			before: func(f *fakeRedis, _ *ProofOfWork) { f.advance(time.Minute - time.Millisecond) },
+		},
+		{
+			name:   "missing counter",
+			answer: func(nonce string) string { return nonce + ":" },
+			err:    ErrChallengeFailed,
+		},
+		{
+			name:   "missing nonce",
+			answer: func(string) string { return ":1" },
+			err:    ErrChallengeFailed,
+		},
+		{
+			name:   "no separator",
+			answer: func(nonce string) string { return nonce },
+			err:    ErrChallengeFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, client := newFakeRedis(t)
+			pow := NewProofOfWork(client, difficulty, time.Minute)
+			c, err := pow.Challenge(ctx)
+			if err != nil {
+				t.Fatalf("Challenge: %v", err)
+			}
+			if tt.before != nil {
+				tt.before(fake, pow)
+			}
+			if err := pow.Verify(ctx, tt.answer(c.Nonce), "203.0.113.7"); err != tt.err {
+				t.Errorf("Verify = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestProofOfWorkRejectsReplay(t *testing.T) {
+	ctx := context.Background()
+	_, client := newFakeRedis(t)
+	pow := NewProofOfWork(client, 8, time.Minute)
+
+	c, err := pow.Challenge(ctx)
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	answer := solve(c.Nonce, 8, false)
+	if err := pow.Verify(ctx, answer, ""); err != nil {
+		t.Fatalf("first Verify = %v, want nil", err)
+	}
+	if err := pow.Verify(ctx, answer, ""); err != ErrChallengeFailed {
+		t.Errorf("replayed Verify = %v, want ErrChallengeFailed", err)
+	}
+	// Nor is another answer to the spent nonce accepted
+	if err := pow.Verify(ctx, solve(c.Nonce, 9, false), ""); err != ErrChallengeFailed {
+		t.Errorf("second answer = %v, want ErrChallengeFailed", err)
+	}
+}
+
+func TestProofOfWorkWrongAnswerSpendsNonce(t *testing.T) {
+	ctx := context.Background()
+	_, client := newFakeRedis(t)
+	pow := NewProofOfWork(client, 8, time.Minute)
+
+	c, err := pow.Challenge(ctx)
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	if err := pow.Verify(ctx, solve(c.Nonce, 0, true), ""); err != ErrChallengeFailed {
+		t.Fatalf("wrong answer = %v, want ErrChallengeFailed", err)
+	}
+	// Guessing is one attempt per challenge
+	if err := pow.Verify(ctx, solve(c.Nonce, 8, false), ""); err != ErrChallengeFailed {
+		t.Errorf("answer after a wrong one = %v, want ErrChallengeFailed", err)
+	}
+}
+
+func TestProofOfWorkUsesIssuedDifficulty(t *testing.T) {
+	ctx := context.Background()
+	_, client := newFakeRedis(t)
+
+	// Issued by a replica demanding 12 bits, verified by one demanding none
+	c, err := NewProofOfWork(client, 12, time.Minute).Challenge(ctx)
+	if err != nil {
+		t.Fatalf("Challenge: %v", err)
+	}
+	if err := NewProofOfWork(client, 0, time.Minute).Verify(ctx, solve(c.Nonce, 11, true), ""); err != ErrChallengeFailed {
+		t.Errorf("Verify = %v, want ErrChallengeFailed", err)
+	}
+}
+
+func TestProofOfWorkRedisFailure(t *testing.T) {
+	_, client := newFakeRedis(t)
+	client.Close()
+	pow := NewProofOfWork(client, 8, time.Minute)
+
+	if _, err := pow.Challenge(context.Background()); err == nil {
+		t.Error("Challenge succeeded without Redis")
+	}
+	err := pow.Verify(context.Background(), "0123456789abcdef0123456789abcdef:1", "")
+	if err == nil || errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Verify = %v, want a Redis error rather than a failed challenge", err)
+	}
+}
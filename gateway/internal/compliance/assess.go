@@ -0,0 +1,214 @@
+package compliance
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/lib/pq"
+)
+
+// Finding is what classification needs to know about a finding
+type Finding struct {
+	ID            string         `json:"id" db:"id"`
+	Title         string         `json:"title" db:"title"`
+	Severity      string         `json:"severity" db:"severity"`
+	Category      *string        `json:"category,omitempty" db:"category"`
+	OWASPCategory *string        `json:"owasp_category,omitempty" db:"owasp_category"`
+	CVEID         *string        `json:"cve_id,omitempty" db:"cve_id"`
+	CWEIDs        pq.StringArray `json:"cwe_ids,omitempty" db:"cwe_ids"`
+}
+
+// Classify returns the OWASP Top 10 classes of a finding, sorted. An explicit
+// OWASP category wins over CWEs, which win over keywords in the category and
+// title. Findings with a CVE are also vulnerable components.
+func Classify(f Finding) []string {
+	classes := make(map[string]bool)
+
+	if f.OWASPCategory != nil {
+		if m := owaspClass.FindStringSubmatch(*f.OWASPCategory); m != nil {
+			classes["A"+m[1]] = true
+		}
+	}
+	if len(classes) == 0 {
+		for _, cwe := range f.CWEIDs {
+			if m := cweID.FindStringSubmatch(cwe); m != nil {
+				id, _ := strconv.Atoi(m[1])
+				for _, class := range cweClasses[id] {
+					classes[class] = true
+				}
+			}
+		}
+	}
+	if len(classes) == 0 && f.Category != nil {
+		if m := owaspClass.FindStringSubmatch(*f.Category); m != nil {
+			classes["A"+m[1]] = true
+		} else if class, ok := keywordClass(*f.Category); ok {
+			classes[class] = true
+		}
+	}
+	if len(classes) == 0 {
+		if class, ok := keywordClass(f.Title); ok {
+			classes[class] = true
+		}
+	}
+	if f.CVEID != nil && *f.CVEID != "" {
+		classes["A06"] = true
+	}
+
+	result := make([]string, 0, len(classes))
+	for class := range classes {
+		result = append(result, class)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Control statuses
+const (
+	StatusFailing = "failing" // Open findings violate the control
+	StatusPassing = "passing" // No open finding maps to the control
+)
+
+// ControlStatus is how a framework control fares against the findings
+type ControlStatus struct {
+	Control
+	Status          string         `json:"status"`
+	Findings        int            `json:"findings"`
+	BySeverity      map[string]int `json:"by_severity"`
+	HighestSeverity string         `json:"highest_severity,omitempty"`
+	// FindingIDs lists up to maxFindingIDs findings, most severe first
+	FindingIDs []string `json:"finding_ids"`
+}
+
+// maxFindingIDs bounds the findings listed per control
+const maxFindingIDs = 50
+
+// AssessmentSummary counts controls and findings of an assessment
+type AssessmentSummary struct {
+	Controls         int `json:"controls"`
+	ControlsFailing  int `json:"controls_failing"`
+	ControlsPassing  int `json:"controls_passing"`
+	Findings         int `json:"findings"`
+	FindingsMapped   int `json:"findings_mapped"`
+	FindingsUnmapped int `json:"findings_unmapped"`
+	// Coverage is the share of controls with no open findings, 0-100
+	Coverage float64 `json:"coverage"`
+}
+
+// Assessment maps a set of findings onto a framework's controls
+type Assessment struct {
+	Framework   string            `json:"framework"`
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	ScanID      string            `json:"scan_id,omitempty"`
+	Summary     AssessmentSummary `json:"summary"`
+	Controls    []ControlStatus   `json:"controls"`
+	Classes     map[string]string `json:"classes"` // OWASP class names, for readers of Control.Classes
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// Assess maps findings onto the framework's controls
+func Assess(framework *Framework, list []Finding) *Assessment {
+	// Most severe first, so capped finding lists keep the worst ones
+	sorted := append([]Finding(nil), list...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return findings.SeverityRank(sorted[i].Severity) < findings.SeverityRank(sorted[j].Severity)
+	})
+
+	byClass := make(map[string][]Finding)
+	mapped := 0
+	for _, f := range sorted {
+		classes := Classify(f)
+		inFramework := false
+		for _, class := range classes {
+			byClass[class] = append(byClass[class], f)
+			if framework.covers(class) {
+				inFramework = true
+			}
+		}
+		if inFramework {
+			mapped++
+		}
+	}
+
+	a := &Assessment{
+		Framework:   framework.ID,
+		Name:        framework.Name,
+		Version:     framework.Version,
+		Controls:    make([]ControlStatus, 0, len(framework.Controls)),
+		Classes:     classNames,
+		GeneratedAt: time.Now(),
+	}
+	for _, control := range framework.Controls {
+		status := ControlStatus{Control: control, Status: StatusPassing, BySeverity: map[string]int{}, FindingIDs: []string{}}
+		seen := make(map[string]bool)
+		for _, class := range control.Classes {
+			for _, f := range byClass[class] {
+				if seen[f.ID] {
+					continue
+				}
+				seen[f.ID] = true
+				status.Findings++
+				status.BySeverity[f.Severity]++
+				if status.HighestSeverity == "" || findings.SeverityRank(f.Severity) < findings.SeverityRank(status.HighestSeverity) {
+					status.HighestSeverity = f.Severity
+				}
+				if len(status.FindingIDs) < maxFindingIDs {
+					status.FindingIDs = append(status.FindingIDs, f.ID)
+				}
+			}
+		}
+		if status.Findings > 0 {
+			status.Status = StatusFailing
+			a.Summary.ControlsFailing++
+		} else {
+			a.Summary.ControlsPassing++
+		}
+		a.Controls = append(a.Controls, status)
+	}
+
+	a.Summary.Controls = len(framework.Controls)
+	a.Summary.Findings = len(list)
+	a.Summary.FindingsMapped = mapped
+	a.Summary.FindingsUnmapped = len(list) - mapped
+	if a.Summary.Controls > 0 {
+		a.Summary.Coverage = float64(a.Summary.ControlsPassing) * 100 / float64(a.Summary.Controls)
+	}
+	return a
+}
+
+// covers reports whether any control of the framework maps class
+func (f *Framework) covers(class string) bool {
+	for _, control := range f.Controls {
+		for _, c := range control.Classes {
+			if c == class {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findingColumns selects every Finding field
+const findingColumns = `v.id, v.title, v.severity, v.category, v.owasp_category, v.cve_id, v.cwe_ids`
+
+// LoadOpenFindings loads the organization's open findings, or one scan's when
+// scanID is set. Suppressed findings and false positives are left out; across
+// scans each issue counts once, as last reported.
+func LoadOpenFindings(ctx context.Context, db *database.DB, orgID, scanID string) ([]Finding, error) {
+	findings := []Finding{}
+	err := db.Reader().SelectContext(ctx, &findings, `
+		SELECT DISTINCT ON (v.fingerprint) `+findingColumns+`
+		FROM vulnerabilities v
+		WHERE v.organization_id IS NOT DISTINCT FROM NULLIF($1, '')::uuid
+		AND ($2 = '' OR v.scan_job_id::text = $2)
+		AND COALESCE(v.status, 'open') IN ('open', 'confirmed')
+		AND v.suppressed_at IS NULL
+		ORDER BY v.fingerprint, v.discovered_at DESC
+	`, orgID, scanID)
+	return findings, err
+}
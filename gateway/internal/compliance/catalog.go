@@ -0,0 +1,177 @@
+// Package compliance maps findings onto the controls of security frameworks
+// (PCI DSS, ISO 27001, NIST CSF) and assesses which controls are affected.
+//
+// Findings are first classified into OWASP Top 10 (2021) weakness classes by
+// CWE, category and CVE; each framework maps those classes to its controls.
+package compliance
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Framework is a compliance framework and the controls findings map to
+type Framework struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Version  string    `json:"version"`
+	Controls []Control `json:"controls"`
+}
+
+// Control is a framework requirement that findings can violate
+type Control struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Classes are the OWASP Top 10 weakness classes (A01-A10) whose findings
+	// violate the control
+	Classes []string `json:"classes"`
+}
+
+// Framework IDs, which double as report types
+const (
+	FrameworkPCIDSS   = "pci-dss"
+	FrameworkISO27001 = "iso-27001"
+	FrameworkNISTCSF  = "nist-csf"
+)
+
+var frameworks = []Framework{
+	{
+		ID: FrameworkPCIDSS, Name: "PCI DSS", Version: "4.0",
+		Controls: []Control{
+			{ID: "2.2.1", Title: "Configuration standards are developed, implemented and maintained", Classes: []string{"A05"}},
+			{ID: "3.5.1", Title: "PAN is rendered unreadable anywhere it is stored", Classes: []string{"A02"}},
+			{ID: "4.2.1", Title: "Strong cryptography protects PAN during transmission over open, public networks", Classes: []string{"A02"}},
+			{ID: "6.2.4", Title: "Software engineering techniques prevent or mitigate common software attacks", Classes: []string{"A01", "A03", "A04", "A08", "A10"}},
+			{ID: "6.3.3", Title: "System components are protected from known vulnerabilities by installing security patches", Classes: []string{"A06"}},
+			{ID: "7.2.1", Title: "An access control model is defined and restricts access appropriately", Classes: []string{"A01"}},
+			{ID: "8.3.1", Title: "All user and administrator access is authenticated", Classes: []string{"A07"}},
+			{ID: "10.2.1", Title: "Audit logs are enabled and active for all system components", Classes: []string{"A09"}},
+		},
+	},
+	{
+		ID: FrameworkISO27001, Name: "ISO/IEC 27001", Version: "2022",
+		Controls: []Control{
+			{ID: "A.5.15", Title: "Access control", Classes: []string{"A01"}},
+			{ID: "A.8.5", Title: "Secure authentication", Classes: []string{"A07"}},
+			{ID: "A.8.8", Title: "Management of technical vulnerabilities", Classes: []string{"A06"}},
+			{ID: "A.8.9", Title: "Configuration management", Classes: []string{"A05"}},
+			{ID: "A.8.15", Title: "Logging", Classes: []string{"A09"}},
+			{ID: "A.8.20", Title: "Networks security", Classes: []string{"A10"}},
+			{ID: "A.8.24", Title: "Use of cryptography", Classes: []string{"A02"}},
+			{ID: "A.8.26", Title: "Application security requirements", Classes: []string{"A04", "A08"}},
+			{ID: "A.8.28", Title: "Secure coding", Classes: []string{"A03", "A04", "A08", "A10"}},
+		},
+	},
+	{
+		ID: FrameworkNISTCSF, Name: "NIST Cybersecurity Framework", Version: "2.0",
+		Controls: []Control{
+			{ID: "ID.RA-01", Title: "Vulnerabilities in assets are identified, validated and recorded", Classes: []string{"A06"}},
+			{ID: "PR.AA-03", Title: "Users, services and hardware are authenticated", Classes: []string{"A07"}},
+			{ID: "PR.AA-05", Title: "Access permissions are defined, managed and enforced", Classes: []string{"A01"}},
+			{ID: "PR.DS-01", Title: "The confidentiality, integrity and availability of data-at-rest are protected", Classes: []string{"A02"}},
+			{ID: "PR.DS-02", Title: "The confidentiality, integrity and availability of data-in-transit are protected", Classes: []string{"A02"}},
+			{ID: "PR.IR-01", Title: "Networks and environments are protected from unauthorized logical access and usage", Classes: []string{"A10"}},
+			{ID: "PR.PS-01", Title: "Configuration management practices are established and applied", Classes: []string{"A05"}},
+			{ID: "PR.PS-02", Title: "Software is maintained, replaced and removed commensurate with risk", Classes: []string{"A06"}},
+			{ID: "PR.PS-04", Title: "Log records are generated and made available for continuous monitoring", Classes: []string{"A09"}},
+			{ID: "PR.PS-06", Title: "Secure software development practices are integrated", Classes: []string{"A03", "A04", "A08", "A10"}},
+		},
+	},
+}
+
+// Frameworks returns every supported framework
+func Frameworks() []Framework {
+	return frameworks
+}
+
+// LookupFramework finds a framework by ID
+func LookupFramework(id string) (*Framework, bool) {
+	for i := range frameworks {
+		if frameworks[i].ID == id {
+			return &frameworks[i], true
+		}
+	}
+	return nil, false
+}
+
+// IsReportType reports whether a report type asks for a compliance report,
+// which is named after its framework
+func IsReportType(reportType string) bool {
+	_, ok := LookupFramework(reportType)
+	return ok
+}
+
+// classNames are the OWASP Top 10 (2021) weakness classes
+var classNames = map[string]string{
+	"A01": "Broken Access Control",
+	"A02": "Cryptographic Failures",
+	"A03": "Injection",
+	"A04": "Insecure Design",
+	"A05": "Security Misconfiguration",
+	"A06": "Vulnerable and Outdated Components",
+	"A07": "Identification and Authentication Failures",
+	"A08": "Software and Data Integrity Failures",
+	"A09": "Security Logging and Monitoring Failures",
+	"A10": "Server-Side Request Forgery",
+}
+
+// cweClasses maps CWEs to OWASP Top 10 classes, after OWASP's own mapping of
+// the CWEs most often reported by scanners
+var cweClasses = map[int][]string{}
+
+func init() {
+	for class, cwes := range map[string][]int{
+		"A01": {22, 23, 35, 59, 200, 201, 219, 264, 275, 276, 284, 285, 352, 359, 377, 402, 425, 441, 497, 538, 540, 548, 552, 566, 601, 639, 651, 668, 706, 862, 863, 913, 922, 1275},
+		"A02": {259, 261, 296, 310, 319, 321, 322, 323, 324, 325, 326, 327, 328, 329, 330, 331, 335, 336, 337, 338, 340, 347, 523, 720, 757, 759, 760, 780, 818, 916},
+		"A03": {20, 74, 75, 77, 78, 79, 80, 83, 87, 88, 89, 90, 91, 93, 94, 95, 96, 97, 98, 99, 113, 116, 138, 184, 470, 471, 564, 610, 643, 644, 652, 917},
+		"A04": {73, 183, 209, 213, 235, 256, 257, 266, 269, 280, 311, 312, 313, 316, 419, 430, 434, 444, 451, 472, 501, 522, 525, 539, 579, 598, 602, 642, 646, 650, 653, 656, 657, 799, 807, 840, 841, 927, 1021, 1173},
+		"A05": {2, 11, 13, 15, 16, 260, 315, 520, 526, 537, 541, 547, 611, 614, 756, 776, 942, 1004, 1032, 1174},
+		"A06": {937, 1035, 1104},
+		"A07": {255, 287, 288, 290, 294, 295, 297, 300, 302, 304, 306, 307, 346, 384, 521, 613, 620, 640, 798, 940, 1216},
+		"A08": {345, 353, 426, 494, 502, 565, 784, 829, 830, 915},
+		"A09": {117, 223, 532, 778},
+		"A10": {918},
+	} {
+		for _, cwe := range cwes {
+			cweClasses[cwe] = append(cweClasses[cwe], class)
+		}
+	}
+}
+
+// categoryKeywords classify free-text finding categories and titles when no
+// CWE or OWASP category is known. Checked in order; the first match wins.
+var categoryKeywords = []struct {
+	class    string
+	keywords []string
+}{
+	{"A10", []string{"ssrf", "server-side request", "server side request"}},
+	{"A03", []string{"injection", "xss", "cross-site scripting", "cross site scripting", "sqli", "xxe", "template"}},
+	{"A02", []string{"crypto", "tls", "ssl", "cipher", "certificate", "encryption", "plaintext", "hsts"}},
+	{"A07", []string{"authentication", "password", "credential", "session", "brute force", "login"}},
+	{"A01", []string{"access control", "authorization", "idor", "path traversal", "directory traversal", "csrf", "open redirect", "privilege"}},
+	{"A06", []string{"outdated", "vulnerable component", "end of life", "end-of-life", "unpatched", "version disclosure"}},
+	{"A05", []string{"misconfiguration", "default", "directory listing", "header", "cors", "exposed", "debug"}},
+	{"A08", []string{"deserialization", "integrity", "subresource"}},
+	{"A09", []string{"logging", "monitoring", "audit"}},
+	{"A04", []string{"design", "business logic", "rate limit"}},
+}
+
+// owaspClass extracts an OWASP Top 10 class from labels like
+// "A03:2021 - Injection"
+var owaspClass = regexp.MustCompile(`\bA(0[1-9]|10):2021\b`)
+
+// cweID parses "CWE-79" into 79
+var cweID = regexp.MustCompile(`^CWE-(\d+)$`)
+
+// keywordClass classifies text by categoryKeywords
+func keywordClass(text string) (string, bool) {
+	text = strings.ToLower(text)
+	for _, k := range categoryKeywords {
+		for _, keyword := range k.keywords {
+			if strings.Contains(text, keyword) {
+				return k.class, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,309 @@
+// Package console relays raw tool output from running scans to WebSocket
+// clients. Workers push stdout and stderr lines over gRPC; the gateway that
+// receives them rate limits each scan, keeps the last few kilobytes in Redis
+// for clients that subscribe late, and fans the lines out to every gateway
+// instance through Redis pub/sub.
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// TopicPrefix starts every console topic; clients subscribe to
+// scan_console:<scan id>
+const TopicPrefix = "scan_console:"
+
+const (
+	bufferPrefix = "console:buf:"
+	sizePrefix   = "console:size:"
+	seqPrefix    = "console:seq:"
+	channel      = "console:output"
+)
+
+// Topic is the WebSocket topic carrying a scan's console output
+func Topic(scanID string) string {
+	return TopicPrefix + scanID
+}
+
+// Config bounds what a scan's console may cost
+type Config struct {
+	RetainBytes  int           // Output kept per scan for late subscribers
+	Retention    time.Duration // How long a quiet scan's output is kept
+	MaxLineBytes int           // Longer lines are truncated
+	RateBytes    int           // Sustained output relayed per scan per second
+	BurstBytes   int           // Output relayed at once before the rate applies
+}
+
+func DefaultConfig() Config {
+	return Config{
+		RetainBytes:  64 * 1024,
+		Retention:    time.Hour,
+		MaxLineBytes: 4096,
+		RateBytes:    32 * 1024,
+		BurstBytes:   128 * 1024,
+	}
+}
+
+// appendScript sequences a chunk, stores it and publishes it in one step so
+// subscribers on every instance see chunks in sequence order. The chunk's
+// JSON object gets a leading seq field. The oldest chunks are trimmed until
+// the buffer fits in ARGV[2] bytes, always keeping the newest.
+var appendScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[3])
+local entry = '{"seq":' .. seq .. ',' .. string.sub(ARGV[1], 2)
+redis.call('RPUSH', KEYS[1], entry)
+local size = redis.call('INCRBY', KEYS[2], string.len(entry))
+while size > tonumber(ARGV[2]) and redis.call('LLEN', KEYS[1]) > 1 do
+	local oldest = redis.call('LPOP', KEYS[1])
+	size = redis.call('DECRBY', KEYS[2], string.len(oldest))
+end
+for i = 1, 3 do
+	redis.call('EXPIRE', KEYS[i], ARGV[3])
+end
+redis.call('PUBLISH', ARGV[4], entry)
+return seq
+`)
+
+// entry is a stored chunk
+type entry struct {
+	Seq int64 `json:"seq"`
+	realtime.ScanConsoleEvent
+}
+
+// limiter is a scan's token bucket, in bytes
+type limiter struct {
+	tokens  float64
+	last    time.Time
+	dropped int // Lines dropped since the last relayed chunk
+}
+
+// Service relays console output and serves the console topics
+type Service struct {
+	redis  *redis.Client
+	db     *database.DB
+	roles  *rbac.RoleStore
+	hub    *realtime.Hub
+	config Config
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	limiters map[string]*limiter
+}
+
+func NewService(redisClient *redis.Client, db *database.DB, roles *rbac.RoleStore, hub *realtime.Hub, config Config, logger *zap.Logger) *Service {
+	return &Service{
+		redis:    redisClient,
+		db:       db,
+		roles:    roles,
+		hub:      hub,
+		config:   config,
+		logger:   logger,
+		limiters: make(map[string]*limiter),
+	}
+}
+
+// Append relays lines of a scan's output, dropping those over the scan's
+// rate limit, and reports how many were relayed and dropped
+func (s *Service) Append(ctx context.Context, scanID string, lines []realtime.ConsoleLine) (int, int, error) {
+	accepted, carried := s.admit(scanID, lines)
+	dropped := len(lines) - len(accepted)
+	metrics.ScanConsoleLines.WithLabelValues("dropped").Add(float64(dropped))
+	if len(accepted) == 0 {
+		return 0, dropped, nil
+	}
+
+	payload, err := json.Marshal(realtime.ScanConsoleEvent{ScanID: scanID, Lines: accepted, Dropped: carried})
+	if err != nil {
+		return 0, dropped, fmt.Errorf("failed to encode console output: %w", err)
+	}
+	keys := []string{bufferPrefix + scanID, sizePrefix + scanID, seqPrefix + scanID}
+	err = appendScript.Run(ctx, s.redis, keys, payload, s.config.RetainBytes, int(s.config.Retention.Seconds()), channel).Err()
+	if err != nil {
+		s.requeueDropped(scanID, carried)
+		return 0, dropped, fmt.Errorf("failed to store console output: %w", err)
+	}
+	metrics.ScanConsoleLines.WithLabelValues("relayed").Add(float64(len(accepted)))
+	return len(accepted), dropped, nil
+}
+
+// admit takes the lines the scan's bucket has room for, truncating long
+// ones, and returns them with the count of lines dropped before them
+func (s *Service) admit(scanID string, lines []realtime.ConsoleLine) ([]realtime.ConsoleLine, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	l, ok := s.limiters[scanID]
+	if !ok {
+		l = &limiter{tokens: float64(s.config.BurstBytes), last: now}
+		s.limiters[scanID] = l
+	}
+	l.tokens += now.Sub(l.last).Seconds() * float64(s.config.RateBytes)
+	if l.tokens > float64(s.config.BurstBytes) {
+		l.tokens = float64(s.config.BurstBytes)
+	}
+	l.last = now
+
+	accepted := make([]realtime.ConsoleLine, 0, len(lines))
+	for _, line := range lines {
+		line.Text = truncate(line.Text, s.config.MaxLineBytes)
+		cost := float64(len(line.Text) + 1)
+		if cost > l.tokens {
+			l.dropped++
+			continue
+		}
+		l.tokens -= cost
+		accepted = append(accepted, line)
+	}
+	if len(accepted) == 0 {
+		return nil, 0
+	}
+	carried := l.dropped
+	l.dropped = 0
+	return accepted, carried
+}
+
+// requeueDropped carries a dropped count that could not be relayed over to
+// the next chunk
+func (s *Service) requeueDropped(scanID string, dropped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.limiters[scanID]; ok {
+		l.dropped += dropped
+	}
+}
+
+// truncate cuts text to at most max bytes without splitting a character
+func truncate(text string, max int) string {
+	if max <= 0 || len(text) <= max {
+		return text
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// Start relays output published by any instance to this instance's
+// subscribers until ctx is done. Output published while the subscription is
+// reconnecting reaches clients only through the backlog.
+func (s *Service) Start(ctx context.Context) {
+	pubsub := s.redis.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	prune := time.NewTicker(time.Minute)
+	defer prune.Stop()
+
+	s.logger.Info("Relaying scan console output",
+		zap.Int("retain_bytes", s.config.RetainBytes),
+		zap.Int("rate_bytes", s.config.RateBytes),
+	)
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-prune.C:
+			s.pruneLimiters(time.Minute)
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var e entry
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				s.logger.Warn("Ignoring malformed console output", zap.Error(err))
+				continue
+			}
+			s.hub.PublishSequenced(Topic(e.ScanID), e.Seq, e.ScanConsoleEvent)
+		}
+	}
+}
+
+// pruneLimiters forgets scans that have been quiet for idle. A bucket idle
+// that long has refilled, so only an unreported dropped count is lost.
+func (s *Service) pruneLimiters(idle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for scanID, l := range s.limiters {
+		if time.Since(l.last) > idle {
+			delete(s.limiters, scanID)
+		}
+	}
+}
+
+// Authorize lets members who may view scans subscribe to the console of a
+// scan in the organization they are connected as
+func (s *Service) Authorize(ctx context.Context, caller realtime.Identity, topic string) error {
+	scanID := strings.TrimPrefix(topic, TopicPrefix)
+	if _, err := uuid.Parse(scanID); err != nil {
+		return &realtime.CommandError{Code: "invalid_request", Message: "Invalid scan ID"}
+	}
+	if caller.OrgID == "" {
+		return &realtime.CommandError{Code: "invalid_request", Message: "Organization context required"}
+	}
+
+	role, err := s.roles.MemberRole(ctx, caller.UserID, caller.OrgID)
+	if errors.Is(err, rbac.ErrNotMember) {
+		return &realtime.CommandError{Code: "forbidden", Message: "Access denied"}
+	}
+	if err != nil {
+		return err
+	}
+	allowed, err := s.roles.HasPermission(ctx, caller.OrgID, role, rbac.PermViewScan)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &realtime.CommandError{Code: "forbidden", Message: "You do not have permission to view scans"}
+	}
+
+	var exists bool
+	err = s.db.Reader().GetContext(ctx, &exists, `
+		SELECT EXISTS (SELECT 1 FROM scan_jobs WHERE id = $1 AND organization_id = $2)
+	`, scanID, caller.OrgID)
+	if err != nil {
+		return fmt.Errorf("failed to look up scan: %w", err)
+	}
+	if !exists {
+		return &realtime.CommandError{Code: "not_found", Message: "Scan not found"}
+	}
+	return nil
+}
+
+// Backlog returns the retained output of a console topic after afterSeq
+func (s *Service) Backlog(ctx context.Context, topic string, afterSeq int64) ([]realtime.SequencedEvent, error) {
+	scanID := strings.TrimPrefix(topic, TopicPrefix)
+	raw, err := s.redis.LRange(ctx, bufferPrefix+scanID, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load console output: %w", err)
+	}
+
+	events := make([]realtime.SequencedEvent, 0, len(raw))
+	for _, payload := range raw {
+		var e entry
+		if err := json.Unmarshal([]byte(payload), &e); err != nil {
+			continue
+		}
+		if e.Seq > afterSeq {
+			events = append(events, realtime.SequencedEvent{Seq: e.Seq, Event: e.ScanConsoleEvent})
+		}
+	}
+	return events, nil
+}
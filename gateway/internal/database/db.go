@@ -0,0 +1,205 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Config controls query timeouts and slow-query logging
+type Config struct {
+	// QueryTimeout bounds each query unless the context already has an
+	// earlier deadline; zero disables it
+	QueryTimeout time.Duration
+	// SlowQueryThreshold logs queries that take longer; zero disables it
+	SlowQueryThreshold time.Duration
+}
+
+// DefaultConfig suits interactive API traffic
+func DefaultConfig() Config {
+	return Config{
+		QueryTimeout:       30 * time.Second,
+		SlowQueryThreshold: 500 * time.Millisecond,
+	}
+}
+
+// DB wraps sqlx.DB so that Get, Select and Exec (with or without a context)
+// run under a timeout, are timed per query name and are logged when slow.
+// Everything else, including transactions and row iterators whose lifetime
+// outlives the call, goes straight to the embedded sqlx.DB.
+type DB struct {
+	*sqlx.DB
+	config  Config
+	logger  *zap.Logger
+	role    string   // "primary" or "replica", for metrics
+	primary *DB      // set on a replica: where failed reads are retried
+	replica *replica // set on the primary when a read replica is attached
+}
+
+// New wraps an open connection pool
+func New(db *sqlx.DB, config Config, logger *zap.Logger) *DB {
+	return &DB{
+		DB:     db,
+		config: config,
+		logger: logger,
+		role:   "primary",
+	}
+}
+
+// Connect opens and pings a connection pool, like sqlx.Connect
+func Connect(driverName, dsn string, config Config, logger *zap.Logger) (*DB, error) {
+	db, err := sqlx.Connect(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return New(db, config, logger), nil
+}
+
+type queryNameKey struct{}
+
+// WithQueryName labels queries made with ctx in metrics and slow-query logs.
+// Without it the name is derived from the statement, e.g. "select:sessions".
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// GetContext runs a single-row query into dest
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	err := db.observe(ctx, query, func(ctx context.Context) error {
+		return db.DB.GetContext(ctx, dest, query, args...)
+	})
+	if db.fallback(err) {
+		return db.primary.GetContext(ctx, dest, query, args...)
+	}
+	return err
+}
+
+// SelectContext runs a multi-row query into dest
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	err := db.observe(ctx, query, func(ctx context.Context) error {
+		return db.DB.SelectContext(ctx, dest, query, args...)
+	})
+	if db.fallback(err) {
+		return db.primary.SelectContext(ctx, dest, query, args...)
+	}
+	return err
+}
+
+// ExecContext runs a statement that returns no rows
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := db.observe(ctx, query, func(ctx context.Context) error {
+		var err error
+		result, err = db.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// NamedExecContext runs a statement with named parameters
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := db.observe(ctx, query, func(ctx context.Context) error {
+		var err error
+		result, err = db.DB.NamedExecContext(ctx, query, arg)
+		return err
+	})
+	return result, err
+}
+
+// Get is GetContext without a caller context
+func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	return db.GetContext(context.Background(), dest, query, args...)
+}
+
+// Select is SelectContext without a caller context
+func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	return db.SelectContext(context.Background(), dest, query, args...)
+}
+
+// Exec is ExecContext without a caller context
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// observe applies the timeout, records the duration and logs slow queries
+func (db *DB) observe(ctx context.Context, query string, run func(ctx context.Context) error) error {
+	if db.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, db.config.QueryTimeout)
+		defer cancel()
+	}
+
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	if name == "" {
+		name = QueryName(query)
+	}
+
+	start := time.Now()
+	err := run(ctx)
+	elapsed := time.Since(start)
+
+	status := "ok"
+	switch {
+	case err == nil, err == sql.ErrNoRows:
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		status = "timeout"
+	default:
+		status = "error"
+	}
+	metrics.DBQueryDuration.WithLabelValues(name, status, db.role).Observe(elapsed.Seconds())
+
+	if db.config.SlowQueryThreshold > 0 && elapsed >= db.config.SlowQueryThreshold {
+		metrics.DBSlowQueries.WithLabelValues(name).Inc()
+		db.logger.Warn("Slow query",
+			zap.String("query_name", name),
+			zap.String("role", db.role),
+			zap.Duration("duration", elapsed),
+			zap.String("status", status),
+			zap.String("query", compact(query)),
+		)
+	}
+	if status == "timeout" {
+		db.logger.Error("Query timed out",
+			zap.String("query_name", name),
+			zap.Duration("timeout", db.config.QueryTimeout),
+		)
+	}
+
+	return err
+}
+
+var (
+	whitespace   = regexp.MustCompile(`\s+`)
+	tableAfterRe = regexp.MustCompile(`(?i)\b(?:from|into|update|join)\s+([a-z_][a-z0-9_.]*)`)
+)
+
+// QueryName derives a low-cardinality label from a statement: the leading
+// verb and the first table it touches
+func QueryName(query string) string {
+	q := strings.TrimSpace(query)
+	verb := strings.ToLower(strings.SplitN(q, " ", 2)[0])
+	if i := strings.IndexAny(verb, "\n\t("); i >= 0 {
+		verb = verb[:i]
+	}
+	if verb == "" {
+		return "unknown"
+	}
+
+	if m := tableAfterRe.FindStringSubmatch(q); m != nil {
+		return verb + ":" + strings.ToLower(m[1])
+	}
+	return verb
+}
+
+// compact collapses whitespace so statements log on one line
+func compact(query string) string {
+	return strings.TrimSpace(whitespace.ReplaceAllString(query, " "))
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+)
+
+// PoolStats is a JSON-friendly snapshot of the connection pool
+type PoolStats struct {
+	MaxOpen      int           `json:"max_open"`
+	Open         int           `json:"open"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration_ns"`
+}
+
+// PoolStats reports the current state of the connection pool
+func (db *DB) PoolStats() PoolStats {
+	s := db.Stats()
+	return PoolStats{
+		MaxOpen:      s.MaxOpenConnections,
+		Open:         s.OpenConnections,
+		InUse:        s.InUse,
+		Idle:         s.Idle,
+		WaitCount:    s.WaitCount,
+		WaitDuration: s.WaitDuration,
+	}
+}
+
+// StartPoolMonitor publishes pool statistics as gauges until ctx is cancelled
+func (db *DB) StartPoolMonitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		db.recordPoolStats()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (db *DB) recordPoolStats() {
+	s := db.PoolStats()
+	metrics.DBConnections.WithLabelValues("max_open").Set(float64(s.MaxOpen))
+	metrics.DBConnections.WithLabelValues("open").Set(float64(s.Open))
+	metrics.DBConnections.WithLabelValues("in_use").Set(float64(s.InUse))
+	metrics.DBConnections.WithLabelValues("idle").Set(float64(s.Idle))
+	metrics.DBConnectionWaits.Set(float64(s.WaitCount))
+	metrics.DBConnectionWaitSeconds.Set(s.WaitDuration.Seconds())
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// ReplicaConfig controls when reads are sent to the replica
+type ReplicaConfig struct {
+	// MaxLag takes the replica out of rotation while it is further behind
+	MaxLag time.Duration
+	// CheckInterval is how often lag and reachability are measured
+	CheckInterval time.Duration
+}
+
+// DefaultReplicaConfig tolerates a few seconds of lag on dashboards and exports
+func DefaultReplicaConfig() ReplicaConfig {
+	return ReplicaConfig{
+		MaxLag:        30 * time.Second,
+		CheckInterval: 10 * time.Second,
+	}
+}
+
+// replica is a read-only pool that falls back to the primary
+type replica struct {
+	db      *DB
+	config  ReplicaConfig
+	healthy atomic.Bool
+}
+
+// AttachReplica routes Reader() queries to a read replica. The replica
+// starts healthy; StartReplicaMonitor keeps its health and lag up to date.
+func (db *DB) AttachReplica(conn *sqlx.DB, config ReplicaConfig) {
+	r := &replica{
+		db:     &DB{DB: conn, config: db.config, logger: db.logger, role: "replica", primary: db},
+		config: config,
+	}
+	r.healthy.Store(true)
+	db.replica = r
+	metrics.DBReplicaHealthy.Set(1)
+}
+
+// ErrReplicaUnhealthy is returned by CheckReplica while reads are served by
+// the primary because the replica is unreachable or lagging
+var ErrReplicaUnhealthy = errors.New("read replica is out of rotation")
+
+// HasReplica reports whether a read replica is attached
+func (db *DB) HasReplica() bool {
+	return db.replica != nil
+}
+
+// CheckReplica reports the replica's health as last measured by the monitor.
+// It is nil without a replica.
+func (db *DB) CheckReplica(ctx context.Context) error {
+	if db.replica != nil && !db.replica.healthy.Load() {
+		return ErrReplicaUnhealthy
+	}
+	return nil
+}
+
+// Reader returns the pool for read-only queries that tolerate replication
+// lag: the replica while it is healthy, otherwise the primary. Queries on the
+// replica that fail to reach it are retried on the primary.
+func (db *DB) Reader() *DB {
+	if db.replica != nil && db.replica.healthy.Load() {
+		return db.replica.db
+	}
+	return db
+}
+
+// StartReplicaMonitor measures replica lag until ctx is cancelled. It is a
+// no-op without a replica.
+func (db *DB) StartReplicaMonitor(ctx context.Context) {
+	r := db.replica
+	if r == nil {
+		return
+	}
+
+	ticker := time.NewTicker(r.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		r.check(ctx, db.logger)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check records the replica's lag and takes it out of rotation when it is
+// unreachable or too far behind
+func (r *replica) check(ctx context.Context, logger *zap.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Replay lag; zero when the replica has nothing left to replay
+	var lagSeconds sql.NullFloat64
+	err := r.db.DB.GetContext(ctx, &lagSeconds, `
+		SELECT CASE
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp())
+		END
+	`)
+
+	healthy := err == nil
+	if err != nil {
+		logger.Warn("Read replica unreachable, reading from primary", zap.Error(err))
+	} else if lagSeconds.Valid {
+		metrics.DBReplicaLagSeconds.Set(lagSeconds.Float64)
+		lag := time.Duration(lagSeconds.Float64 * float64(time.Second))
+		if r.config.MaxLag > 0 && lag > r.config.MaxLag {
+			healthy = false
+			logger.Warn("Read replica lagging, reading from primary", zap.Duration("lag", lag))
+		}
+	}
+
+	r.setHealthy(healthy, logger)
+}
+
+func (r *replica) setHealthy(healthy bool, logger *zap.Logger) {
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		metrics.DBReplicaHealthy.Set(1)
+		logger.Info("Read replica back in rotation")
+	} else {
+		metrics.DBReplicaHealthy.Set(0)
+	}
+}
+
+// fallback reports whether a failed replica query should be retried on the
+// primary. Errors returned by the server (bad SQL, missing rows) are final,
+// except recovery conflicts; connection failures also take the replica out
+// of rotation until the next health check.
+func (db *DB) fallback(err error) bool {
+	if db.primary == nil || err == nil || err == sql.ErrNoRows {
+		return false
+	}
+
+	var pqErr *pq.Error
+	var netErr net.Error
+	switch {
+	case errors.As(err, &pqErr):
+		// 40001: the replica cancelled the query to apply WAL
+		if pqErr.Code != "40001" {
+			return false
+		}
+	case errors.As(err, &netErr), errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		if db.primary.replica != nil {
+			db.primary.replica.setHealthy(false, db.logger)
+		}
+	default:
+		return false
+	}
+
+	metrics.DBReplicaFallbacks.Inc()
+	db.logger.Warn("Replica query failed, retrying on primary", zap.Error(err))
+	return true
+}
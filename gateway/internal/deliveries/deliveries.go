@@ -0,0 +1,541 @@
+// Package deliveries sends organizations' outbound notifications (webhooks
+// signed with HMAC-SHA256, and Slack messages) and logs every attempt with
+// its request and response, so integrations that fail are visible instead of
+// silent. Failed attempts can be replayed exactly as they were first sent.
+// Request bodies are kept sealed with the organization's data key, and
+// bodies are redacted whenever they are shown.
+package deliveries
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/outbound"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/slack"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Channels
+const (
+	ChannelWebhook = "webhook"
+	ChannelSlack   = "slack"
+)
+
+// Sources of deliveries
+const (
+	SourceReportSchedule = "report_schedule"
+	SourceEscalation     = "escalation"
+	SourceSecurityAlert  = "security_alert"
+	SourceTest           = "test"
+)
+
+// Headers set on webhook requests
+const (
+	HeaderEvent     = "X-Cyper-Event"
+	HeaderDelivery  = "X-Cyper-Delivery"
+	HeaderSignature = "X-Cyper-Signature"
+)
+
+var (
+	ErrNotFound           = errors.New("delivery not found")
+	ErrNotReplayable      = errors.New("only failed deliveries can be replayed")
+	ErrSlackNotConfigured = errors.New("Slack is not configured")
+)
+
+// redacted replaces masked values, as in audit entries
+const redacted = "[REDACTED]"
+
+// linkToken matches credentials carried in link query strings, such as
+// acknowledgement and download links
+var linkToken = regexp.MustCompile(`(?i)([?&](?:token|sig|signature|key|code)=)[^&\s"'<>]+`)
+
+// Config tunes delivery and the attempt log
+type Config struct {
+	Timeout         time.Duration
+	MaxResponseSize int           // Response bytes kept per attempt
+	Retention       time.Duration // Attempts are deleted after this long
+	// Transport sends webhooks, under the outbound policy so user-supplied
+	// URLs cannot reach internal services; the default transport when nil
+	Transport http.RoundTripper
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Timeout:         10 * time.Second,
+		MaxResponseSize: 4 << 10,
+		Retention:       30 * 24 * time.Hour,
+	}
+}
+
+// Headers are request headers as sent, stored as JSONB
+type Headers map[string]string
+
+// Value stores headers as JSONB
+func (h Headers) Value() (driver.Value, error) {
+	if h == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(h)
+}
+
+// Scan loads headers from JSONB
+func (h *Headers) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*h = Headers{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	default:
+		return fmt.Errorf("unsupported headers type %T", src)
+	}
+}
+
+// Attempt is one delivery of a notification
+type Attempt struct {
+	ID             string    `json:"id" db:"id"`
+	OrganizationID string    `json:"organization_id" db:"organization_id"`
+	Channel        string    `json:"channel" db:"channel"`
+	Source         string    `json:"source" db:"source"`
+	SourceID       *string   `json:"source_id,omitempty" db:"source_id"`
+	Event          string    `json:"event" db:"event"`
+	Target         string    `json:"target" db:"target"` // Webhook URL or Slack channel
+	RequestHeaders Headers   `json:"request_headers" db:"request_headers"`
+	RequestBody    string    `json:"request_body" db:"request_body"`
+	ResponseStatus *int      `json:"response_status,omitempty" db:"response_status"`
+	ResponseBody   *string   `json:"response_body,omitempty" db:"response_body"`
+	Error          *string   `json:"error,omitempty" db:"error"`
+	DurationMS     int       `json:"duration_ms" db:"duration_ms"`
+	Succeeded      bool      `json:"succeeded" db:"succeeded"`
+	ReplayOf       *string   `json:"replay_of,omitempty" db:"replay_of"`
+	CreatedBy      *string   `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// Columns lists the attempt columns in Attempt's field order
+const Columns = `id, organization_id, channel, source, source_id, event, target, request_headers,
+	request_body, response_status, response_body, error, duration_ms, succeeded, replay_of, created_by, created_at`
+
+// Webhook is a notification to post to a URL
+type Webhook struct {
+	OrgID    string
+	Source   string
+	SourceID string
+	Event    string
+	URL      string
+	Secret   string      // Signs the body when set
+	Payload  interface{} // Sent as JSON
+	SentBy   string      // The user who sent it, for test events
+}
+
+// SlackMessage is a notification to post to a Slack channel or user
+type SlackMessage struct {
+	OrgID    string
+	Source   string
+	SourceID string
+	Event    string
+	Message  slack.Message
+	SentBy   string
+}
+
+// Filter narrows the attempts listed; empty fields match everything
+type Filter struct {
+	Channel string
+	Source  string
+	Outcome string // succeeded or failed
+	Limit   int
+}
+
+// Service delivers notifications and keeps the attempt log
+type Service struct {
+	db         *database.DB
+	cipher     repository.Cipher
+	slack      *slack.Client // Nil when no bot token is configured
+	redactor   *audit.Redactor
+	httpClient *http.Client
+	config     Config
+	logger     *zap.Logger
+}
+
+func NewService(db *database.DB, cipher repository.Cipher, slackClient *slack.Client, config Config, logger *zap.Logger) *Service {
+	redactor, err := audit.NewRedactor(audit.DefaultRedactionConfig())
+	if err != nil {
+		logger.Fatal("Failed to initialize delivery redaction", zap.Error(err))
+	}
+	return &Service{
+		db:         db,
+		cipher:     cipher,
+		slack:      slackClient,
+		redactor:   redactor,
+		httpClient: &http.Client{Timeout: config.Timeout, Transport: config.Transport},
+		config:     config,
+		logger:     logger,
+	}
+}
+
+// SlackConfigured reports whether Slack messages can be sent
+func (s *Service) SlackConfigured() bool {
+	return s.slack != nil
+}
+
+// SendWebhook posts the payload, signed with HMAC-SHA256 of the body when
+// the webhook has a secret, and logs the attempt. The error is the
+// delivery's; failing to log it does not fail the delivery.
+func (s *Service) SendWebhook(ctx context.Context, w Webhook) (*Attempt, error) {
+	body, err := json.Marshal(w.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := Headers{
+		"Content-Type": "application/json",
+		HeaderEvent:    w.Event,
+	}
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		headers[HeaderSignature] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	attempt := &Attempt{
+		OrganizationID: w.OrgID,
+		Channel:        ChannelWebhook,
+		Source:         w.Source,
+		SourceID:       optional(w.SourceID),
+		Event:          w.Event,
+		Target:         w.URL,
+		RequestHeaders: headers,
+		RequestBody:    string(body),
+		CreatedBy:      optional(w.SentBy),
+	}
+	return attempt, s.deliver(ctx, attempt)
+}
+
+// SendSlack posts the message and logs the attempt
+func (s *Service) SendSlack(ctx context.Context, m SlackMessage) (*Attempt, error) {
+	if s.slack == nil {
+		return nil, ErrSlackNotConfigured
+	}
+	body, err := json.Marshal(m.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := &Attempt{
+		OrganizationID: m.OrgID,
+		Channel:        ChannelSlack,
+		Source:         m.Source,
+		SourceID:       optional(m.SourceID),
+		Event:          m.Event,
+		Target:         m.Message.Channel,
+		RequestHeaders: Headers{"Content-Type": "application/json; charset=utf-8"},
+		RequestBody:    string(body),
+		CreatedBy:      optional(m.SentBy),
+	}
+	return attempt, s.deliver(ctx, attempt)
+}
+
+// Replay sends a failed attempt again exactly as it was first sent, and
+// logs it as a new attempt
+func (s *Service) Replay(ctx context.Context, orgID, attemptID, userID string) (*Attempt, error) {
+	original, err := s.load(ctx, orgID, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Succeeded {
+		return nil, ErrNotReplayable
+	}
+	if original.Channel == ChannelSlack && s.slack == nil {
+		return nil, ErrSlackNotConfigured
+	}
+
+	attempt := &Attempt{
+		OrganizationID: original.OrganizationID,
+		Channel:        original.Channel,
+		Source:         original.Source,
+		SourceID:       original.SourceID,
+		Event:          original.Event,
+		Target:         original.Target,
+		RequestHeaders: original.RequestHeaders,
+		RequestBody:    original.RequestBody,
+		ReplayOf:       &original.ID,
+		CreatedBy:      optional(userID),
+	}
+	err = s.deliver(ctx, attempt)
+	return s.Redacted(attempt), err
+}
+
+// deliver sends the attempt's request, then logs the attempt with its outcome
+func (s *Service) deliver(ctx context.Context, a *Attempt) error {
+	a.ID = uuid.New().String()
+	a.CreatedAt = time.Now().UTC()
+	if a.Channel == ChannelWebhook {
+		// Each attempt, replays included, has its own delivery ID
+		headers := make(Headers, len(a.RequestHeaders)+1)
+		for name, value := range a.RequestHeaders {
+			headers[name] = value
+		}
+		headers[HeaderDelivery] = a.ID
+		a.RequestHeaders = headers
+	}
+
+	// Blocked requests are attributed to the organization
+	ctx = outbound.WithOrganization(ctx, a.OrganizationID)
+
+	var status int
+	var response []byte
+	var err error
+	switch a.Channel {
+	case ChannelWebhook:
+		status, response, err = s.post(ctx, a)
+	case ChannelSlack:
+		status, response, err = s.postSlack(ctx, a)
+	default:
+		err = fmt.Errorf("unknown channel %q", a.Channel)
+	}
+	a.DurationMS = int(time.Since(a.CreatedAt).Milliseconds())
+	a.Succeeded = err == nil
+
+	if status != 0 {
+		a.ResponseStatus = &status
+	}
+	if response != nil {
+		if len(response) > s.config.MaxResponseSize {
+			response = response[:s.config.MaxResponseSize]
+		}
+		body := s.redact(string(response))
+		a.ResponseBody = &body
+	}
+	if err != nil {
+		msg := s.redact(err.Error())
+		a.Error = &msg
+	}
+
+	outcome := "sent"
+	if err != nil {
+		outcome = "failed"
+	}
+	metrics.IntegrationDeliveries.WithLabelValues(a.Channel, a.Source, outcome).Inc()
+
+	if logErr := s.record(ctx, a); logErr != nil {
+		s.logger.Error("Failed to record delivery attempt",
+			zap.String("organization_id", a.OrganizationID),
+			zap.String("channel", a.Channel),
+			zap.String("source", a.Source),
+			zap.Error(logErr),
+		)
+	}
+	return err
+}
+
+func (s *Service) post(ctx context.Context, a *Attempt) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Target, strings.NewReader(a.RequestBody))
+	if err != nil {
+		return 0, nil, err
+	}
+	for name, value := range a.RequestHeaders {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	response, err := io.ReadAll(io.LimitReader(resp.Body, int64(s.config.MaxResponseSize)))
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, response, fmt.Errorf("endpoint returned status: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, response, nil
+}
+
+func (s *Service) postSlack(ctx context.Context, a *Attempt) (int, []byte, error) {
+	var msg slack.Message
+	if err := json.Unmarshal([]byte(a.RequestBody), &msg); err != nil {
+		return 0, nil, err
+	}
+	resp, err := s.slack.Send(ctx, msg)
+	if resp == nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, resp.Body, err
+}
+
+func (s *Service) record(ctx context.Context, a *Attempt) error {
+	body, err := s.cipher.Encrypt(ctx, a.OrganizationID, a.RequestBody)
+	if err != nil {
+		return fmt.Errorf("failed to seal request body: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO delivery_attempts (id, organization_id, channel, source, source_id, event, target,
+			request_headers, request_body, response_status, response_body, error, duration_ms, succeeded,
+			replay_of, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`, a.ID, a.OrganizationID, a.Channel, a.Source, a.SourceID, a.Event, a.Target,
+		a.RequestHeaders, body, a.ResponseStatus, a.ResponseBody, a.Error, a.DurationMS, a.Succeeded,
+		a.ReplayOf, a.CreatedBy, a.CreatedAt)
+	return err
+}
+
+// List returns the organization's latest attempts, newest first, redacted
+func (s *Service) List(ctx context.Context, orgID string, f Filter) ([]*Attempt, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	attempts := []*Attempt{}
+	err := s.db.Reader().SelectContext(ctx, &attempts, `
+		SELECT `+Columns+` FROM delivery_attempts
+		WHERE organization_id = $1
+		AND ($2 = '' OR channel = $2)
+		AND ($3 = '' OR source = $3)
+		AND ($4 = '' OR succeeded = ($4 = 'succeeded'))
+		ORDER BY created_at DESC
+		LIMIT $5
+	`, orgID, f.Channel, f.Source, f.Outcome, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range attempts {
+		if err := s.open(ctx, a); err != nil {
+			return nil, err
+		}
+		attempts[i] = s.Redacted(a)
+	}
+	return attempts, nil
+}
+
+// Get returns an attempt of the organization, redacted
+func (s *Service) Get(ctx context.Context, orgID, attemptID string) (*Attempt, error) {
+	a, err := s.load(ctx, orgID, attemptID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Redacted(a), nil
+}
+
+// load returns an attempt with its request body as sent
+func (s *Service) load(ctx context.Context, orgID, attemptID string) (*Attempt, error) {
+	if _, err := uuid.Parse(attemptID); err != nil {
+		return nil, ErrNotFound
+	}
+	var a Attempt
+	err := s.db.GetContext(ctx, &a, `
+		SELECT `+Columns+` FROM delivery_attempts WHERE id = $1 AND organization_id = $2
+	`, attemptID, orgID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, s.open(ctx, &a)
+}
+
+func (s *Service) open(ctx context.Context, a *Attempt) error {
+	body, err := s.cipher.Decrypt(ctx, a.OrganizationID, a.RequestBody)
+	if err != nil {
+		return fmt.Errorf("failed to open request body: %w", err)
+	}
+	a.RequestBody = body
+	return nil
+}
+
+// Redacted is a copy of the attempt fit to show: the signature, credentials
+// and link tokens in the request are masked. Responses are redacted before
+// they are stored.
+func (s *Service) Redacted(a *Attempt) *Attempt {
+	out := *a
+	out.RequestHeaders = make(Headers, len(a.RequestHeaders))
+	for name, value := range a.RequestHeaders {
+		if name == HeaderSignature || s.redactor.MatchesKey(strings.ToLower(name)) {
+			value = redacted
+		}
+		out.RequestHeaders[name] = value
+	}
+	out.RequestBody = s.redact(a.RequestBody)
+	return &out
+}
+
+// redact masks credentials and link tokens in a body or message
+func (s *Service) redact(text string) string {
+	text = linkToken.ReplaceAllString(text, "${1}"+redacted)
+
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	if decoder.Decode(&doc) == nil {
+		masked, paths := s.redactor.Redact(doc)
+		if len(paths) == 0 {
+			return text
+		}
+		var out bytes.Buffer
+		encoder := json.NewEncoder(&out)
+		encoder.SetEscapeHTML(false)
+		if encoder.Encode(masked) == nil {
+			return strings.TrimSuffix(out.String(), "\n")
+		}
+	}
+	masked, _ := s.redactor.RedactString(text)
+	return masked
+}
+
+// Start deletes attempts older than the retention period until ctx is
+// cancelled
+func (s *Service) Start(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.deleteExpired(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deleteExpired keeps the attempts of organizations under legal hold
+func (s *Service) deleteExpired(ctx context.Context) {
+	deleted, held, err := legalhold.Purge(ctx, s.db, "delivery_attempts", "organization_id", "",
+		"created_at < $1", time.Now().Add(-s.config.Retention))
+	if err != nil {
+		s.logger.Error("Failed to delete expired delivery attempts", zap.Error(err))
+		return
+	}
+	if deleted > 0 || held > 0 {
+		s.logger.Info("Deleted expired delivery attempts", zap.Int64("count", deleted), zap.Int64("held", held))
+	}
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
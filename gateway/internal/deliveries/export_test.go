@@ -0,0 +1,4 @@
+package deliveries
+
+// DeleteExpired runs one retention pass for the external tests
+var DeleteExpired = (*Service).deleteExpired
@@ -0,0 +1,48 @@
+//go:build integration
+
+package deliveries_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/deliveries"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+func TestDeleteExpiredKeepsHeldOrganizations(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	admin := env.CreateUser(t, testenv.UserOptions{Role: "admin"})
+	heldOrg := env.CreateOrganization(t, "Held")
+	freeOrg := env.CreateOrganization(t, "Free")
+	if _, err := legalhold.NewService(env.DB, env.Logger).Place(ctx, legalhold.ScopeOrganization, heldOrg.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := time.Now().Add(-48 * time.Hour)
+	for _, orgID := range []string{heldOrg.ID, freeOrg.ID} {
+		_, err := env.DB.ExecContext(ctx, `
+			INSERT INTO delivery_attempts (organization_id, channel, source, event, target, request_body, succeeded, created_at)
+			VALUES ($1, 'webhook', 'test', 'scan.completed', 'https://example.com/hook', '{}', true, $2)
+		`, orgID, expired)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := deliveries.DefaultConfig()
+	config.Retention = 24 * time.Hour
+	deliveries.DeleteExpired(deliveries.NewService(env.DB, repository.Plaintext, nil, config, env.Logger), ctx)
+
+	var remaining []string
+	if err := env.DB.SelectContext(ctx, &remaining, `SELECT organization_id FROM delivery_attempts`); err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0] != heldOrg.ID {
+		t.Fatalf("remaining attempts belong to %v, want only the held organization's", remaining)
+	}
+}
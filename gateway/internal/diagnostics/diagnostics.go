@@ -0,0 +1,321 @@
+// Package diagnostics exposes the gateway's runtime for support: the pprof
+// handlers, on-demand CPU profiles and heap snapshots kept in the storage
+// backend, and runtime statistics (goroutines, GC, connection pools). Its
+// routes belong on the admin listener only.
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	rpprof "runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Capture kinds
+const (
+	KindCPU       = "cpu"
+	KindHeap      = "heap"
+	KindGoroutine = "goroutine"
+)
+
+const (
+	// DefaultCPUDuration is how long a CPU profile samples unless asked otherwise
+	DefaultCPUDuration = 30 * time.Second
+	maxCPUDuration     = 5 * time.Minute
+	// captureLinkTTL is how long the download link of a capture lasts
+	captureLinkTTL = time.Hour
+)
+
+var (
+	// ErrUnknownKind is returned for a capture kind other than cpu, heap or goroutine
+	ErrUnknownKind = errors.New("kind must be cpu, heap or goroutine")
+	// ErrCaptureInProgress is returned while a CPU profile is already running
+	ErrCaptureInProgress = errors.New("a CPU profile is already being captured")
+)
+
+// Capture is a stored profile
+type Capture struct {
+	Kind        string        `json:"kind"`
+	Key         string        `json:"key"`
+	Size        int64         `json:"size"`
+	StartedAt   time.Time     `json:"started_at"`
+	Duration    time.Duration `json:"duration_ns,omitempty"` // CPU profiles only
+	DownloadURL string        `json:"download_url,omitempty"`
+}
+
+// RuntimeStats is a snapshot of the process for support bundles
+type RuntimeStats struct {
+	Hostname   string      `json:"hostname"`
+	GoVersion  string      `json:"go_version"`
+	StartedAt  time.Time   `json:"started_at"`
+	Uptime     string      `json:"uptime"`
+	Goroutines int         `json:"goroutines"`
+	GOMAXPROCS int         `json:"gomaxprocs"`
+	NumCPU     int         `json:"num_cpu"`
+	Memory     MemStats    `json:"memory"`
+	GC         GCStats     `json:"gc"`
+	Database   PoolStats   `json:"database"`
+	Redis      *RedisStats `json:"redis,omitempty"`
+	CapturedAt time.Time   `json:"captured_at"`
+}
+
+// MemStats is the memory part of runtime.MemStats, in bytes
+type MemStats struct {
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapIdle     uint64 `json:"heap_idle"`
+	HeapReleased uint64 `json:"heap_released"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	StackInuse   uint64 `json:"stack_inuse"`
+	Sys          uint64 `json:"sys"`
+	TotalAlloc   uint64 `json:"total_alloc"`
+}
+
+// GCStats summarizes garbage collection since the process started
+type GCStats struct {
+	NumGC         uint32     `json:"num_gc"`
+	PauseTotal    string     `json:"pause_total"`
+	LastPause     string     `json:"last_pause"`
+	LastGC        *time.Time `json:"last_gc,omitempty"`
+	NextGC        uint64     `json:"next_gc"`
+	GCCPUFraction float64    `json:"gc_cpu_fraction"`
+}
+
+// PoolStats is the database pool, primary and (when attached) replica
+type PoolStats struct {
+	Primary database.PoolStats `json:"primary"`
+	Replica bool               `json:"replica_attached"`
+}
+
+// RedisStats is the Redis client's connection pool
+type RedisStats struct {
+	Hits       uint32 `json:"hits"`
+	Misses     uint32 `json:"misses"`
+	Timeouts   uint32 `json:"timeouts"`
+	TotalConns uint32 `json:"total_conns"`
+	IdleConns  uint32 `json:"idle_conns"`
+	StaleConns uint32 `json:"stale_conns"`
+}
+
+// Service captures profiles and runtime statistics
+type Service struct {
+	db        *database.DB
+	redis     *redis.Client
+	store     storage.Store
+	startedAt time.Time
+	logger    *zap.Logger
+
+	// Only one CPU profile can run in a process at a time
+	cpuMu sync.Mutex
+}
+
+func NewService(db *database.DB, redisClient *redis.Client, store storage.Store, logger *zap.Logger) *Service {
+	return &Service{
+		db:        db,
+		redis:     redisClient,
+		store:     store,
+		startedAt: time.Now(),
+		logger:    logger,
+	}
+}
+
+// Runtime returns the current runtime statistics
+func (s *Service) Runtime() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	hostname, _ := os.Hostname()
+	stats := RuntimeStats{
+		Hostname:   hostname,
+		GoVersion:  runtime.Version(),
+		StartedAt:  s.startedAt.UTC(),
+		Uptime:     time.Since(s.startedAt).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Memory: MemStats{
+			HeapAlloc:    mem.HeapAlloc,
+			HeapInuse:    mem.HeapInuse,
+			HeapIdle:     mem.HeapIdle,
+			HeapReleased: mem.HeapReleased,
+			HeapObjects:  mem.HeapObjects,
+			StackInuse:   mem.StackInuse,
+			Sys:          mem.Sys,
+			TotalAlloc:   mem.TotalAlloc,
+		},
+		GC: GCStats{
+			NumGC:         mem.NumGC,
+			PauseTotal:    time.Duration(mem.PauseTotalNs).String(),
+			NextGC:        mem.NextGC,
+			GCCPUFraction: mem.GCCPUFraction,
+		},
+		Database: PoolStats{
+			Primary: s.db.PoolStats(),
+			Replica: s.db.HasReplica(),
+		},
+		CapturedAt: time.Now().UTC(),
+	}
+	if mem.NumGC > 0 {
+		stats.GC.LastPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256]).String()
+		lastGC := time.Unix(0, int64(mem.LastGC)).UTC()
+		stats.GC.LastGC = &lastGC
+	}
+	if s.redis != nil {
+		pool := s.redis.PoolStats()
+		stats.Redis = &RedisStats{
+			Hits:       pool.Hits,
+			Misses:     pool.Misses,
+			Timeouts:   pool.Timeouts,
+			TotalConns: pool.TotalConns,
+			IdleConns:  pool.IdleConns,
+			StaleConns: pool.StaleConns,
+		}
+	}
+	return stats
+}
+
+// Capture records a profile of kind and stores it. CPU profiles sample for
+// duration (DefaultCPUDuration when zero); the other kinds are snapshots.
+func (s *Service) Capture(ctx context.Context, kind string, duration time.Duration) (*Capture, error) {
+	capture := &Capture{Kind: kind, StartedAt: time.Now().UTC()}
+
+	var buf bytes.Buffer
+	switch kind {
+	case KindCPU:
+		if duration <= 0 {
+			duration = DefaultCPUDuration
+		}
+		if duration > maxCPUDuration {
+			duration = maxCPUDuration
+		}
+		if !s.cpuMu.TryLock() {
+			return nil, ErrCaptureInProgress
+		}
+		err := s.profileCPU(ctx, &buf, duration)
+		s.cpuMu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		capture.Duration = duration
+	case KindHeap, KindGoroutine:
+		if kind == KindHeap {
+			// Report live objects as of now rather than the last collection
+			runtime.GC()
+		}
+		if err := rpprof.Lookup(kind).WriteTo(&buf, 0); err != nil {
+			return nil, fmt.Errorf("failed to write %s profile: %w", kind, err)
+		}
+	default:
+		return nil, ErrUnknownKind
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "gateway"
+	}
+	capture.Key = fmt.Sprintf("diagnostics/%s/%s-%s.pprof", hostname, capture.StartedAt.Format("20060102T150405Z"), kind)
+
+	size, err := s.store.Put(ctx, capture.Key, &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store profile: %w", err)
+	}
+	capture.Size = size
+
+	if url, err := s.store.SignedURL(ctx, capture.Key, captureLinkTTL); err == nil {
+		capture.DownloadURL = url
+	} else {
+		logging.FromContext(ctx, s.logger).Warn("Failed to sign profile download link", zap.Error(err))
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Captured profile",
+		zap.String("kind", kind),
+		zap.String("key", capture.Key),
+		zap.Int64("size", size),
+	)
+	return capture, nil
+}
+
+// profileCPU samples the CPU for duration, stopping early if ctx ends
+func (s *Service) profileCPU(ctx context.Context, buf *bytes.Buffer, duration time.Duration) error {
+	if err := rpprof.StartCPUProfile(buf); err != nil {
+		// Someone is profiling through /debug/pprof/profile
+		return ErrCaptureInProgress
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	rpprof.StopCPUProfile()
+	return ctx.Err()
+}
+
+// CaptureRequest payload
+type CaptureRequest struct {
+	Kind    string `json:"kind" binding:"required,oneof=cpu heap goroutine"`
+	Seconds int    `json:"seconds" binding:"min=0,max=300"` // CPU profiles only; 30 by default
+}
+
+// Mount registers the diagnostics routes on r:
+//
+//	GET  /debug/pprof/...    the standard pprof handlers
+//	GET  /debug/runtime      runtime statistics
+//	POST /debug/captures     capture and store a profile
+func (s *Service) Mount(r gin.IRouter) {
+	debug := r.Group("/debug")
+
+	profiles := debug.Group("/pprof")
+	{
+		profiles.GET("/", gin.WrapF(pprof.Index))
+		profiles.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		profiles.GET("/profile", gin.WrapF(pprof.Profile))
+		profiles.GET("/symbol", gin.WrapF(pprof.Symbol))
+		profiles.POST("/symbol", gin.WrapF(pprof.Symbol))
+		profiles.GET("/trace", gin.WrapF(pprof.Trace))
+		profiles.GET("/:profile", func(c *gin.Context) {
+			pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+
+	debug.GET("/runtime", func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.Runtime())
+	})
+	debug.POST("/captures", s.handleCapture)
+}
+
+func (s *Service) handleCapture(c *gin.Context) {
+	var req CaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	capture, err := s.Capture(ctx, req.Kind, time.Duration(req.Seconds)*time.Second)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, capture)
+	case errors.Is(err, ErrUnknownKind):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCaptureInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logging.FromContext(ctx, s.logger).Error("Failed to capture profile", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to capture profile"})
+	}
+}
@@ -0,0 +1,282 @@
+package digests
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/rbac"
+)
+
+// maxAccountEvents bounds the security activity read for the account section
+const maxAccountEvents = 200
+
+// Digest summarizes a period of activity for one user
+type Digest struct {
+	UserID        string        `json:"user_id"`
+	Email         string        `json:"email"`
+	Name          string        `json:"name"`
+	Frequency     string        `json:"frequency"`
+	PeriodStart   time.Time     `json:"period_start"`
+	PeriodEnd     time.Time     `json:"period_end"`
+	Timezone      string        `json:"timezone"`
+	Account       Account       `json:"account"`
+	Organizations []OrgActivity `json:"organizations"`
+
+	location *time.Location
+}
+
+// Account is the user's own sign-ins and credential changes
+type Account struct {
+	Logins       int                  `json:"logins"`
+	FailedLogins int                  `json:"failed_logins"`
+	Events       []auth.SecurityEvent `json:"events"` // everything but sign-ins
+}
+
+// OrgActivity is one organization's activity
+type OrgActivity struct {
+	OrganizationID      string          `json:"organization_id" db:"id"`
+	Name                string          `json:"name" db:"name"`
+	Role                string          `json:"role" db:"role"`
+	Scans               ScanCounts      `json:"scans"`
+	NewFindings         []SeverityCount `json:"new_findings"`
+	NewFindingsTotal    int             `json:"new_findings_total"`
+	UnresolvedCriticals Criticals       `json:"unresolved_criticals"`
+	// AuditEvents is nil for members who may not view the audit trail
+	AuditEvents      []AuditEvent `json:"audit_events"`
+	AuditEventsTotal int          `json:"audit_events_total"`
+}
+
+// ScanCounts counts the scans started in the period by their status now
+type ScanCounts struct {
+	Total     int `json:"total" db:"total"`
+	Completed int `json:"completed" db:"completed"`
+	Failed    int `json:"failed" db:"failed"`
+	Running   int `json:"running" db:"running"`
+}
+
+// SeverityCount counts new findings of one severity
+type SeverityCount struct {
+	Severity string `json:"severity" db:"severity"`
+	Count    int    `json:"count" db:"count"`
+}
+
+// Criticals summarizes open critical findings by age at the period's end
+type Criticals struct {
+	Total     int `json:"total" db:"total"`
+	Aging     int `json:"aging" db:"aging"` // open longer than AgingDays
+	AgingDays int `json:"aging_days"`
+	Over30    int `json:"over_30_days" db:"over_30"`
+	// Oldest lists the longest-open aging criticals
+	Oldest []AgingFinding `json:"oldest"`
+}
+
+// AgingFinding is an unresolved critical finding
+type AgingFinding struct {
+	ID           string    `json:"id" db:"id"`
+	Title        string    `json:"title" db:"title"`
+	Target       string    `json:"target" db:"target"`
+	DiscoveredAt time.Time `json:"discovered_at" db:"discovered_at"`
+	OpenDays     int       `json:"open_days" db:"open_days"`
+}
+
+// AuditEvent is a high or critical severity audit log entry
+type AuditEvent struct {
+	Action    string    `json:"action" db:"action"`
+	Actor     string    `json:"actor" db:"actor"`
+	Target    string    `json:"target" db:"target"`
+	Status    string    `json:"status" db:"status"`
+	Severity  string    `json:"severity" db:"severity"`
+	Timestamp time.Time `json:"timestamp" db:"timestamp"`
+}
+
+// Empty reports whether nothing happened in the period worth sending
+func (d *Digest) Empty() bool {
+	if d.Account.Logins > 0 || d.Account.FailedLogins > 0 || len(d.Account.Events) > 0 {
+		return false
+	}
+	for _, org := range d.Organizations {
+		if org.Scans.Total > 0 || org.NewFindingsTotal > 0 || org.UnresolvedCriticals.Total > 0 || org.AuditEventsTotal > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Build gathers the user's activity between start and end. Organization
+// sections follow the user's role: findings need scan access and audit
+// events audit log access.
+func (s *Service) Build(ctx context.Context, userID, email, name, frequency string, start, end time.Time, loc *time.Location) (*Digest, error) {
+	d := &Digest{
+		UserID:        userID,
+		Email:         email,
+		Name:          name,
+		Frequency:     frequency,
+		PeriodStart:   start,
+		PeriodEnd:     end,
+		Timezone:      loc.String(),
+		Organizations: []OrgActivity{},
+		location:      loc,
+	}
+
+	if err := s.buildAccount(ctx, d); err != nil {
+		return nil, err
+	}
+
+	var orgs []OrgActivity
+	err := s.db.Reader().SelectContext(ctx, &orgs, `
+		SELECT o.id, o.name, m.role
+		FROM organization_memberships m
+		JOIN organizations o ON o.id = m.organization_id
+		WHERE m.user_id = $1 AND COALESCE(o.is_active, true)
+		ORDER BY o.name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organizations: %w", err)
+	}
+
+	for i := range orgs {
+		org := &orgs[i]
+		role := rbac.Role(org.Role)
+		canView, err := s.roles.HasPermission(ctx, org.OrganizationID, role, rbac.PermViewScan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check permissions: %w", err)
+		}
+		if !canView {
+			continue
+		}
+		if err := s.buildOrg(ctx, org, start, end); err != nil {
+			return nil, err
+		}
+
+		canAudit, err := s.roles.HasPermission(ctx, org.OrganizationID, role, rbac.PermViewAuditLogs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check permissions: %w", err)
+		}
+		if canAudit {
+			if err := s.buildAudit(ctx, org, userID, start, end); err != nil {
+				return nil, err
+			}
+		}
+		d.Organizations = append(d.Organizations, *org)
+	}
+	return d, nil
+}
+
+func (s *Service) buildAccount(ctx context.Context, d *Digest) error {
+	d.Account.Events = []auth.SecurityEvent{}
+	if s.activity == nil {
+		return nil
+	}
+	events, err := s.activity(ctx, d.UserID, d.Email, d.PeriodStart, maxAccountEvents)
+	if err != nil {
+		return fmt.Errorf("failed to load security activity: %w", err)
+	}
+	for _, event := range events {
+		if !event.Timestamp.Before(d.PeriodEnd) {
+			continue
+		}
+		switch event.Type {
+		case auth.ActivityLogin:
+			d.Account.Logins++
+		case auth.ActivityLoginFailed:
+			d.Account.FailedLogins++
+		default:
+			if len(d.Account.Events) < s.config.MaxItems {
+				d.Account.Events = append(d.Account.Events, event)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) buildOrg(ctx context.Context, org *OrgActivity, start, end time.Time) error {
+	err := s.db.Reader().GetContext(ctx, &org.Scans, `
+		SELECT COUNT(*) AS total,
+		       COUNT(*) FILTER (WHERE status = 'completed') AS completed,
+		       COUNT(*) FILTER (WHERE status = 'failed') AS failed,
+		       COUNT(*) FILTER (WHERE status IN ('pending', 'running')) AS running
+		FROM scan_jobs
+		WHERE organization_id = $1 AND created_at >= $2 AND created_at < $3
+	`, org.OrganizationID, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to count scans: %w", err)
+	}
+
+	org.NewFindings = []SeverityCount{}
+	err = s.db.Reader().SelectContext(ctx, &org.NewFindings, `
+		SELECT severity, COUNT(*) AS count
+		FROM vulnerabilities
+		WHERE organization_id = $1 AND discovered_at >= $2 AND discovered_at < $3
+		AND suppressed_at IS NULL
+		GROUP BY severity
+		ORDER BY array_position(ARRAY['critical', 'high', 'medium', 'low', 'info'], severity::text)
+	`, org.OrganizationID, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to count new findings: %w", err)
+	}
+	for _, count := range org.NewFindings {
+		org.NewFindingsTotal += count.Count
+	}
+
+	agingSince := end.Add(-s.config.AgingAfter)
+	err = s.db.Reader().GetContext(ctx, &org.UnresolvedCriticals, `
+		SELECT COUNT(*) AS total,
+		       COUNT(*) FILTER (WHERE discovered_at < $3) AS aging,
+		       COUNT(*) FILTER (WHERE discovered_at < $2::timestamp - INTERVAL '30 days') AS over_30
+		FROM vulnerabilities
+		WHERE organization_id = $1 AND severity = 'critical' AND status IN ('open', 'confirmed')
+		AND suppressed_at IS NULL AND discovered_at < $2
+	`, org.OrganizationID, end, agingSince)
+	if err != nil {
+		return fmt.Errorf("failed to count unresolved criticals: %w", err)
+	}
+
+	org.UnresolvedCriticals.AgingDays = int(s.config.AgingAfter / (24 * time.Hour))
+	org.UnresolvedCriticals.Oldest = []AgingFinding{}
+	err = s.db.Reader().SelectContext(ctx, &org.UnresolvedCriticals.Oldest, `
+		SELECT v.id, v.title, COALESCE(st.target_value, '') AS target, v.discovered_at,
+		       EXTRACT(DAY FROM $2::timestamp - v.discovered_at)::int AS open_days
+		FROM vulnerabilities v
+		JOIN scan_jobs sj ON sj.id = v.scan_job_id
+		LEFT JOIN scan_targets st ON st.id = sj.target_id
+		WHERE v.organization_id = $1 AND v.severity = 'critical' AND v.status IN ('open', 'confirmed')
+		AND v.suppressed_at IS NULL AND v.discovered_at < $3
+		ORDER BY v.discovered_at
+		LIMIT $4
+	`, org.OrganizationID, end, agingSince, s.config.MaxItems)
+	if err != nil {
+		return fmt.Errorf("failed to list unresolved criticals: %w", err)
+	}
+	return nil
+}
+
+// buildAudit lists the organization's notable audit events, leaving out the
+// user's own, which the account section covers
+func (s *Service) buildAudit(ctx context.Context, org *OrgActivity, userID string, start, end time.Time) error {
+	err := s.db.Reader().GetContext(ctx, &org.AuditEventsTotal, `
+		SELECT COUNT(*) FROM audit_logs
+		WHERE organization_id = $1 AND timestamp >= $2 AND timestamp < $3
+		AND severity IN ('critical', 'high') AND user_id IS DISTINCT FROM $4::uuid
+	`, org.OrganizationID, start, end, userID)
+	if err != nil {
+		return fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	org.AuditEvents = []AuditEvent{}
+	err = s.db.Reader().SelectContext(ctx, &org.AuditEvents, `
+		SELECT a.action, COALESCE(u.email, 'system') AS actor, COALESCE(a.target, '') AS target,
+		       a.status, a.severity, a.timestamp
+		FROM audit_logs a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.organization_id = $1 AND a.timestamp >= $2 AND a.timestamp < $3
+		AND a.severity IN ('critical', 'high') AND a.user_id IS DISTINCT FROM $4::uuid
+		ORDER BY a.severity = 'critical' DESC, a.timestamp DESC
+		LIMIT $5
+	`, org.OrganizationID, start, end, userID, s.config.MaxItems)
+	if err != nil {
+		return fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return nil
+}
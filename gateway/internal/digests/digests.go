@@ -0,0 +1,295 @@
+// Package digests emails users a daily or weekly summary of their account
+// and organizations: scans run, new findings by severity, unresolved
+// critical findings and how long they have been open, and notable audit
+// events. Users choose the frequency, or opt out, through the
+// notification_digest preference; digests go out at a fixed hour in each
+// user's timezone.
+package digests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/notify"
+	"github.com/cyper-security/gateway/internal/preferences"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"go.uber.org/zap"
+)
+
+// Digest statuses
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+var ErrInvalidFrequency = errors.New("frequency must be daily or weekly")
+
+// Config tunes digest scheduling and content
+type Config struct {
+	PublicURL string // Prefixed to the preferences link in each digest
+	Interval  time.Duration
+	Hour      int          // Local hour digests are sent at
+	Weekday   time.Weekday // Day weekly digests are sent on
+	// MaxDelay skips digests that could not go out this long after their
+	// hour, e.g. after an outage, rather than sending them at odd times
+	MaxDelay time.Duration
+	// AgingAfter is how long an unresolved critical finding may stay open
+	// before the digest lists it
+	AgingAfter time.Duration
+	MaxItems   int // Findings and audit events listed per section
+	Retention  time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Interval:   15 * time.Minute,
+		Hour:       8,
+		Weekday:    time.Monday,
+		MaxDelay:   6 * time.Hour,
+		AgingAfter: 7 * 24 * time.Hour,
+		MaxItems:   5,
+		Retention:  90 * 24 * time.Hour,
+	}
+}
+
+// ActivityFunc returns a user's security activity since a time, newest
+// first; the auth service's SecurityActivity
+type ActivityFunc func(ctx context.Context, userID, email string, since time.Time, limit int) ([]auth.SecurityEvent, error)
+
+// Service builds and sends digests
+type Service struct {
+	db       *database.DB
+	config   Config
+	mailer   *notify.Mailer
+	prefs    *preferences.Service
+	roles    *rbac.RoleStore
+	activity ActivityFunc
+	logger   *zap.Logger
+}
+
+func NewService(db *database.DB, config Config, mailer *notify.Mailer, prefs *preferences.Service, roles *rbac.RoleStore, activity ActivityFunc, logger *zap.Logger) *Service {
+	return &Service{
+		db:       db,
+		config:   config,
+		mailer:   mailer,
+		prefs:    prefs,
+		roles:    roles,
+		activity: activity,
+		logger:   logger,
+	}
+}
+
+// recipient is a user who opted in to digests
+type recipient struct {
+	UserID        string     `db:"id"`
+	Email         string     `db:"email"`
+	Name          string     `db:"name"`
+	Frequency     string     `db:"frequency"`
+	LastPeriodEnd *time.Time `db:"last_period_end"`
+}
+
+// Start sends due digests until ctx is done. Each digest is claimed by
+// inserting its period, so several gateway instances can run the sender.
+func (s *Service) Start(ctx context.Context) {
+	if !s.mailer.Enabled() {
+		s.logger.Warn("SMTP is not configured; activity digests are disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting activity digests",
+		zap.Duration("interval", s.config.Interval),
+		zap.Int("hour", s.config.Hour),
+		zap.String("weekday", s.config.Weekday.String()),
+	)
+
+	for {
+		s.sendDue(ctx)
+		s.deleteOld(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deleteOld keeps the digests of users under legal hold
+func (s *Service) deleteOld(ctx context.Context) {
+	_, held, err := legalhold.Purge(ctx, s.db, "activity_digests", "", "user_id",
+		"created_at < $1", time.Now().UTC().Add(-s.config.Retention))
+	if err != nil {
+		s.logger.Error("Failed to delete old activity digests", zap.Error(err))
+		return
+	}
+	if held > 0 {
+		s.logger.Info("Kept old activity digests under legal hold", zap.Int64("count", held))
+	}
+}
+
+func (s *Service) sendDue(ctx context.Context) {
+	var recipients []recipient
+	err := s.db.SelectContext(ctx, &recipients, `
+		SELECT u.id, u.email, COALESCE(u.full_name, '') AS name, p.value #>> '{}' AS frequency,
+		       (SELECT MAX(d.period_end) FROM activity_digests d
+		        WHERE d.user_id = u.id AND d.frequency = p.value #>> '{}') AS last_period_end
+		FROM user_preferences p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.key = $1 AND p.value #>> '{}' IN ($2, $3) AND u.is_active
+	`, preferences.KeyNotificationDigest, preferences.DigestDaily, preferences.DigestWeekly)
+	if err != nil {
+		s.logger.Error("Failed to load digest recipients", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for _, r := range recipients {
+		if ctx.Err() != nil {
+			return
+		}
+		loc := s.prefs.Lookup(ctx, r.UserID).Location()
+		end := s.periodEnd(r.Frequency, now, loc)
+		if now.Sub(end) > s.config.MaxDelay {
+			continue
+		}
+		if r.LastPeriodEnd != nil && !r.LastPeriodEnd.Before(end.UTC()) {
+			continue
+		}
+		s.send(ctx, r, s.periodStart(r.Frequency, end), end, loc)
+	}
+}
+
+// send claims one user's digest for the period and emails it
+func (s *Service) send(ctx context.Context, r recipient, start, end time.Time, loc *time.Location) {
+	logger := s.logger.With(zap.String("user_id", r.UserID), zap.String("frequency", r.Frequency))
+
+	// A failed digest's period is covered again by the next one
+	var claim struct {
+		ID          string    `db:"id"`
+		PeriodStart time.Time `db:"period_start"`
+	}
+	err := s.db.GetContext(ctx, &claim, `
+		INSERT INTO activity_digests (user_id, frequency, period_start, period_end)
+		SELECT $1, $2, GREATEST(MAX(period_end), $3), $4
+		FROM activity_digests
+		WHERE user_id = $1 AND frequency = $2 AND status IN ($5, $6)
+		ON CONFLICT (user_id, frequency, period_end) DO NOTHING
+		RETURNING id, period_start
+	`, r.UserID, r.Frequency, start.UTC(), end.UTC(), StatusSent, StatusSkipped)
+	if errors.Is(err, sql.ErrNoRows) {
+		return // another instance claimed it
+	}
+	if err != nil {
+		logger.Error("Failed to claim activity digest", zap.Error(err))
+		return
+	}
+
+	digest, err := s.Build(ctx, r.UserID, r.Email, r.Name, r.Frequency, claim.PeriodStart, end.UTC(), loc)
+	status, errMsg := StatusSent, ""
+	switch {
+	case err != nil:
+		status, errMsg = StatusFailed, err.Error()
+	case digest.Empty():
+		status = StatusSkipped
+	default:
+		subject, body, renderErr := s.Render(digest)
+		if renderErr == nil {
+			renderErr = s.mailer.Send([]string{r.Email}, subject, body)
+		}
+		if renderErr != nil {
+			status, errMsg = StatusFailed, renderErr.Error()
+		}
+	}
+
+	if status == StatusFailed {
+		logger.Warn("Failed to send activity digest", zap.String("error", errMsg))
+	}
+	metrics.ActivityDigests.WithLabelValues(r.Frequency, status).Inc()
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE activity_digests
+		SET status = $2, error = NULLIF($3, ''), sent_at = CASE WHEN $2 = $4 THEN NOW() END
+		WHERE id = $1
+	`, claim.ID, status, errMsg, StatusSent)
+	if err != nil {
+		logger.Error("Failed to record activity digest", zap.Error(err))
+	}
+}
+
+// periodEnd is the latest scheduled send time at or before now in loc
+func (s *Service) periodEnd(frequency string, now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	end := time.Date(local.Year(), local.Month(), local.Day(), s.config.Hour, 0, 0, 0, loc)
+	if frequency == preferences.DigestWeekly {
+		end = end.AddDate(0, 0, -((int(local.Weekday()) - int(s.config.Weekday) + 7) % 7))
+	}
+	if end.After(now) {
+		end = s.periodStart(frequency, end)
+	}
+	return end
+}
+
+// periodStart is the scheduled send time before end; a calendar day or week
+// earlier, so daylight saving changes keep the local hour
+func (s *Service) periodStart(frequency string, end time.Time) time.Time {
+	if frequency == preferences.DigestWeekly {
+		return end.AddDate(0, 0, -7)
+	}
+	return end.AddDate(0, 0, -1)
+}
+
+// Preview builds the user's digest for the period ending now, whether or
+// not they receive digests
+func (s *Service) Preview(ctx context.Context, userID, frequency string) (*Digest, error) {
+	if frequency != preferences.DigestDaily && frequency != preferences.DigestWeekly {
+		return nil, ErrInvalidFrequency
+	}
+	var user struct {
+		Email string `db:"email"`
+		Name  string `db:"name"`
+	}
+	err := s.db.GetContext(ctx, &user, `SELECT email, COALESCE(full_name, '') AS name FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	loc := s.prefs.Lookup(ctx, userID).Location()
+	end := time.Now().In(loc)
+	return s.Build(ctx, userID, user.Email, user.Name, frequency, s.periodStart(frequency, end).UTC(), end.UTC(), loc)
+}
+
+// Subject is the digest email's subject line
+func (d *Digest) Subject() string {
+	return "Your " + d.Frequency + " Cyper Security digest"
+}
+
+// Format shows a time in the recipient's timezone
+func (d *Digest) Format(t time.Time) string {
+	return t.In(d.location).Format("2006-01-02 15:04 MST")
+}
+
+// Render returns the digest email's subject and plain-text body
+func (s *Service) Render(d *Digest) (string, string, error) {
+	var body strings.Builder
+	err := digestTemplate.Execute(&body, struct {
+		*Digest
+		PreferencesURL string
+	}{d, strings.TrimRight(s.config.PublicURL, "/") + PreferencesPath})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render digest: %w", err)
+	}
+	return d.Subject(), strings.ReplaceAll(body.String(), "\n", "\r\n"), nil
+}
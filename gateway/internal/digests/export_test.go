@@ -0,0 +1,4 @@
+package digests
+
+// DeleteOld runs one retention pass for the external tests
+var DeleteOld = (*Service).deleteOld
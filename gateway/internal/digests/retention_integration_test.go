@@ -0,0 +1,47 @@
+//go:build integration
+
+package digests_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/digests"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+func TestDeleteOldKeepsHeldUsers(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	admin := env.CreateUser(t, testenv.UserOptions{Role: "admin"})
+	heldUser := env.CreateUser(t, testenv.UserOptions{})
+	freeUser := env.CreateUser(t, testenv.UserOptions{})
+	if _, err := legalhold.NewService(env.DB, env.Logger).Place(ctx, legalhold.ScopeUser, heldUser.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	periodEnd := time.Now().UTC().Add(-48 * time.Hour)
+	for _, userID := range []string{heldUser.ID, freeUser.ID} {
+		_, err := env.DB.ExecContext(ctx, `
+			INSERT INTO activity_digests (user_id, frequency, period_start, period_end, status, created_at)
+			VALUES ($1, 'daily', $2, $3, 'sent', $3)
+		`, userID, periodEnd.Add(-24*time.Hour), periodEnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := digests.DefaultConfig()
+	config.Retention = 24 * time.Hour
+	digests.DeleteOld(digests.NewService(env.DB, config, nil, nil, nil, nil, env.Logger), ctx)
+
+	var remaining []string
+	if err := env.DB.SelectContext(ctx, &remaining, `SELECT user_id FROM activity_digests`); err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0] != heldUser.ID {
+		t.Fatalf("remaining digests belong to %v, want only the held user's", remaining)
+	}
+}
@@ -0,0 +1,53 @@
+package digests
+
+import (
+	"strings"
+	"text/template"
+)
+
+// PreferencesPath is where users change their digest frequency or opt out
+const PreferencesPath = "/api/v1/users/me/preferences"
+
+var digestFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"plural": func(n int, word string) string {
+		if n == 1 {
+			return word
+		}
+		return word + "s"
+	},
+}
+
+// digestTemplate renders the plain-text email; Render converts line endings
+var digestTemplate = template.Must(template.New("digest").Funcs(digestFuncs).Parse(`Hello{{with .Name}} {{.}}{{end}},
+
+Here is your {{.Frequency}} activity summary for {{.Format .PeriodStart}} to {{.Format .PeriodEnd}}.
+
+YOUR ACCOUNT
+{{- with .Account}}
+  Sign-ins: {{.Logins}}{{if .FailedLogins}}, failed attempts: {{.FailedLogins}}{{end}}
+{{- range .Events}}
+  - {{$.Format .Timestamp}}  {{.Action}}{{if not .Success}} (failed){{end}}{{with .IPAddress}} from {{.}}{{end}}
+{{- end}}
+{{- end}}
+{{range .Organizations}}
+{{upper .Name}}
+  Scans: {{.Scans.Total}} started{{if .Scans.Total}} ({{.Scans.Completed}} completed, {{.Scans.Failed}} failed{{if .Scans.Running}}, {{.Scans.Running}} still running{{end}}){{end}}
+  New findings: {{.NewFindingsTotal}}{{if .NewFindings}} ({{range $i, $c := .NewFindings}}{{if $i}}, {{end}}{{$c.Severity}} {{$c.Count}}{{end}}){{end}}
+{{- with .UnresolvedCriticals}}
+  Unresolved criticals: {{.Total}}{{if .Aging}} ({{.Aging}} open over {{.AgingDays}} {{plural .AgingDays "day"}}{{if .Over30}}, {{.Over30}} over 30 days{{end}}){{end}}
+{{- range .Oldest}}
+  - {{.Title}}{{with .Target}} on {{.}}{{end}}, open {{.OpenDays}} {{plural .OpenDays "day"}}
+{{- end}}
+{{- end}}
+{{- if .AuditEvents}}
+  Notable audit events: {{.AuditEventsTotal}}
+{{- range .AuditEvents}}
+  - {{$.Format .Timestamp}}  {{.Action}} by {{.Actor}}{{with .Target}} on {{.}}{{end}} [{{.Severity}}{{if ne .Status "success"}}, {{.Status}}{{end}}]
+{{- end}}
+{{- end}}
+{{end}}
+You receive this digest because your notification_digest preference is set
+to {{.Frequency}}. Set it to daily, weekly or off at:
+{{.PreferencesURL}}
+`))
@@ -0,0 +1,444 @@
+// Package domains manages the email domains organizations prove they own
+// with a DNS TXT record. Users who verify an address on a verified domain
+// can join its organization automatically.
+//
+// A domain can be verified by one organization only, and the most specific
+// verified domain decides where an address belongs: an organization holding
+// example.com does not get users of eu.example.com when another organization
+// verified that. Claiming a subdomain of a domain another organization has
+// verified is refused, as are public email providers.
+package domains
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotFound        = errors.New("domain not found")
+	ErrInvalidDomain   = errors.New("invalid domain name")
+	ErrPublicDomain    = errors.New("public email domains cannot be claimed")
+	ErrExists          = errors.New("domain is already claimed by this organization")
+	ErrConflict        = errors.New("domain is verified by another organization")
+	ErrNotVerified     = errors.New("verification record not found")
+	ErrLookupFailed    = errors.New("DNS lookup failed")
+	ErrInvalidRole     = errors.New("unknown role")
+	ErrOwnerRole       = errors.New("auto-join cannot grant the owner role")
+	ErrAlreadyVerified = errors.New("domain is already verified")
+)
+
+const (
+	// RecordPrefix is prepended to the domain to name the TXT record
+	RecordPrefix = "_cyper-verification."
+	// RecordValuePrefix is prepended to the token to form the TXT value
+	RecordValuePrefix = "cyper-verification="
+)
+
+// publicDomains are email providers anyone can sign up with
+var publicDomains = map[string]bool{
+	"gmail.com": true, "googlemail.com": true, "outlook.com": true, "hotmail.com": true,
+	"live.com": true, "msn.com": true, "yahoo.com": true, "ymail.com": true,
+	"icloud.com": true, "me.com": true, "mac.com": true, "aol.com": true,
+	"proton.me": true, "protonmail.com": true, "pm.me": true, "gmx.com": true,
+	"gmx.net": true, "gmx.de": true, "web.de": true, "mail.com": true,
+	"zoho.com": true, "yandex.com": true, "yandex.ru": true, "mail.ru": true,
+	"qq.com": true, "163.com": true, "126.com": true, "fastmail.com": true,
+	"tutanota.com": true, "hey.com": true,
+}
+
+// Domain is an email domain claimed by an organization
+type Domain struct {
+	ID             string     `json:"id" db:"id"`
+	OrganizationID string     `json:"organization_id" db:"organization_id"`
+	Domain         string     `json:"domain" db:"domain"`
+	Verified       bool       `json:"verified" db:"verified"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+	LastCheckedAt  *time.Time `json:"last_checked_at,omitempty" db:"last_checked_at"`
+	LastError      string     `json:"last_error,omitempty" db:"last_error"`
+	AutoJoin       bool       `json:"auto_join" db:"auto_join"`
+	AutoJoinRole   string     `json:"auto_join_role" db:"auto_join_role"`
+	CreatedBy      *string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+
+	// The TXT record to publish, until the domain is verified
+	Record *Record `json:"record,omitempty" db:"-"`
+
+	// Token is kept out of API responses other than in Record
+	Token string `json:"-" db:"verification_token"`
+}
+
+// Record is the DNS TXT record that proves ownership of a domain
+type Record struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Joined is a membership created by auto-join
+type Joined struct {
+	OrganizationID string `json:"organization_id" db:"organization_id"`
+	Name           string `json:"name" db:"name"`
+	Domain         string `json:"domain" db:"domain"`
+	Role           string `json:"role" db:"auto_join_role"`
+}
+
+// Update changes a domain's auto-join settings; nil fields are left alone
+type Update struct {
+	AutoJoin     *bool   `json:"auto_join"`
+	AutoJoinRole *string `json:"auto_join_role" binding:"omitempty,max=50"`
+}
+
+// TXTResolver looks up TXT records (net.DefaultResolver in production)
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Service stores organization domains and applies auto-join
+type Service struct {
+	db       *database.DB
+	roles    *rbac.RoleStore
+	resolver TXTResolver
+	logger   *zap.Logger
+}
+
+func NewService(db *database.DB, roles *rbac.RoleStore, resolver TXTResolver, logger *zap.Logger) *Service {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &Service{
+		db:       db,
+		roles:    roles,
+		resolver: resolver,
+		logger:   logger,
+	}
+}
+
+const domainColumns = `id, organization_id, domain, verified_at IS NOT NULL AS verified, verified_at,
+	last_checked_at, COALESCE(last_error, '') AS last_error, auto_join, auto_join_role, created_by,
+	created_at, updated_at, verification_token`
+
+// List returns the organization's domains, verified first
+func (s *Service) List(ctx context.Context, orgID string) ([]Domain, error) {
+	domains := []Domain{}
+	err := s.db.Reader().SelectContext(ctx, &domains, `
+		SELECT `+domainColumns+` FROM organization_domains
+		WHERE organization_id = $1
+		ORDER BY verified_at IS NULL, domain
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+	for i := range domains {
+		domains[i].withRecord()
+	}
+	return domains, nil
+}
+
+// Get returns one of the organization's domains
+func (s *Service) Get(ctx context.Context, orgID, id string) (*Domain, error) {
+	var d Domain
+	err := s.db.GetContext(ctx, &d, `
+		SELECT `+domainColumns+` FROM organization_domains
+		WHERE id = $1 AND organization_id = $2
+	`, id, orgID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load domain: %w", err)
+	}
+	d.withRecord()
+	return &d, nil
+}
+
+// Add claims a domain for the organization. It stays unverified, and grants
+// nothing, until its TXT record is published and checked with Verify.
+func (s *Service) Add(ctx context.Context, orgID, userID, name string) (*Domain, error) {
+	domain, err := Normalize(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkConflict(ctx, orgID, domain); err != nil {
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	err = s.db.GetContext(ctx, &id, `
+		INSERT INTO organization_domains (organization_id, domain, verification_token, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, orgID, domain, token, userID)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add domain: %w", err)
+	}
+	return s.Get(ctx, orgID, id)
+}
+
+// Verify looks up the domain's TXT record and marks the domain verified when
+// it carries the token. The outcome of every check is kept on the domain.
+func (s *Service) Verify(ctx context.Context, orgID, id string) (*Domain, error) {
+	d, err := s.Get(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Verified {
+		return d, ErrAlreadyVerified
+	}
+
+	checkErr := s.checkConflict(ctx, orgID, d.Domain)
+	if checkErr == nil {
+		checkErr = s.lookupToken(ctx, d)
+	}
+	if checkErr != nil {
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE organization_domains SET last_checked_at = NOW(), last_error = $2, updated_at = NOW()
+			WHERE id = $1
+		`, d.ID, checkErr.Error())
+		if err != nil {
+			return nil, fmt.Errorf("failed to record verification check: %w", err)
+		}
+		return nil, checkErr
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE organization_domains
+		SET verified_at = NOW(), last_checked_at = NOW(), last_error = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, d.ID)
+	if database.IsUniqueViolation(err) {
+		// Another organization verified it first
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark domain verified: %w", err)
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Organization domain verified",
+		zap.String("organization_id", orgID),
+		zap.String("domain", d.Domain),
+	)
+	return s.Get(ctx, orgID, id)
+}
+
+// Update changes the domain's auto-join settings. The role must exist in the
+// organization and may not be owner.
+func (s *Service) Update(ctx context.Context, orgID, id string, update Update) (*Domain, error) {
+	d, err := s.Get(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if update.AutoJoin != nil {
+		d.AutoJoin = *update.AutoJoin
+	}
+	if update.AutoJoinRole != nil {
+		role := rbac.Role(*update.AutoJoinRole)
+		if role == rbac.RoleOwner {
+			return nil, ErrOwnerRole
+		}
+		exists, err := s.roles.RoleExists(ctx, orgID, role)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check role: %w", err)
+		}
+		if !exists {
+			return nil, ErrInvalidRole
+		}
+		d.AutoJoinRole = string(role)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE organization_domains SET auto_join = $2, auto_join_role = $3, updated_at = NOW()
+		WHERE id = $1
+	`, d.ID, d.AutoJoin, d.AutoJoinRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update domain: %w", err)
+	}
+	return s.Get(ctx, orgID, id)
+}
+
+// Remove deletes the organization's claim on a domain. Members who joined
+// through it stay members.
+func (s *Service) Remove(ctx context.Context, orgID, id string) (*Domain, error) {
+	d, err := s.Get(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.ExecContext(ctx, `DELETE FROM organization_domains WHERE id = $1`, d.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove domain: %w", err)
+	}
+	return d, nil
+}
+
+// AutoJoin adds a user with a verified address to the organization holding
+// the most specific verified domain of the address, if that domain has
+// auto-join on. Existing memberships are left as they are. It returns the
+// membership created, or nil.
+func (s *Service) AutoJoin(ctx context.Context, userID, email string) (*Joined, error) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil, nil
+	}
+	candidates := parents(strings.ToLower(email[at+1:]))
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var match struct {
+		Joined
+		AutoJoin bool `db:"auto_join"`
+	}
+	err := s.db.GetContext(ctx, &match, `
+		SELECT d.organization_id, o.name, d.domain, d.auto_join, d.auto_join_role
+		FROM organization_domains d
+		JOIN organizations o ON o.id = d.organization_id
+		WHERE d.domain = ANY($1) AND d.verified_at IS NOT NULL AND COALESCE(o.is_active, true)
+		ORDER BY length(d.domain) DESC
+		LIMIT 1
+	`, pq.Array(candidates))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to match domain: %w", err)
+	}
+	if !match.AutoJoin {
+		return nil, nil
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO organization_memberships (user_id, organization_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, organization_id) DO NOTHING
+	`, userID, match.OrganizationID, match.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add membership: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+
+	// Users without a home organization land in the one they joined
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE users SET organization_id = $2 WHERE id = $1 AND organization_id IS NULL
+	`, userID, match.OrganizationID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to set home organization", zap.String("user_id", userID), zap.Error(err))
+	}
+
+	logging.FromContext(ctx, s.logger).Info("User auto-joined organization",
+		zap.String("user_id", userID),
+		zap.String("organization_id", match.OrganizationID),
+		zap.String("domain", match.Domain),
+	)
+	return &match.Joined, nil
+}
+
+// checkConflict refuses a domain another organization has verified, or a
+// subdomain of one
+func (s *Service) checkConflict(ctx context.Context, orgID, domain string) error {
+	var conflicts bool
+	err := s.db.GetContext(ctx, &conflicts, `
+		SELECT EXISTS (
+			SELECT 1 FROM organization_domains
+			WHERE domain = ANY($1) AND verified_at IS NOT NULL AND organization_id <> $2
+		)
+	`, pq.Array(parents(domain)), orgID)
+	if err != nil {
+		return fmt.Errorf("failed to check domain conflicts: %w", err)
+	}
+	if conflicts {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *Service) lookupToken(ctx context.Context, d *Domain) error {
+	records, err := s.resolver.LookupTXT(ctx, RecordPrefix+d.Domain)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return ErrNotVerified
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLookupFailed, err)
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == RecordValuePrefix+d.Token {
+			return nil
+		}
+	}
+	return ErrNotVerified
+}
+
+func (d *Domain) withRecord() {
+	if d.Verified {
+		return
+	}
+	d.Record = &Record{
+		Type:  "TXT",
+		Name:  RecordPrefix + d.Domain,
+		Value: RecordValuePrefix + d.Token,
+	}
+}
+
+// Normalize lowercases a domain name and checks it can be claimed
+func Normalize(name string) (string, error) {
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if len(domain) > 253 || !strings.Contains(domain, ".") || net.ParseIP(domain) != nil {
+		return "", ErrInvalidDomain
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", ErrInvalidDomain
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return "", ErrInvalidDomain
+			}
+		}
+	}
+	if publicDomains[domain] {
+		return "", ErrPublicDomain
+	}
+	return domain, nil
+}
+
+// parents returns the domain and each parent with at least two labels,
+// most specific first
+func parents(domain string) []string {
+	var out []string
+	for strings.Count(domain, ".") >= 1 {
+		out = append(out, domain)
+		domain = domain[strings.Index(domain, ".")+1:]
+	}
+	return out
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
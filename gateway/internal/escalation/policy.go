@@ -0,0 +1,182 @@
+// Package escalation pages an organization's contacts about critical events,
+// moving down an ordered list of levels until someone acknowledges.
+package escalation
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Triggers a policy can respond to
+const (
+	TriggerCriticalFinding = "critical_finding"
+	TriggerEmergencyStop   = "emergency_stop"
+)
+
+// Contact channels
+const (
+	ChannelEmail   = "email"
+	ChannelSMS     = "sms"
+	ChannelWebhook = "webhook"
+)
+
+// Escalation statuses
+const (
+	StatusOpen         = "open"
+	StatusAcknowledged = "acknowledged"
+	StatusExhausted    = "exhausted" // Every level was paged and nobody acknowledged
+)
+
+// ErrInvalidPolicy is returned for policies that cannot be paged
+var ErrInvalidPolicy = errors.New("invalid escalation policy")
+
+// maxLevels bounds how deep a policy escalates
+const maxLevels = 10
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Contact is someone paged at a level
+type Contact struct {
+	Name    string `json:"name,omitempty"`
+	Channel string `json:"channel"` // email, sms or webhook
+	Target  string `json:"target"`  // Address, E.164 phone number or URL
+}
+
+// Level is one step of a policy: its contacts are paged together, and the
+// next level is paged if nobody acknowledges within EscalateAfterMinutes
+type Level struct {
+	Contacts             []Contact `json:"contacts"`
+	EscalateAfterMinutes int       `json:"escalate_after_minutes"`
+}
+
+// EscalateAfter is how long the level has to acknowledge
+func (l Level) EscalateAfter() time.Duration {
+	return time.Duration(l.EscalateAfterMinutes) * time.Minute
+}
+
+// Levels are stored as JSONB
+type Levels []Level
+
+// Value stores levels as JSONB
+func (l Levels) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+// Scan loads levels from JSONB
+func (l *Levels) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = Levels{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("unsupported levels type %T", src)
+	}
+}
+
+// Validate checks that every level can be paged
+func (l Levels) Validate() error {
+	if len(l) == 0 || len(l) > maxLevels {
+		return fmt.Errorf("%w: between 1 and %d levels are required", ErrInvalidPolicy, maxLevels)
+	}
+	for i, level := range l {
+		if len(level.Contacts) == 0 {
+			return fmt.Errorf("%w: level %d has no contacts", ErrInvalidPolicy, i+1)
+		}
+		if level.EscalateAfterMinutes < 1 || level.EscalateAfterMinutes > 24*60 {
+			return fmt.Errorf("%w: level %d escalate_after_minutes must be between 1 and 1440", ErrInvalidPolicy, i+1)
+		}
+		for _, contact := range level.Contacts {
+			if err := contact.validate(); err != nil {
+				return fmt.Errorf("%w: level %d: %v", ErrInvalidPolicy, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Contact) validate() error {
+	switch c.Channel {
+	case ChannelEmail:
+		if _, err := mail.ParseAddress(c.Target); err != nil {
+			return fmt.Errorf("invalid email address %q", c.Target)
+		}
+	case ChannelSMS:
+		if !e164.MatchString(c.Target) {
+			return fmt.Errorf("phone number %q must be in E.164 format", c.Target)
+		}
+	case ChannelWebhook:
+		u, err := url.Parse(c.Target)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q", c.Target)
+		}
+	default:
+		return fmt.Errorf("channel must be email, sms or webhook")
+	}
+	return nil
+}
+
+// Policy decides who is paged, in what order, for an organization's triggers
+type Policy struct {
+	ID             string         `json:"id" db:"id"`
+	OrganizationID string         `json:"organization_id" db:"organization_id"`
+	Name           string         `json:"name" db:"name"`
+	Triggers       pq.StringArray `json:"triggers" db:"triggers"`
+	Levels         Levels         `json:"levels" db:"levels"`
+	WebhookSecret  *string        `json:"-" db:"webhook_secret"`
+	Enabled        bool           `json:"enabled" db:"enabled"`
+	CreatedBy      *string        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// PolicyColumns selects every Policy field
+const PolicyColumns = `id, organization_id, name, triggers, levels, webhook_secret, enabled,
+	created_by, created_at, updated_at`
+
+// Escalation is one paging run of a policy for a triggering event. The
+// policy's levels are copied so later edits don't change a run in progress.
+type Escalation struct {
+	ID             string          `json:"id" db:"id"`
+	OrganizationID string          `json:"organization_id" db:"organization_id"`
+	PolicyID       *string         `json:"policy_id,omitempty" db:"policy_id"`
+	Trigger        string          `json:"trigger" db:"trigger"`
+	SubjectID      string          `json:"subject_id" db:"subject_id"` // Finding ID, or the emergency stop's ID
+	Summary        string          `json:"summary" db:"summary"`
+	Details        json.RawMessage `json:"details" db:"details"`
+	Levels         Levels          `json:"levels" db:"levels"`
+	Status         string          `json:"status" db:"status"`
+	Level          int             `json:"level" db:"level"` // Levels paged so far
+	NextNotifyAt   *time.Time      `json:"next_notify_at,omitempty" db:"next_notify_at"`
+	AcknowledgedBy *string         `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+	AckNote        *string         `json:"ack_note,omitempty" db:"ack_note"`
+	AcknowledgedAt *time.Time      `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// EscalationColumns selects every Escalation field
+const EscalationColumns = `id, organization_id, policy_id, trigger, subject_id, summary, details, levels,
+	status, level, next_notify_at, acknowledged_by, ack_note, acknowledged_at, created_at`
+
+// HistoryEntry records one step of an escalation
+type HistoryEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	Kind      string    `json:"kind" db:"kind"` // triggered, notified, notify_failed, acknowledged, exhausted
+	Level     *int      `json:"level,omitempty" db:"level"`
+	Channel   *string   `json:"channel,omitempty" db:"channel"`
+	Target    *string   `json:"target,omitempty" db:"target"`
+	Error     *string   `json:"error,omitempty" db:"error"`
+	ActorID   *string   `json:"actor_id,omitempty" db:"actor_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,453 @@
+package escalation
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/deliveries"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/notify"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNotFound            = errors.New("escalation not found")
+	ErrAlreadyAcknowledged = errors.New("escalation already acknowledged")
+	ErrInvalidAckToken     = errors.New("invalid acknowledgement token")
+)
+
+// maxDueEscalations bounds how many escalations one tick pages
+const maxDueEscalations = 20
+
+// Config tunes the escalation engine
+type Config struct {
+	PublicURL  string // Prefixed to acknowledgement links
+	LinkSecret []byte // Signs acknowledgement links
+	Interval   time.Duration
+	// FindingLookback bounds how old a critical finding may be when first
+	// seen, so enabling a policy doesn't page for the existing backlog
+	FindingLookback time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Interval:        30 * time.Second,
+		FindingLookback: time.Hour,
+	}
+}
+
+// Service starts escalations and pages their contacts
+type Service struct {
+	db         *database.DB
+	config     Config
+	mailer     *notify.Mailer
+	sms        notify.SMSSender // nil when no SMS provider is configured
+	deliveries *deliveries.Service
+	logger     *zap.Logger
+	wake       chan struct{}
+}
+
+func NewService(db *database.DB, config Config, mailer *notify.Mailer, sms notify.SMSSender, deliveryService *deliveries.Service, logger *zap.Logger) *Service {
+	return &Service{
+		db:         db,
+		config:     config,
+		mailer:     mailer,
+		sms:        sms,
+		deliveries: deliveryService,
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Trigger starts an escalation for every enabled policy of the organization
+// that responds to trigger; an empty orgID means every organization, for
+// platform-wide events. Repeated triggers for the same subject are ignored.
+// The first level is paged by the engine straight away.
+func (s *Service) Trigger(ctx context.Context, orgID, trigger, subjectID, summary string, details map[string]interface{}) (int, error) {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal escalation details: %w", err)
+	}
+
+	var created []string
+	err = s.db.SelectContext(ctx, &created, `
+		WITH created AS (
+			INSERT INTO escalations (organization_id, policy_id, trigger, subject_id, summary, details, levels, next_notify_at)
+			SELECT p.organization_id, p.id, $2, $3, $4, $5, p.levels, NOW()
+			FROM escalation_policies p
+			WHERE p.enabled AND $2 = ANY(p.triggers)
+			AND ($1 = '' OR p.organization_id = NULLIF($1, '')::uuid)
+			ON CONFLICT (policy_id, trigger, subject_id) DO NOTHING
+			RETURNING id
+		)
+		INSERT INTO escalation_history (escalation_id, kind)
+		SELECT id, 'triggered' FROM created
+		RETURNING escalation_id
+	`, orgID, trigger, subjectID, summary, detailsJSON)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start escalations: %w", err)
+	}
+
+	if len(created) > 0 {
+		s.Wake()
+	}
+	return len(created), nil
+}
+
+// Wake runs the engine now rather than at its next tick
+func (s *Service) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start escalates new critical findings and pages due levels until ctx is
+// done. Escalations are claimed with SKIP LOCKED so several gateway instances
+// can run the engine.
+func (s *Service) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting escalation engine", zap.Duration("interval", s.config.Interval))
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-ctx.Done():
+			return
+		}
+
+		if n, err := s.escalateCriticalFindings(ctx); err != nil {
+			s.logger.Error("Failed to escalate critical findings", zap.Error(err))
+		} else if n > 0 {
+			s.logger.Info("Escalating critical findings", zap.Int("escalations", n))
+		}
+		s.pageDue(ctx)
+	}
+}
+
+// escalateCriticalFindings starts escalations for recent critical findings
+// that are still open and not suppressed, whichever way they were ingested
+func (s *Service) escalateCriticalFindings(ctx context.Context) (int, error) {
+	var created []string
+	err := s.db.SelectContext(ctx, &created, `
+		WITH created AS (
+			INSERT INTO escalations (organization_id, policy_id, trigger, subject_id, summary, details, levels, next_notify_at)
+			SELECT v.organization_id, p.id, $1, v.id::text, 'Critical finding: ' || v.title,
+			       jsonb_build_object(
+			           'finding_id', v.id,
+			           'scan_id', v.scan_job_id,
+			           'cvss_score', v.cvss_score,
+			           'affected_component', v.affected_component
+			       ),
+			       p.levels, NOW()
+			FROM vulnerabilities v
+			JOIN escalation_policies p ON p.organization_id = v.organization_id
+			WHERE v.severity = 'critical'
+			AND COALESCE(v.status, 'open') IN ('open', 'confirmed')
+			AND v.suppressed_at IS NULL
+			AND v.discovered_at > NOW() - make_interval(secs => $2)
+			AND v.discovered_at >= p.created_at
+			AND p.enabled AND $1 = ANY(p.triggers)
+			ON CONFLICT (policy_id, trigger, subject_id) DO NOTHING
+			RETURNING id
+		)
+		INSERT INTO escalation_history (escalation_id, kind)
+		SELECT id, 'triggered' FROM created
+		RETURNING escalation_id
+	`, TriggerCriticalFinding, s.config.FindingLookback.Seconds())
+	return len(created), err
+}
+
+// page is a level claimed for paging
+type page struct {
+	escalation    Escalation
+	level         int
+	webhookSecret string
+}
+
+// pageDue claims escalations whose current level went unacknowledged and
+// pages the next level, or marks them exhausted. Levels are claimed before
+// they are paged, so a crash in between skips a level rather than paging it
+// twice.
+func (s *Service) pageDue(ctx context.Context) {
+	pages, err := s.claimDue(ctx)
+	if err != nil {
+		s.logger.Error("Failed to claim due escalations", zap.Error(err))
+		return
+	}
+	for _, p := range pages {
+		s.pageLevel(ctx, p)
+	}
+}
+
+func (s *Service) claimDue(ctx context.Context) ([]page, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var due []struct {
+		Escalation
+		WebhookSecret *string `db:"webhook_secret"`
+	}
+	err = tx.SelectContext(ctx, &due, `
+		SELECT e.id, e.organization_id, e.policy_id, e.trigger, e.subject_id, e.summary, e.details, e.levels,
+		       e.status, e.level, e.next_notify_at, e.acknowledged_by, e.ack_note, e.acknowledged_at, e.created_at,
+		       p.webhook_secret
+		FROM escalations e
+		LEFT JOIN escalation_policies p ON p.id = e.policy_id
+		WHERE e.status = 'open' AND e.next_notify_at <= NOW()
+		ORDER BY e.next_notify_at
+		FOR UPDATE OF e SKIP LOCKED
+		LIMIT $1
+	`, maxDueEscalations)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]page, 0, len(due))
+	for _, d := range due {
+		if d.Level >= len(d.Levels) {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE escalations SET status = 'exhausted', next_notify_at = NULL WHERE id = $1
+			`, d.ID)
+			if err == nil {
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO escalation_history (escalation_id, kind, level) VALUES ($1, 'exhausted', $2)
+				`, d.ID, d.Level)
+			}
+			if err != nil {
+				return nil, err
+			}
+			s.logger.Warn("Escalation exhausted without acknowledgement",
+				zap.String("escalation_id", d.ID),
+				zap.String("organization_id", d.OrganizationID),
+			)
+			continue
+		}
+
+		level := d.Levels[d.Level]
+		_, err = tx.ExecContext(ctx, `
+			UPDATE escalations SET level = level + 1, next_notify_at = NOW() + make_interval(secs => $2)
+			WHERE id = $1
+		`, d.ID, level.EscalateAfter().Seconds())
+		if err != nil {
+			return nil, err
+		}
+
+		p := page{escalation: d.Escalation, level: d.Level}
+		if d.WebhookSecret != nil {
+			p.webhookSecret = *d.WebhookSecret
+		}
+		pages = append(pages, p)
+	}
+
+	return pages, tx.Commit()
+}
+
+// pageLevel notifies every contact of a level and records the outcomes
+func (s *Service) pageLevel(ctx context.Context, p page) {
+	e := &p.escalation
+	logger := s.logger.With(zap.String("escalation_id", e.ID), zap.Int("level", p.level+1))
+
+	for _, contact := range e.Levels[p.level].Contacts {
+		err := s.notify(ctx, p, contact)
+
+		kind, outcome := "notified", "sent"
+		var errMsg *string
+		if err != nil {
+			kind, outcome = "notify_failed", "failed"
+			msg := err.Error()
+			errMsg = &msg
+			logger.Warn("Failed to page escalation contact", zap.String("channel", contact.Channel), zap.Error(err))
+		}
+		metrics.EscalationNotifications.WithLabelValues(contact.Channel, outcome).Inc()
+
+		_, err = s.db.ExecContext(ctx, `
+			INSERT INTO escalation_history (escalation_id, kind, level, channel, target, error)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, e.ID, kind, p.level+1, contact.Channel, contact.Target, errMsg)
+		if err != nil {
+			logger.Error("Failed to record escalation history", zap.Error(err))
+		}
+	}
+}
+
+// WebhookPayload is posted to webhook contacts
+type WebhookPayload struct {
+	Event          string          `json:"event"`
+	EscalationID   string          `json:"escalation_id"`
+	OrganizationID string          `json:"organization_id"`
+	Trigger        string          `json:"trigger"`
+	SubjectID      string          `json:"subject_id"`
+	Summary        string          `json:"summary"`
+	Details        json.RawMessage `json:"details"`
+	Level          int             `json:"level"`
+	Levels         int             `json:"levels"`
+	AcknowledgeURL string          `json:"acknowledge_url"`
+	SentAt         time.Time       `json:"sent_at"`
+}
+
+func (s *Service) notify(ctx context.Context, p page, contact Contact) error {
+	e := &p.escalation
+	ackURL := s.AcknowledgeURL(e.ID)
+
+	switch contact.Channel {
+	case ChannelEmail:
+		return s.mailer.Send([]string{contact.Target}, "[Cyper] Escalation: "+e.Summary, s.emailBody(p, ackURL))
+	case ChannelSMS:
+		if s.sms == nil {
+			return fmt.Errorf("SMS is not configured")
+		}
+		return s.sms.SendSMS(ctx, contact.Target, fmt.Sprintf("Cyper alert: %s. Acknowledge: %s", e.Summary, ackURL))
+	case ChannelWebhook:
+		return s.webhook(ctx, p, contact.Target, ackURL)
+	default:
+		return fmt.Errorf("unknown channel %q", contact.Channel)
+	}
+}
+
+func (s *Service) emailBody(p page, ackURL string) string {
+	e := &p.escalation
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\r\n\r\n", e.Summary)
+	fmt.Fprintf(&body, "Trigger: %s\r\n", e.Trigger)
+	fmt.Fprintf(&body, "Escalation level: %d of %d\r\n", p.level+1, len(e.Levels))
+	fmt.Fprintf(&body, "Started: %s\r\n", e.CreatedAt.UTC().Format(time.RFC1123))
+
+	var details map[string]interface{}
+	if json.Unmarshal(e.Details, &details) == nil && len(details) > 0 {
+		keys := make([]string, 0, len(details))
+		for key := range details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		body.WriteString("\r\n")
+		for _, key := range keys {
+			if details[key] != nil {
+				fmt.Fprintf(&body, "%s: %v\r\n", key, details[key])
+			}
+		}
+	}
+
+	if p.level+1 < len(e.Levels) {
+		fmt.Fprintf(&body, "\r\nIf nobody acknowledges within %d minutes, the next contacts will be paged.\r\n",
+			e.Levels[p.level].EscalateAfterMinutes)
+	}
+	fmt.Fprintf(&body, "\r\nAcknowledge: %s\r\n", ackURL)
+	return body.String()
+}
+
+// webhook posts the payload, signed with the policy's secret when it has
+// one, through the organization's delivery log
+func (s *Service) webhook(ctx context.Context, p page, target, ackURL string) error {
+	e := &p.escalation
+	payload := WebhookPayload{
+		Event:          "escalation.paged",
+		EscalationID:   e.ID,
+		OrganizationID: e.OrganizationID,
+		Trigger:        e.Trigger,
+		SubjectID:      e.SubjectID,
+		Summary:        e.Summary,
+		Details:        e.Details,
+		Level:          p.level + 1,
+		Levels:         len(e.Levels),
+		AcknowledgeURL: ackURL,
+		SentAt:         time.Now().UTC(),
+	}
+	_, err := s.deliveries.SendWebhook(ctx, deliveries.Webhook{
+		OrgID:    e.OrganizationID,
+		Source:   deliveries.SourceEscalation,
+		SourceID: e.ID,
+		Event:    payload.Event,
+		URL:      target,
+		Secret:   p.webhookSecret,
+		Payload:  payload,
+	})
+	return err
+}
+
+// AcknowledgeURL is the one-click acknowledgement link sent to contacts
+func (s *Service) AcknowledgeURL(escalationID string) string {
+	return strings.TrimRight(s.config.PublicURL, "/") + "/api/v1/escalations/acknowledge?token=" +
+		url.QueryEscape(escalationID+"."+s.sign(escalationID))
+}
+
+func (s *Service) sign(escalationID string) string {
+	mac := hmac.New(sha256.New, s.config.LinkSecret)
+	mac.Write([]byte("escalation-ack:" + escalationID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAckToken returns the escalation an acknowledgement link is for
+func (s *Service) VerifyAckToken(token string) (string, error) {
+	escalationID, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(escalationID))) {
+		return "", ErrInvalidAckToken
+	}
+	return escalationID, nil
+}
+
+// Acknowledge stops an escalation from paging further levels. orgID scopes
+// the lookup (empty for acknowledgement links); userID is empty when the
+// acknowledgement came from a link. Exhausted escalations can still be
+// acknowledged to record who picked them up.
+func (s *Service) Acknowledge(ctx context.Context, orgID, escalationID, userID, note, via string) (*Escalation, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var e Escalation
+	err = tx.GetContext(ctx, &e, `
+		UPDATE escalations
+		SET status = 'acknowledged', acknowledged_by = NULLIF($3, '')::uuid, ack_note = NULLIF($4, ''),
+		    acknowledged_at = NOW(), next_notify_at = NULL
+		WHERE id = $1 AND ($2 = '' OR organization_id = NULLIF($2, '')::uuid)
+		AND status IN ('open', 'exhausted')
+		RETURNING `+EscalationColumns,
+		escalationID, orgID, userID, note)
+	if err == sql.ErrNoRows {
+		var exists bool
+		err = tx.GetContext(ctx, &exists, `
+			SELECT EXISTS(SELECT 1 FROM escalations
+			WHERE id = $1 AND ($2 = '' OR organization_id = NULLIF($2, '')::uuid))
+		`, escalationID, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrAlreadyAcknowledged
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO escalation_history (escalation_id, kind, level, channel, actor_id)
+		VALUES ($1, 'acknowledged', $2, $3, NULLIF($4, '')::uuid)
+	`, e.ID, e.Level, via, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, tx.Commit()
+}
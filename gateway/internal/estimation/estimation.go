@@ -0,0 +1,282 @@
+// Package estimation predicts the impact of a scan before it runs: how long
+// it takes, how many requests it sends and how much bandwidth it uses. The
+// prediction starts from the scan type's profile, scales with the number of
+// hosts the target expands to, and is replaced by the organization's own
+// completed runs once there are enough of them. Managers see it in scan
+// preflight and when deciding on approvals.
+package estimation
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"go.uber.org/zap"
+)
+
+// Estimate bases
+const (
+	BasisProfile = "profile"
+	BasisHistory = "history"
+)
+
+// Profile is what scanning one host with a scan type in passive mode takes
+type Profile struct {
+	Duration        time.Duration
+	Credits         float64 // Per scan, whatever its size
+	RequestsPerHost int64
+	BytesPerRequest int64
+}
+
+// profiles by scan type; unknown types get defaultProfile
+var profiles = map[string]Profile{
+	"port_scan":     {Duration: 10 * time.Minute, Credits: 1, RequestsPerHost: 2000, BytesPerRequest: 80},
+	"syn":           {Duration: 10 * time.Minute, Credits: 1, RequestsPerHost: 2000, BytesPerRequest: 60},
+	"tcp_connect":   {Duration: 15 * time.Minute, Credits: 1, RequestsPerHost: 2000, BytesPerRequest: 120},
+	"udp":           {Duration: 30 * time.Minute, Credits: 2, RequestsPerHost: 1000, BytesPerRequest: 100},
+	"comprehensive": {Duration: 60 * time.Minute, Credits: 4, RequestsPerHost: 70000, BytesPerRequest: 150},
+	"web_vuln":      {Duration: 45 * time.Minute, Credits: 3, RequestsPerHost: 15000, BytesPerRequest: 2500},
+	"web_scan":      {Duration: 45 * time.Minute, Credits: 3, RequestsPerHost: 15000, BytesPerRequest: 2500},
+	"wifi":          {Duration: 15 * time.Minute, Credits: 2, RequestsPerHost: 500, BytesPerRequest: 200},
+	"cloud_audit":   {Duration: 30 * time.Minute, Credits: 3, RequestsPerHost: 3000, BytesPerRequest: 4000},
+	"exploitation":  {Duration: 90 * time.Minute, Credits: 8, RequestsPerHost: 25000, BytesPerRequest: 3000},
+}
+
+var defaultProfile = Profile{Duration: 30 * time.Minute, Credits: 2, RequestsPerHost: 5000, BytesPerRequest: 500}
+
+// ModeFactors scale a profile's duration, requests and cost by how intrusive
+// the scan is
+var ModeFactors = map[string]float64{
+	"passive":    1,
+	"active":     2,
+	"aggressive": 3,
+}
+
+const (
+	// ParallelHosts is how many hosts of a target a worker scans at once
+	ParallelHosts = 16
+
+	// MaxHosts caps the expansion of large ranges (a /8's worth)
+	MaxHosts = 1 << 24
+
+	// MinHistorySamples is how many completed runs it takes to estimate
+	// from history instead of the profile
+	MinHistorySamples = 3
+
+	// historyWindow and historyRuns bound the runs estimates learn from
+	historyWindow = 90 * 24 * time.Hour
+	historyRuns   = 50
+)
+
+// Estimate is the predicted impact of a scan
+type Estimate struct {
+	Hosts           int64   `json:"hosts"`
+	DurationSeconds int     `json:"duration_seconds"`
+	Requests        int64   `json:"requests"`
+	BandwidthBytes  int64   `json:"bandwidth_bytes"`
+	Credits         float64 `json:"credits"`
+	Basis           string  `json:"basis"` // profile, or history of the organization's runs
+	Samples         int     `json:"samples,omitempty"`
+}
+
+// Value stores an estimate as JSONB
+func (e Estimate) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+// Scan loads an estimate from JSONB
+func (e *Estimate) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*e = Estimate{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	default:
+		return fmt.Errorf("unsupported estimate type %T", src)
+	}
+}
+
+// Duration is the estimated duration
+func (e Estimate) Duration() time.Duration {
+	return time.Duration(e.DurationSeconds) * time.Second
+}
+
+// Summary is the estimate in a line, for emails and logs
+func (e Estimate) Summary() string {
+	return fmt.Sprintf("%d host(s), about %s, %s requests, %s of traffic (%s)",
+		e.Hosts, e.Duration().Round(time.Minute), formatCount(e.Requests), formatBytes(e.BandwidthBytes), e.Basis)
+}
+
+// Params describe the scan to estimate
+type Params struct {
+	OrgID       string
+	ScanType    string
+	ScanMode    string // passive when empty
+	TargetType  string
+	TargetValue string
+}
+
+// Hosts is the number of hosts a target expands to: every address of a CIDR
+// range (less network and broadcast addresses on IPv4), else one
+func Hosts(targetType, targetValue string) int64 {
+	value := strings.TrimSpace(targetValue)
+	if targetType != "cidr" && !strings.Contains(value, "/") {
+		return 1
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return 1
+	}
+	ones, bits := network.Mask.Size()
+	free := bits - ones
+	if free >= 24 {
+		return MaxHosts
+	}
+	hosts := int64(1) << free
+	if bits == 32 && free >= 2 {
+		hosts -= 2
+	}
+	return hosts
+}
+
+// Service estimates scans from profiles and the organization's history
+type Service struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewService(db *database.DB, logger *zap.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+// run is a completed scan estimates learn from
+type run struct {
+	TargetType   string  `db:"target_type"`
+	TargetValue  string  `db:"target_value"`
+	Seconds      float64 `db:"seconds"`
+	RequestsSent *int64  `db:"requests_sent"`
+	BytesSent    *int64  `db:"bytes_sent"`
+}
+
+// Estimate predicts the scan's impact. With MinHistorySamples completed
+// runs of the same scan type and mode in the organization over the last 90
+// days, their duration per batch of hosts replaces the profile's; requests
+// and bandwidth come from the runs whose worker reported them, when there
+// are as many.
+func (s *Service) Estimate(ctx context.Context, p Params) (*Estimate, error) {
+	if p.ScanMode == "" {
+		p.ScanMode = "passive"
+	}
+	profile, ok := profiles[p.ScanType]
+	if !ok {
+		profile = defaultProfile
+	}
+	factor, ok := ModeFactors[p.ScanMode]
+	if !ok {
+		return nil, fmt.Errorf("unknown scan mode %q", p.ScanMode)
+	}
+
+	hosts := Hosts(p.TargetType, p.TargetValue)
+	batches := float64(batchesOf(hosts))
+	secondsPerBatch := profile.Duration.Seconds() * factor
+	requestsPerHost := float64(profile.RequestsPerHost) * factor
+	bytesPerRequest := float64(profile.BytesPerRequest)
+
+	estimate := &Estimate{
+		Hosts:   hosts,
+		Credits: profile.Credits * factor,
+		Basis:   BasisProfile,
+	}
+
+	if p.OrgID != "" {
+		runs := []run{}
+		err := s.db.SelectContext(ctx, &runs, `
+			SELECT st.target_type, st.target_value,
+			       EXTRACT(EPOCH FROM sj.completed_at - sj.started_at) AS seconds,
+			       sj.requests_sent, sj.bytes_sent
+			FROM scan_jobs sj
+			JOIN scan_targets st ON st.id = sj.target_id
+			WHERE sj.organization_id = $1 AND sj.scan_type = $2 AND sj.scan_mode = $3
+			AND sj.status = 'completed' AND sj.started_at IS NOT NULL AND sj.completed_at IS NOT NULL
+			AND sj.completed_at > $4
+			ORDER BY sj.completed_at DESC
+			LIMIT $5
+		`, p.OrgID, p.ScanType, p.ScanMode, time.Now().Add(-historyWindow), historyRuns)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(runs) >= MinHistorySamples {
+			var seconds, requests, bytes float64
+			var sent, traffic int
+			for _, r := range runs {
+				runHosts := Hosts(r.TargetType, r.TargetValue)
+				seconds += r.Seconds / float64(batchesOf(runHosts))
+				if r.RequestsSent != nil && *r.RequestsSent > 0 {
+					requests += float64(*r.RequestsSent) / float64(runHosts)
+					sent++
+					if r.BytesSent != nil && *r.BytesSent > 0 {
+						bytes += float64(*r.BytesSent) / float64(*r.RequestsSent)
+						traffic++
+					}
+				}
+			}
+			secondsPerBatch = seconds / float64(len(runs))
+			if sent >= MinHistorySamples {
+				requestsPerHost = requests / float64(sent)
+			}
+			if traffic >= MinHistorySamples {
+				bytesPerRequest = bytes / float64(traffic)
+			}
+			estimate.Basis = BasisHistory
+			estimate.Samples = len(runs)
+		}
+	}
+
+	estimate.DurationSeconds = int(math.Ceil(secondsPerBatch * batches))
+	estimate.Requests = int64(math.Ceil(requestsPerHost * float64(hosts)))
+	estimate.BandwidthBytes = int64(math.Ceil(float64(estimate.Requests) * bytesPerRequest))
+	return estimate, nil
+}
+
+// batchesOf is how many rounds of ParallelHosts it takes to scan hosts
+func batchesOf(hosts int64) int64 {
+	if hosts < 1 {
+		return 1
+	}
+	return (hosts + ParallelHosts - 1) / ParallelHosts
+}
+
+func formatCount(n int64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.1fB", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
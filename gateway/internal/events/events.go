@@ -0,0 +1,119 @@
+// Package events publishes domain events (user registered, scan completed,
+// finding created) to downstream consumers through a transactional outbox.
+// The envelope and payloads are documented in docs/EVENTS.md.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Source identifies this service in event envelopes
+const Source = "cyper-gateway"
+
+// Event is a typed domain event payload. Bump EventVersion whenever a field
+// is removed or changes meaning; adding fields keeps the version.
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// Event types
+const (
+	TypeUserRegistered = "user.registered"
+	TypeScanCompleted  = "scan.completed"
+	TypeScanFailed     = "scan.failed"
+	TypeFindingCreated = "finding.created"
+)
+
+// UserRegistered is published when an account is created
+type UserRegistered struct {
+	UserID         string    `json:"user_id"`
+	Email          string    `json:"email"`
+	Username       string    `json:"username"`
+	OrganizationID string    `json:"organization_id,omitempty"`
+	RegisteredAt   time.Time `json:"registered_at"`
+}
+
+func (UserRegistered) EventType() string { return TypeUserRegistered }
+func (UserRegistered) EventVersion() int { return 1 }
+
+// ScanFinished is published as scan.completed or scan.failed when a worker
+// closes a scan
+type ScanFinished struct {
+	ScanID             string `json:"scan_id"`
+	UserID             string `json:"user_id"`
+	ScanType           string `json:"scan_type"`
+	Status             string `json:"status"`
+	VulnerabilityCount int    `json:"vulnerability_count"`
+	RiskScore          int32  `json:"risk_score"`
+	ErrorMessage       string `json:"error_message,omitempty"`
+}
+
+func (e ScanFinished) EventType() string {
+	if e.Status == "failed" {
+		return TypeScanFailed
+	}
+	return TypeScanCompleted
+}
+func (ScanFinished) EventVersion() int { return 1 }
+
+// FindingCreated is published for each vulnerability stored from a scan
+type FindingCreated struct {
+	FindingID         string  `json:"finding_id"`
+	ScanID            string  `json:"scan_id"`
+	Title             string  `json:"title"`
+	Severity          string  `json:"severity"`
+	CVSSScore         float64 `json:"cvss_score,omitempty"`
+	Category          string  `json:"category,omitempty"`
+	AffectedComponent string  `json:"affected_component,omitempty"`
+	Fingerprint       string  `json:"fingerprint"`
+	// Suppressed findings matched a suppression rule; consumers should not
+	// alert on them
+	Suppressed bool `json:"suppressed,omitempty"`
+}
+
+func (FindingCreated) EventType() string { return TypeFindingCreated }
+func (FindingCreated) EventVersion() int { return 1 }
+
+// Envelope wraps every published event. Consumers dispatch on Type and
+// Version; ID is stable across redeliveries and can be used to deduplicate.
+type Envelope struct {
+	ID             string          `json:"id"`
+	Type           string          `json:"type"`
+	Version        int             `json:"version"`
+	Source         string          `json:"source"`
+	Subject        string          `json:"subject"` // ID of the entity the event is about
+	OrganizationID string          `json:"organization_id,omitempty"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// Execer is satisfied by *sqlx.Tx and *database.DB
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Enqueue writes an event to the outbox. Pass the transaction that makes the
+// change the event describes, so the event is published exactly when the
+// change commits.
+func Enqueue(ctx context.Context, tx Execer, orgID, subject string, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", event.EventType(), err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO event_outbox (id, event_type, event_version, subject, organization_id, payload)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6)
+	`, uuid.New().String(), event.EventType(), event.EventVersion(), subject, orgID, data)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s event: %w", event.EventType(), err)
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+package events
+
+// DeletePublished runs one retention pass for the external tests
+var DeletePublished = (*Relay).deletePublished
@@ -0,0 +1,147 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher delivers envelopes to an event bus. Publish must not return
+// until the bus has durably accepted the event.
+type Publisher interface {
+	Publish(ctx context.Context, envelope Envelope) error
+	Close() error
+}
+
+// Discard drops every event. It is used when no event bus is configured so
+// the outbox is still drained and pruned.
+type Discard struct{}
+
+func (Discard) Publish(context.Context, Envelope) error { return nil }
+
+func (Discard) Close() error { return nil }
+
+// NATSConfig configures publishing to NATS JetStream. Events go to
+// "<SubjectPrefix>.<type>", e.g. cyper.events.scan.completed.
+type NATSConfig struct {
+	URL           string
+	Stream        string // Created over SubjectPrefix.> if it does not exist
+	SubjectPrefix string
+	CredsFile     string // Optional NATS credentials file
+}
+
+// NATSPublisher publishes to JetStream, using the envelope ID as the message
+// ID so that relay retries are deduplicated by the stream
+type NATSPublisher struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	prefix string
+}
+
+func NewNATSPublisher(config NATSConfig) (*NATSPublisher, error) {
+	opts := []nats.Option{nats.Name(Source), nats.MaxReconnects(-1)}
+	if config.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(config.CredsFile))
+	}
+	conn, err := nats.Connect(config.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	if config.Stream != "" {
+		_, err := js.StreamInfo(config.Stream)
+		if errors.Is(err, nats.ErrStreamNotFound) {
+			_, err = js.AddStream(&nats.StreamConfig{
+				Name:       config.Stream,
+				Subjects:   []string{config.SubjectPrefix + ".>"},
+				Storage:    nats.FileStorage,
+				Duplicates: 10 * time.Minute,
+			})
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to ensure stream %s: %w", config.Stream, err)
+		}
+	}
+
+	return &NATSPublisher{conn: conn, js: js, prefix: config.SubjectPrefix}, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, envelope Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(p.prefix + "." + envelope.Type)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, envelope.ID)
+	msg.Header.Set("Event-Type", envelope.Type)
+	msg.Header.Set("Event-Version", strconv.Itoa(envelope.Version))
+
+	_, err = p.js.PublishMsg(msg, nats.Context(ctx))
+	return err
+}
+
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// KafkaConfig configures publishing to Kafka. All events go to one topic,
+// keyed by subject so events about the same entity stay in order.
+type KafkaConfig struct {
+	Brokers []string
+	Topic   string
+}
+
+// KafkaPublisher publishes synchronously with acknowledgement from all
+// in-sync replicas
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaPublisher(config KafkaConfig) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(config.Brokers...),
+			Topic:                  config.Topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, envelope Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(envelope.Subject),
+		Value: data,
+		Headers: []kafka.Header{
+			{Key: "event-id", Value: []byte(envelope.ID)},
+			{Key: "event-type", Value: []byte(envelope.Type)},
+			{Key: "event-version", Value: []byte(strconv.Itoa(envelope.Version))},
+		},
+		Time: envelope.OccurredAt,
+	})
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
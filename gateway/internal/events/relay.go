@@ -0,0 +1,177 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// RelayConfig tunes how the outbox is drained
+type RelayConfig struct {
+	Interval  time.Duration // Poll interval while the outbox is empty
+	BatchSize int
+	Retention time.Duration // Published events are deleted after this long
+}
+
+func DefaultRelayConfig() RelayConfig {
+	return RelayConfig{
+		Interval:  time.Second,
+		BatchSize: 100,
+		Retention: 7 * 24 * time.Hour,
+	}
+}
+
+// Relay publishes outbox events in the order they were written. Rows are
+// claimed with SKIP LOCKED, so several gateway replicas can relay at once.
+// Delivery is at least once: an event published just before a crash is
+// published again, with the same envelope ID.
+type Relay struct {
+	db        *database.DB
+	publisher Publisher
+	config    RelayConfig
+	logger    *zap.Logger
+}
+
+func NewRelay(db *database.DB, publisher Publisher, config RelayConfig, logger *zap.Logger) *Relay {
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+type outboxRow struct {
+	ID             string    `db:"id"`
+	EventType      string    `db:"event_type"`
+	EventVersion   int       `db:"event_version"`
+	Subject        string    `db:"subject"`
+	OrganizationID *string   `db:"organization_id"`
+	Payload        []byte    `db:"payload"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// Start relays until ctx is cancelled
+func (r *Relay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Keep draining while batches come back full
+			for {
+				n, err := r.relayBatch(ctx)
+				if err != nil {
+					r.logger.Error("Failed to relay events", zap.Error(err))
+					break
+				}
+				if n < r.config.BatchSize {
+					break
+				}
+			}
+		case <-cleanup.C:
+			r.deletePublished(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// relayBatch publishes up to BatchSize events and returns how many it sent.
+// It stops at the first failure so later events do not overtake it.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rows []outboxRow
+	err = tx.SelectContext(ctx, &rows, `
+		SELECT id, event_type, event_version, subject, organization_id, payload, created_at
+		FROM event_outbox
+		WHERE published_at IS NULL
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, r.config.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim events: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	published := make([]string, 0, len(rows))
+	for _, row := range rows {
+		envelope := Envelope{
+			ID:         row.ID,
+			Type:       row.EventType,
+			Version:    row.EventVersion,
+			Source:     Source,
+			Subject:    row.Subject,
+			OccurredAt: row.CreatedAt.UTC(),
+			Data:       json.RawMessage(row.Payload),
+		}
+		if row.OrganizationID != nil {
+			envelope.OrganizationID = *row.OrganizationID
+		}
+
+		if err := r.publisher.Publish(ctx, envelope); err != nil {
+			metrics.EventsPublished.WithLabelValues(row.EventType, "failure").Inc()
+			r.logger.Warn("Failed to publish event",
+				zap.String("event_id", row.ID),
+				zap.String("type", row.EventType),
+				zap.Error(err),
+			)
+			if _, uerr := tx.ExecContext(ctx, `
+				UPDATE event_outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1
+			`, row.ID, err.Error()); uerr != nil {
+				return 0, fmt.Errorf("failed to record publish failure: %w", uerr)
+			}
+			break
+		}
+		metrics.EventsPublished.WithLabelValues(row.EventType, "success").Inc()
+		published = append(published, row.ID)
+	}
+
+	if len(published) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE event_outbox SET published_at = NOW() WHERE id = ANY($1::uuid[])
+		`, pq.Array(published))
+		if err != nil {
+			return 0, fmt.Errorf("failed to mark events published: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit relay batch: %w", err)
+	}
+	if len(published) < len(rows) {
+		return len(published), nil
+	}
+	return len(rows), nil
+}
+
+// deletePublished keeps the events of organizations under legal hold
+func (r *Relay) deletePublished(ctx context.Context) {
+	deleted, held, err := legalhold.Purge(ctx, r.db, "event_outbox", "organization_id", "",
+		"published_at < $1", time.Now().Add(-r.config.Retention))
+	if err != nil {
+		r.logger.Error("Failed to delete published events", zap.Error(err))
+		return
+	}
+	if deleted > 0 || held > 0 {
+		r.logger.Info("Deleted published events", zap.Int64("count", deleted), zap.Int64("held", held))
+	}
+}
@@ -0,0 +1,48 @@
+//go:build integration
+
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/events"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/testenv"
+	"github.com/google/uuid"
+)
+
+func TestDeletePublishedKeepsHeldOrganizations(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	admin := env.CreateUser(t, testenv.UserOptions{Role: "admin"})
+	heldOrg := env.CreateOrganization(t, "Held")
+	freeOrg := env.CreateOrganization(t, "Free")
+	if _, err := legalhold.NewService(env.DB, env.Logger).Place(ctx, legalhold.ScopeOrganization, heldOrg.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	published := time.Now().Add(-48 * time.Hour)
+	for _, orgID := range []*string{&heldOrg.ID, &freeOrg.ID, nil} {
+		_, err := env.DB.ExecContext(ctx, `
+			INSERT INTO event_outbox (id, event_type, event_version, subject, organization_id, payload, published_at)
+			VALUES ($1, 'scan.completed', 1, 'scan', $2, '{}', $3)
+		`, uuid.New().String(), orgID, published)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := events.DefaultRelayConfig()
+	config.Retention = 24 * time.Hour
+	events.DeletePublished(events.NewRelay(env.DB, nil, config, env.Logger), ctx)
+
+	var remaining []*string
+	if err := env.DB.SelectContext(ctx, &remaining, `SELECT organization_id FROM event_outbox`); err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0] == nil || *remaining[0] != heldOrg.ID {
+		t.Fatalf("%d events remain, want only the held organization's", len(remaining))
+	}
+}
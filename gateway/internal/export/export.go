@@ -0,0 +1,392 @@
+// Package export builds organization and personal data export bundles (zip
+// archives of JSON documents) as background tasks and stores them for
+// download.
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/storage"
+	"github.com/cyper-security/gateway/internal/tasks"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Export scopes
+const (
+	ScopeOrganization = "organization"
+	ScopeUser         = "user"
+)
+
+// Export statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusExpired   = "expired"
+)
+
+var ErrExportNotFound = errors.New("export not found")
+
+// TaskBuild is the task kind that builds a bundle
+const TaskBuild = "export.build"
+
+// buildAttempts bounds the builds of one export
+const buildAttempts = 3
+
+// Export is one requested bundle. DownloadURL is set once it has completed,
+// and stops working after a short time; fetch the export again for a new one.
+type Export struct {
+	ID             string     `json:"id" db:"id"`
+	Scope          string     `json:"scope" db:"scope"`
+	OrganizationID *string    `json:"organization_id,omitempty" db:"organization_id"`
+	UserID         *string    `json:"user_id,omitempty" db:"user_id"`
+	RequestedBy    string     `json:"requested_by" db:"requested_by"`
+	Status         string     `json:"status" db:"status"`
+	StorageKey     *string    `json:"-" db:"storage_key"`
+	SizeBytes      *int64     `json:"size_bytes,omitempty" db:"size_bytes"`
+	ErrorMessage   *string    `json:"error,omitempty" db:"error_message"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	DownloadURL    string     `json:"download_url,omitempty" db:"-"`
+}
+
+const exportColumns = `id, scope, organization_id, user_id, requested_by, status, storage_key,
+	size_bytes, error_message, created_at, completed_at, expires_at`
+
+// Config controls how long bundles are kept and how long links last
+type Config struct {
+	Retention    time.Duration // Bundle lifetime after completion
+	LinkTTL      time.Duration // Lifetime of each signed download URL
+	BuildTimeout time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Retention:    7 * 24 * time.Hour,
+		LinkTTL:      time.Hour,
+		BuildTimeout: 10 * time.Minute,
+	}
+}
+
+// Service creates export records and builds their bundles
+type Service struct {
+	db     *database.DB
+	store  storage.Store
+	tasks  *tasks.Service
+	config Config
+	logger *zap.Logger
+}
+
+// NewService creates the service and registers its build task on the
+// "exports" queue
+func NewService(db *database.DB, store storage.Store, taskService *tasks.Service, config Config, logger *zap.Logger) *Service {
+	s := &Service{
+		db:     db,
+		store:  store,
+		tasks:  taskService,
+		config: config,
+		logger: logger,
+	}
+	taskService.Register(TaskBuild, "exports", s.runBuild)
+	return s
+}
+
+// buildTask is the payload of a build task
+type buildTask struct {
+	ExportID string `json:"export_id"`
+}
+
+// RequestOrganization starts an export of an organization's members, scans,
+// findings, report metadata and audit summary. While one is still being built
+// it is returned instead of starting another.
+func (s *Service) RequestOrganization(ctx context.Context, orgID, requestedBy string) (*Export, error) {
+	return s.request(ctx, ScopeOrganization, orgID, requestedBy)
+}
+
+// RequestUser starts an export of the personal data held about a user
+func (s *Service) RequestUser(ctx context.Context, userID string) (*Export, error) {
+	return s.request(ctx, ScopeUser, userID, userID)
+}
+
+func (s *Service) request(ctx context.Context, scope, subjectID, requestedBy string) (*Export, error) {
+	subjectColumn := "organization_id"
+	if scope == ScopeUser {
+		subjectColumn = "user_id"
+	}
+
+	var existing Export
+	err := s.db.GetContext(ctx, &existing, `
+		SELECT `+exportColumns+` FROM data_exports
+		WHERE scope = $1 AND `+subjectColumn+` = $2 AND status IN ('pending', 'running')
+		ORDER BY created_at DESC LIMIT 1
+	`, scope, subjectID)
+	if err == nil {
+		return &existing, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check running exports: %w", err)
+	}
+
+	var export Export
+	err = s.db.GetContext(ctx, &export, `
+		INSERT INTO data_exports (id, scope, `+subjectColumn+`, requested_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+exportColumns,
+		uuid.New().String(), scope, subjectID, requestedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+
+	_, err = s.tasks.Enqueue(ctx, tasks.NewTask{
+		Kind:        TaskBuild,
+		Payload:     buildTask{ExportID: export.ID},
+		MaxAttempts: buildAttempts,
+		UniqueKey:   "export:" + export.ID,
+		CreatedBy:   requestedBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue export: %w", err)
+	}
+	return &export, nil
+}
+
+// Get loads an export, with a fresh download URL when it is ready
+func (s *Service) Get(ctx context.Context, exportID string) (*Export, error) {
+	var export Export
+	err := s.db.GetContext(ctx, &export, `SELECT `+exportColumns+` FROM data_exports WHERE id = $1`, exportID)
+	if err == sql.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export: %w", err)
+	}
+	return s.withDownloadURL(ctx, &export)
+}
+
+// LatestForUser returns the user's most recent personal export that has not
+// failed or expired, or ErrExportNotFound
+func (s *Service) LatestForUser(ctx context.Context, userID string) (*Export, error) {
+	var export Export
+	err := s.db.GetContext(ctx, &export, `
+		SELECT `+exportColumns+` FROM data_exports
+		WHERE scope = 'user' AND user_id = $1
+		  AND status IN ('pending', 'running', 'completed')
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC LIMIT 1
+	`, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export: %w", err)
+	}
+	return s.withDownloadURL(ctx, &export)
+}
+
+func (s *Service) withDownloadURL(ctx context.Context, export *Export) (*Export, error) {
+	if export.Status != StatusCompleted || export.StorageKey == nil || export.ExpiresAt == nil {
+		return export, nil
+	}
+
+	ttl := s.config.LinkTTL
+	if remaining := time.Until(*export.ExpiresAt); remaining < ttl {
+		ttl = remaining
+	}
+	if ttl <= 0 {
+		return export, nil
+	}
+
+	url, err := s.store.SignedURL(ctx, *export.StorageKey, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign download URL: %w", err)
+	}
+	export.DownloadURL = url
+	return export, nil
+}
+
+// runBuild is the build task. A failed build is retried; the last failure
+// fails the export.
+func (s *Service) runBuild(ctx context.Context, task *tasks.Task) error {
+	var payload buildTask
+	if err := task.Decode(&payload); err != nil {
+		return tasks.Permanent(err)
+	}
+	var export Export
+	err := s.db.GetContext(ctx, &export, `SELECT `+exportColumns+` FROM data_exports WHERE id = $1`, payload.ExportID)
+	if err == sql.ErrNoRows {
+		return tasks.Permanent(ErrExportNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if export.Status != StatusPending && export.Status != StatusRunning {
+		// Already finished, or failed by the reaper
+		return nil
+	}
+
+	if err := s.build(ctx, export); err != nil {
+		s.logger.Error("Export failed", zap.String("export_id", export.ID), zap.Int("attempt", task.Attempts), zap.Error(err))
+		if task.LastAttempt() {
+			_, _ = s.db.ExecContext(context.Background(), `
+				UPDATE data_exports SET status = 'failed', error_message = $2, completed_at = NOW() WHERE id = $1
+			`, export.ID, "export could not be built")
+		}
+		return err
+	}
+	return nil
+}
+
+// build writes the bundle to storage and records it as completed
+func (s *Service) build(ctx context.Context, export Export) error {
+	ctx, cancel := context.WithTimeout(ctx, s.config.BuildTimeout)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE data_exports SET status = 'running' WHERE id = $1`, export.ID); err != nil {
+		return fmt.Errorf("failed to start export: %w", err)
+	}
+
+	var sections []section
+	var key string
+	if export.Scope == ScopeOrganization {
+		sections = organizationSections(*export.OrganizationID)
+		key = fmt.Sprintf("exports/organizations/%s/%s.zip", *export.OrganizationID, export.ID)
+	} else {
+		sections = userSections(*export.UserID)
+		key = fmt.Sprintf("exports/users/%s/%s.zip", *export.UserID, export.ID)
+	}
+
+	// Stream the archive into storage as it is written
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.writeBundle(ctx, pw, export, sections))
+	}()
+	size, err := s.store.Put(ctx, key, pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = 'completed', storage_key = $2, size_bytes = $3, completed_at = NOW(), expires_at = $4
+		WHERE id = $1
+	`, export.ID, key, size, time.Now().Add(s.config.Retention))
+	if err != nil {
+		return fmt.Errorf("failed to record completed export: %w", err)
+	}
+
+	s.logger.Info("Export completed",
+		zap.String("export_id", export.ID),
+		zap.String("scope", export.Scope),
+		zap.Int64("size_bytes", size),
+	)
+	return nil
+}
+
+// section is one JSON document in the bundle, produced by a query returning
+// a single JSON value
+type section struct {
+	name  string
+	query string
+	args  []interface{}
+}
+
+func (s *Service) writeBundle(ctx context.Context, w io.Writer, export Export, sections []section) error {
+	zw := zip.NewWriter(w)
+
+	manifest, err := json.MarshalIndent(map[string]interface{}{
+		"export_id":       export.ID,
+		"scope":           export.Scope,
+		"organization_id": export.OrganizationID,
+		"user_id":         export.UserID,
+		"generated_at":    time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(zw, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	for _, sec := range sections {
+		var doc []byte
+		if err := s.db.Reader().GetContext(ctx, &doc, sec.query, sec.args...); err != nil {
+			return fmt.Errorf("failed to export %s: %w", sec.name, err)
+		}
+		if err := writeFile(zw, sec.name+".json", doc); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// StartReaper deletes bundles past their expiry and fails exports whose
+// builds have been stuck for longer than their retries could take
+func (s *Service) StartReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.reap(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Service) reap(ctx context.Context) {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE data_exports
+		SET status = 'failed', error_message = 'export was interrupted', completed_at = NOW()
+		WHERE status IN ('pending', 'running') AND created_at < $1
+	`, time.Now().Add(-2*buildAttempts*s.config.BuildTimeout))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to fail interrupted exports", zap.Error(err))
+	}
+
+	var expired []Export
+	err = s.db.SelectContext(ctx, &expired, `
+		SELECT `+exportColumns+` FROM data_exports
+		WHERE status = 'completed' AND expires_at < NOW()
+	`)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to list expired exports", zap.Error(err))
+		return
+	}
+
+	for _, export := range expired {
+		if export.StorageKey != nil {
+			if err := s.store.Delete(ctx, *export.StorageKey); err != nil {
+				logging.FromContext(ctx, s.logger).Error("Failed to delete expired export", zap.String("export_id", export.ID), zap.Error(err))
+				continue
+			}
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE data_exports SET status = 'expired' WHERE id = $1`, export.ID); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to mark export expired", zap.String("export_id", export.ID), zap.Error(err))
+		}
+	}
+}
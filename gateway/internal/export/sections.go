@@ -0,0 +1,127 @@
+package export
+
+// jsonArray wraps a row query so it returns one JSON array of its rows
+func jsonArray(query string) string {
+	return `SELECT COALESCE(json_agg(t), '[]'::json) FROM (` + query + `) t`
+}
+
+// organizationSections lists the documents in an organization export. Report
+// content and raw scan output are left out; reports are listed by metadata.
+func organizationSections(orgID string) []section {
+	return []section{
+		{
+			name: "organization",
+			query: `SELECT row_to_json(t) FROM (
+				SELECT id, name, domain, subscription_tier, max_users, features, contact_email, is_active, created_at
+				FROM organizations WHERE id = $1
+			) t`,
+			args: []interface{}{orgID},
+		},
+		{
+			name: "members",
+			query: jsonArray(`
+				SELECT u.id AS user_id, u.email, u.username, u.full_name, om.role, om.created_at AS joined_at
+				FROM organization_memberships om
+				JOIN users u ON u.id = om.user_id
+				WHERE om.organization_id = $1
+				ORDER BY om.created_at`),
+			args: []interface{}{orgID},
+		},
+		{
+			name: "scans",
+			query: jsonArray(`
+				SELECT sj.id, sj.user_id, st.target_type, st.target_value, sj.scan_type, sj.scan_mode,
+				       sj.status, sj.created_at, sj.started_at, sj.completed_at, sj.error_message
+				FROM scan_jobs sj
+				JOIN scan_targets st ON st.id = sj.target_id
+				WHERE sj.organization_id = $1
+				ORDER BY sj.created_at`),
+			args: []interface{}{orgID},
+		},
+		{
+			name: "findings",
+			query: jsonArray(`
+				SELECT v.id, v.scan_job_id, v.title, v.description, v.severity, v.cvss_score, v.cvss_vector,
+				       v.category, v.owasp_category, v.affected_component, v.remediation, v."references",
+				       v.status, v.discovered_at, v.updated_at
+				FROM vulnerabilities v
+				JOIN scan_jobs sj ON sj.id = v.scan_job_id
+				WHERE sj.organization_id = $1
+				ORDER BY v.discovered_at`),
+			args: []interface{}{orgID},
+		},
+		{
+			name: "reports",
+			query: jsonArray(`
+				SELECT r.id, r.scan_job_id, r.report_type, r.title, r.format, r.content_type,
+				       r.generated_by, r.generated_at
+				FROM reports r
+				JOIN scan_jobs sj ON sj.id = r.scan_job_id
+				WHERE sj.organization_id = $1
+				ORDER BY r.generated_at`),
+			args: []interface{}{orgID},
+		},
+		{
+			name: "audit_summary",
+			query: jsonArray(`
+				SELECT action, status, COUNT(*) AS count, MIN(timestamp) AS first_seen, MAX(timestamp) AS last_seen
+				FROM audit_logs
+				WHERE organization_id = $1
+				GROUP BY action, status
+				ORDER BY action, status`),
+			args: []interface{}{orgID},
+		},
+	}
+}
+
+// userSections lists the documents in a personal data export
+func userSections(userID string) []section {
+	return []section{
+		{
+			name: "profile",
+			query: `SELECT row_to_json(t) FROM (
+				SELECT id, email, username, full_name, organization_id, role, features, is_active,
+				       terms_accepted_at, terms_version, created_at, last_login_at
+				FROM users WHERE id = $1
+			) t`,
+			args: []interface{}{userID},
+		},
+		{
+			name: "memberships",
+			query: jsonArray(`
+				SELECT o.id AS organization_id, o.name, om.role, om.created_at AS joined_at
+				FROM organization_memberships om
+				JOIN organizations o ON o.id = om.organization_id
+				WHERE om.user_id = $1
+				ORDER BY om.created_at`),
+			args: []interface{}{userID},
+		},
+		{
+			name: "sessions",
+			query: jsonArray(`
+				SELECT id, ip_address, user_agent, created_at, last_activity_at, expires_at, revoked_at
+				FROM sessions WHERE user_id = $1
+				ORDER BY created_at`),
+			args: []interface{}{userID},
+		},
+		{
+			name: "scans",
+			query: jsonArray(`
+				SELECT sj.id, sj.organization_id, st.target_type, st.target_value, sj.scan_type, sj.scan_mode,
+				       sj.status, sj.created_at, sj.completed_at
+				FROM scan_jobs sj
+				JOIN scan_targets st ON st.id = sj.target_id
+				WHERE sj.user_id = $1
+				ORDER BY sj.created_at`),
+			args: []interface{}{userID},
+		},
+		{
+			name: "activity",
+			query: jsonArray(`
+				SELECT action, resource_type, resource_id, target, details, ip_address, user_agent, status, timestamp
+				FROM audit_logs WHERE user_id = $1
+				ORDER BY timestamp`),
+			args: []interface{}{userID},
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package findings
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterExportFormat(ExportFormat{
+		Name:        "defectdojo",
+		ContentType: "application/json",
+		Extension:   "json",
+		New:         newDefectDojoWriter,
+	})
+}
+
+// defectDojoFinding is one finding in DefectDojo's Generic Findings Import
+// JSON format
+type defectDojoFinding struct {
+	Title            string               `json:"title"`
+	Description      string               `json:"description"`
+	Severity         string               `json:"severity"`
+	Mitigation       string               `json:"mitigation,omitempty"`
+	Date             string               `json:"date"`
+	CVE              string               `json:"cve,omitempty"`
+	CWE              int                  `json:"cwe,omitempty"`
+	CVSSv3           string               `json:"cvssv3,omitempty"`
+	CVSSv3Score      *float64             `json:"cvssv3_score,omitempty"`
+	UniqueIDFromTool string               `json:"unique_id_from_tool"`
+	VulnIDFromTool   string               `json:"vuln_id_from_tool,omitempty"`
+	Active           bool                 `json:"active"`
+	Verified         bool                 `json:"verified"`
+	FalsePositive    bool                 `json:"false_p"`
+	IsMitigated      bool                 `json:"is_mitigated"`
+	RiskAccepted     bool                 `json:"risk_accepted"`
+	Endpoints        []defectDojoEndpoint `json:"endpoints,omitempty"`
+	StaticFinding    bool                 `json:"static_finding"`
+	DynamicFinding   bool                 `json:"dynamic_finding"`
+}
+
+type defectDojoEndpoint struct {
+	Protocol string `json:"protocol,omitempty"`
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// defectDojoWriter streams {"findings": [...]}, one finding at a time
+type defectDojoWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newDefectDojoWriter(w io.Writer, _ []Column) (RecordWriter, error) {
+	if _, err := io.WriteString(w, `{"findings":[`); err != nil {
+		return nil, err
+	}
+	return &defectDojoWriter{w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (dw *defectDojoWriter) Write(r *Record) error {
+	if dw.count > 0 {
+		if _, err := io.WriteString(dw.w, ","); err != nil {
+			return err
+		}
+	}
+	dw.count++
+
+	finding := defectDojoFinding{
+		Title:            r.Title,
+		Description:      r.Description,
+		Severity:         defectDojoSeverity(r.Severity),
+		Mitigation:       deref(r.Remediation),
+		Date:             r.DiscoveredAt.UTC().Format("2006-01-02"),
+		CVE:              deref(r.CVEID),
+		CVSSv3:           deref(r.CVSSVector),
+		CVSSv3Score:      r.CVSSScore,
+		UniqueIDFromTool: r.ID,
+		VulnIDFromTool:   r.Fingerprint,
+		Active:           r.Status == "open" || r.Status == "confirmed",
+		Verified:         r.Status == "confirmed",
+		FalsePositive:    r.Status == "false_positive",
+		IsMitigated:      r.Status == "fixed",
+		RiskAccepted:     r.Status == "accepted",
+		DynamicFinding:   true,
+	}
+	// DefectDojo takes a single CWE
+	if len(r.CWEIDs) > 0 {
+		finding.CWE, _ = strconv.Atoi(strings.TrimPrefix(strings.ToUpper(r.CWEIDs[0]), "CWE-"))
+	}
+	if endpoint, ok := defectDojoEndpointFor(r); ok {
+		finding.Endpoints = []defectDojoEndpoint{endpoint}
+	}
+	return dw.enc.Encode(finding)
+}
+
+func (dw *defectDojoWriter) Close() error {
+	_, err := io.WriteString(dw.w, "]}\n")
+	return err
+}
+
+// defectDojoEndpointFor locates the finding at its affected component when
+// that is a URL, otherwise at the scan target
+func defectDojoEndpointFor(r *Record) (defectDojoEndpoint, bool) {
+	location := r.Target
+	if r.AffectedComponent != nil && strings.Contains(*r.AffectedComponent, "://") {
+		location = *r.AffectedComponent
+	}
+	if location == "" {
+		return defectDojoEndpoint{}, false
+	}
+
+	u, err := url.Parse(location)
+	if err != nil || u.Host == "" {
+		return defectDojoEndpoint{Host: location}, true
+	}
+	endpoint := defectDojoEndpoint{Protocol: u.Scheme, Host: u.Hostname(), Path: strings.TrimPrefix(u.Path, "/")}
+	endpoint.Port, _ = strconv.Atoi(u.Port())
+	return endpoint, true
+}
+
+func defectDojoSeverity(severity string) string {
+	switch severity {
+	case "critical":
+		return "Critical"
+	case "high":
+		return "High"
+	case "medium":
+		return "Medium"
+	case "low":
+		return "Low"
+	default:
+		return "Info"
+	}
+}
@@ -0,0 +1,107 @@
+package findings
+
+import "sort"
+
+// Finding is a vulnerability as compared between scans
+type Finding struct {
+	ID                string   `json:"id" db:"id"`
+	Fingerprint       string   `json:"fingerprint" db:"fingerprint"`
+	Title             string   `json:"title" db:"title"`
+	Severity          string   `json:"severity" db:"severity"`
+	CVSSScore         *float64 `json:"cvss_score,omitempty" db:"cvss_score"`
+	Category          *string  `json:"category,omitempty" db:"category"`
+	AffectedComponent *string  `json:"affected_component,omitempty" db:"affected_component"`
+	Status            string   `json:"status" db:"status"`
+}
+
+// PersistingFinding is a finding present in both scans
+type PersistingFinding struct {
+	Current         Finding `json:"current"`
+	Previous        Finding `json:"previous"`
+	SeverityChanged bool    `json:"severity_changed"`
+}
+
+// DiffSummary counts findings by change and severity
+type DiffSummary struct {
+	New        int                       `json:"new"`
+	Resolved   int                       `json:"resolved"`
+	Persisting int                       `json:"persisting"`
+	BySeverity map[string]map[string]int `json:"by_severity"` // change -> severity -> count
+}
+
+// Diff is the change in findings from a baseline scan to a current scan
+type Diff struct {
+	Summary    DiffSummary         `json:"summary"`
+	New        []Finding           `json:"new"`
+	Resolved   []Finding           `json:"resolved"`
+	Persisting []PersistingFinding `json:"persisting"`
+}
+
+// Compare matches findings by fingerprint. Duplicate fingerprints within a scan
+// are treated as one finding, keeping the most severe.
+func Compare(current, baseline []Finding) *Diff {
+	cur := index(current)
+	base := index(baseline)
+
+	diff := &Diff{
+		New:        []Finding{},
+		Resolved:   []Finding{},
+		Persisting: []PersistingFinding{},
+		Summary: DiffSummary{BySeverity: map[string]map[string]int{
+			"new": {}, "resolved": {}, "persisting": {},
+		}},
+	}
+
+	for fp, f := range cur {
+		if prev, ok := base[fp]; ok {
+			diff.Persisting = append(diff.Persisting, PersistingFinding{
+				Current:         f,
+				Previous:        prev,
+				SeverityChanged: f.Severity != prev.Severity,
+			})
+			diff.Summary.BySeverity["persisting"][f.Severity]++
+		} else {
+			diff.New = append(diff.New, f)
+			diff.Summary.BySeverity["new"][f.Severity]++
+		}
+	}
+	for fp, f := range base {
+		if _, ok := cur[fp]; !ok {
+			diff.Resolved = append(diff.Resolved, f)
+			diff.Summary.BySeverity["resolved"][f.Severity]++
+		}
+	}
+
+	sortFindings(diff.New)
+	sortFindings(diff.Resolved)
+	sort.Slice(diff.Persisting, func(i, j int) bool {
+		return less(diff.Persisting[i].Current, diff.Persisting[j].Current)
+	})
+
+	diff.Summary.New = len(diff.New)
+	diff.Summary.Resolved = len(diff.Resolved)
+	diff.Summary.Persisting = len(diff.Persisting)
+	return diff
+}
+
+func index(list []Finding) map[string]Finding {
+	m := make(map[string]Finding, len(list))
+	for _, f := range list {
+		if existing, ok := m[f.Fingerprint]; ok && SeverityRank(existing.Severity) <= SeverityRank(f.Severity) {
+			continue
+		}
+		m[f.Fingerprint] = f
+	}
+	return m
+}
+
+func sortFindings(list []Finding) {
+	sort.Slice(list, func(i, j int) bool { return less(list[i], list[j]) })
+}
+
+func less(a, b Finding) bool {
+	if SeverityRank(a.Severity) != SeverityRank(b.Severity) {
+		return SeverityRank(a.Severity) < SeverityRank(b.Severity)
+	}
+	return a.Title < b.Title
+}
@@ -0,0 +1,211 @@
+package findings
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownColumn is returned when a column selection names a column that
+// does not exist
+var ErrUnknownColumn = errors.New("unknown export column")
+
+// Record is a finding as exported, with the scan it came from
+type Record struct {
+	Detail
+	ScanID        string    `db:"scan_job_id"`
+	Target        string    `db:"target"`
+	CVSSVector    *string   `db:"cvss_vector"`
+	OWASPCategory *string   `db:"owasp_category"`
+	AssigneeID    *string   `db:"assignee_id"`
+	DiscoveredAt  time.Time `db:"discovered_at"`
+}
+
+// RecordColumns selects a Record from vulnerabilities v joined to scan_jobs
+// sj and scan_targets st, and left joined to cve_intel ci and
+// known_exploited_cves k
+const RecordColumns = `v.id, v.fingerprint, v.title, v.severity, v.cvss_score, v.category, v.affected_component,
+	COALESCE(v.status, 'open') AS status, v.description, v.remediation,
+	v.suppressed_at IS NOT NULL AS suppressed, v.suppression_justification,
+	v.cve_id, v.cwe_ids, ci.epss_score, k.cve_id IS NOT NULL AS known_exploited,
+	v.scan_job_id, st.target_value AS target, v.cvss_vector, v.owasp_category, v.assignee_id,
+	COALESCE(v.discovered_at, sj.created_at) AS discovered_at`
+
+// Column is one field of a tabular export
+type Column struct {
+	Name  string
+	Value func(r *Record) string
+}
+
+var columns = []Column{
+	{"id", func(r *Record) string { return r.ID }},
+	{"scan_id", func(r *Record) string { return r.ScanID }},
+	{"target", func(r *Record) string { return r.Target }},
+	{"title", func(r *Record) string { return r.Title }},
+	{"severity", func(r *Record) string { return r.Severity }},
+	{"status", func(r *Record) string { return r.Status }},
+	{"cvss_score", func(r *Record) string { return formatScore(r.CVSSScore) }},
+	{"cvss_vector", func(r *Record) string { return deref(r.CVSSVector) }},
+	{"cve_id", func(r *Record) string { return deref(r.CVEID) }},
+	{"cwe_ids", func(r *Record) string { return strings.Join(r.CWEIDs, " ") }},
+	{"epss_score", func(r *Record) string { return formatEPSS(r.EPSSScore) }},
+	{"known_exploited", func(r *Record) string { return strconv.FormatBool(r.KnownExploited) }},
+	{"category", func(r *Record) string { return deref(r.Category) }},
+	{"owasp_category", func(r *Record) string { return deref(r.OWASPCategory) }},
+	{"affected_component", func(r *Record) string { return deref(r.AffectedComponent) }},
+	{"description", func(r *Record) string { return r.Description }},
+	{"remediation", func(r *Record) string { return deref(r.Remediation) }},
+	{"assignee_id", func(r *Record) string { return deref(r.AssigneeID) }},
+	{"suppressed", func(r *Record) string { return strconv.FormatBool(r.Suppressed) }},
+	{"fingerprint", func(r *Record) string { return r.Fingerprint }},
+	{"discovered_at", func(r *Record) string { return r.DiscoveredAt.UTC().Format(time.RFC3339) }},
+}
+
+// DefaultColumns are exported when no columns are selected
+var DefaultColumns = []string{
+	"id", "target", "title", "severity", "status", "cvss_score", "cve_id",
+	"affected_component", "discovered_at",
+}
+
+// ColumnNames lists every exportable column
+func ColumnNames() []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// SelectColumns resolves column names, in the order given
+func SelectColumns(names []string) ([]Column, error) {
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+	selected := make([]Column, 0, len(names))
+	for _, name := range names {
+		col, ok := findColumn(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, name)
+		}
+		selected = append(selected, col)
+	}
+	return selected, nil
+}
+
+func findColumn(name string) (Column, bool) {
+	for _, col := range columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+// RecordWriter streams records in one export format. Close writes whatever
+// the format needs after the last record; it does not close the underlying
+// writer.
+type RecordWriter interface {
+	Write(r *Record) error
+	Close() error
+}
+
+// ExportFormat is a findings export format
+type ExportFormat struct {
+	Name        string
+	ContentType string
+	Extension   string
+	// Tabular formats write the selected columns; the others have the fixed
+	// schema of the tool they are imported into
+	Tabular bool
+	New     func(w io.Writer, columns []Column) (RecordWriter, error)
+}
+
+var exportFormats = map[string]ExportFormat{}
+
+// RegisterExportFormat adds a format, replacing any of the same name
+func RegisterExportFormat(format ExportFormat) {
+	exportFormats[format.Name] = format
+}
+
+// LookupExportFormat returns the named format
+func LookupExportFormat(name string) (ExportFormat, bool) {
+	format, ok := exportFormats[name]
+	return format, ok
+}
+
+// ExportFormatNames lists the registered formats
+func ExportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterExportFormat(ExportFormat{
+		Name:        "csv",
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		Tabular:     true,
+		New:         newCSVWriter,
+	})
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+	row     []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (RecordWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns, row: make([]string, len(columns))}
+	for i, col := range columns {
+		cw.row[i] = col.Name
+	}
+	if err := cw.w.Write(cw.row); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(r *Record) error {
+	for i, col := range cw.columns {
+		cw.row[i] = csvSafe(col.Value(r))
+	}
+	return cw.w.Write(cw.row)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// csvSafe stops spreadsheet applications evaluating scanner-controlled text
+// as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatScore(score *float64) string {
+	if score == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*score, 'f', 1, 64)
+}
+
+func formatEPSS(score *float64) string {
+	if score == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*score, 'f', 4, 64)
+}
@@ -0,0 +1,26 @@
+// Package findings identifies vulnerabilities across scan runs.
+package findings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Fingerprint is the dedup key for a vulnerability: the same issue reported by
+// different scans of a target shares a fingerprint. It must stay in sync with
+// the backfill in database/migrations/007_add_vulnerability_fingerprints.sql.
+func Fingerprint(title, category, affectedComponent string) string {
+	key := strings.Join([]string{
+		normalize(title),
+		normalize(category),
+		normalize(affectedComponent),
+	}, "|")
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize matches lower(btrim(s)) in PostgreSQL
+func normalize(s string) string {
+	return strings.ToLower(strings.Trim(s, " "))
+}
@@ -0,0 +1,162 @@
+package findings
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterExportFormat(ExportFormat{
+		Name:        "nessus",
+		ContentType: "application/xml",
+		Extension:   "nessus",
+		New:         newNessusWriter,
+	})
+}
+
+// nessusItem is a ReportItem in the .nessus (NessusClientData_v2) format
+type nessusItem struct {
+	XMLName      xml.Name `xml:"ReportItem"`
+	Port         int      `xml:"port,attr"`
+	ServiceName  string   `xml:"svc_name,attr"`
+	Protocol     string   `xml:"protocol,attr"`
+	Severity     int      `xml:"severity,attr"`
+	PluginID     uint64   `xml:"pluginID,attr"`
+	PluginName   string   `xml:"pluginName,attr"`
+	PluginFamily string   `xml:"pluginFamily,attr"`
+	Synopsis     string   `xml:"synopsis"`
+	Description  string   `xml:"description"`
+	Solution     string   `xml:"solution,omitempty"`
+	RiskFactor   string   `xml:"risk_factor"`
+	CVEs         []string `xml:"cve,omitempty"`
+	CWEs         []string `xml:"cwe,omitempty"`
+	CVSS3Score   string   `xml:"cvss3_base_score,omitempty"`
+	CVSS3Vector  string   `xml:"cvss3_vector,omitempty"`
+	PluginOutput string   `xml:"plugin_output,omitempty"`
+}
+
+// nessusWriter streams one ReportHost per target. Records must arrive
+// grouped by target.
+type nessusWriter struct {
+	enc  *xml.Encoder
+	host string
+}
+
+func newNessusWriter(w io.Writer, _ []Column) (RecordWriter, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+	nw := &nessusWriter{enc: xml.NewEncoder(w)}
+	nw.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "NessusClientData_v2"}})
+	err := nw.enc.EncodeToken(xml.StartElement{
+		Name: xml.Name{Local: "Report"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: "Cyper Security findings"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nw, nil
+}
+
+func (nw *nessusWriter) Write(r *Record) error {
+	host, port, protocol := nessusLocation(r.Target)
+	if host != nw.host {
+		if nw.host != "" {
+			nw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "ReportHost"}})
+		}
+		nw.host = host
+		nw.enc.EncodeToken(xml.StartElement{
+			Name: xml.Name{Local: "ReportHost"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: host}},
+		})
+	}
+
+	// Like SARIF rules, plugin IDs group findings that differ only by location
+	pluginID, _ := strconv.ParseUint(Fingerprint(r.Title, deref(r.Category), "")[:7], 16, 64)
+	item := nessusItem{
+		Port:         port,
+		ServiceName:  protocol,
+		Protocol:     "tcp",
+		Severity:     nessusSeverity(r.Severity),
+		PluginID:     pluginID,
+		PluginName:   r.Title,
+		PluginFamily: deref(r.Category),
+		Synopsis:     r.Title,
+		Description:  r.Description,
+		Solution:     deref(r.Remediation),
+		RiskFactor:   nessusRiskFactor(r.Severity),
+		CWEs:         r.CWEIDs,
+		CVSS3Score:   formatScore(r.CVSSScore),
+		CVSS3Vector:  deref(r.CVSSVector),
+		PluginOutput: deref(r.AffectedComponent),
+	}
+	if r.CVEID != nil {
+		item.CVEs = []string{*r.CVEID}
+	}
+	if item.PluginFamily == "" {
+		item.PluginFamily = "General"
+	}
+	return nw.enc.Encode(item)
+}
+
+func (nw *nessusWriter) Close() error {
+	if nw.host != "" {
+		nw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "ReportHost"}})
+	}
+	nw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "Report"}})
+	nw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "NessusClientData_v2"}})
+	return nw.enc.Flush()
+}
+
+// nessusLocation splits a scan target into host, port and service name
+func nessusLocation(target string) (string, int, string) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		if target == "" {
+			return "unknown", 0, "general"
+		}
+		return target, 0, "general"
+	}
+	port, _ := strconv.Atoi(u.Port())
+	if port == 0 {
+		switch u.Scheme {
+		case "https":
+			port = 443
+		case "http":
+			port = 80
+		}
+	}
+	service := strings.ToLower(u.Scheme)
+	if service == "http" || service == "https" {
+		service = "www"
+	}
+	return u.Hostname(), port, service
+}
+
+// nessusSeverity maps severities onto Nessus's 0 (info) to 4 (critical)
+func nessusSeverity(severity string) int {
+	switch severity {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	default:
+		return 0
+	}
+}
+
+func nessusRiskFactor(severity string) string {
+	switch severity {
+	case "critical", "high", "medium", "low":
+		return strings.ToUpper(severity[:1]) + severity[1:]
+	default:
+		return "None"
+	}
+}
@@ -0,0 +1,187 @@
+package findings
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Detail is a finding with the fields needed to describe it outside the gateway
+type Detail struct {
+	Finding
+	Description string  `json:"description" db:"description"`
+	Remediation *string `json:"remediation,omitempty" db:"remediation"`
+	// Set when a suppression rule matched the finding at ingestion
+	Suppressed               bool    `json:"suppressed,omitempty" db:"suppressed"`
+	SuppressionJustification *string `json:"suppression_justification,omitempty" db:"suppression_justification"`
+	// Vulnerability intelligence, when the finding has a known CVE
+	CVEID          *string        `json:"cve_id,omitempty" db:"cve_id"`
+	CWEIDs         pq.StringArray `json:"cwe_ids,omitempty" db:"cwe_ids"`
+	EPSSScore      *float64       `json:"epss_score,omitempty" db:"epss_score"`
+	KnownExploited bool           `json:"known_exploited,omitempty" db:"known_exploited"`
+}
+
+// SARIFLog is a SARIF 2.1.0 log, importable into GitHub code scanning
+type SARIFLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool              `json:"tool"`
+	Results []SARIFResult          `json:"results"`
+	Props   map[string]interface{} `json:"properties,omitempty"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+type SARIFRule struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	ShortDescription SARIFMessage           `json:"shortDescription"`
+	Help             *SARIFMessage          `json:"help,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type SARIFResult struct {
+	RuleID              string             `json:"ruleId"`
+	Level               string             `json:"level"`
+	Message             SARIFMessage       `json:"message"`
+	Locations           []SARIFLocation    `json:"locations"`
+	PartialFingerprints map[string]string  `json:"partialFingerprints"`
+	Suppressions        []SARIFSuppression `json:"suppressions,omitempty"`
+}
+
+// SARIFSuppression hides a result from code scanning alerts
+type SARIFSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIF converts a scan's findings into a SARIF log. Findings without an
+// affected component are located at target, since code scanning requires
+// every result to have a location.
+func SARIF(scanID, target string, details []Detail) *SARIFLog {
+	run := SARIFRun{
+		Tool: SARIFTool{Driver: SARIFDriver{
+			Name:           "Cyper Security",
+			InformationURI: "https://cyper.security",
+			Rules:          []SARIFRule{},
+		}},
+		Results: []SARIFResult{},
+		Props:   map[string]interface{}{"scan_id": scanID},
+	}
+
+	rules := make(map[string]bool)
+	for _, d := range details {
+		// Rules group findings that differ only by location
+		ruleID := Fingerprint(d.Title, deref(d.Category), "")[:16]
+		if !rules[ruleID] {
+			rules[ruleID] = true
+			rule := SARIFRule{
+				ID:               ruleID,
+				Name:             d.Title,
+				ShortDescription: SARIFMessage{Text: d.Title},
+				Properties:       map[string]interface{}{},
+			}
+			tags := []string{"security"}
+			if d.Category != nil {
+				tags = append(tags, *d.Category)
+			}
+			// GitHub links CWE tags in this form to their definitions
+			for _, cwe := range d.CWEIDs {
+				tags = append(tags, "external/cwe/"+strings.ToLower(cwe))
+			}
+			rule.Properties["tags"] = tags
+			if d.CVSSScore != nil {
+				// Read by GitHub to rank alerts
+				rule.Properties["security-severity"] = strconv.FormatFloat(*d.CVSSScore, 'f', 1, 64)
+			}
+			if d.Remediation != nil {
+				rule.Help = &SARIFMessage{Text: *d.Remediation}
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+
+		uri := target
+		if d.AffectedComponent != nil && *d.AffectedComponent != "" {
+			uri = *d.AffectedComponent
+		}
+
+		result := SARIFResult{
+			RuleID:  ruleID,
+			Level:   sarifLevel(d.Severity),
+			Message: SARIFMessage{Text: fmt.Sprintf("[%s] %s", d.Severity, d.Description)},
+			Locations: []SARIFLocation{{
+				PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: uri}},
+			}},
+			PartialFingerprints: map[string]string{"cyperFingerprint/v1": d.Fingerprint},
+		}
+		if d.Suppressed {
+			result.Suppressions = []SARIFSuppression{{Kind: "external", Justification: deref(d.SuppressionJustification)}}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	return &SARIFLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []SARIFRun{run},
+	}
+}
+
+// sarifLevel maps severities onto SARIF result levels
+func sarifLevel(severity string) string {
+	switch severity {
+	case "critical", "high":
+		return "error"
+	case "medium":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,26 @@
+package findings
+
+import "strings"
+
+// Severities of findings, which audit entries share
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+// Severities lists every severity, most severe first
+var Severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo}
+
+// SeverityRank orders severities, most severe first (critical is 0), in any
+// case; unknown ones rank last
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if strings.EqualFold(s, severity) {
+			return i
+		}
+	}
+	return len(Severities)
+}
@@ -0,0 +1,107 @@
+package findings
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SuppressionRule marks matching findings of an organization as suppressed
+// when they are ingested, e.g. a known false positive. Every criterion that
+// is set must match.
+type SuppressionRule struct {
+	ID             string     `json:"id" db:"id"`
+	OrganizationID string     `json:"organization_id" db:"organization_id"`
+	Asset          *string    `json:"asset,omitempty" db:"asset"` // scan target value; nil for every asset
+	PluginID       *string    `json:"plugin_id,omitempty" db:"plugin_id"`
+	CVEID          *string    `json:"cve_id,omitempty" db:"cve_id"`
+	PathPattern    *string    `json:"path_pattern,omitempty" db:"path_pattern"`
+	Justification  string     `json:"justification" db:"justification"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedBy      *string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// SuppressionRuleColumns selects every SuppressionRule field
+const SuppressionRuleColumns = `id, organization_id, asset, plugin_id, cve_id, path_pattern,
+	justification, expires_at, created_by, created_at`
+
+// Candidate is an incoming finding as seen by suppression rules
+type Candidate struct {
+	Asset             string
+	PluginID          string
+	CVEID             string
+	AffectedComponent string
+}
+
+// Matches reports whether the rule suppresses c. A rule with no criteria
+// matches nothing.
+func (r *SuppressionRule) Matches(c Candidate) bool {
+	if r.PluginID == nil && r.CVEID == nil && r.PathPattern == nil {
+		return false
+	}
+	if r.Asset != nil && *r.Asset != c.Asset {
+		return false
+	}
+	if r.PluginID != nil && *r.PluginID != c.PluginID {
+		return false
+	}
+	if r.CVEID != nil && !strings.EqualFold(*r.CVEID, c.CVEID) {
+		return false
+	}
+	return r.PathPattern == nil || MatchPath(*r.PathPattern, c.AffectedComponent)
+}
+
+// MatchSuppression returns the first rule suppressing c, or nil
+func MatchSuppression(rules []SuppressionRule, c Candidate) *SuppressionRule {
+	for i := range rules {
+		if rules[i].Matches(c) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// ActiveSuppressionRules loads the organization's unexpired rules that apply
+// to asset, oldest first so the original justification wins
+func ActiveSuppressionRules(ctx context.Context, q sqlx.QueryerContext, orgID, asset string) ([]SuppressionRule, error) {
+	rules := []SuppressionRule{}
+	err := sqlx.SelectContext(ctx, q, &rules, `
+		SELECT `+SuppressionRuleColumns+` FROM finding_suppression_rules
+		WHERE organization_id = $1
+		AND (asset IS NULL OR asset = $2)
+		AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at
+	`, orgID, asset)
+	return rules, err
+}
+
+// MatchPath matches s against a glob in which * matches any run of
+// characters (including /) and ? matches exactly one
+func MatchPath(pattern, s string) bool {
+	p, i := 0, 0
+	star, mark := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, i
+			p++
+		case star >= 0:
+			// Let the last * absorb one more character and retry
+			p = star + 1
+			mark++
+			i = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,112 @@
+package findings
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+)
+
+// xlsxMaxCellLength is Excel's limit on the characters in a cell
+const xlsxMaxCellLength = 32767
+
+// xlsxNumeric columns are written as numbers so they sort and filter as such
+var xlsxNumeric = map[string]bool{
+	"cvss_score": true,
+	"epss_score": true,
+}
+
+// The parts of a workbook with one sheet, other than the sheet itself
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Findings" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func init() {
+	RegisterExportFormat(ExportFormat{
+		Name:        "xlsx",
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extension:   "xlsx",
+		Tabular:     true,
+		New:         newXLSXWriter,
+	})
+}
+
+// xlsxWriter streams a single-sheet workbook. Cells are inline strings, so
+// no shared string table has to be held until the end.
+type xlsxWriter struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	columns []Column
+}
+
+func newXLSXWriter(w io.Writer, columns []Column) (RecordWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &xlsxWriter{zip: zw, sheet: bufio.NewWriter(f), columns: columns}
+	xw.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	xw.sheet.WriteString("<row>")
+	for _, col := range columns {
+		xw.stringCell(col.Name)
+	}
+	xw.sheet.WriteString("</row>")
+	return xw, nil
+}
+
+func (xw *xlsxWriter) Write(r *Record) error {
+	xw.sheet.WriteString("<row>")
+	for _, col := range xw.columns {
+		value := col.Value(r)
+		if xlsxNumeric[col.Name] && value != "" {
+			xw.sheet.WriteString("<c><v>" + value + "</v></c>")
+			continue
+		}
+		xw.stringCell(value)
+	}
+	_, err := xw.sheet.WriteString("</row>")
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	xw.sheet.WriteString("</sheetData></worksheet>")
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+func (xw *xlsxWriter) stringCell(value string) {
+	if runes := []rune(value); len(runes) > xlsxMaxCellLength {
+		value = string(runes[:xlsxMaxCellLength])
+	}
+	xw.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	// EscapeText also replaces characters XML cannot carry
+	xml.EscapeText(xw.sheet, []byte(value))
+	xw.sheet.WriteString("</t></is></c>")
+}
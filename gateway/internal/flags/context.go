@@ -0,0 +1,56 @@
+package flags
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type contextKey struct{}
+
+type evaluator struct {
+	service *Service
+	target  Target
+}
+
+// WithTarget attaches the flag service and the user and organization to
+// evaluate for, so code further down can call IsEnabled with just the context
+func WithTarget(ctx context.Context, service *Service, target Target) context.Context {
+	return context.WithValue(ctx, contextKey{}, evaluator{service: service, target: target})
+}
+
+// IsEnabled reports whether a flag is on for the request's user and
+// organization. It is false outside requests that went through Middleware.
+func IsEnabled(ctx context.Context, key string) bool {
+	e, ok := ctx.Value(contextKey{}).(evaluator)
+	if !ok || e.service == nil {
+		return false
+	}
+	return e.service.Evaluate(ctx, key, e.target)
+}
+
+// Middleware makes IsEnabled available in handlers. It must run after
+// AuthMiddleware, which sets user_id and organization_id.
+func (s *Service) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target := Target{
+			UserID: c.GetString("user_id"),
+			OrgID:  c.GetString("organization_id"),
+		}
+		c.Request = c.Request.WithContext(WithTarget(c.Request.Context(), s, target))
+		c.Next()
+	}
+}
+
+// Require hides a route (404) unless the flag is on for the caller
+func Require(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsEnabled(c.Request.Context(), key) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
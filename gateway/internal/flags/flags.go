@@ -0,0 +1,339 @@
+package flags
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrFlagNotFound     = errors.New("feature flag not found")
+	ErrOverrideNotFound = errors.New("feature flag override not found")
+	ErrInvalidKey       = errors.New("flag keys may only contain lowercase letters, digits and underscores")
+	ErrInvalidScope     = errors.New("override scope must be organization or user")
+	ErrInvalidRollout   = errors.New("rollout percentage must be between 0 and 100")
+)
+
+// Override scopes
+const (
+	ScopeOrganization = "organization"
+	ScopeUser         = "user"
+)
+
+// cacheKey holds every flag with its overrides as one JSON document, shared
+// by all gateway instances and dropped on each change
+const cacheKey = "feature_flags"
+
+var keyPattern = regexp.MustCompile(`^[a-z0-9_]{1,100}$`)
+
+// Flag is a runtime feature toggle
+type Flag struct {
+	Key               string     `json:"key" db:"key"`
+	Description       string     `json:"description" db:"description"`
+	Enabled           bool       `json:"enabled" db:"enabled"`
+	RolloutPercentage int        `json:"rollout_percentage" db:"rollout_percentage"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	UpdatedBy         *string    `json:"updated_by,omitempty" db:"updated_by"`
+	Overrides         []Override `json:"overrides" db:"-"`
+}
+
+// Override forces a flag on or off for one organization or user
+type Override struct {
+	FlagKey   string    `json:"flag_key" db:"flag_key"`
+	Scope     string    `json:"scope" db:"scope"`
+	TargetID  string    `json:"target_id" db:"target_id"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	CreatedBy *string   `json:"created_by,omitempty" db:"created_by"`
+}
+
+// Target is who a flag is evaluated for
+type Target struct {
+	UserID string
+	OrgID  string
+}
+
+// FlagUpdate changes a flag, creating it if needed; nil fields are left as
+// they are (or take their defaults on creation)
+type FlagUpdate struct {
+	Description       *string `json:"description"`
+	Enabled           *bool   `json:"enabled"`
+	RolloutPercentage *int    `json:"rollout_percentage"`
+}
+
+// Service stores flags in the database, caches them in Redis and evaluates
+// them against users and organizations
+type Service struct {
+	db       *database.DB
+	redis    *redis.Client
+	cacheTTL time.Duration
+	logger   *zap.Logger
+}
+
+func NewService(db *database.DB, redisClient *redis.Client, logger *zap.Logger) *Service {
+	return &Service{
+		db:       db,
+		redis:    redisClient,
+		cacheTTL: time.Minute,
+		logger:   logger,
+	}
+}
+
+// Evaluate reports whether a flag is on for the target. Unknown flags and
+// lookup failures evaluate to false.
+func (s *Service) Evaluate(ctx context.Context, key string, target Target) bool {
+	all, err := s.snapshot(ctx)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to load feature flags", zap.Error(err))
+		return false
+	}
+	flag, ok := all[key]
+	if !ok {
+		return false
+	}
+	return flag.evaluate(target)
+}
+
+// EnabledFlags lists the keys of every flag that is on for the user, for the
+// JWT features claim
+func (s *Service) EnabledFlags(ctx context.Context, userID, orgID string) ([]string, error) {
+	all, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	target := Target{UserID: userID, OrgID: orgID}
+	var enabled []string
+	for key, flag := range all {
+		if flag.evaluate(target) {
+			enabled = append(enabled, key)
+		}
+	}
+	sort.Strings(enabled)
+	return enabled, nil
+}
+
+// evaluate applies user overrides, then organization overrides, then the
+// default and rollout percentage
+func (f *Flag) evaluate(target Target) bool {
+	orgOverride := -1
+	for _, o := range f.Overrides {
+		switch {
+		case o.Scope == ScopeUser && target.UserID != "" && o.TargetID == target.UserID:
+			return o.Enabled
+		case o.Scope == ScopeOrganization && target.OrgID != "" && o.TargetID == target.OrgID:
+			orgOverride = boolToInt(o.Enabled)
+		}
+	}
+	if orgOverride >= 0 {
+		return orgOverride == 1
+	}
+
+	if f.Enabled {
+		return true
+	}
+	return f.RolloutPercentage > 0 && target.UserID != "" && bucket(f.Key, target.UserID) < f.RolloutPercentage
+}
+
+// bucket places a user in 0-99 for a flag; stable across requests and
+// independent between flags
+func bucket(key, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key + ":" + userID))
+	return int(h.Sum32() % 100)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// snapshot returns all flags keyed by name, from Redis when cached
+func (s *Service) snapshot(ctx context.Context) (map[string]*Flag, error) {
+	if raw, err := s.redis.Get(ctx, cacheKey).Bytes(); err == nil {
+		var cached map[string]*Flag
+		if err := json.Unmarshal(raw, &cached); err == nil {
+			return cached, nil
+		}
+	} else if err != redis.Nil {
+		logging.FromContext(ctx, s.logger).Warn("Feature flag cache unavailable", zap.Error(err))
+	}
+
+	list, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]*Flag, len(list))
+	for i := range list {
+		all[list[i].Key] = &list[i]
+	}
+
+	if raw, err := json.Marshal(all); err == nil {
+		if err := s.redis.Set(ctx, cacheKey, raw, s.cacheTTL).Err(); err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to cache feature flags", zap.Error(err))
+		}
+	}
+	return all, nil
+}
+
+// invalidate drops the cached flags so every instance reloads them
+func (s *Service) invalidate(ctx context.Context) {
+	if err := s.redis.Del(ctx, cacheKey).Err(); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to invalidate feature flag cache", zap.Error(err))
+	}
+}
+
+// List returns every flag with its overrides, read from the database
+func (s *Service) List(ctx context.Context) ([]Flag, error) {
+	var list []Flag
+	err := s.db.SelectContext(ctx, &list, `
+		SELECT key, description, enabled, rollout_percentage, created_at, updated_at, updated_by
+		FROM feature_flags
+		ORDER BY key
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	var overrides []Override
+	err = s.db.SelectContext(ctx, &overrides, `
+		SELECT flag_key, scope, target_id, enabled, created_at, created_by
+		FROM feature_flag_overrides
+		ORDER BY flag_key, scope, created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flag overrides: %w", err)
+	}
+
+	index := make(map[string]int, len(list))
+	for i := range list {
+		list[i].Overrides = []Override{}
+		index[list[i].Key] = i
+	}
+	for _, o := range overrides {
+		if i, ok := index[o.FlagKey]; ok {
+			list[i].Overrides = append(list[i].Overrides, o)
+		}
+	}
+	return list, nil
+}
+
+// Get returns one flag with its overrides
+func (s *Service) Get(ctx context.Context, key string) (*Flag, error) {
+	list, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Key == key {
+			return &list[i], nil
+		}
+	}
+	return nil, ErrFlagNotFound
+}
+
+// Set creates or updates a flag
+func (s *Service) Set(ctx context.Context, key string, update FlagUpdate, actorID string) (*Flag, error) {
+	if !keyPattern.MatchString(key) {
+		return nil, ErrInvalidKey
+	}
+	if update.RolloutPercentage != nil && (*update.RolloutPercentage < 0 || *update.RolloutPercentage > 100) {
+		return nil, ErrInvalidRollout
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO feature_flags (key, description, enabled, rollout_percentage, updated_by)
+		VALUES ($1, COALESCE($2, ''), COALESCE($3, false), COALESCE($4, 0), $5)
+		ON CONFLICT (key) DO UPDATE SET
+			description = COALESCE($2, feature_flags.description),
+			enabled = COALESCE($3, feature_flags.enabled),
+			rollout_percentage = COALESCE($4, feature_flags.rollout_percentage),
+			updated_by = $5,
+			updated_at = NOW()
+	`, key, update.Description, update.Enabled, update.RolloutPercentage, nullable(actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save feature flag: %w", err)
+	}
+
+	s.invalidate(ctx)
+	return s.Get(ctx, key)
+}
+
+// Delete removes a flag and its overrides
+func (s *Service) Delete(ctx context.Context, key string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM feature_flags WHERE key = $1", key)
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrFlagNotFound
+	}
+
+	s.invalidate(ctx)
+	return nil
+}
+
+// SetOverride forces a flag on or off for an organization or user
+func (s *Service) SetOverride(ctx context.Context, key, scope, targetID string, enabled bool, actorID string) error {
+	if scope != ScopeOrganization && scope != ScopeUser {
+		return ErrInvalidScope
+	}
+
+	var exists bool
+	if err := s.db.GetContext(ctx, &exists, "SELECT EXISTS(SELECT 1 FROM feature_flags WHERE key = $1)", key); err != nil {
+		return fmt.Errorf("failed to look up feature flag: %w", err)
+	}
+	if !exists {
+		return ErrFlagNotFound
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO feature_flag_overrides (flag_key, scope, target_id, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (flag_key, scope, target_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			created_by = EXCLUDED.created_by,
+			created_at = NOW()
+	`, key, scope, targetID, enabled, nullable(actorID))
+	if err != nil {
+		return fmt.Errorf("failed to save feature flag override: %w", err)
+	}
+
+	s.invalidate(ctx)
+	return nil
+}
+
+// DeleteOverride removes an override so the target falls back to the next level
+func (s *Service) DeleteOverride(ctx context.Context, key, scope, targetID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM feature_flag_overrides
+		WHERE flag_key = $1 AND scope = $2 AND target_id = $3
+	`, key, scope, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag override: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrOverrideNotFound
+	}
+
+	s.invalidate(ctx)
+	return nil
+}
+
+func nullable(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
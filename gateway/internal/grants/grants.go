@@ -0,0 +1,533 @@
+// Package grants lets managed security providers scan on behalf of their
+// clients. The provider's organization drafts a grant (targets, time window,
+// allowed scan types) and the client receives a signed link to review it;
+// the client approves or rejects it there, without an account. An approved
+// grant is signed over its scope and stands in for an authorized target when
+// scans are created, until it expires or either side revokes it.
+package grants
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/notify"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Grant states
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusRevoked  = "revoked"
+)
+
+// Client decisions
+const (
+	Approve = "approve"
+	Reject  = "reject"
+)
+
+// RevokedByClient records a revocation through the client's link
+const RevokedByClient = "client"
+
+// ReviewPath is the public endpoint behind the client's link
+const ReviewPath = "/api/v1/scan-grants/review"
+
+var (
+	ErrNotFound      = errors.New("scan grant not found")
+	ErrInvalidToken  = errors.New("invalid scan grant link")
+	ErrInvalidDraft  = errors.New("invalid scan grant")
+	ErrNotPending    = errors.New("scan grant was already decided")
+	ErrNotApproved   = errors.New("scan grant is not approved")
+	ErrRevoked       = errors.New("scan grant was revoked")
+	ErrNotYetValid   = errors.New("scan grant is not valid yet")
+	ErrExpired       = errors.New("scan grant has expired")
+	ErrOutOfScope    = errors.New("target is not covered by the scan grant")
+	ErrScanType      = errors.New("scan type is not allowed by the scan grant")
+	ErrBadSignature  = errors.New("scan grant does not match its signature")
+	ErrAlreadyEnded  = errors.New("scan grant was already rejected or revoked")
+	ErrInvalidChoice = errors.New("decision must be approve or reject")
+)
+
+// IsDenial reports whether err is a grant refusing to authorize a scan,
+// rather than a failure to check it
+func IsDenial(err error) bool {
+	switch err {
+	case ErrNotFound, ErrNotApproved, ErrRevoked, ErrNotYetValid, ErrExpired, ErrOutOfScope, ErrScanType, ErrBadSignature:
+		return true
+	}
+	return false
+}
+
+// Config configures grant links
+type Config struct {
+	PublicURL  string // Prefixed to review links
+	LinkSecret []byte // Signs review links and approved grants
+	// MaxValidity bounds how long a grant may authorize scans
+	MaxValidity time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{MaxValidity: 365 * 24 * time.Hour}
+}
+
+// Target is one asset a grant covers
+type Target struct {
+	Type  string `json:"target_type" binding:"required"` // ip, domain, cidr, url
+	Value string `json:"target_value" binding:"required"`
+}
+
+// Targets are a grant's assets, stored as JSONB
+type Targets []Target
+
+// Value stores targets as JSONB
+func (t Targets) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+// Scan loads targets from JSONB
+func (t *Targets) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Targets{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	default:
+		return fmt.Errorf("unsupported targets type %T", src)
+	}
+}
+
+// Covers returns the grant target that covers value: the same host, or a
+// CIDR range containing the address or range
+func (t Targets) Covers(value string) (Target, bool) {
+	value = strings.TrimSpace(value)
+	for _, target := range t {
+		if strings.EqualFold(target.Value, value) {
+			return target, true
+		}
+	}
+	for _, target := range t {
+		_, network, err := net.ParseCIDR(target.Value)
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(value); ip != nil && network.Contains(ip) {
+			return Target{Type: "ip", Value: value}, true
+		}
+		if _, inner, err := net.ParseCIDR(value); err == nil {
+			innerOnes, _ := inner.Mask.Size()
+			outerOnes, _ := network.Mask.Size()
+			if network.Contains(inner.IP) && innerOnes >= outerOnes {
+				return Target{Type: "cidr", Value: inner.String()}, true
+			}
+		}
+	}
+	return Target{}, false
+}
+
+// Grant is a client's delegated authorization to scan their assets
+type Grant struct {
+	ID             string         `json:"id" db:"id"`
+	OrganizationID string         `json:"organization_id" db:"organization_id"`
+	ClientName     string         `json:"client_name" db:"client_name"`
+	ClientEmail    string         `json:"client_email" db:"client_email"`
+	Targets        Targets        `json:"targets" db:"targets"`
+	ScanTypes      pq.StringArray `json:"scan_types" db:"scan_types"`
+	ValidFrom      time.Time      `json:"valid_from" db:"valid_from"`
+	ValidUntil     time.Time      `json:"valid_until" db:"valid_until"`
+	Notes          *string        `json:"notes,omitempty" db:"notes"`
+	Status         string         `json:"status" db:"status"`
+	RequestedBy    *string        `json:"requested_by,omitempty" db:"requested_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	DecidedByName  *string        `json:"decided_by_name,omitempty" db:"decided_by_name"`
+	DecidedByTitle *string        `json:"decided_by_title,omitempty" db:"decided_by_title"`
+	DecidedAt      *time.Time     `json:"decided_at,omitempty" db:"decided_at"`
+	DecidedFromIP  *string        `json:"decided_from_ip,omitempty" db:"decided_from_ip"`
+	Signature      *string        `json:"signature,omitempty" db:"signature"`
+	RevokedAt      *time.Time     `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy      *string        `json:"revoked_by,omitempty" db:"revoked_by"`
+	RevokeReason   *string        `json:"revoke_reason,omitempty" db:"revoke_reason"`
+}
+
+// Columns selects every Grant field from scan_grants
+const Columns = `id, organization_id, client_name, client_email, targets, scan_types,
+	valid_from, valid_until, notes, status, requested_by, created_at,
+	decided_by_name, decided_by_title, decided_at, decided_from_ip, signature,
+	revoked_at, revoked_by, revoke_reason`
+
+// Draft is a grant as the provider proposes it
+type Draft struct {
+	ClientName  string
+	ClientEmail string
+	Targets     Targets
+	ScanTypes   []string
+	ValidFrom   time.Time
+	ValidUntil  time.Time
+	Notes       string
+}
+
+// Decision is the client's answer to a grant
+type Decision struct {
+	Decision  string
+	Name      string // Who decided, as they signed
+	Title     string
+	IPAddress string
+}
+
+type Service struct {
+	db     *database.DB
+	config Config
+	mailer *notify.Mailer
+	logger *zap.Logger
+}
+
+func NewService(db *database.DB, config Config, mailer *notify.Mailer, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		config: config,
+		mailer: mailer,
+		logger: logger,
+	}
+}
+
+// Create stores a pending grant and emails the client its review link
+func (s *Service) Create(ctx context.Context, orgID, userID string, d Draft) (*Grant, error) {
+	if err := s.validate(d); err != nil {
+		return nil, err
+	}
+
+	var g Grant
+	err := s.db.GetContext(ctx, &g, `
+		INSERT INTO scan_grants (
+			organization_id, client_name, client_email, targets, scan_types,
+			valid_from, valid_until, notes, requested_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, '')::uuid)
+		RETURNING `+Columns,
+		orgID, d.ClientName, d.ClientEmail, d.Targets, pq.StringArray(d.ScanTypes),
+		d.ValidFrom.UTC(), d.ValidUntil.UTC(), d.Notes, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scan grant: %w", err)
+	}
+
+	s.sendReviewLink(ctx, &g)
+	return &g, nil
+}
+
+func (s *Service) validate(d Draft) error {
+	switch {
+	case strings.TrimSpace(d.ClientName) == "" || strings.TrimSpace(d.ClientEmail) == "":
+		return fmt.Errorf("%w: client_name and client_email are required", ErrInvalidDraft)
+	case len(d.Targets) == 0:
+		return fmt.Errorf("%w: at least one target is required", ErrInvalidDraft)
+	case len(d.ScanTypes) == 0:
+		return fmt.Errorf("%w: at least one scan type is required", ErrInvalidDraft)
+	case !d.ValidUntil.After(d.ValidFrom):
+		return fmt.Errorf("%w: valid_until must be after valid_from", ErrInvalidDraft)
+	case !d.ValidUntil.After(time.Now()):
+		return fmt.Errorf("%w: valid_until must be in the future", ErrInvalidDraft)
+	case s.config.MaxValidity > 0 && d.ValidUntil.Sub(d.ValidFrom) > s.config.MaxValidity:
+		return fmt.Errorf("%w: a grant may be valid for at most %s", ErrInvalidDraft, s.config.MaxValidity)
+	}
+	for _, target := range d.Targets {
+		if target.Type == "cidr" {
+			if _, _, err := net.ParseCIDR(target.Value); err != nil {
+				return fmt.Errorf("%w: %q is not a CIDR range", ErrInvalidDraft, target.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// sendReviewLink emails the client; SMTP must not hold up the request
+func (s *Service) sendReviewLink(ctx context.Context, g *Grant) {
+	if !s.mailer.Enabled() {
+		return
+	}
+	var orgName string
+	if err := s.db.GetContext(ctx, &orgName, `SELECT name FROM organizations WHERE id = $1`, g.OrganizationID); err != nil {
+		s.logger.Warn("Failed to load organization name for scan grant", zap.String("grant_id", g.ID), zap.Error(err))
+		orgName = "Your security provider"
+	}
+
+	subject := fmt.Sprintf("%s asks for your authorization to scan %d target(s)", orgName, len(g.Targets))
+	body := reviewBody(orgName, g, s.ReviewURL(g.ID))
+	go func() {
+		if err := s.mailer.Send([]string{g.ClientEmail}, subject, body); err != nil {
+			s.logger.Error("Failed to email scan grant link", zap.String("grant_id", g.ID), zap.Error(err))
+		}
+	}()
+}
+
+// ReviewURL is the client's link to review, approve, reject or revoke a grant
+func (s *Service) ReviewURL(grantID string) string {
+	return strings.TrimRight(s.config.PublicURL, "/") + ReviewPath + "?token=" +
+		url.QueryEscape(grantID+"."+s.mac("scan-grant-link:"+grantID))
+}
+
+// VerifyToken returns the grant a review link is for
+func (s *Service) VerifyToken(token string) (string, error) {
+	grantID, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.mac("scan-grant-link:"+grantID))) {
+		return "", ErrInvalidToken
+	}
+	return grantID, nil
+}
+
+func (s *Service) mac(message string) string {
+	mac := hmac.New(sha256.New, s.config.LinkSecret)
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign is the signature over what the client approved
+func (s *Service) sign(g *Grant) string {
+	scope, _ := json.Marshal(struct {
+		ID             string   `json:"id"`
+		OrganizationID string   `json:"organization_id"`
+		Targets        Targets  `json:"targets"`
+		ScanTypes      []string `json:"scan_types"`
+		ValidFrom      int64    `json:"valid_from"`
+		ValidUntil     int64    `json:"valid_until"`
+		DecidedByName  string   `json:"decided_by_name"`
+		DecidedAt      int64    `json:"decided_at"`
+	}{
+		ID:             g.ID,
+		OrganizationID: g.OrganizationID,
+		Targets:        g.Targets,
+		ScanTypes:      g.ScanTypes,
+		ValidFrom:      g.ValidFrom.Unix(),
+		ValidUntil:     g.ValidUntil.Unix(),
+		DecidedByName:  deref(g.DecidedByName),
+		DecidedAt:      derefTime(g.DecidedAt).Unix(),
+	})
+	return s.mac("scan-grant:" + string(scope))
+}
+
+// Get returns one of the organization's grants
+func (s *Service) Get(ctx context.Context, orgID, grantID string) (*Grant, error) {
+	var g Grant
+	err := s.db.GetContext(ctx, &g, `
+		SELECT `+Columns+` FROM scan_grants WHERE id = $1 AND organization_id = $2
+	`, grantID, orgID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &g, err
+}
+
+// GetByToken returns the grant a review link is for
+func (s *Service) GetByToken(ctx context.Context, token string) (*Grant, error) {
+	grantID, err := s.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+	var g Grant
+	err = s.db.GetContext(ctx, &g, `SELECT `+Columns+` FROM scan_grants WHERE id = $1`, grantID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &g, err
+}
+
+// List returns the organization's grants, newest first, optionally of one status
+func (s *Service) List(ctx context.Context, orgID, status string) ([]Grant, error) {
+	grants := []Grant{}
+	err := s.db.SelectContext(ctx, &grants, `
+		SELECT `+Columns+` FROM scan_grants
+		WHERE organization_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+	`, orgID, status)
+	return grants, err
+}
+
+// Decide records the client's approval or rejection of a pending grant.
+// Approval signs the grant's scope.
+func (s *Service) Decide(ctx context.Context, token string, d Decision) (*Grant, error) {
+	if d.Decision != Approve && d.Decision != Reject {
+		return nil, ErrInvalidChoice
+	}
+	grantID, err := s.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var g Grant
+	err = tx.GetContext(ctx, &g, `SELECT `+Columns+` FROM scan_grants WHERE id = $1 FOR UPDATE`, grantID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if g.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	if d.Decision == Approve && !g.ValidUntil.After(time.Now()) {
+		return nil, ErrExpired
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	g.DecidedByName, g.DecidedAt = &d.Name, &now
+	status, signature := StatusRejected, (*string)(nil)
+	if d.Decision == Approve {
+		status = StatusApproved
+		sig := s.sign(&g)
+		signature = &sig
+	}
+
+	err = tx.GetContext(ctx, &g, `
+		UPDATE scan_grants
+		SET status = $2, decided_by_name = $3, decided_by_title = NULLIF($4, ''),
+		    decided_at = $5, decided_from_ip = NULLIF($6, ''), signature = $7
+		WHERE id = $1
+		RETURNING `+Columns,
+		grantID, status, d.Name, d.Title, now, d.IPAddress, signature,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &g, tx.Commit()
+}
+
+// Revoke ends a pending or approved grant. revokedBy is the member's user
+// ID, or RevokedByClient with an empty orgID for the client's link. Scans
+// already created under the grant are not stopped.
+func (s *Service) Revoke(ctx context.Context, orgID, grantID, revokedBy, reason string) (*Grant, error) {
+	var g Grant
+	err := s.db.GetContext(ctx, &g, `
+		UPDATE scan_grants
+		SET status = 'revoked', revoked_at = NOW(), revoked_by = $3, revoke_reason = NULLIF($4, '')
+		WHERE id = $1 AND ($2 = '' OR organization_id::text = $2)
+		AND status IN ('pending', 'approved')
+		RETURNING `+Columns,
+		grantID, orgID, revokedBy, reason,
+	)
+	if err == sql.ErrNoRows {
+		if _, getErr := s.lookup(ctx, orgID, grantID); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrAlreadyEnded
+	}
+	return &g, err
+}
+
+// RevokeByToken is the client revoking through their review link
+func (s *Service) RevokeByToken(ctx context.Context, token, reason string) (*Grant, error) {
+	grantID, err := s.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.Revoke(ctx, "", grantID, RevokedByClient, reason)
+}
+
+func (s *Service) lookup(ctx context.Context, orgID, grantID string) (*Grant, error) {
+	var g Grant
+	err := s.db.GetContext(ctx, &g, `
+		SELECT `+Columns+` FROM scan_grants WHERE id = $1 AND ($2 = '' OR organization_id::text = $2)
+	`, grantID, orgID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &g, err
+}
+
+// Authorize checks that the organization's grant allows a scan of scanType
+// against value now, returning the grant and the covered target. Pending,
+// rejected, revoked, expired or tampered grants authorize nothing.
+func (s *Service) Authorize(ctx context.Context, orgID, grantID, value, scanType string) (*Grant, Target, error) {
+	g, err := s.Get(ctx, orgID, grantID)
+	if err != nil {
+		return nil, Target{}, err
+	}
+	if err := s.Check(g, time.Now()); err != nil {
+		return g, Target{}, err
+	}
+	allowed := false
+	for _, t := range g.ScanTypes {
+		if t == scanType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return g, Target{}, ErrScanType
+	}
+	target, ok := g.Targets.Covers(value)
+	if !ok {
+		return g, Target{}, ErrOutOfScope
+	}
+	return g, target, nil
+}
+
+// Check says why g cannot authorize scans at now, or nil when it can
+func (s *Service) Check(g *Grant, now time.Time) error {
+	switch {
+	case g.Status == StatusRevoked:
+		return ErrRevoked
+	case g.Status != StatusApproved:
+		return ErrNotApproved
+	case g.Signature == nil || !hmac.Equal([]byte(*g.Signature), []byte(s.sign(g))):
+		return ErrBadSignature
+	case now.Before(g.ValidFrom):
+		return ErrNotYetValid
+	case !now.Before(g.ValidUntil):
+		return ErrExpired
+	}
+	return nil
+}
+
+func reviewBody(orgName string, g *Grant, reviewURL string) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s asks for your authorization to run security scans against the assets below on your behalf.\r\n\r\n", orgName)
+	body.WriteString("Targets:\r\n")
+	for _, t := range g.Targets {
+		fmt.Fprintf(&body, "  - %s (%s)\r\n", t.Value, t.Type)
+	}
+	fmt.Fprintf(&body, "Scan types: %s\r\n", strings.Join(g.ScanTypes, ", "))
+	fmt.Fprintf(&body, "Valid:      %s to %s\r\n", g.ValidFrom.UTC().Format("2006-01-02 15:04 MST"), g.ValidUntil.UTC().Format("2006-01-02 15:04 MST"))
+	if g.Notes != nil {
+		fmt.Fprintf(&body, "Notes:      %s\r\n", *g.Notes)
+	}
+	body.WriteString("\r\nReview, approve or reject the request here. The same link revokes it later:\r\n")
+	fmt.Fprintf(&body, "%s\r\n", reviewURL)
+	body.WriteString("\r\nIf you do not recognize this request, do not approve it.\r\n")
+	return body.String()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
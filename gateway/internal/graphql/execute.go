@@ -0,0 +1,716 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Request is a GraphQL request as POSTed by clients
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response carries the data selected and any errors. Data is absent when the
+// request failed before execution (syntax, validation or limits).
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a request error, or a field error with the path of the field
+// that resolved to null because of it
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Limits bounds the cost of a query before it runs
+type Limits struct {
+	MaxDepth      int // Nesting of fields below the root, 0 for no limit
+	MaxComplexity int // Sum of field costs (see Field.Cost), 0 for no limit
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxDepth:      8,
+		MaxComplexity: 5000,
+	}
+}
+
+// Execute runs a query against the schema. Only queries are supported;
+// introspection is limited to __typename (Schema.SDL describes the schema).
+func Execute(ctx context.Context, schema *Schema, req Request, limits Limits) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return failed(err)
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return failed(err)
+	}
+	if op.Type != "query" {
+		return failed(fmt.Errorf("%s operations are not supported", op.Type))
+	}
+
+	e := &executor{ctx: ctx, schema: schema, doc: doc, limits: limits}
+	if e.variables, err = e.coerceVariables(op, req.Variables); err != nil {
+		return failed(err)
+	}
+	complexity, err := e.validate(schema.Query, op.Selections, 1, map[string]bool{})
+	if err != nil {
+		return failed(err)
+	}
+	if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+		return failed(fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity))
+	}
+
+	var data interface{} = &OrderedMap{}
+	e.execObject(schema.Query, op.Selections, []target{{
+		out:  data.(*OrderedMap),
+		null: func() { data = nil },
+	}})
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func failed(err error) *Response {
+	return &Response{Errors: []*Error{{Message: err.Error()}}}
+}
+
+func selectOperation(doc *Document, name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document has several operations")
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+type executor struct {
+	ctx       context.Context
+	schema    *Schema
+	doc       *Document
+	limits    Limits
+	variables map[string]interface{}
+	errors    []*Error
+}
+
+func (e *executor) addError(err error, path []interface{}) {
+	e.errors = append(e.errors, &Error{Message: err.Error(), Path: path})
+}
+
+// coerceVariables checks the request's variables against the operation's
+// definitions, applying defaults
+func (e *executor) coerceVariables(op *Operation, given map[string]interface{}) (map[string]interface{}, error) {
+	types := map[string]Type{}
+	collectTypes(e.schema.Query, types)
+	for _, t := range []Type{String, ID, Int, Float, Boolean} {
+		types[t.String()] = t
+	}
+
+	variables := map[string]interface{}{}
+	for _, def := range op.Variables {
+		t, err := parseTypeRef(def.Type, types)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %w", def.Name, err)
+		}
+		value, ok := given[def.Name]
+		if !ok {
+			if def.Default == nil {
+				if _, required := t.(*NonNull); required {
+					return nil, fmt.Errorf("variable $%s of type %s is required", def.Name, def.Type)
+				}
+				continue
+			}
+			value = def.Default
+		}
+		coerced, err := coerceInput(t, value)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %w", def.Name, err)
+		}
+		variables[def.Name] = coerced
+	}
+	return variables, nil
+}
+
+func parseTypeRef(ref string, types map[string]Type) (Type, error) {
+	if strings.HasSuffix(ref, "!") {
+		inner, err := parseTypeRef(strings.TrimSuffix(ref, "!"), types)
+		if err != nil {
+			return nil, err
+		}
+		return &NonNull{Of: inner}, nil
+	}
+	if strings.HasPrefix(ref, "[") && strings.HasSuffix(ref, "]") {
+		inner, err := parseTypeRef(ref[1:len(ref)-1], types)
+		if err != nil {
+			return nil, err
+		}
+		return &List{Of: inner}, nil
+	}
+	t, ok := types[ref]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s", ref)
+	}
+	if _, ok := t.(*Object); ok {
+		return nil, fmt.Errorf("%s is not an input type", ref)
+	}
+	return t, nil
+}
+
+// coerceInput converts an argument or variable value to the type's Go form
+func coerceInput(t Type, v interface{}) (interface{}, error) {
+	if n, ok := t.(*NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("expected %s, got null", t)
+		}
+		return coerceInput(n.Of, v)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	switch t := t.(type) {
+	case *Scalar:
+		if enum, ok := v.(EnumValue); ok {
+			return nil, fmt.Errorf("expected %s, got %s", t.Name, enum.Name)
+		}
+		return t.ParseValue(v)
+	case *Enum:
+		var name string
+		switch value := v.(type) {
+		case EnumValue:
+			name = value.Name
+		case string:
+			name = value
+		}
+		if !t.has(name) {
+			return nil, fmt.Errorf("expected one of %s for %s, got %v", strings.Join(t.Values, ", "), t.Name, v)
+		}
+		return name, nil
+	case *List:
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			coerced, err := coerceInput(t.Of, item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = coerced
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// substitute replaces variable references in a literal; present is false for
+// a lone variable that was not provided
+func (e *executor) substitute(v interface{}) (value interface{}, present bool, err error) {
+	switch v := v.(type) {
+	case Variable:
+		value, present = e.variables[v.Name]
+		return value, present, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			if list[i], _, err = e.substitute(item); err != nil {
+				return nil, false, err
+			}
+		}
+		return list, true, nil
+	case map[string]interface{}:
+		return nil, false, fmt.Errorf("input objects are not supported")
+	}
+	return v, true, nil
+}
+
+func (e *executor) coerceArgs(defs map[string]*Argument, given map[string]interface{}) (map[string]interface{}, error) {
+	for name := range given {
+		if _, ok := defs[name]; !ok {
+			return nil, fmt.Errorf("unknown argument %q", name)
+		}
+	}
+	args := make(map[string]interface{}, len(defs))
+	for name, def := range defs {
+		literal, ok := given[name]
+		var value interface{}
+		if ok {
+			var err error
+			if value, ok, err = e.substitute(literal); err != nil {
+				return nil, fmt.Errorf("argument %q: %w", name, err)
+			}
+		}
+		if !ok {
+			if def.Default == nil {
+				if _, required := def.Type.(*NonNull); required {
+					return nil, fmt.Errorf("argument %q of type %s is required", name, def.Type)
+				}
+				continue
+			}
+			value = def.Default
+		}
+		coerced, err := coerceInput(def.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", name, err)
+		}
+		args[name] = coerced
+	}
+	return args, nil
+}
+
+// validate checks the selections against typ and returns their complexity
+func (e *executor) validate(typ *Object, selections []Selection, depth int, fragments map[string]bool) (int, error) {
+	cost := 0
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *FieldSelection:
+			if _, err := e.included(sel.Directives); err != nil {
+				return 0, err
+			}
+			if sel.Name == "__typename" {
+				if len(sel.Selections) > 0 {
+					return 0, fmt.Errorf("field \"__typename\" cannot have a selection")
+				}
+				continue
+			}
+			def, ok := typ.Fields[sel.Name]
+			if !ok {
+				return 0, fmt.Errorf("cannot query field %q on type %s", sel.Name, typ.Name)
+			}
+			args, err := e.coerceArgs(def.Args, sel.Arguments)
+			if err != nil {
+				return 0, fmt.Errorf("field %q: %w", sel.Name, err)
+			}
+			fieldCost := def.Cost
+			if fieldCost == 0 {
+				fieldCost = 1
+			}
+
+			obj, isObject := namedType(def.Type).(*Object)
+			if !isObject {
+				if len(sel.Selections) > 0 {
+					return 0, fmt.Errorf("field %q of type %s cannot have a selection", sel.Name, def.Type)
+				}
+				cost += fieldCost
+				continue
+			}
+			if len(sel.Selections) == 0 {
+				return 0, fmt.Errorf("field %q of type %s must have a selection of subfields", sel.Name, def.Type)
+			}
+			if e.limits.MaxDepth > 0 && depth+1 > e.limits.MaxDepth {
+				return 0, fmt.Errorf("query is nested deeper than %d levels", e.limits.MaxDepth)
+			}
+			childCost, err := e.validate(obj, sel.Selections, depth+1, fragments)
+			if err != nil {
+				return 0, err
+			}
+			if limit, ok := args["limit"].(int); ok && limit > 1 && isList(def.Type) {
+				childCost *= limit
+			}
+			cost += fieldCost + childCost
+
+		case *FragmentSpread:
+			if _, err := e.included(sel.Directives); err != nil {
+				return 0, err
+			}
+			frag, ok := e.doc.Fragments[sel.Name]
+			if !ok {
+				return 0, fmt.Errorf("unknown fragment %q", sel.Name)
+			}
+			if fragments[sel.Name] {
+				return 0, fmt.Errorf("fragment %q spreads itself", sel.Name)
+			}
+			if frag.TypeCondition != typ.Name {
+				return 0, fmt.Errorf("fragment %q on %s cannot be spread on type %s", sel.Name, frag.TypeCondition, typ.Name)
+			}
+			fragments[sel.Name] = true
+			childCost, err := e.validate(typ, frag.Selections, depth, fragments)
+			delete(fragments, sel.Name)
+			if err != nil {
+				return 0, err
+			}
+			cost += childCost
+
+		case *InlineFragment:
+			if _, err := e.included(sel.Directives); err != nil {
+				return 0, err
+			}
+			if sel.TypeCondition != "" && sel.TypeCondition != typ.Name {
+				return 0, fmt.Errorf("inline fragment on %s cannot be spread on type %s", sel.TypeCondition, typ.Name)
+			}
+			childCost, err := e.validate(typ, sel.Selections, depth, fragments)
+			if err != nil {
+				return 0, err
+			}
+			cost += childCost
+		}
+	}
+	return cost, nil
+}
+
+// included evaluates @skip and @include
+func (e *executor) included(directives []*Directive) (bool, error) {
+	include := true
+	for _, d := range directives {
+		if d.Name != "skip" && d.Name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", d.Name)
+		}
+		args, err := e.coerceArgs(map[string]*Argument{"if": {Type: &NonNull{Of: Boolean}}}, d.Arguments)
+		if err != nil {
+			return false, fmt.Errorf("directive @%s: %w", d.Name, err)
+		}
+		if args["if"].(bool) == (d.Name == "skip") {
+			include = false
+		}
+	}
+	return include, nil
+}
+
+// fieldGroup is the fields selected under one response key, merged
+type fieldGroup struct {
+	key    string
+	fields []*FieldSelection
+}
+
+func (e *executor) collectFields(typ *Object, selections []Selection, groups []*fieldGroup, byKey map[string]*fieldGroup) []*fieldGroup {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *FieldSelection:
+			if ok, _ := e.included(sel.Directives); !ok {
+				continue
+			}
+			key := sel.ResponseKey()
+			group, ok := byKey[key]
+			if !ok {
+				group = &fieldGroup{key: key}
+				byKey[key] = group
+				groups = append(groups, group)
+			}
+			group.fields = append(group.fields, sel)
+		case *FragmentSpread:
+			if ok, _ := e.included(sel.Directives); !ok {
+				continue
+			}
+			groups = e.collectFields(typ, e.doc.Fragments[sel.Name].Selections, groups, byKey)
+		case *InlineFragment:
+			if ok, _ := e.included(sel.Directives); !ok {
+				continue
+			}
+			groups = e.collectFields(typ, sel.Selections, groups, byKey)
+		}
+	}
+	return groups
+}
+
+// target is an object being filled in
+type target struct {
+	source interface{}
+	out    *OrderedMap
+	path   []interface{}
+	null   func() // Nulls the object where it is held
+}
+
+// slot is a resolved value waiting to be completed into the response. A
+// value that cannot be completed is replaced by null; null nulls the nearest
+// nullable position that holds it, which is the enclosing object or list
+// (parentNull) when the slot's type is non-null.
+type slot struct {
+	value      interface{}
+	path       []interface{}
+	set        func(v interface{})
+	null       func()
+	parentNull func()
+}
+
+// execObject resolves the selections for every target together: each field
+// is resolved for all of them before any thunk is called, and their children
+// are completed together, so loaders batch across the whole list
+func (e *executor) execObject(typ *Object, selections []Selection, targets []target) {
+	for _, group := range e.collectFields(typ, selections, nil, map[string]*fieldGroup{}) {
+		key := group.key
+		field := group.fields[0]
+		if field.Name == "__typename" {
+			for _, t := range targets {
+				t.out.Set(key, typ.Name)
+			}
+			continue
+		}
+
+		def := typ.Fields[field.Name]
+		args, err := e.coerceArgs(def.Args, field.Arguments)
+		values := make([]interface{}, len(targets))
+		errs := make([]error, len(targets))
+		for i, t := range targets {
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			values[i], errs[i] = e.resolve(def, field.Name, t.source, args)
+		}
+		for i := range targets {
+			if thunk, ok := values[i].(Thunk); ok && errs[i] == nil {
+				values[i], errs[i] = e.force(thunk)
+			}
+		}
+
+		var subselections []Selection
+		for _, f := range group.fields {
+			subselections = append(subselections, f.Selections...)
+		}
+		slots := make([]slot, 0, len(targets))
+		for i, t := range targets {
+			out := t.out
+			path := extendPath(t.path, key)
+			out.Set(key, nil)
+			if errs[i] != nil {
+				e.addError(errs[i], path)
+				if _, required := def.Type.(*NonNull); required {
+					t.null()
+				}
+				continue
+			}
+			slots = append(slots, slot{
+				value:      values[i],
+				path:       path,
+				set:        func(v interface{}) { out.Set(key, v) },
+				null:       func() { out.Set(key, nil) },
+				parentNull: t.null,
+			})
+		}
+		e.complete(def.Type, subselections, slots)
+	}
+}
+
+func (e *executor) resolve(def *Field, name string, source interface{}, args map[string]interface{}) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("internal error resolving %s", name)
+		}
+	}()
+	if def.Resolve == nil {
+		return defaultResolve(source, name)
+	}
+	return def.Resolve(ResolveParams{Context: e.ctx, Source: source, Args: args})
+}
+
+func (e *executor) force(thunk Thunk) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("internal error loading value")
+		}
+	}()
+	return thunk()
+}
+
+// complete converts resolved values to the field type's response form
+func (e *executor) complete(t Type, selections []Selection, slots []slot) {
+	if len(slots) == 0 {
+		return
+	}
+	switch t := t.(type) {
+	case *NonNull:
+		present := slots[:0:0]
+		for _, s := range slots {
+			if isNil(s.value) {
+				e.addError(fmt.Errorf("cannot return null for non-null field"), s.path)
+				s.parentNull()
+				continue
+			}
+			s.null = s.parentNull
+			present = append(present, s)
+		}
+		e.complete(t.Of, selections, present)
+
+	case *Scalar:
+		for _, s := range slots {
+			if isNil(s.value) {
+				s.set(nil)
+				continue
+			}
+			v, err := t.Serialize(deref(s.value))
+			if err != nil {
+				e.addError(err, s.path)
+				s.null()
+				continue
+			}
+			s.set(v)
+		}
+
+	case *Enum:
+		for _, s := range slots {
+			if isNil(s.value) {
+				s.set(nil)
+				continue
+			}
+			v, err := serializeString(deref(s.value))
+			if err != nil || !t.has(v.(string)) {
+				e.addError(fmt.Errorf("invalid %s value %v", t.Name, s.value), s.path)
+				s.null()
+				continue
+			}
+			s.set(v)
+		}
+
+	case *List:
+		var items []slot
+		for _, s := range slots {
+			if isNil(s.value) {
+				s.set(nil)
+				continue
+			}
+			rv := reflect.ValueOf(deref(s.value))
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				e.addError(fmt.Errorf("expected a list, got %T", s.value), s.path)
+				s.null()
+				continue
+			}
+			list := make([]interface{}, rv.Len())
+			s.set(list)
+			for i := 0; i < rv.Len(); i++ {
+				i := i
+				items = append(items, slot{
+					value:      rv.Index(i).Interface(),
+					path:       extendPath(s.path, i),
+					set:        func(v interface{}) { list[i] = v },
+					null:       func() { list[i] = nil },
+					parentNull: s.null,
+				})
+			}
+		}
+		e.complete(t.Of, selections, items)
+
+	case *Object:
+		var targets []target
+		for _, s := range slots {
+			if isNil(s.value) {
+				s.set(nil)
+				continue
+			}
+			out := &OrderedMap{}
+			s.set(out)
+			targets = append(targets, target{source: s.value, out: out, path: s.path, null: s.null})
+		}
+		if len(targets) > 0 {
+			e.execObject(t, selections, targets)
+		}
+	}
+}
+
+// defaultResolve reads a map key or a struct field tagged `graphql:"name"`
+func defaultResolve(source interface{}, name string) (interface{}, error) {
+	if m, ok := source.(map[string]interface{}); ok {
+		return m[name], nil
+	}
+	rv := reflect.ValueOf(source)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot read field %s from %T", name, source)
+	}
+	if v, ok := structField(rv, name); ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%T has no field %s", source, name)
+}
+
+// structField finds the tagged field, looking into embedded structs
+func structField(rv reflect.Value, name string) (interface{}, bool) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tag := strings.Split(f.Tag.Get("graphql"), ",")[0]; tag == name {
+			return rv.Field(i).Interface(), true
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if v, ok := structField(rv.Field(i), name); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func deref(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return rv.Interface()
+}
+
+func extendPath(path []interface{}, elem interface{}) []interface{} {
+	extended := make([]interface{}, len(path), len(path)+1)
+	copy(extended, path)
+	return append(extended, elem)
+}
+
+// OrderedMap is a response object, keeping fields in selection order
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// Set adds or replaces a field
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if m.values == nil {
+		m.values = map[string]interface{}{}
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns a field's value
+func (m *OrderedMap) Get(key string) interface{} {
+	return m.values[key]
+}
+
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,217 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newTestSchema has organizations with parents and scans:
+//
+//	org(id: ID!): Org
+//	orgs(limit: Int = 10): [Org!]!
+func newTestSchema() *Schema {
+	orgs := map[string]map[string]interface{}{
+		"1": {"id": "1", "name": "Acme"},
+		"2": {"id": "2", "name": "Acme EU", "parent_id": "1"},
+	}
+	org := &Object{Name: "Org"}
+	scan := &Object{Name: "Scan", Fields: Fields{
+		"id":       {Type: &NonNull{Of: ID}},
+		"severity": {Type: String},
+	}}
+	org.Fields = Fields{
+		"id":   {Type: &NonNull{Of: ID}},
+		"name": {Type: String},
+		"parent": {Type: org, Resolve: func(p ResolveParams) (interface{}, error) {
+			if parent, ok := p.Source.(map[string]interface{})["parent_id"].(string); ok {
+				return orgs[parent], nil
+			}
+			return nil, nil
+		}},
+		"scans": {
+			Type: &List{Of: scan},
+			Args: map[string]*Argument{"limit": {Type: Int, Default: 10}},
+			Cost: 2,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return []interface{}{map[string]interface{}{"id": "s1", "severity": "high"}}, nil
+			},
+		},
+	}
+	return &Schema{Query: &Object{Name: "Query", Fields: Fields{
+		"org": {
+			Type: org,
+			Args: map[string]*Argument{"id": {Type: &NonNull{Of: ID}}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return orgs[p.Args["id"].(string)], nil
+			},
+		},
+		"orgs": {
+			Type: &NonNull{Of: &List{Of: &NonNull{Of: org}}},
+			Args: map[string]*Argument{"limit": {Type: Int, Default: 10}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return []interface{}{orgs["1"], orgs["2"]}, nil
+			},
+		},
+	}}}
+}
+
+func execute(t *testing.T, query string, variables map[string]interface{}, limits Limits) (string, string) {
+	t.Helper()
+	resp := Execute(context.Background(), newTestSchema(), Request{Query: query, Variables: variables}, limits)
+	var errs []string
+	for _, err := range resp.Errors {
+		errs = append(errs, err.Message)
+	}
+	if resp.Data == nil {
+		return "", strings.Join(errs, "; ")
+	}
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("marshaling data: %v", err)
+	}
+	return string(data), strings.Join(errs, "; ")
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{
+			name:  "aliases",
+			query: `{ a: org(id: "1") { name } b: org(id: "2") { label: name id } }`,
+			want:  `{"a":{"name":"Acme"},"b":{"label":"Acme EU","id":"2"}}`,
+		},
+		{
+			name:  "fragments",
+			query: `{ org(id: "2") { ...Names parent { ...Names } } } fragment Names on Org { id name }`,
+			want:  `{"org":{"id":"2","name":"Acme EU","parent":{"id":"1","name":"Acme"}}}`,
+		},
+		{
+			name:      "inline fragments and directives",
+			query:     `query Q($full: Boolean!) { org(id: "1") { id ... on Org @include(if: $full) { name } __typename } }`,
+			variables: map[string]interface{}{"full": true},
+			want:      `{"org":{"id":"1","name":"Acme","__typename":"Org"}}`,
+		},
+		{
+			name:  "repeated fields merge",
+			query: `{ org(id: "2") { parent { id } parent { name } } }`,
+			want:  `{"org":{"parent":{"id":"1","name":"Acme"}}}`,
+		},
+		{
+			name:  "null for a missing object",
+			query: `{ org(id: "9") { id } }`,
+			want:  `{"org":null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, errs := execute(t, tt.query, tt.variables, DefaultLimits())
+			if errs != "" {
+				t.Fatalf("errors: %s", errs)
+			}
+			if data != tt.want {
+				t.Errorf("data = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{"mutations", `mutation { org(id: "1") { id } }`, "mutation operations are not supported"},
+		{"several operations without a name", `query A { orgs { id } } query B { orgs { id } }`, "operationName is required"},
+		{"unknown field", `{ org(id: "1") { owner } }`, `cannot query field "owner" on type Org`},
+		{"unknown argument", `{ org(id: "1", tenant: "x") { id } }`, `unknown argument "tenant"`},
+		{"missing argument", `{ org { id } }`, `argument "id" of type ID! is required`},
+		{"leaf with a selection", `{ org(id: "1") { id { x } } }`, "cannot have a selection"},
+		{"object without a selection", `{ org(id: "1") }`, "must have a selection of subfields"},
+		{"unknown fragment", `{ org(id: "1") { ...Missing } }`, `unknown fragment "Missing"`},
+		{"fragment on another type", `{ org(id: "1") { ...F } } fragment F on Scan { id }`, "cannot be spread on type Org"},
+		{"fragment cycle", `{ org(id: "1") { ...A } } fragment A on Org { parent { ...B } } fragment B on Org { ...A }`, `fragment "A" spreads itself`},
+		{"unknown directive", `{ org(id: "1") @cache { id } }`, "unknown directive @cache"},
+		{"missing variable", `query Q($id: ID!) { org(id: $id) { id } }`, "variable $id of type ID! is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, errs := execute(t, tt.query, nil, DefaultLimits())
+			if data != "" || !strings.Contains(errs, tt.err) {
+				t.Errorf("data %s, errors %q; want no data and an error containing %q", data, errs, tt.err)
+			}
+		})
+	}
+}
+
+func TestExecuteDepthLimit(t *testing.T) {
+	// The root selection is level 1, so org { parent { parent { id } } } is 4
+	limits := Limits{MaxDepth: 4}
+	tests := []struct {
+		name  string
+		query string
+		ok    bool
+	}{
+		{"at the limit", `{ org(id: "2") { parent { parent { id } } } }`, true},
+		{"over the limit", `{ org(id: "2") { parent { parent { parent { id } } } } }`, false},
+		{"aliases do not hide depth", `{ a: org(id: "2") { b: parent { c: parent { d: parent { id } } } } }`, false},
+		{"fragments count where they are spread", `{ org(id: "2") { parent { ...P } } } fragment P on Org { parent { parent { id } } }`, false},
+		{"fragments within the limit", `{ org(id: "2") { ...P } } fragment P on Org { parent { parent { id } } }`, true},
+		{"inline fragments count too", `{ org(id: "2") { parent { ... on Org { parent { parent { id } } } } } }`, false},
+		{"leaves do not count", `{ org(id: "2") { parent { parent { id name __typename } } } }`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := execute(t, tt.query, nil, limits)
+			if tt.ok && errs != "" {
+				t.Errorf("errors: %s", errs)
+			}
+			if !tt.ok && !strings.Contains(errs, "nested deeper than 4 levels") {
+				t.Errorf("errors %q, want the depth limit", errs)
+			}
+		})
+	}
+
+	// No limit at all
+	query := `{ org(id: "2") { parent { parent { parent { parent { id } } } } } }`
+	if _, errs := execute(t, query, nil, Limits{}); errs != "" {
+		t.Errorf("without limits: %s", errs)
+	}
+}
+
+func TestExecuteComplexityLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		complexity int
+	}{
+		// orgs 1 + 10 (default limit) * (id 1 + name 1)
+		{"list default limit", `{ orgs { id name } }`, 21},
+		{"list limit argument", `{ orgs(limit: 2) { id } }`, 3},
+		{"list limit variable", `query Q($n: Int) { orgs(limit: $n) { id } }`, 4},
+		// Field cost 2 for scans, times its own limit
+		{"nested lists multiply", `{ orgs(limit: 2) { scans(limit: 3) { id severity } } }`, 1 + 2*(2+3*2)},
+		// Aliased copies each count
+		{"aliases add up", `{ a: org(id: "1") { id } b: org(id: "1") { id } }`, 4},
+		{"fragments add up", `{ org(id: "1") { ...F ...F } } fragment F on Org { id name }`, 5},
+		{"__typename is free", `{ org(id: "1") { id __typename } }`, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variables := map[string]interface{}{"n": float64(3)}
+			if _, errs := execute(t, tt.query, variables, Limits{MaxComplexity: tt.complexity}); errs != "" {
+				t.Errorf("at its complexity of %d: %s", tt.complexity, errs)
+			}
+			_, errs := execute(t, tt.query, variables, Limits{MaxComplexity: tt.complexity - 1})
+			if !strings.Contains(errs, "exceeds the limit") {
+				t.Errorf("over the limit: errors %q, want the complexity limit", errs)
+			}
+		})
+	}
+}
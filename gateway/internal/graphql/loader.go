@@ -0,0 +1,93 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// Thunk is a deferred field value. Execute resolves a field for every object
+// in a list before calling any of their thunks, so the Loads behind them are
+// fetched in one batch.
+type Thunk func() (interface{}, error)
+
+// BatchFunc loads the values for keys. Keys missing from the result resolve
+// to null.
+type BatchFunc func(ctx context.Context, keys []string) (map[string]interface{}, error)
+
+// Loader batches and caches loads by key for one request (a dataloader):
+// keys requested through Load are fetched together the first time any of
+// their thunks is called.
+type Loader struct {
+	batch BatchFunc
+
+	mu      sync.Mutex
+	pending []string
+	queued  map[string]bool
+	values  map[string]interface{}
+	errs    map[string]error
+}
+
+func NewLoader(batch BatchFunc) *Loader {
+	return &Loader{
+		batch:  batch,
+		queued: map[string]bool{},
+		values: map[string]interface{}{},
+		errs:   map[string]error{},
+	}
+}
+
+// Load queues key for the next batch
+func (l *Loader) Load(ctx context.Context, key string) Thunk {
+	l.mu.Lock()
+	if _, done := l.values[key]; !done && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.queued[key] {
+			l.dispatch(ctx)
+		}
+		return l.values[key], l.errs[key]
+	}
+}
+
+// dispatch fetches every queued key; l.mu is held
+func (l *Loader) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+	values, err := l.batch(ctx, keys)
+	for _, key := range keys {
+		delete(l.queued, key)
+		l.values[key] = values[key]
+		if err != nil {
+			l.errs[key] = err
+		}
+	}
+}
+
+// Loaders memoizes one Loader per name for a request, e.g. one per
+// combination of arguments that changes what a batch loads
+type Loaders struct {
+	mu      sync.Mutex
+	loaders map[string]*Loader
+}
+
+func NewLoaders() *Loaders {
+	return &Loaders{loaders: map[string]*Loader{}}
+}
+
+// Get returns the named loader, creating it with batch on first use
+func (l *Loaders) Get(name string, batch BatchFunc) *Loader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	loader, ok := l.loaders[name]
+	if !ok {
+		loader = NewLoader(batch)
+		l.loaders[name] = loader
+	}
+	return loader
+}
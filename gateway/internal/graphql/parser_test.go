@@ -0,0 +1,150 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	query := `
+		# Organizations and their scans
+		query Overview($org: ID!, $severities: [String!] = ["critical", "high"], $limit: Int = 5) {
+			first: org(id: $org) { id ...OrgFields }
+			second: org(id: "b\"2é") @skip(if: true) {
+				... on Org { name }
+				... @include(if: false) { id }
+			}
+			scans(limit: $limit, order: DESC, min: -1.5e3, tags: [], filter: {open: true, owner: null})
+		}
+
+		fragment OrgFields on Org { name, parent { id } }
+
+		{ __typename }
+	`
+	doc, err := Parse(query)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := &Document{
+		Operations: []*Operation{
+			{
+				Type: "query",
+				Name: "Overview",
+				Variables: []*VariableDefinition{
+					{Name: "org", Type: "ID!"},
+					{Name: "severities", Type: "[String!]", Default: []interface{}{"critical", "high"}},
+					{Name: "limit", Type: "Int", Default: 5},
+				},
+				Selections: []Selection{
+					&FieldSelection{
+						Alias:     "first",
+						Name:      "org",
+						Arguments: map[string]interface{}{"id": Variable{Name: "org"}},
+						Selections: []Selection{
+							&FieldSelection{Name: "id"},
+							&FragmentSpread{Name: "OrgFields"},
+						},
+					},
+					&FieldSelection{
+						Alias:      "second",
+						Name:       "org",
+						Arguments:  map[string]interface{}{"id": "b\"2é"},
+						Directives: []*Directive{{Name: "skip", Arguments: map[string]interface{}{"if": true}}},
+						Selections: []Selection{
+							&InlineFragment{TypeCondition: "Org", Selections: []Selection{&FieldSelection{Name: "name"}}},
+							&InlineFragment{
+								Directives: []*Directive{{Name: "include", Arguments: map[string]interface{}{"if": false}}},
+								Selections: []Selection{&FieldSelection{Name: "id"}},
+							},
+						},
+					},
+					&FieldSelection{
+						Name: "scans",
+						Arguments: map[string]interface{}{
+							"limit":  Variable{Name: "limit"},
+							"order":  EnumValue{Name: "DESC"},
+							"min":    -1.5e3,
+							"tags":   []interface{}{},
+							"filter": map[string]interface{}{"open": true, "owner": nil},
+						},
+					},
+				},
+			},
+			{
+				Type:       "query",
+				Selections: []Selection{&FieldSelection{Name: "__typename"}},
+			},
+		},
+		Fragments: map[string]*Fragment{
+			"OrgFields": {
+				Name:          "OrgFields",
+				TypeCondition: "Org",
+				Selections: []Selection{
+					&FieldSelection{Name: "name"},
+					&FieldSelection{Name: "parent", Selections: []Selection{&FieldSelection{Name: "id"}}},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(doc.Operations, want.Operations) {
+		for i := range doc.Operations {
+			t.Errorf("operation %d = %+v", i, doc.Operations[i])
+		}
+	}
+	if !reflect.DeepEqual(doc.Fragments, want.Fragments) {
+		t.Errorf("fragments = %+v, want %+v", doc.Fragments, want.Fragments)
+	}
+}
+
+func TestFieldResponseKey(t *testing.T) {
+	doc, err := Parse(`{ org { id } a: org { id } }`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var keys []string
+	for _, sel := range doc.Operations[0].Selections {
+		keys = append(keys, sel.(*FieldSelection).ResponseKey())
+	}
+	if !reflect.DeepEqual(keys, []string{"org", "a"}) {
+		t.Errorf("response keys = %v, want [org a]", keys)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{"empty document", `  # nothing`, "no operations"},
+		{"empty selection", `{ org { } }`, "empty selection set"},
+		{"unclosed selection", `{ org { id }`, "unexpected end of query"},
+		{"missing selection", `query Q`, "unexpected end of query"},
+		{"unterminated string", `{ org(id: "abc) { id } }`, "unterminated string"},
+		{"string across lines", "{ org(id: \"a\nb\") { id } }", "unterminated string"},
+		{"block string", `{ org(id: """a""") { id } }`, "block strings are not supported"},
+		{"invalid escape", `{ org(id: "\q") { id } }`, "invalid escape"},
+		{"invalid unicode escape", `{ org(id: "\u12") { id } }`, "invalid unicode escape"},
+		{"invalid number", `{ scans(limit: 1.) }`, "invalid number"},
+		{"integer out of range", `{ scans(limit: 2147483648) }`, "out of range"},
+		{"unexpected character", `{ org; }`, "unexpected character"},
+		{"reported line", "{\n  org\n  ? }", "line 3"},
+		{"duplicate argument", `{ org(id: 1, id: 2) { id } }`, "given more than once"},
+		{"duplicate fragment", `{ a } fragment F on Org { id } fragment F on Org { id }`, "defined more than once"},
+		{"fragment named on", `{ a } fragment on on Org { id }`, "cannot be named"},
+		{"fragment without type condition", `{ a } fragment F { id }`, "unexpected"},
+		{"variable in a default", `query Q($a: Int = $b) { a }`, "unexpected \"$\""},
+		{"unknown keyword", `schema { query: Query }`, "unexpected \"schema\""},
+		{"too long", "{ " + strings.Repeat("a ", maxQueryLength) + "}", "longer than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse(%q) = %v, want an error containing %q", tt.query, err, tt.err)
+			}
+		})
+	}
+}
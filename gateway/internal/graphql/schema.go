@@ -0,0 +1,341 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Type is a GraphQL type: *Scalar, *Enum, *Object, *List or *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf type. Serialize converts resolved Go values for the
+// response; ParseValue coerces argument and variable values.
+type Scalar struct {
+	Name        string
+	Description string
+	Serialize   func(v interface{}) (interface{}, error)
+	ParseValue  func(v interface{}) (interface{}, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Enum is a leaf type whose values are strings from a fixed set
+type Enum struct {
+	Name        string
+	Description string
+	Values      []string
+}
+
+func (e *Enum) String() string { return e.Name }
+
+func (e *Enum) has(value string) bool {
+	for _, v := range e.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Object is a type with fields. Fields may be set after creation so that
+// objects can refer to each other.
+type Object struct {
+	Name        string
+	Description string
+	Fields      Fields
+}
+
+func (o *Object) String() string { return o.Name }
+
+type Fields map[string]*Field
+
+// Field is a field of an object. Without Resolve, the field is read from the
+// source value: a map key, or a struct field tagged `graphql:"name"`.
+type Field struct {
+	Type        Type
+	Description string
+	Args        map[string]*Argument
+	Resolve     ResolveFunc
+	// Cost is the field's weight in the complexity limit, 1 if unset. List
+	// fields with a "limit" argument multiply their selections' cost by it.
+	Cost int
+}
+
+type Argument struct {
+	Type        Type
+	Default     interface{}
+	Description string
+}
+
+// ResolveFunc returns the field's value or a Thunk to batch loading
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+type ResolveParams struct {
+	Context context.Context
+	Source  interface{}
+	Args    map[string]interface{}
+}
+
+// List wraps a type as a list
+type List struct {
+	Of Type
+}
+
+func (l *List) String() string { return "[" + l.Of.String() + "]" }
+
+// NonNull wraps a type as non-nullable
+type NonNull struct {
+	Of Type
+}
+
+func (n *NonNull) String() string { return n.Of.String() + "!" }
+
+// Schema is the root of the types a query can select
+type Schema struct {
+	Query *Object
+}
+
+// Built-in scalars
+var (
+	String = &Scalar{
+		Name:       "String",
+		Serialize:  serializeString,
+		ParseValue: parseString,
+	}
+	ID = &Scalar{
+		Name:       "ID",
+		Serialize:  serializeString,
+		ParseValue: parseString,
+	}
+	Int = &Scalar{
+		Name: "Int",
+		Serialize: func(v interface{}) (interface{}, error) {
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return rv.Int(), nil
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return int64(rv.Uint()), nil
+			}
+			return nil, fmt.Errorf("cannot serialize %T as Int", v)
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			switch n := v.(type) {
+			case int:
+				return n, nil
+			case float64: // JSON variables
+				if n == math.Trunc(n) && math.Abs(n) <= math.MaxInt32 {
+					return int(n), nil
+				}
+			}
+			return nil, fmt.Errorf("expected Int, got %v", v)
+		},
+	}
+	Float = &Scalar{
+		Name: "Float",
+		Serialize: func(v interface{}) (interface{}, error) {
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Float32, reflect.Float64:
+				return rv.Float(), nil
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return float64(rv.Int()), nil
+			}
+			return nil, fmt.Errorf("cannot serialize %T as Float", v)
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			switch n := v.(type) {
+			case int:
+				return float64(n), nil
+			case float64:
+				return n, nil
+			}
+			return nil, fmt.Errorf("expected Float, got %v", v)
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		Serialize: func(v interface{}) (interface{}, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("cannot serialize %T as Boolean", v)
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("expected Boolean, got %v", v)
+		},
+	}
+	// Time is an RFC 3339 timestamp
+	Time = &Scalar{
+		Name:        "Time",
+		Description: "RFC 3339 timestamp",
+		Serialize: func(v interface{}) (interface{}, error) {
+			if t, ok := v.(time.Time); ok {
+				return t.UTC().Format(time.RFC3339), nil
+			}
+			return nil, fmt.Errorf("cannot serialize %T as Time", v)
+		},
+		ParseValue: func(v interface{}) (interface{}, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected Time, got %v", v)
+			}
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return nil, fmt.Errorf("expected RFC 3339 Time, got %q", s)
+			}
+			return t, nil
+		},
+	}
+)
+
+func serializeString(v interface{}) (interface{}, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case fmt.Stringer:
+		return s.String(), nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+		return rv.String(), nil
+	}
+	return nil, fmt.Errorf("cannot serialize %T as String", v)
+}
+
+func parseString(v interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("expected String, got %v", v)
+}
+
+// SDL prints the schema in the GraphQL schema definition language
+func (s *Schema) SDL() string {
+	types := map[string]Type{}
+	collectTypes(s.Query, types)
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		switch t := types[name].(type) {
+		case *Scalar:
+			switch t {
+			case String, ID, Int, Float, Boolean:
+				continue
+			}
+			writeDescription(&b, t.Description, "")
+			fmt.Fprintf(&b, "scalar %s\n\n", t.Name)
+		case *Enum:
+			writeDescription(&b, t.Description, "")
+			fmt.Fprintf(&b, "enum %s {\n", t.Name)
+			for _, v := range t.Values {
+				fmt.Fprintf(&b, "  %s\n", v)
+			}
+			b.WriteString("}\n\n")
+		case *Object:
+			writeDescription(&b, t.Description, "")
+			fmt.Fprintf(&b, "type %s {\n", t.Name)
+			for _, fieldName := range sortedKeys(t.Fields) {
+				field := t.Fields[fieldName]
+				writeDescription(&b, field.Description, "  ")
+				fmt.Fprintf(&b, "  %s%s: %s\n", fieldName, argsSDL(field.Args), field.Type)
+			}
+			b.WriteString("}\n\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func argsSDL(args map[string]*Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		arg := args[name]
+		part := name + ": " + arg.Type.String()
+		if arg.Default != nil {
+			part += fmt.Sprintf(" = %#v", arg.Default)
+		}
+		parts = append(parts, part)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func writeDescription(b *strings.Builder, description, indent string) {
+	if description != "" {
+		fmt.Fprintf(b, "%s\"%s\"\n", indent, strings.ReplaceAll(description, `"`, `\"`))
+	}
+}
+
+func sortedKeys(fields Fields) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func collectTypes(t Type, types map[string]Type) {
+	switch t := t.(type) {
+	case *List:
+		collectTypes(t.Of, types)
+	case *NonNull:
+		collectTypes(t.Of, types)
+	case *Object:
+		if _, seen := types[t.Name]; seen {
+			return
+		}
+		types[t.Name] = t
+		for _, field := range t.Fields {
+			collectTypes(field.Type, types)
+			for _, arg := range field.Args {
+				collectTypes(arg.Type, types)
+			}
+		}
+	default:
+		types[t.String()] = t
+	}
+}
+
+// namedType strips List and NonNull wrappers
+func namedType(t Type) Type {
+	for {
+		switch w := t.(type) {
+		case *List:
+			t = w.Of
+		case *NonNull:
+			t = w.Of
+		default:
+			return t
+		}
+	}
+}
+
+func isList(t Type) bool {
+	if n, ok := t.(*NonNull); ok {
+		t = n.Of
+	}
+	_, ok := t.(*List)
+	return ok
+}
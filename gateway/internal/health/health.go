@@ -0,0 +1,135 @@
+// Package health checks the gateway's dependencies. The same checks back the
+// /ready probe, which fails while a critical dependency is down, and the
+// component list of the public status page.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Component statuses
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded" // Up, but slower than the check's threshold
+	StatusDown        = "down"
+)
+
+// Check probes one dependency
+type Check struct {
+	Name string
+	// Critical checks fail readiness while down; the others only show on the
+	// status page
+	Critical bool
+	// Slow marks the dependency degraded when it answers later than this;
+	// zero never does
+	Slow time.Duration
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	LatencyMS int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the outcome of every check
+type Report struct {
+	Ready     bool      `json:"ready"` // No critical check is down
+	Checks    []Result  `json:"checks"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Checker runs the registered checks concurrently, each bounded by a timeout
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check. Checks run in the order added.
+func (c *Checker) Add(check Check) {
+	c.checks = append(c.checks, check)
+}
+
+// Check runs every check
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Ready:     true,
+		Checks:    make([]Result, len(c.checks)),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Critical && result.Status == StatusDown {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	latency := time.Since(start)
+
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusOperational,
+		Critical:  check.Critical,
+		LatencyMS: latency.Milliseconds(),
+		CheckedAt: time.Now().UTC(),
+	}
+	switch {
+	case err != nil:
+		result.Status = StatusDown
+		result.Error = err.Error()
+	case check.Slow > 0 && latency > check.Slow:
+		result.Status = StatusDegraded
+	}
+
+	up := 0.0
+	if result.Status != StatusDown {
+		up = 1
+	}
+	metrics.DependencyUp.WithLabelValues(check.Name).Set(up)
+	return result
+}
+
+// Handler serves the readiness probe: 200 when ready, 503 otherwise, with the
+// report either way
+func (c *Checker) Handler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := c.Check(ctx.Request.Context())
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.JSON(status, report)
+	}
+}
@@ -0,0 +1,193 @@
+package i18n
+
+// builtin holds the catalogs shipped with the gateway. English only needs the
+// validation templates; other messages are already English.
+var builtin = map[string]Catalog{
+	"en": {
+		"validation.required":   "{field} is required",
+		"validation.min":        "{field} must be at least {param}",
+		"validation.min.string": "{field} must be at least {param} characters long",
+		"validation.min.items":  "{field} must contain at least {param} items",
+		"validation.max":        "{field} must be at most {param}",
+		"validation.max.string": "{field} must be at most {param} characters long",
+		"validation.max.items":  "{field} must contain at most {param} items",
+		"validation.len":        "{field} must be {param}",
+		"validation.len.string": "{field} must be exactly {param} characters long",
+		"validation.len.items":  "{field} must contain exactly {param} items",
+		"validation.gte":        "{field} must be at least {param}",
+		"validation.lte":        "{field} must be at most {param}",
+		"validation.oneof":      "{field} must be one of: {param}",
+		"validation.email":      "{field} must be a valid email address",
+		"validation.uuid":       "{field} must be a valid UUID",
+		"validation.url":        "{field} must be a valid URL",
+		"validation.datetime":   "{field} must be a date and time in the format {param}",
+		"validation.type":       "{field} must be of type {type}",
+		"validation.invalid":    "{field} is invalid",
+	},
+
+	"pt": {
+		"validation.required":   "{field} é obrigatório",
+		"validation.min":        "{field} deve ser no mínimo {param}",
+		"validation.min.string": "{field} deve ter pelo menos {param} caracteres",
+		"validation.min.items":  "{field} deve conter pelo menos {param} itens",
+		"validation.max":        "{field} deve ser no máximo {param}",
+		"validation.max.string": "{field} deve ter no máximo {param} caracteres",
+		"validation.max.items":  "{field} deve conter no máximo {param} itens",
+		"validation.len":        "{field} deve ser {param}",
+		"validation.len.string": "{field} deve ter exatamente {param} caracteres",
+		"validation.len.items":  "{field} deve conter exatamente {param} itens",
+		"validation.gte":        "{field} deve ser no mínimo {param}",
+		"validation.lte":        "{field} deve ser no máximo {param}",
+		"validation.oneof":      "{field} deve ser um destes valores: {param}",
+		"validation.email":      "{field} deve ser um endereço de e-mail válido",
+		"validation.uuid":       "{field} deve ser um UUID válido",
+		"validation.url":        "{field} deve ser uma URL válida",
+		"validation.datetime":   "{field} deve ser uma data e hora no formato {param}",
+		"validation.type":       "{field} deve ser do tipo {type}",
+		"validation.invalid":    "{field} é inválido",
+
+		"Invalid request":           "Requisição inválida",
+		"Malformed JSON body":       "Corpo JSON malformado",
+		"Unsupported locale":        "Idioma não suportado",
+		"Failed to load preference": "Falha ao carregar a preferência",
+		"Failed to save preference": "Falha ao salvar a preferência",
+		"Escalation not found":      "Escalonamento não encontrado",
+		"Invalid escalation ID":     "ID de escalonamento inválido",
+
+		"unauthorized":                                       "não autorizado",
+		"missing authorization header":                       "cabeçalho de autorização ausente",
+		"invalid token":                                      "token inválido",
+		"invalid credentials":                                "credenciais inválidas",
+		"session revoked or expired":                         "sessão revogada ou expirada",
+		"invalid or expired link":                            "link inválido ou expirado",
+		"failed to register user":                            "falha ao registrar o usuário",
+		"terms version is outdated, fetch the current terms": "a versão dos termos está desatualizada, obtenha os termos atuais",
+
+		"Access denied":                                     "Acesso negado",
+		"Insufficient role":                                 "Papel insuficiente",
+		"Platform admin required":                           "É necessário ser administrador da plataforma",
+		"Organization context required":                     "É necessário o contexto de uma organização",
+		"Denied by access policy":                           "Negado pela política de acesso",
+		"You do not have permission to perform this action": "Você não tem permissão para realizar esta ação",
+		"Failed to check permissions":                       "Falha ao verificar as permissões",
+		"Failed to verify access":                           "Falha ao verificar o acesso",
+		"Emergency stop is active":                          "A parada de emergência está ativa",
+		"Service is under maintenance":                      "O serviço está em manutenção",
+
+		"Not found":                         "Não encontrado",
+		"User not found":                    "Usuário não encontrado",
+		"Organization not found":            "Organização não encontrada",
+		"Scan not found":                    "Varredura não encontrada",
+		"Finding not found":                 "Vulnerabilidade não encontrada",
+		"Report not found":                  "Relatório não encontrado",
+		"Report template not found":         "Modelo de relatório não encontrado",
+		"Report schedule not found":         "Agendamento de relatório não encontrado",
+		"Report file no longer available":   "O arquivo do relatório não está mais disponível",
+		"Export not found":                  "Exportação não encontrada",
+		"File no longer available":          "O arquivo não está mais disponível",
+		"Role not found":                    "Papel não encontrado",
+		"Policy not found":                  "Política não encontrada",
+		"Authorization not found":           "Autorização não encontrada",
+		"Invalid scan ID":                   "ID de varredura inválido",
+		"Invalid finding ID":                "ID de vulnerabilidade inválido",
+		"Invalid severity":                  "Severidade inválida",
+		"Invalid role":                      "Papel inválido",
+		"Invalid download link":             "Link de download inválido",
+		"Cannot compare a scan with itself": "Não é possível comparar uma varredura com ela mesma",
+		"Scans must be of the same target":  "As varreduras devem ser do mesmo alvo",
+
+		"Failed to load scan":       "Falha ao carregar a varredura",
+		"Failed to load scans":      "Falha ao carregar as varreduras",
+		"Failed to load findings":   "Falha ao carregar as vulnerabilidades",
+		"Failed to load report":     "Falha ao carregar o relatório",
+		"Failed to generate report": "Falha ao gerar o relatório",
+		"Failed to list reports":    "Falha ao listar os relatórios",
+		"Failed to start analysis":  "Falha ao iniciar a análise",
+		"Analysis failed":           "A análise falhou",
+		"Failed to list audit logs": "Falha ao listar os registros de auditoria",
+		"Failed to export logs":     "Falha ao exportar os registros",
+	},
+
+	"ar": {
+		"validation.required":   "{field} مطلوب",
+		"validation.min":        "يجب أن يكون {field} على الأقل {param}",
+		"validation.min.string": "يجب أن يتكون {field} من {param} أحرف على الأقل",
+		"validation.min.items":  "يجب أن يحتوي {field} على {param} عناصر على الأقل",
+		"validation.max":        "يجب أن يكون {field} على الأكثر {param}",
+		"validation.max.string": "يجب ألا يتجاوز {field} {param} حرفًا",
+		"validation.max.items":  "يجب ألا يحتوي {field} على أكثر من {param} عناصر",
+		"validation.len":        "يجب أن يكون {field} {param}",
+		"validation.len.string": "يجب أن يتكون {field} من {param} أحرف بالضبط",
+		"validation.len.items":  "يجب أن يحتوي {field} على {param} عناصر بالضبط",
+		"validation.gte":        "يجب أن يكون {field} على الأقل {param}",
+		"validation.lte":        "يجب أن يكون {field} على الأكثر {param}",
+		"validation.oneof":      "يجب أن يكون {field} إحدى القيم: {param}",
+		"validation.email":      "يجب أن يكون {field} عنوان بريد إلكتروني صالحًا",
+		"validation.uuid":       "يجب أن يكون {field} معرّف UUID صالحًا",
+		"validation.url":        "يجب أن يكون {field} عنوان URL صالحًا",
+		"validation.datetime":   "يجب أن يكون {field} تاريخًا ووقتًا بالتنسيق {param}",
+		"validation.type":       "يجب أن يكون {field} من النوع {type}",
+		"validation.invalid":    "{field} غير صالح",
+
+		"Invalid request":           "طلب غير صالح",
+		"Malformed JSON body":       "نص JSON غير سليم",
+		"Unsupported locale":        "اللغة غير مدعومة",
+		"Failed to load preference": "تعذّر تحميل التفضيل",
+		"Failed to save preference": "تعذّر حفظ التفضيل",
+		"Escalation not found":      "التصعيد غير موجود",
+		"Invalid escalation ID":     "معرّف التصعيد غير صالح",
+
+		"unauthorized":                                       "غير مصرّح",
+		"missing authorization header":                       "ترويسة التفويض مفقودة",
+		"invalid token":                                      "رمز غير صالح",
+		"invalid credentials":                                "بيانات اعتماد غير صالحة",
+		"session revoked or expired":                         "الجلسة ملغاة أو منتهية الصلاحية",
+		"invalid or expired link":                            "الرابط غير صالح أو منتهي الصلاحية",
+		"failed to register user":                            "تعذّر تسجيل المستخدم",
+		"terms version is outdated, fetch the current terms": "إصدار الشروط قديم، يرجى جلب الشروط الحالية",
+
+		"Access denied":                                     "تم رفض الوصول",
+		"Insufficient role":                                 "الدور غير كافٍ",
+		"Platform admin required":                           "يتطلب ذلك مسؤول المنصة",
+		"Organization context required":                     "يلزم تحديد المؤسسة",
+		"Denied by access policy":                           "مرفوض بموجب سياسة الوصول",
+		"You do not have permission to perform this action": "ليست لديك صلاحية لتنفيذ هذا الإجراء",
+		"Failed to check permissions":                       "تعذّر التحقق من الصلاحيات",
+		"Failed to verify access":                           "تعذّر التحقق من الوصول",
+		"Emergency stop is active":                          "الإيقاف الطارئ مفعّل",
+		"Service is under maintenance":                      "الخدمة قيد الصيانة",
+
+		"Not found":                         "غير موجود",
+		"User not found":                    "المستخدم غير موجود",
+		"Organization not found":            "المؤسسة غير موجودة",
+		"Scan not found":                    "الفحص غير موجود",
+		"Finding not found":                 "الثغرة غير موجودة",
+		"Report not found":                  "التقرير غير موجود",
+		"Report template not found":         "قالب التقرير غير موجود",
+		"Report schedule not found":         "جدول التقرير غير موجود",
+		"Report file no longer available":   "ملف التقرير لم يعد متاحًا",
+		"Export not found":                  "التصدير غير موجود",
+		"File no longer available":          "الملف لم يعد متاحًا",
+		"Role not found":                    "الدور غير موجود",
+		"Policy not found":                  "السياسة غير موجودة",
+		"Authorization not found":           "التفويض غير موجود",
+		"Invalid scan ID":                   "معرّف الفحص غير صالح",
+		"Invalid finding ID":                "معرّف الثغرة غير صالح",
+		"Invalid severity":                  "درجة الخطورة غير صالحة",
+		"Invalid role":                      "دور غير صالح",
+		"Invalid download link":             "رابط التنزيل غير صالح",
+		"Cannot compare a scan with itself": "لا يمكن مقارنة الفحص بنفسه",
+		"Scans must be of the same target":  "يجب أن تكون الفحوصات لنفس الهدف",
+
+		"Failed to load scan":       "تعذّر تحميل الفحص",
+		"Failed to load scans":      "تعذّر تحميل الفحوصات",
+		"Failed to load findings":   "تعذّر تحميل الثغرات",
+		"Failed to load report":     "تعذّر تحميل التقرير",
+		"Failed to generate report": "تعذّر إنشاء التقرير",
+		"Failed to list reports":    "تعذّر عرض التقارير",
+		"Failed to start analysis":  "تعذّر بدء التحليل",
+		"Analysis failed":           "فشل التحليل",
+		"Failed to list audit logs": "تعذّر عرض سجلات التدقيق",
+		"Failed to export logs":     "تعذّر تصدير السجلات",
+	},
+}
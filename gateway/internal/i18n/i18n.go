@@ -0,0 +1,173 @@
+// Package i18n translates API error and validation messages into the
+// caller's language.
+//
+// Catalogs are keyed by the English message, so handlers keep writing plain
+// English errors and untranslated messages fall through unchanged.
+// Validation messages use "validation.*" keys with {field} and {param}
+// placeholders. Deployments extend or override the built-in catalogs with
+// <locale>.json files (see Registry.LoadDir).
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when the caller's language is not supported
+const DefaultLocale = "en"
+
+// Catalog maps English messages and validation keys to translations
+type Catalog map[string]string
+
+// Registry holds the catalogs of every supported locale
+type Registry struct {
+	mu       sync.RWMutex
+	catalogs map[string]Catalog
+}
+
+// NewRegistry returns a registry with the built-in English, Portuguese and
+// Arabic catalogs
+func NewRegistry() *Registry {
+	r := &Registry{catalogs: make(map[string]Catalog)}
+	for locale, catalog := range builtin {
+		r.Register(locale, catalog)
+	}
+	return r
+}
+
+// Register adds a catalog for locale, overriding existing entries with the
+// same keys. Registering a new locale makes it available for negotiation.
+func (r *Registry) Register(locale string, catalog Catalog) {
+	locale = Normalize(locale)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.catalogs[locale]
+	if !ok {
+		existing = make(Catalog, len(catalog))
+		r.catalogs[locale] = existing
+	}
+	for key, message := range catalog {
+		existing[key] = message
+	}
+}
+
+// LoadDir registers every <locale>.json file in dir, each a JSON object of
+// message to translation
+func (r *Registry) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read catalog %s: %w", path, err)
+		}
+		var catalog Catalog
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return fmt.Errorf("failed to parse catalog %s: %w", path, err)
+		}
+		r.Register(strings.TrimSuffix(filepath.Base(path), ".json"), catalog)
+	}
+	return nil
+}
+
+// Locales lists the supported locales
+func (r *Registry) Locales() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	locales := make([]string, 0, len(r.catalogs))
+	for locale := range r.catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supported reports whether locale has a catalog
+func (r *Registry) Supported(locale string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.catalogs[Normalize(locale)]
+	return ok
+}
+
+// Negotiate picks the best supported locale for an Accept-Language header,
+// matching "pt-BR" to "pt" when there is no regional catalog
+func (r *Registry) Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		locale  string
+		quality float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = v
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{Normalize(tag), quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+
+	for _, c := range candidates {
+		if r.Supported(c.locale) {
+			return c.locale
+		}
+		if base, _, ok := strings.Cut(c.locale, "-"); ok && r.Supported(base) {
+			return base
+		}
+	}
+	return DefaultLocale
+}
+
+// Translate returns message in locale, falling back to the base language
+// and then to message itself
+func (r *Registry) Translate(locale, message string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	locale = Normalize(locale)
+	if translated, ok := r.catalogs[locale][message]; ok {
+		return translated
+	}
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		if translated, ok := r.catalogs[base][message]; ok {
+			return translated
+		}
+	}
+	if translated, ok := r.catalogs[DefaultLocale][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Format translates key and fills its {name} placeholders from params
+func (r *Registry) Format(locale, key string, params map[string]string) string {
+	message := r.Translate(locale, key)
+	for name, value := range params {
+		message = strings.ReplaceAll(message, "{"+name+"}", value)
+	}
+	return message
+}
+
+// Normalize lower-cases a language tag and uses "-" as the separator
+func Normalize(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Keys on the gin context: the resolved locale and the translator itself
+const (
+	localeKey     = "locale"
+	translatorKey = "i18n.translator"
+)
+
+// UserLocaleFunc returns a user's stored locale, or "" when they have none
+type UserLocaleFunc func(ctx context.Context, userID string) string
+
+// Translator resolves each caller's locale and translates error responses
+type Translator struct {
+	registry   *Registry
+	userLocale UserLocaleFunc
+}
+
+func NewTranslator(registry *Registry, userLocale UserLocaleFunc) *Translator {
+	return &Translator{registry: registry, userLocale: userLocale}
+}
+
+// Registry returns the catalogs used for translation
+func (t *Translator) Registry() *Registry {
+	return t.registry
+}
+
+// Locale is the caller's language: their stored preference when signed in,
+// otherwise negotiated from Accept-Language. It is resolved on first use, so
+// requests that never produce a message skip the preference lookup.
+func (t *Translator) Locale(c *gin.Context) string {
+	if locale := c.GetString(localeKey); locale != "" {
+		return locale
+	}
+
+	locale := ""
+	if userID := c.GetString("user_id"); userID != "" && t.userLocale != nil {
+		if stored := t.userLocale(c.Request.Context(), userID); stored != "" && t.registry.Supported(stored) {
+			locale = Normalize(stored)
+		}
+	}
+	if locale == "" {
+		locale = t.registry.Negotiate(c.GetHeader("Accept-Language"))
+	}
+	c.Set(localeKey, locale)
+	return locale
+}
+
+// T translates message into the caller's language
+func (t *Translator) T(c *gin.Context, message string) string {
+	return t.registry.Translate(t.Locale(c), message)
+}
+
+// FromContext returns the translator installed by Middleware, or nil
+func FromContext(c *gin.Context) *Translator {
+	t, _ := c.Value(translatorKey).(*Translator)
+	return t
+}
+
+// Middleware translates the "error" message of JSON error responses.
+// Handlers keep writing English; the body is rewritten as it is written.
+func (t *Translator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(translatorKey, t)
+		c.Writer = &translatingWriter{ResponseWriter: c.Writer, translator: t, c: c}
+		c.Next()
+	}
+}
+
+// translatingWriter rewrites JSON error bodies, which gin writes in one call
+type translatingWriter struct {
+	gin.ResponseWriter
+	translator *Translator
+	c          *gin.Context
+}
+
+func (w *translatingWriter) Write(data []byte) (int, error) {
+	if translated, ok := w.translate(data); ok {
+		if _, err := w.ResponseWriter.Write(translated); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *translatingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *translatingWriter) translate(data []byte) ([]byte, bool) {
+	if w.Status() < 400 || w.Written() || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return nil, false
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, false
+	}
+	var message string
+	if err := json.Unmarshal(body["error"], &message); err != nil || message == "" {
+		return nil, false
+	}
+
+	locale := w.translator.Locale(w.c)
+	translated := w.translator.registry.Translate(locale, message)
+	if translated == message {
+		return nil, false
+	}
+
+	body["error"], _ = json.Marshal(translated)
+	out, err := json.Marshal(body)
+	if err != nil {
+		return nil, false
+	}
+	w.Header().Set("Content-Language", locale)
+	return out, true
+}
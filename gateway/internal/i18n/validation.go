@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// UseJSONFieldNames makes validation errors name fields as clients send them
+// (the json or form tag) rather than by Go struct field
+func UseJSONFieldNames() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// BindError describes why a request failed to bind, in the caller's
+// language: a summary in "error" and, for validation failures, one entry per
+// field in "fields"
+func (t *Translator) BindError(c *gin.Context, err error) gin.H {
+	locale := t.Locale(c)
+	c.Header("Content-Language", locale)
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fe.Namespace()[strings.Index(fe.Namespace(), ".")+1:],
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: t.fieldMessage(locale, fe),
+			})
+		}
+		return gin.H{"error": t.registry.Translate(locale, "Invalid request"), "fields": fields}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		message := t.registry.Format(locale, "validation.type", map[string]string{
+			"field": typeErr.Field,
+			"type":  typeErr.Type.Kind().String(),
+		})
+		return gin.H{"error": message}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || err.Error() == "unexpected EOF" {
+		return gin.H{"error": t.registry.Translate(locale, "Malformed JSON body")}
+	}
+
+	return gin.H{"error": err.Error()}
+}
+
+// fieldMessage renders a validator failure from the "validation.<rule>"
+// catalog entry. Length rules use .string or .items variants by field kind.
+func (t *Translator) fieldMessage(locale string, fe validator.FieldError) string {
+	key := "validation." + fe.Tag()
+	switch fe.Tag() {
+	case "min", "max", "len", "gte", "lte":
+		switch fe.Kind() {
+		case reflect.String:
+			key += ".string"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			key += ".items"
+		}
+	}
+
+	params := map[string]string{
+		"field": fe.Field(),
+		"param": strings.ReplaceAll(fe.Param(), " ", ", "),
+	}
+	message := t.registry.Format(locale, key, params)
+	if message == key {
+		return t.registry.Format(locale, "validation.invalid", params)
+	}
+	return message
+}
@@ -0,0 +1,86 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+func init() {
+	Register("burp", parseBurp)
+}
+
+type burpIssues struct {
+	XMLName xml.Name    `xml:"issues"`
+	Issues  []burpIssue `xml:"issue"`
+}
+
+type burpIssue struct {
+	Type                  string `xml:"type"`
+	Name                  string `xml:"name"`
+	Host                  string `xml:"host"`
+	Path                  string `xml:"path"`
+	Location              string `xml:"location"`
+	Severity              string `xml:"severity"`
+	Confidence            string `xml:"confidence"`
+	IssueBackground       string `xml:"issueBackground"`
+	IssueDetail           string `xml:"issueDetail"`
+	RemediationBackground string `xml:"remediationBackground"`
+	RemediationDetail     string `xml:"remediationDetail"`
+	Classifications       string `xml:"vulnerabilityClassifications"`
+}
+
+// parseBurp reads Burp Suite's "Report issues" XML export. Issues Burp marked
+// as false positives are skipped.
+func parseBurp(data []byte) ([]Finding, error) {
+	var report burpIssues
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&report); err != nil {
+		return nil, err
+	}
+
+	var parsed []Finding
+	for _, issue := range report.Issues {
+		if strings.EqualFold(issue.Severity, "False positive") || strings.EqualFold(issue.Confidence, "False positive") {
+			continue
+		}
+		description := plainText(strings.Join(nonEmpty(issue.IssueDetail, issue.IssueBackground), "\n\n"))
+		if description == "" {
+			description = issue.Name
+		}
+		f := Finding{
+			Host:              hostOf(issue.Host),
+			Title:             issue.Name,
+			Description:       description,
+			Severity:          severity(issue.Severity),
+			Category:          "web",
+			AffectedComponent: strings.TrimRight(strings.TrimSpace(issue.Host), "/") + strings.TrimSpace(issue.Path),
+			Remediation:       plainText(strings.Join(nonEmpty(issue.RemediationDetail, issue.RemediationBackground), "\n\n")),
+			PluginID:          "burp:" + strings.TrimSpace(issue.Type),
+			CVEID:             cvePattern.FindString(issue.IssueDetail),
+		}
+		for _, m := range cwePattern.FindAllStringSubmatch(issue.Classifications, -1) {
+			f.CWEIDs = appendUnique(f.CWEIDs, "CWE-"+m[1])
+		}
+		parsed = append(parsed, f)
+	}
+	return parsed, nil
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func appendUnique(values []string, v string) []string {
+	for _, existing := range values {
+		if existing == v {
+			return values
+		}
+	}
+	return append(values, v)
+}
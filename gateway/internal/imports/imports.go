@@ -0,0 +1,491 @@
+// Package imports brings in the results of scanners customers run
+// themselves. An adapter per file format (Nmap XML, Nuclei JSONL, Burp Suite
+// XML) parses a file into findings, which are grouped by host and stored as
+// one completed scan per host, so they reach dashboards, reports, webhooks
+// and suppression rules like the results of gateway scans. Each host's scan
+// is linked to the organization's matching asset when there is one.
+package imports
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/events"
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/intel"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// MaxFileBytes bounds an imported file
+const MaxFileBytes = 50 << 20
+
+// ScanMode marks imported scans in scan_jobs
+const ScanMode = "imported"
+
+var (
+	// ErrUnknownFormat is returned for a format without an adapter
+	ErrUnknownFormat = errors.New("unknown import format")
+	// ErrInvalidFile is returned when a file cannot be parsed in its format
+	ErrInvalidFile = errors.New("file could not be parsed")
+	// ErrNoFindings is returned when a file parses but holds no findings
+	ErrNoFindings = errors.New("file contains no findings")
+	// ErrAlreadyImported is returned when the organization imported the same
+	// file before
+	ErrAlreadyImported = errors.New("file has already been imported")
+	// ErrImportNotFound is returned for an import outside the organization
+	ErrImportNotFound = errors.New("import not found")
+)
+
+// Finding is a scanner finding in the gateway's terms, with the host it was
+// found on
+type Finding struct {
+	Host              string // Hostname or IP address
+	Title             string
+	Description       string
+	Severity          string // critical, high, medium, low or info
+	CVSSScore         float64
+	CVSSVector        string
+	Category          string
+	AffectedComponent string
+	Remediation       string
+	PluginID          string // "<scanner>:<check>", for suppression rules
+	CVEID             string
+	CWEIDs            []string
+}
+
+// Adapter parses one file format
+type Adapter func(data []byte) ([]Finding, error)
+
+var adapters = map[string]Adapter{}
+
+// Register adds an adapter for a format
+func Register(format string, adapter Adapter) {
+	adapters[format] = adapter
+}
+
+// Formats lists the formats that can be imported
+func Formats() []string {
+	formats := make([]string, 0, len(adapters))
+	for format := range adapters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Parse runs the format's adapter
+func Parse(format string, data []byte) ([]Finding, error) {
+	adapter, ok := adapters[format]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	parsed, err := adapter(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return parsed, nil
+}
+
+// Import is one imported file and the scans it produced
+type Import struct {
+	ID                 string         `json:"id" db:"id"`
+	OrganizationID     string         `json:"organization_id" db:"organization_id"`
+	Format             string         `json:"format" db:"format"`
+	Filename           string         `json:"filename" db:"filename"`
+	SHA256             string         `json:"sha256" db:"sha256"`
+	ImportedBy         string         `json:"imported_by" db:"imported_by"`
+	Hosts              int            `json:"hosts" db:"hosts"`
+	LinkedHosts        int            `json:"linked_hosts" db:"linked_hosts"` // Hosts matched to an asset
+	FindingsStored     int            `json:"findings_stored" db:"findings_stored"`
+	FindingsDuplicate  int            `json:"findings_duplicate" db:"findings_duplicate"` // Repeats within the file, dropped
+	FindingsSuppressed int            `json:"findings_suppressed" db:"findings_suppressed"`
+	ScanIDs            pq.StringArray `json:"scan_ids" db:"scan_ids"`
+	CreatedAt          time.Time      `json:"created_at" db:"created_at"`
+}
+
+const importColumns = `i.id, i.organization_id, i.format, i.filename, i.sha256, i.imported_by,
+	i.hosts, i.linked_hosts, i.findings_stored, i.findings_duplicate, i.findings_suppressed,
+	ARRAY(SELECT sj.id::text FROM scan_jobs sj WHERE sj.import_id = i.id ORDER BY sj.created_at) AS scan_ids,
+	i.created_at`
+
+// File is an uploaded scanner file
+type File struct {
+	OrganizationID string
+	UserID         string
+	Format         string
+	Filename       string
+	Data           []byte
+}
+
+// Service stores imported files as scans
+type Service struct {
+	db     *database.DB
+	cipher repository.Cipher
+	logger *zap.Logger
+}
+
+// NewService creates the service; cipher encrypts scan results' raw data
+// and may be repository.Plaintext
+func NewService(db *database.DB, cipher repository.Cipher, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		cipher: cipher,
+		logger: logger,
+	}
+}
+
+// asset is an authorized target findings can be linked to
+type asset struct {
+	ID          string `db:"id"`
+	TargetValue string `db:"target_value"`
+}
+
+// hostScan is the deduplicated findings of one host
+type hostScan struct {
+	host     string
+	findings []Finding
+}
+
+// Import parses the file and stores a completed scan for each host in it
+func (s *Service) Import(ctx context.Context, file File) (*Import, error) {
+	parsed, err := Parse(file.Format, file.Data)
+	if err != nil {
+		return nil, err
+	}
+	hosts, duplicates := groupByHost(parsed)
+	if len(hosts) == 0 {
+		return nil, ErrNoFindings
+	}
+	sum := sha256.Sum256(file.Data)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var importID string
+	err = tx.GetContext(ctx, &importID, `
+		INSERT INTO scan_imports (organization_id, format, filename, sha256, imported_by, hosts, findings_duplicate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, file.OrganizationID, file.Format, file.Filename, hex.EncodeToString(sum[:]), file.UserID, len(hosts), duplicates)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrAlreadyImported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []asset
+	err = tx.SelectContext(ctx, &assets, `
+		SELECT id, target_value FROM authorized_targets
+		WHERE organization_id = $1
+		ORDER BY verification_status = 'approved' DESC, created_at DESC
+	`, file.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, stored, suppressed := 0, 0, 0
+	for _, hs := range hosts {
+		assetID := matchAsset(assets, hs.host)
+		if assetID != nil {
+			linked++
+		}
+		n, sup, err := s.storeHost(ctx, tx, importID, file, hs, assetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store host %s: %w", hs.host, err)
+		}
+		stored += n
+		suppressed += sup
+	}
+
+	var imp Import
+	err = tx.GetContext(ctx, &imp, `
+		UPDATE scan_imports i
+		SET linked_hosts = $2, findings_stored = $3, findings_suppressed = $4
+		WHERE i.id = $1
+		RETURNING `+importColumns, importID, linked, stored, suppressed)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Scan results imported",
+		zap.String("import_id", imp.ID),
+		zap.String("organization_id", file.OrganizationID),
+		zap.String("format", file.Format),
+		zap.Int("hosts", imp.Hosts),
+		zap.Int("findings", imp.FindingsStored),
+	)
+	return &imp, nil
+}
+
+// storeHost records a completed scan of one host and its findings, as the
+// worker result RPC does, and returns the findings stored and suppressed
+func (s *Service) storeHost(ctx context.Context, tx *sqlx.Tx, importID string, file File, hs hostScan, assetID *string) (int, int, error) {
+	targetType := "domain"
+	if net.ParseIP(hs.host) != nil {
+		targetType = "ip"
+	}
+	var targetID string
+	err := tx.GetContext(ctx, &targetID, `
+		INSERT INTO scan_targets (target_type, target_value) VALUES ($1, $2) RETURNING id
+	`, targetType, hs.host)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	scanType := file.Format + "_import"
+	var scanID string
+	err = tx.GetContext(ctx, &scanID, `
+		INSERT INTO scan_jobs (
+			user_id, organization_id, target_id, authorization_target_id, scan_type, scan_mode,
+			status, requires_authorization, import_id, started_at, completed_at, progress_percentage
+		) VALUES ($1, $2, $3, $4, $5, $6, 'completed', false, $7, NOW(), NOW(), 100)
+		RETURNING id
+	`, file.UserID, file.OrganizationID, targetID, assetID, scanType, ScanMode, importID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	counts := map[string]int{}
+	for _, f := range hs.findings {
+		counts[f.Severity]++
+	}
+	severityCounts, _ := json.Marshal(counts)
+	summary, _ := json.Marshal(map[string]interface{}{
+		"import_id": importID,
+		"format":    file.Format,
+		"filename":  file.Filename,
+	})
+	orgID := file.OrganizationID
+	result := repository.ScanResult{
+		ScanJobID:      scanID,
+		OrganizationID: &orgID,
+		ResultType:     "import",
+		Summary:        summary,
+		SeverityCounts: severityCounts,
+	}
+	if err := repository.NewScanResultRepo(tx, s.cipher).Create(ctx, &result); err != nil {
+		return 0, 0, err
+	}
+
+	// Known false positives are stored but marked suppressed
+	suppressions, err := findings.ActiveSuppressionRules(ctx, tx, orgID, hs.host)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	suppressed := 0
+	for _, f := range hs.findings {
+		fingerprint := findings.Fingerprint(f.Title, f.Category, f.AffectedComponent)
+		var ruleID *string
+		if rule := findings.MatchSuppression(suppressions, findings.Candidate{
+			Asset:             hs.host,
+			PluginID:          f.PluginID,
+			CVEID:             f.CVEID,
+			AffectedComponent: f.AffectedComponent,
+		}); rule != nil {
+			ruleID = &rule.ID
+			suppressed++
+		}
+
+		var findingID string
+		err = tx.GetContext(ctx, &findingID, `
+			INSERT INTO vulnerabilities (
+				scan_result_id, scan_job_id, organization_id, title, description, severity,
+				cvss_score, cvss_vector, category, affected_component, remediation, fingerprint,
+				plugin_id, cve_id, suppression_rule_id, suppressed_at, cwe_ids
+			) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), $12,
+				NULLIF($13, ''), NULLIF(upper($14), ''), $15, CASE WHEN $15::uuid IS NULL THEN NULL ELSE NOW() END,
+				NULLIF($16::text[], '{}'))
+			RETURNING id
+		`, result.ID, scanID, orgID, f.Title, f.Description, f.Severity,
+			f.CVSSScore, f.CVSSVector, f.Category, f.AffectedComponent, f.Remediation,
+			fingerprint, f.PluginID, f.CVEID, ruleID, pq.Array(nonNil(f.CWEIDs)))
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to store finding %q: %w", f.Title, err)
+		}
+
+		err = events.Enqueue(ctx, tx, orgID, scanID, events.FindingCreated{
+			FindingID:         findingID,
+			ScanID:            scanID,
+			Title:             f.Title,
+			Severity:          f.Severity,
+			CVSSScore:         f.CVSSScore,
+			Category:          f.Category,
+			AffectedComponent: f.AffectedComponent,
+			Fingerprint:       fingerprint,
+			Suppressed:        ruleID != nil,
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if _, err := intel.EnrichScan(ctx, tx, scanID); err != nil {
+		return 0, 0, err
+	}
+
+	err = events.Enqueue(ctx, tx, orgID, scanID, events.ScanFinished{
+		ScanID:             scanID,
+		UserID:             file.UserID,
+		ScanType:           scanType,
+		Status:             "completed",
+		VulnerabilityCount: len(hs.findings),
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(hs.findings), suppressed, nil
+}
+
+// List returns the organization's imports, newest first
+func (s *Service) List(ctx context.Context, orgID string, limit int) ([]Import, error) {
+	list := []Import{}
+	err := s.db.Reader().SelectContext(ctx, &list, `
+		SELECT `+importColumns+` FROM scan_imports i
+		WHERE i.organization_id = $1
+		ORDER BY i.created_at DESC
+		LIMIT $2
+	`, orgID, limit)
+	return list, err
+}
+
+// Get returns one of the organization's imports
+func (s *Service) Get(ctx context.Context, orgID, importID string) (*Import, error) {
+	var imp Import
+	err := s.db.Reader().GetContext(ctx, &imp, `
+		SELECT `+importColumns+` FROM scan_imports i
+		WHERE i.id::text = $1 AND i.organization_id = $2
+	`, importID, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrImportNotFound
+	}
+	return &imp, err
+}
+
+// groupByHost groups findings by host, in order of first appearance,
+// dropping repeats of a finding on the same host
+func groupByHost(parsed []Finding) ([]hostScan, int) {
+	var hosts []hostScan
+	index := map[string]int{}
+	seen := map[string]bool{}
+	duplicates := 0
+	for _, f := range parsed {
+		f.Host = strings.ToLower(strings.TrimSpace(f.Host))
+		if f.Host == "" || f.Title == "" {
+			continue
+		}
+		key := f.Host + "|" + findings.Fingerprint(f.Title, f.Category, f.AffectedComponent)
+		if seen[key] {
+			duplicates++
+			continue
+		}
+		seen[key] = true
+
+		i, ok := index[f.Host]
+		if !ok {
+			i = len(hosts)
+			index[f.Host] = i
+			hosts = append(hosts, hostScan{host: f.Host})
+		}
+		hosts[i].findings = append(hosts[i].findings, f)
+	}
+	return hosts, duplicates
+}
+
+// matchAsset finds the asset for a host: one with the same host name or
+// address first, then a wildcard domain or CIDR range covering it
+func matchAsset(assets []asset, host string) *string {
+	for i := range assets {
+		if hostOf(assets[i].TargetValue) == host {
+			return &assets[i].ID
+		}
+	}
+	ip := net.ParseIP(host)
+	for i := range assets {
+		value := strings.ToLower(strings.TrimSpace(assets[i].TargetValue))
+		if strings.HasPrefix(value, "*.") && strings.HasSuffix(host, value[1:]) {
+			return &assets[i].ID
+		}
+		if _, network, err := net.ParseCIDR(value); err == nil && ip != nil && network.Contains(ip) {
+			return &assets[i].ID
+		}
+	}
+	return nil
+}
+
+// hostOf returns the lower-cased host name or address in a URL, host:port
+// or bare host
+func hostOf(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if strings.Contains(value, "://") {
+		if u, err := url.Parse(value); err == nil {
+			return u.Hostname()
+		}
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		return host
+	}
+	return strings.Trim(value, "[]")
+}
+
+var (
+	htmlTags    = regexp.MustCompile(`<[^>]*>`)
+	blankLines  = regexp.MustCompile(`\n\s*\n\s*`)
+	cvePattern  = regexp.MustCompile(`CVE-\d{4}-\d{4,}`)
+	cwePattern  = regexp.MustCompile(`CWE-(\d+)`)
+	severityMap = map[string]string{
+		"critical":      "critical",
+		"high":          "high",
+		"medium":        "medium",
+		"moderate":      "medium",
+		"low":           "low",
+		"info":          "info",
+		"information":   "info",
+		"informational": "info",
+	}
+)
+
+// severity maps a scanner's severity name, defaulting to info
+func severity(name string) string {
+	if s, ok := severityMap[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return s
+	}
+	return "info"
+}
+
+// plainText strips the markup scanners put in their descriptions
+func plainText(s string) string {
+	s = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "</p>", "\n\n", "</li>", "\n").Replace(s)
+	s = html.UnescapeString(htmlTags.ReplaceAllString(s, ""))
+	return strings.TrimSpace(blankLines.ReplaceAllString(s, "\n\n"))
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
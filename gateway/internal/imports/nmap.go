@@ -0,0 +1,173 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	Register("nmap", parseNmap)
+}
+
+type nmapRun struct {
+	XMLName xml.Name   `xml:"nmaprun"`
+	Hosts   []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	Status struct {
+		State string `xml:"state,attr"`
+	} `xml:"status"`
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports       []nmapPort   `xml:"ports>port"`
+	HostScripts []nmapScript `xml:"hostscript>script"`
+}
+
+type nmapPort struct {
+	Protocol string `xml:"protocol,attr"`
+	PortID   string `xml:"portid,attr"`
+	State    struct {
+		State string `xml:"state,attr"`
+	} `xml:"state"`
+	Service struct {
+		Name    string `xml:"name,attr"`
+		Product string `xml:"product,attr"`
+		Version string `xml:"version,attr"`
+	} `xml:"service"`
+	Scripts []nmapScript `xml:"script"`
+}
+
+type nmapScript struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+// parseNmap reads Nmap's XML output (-oX). Open ports become informational
+// findings; vulnerability scripts (--script vuln) reporting VULNERABLE become
+// findings at their risk factor.
+func parseNmap(data []byte) ([]Finding, error) {
+	var run nmapRun
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&run); err != nil {
+		return nil, err
+	}
+
+	var parsed []Finding
+	for _, h := range run.Hosts {
+		if h.Status.State != "" && h.Status.State != "up" {
+			continue
+		}
+		address := nmapAddress(h)
+		if address == "" {
+			continue
+		}
+		// The name the user scanned is the one their assets use
+		host := address
+		for _, name := range h.Hostnames {
+			if name.Type == "user" {
+				host = name.Name
+				break
+			}
+		}
+
+		for _, p := range h.Ports {
+			if p.State.State != "open" {
+				continue
+			}
+			component := fmt.Sprintf("%s:%s/%s", address, p.PortID, p.Protocol)
+			service := strings.TrimSpace(p.Service.Product + " " + p.Service.Version)
+			description := fmt.Sprintf("Port %s/%s is open", p.PortID, p.Protocol)
+			if service != "" {
+				description += " running " + service
+			}
+			parsed = append(parsed, Finding{
+				Host:              host,
+				Title:             fmt.Sprintf("Open port %s/%s (%s)", p.PortID, p.Protocol, orDefault(p.Service.Name, "unknown")),
+				Description:       description,
+				Severity:          "info",
+				Category:          "network",
+				AffectedComponent: component,
+				PluginID:          "nmap:open-port",
+			})
+			for _, script := range p.Scripts {
+				if f, ok := nmapScriptFinding(host, component, script); ok {
+					parsed = append(parsed, f)
+				}
+			}
+		}
+		for _, script := range h.HostScripts {
+			if f, ok := nmapScriptFinding(host, address, script); ok {
+				parsed = append(parsed, f)
+			}
+		}
+	}
+	return parsed, nil
+}
+
+// nmapAddress prefers the IP address over the MAC address
+func nmapAddress(h nmapHost) string {
+	for _, a := range h.Addresses {
+		if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+			return a.Addr
+		}
+	}
+	return ""
+}
+
+// nmapScriptFinding reads the report of an NSE vulnerability script:
+//
+//	VULNERABLE:
+//	The Heartbleed Bug is a serious vulnerability in OpenSSL
+//	  State: VULNERABLE
+//	  Risk factor: High
+//	  IDs:  CVE:CVE-2014-0160
+func nmapScriptFinding(host, component string, script nmapScript) (Finding, bool) {
+	output := script.Output
+	if !strings.Contains(output, "VULNERABLE") || strings.Contains(output, "NOT VULNERABLE") {
+		return Finding{}, false
+	}
+
+	title := script.ID
+	severityName := "medium"
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "VULNERABLE:" && i+1 < len(lines):
+			if next := strings.TrimSpace(lines[i+1]); next != "" {
+				title = next
+			}
+		case strings.HasPrefix(line, "Risk factor:"):
+			// Often followed by the score: "Risk factor: High  CVSSv2: 7.5"
+			if fields := strings.Fields(strings.TrimPrefix(line, "Risk factor:")); len(fields) > 0 {
+				severityName = fields[0]
+			}
+		}
+	}
+
+	return Finding{
+		Host:              host,
+		Title:             title,
+		Description:       strings.TrimSpace(output),
+		Severity:          severity(severityName),
+		Category:          "network",
+		AffectedComponent: component,
+		PluginID:          "nmap:" + script.ID,
+		CVEID:             cvePattern.FindString(output),
+	}, true
+}
+
+func orDefault(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
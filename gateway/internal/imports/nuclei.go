@@ -0,0 +1,112 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	Register("nuclei", parseNuclei)
+}
+
+// nucleiMaxLine bounds one JSONL result, which carries the request and
+// response when nuclei runs with -include-rr
+const nucleiMaxLine = 8 << 20
+
+type nucleiResult struct {
+	TemplateID string `json:"template-id"`
+	Type       string `json:"type"`
+	Host       string `json:"host"`
+	MatchedAt  string `json:"matched-at"`
+	IP         string `json:"ip"`
+	Info       struct {
+		Name           string `json:"name"`
+		Severity       string `json:"severity"`
+		Description    string `json:"description"`
+		Remediation    string `json:"remediation"`
+		Classification struct {
+			CVEIDs      stringList `json:"cve-id"`
+			CWEIDs      stringList `json:"cwe-id"`
+			CVSSScore   float64    `json:"cvss-score"`
+			CVSSMetrics string     `json:"cvss-metrics"`
+		} `json:"classification"`
+	} `json:"info"`
+	ExtractedResults []string `json:"extracted-results"`
+}
+
+// stringList decodes nuclei's string-or-array fields
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		if one != "" {
+			*l = strings.Split(one, ",")
+		}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*l = many
+	return nil
+}
+
+// parseNuclei reads nuclei's JSONL output (-jsonl), one result per line
+func parseNuclei(data []byte) ([]Finding, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64<<10), nucleiMaxLine)
+
+	var parsed []Finding
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var r nucleiResult
+		if err := json.Unmarshal(text, &r); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		host := hostOf(r.Host)
+		if host == "" {
+			host = r.IP
+		}
+		description := r.Info.Description
+		if len(r.ExtractedResults) > 0 {
+			description = strings.TrimSpace(description + "\n\nExtracted: " + strings.Join(r.ExtractedResults, ", "))
+		}
+		if description == "" {
+			description = r.Info.Name
+		}
+		f := Finding{
+			Host:              host,
+			Title:             r.Info.Name,
+			Description:       description,
+			Severity:          severity(r.Info.Severity),
+			CVSSScore:         r.Info.Classification.CVSSScore,
+			CVSSVector:        r.Info.Classification.CVSSMetrics,
+			Category:          r.Type,
+			AffectedComponent: orDefault(r.MatchedAt, r.Host),
+			Remediation:       r.Info.Remediation,
+			PluginID:          "nuclei:" + r.TemplateID,
+		}
+		if len(r.Info.Classification.CVEIDs) > 0 {
+			f.CVEID = strings.TrimSpace(r.Info.Classification.CVEIDs[0])
+		}
+		for _, cwe := range r.Info.Classification.CWEIDs {
+			f.CWEIDs = append(f.CWEIDs, strings.ToUpper(strings.TrimSpace(cwe)))
+		}
+		parsed = append(parsed, f)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line+1, err)
+	}
+	return parsed, nil
+}
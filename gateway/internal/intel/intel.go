@@ -0,0 +1,144 @@
+// Package intel keeps a local copy of vulnerability intelligence (CVE
+// metadata from NVD or OSV, EPSS scores and CISA's known-exploited catalog)
+// and enriches findings with it.
+package intel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/lib/pq"
+)
+
+// Sources a CVE record can come from
+const (
+	SourceNVD     = "nvd"
+	SourceOSV     = "osv"
+	SourceUnknown = "unknown" // looked up in both without success
+)
+
+// validCVE matches CVE identifiers such as CVE-2024-3094
+var validCVE = regexp.MustCompile(`^CVE-\d{4}-\d{4,}$`)
+
+// NormalizeCVE upper-cases id, reporting whether it is a CVE identifier
+func NormalizeCVE(id string) (string, bool) {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	return id, validCVE.MatchString(id)
+}
+
+// CVE is the intelligence known about one CVE
+type CVE struct {
+	ID             string         `json:"cve_id" db:"cve_id"`
+	Source         string         `json:"source" db:"source"`
+	Description    *string        `json:"description,omitempty" db:"description"`
+	CVSSScore      *float64       `json:"cvss_score,omitempty" db:"cvss_score"`
+	CVSSVector     *string        `json:"cvss_vector,omitempty" db:"cvss_vector"`
+	CVSSVersion    *string        `json:"cvss_version,omitempty" db:"cvss_version"`
+	CWEIDs         pq.StringArray `json:"cwe_ids" db:"cwe_ids"`
+	EPSSScore      *float64       `json:"epss_score,omitempty" db:"epss_score"`           // Probability of exploitation in the next 30 days
+	EPSSPercentile *float64       `json:"epss_percentile,omitempty" db:"epss_percentile"` // Rank among all scored CVEs
+	EPSSUpdatedAt  *time.Time     `json:"epss_updated_at,omitempty" db:"epss_updated_at"`
+	KnownExploited bool           `json:"known_exploited" db:"known_exploited"` // Listed in CISA's KEV catalog
+	KEVAddedAt     *time.Time     `json:"kev_added_at,omitempty" db:"kev_added_at"`
+	PublishedAt    *time.Time     `json:"published_at,omitempty" db:"published_at"`
+	ModifiedAt     *time.Time     `json:"modified_at,omitempty" db:"modified_at"`
+	SyncedAt       time.Time      `json:"synced_at" db:"synced_at"`
+}
+
+// cveSelect selects CVE records, with KEV membership joined in
+const cveSelect = `
+	SELECT c.cve_id, c.source, c.description, c.cvss_score, c.cvss_vector, c.cvss_version, c.cwe_ids,
+	       c.epss_score, c.epss_percentile, c.epss_updated_at,
+	       k.cve_id IS NOT NULL AS known_exploited, k.added_at AS kev_added_at,
+	       c.published_at, c.modified_at, c.synced_at
+	FROM cve_intel c
+	LEFT JOIN known_exploited_cves k ON k.cve_id = c.cve_id
+`
+
+// Execer is satisfied by *sqlx.Tx and *database.DB
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// enrichQuery copies CVSS and CWE data onto findings whose CVE is known and
+// that were not enriched since it was last synced. Scores and CWEs reported
+// by the scanner take precedence.
+const enrichQuery = `
+	UPDATE vulnerabilities v
+	SET cvss_score = COALESCE(v.cvss_score, c.cvss_score),
+	    cvss_vector = COALESCE(v.cvss_vector, c.cvss_vector),
+	    cwe_ids = COALESCE(NULLIF(v.cwe_ids, '{}'), c.cwe_ids),
+	    enriched_at = NOW()
+	FROM cve_intel c
+	WHERE c.cve_id = v.cve_id AND c.source <> 'unknown'
+	AND (v.enriched_at IS NULL OR v.enriched_at < c.synced_at)
+`
+
+// EnrichScan enriches a scan's findings from the local CVE data, e.g. while
+// its results are ingested. CVEs not known locally are fetched by the next
+// sync and their findings enriched then.
+func EnrichScan(ctx context.Context, db Execer, scanJobID string) (int64, error) {
+	result, err := db.ExecContext(ctx, enrichQuery+` AND v.scan_job_id = $1`, scanJobID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// enrichAll enriches every finding whose CVE data is new to it
+func enrichAll(ctx context.Context, db Execer) (int64, error) {
+	result, err := db.ExecContext(ctx, enrichQuery)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ErrNotFound is returned for CVEs without intelligence
+var ErrNotFound = errors.New("no intelligence for this CVE")
+
+// Get returns what is known about a CVE
+func Get(ctx context.Context, db *database.DB, id string) (*CVE, error) {
+	var cve CVE
+	err := db.Reader().GetContext(ctx, &cve, cveSelect+` WHERE c.cve_id = $1 AND c.source <> 'unknown'`, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CVE %s: %w", id, err)
+	}
+	return &cve, nil
+}
+
+// Filter selects CVEs to list; zero fields match everything
+type Filter struct {
+	IDs            []string
+	KnownExploited bool
+	MinEPSS        float64
+	CWE            string
+	Limit          int
+}
+
+// List returns matching CVEs, most likely to be exploited first
+func List(ctx context.Context, db *database.DB, f Filter) ([]CVE, error) {
+	cves := []CVE{}
+	err := db.Reader().SelectContext(ctx, &cves, cveSelect+`
+		WHERE c.source <> 'unknown'
+		AND (cardinality($1::text[]) = 0 OR c.cve_id = ANY($1::text[]))
+		AND (NOT $2::boolean OR k.cve_id IS NOT NULL)
+		AND ($3::float8 = 0 OR c.epss_score >= $3::float8)
+		AND ($4::text = '' OR $4::text = ANY(c.cwe_ids))
+		ORDER BY k.cve_id IS NOT NULL DESC, c.epss_score DESC NULLS LAST, c.cve_id DESC
+		LIMIT $5
+	`, pq.StringArray(f.IDs), f.KnownExploited, f.MinEPSS, f.CWE, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CVEs: %w", err)
+	}
+	return cves, nil
+}
@@ -0,0 +1,349 @@
+package intel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// errNotFound is returned by lookups of a CVE the source doesn't have
+var errNotFound = errors.New("cve not found")
+
+// maxFeedBytes bounds an upstream response; the KEV catalog is a few MB
+const maxFeedBytes = 64 << 20
+
+// getJSON fetches url into v, sending headers
+func (s *Service) getJSON(ctx context.Context, rawURL string, headers map[string]string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, val := range headers {
+		req.Header.Set(k, val)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %d: %s", req.URL.Host, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxFeedBytes)).Decode(v)
+}
+
+// NVD CVE API 2.0 (https://nvd.nist.gov/developers/vulnerabilities)
+
+// nvdPageSize is the most results the NVD API returns per page
+const nvdPageSize = 2000
+
+// nvdMaxRange is the longest lastModified window the NVD API accepts
+const nvdMaxRange = 120 * 24 * time.Hour
+
+type nvdResponse struct {
+	ResultsPerPage  int `json:"resultsPerPage"`
+	StartIndex      int `json:"startIndex"`
+	TotalResults    int `json:"totalResults"`
+	Vulnerabilities []struct {
+		CVE nvdCVE `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+type nvdCVE struct {
+	ID           string  `json:"id"`
+	Published    nvdTime `json:"published"`
+	LastModified nvdTime `json:"lastModified"`
+	Descriptions []struct {
+		Lang  string `json:"lang"`
+		Value string `json:"value"`
+	} `json:"descriptions"`
+	Metrics    map[string][]nvdMetric `json:"metrics"`
+	Weaknesses []struct {
+		Description []struct {
+			Value string `json:"value"`
+		} `json:"description"`
+	} `json:"weaknesses"`
+}
+
+type nvdMetric struct {
+	Type     string `json:"type"` // Primary (NVD's own) or Secondary (a CNA's)
+	CVSSData struct {
+		Version      string  `json:"version"`
+		VectorString string  `json:"vectorString"`
+		BaseScore    float64 `json:"baseScore"`
+	} `json:"cvssData"`
+}
+
+// nvdTime parses NVD timestamps, which are UTC without a zone
+type nvdTime struct{ time.Time }
+
+func (t *nvdTime) UnmarshalJSON(data []byte) error {
+	s, err := strconv.Unquote(string(data))
+	if err != nil || s == "" {
+		return err
+	}
+	t.Time, err = time.Parse("2006-01-02T15:04:05.999", s)
+	return err
+}
+
+// nvdMetricPreference orders CVSS versions by which score is reported
+var nvdMetricPreference = []string{"cvssMetricV31", "cvssMetricV30", "cvssMetricV40", "cvssMetricV2"}
+
+func (c *nvdCVE) record() CVE {
+	cve := CVE{ID: c.ID, Source: SourceNVD, CWEIDs: pq.StringArray{}}
+	for _, d := range c.Descriptions {
+		if d.Lang == "en" {
+			description := d.Value
+			cve.Description = &description
+			break
+		}
+	}
+	if !c.Published.IsZero() {
+		cve.PublishedAt = &c.Published.Time
+	}
+	if !c.LastModified.IsZero() {
+		cve.ModifiedAt = &c.LastModified.Time
+	}
+
+	// Prefer NVD's own score over a CNA's within the newest v3 version
+	for _, key := range nvdMetricPreference {
+		metrics := c.Metrics[key]
+		if len(metrics) == 0 {
+			continue
+		}
+		chosen := metrics[0]
+		for _, m := range metrics {
+			if m.Type == "Primary" {
+				chosen = m
+				break
+			}
+		}
+		score, vector, version := chosen.CVSSData.BaseScore, chosen.CVSSData.VectorString, chosen.CVSSData.Version
+		cve.CVSSScore, cve.CVSSVector, cve.CVSSVersion = &score, &vector, &version
+		break
+	}
+
+	seen := make(map[string]bool)
+	for _, w := range c.Weaknesses {
+		for _, d := range w.Description {
+			// NVD uses NVD-CWE-Other and NVD-CWE-noinfo for unclassified weaknesses
+			if strings.HasPrefix(d.Value, "CWE-") && !seen[d.Value] {
+				seen[d.Value] = true
+				cve.CWEIDs = append(cve.CWEIDs, d.Value)
+			}
+		}
+	}
+	return cve
+}
+
+func (s *Service) nvdHeaders() map[string]string {
+	if s.config.NVDAPIKey == "" {
+		return nil
+	}
+	return map[string]string{"apiKey": s.config.NVDAPIKey}
+}
+
+// nvdPage fetches one page of CVEs matching query
+func (s *Service) nvdPage(ctx context.Context, query url.Values, startIndex int) (*nvdResponse, error) {
+	query.Set("startIndex", strconv.Itoa(startIndex))
+	query.Set("resultsPerPage", strconv.Itoa(nvdPageSize))
+
+	var page nvdResponse
+	if err := s.getJSON(ctx, s.config.NVDURL+"?"+query.Encode(), s.nvdHeaders(), &page); err != nil {
+		return nil, fmt.Errorf("nvd: %w", err)
+	}
+	return &page, nil
+}
+
+// nvdModified calls fn with each page of CVEs modified in [from, to)
+func (s *Service) nvdModified(ctx context.Context, from, to time.Time, fn func([]CVE) error) error {
+	const layout = "2006-01-02T15:04:05.000-07:00"
+	query := url.Values{
+		"lastModStartDate": {from.UTC().Format(layout)},
+		"lastModEndDate":   {to.UTC().Format(layout)},
+	}
+
+	for start := 0; ; {
+		page, err := s.nvdPage(ctx, query, start)
+		if err != nil {
+			return err
+		}
+		records := make([]CVE, 0, len(page.Vulnerabilities))
+		for _, v := range page.Vulnerabilities {
+			records = append(records, v.CVE.record())
+		}
+		if err := fn(records); err != nil {
+			return err
+		}
+
+		start += len(page.Vulnerabilities)
+		if len(page.Vulnerabilities) == 0 || start >= page.TotalResults {
+			return nil
+		}
+		if err := s.nvdThrottle(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// nvdLookup fetches a single CVE
+func (s *Service) nvdLookup(ctx context.Context, id string) (*CVE, error) {
+	page, err := s.nvdPage(ctx, url.Values{"cveId": {id}}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Vulnerabilities) == 0 {
+		return nil, errNotFound
+	}
+	cve := page.Vulnerabilities[0].CVE.record()
+	return &cve, nil
+}
+
+// nvdThrottle waits between NVD requests to stay under its rate limit: 5
+// requests per 30 seconds without an API key, 50 with one
+func (s *Service) nvdThrottle(ctx context.Context) error {
+	delay := 6 * time.Second
+	if s.config.NVDAPIKey != "" {
+		delay = 600 * time.Millisecond
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// OSV (https://google.github.io/osv.dev/get-v1-vulns/), consulted for CVEs
+// NVD has not published yet
+
+type osvVuln struct {
+	ID        string    `json:"id"`
+	Summary   string    `json:"summary"`
+	Details   string    `json:"details"`
+	Published time.Time `json:"published"`
+	Modified  time.Time `json:"modified"`
+	Severity  []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	DatabaseSpecific struct {
+		CWEIDs []string `json:"cwe_ids"`
+	} `json:"database_specific"`
+}
+
+// osvLookup fetches a single CVE. OSV carries CVSS vectors without scores.
+func (s *Service) osvLookup(ctx context.Context, id string) (*CVE, error) {
+	var v osvVuln
+	if err := s.getJSON(ctx, strings.TrimRight(s.config.OSVURL, "/")+"/"+url.PathEscape(id), nil, &v); err != nil {
+		return nil, fmt.Errorf("osv: %w", err)
+	}
+
+	cve := CVE{ID: id, Source: SourceOSV, CWEIDs: pq.StringArray(v.DatabaseSpecific.CWEIDs)}
+	if cve.CWEIDs == nil {
+		cve.CWEIDs = pq.StringArray{}
+	}
+	description := v.Details
+	if description == "" {
+		description = v.Summary
+	}
+	if description != "" {
+		cve.Description = &description
+	}
+	if !v.Published.IsZero() {
+		cve.PublishedAt = &v.Published
+	}
+	if !v.Modified.IsZero() {
+		cve.ModifiedAt = &v.Modified
+	}
+	for _, sev := range v.Severity {
+		if strings.HasPrefix(sev.Type, "CVSS_V3") {
+			vector, version := sev.Score, strings.TrimPrefix(strings.SplitN(sev.Score, "/", 2)[0], "CVSS:")
+			cve.CVSSVector, cve.CVSSVersion = &vector, &version
+			break
+		}
+	}
+	return &cve, nil
+}
+
+// CISA Known Exploited Vulnerabilities catalog
+
+type kevCatalog struct {
+	Vulnerabilities []struct {
+		CVEID     string `json:"cveID"`
+		DateAdded string `json:"dateAdded"`
+	} `json:"vulnerabilities"`
+}
+
+// kevEntries returns the catalog's CVE IDs and the dates they were added
+func (s *Service) kevEntries(ctx context.Context) (ids, added []string, err error) {
+	var catalog kevCatalog
+	if err := s.getJSON(ctx, s.config.KEVURL, nil, &catalog); err != nil {
+		return nil, nil, fmt.Errorf("kev: %w", err)
+	}
+	for _, v := range catalog.Vulnerabilities {
+		if id, ok := NormalizeCVE(v.CVEID); ok {
+			ids = append(ids, id)
+			added = append(added, v.DateAdded)
+		}
+	}
+	if len(ids) == 0 {
+		// An empty catalog is a broken download, not a cleared list
+		return nil, nil, errors.New("kev: catalog is empty")
+	}
+	return ids, added, nil
+}
+
+// FIRST Exploit Prediction Scoring System (https://www.first.org/epss/api)
+
+// epssBatchSize is how many CVEs are scored per EPSS request
+const epssBatchSize = 100
+
+type epssResponse struct {
+	Data []struct {
+		CVE        string `json:"cve"`
+		EPSS       string `json:"epss"`
+		Percentile string `json:"percentile"`
+	} `json:"data"`
+}
+
+type epssScore struct {
+	Score      float64
+	Percentile float64
+}
+
+// epssScores looks up the current scores of up to epssBatchSize CVEs
+func (s *Service) epssScores(ctx context.Context, ids []string) (map[string]epssScore, error) {
+	var resp epssResponse
+	query := url.Values{"cve": {strings.Join(ids, ",")}}
+	if err := s.getJSON(ctx, s.config.EPSSURL+"?"+query.Encode(), nil, &resp); err != nil {
+		return nil, fmt.Errorf("epss: %w", err)
+	}
+
+	scores := make(map[string]epssScore, len(resp.Data))
+	for _, d := range resp.Data {
+		score, err := strconv.ParseFloat(d.EPSS, 64)
+		if err != nil {
+			continue
+		}
+		percentile, _ := strconv.ParseFloat(d.Percentile, 64)
+		scores[strings.ToUpper(d.CVE)] = epssScore{Score: score, Percentile: percentile}
+	}
+	return scores, nil
+}
@@ -0,0 +1,392 @@
+package intel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Feeds synced on SyncInterval
+const (
+	FeedNVD  = "nvd"
+	FeedKEV  = "kev"
+	FeedEPSS = "epss"
+)
+
+// Config points the sync at its upstream feeds and sets its pace
+type Config struct {
+	NVDURL    string
+	NVDAPIKey string // Optional; raises NVD's rate limit tenfold
+	OSVURL    string
+	KEVURL    string
+	EPSSURL   string
+
+	// SyncInterval is how often each feed is re-synced
+	SyncInterval time.Duration
+	// CheckInterval is how often CVEs new to findings are looked up and
+	// findings enriched
+	CheckInterval time.Duration
+	// InitialLookback is how much NVD history the first sync fetches
+	InitialLookback time.Duration
+	// LookupBatch bounds the CVEs looked up one by one per check
+	LookupBatch int
+	// Transport sends requests, e.g. under an outbound policy; the default
+	// transport when nil
+	Transport http.RoundTripper
+}
+
+func DefaultConfig() Config {
+	return Config{
+		NVDURL:          "https://services.nvd.nist.gov/rest/json/cves/2.0",
+		OSVURL:          "https://api.osv.dev/v1/vulns",
+		KEVURL:          "https://www.cisa.gov/sites/default/files/feeds/known_exploited_vulnerabilities.json",
+		EPSSURL:         "https://api.first.org/data/v1/epss",
+		SyncInterval:    6 * time.Hour,
+		CheckInterval:   5 * time.Minute,
+		InitialLookback: 30 * 24 * time.Hour,
+		LookupBatch:     20,
+	}
+}
+
+// Service syncs vulnerability intelligence into the database and enriches
+// findings from it
+type Service struct {
+	db         *database.DB
+	config     Config
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+func NewService(db *database.DB, config Config, logger *zap.Logger) *Service {
+	return &Service{
+		db:         db,
+		config:     config,
+		httpClient: &http.Client{Timeout: time.Minute, Transport: config.Transport},
+		logger:     logger,
+	}
+}
+
+// SyncStatus is the state of one upstream feed
+type SyncStatus struct {
+	Source        string     `json:"source" db:"source"`
+	SyncedThrough *time.Time `json:"synced_through,omitempty" db:"synced_through"` // NVD only
+	LastRunAt     *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastError     *string    `json:"last_error,omitempty" db:"last_error"`
+}
+
+// Status reports when each feed was last synced
+func (s *Service) Status(ctx context.Context) ([]SyncStatus, error) {
+	status := []SyncStatus{}
+	err := s.db.SelectContext(ctx, &status, `
+		SELECT source, synced_through, last_run_at, last_error FROM intel_sync_state ORDER BY source
+	`)
+	return status, err
+}
+
+// Start syncs due feeds, looks up CVEs new to findings and enriches findings
+// until ctx is done. Feeds are claimed in the database, so with several
+// gateway instances each feed is synced by one of them.
+func (s *Service) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting vulnerability intelligence sync",
+		zap.Duration("sync_interval", s.config.SyncInterval),
+		zap.Duration("check_interval", s.config.CheckInterval),
+	)
+
+	for {
+		s.run(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Service) run(ctx context.Context) {
+	feeds := []struct {
+		source string
+		sync   func(ctx context.Context, syncedThrough *time.Time) error
+	}{
+		{FeedNVD, s.syncNVD},
+		{FeedKEV, s.syncKEV},
+		{FeedEPSS, s.syncEPSS},
+	}
+	for _, feed := range feeds {
+		syncedThrough, due, err := s.claim(ctx, feed.source)
+		if err != nil {
+			s.logger.Error("Failed to claim intelligence feed", zap.String("source", feed.source), zap.Error(err))
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		err = feed.sync(ctx, syncedThrough)
+		s.finish(ctx, feed.source, err)
+	}
+
+	if err := s.lookupMissing(ctx); err != nil {
+		s.logger.Warn("Failed to look up CVEs of new findings", zap.Error(err))
+	}
+	if n, err := enrichAll(ctx, s.db); err != nil {
+		s.logger.Error("Failed to enrich findings", zap.Error(err))
+	} else if n > 0 {
+		s.logger.Info("Enriched findings with CVE intelligence", zap.Int64("findings", n))
+	}
+}
+
+// claim marks a feed as running if it is due, returning its watermark
+func (s *Service) claim(ctx context.Context, source string) (*time.Time, bool, error) {
+	var syncedThrough *time.Time
+	err := s.db.GetContext(ctx, &syncedThrough, `
+		UPDATE intel_sync_state SET last_run_at = NOW()
+		WHERE source = $1 AND (last_run_at IS NULL OR last_run_at <= NOW() - make_interval(secs => $2))
+		RETURNING synced_through
+	`, source, s.config.SyncInterval.Seconds())
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	return syncedThrough, err == nil, err
+}
+
+// finish records a feed's outcome
+func (s *Service) finish(ctx context.Context, source string, syncErr error) {
+	outcome, lastError := "success", ""
+	if syncErr != nil {
+		outcome, lastError = "failure", syncErr.Error()
+		s.logger.Error("Intelligence feed sync failed", zap.String("source", source), zap.Error(syncErr))
+	}
+	metrics.IntelSyncs.WithLabelValues(source, outcome).Inc()
+
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE intel_sync_state SET last_error = NULLIF($2, '') WHERE source = $1
+	`, source, lastError); err != nil {
+		s.logger.Error("Failed to record intelligence feed outcome", zap.String("source", source), zap.Error(err))
+	}
+}
+
+// syncNVD fetches CVEs modified since the watermark, advancing it after each
+// window so an interrupted sync resumes where it stopped
+func (s *Service) syncNVD(ctx context.Context, syncedThrough *time.Time) error {
+	now := time.Now().UTC()
+	from := now.Add(-s.config.InitialLookback)
+	if syncedThrough != nil {
+		from = *syncedThrough
+	}
+
+	total := 0
+	for from.Before(now) {
+		to := from.Add(nvdMaxRange)
+		if to.After(now) {
+			to = now
+		}
+
+		err := s.nvdModified(ctx, from, to, func(records []CVE) error {
+			total += len(records)
+			return s.upsert(ctx, records)
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := s.db.ExecContext(ctx, `
+			UPDATE intel_sync_state SET synced_through = $2 WHERE source = $1
+		`, FeedNVD, to); err != nil {
+			return fmt.Errorf("failed to advance NVD watermark: %w", err)
+		}
+		from = to
+	}
+
+	metrics.IntelRecordsSynced.WithLabelValues(FeedNVD).Add(float64(total))
+	s.logger.Info("Synced CVEs from NVD", zap.Int("cves", total), zap.Time("synced_through", now))
+	return nil
+}
+
+// upsert stores CVE records. OSV records never replace NVD ones.
+func (s *Service) upsert(ctx context.Context, records []CVE) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range records {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO cve_intel (
+				cve_id, source, description, cvss_score, cvss_vector, cvss_version, cwe_ids,
+				published_at, modified_at, synced_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+			ON CONFLICT (cve_id) DO UPDATE
+			SET source = EXCLUDED.source, description = EXCLUDED.description,
+			    cvss_score = EXCLUDED.cvss_score, cvss_vector = EXCLUDED.cvss_vector,
+			    cvss_version = EXCLUDED.cvss_version, cwe_ids = EXCLUDED.cwe_ids,
+			    published_at = EXCLUDED.published_at, modified_at = EXCLUDED.modified_at,
+			    synced_at = NOW()
+			WHERE cve_intel.source <> 'nvd' OR EXCLUDED.source = 'nvd'
+		`, r.ID, r.Source, r.Description, r.CVSSScore, r.CVSSVector, r.CVSSVersion, r.CWEIDs,
+			r.PublishedAt, r.ModifiedAt)
+		if err != nil {
+			return fmt.Errorf("failed to store %s: %w", r.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// lookupMissing fetches CVEs referenced by findings that aren't stored yet,
+// from NVD and then OSV. CVEs neither knows are retried daily.
+func (s *Service) lookupMissing(ctx context.Context) error {
+	var ids []string
+	err := s.db.SelectContext(ctx, &ids, `
+		SELECT DISTINCT v.cve_id FROM vulnerabilities v
+		WHERE v.cve_id IS NOT NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM cve_intel c
+			WHERE c.cve_id = v.cve_id
+			AND (c.source <> 'unknown' OR c.synced_at > NOW() - INTERVAL '1 day')
+		)
+		LIMIT $1
+	`, s.config.LookupBatch)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	found := []string{}
+	for i, id := range ids {
+		if i > 0 {
+			if err := s.nvdThrottle(ctx); err != nil {
+				return err
+			}
+		}
+
+		cve, err := s.nvdLookup(ctx, id)
+		if errors.Is(err, errNotFound) {
+			cve, err = s.osvLookup(ctx, id)
+		}
+		if errors.Is(err, errNotFound) {
+			cve, err = &CVE{ID: id, Source: SourceUnknown, CWEIDs: pq.StringArray{}}, nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.upsert(ctx, []CVE{*cve}); err != nil {
+			return err
+		}
+		if cve.Source != SourceUnknown {
+			found = append(found, id)
+		}
+	}
+	metrics.IntelRecordsSynced.WithLabelValues("lookup").Add(float64(len(found)))
+
+	// Score new CVEs now rather than at the next EPSS sync
+	if len(found) > 0 {
+		if err := s.updateEPSS(ctx, found); err != nil {
+			s.logger.Warn("Failed to score looked-up CVEs", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// syncKEV replaces the known-exploited catalog
+func (s *Service) syncKEV(ctx context.Context, _ *time.Time) error {
+	ids, added, err := s.kevEntries(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM known_exploited_cves WHERE NOT (cve_id = ANY($1::text[]))
+	`, pq.StringArray(ids)); err != nil {
+		return fmt.Errorf("failed to prune KEV catalog: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO known_exploited_cves (cve_id, added_at)
+		SELECT id, NULLIF(added, '')::date FROM unnest($1::text[], $2::text[]) AS k(id, added)
+		ON CONFLICT (cve_id) DO UPDATE SET added_at = EXCLUDED.added_at
+	`, pq.StringArray(ids), pq.StringArray(added)); err != nil {
+		return fmt.Errorf("failed to store KEV catalog: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	metrics.IntelRecordsSynced.WithLabelValues(FeedKEV).Add(float64(len(ids)))
+	s.logger.Info("Synced CISA KEV catalog", zap.Int("cves", len(ids)))
+	return nil
+}
+
+// syncEPSS refreshes the scores of CVEs that findings reference. EPSS scores
+// every published CVE daily; only the ones that matter here are kept.
+func (s *Service) syncEPSS(ctx context.Context, _ *time.Time) error {
+	var ids []string
+	err := s.db.SelectContext(ctx, &ids, `
+		SELECT c.cve_id FROM cve_intel c
+		WHERE c.source <> 'unknown'
+		AND EXISTS (SELECT 1 FROM vulnerabilities v WHERE v.cve_id = c.cve_id)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to load CVEs to score: %w", err)
+	}
+	if err := s.updateEPSS(ctx, ids); err != nil {
+		return err
+	}
+
+	metrics.IntelRecordsSynced.WithLabelValues(FeedEPSS).Add(float64(len(ids)))
+	s.logger.Info("Synced EPSS scores", zap.Int("cves", len(ids)))
+	return nil
+}
+
+// updateEPSS fetches and stores current EPSS scores for ids
+func (s *Service) updateEPSS(ctx context.Context, ids []string) error {
+	for start := 0; start < len(ids); start += epssBatchSize {
+		end := start + epssBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		scores, err := s.epssScores(ctx, ids[start:end])
+		if err != nil {
+			return err
+		}
+		var scored []string
+		var values, percentiles []float64
+		for id, score := range scores {
+			scored = append(scored, id)
+			values = append(values, score.Score)
+			percentiles = append(percentiles, score.Percentile)
+		}
+		if len(scored) == 0 {
+			continue
+		}
+
+		if _, err := s.db.ExecContext(ctx, `
+			UPDATE cve_intel c
+			SET epss_score = u.score, epss_percentile = u.percentile, epss_updated_at = NOW()
+			FROM unnest($1::text[], $2::float8[], $3::float8[]) AS u(cve_id, score, percentile)
+			WHERE c.cve_id = u.cve_id
+		`, pq.StringArray(scored), pq.Float64Array(values), pq.Float64Array(percentiles)); err != nil {
+			return fmt.Errorf("failed to store EPSS scores: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Package legalhold freezes an organization's or user's data for compliance.
+// A hold placed by a platform admin stays active until released; while it is,
+// nothing under the held scope may be deleted or anonymized and its audit
+// logs are exempt from retention purges. Released holds are kept as the hold
+// history.
+//
+// Enforcement is in the database: delete triggers on organizations, users
+// and audit_logs fail with SQLSTATE LH001 for held rows, so every code path
+// is covered. Retention purges of other tables go through Purge, which keeps
+// the rows of held scopes, and single-record deletions call Check first.
+package legalhold
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Hold scopes
+const (
+	ScopeOrganization = "organization"
+	ScopeUser         = "user"
+)
+
+// heldErrorCode is the SQLSTATE raised by the hold triggers
+const heldErrorCode = "LH001"
+
+var (
+	// ErrHeld is returned when data under an active hold would be deleted
+	ErrHeld = errors.New("data is under legal hold")
+	// ErrAlreadyHeld is returned when placing a second active hold on a scope
+	ErrAlreadyHeld = errors.New("an active legal hold already exists for this scope")
+	// ErrHoldNotFound is returned when releasing a hold that does not exist or
+	// is already released
+	ErrHoldNotFound = errors.New("active legal hold not found")
+	// ErrTargetNotFound is returned when the held organization or user does
+	// not exist
+	ErrTargetNotFound = errors.New("legal hold target not found")
+	// ErrInvalidScope is returned for a scope other than organization or user
+	ErrInvalidScope = errors.New("scope must be organization or user")
+)
+
+// Hold is one legal hold, active while ReleasedAt is unset
+type Hold struct {
+	ID            string     `json:"id" db:"id"`
+	Scope         string     `json:"scope" db:"scope"`
+	TargetID      string     `json:"target_id" db:"target_id"`
+	Reason        string     `json:"reason" db:"reason"`
+	CaseReference *string    `json:"case_reference,omitempty" db:"case_reference"`
+	PlacedBy      string     `json:"placed_by" db:"placed_by"`
+	PlacedAt      time.Time  `json:"placed_at" db:"placed_at"`
+	ReleasedBy    *string    `json:"released_by,omitempty" db:"released_by"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty" db:"released_at"`
+	ReleaseReason *string    `json:"release_reason,omitempty" db:"release_reason"`
+}
+
+// HoldColumns lists Hold's columns for SELECT and RETURNING clauses
+const HoldColumns = `id, scope, target_id, reason, case_reference, placed_by,
+	placed_at, released_by, released_at, release_reason`
+
+// Service places, releases and checks legal holds
+type Service struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewService(db *database.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Place puts the organization or user under hold
+func (s *Service) Place(ctx context.Context, scope, targetID, reason, caseReference, adminID string) (*Hold, error) {
+	table := ""
+	switch scope {
+	case ScopeOrganization:
+		table = "organizations"
+	case ScopeUser:
+		table = "users"
+	default:
+		return nil, ErrInvalidScope
+	}
+
+	var exists bool
+	err := s.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id::text = $1)`, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTargetNotFound
+	}
+
+	var ref *string
+	if caseReference != "" {
+		ref = &caseReference
+	}
+	var hold Hold
+	err = s.db.GetContext(ctx, &hold, `
+		INSERT INTO legal_holds (scope, target_id, reason, case_reference, placed_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+HoldColumns+`
+	`, scope, targetID, reason, ref, adminID)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrAlreadyHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Legal hold placed",
+		zap.String("hold_id", hold.ID),
+		zap.String("scope", scope),
+		zap.String("target_id", targetID),
+		zap.String("placed_by", adminID),
+	)
+	return &hold, nil
+}
+
+// Release lifts an active hold; the row is kept as history
+func (s *Service) Release(ctx context.Context, holdID, adminID, reason string) (*Hold, error) {
+	var hold Hold
+	err := s.db.GetContext(ctx, &hold, `
+		UPDATE legal_holds
+		SET released_by = $2, released_at = CURRENT_TIMESTAMP, release_reason = $3
+		WHERE id::text = $1 AND released_at IS NULL
+		RETURNING `+HoldColumns+`
+	`, holdID, adminID, reason)
+	if err == sql.ErrNoRows {
+		return nil, ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Legal hold released",
+		zap.String("hold_id", hold.ID),
+		zap.String("scope", hold.Scope),
+		zap.String("target_id", hold.TargetID),
+		zap.String("released_by", adminID),
+	)
+	return &hold, nil
+}
+
+// List returns active holds, or the full history, newest first
+func (s *Service) List(ctx context.Context, includeReleased bool) ([]Hold, error) {
+	holds := []Hold{}
+	err := s.db.SelectContext(ctx, &holds, `
+		SELECT `+HoldColumns+` FROM legal_holds
+		WHERE $1 OR released_at IS NULL
+		ORDER BY placed_at DESC
+	`, includeReleased)
+	return holds, err
+}
+
+// Check returns ErrHeld if the organization or the user is under hold.
+// Either ID may be empty.
+func (s *Service) Check(ctx context.Context, orgID, userID string) error {
+	var held bool
+	err := s.db.GetContext(ctx, &held, `
+		SELECT under_legal_hold(NULLIF($1, '')::uuid, NULLIF($2, '')::uuid)
+	`, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if held {
+		return ErrHeld
+	}
+	return nil
+}
+
+// IsHeldError reports whether err is a hold trigger refusing a deletion
+func IsHeldError(err error) bool {
+	if errors.Is(err, ErrHeld) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == heldErrorCode
+}
+
+// Purge deletes the rows of table matching where, except those whose
+// organization or user is under hold, and returns how many it deleted and
+// how many it kept. orgColumn and userColumn name the row's organization and
+// user columns, "" for none; the table needs an id column.
+func Purge(ctx context.Context, db *database.DB, table, orgColumn, userColumn, where string, args ...interface{}) (deleted, held int64, err error) {
+	var counts struct {
+		Deleted int64 `db:"deleted"`
+		Held    int64 `db:"held"`
+	}
+	err = db.GetContext(ctx, &counts, fmt.Sprintf(`
+		WITH expired AS (
+			SELECT id, under_legal_hold(%s, %s) AS held FROM %s WHERE %s
+		), purged AS (
+			DELETE FROM %s WHERE id IN (SELECT id FROM expired WHERE NOT held) RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM purged) AS deleted,
+			(SELECT COUNT(*) FROM expired WHERE held) AS held
+	`, columnOrNull(orgColumn), columnOrNull(userColumn), table, where, table), args...)
+	return counts.Deleted, counts.Held, err
+}
+
+func columnOrNull(column string) string {
+	if column == "" {
+		return "NULL"
+	}
+	return column
+}
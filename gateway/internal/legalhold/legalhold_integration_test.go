@@ -0,0 +1,129 @@
+//go:build integration
+
+package legalhold_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+func TestCheck(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	holds := legalhold.NewService(env.DB, env.Logger)
+
+	admin := env.CreateUser(t, testenv.UserOptions{Role: "admin"})
+	heldOrg := env.CreateOrganization(t, "Held")
+	freeOrg := env.CreateOrganization(t, "Free")
+	heldUser := env.CreateUser(t, testenv.UserOptions{})
+	freeUser := env.CreateUser(t, testenv.UserOptions{})
+	releasedOrg := env.CreateOrganization(t, "Released")
+
+	if _, err := holds.Place(ctx, legalhold.ScopeOrganization, heldOrg.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := holds.Place(ctx, legalhold.ScopeUser, heldUser.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	released, err := holds.Place(ctx, legalhold.ScopeOrganization, releasedOrg.ID, "litigation", "", admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := holds.Release(ctx, released.ID, admin.ID, "settled"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		orgID  string
+		userID string
+		held   bool
+	}{
+		{"held organization", heldOrg.ID, "", true},
+		{"held user", "", heldUser.ID, true},
+		{"held user in free organization", freeOrg.ID, heldUser.ID, true},
+		{"free organization and user", freeOrg.ID, freeUser.ID, false},
+		{"released hold", releasedOrg.ID, "", false},
+		{"no scope", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := holds.Check(ctx, tt.orgID, tt.userID)
+			if tt.held {
+				if !errors.Is(err, legalhold.ErrHeld) || !legalhold.IsHeldError(err) {
+					t.Fatalf("Check = %v, want ErrHeld", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestIsHeldErrorMatchesTrigger(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	admin := env.CreateUser(t, testenv.UserOptions{Role: "admin"})
+	org := env.CreateOrganization(t, "Held")
+	if _, err := legalhold.NewService(env.DB, env.Logger).Place(ctx, legalhold.ScopeOrganization, org.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := env.DB.ExecContext(ctx, `DELETE FROM organizations WHERE id = $1`, org.ID)
+	if !legalhold.IsHeldError(err) {
+		t.Fatalf("deleting a held organization = %v, want the hold trigger's error", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	admin := env.CreateUser(t, testenv.UserOptions{Role: "admin"})
+	heldOrg := env.CreateOrganization(t, "Held")
+	freeOrg := env.CreateOrganization(t, "Free")
+	if _, err := legalhold.NewService(env.DB, env.Logger).Place(ctx, legalhold.ScopeOrganization, heldOrg.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, row := range []struct {
+		orgID   string
+		created time.Time
+	}{
+		{heldOrg.ID, old},
+		{freeOrg.ID, old},
+		{freeOrg.ID, time.Now()},
+	} {
+		_, err := env.DB.ExecContext(ctx, `
+			INSERT INTO delivery_attempts (organization_id, channel, source, event, target, request_body, succeeded, created_at)
+			VALUES ($1, 'webhook', 'test', 'scan.completed', 'https://example.com/hook', '{}', true, $2)
+		`, row.orgID, row.created)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, held, err := legalhold.Purge(ctx, env.DB, "delivery_attempts", "organization_id", "",
+		"created_at < $1", time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if deleted != 1 || held != 1 {
+		t.Fatalf("Purge deleted %d and held %d, want 1 and 1", deleted, held)
+	}
+
+	var remaining []string
+	if err := env.DB.SelectContext(ctx, &remaining, `SELECT organization_id FROM delivery_attempts ORDER BY created_at`); err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 2 || remaining[0] != heldOrg.ID || remaining[1] != freeOrg.ID {
+		t.Fatalf("remaining attempts belong to %v, want the held organization's old one and the new one", remaining)
+	}
+}
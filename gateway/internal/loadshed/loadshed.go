@@ -0,0 +1,178 @@
+// Package loadshed bounds how many expensive requests (report generation,
+// exports) run at once so a spike degrades into queuing and fast rejections
+// instead of exhausting the database pool or the brain service.
+//
+// Each route group gets its own Limiter. Requests past the limit wait in a
+// bounded queue for up to QueueTimeout; when the queue is full or the wait
+// runs out they are shed with 503. A user holding more than MaxPerUser slots
+// of a group gets 429 right away, so one client cannot fill the queue.
+package loadshed
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Shedding reasons, as recorded in metrics
+const (
+	ReasonQueueFull    = "queue_full"
+	ReasonQueueTimeout = "queue_timeout"
+	ReasonUserLimit    = "user_limit"
+)
+
+// Config bounds one route group
+type Config struct {
+	// MaxConcurrent is how many requests run at once
+	MaxConcurrent int
+	// MaxQueue is how many more may wait for a slot; zero sheds immediately
+	MaxQueue int
+	// QueueTimeout is how long a request waits before it is shed
+	QueueTimeout time.Duration
+	// MaxPerUser bounds the running and queued requests of one user; zero
+	// leaves users unbounded
+	MaxPerUser int
+	// RetryAfter is sent with shed responses; zero sends QueueTimeout
+	RetryAfter time.Duration
+}
+
+// Limiter bounds the concurrent requests of one route group
+type Limiter struct {
+	group   string
+	config  Config
+	slots   chan struct{}
+	waiting atomic.Int64
+
+	mu      sync.Mutex
+	perUser map[string]int
+}
+
+func NewLimiter(group string, config Config) *Limiter {
+	if config.MaxConcurrent < 1 {
+		config.MaxConcurrent = 1
+	}
+	metrics.ConcurrencyLimit.WithLabelValues(group).Set(float64(config.MaxConcurrent))
+	return &Limiter{
+		group:   group,
+		config:  config,
+		slots:   make(chan struct{}, config.MaxConcurrent),
+		perUser: make(map[string]int),
+	}
+}
+
+// Middleware holds a slot for the rest of the chain. Put it after the
+// authorization checks so rejected requests never take a slot.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if !l.enter(userID) {
+			l.shed(c, http.StatusTooManyRequests, ReasonUserLimit, "Too many concurrent requests of this kind")
+			return
+		}
+		defer l.leave(userID)
+
+		if reason, ok := l.acquire(c); !ok {
+			if reason != "" {
+				l.shed(c, http.StatusServiceUnavailable, reason, "Server is busy, please retry")
+			}
+			return
+		}
+		defer l.release()
+
+		c.Next()
+	}
+}
+
+// enter counts a request against its user's limit
+func (l *Limiter) enter(userID string) bool {
+	if l.config.MaxPerUser <= 0 || userID == "" {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perUser[userID] >= l.config.MaxPerUser {
+		return false
+	}
+	l.perUser[userID]++
+	return true
+}
+
+func (l *Limiter) leave(userID string) {
+	if l.config.MaxPerUser <= 0 || userID == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perUser[userID] <= 1 {
+		delete(l.perUser, userID)
+	} else {
+		l.perUser[userID]--
+	}
+}
+
+// acquire takes a slot, queuing if none is free. It returns the reason the
+// request was shed, or no reason when the client went away while queued.
+func (l *Limiter) acquire(c *gin.Context) (string, bool) {
+	select {
+	case l.slots <- struct{}{}:
+		metrics.ConcurrencyInFlight.WithLabelValues(l.group).Inc()
+		metrics.ConcurrencyQueueWait.WithLabelValues(l.group).Observe(0)
+		return "", true
+	default:
+	}
+
+	if l.waiting.Add(1) > int64(l.config.MaxQueue) {
+		l.waiting.Add(-1)
+		return ReasonQueueFull, false
+	}
+	metrics.ConcurrencyQueued.WithLabelValues(l.group).Inc()
+	defer func() {
+		l.waiting.Add(-1)
+		metrics.ConcurrencyQueued.WithLabelValues(l.group).Dec()
+	}()
+
+	start := time.Now()
+	timer := time.NewTimer(l.config.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		metrics.ConcurrencyInFlight.WithLabelValues(l.group).Inc()
+		metrics.ConcurrencyQueueWait.WithLabelValues(l.group).Observe(time.Since(start).Seconds())
+		return "", true
+	case <-timer.C:
+		return ReasonQueueTimeout, false
+	case <-c.Request.Context().Done():
+		c.Abort()
+		return "", false
+	}
+}
+
+func (l *Limiter) release() {
+	<-l.slots
+	metrics.ConcurrencyInFlight.WithLabelValues(l.group).Dec()
+}
+
+// shed rejects the request with Retry-After
+func (l *Limiter) shed(c *gin.Context, status int, reason, message string) {
+	metrics.LoadShed.WithLabelValues(l.group, reason).Inc()
+
+	retryAfterDuration := l.config.RetryAfter
+	if retryAfterDuration <= 0 {
+		retryAfterDuration = l.config.QueueTimeout
+	}
+	retryAfter := int(math.Max(1, math.Ceil(retryAfterDuration.Seconds())))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(status, gin.H{
+		"error":       message,
+		"code":        "overloaded",
+		"reason":      reason,
+		"retry_after": retryAfter,
+	})
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RecentEntry is one log entry kept by Recent
+type RecentEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Logger  string                 `json:"logger,omitempty"`
+	Message string                 `json:"message"`
+	Caller  string                 `json:"caller,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Recent keeps the last entries at or above a level in memory, so support
+// bundles can include recent errors without access to the log pipeline
+type Recent struct {
+	zapcore.LevelEnabler
+	fields []zapcore.Field
+	ring   *ring
+}
+
+type ring struct {
+	mu      sync.Mutex
+	entries []RecentEntry
+	next    int
+	full    bool
+}
+
+// NewRecent keeps the last size entries at level or above
+func NewRecent(size int, level zapcore.Level) *Recent {
+	if size <= 0 {
+		size = 500
+	}
+	return &Recent{
+		LevelEnabler: level,
+		ring:         &ring{entries: make([]RecentEntry, size)},
+	}
+}
+
+// Attach returns logger also writing to r
+func (r *Recent) Attach(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, r)
+	}))
+}
+
+// Entries returns the kept entries, oldest first
+func (r *Recent) Entries() []RecentEntry {
+	r.ring.mu.Lock()
+	defer r.ring.mu.Unlock()
+
+	if !r.ring.full {
+		return append([]RecentEntry(nil), r.ring.entries[:r.ring.next]...)
+	}
+	out := make([]RecentEntry, 0, len(r.ring.entries))
+	out = append(out, r.ring.entries[r.ring.next:]...)
+	return append(out, r.ring.entries[:r.ring.next]...)
+}
+
+// With implements zapcore.Core
+func (r *Recent) With(fields []zapcore.Field) zapcore.Core {
+	return &Recent{
+		LevelEnabler: r.LevelEnabler,
+		fields:       append(append([]zapcore.Field(nil), r.fields...), fields...),
+		ring:         r.ring,
+	}
+}
+
+// Check implements zapcore.Core
+func (r *Recent) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if r.Enabled(entry.Level) {
+		return checked.AddCore(entry, r)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core
+func (r *Recent) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range r.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	kept := RecentEntry{
+		Time:    entry.Time.UTC(),
+		Level:   entry.Level.String(),
+		Logger:  entry.LoggerName,
+		Message: entry.Message,
+		Fields:  enc.Fields,
+	}
+	if entry.Caller.Defined {
+		kept.Caller = entry.Caller.TrimmedPath()
+	}
+
+	r.ring.mu.Lock()
+	r.ring.entries[r.ring.next] = kept
+	r.ring.next = (r.ring.next + 1) % len(r.ring.entries)
+	if r.ring.next == 0 {
+		r.ring.full = true
+	}
+	r.ring.mu.Unlock()
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (r *Recent) Sync() error {
+	return nil
+}
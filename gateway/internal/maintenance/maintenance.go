@@ -0,0 +1,216 @@
+// Package maintenance implements the platform-wide maintenance switch. The
+// switch lives in Redis so every gateway instance sees it; each instance
+// polls it and notifies listeners (WebSocket broadcast) when it flips.
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const stateKey = "maintenance:state"
+
+// defaultRetryAfter is sent when maintenance has no scheduled end
+const defaultRetryAfter = 5 * time.Minute
+
+// State describes the current maintenance window
+type State struct {
+	Active    bool       `json:"active"`
+	Message   string     `json:"message,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"` // Nil until switched off by hand
+	StartedBy string     `json:"started_by,omitempty"`
+}
+
+// RetryAfter is how long clients should wait before retrying
+func (s State) RetryAfter() time.Duration {
+	if s.EndsAt != nil {
+		if d := time.Until(*s.EndsAt); d > 0 {
+			return d
+		}
+	}
+	return defaultRetryAfter
+}
+
+// ActiveError is returned when maintenance blocks an operation
+type ActiveError struct {
+	State State
+}
+
+func (e *ActiveError) Error() string {
+	return "maintenance mode is active"
+}
+
+// Service reads and flips the maintenance switch. Checks on the request path
+// use the state from the last poll, so they never wait on Redis.
+type Service struct {
+	redis  *redis.Client
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	current   State
+	listeners []func(State)
+}
+
+func NewService(redisClient *redis.Client, logger *zap.Logger) *Service {
+	return &Service{
+		redis:  redisClient,
+		logger: logger,
+	}
+}
+
+// OnChange registers a callback for when maintenance starts or ends
+func (s *Service) OnChange(fn func(State)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Current returns the last known state
+func (s *Service) Current() State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Active reports whether maintenance is on
+func (s *Service) Active() bool {
+	return s.Current().Active
+}
+
+// Enable starts maintenance. A positive duration ends it automatically.
+func (s *Service) Enable(ctx context.Context, userID, message string, duration time.Duration) (State, error) {
+	now := time.Now().UTC()
+	state := State{
+		Active:    true,
+		Message:   message,
+		StartedAt: &now,
+		StartedBy: userID,
+	}
+	if duration > 0 {
+		endsAt := now.Add(duration)
+		state.EndsAt = &endsAt
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return State{}, err
+	}
+	if err := s.redis.Set(ctx, stateKey, data, duration).Err(); err != nil {
+		return State{}, fmt.Errorf("failed to enable maintenance: %w", err)
+	}
+
+	s.update(state)
+	return state, nil
+}
+
+// Disable ends maintenance
+func (s *Service) Disable(ctx context.Context) error {
+	if err := s.redis.Del(ctx, stateKey).Err(); err != nil {
+		return fmt.Errorf("failed to disable maintenance: %w", err)
+	}
+	s.update(State{})
+	return nil
+}
+
+// Refresh reloads the state from Redis
+func (s *Service) Refresh(ctx context.Context) error {
+	data, err := s.redis.Get(ctx, stateKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		s.update(State{})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("invalid maintenance state: %w", err)
+	}
+	s.update(state)
+	return nil
+}
+
+// Watch polls the switch until ctx is cancelled, so changes made through
+// another instance (or a window expiring) reach this one
+func (s *Service) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			// Keep the last known state rather than flapping on Redis errors
+			s.logger.Error("Failed to read maintenance state", zap.Error(err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// update stores state and notifies listeners when maintenance starts or ends
+func (s *Service) update(state State) {
+	s.mu.Lock()
+	changed := s.current.Active != state.Active
+	s.current = state
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if state.Active {
+		metrics.MaintenanceActive.Set(1)
+		s.logger.Warn("Maintenance mode started", zap.String("message", state.Message))
+	} else {
+		metrics.MaintenanceActive.Set(0)
+		s.logger.Info("Maintenance mode ended")
+	}
+	for _, fn := range listeners {
+		fn(state)
+	}
+}
+
+// Middleware answers 503 with Retry-After while maintenance is on, unless
+// bypass (if set) lets the request through
+func (s *Service) Middleware(bypass func(c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := s.Current()
+		if !state.Active || (bypass != nil && bypass(c)) {
+			c.Next()
+			return
+		}
+
+		Abort(c, state)
+	}
+}
+
+// Abort ends the request with the maintenance response
+func Abort(c *gin.Context, state State) {
+	retryAfter := int(math.Ceil(state.RetryAfter().Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":       "Service is under maintenance",
+		"code":        "maintenance",
+		"message":     state.Message,
+		"ends_at":     state.EndsAt,
+		"retry_after": retryAfter,
+	})
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// StartCollector periodically refreshes gauges that reflect database state
+func StartCollector(ctx context.Context, db *sqlx.DB, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	collect(ctx, db, logger)
+	for {
+		select {
+		case <-ticker.C:
+			collect(ctx, db, logger)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func collect(ctx context.Context, db *sqlx.DB, logger *zap.Logger) {
+	var activeSessions int
+	err := db.GetContext(ctx, &activeSessions, `
+		SELECT COUNT(*) FROM sessions
+		WHERE revoked_at IS NULL AND expires_at > NOW()
+	`)
+	if err != nil {
+		logger.Error("Failed to collect active sessions", zap.Error(err))
+	} else {
+		ActiveSessions.Set(float64(activeSessions))
+	}
+
+	var activeScans int
+	err = db.GetContext(ctx, &activeScans, `
+		SELECT COUNT(*) FROM scan_jobs WHERE status = 'running'
+	`)
+	if err != nil {
+		logger.Error("Failed to collect active scans", zap.Error(err))
+	} else {
+		ScansActive.Set(float64(activeScans))
+	}
+}
@@ -40,6 +40,35 @@ var (
 		},
 	)
 
+	// Scan timing, for SLO dashboards. Durations run from dispatch to the
+	// worker's result, including any time spent paused.
+	ScanDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_scan_duration_seconds",
+			Help:    "Scan run time from dispatch to result, by scan type and outcome (completed or failed)",
+			Buckets: prometheus.ExponentialBuckets(30, 2, 10), // 30s to ~4h
+		},
+		[]string{"scan_type", "outcome"},
+	)
+
+	ScanPhaseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_scan_phase_duration_seconds",
+			Help:    "Time spent in each scan phase as reported by workers",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 14), // 1s to ~2.3h
+		},
+		[]string{"scan_type", "phase"},
+	)
+
+	ScanQueueWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_scan_queue_wait_seconds",
+			Help:    "Time scans wait for a worker after becoming dispatchable (queued, approved or re-queued)",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+		},
+		[]string{"scan_type"},
+	)
+
 	ReportsGenerated = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "cypersecurity_reports_generated_total",
@@ -56,6 +85,14 @@ var (
 		[]string{"status"}, // success, failure
 	)
 
+	AuthChallenges = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_auth_challenges_total",
+			Help: "CAPTCHA / proof-of-work challenges on auth endpoints",
+		},
+		[]string{"action", "result"}, // issued, passed, failed
+	)
+
 	ActiveSessions = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "cypersecurity_active_sessions",
@@ -71,6 +108,13 @@ var (
 		},
 	)
 
+	MaintenanceActive = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_maintenance_active",
+			Help: "Whether maintenance mode is currently active (1=active, 0=inactive)",
+		},
+	)
+
 	// Audit logs
 	AuditLogsTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -86,4 +130,555 @@ var (
 			Help: "Total audit logs successfully signed",
 		},
 	)
+
+	AuditSigningQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_audit_signing_queue_depth",
+			Help: "Number of audit logs waiting to be signed",
+		},
+	)
+
+	AuditSigningFailures = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_audit_signing_failures_total",
+			Help: "Total audit logs that failed to be signed",
+		},
+	)
+
+	AuditBufferQueueDepth = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_audit_buffer_queue_depth",
+			Help: "Audit logs waiting in the write buffer",
+		},
+	)
+
+	AuditBufferOverflows = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_audit_buffer_overflows_total",
+			Help: "Audit logs written synchronously because the buffer was full",
+		},
+	)
+
+	AuditBatchSize = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_audit_batch_size",
+			Help:    "Audit logs written per batch insert",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+	)
+
+	AuditStreamSubscribers = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_audit_stream_subscribers",
+			Help: "Clients tailing the live audit stream",
+		},
+	)
+
+	AuditStreamDropped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_audit_stream_dropped_total",
+			Help: "Audit entries not delivered to a stream client that fell behind",
+		},
+	)
+
+	// Authorization pulse checks
+	PulseCheckDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_pulse_check_duration_seconds",
+			Help:    "Duration of authorization pulse check runs in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	AuthPulses = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_auth_pulses_total",
+			Help: "Client authorization pulses by outcome (authorized, revoked, expired, error)",
+		},
+		[]string{"status"},
+	)
+
+	AuthPulseLatency = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_auth_pulse_latency_seconds",
+			Help:    "Time to evaluate a client authorization pulse in seconds",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1},
+		},
+	)
+
+	// WebSocket metrics
+	WebSocketClients = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_websocket_clients",
+			Help: "Number of connected WebSocket clients",
+		},
+	)
+
+	WebSocketTopicSubscribers = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_websocket_topic_subscribers",
+			Help: "Number of WebSocket clients subscribed to each topic",
+		},
+		[]string{"topic"},
+	)
+
+	WebSocketMessagesBroadcast = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_websocket_messages_broadcast_total",
+			Help: "Total WebSocket messages delivered to clients",
+		},
+		[]string{"type"},
+	)
+
+	WebSocketMessagesDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_websocket_messages_dropped_total",
+			Help: "Total WebSocket messages dropped because a client's send buffer was full",
+		},
+		[]string{"type"},
+	)
+
+	WebSocketConnectionAge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_websocket_connection_age",
+			Help: "Connected WebSocket clients by connection age, sampled by the liveness reaper",
+		},
+		[]string{"age"}, // lt_1m, 1m_10m, 10m_1h, 1h_6h, gte_6h
+	)
+
+	WebSocketClientsReaped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_websocket_clients_reaped_total",
+			Help: "Total WebSocket connections closed because no pong arrived in time",
+		},
+	)
+
+	WebSocketMessagesReplayed = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_websocket_messages_replayed_total",
+			Help: "Total buffered topic messages replayed to reconnecting clients",
+		},
+	)
+
+	DBNotifications = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_db_notifications_total",
+			Help: "Postgres notifications bridged to WebSocket clients, by outcome",
+		},
+		[]string{"channel", "result"}, // delivered, dropped, invalid
+	)
+
+	DBNotificationReconnects = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_db_notification_reconnects_total",
+			Help: "Times the notification listener reconnected (notifications in the gap are lost)",
+		},
+	)
+
+	// Domain event metrics
+	EventsPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_events_published_total",
+			Help: "Outbox events published to the event bus, by outcome",
+		},
+		[]string{"type", "result"}, // success, failure
+	)
+
+	// Internal gRPC metrics
+	GRPCRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_grpc_requests_total",
+			Help: "Total number of internal gRPC requests",
+		},
+		[]string{"method", "code"},
+	)
+
+	GRPCRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_grpc_request_duration_seconds",
+			Help:    "Internal gRPC request duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+
+	// Session validation cache
+	SessionCacheRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_session_cache_requests_total",
+			Help: "Session validation cache lookups by result (hit, miss, error)",
+		},
+		[]string{"result"},
+	)
+
+	// Database queries and connection pool
+	DBQueryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_db_query_duration_seconds",
+			Help:    "Database query duration in seconds by query name, status (ok, error, timeout) and role (primary, replica)",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"query", "status", "role"},
+	)
+
+	DBSlowQueries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_db_slow_queries_total",
+			Help: "Queries slower than the slow-query threshold",
+		},
+		[]string{"query"},
+	)
+
+	DBConnections = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_db_connections",
+			Help: "Database connection pool size by state (max_open, open, in_use, idle)",
+		},
+		[]string{"state"},
+	)
+
+	DBConnectionWaits = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_db_connection_waits",
+			Help: "Cumulative number of times a query waited for a free connection",
+		},
+	)
+
+	DBConnectionWaitSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_db_connection_wait_seconds",
+			Help: "Cumulative time spent waiting for a free connection",
+		},
+	)
+
+	DBReplicaLagSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_db_replica_lag_seconds",
+			Help: "Replication replay lag of the read replica",
+		},
+	)
+
+	DBReplicaHealthy = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_db_replica_healthy",
+			Help: "1 while reads are routed to the replica, 0 while they fall back to the primary",
+		},
+	)
+
+	DBReplicaFallbacks = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_db_replica_fallbacks_total",
+			Help: "Replica queries retried on the primary",
+		},
+	)
+
+	ScanWorkers = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_scan_workers",
+			Help: "Registered scanner workers by status",
+		},
+		[]string{"status"},
+	)
+
+	ScanJobsRequeued = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_scan_jobs_requeued_total",
+			Help: "Running scan jobs recovered from lost workers, by outcome (pending or failed)",
+		},
+		[]string{"status"},
+	)
+
+	EscalationNotifications = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_escalation_notifications_total",
+			Help: "Escalation contacts paged, by channel and outcome (sent or failed)",
+		},
+		[]string{"channel", "outcome"},
+	)
+
+	IntelSyncs = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_intel_syncs_total",
+			Help: "Vulnerability intelligence feed syncs, by source (nvd, kev, epss) and outcome",
+		},
+		[]string{"source", "outcome"},
+	)
+
+	IntelRecordsSynced = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_intel_records_synced_total",
+			Help: "CVE records stored or scored, by source (nvd, kev, epss, or lookup for CVEs fetched one by one)",
+		},
+		[]string{"source"},
+	)
+
+	DependencyUp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_dependency_up",
+			Help: "Whether a dependency passed its last health check (1) or not (0), by check",
+		},
+		[]string{"check"},
+	)
+
+	StatusSamples = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_status_samples_total",
+			Help: "Status page uptime samples, by outcome (recorded, skipped or failed)",
+		},
+		[]string{"outcome"},
+	)
+
+	ConcurrencyLimit = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_concurrency_limit",
+			Help: "Maximum concurrent requests of a load-shedding route group",
+		},
+		[]string{"group"},
+	)
+
+	ConcurrencyInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_concurrency_in_flight",
+			Help: "Requests of a load-shedding route group holding a slot; saturated at cypersecurity_concurrency_limit",
+		},
+		[]string{"group"},
+	)
+
+	ConcurrencyQueued = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_concurrency_queued",
+			Help: "Requests of a load-shedding route group waiting for a slot",
+		},
+		[]string{"group"},
+	)
+
+	ConcurrencyQueueWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_concurrency_queue_wait_seconds",
+			Help:    "Time requests waited for a slot before running, by route group",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12), // 10ms to ~20s
+		},
+		[]string{"group"},
+	)
+
+	LoadShed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_load_shed_total",
+			Help: "Requests rejected by load shedding, by route group and reason (queue_full, queue_timeout or user_limit)",
+		},
+		[]string{"group", "reason"},
+	)
+
+	SessionsExtended = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_sessions_extended_total",
+			Help: "Sessions whose expiry slid forward because they were in use",
+		},
+	)
+
+	SessionActivityFlushed = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_session_activity_flushed_total",
+			Help: "Session last-activity updates written to the database from Redis",
+		},
+	)
+
+	SessionsRevokedIdle = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_sessions_revoked_idle_total",
+			Help: "Sessions revoked for being idle longer than the idle timeout",
+		},
+	)
+
+	SessionLimitHits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_session_limit_hits_total",
+			Help: "Logins over the concurrent session limit, by action taken",
+		},
+		[]string{"action"},
+	)
+
+	ScanWindowActions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_scan_window_actions_total",
+			Help: "Scans deferred, paused or resumed to keep them within their execution windows",
+		},
+		[]string{"action"},
+	)
+
+	ReportBackendRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_report_backend_requests_total",
+			Help: "Reports rendered per backend, by outcome (success, fallback, error)",
+		},
+		[]string{"backend", "outcome"},
+	)
+
+	DataKeyRotations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_data_key_rotations_total",
+			Help: "Organization data keys re-wrapped under the current master key or replaced for age, and re-wraps that failed",
+		},
+		[]string{"action"},
+	)
+
+	AuditAnchors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_audit_anchors_total",
+			Help: "Audit hash chain heads anchored with the external notary, by outcome (anchored, failed)",
+		},
+		[]string{"outcome"},
+	)
+
+	OrgTokenRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_org_token_requests_total",
+			Help: "Requests made with organization API tokens, by outcome (allowed, denied)",
+		},
+		[]string{"outcome"},
+	)
+
+	BillingEvents = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_billing_events_total",
+			Help: "Billing ledger events by type and outcome (recorded for storage measurements, exported or export_failed for Stripe)",
+		},
+		[]string{"event_type", "outcome"},
+	)
+
+	NetworkPolicyBlocks = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_network_policy_blocks_total",
+			Help: "Logins and requests from addresses outside an organization's allowlist, by stage (login, request, api_token) and outcome (blocked, break_glass)",
+		},
+		[]string{"stage", "outcome"},
+	)
+
+	UploadScans = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_upload_scans_total",
+			Help: "Uploads by kind and outcome (clean, infected, unscanned, rejected by size or type, scan_failed)",
+		},
+		[]string{"kind", "outcome"},
+	)
+
+	PayloadRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_payload_rejections_total",
+			Help: "Request bodies rejected by route group and code (body_too_large, json_too_deep, json_array_too_long)",
+		},
+		[]string{"group", "code"},
+	)
+
+	APIVersionRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_api_version_requests_total",
+			Help: "REST requests by API version, how the version was selected (path or accept) and whether the endpoint was deprecated",
+		},
+		[]string{"version", "selected_by", "deprecated"},
+	)
+
+	DeprecatedEndpointRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_api_deprecated_requests_total",
+			Help: "Requests to deprecated REST endpoints by API version and endpoint, to track callers before removal",
+		},
+		[]string{"version", "endpoint"},
+	)
+
+	IntegrationDeliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_integration_deliveries_total",
+			Help: "Outbound webhook and Slack deliveries by channel, source (report_schedule, escalation, security_alert, test) and outcome (sent or failed)",
+		},
+		[]string{"channel", "source", "outcome"},
+	)
+
+	OutboundBlocked = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_outbound_blocked_total",
+			Help: "Outbound requests refused by the egress policy, by integration and reason (address, scheme, port, redirect_limit, https_downgrade)",
+		},
+		[]string{"integration", "reason"},
+	)
+
+	ActivityDigests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_activity_digests_total",
+			Help: "Activity digest emails by frequency (daily or weekly) and outcome (sent, skipped when there was no activity, or failed)",
+		},
+		[]string{"frequency", "outcome"},
+	)
+
+	ScanConsoleLines = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_scan_console_lines_total",
+			Help: "Scan console lines received from workers, by outcome (relayed, or dropped by the per-scan rate limit)",
+		},
+		[]string{"outcome"},
+	)
+
+	ScanConsoleStreams = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_scan_console_streams",
+			Help: "Worker console streams currently open on this instance",
+		},
+	)
+
+	ArtifactDownloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_artifact_downloads_total",
+			Help: "Report downloads and export links handed out, by artifact type and status (completed, incomplete, link_issued)",
+		},
+		[]string{"artifact", "status"},
+	)
+
+	ArtifactDownloadBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_artifact_download_bytes_total",
+			Help: "Bytes of reports served, and of exports behind the links handed out, by artifact type",
+		},
+		[]string{"artifact"},
+	)
+
+	TasksProcessed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_tasks_processed_total",
+			Help: "Background task runs by queue, kind and outcome (succeeded, retried, failed, cancelled)",
+		},
+		[]string{"queue", "kind", "outcome"},
+	)
+
+	TaskDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cypersecurity_task_duration_seconds",
+			Help:    "Background task run time by queue and kind",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 16), // 50ms to ~27m
+		},
+		[]string{"queue", "kind"},
+	)
+
+	TasksRunning = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cypersecurity_tasks_running",
+			Help: "Background tasks running on this instance, by queue",
+		},
+		[]string{"queue"},
+	)
+
+	ScanEventsPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_scan_events_published_total",
+			Help: "Scan events appended to the Redis scan event stream, by type",
+		},
+		[]string{"type"},
+	)
+
+	ScanEventsConsumed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cypersecurity_scan_events_consumed_total",
+			Help: "Scan events read by a consumer group, by outcome (handled, failed and retried later, dead_lettered)",
+		},
+		[]string{"group", "type", "outcome"},
+	)
 )
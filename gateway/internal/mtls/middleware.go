@@ -0,0 +1,33 @@
+package mtls
+
+import (
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequireIdentity admits requests whose verified client certificate maps to
+// one of the allowed services, and stores it as "service_identity". It must
+// be used on a listener serving ServerConfig.
+func (r *Reloader) RequireIdentity(logger *zap.Logger, allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := r.IdentifyState(c.Request.TLS)
+		for _, a := range allowed {
+			if identity == a {
+				c.Set("service_identity", identity)
+				c.Next()
+				return
+			}
+		}
+
+		logging.FromContext(c.Request.Context(), logger).Warn("Rejected internal call",
+			zap.String("path", c.FullPath()),
+			zap.String("identity", identity),
+			zap.String("ip_address", c.ClientIP()),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": "client certificate not authorized for this endpoint"})
+		c.Abort()
+	}
+}
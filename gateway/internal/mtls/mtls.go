@@ -0,0 +1,226 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+var ErrUnknownIdentity = errors.New("peer certificate does not map to a known service")
+
+// Config locates the certificates used for mutual TLS between internal services
+type Config struct {
+	CertFile string // This service's certificate (presented as server and client)
+	KeyFile  string // Its private key
+	CAFile   string // CA bundle that peer certificates must chain to
+
+	// Identities maps certificate SANs to service names, e.g.
+	// "spiffe://cyper/worker" => "worker" or "*.workers.internal" => "worker".
+	// A leading "*." matches any subdomain. When empty, a peer is identified
+	// by its first URI or DNS SAN, or failing that its common name.
+	Identities map[string]string
+}
+
+// ParseIdentities reads "san=identity" pairs separated by commas
+func ParseIdentities(spec string) (map[string]string, error) {
+	identities := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		san, identity, ok := strings.Cut(pair, "=")
+		if !ok || san == "" || identity == "" {
+			return nil, fmt.Errorf("invalid identity mapping %q, want san=identity", pair)
+		}
+		identities[strings.TrimSpace(san)] = strings.TrimSpace(identity)
+	}
+	return identities, nil
+}
+
+// Reloader holds the current certificate and CA pool. Reload swaps them in
+// place, so connections opened afterwards use the new material without
+// restarting listeners or clients.
+type Reloader struct {
+	config Config
+	logger *zap.Logger
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	pool *x509.CertPool
+}
+
+// NewReloader loads the certificates once, failing if they are unusable
+func NewReloader(config Config, logger *zap.Logger) (*Reloader, error) {
+	r := &Reloader{config: config, logger: logger}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the certificate, key and CA bundle. On error the previous
+// material stays in use.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	if cert.Leaf == nil && len(cert.Certificate) > 0 {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("failed to parse certificate: %w", err)
+		}
+	}
+
+	caPEM, err := os.ReadFile(r.config.CAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in %s", r.config.CAFile)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.pool = pool
+	r.mu.Unlock()
+
+	if cert.Leaf != nil {
+		r.logger.Info("Loaded mTLS certificate",
+			zap.String("subject", cert.Leaf.Subject.String()),
+			zap.Time("not_after", cert.Leaf.NotAfter),
+		)
+	}
+	return nil
+}
+
+// WatchSIGHUP reloads the certificates on SIGHUP until ctx is cancelled
+func (r *Reloader) WatchSIGHUP(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+			if err := r.Reload(); err != nil {
+				r.logger.Error("Certificate reload failed, keeping previous certificate", zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+// ServerConfig requires and verifies client certificates against the current CA
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			return &tls.Config{
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				MinVersion:   tls.VersionTLS12,
+			}, nil
+		},
+	}
+}
+
+// ClientConfig presents the current certificate and verifies the server
+// against the current CA. With expectIdentity set, the server's certificate
+// must also map to that service.
+func (r *Reloader) ClientConfig(expectIdentity string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+		// Verification is done in VerifyConnection so that a reloaded CA
+		// applies to new connections; the default verifier would pin the
+		// pool captured here
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			_, pool := r.current()
+			opts := x509.VerifyOptions{
+				Roots:         pool,
+				DNSName:       state.ServerName,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range state.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+				return err
+			}
+			if expectIdentity != "" {
+				if identity := r.Identify(state.PeerCertificates[0]); identity != expectIdentity {
+					return fmt.Errorf("server identity %q, want %q", identity, expectIdentity)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Identify maps a verified peer certificate to a service identity. It returns
+// "" when identities are configured and none matches.
+func (r *Reloader) Identify(cert *x509.Certificate) string {
+	var sans []string
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	sans = append(sans, cert.DNSNames...)
+
+	if len(r.config.Identities) == 0 {
+		if len(sans) > 0 {
+			return sans[0]
+		}
+		return cert.Subject.CommonName
+	}
+
+	for _, san := range sans {
+		if identity, ok := r.config.Identities[san]; ok {
+			return identity
+		}
+	}
+	for pattern, identity := range r.config.Identities {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			for _, san := range sans {
+				if strings.HasSuffix(san, "."+suffix) {
+					return identity
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// IdentifyState maps the verified client certificate of a connection
+func (r *Reloader) IdentifyState(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return r.Identify(state.VerifiedChains[0][0])
+}
@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"go.uber.org/zap"
+)
+
+// VerifyEmailPath is the public endpoint behind the email verification link
+const VerifyEmailPath = "/api/v1/auth/verify-email"
+
+// EmailVerificationEmailer returns a notifier that emails the user a link
+// verifying their address
+func EmailVerificationEmailer(mailer *Mailer, publicURL string, logger *zap.Logger) auth.EmailVerificationFunc {
+	return func(v auth.EmailVerification) {
+		if !mailer.Enabled() {
+			return
+		}
+
+		verifyURL := strings.TrimRight(publicURL, "/") + VerifyEmailPath + "?token=" + url.QueryEscape(v.Token)
+		body := fmt.Sprintf("Confirm that %s is your email address by opening this link:\r\n%s\r\n\r\n"+
+			"The link expires on %s. If you did not sign up for Cyper Security, ignore this email.\r\n",
+			v.Email, verifyURL, v.ExpiresAt.Format("2006-01-02 15:04 MST"))
+		if err := mailer.Send([]string{v.Email}, "Verify your Cyper Security email address", body); err != nil {
+			logger.Error("Failed to email verification link", zap.String("user_id", v.UserID), zap.Error(err))
+		}
+	}
+}
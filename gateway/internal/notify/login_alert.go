@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/preferences"
+	"go.uber.org/zap"
+)
+
+// RevokeSessionPath is the public endpoint behind the one-click revoke link
+const RevokeSessionPath = "/api/v1/auth/sessions/revoke"
+
+// LoginAlertEmailer returns a notifier that emails the user about a login
+// from a new device or country, with a link that revokes the session. Times
+// are shown in the user's preferred timezone.
+func LoginAlertEmailer(mailer *Mailer, prefs *preferences.Service, publicURL string, logger *zap.Logger) auth.LoginAlertFunc {
+	return func(alert auth.LoginAlert) {
+		if !mailer.Enabled() {
+			return
+		}
+
+		revokeURL := strings.TrimRight(publicURL, "/") + RevokeSessionPath + "?token=" + url.QueryEscape(alert.RevokeToken)
+		loc := prefs.Lookup(context.Background(), alert.UserID).Location()
+		if err := mailer.Send([]string{alert.Email}, "New sign-in to your Cyper Security account", loginAlertBody(alert, revokeURL, loc)); err != nil {
+			logger.Error("Failed to email login alert", zap.String("user_id", alert.UserID), zap.Error(err))
+		}
+	}
+}
+
+func loginAlertBody(alert auth.LoginAlert, revokeURL string, loc *time.Location) string {
+	var body strings.Builder
+
+	switch {
+	case alert.NewDevice && alert.NewCountry:
+		body.WriteString("Your account was signed in to from a new device and a new country.\r\n\r\n")
+	case alert.NewCountry:
+		body.WriteString("Your account was signed in to from a new country.\r\n\r\n")
+	default:
+		body.WriteString("Your account was signed in to from a new device.\r\n\r\n")
+	}
+
+	fmt.Fprintf(&body, "Time:     %s\r\n", alert.OccurredAt.In(loc).Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&body, "Device:   %s, %s on %s\r\n", alert.Device.Browser, alert.Device.OS, alert.Device.Device)
+	fmt.Fprintf(&body, "IP:       %s\r\n", alert.IPAddress)
+	if alert.Geo != nil {
+		location := []string{}
+		for _, part := range []string{alert.Geo.City, alert.Geo.Region, alert.Geo.Country} {
+			if part != "" {
+				location = append(location, part)
+			}
+		}
+		fmt.Fprintf(&body, "Location: %s\r\n", strings.Join(location, ", "))
+	}
+
+	body.WriteString("\r\nIf this was you, no action is needed.\r\n")
+	fmt.Fprintf(&body, "If not, sign this session out immediately and change your password:\r\n%s\r\n", revokeURL)
+	return body.String()
+}
@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"go.uber.org/zap"
+)
+
+// MagicLinkPath is the dashboard page that signs in with a magic link. It
+// posts the token to /api/v1/auth/magic-link/login; opening the link alone
+// does not use it up.
+const MagicLinkPath = "/login/magic-link"
+
+// MagicLinkEmailer returns a notifier that emails the user their sign-in link
+func MagicLinkEmailer(mailer *Mailer, publicURL string, logger *zap.Logger) auth.MagicLinkFunc {
+	return func(link auth.MagicLink) {
+		if !mailer.Enabled() {
+			return
+		}
+
+		loginURL := strings.TrimRight(publicURL, "/") + MagicLinkPath + "?token=" + url.QueryEscape(link.Token)
+		body := fmt.Sprintf("Sign in to Cyper Security by opening this link:\r\n%s\r\n\r\n"+
+			"The link works once and expires on %s. It was requested from %s.\r\n"+
+			"If you did not ask to sign in, ignore this email; nobody can use the link without access to your inbox.\r\n",
+			loginURL, link.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"), link.IPAddress)
+		if err := mailer.Send([]string{link.Email}, "Your Cyper Security sign-in link", body); err != nil {
+			logger.Error("Failed to email magic link", zap.String("user_id", link.UserID), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPConfig configures outgoing email
+type SMTPConfig struct {
+	Host     string // empty disables email
+	Port     string
+	User     string
+	Password string
+	From     string
+}
+
+// Mailer sends plain-text email over SMTP
+type Mailer struct {
+	config SMTPConfig
+}
+
+func NewMailer(config SMTPConfig) *Mailer {
+	return &Mailer{config: config}
+}
+
+// Enabled reports whether SMTP is configured
+func (m *Mailer) Enabled() bool {
+	return m.config.Host != ""
+}
+
+// Send delivers a plain-text message to the recipients
+func (m *Mailer) Send(to []string, subject, body string) error {
+	return m.SendAs("", to, subject, body)
+}
+
+// SendAs is Send with senderName replacing the display name of SMTP_FROM,
+// for organizations that white-label their email. The address is unchanged.
+func (m *Mailer) SendAs(senderName string, to []string, subject, body string) error {
+	if !m.Enabled() {
+		return fmt.Errorf("SMTP is not configured")
+	}
+
+	// SMTP_FROM may carry a display name; the envelope needs the bare address
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM: %w", err)
+	}
+	header := m.config.From
+	if senderName != "" {
+		header = (&mail.Address{Name: senderName, Address: from.Address}).String()
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		header,
+		strings.Join(to, ", "),
+		subject,
+		body,
+	)
+
+	var auth smtp.Auth
+	if m.config.User != "" {
+		auth = smtp.PlainAuth("", m.config.User, m.config.Password, m.config.Host)
+	}
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	return smtp.SendMail(addr, auth, from.Address, to, []byte(msg))
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/privileges"
+	"go.uber.org/zap"
+)
+
+// PrivilegeChangeEmailer returns a notifier that emails an organization's
+// owners and admins when someone's privileges were expanded
+func PrivilegeChangeEmailer(mailer *Mailer, logger *zap.Logger) privileges.AlertFunc {
+	return func(recipient privileges.Recipient, change privileges.Change) {
+		if !mailer.Enabled() || recipient.Email == "" {
+			return
+		}
+
+		subject := "Privileges expanded in your Cyper Security organization"
+		if change.Severity == "critical" {
+			subject = "[Critical] " + subject
+		}
+		if err := mailer.Send([]string{recipient.Email}, subject, privilegeChangeBody(change)); err != nil {
+			logger.Error("Failed to email privilege change", zap.String("user_id", recipient.UserID), zap.Error(err))
+		}
+	}
+}
+
+func privilegeChangeBody(change privileges.Change) string {
+	var body strings.Builder
+
+	actor := "An unknown user"
+	if change.ActorEmail != nil {
+		actor = *change.ActorEmail
+	} else if change.ActorID != nil {
+		actor = "User " + *change.ActorID
+	}
+
+	switch change.Kind {
+	case privileges.KindRoleElevated:
+		from := change.Before.Role
+		if from == "" {
+			from = "no role"
+		}
+		fmt.Fprintf(&body, "%s changed the role of %s from %s to %s.\r\n\r\n", actor, change.SubjectName, from, change.After.Role)
+	case privileges.KindCustomRoleExpanded:
+		fmt.Fprintf(&body, "%s granted the custom role %q new permissions.\r\n\r\n", actor, change.SubjectName)
+	case privileges.KindTokenScopeExpanded:
+		fmt.Fprintf(&body, "%s widened the scopes of the API token %q.\r\n\r\n", actor, change.SubjectName)
+	}
+
+	fmt.Fprintf(&body, "Added:    %s\r\n", strings.Join(change.Added, ", "))
+	if len(change.Removed) > 0 {
+		fmt.Fprintf(&body, "Removed:  %s\r\n", strings.Join(change.Removed, ", "))
+	}
+	fmt.Fprintf(&body, "Time:     %s\r\n", change.CreatedAt.UTC().Format("2006-01-02 15:04 MST"))
+	body.WriteString("\r\nIf you did not expect this change, review your organization's members, roles and API tokens.\r\n")
+	return body.String()
+}
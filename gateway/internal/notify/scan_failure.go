@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/preferences"
+	"github.com/cyper-security/gateway/internal/scanevents"
+)
+
+// ScanFailureEmailer returns a notifier that emails the owner of a scan that
+// failed. Times are shown in the owner's preferred timezone.
+func ScanFailureEmailer(mailer *Mailer, prefs *preferences.Service) scanevents.FailureFunc {
+	return func(ctx context.Context, owner scanevents.Recipient, event scanevents.Event) error {
+		if !mailer.Enabled() || owner.Email == "" {
+			return nil
+		}
+
+		var body strings.Builder
+		body.WriteString("One of your scans failed.\r\n\r\n")
+		fmt.Fprintf(&body, "Scan:     %s\r\n", event.ScanID)
+		if event.ScanType != "" {
+			fmt.Fprintf(&body, "Type:     %s\r\n", event.ScanType)
+		}
+		loc := prefs.Lookup(ctx, owner.UserID).Location()
+		fmt.Fprintf(&body, "Time:     %s\r\n", event.OccurredAt.In(loc).Format("2006-01-02 15:04 MST"))
+		if event.ErrorMessage != "" {
+			fmt.Fprintf(&body, "Error:    %s\r\n", event.ErrorMessage)
+		}
+		body.WriteString("\r\nFindings reported before the failure are kept with the scan.\r\n")
+
+		if err := mailer.Send([]string{owner.Email}, "Your Cyper Security scan failed", body.String()); err != nil {
+			return fmt.Errorf("failed to email scan failure: %w", err)
+		}
+		return nil
+	}
+}
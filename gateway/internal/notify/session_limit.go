@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/preferences"
+	"go.uber.org/zap"
+)
+
+// SessionLimitEmailer returns a notifier that emails the user when a login
+// hit their concurrent session limit, in their preferred timezone
+func SessionLimitEmailer(mailer *Mailer, prefs *preferences.Service, logger *zap.Logger) auth.SessionLimitFunc {
+	return func(alert auth.SessionLimitAlert) {
+		if !mailer.Enabled() {
+			return
+		}
+
+		loc := prefs.Lookup(context.Background(), alert.UserID).Location()
+		if err := mailer.Send([]string{alert.Email}, "Too many active sessions on your Cyper Security account", sessionLimitBody(alert, loc)); err != nil {
+			logger.Error("Failed to email session limit alert", zap.String("user_id", alert.UserID), zap.Error(err))
+		}
+	}
+}
+
+func sessionLimitBody(alert auth.SessionLimitAlert, loc *time.Location) string {
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "Your account reached its limit of %d active sessions.\r\n\r\n", alert.MaxSessions)
+	if alert.Action == auth.SessionLimitReject {
+		body.WriteString("A new sign-in was refused:\r\n\r\n")
+	} else {
+		fmt.Fprintf(&body, "A new sign-in signed out your %d oldest session(s):\r\n\r\n", len(alert.RevokedIDs))
+	}
+
+	fmt.Fprintf(&body, "Time:     %s\r\n", alert.OccurredAt.In(loc).Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&body, "Device:   %s, %s on %s\r\n", alert.Device.Browser, alert.Device.OS, alert.Device.Device)
+	fmt.Fprintf(&body, "IP:       %s\r\n", alert.IPAddress)
+
+	if alert.Action == auth.SessionLimitReject {
+		body.WriteString("\r\nTo sign in there, sign out of a device you no longer use first.\r\n")
+	}
+	body.WriteString("If this sign-in was not you, change your password.\r\n")
+	return body.String()
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SMSSender delivers text messages to phone numbers in E.164 format
+type SMSSender interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// TwilioConfig configures SMS through Twilio's Messages API
+type TwilioConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string            // Sending number or messaging service SID (MG...)
+	Transport  http.RoundTripper // Optional, e.g. under an outbound policy
+}
+
+// Twilio sends SMS through Twilio
+type Twilio struct {
+	config     TwilioConfig
+	endpoint   string
+	httpClient *http.Client
+}
+
+func NewTwilio(config TwilioConfig) *Twilio {
+	return &Twilio{
+		config:     config,
+		endpoint:   "https://api.twilio.com/2010-04-01/Accounts/" + url.PathEscape(config.AccountSID) + "/Messages.json",
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: config.Transport},
+	}
+}
+
+func (t *Twilio) SendSMS(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if strings.HasPrefix(t.config.From, "MG") {
+		form.Set("MessagingServiceSid", t.config.From)
+	} else {
+		form.Set("From", t.config.From)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.config.AccountSID, t.config.AuthToken)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("twilio returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route declares a single REST endpoint for the specification
+type Route struct {
+	Method     string
+	Path       string // gin-style path relative to the API base, e.g. /organizations/:id
+	Summary    string
+	Tag        string
+	Public     bool        // No bearer token required
+	Service    bool        // Called by internal services with X-Service-Token instead of a bearer token
+	Permission string      // RBAC permission enforced by middleware, if any
+	Query      []string    // Query parameter names
+	Request    interface{} // Zero value of the JSON request body type
+	Response   interface{} // Zero value of the JSON response body type
+	Status     int         // Success status code, defaults to 200
+	Payload    string      // Request body limit group (see payload.Guard); empty for the defaults
+	Deprecated string      // Date (YYYY-MM-DD) from which the endpoint is deprecated, if it is
+	Sunset     string      // Date (YYYY-MM-DD) the endpoint is to be removed
+	Successor  string      // Path replacing a deprecated endpoint, version included, e.g. /v2/scans
+}
+
+// Build assembles an OpenAPI document from route declarations
+func Build(info Info, basePath string, tags []Tag, routes []Route) *Document {
+	b := &builder{schemas: make(map[string]*Schema)}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Servers: []Server{{URL: basePath}},
+		Paths:   make(map[string]PathItem),
+		Tags:    tags,
+		Components: Components{
+			SecuritySchemes: map[string]SecurityScheme{
+				"bearerAuth":   {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				"serviceToken": {Type: "apiKey", In: "header", Name: "X-Service-Token"},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		path, params := convertPath(route.Path)
+
+		op := &Operation{
+			OperationID: operationID(route.Method, route.Path),
+			Summary:     route.Summary,
+			Tags:        []string{route.Tag},
+			Parameters:  params,
+			Responses:   make(map[string]Response),
+		}
+
+		for _, name := range route.Query {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+		}
+
+		switch {
+		case route.Service:
+			op.Security = []map[string][]string{{"serviceToken": {}}}
+			op.Responses["401"] = Response{Description: "Missing or invalid service token"}
+		case !route.Public:
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+			op.Responses["401"] = Response{Description: "Missing or invalid token"}
+		}
+		if route.Permission != "" {
+			op.Description = "Requires permission `" + route.Permission + "`."
+			op.Responses["403"] = Response{Description: "Permission denied"}
+		}
+
+		if route.Deprecated != "" {
+			op.Deprecated = true
+			note := "Deprecated since " + route.Deprecated
+			if route.Sunset != "" {
+				note += "; removed after " + route.Sunset
+			}
+			if route.Successor != "" {
+				note += "; use `" + route.Successor + "`"
+			}
+			op.Description = strings.TrimSpace(op.Description + " " + note + ".")
+		}
+
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: b.schemaFor(reflect.TypeOf(route.Request))}},
+			}
+			op.Responses["400"] = Response{Description: "Invalid request"}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := Response{Description: http.StatusText(status)}
+		if route.Response != nil {
+			success.Content = map[string]MediaType{"application/json": {Schema: b.schemaFor(reflect.TypeOf(route.Response))}}
+		}
+		op.Responses[strconv.Itoa(status)] = success
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components.Schemas = b.schemas
+	return doc
+}
+
+// AddSchema registers the schema for value's type under components, returning the
+// registered schema so callers can annotate it
+func (d *Document) AddSchema(value interface{}) *Schema {
+	if d.Components.Schemas == nil {
+		d.Components.Schemas = make(map[string]*Schema)
+	}
+	b := &builder{schemas: d.Components.Schemas}
+
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	ref := b.schemaFor(t)
+	if schema, ok := d.Components.Schemas[t.Name()]; ok && ref.Ref != "" {
+		return schema
+	}
+	return ref
+}
+
+// MissingRoutes returns registered gin routes under basePath that have no declaration
+func MissingRoutes(registered gin.RoutesInfo, basePath string, routes []Route) []string {
+	declared := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		declared[route.Method+" "+basePath+route.Path] = true
+	}
+
+	var missing []string
+	for _, r := range registered {
+		if !strings.HasPrefix(r.Path, basePath+"/") {
+			continue
+		}
+		if !declared[r.Method+" "+r.Path] {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// SpecHandler serves the document as JSON
+func SpecHandler(doc *Document) gin.HandlerFunc {
+	body, err := json.MarshalIndent(doc, "", "  ")
+	return func(c *gin.Context) {
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render specification"})
+			return
+		}
+		c.Data(http.StatusOK, "application/json", body)
+	}
+}
+
+// SwaggerUIHandler serves a Swagger UI page pointed at specURL
+func SwaggerUIHandler(specURL string) gin.HandlerFunc {
+	page := strings.ReplaceAll(swaggerUIPage, "{{SPEC_URL}}", specURL)
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Cyper Gateway API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "{{SPEC_URL}}", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>`
+
+// convertPath turns /organizations/:id into /organizations/{id} plus path parameters
+func convertPath(path string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			name := seg[1:]
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID derives a stable identifier, e.g. POST /organizations/:id/invite -> postOrganizationsIdInvite
+func operationID(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	for _, seg := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' || r == ':' }) {
+		sb.WriteString(strings.ToUpper(seg[:1]) + seg[1:])
+	}
+	return sb.String()
+}
+
+type builder struct {
+	schemas map[string]*Schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor reflects a Go type into a schema, registering named structs as components
+func (b *builder) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Nullable: nullable}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: nullable}
+		}
+		return &Schema{Type: "array", Items: b.schemaFor(t.Elem()), Nullable: nullable}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaFor(t.Elem()), Nullable: nullable}
+	case reflect.Interface:
+		return &Schema{Nullable: nullable}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = &Schema{} // Placeholder guards recursive types
+			b.schemas[name] = b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	return &Schema{}
+}
+
+func (b *builder) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if tag != "" {
+			if parts := strings.Split(tag, ","); parts[0] != "" {
+				name = parts[0]
+			}
+		}
+
+		// Embedded structs without a JSON name are flattened
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			embedded := b.structSchema(field.Type)
+			for k, v := range embedded.Properties {
+				schema.Properties[k] = v
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		schema.Properties[name] = b.schemaFor(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	sort.Strings(schema.Required)
+	return schema
+}
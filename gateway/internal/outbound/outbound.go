@@ -0,0 +1,413 @@
+// Package outbound hardens the HTTP requests the gateway makes to
+// integrations whose URLs come from users or configuration: webhooks, the
+// brain service, secret stores, storage and notaries. Every request and
+// every redirect it follows is checked against a Policy: private, loopback
+// and link-local addresses are refused unless allowed, as are disallowed
+// schemes and ports. Hostnames are resolved and checked at dial time and the
+// connection is made to the checked address, so DNS rebinding cannot slip
+// past. Blocked requests are reported, for security events and metrics.
+package outbound
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Reasons a request is blocked
+const (
+	ReasonAddress   = "address"        // Resolves to a private, loopback, link-local or reserved address
+	ReasonScheme    = "scheme"         // Scheme is not allowed
+	ReasonPort      = "port"           // Port is not allowed
+	ReasonRedirects = "redirect_limit" // Too many redirects
+	ReasonDowngrade = "https_downgrade"
+)
+
+// BlockedError is returned for requests the policy refuses
+type BlockedError struct {
+	Integration    string
+	OrganizationID string // Set when the caller marked the context with one
+	URL            string // Without credentials or query
+	Reason         string
+	Detail         string
+}
+
+func (e *BlockedError) Error() string {
+	if e.URL == "" {
+		return "outbound request blocked: " + e.Detail
+	}
+	return fmt.Sprintf("outbound request to %s blocked: %s", e.URL, e.Detail)
+}
+
+// Policy decides which destinations requests may reach
+type Policy struct {
+	// AllowPrivate permits private, loopback, link-local and other
+	// non-public addresses
+	AllowPrivate bool
+	// AllowedHosts are trusted whatever they resolve to and whatever port
+	// they are reached on: hostnames, IP addresses or CIDR ranges
+	AllowedHosts []string
+	// AllowedPorts are the ports public destinations may be reached on;
+	// empty allows any
+	AllowedPorts []int
+	// RequireHTTPS refuses plain HTTP, to allowed hosts too
+	RequireHTTPS bool
+	MaxRedirects int
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		AllowedPorts: []int{80, 443, 8080, 8443},
+		MaxRedirects: 5,
+	}
+}
+
+// blockedNetworks are refused besides what net.IP's predicates catch:
+// carrier-grade NAT, IETF protocol assignments, benchmarking, reserved and
+// NAT64 ranges (the last maps onto IPv4, private ranges included)
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+	"64:ff9b:1::/48",
+)
+
+// resolver looks hostnames up at dial time, e.g. net.DefaultResolver
+type resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// Guard makes HTTP transports that enforce the policy
+type Guard struct {
+	policy   Policy
+	hosts    hostList
+	resolver resolver
+	logger   *zap.Logger
+
+	mu        sync.RWMutex
+	onBlocked func(ctx context.Context, blocked *BlockedError)
+}
+
+func NewGuard(policy Policy, logger *zap.Logger) (*Guard, error) {
+	hosts, err := parseHosts(policy.AllowedHosts)
+	if err != nil {
+		return nil, err
+	}
+	return &Guard{
+		policy:   policy,
+		hosts:    hosts,
+		resolver: net.DefaultResolver,
+		logger:   logger,
+	}, nil
+}
+
+// OnBlocked sets what else happens when a request is blocked, besides the
+// warning log and metric; e.g. recording a security event
+func (g *Guard) OnBlocked(f func(ctx context.Context, blocked *BlockedError)) {
+	g.mu.Lock()
+	g.onBlocked = f
+	g.mu.Unlock()
+}
+
+// Option adjusts a transport for one integration
+type Option func(*transport)
+
+// AllowURLs trusts the hosts of configured URLs, e.g. an internal brain
+// service or Vault address. Empty and unparsable URLs are ignored.
+func AllowURLs(urls ...string) Option {
+	return func(t *transport) {
+		for _, raw := range urls {
+			if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+				t.hosts.names[strings.ToLower(u.Hostname())] = true
+			}
+		}
+	}
+}
+
+// TLS sets the transport's TLS configuration, e.g. for mutual TLS
+func TLS(config *tls.Config) Option {
+	return func(t *transport) {
+		t.base.TLSClientConfig = config
+	}
+}
+
+// Transport returns a transport for the named integration. It connects
+// directly, ignoring proxy settings, since the addresses it dials are the
+// ones checked.
+func (g *Guard) Transport(integration string, opts ...Option) http.RoundTripper {
+	t := &transport{
+		guard:       g,
+		integration: integration,
+		hosts:       g.hosts.clone(),
+	}
+	t.dialer = net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = nil
+	base.DialContext = t.dial
+	t.base = base
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Client returns a client for the named integration
+func (g *Guard) Client(integration string, timeout time.Duration, opts ...Option) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: g.Transport(integration, opts...)}
+}
+
+type transport struct {
+	guard       *Guard
+	integration string
+	hosts       hostList
+	base        *http.Transport
+	dialer      net.Dialer
+}
+
+// RoundTrip checks the request, and through it each redirect the client
+// follows, before sending it
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if blocked := t.check(req); blocked != nil {
+		return nil, t.block(req, blocked)
+	}
+	resp, err := t.base.RoundTrip(req)
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return nil, t.block(req, blocked)
+	}
+	return resp, err
+}
+
+func (t *transport) check(req *http.Request) *BlockedError {
+	policy := t.guard.policy
+	u := req.URL
+
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && !policy.RequireHTTPS:
+	default:
+		return &BlockedError{Reason: ReasonScheme, Detail: fmt.Sprintf("scheme %q is not allowed", u.Scheme)}
+	}
+
+	// Redirected requests carry the response that redirected them
+	if r := req.Response; r != nil && r.Request != nil && r.Request.URL.Scheme == "https" && u.Scheme != "https" {
+		return &BlockedError{Reason: ReasonDowngrade, Detail: "redirect from https to " + u.Scheme}
+	}
+	redirects := 0
+	for r := req.Response; r != nil && r.Request != nil; r = r.Request.Response {
+		redirects++
+	}
+	if policy.MaxRedirects > 0 && redirects > policy.MaxRedirects {
+		return &BlockedError{Reason: ReasonRedirects, Detail: fmt.Sprintf("more than %d redirects", policy.MaxRedirects)}
+	}
+
+	if t.hosts.allows(u.Hostname()) || len(policy.AllowedPorts) == 0 {
+		return nil
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	n, _ := strconv.Atoi(port)
+	for _, allowed := range policy.AllowedPorts {
+		if n == allowed {
+			return nil
+		}
+	}
+	return &BlockedError{Reason: ReasonPort, Detail: "port " + port + " is not allowed"}
+}
+
+// dial resolves the host and connects to the first address the policy
+// allows, so the address checked is the address used
+func (t *transport) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if t.hosts.allows(host) || t.guard.policy.AllowPrivate {
+		return t.dialer.DialContext(ctx, network, addr)
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := t.guard.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	var blocked net.IP
+	var lastErr error
+	for _, ip := range ips {
+		if !Public(ip) && !t.hosts.allowsIP(ip) {
+			blocked = ip
+			continue
+		}
+		conn, err := t.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	if blocked != nil {
+		return nil, &BlockedError{Reason: ReasonAddress, Detail: host + " resolves to non-public address " + blocked.String()}
+	}
+	return nil, fmt.Errorf("no addresses for %s", host)
+}
+
+// block completes and reports a blocked request
+func (t *transport) block(req *http.Request, blocked *BlockedError) error {
+	ctx := req.Context()
+	blocked.Integration = t.integration
+	blocked.OrganizationID = organizationFrom(ctx)
+	blocked.URL = displayURL(req.URL)
+
+	metrics.OutboundBlocked.WithLabelValues(t.integration, blocked.Reason).Inc()
+	t.guard.logger.Warn("Blocked outbound request",
+		zap.String("integration", t.integration),
+		zap.String("organization_id", blocked.OrganizationID),
+		zap.String("url", blocked.URL),
+		zap.String("reason", blocked.Reason),
+		zap.String("detail", blocked.Detail),
+	)
+
+	t.guard.mu.RLock()
+	onBlocked := t.guard.onBlocked
+	t.guard.mu.RUnlock()
+	if onBlocked != nil {
+		onBlocked(ctx, blocked)
+	}
+	return blocked
+}
+
+// Public reports whether ip is a public unicast address
+func Public(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || ip.Equal(net.IPv4bcast) {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+type organizationKey struct{}
+
+// WithOrganization marks requests made with ctx as the organization's, so
+// blocked ones are attributed to it
+func WithOrganization(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, organizationKey{}, orgID)
+}
+
+func organizationFrom(ctx context.Context) string {
+	orgID, _ := ctx.Value(organizationKey{}).(string)
+	return orgID
+}
+
+// displayURL drops credentials and the query, which may carry tokens
+func displayURL(u *url.URL) string {
+	shown := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	return shown.String()
+}
+
+// hostList is a set of trusted hostnames and networks
+type hostList struct {
+	names    map[string]bool
+	networks []*net.IPNet
+}
+
+func parseHosts(entries []string) (hostList, error) {
+	hosts := hostList{names: map[string]bool{}}
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return hosts, fmt.Errorf("invalid allowed host %q: %w", entry, err)
+			}
+			hosts.networks = append(hosts.networks, network)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			hosts.networks = append(hosts.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			hosts.names[entry] = true
+		}
+	}
+	return hosts, nil
+}
+
+func (h hostList) clone() hostList {
+	names := make(map[string]bool, len(h.names))
+	for name := range h.names {
+		names[name] = true
+	}
+	return hostList{names: names, networks: h.networks}
+}
+
+func (h hostList) allows(host string) bool {
+	if h.names[strings.ToLower(strings.TrimSuffix(host, "."))] {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return h.allowsIP(ip)
+	}
+	return false
+}
+
+func (h hostList) allowsIP(ip net.IP) bool {
+	for _, network := range h.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
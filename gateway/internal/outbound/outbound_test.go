@@ -0,0 +1,223 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		// Loopback
+		{"127.0.0.1", false},
+		{"127.255.0.9", false},
+		{"::1", false},
+		// RFC 1918
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"172.31.255.255", false},
+		{"192.168.1.1", false},
+		// Link-local, cloud metadata included
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		// IPv6 unique local
+		{"fc00::1", false},
+		{"fd12:3456::1", false},
+		// IPv4-mapped IPv6
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		// NAT64 onto a private address, CGNAT, reserved
+		{"64:ff9b::a00:1", false},
+		{"100.64.0.1", false},
+		{"198.18.0.1", false},
+		{"240.0.0.1", false},
+		// Unspecified, broadcast, multicast
+		{"0.0.0.0", false},
+		{"::", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		// Public
+		{"8.8.8.8", true},
+		{"172.32.0.1", true},
+		{"2606:4700:4700::1111", true},
+		{"::ffff:8.8.8.8", true},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if ip == nil {
+			t.Fatalf("invalid test address %s", tt.ip)
+		}
+		if got := Public(ip); got != tt.want {
+			t.Errorf("Public(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+// fakeResolver answers lookups from a fixed table
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	answers, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	addrs := make([]net.IPAddr, 0, len(answers))
+	for _, a := range answers {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(a)})
+	}
+	return addrs, nil
+}
+
+// newTestGuard returns a guard resolving names through dns that records
+// what it blocks
+func newTestGuard(t *testing.T, policy Policy, dns fakeResolver) (*Guard, *[]*BlockedError) {
+	t.Helper()
+	guard, err := NewGuard(policy, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.resolver = dns
+	var blocked []*BlockedError
+	guard.OnBlocked(func(_ context.Context, b *BlockedError) {
+		blocked = append(blocked, b)
+	})
+	return guard, &blocked
+}
+
+func TestTransportBlocksNonPublicAddresses(t *testing.T) {
+	dns := fakeResolver{
+		"metadata.example":    {"169.254.169.254"},
+		"intranet.example":    {"10.1.2.3", "fd00::5"},
+		"loopback.example":    {"127.0.0.1"},
+		"mapped.example":      {"::ffff:192.168.0.10"},
+		"ula.example":         {"fd12:3456::1"},
+		"nat64.example":       {"64:ff9b::7f00:1"},
+		"unspecified.example": {"0.0.0.0"},
+	}
+	guard, blocked := newTestGuard(t, DefaultPolicy(), dns)
+	client := guard.Client("test", 5*time.Second)
+
+	urls := []string{
+		// Literal addresses
+		"http://127.0.0.1/",
+		"http://10.0.0.1:8080/",
+		"http://172.16.0.1/",
+		"http://192.168.1.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"http://[fe80::1]/",
+		"http://[fd00::1]/",
+		"http://[::ffff:10.0.0.1]/",
+		"http://[::ffff:127.0.0.1]/",
+		// Names that resolve to non-public addresses at dial time
+		"http://metadata.example/latest/meta-data/",
+		"http://intranet.example/",
+		"http://loopback.example/",
+		"http://mapped.example/",
+		"http://ula.example/",
+		"http://nat64.example/",
+		"http://unspecified.example/",
+	}
+	for _, raw := range urls {
+		*blocked = nil
+		resp, err := client.Get(raw)
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("GET %s succeeded, want it blocked", raw)
+			continue
+		}
+		var blockedErr *BlockedError
+		if !errors.As(err, &blockedErr) || blockedErr.Reason != ReasonAddress {
+			t.Errorf("GET %s = %v, want an address block", raw, err)
+			continue
+		}
+		if len(*blocked) != 1 || (*blocked)[0].Integration != "test" {
+			t.Errorf("GET %s reported %d blocks, want 1 for the integration", raw, len(*blocked))
+		}
+	}
+}
+
+func TestDialUsesTheCheckedAddress(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := u.Port()
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		dns     fakeResolver
+		url     string
+		allowed bool
+	}{
+		{
+			name:   "name resolving to loopback",
+			policy: Policy{},
+			dns:    fakeResolver{"svc.example": {"127.0.0.1"}},
+			url:    "http://svc.example:" + port + "/",
+		},
+		{
+			name:    "name resolving to an allowed network",
+			policy:  Policy{AllowedHosts: []string{"127.0.0.0/8"}},
+			dns:     fakeResolver{"svc.example": {"127.0.0.1"}},
+			url:     "http://svc.example:" + port + "/",
+			allowed: true,
+		},
+		{
+			// Allowed networks that match no answer do not open the others
+			name:   "answers outside the allowed networks",
+			policy: Policy{AllowedHosts: []string{"192.0.2.0/24"}},
+			dns:    fakeResolver{"svc.example": {"127.0.0.1", "10.0.0.1"}},
+			url:    "http://svc.example:" + port + "/",
+		},
+		{
+			name:    "private addresses allowed",
+			policy:  Policy{AllowPrivate: true},
+			url:     server.URL + "/",
+			allowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits.Store(0)
+			guard, _ := newTestGuard(t, tt.policy, tt.dns)
+			resp, err := guard.Client("test", 5*time.Second).Get(tt.url)
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if tt.allowed {
+				if err != nil || hits.Load() != 1 {
+					t.Fatalf("GET = %v with %d hits, want it to reach the server", err, hits.Load())
+				}
+				return
+			}
+			var blockedErr *BlockedError
+			if !errors.As(err, &blockedErr) || blockedErr.Reason != ReasonAddress {
+				t.Fatalf("GET = %v, want an address block", err)
+			}
+			if hits.Load() != 0 {
+				t.Fatalf("blocked request reached the server")
+			}
+		})
+	}
+}
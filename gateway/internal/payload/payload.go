@@ -0,0 +1,199 @@
+// Package payload guards handlers against abusive request bodies. Every
+// request body is capped in size, and JSON bodies are checked for nesting
+// depth and array length before any handler decodes them, so a small but
+// deeply nested or very wide document cannot exhaust the decoder.
+//
+// Limits are set per route group: routes that legitimately take large
+// bodies (report generation with scan results, file uploads) are assigned
+// to a group with its own limits and everything else gets the defaults.
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Rejection codes, returned in the response body and recorded in metrics
+const (
+	CodeBodyTooLarge     = "body_too_large"
+	CodeJSONTooDeep      = "json_too_deep"
+	CodeJSONArrayTooLong = "json_array_too_long"
+)
+
+// Limits bound one route group's request bodies
+type Limits struct {
+	MaxBytes int64
+	// MaxDepth bounds the nesting of JSON objects and arrays
+	MaxDepth int
+	// MaxArrayLength bounds the elements of any one JSON array
+	MaxArrayLength int
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxBytes:       1 << 20,
+		MaxDepth:       32,
+		MaxArrayLength: 10000,
+	}
+}
+
+// Error is a body over its limits
+type Error struct {
+	Code  string
+	Limit int64
+}
+
+func (e *Error) Error() string {
+	switch e.Code {
+	case CodeBodyTooLarge:
+		return fmt.Sprintf("request body exceeds %d bytes", e.Limit)
+	case CodeJSONTooDeep:
+		return fmt.Sprintf("JSON nesting exceeds depth %d", e.Limit)
+	default:
+		return fmt.Sprintf("JSON array exceeds %d elements", e.Limit)
+	}
+}
+
+// Status is the response status for the error: 413 for size, 422 for shape
+func (e *Error) Status() int {
+	if e.Code == CodeBodyTooLarge {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// CheckJSON walks data and reports the first limit it breaks. Malformed JSON
+// passes, so handlers report syntax errors as they always have.
+func CheckJSON(data []byte, limits Limits) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Element counts of the open arrays, -1 for objects
+	var open []int
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		delim, isDelim := tok.(json.Delim)
+		if isDelim && (delim == ']' || delim == '}') {
+			open = open[:len(open)-1]
+			continue
+		}
+		// Any other token is an element of an enclosing array, or a key or
+		// value of an enclosing object
+		if n := len(open); n > 0 && open[n-1] >= 0 {
+			open[n-1]++
+			if limits.MaxArrayLength > 0 && open[n-1] > limits.MaxArrayLength {
+				return &Error{Code: CodeJSONArrayTooLong, Limit: int64(limits.MaxArrayLength)}
+			}
+		}
+		if isDelim {
+			count := -1
+			if delim == '[' {
+				count = 0
+			}
+			open = append(open, count)
+			if limits.MaxDepth > 0 && len(open) > limits.MaxDepth {
+				return &Error{Code: CodeJSONTooDeep, Limit: int64(limits.MaxDepth)}
+			}
+		}
+	}
+}
+
+// Guard applies each route's group limits
+type Guard struct {
+	defaults Limits
+	groups   map[string]Limits
+	routes   map[string]string // "METHOD /full/path" to group
+}
+
+// NewGuard applies defaults to routes outside a group. routes maps
+// "METHOD /full/path", as gin reports it, to a group name.
+func NewGuard(defaults Limits, routes map[string]string) *Guard {
+	return &Guard{
+		defaults: defaults,
+		groups:   make(map[string]Limits),
+		routes:   routes,
+	}
+}
+
+// SetGroup sets the limits of a route group
+func (g *Guard) SetGroup(name string, limits Limits) {
+	g.groups[name] = limits
+}
+
+// limits resolves the route's group and its limits
+func (g *Guard) limits(method, fullPath string) (string, Limits) {
+	group := g.routes[method+" "+fullPath]
+	if limits, ok := g.groups[group]; ok && group != "" {
+		return group, limits
+	}
+	return "default", g.defaults
+}
+
+// Middleware caps the body and, unless it is a form, buffers and checks it
+// as JSON. It must run on the router, before any handler reads the body.
+func (g *Guard) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		group, limits := g.limits(c.Request.Method, c.FullPath())
+
+		if limits.MaxBytes > 0 && c.Request.ContentLength > limits.MaxBytes {
+			reject(c, group, &Error{Code: CodeBodyTooLarge, Limit: limits.MaxBytes})
+			return
+		}
+		if limits.MaxBytes > 0 {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBytes)
+		}
+		if isForm(c.Request.Header.Get("Content-Type")) {
+			c.Next()
+			return
+		}
+
+		// Handlers bind JSON whatever the declared content type, so every
+		// other body is checked
+		data, err := io.ReadAll(c.Request.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			reject(c, group, &Error{Code: CodeBodyTooLarge, Limit: limits.MaxBytes})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		if err := CheckJSON(data, limits); err != nil {
+			reject(c, group, err.(*Error))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		c.Next()
+	}
+}
+
+func reject(c *gin.Context, group string, err *Error) {
+	metrics.PayloadRejections.WithLabelValues(group, err.Code).Inc()
+	c.AbortWithStatusJSON(err.Status(), gin.H{
+		"error": err.Error(),
+		"code":  err.Code,
+		"limit": err.Limit,
+	})
+}
+
+// isForm reports whether the content type is a multipart or URL-encoded form
+func isForm(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "multipart/") || mediaType == "application/x-www-form-urlencoded"
+}
@@ -0,0 +1,167 @@
+package payload
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckJSON(t *testing.T) {
+	limits := Limits{MaxDepth: 3, MaxArrayLength: 3}
+
+	tests := []struct {
+		name string
+		data string
+		code string // Empty when the body passes
+	}{
+		{"scalar", `42`, ""},
+		{"at max depth", `[[[1]]]`, ""},
+		{"over max depth", `[[[[1]]]]`, CodeJSONTooDeep},
+		{"objects at max depth", `{"a":{"b":{"c":1}}}`, ""},
+		{"objects over max depth", `{"a":{"b":{"c":{"d":1}}}}`, CodeJSONTooDeep},
+		{"arrays in objects at max depth", `{"a":[{"b":1}]}`, ""},
+		{"arrays in objects over max depth", `{"a":[{"b":[1]}]}`, CodeJSONTooDeep},
+		{"depth is of the deepest branch", `{"a":[1],"b":{"c":[[1]]}}`, CodeJSONTooDeep},
+		{"siblings do not add up", `[[1],[2],[3]]`, ""},
+		{"array at max length", `[1,2,3]`, ""},
+		{"array over max length", `[1,2,3,4]`, CodeJSONArrayTooLong},
+		{"nested array over max length", `{"a":[[1,2,3,4]]}`, CodeJSONArrayTooLong},
+		{"containers count as one element", `[{"a":1,"b":2},[1,2,3],"x"]`, ""},
+		{"object keys are not array elements", `{"a":1,"b":2,"c":3,"d":4}`, ""},
+		{"malformed", `{"a":`, ""},
+		{"not JSON", `name=alice`, ""},
+		{"empty", ``, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckJSON([]byte(tt.data), limits)
+			if tt.code == "" {
+				if err != nil {
+					t.Errorf("CheckJSON(%s) = %v, want nil", tt.data, err)
+				}
+				return
+			}
+			perr, ok := err.(*Error)
+			if !ok || perr.Code != tt.code {
+				t.Errorf("CheckJSON(%s) = %v, want %s", tt.data, err, tt.code)
+			}
+		})
+	}
+}
+
+func TestCheckJSONWithoutLimits(t *testing.T) {
+	data := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+	if err := CheckJSON([]byte(data), Limits{}); err != nil {
+		t.Errorf("CheckJSON with zero limits = %v, want nil", err)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := map[string]int{
+		CodeBodyTooLarge:     http.StatusRequestEntityTooLarge,
+		CodeJSONTooDeep:      http.StatusUnprocessableEntity,
+		CodeJSONArrayTooLong: http.StatusUnprocessableEntity,
+	}
+	for code, want := range tests {
+		if got := (&Error{Code: code}).Status(); got != want {
+			t.Errorf("Status(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	guard := NewGuard(Limits{MaxBytes: 64, MaxDepth: 3, MaxArrayLength: 3}, map[string]string{
+		"POST /reports": "reports",
+	})
+	guard.SetGroup("reports", Limits{MaxBytes: 1024, MaxDepth: 8, MaxArrayLength: 100})
+
+	router := gin.New()
+	router.Use(guard.Middleware())
+	// Echoes the body, to check handlers still read it whole
+	echo := func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(data))
+	}
+	router.POST("/items", echo)
+	router.POST("/reports", echo)
+	router.GET("/items", echo)
+	return router
+}
+
+func TestMiddleware(t *testing.T) {
+	router := newTestRouter()
+	deep := `{"a":[{"b":[1]}]}`
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        io.Reader
+		status      int
+		code        string
+	}{
+		{"within limits", http.MethodPost, "/items", "application/json", strings.NewReader(`{"a":[1,2]}`), http.StatusOK, ""},
+		{"no body", http.MethodGet, "/items", "", nil, http.StatusOK, ""},
+		{"too deep", http.MethodPost, "/items", "application/json", strings.NewReader(deep), http.StatusUnprocessableEntity, CodeJSONTooDeep},
+		{"array too long", http.MethodPost, "/items", "application/json", strings.NewReader(`[1,2,3,4]`), http.StatusUnprocessableEntity, CodeJSONArrayTooLong},
+		{"declared length too large", http.MethodPost, "/items", "application/json", strings.NewReader(`"` + strings.Repeat("x", 100) + `"`), http.StatusRequestEntityTooLarge, CodeBodyTooLarge},
+		// No Content-Length, as with chunked bodies: the read is capped instead
+		{"read too large", http.MethodPost, "/items", "application/json", io.MultiReader(strings.NewReader(`"` + strings.Repeat("x", 100) + `"`)), http.StatusRequestEntityTooLarge, CodeBodyTooLarge},
+		{"checked whatever the content type", http.MethodPost, "/items", "text/plain", strings.NewReader(deep), http.StatusUnprocessableEntity, CodeJSONTooDeep},
+		{"malformed JSON reaches the handler", http.MethodPost, "/items", "application/json", strings.NewReader(`{"a":`), http.StatusOK, ""},
+		{"url-encoded forms skip the check", http.MethodPost, "/items", "application/x-www-form-urlencoded", strings.NewReader(`q=[[[[[[1]]]]]]`), http.StatusOK, ""},
+		{"multipart forms skip the check", http.MethodPost, "/items", "multipart/form-data; boundary=x", strings.NewReader(`[[[[[[1]]]]]]`), http.StatusOK, ""},
+		{"forms are still capped", http.MethodPost, "/items", "application/x-www-form-urlencoded", strings.NewReader("q=" + strings.Repeat("x", 100)), http.StatusRequestEntityTooLarge, CodeBodyTooLarge},
+		{"route group limits", http.MethodPost, "/reports", "application/json", strings.NewReader(deep), http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, tt.body)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code == "" {
+				return
+			}
+			var resp struct {
+				Code  string `json:"code"`
+				Limit int64  `json:"limit"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding %s: %v", rec.Body, err)
+			}
+			if resp.Code != tt.code || resp.Limit == 0 {
+				t.Errorf("response = %+v, want code %s and its limit", resp, tt.code)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRestoresBody(t *testing.T) {
+	body := `{"name":"scan","targets":["a","b"]}`
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != body {
+		t.Errorf("handler read %d %q, want 200 %q", rec.Code, rec.Body, body)
+	}
+}
@@ -0,0 +1,398 @@
+// Package preferences stores per-user settings under typed keys: timezone,
+// locale, default organization, notification digest frequency and dashboard
+// layout. Every key validates its values before they are saved; reads are
+// cached in Redis. The timezone and locale follow the user into translated
+// API errors, generated reports and notification emails.
+package preferences
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/i18n"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Preference keys
+const (
+	KeyTimezone            = "timezone"
+	KeyLocale              = "locale"
+	KeyDefaultOrganization = "default_organization_id"
+	KeyNotificationDigest  = "notification_digest"
+	KeyDashboardLayout     = "dashboard_layout"
+)
+
+// Notification digest frequencies
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+const (
+	// DefaultCacheTTL is how long preferences stay cached after a read
+	DefaultCacheTTL = 10 * time.Minute
+	cachePrefix     = "preferences:"
+	// maxLayoutBytes bounds the dashboard layout, which is stored as given
+	maxLayoutBytes = 64 << 10
+)
+
+// Preferences are a user's settings, defaults filled in for unset keys
+type Preferences struct {
+	Timezone              string                 `json:"timezone"`
+	Locale                *string                `json:"locale"` // null follows Accept-Language
+	DefaultOrganizationID *string                `json:"default_organization_id"`
+	NotificationDigest    string                 `json:"notification_digest"`
+	DashboardLayout       map[string]interface{} `json:"dashboard_layout"`
+	UpdatedAt             *time.Time             `json:"updated_at,omitempty"`
+}
+
+// Defaults are the preferences of a user who has set none
+func Defaults() Preferences {
+	return Preferences{
+		Timezone:           "UTC",
+		NotificationDigest: DigestOff,
+	}
+}
+
+// Location is the preferred timezone
+func (p Preferences) Location() *time.Location {
+	if loc, err := time.LoadLocation(p.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// LocaleOr is the preferred locale, or fallback when none is set
+func (p Preferences) LocaleOr(fallback string) string {
+	if p.Locale != nil {
+		return *p.Locale
+	}
+	return fallback
+}
+
+// ValidationError lists the keys of an update that were rejected
+type ValidationError struct {
+	Fields []i18n.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		names[i] = field.Field
+	}
+	return "invalid preferences: " + strings.Join(names, ", ")
+}
+
+// key decodes and validates a value for one key, returning its stored form,
+// and applies a stored value to Preferences
+type key struct {
+	validate func(ctx context.Context, s *Service, userID string, raw json.RawMessage) (json.RawMessage, *i18n.FieldError)
+	apply    func(p *Preferences, raw json.RawMessage) error
+}
+
+var keys = map[string]key{
+	KeyTimezone: {
+		validate: func(_ context.Context, _ *Service, _ string, raw json.RawMessage) (json.RawMessage, *i18n.FieldError) {
+			var name string
+			if json.Unmarshal(raw, &name) != nil {
+				return nil, fieldError(KeyTimezone, "type", "must be a string")
+			}
+			if _, err := time.LoadLocation(name); err != nil || name == "" || strings.EqualFold(name, "local") {
+				return nil, fieldError(KeyTimezone, "timezone", "must be an IANA timezone such as Europe/Paris")
+			}
+			return mustJSON(name), nil
+		},
+		apply: func(p *Preferences, raw json.RawMessage) error { return json.Unmarshal(raw, &p.Timezone) },
+	},
+	KeyLocale: {
+		validate: func(_ context.Context, s *Service, _ string, raw json.RawMessage) (json.RawMessage, *i18n.FieldError) {
+			var locale string
+			if json.Unmarshal(raw, &locale) != nil {
+				return nil, fieldError(KeyLocale, "type", "must be a string")
+			}
+			if s.locales != nil && !s.locales.Supported(locale) {
+				return nil, fieldError(KeyLocale, "oneof", "must be one of "+strings.Join(s.locales.Locales(), ", "))
+			}
+			return mustJSON(i18n.Normalize(locale)), nil
+		},
+		apply: func(p *Preferences, raw json.RawMessage) error { return json.Unmarshal(raw, &p.Locale) },
+	},
+	KeyDefaultOrganization: {
+		validate: func(ctx context.Context, s *Service, userID string, raw json.RawMessage) (json.RawMessage, *i18n.FieldError) {
+			var orgID string
+			if json.Unmarshal(raw, &orgID) != nil {
+				return nil, fieldError(KeyDefaultOrganization, "type", "must be a string")
+			}
+			if _, err := s.orgs.MemberRole(ctx, userID, orgID); err != nil {
+				// Not found and malformed IDs alike: only the user's organizations qualify
+				return nil, fieldError(KeyDefaultOrganization, "member", "must be an organization you belong to")
+			}
+			return mustJSON(orgID), nil
+		},
+		apply: func(p *Preferences, raw json.RawMessage) error { return json.Unmarshal(raw, &p.DefaultOrganizationID) },
+	},
+	KeyNotificationDigest: {
+		validate: func(_ context.Context, _ *Service, _ string, raw json.RawMessage) (json.RawMessage, *i18n.FieldError) {
+			var frequency string
+			if json.Unmarshal(raw, &frequency) != nil {
+				return nil, fieldError(KeyNotificationDigest, "type", "must be a string")
+			}
+			switch frequency {
+			case DigestOff, DigestDaily, DigestWeekly:
+				return mustJSON(frequency), nil
+			}
+			return nil, fieldError(KeyNotificationDigest, "oneof", "must be off, daily or weekly")
+		},
+		apply: func(p *Preferences, raw json.RawMessage) error { return json.Unmarshal(raw, &p.NotificationDigest) },
+	},
+	KeyDashboardLayout: {
+		validate: func(_ context.Context, _ *Service, _ string, raw json.RawMessage) (json.RawMessage, *i18n.FieldError) {
+			if len(raw) > maxLayoutBytes {
+				return nil, fieldError(KeyDashboardLayout, "max", fmt.Sprintf("must be at most %d bytes", maxLayoutBytes))
+			}
+			var layout map[string]interface{}
+			if json.Unmarshal(raw, &layout) != nil || layout == nil {
+				return nil, fieldError(KeyDashboardLayout, "json_object", "must be a JSON object")
+			}
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err != nil {
+				return nil, fieldError(KeyDashboardLayout, "json_object", "must be a JSON object")
+			}
+			return compact.Bytes(), nil
+		},
+		apply: func(p *Preferences, raw json.RawMessage) error { return json.Unmarshal(raw, &p.DashboardLayout) },
+	},
+}
+
+// Keys lists the preference keys
+func Keys() []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales is what locale preferences are checked against
+type Locales interface {
+	Supported(locale string) bool
+	Locales() []string
+}
+
+// Service reads and updates preferences
+type Service struct {
+	db       *database.DB
+	orgs     repository.OrgRepo
+	locales  Locales
+	cache    *redis.Client
+	cacheTTL time.Duration
+	logger   *zap.Logger
+}
+
+func NewService(db *database.DB, locales Locales, logger *zap.Logger) *Service {
+	return &Service{
+		db:      db,
+		orgs:    repository.NewOrgRepo(db),
+		locales: locales,
+		logger:  logger,
+	}
+}
+
+// SetCache caches each user's preferences in Redis for ttl after a read
+func (s *Service) SetCache(client *redis.Client, ttl time.Duration) {
+	s.cache = client
+	s.cacheTTL = ttl
+}
+
+// Get returns the user's preferences
+func (s *Service) Get(ctx context.Context, userID string) (Preferences, error) {
+	if s.cache != nil {
+		if data, err := s.cache.Get(ctx, cachePrefix+userID).Bytes(); err == nil {
+			var prefs Preferences
+			if json.Unmarshal(data, &prefs) == nil {
+				return prefs, nil
+			}
+		}
+	}
+
+	var rows []struct {
+		Key       string          `db:"key"`
+		Value     json.RawMessage `db:"value"`
+		UpdatedAt time.Time       `db:"updated_at"`
+	}
+	err := s.db.Reader().SelectContext(ctx, &rows, `
+		SELECT key, value, updated_at FROM user_preferences WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return Preferences{}, fmt.Errorf("failed to load preferences: %w", err)
+	}
+
+	prefs := Defaults()
+	for _, row := range rows {
+		k, ok := keys[row.Key]
+		if !ok {
+			continue
+		}
+		if err := k.apply(&prefs, row.Value); err != nil {
+			// A value saved by an older release; the default stands
+			logging.FromContext(ctx, s.logger).Warn("Ignoring unreadable preference",
+				zap.String("user_id", userID),
+				zap.String("key", row.Key),
+				zap.Error(err),
+			)
+			continue
+		}
+		if prefs.UpdatedAt == nil || row.UpdatedAt.After(*prefs.UpdatedAt) {
+			updatedAt := row.UpdatedAt
+			prefs.UpdatedAt = &updatedAt
+		}
+	}
+
+	if s.cache != nil {
+		if data, err := json.Marshal(prefs); err == nil {
+			if err := s.cache.Set(ctx, cachePrefix+userID, data, s.cacheTTL).Err(); err != nil {
+				logging.FromContext(ctx, s.logger).Warn("Failed to cache preferences", zap.Error(err))
+			}
+		}
+	}
+	return prefs, nil
+}
+
+// Update applies a partial update: each key present is set, or reset to its
+// default when null. Nothing is saved unless every key validates; a
+// *ValidationError lists the ones that did not.
+func (s *Service) Update(ctx context.Context, userID string, changes map[string]json.RawMessage) (Preferences, error) {
+	values := make(map[string]json.RawMessage, len(changes))
+	var invalid []i18n.FieldError
+	for _, name := range sortedKeys(changes) {
+		raw := changes[name]
+		k, ok := keys[name]
+		if !ok {
+			invalid = append(invalid, *fieldError(name, "unknown", "is not a preference"))
+			continue
+		}
+		if isNull(raw) {
+			values[name] = nil
+			continue
+		}
+		value, fieldErr := k.validate(ctx, s, userID, raw)
+		if fieldErr != nil {
+			invalid = append(invalid, *fieldErr)
+			continue
+		}
+		values[name] = value
+	}
+	if len(invalid) > 0 {
+		return Preferences{}, &ValidationError{Fields: invalid}
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return Preferences{}, fmt.Errorf("failed to update preferences: %w", err)
+	}
+	defer tx.Rollback()
+	for _, name := range sortedKeys(values) {
+		if values[name] == nil {
+			_, err = tx.ExecContext(ctx, `DELETE FROM user_preferences WHERE user_id = $1 AND key = $2`, userID, name)
+		} else {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO user_preferences (user_id, key, value) VALUES ($1, $2, $3)
+				ON CONFLICT (user_id, key) DO UPDATE SET value = $3, updated_at = CURRENT_TIMESTAMP
+			`, userID, name, []byte(values[name]))
+		}
+		if err != nil {
+			return Preferences{}, fmt.Errorf("failed to update preferences: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return Preferences{}, fmt.Errorf("failed to update preferences: %w", err)
+	}
+
+	s.invalidate(ctx, userID)
+	return s.Get(ctx, userID)
+}
+
+// Locale returns the user's locale, or "" when they have none. It is the
+// i18n.UserLocaleFunc of the translator.
+func (s *Service) Locale(ctx context.Context, userID string) string {
+	prefs, err := s.Get(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to load user locale", zap.Error(err))
+		return ""
+	}
+	return prefs.LocaleOr("")
+}
+
+// DefaultOrganization returns the organization the user signs in to, or ""
+// for their home organization
+func (s *Service) DefaultOrganization(ctx context.Context, userID string) string {
+	prefs, err := s.Get(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to load default organization", zap.Error(err))
+		return ""
+	}
+	if prefs.DefaultOrganizationID == nil {
+		return ""
+	}
+	return *prefs.DefaultOrganizationID
+}
+
+// Lookup returns the user's preferences, or the defaults when they cannot be
+// read: notifications and reports go out regardless
+func (s *Service) Lookup(ctx context.Context, userID string) Preferences {
+	if s == nil || userID == "" {
+		return Defaults()
+	}
+	prefs, err := s.Get(ctx, userID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to load preferences, using defaults",
+			zap.String("user_id", userID),
+			zap.Error(err),
+		)
+		return Defaults()
+	}
+	return prefs
+}
+
+func (s *Service) invalidate(ctx context.Context, userID string) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Del(ctx, cachePrefix+userID).Err(); err != nil && !errors.Is(err, redis.Nil) {
+		logging.FromContext(ctx, s.logger).Warn("Failed to invalidate cached preferences", zap.Error(err))
+	}
+}
+
+func fieldError(name, rule, message string) *i18n.FieldError {
+	return &i18n.FieldError{Field: name, Rule: rule, Message: name + " " + message}
+}
+
+func mustJSON(v interface{}) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(bytes.TrimSpace(raw)) == "null"
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
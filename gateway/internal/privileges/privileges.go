@@ -0,0 +1,405 @@
+// Package privileges records changes that give someone more power in an
+// organization: a member's role elevated, a custom role granted more
+// permissions, an API token's scopes widened. Each change is kept with its
+// before and after state, written to the audit log as a security event and
+// sent to the organization's owners and admins.
+package privileges
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Kinds of privilege change
+const (
+	KindRoleElevated       = "role_elevated"
+	KindCustomRoleExpanded = "custom_role_expanded"
+	KindTokenScopeExpanded = "api_token_scope_expanded"
+)
+
+// Grant is what the subject of a change could do before or after it
+type Grant struct {
+	Role        string           `json:"role,omitempty"`
+	Permissions []string         `json:"permissions,omitempty"`
+	Scopes      rbac.TokenScopes `json:"scopes,omitempty"`
+}
+
+// Value stores a grant as JSONB
+func (g Grant) Value() (driver.Value, error) {
+	return json.Marshal(g)
+}
+
+// Scan loads a grant from JSONB
+func (g *Grant) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*g = Grant{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, g)
+	case string:
+		return json.Unmarshal([]byte(v), g)
+	default:
+		return fmt.Errorf("unsupported grant type %T", src)
+	}
+}
+
+// Change is one recorded privilege change
+type Change struct {
+	ID             string         `json:"id" db:"id"`
+	OrganizationID string         `json:"organization_id" db:"organization_id"`
+	ActorID        *string        `json:"actor_id,omitempty" db:"actor_id"`
+	ActorEmail     *string        `json:"actor_email,omitempty" db:"actor_email"`
+	Kind           string         `json:"kind" db:"kind"`
+	SubjectType    string         `json:"subject_type" db:"subject_type"` // user, organization_role or org_api_token
+	SubjectID      string         `json:"subject_id" db:"subject_id"`
+	SubjectName    string         `json:"subject_name" db:"subject_name"`
+	Before         Grant          `json:"before" db:"before"`
+	After          Grant          `json:"after" db:"after"`
+	Added          pq.StringArray `json:"added" db:"added"`
+	Removed        pq.StringArray `json:"removed" db:"removed"`
+	Severity       string         `json:"severity" db:"severity"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+}
+
+// Recipient is an owner or admin told about a change
+type Recipient struct {
+	UserID string `db:"user_id"`
+	Email  string `db:"email"`
+}
+
+// AlertFunc delivers a change to one recipient (e.g. WebSocket, email)
+type AlertFunc func(recipient Recipient, change Change)
+
+// Filter selects recorded changes of an organization
+type Filter struct {
+	OrganizationID string
+	Kind           string
+	SubjectID      string
+	Start          *time.Time
+	End            *time.Time
+	Limit          int
+}
+
+type Service struct {
+	db          *database.DB
+	roles       *rbac.RoleStore
+	auditLogger *audit.AuditLogger
+	notifiers   []AlertFunc
+	logger      *zap.Logger
+}
+
+func NewService(db *database.DB, roles *rbac.RoleStore, auditLogger *audit.AuditLogger, logger *zap.Logger) *Service {
+	return &Service{
+		db:          db,
+		roles:       roles,
+		auditLogger: auditLogger,
+		logger:      logger,
+	}
+}
+
+// AddNotifier registers a delivery channel for privilege change alerts
+func (s *Service) AddNotifier(fn AlertFunc) {
+	s.notifiers = append(s.notifiers, fn)
+}
+
+// RoleChanged records a member's role change when the new role grants
+// permissions the old one did not. A new member (from is empty) only counts
+// when their role can manage the organization, so everyday invitations stay
+// out of the alerts. Returns nil when nothing was elevated.
+func (s *Service) RoleChanged(ctx context.Context, actorID, orgID, userID string, from, to rbac.Role) (*Change, error) {
+	if from == to {
+		return nil, nil
+	}
+	before, err := s.permissions(ctx, orgID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.permissions(ctx, orgID, to)
+	if err != nil {
+		return nil, err
+	}
+	added, removed := diff(before, after)
+	if len(added) == 0 || (from == "" && !contains(after, string(rbac.PermManageOrganization))) {
+		return nil, nil
+	}
+
+	var email string
+	if err := s.db.GetContext(ctx, &email, `SELECT email FROM users WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to look up member: %w", err)
+	}
+
+	change := &Change{
+		OrganizationID: orgID,
+		Kind:           KindRoleElevated,
+		SubjectType:    "user",
+		SubjectID:      userID,
+		SubjectName:    email,
+		Before:         Grant{Role: string(from), Permissions: before},
+		After:          Grant{Role: string(to), Permissions: after},
+		Added:          added,
+		Removed:        removed,
+	}
+	if to == rbac.RoleOwner {
+		change.Severity = "critical"
+	}
+	return change, s.record(ctx, actorID, change)
+}
+
+// CustomRoleChanged records an update to a custom role that granted it
+// permissions it did not have. Returns nil when nothing was added.
+func (s *Service) CustomRoleChanged(ctx context.Context, actorID string, before, after *rbac.CustomRole) (*Change, error) {
+	added, removed := diff(before.Permissions, after.Permissions)
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	change := &Change{
+		OrganizationID: after.OrganizationID,
+		Kind:           KindCustomRoleExpanded,
+		SubjectType:    "organization_role",
+		SubjectID:      after.ID,
+		SubjectName:    after.Name,
+		Before:         Grant{Role: before.Name, Permissions: sorted(before.Permissions)},
+		After:          Grant{Role: after.Name, Permissions: sorted(after.Permissions)},
+		Added:          added,
+		Removed:        removed,
+	}
+	return change, s.record(ctx, actorID, change)
+}
+
+// TokenScopesChanged records new scopes of an API token that let it do
+// something it could not: a new permission, or a permission on more assets.
+// Returns nil when the scopes did not widen.
+func (s *Service) TokenScopesChanged(ctx context.Context, actorID, orgID, tokenID, tokenName string, before, after rbac.TokenScopes) (*Change, error) {
+	added, removed := ScopeDiff(before, after)
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	change := &Change{
+		OrganizationID: orgID,
+		Kind:           KindTokenScopeExpanded,
+		SubjectType:    "org_api_token",
+		SubjectID:      tokenID,
+		SubjectName:    tokenName,
+		Before:         Grant{Scopes: before},
+		After:          Grant{Scopes: after},
+		Added:          added,
+		Removed:        removed,
+	}
+	return change, s.record(ctx, actorID, change)
+}
+
+// List returns an organization's recorded changes, newest first
+func (s *Service) List(ctx context.Context, f Filter) ([]Change, error) {
+	changes := []Change{}
+	err := s.db.SelectContext(ctx, &changes, `
+		SELECT c.id, c.organization_id, c.actor_id, u.email AS actor_email, c.kind,
+			c.subject_type, c.subject_id, c.subject_name, c.before, c.after,
+			c.added, c.removed, c.severity, c.created_at
+		FROM privilege_changes c
+		LEFT JOIN users u ON u.id = c.actor_id
+		WHERE c.organization_id = $1
+		AND ($2 = '' OR c.kind = $2)
+		AND ($3 = '' OR c.subject_id = $3)
+		AND ($4::timestamp IS NULL OR c.created_at >= $4)
+		AND ($5::timestamp IS NULL OR c.created_at <= $5)
+		ORDER BY c.created_at DESC
+		LIMIT $6
+	`, f.OrganizationID, f.Kind, f.SubjectID, f.Start, f.End, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list privilege changes: %w", err)
+	}
+	return changes, nil
+}
+
+// record stores the change, logs it as a security event and alerts the
+// organization's owners and admins
+func (s *Service) record(ctx context.Context, actorID string, change *Change) error {
+	if change.Severity == "" {
+		change.Severity = "high"
+		if contains(change.Added, string(rbac.PermManageOrganization)) {
+			change.Severity = "critical"
+		}
+	}
+	if actorID != "" {
+		change.ActorID = &actorID
+	}
+
+	err := s.db.GetContext(ctx, change, `
+		INSERT INTO privilege_changes (organization_id, actor_id, kind, subject_type, subject_id,
+			subject_name, before, after, added, removed, severity)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, (SELECT email FROM users WHERE id = $2) AS actor_email
+	`, change.OrganizationID, change.ActorID, change.Kind, change.SubjectType, change.SubjectID,
+		change.SubjectName, change.Before, change.After, change.Added, change.Removed, change.Severity)
+	if err != nil {
+		return fmt.Errorf("failed to record privilege change: %w", err)
+	}
+
+	s.auditLogger.Log(ctx, audit.LogParams{
+		UserID:         actorID,
+		OrganizationID: change.OrganizationID,
+		Action:         "privilege_" + change.Kind,
+		ResourceType:   change.SubjectType,
+		ResourceID:     change.SubjectID,
+		Target:         change.SubjectName,
+		Details: map[string]interface{}{
+			"change_id": change.ID,
+			"before":    change.Before,
+			"after":     change.After,
+			"added":     []string(change.Added),
+			"removed":   []string(change.Removed),
+		},
+		Status:   "success",
+		Severity: change.Severity,
+	})
+
+	s.logger.Warn("Privileges expanded",
+		zap.String("kind", change.Kind),
+		zap.String("organization_id", change.OrganizationID),
+		zap.String("actor_id", actorID),
+		zap.String("subject_id", change.SubjectID),
+		zap.Strings("added", change.Added),
+	)
+
+	if len(s.notifiers) == 0 {
+		return nil
+	}
+	recipients, err := s.recipients(ctx, change)
+	if err != nil {
+		s.logger.Error("Failed to resolve privilege change recipients", zap.Error(err))
+		return nil
+	}
+
+	// Delivery (SMTP in particular) must not hold up the response
+	go func(change Change) {
+		for _, recipient := range recipients {
+			for _, notify := range s.notifiers {
+				notify(recipient, change)
+			}
+		}
+	}(*change)
+	return nil
+}
+
+// recipients returns the organization's owners and admins, and the member
+// whose role was elevated
+func (s *Service) recipients(ctx context.Context, change *Change) ([]Recipient, error) {
+	subject := ""
+	if change.Kind == KindRoleElevated {
+		subject = change.SubjectID
+	}
+
+	var recipients []Recipient
+	err := s.db.SelectContext(ctx, &recipients, `
+		SELECT DISTINCT u.id AS user_id, u.email
+		FROM organization_memberships m
+		INNER JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		AND (m.role IN ('owner', 'admin') OR m.user_id::text = $2)
+	`, change.OrganizationID, subject)
+	return recipients, err
+}
+
+// permissions resolves a role's permissions; a missing role has none
+func (s *Service) permissions(ctx context.Context, orgID string, role rbac.Role) ([]string, error) {
+	if role == "" {
+		return nil, nil
+	}
+	perms, err := s.roles.Permissions(ctx, orgID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve role permissions: %w", err)
+	}
+	names := make([]string, len(perms))
+	for i, p := range perms {
+		names[i] = string(p)
+	}
+	return sorted(names), nil
+}
+
+// ScopeDiff compares API token scopes. Grants are named "permission" when
+// on every asset and "permission@asset" otherwise; a grant is added when
+// before did not already cover it.
+func ScopeDiff(before, after rbac.TokenScopes) (added, removed []string) {
+	return scopeGrants(after, before), scopeGrants(before, after)
+}
+
+// scopeGrants names the grants of scopes that other does not cover
+func scopeGrants(scopes, other rbac.TokenScopes) []string {
+	grants := []string{}
+	seen := map[string]bool{}
+	add := func(grant string) {
+		if !seen[grant] {
+			seen[grant] = true
+			grants = append(grants, grant)
+		}
+	}
+	for _, scope := range scopes {
+		if len(scope.Assets) == 0 {
+			if !coversAll(other, scope.Permission) {
+				add(string(scope.Permission))
+			}
+			continue
+		}
+		for _, asset := range scope.Assets {
+			if !other.AllowsAsset(scope.Permission, asset) {
+				add(string(scope.Permission) + "@" + asset)
+			}
+		}
+	}
+	sort.Strings(grants)
+	return grants
+}
+
+// coversAll reports whether scopes grant perm on every asset
+func coversAll(scopes rbac.TokenScopes, perm rbac.Permission) bool {
+	for _, scope := range scopes {
+		if scope.Permission == perm && len(scope.Assets) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// diff returns the permissions only in after, and those only in before
+func diff(before, after []string) (added, removed []string) {
+	added, removed = []string{}, []string{}
+	for _, p := range after {
+		if !contains(before, p) {
+			added = append(added, p)
+		}
+	}
+	for _, p := range before {
+		if !contains(after, p) {
+			removed = append(removed, p)
+		}
+	}
+	return sorted(added), sorted(removed)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sorted(list []string) []string {
+	out := append([]string{}, list...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,293 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to members")
+	ErrReservedRoleName  = errors.New("role name is reserved for a built-in role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrNotMember         = errors.New("user is not a member of the organization")
+)
+
+// CustomRole is an organization-defined role composed of built-in permissions
+type CustomRole struct {
+	ID             string         `json:"id" db:"id"`
+	OrganizationID string         `json:"organization_id" db:"organization_id"`
+	Name           string         `json:"name" db:"name"`
+	Description    string         `json:"description" db:"description"`
+	Permissions    pq.StringArray `json:"permissions" db:"permissions"`
+	CreatedBy      *string        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// RoleStore resolves effective permissions from built-in and custom roles.
+// Custom roles are cached per organization and invalidated on change.
+type RoleStore struct {
+	db     *database.DB
+	ttl    time.Duration
+	mu     sync.RWMutex
+	cache  map[string]cachedRoles
+	logger *zap.Logger
+}
+
+type cachedRoles struct {
+	roles   map[Role][]Permission
+	expires time.Time
+}
+
+func NewRoleStore(db *database.DB, logger *zap.Logger) *RoleStore {
+	return &RoleStore{
+		db:     db,
+		ttl:    time.Minute,
+		cache:  make(map[string]cachedRoles),
+		logger: logger,
+	}
+}
+
+// Permissions returns the permissions granted by role within an organization
+func (s *RoleStore) Permissions(ctx context.Context, orgID string, role Role) ([]Permission, error) {
+	if role.IsValid() {
+		return role.GetPermissions(), nil
+	}
+	if orgID == "" {
+		return nil, nil
+	}
+
+	roles, err := s.orgRoles(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return roles[role], nil
+}
+
+// HasPermission reports whether role grants perm within an organization
+func (s *RoleStore) HasPermission(ctx context.Context, orgID string, role Role, perm Permission) (bool, error) {
+	perms, err := s.Permissions(ctx, orgID, role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if p == perm {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RoleExists reports whether role is built-in or defined by the organization
+func (s *RoleStore) RoleExists(ctx context.Context, orgID string, role Role) (bool, error) {
+	if role.IsValid() {
+		return true, nil
+	}
+
+	roles, err := s.orgRoles(ctx, orgID)
+	if err != nil {
+		return false, err
+	}
+	_, ok := roles[role]
+	return ok, nil
+}
+
+// MemberRole returns the role a user holds in an organization
+func (s *RoleStore) MemberRole(ctx context.Context, userID, orgID string) (Role, error) {
+	var role string
+	err := s.db.GetContext(ctx, &role, `
+		SELECT role FROM organization_memberships
+		WHERE user_id = $1 AND organization_id = $2
+	`, userID, orgID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotMember
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch membership: %w", err)
+	}
+	return Role(role), nil
+}
+
+// orgRoles loads an organization's custom roles, using the cache when fresh
+func (s *RoleStore) orgRoles(ctx context.Context, orgID string) (map[Role][]Permission, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[orgID]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.roles, nil
+	}
+
+	customRoles, err := s.List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[Role][]Permission, len(customRoles))
+	for _, cr := range customRoles {
+		perms := make([]Permission, len(cr.Permissions))
+		for i, p := range cr.Permissions {
+			perms[i] = Permission(p)
+		}
+		roles[Role(cr.Name)] = perms
+	}
+
+	s.mu.Lock()
+	s.cache[orgID] = cachedRoles{roles: roles, expires: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return roles, nil
+}
+
+func (s *RoleStore) invalidate(orgID string) {
+	s.mu.Lock()
+	delete(s.cache, orgID)
+	s.mu.Unlock()
+}
+
+// List returns an organization's custom roles
+func (s *RoleStore) List(ctx context.Context, orgID string) ([]CustomRole, error) {
+	roles := []CustomRole{}
+	err := s.db.SelectContext(ctx, &roles, `
+		SELECT id, organization_id, name, description, permissions, created_by, created_at, updated_at
+		FROM organization_roles
+		WHERE organization_id = $1
+		ORDER BY name
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom roles: %w", err)
+	}
+	return roles, nil
+}
+
+// Get returns one of an organization's custom roles
+func (s *RoleStore) Get(ctx context.Context, orgID, roleID string) (*CustomRole, error) {
+	var role CustomRole
+	err := s.db.GetContext(ctx, &role, `
+		SELECT id, organization_id, name, description, permissions, created_by, created_at, updated_at
+		FROM organization_roles
+		WHERE id = $1 AND organization_id = $2
+	`, roleID, orgID)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom role: %w", err)
+	}
+	return &role, nil
+}
+
+// Create defines a new custom role
+func (s *RoleStore) Create(ctx context.Context, orgID, createdBy, name, description string, perms []string) (*CustomRole, error) {
+	if Role(name).IsValid() {
+		return nil, ErrReservedRoleName
+	}
+	if err := validatePermissions(perms); err != nil {
+		return nil, err
+	}
+
+	var role CustomRole
+	err := s.db.GetContext(ctx, &role, `
+		INSERT INTO organization_roles (organization_id, name, description, permissions, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid)
+		RETURNING id, organization_id, name, description, permissions, created_by, created_at, updated_at
+	`, orgID, name, description, pq.Array(perms), createdBy)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrRoleExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom role: %w", err)
+	}
+
+	s.invalidate(orgID)
+	return &role, nil
+}
+
+// Update replaces a custom role's description and permissions
+func (s *RoleStore) Update(ctx context.Context, orgID, roleID, description string, perms []string) (*CustomRole, error) {
+	if err := validatePermissions(perms); err != nil {
+		return nil, err
+	}
+
+	var role CustomRole
+	err := s.db.GetContext(ctx, &role, `
+		UPDATE organization_roles
+		SET description = $3, permissions = $4
+		WHERE id = $1 AND organization_id = $2
+		RETURNING id, organization_id, name, description, permissions, created_by, created_at, updated_at
+	`, roleID, orgID, description, pq.Array(perms))
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update custom role: %w", err)
+	}
+
+	s.invalidate(orgID)
+	return &role, nil
+}
+
+// Delete removes a custom role that no member holds
+func (s *RoleStore) Delete(ctx context.Context, orgID, roleID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM organization_roles r
+		WHERE r.id = $1 AND r.organization_id = $2
+		AND NOT EXISTS (
+			SELECT 1 FROM organization_memberships m
+			WHERE m.organization_id = r.organization_id AND m.role = r.name
+		)
+	`, roleID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete custom role: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		var exists bool
+		if err := s.db.GetContext(ctx, &exists, `
+			SELECT EXISTS(SELECT 1 FROM organization_roles WHERE id = $1 AND organization_id = $2)
+		`, roleID, orgID); err != nil {
+			return fmt.Errorf("failed to delete custom role: %w", err)
+		}
+		if exists {
+			return ErrRoleInUse
+		}
+		return ErrRoleNotFound
+	}
+
+	s.invalidate(orgID)
+	return nil
+}
+
+// AllPermissions lists every permission a role can be composed of
+func AllPermissions() []Permission {
+	// Owners hold every permission
+	return append([]Permission(nil), rolePermissions[RoleOwner]...)
+}
+
+// IsValid checks if a permission is defined
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions() {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+func validatePermissions(perms []string) error {
+	for _, p := range perms {
+		if !Permission(p).IsValid() {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, p)
+		}
+	}
+	return nil
+}
@@ -3,17 +3,19 @@ package rbac
 import (
 	"net/http"
 
+	"github.com/cyper-security/gateway/internal/logging"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// RequirePermission returns a middleware that checks if the user has the required permission
-func RequirePermission(perm Permission, logger *zap.Logger) gin.HandlerFunc {
+// RequirePermission returns a middleware that checks if the user has the required permission.
+// Custom organization roles are resolved through roles; a nil store allows built-in roles only.
+func RequirePermission(roles *RoleStore, perm Permission, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get role from context (set by auth middleware)
 		roleStr, exists := c.Get("user_role")
 		if !exists {
-			logger.Warn("No role found in context")
+			logging.FromContext(c.Request.Context(), logger).Warn("No role found in context")
 			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 			c.Abort()
 			return
@@ -22,8 +24,27 @@ func RequirePermission(perm Permission, logger *zap.Logger) gin.HandlerFunc {
 		role := Role(roleStr.(string))
 
 		// Check permission
-		if !role.HasPermission(perm) {
-			logger.Warn("Permission denied",
+		allowed := role.HasPermission(perm)
+		if !allowed && roles != nil {
+			var err error
+			allowed, err = roles.HasPermission(c.Request.Context(), c.GetString("organization_id"), role, perm)
+			if err != nil {
+				logging.FromContext(c.Request.Context(), logger).Error("Failed to resolve role permissions", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+				c.Abort()
+				return
+			}
+		}
+
+		// API tokens are further limited to their scopes
+		if allowed && !TokenAllows(c.Request.Context(), c.GetString("organization_id"), perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API token is not scoped for this action"})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			logging.FromContext(c.Request.Context(), logger).Warn("Permission denied",
 				zap.String("role", string(role)),
 				zap.String("required_permission", string(perm)),
 			)
@@ -38,9 +59,17 @@ func RequirePermission(perm Permission, logger *zap.Logger) gin.HandlerFunc {
 	}
 }
 
-// RequireRole returns a middleware that checks if the user has one of the required roles
+// RequireRole returns a middleware that checks if the user has one of the
+// required roles. Role-gated routes are closed to API tokens, whose scopes
+// are permissions.
 func RequireRole(allowedRoles ...Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if _, ok := TokenGrantFrom(c.Request.Context()); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API tokens cannot use this endpoint"})
+			c.Abort()
+			return
+		}
+
 		roleStr, exists := c.Get("user_role")
 		if !exists {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
@@ -68,7 +97,7 @@ func RequireOrganizationContext(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		orgID, exists := c.Get("organization_id")
 		if !exists || orgID == "" {
-			logger.Warn("No organization context found")
+			logging.FromContext(c.Request.Context(), logger).Warn("No organization context found")
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Organization context required",
 			})
@@ -0,0 +1,135 @@
+//go:build integration
+
+package rbac_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/cyper-security/gateway/internal/testenv"
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter guards organization routes as the gateway does: authentication,
+// organization context, then a permission
+func newRouter(env *testenv.Env) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	org := router.Group("/org", env.Auth.AuthMiddleware(), rbac.RequireOrganizationContext(env.Logger))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	org.GET("/scans", rbac.RequirePermission(env.Roles, rbac.PermViewScan, env.Logger), ok)
+	org.POST("/scans", rbac.RequirePermission(env.Roles, rbac.PermCreateScan, env.Logger), ok)
+	org.PUT("/settings", rbac.RequirePermission(env.Roles, rbac.PermManageOrganization, env.Logger), ok)
+	return router
+}
+
+func call(router *gin.Engine, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// member logs in a new user whose home organization is orgID with role
+func member(t *testing.T, env *testenv.Env, orgID string, role rbac.Role) (testenv.User, string) {
+	t.Helper()
+	user := env.CreateUser(t, testenv.UserOptions{OrgID: orgID})
+	env.AddMember(t, user.ID, orgID, role)
+	return user, env.Login(t, user).AccessToken
+}
+
+func TestOrganizationPermissions(t *testing.T) {
+	env := testenv.Setup(t)
+	router := newRouter(env)
+	org := env.CreateOrganization(t, "")
+
+	// A custom role that may run scans but not manage the organization
+	_, err := env.Roles.Create(context.Background(), org.ID, "", "scan-runner", "", []string{string(rbac.PermViewScan), string(rbac.PermCreateScan)})
+	if err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+
+	_, owner := member(t, env, org.ID, rbac.RoleOwner)
+	_, viewer := member(t, env, org.ID, rbac.RoleViewer)
+	_, runner := member(t, env, org.ID, "scan-runner")
+	// Without a membership the token carries no organization
+	outsider := env.Login(t, env.CreateUser(t, testenv.UserOptions{})).AccessToken
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		status int
+	}{
+		{"owner views scans", owner, http.MethodGet, "/org/scans", http.StatusOK},
+		{"owner starts scans", owner, http.MethodPost, "/org/scans", http.StatusOK},
+		{"owner manages the organization", owner, http.MethodPut, "/org/settings", http.StatusOK},
+		{"viewer views scans", viewer, http.MethodGet, "/org/scans", http.StatusOK},
+		{"viewer cannot start scans", viewer, http.MethodPost, "/org/scans", http.StatusForbidden},
+		{"viewer cannot manage the organization", viewer, http.MethodPut, "/org/settings", http.StatusForbidden},
+		{"custom role starts scans", runner, http.MethodPost, "/org/scans", http.StatusOK},
+		{"custom role cannot manage the organization", runner, http.MethodPut, "/org/settings", http.StatusForbidden},
+		{"outsider has no organization context", outsider, http.MethodGet, "/org/scans", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := call(router, tt.method, tt.path, tt.token); status != tt.status {
+				t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, status, tt.status)
+			}
+		})
+	}
+}
+
+func TestOrganizationPermissionChanges(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	router := newRouter(env)
+	org := env.CreateOrganization(t, "")
+
+	t.Run("removed members are refused at once", func(t *testing.T) {
+		user, token := member(t, env, org.ID, rbac.RoleAdmin)
+		if status := call(router, http.MethodPost, "/org/scans", token); status != http.StatusOK {
+			t.Fatalf("before removal: status %d", status)
+		}
+		_, err := env.DB.ExecContext(ctx, `DELETE FROM organization_memberships WHERE user_id = $1 AND organization_id = $2`, user.ID, org.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := call(router, http.MethodGet, "/org/scans", token); status != http.StatusUnauthorized {
+			t.Errorf("after removal: status %d, want 401", status)
+		}
+	})
+
+	t.Run("demotions apply to existing tokens", func(t *testing.T) {
+		user, token := member(t, env, org.ID, rbac.RoleAdmin)
+		_, err := env.DB.ExecContext(ctx, `UPDATE organization_memberships SET role = 'viewer' WHERE user_id = $1 AND organization_id = $2`, user.ID, org.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := call(router, http.MethodPost, "/org/scans", token); status != http.StatusForbidden {
+			t.Errorf("after demotion: status %d, want 403", status)
+		}
+	})
+
+	t.Run("custom role permissions can be withdrawn", func(t *testing.T) {
+		role, err := env.Roles.Create(ctx, org.ID, "", "triager", "", []string{string(rbac.PermViewScan)})
+		if err != nil {
+			t.Fatalf("create role: %v", err)
+		}
+		_, token := member(t, env, org.ID, "triager")
+		if status := call(router, http.MethodGet, "/org/scans", token); status != http.StatusOK {
+			t.Fatalf("with view:scan: status %d", status)
+		}
+		if _, err := env.Roles.Update(ctx, org.ID, role.ID, "", []string{string(rbac.PermTriageFinding)}); err != nil {
+			t.Fatalf("update role: %v", err)
+		}
+		if status := call(router, http.MethodGet, "/org/scans", token); status != http.StatusForbidden {
+			t.Errorf("without view:scan: status %d, want 403", status)
+		}
+	})
+}
@@ -0,0 +1,299 @@
+package rbac
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrPolicyNotFound    = errors.New("policy not found")
+	ErrPolicyExists      = errors.New("policy already exists")
+	ErrInvalidConditions = errors.New("invalid policy conditions")
+)
+
+// AccessPolicy narrows what a role may do by constraining resource attributes.
+// Policies only restrict: a request must first pass RBAC, then satisfy the
+// conditions of every enabled policy that applies to its role and permission.
+type AccessPolicy struct {
+	ID             string         `json:"id" db:"id"`
+	OrganizationID string         `json:"organization_id" db:"organization_id"`
+	Name           string         `json:"name" db:"name"`
+	Description    string         `json:"description" db:"description"`
+	Roles          pq.StringArray `json:"roles" db:"roles"` // Empty applies to every role
+	Permissions    pq.StringArray `json:"permissions" db:"permissions"`
+	Conditions     Conditions     `json:"conditions" db:"conditions"`
+	Enabled        bool           `json:"enabled" db:"enabled"`
+	CreatedBy      *string        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// Conditions are attribute constraints; all that are set must hold
+type Conditions struct {
+	TargetTagsAny  []string    `json:"target_tags_any,omitempty"`  // Target must carry at least one
+	TargetTagsNone []string    `json:"target_tags_none,omitempty"` // Target must carry none
+	ScanTypes      []string    `json:"scan_types,omitempty"`       // Allowed scan types
+	TimeOfDay      *TimeWindow `json:"time_of_day,omitempty"`      // Allowed hours
+}
+
+// TimeWindow allows hours in [Start, End); windows with Start > End wrap midnight
+type TimeWindow struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Timezone string `json:"timezone,omitempty"` // IANA name, defaults to UTC
+}
+
+// Attributes describe the resource and context of a request
+type Attributes struct {
+	TargetTags []string
+	ScanType   string
+	Time       time.Time
+}
+
+// Decision is the outcome of policy evaluation
+type Decision struct {
+	Allowed  bool   `json:"allowed"`
+	PolicyID string `json:"policy_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Value stores conditions as JSONB
+func (c Conditions) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan loads conditions from JSONB
+func (c *Conditions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*c = Conditions{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("unsupported conditions type %T", src)
+	}
+}
+
+// Validate checks that conditions are well formed
+func (c Conditions) Validate() error {
+	if w := c.TimeOfDay; w != nil {
+		if w.Start < 0 || w.Start > 23 || w.End < 0 || w.End > 24 || w.Start == w.End {
+			return fmt.Errorf("%w: time_of_day hours must be 0-24 and differ", ErrInvalidConditions)
+		}
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidConditions, w.Timezone)
+		}
+	}
+	return nil
+}
+
+// Evaluate reports whether attrs satisfy the conditions, with a reason when not
+func (c Conditions) Evaluate(attrs Attributes) (bool, string) {
+	if len(c.TargetTagsAny) > 0 && !intersects(attrs.TargetTags, c.TargetTagsAny) {
+		return false, fmt.Sprintf("target must be tagged with one of %v", c.TargetTagsAny)
+	}
+	if len(c.TargetTagsNone) > 0 && intersects(attrs.TargetTags, c.TargetTagsNone) {
+		return false, fmt.Sprintf("target must not be tagged with any of %v", c.TargetTagsNone)
+	}
+	if len(c.ScanTypes) > 0 && !contains(c.ScanTypes, attrs.ScanType) {
+		return false, fmt.Sprintf("scan type %q is not one of %v", attrs.ScanType, c.ScanTypes)
+	}
+	if w := c.TimeOfDay; w != nil {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return false, "policy time zone is invalid"
+		}
+		hour := attrs.Time.In(loc).Hour()
+		inWindow := hour >= w.Start && hour < w.End
+		if w.Start > w.End {
+			inWindow = hour >= w.Start || hour < w.End
+		}
+		if !inWindow {
+			return false, fmt.Sprintf("allowed only between %02d:00 and %02d:00 %s", w.Start, w.End, loc)
+		}
+	}
+	return true, ""
+}
+
+// appliesTo reports whether the policy constrains role exercising perm
+func (p *AccessPolicy) appliesTo(role Role, perm Permission) bool {
+	if !p.Enabled || !contains(p.Permissions, string(perm)) {
+		return false
+	}
+	return len(p.Roles) == 0 || contains(p.Roles, string(role))
+}
+
+// PolicyEngine evaluates organization access policies, caching them per organization
+type PolicyEngine struct {
+	db     *database.DB
+	ttl    time.Duration
+	mu     sync.RWMutex
+	cache  map[string]cachedPolicies
+	logger *zap.Logger
+}
+
+type cachedPolicies struct {
+	policies []AccessPolicy
+	expires  time.Time
+}
+
+func NewPolicyEngine(db *database.DB, logger *zap.Logger) *PolicyEngine {
+	return &PolicyEngine{
+		db:     db,
+		ttl:    time.Minute,
+		cache:  make(map[string]cachedPolicies),
+		logger: logger,
+	}
+}
+
+// Evaluate checks role exercising perm on a resource with attrs against the organization's policies
+func (e *PolicyEngine) Evaluate(ctx context.Context, orgID string, role Role, perm Permission, attrs Attributes) (Decision, error) {
+	if orgID == "" {
+		return Decision{Allowed: true}, nil
+	}
+	if attrs.Time.IsZero() {
+		attrs.Time = time.Now()
+	}
+
+	policies, err := e.orgPolicies(ctx, orgID)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	for i := range policies {
+		policy := &policies[i]
+		if !policy.appliesTo(role, perm) {
+			continue
+		}
+		if ok, reason := policy.Conditions.Evaluate(attrs); !ok {
+			e.logger.Info("Access denied by policy",
+				zap.String("policy_id", policy.ID),
+				zap.String("role", string(role)),
+				zap.String("permission", string(perm)),
+				zap.String("reason", reason),
+			)
+			return Decision{Allowed: false, PolicyID: policy.ID, Reason: policy.Name + ": " + reason}, nil
+		}
+	}
+
+	return Decision{Allowed: true}, nil
+}
+
+func (e *PolicyEngine) orgPolicies(ctx context.Context, orgID string) ([]AccessPolicy, error) {
+	e.mu.RLock()
+	cached, ok := e.cache[orgID]
+	e.mu.RUnlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.policies, nil
+	}
+
+	policies, err := e.List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.cache[orgID] = cachedPolicies{policies: policies, expires: time.Now().Add(e.ttl)}
+	e.mu.Unlock()
+
+	return policies, nil
+}
+
+func (e *PolicyEngine) invalidate(orgID string) {
+	e.mu.Lock()
+	delete(e.cache, orgID)
+	e.mu.Unlock()
+}
+
+// List returns an organization's access policies
+func (e *PolicyEngine) List(ctx context.Context, orgID string) ([]AccessPolicy, error) {
+	policies := []AccessPolicy{}
+	err := e.db.SelectContext(ctx, &policies, `
+		SELECT id, organization_id, name, description, roles, permissions, conditions,
+		       enabled, created_by, created_at, updated_at
+		FROM access_policies
+		WHERE organization_id = $1
+		ORDER BY name
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access policies: %w", err)
+	}
+	return policies, nil
+}
+
+// Create stores a new access policy
+func (e *PolicyEngine) Create(ctx context.Context, policy AccessPolicy) (*AccessPolicy, error) {
+	if err := validatePermissions(policy.Permissions); err != nil {
+		return nil, err
+	}
+	if err := policy.Conditions.Validate(); err != nil {
+		return nil, err
+	}
+	if policy.Roles == nil {
+		policy.Roles = pq.StringArray{}
+	}
+
+	var created AccessPolicy
+	err := e.db.GetContext(ctx, &created, `
+		INSERT INTO access_policies (organization_id, name, description, roles, permissions, conditions, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, organization_id, name, description, roles, permissions, conditions,
+		          enabled, created_by, created_at, updated_at
+	`, policy.OrganizationID, policy.Name, policy.Description, policy.Roles, policy.Permissions,
+		policy.Conditions, policy.Enabled, policy.CreatedBy)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrPolicyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access policy: %w", err)
+	}
+
+	e.invalidate(policy.OrganizationID)
+	return &created, nil
+}
+
+// Delete removes an access policy
+func (e *PolicyEngine) Delete(ctx context.Context, orgID, policyID string) error {
+	result, err := e.db.ExecContext(ctx, `
+		DELETE FROM access_policies WHERE id = $1 AND organization_id = $2
+	`, policyID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete access policy: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrPolicyNotFound
+	}
+
+	e.invalidate(orgID)
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(a, b []string) bool {
+	for _, v := range a {
+		if contains(b, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -26,16 +26,38 @@ const (
 	PermDeleteScan Permission = "delete:scan"
 	PermStopScan   Permission = "stop:scan"
 
+	// Approving or rejecting scans that need sign-off
+	PermApproveScan Permission = "approve:scan"
+
+	// Finding triage (comments, assignment, status changes)
+	PermTriageFinding Permission = "triage:finding"
+
 	// Report management
 	PermGenerateReport Permission = "generate:report"
 	PermViewReport     Permission = "view:report"
 	PermDeleteReport   Permission = "delete:report"
 
+	// Report template and schedule management
+	PermManageReportTemplates Permission = "manage:report_templates"
+	PermManageReportSchedules Permission = "manage:report_schedules"
+
 	// Team management
 	PermManageTeams Permission = "manage:teams"
 	PermViewTeams   Permission = "view:teams"
+
+	// Audit trail
+	PermViewAuditLogs Permission = "view:audit_logs"
+
+	// Escalation policies and paging
+	PermManageEscalations      Permission = "manage:escalations"
+	PermAcknowledgeEscalations Permission = "acknowledge:escalations"
 )
 
+// PermissionFor builds the permission for an action on a resource, e.g. ("scan", "create") -> create:scan
+func PermissionFor(resource, action string) Permission {
+	return Permission(action + ":" + resource)
+}
+
 // rolePermissions maps roles to their allowed permissions
 var rolePermissions = map[Role][]Permission{
 	RoleOwner: {
@@ -48,11 +70,18 @@ var rolePermissions = map[Role][]Permission{
 		PermViewScan,
 		PermDeleteScan,
 		PermStopScan,
+		PermApproveScan,
+		PermTriageFinding,
 		PermGenerateReport,
 		PermViewReport,
 		PermDeleteReport,
+		PermManageReportTemplates,
+		PermManageReportSchedules,
 		PermManageTeams,
 		PermViewTeams,
+		PermViewAuditLogs,
+		PermManageEscalations,
+		PermAcknowledgeEscalations,
 	},
 	RoleAdmin: {
 		// Admin access (no org deletion, but can manage most things)
@@ -62,11 +91,18 @@ var rolePermissions = map[Role][]Permission{
 		PermViewScan,
 		PermDeleteScan,
 		PermStopScan,
+		PermApproveScan,
+		PermTriageFinding,
 		PermGenerateReport,
 		PermViewReport,
 		PermDeleteReport,
+		PermManageReportTemplates,
+		PermManageReportSchedules,
 		PermManageTeams,
 		PermViewTeams,
+		PermViewAuditLogs,
+		PermManageEscalations,
+		PermAcknowledgeEscalations,
 	},
 	RoleScanner: {
 		// Can run scans and view results
@@ -74,9 +110,11 @@ var rolePermissions = map[Role][]Permission{
 		PermCreateScan,
 		PermViewScan,
 		PermStopScan,
+		PermTriageFinding,
 		PermGenerateReport,
 		PermViewReport,
 		PermViewTeams,
+		PermAcknowledgeEscalations,
 	},
 	RoleViewer: {
 		// Read-only access
@@ -0,0 +1,82 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// commandTimeout bounds how long one inbound command may run
+const commandTimeout = 15 * time.Second
+
+// maxCommandsInFlight bounds the commands one client may have running
+const maxCommandsInFlight = 4
+
+// Identity is who a connection was authenticated as
+type Identity struct {
+	UserID string
+	OrgID  string
+	Role   string
+}
+
+// CommandError is a command failure reported back to the client
+type CommandError struct {
+	Code    string // e.g. forbidden, not_found, conflict
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+// Commander carries out scan and alert commands for connected clients. It
+// runs the same permission checks and services as the matching REST
+// endpoints; failures the client should see are returned as *CommandError.
+type Commander interface {
+	Execute(ctx context.Context, caller Identity, msgType string, command interface{}) (interface{}, error)
+}
+
+// SetCommander enables the scan and alert commands
+func (h *Hub) SetCommander(commander Commander) {
+	h.commander = commander
+}
+
+// runCommand executes a command through the hub's Commander off the read
+// loop, replying with its result or error
+func (c *Client) runCommand(envelope ClientMessage, command interface{}) {
+	fail := func(code, message string) {
+		c.reply(ErrorEvent{RequestID: envelope.RequestID, Command: envelope.Type, Code: code, Message: message})
+	}
+
+	if c.Hub.commander == nil {
+		fail("unsupported", envelope.Type+" is not available")
+		return
+	}
+	select {
+	case c.inFlight <- struct{}{}:
+	default:
+		fail("too_many_commands", "too many commands in progress")
+		return
+	}
+
+	go func() {
+		defer func() { <-c.inFlight }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+
+		result, err := c.Hub.commander.Execute(ctx, c.identity, envelope.Type, command)
+		var cmdErr *CommandError
+		switch {
+		case errors.As(err, &cmdErr):
+			fail(cmdErr.Code, cmdErr.Message)
+		case err != nil:
+			c.logger.Error("Failed to execute command", zap.String("type", envelope.Type), zap.Error(err))
+			fail("internal_error", "command failed")
+		default:
+			c.reply(CommandResultEvent{RequestID: envelope.RequestID, Command: envelope.Type, Result: result})
+		}
+	}()
+}
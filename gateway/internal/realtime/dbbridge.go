@@ -0,0 +1,183 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Channels notified by the scan_jobs and vulnerabilities triggers
+const (
+	ChannelScanEvents    = "scan_events"
+	ChannelFindingEvents = "finding_events"
+)
+
+// DBBridgeConfig tunes the notification listener
+type DBBridgeConfig struct {
+	MinReconnect time.Duration
+	MaxReconnect time.Duration
+	// QueueSize bounds events waiting for the hub. When it is full, progress
+	// updates are dropped (a newer one supersedes them) and other events
+	// wait, which in turn holds notifications back in Postgres.
+	QueueSize int
+}
+
+func DefaultDBBridgeConfig() DBBridgeConfig {
+	return DBBridgeConfig{
+		MinReconnect: time.Second,
+		MaxReconnect: time.Minute,
+		QueueSize:    1024,
+	}
+}
+
+// DBBridge turns Postgres notifications into hub events, so changes written
+// to the database by any service (scanner workers, the brain) reach the
+// scan owner's WebSocket connections
+type DBBridge struct {
+	dsn    string
+	hub    *Hub
+	config DBBridgeConfig
+	logger *zap.Logger
+}
+
+func NewDBBridge(dsn string, hub *Hub, config DBBridgeConfig, logger *zap.Logger) *DBBridge {
+	return &DBBridge{
+		dsn:    dsn,
+		hub:    hub,
+		config: config,
+		logger: logger,
+	}
+}
+
+type bridgedEvent struct {
+	channel   string
+	userID    string
+	event     Event
+	droppable bool
+}
+
+// Run listens until ctx is cancelled. The listener reconnects on its own;
+// notifications sent while it was disconnected are lost.
+func (b *DBBridge) Run(ctx context.Context) {
+	listener := pq.NewListener(b.dsn, b.config.MinReconnect, b.config.MaxReconnect, b.onListenerEvent)
+	defer listener.Close()
+
+	for _, channel := range []string{ChannelScanEvents, ChannelFindingEvents} {
+		if err := listener.Listen(channel); err != nil {
+			b.logger.Error("Failed to listen for notifications", zap.String("channel", channel), zap.Error(err))
+		}
+	}
+	b.logger.Info("Bridging database notifications to WebSocket clients")
+
+	queue := make(chan bridgedEvent, b.config.QueueSize)
+	defer close(queue)
+	go func() {
+		for e := range queue {
+			b.hub.PublishToUser(e.userID, e.event)
+			metrics.DBNotifications.WithLabelValues(e.channel, "delivered").Inc()
+		}
+	}()
+
+	for {
+		select {
+		case n := <-listener.Notify:
+			if n == nil {
+				// Sent after a reconnect
+				continue
+			}
+			e, ok := b.decode(n)
+			if !ok {
+				metrics.DBNotifications.WithLabelValues(n.Channel, "invalid").Inc()
+				continue
+			}
+
+			select {
+			case queue <- e:
+			default:
+				if e.droppable {
+					metrics.DBNotifications.WithLabelValues(e.channel, "dropped").Inc()
+					continue
+				}
+				select {
+				case queue <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+		case <-time.After(90 * time.Second):
+			// Detect a dead connection while no notifications arrive
+			go listener.Ping()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *DBBridge) onListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		b.logger.Warn("Notification listener disconnected", zap.Error(err))
+	case pq.ListenerEventReconnected:
+		metrics.DBNotificationReconnects.Inc()
+		b.logger.Info("Notification listener reconnected; events during the outage were not delivered")
+	case pq.ListenerEventConnectionAttemptFailed:
+		b.logger.Error("Notification listener failed to connect", zap.Error(err))
+	}
+}
+
+// decode maps a trigger payload to the event sent to the scan owner
+func (b *DBBridge) decode(n *pq.Notification) (bridgedEvent, bool) {
+	switch n.Channel {
+	case ChannelScanEvents:
+		var p struct {
+			ScanID             string `json:"scan_id"`
+			UserID             string `json:"user_id"`
+			Status             string `json:"status"`
+			Progress           int    `json:"progress"`
+			CurrentPhase       string `json:"current_phase"`
+			ErrorMessage       string `json:"error_message"`
+			VulnerabilityCount int    `json:"vulnerability_count"`
+		}
+		if err := json.Unmarshal([]byte(n.Extra), &p); err != nil || p.UserID == "" {
+			b.logger.Warn("Invalid scan notification", zap.String("payload", n.Extra), zap.Error(err))
+			return bridgedEvent{}, false
+		}
+
+		switch p.Status {
+		case "completed", "failed", "stopped", "rejected":
+			return bridgedEvent{channel: n.Channel, userID: p.UserID, event: ScanCompleteEvent{
+				ScanID:             p.ScanID,
+				Status:             p.Status,
+				VulnerabilityCount: p.VulnerabilityCount,
+				ErrorMessage:       p.ErrorMessage,
+			}}, true
+		}
+		// Pauses and resumes must reach the client; a newer update supersedes
+		// plain progress
+		return bridgedEvent{channel: n.Channel, userID: p.UserID, droppable: p.Status == "running", event: ScanProgressEvent{
+			ScanID:       p.ScanID,
+			Progress:     p.Progress,
+			CurrentPhase: p.CurrentPhase,
+			Status:       p.Status,
+		}}, true
+
+	case ChannelFindingEvents:
+		var p struct {
+			VulnerabilityFoundEvent
+			UserID string `json:"user_id"`
+		}
+		if err := json.Unmarshal([]byte(n.Extra), &p); err != nil || p.UserID == "" {
+			b.logger.Warn("Invalid finding notification", zap.String("payload", n.Extra), zap.Error(err))
+			return bridgedEvent{}, false
+		}
+		return bridgedEvent{channel: n.Channel, userID: p.UserID, event: p.VulnerabilityFoundEvent}, true
+	}
+
+	return bridgedEvent{}, false
+}
@@ -0,0 +1,323 @@
+package realtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/estimation"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Event is a typed payload delivered to clients in a Message envelope.
+// Bump EventVersion whenever a field is removed or changes meaning.
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// Server-to-client event types
+const (
+	EventScanProgress       = "scan_progress"
+	EventScanComplete       = "scan_complete"
+	EventVulnerabilityFound = "vulnerability_found"
+	EventAlert              = "alert"
+	EventFindingActivity    = "finding_activity"
+	EventScanApproval       = "scan_approval"
+	EventAnalysisChunk      = "analysis_chunk"
+	EventScanConsole        = "scan_console"
+	EventSystemStatus       = "system_status"
+	EventPong               = "pong"
+	EventCommandResult      = "command_result"
+	EventError              = "error"
+
+	// EventReplay labels metrics for replayed messages; payloads keep their original type
+	EventReplay = "replay"
+)
+
+// Client-to-server command types
+const (
+	CommandPing             = "ping"
+	CommandSubscribe        = "subscribe"
+	CommandUnsubscribe      = "unsubscribe"
+	CommandStopScan         = "stop_scan"
+	CommandRerunScan        = "rerun_scan"
+	CommandAcknowledgeAlert = "acknowledge_alert"
+)
+
+// ScanProgressEvent reports progress of a running scan
+type ScanProgressEvent struct {
+	ScanID       string `json:"scan_id"`
+	Progress     int    `json:"progress"` // 0-100
+	CurrentPhase string `json:"current_phase"`
+	Status       string `json:"status,omitempty"` // pending_approval, pending, running, paused
+}
+
+func (ScanProgressEvent) EventType() string { return EventScanProgress }
+func (ScanProgressEvent) EventVersion() int { return 1 }
+
+// ScanCompleteEvent reports that a scan finished
+type ScanCompleteEvent struct {
+	ScanID             string         `json:"scan_id"`
+	Status             string         `json:"status"` // completed, failed, stopped, rejected
+	VulnerabilityCount int            `json:"vulnerability_count"`
+	SeverityCounts     map[string]int `json:"severity_counts,omitempty"`
+	ErrorMessage       string         `json:"error_message,omitempty"`
+}
+
+func (ScanCompleteEvent) EventType() string { return EventScanComplete }
+func (ScanCompleteEvent) EventVersion() int { return 1 }
+
+// VulnerabilityFoundEvent reports a finding as soon as a scan records it
+type VulnerabilityFoundEvent struct {
+	ScanID            string   `json:"scan_id"`
+	VulnerabilityID   string   `json:"vulnerability_id"`
+	Title             string   `json:"title"`
+	Severity          string   `json:"severity"`
+	CVSSScore         *float64 `json:"cvss_score,omitempty"`
+	CVEID             string   `json:"cve_id,omitempty"`
+	AffectedComponent string   `json:"affected_component,omitempty"`
+}
+
+func (VulnerabilityFoundEvent) EventType() string { return EventVulnerabilityFound }
+func (VulnerabilityFoundEvent) EventVersion() int { return 1 }
+
+// AlertEvent notifies a user of a security alert, such as an audit anomaly
+type AlertEvent struct {
+	ID         string                 `json:"id,omitempty"` // For acknowledge_alert, when the alert can be acknowledged
+	Severity   string                 `json:"severity"`
+	Kind       string                 `json:"kind"`
+	UserID     string                 `json:"user_id,omitempty"`
+	Target     string                 `json:"target,omitempty"`
+	Count      int                    `json:"count"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	DetectedAt time.Time              `json:"detected_at"`
+}
+
+func (AlertEvent) EventType() string { return EventAlert }
+func (AlertEvent) EventVersion() int { return 1 }
+
+// FindingActivityEvent tells a finding's watchers about triage activity
+type FindingActivityEvent struct {
+	FindingID      string     `json:"finding_id"`
+	OrganizationID string     `json:"organization_id"`
+	Kind           string     `json:"kind"` // comment, assigned, status_changed
+	ActorID        string     `json:"actor_id"`
+	Title          string     `json:"title"`
+	CommentID      string     `json:"comment_id,omitempty"`
+	AssigneeID     string     `json:"assignee_id,omitempty"`
+	DueDate        *time.Time `json:"due_date,omitempty"`
+	FromStatus     string     `json:"from_status,omitempty"`
+	ToStatus       string     `json:"to_status,omitempty"`
+}
+
+func (FindingActivityEvent) EventType() string { return EventFindingActivity }
+func (FindingActivityEvent) EventVersion() int { return 1 }
+
+// ScanApprovalEvent asks approvers to review a scan, and tells its requester
+// about each decision
+type ScanApprovalEvent struct {
+	ScanID         string               `json:"scan_id"`
+	OrganizationID string               `json:"organization_id"`
+	Kind           string               `json:"kind"` // requested, approved, rejected
+	ScanType       string               `json:"scan_type"`
+	TargetValue    string               `json:"target_value"`
+	RequestedBy    string               `json:"requested_by"`
+	ActorID        string               `json:"actor_id,omitempty"` // Approver, for decisions
+	Justification  string               `json:"justification,omitempty"`
+	Status         string               `json:"status"` // pending_approval, pending, rejected
+	Approvals      int                  `json:"approvals"`
+	Required       int                  `json:"required"`
+	Estimate       *estimation.Estimate `json:"estimate,omitempty"` // Predicted duration, requests and bandwidth
+}
+
+func (ScanApprovalEvent) EventType() string { return EventScanApproval }
+func (ScanApprovalEvent) EventVersion() int { return 1 }
+
+// AnalysisChunkEvent relays a piece of a streaming scan analysis to the
+// user who requested it. The last event of an analysis has Done or Error set.
+type AnalysisChunkEvent struct {
+	ScanID string `json:"scan_id"`
+	Text   string `json:"text,omitempty"`
+	Done   bool   `json:"done,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (AnalysisChunkEvent) EventType() string { return EventAnalysisChunk }
+func (AnalysisChunkEvent) EventVersion() int { return 1 }
+
+// ScanConsoleEvent carries raw tool output from a running scan to the
+// subscribers of its console topic. Dropped counts lines discarded by the
+// rate limit since the previous event.
+type ScanConsoleEvent struct {
+	ScanID  string        `json:"scan_id"`
+	Lines   []ConsoleLine `json:"lines"`
+	Dropped int           `json:"dropped,omitempty"`
+}
+
+// ConsoleLine is one line of tool output
+type ConsoleLine struct {
+	Stream string    `json:"stream"` // stdout or stderr
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+func (ScanConsoleEvent) EventType() string { return EventScanConsole }
+func (ScanConsoleEvent) EventVersion() int { return 1 }
+
+// SystemStatusEvent announces platform-wide status changes
+type SystemStatusEvent struct {
+	Status        string     `json:"status"` // operational, degraded, emergency_stop, maintenance
+	Message       string     `json:"message,omitempty"`
+	EmergencyStop bool       `json:"emergency_stop"`
+	Maintenance   bool       `json:"maintenance"`
+	EndsAt        *time.Time `json:"ends_at,omitempty"` // Scheduled end of maintenance
+}
+
+func (SystemStatusEvent) EventType() string { return EventSystemStatus }
+func (SystemStatusEvent) EventVersion() int { return 1 }
+
+// PongEvent answers a ping command
+type PongEvent struct{}
+
+func (PongEvent) EventType() string { return EventPong }
+func (PongEvent) EventVersion() int { return 1 }
+
+// CommandResultEvent answers a command that carried a request ID
+type CommandResultEvent struct {
+	RequestID string      `json:"request_id"`
+	Command   string      `json:"command"`
+	Result    interface{} `json:"result,omitempty"`
+}
+
+func (CommandResultEvent) EventType() string { return EventCommandResult }
+func (CommandResultEvent) EventVersion() int { return 1 }
+
+// ErrorEvent reports a rejected client command. RequestID and Command are
+// set when the command carried a request ID.
+type ErrorEvent struct {
+	RequestID string `json:"request_id,omitempty"`
+	Command   string `json:"command,omitempty"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+func (ErrorEvent) EventType() string { return EventError }
+func (ErrorEvent) EventVersion() int { return 1 }
+
+// ClientMessage is the envelope for messages sent by clients. Replies to the
+// message echo RequestID so clients can match them up.
+type ClientMessage struct {
+	Type      string          `json:"type" binding:"required"`
+	RequestID string          `json:"request_id,omitempty" binding:"omitempty,max=64"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// PingCommand asks the server for a pong
+type PingCommand struct{}
+
+// SubscribeCommand subscribes to, or unsubscribes from, a topic. Reconnecting
+// clients pass the last seq they received to replay what they missed.
+type SubscribeCommand struct {
+	Topic   string `json:"topic" binding:"required,max=128"`
+	LastSeq *int64 `json:"last_seq,omitempty" binding:"omitempty,min=0"`
+}
+
+// StopScanCommand stops a scan, as POST /scans/:id/stop
+type StopScanCommand struct {
+	ScanID string `json:"scan_id" binding:"required,uuid"`
+	Reason string `json:"reason,omitempty" binding:"max=500"`
+}
+
+// RerunScanCommand queues a new scan with a finished scan's target and
+// settings, as POST /scans/:id/rerun
+type RerunScanCommand struct {
+	ScanID string `json:"scan_id" binding:"required,uuid"`
+}
+
+// AcknowledgeAlertCommand acknowledges an anomaly alert raised for the
+// connection's organization; its admins may acknowledge it
+type AcknowledgeAlertCommand struct {
+	AlertID string `json:"alert_id" binding:"required,max=255"`
+}
+
+// EventSpec describes one entry of the event catalog
+type EventSpec struct {
+	Type    string
+	Version int
+	Inbound bool        // Sent by clients rather than the server
+	Payload interface{} // Zero value of the data type
+}
+
+// EventCatalog lists every WebSocket message type for schema generation
+func EventCatalog() []EventSpec {
+	outbound := []Event{
+		ScanProgressEvent{},
+		ScanCompleteEvent{},
+		VulnerabilityFoundEvent{},
+		AlertEvent{},
+		FindingActivityEvent{},
+		ScanApprovalEvent{},
+		AnalysisChunkEvent{},
+		ScanConsoleEvent{},
+		SystemStatusEvent{},
+		PongEvent{},
+		CommandResultEvent{},
+		ErrorEvent{},
+	}
+
+	catalog := make([]EventSpec, 0, len(outbound)+len(commands))
+	for _, event := range outbound {
+		catalog = append(catalog, EventSpec{Type: event.EventType(), Version: event.EventVersion(), Payload: event})
+	}
+	for _, msgType := range []string{CommandPing, CommandSubscribe, CommandUnsubscribe, CommandStopScan, CommandRerunScan, CommandAcknowledgeAlert} {
+		catalog = append(catalog, EventSpec{Type: msgType, Version: 1, Inbound: true, Payload: commands[msgType]()})
+	}
+	return catalog
+}
+
+// commands maps inbound message types to their payload constructors
+var commands = map[string]func() interface{}{
+	CommandPing:             func() interface{} { return &PingCommand{} },
+	CommandSubscribe:        func() interface{} { return &SubscribeCommand{} },
+	CommandUnsubscribe:      func() interface{} { return &SubscribeCommand{} },
+	CommandStopScan:         func() interface{} { return &StopScanCommand{} },
+	CommandRerunScan:        func() interface{} { return &RerunScanCommand{} },
+	CommandAcknowledgeAlert: func() interface{} { return &AcknowledgeAlertCommand{} },
+}
+
+// decodeCommand validates a raw client message against the command schemas.
+// The envelope is returned as far as it could be read, so errors can echo
+// its request ID.
+func decodeCommand(raw []byte) (ClientMessage, interface{}, error) {
+	var envelope ClientMessage
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return envelope, nil, fmt.Errorf("malformed message: %w", err)
+	}
+	if err := binding.Validator.ValidateStruct(&envelope); err != nil {
+		return ClientMessage{Type: envelope.Type}, nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	newCommand, ok := commands[envelope.Type]
+	if !ok {
+		return envelope, nil, fmt.Errorf("unknown message type %q", envelope.Type)
+	}
+
+	data := envelope.Data
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		data = []byte("{}")
+	}
+
+	command := newCommand()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(command); err != nil {
+		return envelope, nil, fmt.Errorf("invalid %s data: %w", envelope.Type, err)
+	}
+	if err := binding.Validator.ValidateStruct(command); err != nil {
+		return envelope, nil, fmt.Errorf("invalid %s data: %w", envelope.Type, err)
+	}
+
+	return envelope, command, nil
+}
@@ -3,6 +3,7 @@ package realtime
 import (
 	"net/http"
 
+	"github.com/cyper-security/gateway/internal/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
@@ -43,18 +44,22 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 	// Upgrade connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		h.logger.Error("Failed to upgrade connection", zap.Error(err))
+		logging.FromContext(c.Request.Context(), h.logger).Error("Failed to upgrade connection", zap.Error(err))
 		return
 	}
 
 	// Register client
-	client := h.hub.RegisterClient(userID.(string), conn)
+	client := h.hub.RegisterClient(c.Request.Context(), Identity{
+		UserID: userID.(string),
+		OrgID:  c.GetString("organization_id"),
+		Role:   c.GetString("user_role"),
+	}, conn)
 
 	// Start read and write pumps
 	go client.WritePump()
 	go client.ReadPump()
 
-	h.logger.Info("WebSocket connection established",
+	logging.FromContext(c.Request.Context(), h.logger).Info("WebSocket connection established",
 		zap.String("user_id", userID.(string)),
 		zap.String("client_id", client.ID),
 	)
@@ -62,35 +67,29 @@ func (h *Handler) HandleWebSocket(c *gin.Context) {
 
 // BroadcastScanProgress broadcasts scan progress update
 func (h *Handler) BroadcastScanProgress(userID, scanID string, progress int, currentPhase string) {
-	h.hub.BroadcastToUser(userID, "scan_progress", map[string]interface{}{
-		"scan_id":       scanID,
-		"progress":      progress,
-		"current_phase": currentPhase,
+	h.hub.PublishToUser(userID, ScanProgressEvent{
+		ScanID:       scanID,
+		Progress:     progress,
+		CurrentPhase: currentPhase,
 	})
 }
 
 // BroadcastScanComplete broadcasts scan completion
-func (h *Handler) BroadcastScanComplete(userID, scanID string, results map[string]interface{}) {
-	h.hub.BroadcastToUser(userID, "scan_complete", map[string]interface{}{
-		"scan_id": scanID,
-		"results": results,
-	})
+func (h *Handler) BroadcastScanComplete(userID string, event ScanCompleteEvent) {
+	h.hub.PublishToUser(userID, event)
 }
 
 // BroadcastVulnerability broadcasts new vulnerability found
-func (h *Handler) BroadcastVulnerability(userID, scanID string, vulnerability map[string]interface{}) {
-	h.hub.BroadcastToUser(userID, "vulnerability_found", map[string]interface{}{
-		"scan_id":       scanID,
-		"vulnerability": vulnerability,
-	})
+func (h *Handler) BroadcastVulnerability(userID string, event VulnerabilityFoundEvent) {
+	h.hub.PublishToUser(userID, event)
 }
 
 // BroadcastAlert broadcasts security alert
-func (h *Handler) BroadcastAlert(userID string, alert map[string]interface{}) {
-	h.hub.BroadcastToUser(userID, "alert", alert)
+func (h *Handler) BroadcastAlert(userID string, alert AlertEvent) {
+	h.hub.PublishToUser(userID, alert)
 }
 
 // BroadcastSystemStatus broadcasts system status
-func (h *Handler) BroadcastSystemStatus(status map[string]interface{}) {
-	h.hub.Broadcast("system_status", status)
+func (h *Handler) BroadcastSystemStatus(status SystemStatusEvent) {
+	h.hub.Publish(status)
 }
@@ -3,9 +3,13 @@ package realtime
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
@@ -18,6 +22,10 @@ type Hub struct {
 	unregister chan *Client
 	broadcast  chan *Message
 	mu         sync.RWMutex
+	replay     *ReplayStore           // Optional; sequences and buffers topic messages
+	commander  Commander              // Optional; carries out scan and alert commands
+	sources    map[string]TopicSource // Topic prefixes with their own access checks and backlog
+	liveness   LivenessConfig
 	logger     *zap.Logger
 }
 
@@ -25,18 +33,41 @@ type Hub struct {
 type Client struct {
 	ID       string
 	UserID   string
+	identity Identity
 	Hub      *Hub
 	Conn     *websocket.Conn
 	Send     chan []byte
-	mu       sync.Mutex
+	topics   map[string]bool
+	// Live topic messages held back while missed messages are replayed
+	pending map[string][]replayedMessage
+	// Slots for commands running on the client's behalf
+	inFlight chan struct{}
+	// When the connection was opened and last answered a ping (Unix nanoseconds)
+	connectedAt time.Time
+	lastPong    atomic.Int64
+	mu          sync.Mutex
+	// Carries the upgrade request's correlation fields plus client_id
+	logger *zap.Logger
 }
 
-// Message represents a WebSocket message
+// Message is the envelope for events sent to clients
 type Message struct {
-	Type      string                 `json:"type"`
-	UserID    string                 `json:"user_id,omitempty"`
-	Data      map[string]interface{} `json:"data"`
-	Timestamp time.Time              `json:"timestamp"`
+	Type      string    `json:"type"`
+	Version   int       `json:"version"`
+	UserID    string    `json:"user_id,omitempty"`
+	Topic     string    `json:"topic,omitempty"`
+	Seq       int64     `json:"seq,omitempty"` // Per-topic sequence, set when replay is enabled
+	Data      Event     `json:"data"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func newMessage(event Event) *Message {
+	return &Message{
+		Type:      event.EventType(),
+		Version:   event.EventVersion(),
+		Data:      event,
+		Timestamp: time.Now(),
+	}
 }
 
 // NewHub creates a new WebSocket hub
@@ -46,10 +77,16 @@ func NewHub(logger *zap.Logger) *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan *Message, 256),
+		liveness:   DefaultLivenessConfig(),
 		logger:     logger,
 	}
 }
 
+// SetReplayStore enables sequence numbers and missed-message replay for topics
+func (h *Hub) SetReplayStore(store *ReplayStore) {
+	h.replay = store
+}
+
 // Run starts the hub
 func (h *Hub) Run(ctx context.Context) {
 	h.logger.Info("Starting WebSocket hub")
@@ -59,6 +96,7 @@ func (h *Hub) Run(ctx context.Context) {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client.ID] = client
+			metrics.WebSocketClients.Set(float64(len(h.clients)))
 			h.mu.Unlock()
 			h.logger.Info("Client registered",
 				zap.String("client_id", client.ID),
@@ -66,15 +104,12 @@ func (h *Hub) Run(ctx context.Context) {
 			)
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client.ID]; ok {
-				delete(h.clients, client.ID)
-				close(client.Send)
-				h.logger.Info("Client unregistered", zap.String("client_id", client.ID))
-			}
-			h.mu.Unlock()
+			h.removeClient(client)
 
 		case message := <-h.broadcast:
+			payload := h.marshalMessage(message)
+			var slow []*Client
+
 			h.mu.RLock()
 			for _, client := range h.clients {
 				// If message has a specific user, only send to that user
@@ -82,17 +117,31 @@ func (h *Hub) Run(ctx context.Context) {
 					continue
 				}
 
+				// Topic messages only go to subscribers
+				if message.Topic != "" {
+					if !client.IsSubscribed(message.Topic) {
+						continue
+					}
+					if client.holdForReplay(message.Topic, message.Seq, payload) {
+						continue
+					}
+				}
+
 				select {
-				case client.Send <- h.marshalMessage(message):
+				case client.Send <- payload:
+					metrics.WebSocketMessagesBroadcast.WithLabelValues(message.Type).Inc()
 				default:
 					// Client's send channel is full, close the connection
-					h.mu.RUnlock()
-					h.unregister <- client
-					h.mu.RLock()
+					metrics.WebSocketMessagesDropped.WithLabelValues(message.Type).Inc()
+					slow = append(slow, client)
 				}
 			}
 			h.mu.RUnlock()
 
+			for _, client := range slow {
+				h.removeClient(client)
+			}
+
 		case <-ctx.Done():
 			h.logger.Info("Stopping WebSocket hub")
 			return
@@ -100,15 +149,47 @@ func (h *Hub) Run(ctx context.Context) {
 	}
 }
 
-// RegisterClient registers a new client
-func (h *Hub) RegisterClient(userID string, conn *websocket.Conn) *Client {
+// removeClient drops a client and releases its topic subscriptions
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client.ID]; !ok {
+		return
+	}
+
+	delete(h.clients, client.ID)
+
+	client.mu.Lock()
+	close(client.Send)
+	for topic := range client.topics {
+		metrics.WebSocketTopicSubscribers.WithLabelValues(topic).Dec()
+	}
+	client.topics = nil
+	client.mu.Unlock()
+
+	metrics.WebSocketClients.Set(float64(len(h.clients)))
+	h.logger.Info("Client unregistered", zap.String("client_id", client.ID))
+}
+
+// RegisterClient registers a new client; its log lines carry the correlation
+// fields of the upgrade request in ctx
+func (h *Hub) RegisterClient(ctx context.Context, identity Identity, conn *websocket.Conn) *Client {
+	id := uuid.New().String()
 	client := &Client{
-		ID:     uuid.New().String(),
-		UserID: userID,
-		Hub:    h,
-		Conn:   conn,
-		Send:   make(chan []byte, 256),
+		ID:          id,
+		UserID:      identity.UserID,
+		identity:    identity,
+		Hub:         h,
+		Conn:        conn,
+		Send:        make(chan []byte, 256),
+		topics:      make(map[string]bool),
+		pending:     make(map[string][]replayedMessage),
+		inFlight:    make(chan struct{}, maxCommandsInFlight),
+		connectedAt: time.Now(),
+		logger:      logging.FromContext(ctx, h.logger).With(zap.String("client_id", id)),
 	}
+	client.touchPong()
 
 	h.register <- client
 	return client
@@ -119,23 +200,40 @@ func (h *Hub) UnregisterClient(client *Client) {
 	h.unregister <- client
 }
 
-// Broadcast sends a message to all connected clients
-func (h *Hub) Broadcast(msgType string, data map[string]interface{}) {
-	h.broadcast <- &Message{
-		Type:      msgType,
-		Data:      data,
-		Timestamp: time.Now(),
-	}
+// Publish sends an event to all connected clients
+func (h *Hub) Publish(event Event) {
+	h.broadcast <- newMessage(event)
 }
 
-// BroadcastToUser sends a message to a specific user
-func (h *Hub) BroadcastToUser(userID, msgType string, data map[string]interface{}) {
-	h.broadcast <- &Message{
-		Type:      msgType,
-		UserID:    userID,
-		Data:      data,
-		Timestamp: time.Now(),
+// PublishToUser sends an event to a specific user
+func (h *Hub) PublishToUser(userID string, event Event) {
+	msg := newMessage(event)
+	msg.UserID = userID
+	h.broadcast <- msg
+}
+
+// PublishToTopic sends an event to clients subscribed to a topic. With a replay
+// store configured the message is sequenced and buffered for reconnecting clients.
+func (h *Hub) PublishToTopic(topic string, event Event) {
+	msg := newMessage(event)
+	msg.Topic = topic
+
+	if h.replay != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		seq, err := h.replay.NextSeq(ctx, topic)
+		if err != nil {
+			h.logger.Error("Failed to sequence topic message", zap.String("topic", topic), zap.Error(err))
+		} else {
+			msg.Seq = seq
+			if err := h.replay.Append(ctx, topic, seq, h.marshalMessage(msg)); err != nil {
+				h.logger.Error("Failed to buffer topic message", zap.String("topic", topic), zap.Error(err))
+			}
+		}
 	}
+
+	h.broadcast <- msg
 }
 
 // GetClientCount returns the number of connected clients
@@ -149,7 +247,7 @@ func (h *Hub) GetClientCount() int {
 func (h *Hub) GetUserClientCount(userID string) int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	
+
 	count := 0
 	for _, client := range h.clients {
 		if client.UserID == userID {
@@ -175,9 +273,11 @@ func (c *Client) ReadPump() {
 		c.Conn.Close()
 	}()
 
-	c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	pongTimeout := c.Hub.liveness.PongTimeout
+	c.Conn.SetReadDeadline(time.Now().Add(pongTimeout))
 	c.Conn.SetPongHandler(func(string) error {
-		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		c.touchPong()
+		c.Conn.SetReadDeadline(time.Now().Add(pongTimeout))
 		return nil
 	})
 
@@ -185,26 +285,26 @@ func (c *Client) ReadPump() {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				c.Hub.logger.Error("WebSocket error", zap.Error(err))
+				c.logger.Error("WebSocket error", zap.Error(err))
 			}
 			break
 		}
 
-		// Handle incoming messages (e.g., subscriptions, commands)
-		var msg Message
-		if err := json.Unmarshal(message, &msg); err != nil {
-			c.Hub.logger.Error("Failed to unmarshal message", zap.Error(err))
+		// Validate incoming commands against the catalog before acting on them
+		envelope, command, err := decodeCommand(message)
+		if err != nil {
+			c.logger.Debug("Rejected client message", zap.String("client_id", c.ID), zap.Error(err))
+			c.reply(ErrorEvent{RequestID: envelope.RequestID, Command: envelope.Type, Code: "invalid_message", Message: err.Error()})
 			continue
 		}
 
-		// Process message based on type
-		c.handleMessage(&msg)
+		c.handleCommand(envelope, command)
 	}
 }
 
 // WritePump pumps messages from the hub to the WebSocket connection
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(54 * time.Second)
+	ticker := time.NewTicker(c.Hub.liveness.PingInterval)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -246,31 +346,163 @@ func (c *Client) WritePump() {
 	}
 }
 
-func (c *Client) handleMessage(msg *Message) {
-	c.Hub.logger.Debug("Received message",
-		zap.String("type", msg.Type),
+func (c *Client) handleCommand(envelope ClientMessage, command interface{}) {
+	msgType := envelope.Type
+	c.logger.Debug("Received message",
+		zap.String("type", msgType),
 		zap.String("client_id", c.ID),
+		zap.String("request_id", envelope.RequestID),
 	)
 
-	// Handle different message types
-	switch msg.Type {
-	case "ping":
-		// Respond with pong
-		c.Send <- c.Hub.marshalMessage(&Message{
-			Type:      "pong",
-			Data:      map[string]interface{}{},
-			Timestamp: time.Now(),
-		})
+	switch cmd := command.(type) {
+	case *PingCommand:
+		// Clients that cannot see protocol pings prove liveness this way
+		c.touchPong()
+		c.reply(PongEvent{})
+
+	case *SubscribeCommand:
+		source := c.Hub.topicSource(cmd.Topic)
+		switch {
+		case msgType == CommandUnsubscribe:
+			c.Unsubscribe(cmd.Topic)
+		case source != nil:
+			c.subscribeToSource(envelope, cmd, source)
+		case cmd.LastSeq != nil && c.Hub.replay != nil:
+			lastSeq := *cmd.LastSeq
+			c.subscribeWithBacklog(cmd.Topic, cmd.LastSeq, func(ctx context.Context) ([]replayedMessage, error) {
+				return c.Hub.replay.Since(ctx, cmd.Topic, lastSeq)
+			})
+		default:
+			c.Subscribe(cmd.Topic)
+		}
+
+	case *StopScanCommand, *RerunScanCommand, *AcknowledgeAlertCommand:
+		c.runCommand(envelope, command)
+	}
+}
+
+// subscribeWithBacklog subscribes to a topic and first delivers the messages
+// load returns, those newer than lastSeq. Live messages arriving meanwhile are
+// held and flushed after the backlog so the client sees each sequence number
+// once, in order. Without lastSeq nothing is reported missing.
+func (c *Client) subscribeWithBacklog(topic string, lastSeq *int64, load func(ctx context.Context) ([]replayedMessage, error)) {
+	c.mu.Lock()
+	if c.topics == nil {
+		c.mu.Unlock()
+		return
+	}
+	c.pending[topic] = []replayedMessage{}
+	c.mu.Unlock()
+
+	c.Subscribe(topic)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	missed, err := load(ctx)
+	if err != nil {
+		c.logger.Error("Failed to load replay buffer", zap.String("topic", topic), zap.Error(err))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	held := c.pending[topic]
+	delete(c.pending, topic)
+	if c.topics == nil {
+		return
+	}
+
+	var highest int64
+	if lastSeq != nil {
+		highest = *lastSeq
+		// The oldest buffered message is newer than lastSeq+1: older ones were trimmed
+		if err == nil && len(missed) > 0 && missed[0].Seq > highest+1 {
+			c.sendLocked(EventError, c.Hub.marshalMessage(newMessage(ErrorEvent{
+				Code:    "replay_incomplete",
+				Message: fmt.Sprintf("messages after seq %d on topic %s are no longer buffered", highest, topic),
+			})))
+		}
+	}
+
+	for _, queue := range [][]replayedMessage{missed, held} {
+		for _, m := range queue {
+			if m.Seq != 0 && m.Seq <= highest {
+				continue
+			}
+			if m.Seq > highest {
+				highest = m.Seq
+			}
+			c.sendLocked(EventReplay, m.Payload)
+		}
+	}
+
+	metrics.WebSocketMessagesReplayed.Add(float64(len(missed)))
+}
 
-	case "subscribe":
-		// Handle subscription to specific events
-		// TODO: Implement subscription logic
+// holdForReplay queues a live topic message while that topic is being replayed
+func (c *Client) holdForReplay(topic string, seq int64, payload []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	case "unsubscribe":
-		// Handle unsubscription
-		// TODO: Implement unsubscription logic
+	queue, replaying := c.pending[topic]
+	if !replaying {
+		return false
+	}
+	c.pending[topic] = append(queue, replayedMessage{Seq: seq, Payload: payload})
+	return true
+}
 
+// sendLocked queues a payload without blocking; c.mu must be held
+func (c *Client) sendLocked(msgType string, payload []byte) {
+	select {
+	case c.Send <- payload:
 	default:
-		c.Hub.logger.Warn("Unknown message type", zap.String("type", msg.Type))
+		metrics.WebSocketMessagesDropped.WithLabelValues(msgType).Inc()
+	}
+}
+
+// reply sends an event directly to this client, dropping it if the client is gone or backed up
+func (c *Client) reply(event Event) {
+	payload := c.Hub.marshalMessage(newMessage(event))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// topics is cleared when the hub closes Send
+	if c.topics == nil {
+		return
 	}
+	c.sendLocked(event.EventType(), payload)
+}
+
+// Subscribe adds the client to a topic
+func (c *Client) Subscribe(topic string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.topics == nil || c.topics[topic] {
+		return
+	}
+	c.topics[topic] = true
+	metrics.WebSocketTopicSubscribers.WithLabelValues(topic).Inc()
+}
+
+// Unsubscribe removes the client from a topic
+func (c *Client) Unsubscribe(topic string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.topics[topic] {
+		return
+	}
+	delete(c.topics, topic)
+	metrics.WebSocketTopicSubscribers.WithLabelValues(topic).Dec()
+}
+
+// IsSubscribed reports whether the client is subscribed to a topic
+func (c *Client) IsSubscribed(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.topics[topic]
 }
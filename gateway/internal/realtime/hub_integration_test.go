@@ -0,0 +1,143 @@
+//go:build integration
+
+package realtime_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/cyper-security/gateway/internal/testenv"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// received is a message as a client reads it
+type received struct {
+	Type   string          `json:"type"`
+	Topic  string          `json:"topic"`
+	UserID string          `json:"user_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// newServer serves the WebSocket endpoint behind the real auth middleware
+func newServer(t *testing.T, env *testenv.Env) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", env.Auth.AuthMiddleware(), realtime.NewHandler(env.Hub, env.Logger).HandleWebSocket)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+// connect opens a WebSocket as the user
+func connect(t *testing.T, env *testenv.Env, url string, user testenv.User) *websocket.Conn {
+	t.Helper()
+	header := http.Header{"Authorization": {"Bearer " + env.Login(t, user).AccessToken}}
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// send writes a client command
+func send(t *testing.T, conn *websocket.Conn, msgType string, data interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(realtime.ClientMessage{Type: msgType, Data: raw}); err != nil {
+		t.Fatalf("send %s: %v", msgType, err)
+	}
+}
+
+// next reads the client's next message
+func next(t *testing.T, conn *websocket.Conn) received {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg received
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+// flush waits until the server has handled every command sent so far, since
+// commands are handled in order and a ping is answered
+func flush(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	send(t, conn, realtime.CommandPing, struct{}{})
+	if msg := next(t, conn); msg.Type != realtime.EventPong {
+		t.Fatalf("got %s before the pong", msg.Type)
+	}
+}
+
+func TestWebSocketBroadcast(t *testing.T) {
+	env := testenv.Setup(t)
+	url := newServer(t, env)
+	subscriber := env.CreateUser(t, testenv.UserOptions{})
+	bystander := env.CreateUser(t, testenv.UserOptions{})
+
+	subscriberConn := connect(t, env, url, subscriber)
+	bystanderConn := connect(t, env, url, bystander)
+	topic := "scan:" + subscriber.ID
+	send(t, subscriberConn, realtime.CommandSubscribe, realtime.SubscribeCommand{Topic: topic})
+	flush(t, subscriberConn)
+	flush(t, bystanderConn)
+
+	t.Run("topic subscribers receive topic events", func(t *testing.T) {
+		env.Hub.PublishToTopic(topic, realtime.ScanProgressEvent{ScanID: "scan-1", Progress: 40, CurrentPhase: "enumeration"})
+
+		msg := next(t, subscriberConn)
+		if msg.Type != (realtime.ScanProgressEvent{}).EventType() || msg.Topic != topic {
+			t.Fatalf("received %s on %q, want scan progress on %q", msg.Type, msg.Topic, topic)
+		}
+		var progress realtime.ScanProgressEvent
+		if err := json.Unmarshal(msg.Data, &progress); err != nil {
+			t.Fatal(err)
+		}
+		if progress.ScanID != "scan-1" || progress.Progress != 40 {
+			t.Fatalf("received %+v", progress)
+		}
+	})
+
+	t.Run("user events reach only that user", func(t *testing.T) {
+		env.Hub.PublishToUser(bystander.ID, realtime.AlertEvent{Kind: "failed_logins", Count: 12, DetectedAt: time.Now()})
+
+		// The bystander's first message is its alert, not the topic event
+		// it never subscribed to
+		msg := next(t, bystanderConn)
+		if msg.Type != (realtime.AlertEvent{}).EventType() || msg.UserID != bystander.ID {
+			t.Fatalf("bystander received %s for %q, want its alert", msg.Type, msg.UserID)
+		}
+		flush(t, subscriberConn)
+	})
+
+	t.Run("broadcasts reach every client", func(t *testing.T) {
+		env.Hub.Publish(realtime.SystemStatusEvent{})
+		for _, conn := range []*websocket.Conn{subscriberConn, bystanderConn} {
+			if msg := next(t, conn); msg.Type != (realtime.SystemStatusEvent{}).EventType() {
+				t.Fatalf("received %s, want the system status", msg.Type)
+			}
+		}
+	})
+
+	t.Run("connections need a valid token", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer invalid"}})
+		if err == nil {
+			t.Fatal("dial succeeded without a valid token")
+		}
+		if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("dial = %v, want 401", err)
+		}
+	})
+}
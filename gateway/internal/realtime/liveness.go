@@ -0,0 +1,111 @@
+package realtime
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// LivenessConfig controls how dead connections are detected. A client whose
+// TCP connection died without a close frame never errors on its own; it is
+// found by the pongs it stops sending.
+type LivenessConfig struct {
+	// PingInterval is how often each client is pinged
+	PingInterval time.Duration
+	// PongTimeout is how long a client may go without a pong before it is
+	// considered dead; it must exceed PingInterval
+	PongTimeout time.Duration
+	// ReapInterval is how often the reaper looks for dead clients
+	ReapInterval time.Duration
+}
+
+func DefaultLivenessConfig() LivenessConfig {
+	return LivenessConfig{
+		PingInterval: 54 * time.Second,
+		PongTimeout:  60 * time.Second,
+		ReapInterval: 15 * time.Second,
+	}
+}
+
+// SetLiveness configures pings and the liveness reaper
+func (h *Hub) SetLiveness(config LivenessConfig) {
+	h.liveness = config
+}
+
+// connectionAgeBuckets label the connection age gauge, youngest first
+var connectionAgeBuckets = []struct {
+	label string
+	under time.Duration // Zero for the last bucket
+}{
+	{"lt_1m", time.Minute},
+	{"1m_10m", 10 * time.Minute},
+	{"10m_1h", time.Hour},
+	{"1h_6h", 6 * time.Hour},
+	{"gte_6h", 0},
+}
+
+// touchPong records that the client answered a ping
+func (c *Client) touchPong() {
+	c.lastPong.Store(time.Now().UnixNano())
+}
+
+// LastPong is when the client last answered a ping (or connected)
+func (c *Client) LastPong() time.Time {
+	return time.Unix(0, c.lastPong.Load())
+}
+
+// StartReaper closes connections that have not answered a ping within the
+// pong timeout, and samples connection ages, until ctx is cancelled
+func (h *Hub) StartReaper(ctx context.Context) {
+	ticker := time.NewTicker(h.liveness.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.reap()
+		}
+	}
+}
+
+// reap closes stale clients and refreshes the connection age gauge
+func (h *Hub) reap() {
+	now := time.Now()
+	ages := make(map[string]int, len(connectionAgeBuckets))
+	var stale []*Client
+
+	h.mu.RLock()
+	for _, client := range h.clients {
+		if now.Sub(client.LastPong()) > h.liveness.PongTimeout {
+			stale = append(stale, client)
+			continue
+		}
+		age := now.Sub(client.connectedAt)
+		for _, bucket := range connectionAgeBuckets {
+			if bucket.under == 0 || age < bucket.under {
+				ages[bucket.label]++
+				break
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, bucket := range connectionAgeBuckets {
+		metrics.WebSocketConnectionAge.WithLabelValues(bucket.label).Set(float64(ages[bucket.label]))
+	}
+
+	for _, client := range stale {
+		client.logger.Info("Closing unresponsive WebSocket client",
+			zap.String("user_id", client.UserID),
+			zap.Time("last_pong", client.LastPong()),
+		)
+		metrics.WebSocketClientsReaped.Inc()
+		// Closing the connection also ends the client's read and write pumps
+		h.removeClient(client)
+		client.Conn.Close()
+	}
+}
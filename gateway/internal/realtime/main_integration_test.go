@@ -0,0 +1,11 @@
+//go:build integration
+
+package realtime_test
+
+import (
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+func TestMain(m *testing.M) { testenv.Main(m) }
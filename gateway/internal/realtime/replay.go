@@ -0,0 +1,90 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	replaySeqPrefix    = "ws:seq:"
+	replayBufferPrefix = "ws:replay:"
+)
+
+// ReplayConfig bounds the per-topic replay buffer
+type ReplayConfig struct {
+	BufferSize int64         // Messages kept per topic
+	Retention  time.Duration // How long an idle topic's buffer survives
+}
+
+// DefaultReplayConfig covers short reconnects without holding much in Redis
+func DefaultReplayConfig() ReplayConfig {
+	return ReplayConfig{
+		BufferSize: 100,
+		Retention:  10 * time.Minute,
+	}
+}
+
+// ReplayStore sequences topic messages and keeps the most recent ones in Redis
+// so reconnecting clients can recover what they missed
+type ReplayStore struct {
+	client *redis.Client
+	config ReplayConfig
+}
+
+func NewReplayStore(client *redis.Client, config ReplayConfig) *ReplayStore {
+	return &ReplayStore{client: client, config: config}
+}
+
+// replayedMessage is a buffered payload and its sequence number
+type replayedMessage struct {
+	Seq     int64
+	Payload []byte
+}
+
+// NextSeq allocates the next sequence number for a topic
+func (s *ReplayStore) NextSeq(ctx context.Context, topic string) (int64, error) {
+	seq, err := s.client.Incr(ctx, replaySeqPrefix+topic).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate sequence for topic %s: %w", topic, err)
+	}
+	return seq, nil
+}
+
+// Append stores a sequenced payload, trimming the buffer to its configured size
+func (s *ReplayStore) Append(ctx context.Context, topic string, seq int64, payload []byte) error {
+	key := replayBufferPrefix + topic
+
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(seq), Member: payload})
+		pipe.ZRemRangeByRank(ctx, key, 0, -(s.config.BufferSize + 1))
+		pipe.Expire(ctx, key, s.config.Retention)
+		pipe.Expire(ctx, replaySeqPrefix+topic, s.config.Retention)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to buffer message for topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Since returns buffered messages with a sequence greater than lastSeq, oldest first
+func (s *ReplayStore) Since(ctx context.Context, topic string, lastSeq int64) ([]replayedMessage, error) {
+	entries, err := s.client.ZRangeByScoreWithScores(ctx, replayBufferPrefix+topic, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(lastSeq, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay buffer for topic %s: %w", topic, err)
+	}
+
+	messages := make([]replayedMessage, 0, len(entries))
+	for _, entry := range entries {
+		payload, _ := entry.Member.(string)
+		messages = append(messages, replayedMessage{Seq: int64(entry.Score), Payload: []byte(payload)})
+	}
+	return messages, nil
+}
@@ -0,0 +1,90 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SequencedEvent is a topic event with the sequence number its source gave it
+type SequencedEvent struct {
+	Seq   int64
+	Event Event
+}
+
+// TopicSource owns a family of topics that not every user may see and that
+// keep their own history, such as a scan's console output. Failures the
+// client should see are returned as *CommandError.
+type TopicSource interface {
+	// Authorize checks that the caller may subscribe to the topic
+	Authorize(ctx context.Context, caller Identity, topic string) error
+	// Backlog returns the retained events with a sequence greater than
+	// afterSeq, oldest first
+	Backlog(ctx context.Context, topic string, afterSeq int64) ([]SequencedEvent, error)
+}
+
+// AddTopicSource routes subscriptions to topics starting with prefix through
+// source. Call before Run.
+func (h *Hub) AddTopicSource(prefix string, source TopicSource) {
+	if h.sources == nil {
+		h.sources = make(map[string]TopicSource)
+	}
+	h.sources[prefix] = source
+}
+
+func (h *Hub) topicSource(topic string) TopicSource {
+	for prefix, source := range h.sources {
+		if strings.HasPrefix(topic, prefix) {
+			return source
+		}
+	}
+	return nil
+}
+
+// PublishSequenced sends an event its TopicSource already sequenced and
+// retained to this instance's subscribers of the topic
+func (h *Hub) PublishSequenced(topic string, seq int64, event Event) {
+	msg := newMessage(event)
+	msg.Topic = topic
+	msg.Seq = seq
+	h.broadcast <- msg
+}
+
+// subscribeToSource checks the caller against the topic's source, then
+// subscribes and delivers the source's backlog
+func (c *Client) subscribeToSource(envelope ClientMessage, cmd *SubscribeCommand, source TopicSource) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := source.Authorize(ctx, c.identity, cmd.Topic); err != nil {
+		var cmdErr *CommandError
+		if !errors.As(err, &cmdErr) {
+			c.logger.Error("Failed to authorize subscription", zap.String("topic", cmd.Topic), zap.Error(err))
+			cmdErr = &CommandError{Code: "internal_error", Message: "subscription failed"}
+		}
+		c.reply(ErrorEvent{RequestID: envelope.RequestID, Command: envelope.Type, Code: cmdErr.Code, Message: cmdErr.Message})
+		return
+	}
+
+	var afterSeq int64
+	if cmd.LastSeq != nil {
+		afterSeq = *cmd.LastSeq
+	}
+	c.subscribeWithBacklog(cmd.Topic, cmd.LastSeq, func(ctx context.Context) ([]replayedMessage, error) {
+		events, err := source.Backlog(ctx, cmd.Topic, afterSeq)
+		if err != nil {
+			return nil, err
+		}
+		backlog := make([]replayedMessage, 0, len(events))
+		for _, e := range events {
+			msg := newMessage(e.Event)
+			msg.Topic = cmd.Topic
+			msg.Seq = e.Seq
+			backlog = append(backlog, replayedMessage{Seq: e.Seq, Payload: c.Hub.marshalMessage(msg)})
+		}
+		return backlog, nil
+	})
+}
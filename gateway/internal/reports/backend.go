@@ -0,0 +1,79 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cyper-security/gateway/internal/brain"
+)
+
+// Report backend names
+const (
+	BackendBrain = "brain"
+	BackendLocal = "local"
+)
+
+// ErrFormatUnsupported is returned by backends asked for a format they
+// cannot render
+var ErrFormatUnsupported = errors.New("format not supported by report backend")
+
+// Rendered is a report as a backend produced it: Content for text formats,
+// File for binary ones
+type Rendered struct {
+	Content string
+	File    []byte
+	Path    string // Where the backend wrote File, if it keeps a copy
+	Backend string // Set by the router
+}
+
+//go:generate go run github.com/matryer/moq@v0.3.4 -pkg mocks -out ../mocks/report_backend.go . ReportBackend
+
+// ReportBackend renders reports. The brain service is the production
+// backend; LocalBackend renders simpler reports inside the gateway.
+type ReportBackend interface {
+	Supports(format string) bool
+	GenerateReport(ctx context.Context, req brain.GenerateReportRequest) (*Rendered, error)
+	Health(ctx context.Context) error
+}
+
+// BrainBackend renders reports through the brain service
+type BrainBackend struct {
+	client *brain.Client
+}
+
+var _ ReportBackend = (*BrainBackend)(nil)
+
+func NewBrainBackend(client *brain.Client) *BrainBackend {
+	return &BrainBackend{client: client}
+}
+
+func (b *BrainBackend) Supports(format string) bool {
+	switch format {
+	case brain.FormatMarkdown, brain.FormatHTML, brain.FormatPDF:
+		return true
+	}
+	return false
+}
+
+// GenerateReport has the brain render the report, then fetches the file it
+// wrote for PDFs
+func (b *BrainBackend) GenerateReport(ctx context.Context, req brain.GenerateReportRequest) (*Rendered, error) {
+	resp, err := b.client.GenerateReport(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Format != brain.FormatPDF {
+		return &Rendered{Content: resp.Report}, nil
+	}
+
+	file, err := b.client.FetchReportFile(resp.Path)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", resp.Path, err)
+	}
+	return &Rendered{File: file, Path: resp.Path}, nil
+}
+
+func (b *BrainBackend) Health(ctx context.Context) error {
+	return b.client.Health(ctx)
+}
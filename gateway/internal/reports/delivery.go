@@ -0,0 +1,127 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/deliveries"
+	"github.com/cyper-security/gateway/internal/notify"
+)
+
+// DeliveryConfig configures how scheduled reports reach their recipients
+type DeliveryConfig struct {
+	PublicURL string // prefixed to download paths in emails and webhooks
+	// SenderName returns the organization's email sender name, or "" for
+	// the default. Optional.
+	SenderName func(ctx context.Context, orgID string) string
+}
+
+// WebhookPayload is posted to a schedule's webhook after each run
+type WebhookPayload struct {
+	Event      string          `json:"event"`
+	ScheduleID string          `json:"schedule_id"`
+	Schedule   string          `json:"schedule"`
+	Reports    []WebhookReport `json:"reports"`
+	SentAt     time.Time       `json:"sent_at"`
+}
+
+type WebhookReport struct {
+	ID          string `json:"id"`
+	ScanID      string `json:"scan_id"`
+	Format      string `json:"format"`
+	DownloadURL string `json:"download_url"`
+}
+
+// Deliverer sends download links by email and webhook
+type Deliverer struct {
+	config     DeliveryConfig
+	mailer     *notify.Mailer
+	deliveries *deliveries.Service
+}
+
+func NewDeliverer(config DeliveryConfig, mailer *notify.Mailer, deliveryService *deliveries.Service) *Deliverer {
+	return &Deliverer{
+		config:     config,
+		mailer:     mailer,
+		deliveries: deliveryService,
+	}
+}
+
+// Deliver notifies the schedule's recipients of the generated reports
+func (d *Deliverer) Deliver(ctx context.Context, schedule *Schedule, generated []*Report) error {
+	var errs []string
+
+	if len(schedule.EmailRecipients) > 0 {
+		if err := d.email(ctx, schedule, generated); err != nil {
+			errs = append(errs, "email: "+err.Error())
+		}
+	}
+	if schedule.WebhookURL != nil && *schedule.WebhookURL != "" {
+		if err := d.webhook(ctx, schedule, generated); err != nil {
+			errs = append(errs, "webhook: "+err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("delivery failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (d *Deliverer) link(report *Report) string {
+	return strings.TrimRight(d.config.PublicURL, "/") + report.DownloadURL
+}
+
+func (d *Deliverer) email(ctx context.Context, schedule *Schedule, generated []*Report) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Your scheduled report %q is ready.\r\n\r\n", schedule.Name)
+	for _, r := range generated {
+		fmt.Fprintf(&body, "- Scan %s (%s): %s\r\n", r.ScanID, r.Format, d.link(r))
+	}
+	if len(generated) == 0 {
+		body.WriteString("No scans matched this schedule during the reporting period.\r\n")
+	}
+
+	senderName := ""
+	if d.config.SenderName != nil {
+		senderName = d.config.SenderName(ctx, schedule.OrganizationID)
+	}
+	return d.mailer.SendAs(senderName, schedule.EmailRecipients, "Scheduled report: "+schedule.Name, body.String())
+}
+
+// webhook posts the payload, signed with the schedule's secret when it has
+// one, through the organization's delivery log
+func (d *Deliverer) webhook(ctx context.Context, schedule *Schedule, generated []*Report) error {
+	payload := WebhookPayload{
+		Event:      "reports.scheduled",
+		ScheduleID: schedule.ID,
+		Schedule:   schedule.Name,
+		Reports:    make([]WebhookReport, 0, len(generated)),
+		SentAt:     time.Now().UTC(),
+	}
+	for _, r := range generated {
+		payload.Reports = append(payload.Reports, WebhookReport{
+			ID:          r.ID,
+			ScanID:      r.ScanID,
+			Format:      r.Format,
+			DownloadURL: d.link(r),
+		})
+	}
+
+	secret := ""
+	if schedule.WebhookSecret != nil {
+		secret = *schedule.WebhookSecret
+	}
+	_, err := d.deliveries.SendWebhook(ctx, deliveries.Webhook{
+		OrgID:    schedule.OrganizationID,
+		Source:   deliveries.SourceReportSchedule,
+		SourceID: schedule.ID,
+		Event:    payload.Event,
+		URL:      *schedule.WebhookURL,
+		Secret:   secret,
+		Payload:  payload,
+	})
+	return err
+}
@@ -0,0 +1,271 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/findings"
+)
+
+// LocalBackend renders Markdown and HTML reports from the findings alone,
+// without the brain's analysis. It keeps reports available while the brain
+// service is down and serves as the baseline in backend experiments.
+type LocalBackend struct{}
+
+var _ ReportBackend = (*LocalBackend)(nil)
+
+func NewLocalBackend() *LocalBackend {
+	return &LocalBackend{}
+}
+
+func (b *LocalBackend) Supports(format string) bool {
+	return format == brain.FormatMarkdown || format == brain.FormatHTML
+}
+
+// Health always succeeds; the renderer has no dependencies
+func (b *LocalBackend) Health(ctx context.Context) error {
+	return nil
+}
+
+// localReport is the data the local templates render
+type localReport struct {
+	Title       string
+	Target      string
+	Generated   string
+	ScanType    string
+	ReportType  string
+	Summary     string
+	Sections    map[string]bool
+	Counts      []severityCount
+	Findings    []findings.Detail
+	Remediation []findings.Detail
+	Footer      string
+	Color       string
+}
+
+// generatedAt is the current time in the timezone the report is rendered in
+func generatedAt(metadata map[string]interface{}) string {
+	loc := time.UTC
+	if name, ok := metadata["timezone"].(string); ok {
+		if l, err := time.LoadLocation(name); err == nil {
+			loc = l
+		}
+	}
+	return time.Now().In(loc).Format("2006-01-02 15:04 MST")
+}
+
+type severityCount struct {
+	Severity string
+	Count    int
+}
+
+func (b *LocalBackend) GenerateReport(ctx context.Context, req brain.GenerateReportRequest) (*Rendered, error) {
+	if !b.Supports(req.Format) {
+		return nil, ErrFormatUnsupported
+	}
+
+	details, err := resultFindings(req.ScanResults)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := DefaultTemplate
+	if t, ok := req.Metadata["template"].(*ReportTemplate); ok && t != nil {
+		tmpl = *t
+	}
+	data := localReport{
+		Target:     resultString(req.ScanResults, "target"),
+		ScanType:   resultString(req.ScanResults, "scan_type"),
+		ReportType: req.ReportType,
+		Summary:    tmpl.ExecutiveSummary,
+		Sections:   map[string]bool{},
+	}
+	data.Title = fmt.Sprintf("%s %s report", data.ScanType, data.ReportType)
+	data.Generated = generatedAt(req.Metadata)
+	for _, section := range tmpl.Sections {
+		data.Sections[section] = true
+	}
+	if branding, ok := req.Metadata["branding"].(map[string]interface{}); ok {
+		data.Footer, _ = branding["footer"].(string)
+		data.Color, _ = branding["primary_color"].(string)
+	}
+
+	counts := map[string]int{}
+	for _, d := range details {
+		if findings.SeverityRank(d.Severity) > findings.SeverityRank(tmpl.MinSeverity) {
+			continue
+		}
+		counts[d.Severity]++
+		data.Findings = append(data.Findings, d)
+		if d.Remediation != nil && *d.Remediation != "" {
+			data.Remediation = append(data.Remediation, d)
+		}
+	}
+	for _, severity := range findings.Severities {
+		data.Counts = append(data.Counts, severityCount{Severity: severity, Count: counts[severity]})
+	}
+
+	var buf bytes.Buffer
+	if req.Format == brain.FormatHTML {
+		err = localHTMLTemplate.Execute(&buf, data)
+	} else {
+		err = localMarkdownTemplate.Execute(&buf, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return &Rendered{Content: buf.String()}, nil
+}
+
+// resultFindings reads the findings out of scan results, which hold
+// findings.Detail values when loaded by the gateway and decoded JSON when
+// sent by the client
+func resultFindings(results brain.ScanResults) ([]findings.Detail, error) {
+	switch v := results["vulnerabilities"].(type) {
+	case nil:
+		return nil, nil
+	case []findings.Detail:
+		return v, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vulnerabilities: %w", err)
+		}
+		var details []findings.Detail
+		if err := json.Unmarshal(data, &details); err != nil {
+			return nil, fmt.Errorf("invalid vulnerabilities: %w", err)
+		}
+		return details, nil
+	}
+}
+
+func resultString(results brain.ScanResults, key string) string {
+	if v, ok := results[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+var localFuncs = map[string]interface{}{
+	"deref": func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	},
+	"cvss": func(score *float64) string {
+		if score == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", *score)
+	},
+	"title": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+}
+
+var localMarkdownTemplate = template.Must(template.New("markdown").Funcs(localFuncs).Parse(`# {{title .Title}}
+
+**Target:** {{.Target}}
+
+**Generated:** {{.Generated}}
+{{if .Sections.executive_summary}}
+## Executive Summary
+
+{{if .Summary}}{{.Summary}}{{else}}This report lists {{len .Findings}} finding(s) from the {{.ScanType}} scan of {{.Target}}.{{end}}
+{{end}}{{if .Sections.risk_overview}}
+## Risk Overview
+
+| Severity | Findings |
+|----------|----------|
+{{range .Counts}}| {{title .Severity}} | {{.Count}} |
+{{end}}{{end}}{{if .Sections.findings}}
+## Findings
+{{range .Findings}}
+### {{.Title}}
+
+- **Severity:** {{title .Severity}}{{with cvss .CVSSScore}} (CVSS {{.}}){{end}}
+{{- with deref .AffectedComponent}}
+- **Affected component:** {{.}}{{end}}
+{{- with deref .CVEID}}
+- **CVE:** {{.}}{{end}}
+{{- if .Suppressed}}
+- **Suppressed:** {{deref .SuppressionJustification}}{{end}}
+
+{{.Description}}
+{{else}}
+No findings.
+{{end}}{{end}}{{if and .Sections.remediation .Remediation}}
+## Remediation
+{{range .Remediation}}
+- **{{.Title}}:** {{deref .Remediation}}{{end}}
+{{end}}{{if .Sections.methodology}}
+## Methodology
+
+Findings were collected by a {{.ScanType}} scan and are listed as stored, without further analysis.
+{{end}}{{with .Footer}}
+---
+
+{{.}}
+{{end}}`))
+
+var localHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(localFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{title .Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; }
+h1, h2 { color: {{if .Color}}{{.Color}}{{else}}#1f2937{{end}}; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #d1d5db; padding: 4px 12px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>{{title .Title}}</h1>
+<p><strong>Target:</strong> {{.Target}}</p>
+<p><strong>Generated:</strong> {{.Generated}}</p>
+{{if .Sections.executive_summary}}
+<h2>Executive Summary</h2>
+<p>{{if .Summary}}{{.Summary}}{{else}}This report lists {{len .Findings}} finding(s) from the {{.ScanType}} scan of {{.Target}}.{{end}}</p>
+{{end}}{{if .Sections.risk_overview}}
+<h2>Risk Overview</h2>
+<table>
+<tr><th>Severity</th><th>Findings</th></tr>
+{{range .Counts}}<tr><td>{{title .Severity}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Sections.findings}}
+<h2>Findings</h2>
+{{range .Findings}}
+<h3>{{.Title}}</h3>
+<ul>
+<li><strong>Severity:</strong> {{title .Severity}}{{with cvss .CVSSScore}} (CVSS {{.}}){{end}}</li>
+{{with deref .AffectedComponent}}<li><strong>Affected component:</strong> {{.}}</li>{{end}}
+{{with deref .CVEID}}<li><strong>CVE:</strong> {{.}}</li>{{end}}
+{{if .Suppressed}}<li><strong>Suppressed:</strong> {{deref .SuppressionJustification}}</li>{{end}}
+</ul>
+<p>{{.Description}}</p>
+{{else}}
+<p>No findings.</p>
+{{end}}{{end}}{{if and .Sections.remediation .Remediation}}
+<h2>Remediation</h2>
+<ul>
+{{range .Remediation}}<li><strong>{{.Title}}:</strong> {{deref .Remediation}}</li>
+{{end}}</ul>
+{{end}}{{if .Sections.methodology}}
+<h2>Methodology</h2>
+<p>Findings were collected by a {{.ScanType}} scan and are listed as stored, without further analysis.</p>
+{{end}}{{with .Footer}}<footer>{{.}}</footer>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,192 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/flags"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// RouterConfig chooses the backend each report is rendered by
+type RouterConfig struct {
+	// Default renders report types without a route
+	Default string
+	// Routes maps report types to their backend
+	Routes map[string]string
+	// Fallback renders a report when its backend fails or does not support
+	// the format; empty disables fallback
+	Fallback string
+	// DownFor is how long a failed backend is skipped in favour of the
+	// fallback before it is tried again
+	DownFor time.Duration
+	// Experiments move reports to another backend for the users and
+	// organizations a feature flag is on for; the first match wins
+	Experiments []Experiment
+}
+
+// Experiment routes reports to Backend while Flag is on for the requester,
+// e.g. to roll out a renderer by percentage or compare two side by side
+type Experiment struct {
+	Flag        string
+	Backend     string
+	ReportTypes []string // Empty for every report type
+}
+
+func DefaultRouterConfig() RouterConfig {
+	return RouterConfig{
+		Default: BackendBrain,
+		DownFor: 30 * time.Second,
+	}
+}
+
+// Router renders reports through the backend configured for their type and
+// requester, falling back to another when it is down
+type Router struct {
+	backends map[string]ReportBackend
+	config   RouterConfig
+	flags    *flags.Service
+	logger   *zap.Logger
+
+	mu   sync.Mutex
+	down map[string]time.Time // Backend name to when it may be tried again
+}
+
+// NewRouter checks that every backend the config names is registered.
+// flagService may be nil when there are no experiments.
+func NewRouter(backends map[string]ReportBackend, config RouterConfig, flagService *flags.Service, logger *zap.Logger) (*Router, error) {
+	names := []string{config.Default}
+	for _, backend := range config.Routes {
+		names = append(names, backend)
+	}
+	if config.Fallback != "" {
+		names = append(names, config.Fallback)
+	}
+	for _, e := range config.Experiments {
+		if e.Flag == "" {
+			return nil, errors.New("report backend experiment needs a feature flag")
+		}
+		names = append(names, e.Backend)
+	}
+	for _, name := range names {
+		if _, ok := backends[name]; !ok {
+			return nil, fmt.Errorf("unknown report backend %q", name)
+		}
+	}
+	if len(config.Experiments) > 0 && flagService == nil {
+		return nil, errors.New("report backend experiments need the feature flag service")
+	}
+
+	return &Router{
+		backends: backends,
+		config:   config,
+		flags:    flagService,
+		logger:   logger,
+		down:     map[string]time.Time{},
+	}, nil
+}
+
+// Select returns the backend a report should be rendered by for target
+func (r *Router) Select(ctx context.Context, reportType string, target flags.Target) string {
+	for _, e := range r.config.Experiments {
+		if !matchesReportType(e.ReportTypes, reportType) {
+			continue
+		}
+		if r.flags.Evaluate(ctx, e.Flag, target) {
+			return e.Backend
+		}
+	}
+	if backend, ok := r.config.Routes[reportType]; ok {
+		return backend
+	}
+	return r.config.Default
+}
+
+func matchesReportType(reportTypes []string, reportType string) bool {
+	if len(reportTypes) == 0 {
+		return true
+	}
+	for _, t := range reportTypes {
+		if t == reportType {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate renders a report with its selected backend, or with the fallback
+// when that backend is down, fails or cannot render the format
+func (r *Router) Generate(ctx context.Context, req brain.GenerateReportRequest, target flags.Target) (*Rendered, error) {
+	primary := r.Select(ctx, req.ReportType, target)
+	attempts := []string{primary}
+	if r.config.Fallback != "" && r.config.Fallback != primary {
+		if r.isDown(primary) {
+			attempts = []string{r.config.Fallback}
+		} else {
+			attempts = append(attempts, r.config.Fallback)
+		}
+	}
+
+	var errs []error
+	for i, name := range attempts {
+		backend := r.backends[name]
+		if !backend.Supports(req.Format) {
+			errs = append(errs, fmt.Errorf("%s: %w", name, ErrFormatUnsupported))
+			continue
+		}
+
+		rendered, err := backend.GenerateReport(ctx, req)
+		if err != nil {
+			metrics.ReportBackendRequests.WithLabelValues(name, "error").Inc()
+			r.markDown(name)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		outcome := "success"
+		if name != primary || i > 0 {
+			outcome = "fallback"
+			r.logger.Warn("Rendered report with fallback backend",
+				zap.String("primary", primary),
+				zap.String("backend", name),
+				zap.Errors("errors", errs),
+			)
+		}
+		metrics.ReportBackendRequests.WithLabelValues(name, outcome).Inc()
+		rendered.Backend = name
+		return rendered, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (r *Router) isDown(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Now().Before(r.down[name])
+}
+
+func (r *Router) markDown(name string) {
+	if r.config.DownFor <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.down[name] = time.Now().Add(r.config.DownFor)
+}
+
+// Health succeeds while the default backend or the fallback is up
+func (r *Router) Health(ctx context.Context) error {
+	err := r.backends[r.config.Default].Health(ctx)
+	if err == nil || r.config.Fallback == "" || r.config.Fallback == r.config.Default {
+		return err
+	}
+	if fallbackErr := r.backends[r.config.Fallback].Health(ctx); fallbackErr != nil {
+		return errors.Join(err, fallbackErr)
+	}
+	return nil
+}
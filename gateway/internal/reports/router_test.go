@@ -0,0 +1,235 @@
+package reports_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/flags"
+	"github.com/cyper-security/gateway/internal/mocks"
+	"github.com/cyper-security/gateway/internal/reports"
+	"go.uber.org/zap"
+)
+
+// newBackend renders the formats given, failing with err when it is set
+func newBackend(err error, formats ...string) *mocks.ReportBackendMock {
+	return &mocks.ReportBackendMock{
+		SupportsFunc: func(format string) bool {
+			for _, f := range formats {
+				if f == format {
+					return true
+				}
+			}
+			return false
+		},
+		GenerateReportFunc: func(ctx context.Context, req brain.GenerateReportRequest) (*reports.Rendered, error) {
+			if err != nil {
+				return nil, err
+			}
+			return &reports.Rendered{Content: "# " + req.ReportType}, nil
+		},
+		HealthFunc: func(context.Context) error { return err },
+	}
+}
+
+func newRouter(t *testing.T, backends map[string]*mocks.ReportBackendMock, config reports.RouterConfig) *reports.Router {
+	t.Helper()
+	registered := map[string]reports.ReportBackend{}
+	for name, b := range backends {
+		registered[name] = b
+	}
+	router, err := reports.NewRouter(registered, config, nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	return router
+}
+
+func generate(router *reports.Router, reportType, format string) (*reports.Rendered, error) {
+	return router.Generate(context.Background(), brain.GenerateReportRequest{ReportType: reportType, Format: format}, flags.Target{UserID: "user-1"})
+}
+
+func TestRouterSelectsBackend(t *testing.T) {
+	remote := newBackend(nil, brain.FormatMarkdown, brain.FormatPDF)
+	local := newBackend(nil, brain.FormatMarkdown)
+	router := newRouter(t, map[string]*mocks.ReportBackendMock{reports.BackendBrain: remote, reports.BackendLocal: local}, reports.RouterConfig{
+		Default: reports.BackendBrain,
+		Routes:  map[string]string{"executive": reports.BackendLocal},
+	})
+
+	tests := []struct {
+		reportType string
+		backend    string
+	}{
+		{"technical", reports.BackendBrain},
+		{"executive", reports.BackendLocal},
+	}
+	for _, tt := range tests {
+		if got := router.Select(context.Background(), tt.reportType, flags.Target{}); got != tt.backend {
+			t.Errorf("Select(%s) = %s, want %s", tt.reportType, got, tt.backend)
+		}
+		rendered, err := generate(router, tt.reportType, brain.FormatMarkdown)
+		if err != nil {
+			t.Fatalf("Generate(%s): %v", tt.reportType, err)
+		}
+		if rendered.Backend != tt.backend || rendered.Content != "# "+tt.reportType {
+			t.Errorf("Generate(%s) = %+v, want it from %s", tt.reportType, rendered, tt.backend)
+		}
+	}
+	if len(remote.GenerateReportCalls()) != 1 || len(local.GenerateReportCalls()) != 1 {
+		t.Errorf("calls: brain %d, local %d; want one each", len(remote.GenerateReportCalls()), len(local.GenerateReportCalls()))
+	}
+}
+
+func TestRouterFallback(t *testing.T) {
+	brainDown := errors.New("brain: connection refused")
+
+	tests := []struct {
+		name    string
+		remote  *mocks.ReportBackendMock
+		local   *mocks.ReportBackendMock
+		format  string
+		backend string // Empty when no backend renders the report
+		errIs   error
+	}{
+		{
+			name:    "primary up",
+			remote:  newBackend(nil, brain.FormatMarkdown),
+			local:   newBackend(nil, brain.FormatMarkdown),
+			format:  brain.FormatMarkdown,
+			backend: reports.BackendBrain,
+		},
+		{
+			name:    "primary fails",
+			remote:  newBackend(brainDown, brain.FormatMarkdown),
+			local:   newBackend(nil, brain.FormatMarkdown),
+			format:  brain.FormatMarkdown,
+			backend: reports.BackendLocal,
+		},
+		{
+			name:    "primary lacks the format",
+			remote:  newBackend(nil, brain.FormatMarkdown),
+			local:   newBackend(nil, brain.FormatMarkdown, brain.FormatHTML),
+			format:  brain.FormatHTML,
+			backend: reports.BackendLocal,
+		},
+		{
+			name:   "both fail",
+			remote: newBackend(brainDown, brain.FormatMarkdown),
+			local:  newBackend(errors.New("template error"), brain.FormatMarkdown),
+			format: brain.FormatMarkdown,
+			errIs:  brainDown,
+		},
+		{
+			name:   "neither renders the format",
+			remote: newBackend(nil, brain.FormatMarkdown),
+			local:  newBackend(nil, brain.FormatMarkdown),
+			format: brain.FormatPDF,
+			errIs:  reports.ErrFormatUnsupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRouter(t, map[string]*mocks.ReportBackendMock{reports.BackendBrain: tt.remote, reports.BackendLocal: tt.local}, reports.RouterConfig{
+				Default:  reports.BackendBrain,
+				Fallback: reports.BackendLocal,
+			})
+			rendered, err := generate(router, "technical", tt.format)
+			if tt.backend == "" {
+				if !errors.Is(err, tt.errIs) {
+					t.Fatalf("Generate = %v, want %v", err, tt.errIs)
+				}
+				// Both backends' errors are reported
+				if !strings.Contains(err.Error(), reports.BackendBrain+":") || !strings.Contains(err.Error(), reports.BackendLocal+":") {
+					t.Errorf("error %q does not name both backends", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if rendered.Backend != tt.backend {
+				t.Errorf("rendered by %s, want %s", rendered.Backend, tt.backend)
+			}
+		})
+	}
+}
+
+func TestRouterWithoutFallback(t *testing.T) {
+	remote := newBackend(errors.New("brain: 503"), brain.FormatMarkdown)
+	local := newBackend(nil, brain.FormatMarkdown)
+	router := newRouter(t, map[string]*mocks.ReportBackendMock{reports.BackendBrain: remote, reports.BackendLocal: local}, reports.RouterConfig{
+		Default: reports.BackendBrain,
+	})
+
+	if _, err := generate(router, "technical", brain.FormatMarkdown); err == nil {
+		t.Fatal("Generate succeeded with the only backend failing")
+	}
+	if n := len(local.GenerateReportCalls()); n != 0 {
+		t.Errorf("local backend called %d times without being the fallback", n)
+	}
+}
+
+func TestRouterSkipsDownBackend(t *testing.T) {
+	remote := newBackend(errors.New("brain: timeout"), brain.FormatMarkdown)
+	local := newBackend(nil, brain.FormatMarkdown)
+	router := newRouter(t, map[string]*mocks.ReportBackendMock{reports.BackendBrain: remote, reports.BackendLocal: local}, reports.RouterConfig{
+		Default:  reports.BackendBrain,
+		Fallback: reports.BackendLocal,
+		DownFor:  time.Hour,
+	})
+
+	for i := 0; i < 3; i++ {
+		rendered, err := generate(router, "technical", brain.FormatMarkdown)
+		if err != nil || rendered.Backend != reports.BackendLocal {
+			t.Fatalf("Generate #%d = %+v, %v; want the fallback", i+1, rendered, err)
+		}
+	}
+	// Tried once, then left alone while down
+	if n := len(remote.GenerateReportCalls()); n != 1 {
+		t.Errorf("brain called %d times, want 1", n)
+	}
+}
+
+func TestNewRouterRejectsUnknownBackends(t *testing.T) {
+	backends := map[string]reports.ReportBackend{reports.BackendBrain: newBackend(nil)}
+	for _, config := range []reports.RouterConfig{
+		{Default: reports.BackendLocal},
+		{Default: reports.BackendBrain, Fallback: reports.BackendLocal},
+		{Default: reports.BackendBrain, Routes: map[string]string{"executive": "typst"}},
+		{Default: reports.BackendBrain, Experiments: []reports.Experiment{{Flag: "local_reports", Backend: reports.BackendBrain}}}, // No flag service
+	} {
+		if _, err := reports.NewRouter(backends, config, nil, zap.NewNop()); err == nil {
+			t.Errorf("NewRouter(%+v) succeeded", config)
+		}
+	}
+}
+
+func TestRouterHealth(t *testing.T) {
+	down := errors.New("down")
+	tests := []struct {
+		name          string
+		remote, local error
+		fallback      string
+		healthy       bool
+	}{
+		{"default up", nil, down, reports.BackendLocal, true},
+		{"fallback up", down, nil, reports.BackendLocal, true},
+		{"both down", down, down, reports.BackendLocal, false},
+		{"no fallback", down, nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRouter(t, map[string]*mocks.ReportBackendMock{
+				reports.BackendBrain: newBackend(tt.remote),
+				reports.BackendLocal: newBackend(tt.local),
+			}, reports.RouterConfig{Default: reports.BackendBrain, Fallback: tt.fallback})
+			if err := router.Health(context.Background()); (err == nil) != tt.healthy {
+				t.Errorf("Health = %v, want healthy %v", err, tt.healthy)
+			}
+		})
+	}
+}
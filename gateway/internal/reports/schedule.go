@@ -0,0 +1,71 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Schedule frequencies
+const (
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Schedule generates reports for an organization's recent scans on a fixed cadence
+type Schedule struct {
+	ID              string         `json:"id" db:"id"`
+	OrganizationID  string         `json:"organization_id" db:"organization_id"`
+	Name            string         `json:"name" db:"name"`
+	Frequency       string         `json:"frequency" db:"frequency"`
+	DayOfWeek       int            `json:"day_of_week" db:"day_of_week"`
+	DayOfMonth      int            `json:"day_of_month" db:"day_of_month"`
+	HourUTC         int            `json:"hour_utc" db:"hour_utc"`
+	TargetValues    pq.StringArray `json:"target_values" db:"target_values"`
+	ScanTypes       pq.StringArray `json:"scan_types" db:"scan_types"`
+	ReportType      string         `json:"report_type" db:"report_type"`
+	Format          string         `json:"format" db:"format"`
+	TemplateID      *string        `json:"template_id,omitempty" db:"template_id"`
+	EmailRecipients pq.StringArray `json:"email_recipients" db:"email_recipients"`
+	WebhookURL      *string        `json:"webhook_url,omitempty" db:"webhook_url"`
+	WebhookSecret   *string        `json:"-" db:"webhook_secret"`
+	Enabled         bool           `json:"enabled" db:"enabled"`
+	NextRunAt       time.Time      `json:"next_run_at" db:"next_run_at"`
+	LastRunAt       *time.Time     `json:"last_run_at,omitempty" db:"last_run_at"`
+	CreatedBy       *string        `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// ScheduleColumns selects every Schedule field
+const ScheduleColumns = `id, organization_id, name, frequency, day_of_week, day_of_month, hour_utc,
+	target_values, scan_types, report_type, format, template_id, email_recipients,
+	webhook_url, webhook_secret, enabled, next_run_at, last_run_at, created_by, created_at, updated_at`
+
+// NextRun returns the first run time strictly after the given time, in UTC
+func (s *Schedule) NextRun(after time.Time) time.Time {
+	after = after.UTC()
+
+	if s.Frequency == FrequencyMonthly {
+		next := time.Date(after.Year(), after.Month(), s.DayOfMonth, s.HourUTC, 0, 0, 0, time.UTC)
+		if !next.After(after) {
+			next = next.AddDate(0, 1, 0)
+		}
+		return next
+	}
+
+	next := time.Date(after.Year(), after.Month(), after.Day(), s.HourUTC, 0, 0, 0, time.UTC)
+	next = next.AddDate(0, 0, (s.DayOfWeek-int(next.Weekday())+7)%7)
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// Period is how far back a run looks for scans when it has not run before
+func (s *Schedule) Period() time.Duration {
+	if s.Frequency == FrequencyMonthly {
+		return 31 * 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
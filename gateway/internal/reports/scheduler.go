@@ -0,0 +1,174 @@
+package reports
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/brain"
+	"go.uber.org/zap"
+)
+
+// maxDueSchedules bounds how many schedules one tick claims
+const maxDueSchedules = 10
+
+// StartScheduler periodically runs due report schedules. Schedules are claimed
+// with SKIP LOCKED so several gateway instances can run the scheduler.
+func StartScheduler(ctx context.Context, service *Service, deliverer *Deliverer, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info("Starting report scheduler", zap.Duration("interval", interval))
+
+	for {
+		select {
+		case <-ticker.C:
+			runDue(ctx, service, deliverer, logger)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func runDue(ctx context.Context, service *Service, deliverer *Deliverer, logger *zap.Logger) {
+	due, err := service.claimDue(ctx)
+	if err != nil {
+		logger.Error("Failed to claim report schedules", zap.Error(err))
+		return
+	}
+
+	for _, claimed := range due {
+		service.runSchedule(ctx, deliverer, claimed.schedule, claimed.since, logger)
+	}
+}
+
+type claimedSchedule struct {
+	schedule *Schedule
+	since    time.Time
+}
+
+// claimDue advances next_run_at for due schedules and returns them with the
+// start of their reporting window
+func (s *Service) claimDue(ctx context.Context) ([]claimedSchedule, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var schedules []Schedule
+	err = tx.SelectContext(ctx, &schedules, `
+		SELECT `+ScheduleColumns+` FROM report_schedules
+		WHERE enabled AND next_run_at <= NOW()
+		ORDER BY next_run_at
+		FOR UPDATE SKIP LOCKED
+		LIMIT $1
+	`, maxDueSchedules)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	claimed := make([]claimedSchedule, 0, len(schedules))
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		since := now.Add(-schedule.Period())
+		if schedule.LastRunAt != nil {
+			since = *schedule.LastRunAt
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE report_schedules SET next_run_at = $2, last_run_at = $3 WHERE id = $1
+		`, schedule.ID, schedule.NextRun(now), now)
+		if err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, claimedSchedule{schedule: schedule, since: since})
+	}
+
+	return claimed, tx.Commit()
+}
+
+// runSchedule generates a report for the latest matching scan of each target
+// completed since the previous run, then delivers the download links
+func (s *Service) runSchedule(ctx context.Context, deliverer *Deliverer, schedule *Schedule, since time.Time, logger *zap.Logger) {
+	logger = logger.With(zap.String("schedule_id", schedule.ID), zap.String("organization_id", schedule.OrganizationID))
+
+	var scanIDs []string
+	err := s.db.SelectContext(ctx, &scanIDs, `
+		SELECT id FROM (
+			SELECT DISTINCT ON (st.target_value, sj.scan_type) sj.id, sj.completed_at
+			FROM scan_jobs sj
+			JOIN scan_targets st ON st.id = sj.target_id
+			WHERE sj.organization_id = $1
+			AND sj.status = 'completed'
+			AND sj.completed_at > $2
+			AND (cardinality($3::text[]) = 0 OR st.target_value = ANY($3::text[]))
+			AND (cardinality($4::text[]) = 0 OR sj.scan_type = ANY($4::text[]))
+			ORDER BY st.target_value, sj.scan_type, sj.completed_at DESC
+		) latest
+		ORDER BY completed_at DESC
+	`, schedule.OrganizationID, since, schedule.TargetValues, schedule.ScanTypes)
+	if err != nil {
+		logger.Error("Failed to select scans for report schedule", zap.Error(err))
+		return
+	}
+
+	templateID := ""
+	if schedule.TemplateID != nil {
+		templateID = *schedule.TemplateID
+	}
+	createdBy := ""
+	if schedule.CreatedBy != nil {
+		createdBy = *schedule.CreatedBy
+	}
+
+	generated := make([]*Report, 0, len(scanIDs))
+	for _, scanID := range scanIDs {
+		report, err := s.Generate(ctx, GenerateParams{
+			ScanID:     scanID,
+			OrgID:      schedule.OrganizationID,
+			TemplateID: templateID,
+			Source:     SourceScheduled,
+			ScheduleID: schedule.ID,
+			// Scheduled reports follow their creator's timezone and locale
+			PreferencesUserID: createdBy,
+			Request: brain.GenerateReportRequest{
+				ReportType: schedule.ReportType,
+				Format:     schedule.Format,
+			},
+		})
+		if err != nil {
+			logger.Error("Failed to generate scheduled report", zap.String("scan_id", scanID), zap.Error(err))
+			continue
+		}
+		generated = append(generated, report)
+	}
+
+	deliveryErr := deliverer.Deliver(ctx, schedule, generated)
+	if deliveryErr != nil {
+		logger.Warn("Failed to deliver scheduled reports", zap.Error(deliveryErr))
+	}
+
+	for _, report := range generated {
+		var errMsg *string
+		if deliveryErr != nil {
+			msg := deliveryErr.Error()
+			errMsg = &msg
+		}
+		_, err := s.db.ExecContext(ctx, `
+			UPDATE reports
+			SET delivered_at = CASE WHEN $2::text IS NULL THEN NOW() END, delivery_error = $2
+			WHERE id = $1
+		`, report.ID, errMsg)
+		if err != nil {
+			logger.Error("Failed to record report delivery", zap.String("report_id", report.ID), zap.Error(err))
+		}
+	}
+
+	logger.Info("Report schedule ran",
+		zap.Int("scans", len(scanIDs)),
+		zap.Int("reports", len(generated)),
+		zap.Bool("delivered", deliveryErr == nil),
+	)
+}
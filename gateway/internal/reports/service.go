@@ -0,0 +1,377 @@
+// Package reports generates, stores and delivers scan reports.
+package reports
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/billing"
+	"github.com/cyper-security/gateway/internal/brain"
+	"github.com/cyper-security/gateway/internal/branding"
+	"github.com/cyper-security/gateway/internal/compliance"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/flags"
+	"github.com/cyper-security/gateway/internal/preferences"
+	"github.com/cyper-security/gateway/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Report sources
+const (
+	SourceManual    = "manual"
+	SourceScheduled = "scheduled"
+)
+
+var (
+	ErrScanNotFound       = errors.New("scan not found")
+	ErrTemplateNotFound   = errors.New("report template not found")
+	ErrUnknownFormat      = errors.New("format must be one of markdown, html, pdf, sarif")
+	ErrBackendUnavailable = errors.New("report backend failed to generate report")
+)
+
+// ContentTypes maps report formats to their MIME types
+var ContentTypes = map[string]string{
+	brain.FormatMarkdown: "text/markdown; charset=utf-8",
+	brain.FormatHTML:     "text/html; charset=utf-8",
+	brain.FormatPDF:      "application/pdf",
+	brain.FormatSARIF:    "application/sarif+json",
+}
+
+// Extensions maps report formats to download file extensions
+var Extensions = map[string]string{
+	brain.FormatMarkdown: "md",
+	brain.FormatHTML:     "html",
+	brain.FormatPDF:      "pdf",
+	brain.FormatSARIF:    "sarif",
+}
+
+// DownloadPath is the API path serving a stored report
+func DownloadPath(reportID string) string {
+	return "/api/v1/reports/" + reportID + "/download"
+}
+
+// Report is a stored report. Content is set for text formats; PDFs are only
+// available from DownloadURL.
+type Report struct {
+	ID            string     `json:"id" db:"id"`
+	ScanID        string     `json:"scan_id" db:"scan_job_id"`
+	ReportType    string     `json:"report_type" db:"report_type"`
+	Format        string     `json:"format" db:"format"`
+	ContentType   string     `json:"content_type" db:"content_type"`
+	Source        string     `json:"source" db:"source"`
+	Backend       string     `json:"backend,omitempty" db:"-"` // Renderer, when just generated
+	ScheduleID    *string    `json:"schedule_id,omitempty" db:"schedule_id"`
+	Content       string     `json:"content,omitempty" db:"-"`
+	DownloadURL   string     `json:"download_url" db:"-"`
+	GeneratedAt   time.Time  `json:"generated_at" db:"generated_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	DeliveryError *string    `json:"delivery_error,omitempty" db:"delivery_error"`
+}
+
+// GenerateParams describes one report to generate
+type GenerateParams struct {
+	ScanID     string
+	OrgID      string
+	UserID     string // empty for scheduled reports
+	TemplateID string
+	Source     string
+	ScheduleID string
+	// PreferencesUserID is the user whose timezone and locale the report is
+	// rendered in; UserID when empty
+	PreferencesUserID string
+	// IncludeSuppressed keeps findings silenced by suppression rules in the
+	// report, flagged as suppressed
+	IncludeSuppressed bool
+	Request           brain.GenerateReportRequest
+}
+
+// Service renders reports through the configured backends and stores them.
+// Text formats are kept in the database; binary files go to artifact storage.
+type Service struct {
+	db       *database.DB
+	backends *Router
+	store    storage.Store
+	prefs    *preferences.Service
+	logger   *zap.Logger
+}
+
+func NewService(db *database.DB, backends *Router, store storage.Store, logger *zap.Logger) *Service {
+	return &Service{
+		db:       db,
+		backends: backends,
+		store:    store,
+		logger:   logger,
+	}
+}
+
+// SetPreferences renders reports in the requesting user's timezone and locale
+func (s *Service) SetPreferences(prefs *preferences.Service) {
+	s.prefs = prefs
+}
+
+// StorageKey is where a report's file is kept in artifact storage
+func StorageKey(orgID, reportID, format string) string {
+	if orgID == "" {
+		orgID = "unscoped"
+	}
+	return fmt.Sprintf("reports/%s/%s.%s", orgID, reportID, Extensions[format])
+}
+
+// Generate renders a report for a scan in the requested format and stores it.
+// SARIF is built from stored findings; other formats go through the report
+// backends, and when the request carries no scan results they are loaded from
+// the database.
+func (s *Service) Generate(ctx context.Context, p GenerateParams) (*Report, error) {
+	req := p.Request
+	if req.Format == "" {
+		req.Format = brain.FormatMarkdown
+	}
+	contentType, ok := ContentTypes[req.Format]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	if req.ReportType == "" {
+		req.ReportType = "technical"
+	}
+	if p.Source == "" {
+		p.Source = SourceManual
+	}
+
+	scan, err := s.loadScan(ctx, p.ScanID, p.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	reportID := uuid.New().String()
+	report := &Report{
+		ID:          reportID,
+		ScanID:      p.ScanID,
+		ReportType:  req.ReportType,
+		Format:      req.Format,
+		ContentType: contentType,
+		Source:      p.Source,
+		DownloadURL: DownloadPath(reportID),
+	}
+	if p.ScheduleID != "" {
+		report.ScheduleID = &p.ScheduleID
+	}
+	var filePath, storageKey *string
+	var sizeBytes int
+
+	if req.Format == brain.FormatSARIF {
+		sarif, err := s.buildSARIF(ctx, p.ScanID, scan.TargetValue, p.IncludeSuppressed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build SARIF report: %w", err)
+		}
+		report.Content = string(sarif)
+		sizeBytes = len(sarif)
+	} else {
+		if req.Metadata == nil {
+			req.Metadata = make(map[string]interface{})
+		}
+		req.Metadata["scan_id"] = p.ScanID
+		req.Metadata["target"] = scan.TargetValue
+		req.Metadata["include_suppressed"] = p.IncludeSuppressed
+
+		// Customize output with the requested or default report template
+		template, err := ResolveTemplate(ctx, s.db, p.OrgID, p.TemplateID)
+		if err == sql.ErrNoRows {
+			return nil, ErrTemplateNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve report template: %w", err)
+		}
+		req.Metadata["template"] = template
+
+		// Render dates and text for the user the report is for
+		prefsUserID := p.PreferencesUserID
+		if prefsUserID == "" {
+			prefsUserID = p.UserID
+		}
+		prefs := s.prefs.Lookup(ctx, prefsUserID)
+		req.Metadata["timezone"] = prefs.Location().String()
+		if prefs.Locale != nil {
+			req.Metadata["locale"] = *prefs.Locale
+		}
+
+		// White-label the report with the organization's branding
+		if p.OrgID != "" {
+			settings, err := branding.Load(ctx, s.db, p.OrgID)
+			if err != nil {
+				return nil, err
+			}
+			if err := settings.SignLogo(ctx, s.store); err != nil {
+				s.logger.Warn("Failed to sign logo URL for report", zap.Error(err))
+			}
+			req.Metadata["branding"] = settings.ReportMetadata()
+		}
+
+		if req.ScanResults == nil {
+			if req.ScanResults, err = s.loadScanResults(ctx, p.ScanID, scan.ScanType, scan.TargetValue, p.IncludeSuppressed); err != nil {
+				return nil, fmt.Errorf("failed to load scan results: %w", err)
+			}
+		}
+
+		// Compliance report types are named after their framework; the brain
+		// writes them from the scan's control assessment
+		if framework, ok := compliance.LookupFramework(req.ReportType); ok {
+			openFindings, err := compliance.LoadOpenFindings(ctx, s.db, p.OrgID, p.ScanID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load findings for compliance: %w", err)
+			}
+			assessment := compliance.Assess(framework, openFindings)
+			assessment.ScanID = p.ScanID
+			req.ScanResults["compliance"] = assessment
+			req.Metadata["compliance"] = assessment
+		}
+		if req.Format == brain.FormatPDF {
+			req.OutputPath = fmt.Sprintf("/reports/%s.pdf", reportID)
+		}
+
+		rendered, err := s.backends.Generate(ctx, req, flags.Target{UserID: p.UserID, OrgID: p.OrgID})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackendUnavailable, err)
+		}
+		report.Backend = rendered.Backend
+		req.Metadata["backend"] = rendered.Backend
+
+		if rendered.File != nil {
+			if rendered.Path != "" {
+				filePath = &rendered.Path
+			}
+
+			key := StorageKey(p.OrgID, reportID, req.Format)
+			if _, err := s.store.Put(ctx, key, bytes.NewReader(rendered.File)); err != nil {
+				return nil, fmt.Errorf("failed to store report file: %w", err)
+			}
+			storageKey = &key
+			sizeBytes = len(rendered.File)
+		} else {
+			report.Content = rendered.Content
+			sizeBytes = len(rendered.Content)
+		}
+	}
+
+	metadata, err := json.Marshal(req.Metadata)
+	if err != nil {
+		metadata = []byte("{}")
+	}
+
+	// Organizations' reports are billed, in the transaction storing them
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err == nil {
+		defer tx.Rollback()
+		err = tx.GetContext(ctx, &report.GeneratedAt, `
+			INSERT INTO reports (
+				id, scan_job_id, organization_id, report_type, title, content, pdf_path, metadata,
+				generated_by, format, content_type, storage_key, source, schedule_id, size_bytes
+			) VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, NULLIF($6, ''), $7, $8,
+				NULLIF($9, '')::uuid, $10, $11, $12, $13, NULLIF($14, '')::uuid, $15)
+			RETURNING generated_at
+		`, reportID, p.ScanID, p.OrgID, req.ReportType, fmt.Sprintf("%s %s report", scan.ScanType, req.ReportType),
+			report.Content, filePath, metadata, p.UserID, req.Format, contentType, storageKey, p.Source, p.ScheduleID, sizeBytes)
+	}
+	if err == nil && p.OrgID != "" {
+		err = billing.Record(ctx, tx, billing.ReportGenerated(p.OrgID, reportID))
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if storageKey != nil {
+			if delErr := s.store.Delete(ctx, *storageKey); delErr != nil {
+				s.logger.Warn("Failed to remove orphaned report file", zap.String("key", *storageKey), zap.Error(delErr))
+			}
+		}
+		return nil, fmt.Errorf("failed to store report: %w", err)
+	}
+
+	return report, nil
+}
+
+// AnalysisInput assembles the brain request analyzing a scan's stored findings
+func (s *Service) AnalysisInput(ctx context.Context, scanID, orgID string) (brain.AnalyzeRequest, error) {
+	scan, err := s.loadScan(ctx, scanID, orgID)
+	if err != nil {
+		return brain.AnalyzeRequest{}, err
+	}
+	results, err := s.loadScanResults(ctx, scanID, scan.ScanType, scan.TargetValue, false)
+	if err != nil {
+		return brain.AnalyzeRequest{}, fmt.Errorf("failed to load scan results: %w", err)
+	}
+	return brain.AnalyzeRequest{ScanType: scan.ScanType, ScanResults: results}, nil
+}
+
+type scanSummary struct {
+	ScanType    string `db:"scan_type"`
+	TargetValue string `db:"target_value"`
+}
+
+// loadScan loads a scan of the organization, or ErrScanNotFound
+func (s *Service) loadScan(ctx context.Context, scanID, orgID string) (*scanSummary, error) {
+	var scan scanSummary
+	err := s.db.GetContext(ctx, &scan, `
+		SELECT sj.scan_type, st.target_value
+		FROM scan_jobs sj
+		JOIN scan_targets st ON st.id = sj.target_id
+		WHERE sj.id = $1 AND sj.organization_id IS NOT DISTINCT FROM NULLIF($2, '')::uuid
+	`, scanID, orgID)
+	if err == sql.ErrNoRows {
+		return nil, ErrScanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scan: %w", err)
+	}
+	return &scan, nil
+}
+
+// buildSARIF renders a scan's findings as a SARIF log; included suppressed
+// findings carry a SARIF suppression
+func (s *Service) buildSARIF(ctx context.Context, scanID, target string, includeSuppressed bool) ([]byte, error) {
+	details, err := s.loadFindings(ctx, scanID, includeSuppressed)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(findings.SARIF(scanID, target, details), "", "  ")
+}
+
+// loadScanResults assembles brain input from a scan's stored findings
+func (s *Service) loadScanResults(ctx context.Context, scanID, scanType, target string, includeSuppressed bool) (brain.ScanResults, error) {
+	details, err := s.loadFindings(ctx, scanID, includeSuppressed)
+	if err != nil {
+		return nil, err
+	}
+	return brain.ScanResults{
+		"scan_id":         scanID,
+		"scan_type":       scanType,
+		"target":          target,
+		"vulnerabilities": details,
+	}, nil
+}
+
+// loadFindings loads a scan's findings other than false positives, and
+// without suppressed ones unless includeSuppressed is set
+func (s *Service) loadFindings(ctx context.Context, scanID string, includeSuppressed bool) ([]findings.Detail, error) {
+	details := []findings.Detail{}
+	err := s.db.Reader().SelectContext(ctx, &details, `
+		SELECT v.id, v.fingerprint, v.title, v.severity, v.cvss_score, v.category, v.affected_component,
+		       COALESCE(v.status, 'open') AS status, v.description, v.remediation,
+		       v.suppressed_at IS NOT NULL AS suppressed, COALESCE(r.justification, v.suppression_justification) AS suppression_justification,
+		       v.cve_id, v.cwe_ids, ci.epss_score, k.cve_id IS NOT NULL AS known_exploited
+		FROM vulnerabilities v
+		LEFT JOIN finding_suppression_rules r ON r.id = v.suppression_rule_id
+		LEFT JOIN cve_intel ci ON ci.cve_id = v.cve_id
+		LEFT JOIN known_exploited_cves k ON k.cve_id = v.cve_id
+		WHERE v.scan_job_id = $1 AND COALESCE(v.status, 'open') <> 'false_positive'
+		AND ($2 OR v.suppressed_at IS NULL)
+		ORDER BY v.cvss_score DESC NULLS LAST, v.title
+	`, scanID, includeSuppressed)
+	return details, err
+}
@@ -0,0 +1,96 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/lib/pq"
+)
+
+// SeverityThresholds maps severities to the minimum CVSS score that earns them
+type SeverityThresholds map[string]float64
+
+func (t SeverityThresholds) Value() (driver.Value, error) {
+	if t == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(t)
+}
+
+func (t *SeverityThresholds) Scan(src interface{}) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("unsupported severity thresholds type %T", src)
+	}
+	return json.Unmarshal(data, t)
+}
+
+// ReportTemplate customizes report output for an organization
+type ReportTemplate struct {
+	ID                 string             `json:"id" db:"id"`
+	OrganizationID     string             `json:"organization_id,omitempty" db:"organization_id"`
+	Name               string             `json:"name" db:"name"`
+	LogoURL            *string            `json:"logo_url,omitempty" db:"logo_url"`
+	Sections           pq.StringArray     `json:"sections" db:"sections"`
+	ExecutiveSummary   string             `json:"executive_summary" db:"executive_summary"`
+	MinSeverity        string             `json:"min_severity" db:"min_severity"`
+	SeverityThresholds SeverityThresholds `json:"severity_thresholds" db:"severity_thresholds"`
+	IsDefault          bool               `json:"is_default" db:"is_default"`
+	CreatedBy          *string            `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt          time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultTemplate is used when an organization has no default template
+var DefaultTemplate = ReportTemplate{
+	ID:          "builtin-default",
+	Name:        "Default",
+	Sections:    pq.StringArray{"executive_summary", "risk_overview", "findings", "remediation", "methodology"},
+	MinSeverity: "info",
+	SeverityThresholds: SeverityThresholds{
+		"critical": 9.0,
+		"high":     7.0,
+		"medium":   4.0,
+		"low":      0.1,
+	},
+}
+
+// TemplateColumns selects every ReportTemplate field
+const TemplateColumns = `id, organization_id, name, logo_url, sections, executive_summary,
+	min_severity, severity_thresholds, is_default, created_by, created_at, updated_at`
+
+// ResolveTemplate returns the requested template, else the organization's
+// default, else DefaultTemplate. An unknown templateID yields sql.ErrNoRows.
+func ResolveTemplate(ctx context.Context, db *database.DB, orgID, templateID string) (*ReportTemplate, error) {
+	if orgID == "" {
+		template := DefaultTemplate
+		return &template, nil
+	}
+
+	var template ReportTemplate
+	var err error
+	if templateID != "" {
+		err = db.GetContext(ctx, &template, `
+			SELECT `+TemplateColumns+` FROM report_templates
+			WHERE id::text = $1 AND organization_id = $2
+		`, templateID, orgID)
+	} else {
+		err = db.GetContext(ctx, &template, `
+			SELECT `+TemplateColumns+` FROM report_templates
+			WHERE organization_id = $1 AND is_default
+		`, orgID)
+		if err == sql.ErrNoRows {
+			template = DefaultTemplate
+			return &template, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+)
+
+// AuditFilter narrows an organization's audit log listing; empty fields
+// match everything
+type AuditFilter struct {
+	OrganizationID string
+	UserID         string
+	Action         string
+	Severity       string
+	Status         string
+	ResourceType   string
+	Start, End     *time.Time
+	Limit, Offset  int
+}
+
+// AuditRepo reads stored audit logs. Entries are written by audit.AuditLogger,
+// which signs and chains them. Listings are served from the read replica
+// when there is one.
+type AuditRepo interface {
+	Get(ctx context.Context, id int64) (*audit.AuditLog, error)
+	// List returns the organization's entries matching filter, newest first
+	List(ctx context.Context, filter AuditFilter) ([]audit.AuditLog, error)
+	// Range returns every entry in [start, end], newest first, or in ID
+	// order (the hash chain's) when byID is set
+	Range(ctx context.Context, start, end time.Time, byID bool) ([]audit.AuditLog, error)
+	// Page returns up to limit entries in [start, end] with IDs above afterID,
+	// in ID order, for walking large ranges
+	Page(ctx context.Context, start, end time.Time, afterID int64, limit int) ([]audit.AuditLog, error)
+	// Summary aggregates an organization's entries in a time range
+	Summary(ctx context.Context, filter AuditSummaryFilter) (*AuditSummary, error)
+}
+
+type auditRepo struct {
+	q Queryer
+}
+
+func NewAuditRepo(q Queryer) AuditRepo {
+	return &auditRepo{q: q}
+}
+
+func (r *auditRepo) Get(ctx context.Context, id int64) (*audit.AuditLog, error) {
+	var log audit.AuditLog
+	err := r.q.GetContext(ctx, &log, `SELECT * FROM audit_logs WHERE id = $1`, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &log, nil
+}
+
+func (r *auditRepo) List(ctx context.Context, f AuditFilter) ([]audit.AuditLog, error) {
+	logs := []audit.AuditLog{}
+	err := reader(r.q).SelectContext(ctx, &logs, `
+		SELECT * FROM audit_logs
+		WHERE organization_id = $1
+		AND ($2 = '' OR user_id::text = $2)
+		AND ($3 = '' OR action = $3)
+		AND ($4 = '' OR severity = $4)
+		AND ($5 = '' OR status = $5)
+		AND ($6 = '' OR resource_type = $6)
+		AND ($7::timestamp IS NULL OR timestamp >= $7)
+		AND ($8::timestamp IS NULL OR timestamp <= $8)
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $9 OFFSET $10
+	`, f.OrganizationID, f.UserID, f.Action, f.Severity, f.Status, f.ResourceType, f.Start, f.End, f.Limit, f.Offset)
+	return logs, err
+}
+
+func (r *auditRepo) Range(ctx context.Context, start, end time.Time, byID bool) ([]audit.AuditLog, error) {
+	order := "timestamp DESC"
+	if byID {
+		order = "id"
+	}
+	logs := []audit.AuditLog{}
+	err := reader(r.q).SelectContext(ctx, &logs, `
+		SELECT * FROM audit_logs
+		WHERE timestamp BETWEEN $1 AND $2
+		ORDER BY `+order, start, end)
+	return logs, err
+}
+
+func (r *auditRepo) Page(ctx context.Context, start, end time.Time, afterID int64, limit int) ([]audit.AuditLog, error) {
+	logs := []audit.AuditLog{}
+	err := reader(r.q).SelectContext(ctx, &logs, `
+		SELECT * FROM audit_logs
+		WHERE timestamp BETWEEN $1 AND $2
+		AND id > $3
+		ORDER BY id
+		LIMIT $4
+	`, start, end, afterID, limit)
+	return logs, err
+}
+
+// AuditSummaryFilter selects the entries an AuditSummary covers
+type AuditSummaryFilter struct {
+	OrganizationID string
+	UserID         string // Empty covers every user
+	Start, End     time.Time
+	Top            int    // Entries in each ranked list
+	Timezone       string // IANA name the hours of day are counted in
+}
+
+// AuditSummary aggregates audit entries for review, instead of raw rows
+type AuditSummary struct {
+	Total       int64   `json:"total"`
+	Failures    int64   `json:"failures"`
+	FailureRate float64 `json:"failure_rate"`
+	// Actions ranks actions by how often they were taken
+	Actions []AuditActionCount `json:"actions"`
+	// TopTargets ranks the resources acted on most
+	TopTargets []AuditTargetCount `json:"top_targets"`
+	Severities map[string]int64   `json:"severities"`
+	// Hours counts entries by hour of day (0-23) in the filter's timezone
+	Hours [24]int64 `json:"hours"`
+}
+
+// AuditActionCount is how often an action was taken and how often it failed
+type AuditActionCount struct {
+	Action      string  `json:"action" db:"action"`
+	Count       int64   `json:"count" db:"count"`
+	Failures    int64   `json:"failures" db:"failures"`
+	FailureRate float64 `json:"failure_rate" db:"-"`
+}
+
+// AuditTargetCount is how often a resource (or free-form target) was acted on
+type AuditTargetCount struct {
+	ResourceType string `json:"resource_type,omitempty" db:"resource_type"`
+	Target       string `json:"target" db:"target"`
+	Count        int64  `json:"count" db:"count"`
+	Failures     int64  `json:"failures" db:"failures"`
+}
+
+// auditSummaryScope is the WHERE clause shared by the summary queries; its
+// parameters are $1 to $4
+const auditSummaryScope = `
+	WHERE organization_id = $1
+	AND ($2 = '' OR user_id::text = $2)
+	AND timestamp >= $3 AND timestamp < $4`
+
+func (r *auditRepo) Summary(ctx context.Context, f AuditSummaryFilter) (*AuditSummary, error) {
+	q := reader(r.q)
+	args := []interface{}{f.OrganizationID, f.UserID, f.Start, f.End}
+	summary := &AuditSummary{
+		Actions:    []AuditActionCount{},
+		TopTargets: []AuditTargetCount{},
+		Severities: map[string]int64{},
+	}
+
+	var severities []struct {
+		Severity string `db:"severity"`
+		Count    int64  `db:"count"`
+		Failures int64  `db:"failures"`
+	}
+	err := q.SelectContext(ctx, &severities, `
+		SELECT COALESCE(severity, 'info') AS severity, COUNT(*) AS count,
+		       COUNT(*) FILTER (WHERE status <> 'success') AS failures
+		FROM audit_logs`+auditSummaryScope+`
+		GROUP BY 1
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range severities {
+		summary.Severities[s.Severity] += s.Count
+		summary.Total += s.Count
+		summary.Failures += s.Failures
+	}
+	summary.FailureRate = rate(summary.Failures, summary.Total)
+
+	err = q.SelectContext(ctx, &summary.Actions, `
+		SELECT action, COUNT(*) AS count, COUNT(*) FILTER (WHERE status <> 'success') AS failures
+		FROM audit_logs`+auditSummaryScope+`
+		GROUP BY action
+		ORDER BY count DESC, action
+		LIMIT $5
+	`, append(args, f.Top)...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range summary.Actions {
+		summary.Actions[i].FailureRate = rate(summary.Actions[i].Failures, summary.Actions[i].Count)
+	}
+
+	err = q.SelectContext(ctx, &summary.TopTargets, `
+		SELECT COALESCE(resource_type, '') AS resource_type,
+		       COALESCE(resource_id::text, target) AS target,
+		       COUNT(*) AS count, COUNT(*) FILTER (WHERE status <> 'success') AS failures
+		FROM audit_logs`+auditSummaryScope+`
+		AND COALESCE(resource_id::text, target) IS NOT NULL
+		GROUP BY 1, 2
+		ORDER BY count DESC, target
+		LIMIT $5
+	`, append(args, f.Top)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Timestamps are stored in UTC
+	var hours []struct {
+		Hour  int   `db:"hour"`
+		Count int64 `db:"count"`
+	}
+	err = q.SelectContext(ctx, &hours, `
+		SELECT EXTRACT(HOUR FROM (timestamp AT TIME ZONE 'UTC') AT TIME ZONE $5)::int AS hour, COUNT(*) AS count
+		FROM audit_logs`+auditSummaryScope+`
+		GROUP BY 1
+	`, append(args, f.Timezone)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hours {
+		if h.Hour >= 0 && h.Hour < 24 {
+			summary.Hours[h.Hour] = h.Count
+		}
+	}
+
+	return summary, nil
+}
+
+// rate is part/total, or 0 when total is
+func rate(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Authorization is a row of authorized_targets. AuthorizationDocumentURL,
+// the proof of authorization, is encrypted at rest.
+type Authorization struct {
+	ID                       string         `json:"id" db:"id"`
+	OrganizationID           string         `json:"organization_id" db:"organization_id"`
+	TargetType               string         `json:"target_type" db:"target_type"`
+	TargetValue              string         `json:"target_value" db:"target_value"`
+	AuthorizationDocumentURL string         `json:"authorization_document_url" db:"authorization_document_url"`
+	AuthorizationHash        string         `json:"authorization_hash" db:"authorization_hash"`
+	AuthorizedBy             string         `json:"authorized_by" db:"authorized_by"`
+	ValidFrom                time.Time      `json:"valid_from" db:"valid_from"`
+	ValidUntil               time.Time      `json:"valid_until" db:"valid_until"`
+	VerificationStatus       string         `json:"verification_status" db:"verification_status"`
+	VerifiedByUserID         *string        `json:"verified_by_user_id" db:"verified_by_user_id"`
+	VerifiedAt               *time.Time     `json:"verified_at" db:"verified_at"`
+	RejectionReason          *string        `json:"rejection_reason" db:"rejection_reason"`
+	Tags                     pq.StringArray `json:"tags" db:"tags"`
+	CreatedAt                time.Time      `json:"created_at" db:"created_at"`
+}
+
+// AuthorizationColumns selects every Authorization field
+const AuthorizationColumns = `id, organization_id, target_type, target_value,
+	COALESCE(authorization_document_url, '') AS authorization_document_url,
+	COALESCE(authorization_hash, '') AS authorization_hash, authorized_by, valid_from, valid_until,
+	COALESCE(verification_status, 'pending') AS verification_status, verified_by_user_id, verified_at,
+	rejection_reason, tags, created_at`
+
+// AuthorizationRepo stores scan authorizations, encrypting their proof with
+// the organization's data key
+type AuthorizationRepo interface {
+	// Create inserts a pending authorization, filling in created_at
+	Create(ctx context.Context, a *Authorization) error
+	Get(ctx context.Context, id string) (*Authorization, error)
+	// List returns the organization's authorizations, newest first, with the
+	// given verification status or all of them when status is empty
+	List(ctx context.Context, orgID, status string) ([]Authorization, error)
+}
+
+type authorizationRepo struct {
+	q      Queryer
+	cipher Cipher
+}
+
+// NewAuthorizationRepo binds an AuthorizationRepo to q; cipher may be
+// Plaintext
+func NewAuthorizationRepo(q Queryer, cipher Cipher) AuthorizationRepo {
+	return &authorizationRepo{q: q, cipher: cipher}
+}
+
+func (r *authorizationRepo) Create(ctx context.Context, a *Authorization) error {
+	docURL, err := r.cipher.Encrypt(ctx, a.OrganizationID, a.AuthorizationDocumentURL)
+	if err != nil {
+		return err
+	}
+	if a.Tags == nil {
+		a.Tags = pq.StringArray{}
+	}
+
+	a.VerificationStatus = "pending"
+	return r.q.GetContext(ctx, &a.CreatedAt, `
+		INSERT INTO authorized_targets (
+			id, organization_id, target_type, target_value,
+			authorization_document_url, authorization_hash,
+			authorized_by, valid_from, valid_until, verification_status, tags
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'pending', $10)
+		RETURNING created_at
+	`, a.ID, a.OrganizationID, a.TargetType, a.TargetValue,
+		docURL, a.AuthorizationHash,
+		a.AuthorizedBy, a.ValidFrom, a.ValidUntil, a.Tags)
+}
+
+func (r *authorizationRepo) Get(ctx context.Context, id string) (*Authorization, error) {
+	var a Authorization
+	err := r.q.GetContext(ctx, &a, `SELECT `+AuthorizationColumns+` FROM authorized_targets WHERE id = $1`, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if err := r.decrypt(ctx, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *authorizationRepo) List(ctx context.Context, orgID, status string) ([]Authorization, error) {
+	query := `SELECT ` + AuthorizationColumns + ` FROM authorized_targets WHERE organization_id = $1`
+	args := []interface{}{orgID}
+	if status != "" {
+		query += ` AND verification_status = $2`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC`
+
+	authorizations := []Authorization{}
+	if err := reader(r.q).SelectContext(ctx, &authorizations, query, args...); err != nil {
+		return nil, err
+	}
+	for i := range authorizations {
+		if err := r.decrypt(ctx, &authorizations[i]); err != nil {
+			return nil, err
+		}
+	}
+	return authorizations, nil
+}
+
+func (r *authorizationRepo) decrypt(ctx context.Context, a *Authorization) error {
+	docURL, err := r.cipher.Decrypt(ctx, a.OrganizationID, a.AuthorizationDocumentURL)
+	if err != nil {
+		return err
+	}
+	a.AuthorizationDocumentURL = docURL
+	return nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Cipher encrypts designated columns with the organization's key, as
+// tenantkeys.Service does. Decrypt returns values it did not encrypt as they
+// are, so rows written before encryption was enabled stay readable.
+type Cipher interface {
+	Encrypt(ctx context.Context, orgID, plaintext string) (string, error)
+	Decrypt(ctx context.Context, orgID, value string) (string, error)
+}
+
+// Plaintext stores designated columns unencrypted, for deployments without
+// a master key
+var Plaintext Cipher = plaintextCipher{}
+
+type plaintextCipher struct{}
+
+func (plaintextCipher) Encrypt(ctx context.Context, orgID, plaintext string) (string, error) {
+	return plaintext, nil
+}
+
+func (plaintextCipher) Decrypt(ctx context.Context, orgID, value string) (string, error) {
+	return value, nil
+}
+
+// encryptJSON seals a JSONB value, storing the ciphertext as a JSON string
+func encryptJSON(ctx context.Context, c Cipher, orgID string, raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	sealed, err := c.Encrypt(ctx, orgID, string(raw))
+	if err != nil {
+		return nil, err
+	}
+	if sealed == string(raw) {
+		return raw, nil
+	}
+	return json.Marshal(sealed)
+}
+
+// decryptJSON reverses encryptJSON; JSON that is not a sealed string is
+// returned as is
+func decryptJSON(ctx context.Context, c Cipher, orgID string, raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 || raw[0] != '"' {
+		return raw, nil
+	}
+	var sealed string
+	if err := json.Unmarshal(raw, &sealed); err != nil {
+		return nil, err
+	}
+	plaintext, err := c.Decrypt(ctx, orgID, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if plaintext == sealed {
+		return raw, nil
+	}
+	return json.RawMessage(plaintext), nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Organization is a row of organizations
+type Organization struct {
+	ID               string    `json:"id" db:"id"`
+	Name             string    `json:"name" db:"name"`
+	Slug             string    `json:"slug" db:"slug"`
+	SubscriptionTier string    `json:"subscription_tier" db:"subscription_tier"`
+	IsActive         bool      `json:"is_active" db:"is_active"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+// OrganizationColumns selects every Organization field
+const OrganizationColumns = `id, name, slug, COALESCE(subscription_tier, 'free') AS subscription_tier,
+	COALESCE(is_active, true) AS is_active, created_at`
+
+// Membership is an organization a user belongs to, with their role there
+type Membership struct {
+	Organization
+	Role string `json:"role" db:"role"`
+}
+
+// OrgRepo manages organizations and their memberships
+type OrgRepo interface {
+	// Create inserts org, filling in its defaults and created_at
+	Create(ctx context.Context, org *Organization) error
+	Get(ctx context.Context, id string) (*Organization, error)
+	// ListForUser returns the user's organizations, newest first
+	ListForUser(ctx context.Context, userID string) ([]Membership, error)
+	// MemberRole returns the user's role in the organization, or ErrNotFound
+	MemberRole(ctx context.Context, userID, orgID string) (string, error)
+	// SetMember adds the user to the organization or changes their role
+	SetMember(ctx context.Context, userID, orgID, role string) error
+}
+
+type orgRepo struct {
+	q Queryer
+}
+
+func NewOrgRepo(q Queryer) OrgRepo {
+	return &orgRepo{q: q}
+}
+
+func (r *orgRepo) Create(ctx context.Context, org *Organization) error {
+	return r.q.GetContext(ctx, org, `
+		INSERT INTO organizations (id, name, slug, subscription_tier)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+OrganizationColumns,
+		org.ID, org.Name, org.Slug, org.SubscriptionTier)
+}
+
+func (r *orgRepo) Get(ctx context.Context, id string) (*Organization, error) {
+	var org Organization
+	err := r.q.GetContext(ctx, &org, `SELECT `+OrganizationColumns+` FROM organizations WHERE id = $1`, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &org, nil
+}
+
+func (r *orgRepo) ListForUser(ctx context.Context, userID string) ([]Membership, error) {
+	memberships := []Membership{}
+	err := r.q.SelectContext(ctx, &memberships, `
+		SELECT o.id, o.name, o.slug, COALESCE(o.subscription_tier, 'free') AS subscription_tier,
+		       COALESCE(o.is_active, true) AS is_active, o.created_at, om.role
+		FROM organizations o
+		INNER JOIN organization_memberships om ON o.id = om.organization_id
+		WHERE om.user_id = $1
+		ORDER BY o.created_at DESC
+	`, userID)
+	return memberships, err
+}
+
+func (r *orgRepo) MemberRole(ctx context.Context, userID, orgID string) (string, error) {
+	var role string
+	err := r.q.GetContext(ctx, &role, `
+		SELECT role FROM organization_memberships
+		WHERE user_id = $1 AND organization_id = $2
+	`, userID, orgID)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return role, nil
+}
+
+func (r *orgRepo) SetMember(ctx context.Context, userID, orgID, role string) error {
+	_, err := r.q.ExecContext(ctx, `
+		INSERT INTO organization_memberships (user_id, organization_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, organization_id) DO UPDATE SET role = $3
+	`, userID, orgID, role)
+	return err
+}
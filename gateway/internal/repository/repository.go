@@ -0,0 +1,69 @@
+// Package repository holds the SQL for the core tables (users,
+// organizations, sessions and audit logs) behind typed interfaces, so
+// handlers and services can be tested with fakes and share queries. A
+// UnitOfWork runs several repository calls in one transaction. Scan
+// authorizations and results have repositories of their own, bound with a
+// Cipher that encrypts their sensitive columns per organization.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/cyper-security/gateway/internal/database"
+)
+
+// ErrNotFound is returned when a lookup matches no row
+var ErrNotFound = errors.New("not found")
+
+// Queryer is what repositories run queries on: *database.DB, a replica
+// from Reader(), or the *sqlx.Tx of a UnitOfWork
+type Queryer interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+var _ Queryer = (*database.DB)(nil)
+
+// Repositories bundles the repositories bound to one Queryer
+type Repositories struct {
+	Users    UserRepo
+	Orgs     OrgRepo
+	Sessions SessionRepo
+	Audit    AuditRepo
+
+	// Q is the underlying Queryer, for writes that belong to the same
+	// transaction but have no repository (e.g. events.Enqueue)
+	Q Queryer
+}
+
+// New binds every repository to q
+func New(q Queryer) *Repositories {
+	return &Repositories{
+		Users:    NewUserRepo(q),
+		Orgs:     NewOrgRepo(q),
+		Sessions: NewSessionRepo(q),
+		Audit:    NewAuditRepo(q),
+		Q:        q,
+	}
+}
+
+// reader returns where to run queries that tolerate replication lag: the
+// read pool of a *database.DB, chosen per query as replica health changes,
+// or q itself inside a transaction
+func reader(q Queryer) Queryer {
+	if db, ok := q.(*database.DB); ok {
+		return db.Reader()
+	}
+	return q
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
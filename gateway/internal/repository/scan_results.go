@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ScanResult is a row of scan_results. RawData, the scanner's evidence, is
+// encrypted at rest.
+type ScanResult struct {
+	ID             string          `json:"id" db:"id"`
+	ScanJobID      string          `json:"scan_job_id" db:"scan_job_id"`
+	OrganizationID *string         `json:"organization_id" db:"organization_id"`
+	ResultType     string          `json:"result_type" db:"result_type"`
+	Summary        json.RawMessage `json:"summary" db:"summary"`
+	RiskScore      *int            `json:"risk_score" db:"risk_score"`
+	SeverityCounts json.RawMessage `json:"severity_counts" db:"severity_counts"`
+	RawData        json.RawMessage `json:"raw_data" db:"raw_data"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// ScanResultColumns selects every ScanResult field
+const ScanResultColumns = `id, scan_job_id, organization_id, result_type, summary, risk_score,
+	severity_counts, raw_data, created_at`
+
+// ScanResultRepo stores the results workers submit, encrypting their raw
+// data with the organization's data key. Results of scans without an
+// organization are stored as submitted. The raw data of an organization with
+// a schema of its own (see tenant_schemas) is kept in that schema's
+// scan_result_data table rather than in scan_results.
+type ScanResultRepo interface {
+	// Create inserts a result, filling in its ID and created_at
+	Create(ctx context.Context, result *ScanResult) error
+	// ListForJob returns the job's results, oldest first
+	ListForJob(ctx context.Context, scanJobID string) ([]ScanResult, error)
+}
+
+type scanResultRepo struct {
+	q      Queryer
+	cipher Cipher
+}
+
+// NewScanResultRepo binds a ScanResultRepo to q; cipher may be Plaintext
+func NewScanResultRepo(q Queryer, cipher Cipher) ScanResultRepo {
+	return &scanResultRepo{q: q, cipher: cipher}
+}
+
+func (r *scanResultRepo) Create(ctx context.Context, result *ScanResult) error {
+	rawData := result.RawData
+	if result.OrganizationID != nil {
+		var err error
+		if rawData, err = encryptJSON(ctx, r.cipher, *result.OrganizationID, rawData); err != nil {
+			return err
+		}
+	}
+
+	var schema string
+	if result.OrganizationID != nil {
+		var err error
+		if schema, err = tenantSchema(ctx, r.q, *result.OrganizationID, true); err != nil {
+			return err
+		}
+	}
+	sharedRawData := rawData
+	if schema != "" {
+		sharedRawData = nil
+	}
+
+	var row struct {
+		ID        string    `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	err := r.q.GetContext(ctx, &row, `
+		INSERT INTO scan_results (scan_job_id, organization_id, result_type, summary, risk_score, severity_counts, raw_data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, result.ScanJobID, result.OrganizationID, result.ResultType, nullJSON(result.Summary), result.RiskScore,
+		nullJSON(result.SeverityCounts), nullJSON(sharedRawData))
+	if err != nil {
+		return err
+	}
+	result.ID, result.CreatedAt = row.ID, row.CreatedAt
+
+	if schema != "" && len(rawData) > 0 {
+		_, err = r.q.ExecContext(ctx, `
+			INSERT INTO `+pq.QuoteIdentifier(schema)+`.scan_result_data (scan_result_id, raw_data, created_at)
+			VALUES ($1, $2, $3)
+		`, result.ID, []byte(rawData), result.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *scanResultRepo) ListForJob(ctx context.Context, scanJobID string) ([]ScanResult, error) {
+	results := []ScanResult{}
+	err := reader(r.q).SelectContext(ctx, &results, `
+		SELECT `+ScanResultColumns+` FROM scan_results
+		WHERE scan_job_id = $1
+		ORDER BY created_at
+	`, scanJobID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		if result.OrganizationID == nil {
+			continue
+		}
+		schema, err := tenantSchema(ctx, reader(r.q), *result.OrganizationID, false)
+		if err != nil {
+			return nil, err
+		}
+		if schema != "" {
+			var stored []byte
+			err := reader(r.q).GetContext(ctx, &stored, `
+				SELECT raw_data FROM `+pq.QuoteIdentifier(schema)+`.scan_result_data WHERE scan_result_id = $1
+			`, result.ID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			if stored != nil {
+				result.RawData = stored
+			}
+		}
+		rawData, err := decryptJSON(ctx, r.cipher, *result.OrganizationID, result.RawData)
+		if err != nil {
+			return nil, err
+		}
+		results[i].RawData = rawData
+	}
+	return results, nil
+}
+
+// tenantSchema returns the schema holding the organization's scan evidence,
+// or "" when it is kept in scan_results. Writers pass lock to share the
+// organization's row lock with tenancy provisioning: a write waits out a
+// move in progress, and the lookup, run as a statement of its own, then
+// sees where the move put the evidence.
+func tenantSchema(ctx context.Context, q Queryer, orgID string, lock bool) (string, error) {
+	if lock {
+		var id string
+		if err := q.GetContext(ctx, &id, `SELECT id FROM organizations WHERE id = $1 FOR SHARE`, orgID); err != nil {
+			return "", notFound(err)
+		}
+	}
+	var schema string
+	err := q.GetContext(ctx, &schema, `SELECT schema_name FROM tenant_schemas WHERE organization_id = $1`, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return schema, err
+}
+
+// nullJSON stores empty JSON values as NULL
+func nullJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return []byte(raw)
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Session is a row of sessions
+type Session struct {
+	ID                string       `db:"id"`
+	UserID            string       `db:"user_id"`
+	TokenHash         string       `db:"token_hash"`
+	IPAddress         string       `db:"ip_address"`
+	UserAgent         string       `db:"user_agent"`
+	ExpiresAt         time.Time    `db:"expires_at"`
+	AbsoluteExpiresAt time.Time    `db:"absolute_expires_at"` // How far ExpiresAt may slide
+	RevokedAt         sql.NullTime `db:"revoked_at"`
+	CreatedAt         time.Time    `db:"created_at"`
+	LastActivityAt    time.Time    `db:"last_activity_at"`
+	Region            string       `db:"region"` // Region of the gateway that issued it
+}
+
+// SessionColumns selects every Session field, with the IP address as text
+const SessionColumns = `id, user_id, token_hash, host(ip_address) AS ip_address, COALESCE(user_agent, '') AS user_agent,
+	expires_at, absolute_expires_at, revoked_at, created_at, last_activity_at, COALESCE(region, '') AS region`
+
+// SessionRepo manages login sessions, identified to the middleware by the
+// hash of their token
+type SessionRepo interface {
+	// Create inserts a session; ID, UserID, TokenHash, IPAddress, UserAgent,
+	// ExpiresAt, AbsoluteExpiresAt and Region are used
+	Create(ctx context.Context, session *Session) error
+	// Get returns the user's session whatever its state
+	Get(ctx context.Context, userID, sessionID string) (*Session, error)
+	// FindActiveByTokenHash returns the unrevoked, unexpired session for a token
+	FindActiveByTokenHash(ctx context.Context, tokenHash string) (*Session, error)
+	// ListActive returns the user's live sessions, most recently used first
+	ListActive(ctx context.Context, userID string) ([]Session, error)
+	// Revoke ends one of the user's live sessions and returns its token hash
+	Revoke(ctx context.Context, userID, sessionID string) (string, error)
+	// Rotate moves a live session onto a new token and returns the old hash
+	Rotate(ctx context.Context, userID, sessionID, tokenHash string, expiresAt time.Time) (string, error)
+	// Extend moves a live session's expiry to expiresAt, capped at its
+	// absolute expiry, and returns the new expiry
+	Extend(ctx context.Context, sessionID string, expiresAt time.Time) (time.Time, error)
+	// TouchActivity records when sessions were last used; times are Unix
+	// seconds, and earlier times than the stored ones are ignored
+	TouchActivity(ctx context.Context, sessionIDs []string, times []int64) error
+	// RevokeIdle ends live sessions unused for idle and returns their token hashes
+	RevokeIdle(ctx context.Context, idle time.Duration) ([]string, error)
+	// RevokeAll ends every live session of the user and returns their token hashes
+	RevokeAll(ctx context.Context, userID string) ([]string, error)
+}
+
+type sessionRepo struct {
+	q Queryer
+}
+
+func NewSessionRepo(q Queryer) SessionRepo {
+	return &sessionRepo{q: q}
+}
+
+func (r *sessionRepo) Create(ctx context.Context, s *Session) error {
+	_, err := r.q.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, token_hash, ip_address, user_agent, expires_at, absolute_expires_at, region)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+	`, s.ID, s.UserID, s.TokenHash, s.IPAddress, s.UserAgent, s.ExpiresAt, s.AbsoluteExpiresAt, s.Region)
+	return err
+}
+
+func (r *sessionRepo) Get(ctx context.Context, userID, sessionID string) (*Session, error) {
+	var session Session
+	err := r.q.GetContext(ctx, &session, `
+		SELECT `+SessionColumns+` FROM sessions WHERE id = $1 AND user_id = $2
+	`, sessionID, userID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepo) FindActiveByTokenHash(ctx context.Context, tokenHash string) (*Session, error) {
+	var session Session
+	err := r.q.GetContext(ctx, &session, `
+		SELECT `+SessionColumns+` FROM sessions
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+	`, tokenHash)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepo) ListActive(ctx context.Context, userID string) ([]Session, error) {
+	sessions := []Session{}
+	err := r.q.SelectContext(ctx, &sessions, `
+		SELECT `+SessionColumns+` FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_activity_at DESC
+	`, userID)
+	return sessions, err
+}
+
+func (r *sessionRepo) Revoke(ctx context.Context, userID, sessionID string) (string, error) {
+	var tokenHash string
+	err := r.q.GetContext(ctx, &tokenHash, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+		RETURNING token_hash
+	`, sessionID, userID)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return tokenHash, nil
+}
+
+func (r *sessionRepo) Rotate(ctx context.Context, userID, sessionID, tokenHash string, expiresAt time.Time) (string, error) {
+	var oldHash string
+	err := r.q.GetContext(ctx, &oldHash, `
+		UPDATE sessions s
+		SET token_hash = $1, expires_at = LEAST($2, s.absolute_expires_at), last_activity_at = NOW()
+		FROM (SELECT id, token_hash FROM sessions WHERE id = $3) old
+		WHERE s.id = old.id AND s.user_id = $4 AND s.revoked_at IS NULL AND s.expires_at > NOW()
+		RETURNING old.token_hash
+	`, tokenHash, expiresAt, sessionID, userID)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return oldHash, nil
+}
+
+func (r *sessionRepo) Extend(ctx context.Context, sessionID string, expiresAt time.Time) (time.Time, error) {
+	var extended time.Time
+	err := r.q.GetContext(ctx, &extended, `
+		UPDATE sessions
+		SET expires_at = GREATEST(expires_at, LEAST($1, absolute_expires_at))
+		WHERE id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING expires_at
+	`, expiresAt, sessionID)
+	if err != nil {
+		return time.Time{}, notFound(err)
+	}
+	return extended, nil
+}
+
+func (r *sessionRepo) TouchActivity(ctx context.Context, sessionIDs []string, times []int64) error {
+	_, err := r.q.ExecContext(ctx, `
+		UPDATE sessions s
+		SET last_activity_at = GREATEST(s.last_activity_at, to_timestamp(t.at)::timestamp)
+		FROM unnest($1::uuid[], $2::bigint[]) AS t(id, at)
+		WHERE s.id = t.id
+	`, pq.StringArray(sessionIDs), pq.Int64Array(times))
+	return err
+}
+
+func (r *sessionRepo) RevokeIdle(ctx context.Context, idle time.Duration) ([]string, error) {
+	tokenHashes := []string{}
+	err := r.q.SelectContext(ctx, &tokenHashes, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE revoked_at IS NULL AND expires_at > NOW()
+		AND last_activity_at < NOW() - $1 * INTERVAL '1 second'
+		RETURNING token_hash
+	`, int64(idle.Seconds()))
+	return tokenHashes, err
+}
+
+func (r *sessionRepo) RevokeAll(ctx context.Context, userID string) ([]string, error) {
+	tokenHashes := []string{}
+	err := r.q.SelectContext(ctx, &tokenHashes, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING token_hash
+	`, userID)
+	return tokenHashes, err
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyper-security/gateway/internal/database"
+)
+
+// UnitOfWork runs repository calls in a single transaction
+type UnitOfWork struct {
+	db *database.DB
+}
+
+func NewUnitOfWork(db *database.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Do calls fn with repositories bound to a new transaction, committing when
+// fn returns nil and rolling back otherwise. fn's error is returned as is, so
+// callers can compare it with sentinel errors.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(repos *Repositories) error) error {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(New(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// User is a row of users
+type User struct {
+	ID              string         `db:"id"`
+	Email           string         `db:"email"`
+	Username        string         `db:"username"`
+	PasswordHash    string         `db:"password_hash"`
+	FullName        sql.NullString `db:"full_name"`
+	OrganizationID  sql.NullString `db:"organization_id"`
+	Role            string         `db:"role"`
+	Features        []byte         `db:"features"`
+	IsActive        bool           `db:"is_active"`
+	TermsAcceptedAt sql.NullTime   `db:"terms_accepted_at"`
+	TermsVersion    sql.NullString `db:"terms_version"`
+	EmailVerifiedAt sql.NullTime   `db:"email_verified_at"`
+	Locale          sql.NullString `db:"locale"`
+	CreatedAt       time.Time      `db:"created_at"`
+	UpdatedAt       time.Time      `db:"updated_at"`
+	LastLoginAt     sql.NullTime   `db:"last_login_at"`
+}
+
+// UserColumns selects every User field
+const UserColumns = `id, email, username, password_hash, full_name, organization_id, role, features,
+	is_active, terms_accepted_at, terms_version, email_verified_at, locale, created_at, updated_at, last_login_at`
+
+// UserRepo reads and updates users
+type UserRepo interface {
+	// Get returns the user whatever their status
+	Get(ctx context.Context, id string) (*User, error)
+	// GetActive returns the user if their account is active
+	GetActive(ctx context.Context, id string) (*User, error)
+	// GetActiveByEmail returns the active user with the email address
+	GetActiveByEmail(ctx context.Context, email string) (*User, error)
+	// IDByEmail returns the ID of the user with the email address
+	IDByEmail(ctx context.Context, email string) (string, error)
+	// Create inserts user, filling in its ID and timestamps
+	Create(ctx context.Context, user *User) error
+	// TouchLastLogin records a successful login
+	TouchLastLogin(ctx context.Context, id string) error
+	// Search returns users whose email, username or name contains query
+	// (every user when it is empty), most recently created first
+	Search(ctx context.Context, query string, limit int) ([]User, error)
+	// SetActive enables or disables the user's account
+	SetActive(ctx context.Context, id string, active bool) error
+	// MarkEmailVerified records that the user proved they own email, which
+	// must still be their address
+	MarkEmailVerified(ctx context.Context, id, email string) error
+}
+
+type userRepo struct {
+	q Queryer
+}
+
+func NewUserRepo(q Queryer) UserRepo {
+	return &userRepo{q: q}
+}
+
+func (r *userRepo) Get(ctx context.Context, id string) (*User, error) {
+	var user User
+	err := r.q.GetContext(ctx, &user, `SELECT `+UserColumns+` FROM users WHERE id = $1`, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepo) GetActive(ctx context.Context, id string) (*User, error) {
+	var user User
+	err := r.q.GetContext(ctx, &user, `SELECT `+UserColumns+` FROM users WHERE id = $1 AND is_active = true`, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepo) GetActiveByEmail(ctx context.Context, email string) (*User, error) {
+	var user User
+	err := r.q.GetContext(ctx, &user, `SELECT `+UserColumns+` FROM users WHERE email = $1 AND is_active = true`, email)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepo) IDByEmail(ctx context.Context, email string) (string, error) {
+	var id string
+	err := r.q.GetContext(ctx, &id, `SELECT id FROM users WHERE email = $1`, email)
+	if err != nil {
+		return "", notFound(err)
+	}
+	return id, nil
+}
+
+func (r *userRepo) TouchLastLogin(ctx context.Context, id string) error {
+	_, err := r.q.ExecContext(ctx, `UPDATE users SET last_login_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func (r *userRepo) Search(ctx context.Context, query string, limit int) ([]User, error) {
+	users := []User{}
+	err := r.q.SelectContext(ctx, &users, `
+		SELECT `+UserColumns+` FROM users
+		WHERE $1 = ''
+		   OR email ILIKE '%' || $1 || '%'
+		   OR username ILIKE '%' || $1 || '%'
+		   OR full_name ILIKE '%' || $1 || '%'
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, query, limit)
+	return users, err
+}
+
+func (r *userRepo) SetActive(ctx context.Context, id string, active bool) error {
+	res, err := r.q.ExecContext(ctx, `UPDATE users SET is_active = $2, updated_at = NOW() WHERE id = $1`, id, active)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepo) MarkEmailVerified(ctx context.Context, id, email string) error {
+	res, err := r.q.ExecContext(ctx, `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND email = $2
+	`, id, email)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepo) Create(ctx context.Context, user *User) error {
+	return r.q.GetContext(ctx, user, `
+		INSERT INTO users (email, username, password_hash, full_name, organization_id, role, features, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+UserColumns,
+		user.Email, user.Username, user.PasswordHash, user.FullName, user.OrganizationID, user.Role, user.Features, user.IsActive)
+}
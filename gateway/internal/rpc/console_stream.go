@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ScanConsoleStream is the server side of a StreamScanConsole call
+type ScanConsoleStream interface {
+	Context() context.Context
+	Recv() (*ScanConsoleChunk, error)
+	SendAndClose(*StreamScanConsoleResponse) error
+}
+
+type scanConsoleStream struct {
+	grpc.ServerStream
+}
+
+func (s *scanConsoleStream) Recv() (*ScanConsoleChunk, error) {
+	chunk := new(ScanConsoleChunk)
+	if err := s.RecvMsg(chunk); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+func (s *scanConsoleStream) SendAndClose(resp *StreamScanConsoleResponse) error {
+	return s.SendMsg(resp)
+}
+
+// StreamScanConsole relays a running job's tool output until the worker
+// closes the stream. Only the worker running the job may stream it; lines
+// the console cannot relay are counted as dropped rather than failing the
+// stream, since the output is informational.
+func (s *InternalService) StreamScanConsole(stream ScanConsoleStream) error {
+	if s.console == nil {
+		return status.Error(codes.Unimplemented, "scan console streaming is disabled")
+	}
+
+	metrics.ScanConsoleStreams.Inc()
+	defer metrics.ScanConsoleStreams.Dec()
+
+	ctx := stream.Context()
+	var scanJobID string
+	resp := &StreamScanConsoleResponse{}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case scanJobID == "":
+			if chunk.ScanJobID == "" {
+				return status.Error(codes.InvalidArgument, "scan_job_id is required")
+			}
+			ctx = logging.With(ctx, s.logger, rpcLogFields(ctx, "StreamScanConsole", chunk)...)
+			if err := s.checkConsoleOwner(ctx, chunk); err != nil {
+				return err
+			}
+			scanJobID = chunk.ScanJobID
+		case chunk.ScanJobID != scanJobID:
+			return status.Error(codes.InvalidArgument, "a console stream carries a single scan job")
+		}
+
+		lines := make([]realtime.ConsoleLine, 0, len(chunk.Lines))
+		now := time.Now().UTC()
+		for _, line := range chunk.Lines {
+			l := realtime.ConsoleLine{Stream: "stdout", Text: line.Text, Time: now}
+			if line.Stream == "stderr" {
+				l.Stream = line.Stream
+			}
+			if line.Time != nil {
+				l.Time = line.Time.UTC()
+			}
+			lines = append(lines, l)
+		}
+
+		relayed, _, err := s.console.Append(ctx, scanJobID, lines)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Warn("Failed to relay scan console output", zap.Error(err))
+		}
+		resp.LinesRelayed += int32(relayed)
+		resp.LinesDropped += int32(len(lines) - relayed)
+	}
+}
+
+// checkConsoleOwner makes sure the job is running on the calling worker
+func (s *InternalService) checkConsoleOwner(ctx context.Context, chunk *ScanConsoleChunk) error {
+	var id string
+	err := s.db.GetContext(ctx, &id, `
+		SELECT id FROM scan_jobs
+		WHERE id = $1 AND status = 'running'
+		AND (worker_id IS NULL OR $2 = '' OR worker_id::text = $2)
+	`, chunk.ScanJobID, chunk.WorkerID)
+	if err == sql.ErrNoRows {
+		return status.Error(codes.FailedPrecondition, "scan job is not running or belongs to another worker")
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to load scan job", zap.Error(err))
+		return status.Error(codes.Internal, "failed to load scan job")
+	}
+	return nil
+}
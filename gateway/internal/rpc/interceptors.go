@@ -0,0 +1,212 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"runtime/debug"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/mtls"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type identityKey struct{}
+
+// ServiceIdentity returns the authenticated calling service, if any
+func ServiceIdentity(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey{}).(string)
+	return identity
+}
+
+// recoveryInterceptor converts handler panics into Internal errors
+func recoveryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("gRPC handler panic",
+					zap.String("method", info.FullMethod),
+					zap.Any("panic", r),
+					zap.ByteString("stack", debug.Stack()),
+				)
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// metricsInterceptor records request counts and latency per method
+func metricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		metrics.GRPCRequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		metrics.GRPCRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// authInterceptor accepts a verified client certificate or the shared service token
+func authInterceptor(serviceToken string, certs *mtls.Reloader, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		identity := authenticate(ctx, serviceToken, certs)
+		if identity == "" {
+			logger.Warn("Unauthenticated internal gRPC call", zap.String("method", info.FullMethod))
+			return nil, status.Error(codes.Unauthenticated, "client certificate or service token required")
+		}
+		return handler(context.WithValue(ctx, identityKey{}, identity), req)
+	}
+}
+
+// authenticate returns the calling service's identity, or "" when it
+// presented neither a known client certificate nor the service token
+func authenticate(ctx context.Context, serviceToken string, certs *mtls.Reloader) string {
+	if identity := peerCertificateIdentity(ctx, certs); identity != "" {
+		return identity
+	}
+
+	if serviceToken != "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			for _, token := range md.Get("x-service-token") {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(serviceToken)) == 1 {
+					return "service-token"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// pollingMethods are called every few seconds by every worker. Their
+// successful calls are not audited; a dispatched job is, by LogScanStart.
+var pollingMethods = map[string]bool{
+	"/" + serviceName + "/DispatchScanJob": true,
+	"/" + serviceName + "/ScanJobControl":  true,
+}
+
+// audited reports whether a call's outcome belongs in the audit log
+func audited(method string, err error) bool {
+	return err != nil || !pollingMethods[method]
+}
+
+// auditInterceptor records internal calls in the audit log, except
+// successful polls
+func auditInterceptor(auditLogger *audit.AuditLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if audited(info.FullMethod, err) {
+			auditCall(ctx, auditLogger, info.FullMethod, err)
+		}
+		return resp, err
+	}
+}
+
+// auditCall records one internal call in the audit log
+func auditCall(ctx context.Context, auditLogger *audit.AuditLogger, method string, err error) {
+	params := audit.LogParams{
+		Action:   "grpc_call",
+		Target:   method,
+		Severity: "info",
+		Details: map[string]interface{}{
+			"method":   method,
+			"identity": ServiceIdentity(ctx),
+			"code":     status.Code(err).String(),
+		},
+	}
+	if err != nil {
+		params.Status = "failure"
+		params.ErrorMessage = err.Error()
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		params.Details["peer"] = p.Addr.String()
+	}
+
+	// Audit with a detached context so cancelled RPCs are still recorded
+	auditLogger.Log(context.WithoutCancel(ctx), params)
+}
+
+// The stream interceptors below apply the same checks to streaming calls;
+// metrics and audit entries cover the whole stream
+
+func recoveryStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("gRPC handler panic",
+					zap.String("method", info.FullMethod),
+					zap.Any("panic", r),
+					zap.ByteString("stack", debug.Stack()),
+				)
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func metricsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+
+		metrics.GRPCRequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		metrics.GRPCRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func authStreamInterceptor(serviceToken string, certs *mtls.Reloader, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		identity := authenticate(ss.Context(), serviceToken, certs)
+		if identity == "" {
+			logger.Warn("Unauthenticated internal gRPC call", zap.String("method", info.FullMethod))
+			return status.Error(codes.Unauthenticated, "client certificate or service token required")
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: context.WithValue(ss.Context(), identityKey{}, identity)})
+	}
+}
+
+func auditStreamInterceptor(auditLogger *audit.AuditLogger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		auditCall(ss.Context(), auditLogger, info.FullMethod, err)
+		return err
+	}
+}
+
+// contextStream replaces a stream's context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// peerCertificateIdentity maps a verified client certificate to a service
+// identity through its SANs
+func peerCertificateIdentity(ctx context.Context, certs *mtls.Reloader) string {
+	if certs == nil {
+		return ""
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ""
+	}
+	return certs.IdentifyState(&tlsInfo.State)
+}
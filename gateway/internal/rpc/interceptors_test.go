@@ -0,0 +1,28 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAudited(t *testing.T) {
+	failed := errors.New("unavailable")
+	tests := []struct {
+		method string
+		err    error
+		want   bool
+	}{
+		{"/" + serviceName + "/DispatchScanJob", nil, false},
+		{"/" + serviceName + "/ScanJobControl", nil, false},
+		{"/" + serviceName + "/DispatchScanJob", failed, true},
+		{"/" + serviceName + "/ScanJobControl", failed, true},
+		{"/" + serviceName + "/SubmitScanResult", nil, true},
+		{"/" + serviceName + "/IntrospectToken", nil, true},
+		{"/" + serviceName + "/StreamScanConsole", nil, true},
+	}
+	for _, tt := range tests {
+		if got := audited(tt.method, tt.err); got != tt.want {
+			t.Errorf("audited(%s, %v) = %v, want %v", tt.method, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Hand-written mirrors of the messages in proto/internal/v1/internal.proto.
+// They are served as JSON by jsonCodec, not protobuf: there is no generated
+// code, and TestMessagesMatchProto keeps their JSON field names in step
+// with the .proto.
+
+type DispatchScanJobRequest struct {
+	WorkerID  string   `json:"worker_id"`
+	ScanTypes []string `json:"scan_types"`
+}
+
+type ScanJob struct {
+	ID                    string          `json:"id" db:"id"`
+	UserID                string          `json:"user_id" db:"user_id"`
+	OrganizationID        string          `json:"organization_id" db:"organization_id"`
+	ScanType              string          `json:"scan_type" db:"scan_type"`
+	ScanMode              string          `json:"scan_mode" db:"scan_mode"`
+	TargetType            string          `json:"target_type" db:"target_type"`
+	TargetValue           string          `json:"target_value" db:"target_value"`
+	Priority              int32           `json:"priority" db:"priority"`
+	Configuration         json.RawMessage `json:"configuration,omitempty" db:"configuration"`
+	AuthorizationTargetID string          `json:"authorization_target_id" db:"authorization_target_id"`
+}
+
+type DispatchScanJobResponse struct {
+	Job *ScanJob `json:"job,omitempty"`
+}
+
+type Vulnerability struct {
+	Title             string   `json:"title"`
+	Description       string   `json:"description"`
+	Severity          string   `json:"severity"`
+	CVSSScore         float64  `json:"cvss_score"`
+	CVSSVector        string   `json:"cvss_vector"`
+	Category          string   `json:"category"`
+	AffectedComponent string   `json:"affected_component"`
+	Remediation       string   `json:"remediation"`
+	PluginID          string   `json:"plugin_id"`
+	CVEID             string   `json:"cve_id"`
+	CWEIDs            []string `json:"cwe_ids"`
+}
+
+type SubmitScanResultRequest struct {
+	ScanJobID       string          `json:"scan_job_id"`
+	WorkerID        string          `json:"worker_id"`
+	Status          string          `json:"status"`
+	ErrorMessage    string          `json:"error_message"`
+	ResultType      string          `json:"result_type"`
+	Summary         json.RawMessage `json:"summary,omitempty"`
+	RiskScore       int32           `json:"risk_score"`
+	SeverityCounts  json.RawMessage `json:"severity_counts,omitempty"`
+	RawData         json.RawMessage `json:"raw_data,omitempty"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	PhaseTimings    []PhaseTiming   `json:"phase_timings"`
+	// Traffic the scan generated, which later scan estimates learn from
+	RequestsSent int64 `json:"requests_sent,omitempty"`
+	BytesSent    int64 `json:"bytes_sent,omitempty"`
+}
+
+type PhaseTiming struct {
+	Phase           string  `json:"phase"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+type SubmitScanResultResponse struct {
+	ScanResultID              string `json:"scan_result_id"`
+	VulnerabilitiesStored     int32  `json:"vulnerabilities_stored"`
+	VulnerabilitiesSuppressed int32  `json:"vulnerabilities_suppressed"`
+}
+
+type ScanJobControlRequest struct {
+	WorkerID   string   `json:"worker_id"`
+	ScanJobIDs []string `json:"scan_job_ids"`
+}
+
+type ScanJobControl struct {
+	ScanJobID string `json:"scan_job_id" db:"scan_job_id"`
+	Action    string `json:"action" db:"action"`
+}
+
+type ScanJobControlResponse struct {
+	Controls []ScanJobControl `json:"controls"`
+}
+
+type IntrospectTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type IntrospectTokenResponse struct {
+	Active         bool       `json:"active"`
+	UserID         string     `json:"user_id,omitempty"`
+	Email          string     `json:"email,omitempty"`
+	Role           string     `json:"role,omitempty"`
+	OrganizationID string     `json:"organization_id,omitempty"`
+	Features       []string   `json:"features,omitempty"`
+	SessionID      string     `json:"session_id,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+type ConsoleLine struct {
+	Stream string     `json:"stream"`
+	Text   string     `json:"text"`
+	Time   *time.Time `json:"time,omitempty"`
+}
+
+type ScanConsoleChunk struct {
+	ScanJobID string        `json:"scan_job_id"`
+	WorkerID  string        `json:"worker_id"`
+	Lines     []ConsoleLine `json:"lines"`
+}
+
+type StreamScanConsoleResponse struct {
+	LinesRelayed int32 `json:"lines_relayed"`
+	LinesDropped int32 `json:"lines_dropped"`
+}
+
+// Scan event types workers publish
+const (
+	ScanEventProgress = "progress"
+	ScanEventFinding  = "finding"
+)
+
+type ScanEvent struct {
+	Type     string         `json:"type"`
+	Progress int32          `json:"progress"`
+	Phase    string         `json:"phase"`
+	Finding  *Vulnerability `json:"finding,omitempty"`
+	Time     *time.Time     `json:"time,omitempty"`
+}
+
+type PublishScanEventsRequest struct {
+	ScanJobID string      `json:"scan_job_id"`
+	WorkerID  string      `json:"worker_id"`
+	Events    []ScanEvent `json:"events"`
+}
+
+type PublishScanEventsResponse struct {
+	EventsPublished int32 `json:"events_published"`
+	// IDs the streamed findings are stored under, in request order
+	FindingIDs []string `json:"finding_ids"`
+}
+
+// jsonCodec serves the messages above as application/grpc+json
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// mirrors maps each message in internal.proto to its Go mirror
+var mirrors = map[string]interface{}{
+	"DispatchScanJobRequest":    DispatchScanJobRequest{},
+	"ScanJob":                   ScanJob{},
+	"DispatchScanJobResponse":   DispatchScanJobResponse{},
+	"Vulnerability":             Vulnerability{},
+	"SubmitScanResultRequest":   SubmitScanResultRequest{},
+	"PhaseTiming":               PhaseTiming{},
+	"SubmitScanResultResponse":  SubmitScanResultResponse{},
+	"ScanJobControlRequest":     ScanJobControlRequest{},
+	"ScanJobControl":            ScanJobControl{},
+	"ScanJobControlResponse":    ScanJobControlResponse{},
+	"IntrospectTokenRequest":    IntrospectTokenRequest{},
+	"IntrospectTokenResponse":   IntrospectTokenResponse{},
+	"ConsoleLine":               ConsoleLine{},
+	"ScanConsoleChunk":          ScanConsoleChunk{},
+	"StreamScanConsoleResponse": StreamScanConsoleResponse{},
+	"ScanEvent":                 ScanEvent{},
+	"PublishScanEventsRequest":  PublishScanEventsRequest{},
+	"PublishScanEventsResponse": PublishScanEventsResponse{},
+}
+
+var (
+	protoMessage = regexp.MustCompile(`(?ms)^message (\w+) \{(.*?)^\}`)
+	protoField   = regexp.MustCompile(`(?m)^\s*(?:repeated\s+)?[\w.]+\s+(\w+)\s*=\s*\d+;`)
+)
+
+// protoFields returns each message's field names from internal.proto
+func protoFields(t *testing.T) map[string][]string {
+	t.Helper()
+	data, err := os.ReadFile("../../proto/internal/v1/internal.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := map[string][]string{}
+	for _, m := range protoMessage.FindAllStringSubmatch(string(data), -1) {
+		var fields []string
+		for _, f := range protoField.FindAllStringSubmatch(m[2], -1) {
+			fields = append(fields, f[1])
+		}
+		sort.Strings(fields)
+		messages[m[1]] = fields
+	}
+	return messages
+}
+
+// jsonFields returns the JSON names of a struct's fields
+func jsonFields(v interface{}) []string {
+	var fields []string
+	typ := reflect.TypeOf(v)
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestMessagesMatchProto(t *testing.T) {
+	messages := protoFields(t)
+	if len(messages) == 0 {
+		t.Fatal("no messages parsed from internal.proto")
+	}
+
+	for name, fields := range messages {
+		mirror, ok := mirrors[name]
+		if !ok {
+			t.Errorf("message %s has no Go mirror", name)
+			continue
+		}
+		if got := jsonFields(mirror); !reflect.DeepEqual(got, fields) {
+			t.Errorf("%s JSON fields = %v, want %v", name, got, fields)
+		}
+	}
+	for name := range mirrors {
+		if _, ok := messages[name]; !ok {
+			t.Errorf("Go mirror %s has no message in internal.proto", name)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/scanevents"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxScanEvents bounds the events of one PublishScanEvents call
+const maxScanEvents = 500
+
+var validSeverities = map[string]bool{
+	"critical": true,
+	"high":     true,
+	"medium":   true,
+	"low":      true,
+	"info":     true,
+}
+
+// PublishScanEvents appends a running job's progress and the findings found
+// so far to the scan event stream. Only the worker running the job may
+// publish for it. Streamed findings are stored as they are consumed, so the
+// ones a worker found before crashing are kept; the worker should still
+// include them in SubmitScanResult, which stores each fingerprint once.
+func (s *InternalService) PublishScanEvents(ctx context.Context, req *PublishScanEventsRequest) (*PublishScanEventsResponse, error) {
+	if s.scanEvents == nil {
+		return nil, status.Error(codes.Unimplemented, "scan event streaming is disabled")
+	}
+	if req.ScanJobID == "" {
+		return nil, status.Error(codes.InvalidArgument, "scan_job_id is required")
+	}
+	if len(req.Events) > maxScanEvents {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d events may be published at once", maxScanEvents)
+	}
+
+	var job struct {
+		UserID         string         `db:"user_id"`
+		OrganizationID sql.NullString `db:"organization_id"`
+	}
+	err := s.db.GetContext(ctx, &job, `
+		SELECT user_id, organization_id FROM scan_jobs
+		WHERE id = $1 AND status IN ('running', 'paused')
+		AND (worker_id IS NULL OR $2 = '' OR worker_id::text = $2)
+	`, req.ScanJobID, req.WorkerID)
+	if err == sql.ErrNoRows {
+		return nil, status.Error(codes.FailedPrecondition, "scan job is not running or belongs to another worker")
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to load scan job", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to load scan job")
+	}
+
+	resp := &PublishScanEventsResponse{FindingIDs: []string{}}
+	published := make([]scanevents.Event, 0, len(req.Events))
+	for i, e := range req.Events {
+		event := scanevents.Event{
+			ScanID:         req.ScanJobID,
+			UserID:         job.UserID,
+			OrganizationID: job.OrganizationID.String,
+			WorkerID:       req.WorkerID,
+			OccurredAt:     time.Now().UTC(),
+		}
+		if e.Time != nil {
+			event.OccurredAt = e.Time.UTC()
+		}
+
+		switch e.Type {
+		case ScanEventProgress:
+			if e.Progress < 0 || e.Progress > 100 {
+				return nil, status.Errorf(codes.InvalidArgument, "events[%d]: progress must be between 0 and 100", i)
+			}
+			event.Type = scanevents.TypeProgress
+			event.Progress = int(e.Progress)
+			event.Phase = e.Phase
+		case ScanEventFinding:
+			if e.Finding == nil || e.Finding.Title == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "events[%d]: finding with a title is required", i)
+			}
+			if !validSeverities[e.Finding.Severity] {
+				return nil, status.Errorf(codes.InvalidArgument, "events[%d]: invalid severity %q", i, e.Finding.Severity)
+			}
+			event.Type = scanevents.TypeFinding
+			event.Finding = scanEventFinding(*e.Finding, uuid.New().String())
+			resp.FindingIDs = append(resp.FindingIDs, event.Finding.ID)
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "events[%d]: type must be 'progress' or 'finding'", i)
+		}
+		published = append(published, event)
+	}
+
+	if err := s.scanEvents.Publish(ctx, published...); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to publish scan events", zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "failed to publish scan events")
+	}
+	resp.EventsPublished = int32(len(published))
+	return resp, nil
+}
+
+// scanEventFinding is a reported vulnerability as carried by the stream
+func scanEventFinding(vuln Vulnerability, id string) *scanevents.Finding {
+	return &scanevents.Finding{
+		ID:                id,
+		Title:             vuln.Title,
+		Description:       vuln.Description,
+		Severity:          vuln.Severity,
+		CVSSScore:         vuln.CVSSScore,
+		CVSSVector:        vuln.CVSSVector,
+		Category:          vuln.Category,
+		AffectedComponent: vuln.AffectedComponent,
+		Remediation:       vuln.Remediation,
+		PluginID:          vuln.PluginID,
+		CVEID:             vuln.CVEID,
+		CWEIDs:            vuln.CWEIDs,
+	}
+}
@@ -0,0 +1,89 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/cyper-security/gateway/internal/mtls"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// Config configures the internal gRPC server
+type Config struct {
+	Port         string
+	TLS          *mtls.Reloader // nil disables TLS (development only)
+	ServiceToken string         // Shared token accepted when no client certificate is presented
+}
+
+// Server hosts the internal gRPC API alongside the gin HTTP server
+type Server struct {
+	grpcServer *grpc.Server
+	config     Config
+	logger     *zap.Logger
+}
+
+func NewServer(service *InternalService, config Config, logger *zap.Logger) (*Server, error) {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			recoveryInterceptor(logger),
+			metricsInterceptor(),
+			authInterceptor(config.ServiceToken, config.TLS, logger),
+			auditInterceptor(service.auditLogger),
+		),
+		grpc.ChainStreamInterceptor(
+			recoveryStreamInterceptor(logger),
+			metricsStreamInterceptor(),
+			authStreamInterceptor(config.ServiceToken, config.TLS, logger),
+			auditStreamInterceptor(service.auditLogger),
+		),
+	}
+
+	if config.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config.TLS.ServerConfig())))
+	} else {
+		logger.Warn("Internal gRPC server running without TLS - do not use in production")
+	}
+
+	grpcServer := grpc.NewServer(opts...)
+	grpcServer.RegisterService(&serviceDesc, service)
+
+	return &Server{
+		grpcServer: grpcServer,
+		config:     config,
+		logger:     logger,
+	}, nil
+}
+
+// ListenAndServe blocks serving gRPC on the configured port
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", s.config.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	s.logger.Info("Internal gRPC server started",
+		zap.String("port", s.config.Port),
+		zap.Bool("mtls", s.config.TLS != nil),
+	)
+	return s.grpcServer.Serve(lis)
+}
+
+// GracefulStop drains in-flight RPCs
+func (s *Server) GracefulStop() {
+	s.grpcServer.GracefulStop()
+}
+
+func stringArray(values []string) interface{} {
+	if values == nil {
+		values = []string{}
+	}
+	return pq.Array(values)
+}
@@ -0,0 +1,555 @@
+package rpc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cyper-security/gateway/internal/audit"
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/billing"
+	"github.com/cyper-security/gateway/internal/console"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/events"
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/intel"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/scanevents"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const serviceName = "cyper.internal.v1.InternalService"
+
+// InternalService implements the internal gRPC contract
+type InternalService struct {
+	db          *database.DB
+	authService *auth.AuthService
+	auditLogger *audit.AuditLogger
+	cipher      repository.Cipher
+	paused      func() bool
+	console     *console.Service
+	scanEvents  *scanevents.Stream
+	logger      *zap.Logger
+}
+
+// NewInternalService creates the service; cipher encrypts the raw data of
+// submitted results and may be repository.Plaintext
+func NewInternalService(db *database.DB, authService *auth.AuthService, auditLogger *audit.AuditLogger, cipher repository.Cipher, logger *zap.Logger) *InternalService {
+	return &InternalService{
+		db:          db,
+		authService: authService,
+		auditLogger: auditLogger,
+		cipher:      cipher,
+		logger:      logger,
+	}
+}
+
+// SetDispatchPaused installs a check that holds queued jobs back while it
+// returns true (e.g. during maintenance); dispatch resumes once it is false
+func (s *InternalService) SetDispatchPaused(paused func() bool) {
+	s.paused = paused
+}
+
+// SetConsole enables StreamScanConsole
+func (s *InternalService) SetConsole(consoleService *console.Service) {
+	s.console = consoleService
+}
+
+// SetScanEvents enables PublishScanEvents, and has SubmitScanResult publish
+// the findings it stores and the scan's outcome to the stream
+func (s *InternalService) SetScanEvents(stream *scanevents.Stream) {
+	s.scanEvents = stream
+}
+
+// DispatchScanJob claims the next pending job for a worker
+func (s *InternalService) DispatchScanJob(ctx context.Context, req *DispatchScanJobRequest) (*DispatchScanJobResponse, error) {
+	if req.WorkerID == "" {
+		return nil, status.Error(codes.InvalidArgument, "worker_id is required")
+	}
+	if s.paused != nil && s.paused() {
+		return &DispatchScanJobResponse{}, nil
+	}
+
+	// Registered workers (see /api/v1/workers/register) own the jobs they
+	// claim, so the job is re-queued if they stop sending heartbeats. Draining
+	// and offline workers get no new work.
+	var dispatched struct {
+		ScanJob
+		QueuedSeconds float64 `db:"queued_seconds"`
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &dispatched, `
+		WITH worker AS (
+			SELECT id, status FROM scan_workers WHERE id::text = $2
+		), next_job AS (
+			SELECT id FROM scan_jobs
+			WHERE status = 'pending'
+			AND (cardinality($1::text[]) = 0 OR scan_type = ANY($1::text[]))
+			-- Within the scan's execution window, if it has one
+			AND (not_before IS NULL OR not_before <= NOW())
+			AND (not_after IS NULL OR not_after > NOW())
+			AND NOT EXISTS (SELECT 1 FROM worker WHERE status <> 'online')
+			ORDER BY priority, created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		UPDATE scan_jobs sj
+		SET status = 'running', started_at = NOW(), current_phase = 'dispatched',
+		    worker_id = (SELECT id FROM worker)
+		FROM next_job, scan_targets st
+		WHERE sj.id = next_job.id AND st.id = sj.target_id
+		RETURNING sj.id, sj.user_id, COALESCE(sj.organization_id::text, '') AS organization_id,
+		          sj.scan_type, sj.scan_mode, st.target_type, st.target_value, sj.priority,
+		          sj.configuration, COALESCE(sj.authorization_target_id::text, '') AS authorization_target_id,
+		          -- Waiting since it last became dispatchable: queued, approved,
+		          -- resumed or re-queued, or its execution window opened
+		          EXTRACT(EPOCH FROM NOW() - GREATEST(COALESCE(sj.queued_at, sj.created_at), sj.not_before)) AS queued_seconds
+	`, stringArray(req.ScanTypes), req.WorkerID)
+	if err == sql.ErrNoRows {
+		return &DispatchScanJobResponse{}, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to dispatch scan job", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to dispatch scan job")
+	}
+	job := dispatched.ScanJob
+
+	// Organizations are billed for scans when they are dispatched
+	if job.OrganizationID != "" {
+		if err := billing.Record(ctx, tx, billing.ScanStarted(job.OrganizationID, job.ID)); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to dispatch scan job", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to dispatch scan job")
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to dispatch scan job", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to dispatch scan job")
+	}
+	metrics.ScanQueueWait.WithLabelValues(job.ScanType).Observe(dispatched.QueuedSeconds)
+
+	s.auditLogger.LogScanStart(ctx, job.UserID, job.ID, job.ScanType, job.TargetValue, job.AuthorizationTargetID)
+
+	logging.FromContext(ctx, s.logger).Info("Scan job dispatched",
+		zap.String("scan_job_id", job.ID),
+		zap.String("worker_id", req.WorkerID),
+		zap.String("scan_type", job.ScanType),
+	)
+
+	return &DispatchScanJobResponse{Job: &job}, nil
+}
+
+// SubmitScanResult stores a job's results and vulnerabilities and closes the job
+func (s *InternalService) SubmitScanResult(ctx context.Context, req *SubmitScanResultRequest) (*SubmitScanResultResponse, error) {
+	if req.ScanJobID == "" {
+		return nil, status.Error(codes.InvalidArgument, "scan_job_id is required")
+	}
+	if req.Status != "completed" && req.Status != "failed" {
+		return nil, status.Error(codes.InvalidArgument, "status must be 'completed' or 'failed'")
+	}
+	if req.ResultType == "" {
+		req.ResultType = "scan"
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	// A job re-queued from a lost worker may since be running elsewhere; only
+	// its current owner can close it
+	var job struct {
+		OrganizationID sql.NullString  `db:"organization_id"`
+		UserID         string          `db:"user_id"`
+		ScanType       string          `db:"scan_type"`
+		TargetValue    string          `db:"target_value"`
+		RunSeconds     sql.NullFloat64 `db:"run_seconds"`
+	}
+	err = tx.GetContext(ctx, &job, `
+		SELECT sj.organization_id, sj.user_id, sj.scan_type, st.target_value,
+		       EXTRACT(EPOCH FROM NOW() - sj.started_at) AS run_seconds
+		FROM scan_jobs sj
+		JOIN scan_targets st ON st.id = sj.target_id
+		WHERE sj.id = $1 AND sj.status = 'running'
+		AND (sj.worker_id IS NULL OR $2 = '' OR sj.worker_id::text = $2)
+		FOR UPDATE OF sj
+	`, req.ScanJobID, req.WorkerID)
+	if err == sql.ErrNoRows {
+		return nil, status.Error(codes.FailedPrecondition, "scan job is not running or belongs to another worker")
+	}
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to lock scan job", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to load scan job")
+	}
+
+	result := repository.ScanResult{
+		ScanJobID:      req.ScanJobID,
+		ResultType:     req.ResultType,
+		Summary:        req.Summary,
+		SeverityCounts: req.SeverityCounts,
+		RawData:        req.RawData,
+	}
+	if job.OrganizationID.Valid {
+		result.OrganizationID = &job.OrganizationID.String
+	}
+	riskScore := int(req.RiskScore)
+	result.RiskScore = &riskScore
+	if err := repository.NewScanResultRepo(tx, s.cipher).Create(ctx, &result); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to store scan result", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to store scan result")
+	}
+	resp := &SubmitScanResultResponse{ScanResultID: result.ID}
+
+	// WebSocket clients hear about the result from the stream rather than
+	// the database triggers
+	if s.scanEvents != nil {
+		if err := scanevents.MarkPublished(ctx, tx); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to mark scan events published", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to store scan result")
+		}
+	}
+
+	// Findings the worker streamed while scanning join the result; the
+	// submitted copies of them are not stored again
+	var streamed []struct {
+		Fingerprint string `db:"fingerprint"`
+		Suppressed  bool   `db:"suppressed"`
+	}
+	err = tx.SelectContext(ctx, &streamed, `
+		UPDATE vulnerabilities SET scan_result_id = $1
+		WHERE scan_job_id = $2 AND scan_result_id IS NULL
+		RETURNING fingerprint, suppression_rule_id IS NOT NULL AS suppressed
+	`, resp.ScanResultID, req.ScanJobID)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to attach streamed findings", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to store scan result")
+	}
+	alreadyStored := make(map[string]int, len(streamed))
+	for _, f := range streamed {
+		alreadyStored[f.Fingerprint]++
+		resp.VulnerabilitiesStored++
+		if f.Suppressed {
+			resp.VulnerabilitiesSuppressed++
+		}
+	}
+	var stored []scanevents.Event
+
+	// Known false positives are stored but marked suppressed
+	var suppressions []findings.SuppressionRule
+	if job.OrganizationID.Valid {
+		suppressions, err = findings.ActiveSuppressionRules(ctx, tx, job.OrganizationID.String, job.TargetValue)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to load suppression rules", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to store scan result")
+		}
+	}
+
+	for _, vuln := range req.Vulnerabilities {
+		fingerprint := findings.Fingerprint(vuln.Title, vuln.Category, vuln.AffectedComponent)
+		if alreadyStored[fingerprint] > 0 {
+			alreadyStored[fingerprint]--
+			continue
+		}
+		var ruleID *string
+		if rule := findings.MatchSuppression(suppressions, findings.Candidate{
+			Asset:             job.TargetValue,
+			PluginID:          vuln.PluginID,
+			CVEID:             vuln.CVEID,
+			AffectedComponent: vuln.AffectedComponent,
+		}); rule != nil {
+			ruleID = &rule.ID
+			resp.VulnerabilitiesSuppressed++
+		}
+
+		var vulnID string
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO vulnerabilities (
+				scan_result_id, scan_job_id, organization_id, title, description, severity,
+				cvss_score, cvss_vector, category, affected_component, remediation, fingerprint,
+				plugin_id, cve_id, suppression_rule_id, suppressed_at, cwe_ids
+			) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), $12,
+				NULLIF($13, ''), NULLIF(upper($14), ''), $15, CASE WHEN $15::uuid IS NULL THEN NULL ELSE NOW() END,
+				NULLIF($16::text[], '{}'))
+			RETURNING id
+		`, resp.ScanResultID, req.ScanJobID, job.OrganizationID, vuln.Title, vuln.Description, vuln.Severity,
+			vuln.CVSSScore, vuln.CVSSVector, vuln.Category, vuln.AffectedComponent, vuln.Remediation,
+			fingerprint, vuln.PluginID, vuln.CVEID, ruleID, stringArray(vuln.CWEIDs)).Scan(&vulnID)
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to store vulnerability", zap.Error(err))
+			return nil, status.Errorf(codes.InvalidArgument, "invalid vulnerability %q", vuln.Title)
+		}
+		resp.VulnerabilitiesStored++
+
+		err = events.Enqueue(ctx, tx, job.OrganizationID.String, req.ScanJobID, events.FindingCreated{
+			FindingID:         vulnID,
+			ScanID:            req.ScanJobID,
+			Title:             vuln.Title,
+			Severity:          vuln.Severity,
+			CVSSScore:         vuln.CVSSScore,
+			Category:          vuln.Category,
+			AffectedComponent: vuln.AffectedComponent,
+			Fingerprint:       fingerprint,
+			Suppressed:        ruleID != nil,
+		})
+		if err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to record finding event", zap.Error(err))
+			return nil, status.Error(codes.Internal, "failed to store scan result")
+		}
+
+		finding := scanEventFinding(vuln, vulnID)
+		finding.Stored = true
+		finding.Suppressed = ruleID != nil
+		stored = append(stored, scanevents.Event{
+			Type:           scanevents.TypeFinding,
+			ScanID:         req.ScanJobID,
+			UserID:         job.UserID,
+			OrganizationID: job.OrganizationID.String,
+			WorkerID:       req.WorkerID,
+			Finding:        finding,
+		})
+	}
+
+	// Fill in CVSS and CWE data for findings whose CVE is already known
+	if _, err := intel.EnrichScan(ctx, tx, req.ScanJobID); err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to enrich findings", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to store scan result")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE scan_jobs
+		SET status = $1, error_message = NULLIF($2, ''), completed_at = NOW(),
+		    progress_percentage = CASE WHEN $1 = 'completed' THEN 100 ELSE progress_percentage END,
+		    requests_sent = NULLIF($4, 0), bytes_sent = NULLIF($5, 0)
+		WHERE id = $3
+	`, req.Status, req.ErrorMessage, req.ScanJobID, req.RequestsSent, req.BytesSent)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to close scan job", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to update scan job")
+	}
+
+	err = events.Enqueue(ctx, tx, job.OrganizationID.String, req.ScanJobID, events.ScanFinished{
+		ScanID:             req.ScanJobID,
+		UserID:             job.UserID,
+		ScanType:           job.ScanType,
+		Status:             req.Status,
+		VulnerabilityCount: int(resp.VulnerabilitiesStored),
+		RiskScore:          req.RiskScore,
+		ErrorMessage:       req.ErrorMessage,
+	})
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to record scan event", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to update scan job")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, "failed to commit scan result")
+	}
+
+	if s.scanEvents != nil {
+		stored = append(stored, scanevents.Event{
+			Type:               scanevents.TypeFinished,
+			ScanID:             req.ScanJobID,
+			UserID:             job.UserID,
+			OrganizationID:     job.OrganizationID.String,
+			WorkerID:           req.WorkerID,
+			ScanType:           job.ScanType,
+			Status:             req.Status,
+			VulnerabilityCount: int(resp.VulnerabilitiesStored),
+			ErrorMessage:       req.ErrorMessage,
+		})
+		// The result is stored either way; only live updates are missed
+		if err := s.scanEvents.Publish(ctx, stored...); err != nil {
+			logging.FromContext(ctx, s.logger).Error("Failed to publish scan result events", zap.Error(err))
+		}
+	}
+
+	if job.RunSeconds.Valid {
+		metrics.ScanDuration.WithLabelValues(job.ScanType, req.Status).Observe(job.RunSeconds.Float64)
+	}
+	for _, timing := range req.PhaseTimings {
+		if timing.DurationSeconds < 0 {
+			continue
+		}
+		metrics.ScanPhaseDuration.WithLabelValues(job.ScanType, phaseLabel(timing.Phase)).Observe(timing.DurationSeconds)
+	}
+
+	logging.FromContext(ctx, s.logger).Info("Scan result ingested",
+		zap.String("scan_job_id", req.ScanJobID),
+		zap.String("status", req.Status),
+		zap.Int32("vulnerabilities", resp.VulnerabilitiesStored),
+		zap.Int32("suppressed", resp.VulnerabilitiesSuppressed),
+	)
+
+	return resp, nil
+}
+
+// scanPhases are the phase labels workers may report timings for; anything
+// else is recorded as "other" to keep metric cardinality bounded
+var scanPhases = map[string]bool{
+	"discovery":          true,
+	"enumeration":        true,
+	"exploitation_check": true,
+	"reporting":          true,
+}
+
+func phaseLabel(phase string) string {
+	if scanPhases[phase] {
+		return phase
+	}
+	return "other"
+}
+
+// Scan job control actions
+const (
+	ControlContinue = "continue"
+	ControlPause    = "pause"
+	ControlStop     = "stop"
+)
+
+// ScanJobControl tells a worker what to do with the jobs it holds. Workers
+// poll it while scanning: paused jobs should be suspended until they report
+// continue again, and stopped jobs (or ones no longer assigned to the
+// worker) abandoned without submitting results.
+func (s *InternalService) ScanJobControl(ctx context.Context, req *ScanJobControlRequest) (*ScanJobControlResponse, error) {
+	if req.WorkerID == "" {
+		return nil, status.Error(codes.InvalidArgument, "worker_id is required")
+	}
+
+	resp := &ScanJobControlResponse{Controls: []ScanJobControl{}}
+	if len(req.ScanJobIDs) == 0 {
+		return resp, nil
+	}
+
+	err := s.db.SelectContext(ctx, &resp.Controls, `
+		SELECT ids.id AS scan_job_id,
+		       CASE
+		           WHEN sj.status = 'running' AND (sj.worker_id IS NULL OR sj.worker_id::text = $2) THEN $3
+		           WHEN sj.status = 'paused' AND sj.worker_id::text = $2 THEN $4
+		           ELSE $5
+		       END AS action
+		FROM unnest($1::text[]) AS ids(id)
+		LEFT JOIN scan_jobs sj ON sj.id::text = ids.id
+	`, stringArray(req.ScanJobIDs), req.WorkerID, ControlContinue, ControlPause, ControlStop)
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Failed to load scan job controls", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to load scan job controls")
+	}
+
+	return resp, nil
+}
+
+// IntrospectToken validates a user token on behalf of an internal service
+func (s *InternalService) IntrospectToken(ctx context.Context, req *IntrospectTokenRequest) (*IntrospectTokenResponse, error) {
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	claims, sessionID, err := s.authService.IntrospectToken(ctx, req.Token)
+	if err != nil {
+		// Invalid tokens are a normal answer, not an RPC failure
+		return &IntrospectTokenResponse{Active: false}, nil
+	}
+
+	resp := &IntrospectTokenResponse{
+		Active:         true,
+		UserID:         claims.UserID,
+		Email:          claims.Email,
+		Role:           claims.Role,
+		OrganizationID: claims.OrgID,
+		Features:       claims.Features,
+		SessionID:      sessionID,
+	}
+	if claims.ExpiresAt != nil {
+		expiresAt := claims.ExpiresAt.Time
+		resp.ExpiresAt = &expiresAt
+	}
+	return resp, nil
+}
+
+// rpcLogFields correlates a call's log lines with the worker and job it
+// concerns, and with the caller's request ID when one is sent as metadata
+func rpcLogFields(ctx context.Context, method string, req interface{}) []zap.Field {
+	fields := []zap.Field{zap.String("rpc_method", method)}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get("x-request-id"); len(ids) > 0 {
+			fields = append(fields, zap.String("request_id", ids[0]))
+		}
+	}
+
+	switch r := req.(type) {
+	case *DispatchScanJobRequest:
+		fields = append(fields, zap.String("worker_id", r.WorkerID))
+	case *SubmitScanResultRequest:
+		fields = append(fields, zap.String("worker_id", r.WorkerID), zap.String("scan_job_id", r.ScanJobID))
+	case *ScanJobControlRequest:
+		fields = append(fields, zap.String("worker_id", r.WorkerID))
+	case *ScanConsoleChunk:
+		fields = append(fields, zap.String("worker_id", r.WorkerID), zap.String("scan_job_id", r.ScanJobID))
+	case *PublishScanEventsRequest:
+		fields = append(fields, zap.String("worker_id", r.WorkerID), zap.String("scan_job_id", r.ScanJobID))
+	}
+	return fields
+}
+
+// serviceDesc registers InternalService without protoc-generated stubs
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*InternalService)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "DispatchScanJob", Handler: unaryHandler("DispatchScanJob", func(s *InternalService, ctx context.Context, req *DispatchScanJobRequest) (interface{}, error) {
+			return s.DispatchScanJob(ctx, req)
+		})},
+		{MethodName: "SubmitScanResult", Handler: unaryHandler("SubmitScanResult", func(s *InternalService, ctx context.Context, req *SubmitScanResultRequest) (interface{}, error) {
+			return s.SubmitScanResult(ctx, req)
+		})},
+		{MethodName: "ScanJobControl", Handler: unaryHandler("ScanJobControl", func(s *InternalService, ctx context.Context, req *ScanJobControlRequest) (interface{}, error) {
+			return s.ScanJobControl(ctx, req)
+		})},
+		{MethodName: "IntrospectToken", Handler: unaryHandler("IntrospectToken", func(s *InternalService, ctx context.Context, req *IntrospectTokenRequest) (interface{}, error) {
+			return s.IntrospectToken(ctx, req)
+		})},
+		{MethodName: "PublishScanEvents", Handler: unaryHandler("PublishScanEvents", func(s *InternalService, ctx context.Context, req *PublishScanEventsRequest) (interface{}, error) {
+			return s.PublishScanEvents(ctx, req)
+		})},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "StreamScanConsole", ClientStreams: true, Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			return srv.(*InternalService).StreamScanConsole(&scanConsoleStream{ServerStream: stream})
+		}},
+	},
+	Metadata: "proto/internal/v1/internal.proto",
+}
+
+// unaryHandler adapts a typed method to the grpc.MethodDesc handler signature
+func unaryHandler[Req any](method string, call func(*InternalService, context.Context, *Req) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	fullMethod := fmt.Sprintf("/%s/%s", serviceName, method)
+
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		svc := srv.(*InternalService)
+		ctx = logging.With(ctx, svc.logger, rpcLogFields(ctx, method, req)...)
+		if interceptor == nil {
+			return call(svc, ctx, req)
+		}
+
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(svc, ctx, req.(*Req))
+		})
+	}
+}
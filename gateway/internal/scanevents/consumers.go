@@ -0,0 +1,282 @@
+package scanevents
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/events"
+	"github.com/cyper-security/gateway/internal/findings"
+	"github.com/cyper-security/gateway/internal/intel"
+	"github.com/cyper-security/gateway/internal/realtime"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Broadcast pushes events to the scan owner's WebSocket connections on this
+// instance; subscribe it with SubscribeEach
+func Broadcast(hub *realtime.Hub) Handler {
+	return func(ctx context.Context, event Event) error {
+		switch event.Type {
+		case TypeProgress:
+			hub.PublishToUser(event.UserID, realtime.ScanProgressEvent{
+				ScanID:       event.ScanID,
+				Progress:     event.Progress,
+				CurrentPhase: event.Phase,
+				Status:       "running",
+			})
+		case TypeFinding:
+			if event.Finding == nil {
+				return nil
+			}
+			found := realtime.VulnerabilityFoundEvent{
+				ScanID:            event.ScanID,
+				VulnerabilityID:   event.Finding.ID,
+				Title:             event.Finding.Title,
+				Severity:          event.Finding.Severity,
+				CVEID:             event.Finding.CVEID,
+				AffectedComponent: event.Finding.AffectedComponent,
+			}
+			if event.Finding.CVSSScore > 0 {
+				score := event.Finding.CVSSScore
+				found.CVSSScore = &score
+			}
+			hub.PublishToUser(event.UserID, found)
+		case TypeFinished:
+			hub.PublishToUser(event.UserID, realtime.ScanCompleteEvent{
+				ScanID:             event.ScanID,
+				Status:             event.Status,
+				VulnerabilityCount: event.VulnerabilityCount,
+				ErrorMessage:       event.ErrorMessage,
+			})
+		}
+		return nil
+	}
+}
+
+// Persister stores what workers report while a scan runs: its progress,
+// and the findings they stream before submitting the scan's result
+type Persister struct {
+	db     *database.DB
+	logger *zap.Logger
+}
+
+func NewPersister(db *database.DB, logger *zap.Logger) *Persister {
+	return &Persister{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Handle stores an event. Events for scans that are no longer running, or
+// that were reassigned to another worker, are ignored.
+func (p *Persister) Handle(ctx context.Context, event Event) error {
+	switch event.Type {
+	case TypeProgress:
+		return p.saveProgress(ctx, event)
+	case TypeFinding:
+		if event.Finding == nil || event.Finding.Stored {
+			return nil
+		}
+		return p.saveFinding(ctx, event)
+	}
+	return nil
+}
+
+func (p *Persister) saveProgress(ctx context.Context, event Event) error {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := MarkPublished(ctx, tx); err != nil {
+		return fmt.Errorf("failed to mark progress published: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE scan_jobs
+		SET progress_percentage = $3, current_phase = COALESCE(NULLIF($4, ''), current_phase)
+		WHERE id = $1 AND status = 'running'
+		AND (worker_id IS NULL OR $2 = '' OR worker_id::text = $2)
+	`, event.ScanID, event.WorkerID, event.Progress, event.Phase)
+	if err != nil {
+		return fmt.Errorf("failed to store scan progress: %w", err)
+	}
+	return tx.Commit()
+}
+
+// saveFinding stores a streamed finding under the ID it was published with,
+// so a redelivered event is stored once. SubmitScanResult attaches it to the
+// scan's result; a finding that arrives after the result was submitted is
+// dropped, as the result is the scan's final word.
+func (p *Persister) saveFinding(ctx context.Context, event Event) error {
+	f := event.Finding
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var job struct {
+		OrganizationID sql.NullString `db:"organization_id"`
+		TargetValue    string         `db:"target_value"`
+	}
+	// Shares the lock SubmitScanResult takes, so the finding is either
+	// stored before the result or not at all
+	err = tx.GetContext(ctx, &job, `
+		SELECT sj.organization_id, st.target_value
+		FROM scan_jobs sj
+		JOIN scan_targets st ON st.id = sj.target_id
+		WHERE sj.id = $1 AND sj.status IN ('running', 'paused')
+		AND (sj.worker_id IS NULL OR $2 = '' OR sj.worker_id::text = $2)
+		FOR SHARE OF sj
+	`, event.ScanID, event.WorkerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		p.logger.Debug("Dropping finding for a scan that is no longer running",
+			zap.String("scan_id", event.ScanID),
+			zap.String("finding_id", f.ID),
+		)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load scan job: %w", err)
+	}
+
+	var suppressions []findings.SuppressionRule
+	if job.OrganizationID.Valid {
+		suppressions, err = findings.ActiveSuppressionRules(ctx, tx, job.OrganizationID.String, job.TargetValue)
+		if err != nil {
+			return fmt.Errorf("failed to load suppression rules: %w", err)
+		}
+	}
+	var ruleID *string
+	if rule := findings.MatchSuppression(suppressions, findings.Candidate{
+		Asset:             job.TargetValue,
+		PluginID:          f.PluginID,
+		CVEID:             f.CVEID,
+		AffectedComponent: f.AffectedComponent,
+	}); rule != nil {
+		ruleID = &rule.ID
+	}
+
+	if err := MarkPublished(ctx, tx); err != nil {
+		return fmt.Errorf("failed to mark finding published: %w", err)
+	}
+	cweIDs := f.CWEIDs
+	if cweIDs == nil {
+		cweIDs = []string{}
+	}
+	fingerprint := findings.Fingerprint(f.Title, f.Category, f.AffectedComponent)
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO vulnerabilities (
+			id, scan_job_id, organization_id, title, description, severity,
+			cvss_score, cvss_vector, category, affected_component, remediation, fingerprint,
+			plugin_id, cve_id, suppression_rule_id, suppressed_at, cwe_ids
+		) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), $12,
+			NULLIF($13, ''), NULLIF(upper($14), ''), $15, CASE WHEN $15::uuid IS NULL THEN NULL ELSE NOW() END,
+			NULLIF($16::text[], '{}'))
+		ON CONFLICT (id) DO NOTHING
+	`, f.ID, event.ScanID, job.OrganizationID, f.Title, f.Description, f.Severity,
+		f.CVSSScore, f.CVSSVector, f.Category, f.AffectedComponent, f.Remediation,
+		fingerprint, f.PluginID, f.CVEID, ruleID, pq.Array(cweIDs))
+	if err != nil {
+		return fmt.Errorf("failed to store finding %q: %w", f.Title, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		// Stored by an earlier delivery
+		return nil
+	}
+
+	err = events.Enqueue(ctx, tx, job.OrganizationID.String, event.ScanID, events.FindingCreated{
+		FindingID:         f.ID,
+		ScanID:            event.ScanID,
+		Title:             f.Title,
+		Severity:          f.Severity,
+		CVSSScore:         f.CVSSScore,
+		Category:          f.Category,
+		AffectedComponent: f.AffectedComponent,
+		Fingerprint:       fingerprint,
+		Suppressed:        ruleID != nil,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := intel.EnrichScan(ctx, tx, event.ScanID); err != nil {
+		return fmt.Errorf("failed to enrich findings: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Waker is told to act now rather than at its next tick, e.g. the
+// escalation engine
+type Waker interface {
+	Wake()
+}
+
+// Recipient is a user notified about a scan
+type Recipient struct {
+	UserID string
+	Email  string
+}
+
+// FailureFunc notifies the owner of a scan that failed. An error has the
+// event delivered again, so it should not fail after notifying.
+type FailureFunc func(ctx context.Context, owner Recipient, event Event) error
+
+// Notifier notifies people about scan events: critical findings wake the
+// escalation engine, and owners hear about their failed scans
+type Notifier struct {
+	db          *database.DB
+	escalations Waker
+	onFailure   []FailureFunc
+	logger      *zap.Logger
+}
+
+// NewNotifier creates a notifier; escalations may be nil
+func NewNotifier(db *database.DB, escalations Waker, logger *zap.Logger) *Notifier {
+	return &Notifier{
+		db:          db,
+		escalations: escalations,
+		logger:      logger,
+	}
+}
+
+// AddFailureNotifier registers a channel for failed scans. Add notifiers
+// before the stream starts.
+func (n *Notifier) AddFailureNotifier(notify FailureFunc) {
+	n.onFailure = append(n.onFailure, notify)
+}
+
+// Handle notifies about an event
+func (n *Notifier) Handle(ctx context.Context, event Event) error {
+	switch event.Type {
+	case TypeFinding:
+		// The engine pages about critical findings once they are stored;
+		// one streamed but not stored yet is picked up at its next tick
+		if event.Finding != nil && event.Finding.Severity == "critical" && !event.Finding.Suppressed && n.escalations != nil {
+			n.escalations.Wake()
+		}
+	case TypeFinished:
+		if event.Status != "failed" || len(n.onFailure) == 0 {
+			return nil
+		}
+		owner := Recipient{UserID: event.UserID}
+		err := n.db.Reader().GetContext(ctx, &owner.Email, `SELECT email FROM users WHERE id = $1`, event.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load scan owner: %w", err)
+		}
+		var errs []error
+		for _, notify := range n.onFailure {
+			if err := notify(ctx, owner, event); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
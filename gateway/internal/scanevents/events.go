@@ -0,0 +1,81 @@
+// Package scanevents carries scan events (progress, findings, completion)
+// through a Redis stream. Workers publish progress and findings over gRPC as
+// a scan runs, and the gateway publishes the outcome once a result is
+// submitted. Consumers (the WebSocket broadcaster, the findings persister and
+// the notifier) each read the stream through a consumer group and
+// acknowledge what they handled, so events delivered to an instance that
+// crashes or restarts are handled again rather than lost.
+package scanevents
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Event types
+const (
+	TypeProgress = "scan.progress"
+	TypeFinding  = "scan.finding"
+	TypeFinished = "scan.finished"
+)
+
+// Finding is a vulnerability reported by a scan
+type Finding struct {
+	// Assigned when the event is published; the persister stores the
+	// finding under this ID
+	ID                string   `json:"id"`
+	Title             string   `json:"title"`
+	Description       string   `json:"description,omitempty"`
+	Severity          string   `json:"severity"`
+	CVSSScore         float64  `json:"cvss_score,omitempty"`
+	CVSSVector        string   `json:"cvss_vector,omitempty"`
+	Category          string   `json:"category,omitempty"`
+	AffectedComponent string   `json:"affected_component,omitempty"`
+	Remediation       string   `json:"remediation,omitempty"`
+	PluginID          string   `json:"plugin_id,omitempty"`
+	CVEID             string   `json:"cve_id,omitempty"`
+	CWEIDs            []string `json:"cwe_ids,omitempty"`
+	// Stored findings were written with the scan's result before the event
+	// was published; the others are stored by the persister
+	Stored     bool `json:"stored,omitempty"`
+	Suppressed bool `json:"suppressed,omitempty"`
+}
+
+// Event is an entry of the scan event stream
+type Event struct {
+	ID             string `json:"-"` // Stream entry ID, set when read
+	Type           string `json:"type"`
+	ScanID         string `json:"scan_id"`
+	UserID         string `json:"user_id"`
+	OrganizationID string `json:"organization_id,omitempty"`
+	WorkerID       string `json:"worker_id,omitempty"`
+
+	// scan.progress
+	Progress int    `json:"progress,omitempty"` // 0-100
+	Phase    string `json:"phase,omitempty"`
+
+	// scan.finding
+	Finding *Finding `json:"finding,omitempty"`
+
+	// scan.finished
+	ScanType           string `json:"scan_type,omitempty"`
+	Status             string `json:"status,omitempty"`
+	VulnerabilityCount int    `json:"vulnerability_count,omitempty"`
+	ErrorMessage       string `json:"error_message,omitempty"`
+
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Execer is satisfied by *sqlx.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// MarkPublished tells the NOTIFY triggers that the changes made in tx are
+// published to the stream, so the database bridge does not push them to
+// WebSocket clients a second time
+func MarkPublished(ctx context.Context, tx Execer) error {
+	_, err := tx.ExecContext(ctx, `SELECT set_config('cyper.scan_events_published', 'on', true)`)
+	return err
+}
@@ -0,0 +1,441 @@
+package scanevents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Config tunes the stream and its consumers
+type Config struct {
+	Stream string
+	// MaxLen caps the stream (approximately). Entries trimmed before a
+	// lagging group read them are lost to it.
+	MaxLen int64
+	// Instance names this instance's consumers. It must be unique per
+	// instance and stable across restarts, so that a restarted instance
+	// picks up the entries it had not acknowledged.
+	Instance  string
+	BatchSize int64
+	Block     time.Duration // How long a read waits for new entries
+	// ClaimIdle is how long an entry may stay unacknowledged before another
+	// consumer takes it over: its consumer crashed, or handling it failed
+	ClaimIdle time.Duration
+	// MaxDeliveries moves an entry to the dead letter stream once it has
+	// been delivered this many times without being acknowledged
+	MaxDeliveries int64
+	// GroupExpiry deletes the broadcast group of an instance that has not
+	// read from the stream for this long
+	GroupExpiry time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Stream:        "scan:events",
+		MaxLen:        100000,
+		BatchSize:     100,
+		Block:         5 * time.Second,
+		ClaimIdle:     30 * time.Second,
+		MaxDeliveries: 5,
+		GroupExpiry:   24 * time.Hour,
+	}
+}
+
+// DeadLetterStream holds entries no consumer could handle
+func (c Config) DeadLetterStream() string {
+	return c.Stream + ":dead"
+}
+
+// Handler handles an event for a consumer group. Returning an error leaves
+// the entry unacknowledged, so it is delivered again after ClaimIdle.
+type Handler func(ctx context.Context, event Event) error
+
+// group is a consumer group reading the stream
+type group struct {
+	name    string // Metric label
+	key     string // Redis group name
+	start   string // Where a new group starts reading
+	handler Handler
+}
+
+// Stream publishes scan events and runs the consumer groups reading them
+type Stream struct {
+	redis  *redis.Client
+	config Config
+	groups []group
+	logger *zap.Logger
+}
+
+func NewStream(redisClient *redis.Client, config Config, logger *zap.Logger) *Stream {
+	return &Stream{
+		redis:  redisClient,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Publish appends events to the stream. It returns once Redis has accepted
+// them all; on error some may have been appended.
+func (s *Stream) Publish(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	pipe := s.redis.Pipeline()
+	for _, event := range events {
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now().UTC()
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+		}
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: s.config.Stream,
+			MaxLen: s.config.MaxLen,
+			Approx: true,
+			Values: map[string]interface{}{"type": event.Type, "event": payload},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish scan events: %w", err)
+	}
+	for _, event := range events {
+		metrics.ScanEventsPublished.WithLabelValues(event.Type).Inc()
+	}
+	return nil
+}
+
+// Subscribe adds a consumer group shared by every instance: each event is
+// handled once, by whichever instance reads it. Subscribe before Start.
+func (s *Stream) Subscribe(name string, handler Handler) {
+	s.groups = append(s.groups, group{name: name, key: name, start: "0", handler: handler})
+}
+
+// SubscribeEach adds a consumer group per instance, for handlers with local
+// state such as WebSocket connections: every instance handles every event
+// published from the time its group was created. Subscribe before Start.
+func (s *Stream) SubscribeEach(name string, handler Handler) {
+	s.groups = append(s.groups, group{name: name, key: name + ":" + s.config.Instance, start: "$", handler: handler})
+}
+
+// Start runs the consumer groups until ctx is cancelled
+func (s *Stream) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, g := range s.groups {
+		wg.Add(1)
+		go func(g group) {
+			defer wg.Done()
+			s.consume(ctx, g)
+		}(g)
+	}
+	s.logger.Info("Consuming scan events",
+		zap.String("stream", s.config.Stream),
+		zap.String("instance", s.config.Instance),
+		zap.Int("groups", len(s.groups)),
+	)
+
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-prune.C:
+			s.pruneGroups(ctx)
+		}
+	}
+}
+
+// consume runs one consumer group: first the entries this consumer was
+// given before a restart, then new entries, taking over those other
+// consumers left unacknowledged for ClaimIdle
+func (s *Stream) consume(ctx context.Context, g group) {
+	for !s.createGroup(ctx, g) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	s.replayPending(ctx, g)
+
+	claim := time.NewTicker(s.config.ClaimIdle / 2)
+	defer claim.Stop()
+	for ctx.Err() == nil {
+		select {
+		case <-claim.C:
+			s.reclaim(ctx, g)
+		default:
+		}
+
+		streams, err := s.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    g.key,
+			Consumer: s.config.Instance,
+			Streams:  []string{s.config.Stream, ">"},
+			Count:    s.config.BatchSize,
+			Block:    s.config.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				// The stream or group was deleted
+				s.createGroup(ctx, g)
+				continue
+			}
+			s.logger.Error("Failed to read scan events", zap.String("group", g.key), zap.Error(err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				s.handle(ctx, g, msg)
+			}
+		}
+	}
+}
+
+// createGroup makes sure the group exists
+func (s *Stream) createGroup(ctx context.Context, g group) bool {
+	err := s.redis.XGroupCreateMkStream(ctx, s.config.Stream, g.key, g.start).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to create scan event consumer group", zap.String("group", g.key), zap.Error(err))
+		}
+		return false
+	}
+	return true
+}
+
+// replayPending handles the entries delivered to this consumer that it had
+// not acknowledged when it last stopped
+func (s *Stream) replayPending(ctx context.Context, g group) {
+	cursor := "0"
+	replayed := 0
+	for ctx.Err() == nil {
+		streams, err := s.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    g.key,
+			Consumer: s.config.Instance,
+			Streams:  []string{s.config.Stream, cursor},
+			Count:    s.config.BatchSize,
+			Block:    -1,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if ctx.Err() == nil {
+				s.logger.Error("Failed to read pending scan events", zap.String("group", g.key), zap.Error(err))
+			}
+			return
+		}
+		if len(streams) == 0 || len(streams[0].Messages) == 0 {
+			break
+		}
+		for _, msg := range streams[0].Messages {
+			cursor = msg.ID
+			// Entries trimmed from the stream come back without values
+			if msg.Values == nil {
+				s.redis.XAck(ctx, s.config.Stream, g.key, msg.ID)
+				continue
+			}
+			s.handle(ctx, g, msg)
+			replayed++
+		}
+	}
+	if replayed > 0 {
+		s.logger.Info("Replayed unacknowledged scan events", zap.String("group", g.key), zap.Int("count", replayed))
+	}
+}
+
+// reclaim takes over entries left unacknowledged for ClaimIdle, and moves
+// those delivered MaxDeliveries times to the dead letter stream
+func (s *Stream) reclaim(ctx context.Context, g group) {
+	pending, err := s.redis.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.config.Stream,
+		Group:  g.key,
+		Idle:   s.config.ClaimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  s.config.BatchSize,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to list pending scan events", zap.String("group", g.key), zap.Error(err))
+		}
+		return
+	}
+
+	var ids []string
+	for _, p := range pending {
+		if p.RetryCount >= s.config.MaxDeliveries {
+			s.deadLetter(ctx, g, p.ID, fmt.Sprintf("delivered %d times", p.RetryCount))
+			continue
+		}
+		ids = append(ids, p.ID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	msgs, err := s.redis.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   s.config.Stream,
+		Group:    g.key,
+		Consumer: s.config.Instance,
+		MinIdle:  s.config.ClaimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to claim pending scan events", zap.String("group", g.key), zap.Error(err))
+		}
+		return
+	}
+	for _, msg := range msgs {
+		s.handle(ctx, g, msg)
+	}
+}
+
+// handle runs the group's handler and acknowledges the entry once handled
+func (s *Stream) handle(ctx context.Context, g group, msg redis.XMessage) {
+	eventType, _ := msg.Values["type"].(string)
+	payload, _ := msg.Values["event"].(string)
+
+	var event Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		s.logger.Warn("Malformed scan event", zap.String("group", g.key), zap.String("entry_id", msg.ID), zap.Error(err))
+		s.deadLetter(ctx, g, msg.ID, "malformed: "+err.Error())
+		return
+	}
+	event.ID = msg.ID
+
+	if err := g.handler(ctx, event); err != nil {
+		metrics.ScanEventsConsumed.WithLabelValues(g.name, eventType, "failed").Inc()
+		if ctx.Err() == nil {
+			s.logger.Warn("Failed to handle scan event",
+				zap.String("group", g.key),
+				zap.String("entry_id", msg.ID),
+				zap.String("type", eventType),
+				zap.String("scan_id", event.ScanID),
+				zap.Error(err),
+			)
+		}
+		return
+	}
+	if err := s.redis.XAck(ctx, s.config.Stream, g.key, msg.ID).Err(); err != nil && ctx.Err() == nil {
+		// Handled again after ClaimIdle; handlers tolerate redelivery
+		s.logger.Warn("Failed to acknowledge scan event", zap.String("group", g.key), zap.String("entry_id", msg.ID), zap.Error(err))
+	}
+	metrics.ScanEventsConsumed.WithLabelValues(g.name, eventType, "handled").Inc()
+}
+
+// deadLetter copies an entry to the dead letter stream and acknowledges it
+func (s *Stream) deadLetter(ctx context.Context, g group, id, reason string) {
+	msgs, err := s.redis.XRangeN(ctx, s.config.Stream, id, id, 1).Result()
+	if err != nil {
+		s.logger.Error("Failed to load scan event for the dead letter stream", zap.String("entry_id", id), zap.Error(err))
+		return
+	}
+
+	eventType := ""
+	if len(msgs) > 0 {
+		eventType, _ = msgs[0].Values["type"].(string)
+		values := map[string]interface{}{
+			"group":    g.key,
+			"entry_id": id,
+			"reason":   reason,
+			"type":     eventType,
+			"event":    msgs[0].Values["event"],
+		}
+		err := s.redis.XAdd(ctx, &redis.XAddArgs{
+			Stream: s.config.DeadLetterStream(),
+			MaxLen: s.config.MaxLen,
+			Approx: true,
+			Values: values,
+		}).Err()
+		if err != nil {
+			s.logger.Error("Failed to dead-letter scan event", zap.String("entry_id", id), zap.Error(err))
+			return
+		}
+	}
+	if err := s.redis.XAck(ctx, s.config.Stream, g.key, id).Err(); err != nil {
+		s.logger.Warn("Failed to acknowledge dead-lettered scan event", zap.String("entry_id", id), zap.Error(err))
+		return
+	}
+	metrics.ScanEventsConsumed.WithLabelValues(g.name, eventType, "dead_lettered").Inc()
+	s.logger.Error("Scan event moved to the dead letter stream",
+		zap.String("group", g.key),
+		zap.String("entry_id", id),
+		zap.String("reason", reason),
+	)
+}
+
+// pruneGroups deletes the per-instance groups of instances that have not
+// read from the stream for GroupExpiry, e.g. replaced pods
+func (s *Stream) pruneGroups(ctx context.Context) {
+	var prefixes []string
+	own := make(map[string]bool)
+	for _, g := range s.groups {
+		if g.key != g.name {
+			prefixes = append(prefixes, g.name+":")
+			own[g.key] = true
+		}
+	}
+	if len(prefixes) == 0 {
+		return
+	}
+
+	groups, err := s.redis.XInfoGroups(ctx, s.config.Stream).Result()
+	if err != nil {
+		s.logger.Warn("Failed to list scan event consumer groups", zap.Error(err))
+		return
+	}
+	for _, info := range groups {
+		if own[info.Name] || !hasAnyPrefix(info.Name, prefixes) {
+			continue
+		}
+		consumers, err := s.redis.XInfoConsumers(ctx, s.config.Stream, info.Name).Result()
+		if err != nil {
+			s.logger.Warn("Failed to list scan event consumers", zap.String("group", info.Name), zap.Error(err))
+			continue
+		}
+		active := false
+		for _, c := range consumers {
+			if c.Idle < s.config.GroupExpiry {
+				active = true
+			}
+		}
+		if active {
+			continue
+		}
+		if err := s.redis.XGroupDestroy(ctx, s.config.Stream, info.Name).Err(); err != nil {
+			s.logger.Warn("Failed to delete scan event consumer group", zap.String("group", info.Name), zap.Error(err))
+			continue
+		}
+		s.logger.Info("Deleted the scan event group of an expired instance", zap.String("group", info.Name))
+	}
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
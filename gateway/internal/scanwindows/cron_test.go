@@ -0,0 +1,251 @@
+package scanwindows
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Time zones for the DST cases, whatever the host has
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func mustParse(t *testing.T, expr string) *Spec {
+	t.Helper()
+	spec, err := ParseSpec(expr)
+	if err != nil {
+		t.Fatalf("ParseSpec(%q): %v", expr, err)
+	}
+	return spec
+}
+
+func bitsOf(values ...int) uint64 {
+	var bits uint64
+	for _, v := range values {
+		bits |= 1 << uint(v)
+	}
+	return bits
+}
+
+func TestParseSpecFields(t *testing.T) {
+	tests := []struct {
+		expr                          string
+		minute, hour, dom, month, dow uint64
+	}{
+		{"0 0 1 1 0", bitsOf(0), bitsOf(0), bitsOf(1), bitsOf(1), bitsOf(0)},
+		{"*/15 9-17 * * 1-5", bitsOf(0, 15, 30, 45), bitsOf(9, 10, 11, 12, 13, 14, 15, 16, 17), bitsOf(seq(1, 31)...), bitsOf(seq(1, 12)...), bitsOf(1, 2, 3, 4, 5)},
+		{"5,10,20-22 0-6/2 1,15 jan-mar mon,wed,FRI", bitsOf(5, 10, 20, 21, 22), bitsOf(0, 2, 4, 6), bitsOf(1, 15), bitsOf(1, 2, 3), bitsOf(1, 3, 5)},
+		// A start with a step runs to the end of the range
+		{"10/20 12/6 * * *", bitsOf(10, 30, 50), bitsOf(12, 18), bitsOf(seq(1, 31)...), bitsOf(seq(1, 12)...), bitsOf(seq(0, 7)...) | 1},
+		// 7 is Sunday as well as 0
+		{"0 0 * * 7", bitsOf(0), bitsOf(0), bitsOf(seq(1, 31)...), bitsOf(seq(1, 12)...), bitsOf(0, 7)},
+		{"0 0 * * sat-7", bitsOf(0), bitsOf(0), bitsOf(seq(1, 31)...), bitsOf(seq(1, 12)...), bitsOf(0, 6, 7)},
+	}
+	for _, tt := range tests {
+		spec := mustParse(t, tt.expr)
+		if spec.minute != tt.minute || spec.hour != tt.hour || spec.dom != tt.dom || spec.month != tt.month || spec.dow != tt.dow {
+			t.Errorf("ParseSpec(%q) = %+v, want minute=%b hour=%b dom=%b month=%b dow=%b",
+				tt.expr, *spec, tt.minute, tt.hour, tt.dom, tt.month, tt.dow)
+		}
+	}
+}
+
+func seq(from, to int) []int {
+	var values []int
+	for v := from; v <= to; v++ {
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"* * * foo *",
+		"1-x * * * *",
+	} {
+		if _, err := ParseSpec(expr); err == nil {
+			t.Errorf("ParseSpec(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	utc := time.UTC
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "strictly after",
+			expr: "30 10 * * *",
+			from: time.Date(2026, 5, 4, 10, 30, 0, 0, utc),
+			want: time.Date(2026, 5, 5, 10, 30, 0, 0, utc),
+		},
+		{
+			name: "seconds are dropped",
+			expr: "* * * * *",
+			from: time.Date(2026, 5, 4, 10, 30, 59, 0, utc),
+			want: time.Date(2026, 5, 4, 10, 31, 0, 0, utc),
+		},
+		{
+			name: "step",
+			expr: "*/15 * * * *",
+			from: time.Date(2026, 5, 4, 10, 31, 0, 0, utc),
+			want: time.Date(2026, 5, 4, 10, 45, 0, 0, utc),
+		},
+		{
+			name: "range of weekdays skips the weekend",
+			expr: "0 9 * * mon-fri",
+			from: time.Date(2026, 5, 8, 10, 0, 0, 0, utc), // Friday
+			want: time.Date(2026, 5, 11, 9, 0, 0, 0, utc), // Monday
+		},
+		{
+			name: "month rollover",
+			expr: "0 0 1 * *",
+			from: time.Date(2026, 12, 15, 0, 0, 0, 0, utc),
+			want: time.Date(2027, 1, 1, 0, 0, 0, 0, utc),
+		},
+		{
+			name: "31st skips shorter months",
+			expr: "0 0 31 * *",
+			from: time.Date(2026, 3, 31, 0, 0, 0, 0, utc),
+			want: time.Date(2026, 5, 31, 0, 0, 0, 0, utc),
+		},
+		{
+			name: "leap day",
+			expr: "0 12 29 feb *",
+			from: time.Date(2026, 1, 1, 0, 0, 0, 0, utc),
+			want: time.Date(2028, 2, 29, 12, 0, 0, 0, utc),
+		},
+		{
+			name: "either-day rule: day of month",
+			expr: "0 0 13 * fri",
+			from: time.Date(2026, 2, 10, 0, 0, 0, 0, utc), // Tuesday
+			want: time.Date(2026, 2, 13, 0, 0, 0, 0, utc), // Friday the 13th
+		},
+		{
+			name: "either-day rule: day of week before day of month",
+			expr: "0 0 20 * mon",
+			from: time.Date(2026, 5, 14, 0, 0, 0, 0, utc), // Thursday
+			want: time.Date(2026, 5, 18, 0, 0, 0, 0, utc), // Monday, before the 20th
+		},
+		{
+			name: "either-day rule: day of month before day of week",
+			expr: "0 0 15 * sun",
+			from: time.Date(2026, 5, 11, 0, 0, 0, 0, utc), // Monday
+			want: time.Date(2026, 5, 15, 0, 0, 0, 0, utc), // Friday the 15th, before Sunday
+		},
+		{
+			name: "restricted day of week only",
+			expr: "0 0 * * sun",
+			from: time.Date(2026, 5, 11, 0, 0, 0, 0, utc),
+			want: time.Date(2026, 5, 17, 0, 0, 0, 0, utc),
+		},
+		{
+			name: "result is in the window's zone",
+			expr: "0 9 * * *",
+			from: time.Date(2026, 1, 5, 12, 0, 0, 0, utc),
+			want: time.Date(2026, 1, 5, 9, 0, 0, 0, mustLoad(t, "America/New_York")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mustParse(t, tt.expr).Next(tt.from, tt.want.Location())
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	got := mustParse(t, "0 0 30 feb *").Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.UTC)
+	if !got.IsZero() {
+		t.Errorf("Next = %s, want the zero time", got)
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	t.Run("spring forward skips the missing hour", func(t *testing.T) {
+		// 2026-03-29 02:00 CET jumps to 03:00 CEST: 02:30 never happens
+		spec := mustParse(t, "30 2 * * *")
+		got := spec.Next(time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), berlin)
+		want := time.Date(2026, 3, 30, 2, 30, 0, 0, berlin)
+		if !got.Equal(want) {
+			t.Errorf("Next = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("spring forward keeps later hours", func(t *testing.T) {
+		spec := mustParse(t, "0 3 * * *")
+		got := spec.Next(time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), berlin)
+		want := time.Date(2026, 3, 29, 3, 0, 0, 0, berlin)
+		if !got.Equal(want) {
+			t.Errorf("Next = %s, want %s", got, want)
+		}
+		if _, offset := got.Zone(); offset != 2*3600 {
+			t.Errorf("offset = %d, want CEST", offset)
+		}
+	})
+
+	t.Run("fall back matches the repeated hour once", func(t *testing.T) {
+		// 2026-10-25 03:00 CEST falls back to 02:00 CET: 02:30 happens twice
+		spec := mustParse(t, "30 2 * * *")
+		first := spec.Next(time.Date(2026, 10, 24, 12, 0, 0, 0, berlin), berlin)
+		want := time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC) // 02:30 CEST
+		if !first.Equal(want) {
+			t.Fatalf("Next = %s, want %s", first, want)
+		}
+		second := spec.Next(first, berlin)
+		want = time.Date(2026, 10, 26, 2, 30, 0, 0, berlin)
+		if !second.Equal(want) {
+			t.Errorf("Next after the first 02:30 = %s, want %s", second, want)
+		}
+	})
+
+	t.Run("fall back from inside the first pass", func(t *testing.T) {
+		spec := mustParse(t, "5,40 2 * * *")
+		from := time.Date(2026, 10, 25, 0, 10, 0, 0, time.UTC) // 02:10 CEST
+		got := spec.Next(from, berlin)
+		want := time.Date(2026, 10, 25, 0, 40, 0, 0, time.UTC) // 02:40 CEST
+		if !got.Equal(want) {
+			t.Fatalf("Next = %s, want %s", got, want)
+		}
+		got = spec.Next(got, berlin)
+		want = time.Date(2026, 10, 26, 2, 5, 0, 0, berlin)
+		if !got.Equal(want) {
+			t.Errorf("Next after 02:40 CEST = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("hourly specs run through the repeated hour", func(t *testing.T) {
+		spec := mustParse(t, "0 * * * *")
+		from := time.Date(2026, 10, 25, 0, 30, 0, 0, time.UTC) // 02:30 CEST
+		got := spec.Next(from, berlin)
+		want := time.Date(2026, 10, 25, 1, 0, 0, 0, time.UTC) // 02:00 CET
+		if !got.Equal(want) {
+			t.Errorf("Next = %s, want %s", got, want)
+		}
+	})
+}
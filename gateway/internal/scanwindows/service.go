@@ -0,0 +1,309 @@
+package scanwindows
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Config tunes the window enforcer
+type Config struct {
+	// Interval is how often scans are checked against their windows; a scan
+	// may start or keep running up to this long past a window's end
+	Interval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Interval: time.Minute,
+	}
+}
+
+// Service stores execution windows and enforces them on scans
+type Service struct {
+	db     *database.DB
+	config Config
+	logger *zap.Logger
+	wake   chan struct{}
+}
+
+func NewService(db *database.DB, config Config, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		config: config,
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// List returns the organization's windows, organization-wide ones first
+func (s *Service) List(ctx context.Context, orgID string) ([]Window, error) {
+	windows := []Window{}
+	err := s.db.SelectContext(ctx, &windows, `
+		SELECT `+WindowColumns+` FROM scan_windows
+		WHERE organization_id = $1
+		ORDER BY authorization_target_id NULLS FIRST, created_at
+	`, orgID)
+	return windows, err
+}
+
+// Create stores a validated window and applies it to queued scans
+func (s *Service) Create(ctx context.Context, w Window) (*Window, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	if w.AuthorizationTargetID != nil {
+		var exists bool
+		err := s.db.GetContext(ctx, &exists, `
+			SELECT EXISTS(SELECT 1 FROM authorized_targets WHERE id::text = $1 AND organization_id = $2)
+		`, *w.AuthorizationTargetID, w.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrTargetNotFound
+		}
+	}
+
+	var created Window
+	err := s.db.GetContext(ctx, &created, `
+		INSERT INTO scan_windows (
+			organization_id, authorization_target_id, name, cron_spec,
+			duration_minutes, timezone, pause_at_end, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+WindowColumns+`
+	`, w.OrganizationID, w.AuthorizationTargetID, w.Name, w.CronSpec,
+		w.DurationMinutes, w.Timezone, w.PauseAtEnd, w.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Wake()
+	return &created, nil
+}
+
+// Delete removes a window and releases the scans it held back
+func (s *Service) Delete(ctx context.Context, orgID, windowID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM scan_windows WHERE id = $1 AND organization_id = $2
+	`, windowID, orgID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrWindowNotFound
+	}
+
+	s.Wake()
+	return nil
+}
+
+// Slot returns when a scan under the authorized target may run, as of now
+func (s *Service) Slot(ctx context.Context, orgID, authorizationTargetID string) (Slot, error) {
+	var windows []Window
+	err := s.db.SelectContext(ctx, &windows, `
+		SELECT `+WindowColumns+` FROM scan_windows
+		WHERE organization_id = $1
+		AND (authorization_target_id IS NULL OR authorization_target_id::text = $2)
+	`, orgID, authorizationTargetID)
+	if err != nil {
+		return Slot{}, err
+	}
+
+	set := windowSet{}
+	for _, w := range windows {
+		if err := w.Validate(); err != nil {
+			s.logger.Warn("Skipping invalid execution window", zap.String("window_id", w.ID), zap.Error(err))
+			continue
+		}
+		set.add(w)
+	}
+	return SlotAt(set.forTarget(authorizationTargetID), time.Now()), nil
+}
+
+// windowSet is one organization's windows
+type windowSet struct {
+	orgWide  []Window
+	byTarget map[string][]Window
+}
+
+func (ws *windowSet) add(w Window) {
+	if w.AuthorizationTargetID == nil {
+		ws.orgWide = append(ws.orgWide, w)
+		return
+	}
+	if ws.byTarget == nil {
+		ws.byTarget = map[string][]Window{}
+	}
+	ws.byTarget[*w.AuthorizationTargetID] = append(ws.byTarget[*w.AuthorizationTargetID], w)
+}
+
+// forTarget returns the target's own windows, or the organization's
+func (ws *windowSet) forTarget(authorizationTargetID string) []Window {
+	if windows, ok := ws.byTarget[authorizationTargetID]; ok {
+		return windows
+	}
+	return ws.orgWide
+}
+
+// Wake runs the enforcer now rather than at its next tick
+func (s *Service) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start enforces windows until ctx is done. Every update is conditional on
+// the scan's status, so several gateway instances can run the enforcer and
+// user actions in between are never overwritten.
+func (s *Service) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting execution window enforcer", zap.Duration("interval", s.config.Interval))
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := s.enforce(ctx); err != nil {
+			s.logger.Error("Failed to enforce execution windows", zap.Error(err))
+		}
+	}
+}
+
+// heldScan is a scan the enforcer may have to hold, pause or resume
+type heldScan struct {
+	ID                    string       `db:"id"`
+	OrganizationID        string       `db:"organization_id"`
+	AuthorizationTargetID string       `db:"authorization_target_id"`
+	Status                string       `db:"status"`
+	NotBefore             sql.NullTime `db:"not_before"`
+	NotAfter              sql.NullTime `db:"not_after"`
+	PausedByWindow        bool         `db:"paused_by_window"`
+}
+
+func (s *Service) enforce(ctx context.Context) error {
+	var windows []Window
+	if err := s.db.SelectContext(ctx, &windows, `SELECT `+WindowColumns+` FROM scan_windows`); err != nil {
+		return err
+	}
+	sets := map[string]*windowSet{}
+	orgIDs := []string{}
+	for _, w := range windows {
+		if err := w.Validate(); err != nil {
+			s.logger.Warn("Skipping invalid execution window", zap.String("window_id", w.ID), zap.Error(err))
+			continue
+		}
+		set, ok := sets[w.OrganizationID]
+		if !ok {
+			set = &windowSet{}
+			sets[w.OrganizationID] = set
+			orgIDs = append(orgIDs, w.OrganizationID)
+		}
+		set.add(w)
+	}
+
+	// Scans of organizations with windows, and scans still held by windows
+	// that have since been removed
+	var scans []heldScan
+	err := s.db.SelectContext(ctx, &scans, `
+		SELECT id, organization_id, COALESCE(authorization_target_id::text, '') AS authorization_target_id,
+		       status, not_before, not_after, paused_by_window
+		FROM scan_jobs
+		WHERE (status IN ('pending_approval', 'pending', 'running') OR (status = 'paused' AND paused_by_window))
+		AND (organization_id::text = ANY($1) OR not_before IS NOT NULL OR not_after IS NOT NULL OR paused_by_window)
+	`, pq.StringArray(orgIDs))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, scan := range scans {
+		slot := Unrestricted
+		if set, ok := sets[scan.OrganizationID]; ok {
+			slot = SlotAt(set.forTarget(scan.AuthorizationTargetID), now)
+		}
+		if err := s.apply(ctx, scan, slot); err != nil {
+			s.logger.Error("Failed to apply execution window", zap.String("scan_job_id", scan.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// apply brings one scan in line with its slot
+func (s *Service) apply(ctx context.Context, scan heldScan, slot Slot) error {
+	switch {
+	case scan.Status == "running" && !slot.Open && slot.PauseAtEnd:
+		result, err := s.db.ExecContext(ctx, `
+			UPDATE scan_jobs
+			SET status = 'paused', paused_at = NOW(), paused_by_window = true,
+			    not_before = $2, not_after = NULL, updated_at = NOW()
+			WHERE id = $1 AND status = 'running'
+		`, scan.ID, slot.NotBefore)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			metrics.ScanWindowActions.WithLabelValues("paused").Inc()
+			s.logger.Info("Paused scan at execution window end", zap.String("scan_job_id", scan.ID))
+		}
+
+	case scan.Status == "paused" && slot.Open:
+		// As a user resume: back to its worker if still online, else re-queued
+		result, err := s.db.ExecContext(ctx, `
+			UPDATE scan_jobs sj
+			SET status = CASE WHEN w.id IS NULL THEN 'pending' ELSE 'running' END,
+			    worker_id = w.id,
+			    started_at = CASE WHEN w.id IS NULL THEN NULL ELSE sj.started_at END,
+			    paused_at = NULL, paused_by_window = false,
+			    not_before = NULL, not_after = $2,
+			    updated_at = NOW()
+			FROM (SELECT id, worker_id FROM scan_jobs WHERE id = $1 AND status = 'paused' AND paused_by_window FOR UPDATE) old
+			LEFT JOIN scan_workers w ON w.id = old.worker_id AND w.status <> 'offline'
+			WHERE sj.id = old.id
+		`, scan.ID, slot.NotAfter)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			metrics.ScanWindowActions.WithLabelValues("resumed").Inc()
+			s.logger.Info("Resumed scan as its execution window opened", zap.String("scan_job_id", scan.ID))
+		}
+
+	case scan.Status != "running":
+		// Queued scans, including those still awaiting approval
+		if sameTime(scan.NotBefore, slot.NotBefore) && sameTime(scan.NotAfter, slot.NotAfter) {
+			return nil
+		}
+		result, err := s.db.ExecContext(ctx, `
+			UPDATE scan_jobs SET not_before = $3, not_after = $4, updated_at = NOW()
+			WHERE id = $1 AND status = $2
+		`, scan.ID, scan.Status, slot.NotBefore, slot.NotAfter)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n > 0 && scan.Status == "pending" && !slot.Open && !scan.NotBefore.Valid {
+			metrics.ScanWindowActions.WithLabelValues("deferred").Inc()
+		}
+	}
+	return nil
+}
+
+func sameTime(stored sql.NullTime, t *time.Time) bool {
+	if !stored.Valid || t == nil {
+		return !stored.Valid && t == nil
+	}
+	return stored.Time.Equal(*t)
+}
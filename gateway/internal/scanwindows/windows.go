@@ -0,0 +1,143 @@
+// Package scanwindows restricts when scans may run to the maintenance windows
+// customers allow testing in. A window opens at each match of a cron spec in
+// its time zone and stays open for its duration. Windows belong to an
+// organization, optionally narrowed to one authorized target; a target's own
+// windows replace the organization-wide ones, and scans with no windows at
+// all run any time.
+//
+// Scans are held back through two columns of scan_jobs the dispatcher
+// honours: not_before (the next opening when created or queued outside a
+// window) and not_after (when the current window closes). The enforcer keeps
+// them current, pauses running scans at window end for windows configured so,
+// and resumes them when the next window opens.
+package scanwindows
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxDuration bounds how long a window stays open
+const MaxDuration = 7 * 24 * time.Hour
+
+var (
+	// ErrWindowNotFound is returned when deleting a window that does not exist
+	ErrWindowNotFound = errors.New("execution window not found")
+	// ErrTargetNotFound is returned when a window names an authorized target
+	// outside its organization
+	ErrTargetNotFound = errors.New("authorized target not found")
+)
+
+// Window is one recurring period in which scans may run
+type Window struct {
+	ID                    string    `json:"id" db:"id"`
+	OrganizationID        string    `json:"organization_id" db:"organization_id"`
+	AuthorizationTargetID *string   `json:"authorization_target_id,omitempty" db:"authorization_target_id"` // Organization-wide when unset
+	Name                  string    `json:"name" db:"name"`
+	CronSpec              string    `json:"cron_spec" db:"cron_spec"` // When the window opens
+	DurationMinutes       int       `json:"duration_minutes" db:"duration_minutes"`
+	Timezone              string    `json:"timezone" db:"timezone"` // IANA name, e.g. Europe/Berlin
+	PauseAtEnd            bool      `json:"pause_at_end" db:"pause_at_end"`
+	CreatedBy             *string   `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+
+	spec *Spec
+	loc  *time.Location
+}
+
+// WindowColumns selects every Window field
+const WindowColumns = `id, organization_id, authorization_target_id, name, cron_spec, duration_minutes,
+	timezone, pause_at_end, created_by, created_at`
+
+// Validate parses the window's spec and time zone
+func (w *Window) Validate() error {
+	spec, err := ParseSpec(w.CronSpec)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q", w.Timezone)
+	}
+	if w.DurationMinutes < 1 || w.duration() > MaxDuration {
+		return fmt.Errorf("duration must be between 1 minute and %s", MaxDuration)
+	}
+	if spec.Next(time.Now(), loc).IsZero() {
+		return errors.New("cron spec never matches")
+	}
+	w.spec, w.loc = spec, loc
+	return nil
+}
+
+func (w *Window) duration() time.Duration {
+	return time.Duration(w.DurationMinutes) * time.Minute
+}
+
+// openAt returns when the window's occurrence covering t closes, if one does
+func (w *Window) openAt(t time.Time) (time.Time, bool) {
+	// The earliest opening that could still cover t
+	start := w.spec.Next(t.Add(-w.duration()-time.Minute), w.loc)
+	for !start.IsZero() && !start.After(t) {
+		end := start.Add(w.duration())
+		if end.After(t) {
+			// Overlapping occurrences extend the window, up to MaxDuration
+			// past t for specs that never let it close
+			for next := w.spec.Next(start, w.loc); !next.IsZero() && !next.After(end) && end.Sub(t) < MaxDuration; next = w.spec.Next(next, w.loc) {
+				end = next.Add(w.duration())
+			}
+			return end, true
+		}
+		start = w.spec.Next(start, w.loc)
+	}
+	return time.Time{}, false
+}
+
+// Slot is when a scan may run, as of a moment
+type Slot struct {
+	Open       bool       `json:"open"`
+	NotBefore  *time.Time `json:"not_before,omitempty"` // Next opening, when closed
+	NotAfter   *time.Time `json:"not_after,omitempty"`  // When the open window closes
+	PauseAtEnd bool       `json:"pause_at_end"`
+}
+
+// Unrestricted is the slot of scans without windows
+var Unrestricted = Slot{Open: true}
+
+// SlotAt evaluates a set of windows at t. With none, scans run any time;
+// otherwise they run while any window is open.
+func SlotAt(windows []Window, t time.Time) Slot {
+	if len(windows) == 0 {
+		return Unrestricted
+	}
+	t = t.UTC()
+
+	var slot Slot
+	var closes, opens time.Time
+	for _, w := range windows {
+		if w.PauseAtEnd {
+			slot.PauseAtEnd = true
+		}
+		if end, ok := w.openAt(t); ok {
+			if end.After(closes) {
+				closes = end
+			}
+			continue
+		}
+		if next := w.spec.Next(t, w.loc); !next.IsZero() && (opens.IsZero() || next.Before(opens)) {
+			opens = next
+		}
+	}
+
+	if !closes.IsZero() {
+		closes = closes.UTC()
+		slot.Open = true
+		slot.NotAfter = &closes
+		return slot
+	}
+	if !opens.IsZero() {
+		opens = opens.UTC()
+		slot.NotBefore = &opens
+	}
+	return slot
+}
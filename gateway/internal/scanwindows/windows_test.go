@@ -0,0 +1,151 @@
+package scanwindows
+
+import (
+	"testing"
+	"time"
+)
+
+func mustWindow(t *testing.T, expr string, minutes int, timezone string, pauseAtEnd bool) Window {
+	t.Helper()
+	w := Window{CronSpec: expr, DurationMinutes: minutes, Timezone: timezone, PauseAtEnd: pauseAtEnd}
+	if err := w.Validate(); err != nil {
+		t.Fatalf("Validate(%q, %d, %q): %v", expr, minutes, timezone, err)
+	}
+	return w
+}
+
+func utcAt(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func checkSlot(t *testing.T, got Slot, open bool, notBefore, notAfter time.Time) {
+	t.Helper()
+	if got.Open != open {
+		t.Errorf("Open = %v, want %v", got.Open, open)
+	}
+	checkTime(t, "NotBefore", got.NotBefore, notBefore)
+	checkTime(t, "NotAfter", got.NotAfter, notAfter)
+}
+
+func checkTime(t *testing.T, name string, got *time.Time, want time.Time) {
+	t.Helper()
+	switch {
+	case want.IsZero() && got != nil:
+		t.Errorf("%s = %s, want none", name, got)
+	case !want.IsZero() && got == nil:
+		t.Errorf("%s = none, want %s", name, want)
+	case got != nil && !got.Equal(want):
+		t.Errorf("%s = %s, want %s", name, got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		w    Window
+	}{
+		{"bad spec", Window{CronSpec: "* * *", DurationMinutes: 60, Timezone: "UTC"}},
+		{"unknown time zone", Window{CronSpec: "0 22 * * *", DurationMinutes: 60, Timezone: "Mars/Olympus"}},
+		{"no duration", Window{CronSpec: "0 22 * * *", DurationMinutes: 0, Timezone: "UTC"}},
+		{"too long", Window{CronSpec: "0 22 * * *", DurationMinutes: int(MaxDuration/time.Minute) + 1, Timezone: "UTC"}},
+		{"never matches", Window{CronSpec: "0 0 31 apr *", DurationMinutes: 60, Timezone: "UTC"}},
+	}
+	for _, tt := range tests {
+		if err := tt.w.Validate(); err == nil {
+			t.Errorf("%s: Validate succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestSlotAtWithoutWindows(t *testing.T) {
+	if got := SlotAt(nil, time.Now()); got != Unrestricted {
+		t.Errorf("SlotAt(nil) = %+v, want Unrestricted", got)
+	}
+}
+
+func TestSlotAtAcrossMidnight(t *testing.T) {
+	// 22:00 to 02:00 UTC
+	windows := []Window{mustWindow(t, "0 22 * * *", 240, "UTC", false)}
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		open      bool
+		notBefore time.Time
+		notAfter  time.Time
+	}{
+		{"before opening", utcAt(5, 4, 21, 59), false, utcAt(5, 4, 22, 0), time.Time{}},
+		{"at opening", utcAt(5, 4, 22, 0), true, time.Time{}, utcAt(5, 5, 2, 0)},
+		{"before midnight", utcAt(5, 4, 23, 30), true, time.Time{}, utcAt(5, 5, 2, 0)},
+		{"after midnight", utcAt(5, 5, 1, 59), true, time.Time{}, utcAt(5, 5, 2, 0)},
+		{"at closing", utcAt(5, 5, 2, 0), false, utcAt(5, 5, 22, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSlot(t, SlotAt(windows, tt.at), tt.open, tt.notBefore, tt.notAfter)
+		})
+	}
+}
+
+func TestSlotAtInTimeZone(t *testing.T) {
+	// 22:00 to 02:00 in Berlin, 20:00 to 00:00 UTC in summer
+	windows := []Window{mustWindow(t, "0 22 * * *", 240, "Europe/Berlin", false)}
+
+	checkSlot(t, SlotAt(windows, utcAt(5, 4, 21, 30)), true, time.Time{}, utcAt(5, 5, 0, 0))
+	checkSlot(t, SlotAt(windows, utcAt(5, 5, 0, 0)), false, utcAt(5, 5, 20, 0), time.Time{})
+}
+
+func TestSlotAtOverlappingOccurrences(t *testing.T) {
+	// Opens at 09:00 and 10:00 for 90 minutes each: one window until 11:30
+	windows := []Window{mustWindow(t, "0 9,10 * * *", 90, "UTC", false)}
+
+	checkSlot(t, SlotAt(windows, utcAt(5, 4, 9, 30)), true, time.Time{}, utcAt(5, 4, 11, 30))
+	checkSlot(t, SlotAt(windows, utcAt(5, 4, 10, 45)), true, time.Time{}, utcAt(5, 4, 11, 30))
+	checkSlot(t, SlotAt(windows, utcAt(5, 4, 11, 30)), false, utcAt(5, 5, 9, 0), time.Time{})
+}
+
+func TestSlotAtWindowThatNeverCloses(t *testing.T) {
+	// Every two hours for three: the window is always open
+	windows := []Window{mustWindow(t, "0 */2 * * *", 180, "UTC", false)}
+	at := utcAt(5, 4, 9, 30)
+
+	got := SlotAt(windows, at)
+	if !got.Open || got.NotAfter == nil {
+		t.Fatalf("SlotAt = %+v, want open", got)
+	}
+	// Reported closing about MaxDuration out rather than searched for forever
+	if d := got.NotAfter.Sub(at); d < MaxDuration || d > MaxDuration+3*time.Hour {
+		t.Errorf("NotAfter is %s away, want about %s", d, MaxDuration)
+	}
+}
+
+func TestSlotAtSeveralWindows(t *testing.T) {
+	windows := []Window{
+		mustWindow(t, "0 9 * * *", 60, "UTC", false),
+		mustWindow(t, "30 9 * * *", 120, "UTC", true),
+		mustWindow(t, "0 20 * * *", 60, "UTC", false),
+	}
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		open      bool
+		notBefore time.Time
+		notAfter  time.Time
+	}{
+		{"one open", utcAt(5, 4, 9, 15), true, time.Time{}, utcAt(5, 4, 10, 0)},
+		{"the later closing wins", utcAt(5, 4, 9, 45), true, time.Time{}, utcAt(5, 4, 11, 30)},
+		{"the earliest opening wins", utcAt(5, 4, 12, 0), false, utcAt(5, 4, 20, 0), time.Time{}},
+		{"next day", utcAt(5, 4, 21, 0), false, utcAt(5, 5, 9, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SlotAt(windows, tt.at)
+			checkSlot(t, got, tt.open, tt.notBefore, tt.notAfter)
+			// Any window pausing at its end makes the slot pause
+			if !got.PauseAtEnd {
+				t.Error("PauseAtEnd = false, want true")
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/awssig"
+)
+
+// AWSConfig points at an AWS Secrets Manager secret whose SecretString is a
+// JSON object keyed by secret name. Credentials are static keys, as exported
+// into the environment by the task role or a sidecar.
+type AWSConfig struct {
+	Region          string
+	SecretID        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Endpoint        string            // Overrides https://secretsmanager.<region>.amazonaws.com
+	Transport       http.RoundTripper // Optional, e.g. under an outbound policy
+}
+
+// AWSProvider reads secrets with the Secrets Manager GetSecretValue API
+type AWSProvider struct {
+	config     AWSConfig
+	signer     awssig.Signer
+	httpClient *http.Client
+}
+
+func NewAWSProvider(config AWSConfig) *AWSProvider {
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", config.Region)
+	}
+	return &AWSProvider{
+		config: config,
+		signer: awssig.Signer{
+			Dialect:         awssig.AWS,
+			Region:          config.Region,
+			Service:         "secretsmanager",
+			AccessKeyID:     config.AccessKeyID,
+			SecretAccessKey: config.SecretAccessKey,
+			SessionToken:    config.SessionToken,
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: config.Transport},
+	}
+}
+
+func (p *AWSProvider) Get(ctx context.Context, name string) (Secret, error) {
+	payload, err := json.Marshal(map[string]string{"SecretId": p.config.SecretID})
+	if err != nil {
+		return Secret{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.Endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	p.signer.Sign(req, payload, time.Now().UTC())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to reach secrets manager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to read secrets manager response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		if strings.HasSuffix(apiErr.Type, "ResourceNotFoundException") {
+			return Secret{}, ErrSecretNotFound
+		}
+		return Secret{}, fmt.Errorf("secrets manager returned status %d: %s %s", resp.StatusCode, apiErr.Type, apiErr.Message)
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+		VersionID    string `json:"VersionId"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return Secret{}, fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+
+	fields := map[string]string{}
+	if err := json.Unmarshal([]byte(out.SecretString), &fields); err != nil {
+		return Secret{}, fmt.Errorf("secret %s is not a JSON object of strings: %w", p.config.SecretID, err)
+	}
+	value, ok := fields[name]
+	if !ok || value == "" {
+		return Secret{}, ErrSecretNotFound
+	}
+	return Secret{Value: value, Version: out.VersionID}, nil
+}
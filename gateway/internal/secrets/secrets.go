@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Secret is a single named value and the backend version it was read at
+type Secret struct {
+	Value   string
+	Version string
+}
+
+// Provider reads secrets by name (JWT_SECRET, DB_PASSWORD, ...) from a backend
+type Provider interface {
+	Get(ctx context.Context, name string) (Secret, error)
+}
+
+// Renewer is implemented by providers whose own credentials expire, e.g. a
+// renewable Vault token
+type Renewer interface {
+	Renew(ctx context.Context) error
+}
+
+// EnvProvider reads secrets from environment variables, the behaviour before
+// an external backend is configured
+type EnvProvider struct{}
+
+func (EnvProvider) Get(_ context.Context, name string) (Secret, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return Secret{}, ErrSecretNotFound
+	}
+	return Secret{Value: value}, nil
+}
+
+type cacheEntry struct {
+	secret    Secret
+	fetchedAt time.Time
+}
+
+// Cache fetches secrets lazily on first use and keeps them for ttl. When a
+// refresh fails the last known value is served, so a backend outage does not
+// take the gateway down with it.
+type Cache struct {
+	provider Provider
+	ttl      time.Duration
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	watchers map[string][]func(string)
+}
+
+func NewCache(provider Provider, ttl time.Duration, logger *zap.Logger) *Cache {
+	return &Cache{
+		provider: provider,
+		ttl:      ttl,
+		logger:   logger,
+		entries:  make(map[string]cacheEntry),
+		watchers: make(map[string][]func(string)),
+	}
+}
+
+// Get returns the named secret, fetching it if it is not cached or has expired
+func (c *Cache) Get(ctx context.Context, name string) (string, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return entry.secret.Value, nil
+	}
+
+	secret, err := c.fetch(ctx, name)
+	if err != nil {
+		if ok && err != ErrSecretNotFound {
+			c.logger.Warn("Secret refresh failed, serving cached value", zap.String("name", name), zap.Error(err))
+			return entry.secret.Value, nil
+		}
+		return "", err
+	}
+	return secret.Value, nil
+}
+
+// OnChange calls fn with the new value whenever a refresh finds that the
+// named secret has changed
+func (c *Cache) OnChange(name string, fn func(value string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watchers[name] = append(c.watchers[name], fn)
+}
+
+// fetch reads name from the provider, stores it and notifies watchers if the
+// value differs from the cached one
+func (c *Cache) fetch(ctx context.Context, name string) (Secret, error) {
+	secret, err := c.provider.Get(ctx, name)
+	if err != nil {
+		return Secret{}, err
+	}
+
+	c.mu.Lock()
+	previous, existed := c.entries[name]
+	c.entries[name] = cacheEntry{secret: secret, fetchedAt: time.Now()}
+	watchers := c.watchers[name]
+	c.mu.Unlock()
+
+	if existed && previous.secret.Value != secret.Value {
+		c.logger.Info("Secret changed", zap.String("name", name), zap.String("version", secret.Version))
+		for _, fn := range watchers {
+			fn(secret.Value)
+		}
+	}
+	return secret, nil
+}
+
+// StartRenewal renews the provider's credentials and re-fetches every cached
+// secret on each tick, so rotations in the backend reach OnChange watchers
+func (c *Cache) StartRenewal(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.renew(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cache) renew(ctx context.Context) {
+	if renewer, ok := c.provider.(Renewer); ok {
+		if err := renewer.Renew(ctx); err != nil {
+			c.logger.Error("Failed to renew secrets backend credentials", zap.Error(err))
+		}
+	}
+
+	c.mu.Lock()
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	c.mu.Unlock()
+
+	for _, name := range names {
+		if _, err := c.fetch(ctx, name); err != nil {
+			c.logger.Error("Failed to refresh secret", zap.String("name", name), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VaultConfig points at a HashiCorp Vault KV v2 secret whose fields are the
+// secret names, e.g. path "secret/data/cyper/gateway" holding JWT_SECRET and
+// DB_PASSWORD
+type VaultConfig struct {
+	Address   string // e.g. https://vault:8200
+	Token     string
+	Namespace string // Vault Enterprise namespace, optional
+	Path      string
+	Transport http.RoundTripper // Optional, e.g. under an outbound policy
+}
+
+// VaultProvider reads secrets over Vault's HTTP API
+type VaultProvider struct {
+	config     VaultConfig
+	httpClient *http.Client
+}
+
+func NewVaultProvider(config VaultConfig) *VaultProvider {
+	config.Address = strings.TrimRight(config.Address, "/")
+	config.Path = strings.Trim(config.Path, "/")
+	return &VaultProvider{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: config.Transport},
+	}
+}
+
+func (p *VaultProvider) Get(ctx context.Context, name string) (Secret, error) {
+	var body struct {
+		Data struct {
+			Data     map[string]interface{} `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodGet, p.config.Path, &body); err != nil {
+		return Secret{}, err
+	}
+
+	value, ok := body.Data.Data[name].(string)
+	if !ok || value == "" {
+		return Secret{}, ErrSecretNotFound
+	}
+	return Secret{Value: value, Version: strconv.Itoa(body.Data.Metadata.Version)}, nil
+}
+
+// Renew extends the lease of the Vault token
+func (p *VaultProvider) Renew(ctx context.Context) error {
+	return p.do(ctx, http.MethodPost, "auth/token/renew-self", nil)
+}
+
+func (p *VaultProvider) do(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, p.config.Address+"/v1/"+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.config.Token)
+	if p.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.config.Namespace)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrSecretNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return nil
+}
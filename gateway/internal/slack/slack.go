@@ -0,0 +1,245 @@
+// Package slack verifies requests from the Slack app (slash commands and
+// interactive messages) and posts messages through the Slack Web API
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid Slack request signature")
+	ErrStaleRequest     = errors.New("Slack request timestamp outside the allowed window")
+)
+
+// maxBodyBytes bounds command and interaction payloads
+const maxBodyBytes = 1 << 20
+
+// maxSkew rejects replayed requests, as Slack recommends
+const maxSkew = 5 * time.Minute
+
+// Verify checks a request's X-Slack-Signature against the app's signing secret
+func Verify(signingSecret []byte, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > maxSkew || d < -maxSkew {
+		return ErrStaleRequest
+	}
+
+	mac := hmac.New(sha256.New, signingSecret)
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyRequests is middleware that rejects requests not signed by Slack.
+// The body is restored so handlers can parse the form as usual.
+func VerifyRequests(signingSecret []byte, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodyBytes))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request"})
+			return
+		}
+
+		err = Verify(signingSecret, c.GetHeader("X-Slack-Request-Timestamp"), c.GetHeader("X-Slack-Signature"), body, time.Now())
+		if err != nil {
+			logging.FromContext(c.Request.Context(), logger).Warn("Rejected Slack request", zap.String("ip_address", c.ClientIP()), zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// Message is a Slack message with optional Block Kit blocks
+type Message struct {
+	Channel         string        `json:"channel,omitempty"`
+	Text            string        `json:"text"`
+	Blocks          []interface{} `json:"blocks,omitempty"`
+	ResponseType    string        `json:"response_type,omitempty"` // ephemeral or in_channel (slash command replies)
+	ReplaceOriginal bool          `json:"replace_original,omitempty"`
+}
+
+// Ephemeral is a slash command reply only the caller sees
+func Ephemeral(text string) Message {
+	return Message{Text: text, ResponseType: "ephemeral"}
+}
+
+// Client posts messages with a bot token
+type Client struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(botToken string) *Client {
+	return &Client{
+		token:      botToken,
+		baseURL:    "https://slack.com/api",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetTransport sends requests through transport, e.g. under an outbound
+// policy
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.httpClient.Transport = transport
+}
+
+// PostMessage sends msg to a channel, or to a user's DM when Channel is a
+// user ID
+func (c *Client) PostMessage(ctx context.Context, msg Message) error {
+	_, err := c.Send(ctx, msg)
+	return err
+}
+
+// Response is Slack's answer to a message, as received
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Send is PostMessage, also returning Slack's answer for delivery logs. The
+// response is nil only when Slack could not be reached.
+func (c *Client) Send(ctx context.Context, msg Message) (*Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	response := &Response{StatusCode: resp.StatusCode, Body: raw}
+	if resp.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("Slack returned %d", resp.StatusCode)
+	}
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return response, err
+	}
+	if !result.OK {
+		return response, fmt.Errorf("chat.postMessage failed: %s", result.Error)
+	}
+	return response, nil
+}
+
+// Respond answers an interaction through its response_url
+func (c *Client) Respond(ctx context.Context, responseURL string, msg Message) error {
+	return c.post(ctx, responseURL, "", msg, nil)
+}
+
+func (c *Client) post(ctx context.Context, url, token string, payload, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack returned %d", resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Section is a Block Kit section with mrkdwn text
+func Section(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "section",
+		"text": map[string]interface{}{"type": "mrkdwn", "text": text},
+	}
+}
+
+// Actions is a Block Kit row of interactive elements
+func Actions(elements ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "actions",
+		"elements": elements,
+	}
+}
+
+// Button is a Block Kit button; clicks arrive as block_actions interactions
+// carrying actionID and value
+func Button(actionID, text, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":      "button",
+		"action_id": actionID,
+		"text":      map[string]interface{}{"type": "plain_text", "text": text},
+		"value":     value,
+	}
+}
+
+// Interaction is the subset of an interactive message payload the gateway
+// handles
+type Interaction struct {
+	Type string `json:"type"`
+	Team struct {
+		ID string `json:"id"`
+	} `json:"team"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+}
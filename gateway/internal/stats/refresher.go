@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"go.uber.org/zap"
+)
+
+// views are the materialized aggregates behind dashboard statistics
+var views = []string{
+	"org_scan_daily_stats",
+	"org_finding_stats",
+	"org_asset_stats",
+	"org_auth_daily_stats",
+}
+
+// StartRefresher periodically refreshes the dashboard aggregates
+func StartRefresher(ctx context.Context, db *database.DB, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info("Starting dashboard stats refresher", zap.Duration("interval", interval))
+
+	refresh(ctx, db, logger)
+	for {
+		select {
+		case <-ticker.C:
+			refresh(ctx, db, logger)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func refresh(ctx context.Context, db *database.DB, logger *zap.Logger) {
+	for _, view := range views {
+		start := time.Now()
+
+		// CONCURRENTLY keeps the view readable during refresh (needs a unique index)
+		if _, err := db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY "+view); err != nil {
+			logger.Error("Failed to refresh stats view", zap.String("view", view), zap.Error(err))
+			continue
+		}
+
+		logger.Debug("Refreshed stats view", zap.String("view", view), zap.Duration("duration", time.Since(start)))
+	}
+}
@@ -0,0 +1,131 @@
+// Package stats serves dashboard statistics from materialized aggregates.
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+)
+
+// DailyScans counts scans created on a day
+type DailyScans struct {
+	Day       time.Time `json:"day" db:"day"`
+	Total     int       `json:"total" db:"total"`
+	Completed int       `json:"completed" db:"completed"`
+	Failed    int       `json:"failed" db:"failed"`
+}
+
+// SeverityCount counts findings of one severity
+type SeverityCount struct {
+	Severity string `json:"severity" db:"severity"`
+	Total    int    `json:"total" db:"total"`
+	Open     int    `json:"open" db:"open"`
+	Fixed    int    `json:"fixed" db:"fixed"`
+}
+
+// Asset is a target ranked by open findings
+type Asset struct {
+	TargetType   string    `json:"target_type" db:"target_type"`
+	TargetValue  string    `json:"target_value" db:"target_value"`
+	OpenFindings int       `json:"open_findings" db:"open_findings"`
+	Critical     int       `json:"critical" db:"critical"`
+	High         int       `json:"high" db:"high"`
+	LastSeenAt   time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// DailyAuth summarizes authentication activity on a day
+type DailyAuth struct {
+	Day          time.Time `json:"day" db:"day"`
+	Logins       int       `json:"logins" db:"logins"`
+	FailedLogins int       `json:"failed_logins" db:"failed_logins"`
+	ActiveUsers  int       `json:"active_users" db:"active_users"`
+}
+
+// OrgStats is the dashboard payload for one organization
+type OrgStats struct {
+	OrganizationID           string          `json:"organization_id"`
+	Days                     int             `json:"days"`
+	ScansOverTime            []DailyScans    `json:"scans_over_time"`
+	FindingsBySeverity       []SeverityCount `json:"findings_by_severity"`
+	MeanTimeToRemediateHours *float64        `json:"mean_time_to_remediate_hours"`
+	TopVulnerableAssets      []Asset         `json:"top_vulnerable_assets"`
+	AuthActivity             []DailyAuth     `json:"auth_activity"`
+}
+
+// Service reads dashboard statistics
+type Service struct {
+	db *database.DB
+}
+
+func NewService(db *database.DB) *Service {
+	return &Service{db: db}
+}
+
+// OrgStats returns statistics for the last days days; asset rankings cover all open findings
+func (s *Service) OrgStats(ctx context.Context, orgID string, days, topAssets int) (*OrgStats, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+
+	stats := &OrgStats{
+		OrganizationID:      orgID,
+		Days:                days,
+		ScansOverTime:       []DailyScans{},
+		FindingsBySeverity:  []SeverityCount{},
+		TopVulnerableAssets: []Asset{},
+		AuthActivity:        []DailyAuth{},
+	}
+
+	err := s.db.Reader().SelectContext(ctx, &stats.ScansOverTime, `
+		SELECT day, total, completed, failed
+		FROM org_scan_daily_stats
+		WHERE organization_id = $1 AND day >= $2
+		ORDER BY day
+	`, orgID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scan stats: %w", err)
+	}
+
+	err = s.db.Reader().SelectContext(ctx, &stats.FindingsBySeverity, `
+		SELECT severity, total, open, fixed
+		FROM org_finding_stats
+		WHERE organization_id = $1
+		ORDER BY array_position(ARRAY['critical', 'high', 'medium', 'low', 'info'], severity::text)
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load finding stats: %w", err)
+	}
+
+	// Weighted by fixed count so severities with more fixes dominate the mean
+	err = s.db.Reader().GetContext(ctx, &stats.MeanTimeToRemediateHours, `
+		SELECT SUM(mean_hours_to_remediate * fixed) / NULLIF(SUM(fixed), 0)
+		FROM org_finding_stats
+		WHERE organization_id = $1 AND fixed > 0
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load remediation stats: %w", err)
+	}
+
+	err = s.db.Reader().SelectContext(ctx, &stats.TopVulnerableAssets, `
+		SELECT target_type, target_value, open_findings, critical, high, last_seen_at
+		FROM org_asset_stats
+		WHERE organization_id = $1
+		ORDER BY critical DESC, high DESC, open_findings DESC
+		LIMIT $2
+	`, orgID, topAssets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load asset stats: %w", err)
+	}
+
+	err = s.db.Reader().SelectContext(ctx, &stats.AuthActivity, `
+		SELECT day, logins, failed_logins, active_users
+		FROM org_auth_daily_stats
+		WHERE organization_id = $1 AND day >= $2
+		ORDER BY day
+	`, orgID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load auth stats: %w", err)
+	}
+
+	return stats, nil
+}
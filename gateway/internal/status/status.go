@@ -0,0 +1,412 @@
+// Package status backs the public status page: the current system status,
+// component health from the readiness checks, and uptime history.
+//
+// Every gateway instance samples the checks each interval, but a Redis claim
+// lets only one record a given interval. Samples are counted per component
+// and day, so history costs one row per component per day. Responses are
+// cached in Redis (and in memory when Redis is unreachable) so polling the
+// page never reaches the database.
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/health"
+	"github.com/cyper-security/gateway/internal/maintenance"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Overall statuses. Components report the health statuses, plus maintenance
+// in uptime history.
+const (
+	StatusOperational = health.StatusOperational
+	StatusDegraded    = health.StatusDegraded
+	StatusMaintenance = "maintenance"
+)
+
+// SystemComponent is the uptime history of the overall status
+const SystemComponent = "system"
+
+// UptimeWindows are the periods uptime is reported over, in days
+var UptimeWindows = []int{1, 7, 30, 90}
+
+const (
+	summaryCacheKey = "status:cache:summary"
+	uptimeCacheKey  = "status:cache:uptime"
+	sampleClaimKey  = "status:sample:"
+)
+
+// Config controls sampling and caching
+type Config struct {
+	// SampleInterval is how often the checks are recorded
+	SampleInterval time.Duration
+	// CacheTTL is how long the current status is served from cache
+	CacheTTL time.Duration
+	// UptimeCacheTTL is how long uptime history is served from cache
+	UptimeCacheTTL time.Duration
+	// RetentionDays is how much uptime history is kept
+	RetentionDays int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SampleInterval: time.Minute,
+		CacheTTL:       15 * time.Second,
+		UptimeCacheTTL: 5 * time.Minute,
+		RetentionDays:  90,
+	}
+}
+
+// Component is the public health of one component. Errors and latencies stay
+// on the internal readiness probe.
+type Component struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // operational, degraded or down
+}
+
+// Summary is the current system status
+type Summary struct {
+	Status      string             `json:"status"` // operational, degraded or maintenance
+	Maintenance *maintenance.State `json:"maintenance,omitempty"`
+	Components  []Component        `json:"components"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// UptimeWindow is a component's uptime over the last Days days
+type UptimeWindow struct {
+	Days int `json:"days"`
+	// Uptime is the share of samples the component was up (operational or
+	// degraded), 0-100; maintenance is left out. Null without samples.
+	Uptime *float64 `json:"uptime"`
+}
+
+// DailyUptime is a component's uptime on one day (UTC)
+type DailyUptime struct {
+	Day         string   `json:"day"`
+	Uptime      *float64 `json:"uptime"`
+	Operational int      `json:"operational_samples"`
+	Degraded    int      `json:"degraded_samples"`
+	Down        int      `json:"down_samples"`
+	Maintenance int      `json:"maintenance_samples"`
+}
+
+// ComponentUptime is a component's uptime history
+type ComponentUptime struct {
+	Name    string         `json:"name"`
+	Windows []UptimeWindow `json:"windows"`
+	Days    []DailyUptime  `json:"days"` // Oldest first; days without samples are left out
+}
+
+// Uptime is the uptime history of the system and every component
+type Uptime struct {
+	SampleInterval int               `json:"sample_interval_seconds"`
+	Components     []ComponentUptime `json:"components"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// Service samples component health and serves the status page
+type Service struct {
+	db          *database.DB
+	redis       *redis.Client
+	checker     *health.Checker
+	maintenance *maintenance.Service
+	config      Config
+	logger      *zap.Logger
+
+	// loadMu lets one request at a time refill an expired cache entry
+	loadMu    sync.Mutex
+	mu        sync.Mutex
+	local     map[string]cacheEntry
+	lastPrune time.Time
+}
+
+type cacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+func NewService(db *database.DB, redisClient *redis.Client, checker *health.Checker, maintenanceService *maintenance.Service, config Config, logger *zap.Logger) *Service {
+	return &Service{
+		db:          db,
+		redis:       redisClient,
+		checker:     checker,
+		maintenance: maintenanceService,
+		config:      config,
+		logger:      logger,
+		local:       make(map[string]cacheEntry),
+	}
+}
+
+// Summary returns the current system status
+func (s *Service) Summary(ctx context.Context) (*Summary, error) {
+	var summary Summary
+	err := s.cached(ctx, summaryCacheKey, s.config.CacheTTL, &summary, func() (interface{}, error) {
+		return s.summarize(s.checker.Check(ctx)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// Uptime returns the uptime history
+func (s *Service) Uptime(ctx context.Context) (*Uptime, error) {
+	var uptime Uptime
+	err := s.cached(ctx, uptimeCacheKey, s.config.UptimeCacheTTL, &uptime, func() (interface{}, error) {
+		return s.loadUptime(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &uptime, nil
+}
+
+// summarize derives the overall status from a health report: maintenance
+// while it is on, degraded while any component is not operational
+func (s *Service) summarize(report health.Report) *Summary {
+	summary := &Summary{
+		Status:     StatusOperational,
+		Components: make([]Component, 0, len(report.Checks)),
+		UpdatedAt:  report.CheckedAt,
+	}
+	for _, result := range report.Checks {
+		summary.Components = append(summary.Components, Component{Name: result.Name, Status: result.Status})
+		if result.Status != health.StatusOperational {
+			summary.Status = StatusDegraded
+		}
+	}
+	if state := s.maintenance.Current(); state.Active {
+		summary.Status = StatusMaintenance
+		summary.Maintenance = &state
+	}
+	return summary
+}
+
+// Start samples component health every interval until ctx is cancelled
+func (s *Service) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.SampleInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Status sampling started", zap.Duration("interval", s.config.SampleInterval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.sample(ctx, now.UTC())
+		}
+	}
+}
+
+// sample records one round of checks, unless another instance already
+// recorded this interval
+func (s *Service) sample(ctx context.Context, now time.Time) {
+	slot := now.Truncate(s.config.SampleInterval)
+	claimed, err := s.redis.SetNX(ctx, sampleClaimKey+strconv.FormatInt(slot.Unix(), 10), 1, 2*s.config.SampleInterval).Result()
+	if err != nil {
+		// Better an interval counted twice than an outage left unrecorded
+		s.logger.Warn("Failed to claim status sample, recording anyway", zap.Error(err))
+		claimed = true
+	}
+	if !claimed {
+		metrics.StatusSamples.WithLabelValues("skipped").Inc()
+		return
+	}
+
+	summary := s.summarize(s.checker.Check(ctx))
+	components := []string{SystemComponent}
+	statuses := []string{summary.Status}
+	for _, component := range summary.Components {
+		components = append(components, component.Name)
+		if summary.Status == StatusMaintenance {
+			statuses = append(statuses, StatusMaintenance)
+		} else {
+			statuses = append(statuses, component.Status)
+		}
+	}
+
+	if err := s.record(ctx, slot, components, statuses); err != nil {
+		metrics.StatusSamples.WithLabelValues("failed").Inc()
+		s.logger.Error("Failed to record status sample", zap.Error(err))
+		return
+	}
+	metrics.StatusSamples.WithLabelValues("recorded").Inc()
+
+	if now.Sub(s.lastPrune) >= 24*time.Hour {
+		if err := s.prune(ctx, now); err != nil {
+			s.logger.Error("Failed to prune uptime history", zap.Error(err))
+		} else {
+			s.lastPrune = now
+		}
+	}
+}
+
+// record adds one sample per component to the day's counts
+func (s *Service) record(ctx context.Context, at time.Time, components, statuses []string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO status_uptime_daily
+			(component, day, operational_samples, degraded_samples, down_samples, maintenance_samples)
+		SELECT t.component, $3::date,
+			(t.status = 'operational')::int, (t.status = 'degraded')::int,
+			(t.status = 'down')::int, (t.status = 'maintenance')::int
+		FROM unnest($1::text[], $2::text[]) AS t(component, status)
+		ON CONFLICT (component, day) DO UPDATE SET
+			operational_samples = status_uptime_daily.operational_samples + EXCLUDED.operational_samples,
+			degraded_samples = status_uptime_daily.degraded_samples + EXCLUDED.degraded_samples,
+			down_samples = status_uptime_daily.down_samples + EXCLUDED.down_samples,
+			maintenance_samples = status_uptime_daily.maintenance_samples + EXCLUDED.maintenance_samples
+	`, pq.StringArray(components), pq.StringArray(statuses), at.Format("2006-01-02"))
+	return err
+}
+
+// prune deletes history older than the retention period
+func (s *Service) prune(ctx context.Context, now time.Time) error {
+	cutoff := now.AddDate(0, 0, -s.config.RetentionDays).Format("2006-01-02")
+	_, err := s.db.ExecContext(ctx, `DELETE FROM status_uptime_daily WHERE day < $1::date`, cutoff)
+	return err
+}
+
+type dailyRow struct {
+	Component   string    `db:"component"`
+	Day         time.Time `db:"day"`
+	Operational int       `db:"operational_samples"`
+	Degraded    int       `db:"degraded_samples"`
+	Down        int       `db:"down_samples"`
+	Maintenance int       `db:"maintenance_samples"`
+}
+
+// loadUptime computes the uptime windows from the daily counts
+func (s *Service) loadUptime(ctx context.Context) (*Uptime, error) {
+	longest := UptimeWindows[len(UptimeWindows)-1]
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, 1-longest)
+
+	rows := []dailyRow{}
+	err := s.db.Reader().SelectContext(ctx, &rows, `
+		SELECT component, day, operational_samples, degraded_samples, down_samples, maintenance_samples
+		FROM status_uptime_daily
+		WHERE day >= $1::date
+		ORDER BY component, day
+	`, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load uptime history: %w", err)
+	}
+
+	byComponent := make(map[string][]dailyRow)
+	for _, row := range rows {
+		byComponent[row.Component] = append(byComponent[row.Component], row)
+	}
+
+	uptime := &Uptime{
+		SampleInterval: int(s.config.SampleInterval.Seconds()),
+		Components:     make([]ComponentUptime, 0, len(byComponent)),
+		UpdatedAt:      time.Now().UTC(),
+	}
+	for name, days := range byComponent {
+		component := ComponentUptime{Name: name, Days: make([]DailyUptime, 0, len(days))}
+		for _, window := range UptimeWindows {
+			start := today.AddDate(0, 0, 1-window)
+			var up, total int
+			for _, day := range days {
+				if !day.Day.Before(start) {
+					up += day.Operational + day.Degraded
+					total += day.Operational + day.Degraded + day.Down
+				}
+			}
+			component.Windows = append(component.Windows, UptimeWindow{Days: window, Uptime: percent(up, total)})
+		}
+		for _, day := range days {
+			component.Days = append(component.Days, DailyUptime{
+				Day:         day.Day.Format("2006-01-02"),
+				Uptime:      percent(day.Operational+day.Degraded, day.Operational+day.Degraded+day.Down),
+				Operational: day.Operational,
+				Degraded:    day.Degraded,
+				Down:        day.Down,
+				Maintenance: day.Maintenance,
+			})
+		}
+		uptime.Components = append(uptime.Components, component)
+	}
+
+	// The overall status first, then components by name
+	sort.Slice(uptime.Components, func(i, j int) bool {
+		a, b := uptime.Components[i].Name, uptime.Components[j].Name
+		if (a == SystemComponent) != (b == SystemComponent) {
+			return a == SystemComponent
+		}
+		return a < b
+	})
+	return uptime, nil
+}
+
+func percent(part, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	p := float64(part) * 100 / float64(total)
+	return &p
+}
+
+// cached decodes key into dest from the cache, or stores what load returns.
+// Redis is shared by every instance; the in-memory copy covers Redis outages.
+func (s *Service) cached(ctx context.Context, key string, ttl time.Duration, dest interface{}, load func() (interface{}, error)) error {
+	if data, ok := s.lookup(ctx, key); ok {
+		return json.Unmarshal(data, dest)
+	}
+
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	// Another request may have refilled it while this one waited
+	if data, ok := s.lookup(ctx, key); ok {
+		return json.Unmarshal(data, dest)
+	}
+
+	value, err := load()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.local[key] = cacheEntry{data: data, expires: time.Now().Add(ttl)}
+	s.mu.Unlock()
+	if err := s.redis.Set(ctx, key, data, ttl).Err(); err != nil {
+		s.logger.Warn("Failed to cache status response", zap.String("key", key), zap.Error(err))
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+func (s *Service) lookup(ctx context.Context, key string) ([]byte, bool) {
+	s.mu.Lock()
+	entry, ok := s.local[key]
+	s.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.data, true
+	}
+
+	data, err := s.redis.Get(ctx, key).Bytes()
+	if err == nil {
+		return data, true
+	}
+	if !errors.Is(err, redis.Nil) {
+		s.logger.Debug("Status cache unavailable", zap.String("key", key), zap.Error(err))
+	}
+	return nil, false
+}
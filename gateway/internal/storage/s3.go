@@ -0,0 +1,357 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/awssig"
+)
+
+// S3Config points at an S3 bucket, or a bucket on an S3-compatible store
+// such as MinIO. Credentials are static keys, as exported into the
+// environment by the task role or a sidecar.
+type S3Config struct {
+	Region          string
+	Bucket          string
+	Prefix          string // Prepended to every key, e.g. "cyper/"
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Endpoint overrides https://<bucket>.s3.<region>.amazonaws.com; buckets
+	// on a custom endpoint are addressed path-style (<endpoint>/<bucket>)
+	Endpoint string
+	// ServerSideEncryption is AES256 or aws:kms, or empty for the bucket
+	// default. KMSKeyID selects the key for aws:kms.
+	ServerSideEncryption string
+	KMSKeyID             string
+	PartSize             int64 // Multipart upload part size, default 8 MiB
+	// Transport sends requests, e.g. under an outbound policy; the default
+	// transport when nil
+	Transport http.RoundTripper
+}
+
+// defaultPartSize keeps memory per upload bounded; parts other than the
+// last must be at least minPartSize
+const (
+	defaultPartSize = 8 << 20
+	minPartSize     = 5 << 20
+)
+
+// maxPresignTTL is the longest expiry SigV4 presigned URLs accept
+const maxPresignTTL = 7 * 24 * time.Hour
+
+// S3 stores objects through the S3 REST API, signing requests with SigV4.
+// Uploads are streamed in parts, so objects of any size are held in memory
+// one part at a time. Signed URLs are presigned GETs against the bucket.
+type S3 struct {
+	config     S3Config
+	signer     awssig.Signer
+	baseURL    string            // Scheme and host, plus /<bucket> for path-style
+	encryption map[string]string // Headers applied when creating objects
+	httpClient *http.Client
+}
+
+func NewS3(config S3Config) *S3 {
+	baseURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", config.Bucket, config.Region)
+	if config.Endpoint != "" {
+		baseURL = strings.TrimRight(config.Endpoint, "/") + "/" + config.Bucket
+	}
+
+	encryption := map[string]string{}
+	if config.ServerSideEncryption != "" {
+		encryption["x-amz-server-side-encryption"] = config.ServerSideEncryption
+	}
+	if config.KMSKeyID != "" {
+		encryption["x-amz-server-side-encryption-aws-kms-key-id"] = config.KMSKeyID
+	}
+
+	return newS3(config, awssig.AWS, "s3", baseURL, encryption)
+}
+
+func newS3(config S3Config, dialect awssig.Dialect, service, baseURL string, encryption map[string]string) *S3 {
+	if config.PartSize <= 0 {
+		config.PartSize = defaultPartSize
+	} else if config.PartSize < minPartSize {
+		config.PartSize = minPartSize
+	}
+	return &S3{
+		config: config,
+		signer: awssig.Signer{
+			Dialect:         dialect,
+			Region:          config.Region,
+			Service:         service,
+			AccessKeyID:     config.AccessKeyID,
+			SecretAccessKey: config.SecretAccessKey,
+			SessionToken:    config.SessionToken,
+		},
+		baseURL:    baseURL,
+		encryption: encryption,
+		// No overall timeout: large objects stream for as long as they take,
+		// bounded by the caller's context
+		httpClient: &http.Client{Transport: config.Transport},
+	}
+}
+
+// Put uploads small objects in one request and larger ones as a multipart
+// upload, which is aborted if anything fails
+func (s *S3) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	if !validKey(key) {
+		return 0, ErrInvalidKey
+	}
+
+	part := make([]byte, s.config.PartSize)
+	n, err := io.ReadFull(r, part)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		resp, err := s.do(ctx, http.MethodPut, key, nil, s.encryption, part[:n])
+		if err != nil {
+			return 0, fmt.Errorf("failed to upload object: %w", err)
+		}
+		resp.Body.Close()
+		return int64(n), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	uploadID, err := s.createMultipartUpload(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := s.uploadParts(ctx, key, uploadID, r, part)
+	if err != nil {
+		// Abort with a fresh context so a cancelled upload still frees its parts
+		abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if resp, abortErr := s.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+		return 0, err
+	}
+	return size, nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, s.encryption, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("invalid multipart upload response: %v", err)
+	}
+	return result.UploadID, nil
+}
+
+// uploadParts sends first and then the rest of r one part at a time and
+// completes the upload
+func (s *S3) uploadParts(ctx context.Context, key, uploadID string, r io.Reader, first []byte) (int64, error) {
+	var parts []completedPart
+	var size int64
+
+	part := first
+	for number := 1; ; number++ {
+		resp, err := s.do(ctx, http.MethodPut, key, url.Values{
+			"partNumber": {strconv.Itoa(number)},
+			"uploadId":   {uploadID},
+		}, nil, part)
+		if err != nil {
+			return 0, fmt.Errorf("failed to upload part %d: %w", number, err)
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+		size += int64(len(part))
+
+		n, err := io.ReadFull(r, first)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, fmt.Errorf("failed to read object: %w", err)
+		}
+		part = first[:n]
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Completion can fail after a 200 response; the error is in the body
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return 0, fmt.Errorf("failed to complete multipart upload: %s %s", result.Code, result.Message)
+	}
+	return size, nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// SignedURL presigns a GET that downloads key as an attachment
+func (s *S3) SignedURL(_ context.Context, key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	if ttl > maxPresignTTL {
+		ttl = maxPresignTTL
+	}
+
+	now := time.Now().UTC()
+	u, err := url.Parse(s.objectURL(key))
+	if err != nil {
+		return "", err
+	}
+
+	q := s.signer.Dialect.QueryPrefix
+	query := url.Values{
+		q + "-Algorithm":               {s.signer.Dialect.Algorithm},
+		q + "-Credential":              {s.config.AccessKeyID + "/" + s.signer.Scope(now)},
+		q + "-Date":                    {now.Format(awssig.DateFormat)},
+		q + "-Expires":                 {strconv.Itoa(int(ttl.Seconds()))},
+		q + "-SignedHeaders":           {"host"},
+		"response-content-disposition": {fmt.Sprintf(`attachment; filename="%s"`, path.Base(key))},
+	}
+	if s.config.SessionToken != "" {
+		query.Set(q+"-Security-Token", s.config.SessionToken)
+	}
+
+	canonicalQuery := canonicalQueryString(query)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet, u.EscapedPath(), canonicalQuery, "host:" + u.Host + "\n", "host", "UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	u.RawQuery = canonicalQuery + "&" + q + "-Signature=" + s.signer.Signature(now, canonicalRequest)
+	return u.String(), nil
+}
+
+// objectURL is the URL of key, with each path segment escaped
+func (s *S3) objectURL(key string) string {
+	return s.baseURL + "/" + uriEncode(s.config.Prefix+key, false)
+}
+
+// do sends a signed request. Responses other than 2xx are returned as errors
+// (ErrNotFound for 404), with the body closed.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.URL.RawQuery = canonicalQueryString(query)
+	req.ContentLength = int64(len(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	s.signer.Sign(req, body, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach object store: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && method != http.MethodPost {
+		return nil, ErrNotFound
+	}
+	var apiErr struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&apiErr)
+	return nil, fmt.Errorf("object store returned status %d: %s %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+}
+
+// canonicalQueryString sorts and strictly escapes query parameters, as
+// SigV4 requires
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode escapes everything but unreserved characters (and slashes,
+// unless encodeSlash is set)
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
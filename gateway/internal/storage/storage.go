@@ -0,0 +1,38 @@
+// Package storage keeps generated artifacts, such as reports and data
+// exports, outside the database and hands out expiring download links for
+// them. Objects live on the local filesystem, in S3 (or an S3-compatible
+// store) or in Google Cloud Storage.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"time"
+)
+
+var (
+	ErrNotFound         = errors.New("object not found")
+	ErrInvalidKey       = errors.New("invalid object key")
+	ErrInvalidSignature = errors.New("download link is invalid or has expired")
+)
+
+// Store is an object store addressed by slash-separated keys
+type Store interface {
+	// Put streams r into key, replacing any existing object, and returns
+	// the number of bytes written
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a link that downloads key without other credentials
+	// until ttl has passed
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// validKey reports whether key is a clean relative path: no leading slash,
+// no empty, "." or ".." segments
+func validKey(key string) bool {
+	clean := path.Clean("/" + key)
+	return key != "" && clean != "/" && clean[1:] == key
+}
@@ -0,0 +1,349 @@
+// Package supportbundle gathers what support needs to diagnose an on-prem
+// gateway into one archive: version and build info, sanitized configuration,
+// recent error logs, migration status, dependency health, runtime statistics
+// and a metrics snapshot. Secrets are redacted from every file.
+package supportbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/bootstrap"
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/health"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Sources is what a bundle is gathered from. Nil sources are left out, so an
+// offline bundle (no running server) still has version, configuration and
+// the migrations shipped with the build.
+type Sources struct {
+	Version       string
+	Environ       func() []string // os.Environ when nil
+	MigrationsDir string
+	DB            *database.DB
+	Health        *health.Checker
+	Bootstrap     *bootstrap.Bootstrapper
+	Logs          *logging.Recent
+	Runtime       func() interface{}
+	Metrics       http.Handler
+}
+
+// Manifest lists a bundle's files and the sections that could not be gathered
+type Manifest struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Hostname    string            `json:"hostname"`
+	Version     string            `json:"version"`
+	Files       []string          `json:"files"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// VersionInfo identifies the build
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	Revision  string `json:"revision,omitempty"`
+	BuiltAt   string `json:"built_at,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// Migration is the status of one migration file. The database does not
+// record applied migrations, so a migration counts as applied when every
+// table it creates exists; one creating no tables is unknown.
+type Migration struct {
+	Name          string   `json:"name"`
+	Status        string   `json:"status"` // applied, pending or unknown
+	MissingTables []string `json:"missing_tables,omitempty"`
+}
+
+// MigrationStatus is the schema as seen against the migrations shipped
+type MigrationStatus struct {
+	Directory  string      `json:"directory"`
+	Latest     string      `json:"latest,omitempty"`
+	Migrations []Migration `json:"migrations"`
+	Tables     int         `json:"tables,omitempty"`
+}
+
+// Builder writes support bundles
+type Builder struct {
+	sources  Sources
+	redactor *Redactor
+	logger   *zap.Logger
+}
+
+func New(sources Sources, redactor *Redactor, logger *zap.Logger) *Builder {
+	if sources.Environ == nil {
+		sources.Environ = os.Environ
+	}
+	if redactor == nil {
+		redactor = NewRedactor()
+	}
+	return &Builder{sources: sources, redactor: redactor, logger: logger}
+}
+
+// FileName is the archive's suggested name
+func FileName(now time.Time) string {
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "gateway"
+	}
+	return fmt.Sprintf("support-bundle-%s-%s.tar.gz", hostname, now.UTC().Format("20060102T150405Z"))
+}
+
+// Write gathers the bundle into w as a gzipped tarball. A section that fails
+// is recorded in the manifest rather than failing the bundle.
+func (b *Builder) Write(ctx context.Context, w io.Writer) error {
+	now := time.Now().UTC()
+	root := strings.TrimSuffix(FileName(now), ".tar.gz")
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	hostname, _ := os.Hostname()
+	manifest := Manifest{
+		GeneratedAt: now,
+		Hostname:    hostname,
+		Version:     b.sources.Version,
+		Errors:      make(map[string]string),
+	}
+
+	add := func(name string, data []byte) error {
+		data = b.redactor.Bytes(data)
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    root + "/" + name,
+			Mode:    0o600,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, name)
+		return nil
+	}
+	addJSON := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return add(name, append(data, '\n'))
+	}
+
+	// Configuration comes before the other sections so the values of its
+	// secret settings are known when they are scrubbed
+	sections := []struct {
+		name  string
+		write func() error
+	}{
+		{"version.json", func() error { return addJSON("version.json", b.version()) }},
+		{"config.json", func() error { return addJSON("config.json", b.config()) }},
+		{"migrations.json", func() error {
+			status, err := b.migrations(ctx)
+			if err != nil || status == nil {
+				return err
+			}
+			return addJSON("migrations.json", status)
+		}},
+		{"health.json", func() error {
+			if b.sources.Health == nil {
+				return nil
+			}
+			return addJSON("health.json", b.sources.Health.Check(ctx))
+		}},
+		{"bootstrap.json", func() error {
+			if b.sources.Bootstrap == nil {
+				return nil
+			}
+			return addJSON("bootstrap.json", b.sources.Bootstrap.Report())
+		}},
+		{"runtime.json", func() error {
+			if b.sources.Runtime == nil {
+				return nil
+			}
+			return addJSON("runtime.json", b.sources.Runtime())
+		}},
+		{"logs/recent.jsonl", func() error {
+			if b.sources.Logs == nil {
+				return nil
+			}
+			return add("logs/recent.jsonl", b.logs())
+		}},
+		{"metrics.prom", func() error {
+			if b.sources.Metrics == nil {
+				return nil
+			}
+			data, err := b.metrics(ctx)
+			if err != nil {
+				return err
+			}
+			return add("metrics.prom", data)
+		}},
+	}
+	for _, section := range sections {
+		if err := section.write(); err != nil {
+			manifest.Errors[section.name] = err.Error()
+			logging.FromContext(ctx, b.logger).Warn("Support bundle section failed",
+				zap.String("section", section.name),
+				zap.Error(err),
+			)
+		}
+	}
+
+	manifest.Files = append(manifest.Files, "manifest.json")
+	if err := addJSON("manifest.json", manifest); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Handler serves a freshly gathered bundle as a download
+func (b *Builder) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "application/gzip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", FileName(time.Now())))
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+		if err := b.Write(c.Request.Context(), c.Writer); err != nil {
+			// Headers are gone; the truncated archive fails to extract
+			logging.FromContext(c.Request.Context(), b.logger).Error("Failed to write support bundle", zap.Error(err))
+		}
+	}
+}
+
+func (b *Builder) version() VersionInfo {
+	info := VersionInfo{
+		Version:   b.sources.Version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Revision = setting.Value
+			case "vcs.time":
+				info.BuiltAt = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// config is the environment, sorted, with secret settings redacted
+func (b *Builder) config() map[string]string {
+	config := make(map[string]string)
+	for _, entry := range b.sources.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			continue
+		}
+		config[name] = b.redactor.Setting(name, value)
+	}
+	return config
+}
+
+// logs is the recent entries, one JSON object per line
+func (b *Builder) logs() []byte {
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	for _, entry := range b.sources.Logs.Entries() {
+		enc.Encode(entry)
+	}
+	return []byte(sb.String())
+}
+
+func (b *Builder) metrics(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	b.sources.Metrics.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return nil, fmt.Errorf("metrics handler returned %d", rec.Code)
+	}
+	return rec.Body.Bytes(), nil
+}
+
+var createTable = regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(?:public\.)?"?([a-z_][a-z0-9_]*)"?`)
+
+func (b *Builder) migrations(ctx context.Context) (*MigrationStatus, error) {
+	if b.sources.MigrationsDir == "" {
+		return nil, nil
+	}
+	files, err := filepath.Glob(filepath.Join(b.sources.MigrationsDir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no migrations in %s", b.sources.MigrationsDir)
+	}
+	sort.Strings(files)
+
+	status := &MigrationStatus{
+		Directory: b.sources.MigrationsDir,
+		Latest:    filepath.Base(files[len(files)-1]),
+	}
+
+	existing := map[string]bool{}
+	if b.sources.DB != nil {
+		var tables []string
+		if err := b.sources.DB.SelectContext(ctx, &tables, `
+			SELECT tablename FROM pg_tables WHERE schemaname = 'public'
+		`); err != nil {
+			return nil, fmt.Errorf("list tables: %w", err)
+		}
+		for _, table := range tables {
+			existing[table] = true
+		}
+		status.Tables = len(tables)
+	}
+
+	for _, file := range files {
+		migration := Migration{Name: filepath.Base(file), Status: "unknown"}
+		script, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		created := createTable.FindAllStringSubmatch(string(script), -1)
+		if b.sources.DB != nil && len(created) > 0 {
+			for _, match := range created {
+				if table := strings.ToLower(match[1]); !existing[table] {
+					migration.MissingTables = append(migration.MissingTables, table)
+				}
+			}
+			migration.Status = "applied"
+			if len(migration.MissingTables) > 0 {
+				migration.Status = "pending"
+			}
+		}
+		status.Migrations = append(status.Migrations, migration)
+	}
+	return status, nil
+}
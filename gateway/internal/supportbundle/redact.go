@@ -0,0 +1,101 @@
+package supportbundle
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces every secret in a bundle
+const Redacted = "[REDACTED]"
+
+// minSecretLength keeps short values ("true", "5432") from being scrubbed
+// everywhere they happen to appear
+const minSecretLength = 6
+
+var (
+	// secretName matches configuration names whose values are secrets
+	secretName = regexp.MustCompile(`(?i)(SECRET|PASSWORD|PASSWD|TOKEN|API_?KEY|PRIVATE|CREDENTIAL|SIGNING|_KEY$|_KEYS$|DSN)`)
+
+	// secretPatterns match secrets wherever they appear in text
+	secretPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+		regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),                          // JWTs
+		regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), // PEM keys
+		regexp.MustCompile(`(?i)(password=)[^\s"&]+`),                                                    // DSNs
+		regexp.MustCompile(`([a-z][a-z0-9+.-]*://[^:/@\s"]+:)[^@\s"/]+(@)`),                              // URL credentials
+	}
+)
+
+// Redactor scrubs secrets from bundle contents: values of secret-named
+// settings, values registered with Add (e.g. fetched from a secrets manager),
+// and anything shaped like a token, key or credential
+type Redactor struct {
+	mu     sync.RWMutex
+	values map[string]struct{}
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{values: make(map[string]struct{})}
+}
+
+// Add registers secret values to scrub wherever they appear
+func (r *Redactor) Add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, value := range values {
+		if len(value) >= minSecretLength {
+			r.values[value] = struct{}{}
+		}
+	}
+}
+
+// Setting returns the value of setting name as it may appear in a bundle
+func (r *Redactor) Setting(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	if secretName.MatchString(name) {
+		r.Add(value)
+		return Redacted
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			r.Add(password)
+			u.User = url.UserPassword(u.User.Username(), "x")
+			return strings.Replace(u.String(), ":x@", ":"+Redacted+"@", 1)
+		}
+	}
+	return r.String(value)
+}
+
+// String scrubs secrets from text
+func (r *Redactor) String(text string) string {
+	r.mu.RLock()
+	values := make([]string, 0, len(r.values))
+	for value := range r.values {
+		values = append(values, value)
+	}
+	r.mu.RUnlock()
+
+	// Longest first, so a secret containing another is scrubbed whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, Redacted)
+	}
+	for _, pattern := range secretPatterns {
+		if pattern.NumSubexp() > 0 {
+			text = pattern.ReplaceAllString(text, "${1}"+Redacted+"${2}")
+		} else {
+			text = pattern.ReplaceAllString(text, Redacted)
+		}
+	}
+	return text
+}
+
+// Bytes scrubs secrets from data
+func (r *Redactor) Bytes(data []byte) []byte {
+	return []byte(r.String(string(data)))
+}
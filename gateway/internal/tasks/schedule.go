@@ -0,0 +1,101 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Schedule enqueues a kind of task at a fixed interval. Runs never overlap:
+// a run is skipped while the previous one is still pending or running.
+type Schedule struct {
+	Name    string
+	Kind    string
+	Every   time.Duration
+	Payload interface{}
+}
+
+// AddSchedule registers a recurring task; its kind must be registered too.
+// Schedules must be added before Start.
+func (s *Service) AddSchedule(schedule Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules = append(s.schedules, schedule)
+}
+
+// syncSchedules stores the schedules, keeping the next run of those already
+// stored unless their interval changed
+func (s *Service) syncSchedules(ctx context.Context) {
+	for _, schedule := range s.schedules {
+		_, err := s.db.ExecContext(ctx, `
+			INSERT INTO task_schedules (name, kind, interval_seconds, next_run_at)
+			VALUES ($1, $2, $3, NOW() + make_interval(secs => $3))
+			ON CONFLICT (name) DO UPDATE SET
+				kind = EXCLUDED.kind,
+				interval_seconds = EXCLUDED.interval_seconds,
+				next_run_at = CASE
+					WHEN task_schedules.interval_seconds = EXCLUDED.interval_seconds THEN task_schedules.next_run_at
+					ELSE EXCLUDED.next_run_at
+				END,
+				updated_at = NOW()
+		`, schedule.Name, schedule.Kind, int(schedule.Every.Seconds()))
+		if err != nil {
+			s.logger.Error("Failed to store task schedule", zap.String("schedule", schedule.Name), zap.Error(err))
+		}
+	}
+}
+
+// runSchedules enqueues the runs that are due. Advancing next_run_at claims
+// the run, so only one instance enqueues it.
+func (s *Service) runSchedules(ctx context.Context) {
+	if len(s.schedules) == 0 {
+		return
+	}
+	names := make([]string, len(s.schedules))
+	for i, schedule := range s.schedules {
+		names[i] = schedule.Name
+	}
+
+	var due []string
+	err := s.db.SelectContext(ctx, &due, `
+		UPDATE task_schedules
+		SET next_run_at = NOW() + make_interval(secs => interval_seconds), last_run_at = NOW(), updated_at = NOW()
+		WHERE name = ANY($1) AND next_run_at <= NOW()
+		RETURNING name
+	`, pq.Array(names))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to claim scheduled tasks", zap.Error(err))
+		}
+		return
+	}
+
+	for _, name := range due {
+		for _, schedule := range s.schedules {
+			if schedule.Name != name {
+				continue
+			}
+			task, err := s.Enqueue(ctx, NewTask{
+				Kind:      schedule.Kind,
+				Payload:   schedule.Payload,
+				UniqueKey: "schedule:" + schedule.Name,
+				// A missed run is made up by the next one
+				MaxAttempts: 1,
+			})
+			if err == ErrDuplicate {
+				continue
+			}
+			if err != nil {
+				s.logger.Error("Failed to enqueue scheduled task", zap.String("schedule", name), zap.Error(err))
+				continue
+			}
+			if _, err := s.db.ExecContext(ctx, `
+				UPDATE task_schedules SET last_task_id = $2 WHERE name = $1
+			`, name, task.ID); err != nil {
+				s.logger.Warn("Failed to record scheduled task", zap.String("schedule", name), zap.Error(err))
+			}
+		}
+	}
+}
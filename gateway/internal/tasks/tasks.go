@@ -0,0 +1,408 @@
+// Package tasks runs background work from a queue kept in PostgreSQL, so
+// work survives restarts and is shared between gateway replicas.
+//
+// Each kind of task has a handler and a queue; every queue has its own pool
+// of workers. Tasks can be delayed, and schedules enqueue a kind at a fixed
+// interval. A failed run is retried with exponential backoff until the
+// task's attempts run out. A worker holds a lock on its task that it keeps
+// extending; when an instance dies its lock lapses and the task is retried.
+// Handlers should be idempotent, since a task can run again after a crash.
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Task states
+const (
+	StatePending   = "pending"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// DefaultQueue is used by kinds registered without a queue
+const DefaultQueue = "default"
+
+var (
+	ErrNotFound       = errors.New("task not found")
+	ErrUnknownKind    = errors.New("no handler registered for task kind")
+	ErrNotRetryable   = errors.New("only failed or cancelled tasks can be retried")
+	ErrNotCancellable = errors.New("only pending or running tasks can be cancelled")
+	ErrDuplicate      = errors.New("a task with the same key is already pending or running")
+)
+
+// Config tunes the workers
+type Config struct {
+	// Concurrency is the number of workers of each queue; queues not listed
+	// get DefaultConcurrency
+	Concurrency        map[string]int
+	DefaultConcurrency int
+	// PollInterval is how often idle workers look for due tasks
+	PollInterval time.Duration
+	// Timeout bounds a single run
+	Timeout time.Duration
+	// LockTimeout is how long a task stays claimed without its worker
+	// extending the lock; it is extended every third of it
+	LockTimeout time.Duration
+	// MaxAttempts is used for tasks enqueued without their own
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed run; it doubles
+	// after each further failure up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Retention is how long finished tasks are kept
+	Retention time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Concurrency:        map[string]int{},
+		DefaultConcurrency: 2,
+		PollInterval:       time.Second,
+		Timeout:            30 * time.Minute,
+		LockTimeout:        2 * time.Minute,
+		MaxAttempts:        5,
+		InitialBackoff:     10 * time.Second,
+		MaxBackoff:         time.Hour,
+		Retention:          7 * 24 * time.Hour,
+	}
+}
+
+// Task is one unit of background work
+type Task struct {
+	ID              string          `json:"id" db:"id"`
+	Queue           string          `json:"queue" db:"queue"`
+	Kind            string          `json:"kind" db:"kind"`
+	Payload         json.RawMessage `json:"payload" db:"payload"`
+	State           string          `json:"state" db:"state"`
+	Attempts        int             `json:"attempts" db:"attempts"`
+	MaxAttempts     int             `json:"max_attempts" db:"max_attempts"`
+	RunAt           time.Time       `json:"run_at" db:"run_at"`
+	UniqueKey       *string         `json:"unique_key,omitempty" db:"unique_key"`
+	LastError       *string         `json:"last_error,omitempty" db:"last_error"`
+	LockedBy        *string         `json:"locked_by,omitempty" db:"locked_by"`
+	LockedUntil     *time.Time      `json:"locked_until,omitempty" db:"locked_until"`
+	CancelRequested bool            `json:"cancel_requested" db:"cancel_requested"`
+	StartedAt       *time.Time      `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+	CreatedBy       *string         `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+const columns = `id, queue, kind, payload, state, attempts, max_attempts, run_at, unique_key, last_error,
+	locked_by, locked_until, cancel_requested, started_at, finished_at, created_by, created_at, updated_at`
+
+// Decode unmarshals the task's payload into v
+func (t *Task) Decode(v interface{}) error {
+	return json.Unmarshal(t.Payload, v)
+}
+
+// LastAttempt reports whether a failure of the current run fails the task
+func (t *Task) LastAttempt() bool {
+	return t.Attempts >= t.MaxAttempts
+}
+
+// Handler runs a task. Returning an error retries it, unless the error is
+// Permanent or the task has no attempts left. ctx is cancelled when the run
+// times out, the task is cancelled or the instance shuts down.
+type Handler func(ctx context.Context, task *Task) error
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying will not fix; the task fails at once
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// NewTask describes a task to enqueue
+type NewTask struct {
+	Kind    string
+	Payload interface{} // Marshalled to JSON
+	// Delay postpones the first run; RunAt, when set, wins
+	Delay time.Duration
+	RunAt time.Time
+	// MaxAttempts defaults to Config.MaxAttempts
+	MaxAttempts int
+	// UniqueKey makes Enqueue return the pending or running task with the
+	// same key instead of adding another
+	UniqueKey string
+	CreatedBy string
+}
+
+// Filter selects tasks to list
+type Filter struct {
+	State  string
+	Queue  string
+	Kind   string
+	Limit  int
+	Offset int
+}
+
+// QueueStats summarizes a queue
+type QueueStats struct {
+	Queue       string     `json:"queue" db:"queue"`
+	Concurrency int        `json:"concurrency" db:"-"`
+	Pending     int        `json:"pending" db:"pending"`
+	Running     int        `json:"running" db:"running"`
+	Failed      int        `json:"failed" db:"failed"`
+	OldestDueAt *time.Time `json:"oldest_due_at,omitempty" db:"oldest_due_at"` // Of the pending tasks already due
+}
+
+type registration struct {
+	queue   string
+	handler Handler
+}
+
+// Service enqueues tasks and runs their workers
+type Service struct {
+	db        *database.DB
+	config    Config
+	workerID  string
+	mu        sync.RWMutex
+	kinds     map[string]registration
+	schedules []Schedule
+	wake      map[string]chan struct{}
+	logger    *zap.Logger
+}
+
+func NewService(db *database.DB, config Config, logger *zap.Logger) *Service {
+	host, _ := os.Hostname()
+	return &Service{
+		db:       db,
+		config:   config,
+		workerID: fmt.Sprintf("%s/%s", host, uuid.New().String()[:8]),
+		kinds:    make(map[string]registration),
+		wake:     make(map[string]chan struct{}),
+		logger:   logger,
+	}
+}
+
+// Register sets the handler and queue of a kind of task. Every kind must be
+// registered before Start.
+func (s *Service) Register(kind, queue string, handler Handler) {
+	if queue == "" {
+		queue = DefaultQueue
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kinds[kind] = registration{queue: queue, handler: handler}
+	if _, ok := s.wake[queue]; !ok {
+		s.wake[queue] = make(chan struct{}, 1)
+	}
+}
+
+func (s *Service) registration(kind string) (registration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reg, ok := s.kinds[kind]
+	return reg, ok
+}
+
+// Enqueue adds a task to its kind's queue
+func (s *Service) Enqueue(ctx context.Context, t NewTask) (*Task, error) {
+	reg, ok := s.registration(t.Kind)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, t.Kind)
+	}
+	if t.Payload == nil {
+		t.Payload = struct{}{}
+	}
+	payload, err := json.Marshal(t.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task payload: %w", err)
+	}
+	delay := t.Delay
+	if !t.RunAt.IsZero() {
+		delay = time.Until(t.RunAt)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	maxAttempts := t.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = s.config.MaxAttempts
+	}
+
+	var task Task
+	err = s.db.GetContext(ctx, &task, `
+		INSERT INTO tasks (queue, kind, payload, max_attempts, run_at, unique_key, created_by)
+		VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5), NULLIF($6, ''), NULLIF($7, '')::uuid)
+		ON CONFLICT (unique_key) WHERE unique_key IS NOT NULL AND state IN ('pending', 'running') DO NOTHING
+		RETURNING `+columns,
+		reg.queue, t.Kind, payload, maxAttempts, delay.Seconds(), t.UniqueKey, t.CreatedBy)
+	if err == sql.ErrNoRows {
+		err = s.db.GetContext(ctx, &task, `
+			SELECT `+columns+` FROM tasks
+			WHERE unique_key = $1 AND state IN ('pending', 'running')
+		`, t.UniqueKey)
+		if err == sql.ErrNoRows {
+			// It finished in between; the caller may enqueue again
+			return nil, ErrDuplicate
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load duplicate task: %w", err)
+		}
+		return &task, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	if delay == 0 {
+		s.notify(reg.queue)
+	}
+	return &task, nil
+}
+
+// notify wakes an idle worker of the queue on this instance
+func (s *Service) notify(queue string) {
+	s.mu.RLock()
+	wake := s.wake[queue]
+	s.mu.RUnlock()
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Get loads a task
+func (s *Service) Get(ctx context.Context, taskID string) (*Task, error) {
+	var task Task
+	err := s.db.GetContext(ctx, &task, `SELECT `+columns+` FROM tasks WHERE id = $1`, taskID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return &task, nil
+}
+
+// List returns tasks, newest first
+func (s *Service) List(ctx context.Context, f Filter) ([]Task, error) {
+	tasks := []Task{}
+	err := s.db.SelectContext(ctx, &tasks, `
+		SELECT `+columns+` FROM tasks
+		WHERE ($1 = '' OR state = $1)
+		AND ($2 = '' OR queue = $2)
+		AND ($3 = '' OR kind = $3)
+		ORDER BY created_at DESC, id
+		LIMIT $4 OFFSET $5
+	`, f.State, f.Queue, f.Kind, f.Limit, f.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// Queues summarizes every queue with registered kinds or stored tasks
+func (s *Service) Queues(ctx context.Context) ([]QueueStats, error) {
+	stats := []QueueStats{}
+	err := s.db.SelectContext(ctx, &stats, `
+		SELECT queue,
+			COUNT(*) FILTER (WHERE state = 'pending') AS pending,
+			COUNT(*) FILTER (WHERE state = 'running') AS running,
+			COUNT(*) FILTER (WHERE state = 'failed') AS failed,
+			MIN(run_at) FILTER (WHERE state = 'pending' AND run_at <= NOW()) AS oldest_due_at
+		FROM tasks
+		GROUP BY queue
+		ORDER BY queue
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize queues: %w", err)
+	}
+
+	concurrency := s.concurrency()
+	for i := range stats {
+		stats[i].Concurrency = concurrency[stats[i].Queue]
+		delete(concurrency, stats[i].Queue)
+	}
+	for queue, n := range concurrency {
+		stats = append(stats, QueueStats{Queue: queue, Concurrency: n})
+	}
+	return stats, nil
+}
+
+// Retry queues a failed or cancelled task to run again now, with its
+// attempts reset
+func (s *Service) Retry(ctx context.Context, taskID string) (*Task, error) {
+	var task Task
+	err := s.db.GetContext(ctx, &task, `
+		UPDATE tasks
+		SET state = 'pending', attempts = 0, run_at = NOW(), cancel_requested = false,
+			locked_by = NULL, locked_until = NULL, finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND state IN ('failed', 'cancelled')
+		RETURNING `+columns, taskID)
+	if database.IsUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
+	if err == sql.ErrNoRows {
+		if _, err := s.Get(ctx, taskID); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotRetryable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry task: %w", err)
+	}
+
+	s.notify(task.Queue)
+	return &task, nil
+}
+
+// Cancel stops a task. Pending tasks are cancelled at once; a running task's
+// worker sees the request when it next extends its lock and cancels the
+// run's context.
+func (s *Service) Cancel(ctx context.Context, taskID string) (*Task, error) {
+	var task Task
+	err := s.db.GetContext(ctx, &task, `
+		UPDATE tasks
+		SET cancel_requested = true,
+			state = CASE WHEN state = 'pending' THEN 'cancelled' ELSE state END,
+			finished_at = CASE WHEN state = 'pending' THEN NOW() ELSE finished_at END,
+			updated_at = NOW()
+		WHERE id = $1 AND state IN ('pending', 'running')
+		RETURNING `+columns, taskID)
+	if err == sql.ErrNoRows {
+		if _, err := s.Get(ctx, taskID); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotCancellable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel task: %w", err)
+	}
+	return &task, nil
+}
+
+// concurrency returns the number of workers of each queue with registered kinds
+func (s *Service) concurrency() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	queues := make(map[string]int, len(s.wake))
+	for queue := range s.wake {
+		n := s.config.DefaultConcurrency
+		if c, ok := s.config.Concurrency[queue]; ok {
+			n = c
+		}
+		queues[queue] = n
+	}
+	return queues
+}
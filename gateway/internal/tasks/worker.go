@@ -0,0 +1,306 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Start runs every queue's workers, the schedules and the reaper until ctx
+// is cancelled, then waits for running tasks to stop. Tasks interrupted by
+// the shutdown go back to their queue without using up an attempt.
+func (s *Service) Start(ctx context.Context) {
+	s.syncSchedules(ctx)
+
+	var wg sync.WaitGroup
+	queues := s.concurrency()
+	for queue, n := range queues {
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(queue string) {
+				defer wg.Done()
+				s.work(ctx, queue)
+			}(queue)
+		}
+	}
+	s.logger.Info("Task workers started", zap.String("worker_id", s.workerID), zap.Any("queues", queues))
+
+	schedule := time.NewTicker(s.config.PollInterval)
+	defer schedule.Stop()
+	reap := time.NewTicker(s.config.LockTimeout / 2)
+	defer reap.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-schedule.C:
+			s.runSchedules(ctx)
+		case <-reap.C:
+			s.reapLapsed(ctx)
+		case <-purge.C:
+			s.purgeFinished(ctx)
+		}
+	}
+}
+
+// work runs one worker of a queue
+func (s *Service) work(ctx context.Context, queue string) {
+	s.mu.RLock()
+	wake := s.wake[queue]
+	s.mu.RUnlock()
+	idle := time.NewTimer(s.config.PollInterval)
+	defer idle.Stop()
+
+	for {
+		task, err := s.claim(ctx, queue)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Failed to claim task", zap.String("queue", queue), zap.Error(err))
+		}
+		if task != nil {
+			s.run(ctx, task)
+			continue
+		}
+
+		if !idle.Stop() {
+			select {
+			case <-idle.C:
+			default:
+			}
+		}
+		idle.Reset(s.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-idle.C:
+		}
+	}
+}
+
+// claim locks the queue's next due task for this instance, or returns nil
+func (s *Service) claim(ctx context.Context, queue string) (*Task, error) {
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+	var task Task
+	err := s.db.GetContext(ctx, &task, `
+		UPDATE tasks
+		SET state = 'running', attempts = attempts + 1, locked_by = $2,
+			locked_until = NOW() + make_interval(secs => $3), started_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM tasks
+			WHERE queue = $1 AND state = 'pending' AND run_at <= NOW()
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+columns,
+		queue, s.workerID, s.config.LockTimeout.Seconds())
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// run calls the task's handler and records the outcome
+func (s *Service) run(ctx context.Context, task *Task) {
+	logger := s.logger.With(
+		zap.String("task_id", task.ID),
+		zap.String("kind", task.Kind),
+		zap.Int("attempt", task.Attempts),
+	)
+
+	var err error
+	var cancelRequested atomic.Bool
+	start := time.Now()
+	if reg, ok := s.registration(task.Kind); !ok {
+		// Another version of the gateway may know it
+		err = fmt.Errorf("%w: %s", ErrUnknownKind, task.Kind)
+	} else {
+		runCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+		done := make(chan struct{})
+		go s.holdLock(runCtx, task, cancel, &cancelRequested, done)
+
+		metrics.TasksRunning.WithLabelValues(task.Queue).Inc()
+		err = s.call(runCtx, reg.handler, task, logger)
+		metrics.TasksRunning.WithLabelValues(task.Queue).Dec()
+
+		cancel()
+		<-done
+	}
+	metrics.TaskDuration.WithLabelValues(task.Queue, task.Kind).Observe(time.Since(start).Seconds())
+
+	s.finish(ctx, task, err, cancelRequested.Load(), logger)
+}
+
+// call runs the handler, turning a panic into an error
+func (s *Service) call(ctx context.Context, handler Handler, task *Task, logger *zap.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Task panicked", zap.Any("panic", r), zap.ByteString("stack", debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx, task)
+}
+
+// holdLock extends the task's lock while it runs, and cancels the run when
+// the task is cancelled or another instance took it over
+func (s *Service) holdLock(ctx context.Context, task *Task, cancel context.CancelFunc, cancelRequested *atomic.Bool, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(s.config.LockTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var requested bool
+		err := s.db.GetContext(ctx, &requested, `
+			UPDATE tasks SET locked_until = NOW() + make_interval(secs => $3), updated_at = NOW()
+			WHERE id = $1 AND locked_by = $2 AND state = 'running'
+			RETURNING cancel_requested
+		`, task.ID, s.workerID, s.config.LockTimeout.Seconds())
+		switch {
+		case err == sql.ErrNoRows:
+			s.logger.Warn("Lost task lock", zap.String("task_id", task.ID))
+			cancel()
+			return
+		case err != nil:
+			if ctx.Err() == nil {
+				s.logger.Warn("Failed to extend task lock", zap.String("task_id", task.ID), zap.Error(err))
+			}
+		case requested:
+			cancelRequested.Store(true)
+			cancel()
+			return
+		}
+	}
+}
+
+// finish records how a run ended: success, a retry after backoff, failure
+// or cancellation
+func (s *Service) finish(ctx context.Context, task *Task, runErr error, cancelRequested bool, logger *zap.Logger) {
+	state, outcome := StateSucceeded, StateSucceeded
+	var delay time.Duration
+	var lastError *string
+	refund := 0
+	if runErr != nil {
+		message := runErr.Error()
+		lastError = &message
+		var permanent permanentError
+		switch {
+		case cancelRequested:
+			state, outcome = StateCancelled, StateCancelled
+		case ctx.Err() != nil:
+			// Shutting down; the run does not count against the task
+			state, outcome, refund = StatePending, "interrupted", 1
+		case errors.As(runErr, &permanent) || task.LastAttempt():
+			state, outcome = StateFailed, StateFailed
+		default:
+			state, outcome = StatePending, "retried"
+			delay = s.backoff(task.Attempts)
+		}
+	}
+
+	// The outcome is recorded even while shutting down
+	recordCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := s.db.ExecContext(recordCtx, `
+		UPDATE tasks
+		SET state = $3, attempts = attempts - $4, last_error = COALESCE($5, last_error),
+			run_at = CASE WHEN $3 = 'pending' THEN NOW() + make_interval(secs => $6) ELSE run_at END,
+			finished_at = CASE WHEN $3 = 'pending' THEN NULL ELSE NOW() END,
+			locked_by = NULL, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2
+	`, task.ID, s.workerID, state, refund, lastError, delay.Seconds())
+	if err != nil {
+		logger.Error("Failed to record task outcome", zap.String("state", state), zap.Error(err))
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		// The lock lapsed and the reaper or another instance has the task
+		logger.Warn("Task outcome discarded after losing its lock", zap.String("state", state))
+		return
+	}
+
+	metrics.TasksProcessed.WithLabelValues(task.Queue, task.Kind, outcome).Inc()
+	switch outcome {
+	case StateSucceeded, "interrupted":
+		logger.Debug("Task finished", zap.String("outcome", outcome))
+	case "retried":
+		logger.Warn("Task failed, retrying", zap.Duration("retry_in", delay), zap.Error(runErr))
+	default:
+		logger.Error("Task stopped", zap.String("outcome", outcome), zap.Error(runErr))
+	}
+}
+
+// backoff is the wait after the given number of failed runs
+func (s *Service) backoff(failures int) time.Duration {
+	wait := s.config.InitialBackoff
+	for i := 1; i < failures && wait < s.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if s.config.MaxBackoff > 0 && wait > s.config.MaxBackoff {
+		wait = s.config.MaxBackoff
+	}
+	return wait
+}
+
+// reapLapsed returns tasks whose worker stopped extending the lock (the
+// instance died) to their queue, or fails them when out of attempts
+func (s *Service) reapLapsed(ctx context.Context) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE tasks
+		SET state = CASE
+				WHEN cancel_requested THEN 'cancelled'
+				WHEN attempts >= max_attempts THEN 'failed'
+				ELSE 'pending'
+			END,
+			finished_at = CASE WHEN cancel_requested OR attempts >= max_attempts THEN NOW() END,
+			last_error = 'worker stopped responding',
+			run_at = NOW(), locked_by = NULL, locked_until = NULL, updated_at = NOW()
+		WHERE state = 'running' AND locked_until < NOW()
+	`)
+	if err != nil {
+		s.logger.Error("Failed to reap lapsed tasks", zap.Error(err))
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.logger.Warn("Recovered tasks from unresponsive workers", zap.Int64("count", n))
+	}
+}
+
+// purgeFinished deletes finished tasks past retention
+func (s *Service) purgeFinished(ctx context.Context) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM tasks
+		WHERE state IN ('succeeded', 'failed', 'cancelled')
+		AND finished_at < NOW() - make_interval(secs => $1)
+	`, s.config.Retention.Seconds())
+	if err != nil {
+		s.logger.Error("Failed to purge finished tasks", zap.Error(err))
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		s.logger.Info("Purged finished tasks", zap.Int64("count", n))
+	}
+}
@@ -0,0 +1,52 @@
+package tenancy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration is one of the migrations applied to every tenant schema
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// LoadMigrations reads the tenant migrations in dir. Files are named
+// NNN_description.sql and applied in version order.
+func LoadMigrations(dir string) ([]Migration, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no tenant migrations in %s", dir)
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	seen := map[int]string{}
+	for _, file := range files {
+		name := filepath.Base(file)
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("tenant migration %s is not named NNN_description.sql", name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("tenant migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(sql)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
@@ -0,0 +1,357 @@
+// Package tenancy gives organizations that need stronger isolation, such as
+// enterprise customers, a database schema of their own. The organization's
+// scan evidence (the raw data workers submit) is kept in tables of that
+// schema rather than in rows of the shared tables, so it can be backed up,
+// restored, granted or dropped on its own. Everything else stays in the
+// shared tables, filtered by organization as before. The repository layer
+// finds an organization's schema in tenant_schemas; this package creates and
+// removes schemas and keeps them migrated.
+package tenancy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/tasks"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Isolation modes
+const (
+	IsolationShared = "shared"
+	IsolationSchema = "schema"
+)
+
+// Task kinds moving an existing organization in or out of its own schema
+const (
+	TaskProvision   = "tenancy.provision"
+	TaskDeprovision = "tenancy.deprovision"
+)
+
+var (
+	ErrIsolated    = errors.New("organization already has its own schema")
+	ErrNotIsolated = errors.New("organization does not have its own schema")
+	ErrOrgNotFound = errors.New("organization not found")
+)
+
+// Schema is a row of tenant_schemas
+type Schema struct {
+	OrganizationID string    `json:"organization_id" db:"organization_id"`
+	SchemaName     string    `json:"schema_name" db:"schema_name"`
+	Version        int       `json:"version" db:"version"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+const schemaColumns = `organization_id, schema_name, version, created_at, updated_at`
+
+// SchemaName is the schema of an organization: tenant_ and its ID without
+// hyphens, which stays within Postgres' 63 byte identifier limit
+func SchemaName(orgID string) string {
+	return "tenant_" + strings.ReplaceAll(orgID, "-", "")
+}
+
+// Service provisions, removes and migrates tenant schemas
+type Service struct {
+	db         *database.DB
+	tasks      *tasks.Service
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+func NewService(db *database.DB, taskService *tasks.Service, migrations []Migration, logger *zap.Logger) *Service {
+	s := &Service{
+		db:         db,
+		tasks:      taskService,
+		migrations: migrations,
+		logger:     logger,
+	}
+	taskService.Register(TaskProvision, "tenancy", s.runProvision)
+	taskService.Register(TaskDeprovision, "tenancy", s.runDeprovision)
+	return s
+}
+
+// Version is the tenant migration version schemas are brought to
+func (s *Service) Version() int {
+	if len(s.migrations) == 0 {
+		return 0
+	}
+	return s.migrations[len(s.migrations)-1].Version
+}
+
+// List returns every tenant schema
+func (s *Service) List(ctx context.Context) ([]Schema, error) {
+	schemas := []Schema{}
+	err := s.db.Reader().SelectContext(ctx, &schemas, `SELECT `+schemaColumns+` FROM tenant_schemas ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant schemas: %w", err)
+	}
+	return schemas, nil
+}
+
+// Get returns the organization's schema, or nil when its data is kept in
+// the shared tables
+func (s *Service) Get(ctx context.Context, orgID string) (*Schema, error) {
+	var schema Schema
+	err := s.db.GetContext(ctx, &schema, `SELECT `+schemaColumns+` FROM tenant_schemas WHERE organization_id = $1`, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant schema: %w", err)
+	}
+	return &schema, nil
+}
+
+// Provision gives the organization its own schema within q, a transaction:
+// it creates the schema, applies the tenant migrations and moves the
+// organization's existing evidence into it. Used when an organization is
+// created; existing organizations are moved by Isolate.
+func (s *Service) Provision(ctx context.Context, q repository.Queryer, orgID string) error {
+	if err := lockOrganization(ctx, q, orgID); err != nil {
+		return err
+	}
+	var exists bool
+	if err := q.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM tenant_schemas WHERE organization_id = $1)`, orgID); err != nil {
+		return fmt.Errorf("failed to check tenant schema: %w", err)
+	}
+	if exists {
+		return ErrIsolated
+	}
+
+	schema := SchemaName(orgID)
+	if _, err := q.ExecContext(ctx, `CREATE SCHEMA `+pq.QuoteIdentifier(schema)); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", schema, err)
+	}
+	if err := s.apply(ctx, q, schema, 0); err != nil {
+		return err
+	}
+
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO `+pq.QuoteIdentifier(schema)+`.scan_result_data (scan_result_id, raw_data, created_at)
+		SELECT id, raw_data, COALESCE(created_at, NOW()) FROM scan_results
+		WHERE organization_id = $1 AND raw_data IS NOT NULL
+	`, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to move scan evidence: %w", err)
+	}
+	_, err = q.ExecContext(ctx, `UPDATE scan_results SET raw_data = NULL WHERE organization_id = $1 AND raw_data IS NOT NULL`, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to clear shared scan evidence: %w", err)
+	}
+
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO tenant_schemas (organization_id, schema_name, version) VALUES ($1, $2, $3)
+	`, orgID, schema, s.Version())
+	if err != nil {
+		return fmt.Errorf("failed to record tenant schema: %w", err)
+	}
+	return nil
+}
+
+// Deprovision moves the organization's evidence back into the shared
+// tables and drops its schema, within q, a transaction
+func (s *Service) Deprovision(ctx context.Context, q repository.Queryer, orgID string) error {
+	if err := lockOrganization(ctx, q, orgID); err != nil {
+		return err
+	}
+	var schema string
+	err := q.GetContext(ctx, &schema, `SELECT schema_name FROM tenant_schemas WHERE organization_id = $1`, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotIsolated
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load tenant schema: %w", err)
+	}
+
+	_, err = q.ExecContext(ctx, `
+		UPDATE scan_results sr SET raw_data = d.raw_data
+		FROM `+pq.QuoteIdentifier(schema)+`.scan_result_data d
+		WHERE d.scan_result_id = sr.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to move scan evidence back: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `DROP SCHEMA `+pq.QuoteIdentifier(schema)+` CASCADE`); err != nil {
+		return fmt.Errorf("failed to drop schema %s: %w", schema, err)
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM tenant_schemas WHERE organization_id = $1`, orgID); err != nil {
+		return fmt.Errorf("failed to remove tenant schema: %w", err)
+	}
+	return nil
+}
+
+// isolationTask is the payload of the provision and deprovision tasks
+type isolationTask struct {
+	OrganizationID string `json:"organization_id"`
+}
+
+// Isolate queues moving an existing organization into its own schema. The
+// move copies all of the organization's evidence in one transaction, so it
+// runs as a background task.
+func (s *Service) Isolate(ctx context.Context, orgID, requestedBy string) (*tasks.Task, error) {
+	return s.enqueue(ctx, TaskProvision, orgID, requestedBy)
+}
+
+// Share queues moving the organization's evidence back into the shared
+// tables and dropping its schema
+func (s *Service) Share(ctx context.Context, orgID, requestedBy string) (*tasks.Task, error) {
+	return s.enqueue(ctx, TaskDeprovision, orgID, requestedBy)
+}
+
+func (s *Service) enqueue(ctx context.Context, kind, orgID, requestedBy string) (*tasks.Task, error) {
+	return s.tasks.Enqueue(ctx, tasks.NewTask{
+		Kind:    kind,
+		Payload: isolationTask{OrganizationID: orgID},
+		// One move per organization at a time, in either direction
+		UniqueKey: "tenancy:" + orgID,
+		CreatedBy: requestedBy,
+	})
+}
+
+func (s *Service) runProvision(ctx context.Context, task *tasks.Task) error {
+	return s.runInTx(ctx, task, s.Provision, ErrIsolated)
+}
+
+func (s *Service) runDeprovision(ctx context.Context, task *tasks.Task) error {
+	return s.runInTx(ctx, task, s.Deprovision, ErrNotIsolated)
+}
+
+// runInTx runs a move in a transaction of its own. Finding the organization
+// already where the task would move it is success, e.g. when a retry
+// follows an attempt that committed.
+func (s *Service) runInTx(ctx context.Context, task *tasks.Task, move func(context.Context, repository.Queryer, string) error, done error) error {
+	var payload isolationTask
+	if err := task.Decode(&payload); err != nil {
+		return tasks.Permanent(err)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = move(ctx, tx, payload.OrganizationID)
+	if errors.Is(err, done) {
+		return nil
+	}
+	if errors.Is(err, ErrOrgNotFound) {
+		return tasks.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Info("Moved organization evidence",
+		zap.String("organization_id", payload.OrganizationID),
+		zap.String("kind", task.Kind),
+	)
+	return nil
+}
+
+// Migrate brings every tenant schema to the latest tenant migration, each
+// in a transaction of its own. Instances starting together each lock a
+// schema's row before migrating it, so a migration is applied once.
+func (s *Service) Migrate(ctx context.Context) error {
+	var behind []string
+	err := s.db.SelectContext(ctx, &behind, `SELECT organization_id FROM tenant_schemas WHERE version < $1`, s.Version())
+	if err != nil {
+		return fmt.Errorf("failed to list tenant schemas: %w", err)
+	}
+
+	var errs []error
+	for _, orgID := range behind {
+		if err := s.migrateOne(ctx, orgID); err != nil {
+			s.logger.Error("Failed to migrate tenant schema", zap.String("organization_id", orgID), zap.Error(err))
+			errs = append(errs, fmt.Errorf("organization %s: %w", orgID, err))
+		}
+	}
+	if len(behind) > 0 {
+		s.logger.Info("Migrated tenant schemas", zap.Int("schemas", len(behind)-len(errs)), zap.Int("version", s.Version()))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) migrateOne(ctx context.Context, orgID string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var schema Schema
+	err = tx.GetContext(ctx, &schema, `SELECT `+schemaColumns+` FROM tenant_schemas WHERE organization_id = $1 FOR UPDATE`, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Removed since it was listed
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if schema.Version >= s.Version() {
+		// Migrated by another instance
+		return nil
+	}
+
+	if err := s.apply(ctx, tx, schema.SchemaName, schema.Version); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE tenant_schemas SET version = $2, updated_at = NOW() WHERE organization_id = $1`, orgID, s.Version())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// apply runs the migrations after version with the schema first on the
+// search_path, then restores the search_path for the rest of the transaction
+func (s *Service) apply(ctx context.Context, q repository.Queryer, schema string, version int) error {
+	if len(s.migrations) == 0 {
+		return errors.New("no tenant migrations are loaded")
+	}
+	var searchPath string
+	if err := q.GetContext(ctx, &searchPath, `SELECT current_setting('search_path')`); err != nil {
+		return fmt.Errorf("failed to read search_path: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `SELECT set_config('search_path', $1, true)`, pq.QuoteIdentifier(schema)+", public"); err != nil {
+		return fmt.Errorf("failed to set search_path: %w", err)
+	}
+
+	for _, m := range s.migrations {
+		if m.Version <= version {
+			continue
+		}
+		if _, err := q.ExecContext(ctx, m.SQL); err != nil {
+			return fmt.Errorf("tenant migration %s failed on %s: %w", m.Name, schema, err)
+		}
+	}
+
+	if _, err := q.ExecContext(ctx, `SELECT set_config('search_path', $1, true)`, searchPath); err != nil {
+		return fmt.Errorf("failed to restore search_path: %w", err)
+	}
+	return nil
+}
+
+// lockOrganization takes the row lock that scan result writes share, so
+// evidence is never written to the shared tables while it is being moved
+func lockOrganization(ctx context.Context, q repository.Queryer, orgID string) error {
+	var id string
+	err := q.GetContext(ctx, &id, `SELECT id FROM organizations WHERE id = $1 FOR UPDATE`, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrOrgNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock organization: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+//go:build integration
+
+package tenancy_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/repository"
+	"github.com/cyper-security/gateway/internal/tasks"
+	"github.com/cyper-security/gateway/internal/tenancy"
+	"github.com/cyper-security/gateway/internal/testenv"
+)
+
+func loadMigrations(t *testing.T) []tenancy.Migration {
+	t.Helper()
+	dir, err := testenv.SQLDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := tenancy.LoadMigrations(filepath.Join(dir, "tenant_migrations"))
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	return migrations
+}
+
+func newService(env *testenv.Env, migrations []tenancy.Migration) *tenancy.Service {
+	return tenancy.NewService(env.DB, tasks.NewService(env.DB, tasks.DefaultConfig(), env.Logger), migrations, env.Logger)
+}
+
+// inTx runs a move in a transaction, committed when it succeeds
+func inTx(t *testing.T, env *testenv.Env, move func(context.Context, repository.Queryer, string) error, orgID string) error {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := env.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := move(ctx, tx, orgID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// createResult stores scan evidence for an organization in the shared tables
+func createResult(t *testing.T, env *testenv.Env, orgID string) string {
+	t.Helper()
+	user := env.CreateUser(t, testenv.UserOptions{OrgID: orgID})
+	scanID := env.CreateScan(t, user.ID, orgID)
+
+	var id string
+	err := env.DB.GetContext(context.Background(), &id, `
+		INSERT INTO scan_results (scan_job_id, organization_id, result_type, raw_data)
+		VALUES ($1, $2, 'web', '{"headers":{"server":"nginx"}}')
+		RETURNING id
+	`, scanID, orgID)
+	if err != nil {
+		t.Fatalf("create scan result: %v", err)
+	}
+	return id
+}
+
+func schemaExists(t *testing.T, env *testenv.Env, schema string) bool {
+	t.Helper()
+	var exists bool
+	err := env.DB.GetContext(context.Background(), &exists, `SELECT EXISTS (SELECT 1 FROM information_schema.schemata WHERE schema_name = $1)`, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func TestProvisionAndDeprovision(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	svc := newService(env, loadMigrations(t))
+	org := env.CreateOrganization(t, "")
+	resultID := createResult(t, env, org.ID)
+	schema := tenancy.SchemaName(org.ID)
+
+	if err := inTx(t, env, svc.Provision, org.ID); err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+	if !schemaExists(t, env, schema) {
+		t.Fatalf("schema %s was not created", schema)
+	}
+	got, err := svc.Get(ctx, org.ID)
+	if err != nil || got == nil {
+		t.Fatalf("Get = %v, %v", got, err)
+	}
+	if got.SchemaName != schema || got.Version != svc.Version() {
+		t.Errorf("tenant schema = %+v, want %s at version %d", got, schema, svc.Version())
+	}
+
+	// The evidence moved out of the shared table
+	var moved struct {
+		Shared *string `db:"shared"`
+		Tenant *string `db:"tenant"`
+	}
+	loadEvidence := `
+		SELECT sr.raw_data::text AS shared, d.raw_data::text AS tenant
+		FROM scan_results sr
+		LEFT JOIN ` + schema + `.scan_result_data d ON d.scan_result_id = sr.id
+		WHERE sr.id = $1
+	`
+	if err := env.DB.GetContext(ctx, &moved, loadEvidence, resultID); err != nil {
+		t.Fatal(err)
+	}
+	if moved.Shared != nil || moved.Tenant == nil {
+		t.Errorf("after Provision shared = %v, tenant = %v; want the evidence in the tenant schema only", moved.Shared, moved.Tenant)
+	}
+
+	if err := inTx(t, env, svc.Provision, org.ID); !errors.Is(err, tenancy.ErrIsolated) {
+		t.Errorf("second Provision = %v, want ErrIsolated", err)
+	}
+
+	if err := inTx(t, env, svc.Deprovision, org.ID); err != nil {
+		t.Fatalf("Deprovision: %v", err)
+	}
+	if schemaExists(t, env, schema) {
+		t.Errorf("schema %s was not dropped", schema)
+	}
+	if got, err := svc.Get(ctx, org.ID); err != nil || got != nil {
+		t.Errorf("Get after Deprovision = %v, %v; want no schema", got, err)
+	}
+	var shared *string
+	if err := env.DB.GetContext(ctx, &shared, `SELECT raw_data::text FROM scan_results WHERE id = $1`, resultID); err != nil {
+		t.Fatal(err)
+	}
+	if shared == nil || moved.Tenant == nil || *shared != *moved.Tenant {
+		t.Errorf("evidence after Deprovision = %v, want %v", shared, moved.Tenant)
+	}
+
+	if err := inTx(t, env, svc.Deprovision, org.ID); !errors.Is(err, tenancy.ErrNotIsolated) {
+		t.Errorf("second Deprovision = %v, want ErrNotIsolated", err)
+	}
+}
+
+func TestProvisionRollsBack(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	svc := newService(env, loadMigrations(t))
+	org := env.CreateOrganization(t, "")
+	resultID := createResult(t, env, org.ID)
+
+	tx, err := env.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Provision(ctx, tx, org.ID); err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if schemaExists(t, env, tenancy.SchemaName(org.ID)) {
+		t.Error("schema survived the rollback")
+	}
+	var hasEvidence bool
+	if err := env.DB.GetContext(ctx, &hasEvidence, `SELECT raw_data IS NOT NULL FROM scan_results WHERE id = $1`, resultID); err != nil {
+		t.Fatal(err)
+	}
+	if !hasEvidence {
+		t.Error("shared evidence was cleared by a rolled back Provision")
+	}
+}
+
+func TestMigrateTenantSchemas(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	migrations := loadMigrations(t)
+	org := env.CreateOrganization(t, "")
+	if err := inTx(t, env, newService(env, migrations).Provision, org.ID); err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+
+	// A release adding a tenant migration brings existing schemas up to it
+	next := tenancy.Migration{
+		Version: migrations[len(migrations)-1].Version + 1,
+		Name:    "999_add_checksum.sql",
+		SQL:     `ALTER TABLE scan_result_data ADD COLUMN checksum TEXT`,
+	}
+	svc := newService(env, append(migrations, next))
+	if err := svc.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	got, err := svc.Get(ctx, org.ID)
+	if err != nil || got == nil {
+		t.Fatalf("Get = %v, %v", got, err)
+	}
+	if got.Version != next.Version {
+		t.Errorf("version = %d, want %d", got.Version, next.Version)
+	}
+	var column struct {
+		Tenant bool `db:"tenant"`
+		Public bool `db:"public"`
+	}
+	err = env.DB.GetContext(ctx, &column, `
+		SELECT
+			EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = $1 AND table_name = 'scan_result_data' AND column_name = 'checksum') AS tenant,
+			EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = 'public' AND column_name = 'checksum') AS public
+	`, got.SchemaName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !column.Tenant || column.Public {
+		t.Errorf("checksum column in tenant schema = %v, in public = %v", column.Tenant, column.Public)
+	}
+
+	// Schemas already at the latest version are left alone
+	if err := svc.Migrate(ctx); err != nil {
+		t.Errorf("second Migrate: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package tenantkeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/awssig"
+)
+
+// KMSConfig names the AWS KMS key data keys are wrapped with. Credentials
+// are static keys, as exported into the environment by the task role or a
+// sidecar.
+type KMSConfig struct {
+	Region          string
+	KeyID           string // Key ID, ARN or alias
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Endpoint        string            // Overrides https://kms.<region>.amazonaws.com
+	Transport       http.RoundTripper // Optional, e.g. under an outbound policy
+}
+
+// KMSMasterKeys wraps data keys with the KMS Encrypt and Decrypt APIs, bound
+// to their organization by the encryption context. Rotating the KMS key's
+// material needs nothing from the gateway; moving to another key does, by
+// changing KeyID and letting the rotation job re-wrap.
+type KMSMasterKeys struct {
+	config     KMSConfig
+	signer     awssig.Signer
+	httpClient *http.Client
+}
+
+var _ MasterKeys = (*KMSMasterKeys)(nil)
+
+func NewKMSMasterKeys(config KMSConfig) *KMSMasterKeys {
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", config.Region)
+	}
+	return &KMSMasterKeys{
+		config: config,
+		signer: awssig.Signer{
+			Dialect:         awssig.AWS,
+			Region:          config.Region,
+			Service:         "kms",
+			AccessKeyID:     config.AccessKeyID,
+			SecretAccessKey: config.SecretAccessKey,
+			SessionToken:    config.SessionToken,
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: config.Transport},
+	}
+}
+
+func (k *KMSMasterKeys) CurrentID() string {
+	return k.config.KeyID
+}
+
+func (k *KMSMasterKeys) Wrap(ctx context.Context, orgID string, dataKey []byte) ([]byte, error) {
+	var out struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+	}
+	err := k.call(ctx, "TrentService.Encrypt", map[string]interface{}{
+		"KeyId":             k.config.KeyID,
+		"Plaintext":         dataKey,
+		"EncryptionContext": map[string]string{"organization_id": orgID},
+	}, &out)
+	return out.CiphertextBlob, err
+}
+
+func (k *KMSMasterKeys) Unwrap(ctx context.Context, keyID, orgID string, wrapped []byte) ([]byte, error) {
+	var out struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	err := k.call(ctx, "TrentService.Decrypt", map[string]interface{}{
+		"KeyId":             keyID,
+		"CiphertextBlob":    wrapped,
+		"EncryptionContext": map[string]string{"organization_id": orgID},
+	}, &out)
+	return out.Plaintext, err
+}
+
+func (k *KMSMasterKeys) call(ctx context.Context, target string, in, out interface{}) error {
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.config.Endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	k.signer.Sign(req, payload, time.Now().UTC())
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach KMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read KMS response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		return fmt.Errorf("KMS returned status %d: %s %s", resp.StatusCode, apiErr.Type, apiErr.Message)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode KMS response: %w", err)
+	}
+	return nil
+}
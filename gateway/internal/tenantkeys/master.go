@@ -0,0 +1,96 @@
+package tenantkeys
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MasterKeys wraps and unwraps organizations' data keys. Wrap always uses the
+// current master key; Unwrap takes the ID a data key was wrapped under, so
+// data keys wrapped by a rotated-out master key stay readable until the
+// rotation job re-wraps them.
+type MasterKeys interface {
+	CurrentID() string
+	Wrap(ctx context.Context, orgID string, dataKey []byte) ([]byte, error)
+	Unwrap(ctx context.Context, keyID, orgID string, wrapped []byte) ([]byte, error)
+}
+
+// ErrUnknownMasterKey is returned for data keys wrapped by a master key that
+// is no longer configured
+var ErrUnknownMasterKey = errors.New("unknown master key")
+
+// LocalMasterKeys wraps data keys with AES-256-GCM master keys held in the
+// gateway's configuration, for deployments without a KMS
+type LocalMasterKeys struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+var _ MasterKeys = (*LocalMasterKeys)(nil)
+
+// NewLocalMasterKeys takes 32-byte keys by ID; current names the one new data
+// keys are wrapped with, the others are kept to unwrap older data keys
+func NewLocalMasterKeys(current string, keys map[string][]byte) (*LocalMasterKeys, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("master key %q is not configured", current)
+	}
+
+	m := &LocalMasterKeys{current: current, keys: map[string]cipher.AEAD{}}
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("master key %q must be 32 bytes, got %d", id, len(key))
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		m.keys[id] = aead
+	}
+	return m, nil
+}
+
+func (m *LocalMasterKeys) CurrentID() string {
+	return m.current
+}
+
+func (m *LocalMasterKeys) Wrap(ctx context.Context, orgID string, dataKey []byte) ([]byte, error) {
+	return seal(m.keys[m.current], dataKey, []byte(m.current+"/"+orgID))
+}
+
+func (m *LocalMasterKeys) Unwrap(ctx context.Context, keyID, orgID string, wrapped []byte) ([]byte, error) {
+	aead, ok := m.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownMasterKey, keyID)
+	}
+	return open(aead, wrapped, []byte(keyID+"/"+orgID))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext, prefixing the random nonce
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
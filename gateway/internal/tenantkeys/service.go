@@ -0,0 +1,369 @@
+// Package tenantkeys encrypts sensitive columns per organization with
+// envelope encryption: every organization has its own AES-256 data keys,
+// stored wrapped by a master key (a KMS key or one held in configuration),
+// and values are sealed with the organization's newest data key. Sealed
+// values carry the data key version, so replacing a data key leaves older
+// values readable, and re-wrapping data keys under a new master key never
+// touches the values themselves.
+package tenantkeys
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// sealedPrefix marks encrypted values; the data key version and the
+// base64 nonce and ciphertext follow
+const sealedPrefix = "enc:v1:"
+
+// currentTTL is how long an organization's newest data key version is cached
+// before checking whether another instance replaced it
+const currentTTL = 5 * time.Minute
+
+// ErrMalformed is returned for values that look encrypted but cannot be parsed
+var ErrMalformed = errors.New("malformed encrypted value")
+
+// Config tunes data key rotation
+type Config struct {
+	// Interval is how often data keys are checked for re-wrapping and
+	// replacement
+	Interval time.Duration
+	// MaxAge replaces an organization's data key with a new version once it
+	// is this old; zero keeps data keys until the master key changes
+	MaxAge time.Duration
+	// BatchSize bounds the data keys handled per pass
+	BatchSize int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Interval:  time.Hour,
+		BatchSize: 100,
+	}
+}
+
+type keyID struct {
+	orgID   string
+	version int
+}
+
+type currentKey struct {
+	version int
+	expires time.Time
+}
+
+// Service seals and opens column values with organizations' data keys and
+// rotates the data keys in the background
+type Service struct {
+	db     *database.DB
+	master MasterKeys
+	config Config
+	logger *zap.Logger
+
+	mu      sync.RWMutex
+	keys    map[keyID]cipher.AEAD // Unwrapped data keys
+	current map[string]currentKey // Organization to its newest version
+}
+
+func NewService(db *database.DB, master MasterKeys, config Config, logger *zap.Logger) *Service {
+	return &Service{
+		db:      db,
+		master:  master,
+		config:  config,
+		logger:  logger,
+		keys:    map[keyID]cipher.AEAD{},
+		current: map[string]currentKey{},
+	}
+}
+
+// IsEncrypted reports whether value was sealed by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// Encrypt seals plaintext with the organization's newest data key, creating
+// the organization's first one if needed. Empty values stay empty.
+func (s *Service) Encrypt(ctx context.Context, orgID, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	version, aead, err := s.currentKey(ctx, orgID)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(plaintext), []byte(orgID))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + strconv.Itoa(version) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt for the same organization. Values
+// that are not encrypted, such as rows written before encryption was
+// enabled, are returned unchanged.
+func (s *Service) Decrypt(ctx context.Context, orgID, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	versionPart, encoded, ok := strings.Cut(strings.TrimPrefix(value, sealedPrefix), ":")
+	version, err := strconv.Atoi(versionPart)
+	if !ok || err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	aead, err := s.key(ctx, orgID, version)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealed, []byte(orgID))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value for organization %s: %w", orgID, err)
+	}
+	return string(plaintext), nil
+}
+
+// currentKey returns the organization's newest data key
+func (s *Service) currentKey(ctx context.Context, orgID string) (int, cipher.AEAD, error) {
+	s.mu.RLock()
+	current, ok := s.current[orgID]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(current.expires) {
+		aead, err := s.key(ctx, orgID, current.version)
+		return current.version, aead, err
+	}
+
+	var version int
+	err := s.db.GetContext(ctx, &version, `
+		SELECT COALESCE(MAX(version), 0) FROM organization_data_keys WHERE organization_id = $1
+	`, orgID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to load data key: %w", err)
+	}
+	if version == 0 {
+		if version, err = s.createKey(ctx, orgID, 1); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	s.mu.Lock()
+	s.current[orgID] = currentKey{version: version, expires: time.Now().Add(currentTTL)}
+	s.mu.Unlock()
+
+	aead, err := s.key(ctx, orgID, version)
+	return version, aead, err
+}
+
+// key returns one version of the organization's data key, unwrapping it on
+// first use
+func (s *Service) key(ctx context.Context, orgID string, version int) (cipher.AEAD, error) {
+	id := keyID{orgID: orgID, version: version}
+	s.mu.RLock()
+	aead, ok := s.keys[id]
+	s.mu.RUnlock()
+	if ok {
+		return aead, nil
+	}
+
+	var row struct {
+		WrappedKey  []byte `db:"wrapped_key"`
+		MasterKeyID string `db:"master_key_id"`
+	}
+	err := s.db.GetContext(ctx, &row, `
+		SELECT wrapped_key, master_key_id FROM organization_data_keys
+		WHERE organization_id = $1 AND version = $2
+	`, orgID, version)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("data key version %d of organization %s not found", version, orgID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data key: %w", err)
+	}
+
+	dataKey, err := s.master.Unwrap(ctx, row.MasterKeyID, orgID, row.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	aead, err = newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys[id] = aead
+	s.mu.Unlock()
+	return aead, nil
+}
+
+// createKey stores a new data key version for the organization and returns
+// the newest version, which is another instance's when it created one first
+func (s *Service) createKey(ctx context.Context, orgID string, version int) (int, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return 0, err
+	}
+	wrapped, err := s.master.Wrap(ctx, orgID, dataKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO organization_data_keys (organization_id, version, wrapped_key, master_key_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, version) DO NOTHING
+	`, orgID, version, wrapped, s.master.CurrentID())
+	if err != nil {
+		return 0, fmt.Errorf("failed to store data key: %w", err)
+	}
+
+	var newest int
+	err = s.db.GetContext(ctx, &newest, `
+		SELECT MAX(version) FROM organization_data_keys WHERE organization_id = $1
+	`, orgID)
+	return newest, err
+}
+
+// Start rotates data keys every Interval until ctx is cancelled
+func (s *Service) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting data key rotation",
+		zap.Duration("interval", s.config.Interval),
+		zap.String("master_key_id", s.master.CurrentID()),
+	)
+
+	for {
+		s.rotate(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Service) rotate(ctx context.Context) {
+	if n, err := s.rewrap(ctx); err != nil {
+		s.logger.Error("Failed to re-wrap data keys", zap.Error(err))
+	} else if n > 0 {
+		s.logger.Info("Re-wrapped data keys under the current master key", zap.Int("count", n))
+	}
+
+	if s.config.MaxAge <= 0 {
+		return
+	}
+	if n, err := s.replaceExpired(ctx); err != nil {
+		s.logger.Error("Failed to replace expired data keys", zap.Error(err))
+	} else if n > 0 {
+		s.logger.Info("Replaced expired data keys", zap.Int("count", n))
+	}
+}
+
+// rewrap moves data keys wrapped by an older master key to the current one
+func (s *Service) rewrap(ctx context.Context) (int, error) {
+	current := s.master.CurrentID()
+	var stale []struct {
+		OrganizationID string `db:"organization_id"`
+		Version        int    `db:"version"`
+		WrappedKey     []byte `db:"wrapped_key"`
+		MasterKeyID    string `db:"master_key_id"`
+	}
+	err := s.db.SelectContext(ctx, &stale, `
+		SELECT organization_id, version, wrapped_key, master_key_id
+		FROM organization_data_keys
+		WHERE master_key_id <> $1
+		ORDER BY created_at
+		LIMIT $2
+	`, current, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	rewrapped := 0
+	for _, k := range stale {
+		dataKey, err := s.master.Unwrap(ctx, k.MasterKeyID, k.OrganizationID, k.WrappedKey)
+		if err != nil {
+			metrics.DataKeyRotations.WithLabelValues("failed").Inc()
+			s.logger.Error("Failed to unwrap data key for re-wrapping",
+				zap.String("organization_id", k.OrganizationID),
+				zap.Int("version", k.Version),
+				zap.String("master_key_id", k.MasterKeyID),
+				zap.Error(err),
+			)
+			continue
+		}
+		wrapped, err := s.master.Wrap(ctx, k.OrganizationID, dataKey)
+		if err != nil {
+			return rewrapped, fmt.Errorf("failed to wrap data key: %w", err)
+		}
+
+		// Another instance may have re-wrapped it meanwhile
+		res, err := s.db.ExecContext(ctx, `
+			UPDATE organization_data_keys
+			SET wrapped_key = $1, master_key_id = $2, rewrapped_at = NOW()
+			WHERE organization_id = $3 AND version = $4 AND master_key_id = $5
+		`, wrapped, current, k.OrganizationID, k.Version, k.MasterKeyID)
+		if err != nil {
+			return rewrapped, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			rewrapped++
+			metrics.DataKeyRotations.WithLabelValues("rewrapped").Inc()
+		}
+	}
+	return rewrapped, nil
+}
+
+// replaceExpired gives organizations whose newest data key is older than
+// MaxAge a new version; values sealed earlier keep their version
+func (s *Service) replaceExpired(ctx context.Context) (int, error) {
+	var expired []struct {
+		OrganizationID string `db:"organization_id"`
+		Version        int    `db:"version"`
+	}
+	err := s.db.SelectContext(ctx, &expired, `
+		SELECT organization_id, MAX(version) AS version
+		FROM organization_data_keys
+		GROUP BY organization_id
+		HAVING MAX(created_at) < NOW() - make_interval(secs => $1)
+		LIMIT $2
+	`, s.config.MaxAge.Seconds(), s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	replaced := 0
+	for _, k := range expired {
+		version, err := s.createKey(ctx, k.OrganizationID, k.Version+1)
+		if err != nil {
+			return replaced, err
+		}
+		s.mu.Lock()
+		s.current[k.OrganizationID] = currentKey{version: version, expires: time.Now().Add(currentTTL)}
+		s.mu.Unlock()
+		replaced++
+		metrics.DataKeyRotations.WithLabelValues("replaced").Inc()
+	}
+	return replaced, nil
+}
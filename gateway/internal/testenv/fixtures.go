@@ -0,0 +1,163 @@
+//go:build integration
+
+package testenv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/auth"
+	"github.com/cyper-security/gateway/internal/rbac"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultPassword is used for fixture users created without one
+const DefaultPassword = "Correct-Horse-Battery-9"
+
+// UserOptions customises CreateUser; zero values get unique defaults
+type UserOptions struct {
+	Email    string
+	Password string
+	Role     string // users.role, "analyst" by default
+	OrgID    string // primary organization, none by default
+	// WithoutTerms leaves the current terms unaccepted so Login is refused
+	WithoutTerms bool
+}
+
+// User is a fixture user with its plaintext password
+type User struct {
+	ID       string
+	Email    string
+	Password string
+}
+
+// Organization is a fixture organization
+type Organization struct {
+	ID   string
+	Name string
+}
+
+// CreateUser inserts an active user who has accepted the current terms
+func (e *Env) CreateUser(tb testing.TB, opts UserOptions) User {
+	tb.Helper()
+
+	suffix := uuid.New().String()[:8]
+	if opts.Email == "" {
+		opts.Email = "user-" + suffix + "@example.com"
+	}
+	if opts.Password == "" {
+		opts.Password = DefaultPassword
+	}
+	if opts.Role == "" {
+		opts.Role = "analyst"
+	}
+
+	// MinCost keeps fixtures fast; Login only compares hashes
+	hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.MinCost)
+	if err != nil {
+		tb.Fatalf("testenv: hash password: %v", err)
+	}
+
+	var orgID interface{}
+	if opts.OrgID != "" {
+		orgID = opts.OrgID
+	}
+
+	var id string
+	err = e.DB.GetContext(context.Background(), &id, `
+		INSERT INTO users (email, username, password_hash, role, organization_id, terms_accepted_at, terms_version)
+		SELECT $1, $2, $3, $4, $5,
+			CASE WHEN $6::boolean THEN NULL ELSE NOW() END,
+			CASE WHEN $6::boolean THEN NULL ELSE (SELECT version FROM terms_versions ORDER BY effective_at DESC LIMIT 1) END
+		RETURNING id
+	`, opts.Email, "user_"+suffix, string(hash), opts.Role, orgID, opts.WithoutTerms)
+	if err != nil {
+		tb.Fatalf("testenv: create user: %v", err)
+	}
+
+	return User{ID: id, Email: opts.Email, Password: opts.Password}
+}
+
+// CreateOrganization inserts an organization on the free tier
+func (e *Env) CreateOrganization(tb testing.TB, name string) Organization {
+	tb.Helper()
+
+	if name == "" {
+		name = "org-" + uuid.New().String()[:8]
+	}
+
+	var id string
+	err := e.DB.GetContext(context.Background(), &id, `
+		INSERT INTO organizations (name, subscription_tier)
+		VALUES ($1, 'free')
+		RETURNING id
+	`, name)
+	if err != nil {
+		tb.Fatalf("testenv: create organization: %v", err)
+	}
+
+	return Organization{ID: id, Name: name}
+}
+
+// AddMember gives a user a role in an organization
+func (e *Env) AddMember(tb testing.TB, userID, orgID string, role rbac.Role) {
+	tb.Helper()
+
+	_, err := e.DB.ExecContext(context.Background(), `
+		INSERT INTO organization_memberships (user_id, organization_id, role)
+		VALUES ($1, $2, $3)
+	`, userID, orgID, string(role))
+	if err != nil {
+		tb.Fatalf("testenv: add member: %v", err)
+	}
+}
+
+// Login signs a user in through AuthService, creating a session
+func (e *Env) Login(tb testing.TB, user User) *auth.LoginResponse {
+	tb.Helper()
+
+	resp, err := e.Auth.Login(context.Background(), auth.LoginRequest{
+		Email:    user.Email,
+		Password: user.Password,
+	}, "127.0.0.1", "testenv")
+	if err != nil {
+		tb.Fatalf("testenv: login %s: %v", user.Email, err)
+	}
+	return resp
+}
+
+// Claims validates an access token as AuthMiddleware would
+func (e *Env) Claims(tb testing.TB, accessToken string) *auth.Claims {
+	tb.Helper()
+
+	claims, err := e.Auth.ValidateToken(accessToken)
+	if err != nil {
+		tb.Fatalf("testenv: validate token: %v", err)
+	}
+	return claims
+}
+
+// CreateScan inserts a completed scan of a new domain target by a user, in
+// an organization or, with orgID empty, outside any
+func (e *Env) CreateScan(tb testing.TB, userID, orgID string) string {
+	tb.Helper()
+
+	var id string
+	err := e.DB.GetContext(context.Background(), &id, `
+		WITH target AS (
+			INSERT INTO scan_targets (target_type, target_value)
+			VALUES ('domain', $3)
+			RETURNING id
+		)
+		INSERT INTO scan_jobs (user_id, organization_id, target_id, scan_type, scan_mode, status,
+			requires_authorization, started_at, completed_at, progress_percentage)
+		SELECT $1, NULLIF($2, '')::uuid, target.id, 'web', 'passive', 'completed', false, NOW(), NOW(), 100
+		FROM target
+		RETURNING id
+	`, userID, orgID, "scan-"+uuid.New().String()[:8]+".example.com")
+	if err != nil {
+		tb.Fatalf("testenv: create scan: %v", err)
+	}
+	return id
+}
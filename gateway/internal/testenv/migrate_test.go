@@ -0,0 +1,210 @@
+//go:build integration
+
+package testenv
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) { Main(m) }
+
+// freshDatabase creates an empty database on the environment's server,
+// dropped when the test ends
+func freshDatabase(t *testing.T, env *Env) *database.DB {
+	t.Helper()
+
+	name := "migrate_" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+	if _, err := env.DB.ExecContext(context.Background(), "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+
+	// TEST_DATABASE_DSN may be a URL or key=value pairs, where the last
+	// dbname wins
+	dsn := env.dsn + " dbname=" + name
+	if u, err := url.Parse(env.dsn); err == nil && u.Scheme != "" {
+		u.Path = "/" + name
+		dsn = u.String()
+	}
+	conn, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("connect to %s: %v", name, err)
+	}
+	db := database.New(conn, database.DefaultConfig(), zap.NewNop())
+
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := env.DB.ExecContext(context.Background(), "DROP DATABASE "+name+" WITH (FORCE)"); err != nil {
+			t.Errorf("drop database: %v", err)
+		}
+	})
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	env := Setup(t)
+	db := freshDatabase(t, env)
+	ctx := context.Background()
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	// Tables from the schema, an early and the latest migrations
+	for _, table := range []string{"users", "organization_memberships", "terms_versions", "tasks", "tenant_schemas", "security_alerts"} {
+		var exists bool
+		if err := db.GetContext(ctx, &exists, `SELECT to_regclass($1) IS NOT NULL`, table); err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("table %s is missing after Migrate", table)
+		}
+	}
+	var terms int
+	if err := db.GetContext(ctx, &terms, `SELECT COUNT(*) FROM terms_versions`); err != nil {
+		t.Fatal(err)
+	}
+	if terms == 0 {
+		t.Error("the terms versions seeded by migrations are missing")
+	}
+
+	// The environment's own database was migrated the same way
+	var tables, envTables int
+	countTables := `SELECT COUNT(*) FROM pg_tables WHERE schemaname = 'public'`
+	if err := db.GetContext(ctx, &tables, countTables); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.DB.GetContext(ctx, &envTables, countTables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != envTables {
+		t.Errorf("fresh database has %d tables, the environment's %d", tables, envTables)
+	}
+}
+
+func TestMigrateOrderAndFailure(t *testing.T) {
+	env := Setup(t)
+	db := freshDatabase(t, env)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"schema.sql": `CREATE TABLE widgets (id SERIAL PRIMARY KEY);`,
+		// 010 sorts after 002 and depends on it
+		"migrations/002_add_name.sql":    `ALTER TABLE widgets ADD COLUMN name TEXT;`,
+		"migrations/010_add_index.sql":   `CREATE INDEX idx_widgets_name ON widgets (name);`,
+		"migrations/011_broken.sql":      `ALTER TABLE missing ADD COLUMN x INT;`,
+		"migrations/012_never_run.sql":   `CREATE TABLE never_run (id INT);`,
+		"migrations/README.md":           `not a migration`,
+		"migrations/003_add_counter.sql": `ALTER TABLE widgets ADD COLUMN counter INT DEFAULT 0;`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("TEST_SQL_DIR", dir)
+
+	err := Migrate(context.Background(), db)
+	if err == nil || !strings.Contains(err.Error(), "011_broken.sql") {
+		t.Fatalf("Migrate = %v, want the failure of 011_broken.sql", err)
+	}
+
+	// Everything before the failure was applied, nothing after it
+	var applied struct {
+		Index    bool `db:"idx"`
+		Counter  bool `db:"counter"`
+		NeverRun bool `db:"never_run"`
+	}
+	err = db.GetContext(context.Background(), &applied, `
+		SELECT to_regclass('idx_widgets_name') IS NOT NULL AS idx,
+			EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'widgets' AND column_name = 'counter') AS counter,
+			to_regclass('never_run') IS NOT NULL AS never_run
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applied.Index || !applied.Counter {
+		t.Errorf("migrations before the failure were not applied: %+v", applied)
+	}
+	if applied.NeverRun {
+		t.Error("a migration after the failure was applied")
+	}
+}
+
+// The queue wait metric measures from queued_at, which tracks when a scan
+// last became dispatchable rather than when it was created
+func TestScanQueuedAt(t *testing.T) {
+	env := Setup(t)
+	ctx := context.Background()
+	user := env.CreateUser(t, UserOptions{})
+
+	var id string
+	err := env.DB.GetContext(ctx, &id, `
+		WITH target AS (
+			INSERT INTO scan_targets (target_type, target_value) VALUES ('domain', 'queued.example.com')
+			RETURNING id
+		)
+		INSERT INTO scan_jobs (user_id, target_id, scan_type, scan_mode, status, requires_authorization, created_at)
+		SELECT $1, target.id, 'web', 'passive', 'pending_approval', false, NOW() - INTERVAL '1 hour'
+		FROM target
+		RETURNING id
+	`, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// queuedFor returns how long ago the scan was queued, or -1 when it is not
+	queuedFor := func() float64 {
+		t.Helper()
+		var seconds float64
+		err := env.DB.GetContext(ctx, &seconds, `
+			SELECT COALESCE(EXTRACT(EPOCH FROM NOW() - queued_at), -1) FROM scan_jobs WHERE id = $1
+		`, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return seconds
+	}
+	setStatus := func(status string) {
+		t.Helper()
+		if _, err := env.DB.ExecContext(ctx, `UPDATE scan_jobs SET status = $2 WHERE id = $1`, id, status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := queuedFor(); got != -1 {
+		t.Fatalf("scan awaiting approval was queued %.0fs ago", got)
+	}
+
+	setStatus("pending")
+	if got := queuedFor(); got < 0 || got > 60 {
+		t.Fatalf("approved scan queued %.0fs ago, want just now rather than at creation", got)
+	}
+
+	// Re-queued after running, e.g. when its worker was lost
+	if _, err := env.DB.ExecContext(ctx, `UPDATE scan_jobs SET queued_at = NOW() - INTERVAL '1 hour' WHERE id = $1`, id); err != nil {
+		t.Fatal(err)
+	}
+	setStatus("pending")
+	if got := queuedFor(); got < 3000 {
+		t.Fatalf("a status update that left the scan pending re-queued it")
+	}
+	setStatus("running")
+	setStatus("pending")
+	if got := queuedFor(); got < 0 || got > 60 {
+		t.Fatalf("re-queued scan queued %.0fs ago, want just now", got)
+	}
+}
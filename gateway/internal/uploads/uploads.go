@@ -0,0 +1,388 @@
+// Package uploads accepts user-supplied files, such as organization logos
+// and finding evidence, into the artifact store. Every upload is checked
+// against a size limit and a list of content types sniffed from its bytes,
+// then passed through a content scanner (ClamAV in production). Infected
+// uploads are kept under a quarantine prefix for review instead of their
+// destination key, and every upload's verdict is recorded in
+// uploaded_artifacts.
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/cyper-security/gateway/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// What an upload is for
+const (
+	KindLogo     = "logo"
+	KindEvidence = "evidence"
+)
+
+// Scan verdicts
+const (
+	VerdictClean    = "clean"
+	VerdictInfected = "infected"
+	// VerdictUnscanned: no scanner is configured, or it failed and the
+	// service fails open
+	VerdictUnscanned = "unscanned"
+)
+
+var (
+	ErrTooLarge        = errors.New("upload is too large")
+	ErrUnsupportedType = errors.New("upload type is not allowed")
+	// ErrQuarantined matches the QuarantineError returned when the scanner
+	// found malware
+	ErrQuarantined = errors.New("upload was quarantined by the content scanner")
+	// ErrScanUnavailable is returned when the scanner failed and the service
+	// fails closed
+	ErrScanUnavailable = errors.New("content scanner is unavailable")
+)
+
+// MaxEvidenceBytes bounds a finding evidence file
+const MaxEvidenceBytes = 25 << 20
+
+// EvidencePolicy accepts screenshots, PDFs, text (logs, requests, JSON) and
+// zip archives, e.g. of packet captures
+var EvidencePolicy = Policy{
+	MaxBytes: MaxEvidenceBytes,
+	Types: map[string]string{
+		"image/png":       "png",
+		"image/jpeg":      "jpg",
+		"image/gif":       "gif",
+		"image/webp":      "webp",
+		"application/pdf": "pdf",
+		"text/plain":      "txt",
+		"application/zip": "zip",
+	},
+}
+
+// QuarantineError is returned by Accept for an infected upload
+type QuarantineError struct {
+	Artifact *Artifact
+}
+
+func (e *QuarantineError) Error() string {
+	return ErrQuarantined.Error()
+}
+
+func (e *QuarantineError) Is(target error) bool {
+	return target == ErrQuarantined
+}
+
+// Policy is what an upload must satisfy
+type Policy struct {
+	MaxBytes int64
+	// Types maps accepted content types, as sniffed from the upload, to the
+	// file extension it is stored with
+	Types map[string]string
+}
+
+// ScanResult is a scanner's verdict on an upload
+type ScanResult struct {
+	Infected  bool
+	Signature string // Malware name when infected
+}
+
+// Scanner inspects upload content
+type Scanner interface {
+	// Name identifies the scanner in artifact metadata
+	Name() string
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// Artifact is the metadata of an upload, including its scan verdict
+type Artifact struct {
+	ID             string     `json:"id" db:"id"`
+	OrganizationID string     `json:"organization_id" db:"organization_id"`
+	Kind           string     `json:"kind" db:"kind"`
+	FindingID      *string    `json:"finding_id,omitempty" db:"finding_id"`
+	Filename       string     `json:"filename" db:"filename"`
+	ContentType    string     `json:"content_type" db:"content_type"`
+	SizeBytes      int64      `json:"size_bytes" db:"size_bytes"`
+	SHA256         string     `json:"sha256" db:"sha256"`
+	StorageKey     string     `json:"-" db:"storage_key"`
+	ScanVerdict    string     `json:"scan_verdict" db:"scan_verdict"`
+	ScanSignature  *string    `json:"scan_signature,omitempty" db:"scan_signature"`
+	Scanner        *string    `json:"scanner,omitempty" db:"scanner"`
+	ScannedAt      *time.Time `json:"scanned_at,omitempty" db:"scanned_at"`
+	Quarantined    bool       `json:"quarantined" db:"quarantined"`
+	UploadedBy     *string    `json:"uploaded_by,omitempty" db:"uploaded_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Columns selects every Artifact field
+const Columns = `id, organization_id, kind, finding_id, filename, content_type, size_bytes, sha256,
+	storage_key, scan_verdict, scan_signature, scanner, scanned_at, quarantined, uploaded_by, created_at`
+
+// Upload is a file to accept
+type Upload struct {
+	OrganizationID string
+	Kind           string
+	FindingID      string // For evidence
+	Filename       string
+	UploadedBy     string
+	Policy         Policy
+	// KeyPrefix is prepended to the artifact ID and extension to form the
+	// storage key of a clean upload
+	KeyPrefix string
+}
+
+// Config tunes the service
+type Config struct {
+	// FailOpen accepts uploads as unscanned when the scanner fails, instead
+	// of refusing them
+	FailOpen bool
+}
+
+// Service validates, scans and stores uploads
+type Service struct {
+	db      *database.DB
+	store   storage.Store
+	scanner Scanner // nil: uploads are stored unscanned
+	holds   *legalhold.Service
+	config  Config
+	logger  *zap.Logger
+}
+
+func NewService(db *database.DB, store storage.Store, scanner Scanner, config Config, logger *zap.Logger) *Service {
+	return &Service{
+		db:      db,
+		store:   store,
+		scanner: scanner,
+		config:  config,
+		logger:  logger,
+	}
+}
+
+// Accept reads r, validates it against the upload's policy, scans it and
+// stores it. Infected uploads are stored under quarantine/ and reported
+// with a *QuarantineError.
+func (s *Service) Accept(ctx context.Context, u Upload, r io.Reader) (*Artifact, error) {
+	data, err := io.ReadAll(io.LimitReader(r, u.Policy.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > u.Policy.MaxBytes {
+		metrics.UploadScans.WithLabelValues(u.Kind, "rejected").Inc()
+		return nil, ErrTooLarge
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	ext, ok := u.Policy.Types[contentType]
+	if !ok {
+		metrics.UploadScans.WithLabelValues(u.Kind, "rejected").Inc()
+		return nil, ErrUnsupportedType
+	}
+
+	filename := path.Base(strings.ReplaceAll(u.Filename, "\\", "/"))
+	if len(filename) > 255 {
+		filename = filename[:255]
+	}
+
+	sum := sha256.Sum256(data)
+	artifact := &Artifact{
+		ID:             uuid.New().String(),
+		OrganizationID: u.OrganizationID,
+		Kind:           u.Kind,
+		Filename:       filename,
+		ContentType:    contentType,
+		SizeBytes:      int64(len(data)),
+		SHA256:         hex.EncodeToString(sum[:]),
+		ScanVerdict:    VerdictUnscanned,
+	}
+	if u.FindingID != "" {
+		artifact.FindingID = &u.FindingID
+	}
+	if u.UploadedBy != "" {
+		artifact.UploadedBy = &u.UploadedBy
+	}
+
+	if s.scanner != nil {
+		if err := s.scan(ctx, artifact, data); err != nil {
+			return nil, err
+		}
+	}
+
+	artifact.StorageKey = u.KeyPrefix + artifact.ID + "." + ext
+	if artifact.Quarantined {
+		artifact.StorageKey = fmt.Sprintf("quarantine/%s/%s", u.OrganizationID, artifact.ID)
+	}
+	if _, err := s.store.Put(ctx, artifact.StorageKey, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	err = s.db.GetContext(ctx, artifact, `
+		INSERT INTO uploaded_artifacts (id, organization_id, kind, finding_id, filename, content_type, size_bytes,
+			sha256, storage_key, scan_verdict, scan_signature, scanner, scanned_at, quarantined, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING `+Columns,
+		artifact.ID, artifact.OrganizationID, artifact.Kind, artifact.FindingID, artifact.Filename, artifact.ContentType,
+		artifact.SizeBytes, artifact.SHA256, artifact.StorageKey, artifact.ScanVerdict, artifact.ScanSignature,
+		artifact.Scanner, artifact.ScannedAt, artifact.Quarantined, artifact.UploadedBy)
+	if err != nil {
+		s.deleteObject(ctx, artifact.StorageKey)
+		return nil, fmt.Errorf("failed to record upload: %w", err)
+	}
+
+	metrics.UploadScans.WithLabelValues(u.Kind, artifact.ScanVerdict).Inc()
+	if artifact.Quarantined {
+		logging.FromContext(ctx, s.logger).Warn("Upload quarantined",
+			zap.String("artifact_id", artifact.ID),
+			zap.String("organization_id", artifact.OrganizationID),
+			zap.String("kind", artifact.Kind),
+			zap.Stringp("signature", artifact.ScanSignature),
+		)
+		return nil, &QuarantineError{Artifact: artifact}
+	}
+	return artifact, nil
+}
+
+// scan sets the artifact's verdict
+func (s *Service) scan(ctx context.Context, artifact *Artifact, data []byte) error {
+	result, err := s.scanner.Scan(ctx, bytes.NewReader(data))
+	if err != nil {
+		logging.FromContext(ctx, s.logger).Error("Content scan failed",
+			zap.String("scanner", s.scanner.Name()),
+			zap.String("kind", artifact.Kind),
+			zap.Bool("fail_open", s.config.FailOpen),
+			zap.Error(err),
+		)
+		if !s.config.FailOpen {
+			metrics.UploadScans.WithLabelValues(artifact.Kind, "scan_failed").Inc()
+			return ErrScanUnavailable
+		}
+		return nil
+	}
+
+	name := s.scanner.Name()
+	now := time.Now().UTC()
+	artifact.Scanner = &name
+	artifact.ScannedAt = &now
+	artifact.ScanVerdict = VerdictClean
+	if result.Infected {
+		artifact.ScanVerdict = VerdictInfected
+		artifact.ScanSignature = &result.Signature
+		artifact.Quarantined = true
+	}
+	return nil
+}
+
+// SetLegalHolds keeps uploads of held organizations and users on Discard
+// and Delete
+func (s *Service) SetLegalHolds(holds *legalhold.Service) {
+	s.holds = holds
+}
+
+// Discard deletes an accepted upload that its caller failed to use
+func (s *Service) Discard(ctx context.Context, artifact *Artifact) {
+	if s.held(ctx, artifact.StorageKey, artifact.OrganizationID, artifact.UploadedBy) {
+		return
+	}
+	s.deleteObject(ctx, artifact.StorageKey)
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM uploaded_artifacts WHERE id = $1`, artifact.ID); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to delete upload record", zap.String("artifact_id", artifact.ID), zap.Error(err))
+	}
+}
+
+// Delete removes an upload by its storage key, e.g. a replaced logo
+func (s *Service) Delete(ctx context.Context, key string) {
+	if s.holds != nil {
+		var owner struct {
+			OrganizationID string  `db:"organization_id"`
+			UploadedBy     *string `db:"uploaded_by"`
+		}
+		err := s.db.GetContext(ctx, &owner, `
+			SELECT organization_id, uploaded_by FROM uploaded_artifacts WHERE storage_key = $1
+		`, key)
+		if err != nil && err != sql.ErrNoRows {
+			logging.FromContext(ctx, s.logger).Warn("Failed to load upload record; keeping upload", zap.String("key", key), zap.Error(err))
+			return
+		}
+		if err == nil && s.held(ctx, key, owner.OrganizationID, owner.UploadedBy) {
+			return
+		}
+	}
+	s.deleteObject(ctx, key)
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM uploaded_artifacts WHERE storage_key = $1`, key); err != nil {
+		logging.FromContext(ctx, s.logger).Warn("Failed to delete upload record", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// List returns the organization's uploads of a kind ("" for all), newest
+// first, quarantined ones included
+func (s *Service) List(ctx context.Context, orgID, kind string, limit int) ([]Artifact, error) {
+	artifacts := []Artifact{}
+	err := s.db.Reader().SelectContext(ctx, &artifacts, `
+		SELECT `+Columns+` FROM uploaded_artifacts
+		WHERE organization_id = $1 AND ($2 = '' OR kind = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, orgID, kind, limit)
+	return artifacts, err
+}
+
+// ListEvidence returns a finding's evidence, oldest first
+func (s *Service) ListEvidence(ctx context.Context, orgID, findingID string) ([]Artifact, error) {
+	artifacts := []Artifact{}
+	err := s.db.Reader().SelectContext(ctx, &artifacts, `
+		SELECT `+Columns+` FROM uploaded_artifacts
+		WHERE organization_id = $1 AND finding_id = $2 AND kind = 'evidence'
+		ORDER BY created_at
+	`, orgID, findingID)
+	return artifacts, err
+}
+
+// SignedURL links to a clean upload; quarantined ones are never served
+func (s *Service) SignedURL(ctx context.Context, artifact *Artifact, ttl time.Duration) (string, error) {
+	if artifact.Quarantined {
+		return "", ErrQuarantined
+	}
+	return s.store.SignedURL(ctx, artifact.StorageKey, ttl)
+}
+
+// held reports whether an upload must be kept because its organization or
+// uploader is under legal hold. A failed check keeps the upload.
+func (s *Service) held(ctx context.Context, key, orgID string, uploadedBy *string) bool {
+	if s.holds == nil {
+		return false
+	}
+	userID := ""
+	if uploadedBy != nil {
+		userID = *uploadedBy
+	}
+	err := s.holds.Check(ctx, orgID, userID)
+	if err == nil {
+		return false
+	}
+	log := logging.FromContext(ctx, s.logger)
+	if legalhold.IsHeldError(err) {
+		log.Info("Kept upload under legal hold", zap.String("key", key))
+	} else {
+		log.Warn("Failed to check legal hold; keeping upload", zap.String("key", key), zap.Error(err))
+	}
+	return true
+}
+
+func (s *Service) deleteObject(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil && err != storage.ErrNotFound {
+		logging.FromContext(ctx, s.logger).Warn("Failed to delete upload object", zap.String("key", key), zap.Error(err))
+	}
+}
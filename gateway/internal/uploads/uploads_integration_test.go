@@ -0,0 +1,91 @@
+//go:build integration
+
+package uploads_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cyper-security/gateway/internal/legalhold"
+	"github.com/cyper-security/gateway/internal/storage"
+	"github.com/cyper-security/gateway/internal/testenv"
+	"github.com/cyper-security/gateway/internal/uploads"
+)
+
+var textPolicy = uploads.Policy{
+	MaxBytes: 1 << 10,
+	Types:    map[string]string{"text/plain": "txt"},
+}
+
+func TestDeletionsKeepHeldUploads(t *testing.T) {
+	env := testenv.Setup(t)
+	ctx := context.Background()
+	store, err := storage.NewLocal(t.TempDir(), "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	holds := legalhold.NewService(env.DB, env.Logger)
+	service := uploads.NewService(env.DB, store, nil, uploads.Config{}, env.Logger)
+	service.SetLegalHolds(holds)
+
+	admin := env.CreateUser(t, testenv.UserOptions{Role: "admin"})
+	heldOrg := env.CreateOrganization(t, "Held")
+	freeOrg := env.CreateOrganization(t, "Free")
+	heldUser := env.CreateUser(t, testenv.UserOptions{})
+	if _, err := holds.Place(ctx, legalhold.ScopeOrganization, heldOrg.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := holds.Place(ctx, legalhold.ScopeUser, heldUser.ID, "litigation", "", admin.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	deletions := map[string]func(*uploads.Artifact){
+		"Discard": func(a *uploads.Artifact) { service.Discard(ctx, a) },
+		"Delete":  func(a *uploads.Artifact) { service.Delete(ctx, a.StorageKey) },
+	}
+	tests := []struct {
+		name       string
+		orgID      string
+		uploadedBy string
+		kept       bool
+	}{
+		{"held organization", heldOrg.ID, "", true},
+		{"held uploader", freeOrg.ID, heldUser.ID, true},
+		{"no hold", freeOrg.ID, "", false},
+	}
+	for method, deleteUpload := range deletions {
+		for _, tt := range tests {
+			t.Run(method+"/"+tt.name, func(t *testing.T) {
+				artifact, err := service.Accept(ctx, uploads.Upload{
+					OrganizationID: tt.orgID,
+					Kind:           uploads.KindLogo,
+					Filename:       "notes.txt",
+					UploadedBy:     tt.uploadedBy,
+					Policy:         textPolicy,
+					KeyPrefix:      "test/" + tt.orgID + "/",
+				}, strings.NewReader("retained for review"))
+				if err != nil {
+					t.Fatalf("Accept: %v", err)
+				}
+
+				deleteUpload(artifact)
+
+				var records int
+				if err := env.DB.GetContext(ctx, &records, `SELECT COUNT(*) FROM uploaded_artifacts WHERE id = $1`, artifact.ID); err != nil {
+					t.Fatal(err)
+				}
+				object, err := store.Open(ctx, artifact.StorageKey)
+				if err == nil {
+					object.Close()
+				} else if !errors.Is(err, storage.ErrNotFound) {
+					t.Fatal(err)
+				}
+				if kept := records == 1 && err == nil; kept != tt.kept {
+					t.Errorf("upload kept = %v (records %d, object error %v), want %v", kept, records, err, tt.kept)
+				}
+			})
+		}
+	}
+}
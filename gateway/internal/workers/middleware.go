@@ -0,0 +1,36 @@
+package workers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequireServiceToken authenticates workers with the internal service token
+// (the same X-Service-Token accepted by the gRPC API). Without a configured
+// token the worker API is closed.
+func RequireServiceToken(serviceToken string, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if serviceToken == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Worker API is not configured"})
+			c.Abort()
+			return
+		}
+
+		token := c.GetHeader("X-Service-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(serviceToken)) != 1 {
+			logging.FromContext(c.Request.Context(), logger).Warn("Unauthenticated worker API call",
+				zap.String("path", c.FullPath()),
+				zap.String("ip_address", c.ClientIP()),
+			)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "service token required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,347 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cyper-security/gateway/internal/database"
+	"github.com/cyper-security/gateway/internal/logging"
+	"github.com/cyper-security/gateway/internal/metrics"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var ErrWorkerNotFound = errors.New("worker not found")
+
+// Worker statuses
+const (
+	StatusOnline   = "online"
+	StatusDraining = "draining" // finishing current jobs, not given new ones
+	StatusOffline  = "offline"
+)
+
+// Config controls heartbeat expectations and recovery of abandoned jobs
+type Config struct {
+	// HeartbeatInterval is how often workers are asked to check in
+	HeartbeatInterval time.Duration
+	// StaleAfter marks a worker offline when it has not checked in for this long
+	StaleAfter time.Duration
+	// ReapInterval is how often stale workers are looked for
+	ReapInterval time.Duration
+	// MaxRequeues fails a job instead of re-queuing it once it has been
+	// orphaned this many times, so a job that crashes workers cannot loop
+	MaxRequeues int
+}
+
+// DefaultConfig tolerates two missed heartbeats
+func DefaultConfig() Config {
+	return Config{
+		HeartbeatInterval: 30 * time.Second,
+		StaleAfter:        90 * time.Second,
+		ReapInterval:      30 * time.Second,
+		MaxRequeues:       3,
+	}
+}
+
+// Worker is a registered scanner worker
+type Worker struct {
+	ID              string          `json:"id" db:"id"`
+	Name            string          `json:"name" db:"name"`
+	Hostname        *string         `json:"hostname,omitempty" db:"hostname"`
+	Version         *string         `json:"version,omitempty" db:"version"`
+	ScanTypes       pq.StringArray  `json:"scan_types" db:"scan_types"`
+	Capacity        int             `json:"capacity" db:"capacity"`
+	ActiveJobs      int             `json:"active_jobs" db:"active_jobs"`
+	Metadata        json.RawMessage `json:"metadata" db:"metadata"`
+	Status          string          `json:"status" db:"status"`
+	IPAddress       *string         `json:"ip_address,omitempty" db:"ip_address"`
+	RegisteredAt    time.Time       `json:"registered_at" db:"registered_at"`
+	LastHeartbeatAt time.Time       `json:"last_heartbeat_at" db:"last_heartbeat_at"`
+	OfflineAt       *time.Time      `json:"offline_at,omitempty" db:"offline_at"`
+
+	// Jobs currently assigned to the worker; filled in by Get
+	RunningJobs []string `json:"running_jobs,omitempty" db:"-"`
+}
+
+// Registration is what a worker advertises when it starts
+type Registration struct {
+	// ID re-registers a known worker after a restart; empty registers a new one
+	ID        string          `json:"id" binding:"omitempty,uuid"`
+	Name      string          `json:"name" binding:"required,max=255"`
+	Hostname  string          `json:"hostname" binding:"max=255"`
+	Version   string          `json:"version" binding:"max=50"`
+	ScanTypes []string        `json:"scan_types" binding:"required,min=1"`
+	Capacity  int             `json:"capacity" binding:"min=0,max=1000"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+// Heartbeat reports a worker's current load; capability fields left empty
+// keep their registered values
+type Heartbeat struct {
+	ActiveJobs int      `json:"active_jobs" binding:"min=0"`
+	ScanTypes  []string `json:"scan_types"`
+	Capacity   *int     `json:"capacity" binding:"omitempty,min=0,max=1000"`
+	Draining   bool     `json:"draining"`
+}
+
+// HeartbeatAck tells the worker when to check in next
+type HeartbeatAck struct {
+	WorkerID          string `json:"worker_id"`
+	Status            string `json:"status"`
+	HeartbeatInterval int    `json:"heartbeat_interval_seconds"`
+}
+
+// Registry tracks workers in the database
+type Registry struct {
+	db     *database.DB
+	config Config
+	logger *zap.Logger
+}
+
+func NewRegistry(db *database.DB, config Config, logger *zap.Logger) *Registry {
+	return &Registry{
+		db:     db,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Config returns the registry's configuration
+func (r *Registry) Config() Config {
+	return r.config
+}
+
+// Register adds a worker, or brings a known one back online with its new
+// capabilities
+func (r *Registry) Register(ctx context.Context, reg Registration, ipAddress string) (*HeartbeatAck, error) {
+	if reg.Capacity == 0 {
+		reg.Capacity = 1
+	}
+	metadata := reg.Metadata
+	if len(metadata) == 0 {
+		metadata = json.RawMessage(`{}`)
+	}
+
+	var id string
+	err := r.db.GetContext(ctx, &id, `
+		INSERT INTO scan_workers (id, name, hostname, version, scan_types, capacity, metadata, ip_address)
+		VALUES (COALESCE(NULLIF($1, '')::uuid, gen_random_uuid()), $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, NULLIF($8, '')::inet)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			hostname = EXCLUDED.hostname,
+			version = EXCLUDED.version,
+			scan_types = EXCLUDED.scan_types,
+			capacity = EXCLUDED.capacity,
+			metadata = EXCLUDED.metadata,
+			ip_address = EXCLUDED.ip_address,
+			status = 'online',
+			active_jobs = 0,
+			last_heartbeat_at = NOW(),
+			offline_at = NULL
+		RETURNING id
+	`, reg.ID, reg.Name, reg.Hostname, reg.Version, pq.StringArray(reg.ScanTypes), reg.Capacity, metadata, ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register worker: %w", err)
+	}
+
+	// A restarted worker has lost whatever it was running
+	if reg.ID != "" {
+		if _, err := r.requeueJobs(ctx, id); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to re-queue jobs of restarted worker", zap.String("worker_id", id), zap.Error(err))
+		}
+	}
+
+	logging.FromContext(ctx, r.logger).Info("Worker registered",
+		zap.String("worker_id", id),
+		zap.String("name", reg.Name),
+		zap.Strings("scan_types", reg.ScanTypes),
+		zap.Int("capacity", reg.Capacity),
+	)
+
+	return r.ack(id, StatusOnline), nil
+}
+
+// Heartbeat records that a worker is alive and its current load
+func (r *Registry) Heartbeat(ctx context.Context, workerID string, hb Heartbeat) (*HeartbeatAck, error) {
+	status := StatusOnline
+	if hb.Draining {
+		status = StatusDraining
+	}
+
+	var scanTypes interface{}
+	if len(hb.ScanTypes) > 0 {
+		scanTypes = pq.StringArray(hb.ScanTypes)
+	}
+
+	var previous string
+	err := r.db.GetContext(ctx, &previous, `
+		UPDATE scan_workers w
+		SET active_jobs = $2,
+		    scan_types = COALESCE($3, w.scan_types),
+		    capacity = COALESCE($4, w.capacity),
+		    status = $5,
+		    last_heartbeat_at = NOW(),
+		    offline_at = NULL
+		FROM (SELECT id, status FROM scan_workers WHERE id = $1 FOR UPDATE) old
+		WHERE w.id = old.id
+		RETURNING old.status
+	`, workerID, hb.ActiveJobs, scanTypes, hb.Capacity, status)
+	if err == sql.ErrNoRows {
+		return nil, ErrWorkerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	if previous == StatusOffline {
+		logging.FromContext(ctx, r.logger).Info("Worker back online", zap.String("worker_id", workerID))
+	}
+
+	return r.ack(workerID, status), nil
+}
+
+func (r *Registry) ack(workerID, status string) *HeartbeatAck {
+	return &HeartbeatAck{
+		WorkerID:          workerID,
+		Status:            status,
+		HeartbeatInterval: int(r.config.HeartbeatInterval.Seconds()),
+	}
+}
+
+// List returns the fleet, optionally filtered by status
+func (r *Registry) List(ctx context.Context, status string) ([]Worker, error) {
+	workers := []Worker{}
+	err := r.db.SelectContext(ctx, &workers, `
+		SELECT id, name, hostname, version, scan_types, capacity, active_jobs, metadata,
+		       status, host(ip_address) AS ip_address, registered_at, last_heartbeat_at, offline_at
+		FROM scan_workers
+		WHERE $1 = '' OR status = $1
+		ORDER BY status, name
+	`, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	return workers, nil
+}
+
+// Get returns one worker with the jobs it is running
+func (r *Registry) Get(ctx context.Context, workerID string) (*Worker, error) {
+	var w Worker
+	err := r.db.GetContext(ctx, &w, `
+		SELECT id, name, hostname, version, scan_types, capacity, active_jobs, metadata,
+		       status, host(ip_address) AS ip_address, registered_at, last_heartbeat_at, offline_at
+		FROM scan_workers
+		WHERE id = $1
+	`, workerID)
+	if err == sql.ErrNoRows {
+		return nil, ErrWorkerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load worker: %w", err)
+	}
+
+	err = r.db.SelectContext(ctx, &w.RunningJobs, `
+		SELECT id FROM scan_jobs WHERE worker_id = $1 AND status = 'running' ORDER BY started_at
+	`, workerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load worker jobs: %w", err)
+	}
+	return &w, nil
+}
+
+// StartReaper marks workers that stopped sending heartbeats offline and
+// re-queues their jobs, until ctx is cancelled
+func (r *Registry) StartReaper(ctx context.Context) {
+	ticker := time.NewTicker(r.config.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		r.reap(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Registry) reap(ctx context.Context) {
+	var stale []string
+	err := r.db.SelectContext(ctx, &stale, `
+		UPDATE scan_workers
+		SET status = 'offline', offline_at = NOW(), active_jobs = 0
+		WHERE status <> 'offline' AND last_heartbeat_at < NOW() - $1 * INTERVAL '1 second'
+		RETURNING id
+	`, r.config.StaleAfter.Seconds())
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("Failed to mark stale workers offline", zap.Error(err))
+		return
+	}
+
+	for _, workerID := range stale {
+		logging.FromContext(ctx, r.logger).Warn("Worker missed heartbeats, marked offline", zap.String("worker_id", workerID))
+		if _, err := r.requeueJobs(ctx, workerID); err != nil {
+			logging.FromContext(ctx, r.logger).Error("Failed to re-queue jobs of offline worker", zap.String("worker_id", workerID), zap.Error(err))
+		}
+	}
+
+	r.recordFleet(ctx)
+}
+
+// requeueJobs returns a worker's running jobs to the queue, failing those
+// that have already been orphaned MaxRequeues times
+func (r *Registry) requeueJobs(ctx context.Context, workerID string) (int, error) {
+	var jobs []struct {
+		ID     string `db:"id"`
+		Status string `db:"status"`
+	}
+	err := r.db.SelectContext(ctx, &jobs, `
+		UPDATE scan_jobs
+		SET status = CASE WHEN requeue_count >= $2 THEN 'failed' ELSE 'pending' END,
+		    error_message = CASE WHEN requeue_count >= $2 THEN 'Worker lost too many times' ELSE error_message END,
+		    completed_at = CASE WHEN requeue_count >= $2 THEN NOW() ELSE NULL END,
+		    started_at = CASE WHEN requeue_count >= $2 THEN started_at ELSE NULL END,
+		    current_phase = CASE WHEN requeue_count >= $2 THEN current_phase ELSE 'requeued' END,
+		    progress_percentage = CASE WHEN requeue_count >= $2 THEN progress_percentage ELSE 0 END,
+		    requeue_count = requeue_count + 1,
+		    worker_id = NULL,
+		    updated_at = NOW()
+		WHERE worker_id = $1 AND status = 'running'
+		RETURNING id, status
+	`, workerID, r.config.MaxRequeues)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, job := range jobs {
+		metrics.ScanJobsRequeued.WithLabelValues(job.Status).Inc()
+		logging.FromContext(ctx, r.logger).Warn("Scan job recovered from lost worker",
+			zap.String("scan_job_id", job.ID),
+			zap.String("worker_id", workerID),
+			zap.String("status", job.Status),
+		)
+	}
+	return len(jobs), nil
+}
+
+// recordFleet publishes worker counts by status
+func (r *Registry) recordFleet(ctx context.Context) {
+	var counts []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &counts, "SELECT status, COUNT(*) AS count FROM scan_workers GROUP BY status"); err != nil {
+		return
+	}
+
+	for _, status := range []string{StatusOnline, StatusDraining, StatusOffline} {
+		metrics.ScanWorkers.WithLabelValues(status).Set(0)
+	}
+	for _, c := range counts {
+		metrics.ScanWorkers.WithLabelValues(c.Status).Set(float64(c.Count))
+	}
+}